@@ -47,6 +47,10 @@ type AddrManager struct {
 	lamtx          sync.Mutex
 	localAddresses map[string]*LocalAddress
 	version        int
+
+	// anchors are the addresses of the outbound peers to reconnect to
+	// first, which are persisted across restarts.
+	anchors []*wire.NetAddressV2
 }
 
 type serializedKnownAddress struct {
@@ -58,15 +62,27 @@ type serializedKnownAddress struct {
 	LastSuccess int64
 	Services    wire.ServiceFlag
 	SrcServices wire.ServiceFlag
+	// Network is only set for the addresses which can't be represented by
+	// a legacy address, such as Tor v3 onion services.
+	Network wire.NetworkID `json:",omitempty"`
 	// no refcount or tried, that is available from context.
 }
 
+type serializedAnchor struct {
+	Addr     string
+	Services wire.ServiceFlag
+	// Network is only set for the addresses which can't be represented by
+	// a legacy address, such as Tor v3 onion services.
+	Network wire.NetworkID `json:",omitempty"`
+}
+
 type serializedAddrManager struct {
 	Version      int
 	Key          [32]byte
 	Addresses    []*serializedKnownAddress
 	NewBuckets   [newBucketCount][]string // string is NetAddressKey
 	TriedBuckets [triedBucketCount][]string
+	Anchors      []*serializedAnchor `json:",omitempty"`
 }
 
 type LocalAddress struct {
@@ -159,18 +175,24 @@ const (
 
 	// serialisationVersion is the current version of the on-disk format.
 	serialisationVersion = 2
+
+	// MaxAnchors is the maximum number of anchor addresses persisted
+	// across restarts.
+	MaxAnchors = 2
 )
 
 // updateAddress is a helper function to either update an address already known
 // to the address manager, or to add the address if not already known.
-func (a *AddrManager) updateAddress(netAddr, srcAddr *wire.NetAddress) {
+func (a *AddrManager) updateAddress(netAddr *wire.NetAddressV2,
+	srcAddr *wire.NetAddress) {
+
 	// Filter out non-routable addresses. Note that non-routable
 	// also includes invalid and local addresses.
-	if !IsRoutable(netAddr) {
+	if !IsRoutableV2(netAddr) {
 		return
 	}
 
-	addr := NetAddressKey(netAddr)
+	addr := NetAddressKeyV2(netAddr)
 	ka := a.find(netAddr)
 	if ka != nil {
 		// TODO: only update addresses periodically.
@@ -185,7 +207,7 @@ func (a *AddrManager) updateAddress(netAddr, srcAddr *wire.NetAddress) {
 
 			naCopy := *ka.na
 			naCopy.Timestamp = netAddr.Timestamp
-			naCopy.AddService(netAddr.Services)
+			naCopy.Services |= netAddr.Services
 			ka.mtx.Lock()
 			ka.na = &naCopy
 			ka.mtx.Unlock()
@@ -267,7 +289,7 @@ func (a *AddrManager) expireNew(bucket int) {
 	}
 
 	if oldest != nil {
-		key := NetAddressKey(oldest.na)
+		key := NetAddressKeyV2(oldest.na)
 		log.Tracef("expiring oldest address %v", key)
 
 		delete(a.addrNew[bucket], key)
@@ -296,13 +318,15 @@ func (a *AddrManager) pickTried(bucket int) *list.Element {
 	return oldestElem
 }
 
-func (a *AddrManager) getNewBucket(netAddr, srcAddr *wire.NetAddress) int {
+func (a *AddrManager) getNewBucket(netAddr *wire.NetAddressV2,
+	srcAddr *wire.NetAddress) int {
+
 	// bitcoind:
 	// doublesha256(key + sourcegroup + int64(doublesha256(key + group + sourcegroup))%bucket_per_source_group) % num_new_buckets
 
 	data1 := []byte{}
 	data1 = append(data1, a.key[:]...)
-	data1 = append(data1, []byte(GroupKey(netAddr))...)
+	data1 = append(data1, []byte(GroupKeyV2(netAddr))...)
 	data1 = append(data1, []byte(GroupKey(srcAddr))...)
 	hash1 := chainhash.DoubleHashB(data1)
 	hash64 := binary.LittleEndian.Uint64(hash1)
@@ -318,12 +342,12 @@ func (a *AddrManager) getNewBucket(netAddr, srcAddr *wire.NetAddress) int {
 	return int(binary.LittleEndian.Uint64(hash2) % newBucketCount)
 }
 
-func (a *AddrManager) getTriedBucket(netAddr *wire.NetAddress) int {
+func (a *AddrManager) getTriedBucket(netAddr *wire.NetAddressV2) int {
 	// bitcoind hashes this as:
 	// doublesha256(key + group + truncate_to_64bits(doublesha256(key)) % buckets_per_group) % num_buckets
 	data1 := []byte{}
 	data1 = append(data1, a.key[:]...)
-	data1 = append(data1, []byte(NetAddressKey(netAddr))...)
+	data1 = append(data1, []byte(NetAddressKeyV2(netAddr))...)
 	hash1 := chainhash.DoubleHashB(data1)
 	hash64 := binary.LittleEndian.Uint64(hash1)
 	hash64 %= triedBucketsPerGroup
@@ -331,7 +355,7 @@ func (a *AddrManager) getTriedBucket(netAddr *wire.NetAddress) int {
 	binary.LittleEndian.PutUint64(hashbuf[:], hash64)
 	data2 := []byte{}
 	data2 = append(data2, a.key[:]...)
-	data2 = append(data2, GroupKey(netAddr)...)
+	data2 = append(data2, GroupKeyV2(netAddr)...)
 	data2 = append(data2, hashbuf[:]...)
 
 	hash2 := chainhash.DoubleHashB(data2)
@@ -384,6 +408,9 @@ func (a *AddrManager) savePeers() {
 			ska.Services = v.na.Services
 			ska.SrcServices = v.srcAddr.Services
 		}
+		if v.na.ToLegacy() == nil {
+			ska.Network = v.na.Network
+		}
 		// Tried and refs are implicit in the rest of the structure
 		// and will be worked out from context on unserialisation.
 		sam.Addresses[i] = ska
@@ -402,10 +429,20 @@ func (a *AddrManager) savePeers() {
 		j := 0
 		for e := a.addrTried[i].Front(); e != nil; e = e.Next() {
 			ka := e.Value.(*KnownAddress)
-			sam.TriedBuckets[i][j] = NetAddressKey(ka.na)
+			sam.TriedBuckets[i][j] = NetAddressKeyV2(ka.na)
 			j++
 		}
 	}
+	for _, na := range a.anchors {
+		anchor := &serializedAnchor{
+			Addr:     NetAddressKeyV2(na),
+			Services: na.Services,
+		}
+		if na.ToLegacy() == nil {
+			anchor.Network = na.Network
+		}
+		sam.Anchors = append(sam.Anchors, anchor)
+	}
 
 	w, err := os.Create(a.peersFile)
 	if err != nil {
@@ -479,7 +516,8 @@ func (a *AddrManager) deserializePeers(filePath string) error {
 		if sam.Version == 1 {
 			v.Services = wire.SFNodeNetwork
 		}
-		ka.na, err = a.DeserializeNetAddress(v.Addr, v.Services)
+		ka.na, err = a.deserializeNetAddressV2(v.Addr, v.Network,
+			v.Services)
 		if err != nil {
 			return fmt.Errorf("failed to deserialize netaddress "+
 				"%s: %v", v.Addr, err)
@@ -500,7 +538,7 @@ func (a *AddrManager) deserializePeers(filePath string) error {
 		ka.attempts = v.Attempts
 		ka.lastattempt = time.Unix(v.LastAttempt, 0)
 		ka.lastsuccess = time.Unix(v.LastSuccess, 0)
-		a.addrIndex[NetAddressKey(ka.na)] = ka
+		a.addrIndex[NetAddressKeyV2(ka.na)] = ka
 	}
 
 	for i := range sam.NewBuckets {
@@ -532,6 +570,16 @@ func (a *AddrManager) deserializePeers(filePath string) error {
 		}
 	}
 
+	for _, v := range sam.Anchors {
+		na, err := a.deserializeNetAddressV2(v.Addr, v.Network,
+			v.Services)
+		if err != nil {
+			return fmt.Errorf("failed to deserialize anchor "+
+				"%s: %v", v.Addr, err)
+		}
+		a.anchors = append(a.anchors, na)
+	}
+
 	// Sanity checking.
 	for k, v := range a.addrIndex {
 		if v.refs == 0 && !v.tried {
@@ -564,6 +612,32 @@ func (a *AddrManager) DeserializeNetAddress(addr string,
 	return a.HostToNetAddress(host, uint16(port), services)
 }
 
+// deserializeNetAddressV2 converts a given address string of the passed network
+// to a *wire.NetAddressV2.  The addresses without network are legacy ones.
+func (a *AddrManager) deserializeNetAddressV2(addr string,
+	network wire.NetworkID, services wire.ServiceFlag) (*wire.NetAddressV2,
+	error) {
+
+	if network == 0 {
+		na, err := a.DeserializeNetAddress(addr, services)
+		if err != nil {
+			return nil, err
+		}
+		return wire.NetAddressV2FromLegacy(na), nil
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	return wire.NetAddressV2FromHost(network, host, uint16(port), services)
+}
+
 // Start begins the core address handler which manages a pool of known
 // addresses, timeouts, and interval based writes.
 func (a *AddrManager) Start() {
@@ -604,7 +678,23 @@ func (a *AddrManager) AddAddresses(addrs []*wire.NetAddress, srcAddr *wire.NetAd
 	defer a.mtx.Unlock()
 
 	for _, na := range addrs {
-		a.updateAddress(na, srcAddr)
+		a.updateAddress(wire.NetAddressV2FromLegacy(na), srcAddr)
+	}
+}
+
+// AddAddressesV2 adds new addresses received in an addrv2 message to the
+// address manager, including the ones of networks which can't be represented
+// by legacy addresses.  It enforces a max number of addresses and silently
+// ignores duplicate addresses.  It is safe for concurrent access.
+func (a *AddrManager) AddAddressesV2(addrs []*wire.NetAddressV2, srcAddr *wire.NetAddress) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for _, na := range addrs {
+		// Make a copy of the net address to avoid races since it is
+		// updated elsewhere in the addrmanager code.
+		naCopy := *na
+		a.updateAddress(&naCopy, srcAddr)
 	}
 }
 
@@ -615,7 +705,7 @@ func (a *AddrManager) AddAddress(addr, srcAddr *wire.NetAddress) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
-	a.updateAddress(addr, srcAddr)
+	a.updateAddress(wire.NetAddressV2FromLegacy(addr), srcAddr)
 }
 
 // AddAddressByIP adds an address where we are given an ip:port and not a
@@ -662,11 +752,30 @@ func (a *AddrManager) NeedMoreAddresses() bool {
 	return a.numAddresses() < needAddressThreshold
 }
 
-// AddressCache returns the current address cache.  It must be treated as
-// read-only (but since it is a copy now, this is not as dangerous).
+// AddressCache returns the current address cache of the addresses which can
+// be represented by legacy addresses.  It must be treated as read-only (but
+// since it is a copy now, this is not as dangerous).
 func (a *AddrManager) AddressCache() []*wire.NetAddress {
-	allAddr := a.getAddresses()
+	allAddr := a.getAddresses(true)
+
+	legacyAddrs := make([]*wire.NetAddress, 0, len(allAddr))
+	for _, na := range shuffleAddresses(allAddr) {
+		legacyAddrs = append(legacyAddrs, na.ToLegacy())
+	}
+
+	return legacyAddrs
+}
+
+// AddressCacheV2 returns the current address cache including the addresses of
+// the networks which can't be represented by legacy addresses, to be relayed
+// in addrv2 messages.  It must be treated as read-only.
+func (a *AddrManager) AddressCacheV2() []*wire.NetAddressV2 {
+	return shuffleAddresses(a.getAddresses(false))
+}
 
+// shuffleAddresses returns a random selection of the passed addresses limited
+// to the amount we are willing to share.
+func shuffleAddresses(allAddr []*wire.NetAddressV2) []*wire.NetAddressV2 {
 	numAddresses := len(allAddr) * getAddrPercent / 100
 	if numAddresses > getAddrMax {
 		numAddresses = getAddrMax
@@ -685,8 +794,9 @@ func (a *AddrManager) AddressCache() []*wire.NetAddress {
 }
 
 // getAddresses returns all of the addresses currently found within the
-// manager's address cache.
-func (a *AddrManager) getAddresses() []*wire.NetAddress {
+// manager's address cache, only including the ones which can be represented by
+// legacy addresses when legacyOnly is set.
+func (a *AddrManager) getAddresses(legacyOnly bool) []*wire.NetAddressV2 {
 	a.mtx.RLock()
 	defer a.mtx.RUnlock()
 
@@ -695,8 +805,11 @@ func (a *AddrManager) getAddresses() []*wire.NetAddress {
 		return nil
 	}
 
-	addrs := make([]*wire.NetAddress, 0, addrIndexLen)
+	addrs := make([]*wire.NetAddressV2, 0, addrIndexLen)
 	for _, v := range a.addrIndex {
+		if legacyOnly && !hasLegacyNetwork(v.na) {
+			continue
+		}
 		addrs = append(addrs, v.na)
 	}
 
@@ -708,6 +821,7 @@ func (a *AddrManager) getAddresses() []*wire.NetAddress {
 func (a *AddrManager) reset() {
 
 	a.addrIndex = make(map[string]*KnownAddress)
+	a.anchors = nil
 
 	// fill key with bytes from a good random source.
 	io.ReadFull(crand.Reader, a.key[:])
@@ -771,6 +885,13 @@ func NetAddressKey(na *wire.NetAddress) string {
 	return net.JoinHostPort(ipString(na), port)
 }
 
+// NetAddressKeyV2 returns a string key in the form of host:port, which is the
+// same as the one returned by NetAddressKey for the addresses which can be
+// represented by legacy addresses.
+func NetAddressKeyV2(na *wire.NetAddressV2) string {
+	return na.String()
+}
+
 // GetAddress returns a single address that should be routable.  It picks a
 // random one from the possible addresses with preference given to ones that
 // have not been used recently and should not pick 'close' addresses
@@ -806,7 +927,7 @@ func (a *AddrManager) GetAddress() *KnownAddress {
 			randval := a.rand.Intn(large)
 			if float64(randval) < (factor * ka.chance() * float64(large)) {
 				log.Tracef("Selected %v from tried bucket",
-					NetAddressKey(ka.na))
+					NetAddressKeyV2(ka.na))
 				return ka
 			}
 			factor *= 1.2
@@ -834,7 +955,7 @@ func (a *AddrManager) GetAddress() *KnownAddress {
 			randval := a.rand.Intn(large)
 			if float64(randval) < (factor * ka.chance() * float64(large)) {
 				log.Tracef("Selected %v from new bucket",
-					NetAddressKey(ka.na))
+					NetAddressKeyV2(ka.na))
 				return ka
 			}
 			factor *= 1.2
@@ -842,13 +963,42 @@ func (a *AddrManager) GetAddress() *KnownAddress {
 	}
 }
 
-func (a *AddrManager) find(addr *wire.NetAddress) *KnownAddress {
-	return a.addrIndex[NetAddressKey(addr)]
+// SetAnchors sets the addresses of the outbound peers to reconnect to first
+// after a restart, up to MaxAnchors of them.  They are usually the peers which
+// have been connected the longest when shutting down, which makes it harder
+// for an attacker to take over all the outbound connections of a restarted
+// node.
+func (a *AddrManager) SetAnchors(addrs []*wire.NetAddressV2) {
+	if len(addrs) > MaxAnchors {
+		addrs = addrs[:MaxAnchors]
+	}
+
+	a.mtx.Lock()
+	a.anchors = append([]*wire.NetAddressV2(nil), addrs...)
+	a.mtx.Unlock()
+}
+
+// TakeAnchor returns and removes the next address set with SetAnchors, or nil
+// when there are none left, so each anchor is only connected to once.
+func (a *AddrManager) TakeAnchor() *wire.NetAddressV2 {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if len(a.anchors) == 0 {
+		return nil
+	}
+	na := a.anchors[0]
+	a.anchors = a.anchors[1:]
+	return na
+}
+
+func (a *AddrManager) find(addr *wire.NetAddressV2) *KnownAddress {
+	return a.addrIndex[NetAddressKeyV2(addr)]
 }
 
 // Attempt increases the given address' attempt counter and updates
 // the last attempt time.
-func (a *AddrManager) Attempt(addr *wire.NetAddress) {
+func (a *AddrManager) Attempt(addr *wire.NetAddressV2) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -869,7 +1019,7 @@ func (a *AddrManager) Attempt(addr *wire.NetAddress) {
 // Connected Marks the given address as currently connected and working at the
 // current time.  The address must already be known to AddrManager else it will
 // be ignored.
-func (a *AddrManager) Connected(addr *wire.NetAddress) {
+func (a *AddrManager) Connected(addr *wire.NetAddressV2) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -894,7 +1044,7 @@ func (a *AddrManager) Connected(addr *wire.NetAddress) {
 // Good marks the given address as good.  To be called after a successful
 // connection and version exchange.  If the address is unknown to the address
 // manager it will be ignored.
-func (a *AddrManager) Good(addr *wire.NetAddress) {
+func (a *AddrManager) Good(addr *wire.NetAddressV2) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -921,7 +1071,7 @@ func (a *AddrManager) Good(addr *wire.NetAddress) {
 
 	// remove from all new buckets.
 	// record one of the buckets in question and call it the `first'
-	addrKey := NetAddressKey(addr)
+	addrKey := NetAddressKeyV2(addr)
 	oldBucket := -1
 	for i := range a.addrNew {
 		// we check for existence so we can record the first one
@@ -975,7 +1125,7 @@ func (a *AddrManager) Good(addr *wire.NetAddress) {
 	// something back.
 	a.nNew++
 
-	rmkey := NetAddressKey(rmka.na)
+	rmkey := NetAddressKeyV2(rmka.na)
 	log.Tracef("Replacing %s with %s in tried", rmkey, addrKey)
 
 	// We made sure there is space here just above.
@@ -983,7 +1133,7 @@ func (a *AddrManager) Good(addr *wire.NetAddress) {
 }
 
 // SetServices sets the services for the giiven address to the provided value.
-func (a *AddrManager) SetServices(addr *wire.NetAddress, services wire.ServiceFlag) {
+func (a *AddrManager) SetServices(addr *wire.NetAddressV2, services wire.ServiceFlag) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -1,6 +1,7 @@
 package addrmgr
 
 import (
+	"bytes"
 	"io/ioutil"
 	"math/rand"
 	"net"
@@ -60,7 +61,7 @@ func assertAddrs(t *testing.T, addrMgr *AddrManager,
 
 	t.Helper()
 
-	addrs := addrMgr.getAddresses()
+	addrs := addrMgr.getAddresses(false)
 
 	if len(addrs) != len(expectedAddrs) {
 		t.Fatalf("expected to find %d addresses, found %d",
@@ -68,13 +69,13 @@ func assertAddrs(t *testing.T, addrMgr *AddrManager,
 	}
 
 	for _, addr := range addrs {
-		addrStr := NetAddressKey(addr)
+		addrStr := NetAddressKeyV2(addr)
 		expectedAddr, ok := expectedAddrs[addrStr]
 		if !ok {
 			t.Fatalf("expected to find address %v", addrStr)
 		}
 
-		assertAddr(t, addr, expectedAddr)
+		assertAddr(t, addr.ToLegacy(), expectedAddr)
 	}
 }
 
@@ -160,13 +161,13 @@ func TestAddrManagerV1ToV2(t *testing.T) {
 	// that this default is set, we'll override each addresses' services
 	// with the original value from when they were created.
 	addrMgr.loadPeers()
-	addrs := addrMgr.getAddresses()
+	addrs := addrMgr.getAddresses(false)
 	if len(addrs) != len(expectedAddrs) {
 		t.Fatalf("expected to find %d adddresses, found %d",
 			len(expectedAddrs), len(addrs))
 	}
 	for _, addr := range addrs {
-		addrStr := NetAddressKey(addr)
+		addrStr := NetAddressKeyV2(addr)
 		expectedAddr, ok := expectedAddrs[addrStr]
 		if !ok {
 			t.Fatalf("expected to find address %v", addrStr)
@@ -192,3 +193,128 @@ func TestAddrManagerV1ToV2(t *testing.T) {
 	addrMgr.loadPeers()
 	assertAddrs(t, addrMgr, expectedAddrs)
 }
+
+// TestAddrManagerSerializationV2 ensures the addresses of the networks which
+// can't be represented by legacy addresses are properly serialized and
+// deserialized, and are only served in the addrv2 address cache.
+func TestAddrManagerSerializationV2(t *testing.T) {
+	t.Parallel()
+
+	tempDir, err := ioutil.TempDir("", "addrmgr")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	torV3, err := wire.NewNetAddressTorV3(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion",
+		9246, wire.SFNodeNetwork)
+	if err != nil {
+		t.Fatalf("NewNetAddressTorV3: unexpected error: %v", err)
+	}
+	i2pAddr := make([]byte, 32)
+	rand.Read(i2pAddr)
+	cjdnsAddr := net.ParseIP("fc32:17ea:e415:c3bf:9808:149d:b5a2:c9aa")
+	addrs := []*wire.NetAddressV2{
+		torV3,
+		wire.NewNetAddressV2(wire.NetworkI2P, i2pAddr, 0,
+			wire.SFNodeNetwork),
+		wire.NewNetAddressV2(wire.NetworkCJDNS, cjdnsAddr, 9246,
+			wire.SFNodeNetwork),
+		wire.NetAddressV2FromLegacy(wire.NewNetAddressIPPort(
+			net.ParseIP("173.194.115.66"), 9246, wire.SFNodeNetwork)),
+
+		// Unroutable addresses which must be ignored.
+		wire.NewNetAddressV2(wire.NetworkCJDNS,
+			net.ParseIP("2001:db8::1"), 9246, wire.SFNodeNetwork),
+		wire.NewNetAddressV2(wire.NetworkID(42), []byte{1, 2, 3}, 9246,
+			wire.SFNodeNetwork),
+	}
+
+	addrMgr := New(tempDir, nil)
+	addrMgr.AddAddressesV2(addrs, randAddr(t))
+
+	assertAddrsV2 := func(addrMgr *AddrManager) {
+		t.Helper()
+
+		got := addrMgr.getAddresses(false)
+		if len(got) != 4 {
+			t.Fatalf("expected to find 4 addresses, found %d",
+				len(got))
+		}
+		for _, want := range addrs[:4] {
+			ka := addrMgr.find(want)
+			if ka == nil {
+				t.Fatalf("expected to find address %v", want)
+			}
+			if ka.na.Network != want.Network ||
+				!bytes.Equal(ka.na.Addr, want.Addr) ||
+				ka.na.Port != want.Port ||
+				ka.na.Services != want.Services {
+
+				t.Fatalf("expected address %v, got %v", want,
+					ka.na)
+			}
+		}
+
+		// Only the IPv4 address can be served in addr messages.
+		legacy := addrMgr.getAddresses(true)
+		if len(legacy) != 1 || legacy[0].Network != wire.NetworkIPv4 {
+			t.Fatalf("unexpected legacy addresses %v", legacy)
+		}
+	}
+	assertAddrsV2(addrMgr)
+
+	addrMgr.savePeers()
+	addrMgr = New(tempDir, nil)
+	addrMgr.loadPeers()
+	assertAddrsV2(addrMgr)
+}
+
+// TestAddrManagerAnchors ensures the anchors are persisted across restarts, and
+// are only handed out once.
+func TestAddrManagerAnchors(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	torV3, err := wire.NewNetAddressTorV3(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion",
+		9246, wire.SFNodeNetwork)
+	if err != nil {
+		t.Fatalf("NewNetAddressTorV3: unexpected error: %v", err)
+	}
+	anchors := []*wire.NetAddressV2{
+		wire.NetAddressV2FromLegacy(wire.NewNetAddressIPPort(
+			net.ParseIP("173.194.115.66"), 9246, wire.SFNodeNetwork)),
+		torV3,
+		wire.NetAddressV2FromLegacy(wire.NewNetAddressIPPort(
+			net.ParseIP("173.194.115.67"), 9246, wire.SFNodeNetwork)),
+	}
+
+	addrMgr := New(tempDir, nil)
+	addrMgr.SetAnchors(anchors)
+	addrMgr.savePeers()
+
+	addrMgr = New(tempDir, nil)
+	addrMgr.loadPeers()
+	for i, want := range anchors[:MaxAnchors] {
+		got := addrMgr.TakeAnchor()
+		if got == nil || NetAddressKeyV2(got) != NetAddressKeyV2(want) ||
+			got.Network != want.Network || got.Services != want.Services {
+
+			t.Fatalf("anchor #%d: got %v, want %v", i, got, want)
+		}
+	}
+	if got := addrMgr.TakeAnchor(); got != nil {
+		t.Fatalf("unexpected anchor %v", got)
+	}
+
+	// The anchors which were taken are not saved again.
+	addrMgr.savePeers()
+	addrMgr = New(tempDir, nil)
+	addrMgr.loadPeers()
+	if got := addrMgr.TakeAnchor(); got != nil {
+		t.Fatalf("unexpected anchor %v after restart", got)
+	}
+}
@@ -300,7 +300,7 @@ func TestGood(t *testing.T) {
 
 	n.AddAddresses(addrs, srcAddr)
 	for _, addr := range addrs {
-		n.Good(addr)
+		n.Good(wire.NetAddressV2FromLegacy(addr))
 	}
 
 	numAddrs := n.NumAddresses()
@@ -331,8 +331,8 @@ func TestGetAddress(t *testing.T) {
 	if ka == nil {
 		t.Fatalf("Did not get an address where there is one in the pool")
 	}
-	if ka.NetAddress().IP.String() != someIP {
-		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().IP.String(), someIP)
+	if ka.NetAddress().Host() != someIP {
+		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().Host(), someIP)
 	}
 
 	// Mark this as a good address and get it
@@ -341,8 +341,8 @@ func TestGetAddress(t *testing.T) {
 	if ka == nil {
 		t.Fatalf("Did not get an address where there is one in the pool")
 	}
-	if ka.NetAddress().IP.String() != someIP {
-		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().IP.String(), someIP)
+	if ka.NetAddress().Host() != someIP {
+		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().Host(), someIP)
 	}
 
 	numAddrs := n.NumAddresses()
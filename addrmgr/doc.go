@@ -34,5 +34,14 @@ provided by the caller about connected, known good, and attempted addresses to
 periodically purge peers which no longer appear to be good peers as well as
 bias the selection toward known good peers.  The general idea is to make a best
 effort at only providing usable addresses.
+
+# Address Relay Version 2
+
+Besides the legacy addresses of the addr message, the address manager stores the
+addresses received in addrv2 messages (BIP0155), which include networks whose
+addresses don't fit in an IPv6 address such as Tor v3 onion services, I2P, and
+CJDNS.  Those addresses are only served by AddressCacheV2 to be relayed to the
+peers supporting addrv2 messages, while AddressCache only returns the addresses
+which can be represented by legacy addresses.
 */
 package addrmgr
@@ -15,7 +15,7 @@ import (
 // to determine how viable an address is.
 type KnownAddress struct {
 	mtx         sync.RWMutex // na and lastattempt
-	na          *wire.NetAddress
+	na          *wire.NetAddressV2
 	srcAddr     *wire.NetAddress
 	attempts    int
 	lastattempt time.Time
@@ -24,9 +24,9 @@ type KnownAddress struct {
 	refs        int // reference count of new buckets
 }
 
-// NetAddress returns the underlying wire.NetAddress associated with the
+// NetAddress returns the underlying wire.NetAddressV2 associated with the
 // known address.
-func (ka *KnownAddress) NetAddress() *wire.NetAddress {
+func (ka *KnownAddress) NetAddress() *wire.NetAddressV2 {
 	ka.mtx.RLock()
 	defer ka.mtx.RUnlock()
 	return ka.na
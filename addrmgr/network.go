@@ -279,3 +279,54 @@ func GroupKey(na *wire.NetAddress) string {
 
 	return na.IP.Mask(net.CIDRMask(bits, 128)).String()
 }
+
+// hasLegacyNetwork returns whether or not the passed address is of a network
+// which can be represented by legacy addresses.
+func hasLegacyNetwork(na *wire.NetAddressV2) bool {
+	switch na.Network {
+	case wire.NetworkIPv4, wire.NetworkIPv6, wire.NetworkTorV2:
+		return true
+	}
+	return false
+}
+
+// IsRoutableV2 returns whether or not the passed address is routable over its
+// network.  The addresses which can be represented by legacy addresses follow
+// the same rules as IsRoutable, while Tor v3 and I2P addresses are always
+// routable and CJDNS addresses must be within the fc00::/8 range.  Addresses
+// of unknown networks are never routable.
+func IsRoutableV2(na *wire.NetAddressV2) bool {
+	switch na.Network {
+	case wire.NetworkIPv4, wire.NetworkIPv6, wire.NetworkTorV2:
+		return IsRoutable(na.ToLegacy())
+
+	case wire.NetworkTorV3, wire.NetworkI2P:
+		return true
+
+	case wire.NetworkCJDNS:
+		return na.Addr[0] == 0xfc
+	}
+
+	return false
+}
+
+// GroupKeyV2 returns a string representing the network group an address is
+// part of.  It is the same as GroupKey for the addresses which can be
+// represented by legacy addresses.  Tor v3, I2P, and CJDNS addresses are
+// grouped by network and the first 4 bits of their random part, and the
+// addresses of unknown networks are unroutable.
+func GroupKeyV2(na *wire.NetAddressV2) string {
+	if hasLegacyNetwork(na) {
+		return GroupKey(na.ToLegacy())
+	}
+	if !IsRoutableV2(na) {
+		return "unroutable"
+	}
+
+	// The first byte of CJDNS addresses is the constant 0xfc.
+	random := na.Addr
+	if na.Network == wire.NetworkCJDNS {
+		random = random[1:]
+	}
+	return fmt.Sprintf("%v:%d", na.Network, random[0]>>4)
+}
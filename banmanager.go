@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbcd/database"
+)
+
+// banListBucketName is the name of the database bucket used to persist banned
+// addresses and subnets across restarts.  Each entry is keyed by the subnet in
+// CIDR notation and holds the ban creation and expiration times.
+var banListBucketName = []byte("banlist")
+
+// bannedPeriod is the time period during which an address or subnet is banned.
+type bannedPeriod struct {
+	since time.Time
+	until time.Time
+}
+
+// banManager tracks banned addresses and subnets and persists them to the
+// database so bans survive restarts.  Single addresses are stored as subnets
+// with a full mask.
+type banManager struct {
+	db database.DB
+
+	mtx  sync.Mutex
+	bans map[string]*bannedSubnet
+}
+
+// bannedSubnet pairs a banned subnet with its ban period.
+type bannedSubnet struct {
+	subnet *net.IPNet
+	period bannedPeriod
+}
+
+// newBanManager returns a ban manager populated with the unexpired bans stored
+// in the provided database.  Expired bans are removed from the database.
+func newBanManager(db database.DB) (*banManager, error) {
+	bm := &banManager{
+		db:   db,
+		bans: make(map[string]*bannedSubnet),
+	}
+
+	now := time.Now()
+	var expired [][]byte
+	err := db.Update(func(dbTx database.Tx) error {
+		bucket, err := dbTx.Metadata().CreateBucketIfNotExists(
+			banListBucketName)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.ForEach(func(k, v []byte) error {
+			_, subnet, err := net.ParseCIDR(string(k))
+			if err != nil || len(v) != 16 {
+				srvrLog.Warnf("Ignoring malformed ban entry %q", k)
+				expired = append(expired, k)
+				return nil
+			}
+
+			period := bannedPeriod{
+				since: time.Unix(int64(binary.LittleEndian.Uint64(v[0:8])), 0),
+				until: time.Unix(int64(binary.LittleEndian.Uint64(v[8:16])), 0),
+			}
+			if !now.Before(period.until) {
+				expired = append(expired, k)
+				return nil
+			}
+			bm.bans[subnet.String()] = &bannedSubnet{
+				subnet: subnet,
+				period: period,
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bm.bans) > 0 {
+		srvrLog.Debugf("Loaded %d banned addresses and subnets", len(bm.bans))
+	}
+	return bm, nil
+}
+
+// parseBanSubnet parses the provided address, which may either be a single IP
+// address or a subnet in CIDR notation, into a subnet.  Single addresses are
+// returned as a subnet with a full mask.
+func parseBanSubnet(addr string) (*net.IPNet, error) {
+	if ip := net.ParseIP(addr); ip != nil {
+		return singleHostSubnet(ip), nil
+	}
+
+	_, subnet, err := net.ParseCIDR(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address or subnet %q", addr)
+	}
+	return subnet, nil
+}
+
+// singleHostSubnet returns a subnet that contains only the provided IP.
+func singleHostSubnet(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip.To16(), Mask: net.CIDRMask(128, 128)}
+}
+
+// banKey returns the key used to identify the provided subnet.  Single
+// addresses are identified by the bare address.
+func banKey(subnet *net.IPNet) string {
+	if ones, bits := subnet.Mask.Size(); ones == bits {
+		return subnet.IP.String()
+	}
+	return subnet.String()
+}
+
+// Ban bans the provided subnet for the given period, replacing any existing
+// ban of the same subnet.
+//
+// This function is safe for concurrent access.
+func (bm *banManager) Ban(subnet *net.IPNet, since, until time.Time) error {
+	var v [16]byte
+	binary.LittleEndian.PutUint64(v[0:8], uint64(since.Unix()))
+	binary.LittleEndian.PutUint64(v[8:16], uint64(until.Unix()))
+
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	err := bm.db.Update(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(banListBucketName)
+		return bucket.Put([]byte(subnet.String()), v[:])
+	})
+	if err != nil {
+		return err
+	}
+
+	bm.bans[subnet.String()] = &bannedSubnet{
+		subnet: subnet,
+		period: bannedPeriod{since: since, until: until},
+	}
+	return nil
+}
+
+// Unban removes the ban of the provided subnet.  An error is returned if the
+// subnet is not banned.
+//
+// This function is safe for concurrent access.
+func (bm *banManager) Unban(subnet *net.IPNet) error {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	key := subnet.String()
+	if _, ok := bm.bans[key]; !ok {
+		return fmt.Errorf("%s is not banned", banKey(subnet))
+	}
+
+	err := bm.db.Update(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(banListBucketName)
+		return bucket.Delete([]byte(key))
+	})
+	if err != nil {
+		return err
+	}
+
+	delete(bm.bans, key)
+	return nil
+}
+
+// Clear removes all bans.
+//
+// This function is safe for concurrent access.
+func (bm *banManager) Clear() error {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	err := bm.db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		if err := meta.DeleteBucket(banListBucketName); err != nil {
+			return err
+		}
+		_, err := meta.CreateBucket(banListBucketName)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	bm.bans = make(map[string]*bannedSubnet)
+	return nil
+}
+
+// IsBanned returns the ban period of the ban covering the provided IP and
+// whether or not such an unexpired ban exists.  When several bans cover the
+// address, the one lasting the longest is returned.
+//
+// This function is safe for concurrent access.
+func (bm *banManager) IsBanned(ip net.IP) (bannedPeriod, bool) {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	now := time.Now()
+	var period bannedPeriod
+	var banned bool
+	for _, ban := range bm.bans {
+		if !now.Before(ban.period.until) || !ban.subnet.Contains(ip) {
+			continue
+		}
+		if !banned || ban.period.until.After(period.until) {
+			period = ban.period
+			banned = true
+		}
+	}
+	return period, banned
+}
+
+// IsAddrBanned returns whether or not the host of the provided network address
+// is banned.  Addresses with a host that is not an IP address, such as onion
+// addresses, are never considered banned.
+//
+// This function is safe for concurrent access.
+func (bm *banManager) IsAddrBanned(addr net.Addr) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	_, banned := bm.IsBanned(ip)
+	return banned
+}
+
+// Banned returns the unexpired bans keyed by the banned address or subnet.
+//
+// This function is safe for concurrent access.
+func (bm *banManager) Banned() map[string]bannedPeriod {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	now := time.Now()
+	banned := make(map[string]bannedPeriod, len(bm.bans))
+	for _, ban := range bm.bans {
+		if now.Before(ban.period.until) {
+			banned[banKey(ban.subnet)] = ban.period
+		}
+	}
+	return banned
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/database"
+	_ "github.com/lbryio/lbcd/database/ffldb"
+	"github.com/lbryio/lbcd/wire"
+)
+
+// TestBanManager ensures bans of addresses and subnets are matched, removed
+// and persisted as expected.
+func TestBanManager(t *testing.T) {
+	db, err := database.Create("ffldb", t.TempDir(), wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer db.Close()
+
+	bm, err := newBanManager(db)
+	if err != nil {
+		t.Fatalf("newBanManager: %v", err)
+	}
+
+	since := time.Now()
+	until := since.Add(time.Hour)
+	for _, addr := range []string{"10.0.0.1", "192.168.0.0/16", "2001:db8::/32"} {
+		subnet, err := parseBanSubnet(addr)
+		if err != nil {
+			t.Fatalf("parseBanSubnet(%q): %v", addr, err)
+		}
+		if err := bm.Ban(subnet, since, until); err != nil {
+			t.Fatalf("Ban(%q): %v", addr, err)
+		}
+	}
+
+	// An expired ban is persisted but never reported.
+	expired, _ := parseBanSubnet("10.0.0.2")
+	if err := bm.Ban(expired, since.Add(-time.Hour), since); err != nil {
+		t.Fatalf("Ban: %v", err)
+	}
+
+	tests := []struct {
+		ip     string
+		banned bool
+	}{
+		{"10.0.0.1", true},
+		{"10.0.0.2", false},
+		{"10.0.0.3", false},
+		{"192.168.12.34", true},
+		{"192.169.0.1", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+	}
+	check := func(bm *banManager) {
+		t.Helper()
+		for _, test := range tests {
+			_, banned := bm.IsBanned(net.ParseIP(test.ip))
+			if banned != test.banned {
+				t.Errorf("IsBanned(%s): got %v, want %v", test.ip,
+					banned, test.banned)
+			}
+		}
+	}
+	check(bm)
+
+	banned := bm.Banned()
+	if len(banned) != 3 {
+		t.Fatalf("Banned: got %d entries, want 3", len(banned))
+	}
+	if _, ok := banned["10.0.0.1"]; !ok {
+		t.Fatalf("Banned: single address not reported by address")
+	}
+	if _, ok := banned["192.168.0.0/16"]; !ok {
+		t.Fatalf("Banned: subnet not reported in CIDR notation")
+	}
+
+	// Reloading from the database restores the bans.
+	bm, err = newBanManager(db)
+	if err != nil {
+		t.Fatalf("newBanManager: %v", err)
+	}
+	check(bm)
+
+	subnet, _ := parseBanSubnet("192.168.0.0/16")
+	if err := bm.Unban(subnet); err != nil {
+		t.Fatalf("Unban: %v", err)
+	}
+	if err := bm.Unban(subnet); err == nil {
+		t.Fatalf("Unban: expected error removing missing ban")
+	}
+	if _, banned := bm.IsBanned(net.ParseIP("192.168.12.34")); banned {
+		t.Fatalf("IsBanned: address still banned after Unban")
+	}
+
+	if err := bm.Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	bm, err = newBanManager(db)
+	if err != nil {
+		t.Fatalf("newBanManager: %v", err)
+	}
+	if n := len(bm.Banned()); n != 0 {
+		t.Fatalf("Banned: got %d entries after Clear, want 0", n)
+	}
+}
@@ -100,6 +100,11 @@ type blockNode struct {
 	// only be accessed using the concurrent-safe NodeStatus method on
 	// blockIndex once the node has been added to the global index.
 	status blockStatus
+
+	// headerRecord is the position of the record of the block in the
+	// header file plus one, or zero when it's not stored there.  It's only
+	// accessed with the block index lock held.
+	headerRecord uint32
 }
 
 // initBlockNode initializes a block node from the given header and parent node,
@@ -232,6 +237,10 @@ type blockIndex struct {
 	db          database.DB
 	chainParams *chaincfg.Params
 
+	// headers is the file the block nodes are stored in instead of the
+	// block index bucket of the database when it's not nil.
+	headers *headerFile
+
 	sync.RWMutex
 	index map[chainhash.Hash]*blockNode
 	dirty map[*blockNode]struct{}
@@ -322,8 +331,106 @@ func (bi *blockIndex) UnsetStatusFlags(node *blockNode, flags blockStatus) {
 	bi.Unlock()
 }
 
-// flushToDB writes all dirty block nodes to the database. If all writes
-// succeed, this clears the dirty set.
+// Descendants returns all block nodes in the index that have the provided node
+// as an ancestor.  The provided node itself is not included and the returned
+// nodes are in no particular order.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) Descendants(node *blockNode) []*blockNode {
+	// Whether each node walked so far descends from the provided node is
+	// remembered so the index is iterated once without walking the shared
+	// ancestry of the nodes back to the provided node again.
+	descends := make(map[*blockNode]bool)
+	var descendants []*blockNode
+	var walked []*blockNode
+	bi.RLock()
+	for _, n := range bi.index {
+		walked = walked[:0]
+		result := false
+		for p := n; ; p = p.parent {
+			if p == nil || p.height <= node.height {
+				result = p == node
+				break
+			}
+			if known, ok := descends[p]; ok {
+				result = known
+				break
+			}
+			walked = append(walked, p)
+		}
+		for _, p := range walked {
+			descends[p] = result
+		}
+		if result && n != node {
+			descendants = append(descendants, n)
+		}
+	}
+	bi.RUnlock()
+	return descendants
+}
+
+// WorkCandidates returns all block nodes in the index that have their block
+// data stored, are not known to be invalid, and have more cumulative work than
+// the provided amount.  The returned nodes are in no particular order.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) WorkCandidates(minWork *big.Int) []*blockNode {
+	var candidates []*blockNode
+	bi.RLock()
+	for _, n := range bi.index {
+		if n.status.HaveData() && !n.status.KnownInvalid() &&
+			n.workSum.Cmp(minWork) > 0 {
+
+			candidates = append(candidates, n)
+		}
+	}
+	bi.RUnlock()
+	return candidates
+}
+
+// SideChainNodes returns all block nodes in the index that are not part of the
+// passed chain view.  The returned nodes are in no particular order.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) SideChainNodes(view *chainView) []*blockNode {
+	var nodes []*blockNode
+	bi.RLock()
+	for _, n := range bi.index {
+		if !view.Contains(n) {
+			nodes = append(nodes, n)
+		}
+	}
+	bi.RUnlock()
+	return nodes
+}
+
+// RemoveNodes removes the passed block nodes from the index.  Their pending
+// changes are discarded, so the caller is responsible for removing them from
+// the database if desired.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) RemoveNodes(nodes []*blockNode) {
+	bi.Lock()
+	for _, node := range nodes {
+		delete(bi.index, node.hash)
+		delete(bi.dirty, node)
+	}
+	bi.Unlock()
+}
+
+// Len returns the number of block nodes in the index.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) Len() int {
+	bi.RLock()
+	n := len(bi.index)
+	bi.RUnlock()
+	return n
+}
+
+// flushToDB writes all dirty block nodes to the database, or to the header file
+// when the index is stored there. If all writes succeed, this clears the dirty
+// set.
 func (bi *blockIndex) flushToDB() error {
 	bi.Lock()
 	if len(bi.dirty) == 0 {
@@ -331,15 +438,20 @@ func (bi *blockIndex) flushToDB() error {
 		return nil
 	}
 
-	err := bi.db.Update(func(dbTx database.Tx) error {
-		for node := range bi.dirty {
-			err := dbStoreBlockNode(dbTx, node)
-			if err != nil {
-				return err
+	var err error
+	if bi.headers != nil {
+		err = bi.flushToHeaderFile()
+	} else {
+		err = bi.db.Update(func(dbTx database.Tx) error {
+			for node := range bi.dirty {
+				err := dbStoreBlockNode(dbTx, node)
+				if err != nil {
+					return err
+				}
 			}
-		}
-		return nil
-	})
+			return nil
+		})
+	}
 
 	// If write was successful, clear the dirty set.
 	if err == nil {
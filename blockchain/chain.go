@@ -9,6 +9,7 @@ import (
 	"container/list"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
@@ -20,12 +21,13 @@ import (
 	btcutil "github.com/lbryio/lbcutil"
 
 	"github.com/lbryio/lbcd/claimtrie"
+	claimtrieconfig "github.com/lbryio/lbcd/claimtrie/config"
 )
 
 const (
-	// maxOrphanBlocks is the maximum number of orphan blocks that can be
-	// queued.
-	maxOrphanBlocks = 100
+	// DefaultMaxOrphanBlocks is the default maximum number of orphan blocks
+	// that can be queued.
+	DefaultMaxOrphanBlocks = 100
 )
 
 // BlockLocator is used to help locate a specific block.  The algorithm for
@@ -99,12 +101,15 @@ type BlockChain struct {
 	// separate mutex.
 	checkpoints         []chaincfg.Checkpoint
 	checkpointsByHeight map[int32]*chaincfg.Checkpoint
+	assumeUtxo          []chaincfg.AssumeUtxo
 	db                  database.DB
 	chainParams         *chaincfg.Params
 	timeSource          MedianTimeSource
-	sigCache            *txscript.SigCache
+	sigCache            txscript.SignatureCache
 	indexManager        IndexManager
 	hashCache           *txscript.HashCache
+	valScheduler        *ValidationScheduler
+	maxOrphanBlocks     int
 
 	// The following fields are calculated based upon the provided chain
 	// parameters.  They are also set when the instance is created and
@@ -191,6 +196,51 @@ type BlockChain struct {
 	notificationsLock sync.RWMutex
 	notifications     []NotificationCallback
 
+	// pruneTarget is the target size in bytes of the block files to retain
+	// and pruneHeight is the height of the lowest main chain block whose
+	// data has not been pruned.  pruneRetryHeight is the height of the
+	// main chain before which pruning isn't attempted again after it was
+	// skipped to retain the most recent blocks.  The prune heights are
+	// protected by the chain lock.
+	pruneTarget      uint64
+	pruneHeight      int32
+	pruneRetryHeight int32
+
+	// pruneForkDepth is the depth below the tip of the main chain beyond
+	// which stale forks are pruned from the block index, and pruneForkDB
+	// whether their entries are deleted from the database as well.  The
+	// remaining fields track the pruning and are protected by the chain
+	// lock.
+	pruneForkDepth      int32
+	pruneForkDB         bool
+	retainedForkNodes   int
+	prunedForkNodes     uint64
+	lastForkPruneHeight int32
+
+	// utxoSnapshotHeight is the height of the block of the utxo snapshot
+	// the chain state was loaded from, or zero when it wasn't loaded from
+	// one.  The main chain can't be reorganized at or below it.  It is
+	// protected by the chain lock.
+	utxoSnapshotHeight int32
+
+	// snapshotValidation houses the state of the validation of the blocks
+	// below the utxo snapshot the chain state was loaded from, if any, and
+	// snapshotClaimTrieCfg the configuration of the claim trie their claim
+	// scripts are processed with.  The validation is protected by the chain
+	// lock.
+	snapshotValidation   *snapshotValidation
+	snapshotClaimTrieCfg *claimtrieconfig.Config
+
+	// utxoStats houses the statistics of the utxo set once they have been
+	// requested so they are maintained incrementally afterwards.  It is
+	// protected by the utxo stats lock.
+	utxoStatsLock sync.Mutex
+	utxoStats     *utxoStats
+
+	// claimTrieImportLock serializes the imports of claim trie snapshots
+	// since they stage the imported claim trie in the same place.
+	claimTrieImportLock sync.Mutex
+
 	claimTrie *claimtrie.ClaimTrie
 }
 
@@ -304,7 +354,10 @@ func (b *BlockChain) removeOrphanBlock(orphan *orphanBlock) {
 // blocks and will remove the oldest received orphan block if the limit is
 // exceeded.
 func (b *BlockChain) addOrphanBlock(block *btcutil.Block) {
-	// Remove expired orphan blocks.
+	// Remove expired orphan blocks.  The oldest orphan block is found
+	// again since the previous one may have been connected or removed in
+	// the meantime.
+	b.oldestOrphan = nil
 	for _, oBlock := range b.orphans {
 		if time.Now().After(oBlock.expiration) {
 			b.removeOrphanBlock(oBlock)
@@ -319,7 +372,7 @@ func (b *BlockChain) addOrphanBlock(block *btcutil.Block) {
 	}
 
 	// Limit orphan blocks to prevent memory exhaustion.
-	if len(b.orphans)+1 > maxOrphanBlocks {
+	if len(b.orphans)+1 > b.maxOrphanBlocks && b.oldestOrphan != nil {
 		// Remove the oldest orphan to make room for the new one.
 		b.removeOrphanBlock(b.oldestOrphan)
 		b.oldestOrphan = nil
@@ -676,6 +729,9 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 	// now that the modifications have been committed to the database.
 	view.commit()
 
+	// Update the utxo set statistics with the block.
+	b.updateUtxoStats(block, stxos, true)
+
 	// This node is now the end of the best chain.
 	b.bestChain.SetTip(node)
 
@@ -688,6 +744,18 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 	b.stateSnapshot = state
 	b.stateLock.Unlock()
 
+	// Delete the oldest block files when they exceed the prune target.
+	if b.pruneTarget != 0 {
+		if err := b.pruneBlockFiles(); err != nil {
+			return err
+		}
+	}
+
+	// Prune the stale forks from the block index now and then.
+	if err := b.maybePruneStaleForks(); err != nil {
+		return err
+	}
+
 	// Notify the caller that the block was connected to the main chain.
 	// The caller would typically want to react with actions such as
 	// updating wallets.
@@ -741,6 +809,7 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view
 	state := newBestState(prevNode, blockSize, blockWeight, numTxns,
 		newTotalTxns, prevNode.CalcPastMedianTime())
 
+	var stxos []SpentTxOut
 	err = b.db.Update(func(dbTx database.Tx) error {
 		// Update best block state.
 		err := dbPutBestState(dbTx, state, node.workSum)
@@ -765,7 +834,7 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view
 
 		// Before we delete the spend journal entry for this back,
 		// we'll fetch it as is so the indexers can utilize if needed.
-		stxos, err := dbFetchSpendJournalEntry(dbTx, block)
+		stxos, err = dbFetchSpendJournalEntry(dbTx, block)
 		if err != nil {
 			return err
 		}
@@ -803,6 +872,9 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view
 	// now that the modifications have been committed to the database.
 	view.commit()
 
+	// Update the utxo set statistics with the block.
+	b.updateUtxoStats(block, stxos, false)
+
 	// This node's parent is now the end of the best chain.
 	b.bestChain.SetTip(node.parent)
 
@@ -1003,7 +1075,8 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 		// In the case the block is determined to be invalid due to a
 		// rule violation, mark it as invalid and mark all of its
 		// descendants as having an invalid ancestor.
-		err = b.checkConnectBlock(n, block, view, nil)
+		err = b.checkConnectBlock(n, block, view, nil, BFNone,
+			ValidationPriorityHigh)
 		if err != nil {
 			if _, ok := err.(RuleError); ok {
 				b.index.UnsetStatusFlags(n, statusValid)
@@ -1012,6 +1085,10 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 					dn := de.Value.(*blockNode)
 					b.index.SetStatusFlags(dn, statusInvalidAncestor)
 				}
+				b.sendNotification(NTBlockInvalid, &InvalidBlock{
+					Block: block,
+					Err:   err,
+				})
 			}
 			return err
 		}
@@ -1140,12 +1217,17 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *btcutil.Block, fla
 		view.SetBestHash(parentHash)
 		stxos := make([]SpentTxOut, 0, countSpentOutputs(block))
 		if !fastAdd {
-			err := b.checkConnectBlock(node, block, view, &stxos)
+			err := b.checkConnectBlock(node, block, view, &stxos,
+				flags, ValidationPriorityHigh)
 			if err == nil {
 				b.index.SetStatusFlags(node, statusValid)
 			} else if _, ok := err.(RuleError); ok {
 				b.index.UnsetStatusFlags(node, statusValid)
 				b.index.SetStatusFlags(node, statusValidateFailed)
+				b.sendNotification(NTBlockInvalid, &InvalidBlock{
+					Block: block,
+					Err:   err,
+				})
 			} else {
 				return false, err
 			}
@@ -1183,6 +1265,10 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *btcutil.Block, fla
 				b.index.SetStatusFlags(
 					node, statusValidateFailed,
 				)
+				b.sendNotification(NTBlockInvalid, &InvalidBlock{
+					Block: block,
+					Err:   err,
+				})
 			}
 
 			flushIndexState()
@@ -1708,7 +1794,10 @@ func (b *BlockChain) LocateHeaders(locator BlockLocator, hashStop *chainhash.Has
 	return headers
 }
 
-// InvalidateBlock takes a block hash and invalidates it.
+// InvalidateBlock takes a block hash and invalidates it.  When the block is
+// part of the main chain, the chain and the claimtrie are rolled back to the
+// parent of the block and the best remaining valid chain, if any, is
+// activated.
 //
 // This function is safe for concurrent access.
 func (b *BlockChain) InvalidateBlock(hash *chainhash.Hash) error {
@@ -1718,6 +1807,8 @@ func (b *BlockChain) InvalidateBlock(hash *chainhash.Hash) error {
 }
 
 // invalidateBlock takes a block hash and invalidates it.
+//
+// This function MUST be called with the chain state lock held (for writes).
 func (b *BlockChain) invalidateBlock(hash *chainhash.Hash) error {
 	node := b.index.LookupNode(hash)
 	if node == nil {
@@ -1738,19 +1829,25 @@ func (b *BlockChain) invalidateBlock(hash *chainhash.Hash) error {
 
 	b.index.SetStatusFlags(node, statusValidateFailed)
 	b.index.UnsetStatusFlags(node, statusValid)
-
-	detachNodes, attachNodes := b.getReorganizeNodes(node.parent)
-
-	err := b.reorganizeChain(detachNodes, attachNodes)
-	if err != nil {
-		return err
+	for _, n := range b.index.Descendants(node) {
+		b.index.SetStatusFlags(n, statusInvalidAncestor)
+		b.index.UnsetStatusFlags(n, statusValid)
 	}
 
-	for i, e := 0, detachNodes.Front(); e != nil; i, e = i+1, e.Next() {
-		n := e.Value.(*blockNode)
+	// Roll the main chain back to the fork point when the block is part of
+	// it.  Disconnecting the blocks also resets the claimtrie to the height
+	// of the fork point.  Blocks on side chains only need their status
+	// updated.
+	if b.bestChain.Contains(node) {
+		detachNodes, attachNodes := b.getReorganizeNodes(node.parent)
+		err := b.reorganizeChain(detachNodes, attachNodes)
+		if err != nil {
+			return err
+		}
 
-		b.index.SetStatusFlags(n, statusInvalidAncestor)
-		b.index.UnsetStatusFlags(n, statusValid)
+		if err := b.activateBestChain(); err != nil {
+			return err
+		}
 	}
 
 	if writeErr := b.index.flushToDB(); writeErr != nil {
@@ -1760,14 +1857,20 @@ func (b *BlockChain) invalidateBlock(hash *chainhash.Hash) error {
 	return nil
 }
 
-// ReconsiderBlock takes a block hash and allows it to be revalidated.
+// ReconsiderBlock takes a block hash and allows it to be revalidated.  The
+// invalid status is removed from the block, its invalid ancestors, and its
+// descendants, and the chain is reorganized to the resulting best valid chain.
 //
 // This function is safe for concurrent access.
 func (b *BlockChain) ReconsiderBlock(hash *chainhash.Hash) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
 	return b.reconsiderBlock(hash)
 }
 
 // reconsiderBlock takes a block hash and allows it to be revalidated.
+//
+// This function MUST be called with the chain state lock held (for writes).
 func (b *BlockChain) reconsiderBlock(hash *chainhash.Hash) error {
 	node := b.index.LookupNode(hash)
 	if node == nil {
@@ -1781,48 +1884,107 @@ func (b *BlockChain) reconsiderBlock(hash *chainhash.Hash) error {
 		return err
 	}
 
-	// Keep a reference to the first node in the chain of invalid
-	// blocks so we can reprocess after status flags are updated.
-	firstNode := node
+	// Clear the invalid status back to the point where the blocks are valid
+	// again as well as from every block built on top of the block.
+	for n := node; n != nil && n.status.KnownInvalid(); n = n.parent {
+		b.index.UnsetStatusFlags(n, statusInvalidAncestor|statusValidateFailed)
+	}
+	for _, n := range b.index.Descendants(node) {
+		b.index.UnsetStatusFlags(n, statusInvalidAncestor|statusValidateFailed)
+	}
 
-	// Find previous node to the point where the blocks are valid again.
-	for n := node; n.status.KnownInvalid(); n = n.parent {
-		b.index.UnsetStatusFlags(n, statusInvalidAncestor)
-		b.index.UnsetStatusFlags(n, statusValidateFailed)
+	err := b.activateBestChain()
 
-		firstNode = n
+	if writeErr := b.index.flushToDB(); writeErr != nil {
+		log.Warnf("Error flushing block index changes to disk: %v", writeErr)
 	}
 
-	// do we need an rlock on chainstate for this section?
-	var blk *btcutil.Block
-	err := b.db.View(func(dbTx database.Tx) error {
-		var err error
-		blk, err = dbFetchBlockByNode(dbTx, firstNode)
-		return err
+	return err
+}
+
+// activateBestChain reorganizes the chain to the block with the most
+// cumulative work that is not known to be invalid and whose block data, along
+// with that of all of its ancestors back to the main chain, is available.
+// Candidates that fail validation during the reorganize are marked invalid by
+// reorganizeChain and the next best candidate is tried.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) activateBestChain() error {
+	candidates := b.index.WorkCandidates(b.bestChain.Tip().workSum)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].workSum.Cmp(candidates[j].workSum) > 0
 	})
-	if err != nil {
-		return err
-	}
 
-	// Process it all again. This will take care of the
-	// orphans as well.
-	_, _, err = b.ProcessBlock(blk, BFNoDupBlockCheck)
-	if err != nil {
-		return err
-	}
+	for _, candidate := range candidates {
+		if b.index.NodeStatus(candidate).KnownInvalid() ||
+			candidate.workSum.Cmp(b.bestChain.Tip().workSum) <= 0 {
 
-	if writeErr := b.index.flushToDB(); writeErr != nil {
-		log.Warnf("Error flushing block index changes to disk: %v", writeErr)
+			continue
+		}
+
+		// Every block that would be attached must have its data.
+		forkNode := b.bestChain.FindFork(candidate)
+		haveData := true
+		for n := candidate; n != nil && n != forkNode; n = n.parent {
+			if !b.index.NodeStatus(n).HaveData() {
+				haveData = false
+				break
+			}
+		}
+		if !haveData {
+			continue
+		}
+
+		detachNodes, attachNodes := b.getReorganizeNodes(candidate)
+		if attachNodes.Len() == 0 {
+			continue
+		}
+		err := b.reorganizeChain(detachNodes, attachNodes)
+		if err != nil {
+			if _, ok := err.(RuleError); ok {
+				log.Warnf("Unable to activate chain ending at block %v: %v",
+					candidate.hash, err)
+				continue
+			}
+			return err
+		}
 	}
 
 	return nil
 }
 
+// ValidationScheduler returns the scheduler used to validate the scripts of
+// blocks.  It is nil when the chain was created without one.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ValidationScheduler() *ValidationScheduler {
+	return b.valScheduler
+}
+
 // ClaimTrie returns the claimTrie associated wit hthe chain.
 func (b *BlockChain) ClaimTrie() *claimtrie.ClaimTrie {
 	return b.claimTrie
 }
 
+// Close writes the state the chain keeps in memory to the database and
+// releases the resources it holds, such as the header file, besides the
+// database and the claim trie passed to New.  The chain must not be used
+// afterwards.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) Close() error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	err := b.stopSnapshotValidation()
+	if b.index.headers != nil {
+		if closeErr := b.index.headers.close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
 // IndexManager provides a generic interface that the is called when blocks are
 // connected and disconnected to and from the tip of the main chain for the
 // purpose of supporting optional indexes.
@@ -1891,7 +2053,7 @@ type Config struct {
 	//
 	// This field can be nil if the caller is not interested in using a
 	// signature cache.
-	SigCache *txscript.SigCache
+	SigCache txscript.SignatureCache
 
 	// IndexManager defines an index manager to use when initializing the
 	// chain and connecting and disconnecting blocks.
@@ -1910,7 +2072,63 @@ type Config struct {
 	// signature cache.
 	HashCache *txscript.HashCache
 
+	// ValidationScheduler defines the scheduler used to validate the
+	// scripts of blocks.  It allows the number of script validation workers
+	// to be tuned at runtime.
+	//
+	// This field can be nil in which case the scripts of each block are
+	// validated by goroutines started for it.  The caller is responsible
+	// for stopping the scheduler it provides once the chain is no longer
+	// used.
+	ValidationScheduler *ValidationScheduler
+
+	// Prune specifies the target size in bytes of the block files to
+	// retain.  The oldest block files are deleted once their total size
+	// exceeds the target.  A value of zero disables pruning.
+	Prune uint64
+
+	// PruneForkDepth specifies the depth below the tip of the main chain
+	// beyond which the stale forks branching off it are pruned from the
+	// block index.  Forks with at least as much work as the main chain
+	// are never pruned.  A value of zero disables pruning, and it must be
+	// at least MinPruneForkDepth otherwise.
+	PruneForkDepth int32
+
+	// PruneForkDB specifies whether the pruned stale forks are deleted
+	// from the block index stored in the database as well, so they are
+	// not loaded again on the next start.
+	PruneForkDB bool
+
+	// HeaderFile specifies the path of the flat file the block headers and
+	// their validation status are stored in instead of the database.  The
+	// block index stored in the database is moved to it on the first
+	// start.  When empty, the block index is stored in the database.
+	HeaderFile string
+
+	// AssumeUtxo hold caller-defined assumed utxo sets that utxo snapshots
+	// can be loaded for in addition to the ones in ChainParams.
+	//
+	// This field can be nil if the caller does not wish to specify any
+	// assumed utxo sets.
+	AssumeUtxo []chaincfg.AssumeUtxo
+
+	// MaxOrphanBlocks is the maximum number of blocks whose parent is
+	// unknown held until the parent is processed.  The oldest orphan block
+	// is removed when the limit is reached.
+	//
+	// This field can be zero in which case DefaultMaxOrphanBlocks is used.
+	MaxOrphanBlocks int
+
 	ClaimTrie *claimtrie.ClaimTrie
+
+	// SnapshotClaimTrie is the configuration of the claim trie the claim
+	// scripts of the blocks below a utxo snapshot the chain state is loaded
+	// from are processed with while they are validated in the background.
+	// Its data directory must differ from the one of ClaimTrie.
+	//
+	// This field can be nil when the chain has no claim trie, otherwise a
+	// utxo snapshot can't be loaded without it.
+	SnapshotClaimTrie *claimtrieconfig.Config
 }
 
 // New returns a BlockChain instance using the provided configuration details.
@@ -1950,22 +2168,66 @@ func New(config *Config) (*BlockChain, error) {
 	b := BlockChain{
 		checkpoints:         config.Checkpoints,
 		checkpointsByHeight: checkpointsByHeight,
-		db:                  config.DB,
-		chainParams:         params,
-		timeSource:          config.TimeSource,
-		sigCache:            config.SigCache,
-		indexManager:        config.IndexManager,
-		minRetargetTimespan: targetTimespan - (targetTimespan / 8),
-		maxRetargetTimespan: targetTimespan + (targetTimespan / 2),
-		blocksPerRetarget:   int32(targetTimespan / targetTimePerBlock),
-		index:               newBlockIndex(config.DB, params),
-		hashCache:           config.HashCache,
-		bestChain:           newChainView(nil),
-		orphans:             make(map[chainhash.Hash]*orphanBlock),
-		prevOrphans:         make(map[chainhash.Hash][]*orphanBlock),
-		warningCaches:       newThresholdCaches(vbNumBits),
-		deploymentCaches:    newThresholdCaches(chaincfg.DefinedDeployments),
-		claimTrie:           config.ClaimTrie,
+		assumeUtxo: append(append([]chaincfg.AssumeUtxo(nil),
+			params.AssumeUtxo...), config.AssumeUtxo...),
+		db:                   config.DB,
+		chainParams:          params,
+		timeSource:           config.TimeSource,
+		sigCache:             config.SigCache,
+		indexManager:         config.IndexManager,
+		minRetargetTimespan:  targetTimespan - (targetTimespan / 8),
+		maxRetargetTimespan:  targetTimespan + (targetTimespan / 2),
+		blocksPerRetarget:    int32(targetTimespan / targetTimePerBlock),
+		index:                newBlockIndex(config.DB, params),
+		hashCache:            config.HashCache,
+		valScheduler:         config.ValidationScheduler,
+		maxOrphanBlocks:      config.MaxOrphanBlocks,
+		bestChain:            newChainView(nil),
+		orphans:              make(map[chainhash.Hash]*orphanBlock),
+		prevOrphans:          make(map[chainhash.Hash][]*orphanBlock),
+		warningCaches:        newThresholdCaches(vbNumBits),
+		deploymentCaches:     newThresholdCaches(chaincfg.DefinedDeployments),
+		pruneTarget:          config.Prune,
+		pruneForkDepth:       config.PruneForkDepth,
+		pruneForkDB:          config.PruneForkDB,
+		claimTrie:            config.ClaimTrie,
+		snapshotClaimTrieCfg: config.SnapshotClaimTrie,
+	}
+
+	if b.maxOrphanBlocks <= 0 {
+		b.maxOrphanBlocks = DefaultMaxOrphanBlocks
+	}
+	if b.pruneForkDepth != 0 && b.pruneForkDepth < MinPruneForkDepth {
+		return nil, AssertError("blockchain.New stale fork prune depth " +
+			"is below the minimum")
+	}
+
+	// Finish resetting the chain state when a reindex was interrupted
+	// while resetting it.
+	reindexState, prevTip, err := dbFetchReindexState(b.db)
+	if err != nil {
+		return nil, err
+	}
+	if reindexState == reindexStateReset ||
+		reindexState == reindexStateResetIndex {
+
+		log.Infof("Resuming the interrupted reset of the chain state")
+		err := resetChainState(b.db, params,
+			reindexState == reindexStateResetIndex, &prevTip,
+			config.Interrupt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Open the header file the block index is stored in, if any.
+	if config.HeaderFile != "" {
+		headers, err := openHeaderFile(b.db, config.HeaderFile)
+		if err != nil {
+			return nil, err
+		}
+		b.index.headers = headers
+		b.db.OnFlush(headers.sync)
 	}
 
 	// Initialize the chain state from the passed database.  When the db
@@ -1975,6 +2237,17 @@ func New(config *Config) (*BlockChain, error) {
 		return nil, err
 	}
 
+	// Load the pruning state now that the best chain is known.
+	if err := b.initPruneState(); err != nil {
+		return nil, err
+	}
+	if err := b.initUtxoSnapshotState(); err != nil {
+		return nil, err
+	}
+	if err := b.initSnapshotValidation(); err != nil {
+		return nil, err
+	}
+
 	// Helper function to insert the output in genesis block in to the
 	// transaction database.
 	fn := func(dbTx database.Tx) error {
@@ -2016,6 +2289,24 @@ func New(config *Config) (*BlockChain, error) {
 		}
 	}
 
+	// Connect the stored blocks when the chain state is being reindexed.
+	if err := b.connectStoredBlocks(config.Interrupt); err != nil {
+		if b.claimTrie != nil {
+			b.claimTrie.Close()
+		}
+		return nil, err
+	}
+
+	// Prune the stale forks loaded from the database.
+	if b.pruneForkDepth != 0 {
+		if err := b.pruneStaleForks(); err != nil {
+			if b.claimTrie != nil {
+				b.claimTrie.Close()
+			}
+			return nil, err
+		}
+	}
+
 	bestNode := b.bestChain.Tip()
 	log.Infof("Chain state (height %d, hash %v, totaltx %d, work %v)",
 		bestNode.height, bestNode.hash, b.stateSnapshot.TotalTxns,
@@ -2035,11 +2326,7 @@ func rebuildMissingClaimTrieData(b *BlockChain, done <-chan struct{}) error {
 
 	start := time.Now()
 	lastReport := time.Now()
-	// TODO: move this view inside the loop (or recreate it every 5 sec.)
-	// as accumulating all inputs has potential to use a huge amount of RAM
-	// but we need to get the spent inputs working for that to be possible
-	view := NewUtxoViewpoint()
-	for h := int32(0); h < target; h++ {
+	for h := b.claimTrie.Height(); h < target; h++ {
 		select {
 		case <-done:
 			return fmt.Errorf("rebuild unfinished at height %d", b.claimTrie.Height())
@@ -2047,32 +2334,8 @@ func rebuildMissingClaimTrieData(b *BlockChain, done <-chan struct{}) error {
 		}
 
 		n := b.bestChain.NodeByHeight(h + 1)
-
-		var block *btcutil.Block
-		err := b.db.View(func(dbTx database.Tx) error {
-			var err error
-			block, err = dbFetchBlockByNode(dbTx, n)
+		if err := b.connectClaimTrieBlock(b.claimTrie, n); err != nil {
 			return err
-		})
-		if err != nil {
-			return err
-		}
-
-		err = view.fetchInputUtxos(b.db, block)
-		if err != nil {
-			return err
-		}
-
-		err = view.connectTransactions(block, nil)
-		if err != nil {
-			return err
-		}
-
-		if h >= b.claimTrie.Height() {
-			err = b.ParseClaimScripts(block, n, view, false)
-			if err != nil {
-				return err
-			}
 		}
 		if time.Since(lastReport) > time.Second*5 {
 			lastReport = time.Now()
@@ -2083,3 +2346,36 @@ func rebuildMissingClaimTrieData(b *BlockChain, done <-chan struct{}) error {
 		b.claimTrie.Height(), time.Since(start))
 	return nil
 }
+
+// connectClaimTrieBlock processes the claim scripts of the block of the passed
+// main chain node on top of the passed claim trie, which must be at the height
+// of its parent.  The outputs spent by the block are loaded from the spend
+// journal rather than by replaying the chain from genesis, so only the block
+// bodies above the claim trie height are needed.
+func (b *BlockChain) connectClaimTrieBlock(ct *claimtrie.ClaimTrie, n *blockNode) error {
+	var block *btcutil.Block
+	var stxos []SpentTxOut
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		block, err = dbFetchBlockByNode(dbTx, n)
+		if err != nil {
+			return err
+		}
+		stxos, err = dbFetchSpendJournalEntry(dbTx, block)
+		return err
+	})
+	if isDbBlockNotFoundErr(err) && b.pruneTarget != 0 {
+		return fmt.Errorf("unable to rebuild claim trie data at "+
+			"height %d: the block has been pruned and the "+
+			"chain must be resynced", n.height)
+	}
+	if err != nil {
+		return err
+	}
+	if len(stxos) != countSpentOutputs(block) {
+		return AssertError(fmt.Sprintf("spend journal for block "+
+			"%v at height %d is inconsistent", n.hash, n.height))
+	}
+
+	return parseClaimScripts(ct, block, n, spentViewFromJournal(block, stxos), false)
+}
@@ -868,3 +868,103 @@ func TestIntervalBlockHashes(t *testing.T) {
 		}
 	}
 }
+
+// TestBlockIndexDescendants ensures the block index reports the expected
+// descendants and chain candidates for a tree of block nodes.
+func TestBlockIndexDescendants(t *testing.T) {
+	// Construct a synthetic block index consisting of the following
+	// structure.
+	// 	genesis -> 1 -> 2 -> 3 -> 4
+	// 	                \-> 3a -> 4a -> 5a
+	params := &chaincfg.RegressionNetParams
+	chain := newFakeChain(params)
+	bits := params.PowLimitBits
+	timestamp := time.Unix(params.GenesisBlock.Header.Timestamp.Unix(), 0)
+	newNodes := func(parent *blockNode, numNodes int) []*blockNode {
+		nodes := make([]*blockNode, 0, numNodes)
+		for i := 0; i < numNodes; i++ {
+			timestamp = timestamp.Add(time.Second)
+			node := newFakeNode(parent, 1, bits, timestamp)
+			node.status = statusDataStored
+			chain.index.AddNode(node)
+			nodes = append(nodes, node)
+			parent = node
+		}
+		return nodes
+	}
+	branch0Nodes := newNodes(chain.bestChain.Genesis(), 4)
+	branch1Nodes := newNodes(branch0Nodes[1], 3)
+	chain.bestChain.SetTip(branch0Nodes[3])
+
+	descendants := chain.index.Descendants(branch0Nodes[1])
+	if len(descendants) != 5 {
+		t.Fatalf("Descendants: got %d nodes, want 5", len(descendants))
+	}
+	for _, n := range descendants {
+		if n == branch0Nodes[0] || n == branch0Nodes[1] {
+			t.Fatalf("Descendants: unexpected node %v", n)
+		}
+	}
+	if n := len(chain.index.Descendants(branch1Nodes[2])); n != 0 {
+		t.Fatalf("Descendants: got %d nodes for tip, want 0", n)
+	}
+	descendants = chain.index.Descendants(branch1Nodes[0])
+	if len(descendants) != 2 {
+		t.Fatalf("Descendants: got %d nodes for side chain, want 2",
+			len(descendants))
+	}
+	for _, n := range descendants {
+		if n != branch1Nodes[1] && n != branch1Nodes[2] {
+			t.Fatalf("Descendants: unexpected side chain node %v", n)
+		}
+	}
+
+	// Only the side chain tip has more work than the main chain tip.
+	candidates := chain.index.WorkCandidates(branch0Nodes[3].workSum)
+	if len(candidates) != 1 || candidates[0] != branch1Nodes[2] {
+		t.Fatalf("WorkCandidates: got %v, want %v", candidates,
+			branch1Nodes[2])
+	}
+
+	// Invalid nodes are never candidates.
+	chain.index.SetStatusFlags(branch1Nodes[2], statusValidateFailed)
+	candidates = chain.index.WorkCandidates(branch0Nodes[3].workSum)
+	if len(candidates) != 0 {
+		t.Fatalf("WorkCandidates: got %v, want none", candidates)
+	}
+}
+
+// TestOrphanBlockLimit ensures the orphan pool holds at most the configured
+// number of blocks, removing the oldest ones first, even after orphans were
+// connected and removed from the pool.
+func TestOrphanBlockLimit(t *testing.T) {
+	b := &BlockChain{
+		orphans:         make(map[chainhash.Hash]*orphanBlock),
+		prevOrphans:     make(map[chainhash.Hash][]*orphanBlock),
+		maxOrphanBlocks: 2,
+	}
+	blocks := make([]*btcutil.Block, 4)
+	for i := range blocks {
+		blocks[i] = btcutil.NewBlock(&wire.MsgBlock{
+			Header: wire.BlockHeader{Nonce: uint32(i)},
+		})
+	}
+
+	b.addOrphanBlock(blocks[0])
+	b.addOrphanBlock(blocks[1])
+
+	// Remove the oldest orphan as if it was connected, which must not
+	// prevent the next oldest one from being removed.
+	b.removeOrphanBlock(b.orphans[*blocks[0].Hash()])
+	b.addOrphanBlock(blocks[2])
+	b.addOrphanBlock(blocks[3])
+
+	if len(b.orphans) != 2 {
+		t.Fatalf("orphan pool holds %d blocks, want 2", len(b.orphans))
+	}
+	for _, block := range blocks[2:] {
+		if !b.IsKnownOrphan(block.Hash()) {
+			t.Fatalf("orphan block %v was removed", block.Hash())
+		}
+	}
+}
@@ -32,6 +32,10 @@ const (
 	// journal bucket that is used to track all spent transactions for use
 	// in reorgs.
 	latestSpendJournalBucketVersion = 1
+
+	// utxoSetPrefetchBlocks is the number of database blocks read ahead by
+	// the cursors scanning the entire utxo set.
+	utxoSetPrefetchBlocks = 256
 )
 
 var (
@@ -111,6 +115,13 @@ func isDbBucketNotFoundErr(err error) bool {
 	return ok && dbErr.ErrorCode == database.ErrBucketNotFound
 }
 
+// isDbBlockNotFoundErr returns whether or not the passed error is a
+// database.Error with an error code of database.ErrBlockNotFound.
+func isDbBlockNotFoundErr(err error) bool {
+	dbErr, ok := err.(database.Error)
+	return ok && dbErr.ErrorCode == database.ErrBlockNotFound
+}
+
 // dbFetchVersion fetches an individual version with the given key from the
 // metadata bucket.  It is primarily used to track versions on entities such as
 // buckets.  It returns zero if the provided key does not exist.
@@ -771,7 +782,13 @@ func dbFetchUtxoEntry(dbTx database.Tx, outpoint wire.OutPoint) (*UtxoEntry, err
 // particular, only the entries that have been marked as modified are written
 // to the database.
 func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) error {
-	utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+	return dbPutUtxoViewBucket(dbTx.Metadata().Bucket(utxoSetBucketName),
+		view)
+}
+
+// dbPutUtxoViewBucket updates the utxo set housed in the passed bucket based on
+// the provided utxo view as described by dbPutUtxoView.
+func dbPutUtxoViewBucket(utxoBucket database.Bucket, view *UtxoViewpoint) error {
 	for outpoint, entry := range view.entries {
 		// No need to update the database if the entry was not modified.
 		if entry == nil || !entry.isModified() {
@@ -1117,7 +1134,15 @@ func (b *BlockChain) initChainState() error {
 	if !initialized {
 		// At this point the database has not already been initialized, so
 		// initialize both it and the chain state to the genesis block.
-		return b.createChainState()
+		// The genesis block is then moved to the header file when the
+		// block index is stored there.
+		if err := b.createChainState(); err != nil {
+			return err
+		}
+		if b.index.headers != nil {
+			return b.index.moveBucketToHeaderFile()
+		}
+		return nil
 	}
 
 	if !hasBlockIndex {
@@ -1127,6 +1152,19 @@ func (b *BlockChain) initChainState() error {
 		}
 	}
 
+	// Load the block index from the header file when it's stored there,
+	// and move the entries left in the database to it, which migrates the
+	// block index of an existing database on the first start.
+	if b.index.headers != nil {
+		log.Infof("Loading block index from %s...", b.index.headers.path)
+		if err := b.index.loadHeaderFile(); err != nil {
+			return err
+		}
+		if err := b.index.moveBucketToHeaderFile(); err != nil {
+			return err
+		}
+	}
+
 	// Attempt to load the chain state from the database.
 	err = b.db.View(func(dbTx database.Tx) error {
 		// Fetch the stored chain state from the database metadata.
@@ -1144,53 +1182,56 @@ func (b *BlockChain) initChainState() error {
 		// chain and construct the block index accordingly.  Since the
 		// number of nodes are already known, perform a single alloc
 		// for them versus a whole bunch of little ones to reduce
-		// pressure on the GC.
-		log.Infof("Loading block index...")
-
-		blockIndexBucket := dbTx.Metadata().Bucket(blockIndexBucketName)
-
-		var i int32
-		var lastNode *blockNode
-		cursor := blockIndexBucket.Cursor()
-		for ok := cursor.First(); ok; ok = cursor.Next() {
-			header, status, err := deserializeBlockRow(cursor.Value())
-			if err != nil {
-				return err
-			}
-
-			// Determine the parent block node. Since we iterate block headers
-			// in order of height, if the blocks are mostly linear there is a
-			// very good chance the previous header processed is the parent.
-			var parent *blockNode
-			if lastNode == nil {
-				blockHash := header.BlockHash()
-				if !blockHash.IsEqual(b.chainParams.GenesisHash) {
-					return AssertError(fmt.Sprintf("initChainState: Expected "+
-						"first entry in block index to be genesis block, "+
-						"found %s", blockHash))
+		// pressure on the GC.  The block index was already loaded when
+		// it's stored in the header file.
+		if b.index.headers == nil {
+			log.Infof("Loading block index...")
+
+			blockIndexBucket := dbTx.Metadata().Bucket(blockIndexBucketName)
+
+			var i int32
+			var lastNode *blockNode
+			cursor := blockIndexBucket.Cursor()
+			for ok := cursor.First(); ok; ok = cursor.Next() {
+				header, status, err := deserializeBlockRow(cursor.Value())
+				if err != nil {
+					return err
 				}
-			} else if header.PrevBlock == lastNode.hash {
-				// Since we iterate block headers in order of height, if the
-				// blocks are mostly linear there is a very good chance the
-				// previous header processed is the parent.
-				parent = lastNode
-			} else {
-				parent = b.index.LookupNode(&header.PrevBlock)
-				if parent == nil {
-					return AssertError(fmt.Sprintf("initChainState: Could "+
-						"not find parent for block %s", header.BlockHash()))
+
+				// Determine the parent block node. Since we iterate block headers
+				// in order of height, if the blocks are mostly linear there is a
+				// very good chance the previous header processed is the parent.
+				var parent *blockNode
+				if lastNode == nil {
+					blockHash := header.BlockHash()
+					if !blockHash.IsEqual(b.chainParams.GenesisHash) {
+						return AssertError(fmt.Sprintf("initChainState: Expected "+
+							"first entry in block index to be genesis block, "+
+							"found %s", blockHash))
+					}
+				} else if header.PrevBlock == lastNode.hash {
+					// Since we iterate block headers in order of height, if the
+					// blocks are mostly linear there is a very good chance the
+					// previous header processed is the parent.
+					parent = lastNode
+				} else {
+					parent = b.index.LookupNode(&header.PrevBlock)
+					if parent == nil {
+						return AssertError(fmt.Sprintf("initChainState: Could "+
+							"not find parent for block %s", header.BlockHash()))
+					}
 				}
-			}
 
-			// Initialize the block node for the block, connect it,
-			// and add it to the block index.
-			node := new(blockNode)
-			initBlockNode(node, header, parent)
-			node.status = status
-			b.index.addNode(node)
+				// Initialize the block node for the block, connect it,
+				// and add it to the block index.
+				node := new(blockNode)
+				initBlockNode(node, header, parent)
+				node.status = status
+				b.index.addNode(node)
 
-			lastNode = node
-			i++
+				lastNode = node
+				i++
+			}
 		}
 
 		// Set the best chain view to the stored best state.
@@ -1339,6 +1380,14 @@ func dbStoreBlockNode(dbTx database.Tx, node *blockNode) error {
 	return blockIndexBucket.Put(key, value)
 }
 
+// dbRemoveBlockNode removes the block header and validation status of the
+// passed block node from the block index bucket.
+func dbRemoveBlockNode(dbTx database.Tx, node *blockNode) error {
+	blockIndexBucket := dbTx.Metadata().Bucket(blockIndexBucketName)
+	key := blockIndexKey(&node.hash, uint32(node.height))
+	return blockIndexBucket.Delete(key)
+}
+
 // dbStoreBlock stores the provided block in the database if it is not already
 // there. The full block data is written to ffldb.
 func dbStoreBlock(dbTx database.Tx, block *btcutil.Block) error {
@@ -3,9 +3,11 @@ package blockchain
 import (
 	"bytes"
 	"fmt"
+	"io"
 
 	"github.com/pkg/errors"
 
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
 	"github.com/lbryio/lbcd/txscript"
 	"github.com/lbryio/lbcd/wire"
 	btcutil "github.com/lbryio/lbcutil"
@@ -31,38 +33,110 @@ func (b *BlockChain) SetClaimtrieHeader(block *btcutil.Block, view *UtxoViewpoin
 	return errors.Wrapf(err, "in reset height")
 }
 
+// checkClaimtrieTemplate ensures the claimtrie root in the header of the passed
+// block template matches the root computed by processing its claim scripts on
+// top of the current claimtrie.  The claimtrie is reset to the current tip
+// afterwards.  The view must contain the outputs spent by the block.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) checkClaimtrieTemplate(block *btcutil.Block, view *UtxoViewpoint) error {
+	if b.claimTrie == nil {
+		return nil
+	}
+
+	height := b.claimTrie.Height()
+	err := b.ParseClaimScripts(block, nil, view, false)
+	if err != nil {
+		// Discard the changes of the claim scripts processed before the
+		// failure, which would otherwise be applied with the next block.
+		if b.claimTrie.Height() == height {
+			_ = b.claimTrie.AppendBlock(true)
+		}
+		_ = b.claimTrie.ResetHeight(height)
+		return ruleError(ErrBadClaimTrie, err.Error())
+	}
+
+	hash := *b.claimTrie.MerkleHash()
+	err = b.claimTrie.ResetHeight(height)
+	if err != nil {
+		return errors.Wrapf(err, "in reset height")
+	}
+
+	header := &block.MsgBlock().Header
+	if hash != header.ClaimTrie {
+		str := fmt.Sprintf("computed claimtrie root %s != header's "+
+			"ClaimTrie: %s", hash, header.ClaimTrie)
+		return ruleError(ErrBadClaimTrie, str)
+	}
+
+	return nil
+}
+
 func (b *BlockChain) ParseClaimScripts(block *btcutil.Block, bn *blockNode, view *UtxoViewpoint, shouldFlush bool) error {
+	return parseClaimScripts(b.claimTrie, block, bn, view, shouldFlush)
+}
+
+// parseClaimScripts processes the claim scripts of the passed block on top of
+// the passed claim trie, as described by ParseClaimScripts.
+func parseClaimScripts(ct *claimtrie.ClaimTrie, block *btcutil.Block, bn *blockNode, view *UtxoViewpoint, shouldFlush bool) error {
 	ht := block.Height()
 
 	for _, tx := range block.Transactions() {
 		h := handler{ht, tx, view, map[string][]byte{}}
-		if err := h.handleTxIns(b.claimTrie); err != nil {
+		if err := h.handleTxIns(ct); err != nil {
 			return err
 		}
-		if err := h.handleTxOuts(b.claimTrie); err != nil {
+		if err := h.handleTxOuts(ct); err != nil {
 			return err
 		}
 	}
 
-	err := b.claimTrie.AppendBlock(bn == nil)
+	err := ct.AppendBlock(bn == nil)
 	if err != nil {
 		return errors.Wrapf(err, "in append block")
 	}
 
 	if shouldFlush {
-		b.claimTrie.FlushToDisk()
+		ct.FlushToDisk()
 	}
 
-	hash := b.claimTrie.MerkleHash()
+	hash := ct.MerkleHash()
 	if bn != nil && bn.claimTrie != *hash {
 		// undo our AppendBlock call as we've decided that our interpretation of the block data is incorrect,
 		// or that the person who made the block assembled the pieces incorrectly.
-		_ = b.claimTrie.ResetHeight(b.claimTrie.Height() - 1)
+		_ = ct.ResetHeight(ct.Height() - 1)
 		return errors.Errorf("height: %d, computed hash: %s != header's ClaimTrie: %s", ht, *hash, bn.claimTrie)
 	}
 	return nil
 }
 
+// spentViewFromJournal returns a utxo view populated with all of the outputs
+// spent by the passed block as recorded in its spend journal entry.  This
+// allows the claim scripts of a block to be processed without needing the
+// historical utxo set.
+func spentViewFromJournal(block *btcutil.Block, stxos []SpentTxOut) *UtxoViewpoint {
+	view := NewUtxoViewpoint()
+	stxoIdx := 0
+	for _, tx := range block.Transactions()[1:] {
+		for _, txIn := range tx.MsgTx().TxIn {
+			stxo := &stxos[stxoIdx]
+			stxoIdx++
+
+			var flags txoFlags
+			if stxo.IsCoinBase {
+				flags |= tfCoinBase
+			}
+			view.entries[txIn.PreviousOutPoint] = &UtxoEntry{
+				amount:      stxo.Amount,
+				pkScript:    stxo.PkScript,
+				blockHeight: stxo.Height,
+				packedFlags: flags,
+			}
+		}
+	}
+	return view
+}
+
 type handler struct {
 	ht    int32
 	tx    *btcutil.Tx
@@ -155,6 +229,29 @@ func (h *handler) handleTxOuts(ct *claimtrie.ClaimTrie) error {
 	return nil
 }
 
+// ClaimTrieRootAt returns the claimtrie root computed for the block at the given
+// height of the main chain, as stored when the block was connected.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ClaimTrieRootAt(height int32) (*chainhash.Hash, error) {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	return b.claimTrie.MerkleHashAt(height)
+}
+
+// ClaimTrieStats returns the aggregate statistics of the claimtrie nodes at the
+// tip of the main chain.  The first call computes them from all the names,
+// which can take a while.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ClaimTrieStats() (*node.Stats, error) {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	return b.claimTrie.Stats()
+}
+
 func (b *BlockChain) GetNamesChangedInBlock(height int32) ([]string, error) {
 	b.chainLock.RLock()
 	defer b.chainLock.RUnlock()
@@ -162,6 +259,19 @@ func (b *BlockChain) GetNamesChangedInBlock(height int32) ([]string, error) {
 	return b.claimTrie.NamesChangedInBlock(height)
 }
 
+// GetClaimStateChangesInBlock returns the claims activated and the claims
+// expired by the block at the given height of the main chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) GetClaimStateChangesInBlock(height int32) (activated,
+	expired []claimtrie.ClaimStateChange, err error) {
+
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	return b.claimTrie.ClaimStateChangesInBlock(height)
+}
+
 func (b *BlockChain) GetClaimsForName(height int32, name string) (string, *node.Node, error) {
 
 	normalizedName := normalization.NormalizeIfNecessary([]byte(name), height)
@@ -181,3 +291,235 @@ func (b *BlockChain) GetClaimsForName(height int32, name string) (string, *node.
 	n.SortClaimsByBid()
 	return string(normalizedName), n, nil
 }
+
+// ProveClaim returns the proof the active claim with the passed outpoint is a
+// claim of the passed name in the claim trie at the tip of the chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ProveClaim(name string, op wire.OutPoint) (*claimtrie.ClaimProof, error) {
+
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	normalizedName := normalization.NormalizeIfNecessary([]byte(name), b.claimTrie.Height())
+	return b.claimTrie.ProveClaim(normalizedName, op)
+}
+
+// SimulateClaims returns the node of the given name as it would be at the given
+// height if the given hypothetical claims and supports were added by the next
+// block, along with the normalized name and the height.  The name and height
+// of the changes are set here, and a height of zero selects the height at
+// which all of them are active.  The claim trie isn't modified.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) SimulateClaims(name string, changes []change.Change, height int32) (string, *node.Node, int32, error) {
+
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	nextHeight := b.claimTrie.Height() + 1
+	normalizedName := normalization.NormalizeIfNecessary([]byte(name), nextHeight)
+	if height != 0 && height < nextHeight {
+		return string(normalizedName), nil, height, fmt.Errorf("height %d "+
+			"is below the height of the next block %d", height, nextHeight)
+	}
+
+	simulated := map[wire.OutPoint]bool{}
+	for i := range changes {
+		changes[i].Name = normalizedName
+		changes[i].Height = nextHeight
+		simulated[changes[i].OutPoint] = true
+	}
+
+	simulateAt := height
+	if simulateAt == 0 {
+		simulateAt = nextHeight
+	}
+	n, err := b.claimTrie.SimulateNodeAt(simulateAt, normalizedName, changes)
+	if err != nil {
+		return string(normalizedName), nil, simulateAt, err
+	}
+	if n == nil {
+		return string(normalizedName), nil, simulateAt, fmt.Errorf("name "+
+			"does not exist at height %d: %s", simulateAt, name)
+	}
+
+	// Advance to the height at which the last of the hypothetical claims
+	// and supports is active, which can't be before the delays computed at
+	// the next block are over.
+	if height == 0 {
+		for _, list := range []node.ClaimList{n.Claims, n.Supports} {
+			for _, c := range list {
+				if simulated[c.OutPoint] && c.ActiveAt > simulateAt {
+					simulateAt = c.ActiveAt
+				}
+			}
+		}
+		if simulateAt != nextHeight {
+			n, err = b.claimTrie.SimulateNodeAt(simulateAt,
+				normalizedName, changes)
+			if err != nil {
+				return string(normalizedName), nil, simulateAt, err
+			}
+		}
+	}
+
+	n.SortClaimsByBid()
+	return string(normalizedName), n, simulateAt, nil
+}
+
+// ExportClaimTrieSnapshot writes a snapshot of the claim trie at the given
+// height of the main chain to w.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ExportClaimTrieSnapshot(w io.Writer, height int32) (*claimtrie.SnapshotHeader, error) {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	return b.claimTrie.ExportSnapshot(w, height)
+}
+
+// ImportClaimTrieSnapshot replaces the claim trie with the snapshot read from r
+// along with the claim trie data of the blocks above the snapshot height.  The
+// snapshot is only accepted when its claim trie root matches the one committed
+// to by the main chain block at its height.
+//
+// The snapshot is imported into a staged claim trie, which is caught up with
+// the main chain a block at a time while the chain lock is only held for reads,
+// so blocks keep being connected to the chain meanwhile.  The chain lock is
+// only held for writes to process the blocks connected since and to swap the
+// staged claim trie in.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ImportClaimTrieSnapshot(r io.ReadSeeker) (*claimtrie.SnapshotHeader, error) {
+	b.claimTrieImportLock.Lock()
+	defer b.claimTrieImportLock.Unlock()
+
+	header, err := claimtrie.ReadSnapshotHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	b.chainLock.RLock()
+	n := b.bestChain.NodeByHeight(header.Height)
+	bestHeight := b.bestChain.Height()
+	b.chainLock.RUnlock()
+	if n == nil {
+		return nil, errors.Errorf("snapshot height %d is above the best height %d",
+			header.Height, bestHeight)
+	}
+	if n.claimTrie != header.ClaimTrieRoot {
+		return nil, errors.Errorf("snapshot claim trie root %s doesn't match %s "+
+			"of block %s at height %d", header.ClaimTrieRoot, n.claimTrie,
+			n.hash, n.height)
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "rewinding snapshot")
+	}
+
+	log.Infof("Importing claim trie snapshot at height %d", header.Height)
+	staged, _, err := b.claimTrie.StageSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+	discard := func() {
+		if err := staged.Discard(); err != nil {
+			log.Warnf("Unable to discard the imported claim trie: %v", err)
+		}
+	}
+
+	last, done := n, false
+	for !done {
+		b.chainLock.RLock()
+		last, done, err = b.catchUpClaimTrie(staged, last)
+		b.chainLock.RUnlock()
+		if err != nil {
+			discard()
+			return nil, err
+		}
+	}
+
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+	for done = false; !done; {
+		last, done, err = b.catchUpClaimTrie(staged, last)
+		if err != nil {
+			discard()
+			return nil, err
+		}
+	}
+	if err = b.claimTrie.Replace(staged); err != nil {
+		return nil, err
+	}
+	log.Infof("Imported claim trie snapshot at height %d, caught up to "+
+		"height %d", header.Height, last.height)
+
+	return header, nil
+}
+
+// catchUpClaimTrie connects the next block of the main chain to the passed
+// claim trie, which isn't the one of the chain, and returns its node along with
+// whether it is the tip of the main chain.  The passed node is the one of the
+// last block connected to the claim trie, whose blocks which were disconnected
+// from the main chain since are rolled back first.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) catchUpClaimTrie(ct *claimtrie.ClaimTrie, last *blockNode) (*blockNode, bool, error) {
+	if fork := b.bestChain.FindFork(last); fork != last {
+		if err := ct.ResetHeight(fork.height); err != nil {
+			return nil, false, errors.Wrapf(err, "in reset height")
+		}
+		last = fork
+	}
+
+	next := b.bestChain.Next(last)
+	if next == nil {
+		return last, true, nil
+	}
+	if err := b.connectClaimTrieBlock(ct, next); err != nil {
+		return nil, false, err
+	}
+	return next, next == b.bestChain.Tip(), nil
+}
+
+// CompactClaimTrie removes the obsolete changes of the claim trie below the
+// final height and compacts its node repo.  See claimtrie.Compact for details.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) CompactClaimTrie(finalHeight int32, interrupt <-chan struct{}) (*claimtrie.CompactResult, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	result, err := b.claimTrie.Compact(finalHeight, interrupt)
+	if err != nil {
+		return nil, err
+	}
+	b.claimTrie.FlushToDisk()
+	return result, nil
+}
+
+// VerifyClaimTrie checks the claim trie at the tip of the main chain against
+// the claim trie root committed to by the tip and the changes of every name,
+// optionally repairing the names which diverged.  See claimtrie.Verify for
+// details.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) VerifyClaimTrie(repair bool, interrupt <-chan struct{}) (*claimtrie.VerifyResult, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	tip := b.bestChain.Tip()
+	if b.claimTrie.Height() != tip.height {
+		return nil, errors.Errorf("claim trie height %d doesn't match the best height %d",
+			b.claimTrie.Height(), tip.height)
+	}
+
+	result, err := b.claimTrie.Verify(&tip.claimTrie, repair, interrupt)
+	if err != nil {
+		return nil, err
+	}
+	if result.DivergentCount > 0 {
+		log.Warnf("Found %d claim trie names which diverge from their "+
+			"changes at height %d", result.DivergentCount, tip.height)
+	}
+	return result, nil
+}
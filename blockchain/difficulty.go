@@ -5,6 +5,7 @@
 package blockchain
 
 import (
+	"fmt"
 	"math/big"
 	"time"
 
@@ -216,6 +217,37 @@ func (b *BlockChain) findPrevTestNetDifficulty(startNode *blockNode) uint32 {
 	return lastBits
 }
 
+// calcRetargetTimespans returns the actual timespan, in seconds, of the blocks
+// of the retarget interval ending at the passed block node, along with the
+// timespan the difficulty of the next block is adjusted by once it's dampened
+// and limited to the allowed range.
+func (b *BlockChain) calcRetargetTimespans(lastNode *blockNode) (int64, int64, error) {
+	// Get the block node at the previous retarget (targetTimespan days
+	// worth of blocks).
+	blocksBack := b.blocksPerRetarget
+	if blocksBack > lastNode.height {
+		blocksBack = lastNode.height
+	}
+	firstNode := lastNode.RelativeAncestor(blocksBack)
+	if firstNode == nil {
+		return 0, 0, AssertError("unable to obtain previous retarget block")
+	}
+
+	targetTimeSpan := int64(b.chainParams.TargetTimespan / time.Second)
+
+	// Limit the amount of adjustment that can occur to the previous
+	// difficulty.
+	actualTimespan := lastNode.timestamp - firstNode.timestamp
+	adjustedTimespan := targetTimeSpan + (actualTimespan-targetTimeSpan)/8
+	if adjustedTimespan < b.minRetargetTimespan {
+		adjustedTimespan = b.minRetargetTimespan
+	} else if adjustedTimespan > b.maxRetargetTimespan {
+		adjustedTimespan = b.maxRetargetTimespan
+	}
+
+	return actualTimespan, adjustedTimespan, nil
+}
+
 // calcNextRequiredDifficulty calculates the required difficulty for the block
 // after the passed previous block node based on the difficulty retarget rules.
 // This function differs from the exported CalcNextRequiredDifficulty in that
@@ -246,29 +278,12 @@ func (b *BlockChain) calcNextRequiredDifficulty(lastNode *blockNode, newBlockTim
 		return b.findPrevTestNetDifficulty(lastNode), nil
 	}
 
-	// Get the block node at the previous retarget (targetTimespan days
-	// worth of blocks).
-	blocksBack := b.blocksPerRetarget
-	if blocksBack > lastNode.height {
-		blocksBack = lastNode.height
-	}
-	firstNode := lastNode.RelativeAncestor(blocksBack)
-	if firstNode == nil {
-		return 0, AssertError("unable to obtain previous retarget block")
+	actualTimespan, adjustedTimespan, err := b.calcRetargetTimespans(lastNode)
+	if err != nil {
+		return 0, err
 	}
-
 	targetTimeSpan := int64(b.chainParams.TargetTimespan / time.Second)
 
-	// Limit the amount of adjustment that can occur to the previous
-	// difficulty.
-	actualTimespan := lastNode.timestamp - firstNode.timestamp
-	adjustedTimespan := targetTimeSpan + (actualTimespan-targetTimeSpan)/8
-	if adjustedTimespan < b.minRetargetTimespan {
-		adjustedTimespan = b.minRetargetTimespan
-	} else if adjustedTimespan > b.maxRetargetTimespan {
-		adjustedTimespan = b.maxRetargetTimespan
-	}
-
 	// Calculate new target difficulty as:
 	//  currentDifficulty * (adjustedTimespan / targetTimespan)
 	// The result uses integer division which means it will be slightly
@@ -310,3 +325,101 @@ func (b *BlockChain) CalcNextRequiredDifficulty(timestamp time.Time) (uint32, er
 	b.chainLock.Unlock()
 	return difficulty, err
 }
+
+// DifficultyRetarget houses the details of the difficulty required for a block
+// as determined by the difficulty retarget rules.
+type DifficultyRetarget struct {
+	// Height is the height of the block.
+	Height int32
+
+	// Bits is the difficulty required for the block, and PrevBits the
+	// difficulty of its parent.
+	Bits     uint32
+	PrevBits uint32
+
+	// ActualTimespan is the time the blocks of the retarget interval
+	// ending at the parent of the block took, and AdjustedTimespan the
+	// dampened and limited timespan the difficulty is adjusted by.  They
+	// are not set for networks which reduce the difficulty to the minimum
+	// once too much time elapsed since the last block, since the
+	// difficulty isn't retargeted there.
+	ActualTimespan   time.Duration
+	AdjustedTimespan time.Duration
+}
+
+// ProjectNextDifficulty returns the details of the difficulty required for the
+// block after the end of the current best chain when it has the passed
+// timestamp.  The timestamp only matters for networks which reduce the
+// difficulty to the minimum once too much time elapsed since the last block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ProjectNextDifficulty(timestamp time.Time) (*DifficultyRetarget, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	tip := b.bestChain.Tip()
+	bits, err := b.calcNextRequiredDifficulty(tip, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	retarget := &DifficultyRetarget{
+		Height:   tip.height + 1,
+		Bits:     bits,
+		PrevBits: tip.bits,
+	}
+	if !b.chainParams.ReduceMinDifficulty {
+		actual, adjusted, err := b.calcRetargetTimespans(tip)
+		if err != nil {
+			return nil, err
+		}
+		retarget.ActualTimespan = time.Duration(actual) * time.Second
+		retarget.AdjustedTimespan = time.Duration(adjusted) * time.Second
+	}
+	return retarget, nil
+}
+
+// CalcNetworkHashPS returns the estimated number of hashes per second performed
+// by the network to mine the blocks of the main chain after the passed start
+// height up to and including the passed end height.  It's the work of the
+// blocks divided by the time between the earliest and the latest timestamps of
+// the blocks in the range, including the start block.  Zero is returned when
+// they have the same timestamp.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) CalcNetworkHashPS(startHeight, endHeight int32) (*big.Int, error) {
+	if startHeight < 0 || startHeight > endHeight {
+		str := fmt.Sprintf("invalid block range %d-%d", startHeight,
+			endHeight)
+		return nil, AssertError(str)
+	}
+
+	endNode := b.bestChain.NodeByHeight(endHeight)
+	if endNode == nil {
+		str := fmt.Sprintf("no block at height %d exists", endHeight)
+		return nil, errNotInMainChain(str)
+	}
+	startNode := endNode.Ancestor(startHeight)
+
+	// The total work of the blocks is the difference between the
+	// cumulative work of the end and start blocks.
+	totalWork := new(big.Int).Sub(endNode.workSum, startNode.workSum)
+
+	// Find the min and max block timestamps since they are not
+	// necessarily the ones of the start and end blocks.
+	minTimestamp, maxTimestamp := endNode.timestamp, endNode.timestamp
+	for node := endNode; node != startNode.parent; node = node.parent {
+		if node.timestamp < minTimestamp {
+			minTimestamp = node.timestamp
+		}
+		if node.timestamp > maxTimestamp {
+			maxTimestamp = node.timestamp
+		}
+	}
+
+	timeDiff := maxTimestamp - minTimestamp
+	if timeDiff == 0 {
+		return big.NewInt(0), nil
+	}
+	return totalWork.Div(totalWork, big.NewInt(timeDiff)), nil
+}
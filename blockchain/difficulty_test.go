@@ -7,6 +7,9 @@ package blockchain
 import (
 	"math/big"
 	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/chaincfg"
 )
 
 // TestBigToCompact ensures BigToCompact converts big integers to the expected
@@ -73,3 +76,70 @@ func TestCalcWork(t *testing.T) {
 		}
 	}
 }
+
+// TestNetworkHashPSAndNextDifficulty ensures the network hashes per second are
+// calculated from the work and the timestamps of the requested blocks, and the
+// projected difficulty of the next block follows the retarget rules.
+func TestNetworkHashPSAndNextDifficulty(t *testing.T) {
+	params := chaincfg.MainNetParams
+	chain := newFakeChain(&params)
+
+	// Build a chain of blocks 150 seconds apart, except for a block with
+	// a timestamp before the one of its parent and a last block 300
+	// seconds after its parent.
+	const bits = 0x1b0404cb
+	node := chain.bestChain.Tip()
+	genesisTime := time.Unix(node.timestamp, 0)
+	offsets := []int64{150, 300, 450, 350, 600, 750, 900, 1200}
+	for _, offset := range offsets {
+		timestamp := genesisTime.Add(time.Duration(offset) * time.Second)
+		node = newFakeNode(node, 1, bits, timestamp)
+		chain.index.AddNode(node)
+		chain.bestChain.SetTip(node)
+	}
+
+	work := CalcWork(bits)
+	tests := []struct {
+		start, end int32
+		want       int64
+	}{
+		// The genesis block has the lowest timestamp.
+		{0, 8, 8 * work.Int64() / 1200},
+		// Block 4 doesn't have the lowest timestamp of the range.
+		{3, 5, 2 * work.Int64() / (600 - 350)},
+		{4, 4, 0},
+	}
+	for _, test := range tests {
+		got, err := chain.CalcNetworkHashPS(test.start, test.end)
+		if err != nil {
+			t.Fatalf("CalcNetworkHashPS(%d, %d): unexpected error: %v",
+				test.start, test.end, err)
+		}
+		if got.Int64() != test.want {
+			t.Fatalf("CalcNetworkHashPS(%d, %d): got %d, want %d",
+				test.start, test.end, got, test.want)
+		}
+	}
+	if _, err := chain.CalcNetworkHashPS(0, 9); err == nil {
+		t.Fatal("CalcNetworkHashPS: expected error for unknown height")
+	}
+
+	// The last block took 300 seconds, which is dampened to 168 seconds.
+	retarget, err := chain.ProjectNextDifficulty(time.Now())
+	if err != nil {
+		t.Fatalf("ProjectNextDifficulty: unexpected error: %v", err)
+	}
+	wantTarget := new(big.Int).Mul(CompactToBig(bits), big.NewInt(168))
+	wantTarget.Div(wantTarget, big.NewInt(150))
+	want := DifficultyRetarget{
+		Height:           9,
+		Bits:             BigToCompact(wantTarget),
+		PrevBits:         bits,
+		ActualTimespan:   300 * time.Second,
+		AdjustedTimespan: 168 * time.Second,
+	}
+	if *retarget != want {
+		t.Fatalf("ProjectNextDifficulty: got %+v, want %+v", *retarget,
+			want)
+	}
+}
@@ -224,6 +224,10 @@ const (
 	// ErrBadClaimTrie indicates the calculated ClaimTrie root does not match
 	// the expected value.
 	ErrBadClaimTrie
+
+	// ErrBadSignetSolution indicates a block of a signet network doesn't
+	// have a valid solution to the signet challenge of the network.
+	ErrBadSignetSolution
 )
 
 // Map of ErrorCode values back to their constant names for pretty printing.
@@ -272,6 +276,7 @@ var errorCodeStrings = map[ErrorCode]string{
 	ErrInvalidAncestorBlock:      "ErrInvalidAncestorBlock",
 	ErrPrevBlockNotBest:          "ErrPrevBlockNotBest",
 	ErrBadClaimTrie:              "ErrBadClaimTrie",
+	ErrBadSignetSolution:         "ErrBadSignetSolution",
 }
 
 // String returns the ErrorCode as a human-readable name.
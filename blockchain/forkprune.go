@@ -0,0 +1,146 @@
+package blockchain
+
+const (
+	// MinPruneForkDepth is the minimum depth below the tip of the main
+	// chain that stale forks must branch off at to be pruned from the
+	// block index.  It's far deeper than any reorganization the chain is
+	// expected to handle.
+	MinPruneForkDepth = MinBlocksToKeep
+
+	// pruneForkInterval is the number of main chain blocks between the
+	// attempts to prune the stale forks from the block index.
+	pruneForkInterval = 576
+)
+
+// ForkPruneStats holds statistics about the stale forks of the block index and
+// their pruning.
+type ForkPruneStats struct {
+	// Depth is the depth below the tip of the main chain beyond which
+	// stale forks are pruned.  It is zero when pruning is disabled.
+	Depth int32
+
+	// IndexNodes is the number of block nodes in the block index and
+	// StaleNodes the number of them which aren't part of the main chain.
+	IndexNodes int
+	StaleNodes int
+
+	// PrunedNodes is the number of block nodes pruned from the block index
+	// since the chain was loaded, and LastPruneHeight the height of the
+	// main chain when stale forks were last pruned.
+	PrunedNodes     uint64
+	LastPruneHeight int32
+}
+
+// pruneStaleForks removes the block nodes of the stale forks which branch off
+// the main chain more than the configured depth below its tip from the block
+// index, and from the database as well when configured to.  Forks with at
+// least as much work as the main chain are retained along with their
+// ancestors since they may still become the main chain.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) pruneStaleForks() error {
+	tip := b.bestChain.Tip()
+	minForkHeight := tip.height - b.pruneForkDepth
+	staleNodes := b.index.SideChainNodes(b.bestChain)
+
+	retain := make(map[*blockNode]struct{})
+	for _, node := range staleNodes {
+		if node.status.KnownInvalid() || node.workSum.Cmp(tip.workSum) < 0 {
+			continue
+		}
+		for n := node; !b.bestChain.Contains(n); n = n.parent {
+			if _, ok := retain[n]; ok {
+				break
+			}
+			retain[n] = struct{}{}
+		}
+	}
+
+	// forkHeights caches the height of the fork point of the visited nodes
+	// so each stale branch is only walked once.
+	forkHeights := make(map[*blockNode]int32, len(staleNodes))
+	forkHeight := func(node *blockNode) int32 {
+		var path []*blockNode
+		var height int32
+		for n := node; ; n = n.parent {
+			if b.bestChain.Contains(n) {
+				height = n.height
+				break
+			}
+			if h, ok := forkHeights[n]; ok {
+				height = h
+				break
+			}
+			path = append(path, n)
+		}
+		for _, n := range path {
+			forkHeights[n] = height
+		}
+		return height
+	}
+
+	var pruned []*blockNode
+	for _, node := range staleNodes {
+		if _, ok := retain[node]; ok {
+			continue
+		}
+		if forkHeight(node) < minForkHeight {
+			pruned = append(pruned, node)
+		}
+	}
+
+	b.retainedForkNodes = len(staleNodes) - len(pruned)
+	b.lastForkPruneHeight = tip.height
+	if len(pruned) == 0 {
+		return nil
+	}
+
+	b.index.RemoveNodes(pruned)
+	if b.pruneForkDB {
+		if err := b.index.deleteNodes(pruned); err != nil {
+			return err
+		}
+	}
+	b.prunedForkNodes += uint64(len(pruned))
+
+	log.Infof("Pruned %d block index entries of stale forks branching "+
+		"off below height %d", len(pruned), minForkHeight)
+	return nil
+}
+
+// maybePruneStaleForks prunes the stale forks from the block index when
+// pruning is enabled, the main chain reached the next prune interval and new
+// stale blocks were added to the block index since the last prune.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) maybePruneStaleForks() error {
+	if b.pruneForkDepth == 0 {
+		return nil
+	}
+	height := b.bestChain.Height()
+	if height%pruneForkInterval != 0 {
+		return nil
+	}
+	if b.index.Len()-int(height)-1 <= b.retainedForkNodes {
+		return nil
+	}
+	return b.pruneStaleForks()
+}
+
+// ForkPruneStats returns statistics about the stale forks of the block index
+// and their pruning.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ForkPruneStats() ForkPruneStats {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	indexNodes := b.index.Len()
+	return ForkPruneStats{
+		Depth:           b.pruneForkDepth,
+		IndexNodes:      indexNodes,
+		StaleNodes:      indexNodes - int(b.bestChain.Height()) - 1,
+		PrunedNodes:     b.prunedForkNodes,
+		LastPruneHeight: b.lastForkPruneHeight,
+	}
+}
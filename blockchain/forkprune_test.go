@@ -0,0 +1,106 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/chaincfg"
+)
+
+// chainedWorkNodes returns the specified number of nodes with the proof of work
+// limit of the regression test network constructed such that each subsequent
+// node points to the previous one to create a chain.  Unlike the nodes of
+// chainedNodes, each of them adds work to the chain.
+func chainedWorkNodes(parent *blockNode, numNodes int) []*blockNode {
+	bits := chaincfg.RegressionNetParams.PowLimitBits
+	nodes := make([]*blockNode, numNodes)
+	tip := parent
+	for i := range nodes {
+		timestamp := time.Unix(tip.timestamp+int64(i%7)+1, 0)
+		nodes[i] = newFakeNode(tip, 1, bits, timestamp)
+		tip = nodes[i]
+	}
+	return nodes
+}
+
+// TestPruneStaleForks ensures only the stale forks branching off the main
+// chain below the prune depth are pruned from the block index, while the
+// recent forks and the forks with as much work as the main chain are retained.
+func TestPruneStaleForks(t *testing.T) {
+	chain := newFakeChain(&chaincfg.RegressionNetParams)
+	chain.pruneForkDepth = MinPruneForkDepth
+
+	addNodes := func(nodes []*blockNode) {
+		for _, node := range nodes {
+			chain.index.AddNode(node)
+		}
+	}
+	mainNodes := chainedWorkNodes(chain.bestChain.Genesis(), 400)
+	addNodes(mainNodes)
+	chain.bestChain.SetTip(tstTip(mainNodes))
+
+	// The first fork branches off too deep, the second one is recent and
+	// the third one has as much work as the main chain.
+	deepFork := chainedWorkNodes(mainNodes[9], 5)
+	recentFork := chainedWorkNodes(mainNodes[389], 3)
+	heavyFork := chainedWorkNodes(mainNodes[19], 380)
+	addNodes(deepFork)
+	addNodes(recentFork)
+	addNodes(heavyFork)
+
+	if err := chain.maybePruneStaleForks(); err != nil {
+		t.Fatalf("maybePruneStaleForks: unexpected error: %v", err)
+	}
+	if chain.prunedForkNodes != 0 {
+		t.Fatalf("stale forks pruned outside of the prune interval")
+	}
+
+	if err := chain.pruneStaleForks(); err != nil {
+		t.Fatalf("pruneStaleForks: unexpected error: %v", err)
+	}
+	for _, node := range deepFork {
+		if chain.index.HaveBlock(&node.hash) {
+			t.Fatalf("node %v of the deep fork was not pruned",
+				node.hash)
+		}
+	}
+	for _, fork := range [][]*blockNode{mainNodes, recentFork, heavyFork} {
+		for _, node := range fork {
+			if !chain.index.HaveBlock(&node.hash) {
+				t.Fatalf("node %v at height %d was pruned",
+					node.hash, node.height)
+			}
+		}
+	}
+
+	stats := chain.ForkPruneStats()
+	want := ForkPruneStats{
+		Depth:           MinPruneForkDepth,
+		IndexNodes:      1 + len(mainNodes) + len(recentFork) + len(heavyFork),
+		StaleNodes:      len(recentFork) + len(heavyFork),
+		PrunedNodes:     uint64(len(deepFork)),
+		LastPruneHeight: 400,
+	}
+	if stats != want {
+		t.Fatalf("unexpected stats - got %+v, want %+v", stats, want)
+	}
+
+	// Once the main chain has more work, the formerly heavy fork is stale
+	// and pruned as well.
+	mainNodes = append(mainNodes, chainedWorkNodes(tstTip(mainNodes), 5)...)
+	addNodes(mainNodes[400:])
+	chain.bestChain.SetTip(tstTip(mainNodes))
+	if err := chain.pruneStaleForks(); err != nil {
+		t.Fatalf("pruneStaleForks: unexpected error: %v", err)
+	}
+	for _, node := range heavyFork {
+		if chain.index.HaveBlock(&node.hash) {
+			t.Fatalf("node %v of the heavy fork was not pruned",
+				node.hash)
+		}
+	}
+	if stats := chain.ForkPruneStats(); stats.StaleNodes != len(recentFork) {
+		t.Fatalf("unexpected number of stale nodes - got %d, want %d",
+			stats.StaleNodes, len(recentFork))
+	}
+}
@@ -0,0 +1,415 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/wire"
+	"golang.org/x/exp/mmap"
+)
+
+const (
+	// headerRecordSize is the size of a record of the header file, which is
+	// a serialized block header followed by the validation status of the
+	// block.
+	headerRecordSize = blockHdrSize + 1
+
+	// headerRecordRemoved is the status of the records of the block nodes
+	// deleted from the block index.  It's never the status of a block since
+	// the status flags don't use all the bits.
+	headerRecordRemoved = 0xff
+
+	// headerFileMoveBatchSize is the number of entries deleted from the
+	// block index bucket per database transaction once they're moved to the
+	// header file.
+	headerFileMoveBatchSize = 50000
+)
+
+// headerFileRecordsKeyName is the name of the db key used to store the number
+// of records of the header file which are part of the block index.
+var headerFileRecordsKeyName = []byte("headerfilerecords")
+
+// headerFile stores the headers and the validation status of the nodes of the
+// block index in an append-only flat file of fixed-size records instead of the
+// block index bucket of the database.  The records are appended in the order
+// of the heights of the nodes, so the parent of a node always precedes it, and
+// only their status is updated in place afterwards.  Loading millions of
+// headers is then a sequential read of a memory-mapped file rather than an
+// iteration over the database.
+//
+// The number of records which are part of the block index is stored in the
+// database once they're written to the file, and the file is synced whenever
+// the database flushes its cache, before the number is written, rather than on
+// every write.  So the records left by an interrupted flush are discarded the
+// next time the file is opened, and the changes which may be lost on a crash
+// are the same as for the block index stored in the database.
+type headerFile struct {
+	path    string
+	file    *os.File
+	records uint32
+
+	// closeLock protects the file from being closed while the database
+	// syncs it.
+	closeLock sync.Mutex
+	closed    bool
+}
+
+// dbFetchHeaderFileRecords returns the number of records of the header file
+// which are part of the block index.  It's zero when the block index isn't
+// stored in the header file yet.
+func dbFetchHeaderFileRecords(dbTx database.Tx) uint32 {
+	serialized := dbTx.Metadata().Get(headerFileRecordsKeyName)
+	if len(serialized) != 4 {
+		return 0
+	}
+	return byteOrder.Uint32(serialized)
+}
+
+// dbPutHeaderFileRecords stores the number of records of the header file which
+// are part of the block index.
+func dbPutHeaderFileRecords(dbTx database.Tx, records uint32) error {
+	var serialized [4]byte
+	byteOrder.PutUint32(serialized[:], records)
+	return dbTx.Metadata().Put(headerFileRecordsKeyName, serialized[:])
+}
+
+// openHeaderFile opens the header file at the passed path, creating it when it
+// doesn't exist, and discards the records which aren't part of the block index
+// stored in the passed database.
+func openHeaderFile(db database.DB, path string) (*headerFile, error) {
+	var records uint32
+	err := db.View(func(dbTx database.Tx) error {
+		records = dbFetchHeaderFileRecords(dbTx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	size := int64(records) * headerRecordSize
+	if fi.Size() < size {
+		file.Close()
+		return nil, fmt.Errorf("the header file %s is %d bytes long "+
+			"while its %d records take %d bytes -- reindex the "+
+			"block index to rebuild it", path, fi.Size(), records,
+			size)
+	}
+	if fi.Size() > size {
+		log.Infof("Discarding %d bytes of the header file %s which "+
+			"are not part of the block index", fi.Size()-size, path)
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return &headerFile{
+		path:    path,
+		file:    file,
+		records: records,
+	}, nil
+}
+
+// write stores the passed block nodes in the file.  The records
+// of the nodes already stored are updated in place while the records of the
+// new ones are appended in the passed order, which must place the parent of a
+// node before it.
+func (f *headerFile) write(nodes []*blockNode) error {
+	var appended bytes.Buffer
+	var status [1]byte
+	for _, node := range nodes {
+		if node.headerRecord != 0 {
+			status[0] = byte(node.status)
+			offset := int64(node.headerRecord-1)*headerRecordSize +
+				blockHdrSize
+			if _, err := f.file.WriteAt(status[:], offset); err != nil {
+				return err
+			}
+			continue
+		}
+
+		header := node.Header()
+		if err := header.Serialize(&appended); err != nil {
+			return err
+		}
+		appended.WriteByte(byte(node.status))
+	}
+
+	if appended.Len() > 0 {
+		offset := int64(f.records) * headerRecordSize
+		if _, err := f.file.WriteAt(appended.Bytes(), offset); err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if node.headerRecord == 0 {
+				f.records++
+				node.headerRecord = f.records
+			}
+		}
+	}
+
+	return nil
+}
+
+// sync syncs the file to persistent storage unless it is closed.  It is called
+// by the database each time it flushes its cache.
+func (f *headerFile) sync() error {
+	f.closeLock.Lock()
+	defer f.closeLock.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// close syncs and closes the file.  The file must not be used afterwards.
+func (f *headerFile) close() error {
+	f.closeLock.Lock()
+	defer f.closeLock.Unlock()
+
+	f.closed = true
+	err := f.file.Sync()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// remove marks the records of the passed block nodes as removed so they are
+// not loaded again.
+func (f *headerFile) remove(nodes []*blockNode) error {
+	status := [1]byte{headerRecordRemoved}
+	for _, node := range nodes {
+		if node.headerRecord == 0 {
+			continue
+		}
+		offset := int64(node.headerRecord-1)*headerRecordSize + blockHdrSize
+		if _, err := f.file.WriteAt(status[:], offset); err != nil {
+			return err
+		}
+		node.headerRecord = 0
+	}
+	return nil
+}
+
+// loadHeaderFile adds the block nodes stored in the header file to the block
+// index.  The file is memory-mapped and read sequentially, and since the parent
+// of a node always precedes it, the previous node is usually its parent.
+//
+// This function MUST be called with the block index lock held (for writes) or
+// while the block index is initialized.
+func (bi *blockIndex) loadHeaderFile() error {
+	f := bi.headers
+	r, err := mmap.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var record [headerRecordSize]byte
+	var lastNode *blockNode
+	for i := uint32(0); i < f.records; i++ {
+		_, err := r.ReadAt(record[:], int64(i)*headerRecordSize)
+		if err != nil {
+			return err
+		}
+		status := record[blockHdrSize]
+		if status == headerRecordRemoved {
+			continue
+		}
+		var header wire.BlockHeader
+		err = header.Deserialize(bytes.NewReader(record[:blockHdrSize]))
+		if err != nil {
+			return err
+		}
+
+		// Determine the parent block node, which must be the genesis
+		// block for the first record.
+		var parent *blockNode
+		if lastNode == nil {
+			blockHash := header.BlockHash()
+			if !blockHash.IsEqual(bi.chainParams.GenesisHash) {
+				return AssertError(fmt.Sprintf("loadHeaderFile: "+
+					"Expected first record of the header file "+
+					"to be genesis block, found %s", blockHash))
+			}
+		} else if header.PrevBlock == lastNode.hash {
+			parent = lastNode
+		} else {
+			parent = bi.index[header.PrevBlock]
+			if parent == nil {
+				return AssertError(fmt.Sprintf("loadHeaderFile: "+
+					"Could not find parent for block %s",
+					header.BlockHash()))
+			}
+		}
+
+		node := new(blockNode)
+		initBlockNode(node, &header, parent)
+		node.status = blockStatus(status)
+		node.headerRecord = i + 1
+
+		// A block removed from the index without deleting its record
+		// and seen again afterwards is stored twice, in which case the
+		// most recent record takes precedence.
+		if existing := bi.index[node.hash]; existing != nil {
+			existing.status = node.status
+			existing.headerRecord = node.headerRecord
+			continue
+		}
+		bi.addNode(node)
+		lastNode = node
+	}
+
+	return nil
+}
+
+// flushToHeaderFile writes all dirty block nodes to the header file and stores
+// its number of records in the database.  The nodes are written in the order of
+// their heights, so the parent of a new node is always stored before it.
+//
+// This function MUST be called with the block index lock held (for writes).
+func (bi *blockIndex) flushToHeaderFile() error {
+	nodes := make([]*blockNode, 0, len(bi.dirty))
+	for node := range bi.dirty {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].height < nodes[j].height
+	})
+
+	if err := bi.headers.write(nodes); err != nil {
+		return err
+	}
+	return bi.db.Update(func(dbTx database.Tx) error {
+		return dbPutHeaderFileRecords(dbTx, bi.headers.records)
+	})
+}
+
+// moveBucketToHeaderFile moves the block nodes stored in the block index bucket
+// of the database to the header file.  This migrates the block index of an
+// existing database, and the nodes stored in the bucket by the paths which
+// write to the database directly.  The nodes not in the block index yet are
+// added to it, while the status stored in the bucket replaces the status of
+// the others since the entries of the bucket are always the most recent.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) moveBucketToHeaderFile() error {
+	bi.Lock()
+	defer bi.Unlock()
+
+	var keys [][]byte
+	err := bi.db.View(func(dbTx database.Tx) error {
+		blockIndexBucket := dbTx.Metadata().Bucket(blockIndexBucketName)
+		if blockIndexBucket == nil {
+			return nil
+		}
+
+		// The entries are iterated in the order of their heights, so
+		// the parent of a node not in the index yet is either in the
+		// index already or added by a previous entry.
+		cursor := blockIndexBucket.Cursor()
+		for ok := cursor.First(); ok; ok = cursor.Next() {
+			header, status, err := deserializeBlockRow(cursor.Value())
+			if err != nil {
+				return err
+			}
+
+			blockHash := header.BlockHash()
+			node := bi.index[blockHash]
+			if node == nil {
+				var parent *blockNode
+				if !blockHash.IsEqual(bi.chainParams.GenesisHash) {
+					parent = bi.index[header.PrevBlock]
+					if parent == nil {
+						return AssertError(fmt.Sprintf(
+							"moveBucketToHeaderFile: "+
+								"Could not find parent "+
+								"for block %s",
+							blockHash))
+					}
+				}
+				node = new(blockNode)
+				initBlockNode(node, header, parent)
+				bi.addNode(node)
+			}
+			node.status = status
+			bi.dirty[node] = struct{}{}
+
+			keys = append(keys, append([]byte(nil), cursor.Key()...))
+		}
+		return nil
+	})
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	// Write the nodes to the header file before deleting them from the
+	// bucket.  They are merged again on the next start if the move is
+	// interrupted in between.
+	if err := bi.flushToHeaderFile(); err != nil {
+		return err
+	}
+	bi.dirty = make(map[*blockNode]struct{})
+
+	for batch := keys; len(batch) > 0; {
+		n := len(batch)
+		if n > headerFileMoveBatchSize {
+			n = headerFileMoveBatchSize
+		}
+		err := bi.db.Update(func(dbTx database.Tx) error {
+			blockIndexBucket := dbTx.Metadata().Bucket(blockIndexBucketName)
+			for _, key := range batch[:n] {
+				if err := blockIndexBucket.Delete(key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		batch = batch[n:]
+	}
+
+	log.Infof("Moved %d block index entries from the database to the "+
+		"header file %s", len(keys), bi.headers.path)
+	return nil
+}
+
+// deleteNodes deletes the passed block nodes, which must have been removed from
+// the block index, from the header file or the database the index is stored
+// in, so they are not loaded again.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) deleteNodes(nodes []*blockNode) error {
+	bi.Lock()
+	defer bi.Unlock()
+
+	if bi.headers != nil {
+		return bi.headers.remove(nodes)
+	}
+	return bi.db.Update(func(dbTx database.Tx) error {
+		for _, node := range nodes {
+			err := dbRemoveBlockNode(dbTx, node)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
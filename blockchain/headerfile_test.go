@@ -0,0 +1,314 @@
+package blockchain
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// openTestHeaderIndex returns a new block index stored in the header file at
+// the passed path along with the passed database.
+func openTestHeaderIndex(t *testing.T, db database.DB, params *chaincfg.Params,
+	path string) *blockIndex {
+
+	t.Helper()
+
+	headers, err := openHeaderFile(db, path)
+	if err != nil {
+		t.Fatalf("openHeaderFile: unexpected error: %v", err)
+	}
+	t.Cleanup(func() { headers.file.Close() })
+	bi := newBlockIndex(db, params)
+	bi.headers = headers
+	return bi
+}
+
+// loadTestHeaderIndex returns a new block index loaded from the header file at
+// the passed path, and ensures it holds the passed nodes with their status.
+func loadTestHeaderIndex(t *testing.T, db database.DB, params *chaincfg.Params,
+	path string, nodes []*blockNode) *blockIndex {
+
+	t.Helper()
+
+	bi := openTestHeaderIndex(t, db, params, path)
+	if err := bi.loadHeaderFile(); err != nil {
+		t.Fatalf("loadHeaderFile: unexpected error: %v", err)
+	}
+	if bi.Len() != len(nodes) {
+		t.Fatalf("loaded %d nodes, want %d", bi.Len(), len(nodes))
+	}
+	for _, node := range nodes {
+		loaded := bi.LookupNode(&node.hash)
+		if loaded == nil {
+			t.Fatalf("block %v (height %d) was not loaded",
+				node.hash, node.height)
+		}
+		if loaded.height != node.height || loaded.status != node.status {
+			t.Fatalf("block %v: got height %d and status %v, want "+
+				"%d and %v", node.hash, loaded.height,
+				loaded.status, node.height, node.status)
+		}
+	}
+	return bi
+}
+
+// TestHeaderFile ensures the block index stored in the header file is loaded
+// with the status updates and without the deleted nodes, that the records
+// appended after the last flush are discarded, and that the block index
+// stored in the database is moved to the header file.
+func TestHeaderFile(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.Create(testDbType, filepath.Join(dir, "db"),
+		blockDataNet)
+	if err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	defer db.Close()
+	err = db.Update(func(dbTx database.Tx) error {
+		_, err := dbTx.Metadata().CreateBucket(blockIndexBucketName)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unable to create the block index bucket: %v", err)
+	}
+
+	params := chaincfg.RegressionNetParams
+	path := filepath.Join(dir, "headers.dat")
+	genesis := newBlockNode(&params.GenesisBlock.Header, nil)
+	genesis.status = statusDataStored | statusValid
+	mainChain := chainedNodes(genesis, 10)
+	fork := chainedNodes(mainChain[3], 3)
+	nodes := append([]*blockNode{genesis}, mainChain...)
+
+	bi := openTestHeaderIndex(t, db, &params, path)
+	for _, node := range append(nodes, fork...) {
+		bi.AddNode(node)
+	}
+	bi.SetStatusFlags(mainChain[9], statusDataStored)
+	if err := bi.flushToDB(); err != nil {
+		t.Fatalf("flushToDB: unexpected error: %v", err)
+	}
+	loadTestHeaderIndex(t, db, &params, path, append(nodes, fork...))
+
+	// Update the status of a block and delete the fork.  The status is
+	// updated in place, so no record is appended.
+	bi.SetStatusFlags(mainChain[5], statusValidateFailed)
+	bi.RemoveNodes(fork)
+	if err := bi.deleteNodes(fork); err != nil {
+		t.Fatalf("deleteNodes: unexpected error: %v", err)
+	}
+	if err := bi.flushToDB(); err != nil {
+		t.Fatalf("flushToDB: unexpected error: %v", err)
+	}
+	if bi.headers.records != uint32(len(nodes)+len(fork)) {
+		t.Fatalf("header file has %d records, want %d",
+			bi.headers.records, len(nodes)+len(fork))
+	}
+	loadTestHeaderIndex(t, db, &params, path, nodes)
+
+	// Append a partial record, as left by an interrupted flush, which is
+	// discarded when the file is opened.
+	_, err = bi.headers.file.WriteAt(make([]byte, headerRecordSize/2),
+		int64(bi.headers.records)*headerRecordSize)
+	if err != nil {
+		t.Fatalf("unable to append to the header file: %v", err)
+	}
+	loadTestHeaderIndex(t, db, &params, path, nodes)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unable to stat the header file: %v", err)
+	}
+	if want := int64(len(nodes)+len(fork)) * headerRecordSize; fi.Size() != want {
+		t.Fatalf("header file is %d bytes long, want %d", fi.Size(),
+			want)
+	}
+
+	// Store a block index in the database and move it to a new header
+	// file.
+	err = db.Update(func(dbTx database.Tx) error {
+		err := dbTx.Metadata().Delete(headerFileRecordsKeyName)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if err := dbStoreBlockNode(dbTx, node); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to store the block index: %v", err)
+	}
+	bi = openTestHeaderIndex(t, db, &params, path)
+	if bi.headers.records != 0 {
+		t.Fatalf("header file has %d records after the block index "+
+			"was reset, want 0", bi.headers.records)
+	}
+	if err := bi.moveBucketToHeaderFile(); err != nil {
+		t.Fatalf("moveBucketToHeaderFile: unexpected error: %v", err)
+	}
+	err = db.View(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(blockIndexBucketName)
+		return bucket.ForEach(func(k, _ []byte) error {
+			t.Fatalf("block index entry %x left in the database", k)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("unable to read the block index bucket: %v", err)
+	}
+	loadTestHeaderIndex(t, db, &params, path, nodes)
+}
+
+// TestHeaderFileChain ensures a chain storing its block index in a header file
+// stores the genesis block in it and loads it again once restarted.
+func TestHeaderFileChain(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.Create(testDbType, filepath.Join(dir, "db"),
+		blockDataNet)
+	if err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	defer db.Close()
+
+	params := chaincfg.RegressionNetParams
+	path := filepath.Join(dir, "headers.dat")
+	for i := 0; i < 2; i++ {
+		chain, err := New(&Config{
+			DB:          db,
+			ChainParams: &params,
+			TimeSource:  NewMedianTime(),
+			HeaderFile:  path,
+		})
+		if err != nil {
+			t.Fatalf("New: unexpected error: %v", err)
+		}
+		if err := chain.Close(); err != nil {
+			t.Fatalf("Close: unexpected error: %v", err)
+		}
+		if chain.index.headers.records != 1 {
+			t.Fatalf("header file has %d records, want 1",
+				chain.index.headers.records)
+		}
+		if !chain.bestChain.Tip().hash.IsEqual(params.GenesisHash) {
+			t.Fatalf("unexpected tip %v", chain.bestChain.Tip().hash)
+		}
+	}
+}
+
+// TestHeaderFileBackup ensures the header file stored in the directory of the
+// database is part of its backups, that a chain restored from a backup holds
+// the block index of the backup without the records appended after it, and
+// that closing the chain closes the header file.
+func TestHeaderFileBackup(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "db")
+	db, err := database.Create(testDbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	defer db.Close()
+
+	params := chaincfg.RegressionNetParams
+	chain, err := New(&Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  NewMedianTime(),
+		HeaderFile:  filepath.Join(dbPath, "headers.dat"),
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	processBlocks := func(prev *wire.BlockHeader, height, count int32) *btcutil.Block {
+		var block *btcutil.Block
+		for i := int32(0); i < count; i++ {
+			block = newTestBlock(&params, prev, height+i)
+			_, _, err := chain.ProcessBlock(block, BFNone)
+			if err != nil {
+				t.Fatalf("ProcessBlock %d: unexpected error: %v",
+					height+i, err)
+			}
+			prev = &block.MsgBlock().Header
+		}
+		return block
+	}
+	tip := processBlocks(&params.GenesisBlock.Header, 1, 3)
+
+	var backup bytes.Buffer
+	if err := db.Backup(&backup); err != nil {
+		t.Fatalf("Backup: unexpected error: %v", err)
+	}
+
+	// The block connected after the backup is appended to the header file
+	// before the chain is closed.
+	processBlocks(&tip.MsgBlock().Header, 4, 1)
+	if err := chain.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+	if _, err := chain.index.headers.file.Stat(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("header file is still open after Close: %v", err)
+	}
+
+	restorePath := filepath.Join(dir, "restore")
+	tr := tar.NewReader(&backup)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: unexpected error: %v", err)
+		}
+		path := filepath.Join(restorePath, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("MkdirAll: unexpected error: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("ReadAll: unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("WriteFile: unexpected error: %v", err)
+		}
+	}
+
+	restoredDB, err := database.Open(testDbType, restorePath, blockDataNet)
+	if err != nil {
+		t.Fatalf("error opening restored db: %v", err)
+	}
+	defer restoredDB.Close()
+	restored, err := New(&Config{
+		DB:          restoredDB,
+		ChainParams: &params,
+		TimeSource:  NewMedianTime(),
+		HeaderFile:  filepath.Join(restorePath, "headers.dat"),
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	defer restored.Close()
+
+	if restored.index.headers.records != 4 {
+		t.Fatalf("restored header file has %d records, want 4",
+			restored.index.headers.records)
+	}
+	best := restored.BestSnapshot()
+	if best.Height != 3 || !best.Hash.IsEqual(tip.Hash()) {
+		t.Fatalf("restored tip is %v (height %d), want %v (height 3)",
+			best.Hash, best.Height, tip.Hash())
+	}
+	if restored.index.Len() != 4 {
+		t.Fatalf("restored block index has %d nodes, want 4",
+			restored.index.Len())
+	}
+}
@@ -0,0 +1,460 @@
+package indexers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/claimtrie/metadata"
+	"github.com/lbryio/lbcd/claimtrie/normalization"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+const (
+	// claimChannelIndexName is the human-readable name for the index.
+	claimChannelIndexName = "claim by channel index"
+
+	// claimChannelKeySize is the size of a serialized claim by channel
+	// index key.
+	claimChannelKeySize = change.ClaimIDSize + 4 + change.ClaimIDSize
+
+	// claimChannelEntryMinSize is the size of a serialized claim by channel
+	// index value without the claim name.
+	claimChannelEntryMinSize = chainhash.HashSize + 4
+)
+
+var (
+	// claimChannelIndexKey is the key of the claim by channel index and the
+	// db bucket used to house it.
+	claimChannelIndexKey = []byte("claimbychannelidx")
+)
+
+// ClaimChannelEntry houses the information stored in the claim by channel
+// index for a claim signed by a channel.
+type ClaimChannelEntry struct {
+	// ClaimID is the ID of the claim.
+	ClaimID change.ClaimID
+
+	// OutPoint is the current output of the claim.
+	OutPoint wire.OutPoint
+
+	// Height is the height of the block which contains OutPoint.
+	Height int32
+
+	// Name is the name of the claim as it appears in its script.
+	Name []byte
+}
+
+// -----------------------------------------------------------------------------
+// The claim by channel index consists of an entry for every unspent claim
+// output whose value is signed by a channel.  Entries are keyed by the claim ID
+// of the channel followed by the height of the output and the claim ID, so the
+// claims of a channel are found with a single range scan, ordered from the
+// oldest to the most recently created or updated.
+//
+// The signature itself isn't verified, as the claim trie doesn't verify it
+// either, so the index lists every claim which claims to be signed by a
+// channel.
+//
+// The serialized format for keys and values in the claim by channel bucket is:
+//
+//   <channel id><height><claim id> = <hash><index><name>
+//
+//   Field           Type              Size
+//   channel id      change.ClaimID    20 bytes
+//   height          uint32            4 bytes (big endian)
+//   claim id        change.ClaimID    20 bytes
+//   hash            chainhash.Hash    32 bytes
+//   index           uint32            4 bytes
+//   name            []byte            variable
+//   -----
+//   Total: 44 bytes key, 36 + len(name) bytes value
+// -----------------------------------------------------------------------------
+
+// claimChannelKey returns the key of the claim by channel index entry for the
+// passed channel and claim according to the format described above.
+func claimChannelKey(channelID change.ClaimID, height int32, claimID change.ClaimID) []byte {
+	key := make([]byte, claimChannelKeySize)
+	copy(key, channelID[:])
+	binary.BigEndian.PutUint32(key[change.ClaimIDSize:], uint32(height))
+	copy(key[change.ClaimIDSize+4:], claimID[:])
+	return key
+}
+
+// serializeClaimChannelEntry serializes the value of the passed claim by
+// channel index entry according to the format described above.
+func serializeClaimChannelEntry(entry *ClaimChannelEntry) []byte {
+	serialized := make([]byte, claimChannelEntryMinSize+len(entry.Name))
+	copy(serialized, entry.OutPoint.Hash[:])
+	byteOrder.PutUint32(serialized[chainhash.HashSize:], entry.OutPoint.Index)
+	copy(serialized[claimChannelEntryMinSize:], entry.Name)
+	return serialized
+}
+
+// deserializeClaimChannelEntry deserializes the passed serialized key and value
+// of a claim by channel index entry according to the format described above.
+func deserializeClaimChannelEntry(key, serialized []byte) (*ClaimChannelEntry, error) {
+	if len(key) != claimChannelKeySize ||
+		len(serialized) < claimChannelEntryMinSize {
+
+		return nil, errDeserialize("unexpected end of data")
+	}
+
+	var entry ClaimChannelEntry
+	entry.Height = int32(binary.BigEndian.Uint32(key[change.ClaimIDSize:]))
+	copy(entry.ClaimID[:], key[change.ClaimIDSize+4:])
+	copy(entry.OutPoint.Hash[:], serialized)
+	entry.OutPoint.Index = byteOrder.Uint32(serialized[chainhash.HashSize:])
+	entry.Name = make([]byte, len(serialized)-claimChannelEntryMinSize)
+	copy(entry.Name, serialized[claimChannelEntryMinSize:])
+	return &entry, nil
+}
+
+// dbPutClaimChannelEntry uses an existing database transaction to store the
+// claim by channel index entry for the passed channel.
+func dbPutClaimChannelEntry(dbTx database.Tx, channelID change.ClaimID, entry *ClaimChannelEntry) error {
+	bucket := dbTx.Metadata().Bucket(claimChannelIndexKey)
+	key := claimChannelKey(channelID, entry.Height, entry.ClaimID)
+	return bucket.Put(key, serializeClaimChannelEntry(entry))
+}
+
+// dbRemoveClaimChannelEntry uses an existing database transaction to remove the
+// claim by channel index entry for the passed channel and claim output.  Entries
+// for other outputs of the claim are left untouched.
+func dbRemoveClaimChannelEntry(dbTx database.Tx, channelID change.ClaimID, entry *ClaimChannelEntry) error {
+	bucket := dbTx.Metadata().Bucket(claimChannelIndexKey)
+	key := claimChannelKey(channelID, entry.Height, entry.ClaimID)
+	serialized := bucket.Get(key)
+	if len(serialized) < claimChannelEntryMinSize ||
+		!bytes.Equal(serialized[:claimChannelEntryMinSize],
+			serializeClaimChannelEntry(entry)[:claimChannelEntryMinSize]) {
+
+		return nil
+	}
+	return bucket.Delete(key)
+}
+
+// dbFetchClaimChannelEntries uses an existing database transaction to fetch the
+// claim by channel index entries for the passed channel, skipping the first
+// passed number of entries and returning up to the passed number of entries,
+// from the most recently created or updated claim to the oldest.  The total
+// number of claims signed by the channel is returned as well.
+func dbFetchClaimChannelEntries(dbTx database.Tx, channelID change.ClaimID,
+	numToSkip, numRequested uint32) ([]*ClaimChannelEntry, uint32, error) {
+
+	// Position the cursor past the last entry of the channel by seeking to
+	// the first key of the following channel, if any.
+	next := make([]byte, change.ClaimIDSize)
+	copy(next, channelID[:])
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+
+	cursor := dbTx.Metadata().Bucket(claimChannelIndexKey).Cursor()
+	var ok bool
+	if bytes.Equal(next, make([]byte, change.ClaimIDSize)) ||
+		!cursor.Seek(next) {
+
+		ok = cursor.Last()
+	} else {
+		ok = cursor.Prev()
+	}
+
+	var entries []*ClaimChannelEntry
+	var total uint32
+	for ; ok && bytes.HasPrefix(cursor.Key(), channelID[:]); ok = cursor.Prev() {
+		total++
+		if total <= numToSkip || uint32(len(entries)) >= numRequested {
+			continue
+		}
+
+		entry, err := deserializeClaimChannelEntry(cursor.Key(),
+			cursor.Value())
+		if err != nil {
+			return nil, 0, database.Error{
+				ErrorCode: database.ErrCorruption,
+				Description: fmt.Sprintf("corrupt claim by "+
+					"channel index entry for %s: %v",
+					channelID, err),
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, nil
+}
+
+// signingChannel returns the claim ID of the channel which signed the passed
+// claim value, if any.
+func signingChannel(value []byte) (change.ClaimID, bool) {
+	claim, err := metadata.Decode(value)
+	if err != nil || claim.SigningChannelID == nil {
+		return change.ClaimID{}, false
+	}
+	return *claim.SigningChannelID, true
+}
+
+// ClaimChannelIndex implements a claims by channel index.  That is to say, it
+// supports querying the unspent claims signed by a channel by the claim ID of
+// the channel.
+type ClaimChannelIndex struct {
+	db database.DB
+}
+
+// Ensure the ClaimChannelIndex type implements the Indexer interface.
+var _ Indexer = (*ClaimChannelIndex)(nil)
+
+// Ensure the ClaimChannelIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*ClaimChannelIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *ClaimChannelIndex) NeedsInputs() bool {
+	return true
+}
+
+// Init is only provided to satisfy the Indexer interface as there is nothing to
+// initialize for this index.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimChannelIndex) Init() error {
+	// Nothing to do.
+	return nil
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimChannelIndex) Key() []byte {
+	return claimChannelIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimChannelIndex) Name() string {
+	return claimChannelIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the bucket for the claim by
+// channel index.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimChannelIndex) Create(dbTx database.Tx) error {
+	_, err := dbTx.Metadata().CreateBucket(claimChannelIndexKey)
+	return err
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer removes the entries of the signed
+// claims spent in the block and adds an entry for every signed claim created or
+// updated in the block.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimChannelIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	// The claim trie normalizes names as of the height prior to the block
+	// while it is being processed, so do the same when matching updates
+	// to the claims they spend.
+	height := block.Height()
+	normHeight := height - 1
+
+	stxoIndex := 0
+	for _, tx := range block.Transactions() {
+		spent := make(map[change.ClaimID][]byte)
+		if !blockchain.IsCoinBase(tx) {
+			numTxIn := len(tx.MsgTx().TxIn)
+			txStxos := stxos[stxoIndex : stxoIndex+numTxIn]
+			stxoIndex += numTxIn
+
+			for _, sc := range spentClaims(tx, txStxos) {
+				spent[sc.id] = normalization.NormalizeIfNecessary(
+					sc.name, normHeight)
+				channelID, ok := signingChannel(sc.value)
+				if !ok {
+					continue
+				}
+				err := dbRemoveClaimChannelEntry(dbTx, channelID,
+					&ClaimChannelEntry{
+						ClaimID:  sc.id,
+						OutPoint: sc.outPoint,
+						Height:   sc.height,
+					})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for i, txOut := range tx.MsgTx().TxOut {
+			cs, err := txscript.ExtractClaimScript(txOut.PkScript)
+			if err != nil {
+				continue
+			}
+
+			op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+			var id change.ClaimID
+			switch cs.Opcode {
+			case txscript.OP_CLAIMNAME:
+				id = change.NewClaimID(op)
+			case txscript.OP_UPDATECLAIM:
+				// Updates which don't spend the claim they
+				// reference are ignored by the claim trie.
+				copy(id[:], cs.ClaimID)
+				normName := normalization.NormalizeIfNecessary(
+					cs.Name, normHeight)
+				if !bytes.Equal(spent[id], normName) {
+					continue
+				}
+				delete(spent, id)
+			default:
+				continue
+			}
+
+			channelID, ok := signingChannel(cs.Value)
+			if !ok {
+				continue
+			}
+			err = dbPutClaimChannelEntry(dbTx, channelID,
+				&ClaimChannelEntry{
+					ClaimID:  id,
+					OutPoint: op,
+					Height:   height,
+					Name:     cs.Name,
+				})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the entries of the
+// signed claims created or updated in the block and restores the entries of the
+// signed claims spent in the block.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimChannelIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	// Undo the transactions in reverse order so claims created and spent
+	// within the same block are restored correctly.
+	height := block.Height()
+	stxoIndex := len(stxos)
+	transactions := block.Transactions()
+	for txIdx := len(transactions) - 1; txIdx >= 0; txIdx-- {
+		tx := transactions[txIdx]
+		for i, txOut := range tx.MsgTx().TxOut {
+			cs, err := txscript.ExtractClaimScript(txOut.PkScript)
+			if err != nil {
+				continue
+			}
+
+			op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+			var id change.ClaimID
+			switch cs.Opcode {
+			case txscript.OP_CLAIMNAME:
+				id = change.NewClaimID(op)
+			case txscript.OP_UPDATECLAIM:
+				copy(id[:], cs.ClaimID)
+			default:
+				continue
+			}
+
+			// Only the entry of this very output is removed, so
+			// updates which were ignored when connecting the block
+			// have no effect.
+			channelID, ok := signingChannel(cs.Value)
+			if !ok {
+				continue
+			}
+			err = dbRemoveClaimChannelEntry(dbTx, channelID,
+				&ClaimChannelEntry{
+					ClaimID:  id,
+					OutPoint: op,
+					Height:   height,
+				})
+			if err != nil {
+				return err
+			}
+		}
+
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+		numTxIn := len(tx.MsgTx().TxIn)
+		stxoIndex -= numTxIn
+		txStxos := stxos[stxoIndex : stxoIndex+numTxIn]
+		for _, sc := range spentClaims(tx, txStxos) {
+			channelID, ok := signingChannel(sc.value)
+			if !ok {
+				continue
+			}
+			err := dbPutClaimChannelEntry(dbTx, channelID,
+				&ClaimChannelEntry{
+					ClaimID:  sc.id,
+					OutPoint: sc.outPoint,
+					Height:   sc.height,
+					Name:     sc.name,
+				})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ClaimsByChannel returns the claim by channel index entries of the unspent
+// claims signed by the provided channel, from the most recently created or
+// updated claim to the oldest, along with the total number of such claims.
+// The first numToSkip entries are skipped and at most numRequested entries
+// are returned, which allows paging through the claims of large channels.
+//
+// This function is safe for concurrent access.
+func (idx *ClaimChannelIndex) ClaimsByChannel(channelID change.ClaimID,
+	numToSkip, numRequested uint32) ([]*ClaimChannelEntry, uint32, error) {
+
+	var entries []*ClaimChannelEntry
+	var total uint32
+	err := idx.db.View(func(dbTx database.Tx) error {
+		var err error
+		entries, total, err = dbFetchClaimChannelEntries(dbTx, channelID,
+			numToSkip, numRequested)
+		return err
+	})
+	return entries, total, err
+}
+
+// NewClaimChannelIndex returns a new instance of an indexer that is used to
+// create a mapping of the claim IDs of channels to the unspent claims signed
+// by them.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewClaimChannelIndex(db database.DB) *ClaimChannelIndex {
+	return &ClaimChannelIndex{db: db}
+}
+
+// DropClaimChannelIndex drops the claim by channel index from the provided
+// database if it exists.
+func DropClaimChannelIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, claimChannelIndexKey, claimChannelIndexName, interrupt)
+}
@@ -0,0 +1,179 @@
+package indexers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/database"
+	_ "github.com/lbryio/lbcd/database/ffldb"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// signedClaimValue returns a claim value signed by the passed channel.
+func signedClaimValue(channelID change.ClaimID) string {
+	value := append([]byte{0x01}, channelID[:]...)
+	value = append(value, make([]byte, 64)...)
+	return string(value)
+}
+
+// TestClaimChannelIndexConnectDisconnect ensures the claim by channel index
+// tracks signed claims through creation, update to another channel and
+// abandonment, pages through the claims of a channel and restores the prior
+// state when blocks are disconnected.
+func TestClaimChannelIndexConnectDisconnect(t *testing.T) {
+	dbPath := filepath.Join(os.TempDir(), "claimchannelindex-test")
+	_ = os.RemoveAll(dbPath)
+	db, err := database.Create("ffldb", dbPath, wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewClaimChannelIndex(db)
+	if err := db.Update(idx.Create); err != nil {
+		t.Fatalf("unable to create index: %v", err)
+	}
+
+	// The channels only differ in their last byte to ensure the claims of
+	// adjacent channels don't leak into each other.
+	channel1 := change.ClaimID{0x01, 19: 0x01}
+	channel2 := change.ClaimID{0x01, 19: 0x02}
+
+	mustScript := func(script []byte, err error) []byte {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unable to create claim script: %v", err)
+		}
+		return script
+	}
+
+	// Block 10 creates two claims signed by the first channel and an
+	// unsigned claim.
+	script1 := mustScript(txscript.ClaimNameScript("one",
+		signedClaimValue(channel1)))
+	script2 := mustScript(txscript.ClaimNameScript("two",
+		signedClaimValue(channel1)))
+	tx1 := wire.NewMsgTx(wire.TxVersion)
+	tx1.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	tx1.AddTxOut(wire.NewTxOut(100, script1))
+	tx1.AddTxOut(wire.NewTxOut(100, script2))
+	tx1.AddTxOut(wire.NewTxOut(100, mustScript(txscript.ClaimNameScript(
+		"three", "\x00"))))
+	block1 := claimTestBlock(10, tx1)
+	stxos1 := []blockchain.SpentTxOut{{PkScript: []byte{txscript.OP_TRUE}}}
+	op1 := wire.OutPoint{Hash: tx1.TxHash(), Index: 0}
+	op2 := wire.OutPoint{Hash: tx1.TxHash(), Index: 1}
+	id1 := change.NewClaimID(op1)
+	id2 := change.NewClaimID(op2)
+
+	// Block 11 updates the first claim to be signed by the second channel.
+	updateScript := mustScript(txscript.ClaimUpdateScript("one", id1[:],
+		signedClaimValue(channel2)))
+	tx2 := wire.NewMsgTx(wire.TxVersion)
+	tx2.AddTxIn(wire.NewTxIn(&op1, nil, nil))
+	tx2.AddTxOut(wire.NewTxOut(100, updateScript))
+	block2 := claimTestBlock(11, tx2)
+	stxos2 := []blockchain.SpentTxOut{{PkScript: script1, Height: 10}}
+	op3 := wire.OutPoint{Hash: tx2.TxHash(), Index: 0}
+
+	// Block 12 abandons the second claim.
+	tx3 := wire.NewMsgTx(wire.TxVersion)
+	tx3.AddTxIn(wire.NewTxIn(&op2, nil, nil))
+	tx3.AddTxOut(wire.NewTxOut(90, []byte{txscript.OP_TRUE}))
+	block3 := claimTestBlock(12, tx3)
+	stxos3 := []blockchain.SpentTxOut{{PkScript: script2, Height: 10}}
+
+	created1 := &ClaimChannelEntry{ClaimID: id1, OutPoint: op1, Height: 10,
+		Name: []byte("one")}
+	created2 := &ClaimChannelEntry{ClaimID: id2, OutPoint: op2, Height: 10,
+		Name: []byte("two")}
+	updated1 := &ClaimChannelEntry{ClaimID: id1, OutPoint: op3, Height: 11,
+		Name: []byte("one")}
+
+	// Claims created in the same block are ordered by claim ID.
+	createdBoth := []*ClaimChannelEntry{created1, created2}
+	if string(id1[:]) < string(id2[:]) {
+		createdBoth = []*ClaimChannelEntry{created2, created1}
+	}
+
+	checkEntries := func(step string, channelID change.ClaimID,
+		want []*ClaimChannelEntry) {
+
+		t.Helper()
+		got, total, err := idx.ClaimsByChannel(channelID, 0, 10)
+		if err != nil {
+			t.Fatalf("%s: ClaimsByChannel: unexpected error: %v",
+				step, err)
+		}
+		if !reflect.DeepEqual(got, want) || total != uint32(len(want)) {
+			t.Fatalf("%s: mismatched entries for %s: got %v "+
+				"(total %d), want %v", step, channelID, got,
+				total, want)
+		}
+	}
+
+	tests := []struct {
+		block *btcutil.Block
+		stxos []blockchain.SpentTxOut
+		want1 []*ClaimChannelEntry
+		want2 []*ClaimChannelEntry
+	}{
+		{block1, stxos1, createdBoth, nil},
+		{block2, stxos2, []*ClaimChannelEntry{created2},
+			[]*ClaimChannelEntry{updated1}},
+		{block3, stxos3, nil, []*ClaimChannelEntry{updated1}},
+	}
+	for i, test := range tests {
+		err := db.Update(func(dbTx database.Tx) error {
+			return idx.ConnectBlock(dbTx, test.block, test.stxos)
+		})
+		if err != nil {
+			t.Fatalf("ConnectBlock #%d: unexpected error: %v", i, err)
+		}
+		checkEntries("connect", channel1, test.want1)
+		checkEntries("connect", channel2, test.want2)
+
+		// Page through the claims of the first channel one at a
+		// time.
+		if i != 0 {
+			continue
+		}
+		for skip, want := range createdBoth {
+			got, total, err := idx.ClaimsByChannel(channel1,
+				uint32(skip), 1)
+			if err != nil {
+				t.Fatalf("ClaimsByChannel: unexpected error: %v",
+					err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], want) ||
+				total != 2 {
+
+				t.Fatalf("mismatched page %d: got %v (total "+
+					"%d), want %v", skip, got, total, want)
+			}
+		}
+	}
+
+	for i := len(tests) - 1; i >= 0; i-- {
+		test := tests[i]
+		err := db.Update(func(dbTx database.Tx) error {
+			return idx.DisconnectBlock(dbTx, test.block, test.stxos)
+		})
+		if err != nil {
+			t.Fatalf("DisconnectBlock #%d: unexpected error: %v", i, err)
+		}
+		var want1, want2 []*ClaimChannelEntry
+		if i > 0 {
+			want1, want2 = tests[i-1].want1, tests[i-1].want2
+		}
+		checkEntries("disconnect", channel1, want1)
+		checkEntries("disconnect", channel2, want2)
+	}
+}
@@ -0,0 +1,336 @@
+package indexers
+
+import (
+	"errors"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie/normalization"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+	"github.com/lbryio/lbcutil/gcs/builder"
+)
+
+const (
+	// claimFilterIndexName is the human-readable name for the index.
+	claimFilterIndexName = "claim name filter index"
+)
+
+var (
+	// claimFilterIndexParentBucketKey is the name of the parent bucket
+	// used to house the index.  The rest of the buckets live below this
+	// bucket.
+	claimFilterIndexParentBucketKey = []byte("claimfilteridxparentbucket")
+
+	// claimFilterKey is the name of the db bucket used to house the index
+	// of block hashes to claim name filters.
+	claimFilterKey = []byte("claimfilterbyhashidx")
+
+	// claimFilterHeaderKey is the name of the db bucket used to house the
+	// index of block hashes to claim name filter headers.
+	claimFilterHeaderKey = []byte("claimfilterheaderbyhashidx")
+
+	// claimFilterHashKey is the name of the db bucket used to house the
+	// index of block hashes to claim name filter hashes.
+	claimFilterHashKey = []byte("claimfilterhashbyhashidx")
+
+	// errUnsupportedClaimFilter is returned when querying the claim name
+	// filter index for another type of filter.
+	errUnsupportedClaimFilter = errors.New("unsupported filter type")
+)
+
+// -----------------------------------------------------------------------------
+// The claim name filter index consists of a BIP158-style filter for every block
+// of the main chain, which matches the normalized names of the claims touched
+// by the block.  A name is touched when an output of the block claims, updates
+// or supports it, or when an input of the block spends an output which did.
+// The filters are keyed by the hash of the block like the committed filters,
+// and are chained by filter headers the same way, so light clients only
+// interested in some claims can skip the blocks which don't touch them.
+//
+// The names are normalized as of the height prior to the block like the claim
+// trie does while processing the block.
+// -----------------------------------------------------------------------------
+
+// dbFetchClaimFilterIdxEntry retrieves a data blob from the passed bucket of
+// the claim name filter index.  An entry's absence is not considered an error.
+func dbFetchClaimFilterIdxEntry(dbTx database.Tx, key []byte, h *chainhash.Hash) []byte {
+	idx := dbTx.Metadata().Bucket(claimFilterIndexParentBucketKey).Bucket(key)
+	return idx.Get(h[:])
+}
+
+// dbStoreClaimFilterIdxEntry stores a data blob in the passed bucket of the
+// claim name filter index.
+func dbStoreClaimFilterIdxEntry(dbTx database.Tx, key []byte, h *chainhash.Hash, f []byte) error {
+	idx := dbTx.Metadata().Bucket(claimFilterIndexParentBucketKey).Bucket(key)
+	return idx.Put(h[:], f)
+}
+
+// dbDeleteClaimFilterIdxEntry deletes a data blob from the passed bucket of the
+// claim name filter index.
+func dbDeleteClaimFilterIdxEntry(dbTx database.Tx, key []byte, h *chainhash.Hash) error {
+	idx := dbTx.Metadata().Bucket(claimFilterIndexParentBucketKey).Bucket(key)
+	return idx.Delete(h[:])
+}
+
+// touchedClaimNames returns the distinct normalized names of the claims touched
+// by the passed block using the provided spent outputs.
+func touchedClaimNames(block *btcutil.Block, stxos []blockchain.SpentTxOut) [][]byte {
+	normHeight := block.Height() - 1
+
+	var names [][]byte
+	seen := make(map[string]struct{})
+	addName := func(script []byte) {
+		cs, err := txscript.ExtractClaimScript(script)
+		if err != nil {
+			return
+		}
+		name := normalization.NormalizeIfNecessary(cs.Name, normHeight)
+		if _, ok := seen[string(name)]; ok {
+			return
+		}
+		seen[string(name)] = struct{}{}
+		names = append(names, name)
+	}
+
+	for i := range stxos {
+		addName(stxos[i].PkScript)
+	}
+	for _, tx := range block.Transactions() {
+		for _, txOut := range tx.MsgTx().TxOut {
+			addName(txOut.PkScript)
+		}
+	}
+	return names
+}
+
+// ClaimFilterIndex implements a claim name filter by hash index.  That is to
+// say, it supports querying the filter of the normalized names of the claims
+// touched by each block of the main chain.
+type ClaimFilterIndex struct {
+	db database.DB
+}
+
+// Ensure the ClaimFilterIndex type implements the Indexer interface.
+var _ Indexer = (*ClaimFilterIndex)(nil)
+
+// Ensure the ClaimFilterIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*ClaimFilterIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *ClaimFilterIndex) NeedsInputs() bool {
+	return true
+}
+
+// Init is only provided to satisfy the Indexer interface as there is nothing to
+// initialize for this index.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimFilterIndex) Init() error {
+	// Nothing to do.
+	return nil
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimFilterIndex) Key() []byte {
+	return claimFilterIndexParentBucketKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimFilterIndex) Name() string {
+	return claimFilterIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the buckets for the filters,
+// the filter headers and the filter hashes.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimFilterIndex) Create(dbTx database.Tx) error {
+	parent, err := dbTx.Metadata().CreateBucket(claimFilterIndexParentBucketKey)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range [][]byte{claimFilterKey, claimFilterHeaderKey,
+		claimFilterHashKey} {
+
+		if _, err := parent.CreateBucket(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer stores the filter of the claim
+// names touched by the block along with its hash and header.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimFilterIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	b := builder.WithKeyHash(block.Hash())
+	for _, name := range touchedClaimNames(block, stxos) {
+		b.AddEntry(name)
+	}
+	f, err := b.Build()
+	if err != nil {
+		return err
+	}
+
+	h := block.Hash()
+	filterBytes, err := f.NBytes()
+	if err != nil {
+		return err
+	}
+	err = dbStoreClaimFilterIdxEntry(dbTx, claimFilterKey, h, filterBytes)
+	if err != nil {
+		return err
+	}
+
+	filterHash, err := builder.GetFilterHash(f)
+	if err != nil {
+		return err
+	}
+	err = dbStoreClaimFilterIdxEntry(dbTx, claimFilterHashKey, h, filterHash[:])
+	if err != nil {
+		return err
+	}
+
+	// Chain the header of the filter to the one of the previous block.
+	prevHeader := zeroHash
+	ph := &block.MsgBlock().Header.PrevBlock
+	if !ph.IsEqual(&zeroHash) {
+		pfh := dbFetchClaimFilterIdxEntry(dbTx, claimFilterHeaderKey, ph)
+		if err := prevHeader.SetBytes(pfh); err != nil {
+			return err
+		}
+	}
+	fh, err := builder.MakeHeaderForFilter(f, prevHeader)
+	if err != nil {
+		return err
+	}
+	return dbStoreClaimFilterIdxEntry(dbTx, claimFilterHeaderKey, h, fh[:])
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the filter of the
+// block along with its hash and header.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimFilterIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	_ []blockchain.SpentTxOut) error {
+
+	for _, key := range [][]byte{claimFilterKey, claimFilterHeaderKey,
+		claimFilterHashKey} {
+
+		err := dbDeleteClaimFilterIdxEntry(dbTx, key, block.Hash())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entriesByBlockHashes batch fetches the entries of the passed bucket of the
+// index for a slice of block hashes.
+func (idx *ClaimFilterIndex) entriesByBlockHashes(key []byte,
+	filterType wire.FilterType, blockHashes []*chainhash.Hash) ([][]byte, error) {
+
+	if filterType != wire.GCSFilterClaimName {
+		return nil, errUnsupportedClaimFilter
+	}
+
+	entries := make([][]byte, 0, len(blockHashes))
+	err := idx.db.View(func(dbTx database.Tx) error {
+		for _, blockHash := range blockHashes {
+			entries = append(entries, dbFetchClaimFilterIdxEntry(dbTx,
+				key, blockHash))
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// entryByBlockHash fetches the entry of the passed bucket of the index for a
+// block hash.
+func (idx *ClaimFilterIndex) entryByBlockHash(key []byte,
+	filterType wire.FilterType, h *chainhash.Hash) ([]byte, error) {
+
+	entries, err := idx.entriesByBlockHashes(key, filterType,
+		[]*chainhash.Hash{h})
+	if err != nil {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+// FilterByBlockHash returns the serialized contents of a block's claim name
+// filter.
+func (idx *ClaimFilterIndex) FilterByBlockHash(h *chainhash.Hash,
+	filterType wire.FilterType) ([]byte, error) {
+	return idx.entryByBlockHash(claimFilterKey, filterType, h)
+}
+
+// FiltersByBlockHashes returns the serialized contents of a block's claim name
+// filter for a set of blocks by hash.
+func (idx *ClaimFilterIndex) FiltersByBlockHashes(blockHashes []*chainhash.Hash,
+	filterType wire.FilterType) ([][]byte, error) {
+	return idx.entriesByBlockHashes(claimFilterKey, filterType, blockHashes)
+}
+
+// FilterHeaderByBlockHash returns the serialized contents of a block's claim
+// name filter header.
+func (idx *ClaimFilterIndex) FilterHeaderByBlockHash(h *chainhash.Hash,
+	filterType wire.FilterType) ([]byte, error) {
+	return idx.entryByBlockHash(claimFilterHeaderKey, filterType, h)
+}
+
+// FilterHeadersByBlockHashes returns the serialized contents of a block's
+// claim name filter header for a set of blocks by hash.
+func (idx *ClaimFilterIndex) FilterHeadersByBlockHashes(blockHashes []*chainhash.Hash,
+	filterType wire.FilterType) ([][]byte, error) {
+	return idx.entriesByBlockHashes(claimFilterHeaderKey, filterType, blockHashes)
+}
+
+// FilterHashByBlockHash returns the serialized contents of a block's claim name
+// filter hash.
+func (idx *ClaimFilterIndex) FilterHashByBlockHash(h *chainhash.Hash,
+	filterType wire.FilterType) ([]byte, error) {
+	return idx.entryByBlockHash(claimFilterHashKey, filterType, h)
+}
+
+// FilterHashesByBlockHashes returns the serialized contents of a block's claim
+// name filter hash for a set of blocks by hash.
+func (idx *ClaimFilterIndex) FilterHashesByBlockHashes(blockHashes []*chainhash.Hash,
+	filterType wire.FilterType) ([][]byte, error) {
+	return idx.entriesByBlockHashes(claimFilterHashKey, filterType, blockHashes)
+}
+
+// NewClaimFilterIndex returns a new instance of an indexer that is used to
+// create a mapping of the hashes of all blocks in the blockchain to the filters
+// of the claim names they touch.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewClaimFilterIndex(db database.DB) *ClaimFilterIndex {
+	return &ClaimFilterIndex{db: db}
+}
+
+// DropClaimFilterIndex drops the claim name filter index from the provided
+// database if it exists.
+func DropClaimFilterIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, claimFilterIndexParentBucketKey, claimFilterIndexName,
+		interrupt)
+}
@@ -0,0 +1,118 @@
+package indexers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/database"
+	_ "github.com/lbryio/lbcd/database/ffldb"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	"github.com/lbryio/lbcutil/gcs"
+	"github.com/lbryio/lbcutil/gcs/builder"
+)
+
+// TestClaimFilterIndexConnectDisconnect ensures the claim name filter of a
+// block matches the names claimed by its outputs and spent by its inputs, and
+// that the filter is removed when the block is disconnected.
+func TestClaimFilterIndexConnectDisconnect(t *testing.T) {
+	dbPath := filepath.Join(os.TempDir(), "claimfilterindex-test")
+	_ = os.RemoveAll(dbPath)
+	db, err := database.Create("ffldb", dbPath, wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewClaimFilterIndex(db)
+	if err := db.Update(idx.Create); err != nil {
+		t.Fatalf("unable to create index: %v", err)
+	}
+
+	nameScript, err := txscript.ClaimNameScript("spent", "value")
+	if err != nil {
+		t.Fatalf("unable to create claim script: %v", err)
+	}
+	claimScript, err := txscript.ClaimNameScript("claimed", "value")
+	if err != nil {
+		t.Fatalf("unable to create claim script: %v", err)
+	}
+
+	// The block spends a claim of one name and claims another.
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(100, claimScript))
+	block := claimTestBlock(10, tx)
+	stxos := []blockchain.SpentTxOut{{PkScript: nameScript, Height: 9}}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.ConnectBlock(dbTx, block, stxos)
+	})
+	if err != nil {
+		t.Fatalf("ConnectBlock: unexpected error: %v", err)
+	}
+
+	filterBytes, err := idx.FilterByBlockHash(block.Hash(),
+		wire.GCSFilterClaimName)
+	if err != nil {
+		t.Fatalf("FilterByBlockHash: unexpected error: %v", err)
+	}
+	filter, err := gcs.FromNBytes(builder.DefaultP, builder.DefaultM,
+		filterBytes)
+	if err != nil {
+		t.Fatalf("unable to deserialize filter: %v", err)
+	}
+	key := builder.DeriveKey(block.Hash())
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{"spent", true},
+		{"claimed", true},
+		{"other", false},
+	} {
+		got, err := filter.Match(key, []byte(test.name))
+		if err != nil {
+			t.Fatalf("Match: unexpected error: %v", err)
+		}
+		if got != test.want {
+			t.Fatalf("Match(%q): got %v, want %v", test.name, got,
+				test.want)
+		}
+	}
+
+	header, err := idx.FilterHeaderByBlockHash(block.Hash(),
+		wire.GCSFilterClaimName)
+	if err != nil || len(header) == 0 {
+		t.Fatalf("FilterHeaderByBlockHash: missing header: %v", err)
+	}
+	wantHeader, err := builder.MakeHeaderForFilter(filter, zeroHash)
+	if err != nil {
+		t.Fatalf("unable to make filter header: %v", err)
+	}
+	if string(header) != string(wantHeader[:]) {
+		t.Fatalf("mismatched filter header: got %x, want %x", header,
+			wantHeader[:])
+	}
+
+	_, err = idx.FilterByBlockHash(block.Hash(), wire.GCSFilterRegular)
+	if err == nil {
+		t.Fatal("FilterByBlockHash: expected error for regular filter")
+	}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.DisconnectBlock(dbTx, block, stxos)
+	})
+	if err != nil {
+		t.Fatalf("DisconnectBlock: unexpected error: %v", err)
+	}
+	filterBytes, err = idx.FilterByBlockHash(block.Hash(),
+		wire.GCSFilterClaimName)
+	if err != nil || filterBytes != nil {
+		t.Fatalf("FilterByBlockHash: got %x, %v after disconnect",
+			filterBytes, err)
+	}
+}
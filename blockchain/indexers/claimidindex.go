@@ -0,0 +1,418 @@
+package indexers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/claimtrie/normalization"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+const (
+	// claimIDIndexName is the human-readable name for the index.
+	claimIDIndexName = "claim id index"
+
+	// claimIDEntryMinSize is the size of a serialized claim ID index entry
+	// without the claim name.
+	claimIDEntryMinSize = chainhash.HashSize + 4 + 4 + 1
+)
+
+var (
+	// claimIDIndexKey is the key of the claim ID index and the db bucket
+	// used to house it.
+	claimIDIndexKey = []byte("claimbyididx")
+)
+
+// ClaimIDStatus describes the state of the most recent output of a claim.
+type ClaimIDStatus byte
+
+const (
+	// ClaimIDStatusActive indicates the current output of the claim is
+	// unspent.
+	ClaimIDStatusActive ClaimIDStatus = iota
+
+	// ClaimIDStatusAbandoned indicates the current output of the claim has
+	// been spent without being updated.
+	ClaimIDStatusAbandoned
+)
+
+// String returns the ClaimIDStatus as a human-readable name.
+func (s ClaimIDStatus) String() string {
+	switch s {
+	case ClaimIDStatusActive:
+		return "active"
+	case ClaimIDStatusAbandoned:
+		return "abandoned"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(s))
+	}
+}
+
+// ClaimIDEntry houses the information stored in the claim ID index for a
+// claim.
+type ClaimIDEntry struct {
+	// OutPoint is the most recent output of the claim.
+	OutPoint wire.OutPoint
+
+	// Height is the height of the block which contains OutPoint.
+	Height int32
+
+	// Status is the state of OutPoint.
+	Status ClaimIDStatus
+
+	// Name is the name of the claim as it appears in its script.
+	Name []byte
+}
+
+// -----------------------------------------------------------------------------
+// The claim ID index consists of an entry for every claim that has ever been
+// created in the main chain.  Each entry is keyed by the claim ID and tracks
+// the most recent output of the claim along with its name, so a claim can be
+// found without knowing its name or walking the claim trie.
+//
+// The serialized format for keys and values in the claim ID bucket is:
+//
+//   <claim id> = <hash><index><height><status><name>
+//
+//   Field           Type              Size
+//   claim id        change.ClaimID    20 bytes
+//   hash            chainhash.Hash    32 bytes
+//   index           uint32            4 bytes
+//   height          uint32            4 bytes
+//   status          ClaimIDStatus     1 byte
+//   name            []byte            variable
+//   -----
+//   Total: 41 + len(name) bytes
+// -----------------------------------------------------------------------------
+
+// serializeClaimIDEntry serializes the passed claim ID index entry according to
+// the format described above.
+func serializeClaimIDEntry(entry *ClaimIDEntry) []byte {
+	serialized := make([]byte, claimIDEntryMinSize+len(entry.Name))
+	copy(serialized, entry.OutPoint.Hash[:])
+	offset := chainhash.HashSize
+	byteOrder.PutUint32(serialized[offset:], entry.OutPoint.Index)
+	offset += 4
+	byteOrder.PutUint32(serialized[offset:], uint32(entry.Height))
+	offset += 4
+	serialized[offset] = byte(entry.Status)
+	copy(serialized[offset+1:], entry.Name)
+	return serialized
+}
+
+// deserializeClaimIDEntry deserializes the passed serialized claim ID index
+// entry according to the format described above.
+func deserializeClaimIDEntry(serialized []byte) (*ClaimIDEntry, error) {
+	if len(serialized) < claimIDEntryMinSize {
+		return nil, errDeserialize("unexpected end of data")
+	}
+
+	var entry ClaimIDEntry
+	copy(entry.OutPoint.Hash[:], serialized)
+	offset := chainhash.HashSize
+	entry.OutPoint.Index = byteOrder.Uint32(serialized[offset:])
+	offset += 4
+	entry.Height = int32(byteOrder.Uint32(serialized[offset:]))
+	offset += 4
+	entry.Status = ClaimIDStatus(serialized[offset])
+	entry.Name = make([]byte, len(serialized)-claimIDEntryMinSize)
+	copy(entry.Name, serialized[offset+1:])
+	return &entry, nil
+}
+
+// dbPutClaimIDEntry uses an existing database transaction to store the claim
+// ID index entry for the passed claim ID.
+func dbPutClaimIDEntry(dbTx database.Tx, id change.ClaimID, entry *ClaimIDEntry) error {
+	bucket := dbTx.Metadata().Bucket(claimIDIndexKey)
+	return bucket.Put(id[:], serializeClaimIDEntry(entry))
+}
+
+// dbFetchClaimIDEntry uses an existing database transaction to fetch the claim
+// ID index entry for the passed claim ID.  When there is no entry for the
+// provided claim ID, nil will be returned for both the entry and the error.
+func dbFetchClaimIDEntry(dbTx database.Tx, id change.ClaimID) (*ClaimIDEntry, error) {
+	serialized := dbTx.Metadata().Bucket(claimIDIndexKey).Get(id[:])
+	if serialized == nil {
+		return nil, nil
+	}
+
+	entry, err := deserializeClaimIDEntry(serialized)
+	if err != nil {
+		return nil, database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: fmt.Sprintf("corrupt claim ID index "+
+				"entry for %s: %v", id, err),
+		}
+	}
+	return entry, nil
+}
+
+// dbRemoveClaimIDEntry uses an existing database transaction to remove the
+// claim ID index entry for the passed claim ID.
+func dbRemoveClaimIDEntry(dbTx database.Tx, id change.ClaimID) error {
+	return dbTx.Metadata().Bucket(claimIDIndexKey).Delete(id[:])
+}
+
+// ClaimIDIndex implements a claim by claim ID index.  That is to say, it
+// supports querying the most recent output and name of every claim by its
+// claim ID.
+type ClaimIDIndex struct {
+	db database.DB
+}
+
+// Ensure the ClaimIDIndex type implements the Indexer interface.
+var _ Indexer = (*ClaimIDIndex)(nil)
+
+// Ensure the ClaimIDIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*ClaimIDIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *ClaimIDIndex) NeedsInputs() bool {
+	return true
+}
+
+// Init is only provided to satisfy the Indexer interface as there is nothing to
+// initialize for this index.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimIDIndex) Init() error {
+	// Nothing to do.
+	return nil
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimIDIndex) Key() []byte {
+	return claimIDIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimIDIndex) Name() string {
+	return claimIDIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the bucket for the claim ID
+// index.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimIDIndex) Create(dbTx database.Tx) error {
+	_, err := dbTx.Metadata().CreateBucket(claimIDIndexKey)
+	return err
+}
+
+// spentClaim describes a claim output spent by a transaction.
+type spentClaim struct {
+	id       change.ClaimID
+	outPoint wire.OutPoint
+	height   int32
+	name     []byte
+	value    []byte
+}
+
+// spentClaims returns the claims spent by the inputs of the passed transaction
+// using the provided spent outputs, which must start with the first input of
+// the transaction.
+func spentClaims(tx *btcutil.Tx, stxos []blockchain.SpentTxOut) []spentClaim {
+	var spent []spentClaim
+	for i, txIn := range tx.MsgTx().TxIn {
+		stxo := &stxos[i]
+		cs, err := txscript.ExtractClaimScript(stxo.PkScript)
+		if err != nil {
+			continue
+		}
+
+		var id change.ClaimID
+		switch cs.Opcode {
+		case txscript.OP_CLAIMNAME:
+			id = change.NewClaimID(txIn.PreviousOutPoint)
+		case txscript.OP_UPDATECLAIM:
+			copy(id[:], cs.ClaimID)
+		default:
+			continue
+		}
+
+		spent = append(spent, spentClaim{
+			id:       id,
+			outPoint: txIn.PreviousOutPoint,
+			height:   stxo.Height,
+			name:     cs.Name,
+			value:    cs.Value,
+		})
+	}
+	return spent
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer adds an entry for every claim
+// created in the block, moves updated claims to their new output and marks
+// claims which were spent without an update as abandoned.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimIDIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	// The claim trie normalizes names as of the height prior to the block
+	// while it is being processed, so do the same when matching updates
+	// to the claims they spend.
+	height := block.Height()
+	normHeight := height - 1
+
+	stxoIndex := 0
+	for _, tx := range block.Transactions() {
+		spent := make(map[change.ClaimID][]byte)
+		if !blockchain.IsCoinBase(tx) {
+			numTxIn := len(tx.MsgTx().TxIn)
+			txStxos := stxos[stxoIndex : stxoIndex+numTxIn]
+			stxoIndex += numTxIn
+
+			for _, sc := range spentClaims(tx, txStxos) {
+				spent[sc.id] = normalization.NormalizeIfNecessary(
+					sc.name, normHeight)
+				err := dbPutClaimIDEntry(dbTx, sc.id, &ClaimIDEntry{
+					OutPoint: sc.outPoint,
+					Height:   sc.height,
+					Status:   ClaimIDStatusAbandoned,
+					Name:     sc.name,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for i, txOut := range tx.MsgTx().TxOut {
+			cs, err := txscript.ExtractClaimScript(txOut.PkScript)
+			if err != nil {
+				continue
+			}
+
+			op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+			var id change.ClaimID
+			switch cs.Opcode {
+			case txscript.OP_CLAIMNAME:
+				id = change.NewClaimID(op)
+			case txscript.OP_UPDATECLAIM:
+				// Updates which don't spend the claim they
+				// reference are ignored by the claim trie.
+				copy(id[:], cs.ClaimID)
+				normName := normalization.NormalizeIfNecessary(
+					cs.Name, normHeight)
+				if !bytes.Equal(spent[id], normName) {
+					continue
+				}
+				delete(spent, id)
+			default:
+				continue
+			}
+
+			err = dbPutClaimIDEntry(dbTx, id, &ClaimIDEntry{
+				OutPoint: op,
+				Height:   height,
+				Status:   ClaimIDStatusActive,
+				Name:     cs.Name,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the entries for
+// claims created in the block and moves every claim spent in the block back
+// to the output it had prior to the block.
+//
+// This is part of the Indexer interface.
+func (idx *ClaimIDIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	// Undo the transactions in reverse order so claims created and spent
+	// within the same block are restored correctly.
+	stxoIndex := len(stxos)
+	transactions := block.Transactions()
+	for txIdx := len(transactions) - 1; txIdx >= 0; txIdx-- {
+		tx := transactions[txIdx]
+		for i, txOut := range tx.MsgTx().TxOut {
+			cs, err := txscript.ExtractClaimScript(txOut.PkScript)
+			if err != nil || cs.Opcode != txscript.OP_CLAIMNAME {
+				continue
+			}
+
+			op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+			err = dbRemoveClaimIDEntry(dbTx, change.NewClaimID(op))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Restoring the spent claims also reverts any updates made by
+		// the transaction.
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+		numTxIn := len(tx.MsgTx().TxIn)
+		stxoIndex -= numTxIn
+		txStxos := stxos[stxoIndex : stxoIndex+numTxIn]
+		for _, sc := range spentClaims(tx, txStxos) {
+			err := dbPutClaimIDEntry(dbTx, sc.id, &ClaimIDEntry{
+				OutPoint: sc.outPoint,
+				Height:   sc.height,
+				Status:   ClaimIDStatusActive,
+				Name:     sc.name,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ClaimByID returns the claim ID index entry for the provided claim ID.  When
+// there is no entry for the claim ID, nil will be returned for both the entry
+// and the error.
+//
+// This function is safe for concurrent access.
+func (idx *ClaimIDIndex) ClaimByID(id change.ClaimID) (*ClaimIDEntry, error) {
+	var entry *ClaimIDEntry
+	err := idx.db.View(func(dbTx database.Tx) error {
+		var err error
+		entry, err = dbFetchClaimIDEntry(dbTx, id)
+		return err
+	})
+	return entry, err
+}
+
+// NewClaimIDIndex returns a new instance of an indexer that is used to create a
+// mapping of the IDs of all claims in the blockchain to their most recent
+// output and name.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewClaimIDIndex(db database.DB) *ClaimIDIndex {
+	return &ClaimIDIndex{db: db}
+}
+
+// DropClaimIDIndex drops the claim ID index from the provided database if it
+// exists.
+func DropClaimIDIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, claimIDIndexKey, claimIDIndexName, interrupt)
+}
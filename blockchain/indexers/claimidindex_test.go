@@ -0,0 +1,164 @@
+package indexers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/database"
+	_ "github.com/lbryio/lbcd/database/ffldb"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestClaimIDEntrySerialization ensures claim ID index entries round trip
+// through serialization.
+func TestClaimIDEntrySerialization(t *testing.T) {
+	entry := &ClaimIDEntry{
+		OutPoint: wire.OutPoint{Hash: [32]byte{0x01, 0x02}, Index: 7},
+		Height:   123456,
+		Status:   ClaimIDStatusAbandoned,
+		Name:     []byte("lbry"),
+	}
+
+	got, err := deserializeClaimIDEntry(serializeClaimIDEntry(entry))
+	if err != nil {
+		t.Fatalf("deserializeClaimIDEntry: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, entry) {
+		t.Fatalf("mismatched entry: got %+v, want %+v", got, entry)
+	}
+
+	_, err = deserializeClaimIDEntry(make([]byte, claimIDEntryMinSize-1))
+	if !isDeserializeErr(err) {
+		t.Fatalf("deserializeClaimIDEntry: unexpected error for short "+
+			"data: %v", err)
+	}
+}
+
+// claimTestBlock returns a block at the passed height which contains a
+// coinbase followed by the provided transactions.
+func claimTestBlock(height int32, txns ...*wire.MsgTx) *btcutil.Block {
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		[]byte{byte(height), 0x51}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(1, []byte{txscript.OP_TRUE}))
+
+	msgBlock := wire.NewMsgBlock(&wire.BlockHeader{})
+	_ = msgBlock.AddTransaction(coinbase)
+	for _, tx := range txns {
+		_ = msgBlock.AddTransaction(tx)
+	}
+	block := btcutil.NewBlock(msgBlock)
+	block.SetHeight(height)
+	return block
+}
+
+// TestClaimIDIndexConnectDisconnect ensures the claim ID index tracks claims
+// through creation, update and abandonment and restores the prior state when
+// blocks are disconnected.
+func TestClaimIDIndexConnectDisconnect(t *testing.T) {
+	dbPath := filepath.Join(os.TempDir(), "claimidindex-test")
+	_ = os.RemoveAll(dbPath)
+	db, err := database.Create("ffldb", dbPath, wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewClaimIDIndex(db)
+	if err := db.Update(idx.Create); err != nil {
+		t.Fatalf("unable to create index: %v", err)
+	}
+
+	nameScript, err := txscript.ClaimNameScript("test", "value")
+	if err != nil {
+		t.Fatalf("unable to create claim script: %v", err)
+	}
+
+	// Block 10 creates the claim.
+	tx1 := wire.NewMsgTx(wire.TxVersion)
+	tx1.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	tx1.AddTxOut(wire.NewTxOut(100, nameScript))
+	block1 := claimTestBlock(10, tx1)
+	stxos1 := []blockchain.SpentTxOut{{PkScript: []byte{txscript.OP_TRUE}}}
+	op1 := wire.OutPoint{Hash: tx1.TxHash(), Index: 0}
+	id := change.NewClaimID(op1)
+
+	// Block 11 updates the claim.
+	updateScript, err := txscript.ClaimUpdateScript("test", id[:], "new")
+	if err != nil {
+		t.Fatalf("unable to create update script: %v", err)
+	}
+	tx2 := wire.NewMsgTx(wire.TxVersion)
+	tx2.AddTxIn(wire.NewTxIn(&op1, nil, nil))
+	tx2.AddTxOut(wire.NewTxOut(100, updateScript))
+	block2 := claimTestBlock(11, tx2)
+	stxos2 := []blockchain.SpentTxOut{{PkScript: nameScript, Height: 10}}
+	op2 := wire.OutPoint{Hash: tx2.TxHash(), Index: 0}
+
+	// Block 12 abandons the claim.
+	tx3 := wire.NewMsgTx(wire.TxVersion)
+	tx3.AddTxIn(wire.NewTxIn(&op2, nil, nil))
+	tx3.AddTxOut(wire.NewTxOut(90, []byte{txscript.OP_TRUE}))
+	block3 := claimTestBlock(12, tx3)
+	stxos3 := []blockchain.SpentTxOut{{PkScript: updateScript, Height: 11}}
+
+	created := &ClaimIDEntry{OutPoint: op1, Height: 10,
+		Status: ClaimIDStatusActive, Name: []byte("test")}
+	updated := &ClaimIDEntry{OutPoint: op2, Height: 11,
+		Status: ClaimIDStatusActive, Name: []byte("test")}
+	abandoned := &ClaimIDEntry{OutPoint: op2, Height: 11,
+		Status: ClaimIDStatusAbandoned, Name: []byte("test")}
+
+	checkEntry := func(step string, want *ClaimIDEntry) {
+		t.Helper()
+		got, err := idx.ClaimByID(id)
+		if err != nil {
+			t.Fatalf("%s: ClaimByID: unexpected error: %v", step, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: mismatched entry: got %+v, want %+v", step,
+				got, want)
+		}
+	}
+
+	tests := []struct {
+		block *btcutil.Block
+		stxos []blockchain.SpentTxOut
+		want  *ClaimIDEntry
+	}{
+		{block1, stxos1, created},
+		{block2, stxos2, updated},
+		{block3, stxos3, abandoned},
+	}
+	for i, test := range tests {
+		err := db.Update(func(dbTx database.Tx) error {
+			return idx.ConnectBlock(dbTx, test.block, test.stxos)
+		})
+		if err != nil {
+			t.Fatalf("ConnectBlock #%d: unexpected error: %v", i, err)
+		}
+		checkEntry("connect", test.want)
+	}
+
+	for i := len(tests) - 1; i >= 0; i-- {
+		test := tests[i]
+		err := db.Update(func(dbTx database.Tx) error {
+			return idx.DisconnectBlock(dbTx, test.block, test.stxos)
+		})
+		if err != nil {
+			t.Fatalf("DisconnectBlock #%d: unexpected error: %v", i, err)
+		}
+		var want *ClaimIDEntry
+		if i > 0 {
+			want = tests[i-1].want
+		}
+		checkEntry("disconnect", want)
+	}
+}
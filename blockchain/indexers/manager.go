@@ -580,17 +580,10 @@ func dropIndex(db database.DB, idxKey []byte, idxName string, interrupt <-chan s
 		return err
 	}
 
-	// Since the indexes can be so large, attempting to simply delete
-	// the bucket in a single database transaction would result in massive
-	// memory usage and likely crash many systems due to ulimits.  In order
-	// to avoid this, use a cursor to delete a maximum number of entries out
-	// of the bucket at a time. Recurse buckets depth-first to delete any
-	// sub-buckets.
-	const maxDeletions = 2000000
-	var totalDeleted uint64
-
-	// Recurse through all buckets in the index, cataloging each for
-	// later deletion.
+	// Since the indexes can be so large, the entries of each bucket are
+	// deleted as a range, which doesn't require loading them in memory.
+	// Recurse through all buckets in the index depth-first, cataloging
+	// each for later deletion.
 	var subBuckets [][][]byte
 	var subBucketClosure func(database.Tx, []byte, [][]byte) error
 	subBucketClosure = func(dbTx database.Tx,
@@ -626,35 +619,18 @@ func dropIndex(db database.DB, idxKey []byte, idxName string, interrupt <-chan s
 	// all keys inside them and then dropping the buckets themselves.
 	for i := range subBuckets {
 		bucketName := subBuckets[len(subBuckets)-1-i]
-		// Delete maxDeletions key/value pairs at a time.
-		for numDeleted := maxDeletions; numDeleted == maxDeletions; {
-			numDeleted = 0
-			err := db.Update(func(dbTx database.Tx) error {
-				subBucket := dbTx.Metadata()
-				for _, subBucketName := range bucketName {
-					subBucket = subBucket.Bucket(subBucketName)
-				}
-				cursor := subBucket.Cursor()
-				for ok := cursor.First(); ok; ok = cursor.Next() &&
-					numDeleted < maxDeletions {
-
-					if err := cursor.Delete(); err != nil {
-						return err
-					}
-					numDeleted++
-				}
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-
-			if numDeleted > 0 {
-				totalDeleted += uint64(numDeleted)
-				log.Infof("Deleted %d keys (%d total) from %s",
-					numDeleted, totalDeleted, idxName)
+		err := db.Update(func(dbTx database.Tx) error {
+			subBucket := dbTx.Metadata()
+			for _, subBucketName := range bucketName {
+				subBucket = subBucket.Bucket(subBucketName)
 			}
+			return subBucket.DeleteRange(nil, nil)
+		})
+		if err != nil {
+			return err
 		}
+		log.Infof("Deleted the keys of bucket %s from %s",
+			bucketName[len(bucketName)-1], idxName)
 
 		if interruptRequested(interrupt) {
 			return errInterruptRequested
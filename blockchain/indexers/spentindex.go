@@ -0,0 +1,250 @@
+package indexers
+
+import (
+	"fmt"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+const (
+	// spentIndexName is the human-readable name for the index.
+	spentIndexName = "spent output index"
+
+	// spentIndexKeySize is the size of a key of the spent index, which is
+	// a serialized outpoint.
+	spentIndexKeySize = chainhash.HashSize + 4
+
+	// spentEntrySize is the size of a serialized spent index entry.
+	spentEntrySize = chainhash.HashSize + 4 + 4
+)
+
+var (
+	// spentIndexKey is the key of the spent index and the db bucket used
+	// to house it.
+	spentIndexKey = []byte("spentidx")
+)
+
+// SpentEntry houses the information stored in the spent index for an output.
+type SpentEntry struct {
+	// TxHash is the hash of the transaction spending the output.
+	TxHash chainhash.Hash
+
+	// InputIndex is the index of the input of the transaction spending
+	// the output.
+	InputIndex uint32
+
+	// Height is the height of the block which contains the spending
+	// transaction.
+	Height int32
+}
+
+// -----------------------------------------------------------------------------
+// The spent index consists of an entry for every output spent in the main
+// chain.  Each entry is keyed by the outpoint of the output and tracks the
+// input spending it, so the spender of an output can be found without scanning
+// the blocks following it.
+//
+// The serialized format for keys and values in the spent index bucket is:
+//
+//   <hash><index> = <spending tx hash><input index><height>
+//
+//   Field              Type              Size
+//   hash               chainhash.Hash    32 bytes
+//   index              uint32            4 bytes
+//   spending tx hash   chainhash.Hash    32 bytes
+//   input index        uint32            4 bytes
+//   height             uint32            4 bytes
+//   -----
+//   Total: 36 bytes key and 40 bytes value
+// -----------------------------------------------------------------------------
+
+// spentIndexEntryKey returns the key of the spent index entry for the passed
+// outpoint according to the format described above.
+func spentIndexEntryKey(outPoint *wire.OutPoint) []byte {
+	key := make([]byte, spentIndexKeySize)
+	copy(key, outPoint.Hash[:])
+	byteOrder.PutUint32(key[chainhash.HashSize:], outPoint.Index)
+	return key
+}
+
+// serializeSpentEntry serializes the passed spent index entry according to the
+// format described above.
+func serializeSpentEntry(entry *SpentEntry) []byte {
+	serialized := make([]byte, spentEntrySize)
+	copy(serialized, entry.TxHash[:])
+	offset := chainhash.HashSize
+	byteOrder.PutUint32(serialized[offset:], entry.InputIndex)
+	offset += 4
+	byteOrder.PutUint32(serialized[offset:], uint32(entry.Height))
+	return serialized
+}
+
+// deserializeSpentEntry deserializes the passed serialized spent index entry
+// according to the format described above.
+func deserializeSpentEntry(serialized []byte) (*SpentEntry, error) {
+	if len(serialized) < spentEntrySize {
+		return nil, errDeserialize("unexpected end of data")
+	}
+
+	var entry SpentEntry
+	copy(entry.TxHash[:], serialized)
+	offset := chainhash.HashSize
+	entry.InputIndex = byteOrder.Uint32(serialized[offset:])
+	offset += 4
+	entry.Height = int32(byteOrder.Uint32(serialized[offset:]))
+	return &entry, nil
+}
+
+// dbFetchSpentEntry uses an existing database transaction to fetch the spent
+// index entry for the passed outpoint.  When the output isn't spent in the main
+// chain, nil will be returned for both the entry and the error.
+func dbFetchSpentEntry(dbTx database.Tx, outPoint *wire.OutPoint) (*SpentEntry, error) {
+	bucket := dbTx.Metadata().Bucket(spentIndexKey)
+	serialized := bucket.Get(spentIndexEntryKey(outPoint))
+	if serialized == nil {
+		return nil, nil
+	}
+
+	entry, err := deserializeSpentEntry(serialized)
+	if err != nil {
+		return nil, database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: fmt.Sprintf("corrupt spent index entry "+
+				"for %v: %v", outPoint, err),
+		}
+	}
+	return entry, nil
+}
+
+// SpentIndex implements a spent output index.  That is to say, it supports
+// querying the input which spends each output spent in the main chain.
+type SpentIndex struct {
+	db database.DB
+}
+
+// Ensure the SpentIndex type implements the Indexer interface.
+var _ Indexer = (*SpentIndex)(nil)
+
+// Init is only provided to satisfy the Indexer interface as there is nothing to
+// initialize for this index.
+//
+// This is part of the Indexer interface.
+func (idx *SpentIndex) Init() error {
+	// Nothing to do.
+	return nil
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *SpentIndex) Key() []byte {
+	return spentIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *SpentIndex) Name() string {
+	return spentIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the bucket for the spent
+// index.
+//
+// This is part of the Indexer interface.
+func (idx *SpentIndex) Create(dbTx database.Tx) error {
+	_, err := dbTx.Metadata().CreateBucket(spentIndexKey)
+	return err
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer adds an entry for every output
+// spent by the transactions in the block.
+//
+// This is part of the Indexer interface.
+func (idx *SpentIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	bucket := dbTx.Metadata().Bucket(spentIndexKey)
+	for _, tx := range block.Transactions() {
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+
+		for i, txIn := range tx.MsgTx().TxIn {
+			entry := SpentEntry{
+				TxHash:     *tx.Hash(),
+				InputIndex: uint32(i),
+				Height:     block.Height(),
+			}
+			err := bucket.Put(spentIndexEntryKey(&txIn.PreviousOutPoint),
+				serializeSpentEntry(&entry))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the entries for the
+// outputs spent by the transactions in the block, which are unspent again.
+//
+// This is part of the Indexer interface.
+func (idx *SpentIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	bucket := dbTx.Metadata().Bucket(spentIndexKey)
+	for _, tx := range block.Transactions() {
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+
+		for _, txIn := range tx.MsgTx().TxIn {
+			err := bucket.Delete(spentIndexEntryKey(&txIn.PreviousOutPoint))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SpentBy returns the spent index entry describing the input which spends the
+// passed outpoint in the main chain.  When the output isn't spent in the main
+// chain, nil will be returned for both the entry and the error.
+//
+// This function is safe for concurrent access.
+func (idx *SpentIndex) SpentBy(outPoint *wire.OutPoint) (*SpentEntry, error) {
+	var entry *SpentEntry
+	err := idx.db.View(func(dbTx database.Tx) error {
+		var err error
+		entry, err = dbFetchSpentEntry(dbTx, outPoint)
+		return err
+	})
+	return entry, err
+}
+
+// NewSpentIndex returns a new instance of an indexer that is used to create a
+// mapping of all outputs spent in the blockchain to the inputs spending them.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewSpentIndex(db database.DB) *SpentIndex {
+	return &SpentIndex{db: db}
+}
+
+// DropSpentIndex drops the spent index from the provided database if it
+// exists.
+func DropSpentIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, spentIndexKey, spentIndexName, interrupt)
+}
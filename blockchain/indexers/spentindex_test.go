@@ -0,0 +1,122 @@
+package indexers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lbryio/lbcd/database"
+	_ "github.com/lbryio/lbcd/database/ffldb"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestSpentEntrySerialization ensures spent index entries round trip through
+// serialization.
+func TestSpentEntrySerialization(t *testing.T) {
+	entry := &SpentEntry{
+		TxHash:     [32]byte{0x01, 0x02},
+		InputIndex: 3,
+		Height:     123456,
+	}
+
+	got, err := deserializeSpentEntry(serializeSpentEntry(entry))
+	if err != nil {
+		t.Fatalf("deserializeSpentEntry: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, entry) {
+		t.Fatalf("mismatched entry: got %+v, want %+v", got, entry)
+	}
+
+	_, err = deserializeSpentEntry(make([]byte, spentEntrySize-1))
+	if !isDeserializeErr(err) {
+		t.Fatalf("deserializeSpentEntry: unexpected error for short "+
+			"data: %v", err)
+	}
+}
+
+// TestSpentIndexConnectDisconnect ensures the spent index tracks the inputs
+// spending outputs as blocks are connected and forgets them when the blocks
+// are disconnected.
+func TestSpentIndexConnectDisconnect(t *testing.T) {
+	dbPath := filepath.Join(os.TempDir(), "spentindex-test")
+	_ = os.RemoveAll(dbPath)
+	db, err := database.Create("ffldb", dbPath, wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewSpentIndex(db)
+	if err := db.Update(idx.Create); err != nil {
+		t.Fatalf("unable to create index: %v", err)
+	}
+
+	// Block 10 spends two outputs, block 11 spends the output of block 10.
+	op1 := wire.OutPoint{Hash: [32]byte{0x01}, Index: 0}
+	op2 := wire.OutPoint{Hash: [32]byte{0x02}, Index: 5}
+	tx1 := wire.NewMsgTx(wire.TxVersion)
+	tx1.AddTxIn(wire.NewTxIn(&op1, nil, nil))
+	tx1.AddTxIn(wire.NewTxIn(&op2, nil, nil))
+	tx1.AddTxOut(wire.NewTxOut(100, []byte{txscript.OP_TRUE}))
+	block1 := claimTestBlock(10, tx1)
+
+	op3 := wire.OutPoint{Hash: tx1.TxHash(), Index: 0}
+	tx2 := wire.NewMsgTx(wire.TxVersion)
+	tx2.AddTxIn(wire.NewTxIn(&op3, nil, nil))
+	tx2.AddTxOut(wire.NewTxOut(90, []byte{txscript.OP_TRUE}))
+	block2 := claimTestBlock(11, tx2)
+
+	checkEntry := func(step string, op wire.OutPoint, want *SpentEntry) {
+		t.Helper()
+		got, err := idx.SpentBy(&op)
+		if err != nil {
+			t.Fatalf("%s: SpentBy: unexpected error: %v", step, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: mismatched entry for %v: got %+v, want %+v",
+				step, op, got, want)
+		}
+	}
+
+	spent1 := &SpentEntry{TxHash: tx1.TxHash(), InputIndex: 0, Height: 10}
+	spent2 := &SpentEntry{TxHash: tx1.TxHash(), InputIndex: 1, Height: 10}
+	spent3 := &SpentEntry{TxHash: tx2.TxHash(), InputIndex: 0, Height: 11}
+
+	for _, block := range []*btcutil.Block{block1, block2} {
+		err := db.Update(func(dbTx database.Tx) error {
+			return idx.ConnectBlock(dbTx, block, nil)
+		})
+		if err != nil {
+			t.Fatalf("ConnectBlock: unexpected error: %v", err)
+		}
+	}
+	checkEntry("connect", op1, spent1)
+	checkEntry("connect", op2, spent2)
+	checkEntry("connect", op3, spent3)
+
+	// The coinbase inputs are not indexed.
+	coinbaseOp := block1.Transactions()[0].MsgTx().TxIn[0].PreviousOutPoint
+	checkEntry("connect", coinbaseOp, nil)
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.DisconnectBlock(dbTx, block2, nil)
+	})
+	if err != nil {
+		t.Fatalf("DisconnectBlock: unexpected error: %v", err)
+	}
+	checkEntry("disconnect", op1, spent1)
+	checkEntry("disconnect", op3, nil)
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.DisconnectBlock(dbTx, block1, nil)
+	})
+	if err != nil {
+		t.Fatalf("DisconnectBlock: unexpected error: %v", err)
+	}
+	checkEntry("disconnect", op1, nil)
+	checkEntry("disconnect", op2, nil)
+}
@@ -0,0 +1,109 @@
+/*
+Package muhash implements MuHash3072, a rolling hash of a set of elements where
+elements can be added and removed in any order.
+
+The hash of an element is mapped to a number modulo the 3072-bit prime
+2^3072 - 1103717.  The set is the product of the numbers of its elements, so
+adding an element multiplies it and removing one divides it.  Since
+multiplication is commutative, the final hash only depends on the elements in
+the set, which makes it suitable to hash the unspent transaction output set
+incrementally as blocks are connected and disconnected.
+
+The construction is the one used by Bitcoin Core so the hashes of identical
+sets match.
+*/
+package muhash
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// numBytes is the size of the numbers the elements are mapped to.
+const numBytes = 384
+
+// prime is the 3072-bit modulus 2^3072 - 1103717.
+var prime = func() *big.Int {
+	p := new(big.Int).Lsh(big.NewInt(1), numBytes*8)
+	return p.Sub(p, big.NewInt(1103717))
+}()
+
+// MuHash is the rolling hash of a set of elements.  The zero value is not
+// usable, use New instead.  It is not safe for concurrent access.
+type MuHash struct {
+	numerator   *big.Int
+	denominator *big.Int
+}
+
+// New returns the hash of an empty set.
+func New() *MuHash {
+	return &MuHash{
+		numerator:   big.NewInt(1),
+		denominator: big.NewInt(1),
+	}
+}
+
+// toNum maps the passed element to a number modulo the prime.  The SHA256 of
+// the element keys a ChaCha20 stream whose first 384 bytes are the little
+// endian representation of the number.
+func toNum(element []byte) *big.Int {
+	key := sha256.Sum256(element)
+	var nonce [chacha20.NonceSize]byte
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+	if err != nil {
+		// Not reachable since the key and nonce sizes are fixed.
+		panic(err)
+	}
+	var stream [numBytes]byte
+	cipher.XORKeyStream(stream[:], stream[:])
+
+	reverse(stream[:])
+	num := new(big.Int).SetBytes(stream[:])
+	if num.Cmp(prime) >= 0 {
+		num.Sub(num, prime)
+	}
+	return num
+}
+
+// Add adds the passed element to the set.
+func (m *MuHash) Add(element []byte) {
+	m.numerator.Mul(m.numerator, toNum(element))
+	m.numerator.Mod(m.numerator, prime)
+}
+
+// Remove removes the passed element from the set.  The element should have
+// been added before, otherwise the hash doesn't correspond to any set.
+func (m *MuHash) Remove(element []byte) {
+	m.denominator.Mul(m.denominator, toNum(element))
+	m.denominator.Mod(m.denominator, prime)
+}
+
+// Clone returns a copy of the hash.
+func (m *MuHash) Clone() *MuHash {
+	return &MuHash{
+		numerator:   new(big.Int).Set(m.numerator),
+		denominator: new(big.Int).Set(m.denominator),
+	}
+}
+
+// Finalize returns the 32-byte hash of the set, which is the SHA256 of the
+// little endian representation of the product of its elements.
+func (m *MuHash) Finalize() [sha256.Size]byte {
+	num := new(big.Int).ModInverse(m.denominator, prime)
+	num.Mul(num, m.numerator)
+	num.Mod(num, prime)
+
+	var serialized [numBytes]byte
+	num.FillBytes(serialized[:])
+	reverse(serialized[:])
+	return sha256.Sum256(serialized[:])
+}
+
+// reverse reverses the passed bytes in place.
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -0,0 +1,62 @@
+package muhash
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// element returns a 32-byte element whose first byte is the passed value.
+func element(i byte) []byte {
+	e := make([]byte, 32)
+	e[0] = i
+	return e
+}
+
+// TestMuHash ensures the hashes match the test vector of Bitcoin Core and that
+// they only depend on the elements of the set.
+func TestMuHash(t *testing.T) {
+	t.Parallel()
+
+	// The test vector of Bitcoin Core is displayed byte-reversed.
+	m := New()
+	m.Add(element(0))
+	m.Add(element(1))
+	m.Remove(element(2))
+	hash := m.Finalize()
+	reverse(hash[:])
+	want := "10d312b100cbd32ada024a6646e40d3482fcff103668d2625f10002a607d5863"
+	if got := hex.EncodeToString(hash[:]); got != want {
+		t.Fatalf("wrong hash - got %s, want %s", got, want)
+	}
+
+	// The order of the operations doesn't matter, and removing an element
+	// cancels its addition.
+	a := New()
+	a.Add(element(1))
+	a.Add(element(2))
+	a.Add(element(3))
+	a.Remove(element(2))
+
+	b := New()
+	b.Add(element(4))
+	b.Add(element(3))
+	clone := b.Clone()
+	b.Remove(element(4))
+	b.Add(element(1))
+	if a.Finalize() != b.Finalize() {
+		t.Fatal("hashes of the same set differ")
+	}
+
+	// Clones are independent of the original.
+	if clone.Finalize() == b.Finalize() {
+		t.Fatal("clone was modified with the original")
+	}
+
+	// The empty set has the same hash however it is reached.
+	empty := New()
+	empty.Add(element(5))
+	empty.Remove(element(5))
+	if empty.Finalize() != New().Finalize() {
+		t.Fatal("hash of emptied set differs from the empty set")
+	}
+}
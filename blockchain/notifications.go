@@ -6,6 +6,8 @@ package blockchain
 
 import (
 	"fmt"
+
+	btcutil "github.com/lbryio/lbcutil"
 )
 
 // NotificationType represents the type of a notification message.
@@ -29,6 +31,12 @@ const (
 	// NTBlockDisconnected indicates the associated block was disconnected
 	// from the main chain.
 	NTBlockDisconnected
+
+	// NTBlockInvalid indicates the associated block failed validation while
+	// it was being connected to the main chain.  Its proof of work is valid
+	// and the chain it belongs to has more work than the main chain, so it
+	// may reveal a consensus split.
+	NTBlockInvalid
 )
 
 // notificationTypeStrings is a map of notification types back to their constant
@@ -37,6 +45,7 @@ var notificationTypeStrings = map[NotificationType]string{
 	NTBlockAccepted:     "NTBlockAccepted",
 	NTBlockConnected:    "NTBlockConnected",
 	NTBlockDisconnected: "NTBlockDisconnected",
+	NTBlockInvalid:      "NTBlockInvalid",
 }
 
 // String returns the NotificationType in human-readable form.
@@ -53,11 +62,19 @@ func (n NotificationType) String() string {
 //   - NTBlockAccepted:     *btcutil.Block
 //   - NTBlockConnected:    *btcutil.Block
 //   - NTBlockDisconnected: *btcutil.Block
+//   - NTBlockInvalid:      *InvalidBlock
 type Notification struct {
 	Type NotificationType
 	Data interface{}
 }
 
+// InvalidBlock describes a block which failed validation while it was being
+// connected to the main chain along with the rule it violated.
+type InvalidBlock struct {
+	Block *btcutil.Block
+	Err   error
+}
+
 // Subscribe to block chain notifications. Registers a callback to be executed
 // when various events take place. See the documentation on Notification and
 // NotificationType for details on the types and contents of notifications.
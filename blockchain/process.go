@@ -33,6 +33,13 @@ const (
 	// checks.
 	BFNoDupBlockCheck
 
+	// BFAssumeValid may be set to indicate that the transaction scripts of
+	// the block don't have to be checked since it is already known to be
+	// an ancestor of the block which is assumed to be valid through the
+	// headers linking them.  Unlike BFFastAdd, the block is otherwise fully
+	// validated.
+	BFAssumeValid
+
 	// BFNone is a convenience value to specifically indicate no flags.
 	BFNone BehaviorFlags = 0
 )
@@ -238,8 +245,9 @@ func (b *BlockChain) ProcessBlock(block *btcutil.Block, flags BehaviorFlags) (bo
 
 	// Accept any orphan blocks that depend on this block (they are
 	// no longer orphans) and repeat for those accepted blocks until
-	// there are no more.
-	err = b.processOrphans(blockHash, flags)
+	// there are no more.  They aren't known to be ancestors of the block
+	// assumed to be valid, so their scripts are always checked.
+	err = b.processOrphans(blockHash, flags&^BFAssumeValid)
 	if err != nil {
 		return false, false, err
 	}
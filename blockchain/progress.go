@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"time"
+
+	"github.com/lbryio/lbcd/chaincfg"
+)
+
+// EstimateTotalTxns returns the expected total number of transactions of the
+// chain of the passed params at time now, given the total number of
+// transactions up to a block with the passed timestamp.  The transactions
+// after the later of the block and the chain tx data of the params are
+// assumed to follow at the rate of the chain tx data.
+func EstimateTotalTxns(params *chaincfg.Params, totalTxns uint64, blockTime,
+	now time.Time) float64 {
+
+	data := &params.ChainTxData
+	if totalTxns <= data.TxCount {
+		elapsed := now.Sub(data.Time).Seconds()
+		return float64(data.TxCount) + elapsed*data.TxRate
+	}
+	elapsed := now.Sub(blockTime).Seconds()
+	return float64(totalTxns) + elapsed*data.TxRate
+}
+
+// GuessVerificationProgress returns an estimate between 0 and 1 of the fraction
+// of the chain of the passed params verified at time now, given the total
+// number of transactions up to the last verified block and its timestamp.
+//
+// The progress is the fraction of the expected transactions of the chain which
+// are verified.  It is the fraction of the time elapsed since the genesis block
+// covered by the verified blocks for chains without tx data instead.
+func GuessVerificationProgress(params *chaincfg.Params, totalTxns uint64,
+	blockTime, now time.Time) float64 {
+
+	var progress float64
+	if params.ChainTxData.TxCount == 0 {
+		genesisTime := params.GenesisBlock.Header.Timestamp
+		total := now.Sub(genesisTime).Seconds()
+		if total <= 0 {
+			return 1
+		}
+		progress = blockTime.Sub(genesisTime).Seconds() / total
+	} else {
+		total := EstimateTotalTxns(params, totalTxns, blockTime, now)
+		if total <= 0 {
+			return 1
+		}
+		progress = float64(totalTxns) / total
+	}
+
+	switch {
+	case progress < 0:
+		return 0
+	case progress > 1:
+		return 1
+	}
+	return progress
+}
+
+// VerificationProgress returns an estimate between 0 and 1 of the fraction of
+// the chain verified up to the current best block.  See
+// GuessVerificationProgress for details.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) VerificationProgress() float64 {
+	snapshot := b.BestSnapshot()
+	blockTime := time.Unix(b.bestChain.Tip().timestamp, 0)
+	return GuessVerificationProgress(b.chainParams, snapshot.TotalTxns,
+		blockTime, time.Now())
+}
+
+// RemainingTxns returns an estimate of the number of transactions of the chain
+// left to verify after the current best block, or zero when there is no
+// estimate because the chain has no tx data.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) RemainingTxns() float64 {
+	if b.chainParams.ChainTxData.TxCount == 0 {
+		return 0
+	}
+
+	snapshot := b.BestSnapshot()
+	blockTime := time.Unix(b.bestChain.Tip().timestamp, 0)
+	total := EstimateTotalTxns(b.chainParams, snapshot.TotalTxns,
+		blockTime, time.Now())
+	if remaining := total - float64(snapshot.TotalTxns); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/chaincfg"
+)
+
+// TestGuessVerificationProgress ensures the verification progress is estimated
+// from the chain tx data of the params, or from the block timestamps when there
+// is none.
+func TestGuessVerificationProgress(t *testing.T) {
+	dataTime := time.Unix(1600000000, 0)
+	withData := chaincfg.RegressionNetParams
+	withData.ChainTxData = chaincfg.ChainTxData{
+		Time:    dataTime,
+		TxCount: 1000,
+		TxRate:  0.5,
+	}
+	genesisTime := chaincfg.RegressionNetParams.GenesisBlock.Header.Timestamp
+
+	tests := []struct {
+		name      string
+		params    *chaincfg.Params
+		totalTxns uint64
+		blockTime time.Time
+		now       time.Time
+		want      float64
+	}{{
+		name:      "before tx data",
+		params:    &withData,
+		totalTxns: 500,
+		blockTime: dataTime.Add(-time.Hour),
+		now:       dataTime.Add(1000 * time.Second),
+		want:      500.0 / 1500,
+	}, {
+		name:      "after tx data",
+		params:    &withData,
+		totalTxns: 2000,
+		blockTime: dataTime.Add(time.Hour),
+		now:       dataTime.Add(time.Hour + 4000*time.Second),
+		want:      2000.0 / 4000,
+	}, {
+		name:      "at tip",
+		params:    &withData,
+		totalTxns: 2000,
+		blockTime: dataTime.Add(time.Hour),
+		now:       dataTime.Add(time.Hour),
+		want:      1,
+	}, {
+		name:      "no tx data",
+		params:    &chaincfg.RegressionNetParams,
+		totalTxns: 10,
+		blockTime: genesisTime.Add(time.Hour),
+		now:       genesisTime.Add(4 * time.Hour),
+		want:      0.25,
+	}, {
+		name:      "no tx data, block from the future",
+		params:    &chaincfg.RegressionNetParams,
+		totalTxns: 10,
+		blockTime: genesisTime.Add(5 * time.Hour),
+		now:       genesisTime.Add(4 * time.Hour),
+		want:      1,
+	}}
+
+	for _, test := range tests {
+		got := GuessVerificationProgress(test.params, test.totalTxns,
+			test.blockTime, test.now)
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: unexpected progress - got %v, want %v",
+				test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/database"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// MinBlocksToKeep is the minimum number of blocks at the tip of the main chain
+// whose data is never pruned.  This matches the number of blocks a node
+// advertising NODE_NETWORK_LIMITED must be able to serve per BIP0159 and is
+// far deeper than any reorganization the chain is expected to handle.
+const MinBlocksToKeep = 288
+
+// errPruneRetained is used to roll back a prune attempt that would have
+// removed blocks which must be retained.
+var errPruneRetained = errors.New("prune would remove retained blocks")
+
+// initPruneState loads the pruning state from the database.  A database that
+// has previously been pruned can only be used with pruning enabled since the
+// deleted blocks can't be served or used to rebuild indexes.
+func (b *BlockChain) initPruneState() error {
+	var beenPruned bool
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		beenPruned, err = dbTx.BeenPruned()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !beenPruned {
+		return nil
+	}
+	if b.pruneTarget == 0 {
+		return fmt.Errorf("the block database has previously been " +
+			"pruned and pruning must remain enabled; delete the " +
+			"data directory to resync a full node")
+	}
+
+	// Find the lowest main chain block that still has data available.
+	// Blocks are written to the block files in the order they are
+	// connected, so the heights with pruned data form a contiguous range
+	// starting at the genesis block.
+	tipHeight := b.bestChain.Height()
+	err = b.db.View(func(dbTx database.Tx) error {
+		var searchErr error
+		b.pruneHeight = int32(sort.Search(int(tipHeight)+1, func(h int) bool {
+			node := b.bestChain.NodeByHeight(int32(h))
+			has, err := dbTx.HasBlock(&node.hash)
+			if err != nil {
+				searchErr = err
+			}
+			return has
+		}))
+		return searchErr
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Block data is pruned below height %d", b.pruneHeight)
+	return nil
+}
+
+// pruneBlockFiles deletes the oldest block files once their total size
+// exceeds the configured prune target.  The attempt is abandoned without
+// error if it would remove any of the most recent MinBlocksToKeep blocks of
+// the main chain, and isn't made again until the highest of them is deep
+// enough to be removed.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) pruneBlockFiles() error {
+	tipHeight := b.bestChain.Height()
+	if tipHeight < b.pruneRetryHeight {
+		return nil
+	}
+	minKeepHeight := tipHeight - MinBlocksToKeep + 1
+	newPruneHeight := b.pruneHeight
+	maxRetainedHeight := int32(-1)
+	var numPruned int
+	err := b.db.Update(func(dbTx database.Tx) error {
+		prunedHashes, err := dbTx.PruneBlocks(b.pruneTarget)
+		if err != nil {
+			return err
+		}
+
+		for i := range prunedHashes {
+			node := b.index.LookupNode(&prunedHashes[i])
+			if node == nil || !b.bestChain.Contains(node) {
+				continue
+			}
+			if node.height >= minKeepHeight &&
+				node.height > maxRetainedHeight {
+
+				maxRetainedHeight = node.height
+			}
+			if node.height >= newPruneHeight {
+				newPruneHeight = node.height + 1
+			}
+		}
+		if maxRetainedHeight != -1 {
+			return errPruneRetained
+		}
+		numPruned = len(prunedHashes)
+		return nil
+	})
+	if errors.Is(err, errPruneRetained) {
+		b.pruneRetryHeight = maxRetainedHeight + MinBlocksToKeep
+		log.Debugf("Skipping prune of block files containing blocks "+
+			"up to height %d until height %d", maxRetainedHeight,
+			b.pruneRetryHeight)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if numPruned > 0 {
+		log.Infof("Pruned %d blocks, block data is now available from "+
+			"height %d", numPruned, newPruneHeight)
+	}
+	b.pruneHeight = newPruneHeight
+	return nil
+}
+
+// IsPruned returns whether or not the chain is configured to prune old block
+// data.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsPruned() bool {
+	return b.pruneTarget != 0
+}
+
+// PruneHeight returns the height of the lowest main chain block whose data is
+// still available.  It is zero when no block data has been pruned.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) PruneHeight() int32 {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+	return b.pruneHeight
+}
+
+// IsBlockPruned returns whether or not the block identified by the passed hash
+// is known to the chain but its data has been pruned, or was never downloaded
+// since it's below the utxo snapshot the chain state was loaded from.  Blocks
+// stored again with StorePrunedBlock are not pruned anymore.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsBlockPruned(hash *chainhash.Hash) bool {
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return false
+	}
+
+	// The blocks below a loaded utxo snapshot were never downloaded.
+	b.chainLock.RLock()
+	belowSnapshot := node.height < b.utxoSnapshotHeight &&
+		b.bestChain.Contains(node)
+	b.chainLock.RUnlock()
+	if !belowSnapshot && (b.pruneTarget == 0 ||
+		!b.index.NodeStatus(node).HaveData()) {
+
+		return false
+	}
+
+	var has bool
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		has, err = dbTx.HasBlock(hash)
+		return err
+	})
+	return err == nil && !has
+}
+
+// StorePrunedBlock stores the data of the passed block, which must be known to
+// the chain with its data pruned, or never downloaded since it's below the
+// utxo snapshot the chain state was loaded from.  The block is not connected
+// again since it already is part of the chain, so this only allows serving it
+// once it was fetched again on demand.  Note that its data is subject to be
+// pruned again like the data of the blocks being connected.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) StorePrunedBlock(block *btcutil.Block) error {
+	hash := block.Hash()
+	if !b.IsBlockPruned(hash) {
+		return fmt.Errorf("block %v is not a pruned block of the chain",
+			hash)
+	}
+
+	// The header matches the one known to the chain since it has the same
+	// hash, so checking the sanity of the block, which includes its merkle
+	// root, and its witness commitment ensures the transactions match it.
+	err := checkBlockSanity(block, b.chainParams.PowLimit, b.timeSource,
+		BFNone)
+	if err != nil {
+		return err
+	}
+	if err := ValidateWitnessCommitment(block); err != nil {
+		return err
+	}
+
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+	return b.db.Update(func(dbTx database.Tx) error {
+		return dbTx.StoreBlock(block)
+	})
+}
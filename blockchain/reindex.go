@@ -0,0 +1,444 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+const (
+	// reindexStateReset is the reindex state while the chain state is
+	// being reset to the genesis block.
+	reindexStateReset byte = iota + 1
+
+	// reindexStateResetIndex is the reindex state while the chain state is
+	// being reset to the genesis block and the block index rebuilt from the
+	// stored blocks.
+	reindexStateResetIndex
+
+	// reindexStateConnect is the reindex state while the stored blocks are
+	// being connected to the reset chain state.
+	reindexStateConnect
+)
+
+// reindexBatchSize is the max number of keys deleted or written by a single
+// database transaction while resetting the chain state, which avoids the
+// massive memory usage of a transaction covering the whole utxo set.
+const reindexBatchSize = 2000000
+
+var (
+	// reindexKeyName is the name of the db key used to store the state of
+	// a reindex in progress, so it can be resumed on the next start when
+	// it's interrupted, followed by the hash of the best block before the
+	// reindex.
+	reindexKeyName = []byte("reindex")
+
+	// storedBlocksBucketName is the name of the bucket used by the block
+	// storage of the database to track the location of each stored block,
+	// keyed by block hash.
+	storedBlocksBucketName = []byte("ffldb-blockidx")
+)
+
+// dbFetchReindexState returns the state of the reindex in progress, or zero
+// when there is none, and the hash of the best block before the reindex.
+func dbFetchReindexState(db database.DB) (byte, chainhash.Hash, error) {
+	var state byte
+	var prevTip chainhash.Hash
+	err := db.View(func(dbTx database.Tx) error {
+		v := dbTx.Metadata().Get(reindexKeyName)
+		if len(v) == 1+chainhash.HashSize {
+			state = v[0]
+			copy(prevTip[:], v[1:])
+		}
+		return nil
+	})
+	return state, prevTip, err
+}
+
+// dbPutReindexState stores the passed state of the reindex in progress and the
+// hash of the best block before the reindex.
+func dbPutReindexState(dbTx database.Tx, state byte, prevTip *chainhash.Hash) error {
+	v := make([]byte, 1+chainhash.HashSize)
+	v[0] = state
+	copy(v[1:], prevTip[:])
+	return dbTx.Metadata().Put(reindexKeyName, v)
+}
+
+// ResetChainState resets the chain state stored in the passed database to the
+// genesis block so the blocks already stored in the database are connected
+// again, without downloading them, the next time the chain is loaded with New.
+// When rebuildIndex is set, the block index is also rebuilt from the headers
+// of the stored blocks, which are then all validated again.
+//
+// A reset or reindex interrupted before it's complete is resumed the next time
+// the chain is loaded.  The optional indexes and the claim trie must be removed
+// beforehand so they are rebuilt as the blocks are connected.
+func ResetChainState(db database.DB, params *chaincfg.Params, rebuildIndex bool,
+	interrupt <-chan struct{}) error {
+
+	var serializedState []byte
+	var pruned bool
+	err := db.View(func(dbTx database.Tx) error {
+		serializedState = dbTx.Metadata().Get(chainStateKeyName)
+		var err error
+		pruned, err = dbTx.BeenPruned()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if serializedState == nil {
+		log.Infof("Not reindexing because the database has no chain state")
+		return nil
+	}
+	if pruned {
+		return fmt.Errorf("unable to reindex the chain state since " +
+			"blocks have been pruned from the database")
+	}
+
+	// The best block before the reindex is preferred over the other
+	// stored blocks with as much work when connecting them again.
+	bestState, err := deserializeBestChainState(serializedState)
+	if err != nil {
+		return err
+	}
+	state := reindexStateReset
+	if rebuildIndex {
+		state = reindexStateResetIndex
+	}
+	err = db.Update(func(dbTx database.Tx) error {
+		return dbPutReindexState(dbTx, state, &bestState.hash)
+	})
+	if err != nil {
+		return err
+	}
+
+	return resetChainState(db, params, rebuildIndex, &bestState.hash,
+		interrupt)
+}
+
+// resetChainState removes the utxo set, the spend journal and the main chain
+// indexes, optionally rebuilds the block index, and sets the best chain state
+// to the genesis block.  The reindex state is then updated so the stored
+// blocks are connected when the chain is loaded.
+func resetChainState(db database.DB, params *chaincfg.Params, rebuildIndex bool,
+	prevTip *chainhash.Hash, interrupt <-chan struct{}) error {
+
+	log.Infof("Resetting the chain state.  This might take a while...")
+
+	buckets := [][]byte{utxoSetBucketName, spendJournalBucketName,
+		hashIndexBucketName, heightIndexBucketName}
+	if rebuildIndex {
+		buckets = append(buckets, blockIndexBucketName)
+	}
+	for _, bucketName := range buckets {
+		if err := dbClearBucket(db, bucketName, interrupt); err != nil {
+			return err
+		}
+	}
+
+	if rebuildIndex {
+		if err := rebuildBlockIndex(db, params, interrupt); err != nil {
+			return err
+		}
+	}
+
+	// Set the best chain state to the genesis block, which is stored and
+	// valid by definition.
+	genesisBlock := btcutil.NewBlock(params.GenesisBlock)
+	node := newBlockNode(&params.GenesisBlock.Header, nil)
+	node.status = statusDataStored | statusValid
+	numTxns := uint64(len(params.GenesisBlock.Transactions))
+	blockSize := uint64(params.GenesisBlock.SerializeSize())
+	blockWeight := uint64(GetBlockWeight(genesisBlock))
+	snapshot := newBestState(node, blockSize, blockWeight, numTxns,
+		numTxns, time.Unix(node.timestamp, 0))
+
+	err := db.Update(func(dbTx database.Tx) error {
+		err := dbStoreBlockNode(dbTx, node)
+		if err != nil {
+			return err
+		}
+		err = dbPutBlockIndex(dbTx, &node.hash, node.height)
+		if err != nil {
+			return err
+		}
+		err = dbPutBestState(dbTx, snapshot, node.workSum)
+		if err != nil {
+			return err
+		}
+
+		// The header file, if any, no longer holds the block index
+		// once it's rebuilt in the database, so its records are
+		// discarded when it's opened.
+		if rebuildIndex {
+			err := dbTx.Metadata().Delete(headerFileRecordsKeyName)
+			if err != nil {
+				return err
+			}
+		}
+
+		return dbPutReindexState(dbTx, reindexStateConnect, prevTip)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Reset the chain state to the genesis block")
+	return nil
+}
+
+// dbClearBucket deletes all the keys of the passed bucket, if it exists, in
+// batches of reindexBatchSize keys.
+func dbClearBucket(db database.DB, bucketName []byte, interrupt <-chan struct{}) error {
+	var totalDeleted uint64
+	for numDeleted := reindexBatchSize; numDeleted == reindexBatchSize; {
+		if interruptRequested(interrupt) {
+			return errInterruptRequested
+		}
+
+		numDeleted = 0
+		err := db.Update(func(dbTx database.Tx) error {
+			bucket := dbTx.Metadata().Bucket(bucketName)
+			if bucket == nil {
+				return nil
+			}
+			cursor := bucket.Cursor()
+			for ok := cursor.First(); ok && numDeleted < reindexBatchSize; ok = cursor.Next() {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				numDeleted++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if numDeleted > 0 {
+			totalDeleted += uint64(numDeleted)
+			log.Infof("Deleted %d keys (%d total) from %s", numDeleted,
+				totalDeleted, bucketName)
+		}
+	}
+	return nil
+}
+
+// rebuildBlockIndex rebuilds the block index from the headers of the blocks
+// stored in the database.  The blocks without a path back to the genesis
+// block are left out, and only the genesis block is marked as valid so all
+// the other blocks are validated when they are connected.
+func rebuildBlockIndex(db database.DB, params *chaincfg.Params, interrupt <-chan struct{}) error {
+	log.Infof("Rebuilding the block index from the stored blocks...")
+
+	// Read the header of every stored block and construct the tree of
+	// blocks to determine their heights.
+	blocksMap := make(map[chainhash.Hash]*blockChainContext)
+	headers := make(map[chainhash.Hash][]byte)
+	lastReport := time.Now()
+	err := db.View(func(dbTx database.Tx) error {
+		storedBlocks := dbTx.Metadata().Bucket(storedBlocksBucketName)
+		if storedBlocks == nil {
+			return fmt.Errorf("bucket %s does not exist",
+				storedBlocksBucketName)
+		}
+
+		return storedBlocks.ForEach(func(k, _ []byte) error {
+			if interruptRequested(interrupt) {
+				return errInterruptRequested
+			}
+
+			var hash chainhash.Hash
+			copy(hash[:], k)
+			headerBytes, err := dbTx.FetchBlockHeader(&hash)
+			if err != nil {
+				return err
+			}
+			var header wire.BlockHeader
+			err = header.Deserialize(bytes.NewReader(headerBytes))
+			if err != nil {
+				return err
+			}
+
+			addBlockTreeEdge(blocksMap, &hash, &header.PrevBlock)
+			headers[hash] = headerBytes
+
+			if time.Since(lastReport) > 10*time.Second {
+				lastReport = time.Now()
+				log.Infof("Read %d block headers", len(headers))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := determineBlockHeights(blocksMap); err != nil {
+		return err
+	}
+
+	// Write the block index rows in batches.
+	hashes := make([]chainhash.Hash, 0, len(headers))
+	var numOrphans int
+	for hash := range headers {
+		if blocksMap[hash].height == -1 {
+			numOrphans++
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	for len(hashes) > 0 {
+		if interruptRequested(interrupt) {
+			return errInterruptRequested
+		}
+
+		batch := hashes
+		if len(batch) > reindexBatchSize {
+			batch = batch[:reindexBatchSize]
+		}
+		hashes = hashes[len(batch):]
+
+		err := db.Update(func(dbTx database.Tx) error {
+			blockIndexBucket, err := dbTx.Metadata().
+				CreateBucketIfNotExists(blockIndexBucketName)
+			if err != nil {
+				return err
+			}
+
+			for i := range batch {
+				hash := &batch[i]
+				status := statusDataStored
+				if hash.IsEqual(params.GenesisHash) {
+					status |= statusValid
+				}
+
+				value := make([]byte, blockHdrSize+1)
+				copy(value, headers[*hash])
+				value[blockHdrSize] = byte(status)
+
+				height := uint32(blocksMap[*hash].height)
+				key := blockIndexKey(hash, height)
+				if err := blockIndexBucket.Put(key, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Rebuilt the block index with %d blocks (%d blocks not "+
+		"connected to the genesis block left out)",
+		len(headers)-numOrphans, numOrphans)
+	return nil
+}
+
+// connectStoredBlocks connects the stored blocks of the chain with the most
+// work to the reset chain state when a reindex is in progress.  The progress
+// is committed as each block is connected, so an interrupted reindex resumes
+// from the best block on the next start.
+func (b *BlockChain) connectStoredBlocks(interrupt <-chan struct{}) error {
+	state, prevTip, err := dbFetchReindexState(b.db)
+	if err != nil || state != reindexStateConnect {
+		return err
+	}
+
+	// Find the stored block with the most work which isn't known to be
+	// invalid, preferring the best block before the reindex.
+	var target *blockNode
+	b.index.RLock()
+	for _, node := range b.index.index {
+		if !node.status.HaveData() || node.status.KnownInvalid() {
+			continue
+		}
+		if target == nil {
+			target = node
+			continue
+		}
+		switch node.workSum.Cmp(target.workSum) {
+		case 1:
+			target = node
+		case 0:
+			if node.hash == prevTip {
+				target = node
+			}
+		}
+	}
+	b.index.RUnlock()
+
+	tip := b.bestChain.Tip()
+	if target != nil && target.workSum.Cmp(tip.workSum) > 0 &&
+		target.Ancestor(tip.height) != tip {
+
+		log.Warnf("Unable to reindex to block %v (height %d) since it "+
+			"does not extend the best chain", target.hash, target.height)
+		target = nil
+	}
+
+	if target != nil && target.workSum.Cmp(tip.workSum) > 0 {
+		nodes := make([]*blockNode, target.height-tip.height)
+		for n := target; n != tip; n = n.parent {
+			nodes[n.height-tip.height-1] = n
+		}
+
+		log.Infof("Reindexing %d stored blocks from height %d to %d.  "+
+			"This might take a while...", len(nodes), tip.height+1,
+			target.height)
+
+		start := time.Now()
+		lastReport := start
+		for _, node := range nodes {
+			if interruptRequested(interrupt) {
+				return fmt.Errorf("reindex unfinished at height %d, "+
+					"it will resume on the next start",
+					b.bestChain.Height())
+			}
+
+			var block *btcutil.Block
+			err := b.db.View(func(dbTx database.Tx) error {
+				var err error
+				block, err = dbFetchBlockByNode(dbTx, node)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			b.chainLock.Lock()
+			_, err = b.connectBestChain(node, block, BFNone)
+			b.chainLock.Unlock()
+			if _, ok := err.(RuleError); ok {
+				log.Warnf("Stopping reindex at height %d since "+
+					"block %v is invalid: %v", b.bestChain.Height(),
+					node.hash, err)
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			if time.Since(lastReport) > 10*time.Second {
+				lastReport = time.Now()
+				log.Infof("Reindexed blocks to height %d of %d",
+					node.height, target.height)
+			}
+		}
+
+		log.Infof("Completed reindexing blocks to height %d in %s",
+			b.bestChain.Height(), time.Since(start).Truncate(time.Second))
+	}
+
+	return b.db.Update(func(dbTx database.Tx) error {
+		return dbTx.Metadata().Delete(reindexKeyName)
+	})
+}
@@ -0,0 +1,94 @@
+package blockchain_test
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/blockchain/fullblocktests"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/txscript"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestResetChainState ensures the chain state is rebuilt from the stored blocks
+// after it's reset, with and without rebuilding the block index.
+func TestResetChainState(t *testing.T) {
+	tests, err := fullblocktests.Generate(false)
+	if err != nil {
+		t.Fatalf("failed to generate tests: %v", err)
+	}
+
+	dbPath := t.TempDir()
+	db, err := database.Create(testDbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	defer db.Close()
+
+	params := *fullblocktests.FbRegressionNetParams
+	newChain := func() *blockchain.BlockChain {
+		t.Helper()
+		chain, err := blockchain.New(&blockchain.Config{
+			DB:          db,
+			ChainParams: &params,
+			TimeSource:  blockchain.NewMedianTime(),
+			SigCache:    txscript.NewSigCache(1000),
+		})
+		if err != nil {
+			t.Fatalf("failed to create chain instance: %v", err)
+		}
+		return chain
+	}
+
+	// Process the blocks of the tests up to the first one which isn't
+	// accepted.
+	chain := newChain()
+	func() {
+		for _, instances := range tests {
+			for _, instance := range instances {
+				item, ok := instance.(fullblocktests.AcceptedBlock)
+				if !ok {
+					return
+				}
+				block := btcutil.NewBlock(item.Block)
+				_, _, err := chain.ProcessBlock(block, blockchain.BFNone)
+				if err != nil {
+					t.Fatalf("block %q should have been accepted: %v",
+						item.Name, err)
+				}
+			}
+		}
+	}()
+	want := chain.BestSnapshot()
+	if want.Height == 0 {
+		t.Fatalf("no blocks were processed")
+	}
+
+	for _, rebuildIndex := range []bool{false, true} {
+		err := blockchain.ResetChainState(db, &params, rebuildIndex, nil)
+		if err != nil {
+			t.Fatalf("ResetChainState(%v): %v", rebuildIndex, err)
+		}
+
+		chain := newChain()
+		got := chain.BestSnapshot()
+		if got.Hash != want.Hash || got.Height != want.Height ||
+			got.TotalTxns != want.TotalTxns {
+
+			t.Fatalf("ResetChainState(%v): unexpected best state -- "+
+				"got %v (height %d, %d txns), want %v (height %d, "+
+				"%d txns)", rebuildIndex, got.Hash, got.Height,
+				got.TotalTxns, want.Hash, want.Height,
+				want.TotalTxns)
+		}
+
+		// The stored blocks have been connected, so loading the chain
+		// again doesn't change it.
+		chain = newChain()
+		if chain.BestSnapshot().Hash != want.Hash {
+			t.Fatalf("ResetChainState(%v): unexpected best block "+
+				"after reload %v", rebuildIndex,
+				chain.BestSnapshot().Hash)
+		}
+	}
+}
@@ -32,7 +32,7 @@ type txValidator struct {
 	resultChan   chan error
 	utxoView     *UtxoViewpoint
 	flags        txscript.ScriptFlags
-	sigCache     *txscript.SigCache
+	sigCache     txscript.SignatureCache
 	hashCache    *txscript.HashCache
 }
 
@@ -47,6 +47,55 @@ func (v *txValidator) sendResult(result error) {
 	}
 }
 
+// validateItem validates the script pair for the passed transaction input.
+func (v *txValidator) validateItem(txVI *txValidateItem) error {
+	// Ensure the referenced input utxo is available.
+	txIn := txVI.txIn
+	utxo := v.utxoView.LookupEntry(txIn.PreviousOutPoint)
+	if utxo == nil {
+		str := fmt.Sprintf("unable to find unspent "+
+			"output %v referenced from "+
+			"transaction %s:%d",
+			txIn.PreviousOutPoint, txVI.tx.Hash(),
+			txVI.txInIndex)
+		return ruleError(ErrMissingTxOut, str)
+	}
+
+	// Create a new script engine for the script pair.
+	sigScript := txIn.SignatureScript
+	witness := txIn.Witness
+	pkScript := utxo.PkScript()
+	inputAmount := utxo.Amount()
+	vm, err := txscript.NewEngine(pkScript, txVI.tx.MsgTx(),
+		txVI.txInIndex, v.flags, v.sigCache, txVI.sigHashes,
+		inputAmount)
+	if err != nil {
+		str := fmt.Sprintf("failed to parse input "+
+			"%s:%d which references output %v - "+
+			"%v (input witness %x, input script "+
+			"bytes %x, prev output script bytes %x)",
+			txVI.tx.Hash(), txVI.txInIndex,
+			txIn.PreviousOutPoint, err, witness,
+			sigScript, pkScript)
+		return ruleError(ErrScriptMalformed, str)
+	}
+
+	// Execute the script pair.
+	if err := vm.Execute(); err != nil {
+		str := fmt.Sprintf("failed to validate input "+
+			"%s:%d which references output %v - "+
+			"%v (input witness %x, input script "+
+			"bytes %x, prev output script bytes %x)",
+			txVI.tx.Hash(), txVI.txInIndex,
+			txIn.PreviousOutPoint, err, witness,
+			sigScript, pkScript)
+		return ruleError(ErrScriptValidation, str)
+	}
+
+	// Validation succeeded.
+	return nil
+}
+
 // validateHandler consumes items to validate from the internal validate channel
 // and returns the result of the validation on the internal result channel. It
 // must be run as a goroutine.
@@ -55,58 +104,12 @@ out:
 	for {
 		select {
 		case txVI := <-v.validateChan:
-			// Ensure the referenced input utxo is available.
-			txIn := txVI.txIn
-			utxo := v.utxoView.LookupEntry(txIn.PreviousOutPoint)
-			if utxo == nil {
-				str := fmt.Sprintf("unable to find unspent "+
-					"output %v referenced from "+
-					"transaction %s:%d",
-					txIn.PreviousOutPoint, txVI.tx.Hash(),
-					txVI.txInIndex)
-				err := ruleError(ErrMissingTxOut, str)
-				v.sendResult(err)
-				break out
-			}
-
-			// Create a new script engine for the script pair.
-			sigScript := txIn.SignatureScript
-			witness := txIn.Witness
-			pkScript := utxo.PkScript()
-			inputAmount := utxo.Amount()
-			vm, err := txscript.NewEngine(pkScript, txVI.tx.MsgTx(),
-				txVI.txInIndex, v.flags, v.sigCache, txVI.sigHashes,
-				inputAmount)
+			err := v.validateItem(txVI)
+			v.sendResult(err)
 			if err != nil {
-				str := fmt.Sprintf("failed to parse input "+
-					"%s:%d which references output %v - "+
-					"%v (input witness %x, input script "+
-					"bytes %x, prev output script bytes %x)",
-					txVI.tx.Hash(), txVI.txInIndex,
-					txIn.PreviousOutPoint, err, witness,
-					sigScript, pkScript)
-				err := ruleError(ErrScriptMalformed, str)
-				v.sendResult(err)
 				break out
 			}
 
-			// Execute the script pair.
-			if err := vm.Execute(); err != nil {
-				str := fmt.Sprintf("failed to validate input "+
-					"%s:%d which references output %v - "+
-					"%v (input witness %x, input script "+
-					"bytes %x, prev output script bytes %x)",
-					txVI.tx.Hash(), txVI.txInIndex,
-					txIn.PreviousOutPoint, err, witness,
-					sigScript, pkScript)
-				err := ruleError(ErrScriptValidation, str)
-				v.sendResult(err)
-				break out
-			}
-
-			// Validation succeeded.
-			v.sendResult(nil)
-
 		case <-v.quitChan:
 			break out
 		}
@@ -174,7 +177,7 @@ func (v *txValidator) Validate(items []*txValidateItem) error {
 // newTxValidator returns a new instance of txValidator to be used for
 // validating transaction scripts asynchronously.
 func newTxValidator(utxoView *UtxoViewpoint, flags txscript.ScriptFlags,
-	sigCache *txscript.SigCache, hashCache *txscript.HashCache) *txValidator {
+	sigCache txscript.SignatureCache, hashCache *txscript.HashCache) *txValidator {
 	return &txValidator{
 		validateChan: make(chan *txValidateItem),
 		quitChan:     make(chan struct{}),
@@ -189,7 +192,7 @@ func newTxValidator(utxoView *UtxoViewpoint, flags txscript.ScriptFlags,
 // ValidateTransactionScripts validates the scripts for the passed transaction
 // using multiple goroutines.
 func ValidateTransactionScripts(tx *btcutil.Tx, utxoView *UtxoViewpoint,
-	flags txscript.ScriptFlags, sigCache *txscript.SigCache,
+	flags txscript.ScriptFlags, sigCache txscript.SignatureCache,
 	hashCache *txscript.HashCache) error {
 
 	// First determine if segwit is active according to the scriptFlags. If
@@ -239,10 +242,13 @@ func ValidateTransactionScripts(tx *btcutil.Tx, utxoView *UtxoViewpoint,
 }
 
 // checkBlockScripts executes and validates the scripts for all transactions in
-// the passed block using multiple goroutines.
+// the passed block using the workers of the provided validation scheduler with
+// the given priority.  When the scheduler is nil, the scripts are validated
+// using multiple goroutines dedicated to the block.
 func checkBlockScripts(block *btcutil.Block, utxoView *UtxoViewpoint,
-	scriptFlags txscript.ScriptFlags, sigCache *txscript.SigCache,
-	hashCache *txscript.HashCache) error {
+	scriptFlags txscript.ScriptFlags, sigCache txscript.SignatureCache,
+	hashCache *txscript.HashCache, scheduler *ValidationScheduler,
+	priority ValidationPriority) error {
 
 	// First determine if segwit is active according to the scriptFlags. If
 	// it isn't then we don't need to interact with the HashCache.
@@ -297,7 +303,13 @@ func checkBlockScripts(block *btcutil.Block, utxoView *UtxoViewpoint,
 	// Validate all of the inputs.
 	validator := newTxValidator(utxoView, scriptFlags, sigCache, hashCache)
 	start := time.Now()
-	if err := validator.Validate(txValItems); err != nil {
+	var err error
+	if scheduler != nil {
+		err = scheduler.validate(validator, txValItems, priority)
+	} else {
+		err = validator.Validate(txValItems)
+	}
+	if err != nil {
 		return err
 	}
 	elapsed := time.Since(start)
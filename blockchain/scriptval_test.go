@@ -7,6 +7,7 @@ package blockchain
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/lbryio/lbcd/txscript"
 )
@@ -38,9 +39,107 @@ func TestCheckBlockScripts(t *testing.T) {
 	}
 
 	scriptFlags := txscript.ScriptBip16
-	err = checkBlockScripts(blocks[0], view, scriptFlags, nil, nil)
+	err = checkBlockScripts(blocks[0], view, scriptFlags, nil, nil, nil,
+		ValidationPriorityHigh)
 	if err != nil {
 		t.Errorf("Transaction script validation failed: %v\n", err)
 		return
 	}
 }
+
+// TestValidationScheduler ensures validating the scripts of a known-good block
+// through a validation scheduler succeeds while its workers are resized and
+// that the throughput metrics are updated.
+func TestValidationScheduler(t *testing.T) {
+	testBlockNum := 277647
+	blocks, err := loadBlocks(fmt.Sprintf("%d.dat.bz2", testBlockNum))
+	if err != nil {
+		t.Fatalf("Error loading file: %v\n", err)
+	}
+	view, err := loadUtxoView(fmt.Sprintf("%d.utxostore.bz2", testBlockNum))
+	if err != nil {
+		t.Fatalf("Error loading txstore: %v\n", err)
+	}
+
+	scheduler := NewValidationScheduler(2)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	tests := []struct {
+		workers  int
+		priority ValidationPriority
+	}{
+		{workers: 2, priority: ValidationPriorityHigh},
+		{workers: 8, priority: ValidationPriorityLow},
+		{workers: 1, priority: ValidationPriorityHigh},
+	}
+	numInputs := uint64(0)
+	for _, tx := range blocks[0].Transactions()[1:] {
+		numInputs += uint64(len(tx.MsgTx().TxIn))
+	}
+	for i, test := range tests {
+		scheduler.SetWorkers(test.workers)
+		if got := scheduler.Workers(); got != test.workers {
+			t.Fatalf("#%d: unexpected number of workers -- got %d, "+
+				"want %d", i, got, test.workers)
+		}
+
+		err := checkBlockScripts(blocks[0], view, txscript.ScriptBip16,
+			nil, nil, scheduler, test.priority)
+		if err != nil {
+			t.Fatalf("#%d: transaction script validation failed: %v",
+				i, err)
+		}
+
+		stats := scheduler.Stats()
+		if stats.BatchesValidated != uint64(i+1) {
+			t.Fatalf("#%d: unexpected batches validated -- got %d, "+
+				"want %d", i, stats.BatchesValidated, i+1)
+		}
+		if stats.InputsValidated != numInputs*uint64(i+1) {
+			t.Fatalf("#%d: unexpected inputs validated -- got %d, "+
+				"want %d", i, stats.InputsValidated,
+				numInputs*uint64(i+1))
+		}
+	}
+}
+
+// TestValidationSchedulerSetWorkers ensures removing workers which are busy
+// doesn't prevent the scheduler from being queried, and that stopping the
+// scheduler doesn't wait for them to be removed.
+func TestValidationSchedulerSetWorkers(t *testing.T) {
+	// Mark the scheduler started without launching its workers, so they
+	// never accept being told to exit as if they were busy.
+	scheduler := NewValidationScheduler(2)
+	scheduler.started = 1
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.SetWorkers(1)
+		close(done)
+	}()
+
+	// The new number of workers is reported while SetWorkers waits for the
+	// removed worker to exit.
+	updated := make(chan struct{})
+	go func() {
+		for scheduler.Workers() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+		close(updated)
+	}()
+	select {
+	case <-updated:
+	case <-done:
+		t.Fatal("SetWorkers returned before the worker was removed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Workers blocked while workers are being removed")
+	}
+
+	scheduler.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetWorkers didn't return once the scheduler stopped")
+	}
+}
@@ -0,0 +1,293 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// signetScriptFlags are the script flags used to verify the signet solution of
+// a block.
+const signetScriptFlags = txscript.ScriptBip16 |
+	txscript.ScriptVerifyWitness |
+	txscript.ScriptVerifyDERSignatures |
+	txscript.ScriptStrictMultiSig
+
+// SignetHeader is the marker of the push holding the signet solution of a block
+// within the witness commitment output of its coinbase transaction as defined
+// by BIP0325.
+var SignetHeader = []byte{0xec, 0xc7, 0xda, 0xa2}
+
+// witnessCommitmentIndex returns the index of the output of the passed coinbase
+// transaction holding the witness commitment, or -1 when there is none.
+func witnessCommitmentIndex(coinbase *wire.MsgTx) int {
+	for i := len(coinbase.TxOut) - 1; i >= 0; i-- {
+		pkScript := coinbase.TxOut[i].PkScript
+		if len(pkScript) >= CoinbaseWitnessPkScriptLength &&
+			bytes.HasPrefix(pkScript, WitnessMagicBytes) {
+
+			return i
+		}
+	}
+	return -1
+}
+
+// appendPushData appends a push of the passed data to the script.
+func appendPushData(script, data []byte) []byte {
+	switch n := len(data); {
+	case n < txscript.OP_PUSHDATA1:
+		script = append(script, byte(n))
+	case n <= 0xff:
+		script = append(script, txscript.OP_PUSHDATA1, byte(n))
+	case n <= 0xffff:
+		script = append(script, txscript.OP_PUSHDATA2, 0, 0)
+		binary.LittleEndian.PutUint16(script[len(script)-2:], uint16(n))
+	default:
+		script = append(script, txscript.OP_PUSHDATA4, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(script[len(script)-4:], uint32(n))
+	}
+	return append(script, data...)
+}
+
+// splitSignetCommitment returns the passed witness commitment script with the
+// signet solution removed from the first push starting with the signet header,
+// and the solution itself.  When the script has no such push, it is returned
+// with a push of the signet header appended when addHeader is set.
+func splitSignetCommitment(script []byte, addHeader bool) ([]byte, []byte, error) {
+	var cleared, solution []byte
+	found := false
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	for tokenizer.Next() {
+		data := tokenizer.Data()
+		if len(data) == 0 {
+			cleared = append(cleared, tokenizer.Opcode())
+			continue
+		}
+		if !found && bytes.HasPrefix(data, SignetHeader) {
+			solution = data[len(SignetHeader):]
+			data = SignetHeader
+			found = true
+		}
+		cleared = appendPushData(cleared, data)
+	}
+	if err := tokenizer.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if !found && addHeader {
+		cleared = appendPushData(cleared, SignetHeader)
+	}
+	return cleared, solution, nil
+}
+
+// signetTxs returns the virtual transactions whose spend of the signet
+// challenge of the passed block is signed by its signet solution, along with
+// the solution.  The signed transaction has no signature script and witness.
+//
+// The spent transaction commits to the header of the block, except for its
+// nonce and target difficulty bits so the block can be signed before it's
+// mined, with the merkle root of the transactions of the block with the signet
+// solution removed from the coinbase transaction.
+func signetTxs(block *wire.MsgBlock, challenge []byte,
+	addHeader bool) (*wire.MsgTx, *wire.MsgTx, []byte, error) {
+
+	if len(block.Transactions) == 0 {
+		return nil, nil, nil, fmt.Errorf("block has no transactions")
+	}
+	coinbase := block.Transactions[0]
+	idx := witnessCommitmentIndex(coinbase)
+	if idx < 0 {
+		return nil, nil, nil, fmt.Errorf("block has no witness commitment")
+	}
+	cleared, solution, err := splitSignetCommitment(
+		coinbase.TxOut[idx].PkScript, addHeader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Blocks without a signet solution are signed as is, which allows
+	// trivial challenges such as OP_TRUE.
+	merkleRoot := block.Header.MerkleRoot
+	if len(solution) > 0 || addHeader {
+		modified := coinbase.Copy()
+		modified.TxOut[idx].PkScript = cleared
+		txns := make([]*btcutil.Tx, 0, len(block.Transactions))
+		txns = append(txns, btcutil.NewTx(modified))
+		for _, tx := range block.Transactions[1:] {
+			txns = append(txns, btcutil.NewTx(tx))
+		}
+		merkles := BuildMerkleTreeStore(txns, false)
+		merkleRoot = *merkles[len(merkles)-1]
+	}
+
+	var blockData bytes.Buffer
+	header := &block.Header
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(header.Version))
+	blockData.Write(buf[:])
+	blockData.Write(header.PrevBlock[:])
+	blockData.Write(merkleRoot[:])
+	blockData.Write(header.ClaimTrie[:])
+	binary.LittleEndian.PutUint32(buf[:], uint32(header.Timestamp.Unix()))
+	blockData.Write(buf[:])
+
+	toSpend := wire.NewMsgTx(0)
+	toSpend.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript: appendPushData([]byte{txscript.OP_0},
+			blockData.Bytes()),
+		Sequence: 0,
+	})
+	toSpend.AddTxOut(wire.NewTxOut(0, challenge))
+
+	toSign := wire.NewMsgTx(0)
+	toSign.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: toSpend.TxHash(), Index: 0},
+		Sequence:         0,
+	})
+	toSign.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+
+	return toSpend, toSign, solution, nil
+}
+
+// SignetSigningTxs returns the virtual transactions a signer of the passed
+// block must sign to produce its signet solution: the first one pays to the
+// passed challenge and the second one spends it.  The signature script and
+// witness of the spending transaction form the solution, which is added to the
+// block with AddSignetSolution.
+//
+// The block must have a witness commitment output in its coinbase transaction.
+func SignetSigningTxs(block *wire.MsgBlock, challenge []byte) (*wire.MsgTx, *wire.MsgTx, error) {
+	toSpend, toSign, _, err := signetTxs(block, challenge, true)
+	return toSpend, toSign, err
+}
+
+// AddSignetSolution adds the passed signature script and witness solving the
+// signet challenge of the passed block to the witness commitment output of its
+// coinbase transaction, replacing any previous solution, and updates the
+// merkle root of the block accordingly.
+func AddSignetSolution(block *wire.MsgBlock, sigScript []byte, witness wire.TxWitness) error {
+	if len(block.Transactions) == 0 {
+		return fmt.Errorf("block has no transactions")
+	}
+	coinbase := block.Transactions[0]
+	idx := witnessCommitmentIndex(coinbase)
+	if idx < 0 {
+		return fmt.Errorf("block has no witness commitment")
+	}
+
+	var solution bytes.Buffer
+	solution.Write(SignetHeader)
+	if err := wire.WriteVarBytes(&solution, 0, sigScript); err != nil {
+		return err
+	}
+	if err := wire.WriteVarInt(&solution, 0, uint64(len(witness))); err != nil {
+		return err
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(&solution, 0, item); err != nil {
+			return err
+		}
+	}
+
+	// Replace the push of the signet header, which is appended when
+	// missing.
+	cleared, _, err := splitSignetCommitment(coinbase.TxOut[idx].PkScript, true)
+	if err != nil {
+		return err
+	}
+	var pkScript []byte
+	replaced := false
+	tokenizer := txscript.MakeScriptTokenizer(0, cleared)
+	for tokenizer.Next() {
+		data := tokenizer.Data()
+		switch {
+		case len(data) == 0:
+			pkScript = append(pkScript, tokenizer.Opcode())
+		case !replaced && bytes.Equal(data, SignetHeader):
+			pkScript = appendPushData(pkScript, solution.Bytes())
+			replaced = true
+		default:
+			pkScript = appendPushData(pkScript, data)
+		}
+	}
+	if err := tokenizer.Err(); err != nil {
+		return err
+	}
+	coinbase.TxOut[idx].PkScript = pkScript
+
+	txns := make([]*btcutil.Tx, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		txns = append(txns, btcutil.NewTx(tx))
+	}
+	merkles := BuildMerkleTreeStore(txns, false)
+	block.Header.MerkleRoot = *merkles[len(merkles)-1]
+	return nil
+}
+
+// CheckSignetSolution ensures the signet solution of the passed block solves
+// the passed challenge as defined by BIP0325, except the signed data also
+// commits to the claim trie root of the block.
+func CheckSignetSolution(block *btcutil.Block, challenge []byte) error {
+	toSpend, toSign, solution, err := signetTxs(block.MsgBlock(),
+		challenge, false)
+	if err != nil {
+		str := fmt.Sprintf("invalid signet solution: %v", err)
+		return ruleError(ErrBadSignetSolution, str)
+	}
+
+	if len(solution) > 0 {
+		r := bytes.NewReader(solution)
+		sigScript, err := wire.ReadVarBytes(r, 0, MaxBlockWeight,
+			"signet signature script")
+		if err != nil {
+			str := fmt.Sprintf("malformed signet solution: %v", err)
+			return ruleError(ErrBadSignetSolution, str)
+		}
+		numItems, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			str := fmt.Sprintf("malformed signet solution: %v", err)
+			return ruleError(ErrBadSignetSolution, str)
+		}
+		if numItems > uint64(r.Len()) {
+			str := fmt.Sprintf("malformed signet solution: %d "+
+				"witness items exceed its size", numItems)
+			return ruleError(ErrBadSignetSolution, str)
+		}
+		witness := make(wire.TxWitness, 0, numItems)
+		for i := uint64(0); i < numItems; i++ {
+			item, err := wire.ReadVarBytes(r, 0, MaxBlockWeight,
+				"signet witness item")
+			if err != nil {
+				str := fmt.Sprintf("malformed signet solution: %v",
+					err)
+				return ruleError(ErrBadSignetSolution, str)
+			}
+			witness = append(witness, item)
+		}
+		if r.Len() != 0 {
+			str := fmt.Sprintf("malformed signet solution: %d "+
+				"trailing bytes", r.Len())
+			return ruleError(ErrBadSignetSolution, str)
+		}
+		toSign.TxIn[0].SignatureScript = sigScript
+		toSign.TxIn[0].Witness = witness
+	}
+
+	vm, err := txscript.NewEngine(toSpend.TxOut[0].PkScript, toSign, 0,
+		signetScriptFlags, nil, txscript.NewTxSigHashes(toSign), 0)
+	if err == nil {
+		err = vm.Execute()
+	}
+	if err != nil {
+		str := fmt.Sprintf("signet solution of block %v does not solve "+
+			"the challenge: %v", block.Hash(), err)
+		return ruleError(ErrBadSignetSolution, str)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/btcec"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// newSignetTestBlock returns a block with a coinbase transaction holding a
+// witness commitment and another transaction.
+func newSignetTestBlock() *wire.MsgBlock {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  []byte{txscript.OP_DATA_1, 0x01},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{txscript.OP_TRUE}))
+	commitment := append([]byte{}, WitnessMagicBytes...)
+	commitment = append(commitment, make([]byte, chainhash.HashSize)...)
+	coinbase.AddTxOut(wire.NewTxOut(0, commitment))
+
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x01}},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_TRUE}))
+
+	block := &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:   1,
+			PrevBlock: chainhash.Hash{0x02},
+			ClaimTrie: chainhash.Hash{0x03},
+			Timestamp: time.Unix(1600000000, 0),
+			Bits:      0x1e0377ae,
+		},
+		Transactions: []*wire.MsgTx{coinbase, tx},
+	}
+	txns := []*btcutil.Tx{btcutil.NewTx(coinbase), btcutil.NewTx(tx)}
+	merkles := BuildMerkleTreeStore(txns, false)
+	block.Header.MerkleRoot = *merkles[len(merkles)-1]
+	return block
+}
+
+// TestSignetSolution ensures blocks are only valid with a signet solution
+// solving the challenge and signing their header.
+func TestSignetSolution(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x01, 0x02, 0x03})
+	challenge, err := txscript.NewScriptBuilder().
+		AddData(key.PubKey().SerializeCompressed()).
+		AddOp(txscript.OP_CHECKSIG).Script()
+	if err != nil {
+		t.Fatalf("unable to create challenge: %v", err)
+	}
+
+	sign := func(block *wire.MsgBlock, key *btcec.PrivateKey) {
+		t.Helper()
+		_, toSign, err := SignetSigningTxs(block, challenge)
+		if err != nil {
+			t.Fatalf("SignetSigningTxs: %v", err)
+		}
+		sig, err := txscript.RawTxInSignature(toSign, 0, challenge,
+			txscript.SigHashAll, key)
+		if err != nil {
+			t.Fatalf("unable to sign: %v", err)
+		}
+		sigScript, err := txscript.NewScriptBuilder().AddData(sig).Script()
+		if err != nil {
+			t.Fatalf("unable to create signature script: %v", err)
+		}
+		if err := AddSignetSolution(block, sigScript, nil); err != nil {
+			t.Fatalf("AddSignetSolution: %v", err)
+		}
+	}
+	check := func(block *wire.MsgBlock) error {
+		return CheckSignetSolution(btcutil.NewBlock(block), challenge)
+	}
+	isBadSolution := func(err error) bool {
+		rerr, ok := err.(RuleError)
+		return ok && rerr.ErrorCode == ErrBadSignetSolution
+	}
+
+	// Blocks without a solution don't solve the challenge.
+	block := newSignetTestBlock()
+	if err := check(block); !isBadSolution(err) {
+		t.Fatalf("unsigned block: unexpected error %v", err)
+	}
+
+	// Signed blocks are valid, and the signature doesn't commit to the
+	// nonce so the block can be mined after it's signed.
+	sign(block, key)
+	if err := check(block); err != nil {
+		t.Fatalf("signed block: unexpected error %v", err)
+	}
+	block.Header.Nonce++
+	if err := check(block); err != nil {
+		t.Fatalf("mined block: unexpected error %v", err)
+	}
+
+	// Signing again replaces the solution.
+	sign(block, key)
+	if err := check(block); err != nil {
+		t.Fatalf("signed again block: unexpected error %v", err)
+	}
+
+	// The signature commits to the claim trie root.
+	block.Header.ClaimTrie = chainhash.Hash{0x04}
+	if err := check(block); !isBadSolution(err) {
+		t.Fatalf("modified block: unexpected error %v", err)
+	}
+
+	// Blocks signed with another key don't solve the challenge.
+	otherKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x04})
+	block = newSignetTestBlock()
+	sign(block, otherKey)
+	if err := check(block); !isBadSolution(err) {
+		t.Fatalf("block signed with another key: unexpected error %v",
+			err)
+	}
+
+	// Trivial challenges don't need a solution, but a witness commitment.
+	block = newSignetTestBlock()
+	err = CheckSignetSolution(btcutil.NewBlock(block),
+		[]byte{txscript.OP_TRUE})
+	if err != nil {
+		t.Fatalf("trivial challenge: unexpected error %v", err)
+	}
+	block.Transactions[0].TxOut = block.Transactions[0].TxOut[:1]
+	err = CheckSignetSolution(btcutil.NewBlock(block),
+		[]byte{txscript.OP_TRUE})
+	if !isBadSolution(err) {
+		t.Fatalf("block without witness commitment: unexpected "+
+			"error %v", err)
+	}
+}
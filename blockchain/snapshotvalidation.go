@@ -0,0 +1,519 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// The blocks below a utxo snapshot the chain state was loaded from are
+// downloaded and validated in the background once the chain is current, from
+// the genesis block up to the block of the snapshot.  They are connected to a
+// separate utxo set, stored in its own bucket, and a separate claim trie, so
+// the main chain is not affected.  Once the block of the snapshot is connected,
+// the MuHash of the resulting utxo set is compared with the one of the
+// snapshot, which completes the validation of the chain state.
+
+const (
+	// snapshotValidationPending, snapshotValidationDone and
+	// snapshotValidationFailed are the states of the validation of the
+	// blocks below a utxo snapshot.
+	snapshotValidationPending = 0
+	snapshotValidationDone    = 1
+	snapshotValidationFailed  = 2
+
+	// snapshotValidationFlushBlocks is the number of blocks validated below
+	// a utxo snapshot after which the resulting utxo set and claim trie are
+	// written to the database.
+	snapshotValidationFlushBlocks = 2000
+
+	// snapshotValidationMaxEntries is the number of entries of the utxo
+	// view of the blocks validated below a utxo snapshot after which the
+	// view is written to the database before snapshotValidationFlushBlocks
+	// blocks are validated.
+	snapshotValidationMaxEntries = 500000
+)
+
+var (
+	// snapshotUtxoSetBucketName is the name of the db bucket used to house
+	// the utxo set resulting from the blocks validated below the utxo
+	// snapshot the chain state was loaded from.
+	snapshotUtxoSetBucketName = []byte("snapshotutxoset")
+
+	// snapshotValidationKeyName is the name of the db key used to store
+	// the state of the validation of the blocks below the utxo snapshot
+	// the chain state was loaded from.
+	snapshotValidationKeyName = []byte("snapshotvalidation")
+)
+
+// snapshotValidation houses the state of the validation of the blocks below
+// the utxo snapshot the chain state was loaded from.  It is protected by the
+// chain lock.
+type snapshotValidation struct {
+	// muHash is the MuHash of the utxo set of the snapshot.
+	muHash chainhash.Hash
+
+	// status is the state of the validation, and height the height of the
+	// last block validated.
+	status byte
+	height int32
+
+	// flushHeight is the height of the last block whose changes to the
+	// utxo set and the claim trie were written to the database.  The view
+	// holds the changes to the utxo set of the blocks validated since.
+	flushHeight int32
+	view        *UtxoViewpoint
+
+	// claimTrie is the claim trie the claim scripts of the blocks are
+	// processed with.  It is opened once the first block is validated, and
+	// is only used when the chain has a claim trie.
+	claimTrie *claimtrie.ClaimTrie
+}
+
+// dbPutSnapshotValidation stores the height of the last block validated below
+// the utxo snapshot the chain state was loaded from, the state of the
+// validation and the MuHash of the utxo set of the snapshot.
+func dbPutSnapshotValidation(dbTx database.Tx, sv *snapshotValidation) error {
+	v := make([]byte, 5+chainhash.HashSize)
+	byteOrder.PutUint32(v, uint32(sv.flushHeight))
+	v[4] = sv.status
+	copy(v[5:], sv.muHash[:])
+	return dbTx.Metadata().Put(snapshotValidationKeyName, v)
+}
+
+// initSnapshotValidation loads the state of the validation of the blocks below
+// the utxo snapshot the chain state was loaded from, if any.
+func (b *BlockChain) initSnapshotValidation() error {
+	var sv *snapshotValidation
+	err := b.db.View(func(dbTx database.Tx) error {
+		v := dbTx.Metadata().Get(snapshotValidationKeyName)
+		if len(v) != 5+chainhash.HashSize {
+			return nil
+		}
+		sv = &snapshotValidation{
+			status:      v[4],
+			flushHeight: int32(byteOrder.Uint32(v)),
+		}
+		copy(sv.muHash[:], v[5:])
+		return nil
+	})
+	if err != nil || sv == nil {
+		return err
+	}
+	if sv.status != snapshotValidationPending {
+		b.snapshotValidation = sv
+		return nil
+	}
+	if b.claimTrie != nil && b.snapshotClaimTrieCfg == nil {
+		log.Warnf("The blocks below the utxo snapshot can't be " +
+			"validated without a configuration for their claim " +
+			"trie")
+		return nil
+	}
+	b.snapshotValidation = sv
+	b.rewindSnapshotValidation(sv)
+
+	// Complete the validation when it was interrupted once the block of
+	// the snapshot was connected.
+	if sv.height == b.utxoSnapshotHeight {
+		return b.finishSnapshotValidation(sv)
+	}
+	return nil
+}
+
+// rewindSnapshotValidation discards the changes of the blocks validated below
+// the utxo snapshot since the last time they were written to the database.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) rewindSnapshotValidation(sv *snapshotValidation) {
+	sv.height = sv.flushHeight
+	sv.view = NewUtxoViewpoint()
+	sv.view.SetBestHash(&b.bestChain.NodeByHeight(sv.height).hash)
+	if sv.claimTrie != nil && sv.claimTrie.Height() != sv.height {
+		if err := sv.claimTrie.ResetHeight(sv.height); err != nil {
+			log.Errorf("Unable to reset the claim trie of the "+
+				"blocks below the utxo snapshot: %v", err)
+			sv.claimTrie.Close()
+			sv.claimTrie = nil
+		}
+	}
+}
+
+// openSnapshotClaimTrie opens the claim trie the claim scripts of the blocks
+// validated below the utxo snapshot are processed with, and makes it match the
+// last block whose changes were written to the database.  The validation
+// starts again from the genesis block when the claim trie is behind it or
+// can't be opened.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) openSnapshotClaimTrie(sv *snapshotValidation) error {
+	ct, err := claimtrie.New(*b.snapshotClaimTrieCfg)
+	if err == nil && ct.Height() >= sv.flushHeight {
+		if ct.Height() > sv.flushHeight {
+			err = ct.ResetHeight(sv.flushHeight)
+		}
+		if err == nil {
+			sv.claimTrie = ct
+			return nil
+		}
+	}
+	if ct != nil {
+		ct.Close()
+	}
+	if sv.flushHeight == 0 {
+		return err
+	}
+
+	log.Warnf("Unable to restore the claim trie of the blocks below the "+
+		"utxo snapshot at height %d, validating them again from the "+
+		"genesis block", sv.flushHeight)
+	if err := claimtrie.Remove(*b.snapshotClaimTrieCfg); err != nil {
+		return err
+	}
+	err = b.db.Update(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(snapshotUtxoSetBucketName)
+		if err := bucket.DeleteRange(nil, nil); err != nil {
+			return err
+		}
+		sv.flushHeight = 0
+		return dbPutSnapshotValidation(dbTx, sv)
+	})
+	if err != nil {
+		return err
+	}
+	b.rewindSnapshotValidation(sv)
+	sv.claimTrie, err = claimtrie.New(*b.snapshotClaimTrieCfg)
+	return err
+}
+
+// flushSnapshotValidation writes the changes to the utxo set and the claim trie
+// of the blocks validated below the utxo snapshot since the last flush to the
+// database, along with the state of the validation.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) flushSnapshotValidation(sv *snapshotValidation) error {
+	if sv.claimTrie != nil {
+		sv.claimTrie.FlushToDisk()
+	}
+	flushHeight := sv.flushHeight
+	sv.flushHeight = sv.height
+	err := b.db.Update(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(snapshotUtxoSetBucketName)
+		if err := dbPutUtxoViewBucket(bucket, sv.view); err != nil {
+			return err
+		}
+		return dbPutSnapshotValidation(dbTx, sv)
+	})
+	if err != nil {
+		sv.flushHeight = flushHeight
+		return err
+	}
+	sv.view = NewUtxoViewpoint()
+	sv.view.SetBestHash(&b.bestChain.NodeByHeight(sv.height).hash)
+	return nil
+}
+
+// finishSnapshotValidation compares the MuHash of the utxo set resulting from
+// the blocks validated below the utxo snapshot, up to the block of the
+// snapshot, with the one of the snapshot, and records the outcome.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) finishSnapshotValidation(sv *snapshotValidation) error {
+	var muHash chainhash.Hash
+	err := b.db.View(func(dbTx database.Tx) error {
+		stats, err := scanUtxoStats(dbTx, snapshotUtxoSetBucketName)
+		if err != nil {
+			return err
+		}
+		muHash = stats.muHash.Finalize()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if muHash != sv.muHash {
+		log.Errorf("The MuHash %v of the utxo set resulting from the "+
+			"blocks below the utxo snapshot doesn't match the MuHash "+
+			"%v of the snapshot, the chain state is invalid", muHash,
+			sv.muHash)
+		return b.failSnapshotValidation(sv)
+	}
+
+	sv.status = snapshotValidationDone
+	err = b.db.Update(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(snapshotUtxoSetBucketName)
+		if err := bucket.DeleteRange(nil, nil); err != nil {
+			return err
+		}
+		return dbPutSnapshotValidation(dbTx, sv)
+	})
+	if err != nil {
+		sv.status = snapshotValidationPending
+		return err
+	}
+	b.closeSnapshotValidation(sv)
+	if b.snapshotClaimTrieCfg != nil {
+		if err := claimtrie.Remove(*b.snapshotClaimTrieCfg); err != nil {
+			log.Warnf("Unable to remove the claim trie of the "+
+				"blocks below the utxo snapshot: %v", err)
+		}
+	}
+	log.Infof("Validated the blocks below the utxo snapshot of block %v "+
+		"(height %d)", b.bestChain.NodeByHeight(sv.height).hash,
+		sv.height)
+	return nil
+}
+
+// failSnapshotValidation records the failure of the validation of the blocks
+// below the utxo snapshot.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) failSnapshotValidation(sv *snapshotValidation) error {
+	sv.status = snapshotValidationFailed
+	b.closeSnapshotValidation(sv)
+	return b.db.Update(func(dbTx database.Tx) error {
+		return dbPutSnapshotValidation(dbTx, sv)
+	})
+}
+
+// closeSnapshotValidation releases the utxo view and the claim trie of the
+// blocks validated below the utxo snapshot.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) closeSnapshotValidation(sv *snapshotValidation) {
+	sv.view = nil
+	if sv.claimTrie != nil {
+		sv.claimTrie.Close()
+		sv.claimTrie = nil
+	}
+}
+
+// fetchSnapshotUtxos loads the outputs spent by the passed block, along with
+// the ones it creates when they are checked against BIP0030, from the utxo set
+// of the blocks validated below the utxo snapshot into the passed view, unless
+// they already are in it.  Checking the connection of the block with the view
+// then doesn't load them from the main utxo set.
+func (b *BlockChain) fetchSnapshotUtxos(view *UtxoViewpoint, block *btcutil.Block) error {
+	needed := make([]wire.OutPoint, 0, len(block.Transactions()))
+	if block.Height() < b.chainParams.BIP0034Height {
+		for _, tx := range block.Transactions() {
+			prevOut := wire.OutPoint{Hash: *tx.Hash()}
+			for txOutIdx := range tx.MsgTx().TxOut {
+				prevOut.Index = uint32(txOutIdx)
+				needed = append(needed, prevOut)
+			}
+		}
+	}
+	for _, tx := range block.Transactions()[1:] {
+		for _, txIn := range tx.MsgTx().TxIn {
+			needed = append(needed, txIn.PreviousOutPoint)
+		}
+	}
+
+	return b.db.View(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(snapshotUtxoSetBucketName)
+		for _, outpoint := range needed {
+			if _, ok := view.entries[outpoint]; ok {
+				continue
+			}
+			key := outpointKey(outpoint)
+			serialized := bucket.Get(*key)
+			recycleOutpointKey(key)
+			if serialized == nil {
+				view.entries[outpoint] = nil
+				continue
+			}
+			entry, err := deserializeUtxoEntry(serialized)
+			if err != nil {
+				return err
+			}
+			view.entries[outpoint] = entry
+		}
+		return nil
+	})
+}
+
+// SnapshotValidationBlocks returns the hashes of up to max blocks below the
+// utxo snapshot the chain state was loaded from which are the next ones to be
+// validated in the background, in the order they must be passed to
+// ValidateSnapshotBlock.  None are returned when the chain state wasn't loaded
+// from a utxo snapshot or the validation of the blocks below it is over.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) SnapshotValidationBlocks(max int) []chainhash.Hash {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	sv := b.snapshotValidation
+	if sv == nil || sv.status != snapshotValidationPending {
+		return nil
+	}
+	var hashes []chainhash.Hash
+	for height := sv.height + 1; height <= b.utxoSnapshotHeight &&
+		len(hashes) < max; height++ {
+
+		hashes = append(hashes, b.bestChain.NodeByHeight(height).hash)
+	}
+	return hashes
+}
+
+// SnapshotValidationHeight returns the height of the last block validated
+// below the utxo snapshot the chain state was loaded from.  An error is
+// returned when the validation of the blocks failed, which means the chain
+// state is invalid.  The height of the snapshot is returned once all of the
+// blocks below it are validated, and zero when the chain state wasn't loaded
+// from a snapshot or their validation was never started.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) SnapshotValidationHeight() (int32, error) {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	sv := b.snapshotValidation
+	switch {
+	case sv == nil:
+		return 0, nil
+	case sv.status == snapshotValidationDone:
+		return b.utxoSnapshotHeight, nil
+	case sv.status == snapshotValidationFailed:
+		return sv.flushHeight, errors.New("the blocks below the utxo " +
+			"snapshot are invalid")
+	}
+	return sv.height, nil
+}
+
+// ValidateSnapshotBlock fully validates the passed block, which must be the
+// next block below the utxo snapshot the chain state was loaded from to be
+// validated as reported by SnapshotValidationBlocks, by connecting it to the
+// utxo set and the claim trie resulting from the blocks below it.  Once the
+// block of the snapshot is connected, the MuHash of the resulting utxo set is
+// compared with the one of the snapshot to complete the validation.
+//
+// An error is returned without affecting the validation when the block doesn't
+// match its header, in which case it can be downloaded from another peer.  Any
+// rule violated by the block otherwise fails the validation, which means the
+// chain state loaded from the snapshot is invalid, and no more blocks are
+// validated.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ValidateSnapshotBlock(block *btcutil.Block) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	sv := b.snapshotValidation
+	if sv == nil || sv.status != snapshotValidationPending {
+		return errors.New("no blocks below a utxo snapshot are being " +
+			"validated")
+	}
+	node := b.bestChain.NodeByHeight(sv.height + 1)
+	if node == nil || node.height > b.utxoSnapshotHeight ||
+		node.hash != *block.Hash() {
+
+		return fmt.Errorf("block %v is not the next block below the "+
+			"utxo snapshot to validate", block.Hash())
+	}
+	block.SetHeight(node.height)
+
+	// The header matches the one known to the chain since it has the same
+	// hash, so checking the sanity of the block, which includes its merkle
+	// root, and its witness commitment ensures the transactions match it.
+	err := checkBlockSanity(block, b.chainParams.PowLimit, b.timeSource,
+		BFNone)
+	if err != nil {
+		return err
+	}
+	if err := ValidateWitnessCommitment(block); err != nil {
+		return err
+	}
+
+	if sv.claimTrie == nil && b.claimTrie != nil {
+		if err := b.openSnapshotClaimTrie(sv); err != nil {
+			return err
+		}
+	}
+
+	err = b.connectSnapshotBlock(sv, node, block)
+	if err != nil {
+		if _, ok := err.(RuleError); !ok {
+			// The view may have been partially updated, so the
+			// blocks since the last flush are validated again.
+			b.rewindSnapshotValidation(sv)
+			return err
+		}
+		log.Errorf("Block %v (height %d) below the utxo snapshot is "+
+			"invalid, the chain state is invalid: %v", node.hash,
+			node.height, err)
+		if failErr := b.failSnapshotValidation(sv); failErr != nil {
+			log.Errorf("Unable to record the failed validation of "+
+				"the blocks below the utxo snapshot: %v", failErr)
+		}
+		return err
+	}
+	sv.height = node.height
+
+	if sv.height == b.utxoSnapshotHeight ||
+		sv.height-sv.flushHeight >= snapshotValidationFlushBlocks ||
+		len(sv.view.entries) >= snapshotValidationMaxEntries {
+
+		if err := b.flushSnapshotValidation(sv); err != nil {
+			b.rewindSnapshotValidation(sv)
+			return err
+		}
+		log.Infof("Validated the blocks below the utxo snapshot up to "+
+			"height %d of %d", sv.height, b.utxoSnapshotHeight)
+	}
+	if sv.height == b.utxoSnapshotHeight {
+		return b.finishSnapshotValidation(sv)
+	}
+	return nil
+}
+
+// connectSnapshotBlock checks the passed block below the utxo snapshot in the
+// context of the blocks validated before it and connects it to their utxo view
+// and claim trie.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) connectSnapshotBlock(sv *snapshotValidation, node *blockNode, block *btcutil.Block) error {
+	err := b.checkBlockTxContext(block, node.parent, BFNone)
+	if err != nil {
+		return err
+	}
+	if err := b.fetchSnapshotUtxos(sv.view, block); err != nil {
+		return err
+	}
+	err = b.checkConnectBlock(node, block, sv.view, nil, BFNone,
+		ValidationPriorityLow)
+	if err != nil {
+		return err
+	}
+	if sv.claimTrie != nil {
+		err := parseClaimScripts(sv.claimTrie, block, node, sv.view, false)
+		if err != nil {
+			return ruleError(ErrBadClaimTrie, err.Error())
+		}
+	}
+	return nil
+}
+
+// stopSnapshotValidation writes the changes of the blocks validated below the
+// utxo snapshot since the last flush to the database and releases the utxo
+// view and the claim trie of the validation.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) stopSnapshotValidation() error {
+	sv := b.snapshotValidation
+	if sv == nil || sv.status != snapshotValidationPending {
+		return nil
+	}
+	var err error
+	if sv.height != sv.flushHeight {
+		err = b.flushSnapshotValidation(sv)
+	}
+	b.closeSnapshotValidation(sv)
+	return err
+}
@@ -161,23 +161,27 @@ func readBlockTree(v1BlockIdxBucket database.Bucket) (map[chainhash.Hash]*blockC
 		}
 
 		blockHash := header.BlockHash()
-		prevHash := header.PrevBlock
-
-		if blocksMap[blockHash] == nil {
-			blocksMap[blockHash] = &blockChainContext{height: -1}
-		}
-		if blocksMap[prevHash] == nil {
-			blocksMap[prevHash] = &blockChainContext{height: -1}
-		}
-
-		blocksMap[blockHash].parent = &prevHash
-		blocksMap[prevHash].children =
-			append(blocksMap[prevHash].children, &blockHash)
+		addBlockTreeEdge(blocksMap, &blockHash, &header.PrevBlock)
 		return nil
 	})
 	return blocksMap, err
 }
 
+// addBlockTreeEdge adds the block with the passed hash as a child of the block
+// with the passed previous hash to the passed tree of blocks.
+func addBlockTreeEdge(blocksMap map[chainhash.Hash]*blockChainContext, blockHash, prevHash *chainhash.Hash) {
+	if blocksMap[*blockHash] == nil {
+		blocksMap[*blockHash] = &blockChainContext{height: -1}
+	}
+	if blocksMap[*prevHash] == nil {
+		blocksMap[*prevHash] = &blockChainContext{height: -1}
+	}
+
+	hash, prev := *blockHash, *prevHash
+	blocksMap[hash].parent = &prev
+	blocksMap[prev].children = append(blocksMap[prev].children, &hash)
+}
+
 // determineBlockHeights takes a map of block hashes to a slice of child hashes
 // and uses it to compute the height for each block. The function assigns a
 // height of 0 to the genesis hash and explores the tree of blocks
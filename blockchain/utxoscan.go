@@ -0,0 +1,113 @@
+package blockchain
+
+import (
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/wire"
+)
+
+// utxoScanProgressInterval is the number of unspent transaction outputs scanned
+// by ScanUtxoSet between progress reports.
+const utxoScanProgressInterval = 10000
+
+// UtxoScanMatch is an unspent transaction output found by ScanUtxoSet.
+type UtxoScanMatch struct {
+	OutPoint   wire.OutPoint
+	Amount     int64
+	PkScript   []byte
+	Height     int32
+	IsCoinBase bool
+}
+
+// UtxoScanResult houses the outcome of a scan of the unspent transaction output
+// set as of a block of the main chain.
+type UtxoScanResult struct {
+	// Hash and Height identify the block the scan is for.
+	Hash   chainhash.Hash
+	Height int32
+
+	// TxOuts is the number of unspent transaction outputs scanned.
+	TxOuts int64
+
+	// Matches are the unspent transaction outputs that matched.
+	Matches []UtxoScanMatch
+}
+
+// ScanUtxoSet scans the entire unspent transaction output set as of the end of
+// the main chain for the outputs matched by the passed function, which is given
+// the outpoint and the public key script of each output.
+//
+// The outputs are scanned in the order of their serialized transaction hashes,
+// so the first bytes of the hash of the last scanned output approximate the
+// fraction of the set scanned.  It is passed to progress, when not nil,
+// periodically during the scan.
+//
+// The scan is aborted with an error when the interrupt channel is closed.
+//
+// This function is safe for concurrent access.  The chain is only locked while
+// the scan starts since the database transaction used for the scan is a
+// snapshot of the set.
+func (b *BlockChain) ScanUtxoSet(match func(outpoint *wire.OutPoint, pkScript []byte) bool,
+	progress func(float64), interrupt <-chan struct{}) (*UtxoScanResult, error) {
+
+	b.chainLock.RLock()
+	locked := true
+	defer func() {
+		if locked {
+			b.chainLock.RUnlock()
+		}
+	}()
+
+	var result UtxoScanResult
+	err := b.db.View(func(dbTx database.Tx) error {
+		best := b.stateSnapshot
+		result.Hash = best.Hash
+		result.Height = best.Height
+		b.chainLock.RUnlock()
+		locked = false
+
+		cursor := dbTx.Metadata().Bucket(utxoSetBucketName).
+			PrefetchCursor(utxoSetPrefetchBlocks)
+		for ok := cursor.First(); ok; ok = cursor.Next() {
+			key := cursor.Key()
+			var outpoint wire.OutPoint
+			copy(outpoint.Hash[:], key[:chainhash.HashSize])
+			idx, _ := deserializeVLQ(key[chainhash.HashSize:])
+			outpoint.Index = uint32(idx)
+
+			entry, err := deserializeUtxoEntry(cursor.Value())
+			if err != nil {
+				return err
+			}
+
+			result.TxOuts++
+			if match(&outpoint, entry.PkScript()) {
+				result.Matches = append(result.Matches, UtxoScanMatch{
+					OutPoint:   outpoint,
+					Amount:     entry.Amount(),
+					PkScript:   entry.PkScript(),
+					Height:     entry.BlockHeight(),
+					IsCoinBase: entry.IsCoinBase(),
+				})
+			}
+
+			if result.TxOuts%utxoScanProgressInterval != 0 {
+				continue
+			}
+			if interruptRequested(interrupt) {
+				return errInterruptRequested
+			}
+			if progress != nil {
+				lead := uint16(outpoint.Hash[0])<<8 |
+					uint16(outpoint.Hash[1])
+				progress(float64(lead) / (1 << 16))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,623 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lbryio/lbcd/blockchain/internal/muhash"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// A utxo snapshot holds the unspent transaction output set as of a block of
+// the main chain, along with the headers of the chain up to the block, the
+// block itself and the claim trie as of the block.  It allows a new node to
+// start syncing from the block once the utxo set matches one of the assumed
+// utxo sets of the chain.  It is laid out as follows, with all integers in
+// little endian:
+//
+//	magic (8 bytes) | version (4 bytes) | network (4 bytes) | height (4 bytes)
+//	block hash (32 bytes) | total transactions (8 bytes) | claim trie (1 byte)
+//	headers of the blocks from height 1 to height (blockHdrSize bytes each)
+//	block at height
+//	unspent outputs, each one encoded as:
+//	  1 (1 byte) | tx hash (32 bytes) | output index (4 bytes) |
+//	  entry length (varint) | entry serialized as in the utxo set bucket
+//	0 (1 byte)
+//	sha256 of all of the above (32 bytes)
+//	claim trie snapshot at height when the claim trie byte is 1, see
+//	claimtrie.ExportSnapshot
+//
+// The outputs are sorted as in the utxo set bucket, so the snapshot of a given
+// block is identical on every node.
+
+const (
+	// UtxoSnapshotVersion is the current version of the utxo snapshot
+	// format.
+	UtxoSnapshotVersion = 1
+
+	// utxoSnapshotBatchSize is the number of unspent outputs or block
+	// index entries written by a single database transaction while loading
+	// a utxo snapshot.
+	utxoSnapshotBatchSize = 100000
+)
+
+var (
+	// utxoSnapshotMagic identifies utxo snapshots.
+	utxoSnapshotMagic = [8]byte{'l', 'b', 'c', 'u', 't', 'x', 'o', 0}
+
+	// utxoSnapshotKeyName is the name of the db key used to store the
+	// height and the hash of the block of the utxo snapshot the chain state
+	// was loaded from, if any.
+	utxoSnapshotKeyName = []byte("utxosnapshot")
+)
+
+// UtxoSnapshotInfo describes the unspent transaction output set of a utxo
+// snapshot.
+type UtxoSnapshotInfo struct {
+	// Hash and Height identify the block the snapshot is for.
+	Hash   chainhash.Hash
+	Height int32
+
+	// TxOuts is the number of unspent transaction outputs.
+	TxOuts int64
+
+	// MuHash is the MuHash3072 of the set, as reported by UtxoSetStats.
+	MuHash chainhash.Hash
+}
+
+// findAssumeUtxo returns the assumed utxo set of the chain for the passed
+// block, or nil when there is none.
+func (b *BlockChain) findAssumeUtxo(height int32, hash *chainhash.Hash) *chaincfg.AssumeUtxo {
+	for i := range b.assumeUtxo {
+		assumed := &b.assumeUtxo[i]
+		if assumed.Height == height && assumed.Hash.IsEqual(hash) {
+			return assumed
+		}
+	}
+	return nil
+}
+
+// DumpUtxoSnapshot writes a utxo snapshot of the end of the main chain to w.
+// The returned MuHash is the one an assumed utxo set must have for the
+// snapshot to be loaded.  The chain is locked while the snapshot is written.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) DumpUtxoSnapshot(w io.Writer) (*UtxoSnapshotInfo, error) {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	tip := b.bestChain.Tip()
+	if tip.height == 0 {
+		return nil, errors.New("the chain has no blocks besides the " +
+			"genesis block")
+	}
+	if b.claimTrie != nil && b.claimTrie.Height() != tip.height {
+		return nil, fmt.Errorf("claim trie height %d doesn't match the "+
+			"best height %d", b.claimTrie.Height(), tip.height)
+	}
+
+	bw := bufio.NewWriter(w)
+	hasher := sha256.New()
+	enc := io.MultiWriter(bw, hasher)
+
+	var buf [8]byte
+	enc.Write(utxoSnapshotMagic[:]) // nolint : errchk
+	binary.LittleEndian.PutUint32(buf[:4], UtxoSnapshotVersion)
+	enc.Write(buf[:4]) // nolint : errchk
+	binary.LittleEndian.PutUint32(buf[:4], uint32(b.chainParams.Net))
+	enc.Write(buf[:4]) // nolint : errchk
+	binary.LittleEndian.PutUint32(buf[:4], uint32(tip.height))
+	enc.Write(buf[:4])     // nolint : errchk
+	enc.Write(tip.hash[:]) // nolint : errchk
+	binary.LittleEndian.PutUint64(buf[:], b.stateSnapshot.TotalTxns)
+	enc.Write(buf[:]) // nolint : errchk
+	buf[0] = 0
+	if b.claimTrie != nil {
+		buf[0] = 1
+	}
+	enc.Write(buf[:1]) // nolint : errchk
+
+	for height := int32(1); height <= tip.height; height++ {
+		header := b.bestChain.NodeByHeight(height).Header()
+		if err := header.Serialize(enc); err != nil {
+			return nil, err
+		}
+	}
+
+	info := &UtxoSnapshotInfo{Hash: tip.hash, Height: tip.height}
+	stats := &utxoStats{muHash: muhash.New()}
+	err := b.db.View(func(dbTx database.Tx) error {
+		blockBytes, err := dbTx.FetchBlock(&tip.hash)
+		if err != nil {
+			return err
+		}
+		enc.Write(blockBytes) // nolint : errchk
+
+		cursor := dbTx.Metadata().Bucket(utxoSetBucketName).
+			PrefetchCursor(utxoSetPrefetchBlocks)
+		for ok := cursor.First(); ok; ok = cursor.Next() {
+			key := cursor.Key()
+			var outpoint wire.OutPoint
+			copy(outpoint.Hash[:], key[:chainhash.HashSize])
+			idx, _ := deserializeVLQ(key[chainhash.HashSize:])
+			outpoint.Index = uint32(idx)
+
+			serialized := cursor.Value()
+			entry, err := deserializeUtxoEntry(serialized)
+			if err != nil {
+				return err
+			}
+			stats.add(&outpoint, entry.Amount(), entry.PkScript(),
+				entry.BlockHeight(), entry.IsCoinBase())
+
+			buf[0] = 1
+			enc.Write(buf[:1])          // nolint : errchk
+			enc.Write(outpoint.Hash[:]) // nolint : errchk
+			binary.LittleEndian.PutUint32(buf[:4], outpoint.Index)
+			enc.Write(buf[:4]) // nolint : errchk
+			err = wire.WriteVarInt(enc, 0, uint64(len(serialized)))
+			if err != nil {
+				return err
+			}
+			enc.Write(serialized) // nolint : errchk
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	info.TxOuts = stats.txOuts
+	info.MuHash = stats.muHash.Finalize()
+
+	buf[0] = 0
+	enc.Write(buf[:1])        // nolint : errchk
+	bw.Write(hasher.Sum(nil)) // nolint : errchk
+
+	if b.claimTrie != nil {
+		_, err = b.claimTrie.ExportSnapshot(bw, tip.height)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return info, bw.Flush()
+}
+
+// LoadUtxoSnapshot replaces the chain state, which must not have any blocks
+// besides the genesis block, with the utxo snapshot read from r, whose size is
+// passed.  The snapshot is only accepted when its block and the MuHash of its
+// unspent outputs match one of the assumed utxo sets of the chain, and its
+// headers and block pass the usual sanity and context checks.  The claim trie,
+// when the chain has one, is replaced with the one of the snapshot, which must
+// match the claim trie root committed to by the block.
+//
+// The chain then continues from the block of the snapshot.  The blocks below
+// it are assumed to be valid until they are validated in the background, see
+// ValidateSnapshotBlock, so they are reported as pruned and the main chain
+// can't be reorganized below the snapshot.  Since the blocks are never
+// connected to the main chain, the chain can't have an index manager.  When
+// the chain has a claim trie, the configuration of the claim trie of the
+// blocks below the snapshot must be passed to New as well.
+//
+// A load interrupted before it completes resets the chain state to the genesis
+// block the next time the chain is loaded with New.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) LoadUtxoSnapshot(r io.ReaderAt, size int64,
+	interrupt <-chan struct{}) (*UtxoSnapshotInfo, error) {
+
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	if b.bestChain.Height() != 0 {
+		return nil, fmt.Errorf("a utxo snapshot can only be loaded "+
+			"before any block is connected, the best height is %d",
+			b.bestChain.Height())
+	}
+	if b.indexManager != nil {
+		return nil, errors.New("a utxo snapshot can't be loaded with " +
+			"optional indexes enabled")
+	}
+
+	section := io.NewSectionReader(r, 0, size)
+	br := bufio.NewReader(section)
+	hasher := sha256.New()
+	dec := io.TeeReader(br, hasher)
+
+	var raw [61]byte
+	if _, err := io.ReadFull(dec, raw[:]); err != nil {
+		return nil, fmt.Errorf("reading utxo snapshot header: %v", err)
+	}
+	if !bytes.Equal(raw[:8], utxoSnapshotMagic[:]) {
+		return nil, errors.New("not a utxo snapshot")
+	}
+	version := binary.LittleEndian.Uint32(raw[8:12])
+	if version != UtxoSnapshotVersion {
+		return nil, fmt.Errorf("unsupported utxo snapshot version %d",
+			version)
+	}
+	net := wire.BitcoinNet(binary.LittleEndian.Uint32(raw[12:16]))
+	if net != b.chainParams.Net {
+		return nil, fmt.Errorf("the utxo snapshot is for network %v "+
+			"instead of %v", net, b.chainParams.Net)
+	}
+	height := int32(binary.LittleEndian.Uint32(raw[16:20]))
+	var hash chainhash.Hash
+	copy(hash[:], raw[20:52])
+	totalTxns := binary.LittleEndian.Uint64(raw[52:60])
+	hasClaimTrie := raw[60] == 1
+
+	assumed := b.findAssumeUtxo(height, &hash)
+	if assumed == nil {
+		return nil, fmt.Errorf("block %v at height %d of the utxo "+
+			"snapshot is not one of the assumed utxo sets", hash,
+			height)
+	}
+	if b.claimTrie != nil && !hasClaimTrie {
+		return nil, errors.New("the utxo snapshot doesn't include " +
+			"the claim trie")
+	}
+	if b.claimTrie != nil && b.snapshotClaimTrieCfg == nil {
+		return nil, errors.New("a utxo snapshot can't be loaded " +
+			"without a configuration for the claim trie of the " +
+			"blocks below it")
+	}
+
+	log.Infof("Loading the utxo snapshot of block %v (height %d)", hash,
+		height)
+
+	// Check the headers of the snapshot as they would have been when
+	// connecting the blocks.
+	nodes := make([]*blockNode, 0, height)
+	prevNode := b.bestChain.Genesis()
+	for h := int32(1); h <= height; h++ {
+		if h%10000 == 0 && interruptRequested(interrupt) {
+			return nil, errInterruptRequested
+		}
+
+		var header wire.BlockHeader
+		if err := header.Deserialize(dec); err != nil {
+			return nil, fmt.Errorf("reading header at height %d: %v",
+				h, err)
+		}
+		if header.PrevBlock != prevNode.hash {
+			return nil, fmt.Errorf("header at height %d doesn't "+
+				"connect to the previous one", h)
+		}
+		err := checkBlockHeaderSanity(&header, b.chainParams.PowLimit,
+			b.timeSource, BFNone)
+		if err != nil {
+			return nil, err
+		}
+		err = b.checkBlockHeaderContext(&header, prevNode, BFNone)
+		if err != nil {
+			return nil, err
+		}
+
+		node := newBlockNode(&header, prevNode)
+		node.status = statusValid
+		nodes = append(nodes, node)
+		prevNode = node
+	}
+	tip := prevNode
+	if tip.hash != hash {
+		return nil, fmt.Errorf("the headers of the utxo snapshot end "+
+			"at block %v instead of %v", tip.hash, hash)
+	}
+
+	var msgBlock wire.MsgBlock
+	if err := msgBlock.Deserialize(dec); err != nil {
+		return nil, fmt.Errorf("reading block: %v", err)
+	}
+	block := btcutil.NewBlock(&msgBlock)
+	block.SetHeight(height)
+	if *block.Hash() != hash {
+		return nil, fmt.Errorf("the block of the utxo snapshot is %v "+
+			"instead of %v", block.Hash(), hash)
+	}
+	err := checkBlockSanity(block, b.chainParams.PowLimit, b.timeSource,
+		BFNone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mark the load in progress as a reset of the chain state so it's
+	// undone on the next start when it's interrupted.
+	err = b.db.Update(func(dbTx database.Tx) error {
+		return dbPutReindexState(dbTx, reindexStateResetIndex,
+			b.chainParams.GenesisHash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	abort := func(err error) (*UtxoSnapshotInfo, error) {
+		clearErr := dbClearBucket(b.db, utxoSetBucketName, nil)
+		if clearErr == nil {
+			clearErr = b.db.Update(func(dbTx database.Tx) error {
+				return dbTx.Metadata().Delete(reindexKeyName)
+			})
+		}
+		if clearErr != nil {
+			log.Errorf("Unable to clear the utxo set of the "+
+				"failed snapshot load, the chain state will "+
+				"be reset on the next start: %v", clearErr)
+		}
+		return nil, err
+	}
+
+	// Replace the utxo set with the outputs of the snapshot.  The outputs
+	// of the genesis block are part of the snapshot when unspent.
+	if err := dbClearBucket(b.db, utxoSetBucketName, interrupt); err != nil {
+		return abort(err)
+	}
+	stats := &utxoStats{muHash: muhash.New()}
+	type utxoRow struct {
+		key   *[]byte
+		value []byte
+	}
+	rows := make([]utxoRow, 0, utxoSnapshotBatchSize)
+	writeRows := func() error {
+		err := b.db.Update(func(dbTx database.Tx) error {
+			utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+			for _, row := range rows {
+				err := utxoBucket.Put(*row.key, row.value)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		rows = rows[:0]
+		return err
+	}
+	var marker [1]byte
+	for {
+		if _, err := io.ReadFull(dec, marker[:]); err != nil {
+			return abort(fmt.Errorf("reading output marker: %v", err))
+		}
+		if marker[0] == 0 {
+			break
+		}
+		if marker[0] != 1 {
+			return abort(fmt.Errorf("invalid output marker %d",
+				marker[0]))
+		}
+
+		var outpoint wire.OutPoint
+		var index [4]byte
+		_, err := io.ReadFull(dec, outpoint.Hash[:])
+		if err == nil {
+			_, err = io.ReadFull(dec, index[:])
+		}
+		if err != nil {
+			return abort(fmt.Errorf("reading outpoint: %v", err))
+		}
+		outpoint.Index = binary.LittleEndian.Uint32(index[:])
+		length, err := wire.ReadVarInt(dec, 0)
+		if err != nil || length > wire.MaxBlockPayload {
+			return abort(fmt.Errorf("reading output %v: invalid "+
+				"length", outpoint))
+		}
+		serialized := make([]byte, length)
+		if _, err := io.ReadFull(dec, serialized); err != nil {
+			return abort(fmt.Errorf("reading output %v: %v",
+				outpoint, err))
+		}
+		entry, err := deserializeUtxoEntry(serialized)
+		if err != nil {
+			return abort(fmt.Errorf("decoding output %v: %v",
+				outpoint, err))
+		}
+		stats.add(&outpoint, entry.Amount(), entry.PkScript(),
+			entry.BlockHeight(), entry.IsCoinBase())
+
+		rows = append(rows, utxoRow{outpointKey(outpoint), serialized})
+		if len(rows) == utxoSnapshotBatchSize {
+			if interruptRequested(interrupt) {
+				return abort(errInterruptRequested)
+			}
+			if err := writeRows(); err != nil {
+				return abort(err)
+			}
+			log.Infof("Loaded %d unspent outputs", stats.txOuts)
+		}
+	}
+	if err := writeRows(); err != nil {
+		return abort(err)
+	}
+
+	var checksum [sha256.Size]byte
+	if _, err := io.ReadFull(br, checksum[:]); err != nil {
+		return abort(fmt.Errorf("reading checksum: %v", err))
+	}
+	if !bytes.Equal(checksum[:], hasher.Sum(nil)) {
+		return abort(errors.New("utxo snapshot checksum mismatch"))
+	}
+	muHash := chainhash.Hash(stats.muHash.Finalize())
+	if muHash != *assumed.UtxoSetHash {
+		return abort(fmt.Errorf("the MuHash %v of the %d unspent "+
+			"outputs of the utxo snapshot doesn't match the assumed "+
+			"utxo set hash %v", muHash, stats.txOuts,
+			assumed.UtxoSetHash))
+	}
+
+	// The claim trie snapshot follows the utxo set.
+	if b.claimTrie != nil {
+		pos, err := section.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return abort(err)
+		}
+		offset := pos - int64(br.Buffered())
+		claimTrieReader := io.NewSectionReader(r, offset, size-offset)
+		header, err := claimtrie.ReadSnapshotHeader(claimTrieReader)
+		if err != nil {
+			return abort(err)
+		}
+		if header.Height != height || header.ClaimTrieRoot != tip.claimTrie {
+			return abort(fmt.Errorf("claim trie snapshot root %v "+
+				"at height %d doesn't match %v of the block",
+				header.ClaimTrieRoot, header.Height,
+				tip.claimTrie))
+		}
+		if _, err = claimTrieReader.Seek(0, io.SeekStart); err != nil {
+			return abort(err)
+		}
+		if _, err = b.claimTrie.ImportSnapshot(claimTrieReader); err != nil {
+			return abort(err)
+		}
+		b.claimTrie.FlushToDisk()
+	}
+
+	// Remove any claim trie left by a previous validation of the blocks
+	// below a snapshot, which are validated from the genesis block.
+	if b.snapshotClaimTrieCfg != nil {
+		if err := claimtrie.Remove(*b.snapshotClaimTrieCfg); err != nil {
+			return abort(err)
+		}
+	}
+
+	// Store the headers of the snapshot in the block index, and finally
+	// the block of the snapshot as the new best block, along with the
+	// state of the validation of the blocks below it.
+	for i := 0; i < len(nodes); i += utxoSnapshotBatchSize {
+		batch := nodes[i:]
+		if len(batch) > utxoSnapshotBatchSize {
+			batch = batch[:utxoSnapshotBatchSize]
+		}
+		err := b.db.Update(func(dbTx database.Tx) error {
+			for _, node := range batch {
+				err := dbStoreBlockNode(dbTx, node)
+				if err != nil {
+					return err
+				}
+				err = dbPutBlockIndex(dbTx, &node.hash, node.height)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tip.status |= statusDataStored
+	blockSize := uint64(msgBlock.SerializeSize())
+	blockWeight := uint64(GetBlockWeight(block))
+	numTxns := uint64(len(msgBlock.Transactions))
+	state := newBestState(tip, blockSize, blockWeight, numTxns, totalTxns,
+		tip.CalcPastMedianTime())
+	sv := &snapshotValidation{muHash: muHash}
+	err = b.db.Update(func(dbTx database.Tx) error {
+		err := dbStoreBlock(dbTx, block)
+		if err != nil {
+			return err
+		}
+		err = dbStoreBlockNode(dbTx, tip)
+		if err != nil {
+			return err
+		}
+		err = dbPutBestState(dbTx, state, tip.workSum)
+		if err != nil {
+			return err
+		}
+		err = dbPutUtxoSnapshot(dbTx, height, &hash)
+		if err != nil {
+			return err
+		}
+		bucket, err := dbTx.Metadata().CreateBucketIfNotExists(
+			snapshotUtxoSetBucketName)
+		if err != nil {
+			return err
+		}
+		if err := bucket.DeleteRange(nil, nil); err != nil {
+			return err
+		}
+		err = dbPutSnapshotValidation(dbTx, sv)
+		if err != nil {
+			return err
+		}
+		return dbTx.Metadata().Delete(reindexKeyName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		b.index.addNode(node)
+	}
+	if b.index.headers != nil {
+		if err := b.index.moveBucketToHeaderFile(); err != nil {
+			return nil, err
+		}
+	}
+	b.bestChain.SetTip(tip)
+	b.stateLock.Lock()
+	b.stateSnapshot = state
+	b.stateLock.Unlock()
+	b.pruneHeight = height
+	b.utxoSnapshotHeight = height
+	b.snapshotValidation = sv
+	b.rewindSnapshotValidation(sv)
+	b.checkpointNode = nil
+	b.nextCheckpoint = nil
+	b.utxoStatsLock.Lock()
+	b.utxoStats = stats
+	b.utxoStatsLock.Unlock()
+
+	log.Infof("Loaded the utxo snapshot of block %v (height %d) with %d "+
+		"unspent outputs", hash, height, stats.txOuts)
+
+	return &UtxoSnapshotInfo{
+		Hash:   hash,
+		Height: height,
+		TxOuts: stats.txOuts,
+		MuHash: muHash,
+	}, nil
+}
+
+// dbPutUtxoSnapshot stores the height and the hash of the block of the utxo
+// snapshot the chain state was loaded from.
+func dbPutUtxoSnapshot(dbTx database.Tx, height int32, hash *chainhash.Hash) error {
+	v := make([]byte, 4+chainhash.HashSize)
+	byteOrder.PutUint32(v, uint32(height))
+	copy(v[4:], hash[:])
+	return dbTx.Metadata().Put(utxoSnapshotKeyName, v)
+}
+
+// initUtxoSnapshotState loads the height of the utxo snapshot the chain state
+// was loaded from, if any.  The data of the blocks below it is not available.
+func (b *BlockChain) initUtxoSnapshotState() error {
+	return b.db.View(func(dbTx database.Tx) error {
+		v := dbTx.Metadata().Get(utxoSnapshotKeyName)
+		if len(v) != 4+chainhash.HashSize {
+			return nil
+		}
+		b.utxoSnapshotHeight = int32(byteOrder.Uint32(v))
+		if b.pruneHeight < b.utxoSnapshotHeight {
+			b.pruneHeight = b.utxoSnapshotHeight
+		}
+		return nil
+	})
+}
+
+// UtxoSnapshotHeight returns the height of the block of the utxo snapshot the
+// chain state was loaded from, or zero when it wasn't loaded from one.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) UtxoSnapshotHeight() int32 {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+	return b.utxoSnapshotHeight
+}
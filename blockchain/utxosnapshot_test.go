@@ -0,0 +1,288 @@
+package blockchain_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/blockchain/fullblocktests"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/txscript"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestUtxoSnapshot ensures a utxo snapshot dumped by a chain can be loaded by a
+// new chain only for an assumed utxo set, that the new chain then syncs from
+// the block of the snapshot to the same state as the original chain, and that
+// the blocks below the snapshot are validated against it in the background.
+func TestUtxoSnapshot(t *testing.T) {
+	tests, err := fullblocktests.Generate(false)
+	if err != nil {
+		t.Fatalf("failed to generate tests: %v", err)
+	}
+
+	params := *fullblocktests.FbRegressionNetParams
+	newChain := func(assumeUtxo []chaincfg.AssumeUtxo) (*blockchain.BlockChain, database.DB) {
+		t.Helper()
+		db, err := database.Create(testDbType, t.TempDir(), blockDataNet)
+		if err != nil {
+			t.Fatalf("error creating db: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		chain, err := blockchain.New(&blockchain.Config{
+			DB:          db,
+			ChainParams: &params,
+			TimeSource:  blockchain.NewMedianTime(),
+			SigCache:    txscript.NewSigCache(1000),
+			AssumeUtxo:  assumeUtxo,
+		})
+		if err != nil {
+			t.Fatalf("failed to create chain instance: %v", err)
+		}
+		return chain, db
+	}
+	processBlocks := func(chain *blockchain.BlockChain, blocks []*btcutil.Block) {
+		t.Helper()
+		for _, block := range blocks {
+			_, _, err := chain.ProcessBlock(block, blockchain.BFNone)
+			if err != nil {
+				t.Fatalf("block %v should have been accepted: %v",
+					block.Hash(), err)
+			}
+		}
+	}
+
+	// Process the blocks of the tests up to the first one which isn't
+	// accepted to find the blocks of the resulting main chain.
+	fullChain, _ := newChain(nil)
+	func() {
+		for _, instances := range tests {
+			for _, instance := range instances {
+				item, ok := instance.(fullblocktests.AcceptedBlock)
+				if !ok {
+					return
+				}
+				block := btcutil.NewBlock(item.Block)
+				_, _, err := fullChain.ProcessBlock(block,
+					blockchain.BFNone)
+				if err != nil {
+					t.Fatalf("block %q should have been "+
+						"accepted: %v", item.Name, err)
+				}
+			}
+		}
+	}()
+	want := fullChain.BestSnapshot()
+	if want.Height < 2 {
+		t.Fatalf("not enough blocks were processed")
+	}
+	var blocks []*btcutil.Block
+	for height := int32(1); height <= want.Height; height++ {
+		block, err := fullChain.BlockByHeight(height)
+		if err != nil {
+			t.Fatalf("BlockByHeight(%d): %v", height, err)
+		}
+		blocks = append(blocks, block)
+	}
+	wantStats, err := fullChain.UtxoSetStats()
+	if err != nil {
+		t.Fatalf("UtxoSetStats: %v", err)
+	}
+
+	// Dump a snapshot in the middle of the chain.
+	snapshotHeight := want.Height / 2
+	dumpChain, _ := newChain(nil)
+	processBlocks(dumpChain, blocks[:snapshotHeight])
+	var snapshot bytes.Buffer
+	info, err := dumpChain.DumpUtxoSnapshot(&snapshot)
+	if err != nil {
+		t.Fatalf("DumpUtxoSnapshot: %v", err)
+	}
+	dumpStats, err := dumpChain.UtxoSetStats()
+	if err != nil {
+		t.Fatalf("UtxoSetStats: %v", err)
+	}
+	if info.Height != snapshotHeight || info.Hash != *blocks[snapshotHeight-1].Hash() ||
+		info.TxOuts != dumpStats.TxOuts || info.MuHash != dumpStats.MuHash {
+
+		t.Fatalf("unexpected snapshot info %+v, want stats %+v", info,
+			dumpStats)
+	}
+	snapshotBytes := snapshot.Bytes()
+	load := func(chain *blockchain.BlockChain) (*blockchain.UtxoSnapshotInfo, error) {
+		return chain.LoadUtxoSnapshot(bytes.NewReader(snapshotBytes),
+			int64(len(snapshotBytes)), nil)
+	}
+
+	// The snapshot is rejected when it isn't an assumed utxo set or its
+	// outputs don't match the assumed one.
+	wrongHash := chainhash.Hash{0x01}
+	rejected := [][]chaincfg.AssumeUtxo{
+		nil,
+		{{Height: snapshotHeight, Hash: &info.Hash, UtxoSetHash: &wrongHash}},
+		{{Height: snapshotHeight - 1, Hash: &info.Hash, UtxoSetHash: &info.MuHash}},
+	}
+	for i, assumeUtxo := range rejected {
+		chain, _ := newChain(assumeUtxo)
+		if _, err := load(chain); err == nil {
+			t.Fatalf("#%d: snapshot was loaded", i)
+		}
+		best := chain.BestSnapshot()
+		if best.Height != 0 {
+			t.Fatalf("#%d: unexpected best height %d after a failed "+
+				"load", i, best.Height)
+		}
+		stats, err := chain.UtxoSetStats()
+		if err != nil {
+			t.Fatalf("#%d: UtxoSetStats: %v", i, err)
+		}
+		if stats.TxOuts != 0 {
+			t.Fatalf("#%d: %d outputs left after a failed load", i,
+				stats.TxOuts)
+		}
+	}
+
+	// Load the snapshot for the assumed utxo set and sync the rest of the
+	// chain.
+	assumeUtxo := []chaincfg.AssumeUtxo{{
+		Height:      snapshotHeight,
+		Hash:        &info.Hash,
+		UtxoSetHash: &info.MuHash,
+	}}
+	chain, db := newChain(assumeUtxo)
+	if _, err := load(chain); err != nil {
+		t.Fatalf("LoadUtxoSnapshot: %v", err)
+	}
+	if _, err := load(chain); err == nil {
+		t.Fatalf("snapshot was loaded twice")
+	}
+	best := chain.BestSnapshot()
+	if best.Hash != info.Hash || best.Height != snapshotHeight {
+		t.Fatalf("unexpected best block %v (height %d) after load",
+			best.Hash, best.Height)
+	}
+	if !chain.IsBlockPruned(blocks[0].Hash()) {
+		t.Fatalf("block below the snapshot is not reported as pruned")
+	}
+	if chain.IsBlockPruned(&info.Hash) {
+		t.Fatalf("block of the snapshot is reported as pruned")
+	}
+
+	// A block below the snapshot fetched again is stored once it matches
+	// its header, while the blocks which aren't pruned are rejected.
+	fetched := blocks[snapshotHeight-2]
+	fetchedBytes, err := fetched.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	tampered, err := btcutil.NewBlockFromBytes(fetchedBytes)
+	if err != nil {
+		t.Fatalf("NewBlockFromBytes: %v", err)
+	}
+	tampered.MsgBlock().Transactions[0].LockTime++
+	if err := chain.StorePrunedBlock(tampered); err == nil {
+		t.Fatalf("block not matching its header was stored")
+	}
+	if err := chain.StorePrunedBlock(blocks[snapshotHeight-1]); err == nil {
+		t.Fatalf("block of the snapshot was stored")
+	}
+	if err := chain.StorePrunedBlock(fetched); err != nil {
+		t.Fatalf("StorePrunedBlock: %v", err)
+	}
+	if chain.IsBlockPruned(fetched.Hash()) {
+		t.Fatalf("stored block is reported as pruned")
+	}
+	err = db.View(func(dbTx database.Tx) error {
+		_, err := dbTx.FetchBlock(fetched.Hash())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("stored block can't be fetched: %v", err)
+	}
+
+	// The blocks below the snapshot are validated in order, and the
+	// validation continues after a restart.
+	hashes := chain.SnapshotValidationBlocks(2)
+	if len(hashes) != 2 || hashes[0] != *blocks[0].Hash() ||
+		hashes[1] != *blocks[1].Hash() {
+
+		t.Fatalf("unexpected blocks to validate %v", hashes)
+	}
+	if err := chain.ValidateSnapshotBlock(blocks[1]); err == nil {
+		t.Fatalf("block validated out of order")
+	}
+	validateBlocks := func(chain *blockchain.BlockChain, blocks []*btcutil.Block) {
+		t.Helper()
+		for _, block := range blocks {
+			if err := chain.ValidateSnapshotBlock(block); err != nil {
+				t.Fatalf("ValidateSnapshotBlock(%v): %v",
+					block.Hash(), err)
+			}
+		}
+	}
+	validatedHeight := snapshotHeight / 2
+	validateBlocks(chain, blocks[:validatedHeight])
+	height, err := chain.SnapshotValidationHeight()
+	if err != nil || height != validatedHeight {
+		t.Fatalf("got validation height %d (%v), want %d", height, err,
+			validatedHeight)
+	}
+
+	processBlocks(chain, blocks[snapshotHeight:])
+	got := chain.BestSnapshot()
+	if got.Hash != want.Hash || got.Height != want.Height ||
+		got.TotalTxns != want.TotalTxns {
+
+		t.Fatalf("unexpected best state -- got %v (height %d, %d txns), "+
+			"want %v (height %d, %d txns)", got.Hash, got.Height,
+			got.TotalTxns, want.Hash, want.Height, want.TotalTxns)
+	}
+	gotStats, err := chain.UtxoSetStats()
+	if err != nil {
+		t.Fatalf("UtxoSetStats: %v", err)
+	}
+	if gotStats.MuHash != wantStats.MuHash || gotStats.TxOuts != wantStats.TxOuts {
+		t.Fatalf("unexpected utxo set stats %+v, want %+v", gotStats,
+			wantStats)
+	}
+
+	// The chain state is loaded again on the next start.
+	if err := chain.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	chain, err = blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  blockchain.NewMedianTime(),
+		SigCache:    txscript.NewSigCache(1000),
+	})
+	if err != nil {
+		t.Fatalf("failed to load chain instance: %v", err)
+	}
+	if got := chain.BestSnapshot(); got.Hash != want.Hash {
+		t.Fatalf("unexpected best block %v after restart, want %v",
+			got.Hash, want.Hash)
+	}
+	if chain.UtxoSnapshotHeight() != snapshotHeight ||
+		!chain.IsBlockPruned(blocks[0].Hash()) {
+
+		t.Fatalf("utxo snapshot state was not restored")
+	}
+	height, err = chain.SnapshotValidationHeight()
+	if err != nil || height != validatedHeight {
+		t.Fatalf("got validation height %d (%v) after restart, want %d",
+			height, err, validatedHeight)
+	}
+	validateBlocks(chain, blocks[validatedHeight:snapshotHeight])
+	height, err = chain.SnapshotValidationHeight()
+	if err != nil || height != snapshotHeight {
+		t.Fatalf("got validation height %d (%v), want %d", height, err,
+			snapshotHeight)
+	}
+	if hashes := chain.SnapshotValidationBlocks(1); hashes != nil {
+		t.Fatalf("unexpected blocks to validate %v once validated",
+			hashes)
+	}
+}
@@ -0,0 +1,220 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/lbryio/lbcd/blockchain/internal/muhash"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// UtxoStats houses statistics about the unspent transaction output set as of a
+// block of the main chain.
+type UtxoStats struct {
+	// Hash and Height identify the block the statistics are for.
+	Hash   chainhash.Hash
+	Height int32
+
+	// TxOuts is the number of unspent transaction outputs.
+	TxOuts int64
+
+	// TotalAmount is the total amount of the unspent transaction outputs.
+	TotalAmount int64
+
+	// BogoSize is a database independent estimate of the size of the set.
+	BogoSize int64
+
+	// MuHash is the MuHash3072 of the set, which matches the one computed
+	// by Bitcoin Core for the same set.
+	MuHash chainhash.Hash
+}
+
+// utxoStats maintains the statistics of the unspent transaction output set as
+// blocks are connected and disconnected.
+type utxoStats struct {
+	txOuts      int64
+	totalAmount int64
+	bogoSize    int64
+	muHash      *muhash.MuHash
+}
+
+// utxoStatsElement returns the serialization of an unspent transaction output
+// hashed by MuHash.  It is the outpoint, followed by the height of the block
+// containing the output shifted left by one with the lowest bit set for
+// coinbase outputs, and the serialized output.
+func utxoStatsElement(outpoint *wire.OutPoint, amount int64, pkScript []byte,
+	height int32, isCoinBase bool) []byte {
+
+	code := uint32(height) << 1
+	if isCoinBase {
+		code |= 1
+	}
+
+	var element bytes.Buffer
+	element.Grow(chainhash.HashSize + 4 + 4 + 8 +
+		wire.VarIntSerializeSize(uint64(len(pkScript))) + len(pkScript))
+	var scratch [8]byte
+	element.Write(outpoint.Hash[:])
+	binary.LittleEndian.PutUint32(scratch[:4], outpoint.Index)
+	element.Write(scratch[:4])
+	binary.LittleEndian.PutUint32(scratch[:4], code)
+	element.Write(scratch[:4])
+	binary.LittleEndian.PutUint64(scratch[:], uint64(amount))
+	element.Write(scratch[:])
+	_ = wire.WriteVarInt(&element, 0, uint64(len(pkScript)))
+	element.Write(pkScript)
+	return element.Bytes()
+}
+
+// add adds the passed unspent transaction output to the statistics.
+func (s *utxoStats) add(outpoint *wire.OutPoint, amount int64, pkScript []byte,
+	height int32, isCoinBase bool) {
+
+	s.txOuts++
+	s.totalAmount += amount
+	s.bogoSize += utxoBogoSize(pkScript)
+	s.muHash.Add(utxoStatsElement(outpoint, amount, pkScript, height,
+		isCoinBase))
+}
+
+// remove removes the passed unspent transaction output from the statistics.
+func (s *utxoStats) remove(outpoint *wire.OutPoint, amount int64,
+	pkScript []byte, height int32, isCoinBase bool) {
+
+	s.txOuts--
+	s.totalAmount -= amount
+	s.bogoSize -= utxoBogoSize(pkScript)
+	s.muHash.Remove(utxoStatsElement(outpoint, amount, pkScript, height,
+		isCoinBase))
+}
+
+// utxoBogoSize returns the estimated size of an unspent transaction output
+// with the passed public key script as defined by Bitcoin Core: the outpoint,
+// the height and coinbase flag, the amount, the script length and the script.
+func utxoBogoSize(pkScript []byte) int64 {
+	return 32 + 4 + 4 + 8 + 2 + int64(len(pkScript))
+}
+
+// applyBlock updates the statistics with the outputs created and spent by the
+// passed block, which is being connected to or disconnected from the main
+// chain.  The stxos must be the spent outputs of the block in the order of the
+// spend journal.
+func (s *utxoStats) applyBlock(block *btcutil.Block, stxos []SpentTxOut,
+	connect bool) {
+
+	update := s.remove
+	if connect {
+		update = s.add
+	}
+
+	// Outputs created by the block.  Provably unspendable outputs are
+	// never added to the utxo set.
+	height := block.Height()
+	for i, tx := range block.Transactions() {
+		outpoint := wire.OutPoint{Hash: *tx.Hash()}
+		for txOutIdx, txOut := range tx.MsgTx().TxOut {
+			if txscript.IsUnspendable(txOut.PkScript) {
+				continue
+			}
+			outpoint.Index = uint32(txOutIdx)
+			update(&outpoint, txOut.Value, txOut.PkScript, height,
+				i == 0)
+		}
+	}
+
+	// Outputs spent by the block.  The coinbase doesn't spend anything.
+	update = s.add
+	if connect {
+		update = s.remove
+	}
+	var stxoIdx int
+	for _, tx := range block.Transactions()[1:] {
+		for _, txIn := range tx.MsgTx().TxIn {
+			stxo := &stxos[stxoIdx]
+			stxoIdx++
+			update(&txIn.PreviousOutPoint, stxo.Amount,
+				stxo.PkScript, stxo.Height, stxo.IsCoinBase)
+		}
+	}
+}
+
+// scanUtxoStats computes the statistics of the entire utxo set housed in the
+// passed database bucket.
+func scanUtxoStats(dbTx database.Tx, bucketName []byte) (*utxoStats, error) {
+	stats := &utxoStats{muHash: muhash.New()}
+	cursor := dbTx.Metadata().Bucket(bucketName).
+		PrefetchCursor(utxoSetPrefetchBlocks)
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key := cursor.Key()
+		var outpoint wire.OutPoint
+		copy(outpoint.Hash[:], key[:chainhash.HashSize])
+		idx, _ := deserializeVLQ(key[chainhash.HashSize:])
+		outpoint.Index = uint32(idx)
+
+		entry, err := deserializeUtxoEntry(cursor.Value())
+		if err != nil {
+			return nil, err
+		}
+		stats.add(&outpoint, entry.Amount(), entry.PkScript(),
+			entry.BlockHeight(), entry.IsCoinBase())
+	}
+
+	return stats, nil
+}
+
+// updateUtxoStats updates the maintained utxo set statistics, if any, with the
+// passed block being connected to or disconnected from the main chain.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) updateUtxoStats(block *btcutil.Block, stxos []SpentTxOut,
+	connect bool) {
+
+	b.utxoStatsLock.Lock()
+	if b.utxoStats != nil {
+		b.utxoStats.applyBlock(block, stxos, connect)
+	}
+	b.utxoStatsLock.Unlock()
+}
+
+// UtxoSetStats returns statistics about the unspent transaction output set as
+// of the end of the main chain.
+//
+// The first call scans the entire utxo set, which can take a while.  The
+// statistics are then maintained incrementally as blocks are connected and
+// disconnected so later calls return immediately.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) UtxoSetStats() (*UtxoStats, error) {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	b.utxoStatsLock.Lock()
+	defer b.utxoStatsLock.Unlock()
+
+	if b.utxoStats == nil {
+		log.Infof("Scanning the utxo set to compute its statistics")
+		err := b.db.View(func(dbTx database.Tx) error {
+			var err error
+			b.utxoStats, err = scanUtxoStats(dbTx, utxoSetBucketName)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tip := b.bestChain.Tip()
+	stats := &UtxoStats{
+		Hash:        tip.hash,
+		Height:      tip.height,
+		TxOuts:      b.utxoStats.txOuts,
+		TotalAmount: b.utxoStats.totalAmount,
+		BogoSize:    b.utxoStats.bogoSize,
+		MuHash:      b.utxoStats.muHash.Finalize(),
+	}
+	return stats, nil
+}
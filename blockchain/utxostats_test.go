@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbcd/blockchain/internal/muhash"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestUtxoStatsApplyBlock ensures the utxo set statistics are updated with the
+// outputs created and spent by connected blocks, ignoring provably unspendable
+// outputs, and that disconnecting the block restores them.
+func TestUtxoStatsApplyBlock(t *testing.T) {
+	t.Parallel()
+
+	p2pkh := []byte{0x76, 0xa9, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06,
+		0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+		0x11, 0x12, 0x13, 0x14, 0x88, 0xac}
+	nullData := []byte{0x6a, 0x01, 0x01}
+
+	// The utxo set initially holds a single output created at height 5.
+	prevOut := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 2}
+	stats := &utxoStats{muHash: muhash.New()}
+	stats.add(&prevOut, 5000, p2pkh, 5, false)
+	initialHash := stats.muHash.Finalize()
+
+	// The block spends it with a transaction creating an output and a
+	// provably unspendable one.
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  []byte{0x01, 0x0a},
+	})
+	coinbase.AddTxOut(wire.NewTxOut(100, p2pkh))
+	spend := wire.NewMsgTx(1)
+	spend.AddTxIn(wire.NewTxIn(&prevOut, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(4000, p2pkh))
+	spend.AddTxOut(wire.NewTxOut(0, nullData))
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{coinbase, spend},
+	})
+	block.SetHeight(10)
+	stxos := []SpentTxOut{{Amount: 5000, PkScript: p2pkh, Height: 5}}
+
+	stats.applyBlock(block, stxos, true)
+
+	want := &utxoStats{muHash: muhash.New()}
+	want.add(&wire.OutPoint{Hash: coinbase.TxHash()}, 100, p2pkh, 10,
+		true)
+	want.add(&wire.OutPoint{Hash: spend.TxHash()}, 4000, p2pkh, 10, false)
+	if stats.txOuts != 2 || stats.totalAmount != 4100 ||
+		stats.bogoSize != want.bogoSize {
+
+		t.Fatalf("wrong stats after connect - got %d outputs, amount "+
+			"%d, bogosize %d", stats.txOuts, stats.totalAmount,
+			stats.bogoSize)
+	}
+	if stats.muHash.Finalize() != want.muHash.Finalize() {
+		t.Fatal("wrong muhash after connect")
+	}
+
+	stats.applyBlock(block, stxos, false)
+	if stats.txOuts != 1 || stats.totalAmount != 5000 {
+		t.Fatalf("wrong stats after disconnect - got %d outputs, "+
+			"amount %d", stats.txOuts, stats.totalAmount)
+	}
+	if stats.muHash.Finalize() != initialHash {
+		t.Fatal("wrong muhash after disconnect")
+	}
+}
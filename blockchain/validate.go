@@ -491,6 +491,69 @@ func checkBlockHeaderSanity(header *wire.BlockHeader, powLimit *big.Int, timeSou
 	return nil
 }
 
+// HeaderChain checks a chain of headers extending a block of the chain before
+// their blocks are downloaded.  Each header is checked both on its own and in
+// the context of the headers before it, as it would be once its block is
+// processed, such as against the difficulty retarget rules and the median time
+// of the previous blocks.  The headers are not added to the block index.
+type HeaderChain struct {
+	chain *BlockChain
+	tip   *blockNode
+
+	// nodes are the nodes of the most recent headers of the chain, which
+	// are the ones the next headers are checked against.  The older ones
+	// are detached from them so they don't have to be kept in memory.
+	nodes []*blockNode
+}
+
+// NewHeaderChain returns a HeaderChain extending the block of the passed hash,
+// which must be known to the chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) NewHeaderChain(hash *chainhash.Hash) (*HeaderChain, error) {
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return nil, fmt.Errorf("block %v is not known", hash)
+	}
+	return &HeaderChain{chain: b, tip: node}, nil
+}
+
+// Connect checks the passed header, which must extend the last header of the
+// header chain, and adds it to the header chain once it passes the checks.
+//
+// This function is safe for concurrent access.
+func (hc *HeaderChain) Connect(header *wire.BlockHeader) error {
+	if header.PrevBlock != hc.tip.hash {
+		str := fmt.Sprintf("header of block %v doesn't extend %v",
+			header.BlockHash(), hc.tip.hash)
+		return ruleError(ErrPrevBlockNotBest, str)
+	}
+
+	b := hc.chain
+	err := checkBlockHeaderSanity(header, b.chainParams.PowLimit,
+		b.timeSource, BFNone)
+	if err != nil {
+		return err
+	}
+	b.chainLock.Lock()
+	err = b.checkBlockHeaderContext(header, hc.tip, BFNone)
+	b.chainLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Only the headers needed to check the next ones in context are kept.
+	hc.tip = newBlockNode(header, hc.tip)
+	hc.nodes = append(hc.nodes, hc.tip)
+	keep := int(b.blocksPerRetarget) + medianTimeBlocks
+	if len(hc.nodes) >= 2*keep {
+		first := len(hc.nodes) - keep
+		hc.nodes[first].parent = nil
+		hc.nodes = append(hc.nodes[:0], hc.nodes[first:]...)
+	}
+	return nil
+}
+
 // checkBlockSanity performs some preliminary checks on a block to ensure it is
 // sane before continuing with block processing.  These checks are context free.
 //
@@ -728,6 +791,16 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 		return ruleError(ErrForkTooOld, str)
 	}
 
+	// Prevent blocks which fork the main chain at or below the block of
+	// the utxo snapshot the chain state was loaded from since the chain
+	// state below it isn't available to reorganize to them.
+	if blockHeight <= b.utxoSnapshotHeight {
+		str := fmt.Sprintf("block at height %d forks the main chain "+
+			"at or below the utxo snapshot at height %d",
+			blockHeight, b.utxoSnapshotHeight)
+		return ruleError(ErrForkTooOld, str)
+	}
+
 	// Reject outdated block versions once a majority of the network
 	// has upgraded.  These were originally voted on by BIP0034,
 	// BIP0065, and BIP0066.
@@ -763,6 +836,19 @@ func (b *BlockChain) checkBlockContext(block *btcutil.Block, prevNode *blockNode
 		return err
 	}
 
+	return b.checkBlockTxContext(block, prevNode, flags)
+}
+
+// checkBlockTxContext performs the checks of checkBlockContext which depend on
+// the transactions of the block rather than only on its header, so blocks whose
+// header was already checked in context can be checked without checking it
+// again.
+//
+// See checkBlockContext for how the flags modify its behavior.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) checkBlockTxContext(block *btcutil.Block, prevNode *blockNode, flags BehaviorFlags) error {
+	header := &block.MsgBlock().Header
 	fastAdd := flags&BFFastAdd == BFFastAdd
 	if !fastAdd {
 		// Obtain the latest state of the deployed CSV soft-fork in
@@ -845,6 +931,16 @@ func (b *BlockChain) checkBlockContext(block *btcutil.Block, prevNode *blockNode
 				return ruleError(ErrBlockWeightTooHigh, str)
 			}
 		}
+
+		// Blocks of signet networks must be signed by solving the
+		// challenge of the network.
+		if len(b.chainParams.SignetChallenge) > 0 {
+			err := CheckSignetSolution(block,
+				b.chainParams.SignetChallenge)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -1016,8 +1112,13 @@ func CheckTransactionInputs(tx *btcutil.Tx, txHeight int32, utxoView *UtxoViewpo
 // connects to the end of the current main chain and then calls this function
 // with that node.
 //
+// The flags modify the behavior of this function as follows:
+//   - BFAssumeValid: The transaction scripts are not checked
+//
+// The priority is used when scheduling the script validation of the block.
+//
 // This function MUST be called with the chain state lock held (for writes).
-func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, view *UtxoViewpoint, stxos *[]SpentTxOut) error {
+func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, view *UtxoViewpoint, stxos *[]SpentTxOut, flags BehaviorFlags, priority ValidationPriority) error {
 	// If the side chain blocks end up in the database, a call to
 	// CheckBlockSanity should be done here in case a previous version
 	// allowed a block that is no longer valid.  However, since the
@@ -1178,8 +1279,10 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 	// will therefore be detected by the next checkpoint).  This is a huge
 	// optimization because running the scripts is the most time consuming
 	// portion of block handling.
+	// The same goes for the ancestors of the block which is assumed to be
+	// valid, which are known to link to it through the headers.
 	checkpoint := b.LatestCheckpoint()
-	runScripts := true
+	runScripts := flags&BFAssumeValid != BFAssumeValid
 	if checkpoint != nil && node.height <= checkpoint.Height {
 		runScripts = false
 	}
@@ -1256,7 +1359,7 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 	// prevent CPU exhaustion attacks.
 	if runScripts {
 		err := checkBlockScripts(block, view, scriptFlags, b.sigCache,
-			b.hashCache)
+			b.hashCache, b.valScheduler, priority)
 		if err != nil {
 			return err
 		}
@@ -1271,7 +1374,9 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 
 // CheckConnectBlockTemplate fully validates that connecting the passed block to
 // the main chain does not violate any consensus rules, aside from the proof of
-// work requirement. The block must connect to the current tip of the main chain.
+// work requirement. The block must connect to the current tip of the main chain
+// and its header must commit to the claimtrie root resulting from its claim
+// scripts.
 //
 // This function is safe for concurrent access.
 func (b *BlockChain) CheckConnectBlockTemplate(block *btcutil.Block) error {
@@ -1306,5 +1411,11 @@ func (b *BlockChain) CheckConnectBlockTemplate(block *btcutil.Block) error {
 	view := NewUtxoViewpoint()
 	view.SetBestHash(&tip.hash)
 	newNode := newBlockNode(&header, tip)
-	return b.checkConnectBlock(newNode, block, view, nil)
+	err = b.checkConnectBlock(newNode, block, view, nil, BFNone,
+		ValidationPriorityLow)
+	if err != nil {
+		return err
+	}
+
+	return b.checkClaimtrieTemplate(block, view)
 }
@@ -13,6 +13,9 @@ import (
 
 	"github.com/lbryio/lbcd/chaincfg"
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie"
+	"github.com/lbryio/lbcd/claimtrie/config"
+	"github.com/lbryio/lbcd/txscript"
 	"github.com/lbryio/lbcd/wire"
 	btcutil "github.com/lbryio/lbcutil"
 )
@@ -159,3 +162,286 @@ func GetBlock100000() *btcutil.Block {
 	var results, _ = btcutil.NewBlockFromBytes(block100000Bytes)
 	return results
 }
+
+// TestCheckClaimtrieTemplate ensures block templates with a bad claim script or
+// committing to the wrong claimtrie root are rejected without leaving any of
+// their claims behind in the claimtrie.
+func TestCheckClaimtrieTemplate(t *testing.T) {
+	cfg := config.DefaultConfig
+	cfg.DataDir = t.TempDir()
+	ct, err := claimtrie.New(cfg)
+	if err != nil {
+		t.Fatalf("claimtrie.New: unexpected error: %v", err)
+	}
+	defer ct.Close()
+	chain := &BlockChain{claimTrie: ct}
+
+	claimScript, err := txscript.ClaimNameScript("test", "value")
+	if err != nil {
+		t.Fatalf("ClaimNameScript: unexpected error: %v", err)
+	}
+	coinbase := func(pkScripts ...[]byte) *wire.MsgTx {
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{},
+			wire.MaxPrevOutIndex), []byte{0x51, 0x51}, nil))
+		for _, pkScript := range pkScripts {
+			tx.AddTxOut(wire.NewTxOut(1, pkScript))
+		}
+		return tx
+	}
+	template := func(root chainhash.Hash, txns ...*wire.MsgTx) *btcutil.Block {
+		msgBlock := wire.NewMsgBlock(&wire.BlockHeader{ClaimTrie: root})
+		for _, tx := range txns {
+			msgBlock.AddTransaction(tx)
+		}
+		block := btcutil.NewBlock(msgBlock)
+		block.SetHeight(ct.Height() + 1)
+		return block
+	}
+
+	// The claim of the coinbase is processed before the bad claim script of
+	// the next transaction.
+	spent := wire.OutPoint{Hash: chainhash.Hash{1}}
+	view := NewUtxoViewpoint()
+	view.entries[spent] = NewUtxoEntry(wire.NewTxOut(1,
+		[]byte{txscript.OP_TRUE}), 1, false)
+	badTx := wire.NewMsgTx(1)
+	badTx.AddTxIn(wire.NewTxIn(&spent, nil, nil))
+	badTx.AddTxOut(wire.NewTxOut(1, []byte{txscript.OP_CLAIMNAME}))
+
+	height := ct.Height()
+	root := *ct.MerkleHash()
+	tests := []struct {
+		name  string
+		block *btcutil.Block
+	}{
+		{"bad claim script", template(root, coinbase(claimScript), badTx)},
+		{"wrong root", template(root, coinbase(claimScript))},
+	}
+	for _, test := range tests {
+		err := chain.checkClaimtrieTemplate(test.block, view)
+		if rerr, ok := err.(RuleError); !ok ||
+			rerr.ErrorCode != ErrBadClaimTrie {
+
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if ct.Height() != height {
+			t.Fatalf("%s: got claimtrie height %d, want %d",
+				test.name, ct.Height(), height)
+		}
+		if *ct.MerkleHash() != root {
+			t.Fatalf("%s: got claimtrie root %v, want %v",
+				test.name, ct.MerkleHash(), root)
+		}
+
+		// The claim of the rejected template isn't applied with the
+		// next block.
+		err = chain.checkClaimtrieTemplate(template(root,
+			coinbase([]byte{txscript.OP_TRUE})), view)
+		if err != nil {
+			t.Fatalf("%s: unexpected error for the next template: "+
+				"%v", test.name, err)
+		}
+	}
+}
+
+// solveTestHeader sets the nonce of the passed header to one which satisfies
+// the proof of work of its difficulty bits.
+func solveTestHeader(header *wire.BlockHeader) {
+	target := CompactToBig(header.Bits)
+	for {
+		hash := header.BlockPoWHash()
+		if HashToBig(&hash).Cmp(target) <= 0 {
+			return
+		}
+		header.Nonce++
+	}
+}
+
+// newTestBlock returns a solved block at the passed height extending the block
+// of the passed header a minute later, with a coinbase paying to an OP_TRUE
+// script followed by the passed transactions.
+func newTestBlock(params *chaincfg.Params, prev *wire.BlockHeader,
+	height int32, txns ...*wire.MsgTx) *btcutil.Block {
+
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  []byte{0x01, byte(height), 0x00},
+	})
+	coinbase.AddTxOut(wire.NewTxOut(CalcBlockSubsidy(height, params),
+		[]byte{txscript.OP_TRUE}))
+	txns = append([]*wire.MsgTx{coinbase}, txns...)
+	utilTxns := make([]*btcutil.Tx, 0, len(txns))
+	for _, tx := range txns {
+		utilTxns = append(utilTxns, btcutil.NewTx(tx))
+	}
+	merkles := BuildMerkleTreeStore(utilTxns, false)
+
+	msgBlock := &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    4,
+			PrevBlock:  prev.BlockHash(),
+			MerkleRoot: *merkles[len(merkles)-1],
+			Timestamp:  prev.Timestamp.Add(time.Minute),
+			Bits:       params.PowLimitBits,
+		},
+		Transactions: txns,
+	}
+	solveTestHeader(&msgBlock.Header)
+	block := btcutil.NewBlock(msgBlock)
+	block.SetHeight(height)
+	return block
+}
+
+// TestHeaderChain ensures headers are only connected to a header chain when
+// they extend it and pass the checks in the context of the previous headers,
+// and that the old headers are released as the chain grows.
+func TestHeaderChain(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	chain := newFakeChain(params)
+	if _, err := chain.NewHeaderChain(&chainhash.Hash{0x01}); err == nil {
+		t.Fatal("NewHeaderChain: expected an error for an unknown block")
+	}
+	hc, err := chain.NewHeaderChain(params.GenesisHash)
+	if err != nil {
+		t.Fatalf("NewHeaderChain: unexpected error: %v", err)
+	}
+
+	// nextHeader returns a solved header extending the header chain at
+	// the passed offset from the timestamp of its tip.
+	nextHeader := func(bits uint32, offset time.Duration) *wire.BlockHeader {
+		header := &wire.BlockHeader{
+			Version:   4,
+			PrevBlock: hc.tip.hash,
+			Timestamp: time.Unix(hc.tip.timestamp, 0).Add(offset),
+			Bits:      bits,
+		}
+		solveTestHeader(header)
+		return header
+	}
+
+	keep := int(chain.blocksPerRetarget) + medianTimeBlocks
+	for i := 0; i < 3*keep; i++ {
+		err := hc.Connect(nextHeader(params.PowLimitBits, time.Second))
+		if err != nil {
+			t.Fatalf("Connect #%d: unexpected error: %v", i, err)
+		}
+	}
+	if hc.tip.height != int32(3*keep) {
+		t.Fatalf("got tip height %d, want %d", hc.tip.height, 3*keep)
+	}
+	if len(hc.nodes) >= 2*keep || hc.nodes[0].parent != nil {
+		t.Fatalf("got %d headers kept, want fewer than %d detached "+
+			"from the older ones", len(hc.nodes), 2*keep)
+	}
+
+	tests := []struct {
+		name   string
+		header *wire.BlockHeader
+		want   ErrorCode
+	}{{
+		name:   "not extending the tip",
+		header: &wire.BlockHeader{PrevBlock: *params.GenesisHash},
+		want:   ErrPrevBlockNotBest,
+	}, {
+		name:   "unexpected difficulty",
+		header: nextHeader(0x203fffff, time.Second),
+		want:   ErrUnexpectedDifficulty,
+	}, {
+		name:   "timestamp before the median time",
+		header: nextHeader(params.PowLimitBits, -time.Minute),
+		want:   ErrTimeTooOld,
+	}}
+	tip := hc.tip
+	for _, test := range tests {
+		err := hc.Connect(test.header)
+		if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != test.want {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				test.want)
+		}
+		if hc.tip != tip {
+			t.Fatalf("%s: tip changed for a rejected header",
+				test.name)
+		}
+	}
+}
+
+// TestAssumeValidScripts ensures the scripts of the transactions of blocks
+// processed with BFAssumeValid aren't checked, while the other rules still
+// are.
+func TestAssumeValidScripts(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+
+	// The first block pays to an OP_TRUE script, the second one moves
+	// the output to an OP_0 script, and the third one spends it with an
+	// empty signature script, which fails the script checks.
+	genesis := &params.GenesisBlock.Header
+	block1 := newTestBlock(params, genesis, 1)
+	falseTx := wire.NewMsgTx(1)
+	falseTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{
+		Hash: *block1.Transactions()[0].Hash(),
+	}, nil, nil))
+	falseTx.AddTxOut(wire.NewTxOut(CalcBlockSubsidy(1, params),
+		[]byte{txscript.OP_0}))
+	block2 := newTestBlock(params, &block1.MsgBlock().Header, 2, falseTx)
+	spendTx := wire.NewMsgTx(1)
+	spendTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: falseTx.TxHash()},
+		nil, nil))
+	spendTx.AddTxOut(wire.NewTxOut(CalcBlockSubsidy(1, params),
+		[]byte{txscript.OP_TRUE}))
+	block3 := newTestBlock(params, &block2.MsgBlock().Header, 3, spendTx)
+
+	// Overspending the output breaks the rules other than the scripts.
+	overspendTx := spendTx.Copy()
+	overspendTx.TxOut[0].Value++
+	overspend := newTestBlock(params, &block2.MsgBlock().Header, 3,
+		overspendTx)
+
+	tests := []struct {
+		name     string
+		block    *btcutil.Block
+		flags    BehaviorFlags
+		accepted bool
+		want     ErrorCode
+	}{
+		{name: "assumevalid", block: block3, flags: BFAssumeValid,
+			accepted: true},
+		{name: "none", block: block3, flags: BFNone,
+			want: ErrScriptValidation},
+		{name: "assumevalid overspend", block: overspend,
+			flags: BFAssumeValid, want: ErrSpendTooHigh},
+	}
+	for _, test := range tests {
+		chain, teardownFunc, err := chainSetup("assumevalid", params)
+		if err != nil {
+			t.Fatalf("%s: failed to setup chain instance: %v",
+				test.name, err)
+		}
+		chain.TstSetCoinbaseMaturity(1)
+
+		for _, block := range []*btcutil.Block{block1, block2} {
+			_, _, err := chain.ProcessBlock(block, BFNone)
+			if err != nil {
+				teardownFunc()
+				t.Fatalf("%s: block %d rejected: %v", test.name,
+					block.Height(), err)
+			}
+		}
+		_, _, err = chain.ProcessBlock(test.block, test.flags)
+		height := chain.BestSnapshot().Height
+		teardownFunc()
+		if test.accepted {
+			if err != nil || height != 3 {
+				t.Errorf("%s: got error %v at height %d, want "+
+					"the block accepted", test.name, err,
+					height)
+			}
+			continue
+		}
+		if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != test.want {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				test.want)
+		}
+	}
+}
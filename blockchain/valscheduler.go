@@ -0,0 +1,306 @@
+package blockchain
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ValidationPriority defines the priority of a batch of script validation work
+// submitted to a ValidationScheduler.  Workers always drain all pending high
+// priority work before picking up low priority work.
+type ValidationPriority int
+
+const (
+	// ValidationPriorityLow is used for work that is not on the critical
+	// path of extending the best chain such as validating block template
+	// proposals.
+	ValidationPriorityLow ValidationPriority = iota
+
+	// ValidationPriorityHigh is used for validating blocks that are being
+	// connected to the best chain.
+	ValidationPriorityHigh
+)
+
+// String returns the ValidationPriority as a human-readable name.
+func (p ValidationPriority) String() string {
+	switch p {
+	case ValidationPriorityLow:
+		return "low"
+	case ValidationPriorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// DefaultValidationWorkers returns the default number of script validation
+// workers which is based on the number of processor cores.
+func DefaultValidationWorkers() int {
+	return runtime.NumCPU() * 3
+}
+
+// ValidationStats houses script validation metrics reported by a
+// ValidationScheduler.
+type ValidationStats struct {
+	// Workers is the current number of validation workers.
+	Workers int
+
+	// PendingHigh and PendingLow are the number of batches of each
+	// priority that are currently waiting on or being processed by the
+	// workers.
+	PendingHigh int32
+	PendingLow  int32
+
+	// BatchesValidated and InputsValidated are the total number of
+	// batches and transaction inputs that have been validated.
+	BatchesValidated uint64
+	InputsValidated  uint64
+
+	// ValidationTime is the total wall clock time spent validating
+	// batches.
+	ValidationTime time.Duration
+}
+
+// InputsPerSecond returns the average number of inputs validated per second of
+// validation time.
+func (s *ValidationStats) InputsPerSecond() float64 {
+	if s.ValidationTime <= 0 {
+		return 0
+	}
+	return float64(s.InputsValidated) / s.ValidationTime.Seconds()
+}
+
+// errValidationShutdown is returned when a validation batch is aborted because
+// the scheduler is shutting down.
+var errValidationShutdown = errors.New("validation scheduler is shutting down")
+
+// validationJob is a single transaction input to validate along with the batch
+// it belongs to.
+type validationJob struct {
+	item      *txValidateItem
+	validator *txValidator
+}
+
+// run validates the job's input and reports the result to the batch.
+func (j *validationJob) run() {
+	j.validator.sendResult(j.validator.validateItem(j.item))
+}
+
+// ValidationScheduler provides a pool of long-running goroutines which
+// validate transaction scripts on behalf of the chain.  The number of workers
+// may be changed at any time while the scheduler is running.
+type ValidationScheduler struct {
+	started  int32
+	shutdown int32
+
+	// The following fields are updated atomically.
+	pendingHigh      int32
+	pendingLow       int32
+	batchesValidated uint64
+	inputsValidated  uint64
+	validationNanos  int64
+
+	workerMtx sync.Mutex
+	workers   int
+
+	highChan chan *validationJob
+	lowChan  chan *validationJob
+	stopChan chan struct{}
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewValidationScheduler returns a new script validation scheduler with the
+// provided number of workers.  A value less than one selects the default
+// number of workers.  The scheduler must be started with Start before any
+// validation is submitted to it.
+func NewValidationScheduler(workers int) *ValidationScheduler {
+	if workers < 1 {
+		workers = DefaultValidationWorkers()
+	}
+	return &ValidationScheduler{
+		workers:  workers,
+		highChan: make(chan *validationJob),
+		lowChan:  make(chan *validationJob),
+		stopChan: make(chan struct{}),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start launches the validation workers.
+//
+// This function is safe for concurrent access.
+func (s *ValidationScheduler) Start() {
+	if atomic.AddInt32(&s.started, 1) != 1 {
+		return
+	}
+
+	s.workerMtx.Lock()
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	s.workerMtx.Unlock()
+}
+
+// Stop shuts down all validation workers and waits for them to exit.
+//
+// This function is safe for concurrent access.
+func (s *ValidationScheduler) Stop() {
+	if atomic.AddInt32(&s.shutdown, 1) != 1 {
+		return
+	}
+
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// SetWorkers changes the number of validation workers.  A value less than one
+// selects the default number of workers.  Workers that are removed finish the
+// input they are currently validating before exiting.
+//
+// This function is safe for concurrent access.
+func (s *ValidationScheduler) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = DefaultValidationWorkers()
+	}
+
+	// Only adjust the running goroutines once the scheduler is running.
+	// Otherwise, the new count takes effect on Start.
+	s.workerMtx.Lock()
+	running := atomic.LoadInt32(&s.started) != 0 &&
+		atomic.LoadInt32(&s.shutdown) == 0
+	var numStop int
+	if running {
+		for ; s.workers < workers; s.workers++ {
+			s.wg.Add(1)
+			go s.worker()
+		}
+		numStop = s.workers - workers
+	}
+	s.workers = workers
+	s.workerMtx.Unlock()
+
+	log.Debugf("Script validation workers set to %d", workers)
+
+	// Tell the removed workers to exit once they finish the input they are
+	// validating.  This is done without holding the mutex since it can take
+	// a while.
+	for ; numStop > 0; numStop-- {
+		select {
+		case s.stopChan <- struct{}{}:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Workers returns the current number of validation workers.
+//
+// This function is safe for concurrent access.
+func (s *ValidationScheduler) Workers() int {
+	s.workerMtx.Lock()
+	defer s.workerMtx.Unlock()
+	return s.workers
+}
+
+// Stats returns a snapshot of the script validation metrics.
+//
+// This function is safe for concurrent access.
+func (s *ValidationScheduler) Stats() ValidationStats {
+	return ValidationStats{
+		Workers:          s.Workers(),
+		PendingHigh:      atomic.LoadInt32(&s.pendingHigh),
+		PendingLow:       atomic.LoadInt32(&s.pendingLow),
+		BatchesValidated: atomic.LoadUint64(&s.batchesValidated),
+		InputsValidated:  atomic.LoadUint64(&s.inputsValidated),
+		ValidationTime:   time.Duration(atomic.LoadInt64(&s.validationNanos)),
+	}
+}
+
+// worker validates jobs submitted to the scheduler until it is told to stop or
+// the scheduler shuts down.  Pending high priority jobs are always preferred
+// over low priority ones.  It must be run as a goroutine.
+func (s *ValidationScheduler) worker() {
+	defer s.wg.Done()
+
+	for {
+		// Drain any high priority work first.
+		select {
+		case job := <-s.highChan:
+			job.run()
+			continue
+		default:
+		}
+
+		select {
+		case job := <-s.highChan:
+			job.run()
+		case job := <-s.lowChan:
+			job.run()
+		case <-s.stopChan:
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// validate validates the passed items with the provided priority using the
+// scheduler workers and blocks until all of them are validated or any of them
+// fails.
+func (s *ValidationScheduler) validate(v *txValidator, items []*txValidateItem,
+	priority ValidationPriority) error {
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	jobChan, pending := s.lowChan, &s.pendingLow
+	if priority == ValidationPriorityHigh {
+		jobChan, pending = s.highChan, &s.pendingHigh
+	}
+	atomic.AddInt32(pending, 1)
+	defer atomic.AddInt32(pending, -1)
+
+	start := time.Now()
+	numInputs := len(items)
+	currentItem := 0
+	processedItems := 0
+	for processedItems < numInputs {
+		// Only send items while there are still items that need to
+		// be processed.  The select statement will never select a nil
+		// channel.
+		var sendChan chan *validationJob
+		var job *validationJob
+		if currentItem < numInputs {
+			sendChan = jobChan
+			job = &validationJob{item: items[currentItem], validator: v}
+		}
+
+		select {
+		case sendChan <- job:
+			currentItem++
+
+		case err := <-v.resultChan:
+			processedItems++
+			if err != nil {
+				close(v.quitChan)
+				return err
+			}
+
+		case <-s.quit:
+			close(v.quitChan)
+			return errValidationShutdown
+		}
+	}
+	close(v.quitChan)
+
+	atomic.AddUint64(&s.batchesValidated, 1)
+	atomic.AddUint64(&s.inputsValidated, uint64(numInputs))
+	atomic.AddInt64(&s.validationNanos, int64(time.Since(start)))
+	return nil
+}
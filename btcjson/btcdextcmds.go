@@ -59,6 +59,34 @@ func NewDebugLevelCmd(levelSpec string) *DebugLevelCmd {
 	}
 }
 
+// DebugScriptCmd defines the debugscript JSON-RPC command.  This command is not
+// a standard Bitcoin command.  It is an extension for lbcd.
+type DebugScriptCmd struct {
+	HexTx        string
+	Index        uint32
+	ScriptPubKey *string
+	Amount       *float64
+	Standard     *bool `jsonrpcdefault:"true"`
+}
+
+// NewDebugScriptCmd returns a new DebugScriptCmd which can be used to issue a
+// debugscript JSON-RPC command.  This command is not a standard Bitcoin
+// command.  It is an extension for lbcd.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewDebugScriptCmd(hexTx string, index uint32, scriptPubKey *string,
+	amount *float64, standard *bool) *DebugScriptCmd {
+
+	return &DebugScriptCmd{
+		HexTx:        hexTx,
+		Index:        index,
+		ScriptPubKey: scriptPubKey,
+		Amount:       amount,
+		Standard:     standard,
+	}
+}
+
 // GenerateToAddressCmd defines the generatetoaddress JSON-RPC command.
 type GenerateToAddressCmd struct {
 	NumBlocks int64
@@ -128,6 +156,47 @@ func NewGetHeadersCmd(blockLocators []string, hashStop string) *GetHeadersCmd {
 	}
 }
 
+// GetValidationInfoCmd defines the getvalidationinfo JSON-RPC command.  This
+// command is not a standard Bitcoin command.  It is an extension for lbcd.
+type GetValidationInfoCmd struct{}
+
+// NewGetValidationInfoCmd returns a new instance which can be used to issue a
+// getvalidationinfo JSON-RPC command.
+func NewGetValidationInfoCmd() *GetValidationInfoCmd {
+	return &GetValidationInfoCmd{}
+}
+
+// GetRPCStatsCmd defines the getrpcstats JSON-RPC command.  This command is
+// not a standard Bitcoin command.  It is an extension for lbcd.
+type GetRPCStatsCmd struct {
+	Count *int `jsonrpcdefault:"20"`
+}
+
+// NewGetRPCStatsCmd returns a new instance which can be used to issue a
+// getrpcstats JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetRPCStatsCmd(count *int) *GetRPCStatsCmd {
+	return &GetRPCStatsCmd{
+		Count: count,
+	}
+}
+
+// SetValidationWorkersCmd defines the setvalidationworkers JSON-RPC command.
+// This command is not a standard Bitcoin command.  It is an extension for lbcd.
+type SetValidationWorkersCmd struct {
+	Workers int32
+}
+
+// NewSetValidationWorkersCmd returns a new instance which can be used to issue
+// a setvalidationworkers JSON-RPC command.
+func NewSetValidationWorkersCmd(workers int32) *SetValidationWorkersCmd {
+	return &SetValidationWorkersCmd{
+		Workers: workers,
+	}
+}
+
 // VersionCmd defines the version JSON-RPC command.
 //
 // NOTE: This is a btcsuite extension ported from
@@ -146,11 +215,15 @@ func init() {
 	flags := UsageFlag(0)
 
 	MustRegisterCmd("debuglevel", (*DebugLevelCmd)(nil), flags)
+	MustRegisterCmd("debugscript", (*DebugScriptCmd)(nil), flags)
 	MustRegisterCmd("node", (*NodeCmd)(nil), flags)
 	MustRegisterCmd("generate", (*GenerateCmd)(nil), flags)
 	MustRegisterCmd("generatetoaddress", (*GenerateToAddressCmd)(nil), flags)
 	MustRegisterCmd("getbestblock", (*GetBestBlockCmd)(nil), flags)
 	MustRegisterCmd("getcurrentnet", (*GetCurrentNetCmd)(nil), flags)
 	MustRegisterCmd("getheaders", (*GetHeadersCmd)(nil), flags)
+	MustRegisterCmd("getrpcstats", (*GetRPCStatsCmd)(nil), flags)
+	MustRegisterCmd("getvalidationinfo", (*GetValidationInfoCmd)(nil), flags)
+	MustRegisterCmd("setvalidationworkers", (*SetValidationWorkersCmd)(nil), flags)
 	MustRegisterCmd("version", (*VersionCmd)(nil), flags)
 }
@@ -43,6 +43,40 @@ func TestBtcdExtCmds(t *testing.T) {
 				LevelSpec: "trace",
 			},
 		},
+		{
+			name: "debugscript",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("debugscript", "0100", 1)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDebugScriptCmd("0100", 1, nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"debugscript","params":["0100",1],"id":1}`,
+			unmarshalled: &btcjson.DebugScriptCmd{
+				HexTx:    "0100",
+				Index:    1,
+				Standard: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "debugscript optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("debugscript", "0100", 1, "51", 0.5, false)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDebugScriptCmd("0100", 1,
+					btcjson.String("51"), btcjson.Float64(0.5),
+					btcjson.Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"debugscript","params":["0100",1,"51",0.5,false],"id":1}`,
+			unmarshalled: &btcjson.DebugScriptCmd{
+				HexTx:        "0100",
+				Index:        1,
+				ScriptPubKey: btcjson.String("51"),
+				Amount:       btcjson.Float64(0.5),
+				Standard:     btcjson.Bool(false),
+			},
+		},
 		{
 			name: "node",
 			newCmd: func() (interface{}, error) {
@@ -194,6 +228,56 @@ func TestBtcdExtCmds(t *testing.T) {
 				HashStop: "000000000000000000ba33b33e1fad70b69e234fc24414dd47113bff38f523f7",
 			},
 		},
+		{
+			name: "getvalidationinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getvalidationinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetValidationInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getvalidationinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetValidationInfoCmd{},
+		},
+		{
+			name: "getrpcstats",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getrpcstats")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetRPCStatsCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getrpcstats","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetRPCStatsCmd{
+				Count: btcjson.Int(20),
+			},
+		},
+		{
+			name: "getrpcstats optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getrpcstats", 5)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetRPCStatsCmd(btcjson.Int(5))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getrpcstats","params":[5],"id":1}`,
+			unmarshalled: &btcjson.GetRPCStatsCmd{
+				Count: btcjson.Int(5),
+			},
+		},
+		{
+			name: "setvalidationworkers",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setvalidationworkers", 16)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSetValidationWorkersCmd(16)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setvalidationworkers","params":[16],"id":1}`,
+			unmarshalled: &btcjson.SetValidationWorkersCmd{
+				Workers: 16,
+			},
+		},
 		{
 			name: "version",
 			newCmd: func() (interface{}, error) {
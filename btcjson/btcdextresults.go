@@ -18,3 +18,71 @@ type VersionResult struct {
 	Prerelease    string `json:"prerelease"`
 	BuildMetadata string `json:"buildmetadata"`
 }
+
+// GetValidationInfoResult models the data returned from the getvalidationinfo
+// command.
+type GetValidationInfoResult struct {
+	Workers          int32   `json:"workers"`
+	PendingHigh      int32   `json:"pendinghigh"`
+	PendingLow       int32   `json:"pendinglow"`
+	BatchesValidated uint64  `json:"batchesvalidated"`
+	InputsValidated  uint64  `json:"inputsvalidated"`
+	ValidationTimeMs int64   `json:"validationtimems"`
+	InputsPerSecond  float64 `json:"inputspersecond"`
+}
+
+// RPCMethodStats models the statistics of an RPC method in the data returned
+// from the getrpcstats command.
+type RPCMethodStats struct {
+	Method  string  `json:"method"`
+	Calls   uint64  `json:"calls"`
+	Errors  uint64  `json:"errors"`
+	TotalMs float64 `json:"totalms"`
+	AvgMs   float64 `json:"avgms"`
+	MaxMs   float64 `json:"maxms"`
+}
+
+// RPCClientStats models the statistics of an RPC client in the data returned
+// from the getrpcstats command.
+type RPCClientStats struct {
+	Client    string  `json:"client"`
+	Calls     uint64  `json:"calls"`
+	Errors    uint64  `json:"errors"`
+	TotalMs   float64 `json:"totalms"`
+	TopMethod string  `json:"topmethod"`
+}
+
+// RPCRequestTrace models the trace of an RPC request in the data returned from
+// the getrpcstats command.
+type RPCRequestTrace struct {
+	Method     string  `json:"method"`
+	Client     string  `json:"client"`
+	Time       int64   `json:"time"`
+	DurationMs float64 `json:"durationms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// GetRPCStatsResult models the data returned from the getrpcstats command.
+type GetRPCStatsResult struct {
+	Methods []RPCMethodStats  `json:"methods"`
+	Clients []RPCClientStats  `json:"clients"`
+	Recent  []RPCRequestTrace `json:"recent"`
+}
+
+// DebugScriptStep models the state of the script engine after executing an
+// opcode in the data returned from the debugscript command.
+type DebugScriptStep struct {
+	Script   int      `json:"script"`
+	Opcode   int      `json:"opcode"`
+	Asm      string   `json:"asm"`
+	Stack    []string `json:"stack"`
+	AltStack []string `json:"altstack,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// DebugScriptResult models the data returned from the debugscript command.
+type DebugScriptResult struct {
+	Valid bool              `json:"valid"`
+	Error string            `json:"error,omitempty"`
+	Steps []DebugScriptStep `json:"steps"`
+}
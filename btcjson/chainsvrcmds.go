@@ -48,6 +48,19 @@ func NewAddNodeCmd(addr string, subCmd AddNodeSubCmd) *AddNodeCmd {
 	}
 }
 
+// BackupChainStateCmd defines the backupchainstate JSON-RPC command.
+type BackupChainStateCmd struct {
+	Destination string
+}
+
+// NewBackupChainStateCmd returns a new instance which can be used to issue a
+// backupchainstate JSON-RPC command.
+func NewBackupChainStateCmd(destination string) *BackupChainStateCmd {
+	return &BackupChainStateCmd{
+		Destination: destination,
+	}
+}
+
 // ClearBannedCmd defines the clearbanned JSON-RPC command.
 type ClearBannedCmd struct{}
 
@@ -131,6 +144,19 @@ func NewDeriveAddressesCmd(descriptor string, descriptorRange *DescriptorRange)
 	}
 }
 
+// DumpTxOutSetCmd defines the dumptxoutset JSON-RPC command.
+type DumpTxOutSetCmd struct {
+	Path string
+}
+
+// NewDumpTxOutSetCmd returns a new instance which can be used to issue a
+// dumptxoutset JSON-RPC command.
+func NewDumpTxOutSetCmd(path string) *DumpTxOutSetCmd {
+	return &DumpTxOutSetCmd{
+		Path: path,
+	}
+}
+
 // ChangeType defines the different output types to use for the change address
 // of a transaction built by the node.
 type ChangeType string
@@ -193,6 +219,41 @@ func NewGetAddedNodeInfoCmd(dns bool, node *string) *GetAddedNodeInfoCmd {
 	}
 }
 
+// GetAddressBalanceCmd defines the getaddressbalance JSON-RPC command.
+type GetAddressBalanceCmd struct {
+	Address string
+}
+
+// NewGetAddressBalanceCmd returns a new instance which can be used to issue a
+// getaddressbalance JSON-RPC command.
+func NewGetAddressBalanceCmd(address string) *GetAddressBalanceCmd {
+	return &GetAddressBalanceCmd{
+		Address: address,
+	}
+}
+
+// GetAddressTxIDsCmd defines the getaddresstxids JSON-RPC command.
+type GetAddressTxIDsCmd struct {
+	Address string
+	Skip    *int  `jsonrpcdefault:"0"`
+	Count   *int  `jsonrpcdefault:"100"`
+	Reverse *bool `jsonrpcdefault:"false"`
+}
+
+// NewGetAddressTxIDsCmd returns a new instance which can be used to issue a
+// getaddresstxids JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetAddressTxIDsCmd(address string, skip, count *int, reverse *bool) *GetAddressTxIDsCmd {
+	return &GetAddressTxIDsCmd{
+		Address: address,
+		Skip:    skip,
+		Count:   count,
+		Reverse: reverse,
+	}
+}
+
 // GetBestBlockHashCmd defines the getbestblockhash JSON-RPC command.
 type GetBestBlockHashCmd struct{}
 
@@ -265,6 +326,21 @@ func NewGetBlockFilterCmd(blockHash string, filterType *FilterTypeName) *GetBloc
 	}
 }
 
+// GetBlockFromPeerCmd defines the getblockfrompeer JSON-RPC command.
+type GetBlockFromPeerCmd struct {
+	BlockHash string
+	PeerID    int32
+}
+
+// NewGetBlockFromPeerCmd returns a new instance which can be used to issue a
+// getblockfrompeer JSON-RPC command.
+func NewGetBlockFromPeerCmd(blockHash string, peerID int32) *GetBlockFromPeerCmd {
+	return &GetBlockFromPeerCmd{
+		BlockHash: blockHash,
+		PeerID:    peerID,
+	}
+}
+
 // GetBlockHashCmd defines the getblockhash JSON-RPC command.
 type GetBlockHashCmd struct {
 	Index int64
@@ -337,6 +413,24 @@ func NewGetBlockStatsCmd(hashOrHeight HashOrHeight, stats *[]string) *GetBlockSt
 	}
 }
 
+// GetBlockHeadersCmd defines the getblockheaders JSON-RPC command.
+type GetBlockHeadersCmd struct {
+	HashOrHeight HashOrHeight
+	Count        *int32 `jsonrpcdefault:"2000"`
+	Verbose      *bool  `jsonrpcdefault:"false"`
+}
+
+// NewGetBlockHeadersCmd returns a new instance which can be used to issue a
+// getblockheaders JSON-RPC command.  Either the height or the hash of the first
+// block must be specified.
+func NewGetBlockHeadersCmd(hashOrHeight HashOrHeight, count *int32, verbose *bool) *GetBlockHeadersCmd {
+	return &GetBlockHeadersCmd{
+		HashOrHeight: hashOrHeight,
+		Count:        count,
+		Verbose:      verbose,
+	}
+}
+
 // TemplateRequest is a request object as defined in BIP22
 // (https://en.bitcoin.it/wiki/BIP_0022), it is optionally provided as an
 // pointer argument to GetBlockTemplateCmd.
@@ -361,6 +455,11 @@ type TemplateRequest struct {
 	Data   string `json:"data,omitempty"`
 	WorkID string `json:"workid,omitempty"`
 
+	// Verbose requests a GetBlockTemplateProposalResult describing why a
+	// block proposal was rejected instead of the BIP 0023 reason string.
+	// This is an lbcd extension only used when Mode is "proposal".
+	Verbose bool `json:"verbose,omitempty"`
+
 	// list of supported softfork deployments, by name
 	// Ref: https://en.bitcoin.it/wiki/BIP_0009#getblocktemplate_changes.
 	Rules []string `json:"rules,omitempty"`
@@ -461,12 +560,19 @@ func NewGetCFilterHeaderCmd(hash string,
 }
 
 // GetChainTipsCmd defines the getchaintips JSON-RPC command.
-type GetChainTipsCmd struct{}
+type GetChainTipsCmd struct {
+	Verbose *bool `jsonrpcdefault:"false"`
+}
 
 // NewGetChainTipsCmd returns a new instance which can be used to issue a
 // getchaintips JSON-RPC command.
-func NewGetChainTipsCmd() *GetChainTipsCmd {
-	return &GetChainTipsCmd{}
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetChainTipsCmd(verbose *bool) *GetChainTipsCmd {
+	return &GetChainTipsCmd{
+		Verbose: verbose,
+	}
 }
 
 // GetChainTxStatsCmd defines the getchaintxstats JSON-RPC command.
@@ -545,6 +651,42 @@ func NewGetInfoCmd() *GetInfoCmd {
 	return &GetInfoCmd{}
 }
 
+// GetMempoolAncestorsCmd defines the getmempoolancestors JSON-RPC command.
+type GetMempoolAncestorsCmd struct {
+	TxID    string
+	Verbose *bool `jsonrpcdefault:"false"`
+}
+
+// NewGetMempoolAncestorsCmd returns a new instance which can be used to issue
+// a getmempoolancestors JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetMempoolAncestorsCmd(txHash string, verbose *bool) *GetMempoolAncestorsCmd {
+	return &GetMempoolAncestorsCmd{
+		TxID:    txHash,
+		Verbose: verbose,
+	}
+}
+
+// GetMempoolDescendantsCmd defines the getmempooldescendants JSON-RPC command.
+type GetMempoolDescendantsCmd struct {
+	TxID    string
+	Verbose *bool `jsonrpcdefault:"false"`
+}
+
+// NewGetMempoolDescendantsCmd returns a new instance which can be used to
+// issue a getmempooldescendants JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetMempoolDescendantsCmd(txHash string, verbose *bool) *GetMempoolDescendantsCmd {
+	return &GetMempoolDescendantsCmd{
+		TxID:    txHash,
+		Verbose: verbose,
+	}
+}
+
 // GetMempoolEntryCmd defines the getmempoolentry JSON-RPC command.
 type GetMempoolEntryCmd struct {
 	TxID string
@@ -558,6 +700,16 @@ func NewGetMempoolEntryCmd(txHash string) *GetMempoolEntryCmd {
 	}
 }
 
+// GetMempoolFeeHistogramCmd defines the getmempoolfeehistogram JSON-RPC
+// command.
+type GetMempoolFeeHistogramCmd struct{}
+
+// NewGetMempoolFeeHistogramCmd returns a new instance which can be used to issue
+// a getmempoolfeehistogram JSON-RPC command.
+func NewGetMempoolFeeHistogramCmd() *GetMempoolFeeHistogramCmd {
+	return &GetMempoolFeeHistogramCmd{}
+}
+
 // GetMempoolInfoCmd defines the getmempoolinfo JSON-RPC command.
 type GetMempoolInfoCmd struct{}
 
@@ -586,12 +738,19 @@ func NewGetNetworkInfoCmd() *GetNetworkInfoCmd {
 }
 
 // GetNetTotalsCmd defines the getnettotals JSON-RPC command.
-type GetNetTotalsCmd struct{}
+type GetNetTotalsCmd struct {
+	Verbose *bool `jsonrpcdefault:"false"`
+}
 
 // NewGetNetTotalsCmd returns a new instance which can be used to issue a
 // getnettotals JSON-RPC command.
-func NewGetNetTotalsCmd() *GetNetTotalsCmd {
-	return &GetNetTotalsCmd{}
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetNetTotalsCmd(verbose *bool) *GetNetTotalsCmd {
+	return &GetNetTotalsCmd{
+		Verbose: verbose,
+	}
 }
 
 // GetNetworkHashPSCmd defines the getnetworkhashps JSON-RPC command.
@@ -612,6 +771,22 @@ func NewGetNetworkHashPSCmd(numBlocks, height *int) *GetNetworkHashPSCmd {
 	}
 }
 
+// GetNextDifficultyCmd defines the getnextdifficulty JSON-RPC command.
+type GetNextDifficultyCmd struct {
+	Timestamp *int64
+}
+
+// NewGetNextDifficultyCmd returns a new instance which can be used to issue a
+// getnextdifficulty JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetNextDifficultyCmd(timestamp *int64) *GetNextDifficultyCmd {
+	return &GetNextDifficultyCmd{
+		Timestamp: timestamp,
+	}
+}
+
 // GetNodeAddressesCmd defines the getnodeaddresses JSON-RPC command.
 type GetNodeAddressesCmd struct {
 	Count *int32 `jsonrpcdefault:"1"`
@@ -674,6 +849,21 @@ func NewGetRawTransactionCmd(txHash string, verbose *bool) *GetRawTransactionCmd
 	}
 }
 
+// GetSpentInfoCmd defines the getspentinfo JSON-RPC command.
+type GetSpentInfoCmd struct {
+	Txid string
+	Vout uint32
+}
+
+// NewGetSpentInfoCmd returns a new instance which can be used to issue a
+// getspentinfo JSON-RPC command.
+func NewGetSpentInfoCmd(txHash string, vout uint32) *GetSpentInfoCmd {
+	return &GetSpentInfoCmd{
+		Txid: txHash,
+		Vout: vout,
+	}
+}
+
 // GetTxOutCmd defines the gettxout JSON-RPC command.
 type GetTxOutCmd struct {
 	Txid           string
@@ -775,6 +965,19 @@ func NewListBannedCmd() *ListBannedCmd {
 	return &ListBannedCmd{}
 }
 
+// LoadTxOutSetCmd defines the loadtxoutset JSON-RPC command.
+type LoadTxOutSetCmd struct {
+	Path string
+}
+
+// NewLoadTxOutSetCmd returns a new instance which can be used to issue a
+// loadtxoutset JSON-RPC command.
+func NewLoadTxOutSetCmd(path string) *LoadTxOutSetCmd {
+	return &LoadTxOutSetCmd{
+		Path: path,
+	}
+}
+
 // PingCmd defines the ping JSON-RPC command.
 type PingCmd struct{}
 
@@ -810,6 +1013,41 @@ func NewReconsiderBlockCmd(blockHash string) *ReconsiderBlockCmd {
 	}
 }
 
+// ScanTxOutSetAction is the action performed by the scantxoutset JSON-RPC
+// command.
+type ScanTxOutSetAction string
+
+const (
+	// ScanTxOutSetStart starts a scan of the unspent transaction output
+	// set.
+	ScanTxOutSetStart ScanTxOutSetAction = "start"
+
+	// ScanTxOutSetAbort aborts the scan in progress.
+	ScanTxOutSetAbort ScanTxOutSetAction = "abort"
+
+	// ScanTxOutSetStatus reports the progress of the scan in progress.
+	ScanTxOutSetStatus ScanTxOutSetAction = "status"
+)
+
+// ScanTxOutSetCmd defines the scantxoutset JSON-RPC command.
+type ScanTxOutSetCmd struct {
+	Action      ScanTxOutSetAction
+	ScanObjects *[]string
+}
+
+// NewScanTxOutSetCmd returns a new instance which can be used to issue a
+// scantxoutset JSON-RPC command.  The scan objects are only used by the start
+// action.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewScanTxOutSetCmd(action ScanTxOutSetAction, scanObjects *[]string) *ScanTxOutSetCmd {
+	return &ScanTxOutSetCmd{
+		Action:      action,
+		ScanObjects: scanObjects,
+	}
+}
+
 // SearchRawTransactionsCmd defines the searchrawtransactions JSON-RPC command.
 type SearchRawTransactionsCmd struct {
 	Address     string
@@ -957,7 +1195,8 @@ func NewSetBanCmd(addr string, subCmd SetBanSubCmd, banTime *int,
 // SetGenerateCmd defines the setgenerate JSON-RPC command.
 type SetGenerateCmd struct {
 	Generate     bool
-	GenProcLimit *int `jsonrpcdefault:"-1"`
+	GenProcLimit *int               `jsonrpcdefault:"-1"`
+	Payouts      *map[string]uint32 `jsonrpcusage:"{\"address\":weight,...}"`
 }
 
 // NewSetGenerateCmd returns a new instance which can be used to issue a
@@ -965,10 +1204,11 @@ type SetGenerateCmd struct {
 //
 // The parameters which are pointers indicate they are optional.  Passing nil
 // for optional parameters will use the default value.
-func NewSetGenerateCmd(generate bool, genProcLimit *int) *SetGenerateCmd {
+func NewSetGenerateCmd(generate bool, genProcLimit *int, payouts *map[string]uint32) *SetGenerateCmd {
 	return &SetGenerateCmd{
 		Generate:     generate,
 		GenProcLimit: genProcLimit,
+		Payouts:      payouts,
 	}
 }
 
@@ -1024,6 +1264,46 @@ func NewSubmitBlockCmd(hexBlock string, options *SubmitBlockOptions) *SubmitBloc
 	}
 }
 
+// TestMempoolAcceptCmd defines the testmempoolaccept JSON-RPC command.
+type TestMempoolAcceptCmd struct {
+	// RawTxns is a list of hex-encoded raw transactions.  Transactions
+	// later in the list may spend the outputs of earlier ones.
+	RawTxns []string
+
+	// MaxFeeRate rejects transactions whose fee rate is higher than the
+	// specified value, expressed in LBC/kvB.  A zero value disables the
+	// check.
+	MaxFeeRate *float64 `jsonrpcdefault:"0.1"`
+}
+
+// NewTestMempoolAcceptCmd returns a new instance which can be used to issue a
+// testmempoolaccept JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewTestMempoolAcceptCmd(rawTxns []string, maxFeeRate *float64) *TestMempoolAcceptCmd {
+	return &TestMempoolAcceptCmd{
+		RawTxns:    rawTxns,
+		MaxFeeRate: maxFeeRate,
+	}
+}
+
+// SubmitPackageCmd defines the submitpackage JSON-RPC command.
+type SubmitPackageCmd struct {
+	// RawTxns is a list of hex-encoded raw transactions forming a child
+	// with its unconfirmed parents.  The child must be last and parents
+	// must come before the transactions spending them.
+	RawTxns []string
+}
+
+// NewSubmitPackageCmd returns a new instance which can be used to issue a
+// submitpackage JSON-RPC command.
+func NewSubmitPackageCmd(rawTxns []string) *SubmitPackageCmd {
+	return &SubmitPackageCmd{
+		RawTxns: rawTxns,
+	}
+}
+
 // UptimeCmd defines the uptime JSON-RPC command.
 type UptimeCmd struct{}
 
@@ -1063,6 +1343,22 @@ func NewVerifyChainCmd(checkLevel, checkDepth *int32) *VerifyChainCmd {
 	}
 }
 
+// VerifyDBCmd defines the verifydb JSON-RPC command.
+type VerifyDBCmd struct {
+	Scan *bool `jsonrpcdefault:"false"`
+}
+
+// NewVerifyDBCmd returns a new instance which can be used to issue a verifydb
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewVerifyDBCmd(scan *bool) *VerifyDBCmd {
+	return &VerifyDBCmd{
+		Scan: scan,
+	}
+}
+
 // VerifyMessageCmd defines the verifymessage JSON-RPC command.
 type VerifyMessageCmd struct {
 	Address   string
@@ -1098,19 +1394,25 @@ func init() {
 	flags := UsageFlag(0)
 
 	MustRegisterCmd("addnode", (*AddNodeCmd)(nil), flags)
+	MustRegisterCmd("backupchainstate", (*BackupChainStateCmd)(nil), flags)
 	MustRegisterCmd("createrawtransaction", (*CreateRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decoderawtransaction", (*DecodeRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decodescript", (*DecodeScriptCmd)(nil), flags)
 	MustRegisterCmd("deriveaddresses", (*DeriveAddressesCmd)(nil), flags)
+	MustRegisterCmd("dumptxoutset", (*DumpTxOutSetCmd)(nil), flags)
 	MustRegisterCmd("fundrawtransaction", (*FundRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("getaddednodeinfo", (*GetAddedNodeInfoCmd)(nil), flags)
+	MustRegisterCmd("getaddressbalance", (*GetAddressBalanceCmd)(nil), flags)
+	MustRegisterCmd("getaddresstxids", (*GetAddressTxIDsCmd)(nil), flags)
 	MustRegisterCmd("getbestblockhash", (*GetBestBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblock", (*GetBlockCmd)(nil), flags)
 	MustRegisterCmd("getblockchaininfo", (*GetBlockChainInfoCmd)(nil), flags)
 	MustRegisterCmd("getblockcount", (*GetBlockCountCmd)(nil), flags)
 	MustRegisterCmd("getblockfilter", (*GetBlockFilterCmd)(nil), flags)
+	MustRegisterCmd("getblockfrompeer", (*GetBlockFromPeerCmd)(nil), flags)
 	MustRegisterCmd("getblockhash", (*GetBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblockheader", (*GetBlockHeaderCmd)(nil), flags)
+	MustRegisterCmd("getblockheaders", (*GetBlockHeadersCmd)(nil), flags)
 	MustRegisterCmd("getblockstats", (*GetBlockStatsCmd)(nil), flags)
 	MustRegisterCmd("getblocktemplate", (*GetBlockTemplateCmd)(nil), flags)
 	MustRegisterCmd("getcfilter", (*GetCFilterCmd)(nil), flags)
@@ -1123,12 +1425,16 @@ func init() {
 	MustRegisterCmd("getgenerate", (*GetGenerateCmd)(nil), flags)
 	MustRegisterCmd("gethashespersec", (*GetHashesPerSecCmd)(nil), flags)
 	MustRegisterCmd("getinfo", (*GetInfoCmd)(nil), flags)
+	MustRegisterCmd("getmempoolancestors", (*GetMempoolAncestorsCmd)(nil), flags)
+	MustRegisterCmd("getmempooldescendants", (*GetMempoolDescendantsCmd)(nil), flags)
 	MustRegisterCmd("getmempoolentry", (*GetMempoolEntryCmd)(nil), flags)
+	MustRegisterCmd("getmempoolfeehistogram", (*GetMempoolFeeHistogramCmd)(nil), flags)
 	MustRegisterCmd("getmempoolinfo", (*GetMempoolInfoCmd)(nil), flags)
 	MustRegisterCmd("getmininginfo", (*GetMiningInfoCmd)(nil), flags)
 	MustRegisterCmd("getnetworkinfo", (*GetNetworkInfoCmd)(nil), flags)
 	MustRegisterCmd("getnettotals", (*GetNetTotalsCmd)(nil), flags)
 	MustRegisterCmd("getnetworkhashps", (*GetNetworkHashPSCmd)(nil), flags)
+	MustRegisterCmd("getnextdifficulty", (*GetNextDifficultyCmd)(nil), flags)
 	MustRegisterCmd("getnodeaddresses", (*GetNodeAddressesCmd)(nil), flags)
 	MustRegisterCmd("getpeerinfo", (*GetPeerInfoCmd)(nil), flags)
 	MustRegisterCmd("listbanned", (*ListBannedCmd)(nil), flags)
@@ -1136,24 +1442,30 @@ func init() {
 	MustRegisterCmd("clearbanned", (*ClearBannedCmd)(nil), flags)
 	MustRegisterCmd("getrawmempool", (*GetRawMempoolCmd)(nil), flags)
 	MustRegisterCmd("getrawtransaction", (*GetRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("getspentinfo", (*GetSpentInfoCmd)(nil), flags)
 	MustRegisterCmd("gettxout", (*GetTxOutCmd)(nil), flags)
 	MustRegisterCmd("gettxoutproof", (*GetTxOutProofCmd)(nil), flags)
 	MustRegisterCmd("gettxoutsetinfo", (*GetTxOutSetInfoCmd)(nil), flags)
 	MustRegisterCmd("getwork", (*GetWorkCmd)(nil), flags)
 	MustRegisterCmd("help", (*HelpCmd)(nil), flags)
 	MustRegisterCmd("invalidateblock", (*InvalidateBlockCmd)(nil), flags)
+	MustRegisterCmd("loadtxoutset", (*LoadTxOutSetCmd)(nil), flags)
 	MustRegisterCmd("ping", (*PingCmd)(nil), flags)
 	MustRegisterCmd("preciousblock", (*PreciousBlockCmd)(nil), flags)
 	MustRegisterCmd("reconsiderblock", (*ReconsiderBlockCmd)(nil), flags)
+	MustRegisterCmd("scantxoutset", (*ScanTxOutSetCmd)(nil), flags)
 	MustRegisterCmd("searchrawtransactions", (*SearchRawTransactionsCmd)(nil), flags)
 	MustRegisterCmd("sendrawtransaction", (*SendRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("setgenerate", (*SetGenerateCmd)(nil), flags)
 	MustRegisterCmd("signmessagewithprivkey", (*SignMessageWithPrivKeyCmd)(nil), flags)
 	MustRegisterCmd("stop", (*StopCmd)(nil), flags)
 	MustRegisterCmd("submitblock", (*SubmitBlockCmd)(nil), flags)
+	MustRegisterCmd("submitpackage", (*SubmitPackageCmd)(nil), flags)
+	MustRegisterCmd("testmempoolaccept", (*TestMempoolAcceptCmd)(nil), flags)
 	MustRegisterCmd("uptime", (*UptimeCmd)(nil), flags)
 	MustRegisterCmd("validateaddress", (*ValidateAddressCmd)(nil), flags)
 	MustRegisterCmd("verifychain", (*VerifyChainCmd)(nil), flags)
+	MustRegisterCmd("verifydb", (*VerifyDBCmd)(nil), flags)
 	MustRegisterCmd("verifymessage", (*VerifyMessageCmd)(nil), flags)
 	MustRegisterCmd("verifytxoutproof", (*VerifyTxOutProofCmd)(nil), flags)
 }
@@ -42,6 +42,17 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"addnode","params":["127.0.0.1","remove"],"id":1}`,
 			unmarshalled: &btcjson.AddNodeCmd{Addr: "127.0.0.1", SubCmd: btcjson.ANRemove},
 		},
+		{
+			name: "backupchainstate",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("backupchainstate", "backup.tar")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBackupChainStateCmd("backup.tar")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"backupchainstate","params":["backup.tar"],"id":1}`,
+			unmarshalled: &btcjson.BackupChainStateCmd{Destination: "backup.tar"},
+		},
 		{
 			name: "createrawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -239,6 +250,17 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"decodescript","params":["00"],"id":1}`,
 			unmarshalled: &btcjson.DecodeScriptCmd{HexScript: "00"},
 		},
+		{
+			name: "dumptxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("dumptxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDumpTxOutSetCmd("utxo.dat")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"dumptxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.DumpTxOutSetCmd{Path: "utxo.dat"},
+		},
 		{
 			name: "deriveaddresses no range",
 			newCmd: func() (interface{}, error) {
@@ -309,6 +331,52 @@ func TestChainSvrCmds(t *testing.T) {
 				Node: btcjson.String("127.0.0.1"),
 			},
 		},
+		{
+			name: "getaddressbalance",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddressbalance", "1Address")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressBalanceCmd("1Address")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddressbalance","params":["1Address"],"id":1}`,
+			unmarshalled: &btcjson.GetAddressBalanceCmd{
+				Address: "1Address",
+			},
+		},
+		{
+			name: "getaddresstxids",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddresstxids", "1Address")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressTxIDsCmd("1Address", nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddresstxids","params":["1Address"],"id":1}`,
+			unmarshalled: &btcjson.GetAddressTxIDsCmd{
+				Address: "1Address",
+				Skip:    btcjson.Int(0),
+				Count:   btcjson.Int(100),
+				Reverse: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getaddresstxids optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getaddresstxids", "1Address", 10, 50, true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetAddressTxIDsCmd("1Address",
+					btcjson.Int(10), btcjson.Int(50), btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getaddresstxids","params":["1Address",10,50,true],"id":1}`,
+			unmarshalled: &btcjson.GetAddressTxIDsCmd{
+				Address: "1Address",
+				Skip:    btcjson.Int(10),
+				Count:   btcjson.Int(50),
+				Reverse: btcjson.Bool(true),
+			},
+		},
 		{
 			name: "getbestblockhash",
 			newCmd: func() (interface{}, error) {
@@ -398,6 +466,20 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getblockcount","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetBlockCountCmd{},
 		},
+		{
+			name: "getblockfrompeer",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockfrompeer", "123", 7)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockFromPeerCmd("123", 7)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockfrompeer","params":["123",7],"id":1}`,
+			unmarshalled: &btcjson.GetBlockFromPeerCmd{
+				BlockHash: "123",
+				PeerID:    7,
+			},
+		},
 		{
 			name: "getblockfilter",
 			newCmd: func() (interface{}, error) {
@@ -445,6 +527,37 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: btcjson.Bool(true),
 			},
 		},
+		{
+			name: "getblockheaders",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockheaders", btcjson.HashOrHeight{Value: 123})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockHeadersCmd(btcjson.HashOrHeight{Value: 123}, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockheaders","params":[123],"id":1}`,
+			unmarshalled: &btcjson.GetBlockHeadersCmd{
+				HashOrHeight: btcjson.HashOrHeight{Value: 123},
+				Count:        btcjson.Int32(2000),
+				Verbose:      btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getblockheaders optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockheaders", btcjson.HashOrHeight{Value: "deadbeef"}, 10, true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockHeadersCmd(btcjson.HashOrHeight{Value: "deadbeef"},
+					btcjson.Int32(10), btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockheaders","params":["deadbeef",10,true],"id":1}`,
+			unmarshalled: &btcjson.GetBlockHeadersCmd{
+				HashOrHeight: btcjson.HashOrHeight{Value: "deadbeef"},
+				Count:        btcjson.Int32(10),
+				Verbose:      btcjson.Bool(true),
+			},
+		},
 		{
 			name: "getblockstats height",
 			newCmd: func() (interface{}, error) {
@@ -582,6 +695,28 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "getblocktemplate optional - verbose proposal",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblocktemplate", `{"mode":"proposal","data":"00","verbose":true}`)
+			},
+			staticCmd: func() interface{} {
+				template := btcjson.TemplateRequest{
+					Mode:    "proposal",
+					Data:    "00",
+					Verbose: true,
+				}
+				return btcjson.NewGetBlockTemplateCmd(&template)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblocktemplate","params":[{"mode":"proposal","data":"00","verbose":true}],"id":1}`,
+			unmarshalled: &btcjson.GetBlockTemplateCmd{
+				Request: &btcjson.TemplateRequest{
+					Mode:    "proposal",
+					Data:    "00",
+					Verbose: true,
+				},
+			},
+		},
 		{
 			name: "getcfilter",
 			newCmd: func() (interface{}, error) {
@@ -620,10 +755,25 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("getchaintips")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewGetChainTipsCmd()
+				return btcjson.NewGetChainTipsCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getchaintips","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetChainTipsCmd{
+				Verbose: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getchaintips optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getchaintips", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetChainTipsCmd(btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getchaintips","params":[true],"id":1}`,
+			unmarshalled: &btcjson.GetChainTipsCmd{
+				Verbose: btcjson.Bool(true),
 			},
-			marshalled:   `{"jsonrpc":"1.0","method":"getchaintips","params":[],"id":1}`,
-			unmarshalled: &btcjson.GetChainTipsCmd{},
 		},
 		{
 			name: "getchaintxstats",
@@ -718,6 +868,35 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getinfo","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetInfoCmd{},
 		},
+		{
+			name: "getmempoolancestors",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempoolancestors", "txhash")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolAncestorsCmd("txhash", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempoolancestors","params":["txhash"],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolAncestorsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getmempooldescendants",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempooldescendants", "txhash", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolDescendantsCmd("txhash",
+					btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempooldescendants","params":["txhash",true],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolDescendantsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(true),
+			},
+		},
 		{
 			name: "getmempoolentry",
 			newCmd: func() (interface{}, error) {
@@ -731,6 +910,17 @@ func TestChainSvrCmds(t *testing.T) {
 				TxID: "txhash",
 			},
 		},
+		{
+			name: "getmempoolfeehistogram",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempoolfeehistogram")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolFeeHistogramCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getmempoolfeehistogram","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolFeeHistogramCmd{},
+		},
 		{
 			name: "getmempoolinfo",
 			newCmd: func() (interface{}, error) {
@@ -770,10 +960,25 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("getnettotals")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewGetNetTotalsCmd()
+				return btcjson.NewGetNetTotalsCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getnettotals","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetNetTotalsCmd{
+				Verbose: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getnettotals verbose",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getnettotals", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNetTotalsCmd(btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getnettotals","params":[true],"id":1}`,
+			unmarshalled: &btcjson.GetNetTotalsCmd{
+				Verbose: btcjson.Bool(true),
 			},
-			marshalled:   `{"jsonrpc":"1.0","method":"getnettotals","params":[],"id":1}`,
-			unmarshalled: &btcjson.GetNetTotalsCmd{},
 		},
 		{
 			name: "getnetworkhashps",
@@ -789,6 +994,30 @@ func TestChainSvrCmds(t *testing.T) {
 				Height: btcjson.Int(-1),
 			},
 		},
+		{
+			name: "getnextdifficulty",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getnextdifficulty")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNextDifficultyCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getnextdifficulty","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetNextDifficultyCmd{},
+		},
+		{
+			name: "getnextdifficulty optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getnextdifficulty", 1700000000)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNextDifficultyCmd(btcjson.Int64(1700000000))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getnextdifficulty","params":[1700000000],"id":1}`,
+			unmarshalled: &btcjson.GetNextDifficultyCmd{
+				Timestamp: btcjson.Int64(1700000000),
+			},
+		},
 		{
 			name: "getnetworkhashps optional1",
 			newCmd: func() (interface{}, error) {
@@ -908,6 +1137,20 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: btcjson.Bool(true),
 			},
 		},
+		{
+			name: "getspentinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getspentinfo", "123", 1)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetSpentInfoCmd("123", 1)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getspentinfo","params":["123",1],"id":1}`,
+			unmarshalled: &btcjson.GetSpentInfoCmd{
+				Txid: "123",
+				Vout: 1,
+			},
+		},
 		{
 			name: "gettxout",
 			newCmd: func() (interface{}, error) {
@@ -1044,6 +1287,17 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "loadtxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadtxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewLoadTxOutSetCmd("utxo.dat")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"loadtxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.LoadTxOutSetCmd{Path: "utxo.dat"},
+		},
 		{
 			name: "ping",
 			newCmd: func() (interface{}, error) {
@@ -1081,6 +1335,34 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "scantxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("scantxoutset", "status")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewScanTxOutSetCmd(btcjson.ScanTxOutSetStatus, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scantxoutset","params":["status"],"id":1}`,
+			unmarshalled: &btcjson.ScanTxOutSetCmd{
+				Action: btcjson.ScanTxOutSetStatus,
+			},
+		},
+		{
+			name: "scantxoutset optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("scantxoutset", "start", []string{"addr(1Address)", "claimid(123)"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewScanTxOutSetCmd(btcjson.ScanTxOutSetStart,
+					&[]string{"addr(1Address)", "claimid(123)"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scantxoutset","params":["start",["addr(1Address)","claimid(123)"]],"id":1}`,
+			unmarshalled: &btcjson.ScanTxOutSetCmd{
+				Action:      btcjson.ScanTxOutSetStart,
+				ScanObjects: &[]string{"addr(1Address)", "claimid(123)"},
+			},
+		},
 		{
 			name: "searchrawtransactions",
 			newCmd: func() (interface{}, error) {
@@ -1294,7 +1576,7 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("setgenerate", true)
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewSetGenerateCmd(true, nil)
+				return btcjson.NewSetGenerateCmd(true, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"setgenerate","params":[true],"id":1}`,
 			unmarshalled: &btcjson.SetGenerateCmd{
@@ -1308,7 +1590,7 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("setgenerate", true, 6)
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewSetGenerateCmd(true, btcjson.Int(6))
+				return btcjson.NewSetGenerateCmd(true, btcjson.Int(6), nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"setgenerate","params":[true,6],"id":1}`,
 			unmarshalled: &btcjson.SetGenerateCmd{
@@ -1316,6 +1598,22 @@ func TestChainSvrCmds(t *testing.T) {
 				GenProcLimit: btcjson.Int(6),
 			},
 		},
+		{
+			name: "setgenerate payouts",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setgenerate", true, 2, `{"1Address":3}`)
+			},
+			staticCmd: func() interface{} {
+				payouts := map[string]uint32{"1Address": 3}
+				return btcjson.NewSetGenerateCmd(true, btcjson.Int(2), &payouts)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setgenerate","params":[true,2,{"1Address":3}],"id":1}`,
+			unmarshalled: &btcjson.SetGenerateCmd{
+				Generate:     true,
+				GenProcLimit: btcjson.Int(2),
+				Payouts:      &map[string]uint32{"1Address": 3},
+			},
+		},
 		{
 			name: "signmessagewithprivkey",
 			newCmd: func() (interface{}, error) {
@@ -1374,6 +1672,47 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "testmempoolaccept",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("testmempoolaccept", []string{"rawhex"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewTestMempoolAcceptCmd([]string{"rawhex"}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["rawhex"]],"id":1}`,
+			unmarshalled: &btcjson.TestMempoolAcceptCmd{
+				RawTxns:    []string{"rawhex"},
+				MaxFeeRate: btcjson.Float64(0.1),
+			},
+		},
+		{
+			name: "testmempoolaccept with maxfeerate",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("testmempoolaccept", []string{"rawhex"}, 0.01)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewTestMempoolAcceptCmd([]string{"rawhex"}, btcjson.Float64(0.01))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["rawhex"],0.01],"id":1}`,
+			unmarshalled: &btcjson.TestMempoolAcceptCmd{
+				RawTxns:    []string{"rawhex"},
+				MaxFeeRate: btcjson.Float64(0.01),
+			},
+		},
+		{
+			name: "submitpackage",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("submitpackage", []string{"parenthex", "childhex"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSubmitPackageCmd([]string{"parenthex", "childhex"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"submitpackage","params":[["parenthex","childhex"]],"id":1}`,
+			unmarshalled: &btcjson.SubmitPackageCmd{
+				RawTxns: []string{"parenthex", "childhex"},
+			},
+		},
 		{
 			name: "uptime",
 			newCmd: func() (interface{}, error) {
@@ -1440,6 +1779,32 @@ func TestChainSvrCmds(t *testing.T) {
 				CheckDepth: btcjson.Int32(500),
 			},
 		},
+		{
+			name: "verifydb",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("verifydb")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewVerifyDBCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"verifydb","params":[],"id":1}`,
+			unmarshalled: &btcjson.VerifyDBCmd{
+				Scan: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "verifydb optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("verifydb", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewVerifyDBCmd(btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"verifydb","params":[true],"id":1}`,
+			unmarshalled: &btcjson.VerifyDBCmd{
+				Scan: btcjson.Bool(true),
+			},
+		},
 		{
 			name: "verifymessage",
 			newCmd: func() (interface{}, error) {
@@ -15,6 +15,12 @@ import (
 	btcutil "github.com/lbryio/lbcutil"
 )
 
+// BackupChainStateResult models the data from the backupchainstate command.
+type BackupChainStateResult struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
 // GetBlockHeaderVerboseResult models the data from the getblockheader command when
 // the verbose flag is set.  When the verbose flag is not set, getblockheader
 // returns a hex-encoded string.
@@ -37,35 +43,40 @@ type GetBlockHeaderVerboseResult struct {
 // GetBlockStatsResult models the data from the getblockstats command.
 // Pointers are used instead of values to allow for optional fields.
 type GetBlockStatsResult struct {
-	AverageFee         *int64   `json:"avgfee,omitempty"`
-	AverageFeeRate     *int64   `json:"avgfeerate,omitempty"`
-	AverageTxSize      *int64   `json:"avgtxsize,omitempty"`
-	FeeratePercentiles *[]int64 `json:"feerate_percentiles,omitempty"`
-	Hash               *string  `json:"blockhash,omitempty"`
-	Height             *int64   `json:"height,omitempty"`
-	Ins                *int64   `json:"ins,omitempty"`
-	MaxFee             *int64   `json:"maxfee,omitempty"`
-	MaxFeeRate         *int64   `json:"maxfeerate,omitempty"`
-	MaxTxSize          *int64   `json:"maxtxsize,omitempty"`
-	MedianFee          *int64   `json:"medianfee,omitempty"`
-	MedianTime         *int64   `json:"mediantime,omitempty"`
-	MedianTxSize       *int64   `json:"mediantxsize,omitempty"`
-	MinFee             *int64   `json:"minfee,omitempty"`
-	MinFeeRate         *int64   `json:"minfeerate,omitempty"`
-	MinTxSize          *int64   `json:"mintxsize,omitempty"`
-	Outs               *int64   `json:"outs,omitempty"`
-	SegWitTotalSize    *int64   `json:"swtotal_size,omitempty"`
-	SegWitTotalWeight  *int64   `json:"swtotal_weight,omitempty"`
-	SegWitTxs          *int64   `json:"swtxs,omitempty"`
-	Subsidy            *int64   `json:"subsidy,omitempty"`
-	Time               *int64   `json:"time,omitempty"`
-	TotalOut           *int64   `json:"total_out,omitempty"`
-	TotalSize          *int64   `json:"total_size,omitempty"`
-	TotalWeight        *int64   `json:"total_weight,omitempty"`
-	TotalFee           *int64   `json:"totalfee,omitempty"`
-	Txs                *int64   `json:"txs,omitempty"`
-	UTXOIncrease       *int64   `json:"utxo_increase,omitempty"`
-	UTXOSizeIncrease   *int64   `json:"utxo_size_inc,omitempty"`
+	AverageFee         *int64    `json:"avgfee,omitempty"`
+	AverageFeeRate     *int64    `json:"avgfeerate,omitempty"`
+	AverageTxSize      *int64    `json:"avgtxsize,omitempty"`
+	FeeratePercentiles *[]int64  `json:"feerate_percentiles,omitempty"`
+	Hash               *string   `json:"blockhash,omitempty"`
+	Height             *int64    `json:"height,omitempty"`
+	Ins                *int64    `json:"ins,omitempty"`
+	MaxFee             *int64    `json:"maxfee,omitempty"`
+	MaxFeeRate         *int64    `json:"maxfeerate,omitempty"`
+	MaxTxSize          *int64    `json:"maxtxsize,omitempty"`
+	MedianFee          *int64    `json:"medianfee,omitempty"`
+	MedianTime         *int64    `json:"mediantime,omitempty"`
+	MedianTxSize       *int64    `json:"mediantxsize,omitempty"`
+	MinFee             *int64    `json:"minfee,omitempty"`
+	MinFeeRate         *int64    `json:"minfeerate,omitempty"`
+	MinTxSize          *int64    `json:"mintxsize,omitempty"`
+	Outs               *int64    `json:"outs,omitempty"`
+	SegWitTotalSize    *int64    `json:"swtotal_size,omitempty"`
+	SegWitTotalWeight  *int64    `json:"swtotal_weight,omitempty"`
+	SegWitTxs          *int64    `json:"swtxs,omitempty"`
+	Subsidy            *int64    `json:"subsidy,omitempty"`
+	Time               *int64    `json:"time,omitempty"`
+	TotalOut           *int64    `json:"total_out,omitempty"`
+	TotalSize          *int64    `json:"total_size,omitempty"`
+	TotalWeight        *int64    `json:"total_weight,omitempty"`
+	TotalFee           *int64    `json:"totalfee,omitempty"`
+	Txs                *int64    `json:"txs,omitempty"`
+	UTXOIncrease       *int64    `json:"utxo_increase,omitempty"`
+	UTXOSizeIncrease   *int64    `json:"utxo_size_inc,omitempty"`
+	Claims             *int64    `json:"claims,omitempty"`
+	ClaimUpdates       *int64    `json:"claimupdates,omitempty"`
+	Supports           *int64    `json:"supports,omitempty"`
+	Abandons           *int64    `json:"abandons,omitempty"`
+	ClaimNames         *[]string `json:"claimnames,omitempty"`
 }
 
 type GetBlockVerboseResultBase struct {
@@ -155,6 +166,16 @@ type GetAddedNodeInfoResult struct {
 	Addresses *[]GetAddedNodeInfoResultAddr `json:"addresses,omitempty"`
 }
 
+// GetAddressBalanceResult models the data from the getaddressbalance command.
+// Claim and support outputs are included in the balance and are additionally
+// broken out into their own fields.
+type GetAddressBalanceResult struct {
+	Balance  float64 `json:"balance"`
+	Received float64 `json:"received"`
+	Claims   float64 `json:"claims"`
+	Supports float64 `json:"supports"`
+}
+
 // SoftForkDescription describes the current state of a soft-fork which was
 // deployed using a super-majority block signalling.
 type SoftForkDescription struct {
@@ -211,18 +232,19 @@ type UnifiedSoftForks struct {
 // GetBlockChainInfoResult models the data returned from the getblockchaininfo
 // command.
 type GetBlockChainInfoResult struct {
-	Chain                string  `json:"chain"`
-	Blocks               int32   `json:"blocks"`
-	Headers              int32   `json:"headers"`
-	BestBlockHash        string  `json:"bestblockhash"`
-	Difficulty           float64 `json:"difficulty"`
-	MedianTime           int64   `json:"mediantime"`
-	VerificationProgress float64 `json:"verificationprogress,omitempty"`
-	InitialBlockDownload bool    `json:"initialblockdownload,omitempty"`
-	Pruned               bool    `json:"pruned"`
-	PruneHeight          int32   `json:"pruneheight,omitempty"`
-	ChainWork            string  `json:"chainwork,omitempty"`
-	SizeOnDisk           int64   `json:"size_on_disk,omitempty"`
+	Chain                  string  `json:"chain"`
+	Blocks                 int32   `json:"blocks"`
+	Headers                int32   `json:"headers"`
+	BestBlockHash          string  `json:"bestblockhash"`
+	Difficulty             float64 `json:"difficulty"`
+	MedianTime             int64   `json:"mediantime"`
+	VerificationProgress   float64 `json:"verificationprogress,omitempty"`
+	InitialBlockDownload   bool    `json:"initialblockdownload,omitempty"`
+	EstimatedTimeRemaining int64   `json:"estimatedtimeremaining,omitempty"`
+	Pruned                 bool    `json:"pruned"`
+	PruneHeight            int32   `json:"pruneheight,omitempty"`
+	ChainWork              string  `json:"chainwork,omitempty"`
+	SizeOnDisk             int64   `json:"size_on_disk,omitempty"`
 	*SoftForks
 	*UnifiedSoftForks
 }
@@ -274,6 +296,10 @@ type GetBlockTemplateResult struct {
 	// Witness commitment defined in BIP 0141.
 	DefaultWitnessCommitment string `json:"default_witness_commitment,omitempty"`
 
+	// SignetChallenge is the challenge blocks must be signed with on signet
+	// networks.
+	SignetChallenge string `json:"signet_challenge,omitempty"`
+
 	// Optional long polling from BIP 0022.
 	LongPollID  string `json:"longpollid,omitempty"`
 	LongPollURI string `json:"longpolluri,omitempty"`
@@ -298,6 +324,15 @@ type GetBlockTemplateResult struct {
 	Rules []string `json:"rules,omitempty"`
 }
 
+// GetBlockTemplateProposalResult models the data returned from the
+// getblocktemplate command in proposal mode when a verbose result is requested.
+type GetBlockTemplateProposalResult struct {
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
 // GetMempoolEntryResult models the data returned from the getmempoolentry's
 // fee field
 
@@ -338,16 +373,62 @@ type GetChainTipsResult struct {
 	Status    string `json:"status"`
 }
 
+// GetChainTipsVerboseResult models the data returned from the getchaintips
+// command when the verbose flag is set.  It includes statistics about the
+// stale forks of the block index and their pruning along with the chain tips.
+type GetChainTipsVerboseResult struct {
+	Tips            []GetChainTipsResult `json:"tips"`
+	IndexNodes      int64                `json:"indexnodes"`
+	StaleNodes      int64                `json:"stalenodes"`
+	PruneForkDepth  int32                `json:"pruneforkdepth"`
+	PrunedNodes     uint64               `json:"prunednodes"`
+	LastPruneHeight int32                `json:"lastpruneheight"`
+}
+
 // GetMempoolInfoResult models the data returned from the getmempoolinfo
 // command.
 type GetMempoolInfoResult struct {
-	Size             int64   `json:"size"`             // Current tx count
-	Bytes            int64   `json:"bytes"`            // Sum of all virtual transaction sizes as defined in BIP 141. Differs from actual serialized size because witness data is discounted
-	Usage            int64   `json:"usage"`            // Total memory usage for the mempool
-	TotalFee         float64 `json:"total_fee"`        // Total fees for the mempool in LBC, ignoring modified fees through prioritizetransaction
-	MemPoolMinFee    float64 `json:"mempoolminfee"`    // Minimum fee rate in LBC/kvB for tx to be accepted. Is the maximum of minrelaytxfee and minimum mempool fee
-	MinRelayTxFee    float64 `json:"minrelaytxfee"`    // Current minimum relay fee for transactions
-	UnbroadcastCount int64   `json:"unbroadcastcount"` // Current number of transactions that haven't passed initial broadcast yet
+	Size             int64               `json:"size"`             // Current tx count
+	Bytes            int64               `json:"bytes"`            // Sum of all virtual transaction sizes as defined in BIP 141. Differs from actual serialized size because witness data is discounted
+	Usage            int64               `json:"usage"`            // Total memory usage for the mempool
+	MaxMempool       int64               `json:"maxmempool"`       // Maximum size in bytes of the mempool transactions, zero for no limit
+	TotalFee         float64             `json:"total_fee"`        // Total fees for the mempool in LBC, ignoring modified fees through prioritizetransaction
+	MemPoolMinFee    float64             `json:"mempoolminfee"`    // Minimum fee rate in LBC/kvB for tx to be accepted. Is the maximum of minrelaytxfee and minimum mempool fee
+	MinRelayTxFee    float64             `json:"minrelaytxfee"`    // Current minimum relay fee for transactions
+	UnbroadcastCount int64               `json:"unbroadcastcount"` // Current number of transactions that haven't passed initial broadcast yet
+	Policy           MempoolPolicyResult `json:"policy"`           // Effective policy deciding which transactions are accepted
+}
+
+// GetMempoolFeeHistogramResult models the data returned from the
+// getmempoolfeehistogram command.
+type GetMempoolFeeHistogramResult struct {
+	Buckets []MempoolFeeBucket `json:"buckets"` // Non-empty buckets, highest fee rates first
+	Count   int64              `json:"count"`   // Number of transactions in the mempool
+	VSize   int64              `json:"vsize"`   // Total virtual size of the transactions in the mempool
+}
+
+// MempoolFeeBucket models a bucket of fee rates of the mempool fee histogram,
+// as returned by the getmempoolfeehistogram command.
+type MempoolFeeBucket struct {
+	MinFeeRate float64 `json:"minfeerate"`           // Lower bound of the fee rates in dewies/vB, included
+	MaxFeeRate float64 `json:"maxfeerate,omitempty"` // Upper bound of the fee rates in dewies/vB, excluded, none for the last bucket
+	Count      int64   `json:"count"`                // Number of transactions in the bucket
+	VSize      int64   `json:"vsize"`                // Total virtual size of the transactions in the bucket
+	Fees       float64 `json:"fees"`                 // Total fees of the transactions in the bucket in LBC
+}
+
+// MempoolPolicyResult models the policy deciding which transactions are
+// accepted into the mempool, as returned by the getmempoolinfo command.
+type MempoolPolicyResult struct {
+	AcceptNonStd       bool    `json:"acceptnonstd"`
+	MaxTxVersion       int32   `json:"maxtxversion"`
+	DustRelayFee       float64 `json:"dustrelayfee"` // Fee rate in LBC/kB used to determine dust outputs
+	DataCarrierSize    int     `json:"datacarriersize"`
+	MaxClaimValueSize  int     `json:"maxclaimvaluesize"`
+	MinClaimAmount     float64 `json:"minclaimamount"`   // Minimum amount in LBC of standard claim and update outputs
+	MinSupportAmount   float64 `json:"minsupportamount"` // Minimum amount in LBC of standard support outputs
+	PermitBareMultiSig bool    `json:"permitbaremultisig"`
+	RejectReplacement  bool    `json:"rejectreplacement"`
 }
 
 // NetworksResult models the networks data from the getnetworkinfo command.
@@ -357,6 +438,9 @@ type NetworksResult struct {
 	Reachable                 bool   `json:"reachable"`
 	Proxy                     string `json:"proxy"`
 	ProxyRandomizeCredentials bool   `json:"proxy_randomize_credentials"`
+	InboundPeers              int32  `json:"inboundpeers"`
+	OutboundPeers             int32  `json:"outboundpeers"`
+	OutboundTarget            uint32 `json:"outboundtarget,omitempty"`
 }
 
 // LocalAddressesResult models the localaddresses data from the getnetworkinfo
@@ -367,8 +451,23 @@ type LocalAddressesResult struct {
 	Score   int32  `json:"score"`
 }
 
+// PortMappingResult models the portmapping data from the getnetworkinfo
+// command.
+type PortMappingResult struct {
+	Status          string `json:"status"`
+	Protocol        string `json:"protocol,omitempty"`
+	ExternalAddress string `json:"externaladdress,omitempty"`
+	ExternalPort    uint16 `json:"externalport,omitempty"`
+	InternalPort    uint16 `json:"internalport"`
+	LeaseExpires    int64  `json:"leaseexpires,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
 // GetNetworkInfoResult models the data returned from the getnetworkinfo
 // command.
+//
+// PortMapping is an lbcd extension which is only set when the listening port
+// is mapped on the NAT gateway with NAT-PMP or UPnP.
 type GetNetworkInfoResult struct {
 	Version         int32                  `json:"version"`
 	SubVersion      string                 `json:"subversion"`
@@ -382,6 +481,7 @@ type GetNetworkInfoResult struct {
 	RelayFee        float64                `json:"relayfee"`
 	IncrementalFee  float64                `json:"incrementalfee"`
 	LocalAddresses  []LocalAddressesResult `json:"localaddresses"`
+	PortMapping     *PortMappingResult     `json:"portmapping,omitempty"`
 	Warnings        string                 `json:"warnings"`
 }
 
@@ -397,27 +497,35 @@ type GetNodeAddressesResult struct {
 
 // GetPeerInfoResult models the data returned from the getpeerinfo command.
 type GetPeerInfoResult struct {
-	ID             int32   `json:"id"`
-	Addr           string  `json:"addr"`
-	AddrLocal      string  `json:"addrlocal,omitempty"`
-	Services       string  `json:"services"`
-	RelayTxes      bool    `json:"relaytxes"`
-	LastSend       int64   `json:"lastsend"`
-	LastRecv       int64   `json:"lastrecv"`
-	BytesSent      uint64  `json:"bytessent"`
-	BytesRecv      uint64  `json:"bytesrecv"`
-	ConnTime       int64   `json:"conntime"`
-	TimeOffset     int64   `json:"timeoffset"`
-	PingTime       float64 `json:"pingtime"`
-	PingWait       float64 `json:"pingwait,omitempty"`
-	Version        uint32  `json:"version"`
-	SubVer         string  `json:"subver"`
-	Inbound        bool    `json:"inbound"`
-	StartingHeight int32   `json:"startingheight"`
-	CurrentHeight  int32   `json:"currentheight,omitempty"`
-	BanScore       int32   `json:"banscore"`
-	FeeFilter      int64   `json:"feefilter"`
-	SyncNode       bool    `json:"syncnode"`
+	ID               int32             `json:"id"`
+	Addr             string            `json:"addr"`
+	AddrLocal        string            `json:"addrlocal,omitempty"`
+	Services         string            `json:"services"`
+	RelayTxes        bool              `json:"relaytxes"`
+	LastSend         int64             `json:"lastsend"`
+	LastRecv         int64             `json:"lastrecv"`
+	BytesSent        uint64            `json:"bytessent"`
+	BytesRecv        uint64            `json:"bytesrecv"`
+	BytesSentPerMsg  map[string]uint64 `json:"bytessent_per_msg"`
+	BytesRecvPerMsg  map[string]uint64 `json:"bytesrecv_per_msg"`
+	ConnTime         int64             `json:"conntime"`
+	TimeOffset       int64             `json:"timeoffset"`
+	PingTime         float64           `json:"pingtime"`
+	PingWait         float64           `json:"pingwait,omitempty"`
+	Version          uint32            `json:"version"`
+	SubVer           string            `json:"subver"`
+	Inbound          bool              `json:"inbound"`
+	StartingHeight   int32             `json:"startingheight"`
+	CurrentHeight    int32             `json:"currentheight,omitempty"`
+	BanScore         int32             `json:"banscore"`
+	Misbehavior      map[string]uint32 `json:"misbehavior,omitempty"`
+	FeeFilter        int64             `json:"feefilter"`
+	BloomWork        uint64            `json:"bloomwork"`
+	SyncNode         bool              `json:"syncnode"`
+	BlocksInFlight   int               `json:"blocksinflight"`
+	BlocksReceived   uint64            `json:"blocksreceived"`
+	BlocksReassigned uint64            `json:"blocksreassigned"`
+	BlockStalls      uint32            `json:"blockstalls"`
 }
 
 // GetRawMempoolVerboseResult models the data returned from the getrawmempool
@@ -457,7 +565,18 @@ type GetTxOutResult struct {
 	Coinbase      bool               `json:"coinbase"`
 }
 
+// GetSpentInfoResult models the data from the getspentinfo command.
+type GetSpentInfoResult struct {
+	Txid   string `json:"txid"`
+	Vin    uint32 `json:"vin"`
+	Height int32  `json:"height"`
+}
+
 // GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
+//
+// Transactions, HashSerialized and DiskSize aren't reported by lbcd, which
+// hashes the set with MuHash instead.  They are omitted from the marshalled
+// result when they are zero.
 type GetTxOutSetInfoResult struct {
 	Height         int64          `json:"height"`
 	BestBlock      chainhash.Hash `json:"bestblock"`
@@ -465,10 +584,44 @@ type GetTxOutSetInfoResult struct {
 	TxOuts         int64          `json:"txouts"`
 	BogoSize       int64          `json:"bogosize"`
 	HashSerialized chainhash.Hash `json:"hash_serialized_2"`
+	MuHash         chainhash.Hash `json:"muhash"`
 	DiskSize       int64          `json:"disk_size"`
 	TotalAmount    btcutil.Amount `json:"total_amount"`
 }
 
+// MarshalJSON marshals the result of the gettxoutsetinfo JSON-RPC call with
+// the hashes as strings and the total amount in LBC.
+func (g GetTxOutSetInfoResult) MarshalJSON() ([]byte, error) {
+	hashString := func(hash *chainhash.Hash) string {
+		if *hash == (chainhash.Hash{}) {
+			return ""
+		}
+		return hash.String()
+	}
+
+	return json.Marshal(&struct {
+		Height         int64   `json:"height"`
+		BestBlock      string  `json:"bestblock"`
+		Transactions   int64   `json:"transactions,omitempty"`
+		TxOuts         int64   `json:"txouts"`
+		BogoSize       int64   `json:"bogosize"`
+		HashSerialized string  `json:"hash_serialized_2,omitempty"`
+		MuHash         string  `json:"muhash,omitempty"`
+		DiskSize       int64   `json:"disk_size,omitempty"`
+		TotalAmount    float64 `json:"total_amount"`
+	}{
+		Height:         g.Height,
+		BestBlock:      g.BestBlock.String(),
+		Transactions:   g.Transactions,
+		TxOuts:         g.TxOuts,
+		BogoSize:       g.BogoSize,
+		HashSerialized: hashString(&g.HashSerialized),
+		MuHash:         hashString(&g.MuHash),
+		DiskSize:       g.DiskSize,
+		TotalAmount:    g.TotalAmount.ToBTC(),
+	})
+}
+
 // UnmarshalJSON unmarshals the result of the gettxoutsetinfo JSON-RPC call
 func (g *GetTxOutSetInfoResult) UnmarshalJSON(data []byte) error {
 	// Step 1: Create type aliases of the original struct.
@@ -479,6 +632,7 @@ func (g *GetTxOutSetInfoResult) UnmarshalJSON(data []byte) error {
 	aux := &struct {
 		BestBlock      string  `json:"bestblock"`
 		HashSerialized string  `json:"hash_serialized_2"`
+		MuHash         string  `json:"muhash"`
 		TotalAmount    float64 `json:"total_amount"`
 		*Alias
 	}{
@@ -505,6 +659,13 @@ func (g *GetTxOutSetInfoResult) UnmarshalJSON(data []byte) error {
 
 	g.HashSerialized = *serializedHash
 
+	muHash, err := chainhash.NewHashFromStr(aux.MuHash)
+	if err != nil {
+		return err
+	}
+
+	g.MuHash = *muHash
+
 	amount, err := btcutil.NewAmount(aux.TotalAmount)
 	if err != nil {
 		return err
@@ -516,10 +677,14 @@ func (g *GetTxOutSetInfoResult) UnmarshalJSON(data []byte) error {
 }
 
 // GetNetTotalsResult models the data returned from the getnettotals command.
+//
+// The per message breakdowns are only set when the verbose flag is set.
 type GetNetTotalsResult struct {
-	TotalBytesRecv uint64 `json:"totalbytesrecv"`
-	TotalBytesSent uint64 `json:"totalbytessent"`
-	TimeMillis     int64  `json:"timemillis"`
+	TotalBytesRecv  uint64            `json:"totalbytesrecv"`
+	TotalBytesSent  uint64            `json:"totalbytessent"`
+	TimeMillis      int64             `json:"timemillis"`
+	BytesRecvPerMsg map[string]uint64 `json:"bytesrecv_per_msg,omitempty"`
+	BytesSentPerMsg map[string]uint64 `json:"bytessent_per_msg,omitempty"`
 }
 
 // ScriptSig models a signature script.  It is defined separately since it only
@@ -534,12 +699,13 @@ type ScriptSig struct {
 // getrawtransaction, decoderawtransaction, and searchrawtransaction use the
 // same structure.
 type Vin struct {
-	Coinbase  string     `json:"coinbase"`
-	Txid      string     `json:"txid"`
-	Vout      uint32     `json:"vout"`
-	ScriptSig *ScriptSig `json:"scriptSig"`
-	Sequence  uint32     `json:"sequence"`
-	Witness   []string   `json:"txinwitness"`
+	Coinbase  string            `json:"coinbase"`
+	Txid      string            `json:"txid"`
+	Vout      uint32            `json:"vout"`
+	ScriptSig *ScriptSig        `json:"scriptSig"`
+	Sequence  uint32            `json:"sequence"`
+	Witness   []string          `json:"txinwitness"`
+	PrevOut   *VinPrevOutResult `json:"prevout,omitempty"`
 }
 
 // IsCoinBase returns a bool to show if a Vin is a Coinbase one or not.
@@ -570,35 +736,49 @@ func (v *Vin) MarshalJSON() ([]byte, error) {
 
 	if v.HasWitness() {
 		txStruct := struct {
-			Txid      string     `json:"txid"`
-			Vout      uint32     `json:"vout"`
-			ScriptSig *ScriptSig `json:"scriptSig"`
-			Witness   []string   `json:"txinwitness"`
-			Sequence  uint32     `json:"sequence"`
+			Txid      string            `json:"txid"`
+			Vout      uint32            `json:"vout"`
+			ScriptSig *ScriptSig        `json:"scriptSig"`
+			Witness   []string          `json:"txinwitness"`
+			Sequence  uint32            `json:"sequence"`
+			PrevOut   *VinPrevOutResult `json:"prevout,omitempty"`
 		}{
 			Txid:      v.Txid,
 			Vout:      v.Vout,
 			ScriptSig: v.ScriptSig,
 			Witness:   v.Witness,
 			Sequence:  v.Sequence,
+			PrevOut:   v.PrevOut,
 		}
 		return json.Marshal(txStruct)
 	}
 
 	txStruct := struct {
-		Txid      string     `json:"txid"`
-		Vout      uint32     `json:"vout"`
-		ScriptSig *ScriptSig `json:"scriptSig"`
-		Sequence  uint32     `json:"sequence"`
+		Txid      string            `json:"txid"`
+		Vout      uint32            `json:"vout"`
+		ScriptSig *ScriptSig        `json:"scriptSig"`
+		Sequence  uint32            `json:"sequence"`
+		PrevOut   *VinPrevOutResult `json:"prevout,omitempty"`
 	}{
 		Txid:      v.Txid,
 		Vout:      v.Vout,
 		ScriptSig: v.ScriptSig,
 		Sequence:  v.Sequence,
+		PrevOut:   v.PrevOut,
 	}
 	return json.Marshal(txStruct)
 }
 
+// VinPrevOutResult models the output spent by an input of a transaction.  It
+// is included with the getblock command at verbosity level 3 and with the
+// verbose getrawtransaction command.
+type VinPrevOutResult struct {
+	Generated    bool               `json:"generated"`
+	Height       int32              `json:"height,omitempty"`
+	Value        float64            `json:"value"`
+	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
 // PrevOut represents previous output for an input Vin.
 type PrevOut struct {
 	Addresses []string `json:"addresses,omitempty"`
@@ -683,6 +863,24 @@ type Vout struct {
 	Value        float64            `json:"value"`
 	N            uint32             `json:"n"`
 	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+	Claim        *VoutClaimResult   `json:"claim,omitempty"`
+}
+
+// VoutClaimResult models the claim, update or support created by a transaction
+// output.
+type VoutClaimResult struct {
+	Name     string               `json:"name"`
+	ClaimID  string               `json:"claimid"`
+	Value    string               `json:"value,omitempty"`
+	Metadata *ClaimMetadataResult `json:"metadata,omitempty"`
+	TimeLock *ClaimTimeLockResult `json:"timelock,omitempty"`
+}
+
+// ClaimTimeLockResult models the lock time of a claim output whose claim is
+// paid to a time-locked script.
+type ClaimTimeLockResult struct {
+	Type     string `json:"type"`
+	LockTime int64  `json:"locktime"`
 }
 
 // GetMiningInfoResult models the data from the getmininginfo command.
@@ -701,6 +899,19 @@ type GetMiningInfoResult struct {
 	TestNet            bool    `json:"testnet"`
 }
 
+// GetNextDifficultyResult models the data from the getnextdifficulty command.
+type GetNextDifficultyResult struct {
+	Height            int32   `json:"height"`
+	Bits              string  `json:"bits"`
+	Target            string  `json:"target"`
+	Difficulty        float64 `json:"difficulty"`
+	CurrentDifficulty float64 `json:"currentdifficulty"`
+	Change            float64 `json:"change"`
+	ActualTimespan    int64   `json:"actualtimespan,omitempty"`
+	AdjustedTimespan  int64   `json:"adjustedtimespan,omitempty"`
+	TargetTimespan    int64   `json:"targettimespan"`
+}
+
 // GetWorkResult models the data from the getwork command.
 type GetWorkResult struct {
 	Data     string `json:"data"`
@@ -750,6 +961,53 @@ type TxRawResult struct {
 	Blocktime     int64  `json:"blocktime,omitempty"`
 }
 
+// ScanTxOutSetResult models the data from the scantxoutset command with the
+// start action.
+type ScanTxOutSetResult struct {
+	Success     bool                  `json:"success"`
+	TxOuts      int64                 `json:"txouts"`
+	Height      int32                 `json:"height"`
+	BestBlock   string                `json:"bestblock"`
+	Unspents    []ScanTxOutSetUnspent `json:"unspents"`
+	TotalAmount float64               `json:"total_amount"`
+}
+
+// ScanTxOutSetUnspent models an unspent transaction output found by the
+// scantxoutset command.
+type ScanTxOutSetUnspent struct {
+	TxID         string           `json:"txid"`
+	Vout         uint32           `json:"vout"`
+	ScriptPubKey string           `json:"scriptPubKey"`
+	Desc         string           `json:"desc"`
+	Amount       float64          `json:"amount"`
+	Height       int32            `json:"height"`
+	Coinbase     bool             `json:"coinbase"`
+	Claim        *VoutClaimResult `json:"claim,omitempty"`
+}
+
+// ScanTxOutSetStatusResult models the data from the scantxoutset command with
+// the status action.
+type ScanTxOutSetStatusResult struct {
+	Progress float64 `json:"progress"`
+}
+
+// DumpTxOutSetResult models the data from the dumptxoutset command.
+type DumpTxOutSetResult struct {
+	CoinsWritten int64  `json:"coins_written"`
+	BaseHash     string `json:"base_hash"`
+	BaseHeight   int32  `json:"base_height"`
+	Path         string `json:"path"`
+	MuHash       string `json:"muhash"`
+}
+
+// LoadTxOutSetResult models the data from the loadtxoutset command.
+type LoadTxOutSetResult struct {
+	CoinsLoaded int64  `json:"coins_loaded"`
+	TipHash     string `json:"tip_hash"`
+	BaseHeight  int32  `json:"base_height"`
+	Path        string `json:"path"`
+}
+
 // SearchRawTransactionsResult models the data from the searchrawtransaction
 // command.
 type SearchRawTransactionsResult struct {
@@ -870,3 +1128,62 @@ type LoadWalletResult struct {
 type DumpWalletResult struct {
 	Filename string `json:"filename"`
 }
+
+// TestMempoolAcceptResult models the data from the testmempoolaccept command.
+// The result of the mempool acceptance test for each raw transaction in the
+// input array.  Returns results for each transaction in the same order they
+// were passed in.
+type TestMempoolAcceptResult struct {
+	Txid         string                 `json:"txid"`
+	Wtxid        string                 `json:"wtxid"`
+	PackageError string                 `json:"package-error,omitempty"`
+	Allowed      bool                   `json:"allowed"`
+	Vsize        int32                  `json:"vsize,omitempty"`
+	Fees         *TestMempoolAcceptFees `json:"fees,omitempty"`
+	RejectReason string                 `json:"reject-reason,omitempty"`
+}
+
+// TestMempoolAcceptFees models the fees field of the testmempoolaccept
+// result.
+type TestMempoolAcceptFees struct {
+	// Base is the transaction fee in LBC.
+	Base float64 `json:"base"`
+
+	// EffectiveFeeRate is the effective feerate in LBC per KvB.  May differ
+	// from the base feerate if, for example, there are modified fees from
+	// prioritisetransaction or a package feerate was used.
+	EffectiveFeeRate float64 `json:"effective-feerate"`
+
+	// EffectiveIncludes lists the wtxids of transactions whose fees and
+	// vsizes are included in the effective feerate.
+	EffectiveIncludes []string `json:"effective-includes"`
+}
+
+// SubmitPackageResult models the data from the submitpackage command.
+type SubmitPackageResult struct {
+	// PackageMsg is "success" when the package was accepted.
+	PackageMsg string `json:"package_msg"`
+
+	// TxResults holds the result for each transaction in the package
+	// keyed by its wtxid.
+	TxResults map[string]SubmitPackageTxResult `json:"tx-results"`
+}
+
+// SubmitPackageTxResult models the result for a single transaction of the
+// submitpackage command.
+type SubmitPackageTxResult struct {
+	Txid  string                 `json:"txid"`
+	Vsize int32                  `json:"vsize"`
+	Fees  *TestMempoolAcceptFees `json:"fees,omitempty"`
+}
+
+// VerifyDBResult models the data from the verifydb command.
+type VerifyDBResult struct {
+	Running       bool     `json:"running"`
+	Scans         int64    `json:"scans"`
+	LastScanStart int64    `json:"lastscanstart"`
+	LastScanEnd   int64    `json:"lastscanend"`
+	BlocksChecked int64    `json:"blockschecked"`
+	Corrupt       []string `json:"corrupt"`
+	Repaired      []string `json:"repaired"`
+}
@@ -47,6 +47,29 @@ func TestChainSvrCustomResults(t *testing.T) {
 			},
 			expected: `{"txid":"123","vout":1,"scriptSig":{"asm":"0","hex":"00"},"sequence":4294967295}`,
 		},
+		{
+			name: "custom vin marshal with prevout",
+			result: &btcjson.Vin{
+				Txid: "123",
+				Vout: 1,
+				ScriptSig: &btcjson.ScriptSig{
+					Asm: "0",
+					Hex: "00",
+				},
+				Sequence: 4294967295,
+				PrevOut: &btcjson.VinPrevOutResult{
+					Generated: true,
+					Height:    100,
+					Value:     1,
+					ScriptPubKey: btcjson.ScriptPubKeyResult{
+						Asm:  "OP_TRUE",
+						Hex:  "51",
+						Type: "nonstandard",
+					},
+				},
+			},
+			expected: `{"txid":"123","vout":1,"scriptSig":{"asm":"0","hex":"00"},"sequence":4294967295,"prevout":{"generated":true,"height":100,"value":1,"scriptPubKey":{"asm":"OP_TRUE","hex":"51","type":"nonstandard","subtype":"","isclaim":false,"issupport":false}}}`,
+		},
 		{
 			name: "custom vinprevout marshal with coinbase",
 			result: &btcjson.VinPrevOut{
@@ -199,3 +222,46 @@ func TestChainSvrMiningInfoResults(t *testing.T) {
 		}
 	}
 }
+
+// TestGetTxOutSetInfoResultMarshal ensures GetTxOutSetInfoResult is marshalled
+// with the hashes as strings, the amount in LBC and without the fields lbcd
+// doesn't report, and that it unmarshals back to the same result.
+func TestGetTxOutSetInfoResultMarshal(t *testing.T) {
+	t.Parallel()
+
+	bestBlock, err := chainhash.NewHashFromStr("000000000000005f94116250e2407310463c0a7cf950f1af9ebe935b1c0687ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	muHash, err := chainhash.NewHashFromStr("10d312b100cbd32ada024a6646e40d3482fcff103668d2625f10002a607d5863")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := btcjson.GetTxOutSetInfoResult{
+		Height:      123,
+		BestBlock:   *bestBlock,
+		TxOuts:      2,
+		BogoSize:    150,
+		MuHash:      *muHash,
+		TotalAmount: 20000000,
+	}
+
+	marshalled, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"height":123,"bestblock":"000000000000005f94116250e2407310463c0a7cf950f1af9ebe935b1c0687ab","txouts":2,"bogosize":150,"muhash":"10d312b100cbd32ada024a6646e40d3482fcff103668d2625f10002a607d5863","total_amount":0.2}`
+	if string(marshalled) != want {
+		t.Fatalf("unexpected marshalled data - got %s, want %s",
+			marshalled, want)
+	}
+
+	var out btcjson.GetTxOutSetInfoResult
+	if err := json.Unmarshal(marshalled, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(out, result) {
+		t.Fatalf("unexpected unmarshalled data - got %v, want %v",
+			spew.Sdump(out), spew.Sdump(result))
+	}
+}
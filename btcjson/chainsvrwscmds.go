@@ -23,6 +23,44 @@ func NewAuthenticateCmd(username, passphrase string) *AuthenticateCmd {
 	}
 }
 
+// GetBlockStreamCmd defines the getblockstream JSON-RPC command.
+//
+// NOTE: This is an lbcd extension and requires a websocket connection.
+type GetBlockStreamCmd struct {
+	Hash      string
+	ChunkSize *int32 `jsonrpcdefault:"262144"`
+}
+
+// NewGetBlockStreamCmd returns a new instance which can be used to issue a
+// getblockstream JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetBlockStreamCmd(hash string, chunkSize *int32) *GetBlockStreamCmd {
+	return &GetBlockStreamCmd{
+		Hash:      hash,
+		ChunkSize: chunkSize,
+	}
+}
+
+// NotifyAlertsCmd defines the notifyalerts JSON-RPC command.
+type NotifyAlertsCmd struct{}
+
+// NewNotifyAlertsCmd returns a new instance which can be used to issue a
+// notifyalerts JSON-RPC command.
+func NewNotifyAlertsCmd() *NotifyAlertsCmd {
+	return &NotifyAlertsCmd{}
+}
+
+// StopNotifyAlertsCmd defines the stopnotifyalerts JSON-RPC command.
+type StopNotifyAlertsCmd struct{}
+
+// NewStopNotifyAlertsCmd returns a new instance which can be used to issue a
+// stopnotifyalerts JSON-RPC command.
+func NewStopNotifyAlertsCmd() *StopNotifyAlertsCmd {
+	return &StopNotifyAlertsCmd{}
+}
+
 // NotifyBlocksCmd defines the notifyblocks JSON-RPC command.
 type NotifyBlocksCmd struct{}
 
@@ -41,6 +79,62 @@ func NewStopNotifyBlocksCmd() *StopNotifyBlocksCmd {
 	return &StopNotifyBlocksCmd{}
 }
 
+// NotifyClaimActivatedCmd defines the notifyclaimactivated JSON-RPC command.
+type NotifyClaimActivatedCmd struct{}
+
+// NewNotifyClaimActivatedCmd returns a new instance which can be used to issue
+// a notifyclaimactivated JSON-RPC command.
+func NewNotifyClaimActivatedCmd() *NotifyClaimActivatedCmd {
+	return &NotifyClaimActivatedCmd{}
+}
+
+// StopNotifyClaimActivatedCmd defines the stopnotifyclaimactivated JSON-RPC
+// command.
+type StopNotifyClaimActivatedCmd struct{}
+
+// NewStopNotifyClaimActivatedCmd returns a new instance which can be used to
+// issue a stopnotifyclaimactivated JSON-RPC command.
+func NewStopNotifyClaimActivatedCmd() *StopNotifyClaimActivatedCmd {
+	return &StopNotifyClaimActivatedCmd{}
+}
+
+// NotifyClaimExpiredCmd defines the notifyclaimexpired JSON-RPC command.
+type NotifyClaimExpiredCmd struct{}
+
+// NewNotifyClaimExpiredCmd returns a new instance which can be used to issue a
+// notifyclaimexpired JSON-RPC command.
+func NewNotifyClaimExpiredCmd() *NotifyClaimExpiredCmd {
+	return &NotifyClaimExpiredCmd{}
+}
+
+// StopNotifyClaimExpiredCmd defines the stopnotifyclaimexpired JSON-RPC
+// command.
+type StopNotifyClaimExpiredCmd struct{}
+
+// NewStopNotifyClaimExpiredCmd returns a new instance which can be used to
+// issue a stopnotifyclaimexpired JSON-RPC command.
+func NewStopNotifyClaimExpiredCmd() *StopNotifyClaimExpiredCmd {
+	return &StopNotifyClaimExpiredCmd{}
+}
+
+// NotifyClaimTrieCmd defines the notifyclaimtrie JSON-RPC command.
+type NotifyClaimTrieCmd struct{}
+
+// NewNotifyClaimTrieCmd returns a new instance which can be used to issue a
+// notifyclaimtrie JSON-RPC command.
+func NewNotifyClaimTrieCmd() *NotifyClaimTrieCmd {
+	return &NotifyClaimTrieCmd{}
+}
+
+// StopNotifyClaimTrieCmd defines the stopnotifyclaimtrie JSON-RPC command.
+type StopNotifyClaimTrieCmd struct{}
+
+// NewStopNotifyClaimTrieCmd returns a new instance which can be used to issue
+// a stopnotifyclaimtrie JSON-RPC command.
+func NewStopNotifyClaimTrieCmd() *StopNotifyClaimTrieCmd {
+	return &StopNotifyClaimTrieCmd{}
+}
+
 // NotifyNewTransactionsCmd defines the notifynewtransactions JSON-RPC command.
 type NotifyNewTransactionsCmd struct {
 	Verbose *bool `jsonrpcdefault:"false"`
@@ -105,12 +199,18 @@ type OutPoint struct {
 // LoadTxFilterCmd defines the loadtxfilter request parameters to load or
 // reload a transaction filter.
 //
+// Descriptors and ClaimNames are an lbcd extension which also matches the
+// outputs described by output descriptors and the claims and supports of
+// names.  They are not set by NewLoadTxFilterCmd.
+//
 // NOTE: This is a btcd extension ported from github.com/decred/dcrd/dcrjson
 // and requires a websocket connection.
 type LoadTxFilterCmd struct {
-	Reload    bool
-	Addresses []string
-	OutPoints []OutPoint
+	Reload      bool
+	Addresses   []string
+	OutPoints   []OutPoint
+	Descriptors *[]string
+	ClaimNames  *[]string
 }
 
 // NewLoadTxFilterCmd returns a new instance which can be used to issue a
@@ -226,13 +326,22 @@ func init() {
 	flags := UFWebsocketOnly
 
 	MustRegisterCmd("authenticate", (*AuthenticateCmd)(nil), flags)
+	MustRegisterCmd("getblockstream", (*GetBlockStreamCmd)(nil), flags)
 	MustRegisterCmd("loadtxfilter", (*LoadTxFilterCmd)(nil), flags)
+	MustRegisterCmd("notifyalerts", (*NotifyAlertsCmd)(nil), flags)
 	MustRegisterCmd("notifyblocks", (*NotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("notifyclaimactivated", (*NotifyClaimActivatedCmd)(nil), flags)
+	MustRegisterCmd("notifyclaimexpired", (*NotifyClaimExpiredCmd)(nil), flags)
+	MustRegisterCmd("notifyclaimtrie", (*NotifyClaimTrieCmd)(nil), flags)
 	MustRegisterCmd("notifynewtransactions", (*NotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("notifyreceived", (*NotifyReceivedCmd)(nil), flags)
 	MustRegisterCmd("notifyspent", (*NotifySpentCmd)(nil), flags)
 	MustRegisterCmd("session", (*SessionCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyalerts", (*StopNotifyAlertsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyblocks", (*StopNotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyclaimactivated", (*StopNotifyClaimActivatedCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyclaimexpired", (*StopNotifyClaimExpiredCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyclaimtrie", (*StopNotifyClaimTrieCmd)(nil), flags)
 	MustRegisterCmd("stopnotifynewtransactions", (*StopNotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyspent", (*StopNotifySpentCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyreceived", (*StopNotifyReceivedCmd)(nil), flags)
@@ -41,6 +41,56 @@ func TestChainSvrWsCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"authenticate","params":["user","pass"],"id":1}`,
 			unmarshalled: &btcjson.AuthenticateCmd{Username: "user", Passphrase: "pass"},
 		},
+		{
+			name: "getblockstream",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockstream", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockStreamCmd("123", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockstream","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetBlockStreamCmd{
+				Hash:      "123",
+				ChunkSize: btcjson.Int32(262144),
+			},
+		},
+		{
+			name: "getblockstream optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockstream", "123", 1024)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockStreamCmd("123", btcjson.Int32(1024))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockstream","params":["123",1024],"id":1}`,
+			unmarshalled: &btcjson.GetBlockStreamCmd{
+				Hash:      "123",
+				ChunkSize: btcjson.Int32(1024),
+			},
+		},
+		{
+			name: "notifyalerts",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyalerts")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyAlertsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyalerts","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyAlertsCmd{},
+		},
+		{
+			name: "stopnotifyalerts",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyalerts")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyAlertsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyalerts","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyAlertsCmd{},
+		},
 		{
 			name: "notifyblocks",
 			newCmd: func() (interface{}, error) {
@@ -63,6 +113,72 @@ func TestChainSvrWsCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyblocks","params":[],"id":1}`,
 			unmarshalled: &btcjson.StopNotifyBlocksCmd{},
 		},
+		{
+			name: "notifyclaimactivated",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyclaimactivated")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyClaimActivatedCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyclaimactivated","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyClaimActivatedCmd{},
+		},
+		{
+			name: "stopnotifyclaimactivated",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyclaimactivated")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyClaimActivatedCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyclaimactivated","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyClaimActivatedCmd{},
+		},
+		{
+			name: "notifyclaimexpired",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyclaimexpired")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyClaimExpiredCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyclaimexpired","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyClaimExpiredCmd{},
+		},
+		{
+			name: "stopnotifyclaimexpired",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyclaimexpired")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyClaimExpiredCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyclaimexpired","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyClaimExpiredCmd{},
+		},
+		{
+			name: "notifyclaimtrie",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyclaimtrie")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyClaimTrieCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyclaimtrie","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyClaimTrieCmd{},
+		},
+		{
+			name: "stopnotifyclaimtrie",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyclaimtrie")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyClaimTrieCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyclaimtrie","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyClaimTrieCmd{},
+		},
 		{
 			name: "notifynewtransactions",
 			newCmd: func() (interface{}, error) {
@@ -213,6 +329,27 @@ func TestChainSvrWsCmds(t *testing.T) {
 				OutPoints: []btcjson.OutPoint{{Hash: "0000000000000000000000000000000000000000000000000000000000000123", Index: 0}},
 			},
 		},
+		{
+			name: "loadtxfilter optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadtxfilter", true, `[]`, `[]`, `["pkh(02aa)"]`, `["@channel"]`)
+			},
+			staticCmd: func() interface{} {
+				cmd := btcjson.NewLoadTxFilterCmd(true, []string{},
+					[]btcjson.OutPoint{})
+				cmd.Descriptors = &[]string{"pkh(02aa)"}
+				cmd.ClaimNames = &[]string{"@channel"}
+				return cmd
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadtxfilter","params":[true,[],[],["pkh(02aa)"],["@channel"]],"id":1}`,
+			unmarshalled: &btcjson.LoadTxFilterCmd{
+				Reload:      true,
+				Addresses:   []string{},
+				OutPoints:   []btcjson.OutPoint{},
+				Descriptors: &[]string{"pkh(02aa)"},
+				ClaimNames:  &[]string{"@channel"},
+			},
+		},
 		{
 			name: "rescanblocks",
 			newCmd: func() (interface{}, error) {
@@ -22,6 +22,29 @@ const (
 	// Deprecated: Use FilteredBlockDisconnectedNtfnMethod instead.
 	BlockDisconnectedNtfnMethod = "blockdisconnected"
 
+	// BlockChunkNtfnMethod is the method used for notifications from the
+	// chain server carrying a chunk of a block requested with the
+	// getblockstream command.
+	BlockChunkNtfnMethod = "blockchunk"
+
+	// ChainAlertNtfnMethod is the method used for notifications from the
+	// chain server that it detected an anomaly of the block chain, such as
+	// a large reorganization, an invalid block with valid proof of work or
+	// a stalled chain tip.
+	ChainAlertNtfnMethod = "chainalert"
+
+	// ClaimActivatedNtfnMethod is the method used for notifications from
+	// the chain server that a connected block activated claims.
+	ClaimActivatedNtfnMethod = "claimactivated"
+
+	// ClaimExpiredNtfnMethod is the method used for notifications from the
+	// chain server that a connected block expired claims.
+	ClaimExpiredNtfnMethod = "claimexpired"
+
+	// ClaimTrieChangedNtfnMethod is the method used for notifications from
+	// the chain server that a connected block changed the claim trie.
+	ClaimTrieChangedNtfnMethod = "claimtriechanged"
+
 	// FilteredBlockConnectedNtfnMethod is the new method used for
 	// notifications from the chain server that a block has been connected.
 	FilteredBlockConnectedNtfnMethod = "filteredblockconnected"
@@ -61,6 +84,10 @@ const (
 	// Deprecated: Not used with rescanblocks command.
 	RescanProgressNtfnMethod = "rescanprogress"
 
+	// ShutdownNtfnMethod is the method used for notifications from the
+	// chain server that it is shutting down and will close the connection.
+	ShutdownNtfnMethod = "shutdown"
+
 	// TxAcceptedNtfnMethod is the method used for notifications from the
 	// chain server that a transaction has been accepted into the mempool.
 	TxAcceptedNtfnMethod = "txaccepted"
@@ -98,6 +125,27 @@ func NewBlockConnectedNtfn(hash string, height int32, time int64) *BlockConnecte
 	}
 }
 
+// BlockChunkNtfn defines the blockchunk JSON-RPC notification.
+//
+// NOTE: This is an lbcd extension.
+type BlockChunkNtfn struct {
+	Hash   string `json:"hash"`
+	Offset int32  `json:"offset"`
+	Size   int32  `json:"size"`
+	Data   string `json:"data"`
+}
+
+// NewBlockChunkNtfn returns a new instance which can be used to issue a
+// blockchunk JSON-RPC notification.
+func NewBlockChunkNtfn(hash string, offset, size int32, data string) *BlockChunkNtfn {
+	return &BlockChunkNtfn{
+		Hash:   hash,
+		Offset: offset,
+		Size:   size,
+		Data:   data,
+	}
+}
+
 // BlockDisconnectedNtfn defines the blockdisconnected JSON-RPC notification.
 //
 // Deprecated: Use FilteredBlockDisconnectedNtfn instead.
@@ -119,6 +167,117 @@ func NewBlockDisconnectedNtfn(hash string, height int32, time int64) *BlockDisco
 	}
 }
 
+// Types of the anomalies of the block chain reported by chainalert
+// notifications.
+const (
+	// ChainAlertReorg is the type of the alerts for reorganizations which
+	// disconnected at least the configured number of blocks from the main
+	// chain.
+	ChainAlertReorg = "reorg"
+
+	// ChainAlertInvalidBlock is the type of the alerts for blocks with valid
+	// proof of work which failed validation while they were being
+	// connected to the main chain, which means a chain with more work than
+	// the main chain was rejected.
+	ChainAlertInvalidBlock = "invalidblock"
+
+	// ChainAlertStalledTip is the type of the alerts for a main chain whose
+	// tip didn't change for the configured time.
+	ChainAlertStalledTip = "stalledtip"
+)
+
+// ChainAlertNtfn defines the chainalert JSON-RPC notification.
+type ChainAlertNtfn struct {
+	Type    string
+	Hash    string
+	Height  int32
+	Time    int64
+	Message string
+}
+
+// NewChainAlertNtfn returns a new instance which can be used to issue a
+// chainalert JSON-RPC notification.
+func NewChainAlertNtfn(alertType, hash string, height int32, time int64,
+	message string) *ChainAlertNtfn {
+
+	return &ChainAlertNtfn{
+		Type:    alertType,
+		Hash:    hash,
+		Height:  height,
+		Time:    time,
+		Message: message,
+	}
+}
+
+// ClaimStateChange describes a claim included in the claimactivated and
+// claimexpired notifications.
+type ClaimStateChange struct {
+	Name    string `json:"name"`
+	ClaimID string `json:"claimid"`
+	TxID    string `json:"txid"`
+	N       uint32 `json:"n"`
+	Amount  int64  `json:"amount"`
+}
+
+// ClaimActivatedNtfn defines the claimactivated JSON-RPC notification.
+type ClaimActivatedNtfn struct {
+	Hash   string
+	Height int32
+	Claims []ClaimStateChange
+}
+
+// NewClaimActivatedNtfn returns a new instance which can be used to issue a
+// claimactivated JSON-RPC notification.
+func NewClaimActivatedNtfn(hash string, height int32,
+	claims []ClaimStateChange) *ClaimActivatedNtfn {
+
+	return &ClaimActivatedNtfn{
+		Hash:   hash,
+		Height: height,
+		Claims: claims,
+	}
+}
+
+// ClaimExpiredNtfn defines the claimexpired JSON-RPC notification.
+type ClaimExpiredNtfn struct {
+	Hash   string
+	Height int32
+	Claims []ClaimStateChange
+}
+
+// NewClaimExpiredNtfn returns a new instance which can be used to issue a
+// claimexpired JSON-RPC notification.
+func NewClaimExpiredNtfn(hash string, height int32,
+	claims []ClaimStateChange) *ClaimExpiredNtfn {
+
+	return &ClaimExpiredNtfn{
+		Hash:   hash,
+		Height: height,
+		Claims: claims,
+	}
+}
+
+// ClaimTrieChangedNtfn defines the claimtriechanged JSON-RPC notification.
+type ClaimTrieChangedNtfn struct {
+	Hash          string
+	Height        int32
+	ClaimTrieRoot string
+	Names         []string
+}
+
+// NewClaimTrieChangedNtfn returns a new instance which can be used to issue a
+// claimtriechanged JSON-RPC notification.
+func NewClaimTrieChangedNtfn(hash string, height int32, claimTrieRoot string,
+	names []string) *ClaimTrieChangedNtfn {
+
+	return &ClaimTrieChangedNtfn{
+		Hash:          hash,
+		Height:        height,
+		ClaimTrieRoot: claimTrieRoot,
+		Names:         names,
+	}
+}
+
 // FilteredBlockConnectedNtfn defines the filteredblockconnected JSON-RPC
 // notification.
 type FilteredBlockConnectedNtfn struct {
@@ -245,6 +404,15 @@ func NewRescanProgressNtfn(hash string, height int32, time int64) *RescanProgres
 	}
 }
 
+// ShutdownNtfn defines the shutdown JSON-RPC notification.
+type ShutdownNtfn struct{}
+
+// NewShutdownNtfn returns a new instance which can be used to issue a shutdown
+// JSON-RPC notification.
+func NewShutdownNtfn() *ShutdownNtfn {
+	return &ShutdownNtfn{}
+}
+
 // TxAcceptedNtfn defines the txaccepted JSON-RPC notification.
 type TxAcceptedNtfn struct {
 	TxID   string
@@ -291,13 +459,19 @@ func init() {
 	flags := UFWebsocketOnly | UFNotification
 
 	MustRegisterCmd(BlockConnectedNtfnMethod, (*BlockConnectedNtfn)(nil), flags)
+	MustRegisterCmd(BlockChunkNtfnMethod, (*BlockChunkNtfn)(nil), flags)
 	MustRegisterCmd(BlockDisconnectedNtfnMethod, (*BlockDisconnectedNtfn)(nil), flags)
+	MustRegisterCmd(ChainAlertNtfnMethod, (*ChainAlertNtfn)(nil), flags)
+	MustRegisterCmd(ClaimActivatedNtfnMethod, (*ClaimActivatedNtfn)(nil), flags)
+	MustRegisterCmd(ClaimExpiredNtfnMethod, (*ClaimExpiredNtfn)(nil), flags)
+	MustRegisterCmd(ClaimTrieChangedNtfnMethod, (*ClaimTrieChangedNtfn)(nil), flags)
 	MustRegisterCmd(FilteredBlockConnectedNtfnMethod, (*FilteredBlockConnectedNtfn)(nil), flags)
 	MustRegisterCmd(FilteredBlockDisconnectedNtfnMethod, (*FilteredBlockDisconnectedNtfn)(nil), flags)
 	MustRegisterCmd(RecvTxNtfnMethod, (*RecvTxNtfn)(nil), flags)
 	MustRegisterCmd(RedeemingTxNtfnMethod, (*RedeemingTxNtfn)(nil), flags)
 	MustRegisterCmd(RescanFinishedNtfnMethod, (*RescanFinishedNtfn)(nil), flags)
 	MustRegisterCmd(RescanProgressNtfnMethod, (*RescanProgressNtfn)(nil), flags)
+	MustRegisterCmd(ShutdownNtfnMethod, (*ShutdownNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedVerboseNtfnMethod, (*TxAcceptedVerboseNtfn)(nil), flags)
 	MustRegisterCmd(RelevantTxAcceptedNtfnMethod, (*RelevantTxAcceptedNtfn)(nil), flags)
@@ -44,6 +44,22 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Time:   123456789,
 			},
 		},
+		{
+			name: "blockchunk",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("blockchunk", "123", 1024, 4096, "00ff")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewBlockChunkNtfn("123", 1024, 4096, "00ff")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"blockchunk","params":["123",1024,4096,"00ff"],"id":null}`,
+			unmarshalled: &btcjson.BlockChunkNtfn{
+				Hash:   "123",
+				Offset: 1024,
+				Size:   4096,
+				Data:   "00ff",
+			},
+		},
 		{
 			name: "blockdisconnected",
 			newNtfn: func() (interface{}, error) {
@@ -59,6 +75,71 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Time:   123456789,
 			},
 		},
+		{
+			name: "chainalert",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("chainalert", "reorg", "123", 100000, 123456789, "reorganization of 7 blocks")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewChainAlertNtfn("reorg", "123", 100000, 123456789, "reorganization of 7 blocks")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"chainalert","params":["reorg","123",100000,123456789,"reorganization of 7 blocks"],"id":null}`,
+			unmarshalled: &btcjson.ChainAlertNtfn{
+				Type:    "reorg",
+				Hash:    "123",
+				Height:  100000,
+				Time:    123456789,
+				Message: "reorganization of 7 blocks",
+			},
+		},
+		{
+			name: "claimactivated",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("claimactivated", "123", 100000, `[{"name":"a","claimid":"456","txid":"789","n":1,"amount":100}]`)
+			},
+			staticNtfn: func() interface{} {
+				claims := []btcjson.ClaimStateChange{{Name: "a", ClaimID: "456", TxID: "789", N: 1, Amount: 100}}
+				return btcjson.NewClaimActivatedNtfn("123", 100000, claims)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"claimactivated","params":["123",100000,[{"name":"a","claimid":"456","txid":"789","n":1,"amount":100}]],"id":null}`,
+			unmarshalled: &btcjson.ClaimActivatedNtfn{
+				Hash:   "123",
+				Height: 100000,
+				Claims: []btcjson.ClaimStateChange{{Name: "a", ClaimID: "456", TxID: "789", N: 1, Amount: 100}},
+			},
+		},
+		{
+			name: "claimexpired",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("claimexpired", "123", 100000, `[{"name":"a","claimid":"456","txid":"789","n":1,"amount":100}]`)
+			},
+			staticNtfn: func() interface{} {
+				claims := []btcjson.ClaimStateChange{{Name: "a", ClaimID: "456", TxID: "789", N: 1, Amount: 100}}
+				return btcjson.NewClaimExpiredNtfn("123", 100000, claims)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"claimexpired","params":["123",100000,[{"name":"a","claimid":"456","txid":"789","n":1,"amount":100}]],"id":null}`,
+			unmarshalled: &btcjson.ClaimExpiredNtfn{
+				Hash:   "123",
+				Height: 100000,
+				Claims: []btcjson.ClaimStateChange{{Name: "a", ClaimID: "456", TxID: "789", N: 1, Amount: 100}},
+			},
+		},
+		{
+			name: "claimtriechanged",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("claimtriechanged", "123", 100000, "456", []string{"a", "b"})
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewClaimTrieChangedNtfn("123", 100000, "456", []string{"a", "b"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"claimtriechanged","params":["123",100000,"456",["a","b"]],"id":null}`,
+			unmarshalled: &btcjson.ClaimTrieChangedNtfn{
+				Hash:          "123",
+				Height:        100000,
+				ClaimTrieRoot: "456",
+				Names:         []string{"a", "b"},
+			},
+		},
 		{
 			name: "filteredblockconnected",
 			newNtfn: func() (interface{}, error) {
@@ -168,6 +249,17 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Time:   12345678,
 			},
 		},
+		{
+			name: "shutdown",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("shutdown")
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewShutdownNtfn()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"shutdown","params":[],"id":null}`,
+			unmarshalled: &btcjson.ShutdownNtfn{},
+		},
 		{
 			name: "txaccepted",
 			newNtfn: func() (interface{}, error) {
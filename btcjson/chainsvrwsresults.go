@@ -19,3 +19,13 @@ type RescannedBlock struct {
 	Hash         string   `json:"hash"`
 	Transactions []string `json:"transactions"`
 }
+
+// GetBlockStreamResult models the data from the getblockstream command, which
+// is sent after all chunks of the block.
+//
+// NOTE: This is an lbcd extension.
+type GetBlockStreamResult struct {
+	Hash   string `json:"hash"`
+	Size   int32  `json:"size"`
+	Chunks int32  `json:"chunks"`
+}
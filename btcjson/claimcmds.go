@@ -4,12 +4,23 @@ func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
 
+	MustRegisterCmd("compactclaimtrie", (*CompactClaimTrieCmd)(nil), flags)
+	MustRegisterCmd("exportclaimtrie", (*ExportClaimTrieCmd)(nil), flags)
 	MustRegisterCmd("getchangesinblock", (*GetChangesInBlockCmd)(nil), flags)
+	MustRegisterCmd("getclaimbyid", (*GetClaimByIDCmd)(nil), flags)
+	MustRegisterCmd("getclaimsbychannel", (*GetClaimsByChannelCmd)(nil), flags)
 	MustRegisterCmd("getclaimsforname", (*GetClaimsForNameCmd)(nil), flags)
 	MustRegisterCmd("getclaimsfornamebyid", (*GetClaimsForNameByIDCmd)(nil), flags)
 	MustRegisterCmd("getclaimsfornamebybid", (*GetClaimsForNameByBidCmd)(nil), flags)
 	MustRegisterCmd("getclaimsfornamebyseq", (*GetClaimsForNameBySeqCmd)(nil), flags)
+	MustRegisterCmd("getclaimtrieinfo", (*GetClaimTrieInfoCmd)(nil), flags)
+	MustRegisterCmd("getclaimtrierootatheight", (*GetClaimTrieRootAtHeightCmd)(nil), flags)
+	MustRegisterCmd("importclaimtrie", (*ImportClaimTrieCmd)(nil), flags)
 	MustRegisterCmd("normalize", (*GetNormalizedCmd)(nil), flags)
+	MustRegisterCmd("normalizename", (*NormalizeNameCmd)(nil), flags)
+	MustRegisterCmd("resolve", (*ResolveCmd)(nil), flags)
+	MustRegisterCmd("simulateclaimtakeover", (*SimulateClaimTakeoverCmd)(nil), flags)
+	MustRegisterCmd("verifyclaimtrie", (*VerifyClaimTrieCmd)(nil), flags)
 }
 
 // optional inputs are required to be pointers, but they support things like `jsonrpcdefault:"false"`
@@ -17,16 +28,151 @@ func init() {
 // optional outputs require ",omitempty"
 // traditional bitcoin fields are all lowercase
 
+type ExportClaimTrieCmd struct {
+	Path   string `json:"path"`
+	Height *int32 `json:"height"`
+}
+
+type ImportClaimTrieCmd struct {
+	Path string `json:"path"`
+}
+
+type ClaimTrieSnapshotResult struct {
+	Path          string `json:"path"`
+	Height        int32  `json:"height"`
+	ClaimTrieRoot string `json:"claimtrieroot"`
+}
+
+type VerifyClaimTrieCmd struct {
+	Repair *bool `json:"repair" jsonrpcdefault:"false"`
+}
+
+type VerifyClaimTrieResult struct {
+	Height         int32    `json:"height"`
+	ExpectedRoot   string   `json:"expectedroot"`
+	StoredRoot     string   `json:"storedroot"`
+	ComputedRoot   string   `json:"computedroot"`
+	NamesChecked   int      `json:"nameschecked"`
+	DivergentCount int      `json:"divergentcount"`
+	DivergentNames []string `json:"divergentnames"`
+	Consistent     bool     `json:"consistent"`
+	Repaired       bool     `json:"repaired"`
+}
+
+type CompactClaimTrieCmd struct {
+	FinalHeight *int32 `json:"finalheight"`
+}
+
+type CompactClaimTrieResult struct {
+	Height         int32  `json:"height"`
+	FinalHeight    int32  `json:"finalheight"`
+	NamesChecked   int    `json:"nameschecked"`
+	NamesCompacted int    `json:"namescompacted"`
+	ChangesRemoved int    `json:"changesremoved"`
+	SizeBefore     uint64 `json:"sizebefore"`
+	SizeAfter      uint64 `json:"sizeafter"`
+}
+
+type GetClaimTrieRootAtHeightCmd struct {
+	Height int32 `json:"height"`
+}
+
+// NewGetClaimTrieRootAtHeightCmd returns a new instance which can be used to
+// issue a getclaimtrierootatheight JSON-RPC command.
+func NewGetClaimTrieRootAtHeightCmd(height int32) *GetClaimTrieRootAtHeightCmd {
+	return &GetClaimTrieRootAtHeightCmd{Height: height}
+}
+
+type GetClaimTrieRootAtHeightResult struct {
+	Height          int32  `json:"height"`
+	Hash            string `json:"hash"`
+	ClaimTrieRoot   string `json:"claimtrieroot"`
+	HeaderClaimTrie string `json:"headerclaimtrie"`
+	Matches         bool   `json:"matches"`
+}
+
 type GetChangesInBlockCmd struct {
 	HashOrHeight *string `json:"hashorheight" jsonrpcdefault:""`
 }
 
+// NewGetChangesInBlockCmd returns a new instance which can be used to issue a
+// getchangesinblock JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetChangesInBlockCmd(hashOrHeight *string) *GetChangesInBlockCmd {
+	return &GetChangesInBlockCmd{HashOrHeight: hashOrHeight}
+}
+
 type GetChangesInBlockResult struct {
 	Hash   string   `json:"hash"`
 	Height int32    `json:"height"`
 	Names  []string `json:"names"`
 }
 
+type GetClaimByIDCmd struct {
+	ClaimID       string `json:"claimid"`
+	IncludeValues *bool  `json:"includevalues" jsonrpcdefault:"false"`
+}
+
+// NewGetClaimByIDCmd returns a new instance which can be used to issue a
+// getclaimbyid JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetClaimByIDCmd(claimID string, includeValues *bool) *GetClaimByIDCmd {
+	return &GetClaimByIDCmd{
+		ClaimID:       claimID,
+		IncludeValues: includeValues,
+	}
+}
+
+type GetClaimByIDResult struct {
+	ClaimID string       `json:"claimid"`
+	Name    string       `json:"name"`
+	Status  string       `json:"status"`
+	TXID    string       `json:"txid"`
+	N       uint32       `json:"n"`
+	Height  int32        `json:"height"`
+	Claim   *ClaimResult `json:"claim,omitempty"` // only set while the claim is in the trie
+}
+
+type GetClaimsByChannelCmd struct {
+	ChannelID     string `json:"channelid"`
+	Skip          *int   `json:"skip" jsonrpcdefault:"0"`
+	Count         *int   `json:"count" jsonrpcdefault:"100"`
+	IncludeValues *bool  `json:"includevalues" jsonrpcdefault:"false"`
+}
+
+// NewGetClaimsByChannelCmd returns a new instance which can be used to issue a
+// getclaimsbychannel JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetClaimsByChannelCmd(channelID string, skip, count *int, includeValues *bool) *GetClaimsByChannelCmd {
+	return &GetClaimsByChannelCmd{
+		ChannelID:     channelID,
+		Skip:          skip,
+		Count:         count,
+		IncludeValues: includeValues,
+	}
+}
+
+type ClaimByChannelResult struct {
+	ClaimID string       `json:"claimid"`
+	Name    string       `json:"name"`
+	TXID    string       `json:"txid"`
+	N       uint32       `json:"n"`
+	Height  int32        `json:"height"`
+	Claim   *ClaimResult `json:"claim,omitempty"` // only set when values are requested and the claim is in the trie
+}
+
+type GetClaimsByChannelResult struct {
+	ChannelID string                 `json:"channelid"`
+	Total     uint32                 `json:"total"`
+	Claims    []ClaimByChannelResult `json:"claims"`
+}
+
 type GetClaimsForNameCmd struct {
 	Name          string  `json:"name"`
 	HashOrHeight  *string `json:"hashorheight" jsonrpcdefault:""`
@@ -54,6 +200,69 @@ type GetClaimsForNameBySeqCmd struct {
 	IncludeValues *bool   `json:"includevalues" jsonrpcdefault:"false"`
 }
 
+// NewGetClaimsForNameCmd returns a new instance which can be used to issue a
+// getclaimsforname JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetClaimsForNameCmd(name string, hashOrHeight *string,
+	includeValues *bool) *GetClaimsForNameCmd {
+
+	return &GetClaimsForNameCmd{
+		Name:          name,
+		HashOrHeight:  hashOrHeight,
+		IncludeValues: includeValues,
+	}
+}
+
+// NewGetClaimsForNameByIDCmd returns a new instance which can be used to issue
+// a getclaimsfornamebyid JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetClaimsForNameByIDCmd(name string, partialClaimIDs []string,
+	hashOrHeight *string, includeValues *bool) *GetClaimsForNameByIDCmd {
+
+	return &GetClaimsForNameByIDCmd{
+		Name:            name,
+		PartialClaimIDs: partialClaimIDs,
+		HashOrHeight:    hashOrHeight,
+		IncludeValues:   includeValues,
+	}
+}
+
+// NewGetClaimsForNameByBidCmd returns a new instance which can be used to
+// issue a getclaimsfornamebybid JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetClaimsForNameByBidCmd(name string, bids []int32,
+	hashOrHeight *string, includeValues *bool) *GetClaimsForNameByBidCmd {
+
+	return &GetClaimsForNameByBidCmd{
+		Name:          name,
+		Bids:          bids,
+		HashOrHeight:  hashOrHeight,
+		IncludeValues: includeValues,
+	}
+}
+
+// NewGetClaimsForNameBySeqCmd returns a new instance which can be used to
+// issue a getclaimsfornamebyseq JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetClaimsForNameBySeqCmd(name string, sequences []int32,
+	hashOrHeight *string, includeValues *bool) *GetClaimsForNameBySeqCmd {
+
+	return &GetClaimsForNameBySeqCmd{
+		Name:          name,
+		Sequences:     sequences,
+		HashOrHeight:  hashOrHeight,
+		IncludeValues: includeValues,
+	}
+}
+
 type GetClaimsForNameResult struct {
 	Hash               string        `json:"hash"`
 	Height             int32         `json:"height"`
@@ -74,18 +283,114 @@ type SupportResult struct {
 }
 
 type ClaimResult struct {
-	ClaimID         string          `json:"claimid"`
-	TXID            string          `json:"txid"`
-	N               uint32          `json:"n"`
-	Bid             int32           `json:"bid"`
-	Sequence        int32           `json:"sequence"`
-	Height          int32           `json:"height"`
-	ValidAtHeight   int32           `json:"validatheight"`
-	Amount          int64           `json:"amount"`
-	EffectiveAmount int64           `json:"effectiveamount"`
-	Supports        []SupportResult `json:"supports,omitempty"`
-	Address         string          `json:"address,omitempty"`
-	Value           string          `json:"value,omitempty"`
+	ClaimID         string               `json:"claimid"`
+	TXID            string               `json:"txid"`
+	N               uint32               `json:"n"`
+	Bid             int32                `json:"bid"`
+	Sequence        int32                `json:"sequence"`
+	Height          int32                `json:"height"`
+	ValidAtHeight   int32                `json:"validatheight"`
+	Amount          int64                `json:"amount"`
+	EffectiveAmount int64                `json:"effectiveamount"`
+	Supports        []SupportResult      `json:"supports,omitempty"`
+	Address         string               `json:"address,omitempty"`
+	Value           string               `json:"value,omitempty"`
+	Metadata        *ClaimMetadataResult `json:"metadata,omitempty"`
+}
+
+// ClaimMetadataResult models the metadata decoded from the value of a claim.
+type ClaimMetadataResult struct {
+	Type             string                 `json:"type"`
+	SigningChannelID string                 `json:"signingchannelid,omitempty"`
+	Title            string                 `json:"title,omitempty"`
+	Description      string                 `json:"description,omitempty"`
+	Thumbnail        string                 `json:"thumbnail,omitempty"`
+	Tags             []string               `json:"tags,omitempty"`
+	Stream           *StreamMetadataResult  `json:"stream,omitempty"`
+	Channel          *ChannelMetadataResult `json:"channel,omitempty"`
+	Collection       []string               `json:"collection,omitempty"`
+	RepostedClaimID  string                 `json:"repostedclaimid,omitempty"`
+}
+
+// StreamMetadataResult models the metadata of a claim about a file.
+type StreamMetadataResult struct {
+	MediaType   string          `json:"mediatype,omitempty"`
+	FileName    string          `json:"filename,omitempty"`
+	FileSize    uint64          `json:"filesize,omitempty"`
+	Author      string          `json:"author,omitempty"`
+	License     string          `json:"license,omitempty"`
+	LicenseURL  string          `json:"licenseurl,omitempty"`
+	ReleaseTime int64           `json:"releasetime,omitempty"`
+	Fee         *ClaimFeeResult `json:"fee,omitempty"`
+}
+
+// ClaimFeeResult models the price asked to download the file of a stream.
+type ClaimFeeResult struct {
+	Currency string  `json:"currency"`
+	Address  string  `json:"address,omitempty"`
+	Amount   float64 `json:"amount"`
+}
+
+// ChannelMetadataResult models the metadata of a claim about a channel.
+type ChannelMetadataResult struct {
+	PublicKey  string `json:"publickey"`
+	Email      string `json:"email,omitempty"`
+	WebsiteURL string `json:"websiteurl,omitempty"`
+	Cover      string `json:"cover,omitempty"`
+}
+
+// GetClaimTrieInfoCmd defines the getclaimtrieinfo JSON-RPC command.
+type GetClaimTrieInfoCmd struct{}
+
+// NewGetClaimTrieInfoCmd returns a new instance which can be used to issue a
+// getclaimtrieinfo JSON-RPC command.
+func NewGetClaimTrieInfoCmd() *GetClaimTrieInfoCmd {
+	return &GetClaimTrieInfoCmd{}
+}
+
+// GetClaimTrieInfoResult models the data from the getclaimtrieinfo command.
+type GetClaimTrieInfoResult struct {
+	Height         int32   `json:"height"`
+	CacheEntries   int     `json:"cacheentries"`
+	CacheSize      int64   `json:"cachesize"`
+	CacheBudget    int64   `json:"cachebudget"`
+	CacheHits      uint64  `json:"cachehits"`
+	CacheMisses    uint64  `json:"cachemisses"`
+	CacheHitRate   float64 `json:"cachehitrate"`
+	CacheEvictions uint64  `json:"cacheevictions"`
+
+	Names           int64                `json:"names"`
+	Claims          int64                `json:"claims"`
+	Supports        int64                `json:"supports"`
+	PendingClaims   int64                `json:"pendingclaims"`
+	PendingSupports int64                `json:"pendingsupports"`
+	Depths          []ClaimTrieDepthInfo `json:"depths"`
+
+	Repos []ClaimTrieRepoInfo `json:"repos"`
+}
+
+// ClaimTrieDepthInfo models the number of names at a depth of the claim trie
+// returned by the getclaimtrieinfo command.
+type ClaimTrieDepthInfo struct {
+	Depth int   `json:"depth"`
+	Names int64 `json:"names"`
+}
+
+// ClaimTrieRepoInfo models the metrics of the pebble database of a claim trie
+// repo returned by the getclaimtrieinfo command.
+type ClaimTrieRepoInfo struct {
+	Repo             string `json:"repo"`
+	DiskSize         uint64 `json:"disksize"`
+	BlockCacheSize   int64  `json:"blockcachesize"`
+	BlockCacheHits   int64  `json:"blockcachehits"`
+	BlockCacheMisses int64  `json:"blockcachemisses"`
+	MemTableSize     uint64 `json:"memtablesize"`
+	Flushes          int64  `json:"flushes"`
+	Compactions      int64  `json:"compactions"`
+	CompactionDebt   uint64 `json:"compactiondebt"`
+	ReadAmp          int    `json:"readamp"`
+	FilterHits       int64  `json:"filterhits"`
+	FilterMisses     int64  `json:"filtermisses"`
 }
 
 type GetNormalizedCmd struct {
@@ -95,3 +400,111 @@ type GetNormalizedCmd struct {
 type GetNormalizedResult struct {
 	NormalizedName string `json:"normalizedname"`
 }
+
+type NormalizeNameCmd struct {
+	Name   string `json:"name"`
+	Height *int32 `json:"height"`
+}
+
+// NewNormalizeNameCmd returns a new instance which can be used to issue a
+// normalizename JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNormalizeNameCmd(name string, height *int32) *NormalizeNameCmd {
+	return &NormalizeNameCmd{
+		Name:   name,
+		Height: height,
+	}
+}
+
+type NormalizeNameResult struct {
+	Name           string `json:"name"`
+	NormalizedName string `json:"normalizedname"`
+	Height         int32  `json:"height"`
+	Normalized     bool   `json:"normalized"`
+}
+
+// SimulatedStake is a hypothetical claim or support of the
+// simulateclaimtakeover command.  It's a support of the claim with the claim ID
+// when one is given and a new claim otherwise.
+type SimulatedStake struct {
+	Amount  int64   `json:"amount"`
+	ClaimID *string `json:"claimid,omitempty"`
+}
+
+type SimulateClaimTakeoverCmd struct {
+	Name   string           `json:"name"`
+	Stakes []SimulatedStake `json:"stakes" jsonrpcusage:"[{\"amount\":n,\"claimid\":\"id\"},...]"`
+	Height *int32           `json:"height" jsonrpcdefault:"0"`
+}
+
+// NewSimulateClaimTakeoverCmd returns a new instance which can be used to issue
+// a simulateclaimtakeover JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSimulateClaimTakeoverCmd(name string, stakes []SimulatedStake,
+	height *int32) *SimulateClaimTakeoverCmd {
+
+	return &SimulateClaimTakeoverCmd{
+		Name:   name,
+		Stakes: stakes,
+		Height: height,
+	}
+}
+
+type SimulatedStakeResult struct {
+	ClaimID       string `json:"claimid"`
+	TXID          string `json:"txid"`
+	N             uint32 `json:"n"`
+	IsSupport     bool   `json:"issupport"`
+	Amount        int64  `json:"amount"`
+	Height        int32  `json:"height"`
+	ValidAtHeight int32  `json:"validatheight"`
+}
+
+type SimulateClaimTakeoverResult struct {
+	NormalizedName             string                 `json:"normalizedname"`
+	Height                     int32                  `json:"height"`
+	LastTakeoverHeight         int32                  `json:"lasttakeoverheight"`
+	ControllingClaimID         string                 `json:"controllingclaimid,omitempty"`
+	PreviousControllingClaimID string                 `json:"previouscontrollingclaimid,omitempty"`
+	Takeover                   bool                   `json:"takeover"`
+	Stakes                     []SimulatedStakeResult `json:"stakes"`
+	Claims                     []ClaimResult          `json:"claims"`
+}
+
+type ResolveCmd struct {
+	URI           string `json:"uri"`
+	IncludeValues *bool  `json:"includevalues" jsonrpcdefault:"false"`
+}
+
+type ResolveResult struct {
+	URI           string               `json:"uri"`
+	Hash          string               `json:"hash"`
+	Height        int32                `json:"height"`
+	ClaimTrieRoot string               `json:"claimtrieroot,omitempty"`
+	Channel       *ResolvedClaimResult `json:"channel,omitempty"`
+	Claim         ResolvedClaimResult  `json:"claim"`
+}
+
+// ResolvedClaimResult models a claim resolved from a URI along with the proof
+// it's part of the claim trie.
+type ResolvedClaimResult struct {
+	NormalizedName     string            `json:"normalizedname"`
+	LastTakeoverHeight int32             `json:"lasttakeoverheight"`
+	Claim              ClaimResult       `json:"claim"`
+	Proof              *ClaimProofResult `json:"proof,omitempty"`
+}
+
+// ClaimProofResult models the merkle path from the hash of a claim up to the
+// root of the claim trie.
+type ClaimProofResult struct {
+	Pairs []ClaimProofPairResult `json:"pairs"`
+}
+
+type ClaimProofPairResult struct {
+	Odd  bool   `json:"odd"`
+	Hash string `json:"hash"`
+}
@@ -0,0 +1,266 @@
+package btcjson_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+// TestClaimCmds tests all of the claim commands marshal and unmarshal into
+// valid results include handling of optional fields being omitted in the
+// marshalled command, while optional fields with defaults have the default
+// assigned on unmarshalled commands.
+func TestClaimCmds(t *testing.T) {
+	t.Parallel()
+
+	testID := int(1)
+	tests := []struct {
+		name         string
+		newCmd       func() (interface{}, error)
+		staticCmd    func() interface{}
+		marshalled   string
+		unmarshalled interface{}
+	}{
+		{
+			name: "getclaimsforname",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimsforname", "name")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimsForNameCmd("name", nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimsforname","params":["name"],"id":1}`,
+			unmarshalled: &btcjson.GetClaimsForNameCmd{
+				Name:          "name",
+				IncludeValues: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getclaimsfornamebyid",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimsfornamebyid", "name",
+					[]string{"ab"}, "100", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimsForNameByIDCmd("name",
+					[]string{"ab"}, btcjson.String("100"),
+					btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimsfornamebyid","params":["name",["ab"],"100",true],"id":1}`,
+			unmarshalled: &btcjson.GetClaimsForNameByIDCmd{
+				Name:            "name",
+				PartialClaimIDs: []string{"ab"},
+				HashOrHeight:    btcjson.String("100"),
+				IncludeValues:   btcjson.Bool(true),
+			},
+		},
+		{
+			name: "getclaimsfornamebybid",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimsfornamebybid", "name",
+					[]int32{0, 1})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimsForNameByBidCmd("name",
+					[]int32{0, 1}, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimsfornamebybid","params":["name",[0,1]],"id":1}`,
+			unmarshalled: &btcjson.GetClaimsForNameByBidCmd{
+				Name:          "name",
+				Bids:          []int32{0, 1},
+				IncludeValues: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getclaimsfornamebyseq",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimsfornamebyseq", "name",
+					[]int32{2})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimsForNameBySeqCmd("name",
+					[]int32{2}, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimsfornamebyseq","params":["name",[2]],"id":1}`,
+			unmarshalled: &btcjson.GetClaimsForNameBySeqCmd{
+				Name:          "name",
+				Sequences:     []int32{2},
+				IncludeValues: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getclaimbyid",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimbyid", "abcd")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimByIDCmd("abcd", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimbyid","params":["abcd"],"id":1}`,
+			unmarshalled: &btcjson.GetClaimByIDCmd{
+				ClaimID:       "abcd",
+				IncludeValues: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getclaimsbychannel",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimsbychannel", "abcd")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimsByChannelCmd("abcd", nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimsbychannel","params":["abcd"],"id":1}`,
+			unmarshalled: &btcjson.GetClaimsByChannelCmd{
+				ChannelID:     "abcd",
+				Skip:          btcjson.Int(0),
+				Count:         btcjson.Int(100),
+				IncludeValues: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getclaimsbychannel optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimsbychannel", "abcd", 10, 5, true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimsByChannelCmd("abcd",
+					btcjson.Int(10), btcjson.Int(5), btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimsbychannel","params":["abcd",10,5,true],"id":1}`,
+			unmarshalled: &btcjson.GetClaimsByChannelCmd{
+				ChannelID:     "abcd",
+				Skip:          btcjson.Int(10),
+				Count:         btcjson.Int(5),
+				IncludeValues: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "getchangesinblock",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getchangesinblock", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetChangesInBlockCmd(btcjson.String("123"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getchangesinblock","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetChangesInBlockCmd{
+				HashOrHeight: btcjson.String("123"),
+			},
+		},
+		{
+			name: "getclaimtrierootatheight",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getclaimtrierootatheight", 100)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetClaimTrieRootAtHeightCmd(100)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getclaimtrierootatheight","params":[100],"id":1}`,
+			unmarshalled: &btcjson.GetClaimTrieRootAtHeightCmd{
+				Height: 100,
+			},
+		},
+		{
+			name: "normalizename",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("normalizename", "Name", 100)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNormalizeNameCmd("Name", btcjson.Int32(100))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"normalizename","params":["Name",100],"id":1}`,
+			unmarshalled: &btcjson.NormalizeNameCmd{
+				Name:   "Name",
+				Height: btcjson.Int32(100),
+			},
+		},
+		{
+			name: "simulateclaimtakeover",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("simulateclaimtakeover", "name",
+					`[{"amount":5}]`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSimulateClaimTakeoverCmd("name",
+					[]btcjson.SimulatedStake{{Amount: 5}}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"simulateclaimtakeover","params":["name",[{"amount":5}]],"id":1}`,
+			unmarshalled: &btcjson.SimulateClaimTakeoverCmd{
+				Name:   "name",
+				Stakes: []btcjson.SimulatedStake{{Amount: 5}},
+				Height: btcjson.Int32(0),
+			},
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Marshal the command as created by the new static command
+		// creation function.
+		marshalled, err := btcjson.MarshalCmd(btcjson.RpcVersion1, testID, test.staticCmd())
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			continue
+		}
+
+		// Ensure the command is created without error via the generic
+		// new command creation function.
+		cmd, err := test.newCmd()
+		if err != nil {
+			t.Errorf("Test #%d (%s) unexpected NewCmd error: %v ",
+				i, test.name, err)
+		}
+
+		// Marshal the command as created by the generic new command
+		// creation function.
+		marshalled, err = btcjson.MarshalCmd(btcjson.RpcVersion1, testID, cmd)
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			continue
+		}
+
+		var request btcjson.Request
+		if err := json.Unmarshal(marshalled, &request); err != nil {
+			t.Errorf("Test #%d (%s) unexpected error while "+
+				"unmarshalling JSON-RPC request: %v", i,
+				test.name, err)
+			continue
+		}
+
+		cmd, err = btcjson.UnmarshalCmd(&request)
+		if err != nil {
+			t.Errorf("UnmarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(cmd, test.unmarshalled) {
+			t.Errorf("Test #%d (%s) unexpected unmarshalled command "+
+				"- got %s, want %s", i, test.name,
+				fmt.Sprintf("(%T) %+[1]v", cmd),
+				fmt.Sprintf("(%T) %+[1]v\n", test.unmarshalled))
+			continue
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/btcjson"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+const (
+	// defaultAlertReorgDepth is the default minimum number of blocks a
+	// reorganization must disconnect from the main chain to raise an alert.
+	defaultAlertReorgDepth = 6
+
+	// defaultAlertStallTimeout is the default time after which an alert is
+	// raised when the tip of the main chain didn't change.
+	defaultAlertStallTimeout = 30 * time.Minute
+
+	// alertStallCheckInterval is the interval at which the tip of the main
+	// chain is checked for a stall.
+	alertStallCheckInterval = time.Minute
+
+	// alertWebhookTimeout is the timeout of the requests posting the alerts
+	// to the webhook.
+	alertWebhookTimeout = 10 * time.Second
+
+	// alertQueueSize is the number of alerts which can be queued for the
+	// hooks before the new ones are only logged.
+	alertQueueSize = 100
+)
+
+// chainAlert describes an anomaly of the block chain detected by the chain
+// alerter.  It's posted as is to the webhook.
+type chainAlert struct {
+	Type    string `json:"type"`
+	Hash    string `json:"hash"`
+	Height  int32  `json:"height"`
+	Time    int64  `json:"time"`
+	Message string `json:"message"`
+}
+
+// chainAlerter watches the block chain for the anomalies which may reveal a
+// consensus failure or an attack, so the operators of the node can be paged:
+// reorganizations disconnecting many blocks, invalid blocks with valid proof of
+// work, and a main chain tip which doesn't change.  The alerts are logged and
+// passed to the configured hooks, such as the websocket notifications, and
+// posted to a webhook.
+type chainAlerter struct {
+	reorgDepth   int32
+	stallTimeout time.Duration
+	webhook      string
+	client       *http.Client
+	hooks        []func(*chainAlert)
+
+	// The following fields track the state of the main chain.  They are
+	// protected by the mutex since the chain notifications and the stall
+	// checks are handled by different goroutines.
+	mtx           sync.Mutex
+	tipHash       string
+	tipHeight     int32
+	lastTipChange time.Time
+	stallAlerted  bool
+	disconnected  int32
+	oldTipHash    string
+	oldTipHeight  int32
+
+	alerts chan *chainAlert
+	wg     sync.WaitGroup
+	quit   chan struct{}
+}
+
+// newChainAlerter returns a chain alerter watching the main chain with the
+// passed best state.  Reorganizations disconnecting at least reorgDepth blocks
+// and a tip which didn't change for stallTimeout raise alerts unless they are
+// zero.  The alerts are posted to the webhook when it's not empty, and passed
+// to the hooks.
+func newChainAlerter(best *blockchain.BestState, reorgDepth int32,
+	stallTimeout time.Duration, webhook string,
+	hooks ...func(*chainAlert)) *chainAlerter {
+
+	return &chainAlerter{
+		reorgDepth:    reorgDepth,
+		stallTimeout:  stallTimeout,
+		webhook:       webhook,
+		client:        &http.Client{Timeout: alertWebhookTimeout},
+		hooks:         hooks,
+		tipHash:       best.Hash.String(),
+		tipHeight:     best.Height,
+		lastTipChange: time.Now(),
+		alerts:        make(chan *chainAlert, alertQueueSize),
+		quit:          make(chan struct{}),
+	}
+}
+
+// Start begins delivering the alerts and checking the tip of the main chain
+// for a stall.
+func (a *chainAlerter) Start() {
+	a.wg.Add(1)
+	go a.alertHandler()
+}
+
+// Stop stops the chain alerter.  The queued alerts which weren't delivered yet
+// are discarded.
+func (a *chainAlerter) Stop() {
+	close(a.quit)
+	a.wg.Wait()
+}
+
+// raise logs the passed alert and queues it for the hooks and the webhook.
+//
+// This function is safe for concurrent access.
+func (a *chainAlerter) raise(alert *chainAlert) {
+	alrtLog.Warnf("Chain alert (%s): %s", alert.Type, alert.Message)
+
+	select {
+	case a.alerts <- alert:
+	default:
+		alrtLog.Warnf("Too many queued chain alerts, not delivering "+
+			"the %s alert to the hooks", alert.Type)
+	}
+}
+
+// handleBlockchainNotification tracks the changes of the main chain and raises
+// the alerts for large reorganizations and invalid blocks.  It must be
+// subscribed to the notifications of the chain.
+func (a *chainAlerter) handleBlockchainNotification(notification *blockchain.Notification) {
+	switch notification.Type {
+	case blockchain.NTBlockConnected, blockchain.NTBlockDisconnected:
+		block, ok := notification.Data.(*btcutil.Block)
+		if !ok {
+			alrtLog.Warnf("Chain notification is not a block.")
+			return
+		}
+		if alert := a.tipChanged(notification.Type, block); alert != nil {
+			a.raise(alert)
+		}
+
+	case blockchain.NTBlockInvalid:
+		invalid, ok := notification.Data.(*blockchain.InvalidBlock)
+		if !ok {
+			alrtLog.Warnf("Chain invalid notification is not an " +
+				"invalid block.")
+			return
+		}
+		block := invalid.Block
+		a.raise(&chainAlert{
+			Type:   btcjson.ChainAlertInvalidBlock,
+			Hash:   block.Hash().String(),
+			Height: block.Height(),
+			Time:   time.Now().Unix(),
+			Message: fmt.Sprintf("block %v at height %d with valid "+
+				"proof of work failed validation: %v",
+				block.Hash(), block.Height(), invalid.Err),
+		})
+	}
+}
+
+// tipChanged updates the tip of the main chain after the passed block was
+// connected to or disconnected from it.  It returns the alert for the
+// reorganization which just ended, if it disconnected enough blocks to raise
+// one.
+//
+// This function is safe for concurrent access.
+func (a *chainAlerter) tipChanged(typ blockchain.NotificationType,
+	block *btcutil.Block) *chainAlert {
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.lastTipChange = time.Now()
+	a.stallAlerted = false
+
+	// The blocks of a reorganization are disconnected from the old tip
+	// down to the fork point before the blocks of the new chain are
+	// connected.
+	if typ == blockchain.NTBlockDisconnected {
+		if a.disconnected == 0 {
+			a.oldTipHash = block.Hash().String()
+			a.oldTipHeight = block.Height()
+		}
+		a.disconnected++
+		a.tipHash = block.MsgBlock().Header.PrevBlock.String()
+		a.tipHeight = block.Height() - 1
+		return nil
+	}
+
+	a.tipHash = block.Hash().String()
+	a.tipHeight = block.Height()
+	depth := a.disconnected
+	a.disconnected = 0
+	if a.reorgDepth == 0 || depth < a.reorgDepth {
+		return nil
+	}
+
+	return &chainAlert{
+		Type:   btcjson.ChainAlertReorg,
+		Hash:   a.tipHash,
+		Height: a.tipHeight,
+		Time:   a.lastTipChange.Unix(),
+		Message: fmt.Sprintf("reorganization disconnected %d blocks "+
+			"from the old tip %s at height %d, the new chain "+
+			"continues with block %s at height %d", depth,
+			a.oldTipHash, a.oldTipHeight, a.tipHash, a.tipHeight),
+	}
+}
+
+// checkStall returns the alert for a tip of the main chain which didn't change
+// for the stall timeout as of the passed time.  The alert is only returned once
+// until the tip changes again.
+//
+// This function is safe for concurrent access.
+func (a *chainAlerter) checkStall(now time.Time) *chainAlert {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	stalled := now.Sub(a.lastTipChange)
+	if a.stallTimeout == 0 || a.stallAlerted || stalled < a.stallTimeout {
+		return nil
+	}
+	a.stallAlerted = true
+
+	return &chainAlert{
+		Type:   btcjson.ChainAlertStalledTip,
+		Hash:   a.tipHash,
+		Height: a.tipHeight,
+		Time:   now.Unix(),
+		Message: fmt.Sprintf("the tip %s of the main chain at height "+
+			"%d did not change for %v", a.tipHash, a.tipHeight,
+			stalled.Round(time.Second)),
+	}
+}
+
+// alertHandler delivers the queued alerts to the hooks and the webhook, and
+// periodically checks the tip of the main chain for a stall.  It must be run
+// as a goroutine.
+func (a *chainAlerter) alertHandler() {
+	defer a.wg.Done()
+
+	var stallChecks <-chan time.Time
+	if a.stallTimeout != 0 {
+		interval := alertStallCheckInterval
+		if a.stallTimeout < interval {
+			interval = a.stallTimeout
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		stallChecks = ticker.C
+	}
+
+	for {
+		select {
+		case alert := <-a.alerts:
+			a.deliver(alert)
+
+		case now := <-stallChecks:
+			if alert := a.checkStall(now); alert != nil {
+				a.raise(alert)
+			}
+
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+// deliver passes the alert to the hooks and posts it to the webhook.
+func (a *chainAlerter) deliver(alert *chainAlert) {
+	for _, hook := range a.hooks {
+		hook(alert)
+	}
+
+	if a.webhook == "" {
+		return
+	}
+	if err := a.postWebhook(alert); err != nil {
+		alrtLog.Errorf("Unable to post the %s alert to the webhook: %v",
+			alert.Type, err)
+	}
+}
+
+// postWebhook posts the passed alert as JSON to the webhook.
+func (a *chainAlerter) postWebhook(alert *chainAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Post(a.webhook, "application/json",
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
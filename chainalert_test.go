@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// testAlertBlocks returns a chain of the passed number of blocks starting at the
+// passed height after the passed parent.  The nonce makes the blocks of
+// different chains unique.
+func testAlertBlocks(parent chainhash.Hash, height int32, count int,
+	nonce uint32) []*btcutil.Block {
+
+	blocks := make([]*btcutil.Block, 0, count)
+	for i := 0; i < count; i++ {
+		block := btcutil.NewBlock(&wire.MsgBlock{
+			Header: wire.BlockHeader{
+				PrevBlock: parent,
+				Nonce:     nonce,
+			},
+		})
+		block.SetHeight(height + int32(i))
+		blocks = append(blocks, block)
+		parent = *block.Hash()
+	}
+	return blocks
+}
+
+// newTestChainAlerter returns a chain alerter with the passed settings.  The
+// alerts aren't logged since the log rotator isn't initialized in the tests.
+func newTestChainAlerter(best *blockchain.BestState, reorgDepth int32,
+	stallTimeout time.Duration, webhook string,
+	hooks ...func(*chainAlert)) *chainAlerter {
+
+	alrtLog.SetLevel(btclog.LevelOff)
+	return newChainAlerter(best, reorgDepth, stallTimeout, webhook,
+		hooks...)
+}
+
+// TestChainAlerterReorg ensures an alert is only raised for the reorganizations
+// which disconnect at least the configured number of blocks.
+func TestChainAlerterReorg(t *testing.T) {
+	best := &blockchain.BestState{Hash: chainhash.Hash{}, Height: 0}
+	a := newTestChainAlerter(best, 3, 0, "")
+
+	notify := func(typ blockchain.NotificationType, block *btcutil.Block) {
+		a.handleBlockchainNotification(&blockchain.Notification{
+			Type: typ,
+			Data: block,
+		})
+	}
+	mainChain := testAlertBlocks(chainhash.Hash{}, 1, 5, 0)
+	for _, block := range mainChain {
+		notify(blockchain.NTBlockConnected, block)
+	}
+	if len(a.alerts) != 0 {
+		t.Fatalf("got %d alerts after extending the chain, want 0",
+			len(a.alerts))
+	}
+
+	// Reorganize the last two blocks, which is too shallow to raise an
+	// alert, then the last three blocks.
+	reorg := func(depth int, nonce uint32) {
+		for i := len(mainChain) - 1; i >= len(mainChain)-depth; i-- {
+			notify(blockchain.NTBlockDisconnected, mainChain[i])
+		}
+		fork := mainChain[len(mainChain)-depth-1]
+		blocks := testAlertBlocks(*fork.Hash(), fork.Height()+1,
+			depth+1, nonce)
+		for _, block := range blocks {
+			notify(blockchain.NTBlockConnected, block)
+		}
+		mainChain = append(mainChain[:len(mainChain)-depth], blocks...)
+	}
+	reorg(2, 1)
+	if len(a.alerts) != 0 {
+		t.Fatalf("got %d alerts after a reorganization of 2 blocks, "+
+			"want 0", len(a.alerts))
+	}
+	reorg(3, 2)
+	if len(a.alerts) != 1 {
+		t.Fatalf("got %d alerts after a reorganization of 3 blocks, "+
+			"want 1", len(a.alerts))
+	}
+	alert := <-a.alerts
+	newTip := mainChain[len(mainChain)-4]
+	if alert.Type != btcjson.ChainAlertReorg ||
+		alert.Hash != newTip.Hash().String() ||
+		alert.Height != newTip.Height() {
+
+		t.Fatalf("unexpected reorganization alert %+v, want block %v "+
+			"at height %d", alert, newTip.Hash(), newTip.Height())
+	}
+}
+
+// TestChainAlerterInvalidBlock ensures an alert is raised for the invalid
+// blocks.
+func TestChainAlerterInvalidBlock(t *testing.T) {
+	a := newTestChainAlerter(&blockchain.BestState{}, 0, 0, "")
+
+	block := testAlertBlocks(chainhash.Hash{}, 10, 1, 0)[0]
+	a.handleBlockchainNotification(&blockchain.Notification{
+		Type: blockchain.NTBlockInvalid,
+		Data: &blockchain.InvalidBlock{
+			Block: block,
+			Err:   errors.New("bad block"),
+		},
+	})
+	if len(a.alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(a.alerts))
+	}
+	alert := <-a.alerts
+	if alert.Type != btcjson.ChainAlertInvalidBlock ||
+		alert.Hash != block.Hash().String() || alert.Height != 10 {
+
+		t.Fatalf("unexpected invalid block alert %+v", alert)
+	}
+}
+
+// TestChainAlerterStall ensures a stalled tip raises a single alert until the
+// tip changes.
+func TestChainAlerterStall(t *testing.T) {
+	a := newTestChainAlerter(&blockchain.BestState{}, 0, time.Hour, "")
+
+	start := a.lastTipChange
+	if alert := a.checkStall(start.Add(time.Minute)); alert != nil {
+		t.Fatalf("unexpected alert %+v before the stall timeout", alert)
+	}
+	alert := a.checkStall(start.Add(time.Hour))
+	if alert == nil || alert.Type != btcjson.ChainAlertStalledTip {
+		t.Fatalf("got alert %+v after the stall timeout, want a stalled "+
+			"tip alert", alert)
+	}
+	if alert := a.checkStall(start.Add(2 * time.Hour)); alert != nil {
+		t.Fatalf("unexpected second alert %+v for the same tip", alert)
+	}
+
+	block := testAlertBlocks(chainhash.Hash{}, 1, 1, 0)[0]
+	a.tipChanged(blockchain.NTBlockConnected, block)
+	if alert := a.checkStall(a.lastTipChange.Add(time.Hour)); alert == nil {
+		t.Fatal("no alert after the new tip stalled")
+	}
+}
+
+// TestChainAlerterDeliver ensures the alerts are passed to the hooks and posted
+// to the webhook.
+func TestChainAlerterDeliver(t *testing.T) {
+	posted := make(chan chainAlert, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+
+		var alert chainAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		posted <- alert
+	}))
+	defer ts.Close()
+
+	hooked := make(chan *chainAlert, 1)
+	a := newTestChainAlerter(&blockchain.BestState{}, 0, 0, ts.URL,
+		func(alert *chainAlert) { hooked <- alert })
+	a.Start()
+	defer a.Stop()
+
+	want := &chainAlert{
+		Type:    btcjson.ChainAlertStalledTip,
+		Hash:    "hash",
+		Height:  5,
+		Time:    1234,
+		Message: "message",
+	}
+	a.raise(want)
+	select {
+	case got := <-posted:
+		if got != *want {
+			t.Fatalf("posted alert %+v, want %+v", got, *want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the webhook")
+	}
+	if got := <-hooked; got != want {
+		t.Fatalf("hook got alert %+v, want %+v", got, want)
+	}
+}
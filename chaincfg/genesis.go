@@ -179,3 +179,24 @@ var sigNetGenesisBlock = wire.MsgBlock{
 // sigNetGenesisHash is the hash of the first block in the block chain for the
 // signet test network.
 var sigNetGenesisHash = sigNetGenesisBlock.BlockHash()
+
+// NewGenesisBlock returns a genesis block with the passed timestamp, target
+// difficulty bits and nonce, paying the same coinbase transaction as the
+// genesis block of the main network to an empty claim trie.  It is intended to
+// construct the genesis blocks of custom test networks, such as signets, which
+// are then passed to CustomSignetGenesisParams.
+func NewGenesisBlock(timestamp time.Time, bits, nonce uint32) *wire.MsgBlock {
+	coinbase := genesisCoinbaseTx.Copy()
+	return &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    1,
+			PrevBlock:  chainhash.Hash{},
+			MerkleRoot: coinbase.TxHash(),
+			ClaimTrie:  genesisClaimTrie,
+			Timestamp:  timestamp,
+			Bits:       bits,
+			Nonce:      nonce,
+		},
+		Transactions: []*wire.MsgTx{coinbase},
+	}
+}
@@ -105,3 +105,26 @@ func TestSigNetGenesisBlock(t *testing.T) {
 			spew.Sdump(SigNetParams.GenesisHash))
 	}
 }
+
+// TestNewGenesisBlock ensures NewGenesisBlock constructs the genesis block of
+// the main network from its timestamp, bits and nonce, and that custom signet
+// parameters use the passed genesis block.
+func TestNewGenesisBlock(t *testing.T) {
+	header := &genesisBlock.Header
+	block := NewGenesisBlock(header.Timestamp, header.Bits, header.Nonce)
+	hash := block.BlockHash()
+	if !hash.IsEqual(&genesisHash) {
+		t.Fatalf("NewGenesisBlock: got hash %v, want %v", hash,
+			genesisHash)
+	}
+
+	params := CustomSignetGenesisParams(DefaultSignetChallenge, nil, block)
+	if !params.GenesisHash.IsEqual(&genesisHash) {
+		t.Fatalf("CustomSignetGenesisParams: got genesis hash %v, "+
+			"want %v", params.GenesisHash, genesisHash)
+	}
+	if !bytes.Equal(params.SignetChallenge, DefaultSignetChallenge) {
+		t.Fatalf("CustomSignetGenesisParams: unexpected challenge %x",
+			params.SignetChallenge)
+	}
+}
@@ -62,7 +62,7 @@ var (
 	DefaultSignetDNSSeeds = []DNSSeed{
 		{"178.128.221.177", false},
 		{"2a01:7c8:d005:390::5", false},
-		{"v7ajjeirttkbnt32wpy3c6w3emwnfr3fkla7hpxcfokr3ysd3kqtzmqd.onion:39246", false},
+		{"v7ajjeirttkbnt32wpy3c6w3emwnfr3fkla7hpxcfokr3ysd3kqtzmqd.onion:49246", false},
 	}
 )
 
@@ -78,6 +78,28 @@ type Checkpoint struct {
 	Hash   *chainhash.Hash
 }
 
+// AssumeUtxo identifies an unspent transaction output set as of a block of the
+// main chain that a node can start syncing from by loading a utxo snapshot of
+// the block instead of connecting every block up to it.  The claim trie loaded
+// along with the set is committed to by the header of the block.
+//
+// UtxoSetHash is the MuHash3072 of the set as reported by gettxoutsetinfo.
+type AssumeUtxo struct {
+	Height      int32
+	Hash        *chainhash.Hash
+	UtxoSetHash *chainhash.Hash
+}
+
+// ChainTxData describes the number of transactions of a chain over time, which
+// is used to estimate the progress of the verification of the chain while it is
+// synced.  TxCount is the total number of transactions up to the block at Time,
+// and TxRate the average number of transactions per second after it.
+type ChainTxData struct {
+	Time    time.Time
+	TxCount uint64
+	TxRate  float64
+}
+
 // DNSSeed identifies a DNS seed.
 type DNSSeed struct {
 	// Host defines the hostname of the seed.
@@ -212,9 +234,22 @@ type Params struct {
 	// GenerateSupported specifies whether or not CPU mining is allowed.
 	GenerateSupported bool
 
+	// SignetChallenge is the script blocks of signet networks must be
+	// signed with as defined by BIP0325.  It is nil for other networks.
+	SignetChallenge []byte
+
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
 
+	// AssumeUtxo lists the unspent transaction output sets that utxo
+	// snapshots can be loaded for.
+	AssumeUtxo []AssumeUtxo
+
+	// ChainTxData describes the transactions of the chain to estimate the
+	// sync progress.  The progress is estimated from the timestamps of the
+	// blocks when it is zero.
+	ChainTxData ChainTxData
+
 	// These fields are related to voting on consensus rule changes as
 	// defined by BIP0009.
 	//
@@ -311,6 +346,14 @@ var MainNetParams = Params{
 		{960000, newHashFromStr("60e37b1c2d1f8771290b7f84865cbadf22b5b89d3ce1201d454b09f0775b42c2")},
 	},
 
+	// Rough transaction statistics of the main chain, which only need to be
+	// refreshed with getchaintxstats when the estimates drift too far.
+	ChainTxData: ChainTxData{
+		Time:    time.Unix(1640995200, 0), // 1 Jan 2022 00:00:00 +0000 UTC
+		TxCount: 45000000,
+		TxRate:  0.3,
+	},
+
 	// Consensus rule change deployments.
 	//
 	// The miner confirmation window is defined as:
@@ -601,6 +644,18 @@ var SigNetParams = CustomSignetParams(
 // from a challenge. The challenge is the binary compiled version of the block
 // challenge script.
 func CustomSignetParams(challenge []byte, dnsSeeds []DNSSeed) Params {
+	return CustomSignetGenesisParams(challenge, dnsSeeds, &sigNetGenesisBlock)
+}
+
+// CustomSignetGenesisParams creates network parameters for a custom signet
+// network from a challenge like CustomSignetParams, which starts with the
+// passed genesis block instead of the genesis block of the default signet
+// network.  See NewGenesisBlock.
+func CustomSignetGenesisParams(challenge []byte, dnsSeeds []DNSSeed,
+	genesis *wire.MsgBlock) Params {
+
+	genesisHash := genesis.BlockHash()
+
 	// The message start is defined as the first four bytes of the sha256d
 	// of the challenge script, as a single push (i.e. prefixed with the
 	// challenge script length).
@@ -615,12 +670,12 @@ func CustomSignetParams(challenge []byte, dnsSeeds []DNSSeed) Params {
 	return Params{
 		Name:        "signet",
 		Net:         wire.BitcoinNet(net),
-		DefaultPort: "39246",
+		DefaultPort: "49246",
 		DNSSeeds:    dnsSeeds,
 
 		// Chain parameters
-		GenesisBlock:             &sigNetGenesisBlock,
-		GenesisHash:              &sigNetGenesisHash,
+		GenesisBlock:             genesis,
+		GenesisHash:              &genesisHash,
 		PowLimit:                 sigNetPowLimit,
 		PowLimitBits:             0x1e0377ae,
 		BIP0034Height:            1,
@@ -634,6 +689,7 @@ func CustomSignetParams(challenge []byte, dnsSeeds []DNSSeed) Params {
 		ReduceMinDifficulty:      false,
 		MinDiffReductionTime:     time.Minute * 20, // TargetTimePerBlock * 2
 		GenerateSupported:        false,
+		SignetChallenge:          challenge,
 
 		// Checkpoints ordered from oldest to newest.
 		Checkpoints: nil,
@@ -642,6 +698,9 @@ func CustomSignetParams(challenge []byte, dnsSeeds []DNSSeed) Params {
 		//
 		// The miner confirmation window is defined as:
 		//   target proof of work timespan / target proof of work spacing
+		//
+		// Segwit is active from the first block since the signet
+		// solution is held in the witness commitment output.
 		RuleChangeActivationThreshold: 1916, // 95% of 2016
 		MinerConfirmationWindow:       2016,
 		Deployments: [DefinedDeployments]ConsensusDeployment{
@@ -651,14 +710,16 @@ func CustomSignetParams(challenge []byte, dnsSeeds []DNSSeed) Params {
 				ExpireTime: 1230767999, // December 31, 2008 UTC
 			},
 			DeploymentCSV: {
-				BitNumber:  29,
-				StartTime:  0,             // Always available for vote
-				ExpireTime: math.MaxInt64, // Never expires
+				BitNumber:     29,
+				StartTime:     0,             // Always available for vote
+				ExpireTime:    math.MaxInt64, // Never expires
+				ForceActiveAt: 1,
 			},
 			DeploymentSegwit: {
-				BitNumber:  29,
-				StartTime:  0,             // Always available for vote
-				ExpireTime: math.MaxInt64, // Never expires.
+				BitNumber:     29,
+				StartTime:     0,             // Always available for vote
+				ExpireTime:    math.MaxInt64, // Never expires.
+				ForceActiveAt: 1,
 			},
 			DeploymentTaproot: {
 				BitNumber:  29,
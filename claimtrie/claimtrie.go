@@ -3,9 +3,11 @@ package claimtrie
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
 
+	"github.com/cockroachdb/pebble"
 	"github.com/pkg/errors"
 
 	"github.com/lbryio/lbcd/claimtrie/block"
@@ -36,31 +38,58 @@ type ClaimTrie struct {
 	// due to stake expiration or delayed activation.
 	temporalRepo temporal.Repo
 
+	// Repository for the changes of each node.  It is owned by the nodeManager,
+	// but snapshots access it directly.
+	nodeRepo node.Repo
+
 	// Cache layer of Nodes.
 	nodeManager node.Manager
 
+	// Repository for the undo records of the nodes changed by each block, if
+	// they are kept.  It is owned by the nodeManager, but snapshots clear it
+	// directly.
+	undoRepo *noderepo.UndoPebble
+
 	// Prefix tree (trie) that manages merkle hash of each node.
 	merkleTrie merkletrie.MerkleTrie
 
 	// Current block height, which is increased by one when AppendBlock() is called.
 	height int32
 
+	// Sources of the metrics of the pebble database of each repo.
+	repoMetrics []repoMetricsSource
+
 	// Registrered cleanup functions which are invoked in the Close() in reverse order.
 	cleanups []func() error
 
 	// claimLogger communicates progress of claimtrie rebuild.
 	claimLogger *claimProgressLogger
+
+	// Configuration the claim trie was opened with, so it can be reopened
+	// once its data is replaced.
+	cfg config.Config
+
+	// Directory holding the data of a claim trie created by StageSnapshot,
+	// which is empty for the other ones.
+	stagingDir string
 }
 
 func New(cfg config.Config) (*ClaimTrie, error) {
+	return open(cfg, nil)
+}
+
+// open opens the claim trie stored with the passed configuration.  The passed
+// RAM trie, if any, is used as the merkle trie when it matches the stored root
+// rather than being rebuilt.
+func open(cfg config.Config, ramTrie *merkletrie.RamTrie) (*ClaimTrie, error) {
 
 	var cleanups []func() error
 
 	// The passed in cfg.DataDir has been prepended with netname.
-	dataDir := filepath.Join(cfg.DataDir, "claim_dbs")
+	dataDir := dataDir(cfg)
 
 	dbPath := filepath.Join(dataDir, cfg.BlockRepoPebble.Path)
-	blockRepo, err := blockrepo.NewPebble(dbPath)
+	blockRepo, err := blockrepo.NewPebble(dbPath, cfg.PebbleTuning.Apply)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating block repo")
 	}
@@ -71,7 +100,7 @@ func New(cfg config.Config) (*ClaimTrie, error) {
 	}
 
 	dbPath = filepath.Join(dataDir, cfg.TemporalRepoPebble.Path)
-	temporalRepo, err := temporalrepo.NewPebble(dbPath)
+	temporalRepo, err := temporalrepo.NewPebble(dbPath, cfg.PebbleTuning.Apply)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating temporal repo")
 	}
@@ -80,7 +109,7 @@ func New(cfg config.Config) (*ClaimTrie, error) {
 	// Initialize repository for changes to nodes.
 	// The cleanup is delegated to the Node Manager.
 	dbPath = filepath.Join(dataDir, cfg.NodeRepoPebble.Path)
-	nodeRepo, err := noderepo.NewPebble(dbPath)
+	nodeRepo, err := noderepo.NewPebble(dbPath, cfg.PebbleTuning.Apply)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating node repo")
 	}
@@ -89,22 +118,53 @@ func New(cfg config.Config) (*ClaimTrie, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "creating node base manager")
 	}
+	baseManager.SetCacheBudget(cfg.NodeCacheBudget)
+
+	// Initialize repository for the undo records of the nodes.
+	// The cleanup is delegated to the Node Manager.
+	var undoRepo *noderepo.UndoPebble
+	if cfg.NodeUndoDepth > 0 {
+		dbPath = filepath.Join(dataDir, cfg.NodeUndoRepoPebble.Path)
+		undoRepo, err = noderepo.NewUndoPebble(dbPath, cfg.PebbleTuning.Apply)
+		if err != nil {
+			nodeRepo.Close()
+			return nil, errors.Wrap(err, "creating node undo repo")
+		}
+		baseManager.SetUndoRepo(undoRepo, cfg.NodeUndoDepth,
+			cfg.NodeUndoStartHeight)
+	}
 	normalizingManager := node.NewNormalizingManager(baseManager)
 	nodeManager := &node.HashV2Manager{Manager: normalizingManager}
 	cleanups = append(cleanups, nodeManager.Close)
 
+	repoMetrics := []repoMetricsSource{
+		{"block", blockRepo.Metrics},
+		{"temporal", temporalRepo.Metrics},
+		{"node", nodeRepo.Metrics},
+	}
+	if undoRepo != nil {
+		repoMetrics = append(repoMetrics,
+			repoMetricsSource{"nodeundo", undoRepo.Metrics})
+	}
+
 	var trie merkletrie.MerkleTrie
 	if cfg.RamTrie {
-		trie = merkletrie.NewRamTrie()
+		if ramTrie == nil {
+			ramTrie = merkletrie.NewRamTrie()
+		}
+		trie = ramTrie
 	} else {
 
 		// Initialize repository for MerkleTrie. The cleanup is delegated to MerkleTrie.
 		dbPath = filepath.Join(dataDir, cfg.MerkleTrieRepoPebble.Path)
-		trieRepo, err := merkletrierepo.NewPebble(dbPath)
+		trieRepo, err := merkletrierepo.NewPebble(dbPath, cfg.PebbleTuning.Apply)
 		if err != nil {
 			return nil, errors.Wrap(err, "creating trie repo")
 		}
 
+		repoMetrics = append(repoMetrics,
+			repoMetricsSource{"merkletrie", trieRepo.Metrics})
+
 		persistentTrie := merkletrie.NewPersistentTrie(trieRepo)
 		cleanups = append(cleanups, persistentTrie.Close)
 		trie = persistentTrie
@@ -120,10 +180,14 @@ func New(cfg config.Config) (*ClaimTrie, error) {
 		blockRepo:    blockRepo,
 		temporalRepo: temporalRepo,
 
+		nodeRepo:    nodeRepo,
 		nodeManager: nodeManager,
+		undoRepo:    undoRepo,
 		merkleTrie:  trie,
 
-		height: previousHeight,
+		height:      previousHeight,
+		repoMetrics: repoMetrics,
+		cfg:         cfg,
 	}
 
 	ct.cleanups = cleanups
@@ -247,6 +311,11 @@ func (ct *ClaimTrie) AppendBlock(temporary bool) error {
 	names = append(names, expirations...)
 	names = removeDuplicates(names)
 
+	err = ct.nodeManager.UpdateStats(names, ct.height-1)
+	if err != nil {
+		return errors.Wrap(err, "node manager update stats")
+	}
+
 	for _, name := range names {
 
 		hash, next := ct.nodeManager.Hash(name)
@@ -387,6 +456,58 @@ func (ct *ClaimTrie) MerkleHash() *chainhash.Hash {
 	return ct.merkleTrie.MerkleHash()
 }
 
+// MerkleHashAt returns the Merkle Hash of the claimTrie at the given height,
+// which must not be above the current height.  The hashes of past heights are
+// kept in the block repo, so the claimTrie doesn't have to be rolled back.
+func (ct *ClaimTrie) MerkleHashAt(height int32) (*chainhash.Hash, error) {
+	if height < 0 || height > ct.height {
+		return nil, errors.Errorf("height %d is outside of the claim trie range 0 to %d",
+			height, ct.height)
+	}
+
+	hash, err := ct.blockRepo.Get(height)
+	return hash, errors.Wrapf(err, "block repo get at %d", height)
+}
+
+// repoMetricsSource is a repo along with the function returning the metrics
+// of its pebble database.
+type repoMetricsSource struct {
+	repo    string
+	metrics func() *pebble.Metrics
+}
+
+// RepoMetrics is the internal metrics of the pebble database of a repo.
+type RepoMetrics struct {
+	Repo string
+	*pebble.Metrics
+}
+
+// RepoMetrics returns the internal metrics of the pebble databases of the
+// repos.  The merkle trie has no repo when it's kept in memory.
+func (ct *ClaimTrie) RepoMetrics() []RepoMetrics {
+	metrics := make([]RepoMetrics, 0, len(ct.repoMetrics))
+	for _, source := range ct.repoMetrics {
+		metrics = append(metrics, RepoMetrics{
+			Repo:    source.repo,
+			Metrics: source.metrics(),
+		})
+	}
+	return metrics
+}
+
+// CacheStats returns the statistics of the node cache.
+//
+// This function is safe for concurrent access.
+func (ct *ClaimTrie) CacheStats() node.CacheStats {
+	return ct.nodeManager.CacheStats()
+}
+
+// Stats returns the aggregate statistics of the nodes at the current height.
+// The first call computes them from all the names, which can take a while.
+func (ct *ClaimTrie) Stats() (*node.Stats, error) {
+	return ct.nodeManager.Stats()
+}
+
 // Height returns the current block height.
 func (ct *ClaimTrie) Height() int32 {
 	return ct.height
@@ -417,6 +538,13 @@ func (ct *ClaimTrie) NodeAt(height int32, name []byte) (*node.Node, error) {
 	return ct.nodeManager.NodeAt(height, name)
 }
 
+// SimulateNodeAt returns the node of the name at the height as it would be if
+// the hypothetical changes were made after the current height.  The claim trie
+// isn't modified.
+func (ct *ClaimTrie) SimulateNodeAt(height int32, name []byte, changes []change.Change) (*node.Node, error) {
+	return ct.nodeManager.SimulateNodeAt(height, name, changes)
+}
+
 func (ct *ClaimTrie) NamesChangedInBlock(height int32) ([]string, error) {
 	hits, err := ct.temporalRepo.NodesAt(height)
 	r := make([]string, len(hits))
@@ -426,6 +554,61 @@ func (ct *ClaimTrie) NamesChangedInBlock(height int32) ([]string, error) {
 	return r, err
 }
 
+// ClaimStateChange is a claim which was activated or expired by a block.
+type ClaimStateChange struct {
+	Name  string
+	Claim *node.Claim
+}
+
+// ClaimStateChangesInBlock returns the claims activated and the claims expired
+// by the block at the given height.  A claim is activated when it becomes
+// active after its activation delay or due to a takeover, and expired when it
+// is dropped from its node at its expiration height.
+func (ct *ClaimTrie) ClaimStateChangesInBlock(height int32) (activated,
+	expired []ClaimStateChange, err error) {
+
+	names, err := ct.NamesChangedInBlock(height)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range names {
+		prev, err := ct.NodeAt(height-1, []byte(name))
+		if err != nil {
+			return nil, nil, err
+		}
+		cur, err := ct.NodeAt(height, []byte(name))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		wasActive := map[wire.OutPoint]bool{}
+		if prev != nil {
+			for _, c := range prev.Claims {
+				wasActive[c.OutPoint] = c.Status == node.Activated
+			}
+		}
+		present := map[wire.OutPoint]bool{}
+		if cur != nil {
+			for _, c := range cur.Claims {
+				present[c.OutPoint] = true
+				if c.Status == node.Activated && !wasActive[c.OutPoint] {
+					activated = append(activated, ClaimStateChange{Name: name, Claim: c})
+				}
+			}
+		}
+		if prev != nil {
+			for _, c := range prev.Claims {
+				if !present[c.OutPoint] && c.ExpireAt() <= height {
+					expired = append(expired, ClaimStateChange{Name: name, Claim: c})
+				}
+			}
+		}
+	}
+
+	return activated, expired, nil
+}
+
 func (ct *ClaimTrie) FlushToDisk() {
 	// maybe the user can fix the file lock shown in the warning before they shut down
 	if err := ct.nodeManager.Flush(); err != nil {
@@ -451,3 +634,15 @@ func interruptRequested(interrupted <-chan struct{}) bool {
 
 	return false
 }
+
+// Remove deletes the databases of the claim trie stored with the passed
+// configuration, which must not be open.
+func Remove(cfg config.Config) error {
+	return os.RemoveAll(dataDir(cfg))
+}
+
+// dataDir returns the directory of the databases of the claim trie stored with
+// the passed configuration.
+func dataDir(cfg config.Config) string {
+	return filepath.Join(cfg.DataDir, "claim_dbs")
+}
@@ -1,18 +1,23 @@
 package claimtrie
 
 import (
+	"bytes"
 	"math/rand"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/lbryio/lbcd/claimtrie/change"
 	"github.com/lbryio/lbcd/claimtrie/config"
 	"github.com/lbryio/lbcd/claimtrie/merkletrie"
+	"github.com/lbryio/lbcd/claimtrie/node"
 	"github.com/lbryio/lbcd/claimtrie/param"
 
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
 	"github.com/lbryio/lbcd/wire"
 
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
 	"github.com/stretchr/testify/require"
 )
 
@@ -584,6 +589,67 @@ func TestClaimTakeover(t *testing.T) {
 	r.Equal(o1.String(), n.BestClaim.OutPoint.String())
 }
 
+func TestClaimStateChangesInBlock(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+	param.ActiveParams.ActiveDelayFactor = 1
+	param.ActiveParams.OriginalClaimExpirationTime = 30
+	param.ActiveParams.ExtendedClaimExpirationTime = 30
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	r.NotNil(ct)
+	defer ct.Close()
+
+	incrementBlock(r, ct, 1)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, change.NewClaimID(o1), 8)
+	r.NoError(err)
+
+	incrementBlock(r, ct, 1)
+
+	// The first claim of a name is activated immediately.
+	activated, expired, err := ct.ClaimStateChangesInBlock(ct.height)
+	r.NoError(err)
+	r.Len(activated, 1)
+	r.Equal("test", activated[0].Name)
+	r.Equal(o1, activated[0].Claim.OutPoint)
+	r.Empty(expired)
+
+	incrementBlock(r, ct, 9)
+
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddClaim([]byte("test"), o2, change.NewClaimID(o2), 18)
+	r.NoError(err)
+
+	incrementBlock(r, ct, 1)
+
+	// The second claim waits for its activation delay.
+	activated, expired, err = ct.ClaimStateChangesInBlock(ct.height)
+	r.NoError(err)
+	r.Empty(activated)
+	r.Empty(expired)
+
+	incrementBlock(r, ct, 10)
+
+	activated, expired, err = ct.ClaimStateChangesInBlock(ct.height)
+	r.NoError(err)
+	r.Len(activated, 1)
+	r.Equal(o2, activated[0].Claim.OutPoint)
+	r.Empty(expired)
+
+	incrementBlock(r, ct, 10)
+
+	// The first claim expires 30 blocks after it was accepted.
+	activated, expired, err = ct.ClaimStateChangesInBlock(ct.height)
+	r.NoError(err)
+	r.Empty(activated)
+	r.Len(expired, 1)
+	r.Equal(o1, expired[0].Claim.OutPoint)
+}
+
 func TestSpendClaim(t *testing.T) {
 	r := require.New(t)
 	setup(t)
@@ -945,6 +1011,159 @@ func TestStableTrieHash(t *testing.T) {
 	}
 }
 
+func TestSnapshot(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+	param.ActiveParams.ActiveDelayFactor = 1
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, change.NewClaimID(o1), 1)
+	r.NoError(err)
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddClaim([]byte("other"), o2, change.NewClaimID(o2), 2)
+	r.NoError(err)
+	incrementBlock(r, ct, 10)
+
+	// Leave a takeover pending past the snapshot height.
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	err = ct.AddClaim([]byte("test"), o3, change.NewClaimID(o3), 5)
+	r.NoError(err)
+	err = ct.SpendClaim([]byte("other"), o2, change.NewClaimID(o2))
+	r.NoError(err)
+	incrementBlock(r, ct, 1)
+
+	var snapshot bytes.Buffer
+	header, err := ct.ExportSnapshot(&snapshot, ct.Height())
+	r.NoError(err)
+	r.Equal(ct.Height(), header.Height)
+	r.Equal(*ct.MerkleHash(), header.ClaimTrieRoot)
+
+	// The export is deterministic.
+	var again bytes.Buffer
+	_, err = ct.ExportSnapshot(&again, ct.Height())
+	r.NoError(err)
+	r.Equal(snapshot.Bytes(), again.Bytes())
+
+	cfg2 := cfg
+	cfg2.DataDir = t.TempDir()
+	ct2, err := New(cfg2)
+	r.NoError(err)
+	defer ct2.Close()
+
+	// A corrupt snapshot is rejected before anything is dropped.
+	corrupt := append([]byte{}, snapshot.Bytes()...)
+	corrupt[len(corrupt)-40]++
+	_, err = ct2.ImportSnapshot(bytes.NewReader(corrupt))
+	r.Error(err)
+
+	_, err = ct2.ImportSnapshot(bytes.NewReader(snapshot.Bytes()))
+	r.NoError(err)
+	r.Equal(ct.Height(), ct2.Height())
+	r.Equal(*ct.MerkleHash(), *ct2.MerkleHash())
+
+	// Both tries evolve identically past the snapshot height, including the
+	// pending takeover.
+	before := *ct.MerkleHash()
+	for i := 0; i < 20; i++ {
+		incrementBlock(r, ct, 1)
+		incrementBlock(r, ct2, 1)
+		r.Equal(*ct.MerkleHash(), *ct2.MerkleHash())
+	}
+	r.NotEqual(before, *ct2.MerkleHash())
+
+	// Rolling back below the snapshot height works too.
+	incrementBlock(r, ct, -25)
+	incrementBlock(r, ct2, -25)
+	r.Equal(*ct.MerkleHash(), *ct2.MerkleHash())
+	for i := 0; i < 20; i++ {
+		incrementBlock(r, ct, 1)
+		incrementBlock(r, ct2, 1)
+		r.Equal(*ct.MerkleHash(), *ct2.MerkleHash())
+	}
+
+	_, err = ct.ExportSnapshot(&again, ct.Height()+1)
+	r.Error(err)
+}
+
+func TestStageSnapshot(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+	param.ActiveParams.ActiveDelayFactor = 1
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, change.NewClaimID(o1), 1)
+	r.NoError(err)
+	incrementBlock(r, ct, 10)
+
+	var snapshot bytes.Buffer
+	_, err = ct.ExportSnapshot(&snapshot, ct.Height())
+	r.NoError(err)
+
+	for _, ramTrie := range []bool{true, false} {
+		cfg2 := cfg
+		cfg2.DataDir = t.TempDir()
+		cfg2.RamTrie = ramTrie
+		ct2, err := New(cfg2)
+		r.NoError(err)
+		o2 := wire.OutPoint{Hash: hash, Index: 2}
+		err = ct2.AddClaim([]byte("other"), o2, change.NewClaimID(o2), 2)
+		r.NoError(err)
+		incrementBlock(r, ct2, 5)
+		before := *ct2.MerkleHash()
+		stagingDir := filepath.Join(cfg2.DataDir, stagingDirName)
+
+		// A corrupt snapshot is rejected without leaving anything
+		// behind.
+		corrupt := append([]byte{}, snapshot.Bytes()...)
+		corrupt[len(corrupt)-40]++
+		_, _, err = ct2.StageSnapshot(bytes.NewReader(corrupt))
+		r.Error(err)
+		r.NoDirExists(stagingDir)
+
+		// The claim trie is untouched by the staging, and by discarding
+		// the staged claim trie.
+		staged, header, err := ct2.StageSnapshot(bytes.NewReader(snapshot.Bytes()))
+		r.NoError(err)
+		r.Equal(ct.Height(), header.Height)
+		r.Equal(*ct.MerkleHash(), *staged.MerkleHash())
+		r.Equal(before, *ct2.MerkleHash())
+		r.Error(ct2.Discard())
+		r.NoError(staged.Discard())
+		r.NoDirExists(stagingDir)
+		r.Equal(before, *ct2.MerkleHash())
+
+		// The staged claim trie replaces the claim trie, including once
+		// it is reopened.
+		staged, _, err = ct2.StageSnapshot(bytes.NewReader(snapshot.Bytes()))
+		r.NoError(err)
+		incrementBlock(r, staged, 1)
+		r.Error(ct2.Replace(ct))
+		r.NoError(ct2.Replace(staged))
+		r.NoDirExists(stagingDir)
+		incrementBlock(r, ct, 1)
+		r.Equal(ct.Height(), ct2.Height())
+		r.Equal(*ct.MerkleHash(), *ct2.MerkleHash())
+
+		ct2.Close()
+		ct2, err = New(cfg2)
+		r.NoError(err)
+		r.Equal(ct.Height(), ct2.Height())
+		r.Equal(*ct.MerkleHash(), *ct2.MerkleHash())
+		ct2.Close()
+		incrementBlock(r, ct, -1)
+	}
+}
+
 func TestBlock884431(t *testing.T) {
 	r := require.New(t)
 	setup(t)
@@ -1025,3 +1244,416 @@ func TestBlock884431(t *testing.T) {
 	r.NoError(err)
 	r.Equal(o11.String(), n.BestClaim.OutPoint.String())
 }
+
+func TestVerify(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+	param.ActiveParams.ActiveDelayFactor = 1
+
+	for _, ramTrie := range []bool{false, true} {
+		cfg2 := cfg
+		cfg2.DataDir = t.TempDir()
+		cfg2.RamTrie = ramTrie
+		ct, err := New(cfg2)
+		r.NoError(err)
+
+		hash := chainhash.HashH([]byte{1, 2, 3})
+		o1 := wire.OutPoint{Hash: hash, Index: 1}
+		err = ct.AddClaim([]byte("test"), o1, change.NewClaimID(o1), 1)
+		r.NoError(err)
+		o2 := wire.OutPoint{Hash: hash, Index: 2}
+		err = ct.AddClaim([]byte("tester"), o2, change.NewClaimID(o2), 2)
+		r.NoError(err)
+		incrementBlock(r, ct, 10)
+		root := *ct.MerkleHash()
+
+		result, err := ct.Verify(&root, false, nil)
+		r.NoError(err)
+		r.True(result.Consistent())
+		r.Equal(2, result.NamesChecked)
+
+		// Corrupt the hash of a name in the merkle trie.
+		bogus := chainhash.HashH([]byte("bogus"))
+		ct.merkleTrie.Update([]byte("tester"), &bogus, true)
+		r.NotEqual(root, *ct.MerkleHash())
+
+		result, err = ct.Verify(&root, false, nil)
+		r.NoError(err)
+		r.False(result.Consistent())
+		r.Equal(root, result.ComputedRoot)
+		r.Equal(1, result.DivergentCount)
+		r.Equal([][]byte{[]byte("tester")}, result.DivergentNames)
+		r.False(result.Repaired)
+
+		// Nothing is repaired when the expected root doesn't match.
+		result, err = ct.Verify(&bogus, true, nil)
+		r.NoError(err)
+		r.False(result.Repaired)
+
+		result, err = ct.Verify(&root, true, nil)
+		r.NoError(err)
+		r.True(result.Repaired)
+		r.Equal(root, *ct.MerkleHash())
+
+		result, err = ct.Verify(&root, false, nil)
+		r.NoError(err)
+		r.True(result.Consistent())
+		ct.Close()
+	}
+}
+
+func TestMerkleHashAt(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+	ct, err := New(cfg)
+	r.NoError(err)
+	r.NotNil(ct)
+	defer ct.Close()
+
+	incrementBlock(r, ct, 1)
+	empty := *ct.MerkleHash()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, change.NewClaimID(o1), 1)
+	r.NoError(err)
+	incrementBlock(r, ct, 1)
+	root := *ct.MerkleHash()
+	r.NotEqual(empty, root)
+	incrementBlock(r, ct, 2)
+
+	// The roots of past heights are returned without rolling back.
+	h, err := ct.MerkleHashAt(1)
+	r.NoError(err)
+	r.Equal(empty, *h)
+	h, err = ct.MerkleHashAt(2)
+	r.NoError(err)
+	r.Equal(root, *h)
+	r.Equal(int32(4), ct.Height())
+
+	_, err = ct.MerkleHashAt(5)
+	r.Error(err)
+	_, err = ct.MerkleHashAt(-1)
+	r.Error(err)
+}
+
+// verifyStats ensures the statistics kept up to date by the node manager match
+// the ones computed from all the names.
+func verifyStats(r *require.Assertions, ct *ClaimTrie) *node.Stats {
+	stats, err := ct.Stats()
+	r.NoError(err)
+	ct.nodeManager.ClearCache()
+	scanned, err := ct.Stats()
+	r.NoError(err)
+	r.Equal(scanned, stats)
+	return stats
+}
+
+func TestStats(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+	param.ActiveParams.ActiveDelayFactor = 1
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	r.NotNil(ct)
+	defer ct.Close()
+
+	incrementBlock(r, ct, 1)
+	stats, err := ct.Stats()
+	r.NoError(err)
+	r.Zero(stats.Names)
+	r.Empty(stats.Depths)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	err = ct.AddClaim([]byte("test"), o1, change.NewClaimID(o1), 18)
+	r.NoError(err)
+	o2 := wire.OutPoint{Hash: hash, Index: 2}
+	err = ct.AddClaim([]byte("tester"), o2, change.NewClaimID(o2), 8)
+	r.NoError(err)
+	incrementBlock(r, ct, 10)
+
+	stats = verifyStats(r, ct)
+	r.Equal(int64(2), stats.Names)
+	r.Equal(int64(2), stats.Claims)
+	r.Zero(stats.PendingClaims)
+	r.Equal(map[int]int64{4: 1, 6: 1}, stats.Depths)
+
+	// Claims and supports on a name with a best claim are delayed.
+	o3 := wire.OutPoint{Hash: hash, Index: 3}
+	err = ct.AddClaim([]byte("test"), o3, change.NewClaimID(o3), 28)
+	r.NoError(err)
+	o4 := wire.OutPoint{Hash: hash, Index: 4}
+	err = ct.AddSupport([]byte("test"), o4, 18, change.NewClaimID(o3))
+	r.NoError(err)
+	incrementBlock(r, ct, 1)
+
+	stats = verifyStats(r, ct)
+	r.Equal(int64(2), stats.Names)
+	r.Equal(int64(3), stats.Claims)
+	r.Equal(int64(1), stats.Supports)
+	r.Equal(int64(1), stats.PendingClaims)
+	r.Equal(int64(1), stats.PendingSupports)
+
+	// Temporary blocks are accounted for until they are rolled back.
+	err = ct.SpendClaim([]byte("tester"), o2, change.NewClaimID(o2))
+	r.NoError(err)
+	r.NoError(ct.AppendBlock(true))
+	stats, err = ct.Stats()
+	r.NoError(err)
+	r.Equal(int64(1), stats.Names)
+	r.NoError(ct.ResetHeight(ct.height - 1))
+	stats, err = ct.Stats()
+	r.NoError(err)
+	r.Equal(int64(2), stats.Names)
+
+	incrementBlock(r, ct, 11)
+	stats = verifyStats(r, ct)
+	r.Zero(stats.PendingClaims)
+	r.Zero(stats.PendingSupports)
+
+	err = ct.SpendClaim([]byte("tester"), o2, change.NewClaimID(o2))
+	r.NoError(err)
+	incrementBlock(r, ct, 1)
+	stats = verifyStats(r, ct)
+	r.Equal(int64(1), stats.Names)
+	r.Equal(map[int]int64{4: 1}, stats.Depths)
+
+	incrementBlock(r, ct, -12)
+	stats = verifyStats(r, ct)
+	r.Equal(int64(2), stats.Names)
+	r.Equal(int64(1), stats.PendingClaims)
+	r.Equal(int64(1), stats.PendingSupports)
+}
+
+func TestCompact(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+	param.ActiveParams.ActiveDelayFactor = 1
+	param.ActiveParams.OriginalClaimExpirationTime = 1000000
+	param.ActiveParams.ExtendedClaimExpirationTime = 1000000
+
+	// The claim trie to compact and a reference one see the same changes.
+	cfg2 := cfg
+	cfg2.DataDir = t.TempDir()
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+	ct2, err := New(cfg2)
+	r.NoError(err)
+	defer ct2.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	op := func(index uint32) wire.OutPoint {
+		return wire.OutPoint{Hash: hash, Index: index}
+	}
+	both := func(f func(ct *ClaimTrie) error) {
+		r.NoError(f(ct))
+		r.NoError(f(ct2))
+	}
+	increment := func(c int32) {
+		for ; c > 0; c-- {
+			incrementBlock(r, ct, 1)
+			incrementBlock(r, ct2, 1)
+			r.Equal(*ct2.MerkleHash(), *ct.MerkleHash())
+		}
+	}
+
+	// A claim and its support are spent before another claim takes over,
+	// on two names.  One of them isn't compacted since a change of its
+	// parent checks for active children while the spent claim was active.
+	for i, name := range []string{"other", "tester"} {
+		base := uint32(i * 10)
+		claimID := change.NewClaimID(op(base + 1))
+		both(func(ct *ClaimTrie) error { return ct.AddClaim(b(name), op(base+1), claimID, 1) })
+		increment(1)
+		both(func(ct *ClaimTrie) error { return ct.AddSupport(b(name), op(base+2), 5, claimID) })
+		if name == "tester" {
+			both(func(ct *ClaimTrie) error {
+				return ct.AddClaim(b("test"), op(50), change.NewClaimID(op(50)), 1)
+			})
+		}
+		increment(1)
+		both(func(ct *ClaimTrie) error { return ct.SpendSupport(b(name), op(base+2), claimID) })
+		increment(1)
+		both(func(ct *ClaimTrie) error { return ct.SpendClaim(b(name), op(base+1), claimID) })
+		increment(1)
+		both(func(ct *ClaimTrie) error {
+			return ct.AddClaim(b(name), op(base+3), change.NewClaimID(op(base+3)), 10)
+		})
+		increment(1)
+		both(func(ct *ClaimTrie) error {
+			return ct.AddClaim(b(name), op(base+4), change.NewClaimID(op(base+4)), 2)
+		})
+		increment(1)
+	}
+	finalHeight := ct.Height()
+	increment(CompactRollbackDepth)
+
+	_, err = ct.Compact(finalHeight+1, nil)
+	r.Error(err)
+	result, err := ct.Compact(finalHeight, nil)
+	r.NoError(err)
+	r.Equal(3, result.NamesChecked)
+	r.Equal(1, result.NamesCompacted)
+	r.Equal(4, result.ChangesRemoved)
+	r.Equal(*ct2.MerkleHash(), *ct.MerkleHash())
+
+	// The names can't be queried below the final height anymore.
+	_, err = ct.NodeAt(finalHeight-1, b("other"))
+	r.Error(err)
+	_, err = ct.NodeAt(finalHeight, b("other"))
+	r.NoError(err)
+	_, err = ct2.NodeAt(finalHeight-1, b("other"))
+	r.NoError(err)
+
+	changes, err := ct.nodeRepo.LoadChanges(b("other"))
+	r.NoError(err)
+	r.Len(changes, 2)
+	changes, err = ct.nodeRepo.LoadChanges(b("tester"))
+	r.NoError(err)
+	r.Len(changes, 6)
+
+	// Nothing is left to compact.
+	result, err = ct.Compact(finalHeight, nil)
+	r.NoError(err)
+	r.Zero(result.NamesCompacted)
+
+	// Both tries evolve identically afterwards, including takeovers and
+	// rollbacks above the final height.
+	for _, name := range []string{"other", "tester"} {
+		both(func(ct *ClaimTrie) error {
+			return ct.AddClaim(b(name), op(100), change.NewClaimID(op(100)), 50)
+		})
+	}
+	increment(20)
+	incrementBlock(r, ct, -30)
+	incrementBlock(r, ct2, -30)
+	r.Equal(*ct2.MerkleHash(), *ct.MerkleHash())
+	increment(40)
+}
+
+func TestProveClaim(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+
+	ct, err := New(cfg)
+	r.NoError(err)
+	defer ct.Close()
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	var outPoints []wire.OutPoint
+	names := []string{"test", "test", "test", "tester", "testing", "tea", "a", "other"}
+	for i, name := range names {
+		op := wire.OutPoint{Hash: hash, Index: uint32(i)}
+		r.NoError(ct.AddClaim(b(name), op, change.NewClaimID(op), int64(i+1)))
+		outPoints = append(outPoints, op)
+	}
+	incrementBlock(r, ct, 10)
+
+	_, err = ct.ProveClaim(b("test"), outPoints[0])
+	r.Error(err)
+
+	incrementBlock(r, ct, param.ActiveParams.AllClaimsInMerkleForkHeight)
+	root := ct.MerkleHash()
+	for i, name := range names {
+		proof, err := ct.ProveClaim(b(name), outPoints[i])
+		r.NoError(err)
+		r.Equal(root, proof.ClaimTrieRoot)
+		r.Equal(root, proof.Root())
+		r.Equal(ct.height, proof.Height)
+	}
+
+	// A claim can't be proven for another name, nor with another takeover
+	// height.
+	_, err = ct.ProveClaim(b("tester"), outPoints[0])
+	r.Error(err)
+	proof, err := ct.ProveClaim(b("test"), outPoints[1])
+	r.NoError(err)
+	proof.LastTakeoverHeight++
+	r.NotEqual(root, proof.Root())
+}
+
+func TestPebbleTuning(t *testing.T) {
+	r := require.New(t)
+
+	// Zero values keep the options of the repos.
+	opts := &pebble.Options{MemTableSize: 1 << 20}
+	config.PebbleTuning{}.Apply(opts)
+	r.Nil(opts.Cache)
+	r.Equal(1<<20, opts.MemTableSize)
+	r.Zero(opts.MaxConcurrentCompactions)
+	r.Empty(opts.Levels)
+
+	tuning := config.PebbleTuning{
+		CacheSize:                64 << 20,
+		MemTableSize:             8 << 20,
+		MaxConcurrentCompactions: 3,
+		BloomFilterBits:          10,
+	}
+	tuning.Apply(opts)
+	defer opts.Cache.Unref()
+	r.Equal(int64(64<<20), opts.Cache.MaxSize())
+	r.Equal(8<<20, opts.MemTableSize)
+	r.Equal(3, opts.MaxConcurrentCompactions)
+	r.NotEmpty(opts.Levels)
+	for _, level := range opts.Levels {
+		r.Equal(bloom.FilterPolicy(10), level.FilterPolicy)
+		r.Equal(pebble.TableFilter, level.FilterType)
+	}
+}
+
+func TestRepoMetrics(t *testing.T) {
+	r := require.New(t)
+	setup(t)
+
+	tunedCfg := cfg
+	tunedCfg.RamTrie = false
+	tunedCfg.NodeUndoDepth = 10
+	tunedCfg.PebbleTuning = config.PebbleTuning{
+		CacheSize:       16 << 20,
+		BloomFilterBits: 10,
+	}
+	ct, err := New(tunedCfg)
+	r.NoError(err)
+
+	hash := chainhash.HashH([]byte{1, 2, 3})
+	o1 := wire.OutPoint{Hash: hash, Index: 1}
+	r.NoError(ct.AddClaim([]byte("test"), o1, change.NewClaimID(o1), 18))
+	incrementBlock(r, ct, 1)
+	root := *ct.MerkleHash()
+	ct.Close()
+
+	// The tuned repos restore the claim trie, and report the metrics of
+	// each of their databases.
+	ct, err = New(tunedCfg)
+	r.NoError(err)
+	defer ct.Close()
+	r.Equal(int32(1), ct.Height())
+	r.Equal(root, *ct.MerkleHash())
+
+	metrics := ct.RepoMetrics()
+	var repos []string
+	for _, m := range metrics {
+		repos = append(repos, m.Repo)
+		r.NotNil(m.Metrics)
+	}
+	r.Equal([]string{"block", "temporal", "node", "nodeundo", "merkletrie"},
+		repos)
+	r.Positive(metrics[0].DiskSpaceUsage())
+
+	// The merkle trie has no repo when it's kept in memory.
+	ramCfg := cfg
+	ramCfg.DataDir = t.TempDir()
+	ramCfg.RamTrie = true
+	ramCfg.NodeUndoDepth = 0
+	ramCt, err := New(ramCfg)
+	r.NoError(err)
+	defer ramCt.Close()
+	repos = nil
+	for _, m := range ramCt.RepoMetrics() {
+		repos = append(repos, m.Repo)
+	}
+	r.Equal([]string{"block", "temporal", "node"}, repos)
+}
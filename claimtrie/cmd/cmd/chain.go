@@ -100,6 +100,7 @@ func NewChainReplayCommand() *cobra.Command {
 			for _, dbName := range []string{
 				cfg.BlockRepoPebble.Path,
 				cfg.NodeRepoPebble.Path,
+				cfg.NodeUndoRepoPebble.Path,
 				cfg.MerkleTrieRepoPebble.Path,
 				cfg.TemporalRepoPebble.Path,
 			} {
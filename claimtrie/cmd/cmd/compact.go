@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"math"
+
+	"github.com/lbryio/lbcd/claimtrie"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(NewCompactCommand())
+}
+
+func NewCompactCommand() *cobra.Command {
+
+	var height int32
+
+	cmd := &cobra.Command{
+		Use:   "compact",
+		Short: "Remove the obsolete changes below <height> and compact the node repo",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ct, err := openClaimTrie()
+			if err != nil {
+				return err
+			}
+			defer ct.Close()
+
+			if height == math.MaxInt32 {
+				height = ct.Height() - claimtrie.CompactRollbackDepth
+			}
+
+			result, err := ct.Compact(height, nil)
+			if err != nil {
+				return errors.Wrapf(err, "compact claimtrie")
+			}
+
+			log.Infof("Compacted the claimtrie at height %d below height %d: %d of %d names, %d changes removed",
+				result.Height, result.FinalHeight, result.NamesCompacted, result.NamesChecked, result.ChangesRemoved)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int32Var(&height, "height", math.MaxInt32, "Final height (default: 1000 blocks below the claimtrie height)")
+
+	return cmd
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/lbryio/lbcd/claimtrie"
+	"github.com/lbryio/lbcd/claimtrie/config"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(NewSnapshotExportCommand())
+	rootCmd.AddCommand(NewSnapshotImportCommand())
+}
+
+func openClaimTrie() (*claimtrie.ClaimTrie, error) {
+
+	cfg := config.DefaultConfig
+	cfg.RamTrie = true
+	cfg.DataDir = filepath.Join(dataDir, netName)
+
+	log.Debugf("Open claimtrie: %q", cfg.DataDir)
+	ct, err := claimtrie.New(cfg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create claimtrie")
+	}
+	return ct, nil
+}
+
+func NewSnapshotExportCommand() *cobra.Command {
+
+	var height int32
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the claimtrie state at <height> to a snapshot file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			ct, err := openClaimTrie()
+			if err != nil {
+				return err
+			}
+			defer ct.Close()
+
+			if height == math.MaxInt32 {
+				height = ct.Height()
+			}
+
+			f, err := os.Create(file)
+			if err != nil {
+				return errors.Wrapf(err, "create snapshot file")
+			}
+			defer f.Close()
+
+			header, err := ct.ExportSnapshot(f, height)
+			if err != nil {
+				return errors.Wrapf(err, "export snapshot")
+			}
+
+			log.Infof("Exported snapshot at height %d with root %s to %s",
+				header.Height, header.ClaimTrieRoot, file)
+			return errors.Wrapf(f.Sync(), "sync snapshot file")
+		},
+	}
+
+	cmd.Flags().Int32Var(&height, "height", math.MaxInt32, "Height (default: the claimtrie height)")
+	cmd.Flags().StringVar(&file, "file", "claimtrie.snapshot", "Snapshot file")
+	cmd.Flags().SortFlags = false
+
+	return cmd
+}
+
+func NewSnapshotImportCommand() *cobra.Command {
+
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Replace the claimtrie state with a snapshot file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			f, err := os.Open(file)
+			if err != nil {
+				return errors.Wrapf(err, "open snapshot file")
+			}
+			defer f.Close()
+
+			ct, err := openClaimTrie()
+			if err != nil {
+				return err
+			}
+			defer ct.Close()
+
+			header, err := ct.ImportSnapshot(f)
+			if err != nil {
+				return errors.Wrapf(err, "import snapshot")
+			}
+
+			log.Infof("Imported snapshot at height %d with root %s",
+				header.Height, header.ClaimTrieRoot)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "claimtrie.snapshot", "Snapshot file")
+
+	return cmd
+}
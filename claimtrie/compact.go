@@ -0,0 +1,102 @@
+package claimtrie
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/lbryio/lbcd/claimtrie/node"
+)
+
+// CompactRollbackDepth is the minimum number of blocks between the height of the
+// claim trie and the final height of a compaction.  The compacted names can't
+// be rolled back below the final height, so the claim trie can still be rolled
+// back at least that many blocks.
+const CompactRollbackDepth = 1000
+
+// CompactResult reports the outcome of a compaction of the node repo.
+type CompactResult struct {
+	// Height is the height of the claim trie that was compacted.
+	Height int32
+
+	// FinalHeight is the height below which the obsolete changes were
+	// removed.
+	FinalHeight int32
+
+	// NamesChecked is the number of names found in the node repo.
+	NamesChecked int
+
+	// NamesCompacted is the number of names whose changes were rewritten,
+	// and ChangesRemoved is the number of changes removed from them.
+	NamesCompacted int
+	ChangesRemoved int
+}
+
+// Compact removes the obsolete changes of every name below the final height,
+// which are the changes of the claims and supports spent before the last
+// takeover of the name, and then compacts the node repo.  This shrinks the repo
+// and speeds up the loading of the names which were updated many times.  See
+// node.BaseManager.CompactNode for details.
+//
+// The final height must be at least CompactRollbackDepth blocks below the
+// current height since the claim trie can't be rolled back below it afterwards.
+// The nodes can't be queried below it either, so NodeAt fails below the highest
+// final height of the compactions.
+// The names compacted before an interruption stay compacted.
+func (ct *ClaimTrie) Compact(finalHeight int32, interrupt <-chan struct{}) (*CompactResult, error) {
+
+	if finalHeight < 0 || finalHeight > ct.height-CompactRollbackDepth {
+		return nil, errors.Errorf("final height %d must be between 0 and %d, %d blocks below "+
+			"the claim trie height", finalHeight, ct.height-CompactRollbackDepth, CompactRollbackDepth)
+	}
+
+	result := &CompactResult{
+		Height:      ct.height,
+		FinalHeight: finalHeight,
+	}
+
+	// Historical queries below the final height are rejected from now on
+	// since the nodes rebuilt from the compacted changes are incomplete.
+	if finalHeight > ct.nodeManager.CompactedHeight() {
+		if err := ct.nodeManager.SetCompactedHeight(finalHeight); err != nil {
+			return nil, err
+		}
+	}
+
+	node.Log("Compacting the changes of the entire claim trie...")
+	ct.claimLogger = newClaimProgressLogger("Compacted", node.GetLogger())
+
+	var err error
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		if interruptRequested(interrupt) {
+			return false
+		}
+		clone := make([]byte, len(name))
+		copy(clone, name)
+
+		var removed int
+		removed, err = ct.nodeManager.CompactNode(clone, finalHeight)
+		if err != nil {
+			err = errors.Wrapf(err, "compact %s", clone)
+			return false
+		}
+		result.NamesChecked++
+		if removed > 0 {
+			result.NamesCompacted++
+			result.ChangesRemoved += removed
+		}
+		ct.claimLogger.LogName(name)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if interruptRequested(interrupt) {
+		return nil, errors.New("claim trie compaction interrupted")
+	}
+
+	if err = ct.nodeRepo.Compact(); err != nil {
+		return nil, errors.Wrap(err, "node repo compact")
+	}
+	node.Log("Compacted the claim trie")
+
+	return result, nil
+}
@@ -3,6 +3,10 @@ package config
 import (
 	"path/filepath"
 
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+
+	"github.com/lbryio/lbcd/claimtrie/node"
 	"github.com/lbryio/lbcd/claimtrie/param"
 	btcutil "github.com/lbryio/lbcutil"
 )
@@ -13,12 +17,18 @@ var DefaultConfig = Config{
 	DebugLevel: "info",
 	DataDir:    filepath.Join(btcutil.AppDataDir("lbcd", false), "data"),
 
+	NodeCacheBudget: node.DefaultCacheBudget,
+	NodeUndoDepth:   node.DefaultUndoDepth,
+
 	BlockRepoPebble: pebbleConfig{
 		Path: "blocks_pebble_db",
 	},
 	NodeRepoPebble: pebbleConfig{
 		Path: "node_change_pebble_db",
 	},
+	NodeUndoRepoPebble: pebbleConfig{
+		Path: "node_undo_pebble_db",
+	},
 	TemporalRepoPebble: pebbleConfig{
 		Path: "temporal_pebble_db",
 	},
@@ -34,14 +44,73 @@ type Config struct {
 	DataDir    string
 	DebugLevel string
 
+	// NodeCacheBudget is the approximate number of bytes of memory used to
+	// cache the claim trie nodes.
+	NodeCacheBudget int64
+
+	// NodeUndoDepth is the number of blocks whose undo records are kept to
+	// restore the nodes they changed on rollbacks.  No records are kept
+	// when it is zero.
+	NodeUndoDepth int32
+
+	// NodeUndoStartHeight is the height of the first block with an undo
+	// record.  The blocks below it, such as the ones up to the last
+	// checkpoint which are never rolled back, are connected without the
+	// cost of saving their records.
+	NodeUndoStartHeight int32
+
 	BlockRepoPebble      pebbleConfig
 	NodeRepoPebble       pebbleConfig
+	NodeUndoRepoPebble   pebbleConfig
 	TemporalRepoPebble   pebbleConfig
 	MerkleTrieRepoPebble pebbleConfig
 
+	// PebbleTuning overrides the options of the pebble databases of all the
+	// repos.
+	PebbleTuning PebbleTuning
+
 	Interrupt <-chan struct{}
 }
 
 type pebbleConfig struct {
 	Path string
 }
+
+// PebbleTuning holds the tuning options of the pebble databases of the repos.
+// Zero values keep the defaults of each repo.
+type PebbleTuning struct {
+	// CacheSize is the size in bytes of the block cache of each database.
+	CacheSize int64
+
+	// MemTableSize is the size in bytes of each memtable.
+	MemTableSize int
+
+	// MaxConcurrentCompactions is the maximum number of concurrent
+	// compactions of each database.
+	MaxConcurrentCompactions int
+
+	// BloomFilterBits is the number of bits per key of the bloom filters
+	// of the tables.  Tables written before it's set have no filters.
+	BloomFilterBits int
+}
+
+// Apply overrides the passed options of a pebble database with the non-zero
+// tuning options.
+func (t PebbleTuning) Apply(opts *pebble.Options) {
+	if t.CacheSize > 0 {
+		opts.Cache = pebble.NewCache(t.CacheSize)
+	}
+	if t.MemTableSize > 0 {
+		opts.MemTableSize = t.MemTableSize
+	}
+	if t.MaxConcurrentCompactions > 0 {
+		opts.MaxConcurrentCompactions = t.MaxConcurrentCompactions
+	}
+	if t.BloomFilterBits > 0 {
+		opts.EnsureDefaults()
+		for i := range opts.Levels {
+			opts.Levels[i].FilterPolicy = bloom.FilterPolicy(t.BloomFilterBits)
+			opts.Levels[i].FilterType = pebble.TableFilter
+		}
+	}
+}
@@ -75,6 +75,26 @@ func (t *PersistentTrie) Update(name []byte, hash *chainhash.Hash, restoreChildr
 	n.claimsHash = hash
 }
 
+// ClaimHash returns the claims hash stored for the name, or nil if there is none.
+func (t *PersistentTrie) ClaimHash(name []byte) *chainhash.Hash {
+
+	n := t.root
+	for i, ch := range name {
+		if len(n.childLinks) == 0 {
+			t.resolveChildLinks(n, name[:i])
+		}
+		n = n.childLinks[ch]
+		if n == nil {
+			return nil
+		}
+	}
+
+	if len(n.childLinks) == 0 {
+		t.resolveChildLinks(n, name)
+	}
+	return n.claimsHash
+}
+
 // resolveChildLinks updates the links on n
 func (t *PersistentTrie) resolveChildLinks(n *vertex, key []byte) {
 
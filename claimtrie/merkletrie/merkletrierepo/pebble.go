@@ -11,7 +11,7 @@ type Pebble struct {
 	db *pebble.DB
 }
 
-func NewPebble(path string) (*Pebble, error) {
+func NewPebble(path string, configure ...func(*pebble.Options)) (*Pebble, error) {
 
 	cache := pebble.NewCache(512 << 20)
 	//defer cache.Unref()
@@ -31,7 +31,11 @@ func NewPebble(path string) (*Pebble, error) {
 	//	}
 	//}()
 
-	db, err := pebble.Open(path, &pebble.Options{Cache: cache, BytesPerSync: 32 << 20, MaxOpenFiles: 2000})
+	opts := &pebble.Options{Cache: cache, BytesPerSync: 32 << 20, MaxOpenFiles: 2000}
+	for _, f := range configure {
+		f(opts)
+	}
+	db, err := pebble.Open(path, opts)
 	repo := &Pebble{db: db}
 
 	return repo, errors.Wrapf(err, "unable to open %s", path)
@@ -49,6 +53,11 @@ func (repo *Pebble) Set(key, value []byte) error {
 	return repo.db.Set(key, value, pebble.NoSync)
 }
 
+// Metrics returns the internal metrics of the database.
+func (repo *Pebble) Metrics() *pebble.Metrics {
+	return repo.db.Metrics()
+}
+
 func (repo *Pebble) Close() error {
 
 	err := repo.db.Flush()
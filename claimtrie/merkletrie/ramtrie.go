@@ -13,6 +13,7 @@ import (
 type MerkleTrie interface {
 	SetRoot(h *chainhash.Hash) error
 	Update(name []byte, h *chainhash.Hash, restoreChildren bool)
+	ClaimHash(name []byte) *chainhash.Hash
 	MerkleHash() *chainhash.Hash
 	MerkleHashAllClaims() *chainhash.Hash
 	Flush() error
@@ -55,6 +56,13 @@ func (rt *RamTrie) Update(name []byte, h *chainhash.Hash, _ bool) {
 	}
 }
 
+func (rt *RamTrie) ClaimHash(name []byte) *chainhash.Hash {
+	if n := rt.Find(name); n != nil {
+		return n.claimHash
+	}
+	return nil
+}
+
 func (rt *RamTrie) MerkleHash() *chainhash.Hash {
 	if h := rt.merkleHash(rt.Root); h == nil {
 		return EmptyTrieHash
@@ -134,6 +142,49 @@ func (rt *RamTrie) merkleHashAllClaims(v *collapsedVertex) *chainhash.Hash {
 	return v.merkleHash
 }
 
+// ProofAllClaims returns the pairs proving the claims hash of the name is part
+// of the merkle hash computed by MerkleHashAllClaims, from the node of the name
+// up to the root.  The merkle hash must have been computed since the last
+// update.  It returns false when the name has no claims hash.
+func (rt *RamTrie) ProofAllClaims(name []byte) ([]node.ProofPair, bool) {
+
+	pathIndexes, path := rt.FindPath(name)
+	if len(path) == 0 || path[len(path)-1].claimHash == nil {
+		return nil, false
+	}
+
+	var pairs []node.ProofPair
+	for i := len(path) - 1; i >= 0; i-- {
+		v := path[i]
+		childHashes := make([]*chainhash.Hash, 0, len(v.children))
+		for _, ch := range v.children {
+			if ch.merkleHash == nil {
+				return nil, false
+			}
+			childHashes = append(childHashes, ch.merkleHash)
+		}
+
+		// The claims hash of the name is on the right of its node hash,
+		// while the hashes of the children of the vertices above it are
+		// on the left.
+		if i == len(path)-1 {
+			childHash := NoChildrenHash
+			if len(childHashes) > 0 {
+				childHash = node.ComputeMerkleRoot(childHashes)
+			}
+			pairs = append(pairs, node.ProofPair{Odd: true, Hash: childHash})
+			continue
+		}
+		pairs = append(pairs, node.ComputeMerklePath(childHashes, pathIndexes[i+1])...)
+		claimHash := NoClaimsHash
+		if v.claimHash != nil {
+			claimHash = v.claimHash
+		}
+		pairs = append(pairs, node.ProofPair{Hash: claimHash})
+	}
+	return pairs, true
+}
+
 func (rt *RamTrie) Flush() error {
 	return nil
 }
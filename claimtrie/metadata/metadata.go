@@ -0,0 +1,357 @@
+// Package metadata decodes the metadata LBRY applications store in the values
+// of claims, which is serialized with protocol buffers.
+//
+// Only the fields of general interest are decoded, which are described by the
+// claim.proto, stream.proto and channel.proto schemas of the LBRY types
+// repository.  Unknown fields are skipped so newer revisions of the schemas
+// can still be decoded.
+package metadata
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/lbryio/lbcd/claimtrie/change"
+)
+
+const (
+	// unsignedPrefix is the first byte of the values of claims which
+	// aren't signed by a channel.
+	unsignedPrefix = 0x00
+
+	// signedPrefix is the first byte of the values of claims which are
+	// signed by a channel.  The prefix is followed by the claim ID of the
+	// channel and the signature before the serialized claim.
+	signedPrefix = 0x01
+
+	// signatureSize is the size of the signature of signed claims.
+	signatureSize = 64
+)
+
+var (
+	// ErrLegacy is returned when decoding the value of a claim which uses
+	// one of the legacy formats, which predate the current schemas.
+	ErrLegacy = errors.New("legacy claim value format")
+
+	// ErrTruncated is returned when the value of a claim ends in the
+	// middle of its signature or of a field.
+	ErrTruncated = errors.New("truncated claim value")
+)
+
+// Type is the type of content a claim is about.
+type Type string
+
+// These constants define the types of claims.
+const (
+	TypeUnknown    Type = ""
+	TypeStream     Type = "stream"
+	TypeChannel    Type = "channel"
+	TypeCollection Type = "collection"
+	TypeRepost     Type = "repost"
+)
+
+// Claim is the metadata stored in the value of a claim.
+type Claim struct {
+	Type Type
+
+	// SigningChannelID is the claim ID of the channel which signed the
+	// claim, if any.  The signature itself isn't verified.
+	SigningChannelID *change.ClaimID
+
+	Title       string
+	Description string
+	Thumbnail   string
+	Tags        []string
+
+	// Only one of the following is set depending on the type of claim.
+	Stream     *Stream
+	Channel    *Channel
+	Collection []change.ClaimID
+	Repost     *change.ClaimID
+}
+
+// Stream is the metadata of a claim about a file.
+type Stream struct {
+	MediaType   string
+	FileName    string
+	FileSize    uint64
+	Author      string
+	License     string
+	LicenseURL  string
+	ReleaseTime int64
+	Fee         *Fee
+}
+
+// Fee is the price asked to download the file of a stream.
+type Fee struct {
+	// Currency is one of LBC, BTC or USD.
+	Currency string
+	Address  []byte
+
+	// Amount is expressed in dewies for LBC, in satoshis for BTC and in
+	// cents for USD.
+	Amount uint64
+}
+
+// Channel is the metadata of a claim about a channel.
+type Channel struct {
+	PublicKey  []byte
+	Email      string
+	WebsiteURL string
+	Cover      string
+}
+
+// feeCurrencies maps the values of the currency enum of fees to their names.
+var feeCurrencies = map[uint64]string{
+	1: "LBC",
+	2: "BTC",
+	3: "USD",
+}
+
+// Decode decodes the metadata stored in the passed value of a claim.
+func Decode(value []byte) (*Claim, error) {
+	if len(value) == 0 {
+		return nil, ErrLegacy
+	}
+
+	var claim Claim
+	switch value[0] {
+	case unsignedPrefix:
+		value = value[1:]
+
+	case signedPrefix:
+		if len(value) < 1+change.ClaimIDSize+signatureSize {
+			return nil, ErrTruncated
+		}
+		id := claimIDFromHash(value[1 : 1+change.ClaimIDSize])
+		claim.SigningChannelID = &id
+		value = value[1+change.ClaimIDSize+signatureSize:]
+
+	default:
+		return nil, ErrLegacy
+	}
+
+	err := decodeFields(value, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		var err error
+		switch num {
+		case 1:
+			claim.Type = TypeStream
+			claim.Stream, err = decodeStream(b)
+		case 2:
+			claim.Type = TypeChannel
+			claim.Channel, err = decodeChannel(b)
+		case 3:
+			claim.Type = TypeCollection
+			claim.Collection, err = decodeClaimList(b)
+		case 4:
+			claim.Type = TypeRepost
+			var id change.ClaimID
+			id, err = decodeClaimReference(b)
+			claim.Repost = &id
+		case 8:
+			claim.Title = string(b)
+		case 9:
+			claim.Description = string(b)
+		case 10:
+			claim.Thumbnail, err = decodeSourceURL(b)
+		case 11:
+			claim.Tags = append(claim.Tags, string(b))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// claimIDFromHash returns the claim ID of the passed claim hash, which is how
+// claim IDs are serialized in claim values.
+func claimIDFromHash(hash []byte) change.ClaimID {
+	var id change.ClaimID
+	copy(id[:], hash)
+	return id
+}
+
+// decodeStream decodes the serialized Stream message.
+func decodeStream(value []byte) (*Stream, error) {
+	var stream Stream
+	err := decodeFields(value, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		var err error
+		switch num {
+		case 1:
+			err = decodeSource(b, &stream)
+		case 2:
+			stream.Author = string(b)
+		case 3:
+			stream.License = string(b)
+		case 4:
+			stream.LicenseURL = string(b)
+		case 5:
+			stream.ReleaseTime, err = decodeInt64(typ, b)
+		case 6:
+			stream.Fee, err = decodeFee(b)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stream, nil
+}
+
+// decodeSource decodes the serialized Source message describing the file of a
+// stream into the passed stream.
+func decodeSource(value []byte, stream *Stream) error {
+	return decodeFields(value, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		var err error
+		switch num {
+		case 2:
+			stream.FileName = string(b)
+		case 3:
+			stream.FileSize, err = decodeUint64(typ, b)
+		case 4:
+			stream.MediaType = string(b)
+		}
+		return err
+	})
+}
+
+// decodeSourceURL decodes the URL of the serialized Source message describing
+// an image.
+func decodeSourceURL(value []byte) (string, error) {
+	var url string
+	err := decodeFields(value, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		if num == 5 {
+			url = string(b)
+		}
+		return nil
+	})
+	return url, err
+}
+
+// decodeFee decodes the serialized Fee message.
+func decodeFee(value []byte) (*Fee, error) {
+	var fee Fee
+	err := decodeFields(value, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		var err error
+		switch num {
+		case 1:
+			var currency uint64
+			currency, err = decodeUint64(typ, b)
+			fee.Currency = feeCurrencies[currency]
+		case 2:
+			fee.Address = append([]byte(nil), b...)
+		case 3:
+			fee.Amount, err = decodeUint64(typ, b)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &fee, nil
+}
+
+// decodeChannel decodes the serialized Channel message.
+func decodeChannel(value []byte) (*Channel, error) {
+	var channel Channel
+	err := decodeFields(value, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		var err error
+		switch num {
+		case 1:
+			channel.PublicKey = append([]byte(nil), b...)
+		case 2:
+			channel.Email = string(b)
+		case 3:
+			channel.WebsiteURL = string(b)
+		case 4:
+			channel.Cover, err = decodeSourceURL(b)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// decodeClaimList decodes the claim IDs referenced by the serialized ClaimList
+// message of a collection.
+func decodeClaimList(value []byte) ([]change.ClaimID, error) {
+	var ids []change.ClaimID
+	err := decodeFields(value, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		if num != 2 {
+			return nil
+		}
+		id, err := decodeClaimReference(b)
+		ids = append(ids, id)
+		return err
+	})
+	return ids, err
+}
+
+// decodeClaimReference decodes the claim ID referenced by the serialized
+// ClaimReference message.
+func decodeClaimReference(value []byte) (change.ClaimID, error) {
+	var id change.ClaimID
+	err := decodeFields(value, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		if num == 1 {
+			id = claimIDFromHash(b)
+		}
+		return nil
+	})
+	return id, err
+}
+
+// decodeUint64 decodes the value of a varint field.
+func decodeUint64(typ protowire.Type, b []byte) (uint64, error) {
+	if typ != protowire.VarintType {
+		return 0, fmt.Errorf("unexpected wire type %d of varint field", typ)
+	}
+	v, _ := protowire.ConsumeVarint(b)
+	return v, nil
+}
+
+// decodeInt64 decodes the value of a signed varint field which isn't zigzag
+// encoded.
+func decodeInt64(typ protowire.Type, b []byte) (int64, error) {
+	v, err := decodeUint64(typ, b)
+	return int64(v), err
+}
+
+// decodeFields decodes the fields of the passed serialized message, calling
+// the passed function with the number, the wire type and the value of each
+// field.  The value is the payload of length-delimited fields and the raw
+// encoded value of the other fields.
+func decodeFields(value []byte, f func(num protowire.Number, typ protowire.Type, b []byte) error) error {
+	for len(value) > 0 {
+		num, typ, n := protowire.ConsumeTag(value)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrTruncated, protowire.ParseError(n))
+		}
+		value = value[n:]
+
+		var b []byte
+		switch typ {
+		case protowire.BytesType:
+			b, n = protowire.ConsumeBytes(value)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, value)
+			if n >= 0 {
+				b = value[:n]
+			}
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrTruncated, protowire.ParseError(n))
+		}
+		value = value[n:]
+
+		if err := f(num, typ, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
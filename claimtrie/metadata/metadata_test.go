@@ -0,0 +1,129 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/wire"
+)
+
+// appendMessage appends the passed serialized message as the field of the
+// passed number.
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// appendString appends the passed string as the field of the passed number.
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// appendVarint appends the passed varint as the field of the passed number.
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func TestDecodeStream(t *testing.T) {
+	r := require.New(t)
+
+	var source []byte
+	source = appendVarint(source, 3, 1234)
+	source = appendString(source, 4, "video/mp4")
+	source = appendString(source, 2, "movie.mp4")
+
+	var fee []byte
+	fee = appendVarint(fee, 1, 3)
+	fee = appendVarint(fee, 3, 150)
+
+	var stream []byte
+	stream = appendMessage(stream, 1, source)
+	stream = appendString(stream, 2, "author")
+	stream = appendString(stream, 3, "CC-BY")
+	stream = appendVarint(stream, 5, 1600000000)
+	stream = appendMessage(stream, 6, fee)
+	stream = appendVarint(stream, 99, 7) // unknown field
+
+	var thumbnail []byte
+	thumbnail = appendString(thumbnail, 5, "https://example.com/t.jpg")
+
+	var claim []byte
+	claim = appendMessage(claim, 1, stream)
+	claim = appendString(claim, 8, "title")
+	claim = appendMessage(claim, 10, thumbnail)
+	claim = appendString(claim, 11, "tag1")
+	claim = appendString(claim, 11, "tag2")
+
+	m, err := Decode(append([]byte{unsignedPrefix}, claim...))
+	r.NoError(err)
+	r.Equal(TypeStream, m.Type)
+	r.Nil(m.SigningChannelID)
+	r.Equal("title", m.Title)
+	r.Equal("https://example.com/t.jpg", m.Thumbnail)
+	r.Equal([]string{"tag1", "tag2"}, m.Tags)
+	r.Equal(&Stream{
+		MediaType:   "video/mp4",
+		FileName:    "movie.mp4",
+		FileSize:    1234,
+		Author:      "author",
+		License:     "CC-BY",
+		ReleaseTime: 1600000000,
+		Fee:         &Fee{Currency: "USD", Amount: 150},
+	}, m.Stream)
+
+	// The claim ID of the signing channel precedes the signature.
+	channelID, err := change.NewIDFromString("beef" + strings.Repeat("00", 18))
+	r.NoError(err)
+	signed := []byte{signedPrefix}
+	signed = append(signed, channelID[:]...)
+	signed = append(signed, make([]byte, signatureSize)...)
+	signed = append(signed, claim...)
+
+	m, err = Decode(signed)
+	r.NoError(err)
+	r.Equal(channelID, *m.SigningChannelID)
+	r.Equal("title", m.Title)
+}
+
+func TestDecodeChannelAndRepost(t *testing.T) {
+	r := require.New(t)
+
+	var channel []byte
+	channel = appendMessage(channel, 1, []byte{1, 2, 3})
+	channel = appendString(channel, 3, "https://example.com")
+
+	m, err := Decode(append([]byte{unsignedPrefix}, appendMessage(nil, 2, channel)...))
+	r.NoError(err)
+	r.Equal(TypeChannel, m.Type)
+	r.Equal(&Channel{PublicKey: []byte{1, 2, 3}, WebsiteURL: "https://example.com"}, m.Channel)
+
+	id := change.NewClaimID(wire.OutPoint{Index: 1})
+	reference := appendMessage(nil, 1, id[:])
+	m, err = Decode(append([]byte{unsignedPrefix}, appendMessage(nil, 4, reference)...))
+	r.NoError(err)
+	r.Equal(TypeRepost, m.Type)
+	r.Equal(id, *m.Repost)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	r := require.New(t)
+
+	_, err := Decode(nil)
+	r.ErrorIs(err, ErrLegacy)
+
+	_, err = Decode([]byte(`{"ver": "0.0.3"}`))
+	r.ErrorIs(err, ErrLegacy)
+
+	_, err = Decode([]byte{signedPrefix, 1, 2})
+	r.ErrorIs(err, ErrTruncated)
+
+	claim := appendString(nil, 8, "title")
+	_, err = Decode(append([]byte{unsignedPrefix}, claim[:len(claim)-1]...))
+	r.ErrorIs(err, ErrTruncated)
+}
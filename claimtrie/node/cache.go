@@ -2,72 +2,178 @@ package node
 
 import (
 	"container/list"
+	"sync"
+	"unsafe"
 
 	"github.com/lbryio/lbcd/claimtrie/change"
 )
 
+// DefaultCacheBudget is the default approximate number of bytes of memory used
+// by the cached nodes of a BaseManager.
+const DefaultCacheBudget = 128 << 20
+
+const (
+	// leafOverhead approximates the memory used by a cache entry besides
+	// its node, name and changes: the leaf, its list element and its map
+	// entry.
+	leafOverhead = int64(unsafe.Sizeof(cacheLeaf{}) + unsafe.Sizeof(list.Element{}) + 64)
+
+	// nodeOverhead approximates the memory used by a node besides its
+	// claims and support sums.
+	nodeOverhead = int64(unsafe.Sizeof(Node{}) + 48)
+
+	// claimSize approximates the memory used by a claim and its pointer in
+	// a claim list.
+	claimSize = int64(unsafe.Sizeof(Claim{}) + unsafe.Sizeof(&Claim{}))
+
+	// supportSumSize approximates the memory used by an entry of the
+	// support sums of a node besides its key.
+	supportSumSize = int64(unsafe.Sizeof("") + unsafe.Sizeof(int64(0)) + 16)
+
+	// changeSize approximates the memory used by a change besides its name.
+	changeSize = int64(unsafe.Sizeof(change.Change{}))
+)
+
 type cacheLeaf struct {
 	node    *Node
 	element *list.Element
 	changes []change.Change
 	height  int32
+	size    int64
+}
+
+// CacheStats describes the content and the effectiveness of a node cache.
+type CacheStats struct {
+	Entries   int    // Number of cached nodes.
+	Size      int64  // Approximate bytes of memory used by the cached nodes.
+	Budget    int64  // Approximate bytes of memory the cached nodes may use.
+	Hits      uint64 // Number of lookups served from the cache.
+	Misses    uint64 // Number of lookups that had to load the node changes.
+	Evictions uint64 // Number of nodes evicted to stay within the budget.
 }
 
+// Cache holds recently used nodes, evicting the least recently used ones when
+// their approximate size exceeds the memory budget.  Evicted nodes are rebuilt
+// from their changes in the node repo on their next lookup.
+//
+// The cache is safe for concurrent access.
 type Cache struct {
-	nodes map[string]*cacheLeaf
-	order *list.List
-	limit int
+	mtx    sync.Mutex
+	nodes  map[string]*cacheLeaf
+	order  *list.List
+	budget int64
+	size   int64
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// nodeSize returns the approximate memory used by a cache entry for the passed
+// name, node and changes.
+func nodeSize(key string, n *Node, changes []change.Change) int64 {
+	size := leafOverhead + int64(len(key))
+	if n != nil {
+		size += nodeOverhead
+		size += int64(len(n.Claims)+len(n.Supports)) * claimSize
+		for id := range n.SupportSums {
+			size += supportSumSize + int64(len(id))
+		}
+	}
+	for _, c := range changes {
+		size += changeSize + int64(len(c.Name))
+	}
+	return size
+}
+
+// evict removes the least recently used nodes until the cached nodes fit in the
+// budget along with the passed number of additional bytes.
+//
+// This function MUST be called with the cache lock held.
+func (nc *Cache) evict(extra int64) {
+	for nc.order.Len() > 0 && nc.size+extra > nc.budget {
+		// TODO: maybe ensure that we don't remove nodes that have a lot of changes?
+		key := nc.order.Back().Value.(string)
+		nc.size -= nc.nodes[key].size
+		delete(nc.nodes, key)
+		nc.order.Remove(nc.order.Back())
+		nc.evictions++
+	}
 }
 
 func (nc *Cache) insert(name []byte, n *Node, height int32) {
 	key := string(name)
+	size := nodeSize(key, n, nil)
+
+	nc.mtx.Lock()
+	defer nc.mtx.Unlock()
 
 	existing := nc.nodes[key]
 	if existing != nil {
+		nc.size += size - existing.size
 		existing.node = n
 		existing.height = height
 		existing.changes = nil
+		existing.size = size
 		nc.order.MoveToFront(existing.element)
+		nc.evict(0)
 		return
 	}
 
-	for nc.order.Len() >= nc.limit {
-		// TODO: maybe ensure that we don't remove nodes that have a lot of changes?
-		delete(nc.nodes, nc.order.Back().Value.(string))
-		nc.order.Remove(nc.order.Back())
+	// Nodes which don't fit in the budget on their own aren't cached.
+	if size > nc.budget {
+		return
 	}
+	nc.evict(size)
 
 	element := nc.order.PushFront(key)
-	nc.nodes[key] = &cacheLeaf{node: n, element: element, height: height}
+	nc.nodes[key] = &cacheLeaf{node: n, element: element, height: height, size: size}
+	nc.size += size
 }
 
 func (nc *Cache) fetch(name []byte, height int32) (*Node, []change.Change, int32) {
 	key := string(name)
 
+	nc.mtx.Lock()
+	defer nc.mtx.Unlock()
+
 	existing := nc.nodes[key]
 	if existing != nil && existing.height <= height {
+		nc.hits++
 		nc.order.MoveToFront(existing.element)
 		return existing.node, existing.changes, existing.height
 	}
+	nc.misses++
 	return nil, nil, -1
 }
 
 func (nc *Cache) addChanges(changes []change.Change, height int32) {
+	nc.mtx.Lock()
+	defer nc.mtx.Unlock()
+
 	for _, c := range changes {
 		key := string(c.Name)
 		existing := nc.nodes[key]
 		if existing != nil && existing.height <= height {
 			existing.changes = append(existing.changes, c)
+			size := changeSize + int64(len(c.Name))
+			existing.size += size
+			nc.size += size
 		}
 	}
+	nc.evict(0)
 }
 
 func (nc *Cache) drop(names [][]byte) {
+	nc.mtx.Lock()
+	defer nc.mtx.Unlock()
+
 	for _, name := range names {
 		key := string(name)
 		existing := nc.nodes[key]
 		if existing != nil {
 			// we can't roll it backwards because we don't know its previous height value; just toast it
+			nc.size -= existing.size
 			delete(nc.nodes, key)
 			nc.order.Remove(existing.element)
 		}
@@ -75,11 +181,42 @@ func (nc *Cache) drop(names [][]byte) {
 }
 
 func (nc *Cache) clear() {
+	nc.mtx.Lock()
+	defer nc.mtx.Unlock()
+
 	nc.nodes = map[string]*cacheLeaf{}
 	nc.order = list.New()
+	nc.size = 0
 	// we'll let the GC sort out the remains...
 }
 
-func NewCache(limit int) *Cache {
-	return &Cache{limit: limit, nodes: map[string]*cacheLeaf{}, order: list.New()}
+// SetBudget changes the approximate number of bytes of memory the cached nodes
+// may use, evicting nodes as needed.
+func (nc *Cache) SetBudget(budget int64) {
+	nc.mtx.Lock()
+	defer nc.mtx.Unlock()
+
+	nc.budget = budget
+	nc.evict(0)
+}
+
+// Stats returns the current statistics of the cache.
+func (nc *Cache) Stats() CacheStats {
+	nc.mtx.Lock()
+	defer nc.mtx.Unlock()
+
+	return CacheStats{
+		Entries:   len(nc.nodes),
+		Size:      nc.size,
+		Budget:    nc.budget,
+		Hits:      nc.hits,
+		Misses:    nc.misses,
+		Evictions: nc.evictions,
+	}
+}
+
+// NewCache returns a cache whose nodes use approximately at most budget bytes
+// of memory.
+func NewCache(budget int64) *Cache {
+	return &Cache{budget: budget, nodes: map[string]*cacheLeaf{}, order: list.New()}
 }
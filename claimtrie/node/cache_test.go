@@ -0,0 +1,51 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheBudget(t *testing.T) {
+
+	r := require.New(t)
+
+	entrySize := nodeSize("name1", New(), nil)
+	c := NewCache(2 * entrySize)
+
+	c.insert([]byte("name1"), New(), 1)
+	c.insert([]byte("name2"), New(), 1)
+
+	// touch name1 so name2 is the least recently used
+	n, _, _ := c.fetch([]byte("name1"), 1)
+	r.NotNil(n)
+
+	c.insert([]byte("name3"), New(), 1)
+	n, _, _ = c.fetch([]byte("name2"), 1)
+	r.Nil(n)
+	n, _, _ = c.fetch([]byte("name1"), 1)
+	r.NotNil(n)
+
+	stats := c.Stats()
+	r.Equal(2, stats.Entries)
+	r.Equal(2*entrySize, stats.Size)
+	r.Equal(uint64(2), stats.Hits)
+	r.Equal(uint64(1), stats.Misses)
+	r.Equal(uint64(1), stats.Evictions)
+
+	// shrinking the budget evicts right away
+	c.SetBudget(entrySize)
+	stats = c.Stats()
+	r.Equal(1, stats.Entries)
+	r.Equal(entrySize, stats.Size)
+
+	// nodes larger than the budget are not cached
+	big := New()
+	big.Claims = append(big.Claims, &Claim{}, &Claim{})
+	c.insert([]byte("name4"), big, 1)
+	n, _, _ = c.fetch([]byte("name4"), 1)
+	r.Nil(n)
+
+	c.drop([][]byte{[]byte("name1")})
+	r.Equal(int64(0), c.Stats().Size)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
 	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/claimtrie/param"
 	"github.com/lbryio/lbcd/wire"
 )
 
@@ -57,6 +58,15 @@ func (c *Claim) setStatus(status Status) *Claim {
 	return c
 }
 
+// ExpireAt returns the height at which the claim or support expires.
+func (c *Claim) ExpireAt() int32 {
+	ot := param.ActiveParams.OriginalClaimExpirationTime
+	if c.AcceptedAt+ot > param.ActiveParams.ExtendedClaimExpirationForkHeight {
+		return c.AcceptedAt + param.ActiveParams.ExtendedClaimExpirationTime
+	}
+	return c.AcceptedAt + ot
+}
+
 func OutPointLess(a, b wire.OutPoint) bool {
 
 	switch cmp := bytes.Compare(a.Hash[:], b.Hash[:]); {
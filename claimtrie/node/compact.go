@@ -0,0 +1,242 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/claimtrie/param"
+	"github.com/lbryio/lbcd/wire"
+)
+
+// maxCompactChildChecks is the maximum number of heights at which the changes
+// of the parent names of a name check whether it has an active best claim for
+// the name to be compacted.  Each check replays the changes of the name twice.
+const maxCompactChildChecks = 1000
+
+// CompactNode removes the obsolete changes of the name below the final height,
+// which are the changes of the claims and supports spent before the last
+// takeover of the node at the final height.  The changes are only rewritten
+// when the node rebuilt from the remaining ones has the same state at the last
+// height they reach below the final height, and has an active best claim at the
+// same heights whenever the parent names check for active children, so the
+// node evolves the same way above that height.  The claims are only numbered
+// differently in their sequence.
+//
+// The node can't be rolled back below the final height once compacted.  It
+// returns the number of changes removed.
+func (nm *BaseManager) CompactNode(name []byte, finalHeight int32) (int, error) {
+
+	if nm.tempChanges != nil {
+		return 0, errors.New("unable to compact with temporary changes")
+	}
+	if finalHeight > nm.height {
+		return 0, errors.Errorf("final height %d is above the current height %d",
+			finalHeight, nm.height)
+	}
+
+	changes, err := nm.repo.LoadChanges(name)
+	if err != nil {
+		return 0, errors.Wrap(err, "in load changes")
+	}
+	final := 0
+	for final < len(changes) && changes[final].Height <= finalHeight {
+		final++
+	}
+	if final < 2 {
+		return 0, nil
+	}
+	last := changes[final-1].Height
+
+	n, err := nm.newNodeFromChanges(changes[:final], last)
+	if err != nil {
+		return 0, errors.Wrap(err, "in new node")
+	}
+	if n == nil || !n.HasActiveBestClaim() {
+		return 0, nil
+	}
+
+	// The obsolete changes are all below the last takeover, so the changes
+	// at the last height are kept.
+	obsolete := obsoleteChanges(changes[:final], n)
+	if len(obsolete) == 0 {
+		return 0, nil
+	}
+	compacted := make([]change.Change, 0, len(changes)-len(obsolete))
+	for i := range changes {
+		if !obsolete[i] {
+			compacted = append(compacted, changes[i])
+		}
+	}
+
+	cn, err := nm.newNodeFromChanges(compacted[:final-len(obsolete)], last)
+	if err != nil {
+		return 0, errors.Wrap(err, "in new compacted node")
+	}
+	if cn == nil || !equivalentNodes(n, cn) {
+		return 0, nil
+	}
+	same, err := nm.sameActiveBestClaims(name, changes, compacted, last)
+	if err != nil || !same {
+		return 0, err
+	}
+
+	if err = nm.repo.SetChanges(name, compacted); err != nil {
+		return 0, errors.Wrap(err, "in set changes")
+	}
+	nm.cache.drop([][]byte{name})
+	return len(obsolete), nil
+}
+
+// CompactedHeight returns the final height of the compactions of the repo,
+// below which the nodes can't be rebuilt and NodeAt fails, or 0 when the repo
+// was never compacted.
+func (nm *BaseManager) CompactedHeight() int32 {
+	return nm.compactedHeight
+}
+
+// SetCompactedHeight stores the final height of the compactions of the repo.
+// It's set before the nodes are compacted, so the nodes compacted before an
+// interruption are covered too.
+func (nm *BaseManager) SetCompactedHeight(height int32) error {
+	if err := nm.repo.SetCompactedHeight(height); err != nil {
+		return errors.Wrap(err, "in set compacted height")
+	}
+	nm.compactedHeight = height
+	return nil
+}
+
+// obsoleteChanges returns the indexes of the changes of the claims and supports
+// spent before the last takeover of the node built from the changes.  The
+// changes of a claim are obsolete once it's spent without being updated, except
+// for the best claim.
+func obsoleteChanges(changes []change.Change, n *Node) map[int]bool {
+
+	obsolete := map[int]bool{}
+	added := map[wire.OutPoint]int{}
+	spent := map[change.ClaimID]bool{}
+	for i := range changes {
+		chg := &changes[i]
+		switch chg.Type {
+		case change.AddSupport:
+			added[chg.OutPoint] = i
+		case change.SpendSupport:
+			if j, ok := added[chg.OutPoint]; ok && chg.Height < n.TakenOverAt {
+				obsolete[i] = true
+				obsolete[j] = true
+			}
+			delete(added, chg.OutPoint)
+		case change.AddClaim, change.UpdateClaim:
+			spent[chg.ClaimID] = false
+		case change.SpendClaim:
+			spent[chg.ClaimID] = chg.Height < n.TakenOverAt
+		}
+	}
+
+	delete(spent, n.BestClaim.ClaimID)
+	for i := range changes {
+		switch changes[i].Type {
+		case change.AddClaim, change.UpdateClaim, change.SpendClaim:
+			if spent[changes[i].ClaimID] {
+				obsolete[i] = true
+			}
+		}
+	}
+	return obsolete
+}
+
+// sameActiveBestClaims returns whether the node of the name built from the
+// original changes and the one built from the compacted changes both have an
+// active best claim, or both don't, at each height below the passed one where
+// a change of a parent name checks for active children.
+func (nm *BaseManager) sameActiveBestClaims(name []byte, original,
+	compacted []change.Change, height int32) (bool, error) {
+
+	seen := map[int32]bool{}
+	var heights []int32
+	for i := 0; i < len(name); i++ {
+		changes, err := nm.repo.LoadChanges(name[:i])
+		if err != nil {
+			return false, errors.Wrap(err, "in load parent changes")
+		}
+		for _, chg := range changes {
+			if chg.Height >= height {
+				break
+			}
+			if chg.Type == change.SpendClaim || chg.Type == change.SpendSupport ||
+				chg.Height < param.ActiveParams.MaxRemovalWorkaroundHeight || seen[chg.Height] {
+				continue
+			}
+			seen[chg.Height] = true
+			heights = append(heights, chg.Height)
+		}
+		if len(heights) > maxCompactChildChecks {
+			return false, nil
+		}
+	}
+
+	for _, h := range heights {
+		a, err := nm.newNodeFromChanges(original, h)
+		if err != nil {
+			return false, errors.Wrap(err, "in new node")
+		}
+		b, err := nm.newNodeFromChanges(compacted, h)
+		if err != nil {
+			return false, errors.Wrap(err, "in new compacted node")
+		}
+		if (a != nil && a.HasActiveBestClaim()) != (b != nil && b.HasActiveBestClaim()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// equivalentNodes returns whether the nodes have the same state regardless of
+// the order and the sequence of their claims and supports.
+func equivalentNodes(a, b *Node) bool {
+
+	if a.TakenOverAt != b.TakenOverAt || (a.BestClaim == nil) != (b.BestClaim == nil) {
+		return false
+	}
+	if a.BestClaim != nil && !equivalentClaims(a.BestClaim, b.BestClaim) {
+		return false
+	}
+	if !equivalentClaimLists(a.Claims, b.Claims) || !equivalentClaimLists(a.Supports, b.Supports) {
+		return false
+	}
+
+	// Spent supports leave zero sums behind.
+	for key, sum := range a.SupportSums {
+		if b.SupportSums[key] != sum {
+			return false
+		}
+	}
+	for key, sum := range b.SupportSums {
+		if a.SupportSums[key] != sum {
+			return false
+		}
+	}
+	return true
+}
+
+func equivalentClaimLists(a, b ClaimList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byOutPoint := make(map[wire.OutPoint]*Claim, len(a))
+	for _, c := range a {
+		byOutPoint[c.OutPoint] = c
+	}
+	for _, c := range b {
+		other, ok := byOutPoint[c.OutPoint]
+		if !ok || !equivalentClaims(c, other) {
+			return false
+		}
+	}
+	return true
+}
+
+func equivalentClaims(a, b *Claim) bool {
+	x, y := *a, *b
+	x.Sequence, y.Sequence = 0, 0
+	return x == y
+}
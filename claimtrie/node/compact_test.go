@@ -0,0 +1,203 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/claimtrie/param"
+	"github.com/lbryio/lbcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+// compactTestChange returns a change of the passed type of name1 at the passed
+// height for the output op, which belongs to the claim created by the output
+// claim.
+func compactTestChange(typ change.ChangeType, op *wire.OutPoint, claim *wire.OutPoint, height int32) change.Change {
+	chg := change.NewChange(typ).SetName(name1).SetOutPoint(op).SetHeight(height).SetAmount(1)
+	chg.ClaimID = change.NewClaimID(*claim)
+	return chg
+}
+
+// compactTestChanges returns the changes of a claim of out1 with a support of
+// out2 spent before the claim of out3 takes over at height 5, along with the
+// changes of the claim of out3.
+func compactTestChanges() []change.Change {
+	return []change.Change{
+		compactTestChange(change.AddClaim, out1, out1, 1),
+		compactTestChange(change.AddSupport, out2, out1, 2),
+		compactTestChange(change.SpendSupport, out2, out1, 3),
+		compactTestChange(change.SpendClaim, out1, out1, 4),
+		compactTestChange(change.AddClaim, out3, out3, 5),
+	}
+}
+
+func TestEquivalentNodes(t *testing.T) {
+
+	r := require.New(t)
+
+	newNode := func(sequence int32) *Node {
+		n := New()
+		c := &Claim{OutPoint: *out1, ClaimID: change.NewClaimID(*out1), Amount: 1, Status: Activated, Sequence: sequence}
+		s := &Claim{OutPoint: *out2, ClaimID: c.ClaimID, Amount: 2, Status: Activated, Sequence: sequence + 1}
+		n.BestClaim = c
+		n.TakenOverAt = 1
+		n.Claims = ClaimList{c}
+		n.Supports = ClaimList{s}
+		n.SupportSums[c.ClaimID.Key()] = s.Amount
+		return n
+	}
+
+	// The sequence of the claims doesn't matter, neither do the zero sums
+	// left behind by the spent supports.
+	a, b := newNode(0), newNode(5)
+	r.True(equivalentNodes(a, b))
+	a.SupportSums[change.NewClaimID(*out3).Key()] = 0
+	r.True(equivalentNodes(a, b))
+	r.True(equivalentNodes(b, a))
+
+	b = newNode(0)
+	b.TakenOverAt = 2
+	r.False(equivalentNodes(a, b))
+
+	b = newNode(0)
+	b.BestClaim = nil
+	r.False(equivalentNodes(a, b))
+	r.False(equivalentNodes(b, a))
+
+	b = newNode(0)
+	b.BestClaim.Amount = 3
+	r.False(equivalentNodes(a, b))
+
+	b = newNode(0)
+	b.Claims = append(b.Claims, &Claim{OutPoint: *out4})
+	r.False(equivalentNodes(a, b))
+
+	b = newNode(0)
+	b.Supports[0].OutPoint = *out3
+	r.False(equivalentNodes(a, b))
+
+	b = newNode(0)
+	b.SupportSums[b.BestClaim.ClaimID.Key()] = 3
+	r.False(equivalentNodes(a, b))
+	r.False(equivalentNodes(b, a))
+
+	// The sums missing from either node are zero.
+	b = newNode(0)
+	b.SupportSums[change.NewClaimID(*out4).Key()] = 3
+	r.False(equivalentNodes(a, b))
+	r.False(equivalentNodes(b, a))
+}
+
+func TestObsoleteChanges(t *testing.T) {
+
+	r := require.New(t)
+
+	best := &Node{BestClaim: &Claim{ClaimID: change.NewClaimID(*out3)}, TakenOverAt: 5}
+
+	// The claim and the support spent before the takeover are obsolete.
+	changes := compactTestChanges()
+	r.Equal(map[int]bool{0: true, 1: true, 2: true, 3: true}, obsoleteChanges(changes, best))
+
+	// A support spent at the takeover height and a claim updated after it
+	// is spent are kept.
+	changes[2].Height = 5
+	changes = append(changes[:4], compactTestChange(change.UpdateClaim, out4, out1, 4), changes[4])
+	r.Empty(obsoleteChanges(changes, best))
+
+	// The changes of the best claim are kept even when it was spent.
+	best.BestClaim.ClaimID = change.NewClaimID(*out1)
+	changes = compactTestChanges()
+	r.Equal(map[int]bool{1: true, 2: true}, obsoleteChanges(changes, best))
+}
+
+func TestSameActiveBestClaims(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	m := newUndoManager(t, 0, 0)
+	defer m.Close()
+
+	original := compactTestChanges()
+	compacted := original[4:]
+
+	// The parent name "name" checks for active children at height 3 while
+	// only the original claim of out1 is active.
+	parent := change.NewChange(change.AddClaim).SetName([]byte("name")).SetOutPoint(out4).SetHeight(3)
+	r.NoError(m.repo.AppendChanges([]change.Change{parent}))
+	same, err := m.sameActiveBestClaims(name1, original, compacted, 10)
+	r.NoError(err)
+	r.False(same)
+
+	// The changes at the passed height and above aren't checked.
+	same, err = m.sameActiveBestClaims(name1, original, compacted, 3)
+	r.NoError(err)
+	r.True(same)
+
+	// Neither are the spends of the parent names, so only height 4 is
+	// checked once the claim of out1 is spent.
+	r.NoError(m.repo.SetChanges([]byte("name"), []change.Change{
+		change.NewChange(change.SpendSupport).SetName([]byte("name")).SetOutPoint(out4).SetHeight(2),
+		change.NewChange(change.SpendClaim).SetName([]byte("name")).SetOutPoint(out4).SetHeight(3),
+		parent.SetHeight(4),
+	}))
+	same, err = m.sameActiveBestClaims(name1, original, compacted, 10)
+	r.NoError(err)
+	r.True(same)
+
+	// The checks are given up when the parent names change at too many
+	// heights, even though the changes are the same.
+	var many []change.Change
+	for h := int32(1); h <= maxCompactChildChecks+1; h++ {
+		many = append(many, change.NewChange(change.AddSupport).SetName([]byte("n")).SetOutPoint(out4).SetHeight(h))
+	}
+	r.NoError(m.repo.AppendChanges(many))
+	same, err = m.sameActiveBestClaims(name1, original, original, maxCompactChildChecks+2)
+	r.NoError(err)
+	r.False(same)
+}
+
+func TestCompactNode(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	m := newUndoManager(t, 0, 0)
+	defer m.Close()
+
+	for _, chg := range compactTestChanges() {
+		if chg.Height > m.height+1 {
+			_, err := m.IncrementHeightTo(chg.Height-1, false)
+			r.NoError(err)
+		}
+		m.AppendChange(chg)
+	}
+	_, err := m.IncrementHeightTo(10, false)
+	r.NoError(err)
+	before, err := m.node(name1)
+	r.NoError(err)
+	bestOutPoint := before.BestClaim.OutPoint
+
+	// The final height can't be above the current height.
+	_, err = m.CompactNode(name1, 11)
+	r.Error(err)
+
+	// The changes of the claim of out1 and its support are removed, while
+	// the node keeps the same state.
+	removed, err := m.CompactNode(name1, 10)
+	r.NoError(err)
+	r.Equal(4, removed)
+	changes, err := m.repo.LoadChanges(name1)
+	r.NoError(err)
+	r.Len(changes, 1)
+	after, err := m.node(name1)
+	r.NoError(err)
+	r.Equal(bestOutPoint, after.BestClaim.OutPoint)
+	r.True(equivalentNodes(before, after))
+
+	// Nothing is left to compact.
+	removed, err = m.CompactNode(name1, 10)
+	r.NoError(err)
+	r.Zero(removed)
+}
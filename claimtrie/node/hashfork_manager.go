@@ -3,26 +3,38 @@ package node
 import (
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
 	"github.com/lbryio/lbcd/claimtrie/param"
+	"github.com/lbryio/lbcd/wire"
 )
 
 type HashV2Manager struct {
 	Manager
 }
 
-func (nm *HashV2Manager) computeClaimHashes(name []byte) (*chainhash.Hash, int32) {
-
-	n, err := nm.NodeAt(nm.Height(), name)
-	if err != nil || n == nil {
-		return nil, 0
-	}
+// activeClaimHashes returns the hashes of the active claims of the node in bid
+// order, which make up its claims hash once all claims are in the merkle trie,
+// along with their outpoints.
+func activeClaimHashes(n *Node) ([]*chainhash.Hash, []wire.OutPoint) {
 
 	n.SortClaimsByBid()
 	claimHashes := make([]*chainhash.Hash, 0, len(n.Claims))
+	outPoints := make([]wire.OutPoint, 0, len(n.Claims))
 	for _, c := range n.Claims {
 		if c.Status == Activated { // TODO: unit test this line
 			claimHashes = append(claimHashes, calculateNodeHash(c.OutPoint, n.TakenOverAt))
+			outPoints = append(outPoints, c.OutPoint)
 		}
 	}
+	return claimHashes, outPoints
+}
+
+func (nm *HashV2Manager) computeClaimHashes(name []byte) (*chainhash.Hash, int32) {
+
+	n, err := nm.NodeAt(nm.Height(), name)
+	if err != nil || n == nil {
+		return nil, 0
+	}
+
+	claimHashes, _ := activeClaimHashes(n)
 	if len(claimHashes) > 0 {
 		return ComputeMerkleRoot(claimHashes), n.NextUpdate()
 	}
@@ -37,3 +49,23 @@ func (nm *HashV2Manager) Hash(name []byte) (*chainhash.Hash, int32) {
 
 	return nm.Manager.Hash(name)
 }
+
+// ClaimHash returns the hash of the claim with the passed outpoint in the claims
+// hash of its node, given the last takeover height of the node.
+func ClaimHash(op wire.OutPoint, takeover int32) *chainhash.Hash {
+	return calculateNodeHash(op, takeover)
+}
+
+// ClaimProofPath returns the pairs proving the hash of the active claim with the
+// passed outpoint is part of the claims hash of the node once all claims are in
+// the merkle trie.  It returns false when the node has no such claim.
+func ClaimProofPath(n *Node, op wire.OutPoint) ([]ProofPair, bool) {
+
+	claimHashes, outPoints := activeClaimHashes(n)
+	for i := range outPoints {
+		if outPoints[i] == op {
+			return ComputeMerklePath(claimHashes, i), true
+		}
+	}
+	return nil, false
+}
@@ -35,6 +35,48 @@ func ComputeMerkleRoot(hashes []*chainhash.Hash) *chainhash.Hash {
 	return hashes[0]
 }
 
+// ProofPair is a step of a merkle proof: the hash to combine with the hash
+// being proven, which is the right one of the pair when Odd is set.
+type ProofPair struct {
+	Odd  bool
+	Hash *chainhash.Hash
+}
+
+// ComputeMerklePath returns the pairs proving the hash at the passed index is
+// part of the merkle root of the hashes, as computed by ComputeMerkleRoot.
+func ComputeMerklePath(hashes []*chainhash.Hash, index int) []ProofPair {
+	hashes = append([]*chainhash.Hash(nil), hashes...) // don't destroy the caller's hashes
+	var pairs []ProofPair
+	for len(hashes) > 1 {
+		if (len(hashes) & 1) > 0 { // odd count
+			hashes = append(hashes, hashes[len(hashes)-1])
+		}
+		if (index & 1) > 0 {
+			pairs = append(pairs, ProofPair{Odd: true, Hash: hashes[index-1]})
+		} else {
+			pairs = append(pairs, ProofPair{Hash: hashes[index+1]})
+		}
+		for i := 0; i < len(hashes); i += 2 {
+			hashes[i>>1] = HashMerkleBranches(hashes[i], hashes[i+1])
+		}
+		hashes = hashes[:len(hashes)>>1]
+		index >>= 1
+	}
+	return pairs
+}
+
+// ComputeProofRoot returns the merkle root the pairs prove the hash is part of.
+func ComputeProofRoot(hash *chainhash.Hash, pairs []ProofPair) *chainhash.Hash {
+	for _, pair := range pairs {
+		if pair.Odd {
+			hash = HashMerkleBranches(pair.Hash, hash)
+		} else {
+			hash = HashMerkleBranches(hash, pair.Hash)
+		}
+	}
+	return hash
+}
+
 func calculateNodeHash(op wire.OutPoint, takeover int32) *chainhash.Hash {
 
 	txHash := chainhash.DoubleHashH(op.Hash[:])
@@ -3,6 +3,7 @@ package node
 import (
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -18,10 +19,17 @@ type Manager interface {
 	Height() int32
 	Close() error
 	NodeAt(height int32, name []byte) (*Node, error)
+	SimulateNodeAt(height int32, name []byte, changes []change.Change) (*Node, error)
 	IterateNames(predicate func(name []byte) bool)
 	Hash(name []byte) (*chainhash.Hash, int32)
 	Flush() error
 	ClearCache()
+	CacheStats() CacheStats
+	Stats() (*Stats, error)
+	UpdateStats(names [][]byte, height int32) error
+	CompactNode(name []byte, finalHeight int32) (int, error)
+	CompactedHeight() int32
+	SetCompactedHeight(height int32) error
 }
 
 type BaseManager struct {
@@ -33,24 +41,65 @@ type BaseManager struct {
 	tempChanges map[string][]change.Change
 
 	cache *Cache
+
+	// undoRepo holds the undo records of the last undoDepth blocks, if
+	// set, from the one at undoStartHeight.
+	undoRepo        UndoRepo
+	undoDepth       int32
+	undoStartHeight int32
+
+	// compactedHeight is the final height of the compactions of the repo,
+	// below which the nodes can't be rebuilt.
+	compactedHeight int32
+
+	// stats holds the aggregate statistics of the nodes at the current
+	// height once they are requested.
+	stats    *Stats
+	statsMtx sync.Mutex
 }
 
 func NewBaseManager(repo Repo) (*BaseManager, error) {
 
+	compactedHeight, err := repo.CompactedHeight()
+	if err != nil {
+		return nil, errors.Wrap(err, "in compacted height")
+	}
+
 	nm := &BaseManager{
-		repo:  repo,
-		cache: NewCache(10000), // TODO: how many should we cache?
+		repo:            repo,
+		cache:           NewCache(DefaultCacheBudget),
+		compactedHeight: compactedHeight,
 	}
 
 	return nm, nil
 }
 
+// ClearCache drops the cached nodes along with the aggregate statistics of the
+// nodes, which are computed again the next time they are requested.
 func (nm *BaseManager) ClearCache() {
 	nm.cache.clear()
+	nm.clearStats()
+}
+
+// SetCacheBudget changes the approximate number of bytes of memory used by the
+// cached nodes.  Nodes evicted from the cache are rebuilt from the repo when
+// they are needed again.
+func (nm *BaseManager) SetCacheBudget(budget int64) {
+	nm.cache.SetBudget(budget)
+}
+
+// CacheStats returns the statistics of the node cache.
+func (nm *BaseManager) CacheStats() CacheStats {
+	return nm.cache.Stats()
 }
 
 func (nm *BaseManager) NodeAt(height int32, name []byte) (*Node, error) {
 
+	if height < nm.compactedHeight {
+		return nil, errors.Errorf("the claim trie was compacted below height %d "+
+			"and can't be queried at height %d", nm.compactedHeight, height)
+	}
+
 	n, changes, oldHeight := nm.cache.fetch(name, height)
 	if n == nil {
 		changes, err := nm.repo.LoadChanges(name)
@@ -92,6 +141,31 @@ func (nm *BaseManager) NodeAt(height int32, name []byte) (*Node, error) {
 	return n, nil
 }
 
+// SimulateNodeAt returns the node of the name at the height as it would be if
+// the hypothetical changes were appended to its stored ones.  The changes must
+// be ordered by height and be above the current height.  Neither the repo nor
+// the cache are modified.
+func (nm *BaseManager) SimulateNodeAt(height int32, name []byte, changes []change.Change) (*Node, error) {
+
+	stored, err := nm.repo.LoadChanges(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "in load changes")
+	}
+
+	all := make([]change.Change, 0, len(stored)+len(changes))
+	all = append(all, stored...)
+	if nm.tempChanges != nil {
+		all = append(all, nm.tempChanges[string(name)]...)
+	}
+	all = append(all, changes...)
+
+	n, err := nm.newNodeFromChanges(all, height)
+	if err != nil {
+		return nil, errors.Wrap(err, "in new node")
+	}
+	return n, nil
+}
+
 // Node returns a node at the current height.
 // The returned node may have pending changes.
 func (nm *BaseManager) node(name []byte) (*Node, error) {
@@ -269,6 +343,14 @@ func (nm *BaseManager) IncrementHeightTo(height int32, temporary bool) ([][]byte
 		}
 	}
 
+	if !temporary && nm.undoRepo != nil && height == nm.height+1 &&
+		height >= nm.undoStartHeight {
+
+		if err := nm.saveUndo(height); err != nil {
+			return nil, errors.Wrap(err, "in save undo")
+		}
+	}
+
 	if !temporary {
 		nm.cache.addChanges(nm.changes, height)
 		if err := nm.repo.AppendChanges(nm.changes); err != nil { // destroys names
@@ -299,18 +381,70 @@ func (nm *BaseManager) DecrementHeightTo(affectedNames [][]byte, height int32) (
 		for key := range nm.tempChanges {
 			affectedNames = append(affectedNames, []byte(key))
 		}
+	}
+
+	// The nodes of the affected names are loaded before and after dropping
+	// their changes to update the statistics.
+	nm.statsMtx.Lock()
+	defer nm.statsMtx.Unlock()
+	var names [][]byte
+	if nm.stats != nil {
+		seen := make(map[string]bool, len(affectedNames))
+		for _, name := range affectedNames {
+			if seen[string(name)] {
+				continue
+			}
+			seen[string(name)] = true
+			names = append(names, name)
+
+			n, err := nm.NodeAt(nm.height, name)
+			if err != nil {
+				nm.stats = nil
+				return affectedNames, errors.Wrap(err, "in previous node")
+			}
+			nm.stats.add(name, n, -1)
+		}
+	}
+
+	if nm.tempChanges != nil {
 		nm.tempChanges = nil
 	} else {
 		for _, name := range affectedNames {
 			if err := nm.repo.DropChanges(name, height); err != nil {
+				nm.stats = nil
 				return affectedNames, errors.Wrap(err, "in drop changes")
 			}
 		}
 
 		nm.cache.drop(affectedNames)
+
+		// The nodes changed by the block above the height are restored
+		// from its undo record rather than rebuilt from all their
+		// changes the next time they are needed.
+		if nm.undoRepo != nil {
+			restored, err := nm.loadUndo(height)
+			if err != nil {
+				nm.stats = nil
+				return affectedNames, errors.Wrap(err, "in load undo")
+			}
+			for name, n := range restored {
+				nm.cache.insert([]byte(name), n, height)
+			}
+		}
 	}
 	nm.height = height
 
+	if nm.stats != nil {
+		for _, name := range names {
+			n, err := nm.NodeAt(height, name)
+			if err != nil {
+				nm.stats = nil
+				return affectedNames, errors.Wrap(err, "in current node")
+			}
+			nm.stats.add(name, n, 1)
+		}
+	}
+
 	return affectedNames, nil
 }
 
@@ -397,6 +531,12 @@ func (nm *BaseManager) Height() int32 {
 }
 
 func (nm *BaseManager) Close() error {
+	if nm.undoRepo != nil {
+		if err := nm.undoRepo.Close(); err != nil {
+			nm.repo.Close()
+			return errors.Wrap(err, "in close undo repo")
+		}
+	}
 	return errors.WithStack(nm.repo.Close())
 }
 
@@ -450,5 +590,10 @@ func (nm *BaseManager) Hash(name []byte) (*chainhash.Hash, int32) {
 }
 
 func (nm *BaseManager) Flush() error {
+	if nm.undoRepo != nil {
+		if err := nm.undoRepo.Flush(); err != nil {
+			return err
+		}
+	}
 	return nm.repo.Flush()
 }
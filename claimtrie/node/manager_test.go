@@ -297,3 +297,57 @@ func TestTemporaryAddClaim(t *testing.T) {
 	r.NoError(err)
 	r.Nil(n2)
 }
+
+func TestSimulateNodeAt(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	repo, err := noderepo.NewPebble(t.TempDir())
+	r.NoError(err)
+
+	m, err := NewBaseManager(repo)
+	r.NoError(err)
+	defer m.Close()
+
+	_, err = m.IncrementHeightTo(10, false)
+	r.NoError(err)
+
+	chg := change.NewChange(change.AddClaim).SetName(name1).SetOutPoint(out1).SetHeight(11).SetAmount(3)
+	chg.ClaimID = change.NewClaimID(*out1)
+	m.AppendChange(chg)
+	_, err = m.IncrementHeightTo(11, false)
+	r.NoError(err)
+	_, err = m.IncrementHeightTo(300, false)
+	r.NoError(err)
+
+	// A bigger claim only takes over once its activation delay is over.
+	claim := change.NewChange(change.AddClaim).SetName(name1).SetOutPoint(out2).SetHeight(301).SetAmount(10)
+	claim.ClaimID = change.NewClaimID(*out2)
+	activeAt := 301 + calculateDelay(301, 11)
+	r.Greater(activeAt, int32(301))
+
+	n, err := m.SimulateNodeAt(activeAt-1, name1, []change.Change{claim})
+	r.NoError(err)
+	r.Equal(change.NewClaimID(*out1), n.BestClaim.ClaimID)
+	r.Equal(activeAt, n.Claims.find(byOut(*out2)).ActiveAt)
+
+	n, err = m.SimulateNodeAt(activeAt, name1, []change.Change{claim})
+	r.NoError(err)
+	r.Equal(change.NewClaimID(*out2), n.BestClaim.ClaimID)
+	r.Equal(activeAt, n.TakenOverAt)
+
+	// A support of the controlling claim is active right away and defends it.
+	support := change.NewChange(change.AddSupport).SetName(name1).SetOutPoint(out3).SetHeight(301).SetAmount(20)
+	support.ClaimID = change.NewClaimID(*out1)
+	n, err = m.SimulateNodeAt(activeAt, name1, []change.Change{claim, support})
+	r.NoError(err)
+	r.Equal(change.NewClaimID(*out1), n.BestClaim.ClaimID)
+	r.Equal(int32(11), n.TakenOverAt)
+
+	// The simulation leaves the stored node alone.
+	n, err = m.node(name1)
+	r.NoError(err)
+	r.Equal(1, len(n.Claims))
+	r.Equal(0, len(n.Supports))
+}
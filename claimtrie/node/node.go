@@ -155,16 +155,6 @@ func (n *Node) updateTakeoverHeight(height int32, name []byte, refindBest bool)
 
 func (n *Node) handleExpiredAndActivated(height int32) int {
 
-	ot := param.ActiveParams.OriginalClaimExpirationTime
-	et := param.ActiveParams.ExtendedClaimExpirationTime
-	fk := param.ActiveParams.ExtendedClaimExpirationForkHeight
-	expiresAt := func(c *Claim) int32 {
-		if c.AcceptedAt+ot > fk {
-			return c.AcceptedAt + et
-		}
-		return c.AcceptedAt + ot
-	}
-
 	changes := 0
 	update := func(items ClaimList, sums map[string]int64) ClaimList {
 		for i := 0; i < len(items); i++ {
@@ -176,7 +166,7 @@ func (n *Node) handleExpiredAndActivated(height int32) int {
 					sums[c.ClaimID.Key()] += c.Amount
 				}
 			}
-			if c.Status == Deactivated || expiresAt(c) <= height {
+			if c.Status == Deactivated || c.ExpireAt() <= height {
 				if i < len(items)-1 {
 					items[i] = items[len(items)-1]
 					i--
@@ -199,20 +189,10 @@ func (n *Node) handleExpiredAndActivated(height int32) int {
 // be refreshed due to changes of claims or supports.
 func (n Node) NextUpdate() int32 {
 
-	ot := param.ActiveParams.OriginalClaimExpirationTime
-	et := param.ActiveParams.ExtendedClaimExpirationTime
-	fk := param.ActiveParams.ExtendedClaimExpirationForkHeight
-	expiresAt := func(c *Claim) int32 {
-		if c.AcceptedAt+ot > fk {
-			return c.AcceptedAt + et
-		}
-		return c.AcceptedAt + ot
-	}
-
 	next := int32(math.MaxInt32)
 
 	for _, c := range n.Claims {
-		ea := expiresAt(c)
+		ea := c.ExpireAt()
 		if ea < next {
 			next = ea
 		}
@@ -229,7 +209,7 @@ func (n Node) NextUpdate() int32 {
 	}
 
 	for _, s := range n.Supports {
-		es := expiresAt(s)
+		es := s.ExpireAt()
 		if es < next {
 			next = es
 		}
@@ -186,3 +186,83 @@ func TestIterator(t *testing.T) {
 		return true
 	})
 }
+
+func TestSetChanges(t *testing.T) {
+
+	r := require.New(t)
+
+	dir := t.TempDir()
+	repo, err := NewPebble(dir)
+	r.NoError(err)
+
+	chg := change.NewChange(change.AddClaim).SetName(testNodeName1).SetOutPoint(out1)
+	err = repo.AppendChanges([]change.Change{chg.SetHeight(1), chg.SetHeight(3), chg.SetHeight(5)})
+	r.NoError(err)
+
+	// The replaced changes are kept once the repo is reopened.
+	expected := []change.Change{chg.SetHeight(3), chg.SetHeight(5)}
+	r.NoError(repo.SetChanges(testNodeName1, expected))
+	r.NoError(repo.Close())
+
+	repo, err = NewPebble(dir)
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	changes, err := repo.LoadChanges(testNodeName1)
+	r.NoError(err)
+	r.Equal(expected, changes)
+}
+
+func TestCompactedHeight(t *testing.T) {
+
+	r := require.New(t)
+
+	dir := t.TempDir()
+	repo, err := NewPebble(dir)
+	r.NoError(err)
+
+	height, err := repo.CompactedHeight()
+	r.NoError(err)
+	r.Zero(height)
+
+	chg := change.NewChange(change.AddClaim).SetOutPoint(out1).SetHeight(1)
+	r.NoError(repo.AppendChanges([]change.Change{chg.SetName([]byte{0xff}), chg.SetName([]byte{0xff, 0xff})}))
+	r.NoError(repo.SetCompactedHeight(100))
+
+	// The compacted height is kept once the repo is reopened, and it's
+	// never iterated as a name.
+	r.NoError(repo.Close())
+	repo, err = NewPebble(dir)
+	r.NoError(err)
+	defer func() {
+		err := repo.Close()
+		r.NoError(err)
+	}()
+
+	height, err = repo.CompactedHeight()
+	r.NoError(err)
+	r.Equal(int32(100), height)
+
+	var names [][]byte
+	repo.IterateAll(func(name []byte) bool {
+		names = append(names, append([]byte(nil), name...))
+		return true
+	})
+	r.Equal([][]byte{{0xff}, {0xff, 0xff}}, names)
+	children := 0
+	err = repo.IterateChildren([]byte{0xff}, func(changes []change.Change) bool {
+		children++
+		return true
+	})
+	r.NoError(err)
+	r.Equal(1, children)
+
+	// Clearing the repo resets it.
+	r.NoError(repo.Clear())
+	height, err = repo.CompactedHeight()
+	r.NoError(err)
+	r.Zero(height)
+}
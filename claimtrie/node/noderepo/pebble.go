@@ -2,6 +2,7 @@ package noderepo
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
 	"sort"
 	"sync"
@@ -11,6 +12,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+// compactedHeightKey is the key of the compacted height of the repo.  It's
+// longer than the longest name and sorts after all of them, so the iterations
+// over the names stop before it.
+var compactedHeightKey = bytes.Repeat([]byte{0xff}, 256)
+
 type Pebble struct {
 	db *pebble.DB
 }
@@ -64,7 +70,7 @@ func (a *pooledMerger) Close() error {
 	return nil
 }
 
-func NewPebble(path string) (*Pebble, error) {
+func NewPebble(path string, configure ...func(*pebble.Options)) (*Pebble, error) {
 
 	mp := &sync.Pool{
 		New: func() interface{} {
@@ -72,7 +78,7 @@ func NewPebble(path string) (*Pebble, error) {
 		},
 	}
 
-	db, err := pebble.Open(path, &pebble.Options{
+	opts := &pebble.Options{
 		Merger: &pebble.Merger{
 			Merge: func(key, value []byte) (pebble.ValueMerger, error) {
 				p := &pooledMerger{pool: mp}
@@ -83,7 +89,11 @@ func NewPebble(path string) (*Pebble, error) {
 		Cache:        pebble.NewCache(64 << 20),
 		BytesPerSync: 8 << 20,
 		MaxOpenFiles: 2000,
-	})
+	}
+	for _, f := range configure {
+		f(opts)
+	}
+	db, err := pebble.Open(path, opts)
 
 	repo := &Pebble{db: db}
 
@@ -178,6 +188,44 @@ func (repo *Pebble) DropChanges(name []byte, finalHeight int32) error {
 	return errors.Wrapf(err, "in set at %s", name)
 }
 
+// SetChanges replaces the changes of the node in a synced batch, since the
+// replaced changes are gone for good, unlike the ones appended at each block
+// which are replayed after a crash.
+func (repo *Pebble) SetChanges(name []byte, changes []change.Change) error {
+	buffer := bytes.NewBuffer(nil)
+	for i := range changes {
+		err := changes[i].Marshal(buffer)
+		if err != nil {
+			return errors.Wrap(err, "in marshaller")
+		}
+	}
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+	if err := batch.Set(name, buffer.Bytes(), nil); err != nil {
+		return errors.Wrapf(err, "in set at %s", name)
+	}
+	return errors.Wrap(batch.Commit(pebble.Sync), "in commit")
+}
+
+func (repo *Pebble) Clear() error {
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+
+	iter := repo.db.NewIter(nil)
+	for iter.First(); iter.Valid(); iter.Next() {
+		err := batch.Delete(iter.Key(), pebble.NoSync) // the key is copied into the batch
+		if err != nil {
+			iter.Close()
+			return errors.Wrap(err, "in delete")
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return errors.Wrap(err, "in close")
+	}
+	return errors.Wrap(batch.Commit(pebble.NoSync), "in commit")
+}
+
 func (repo *Pebble) IterateChildren(name []byte, f func(changes []change.Change) bool) error {
 	start := make([]byte, len(name)+1) // zeros that last byte; need a constant len for stack alloc?
 	copy(start, name)
@@ -193,7 +241,7 @@ func (repo *Pebble) IterateChildren(name []byte, f func(changes []change.Change)
 		}
 	}
 	if !validEnd {
-		end = nil // uh, we think this means run to the end of the table
+		end = compactedHeightKey // run to the end of the names
 	}
 
 	prefixIterOptions := &pebble.IterOptions{
@@ -218,7 +266,7 @@ func (repo *Pebble) IterateChildren(name []byte, f func(changes []change.Change)
 }
 
 func (repo *Pebble) IterateAll(predicate func(name []byte) bool) {
-	iter := repo.db.NewIter(nil)
+	iter := repo.db.NewIter(&pebble.IterOptions{UpperBound: compactedHeightKey})
 	defer iter.Close()
 
 	for iter.First(); iter.Valid(); iter.Next() {
@@ -228,6 +276,11 @@ func (repo *Pebble) IterateAll(predicate func(name []byte) bool) {
 	}
 }
 
+// Metrics returns the internal metrics of the database.
+func (repo *Pebble) Metrics() *pebble.Metrics {
+	return repo.db.Metrics()
+}
+
 func (repo *Pebble) Close() error {
 
 	err := repo.db.Flush()
@@ -240,6 +293,49 @@ func (repo *Pebble) Close() error {
 	return errors.Wrap(err, "on close")
 }
 
+// Compact compacts the range of all the keys of the database.
+func (repo *Pebble) Compact() error {
+	iter := repo.db.NewIter(nil)
+	if !iter.First() {
+		return errors.Wrap(iter.Close(), "in close")
+	}
+	start := append([]byte(nil), iter.Key()...)
+	iter.Last()
+	// The end of the range is exclusive.
+	end := append(append([]byte(nil), iter.Key()...), 0)
+	if err := iter.Close(); err != nil {
+		return errors.Wrap(err, "in close")
+	}
+
+	return errors.Wrap(repo.db.Compact(start, end, true), "in compact")
+}
+
+// CompactedHeight returns the final height of the compactions of the repo, or
+// 0 when it was never compacted.
+func (repo *Pebble) CompactedHeight() (int32, error) {
+	value, closer, err := repo.db.Get(compactedHeightKey)
+	if err == pebble.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "in get compacted height")
+	}
+	defer closer.Close()
+	if len(value) != 4 {
+		return 0, errors.Errorf("invalid compacted height of %d bytes", len(value))
+	}
+	return int32(binary.BigEndian.Uint32(value)), nil
+}
+
+// SetCompactedHeight stores the final height of the compactions of the repo,
+// synced along with the compacted changes.
+func (repo *Pebble) SetCompactedHeight(height int32) error {
+	var value [4]byte
+	binary.BigEndian.PutUint32(value[:], uint32(height))
+	err := repo.db.Set(compactedHeightKey, value[:], pebble.Sync)
+	return errors.Wrap(err, "in set compacted height")
+}
+
 func (repo *Pebble) Flush() error {
 	_, err := repo.db.AsyncFlush()
 	return err
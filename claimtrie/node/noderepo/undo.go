@@ -0,0 +1,102 @@
+package noderepo
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/pkg/errors"
+)
+
+// UndoPebble is a pebble backed repo for the undo records of the nodes.
+type UndoPebble struct {
+	db *pebble.DB
+}
+
+// NewUndoPebble opens or creates the pebble database of the undo records at
+// the path.
+func NewUndoPebble(path string, configure ...func(*pebble.Options)) (*UndoPebble, error) {
+
+	opts := &pebble.Options{Cache: pebble.NewCache(16 << 20), MaxOpenFiles: 2000}
+	for _, f := range configure {
+		f(opts)
+	}
+	db, err := pebble.Open(path, opts)
+	repo := &UndoPebble{db: db}
+
+	return repo, errors.Wrapf(err, "unable to open %s", path)
+}
+
+// undoKey returns the key of the undo record of the name at the height.
+//
+// key format: height(4B) + name(variable length)
+func undoKey(height int32, name []byte) []byte {
+	key := make([]byte, 4+len(name))
+	binary.BigEndian.PutUint32(key, uint32(height))
+	copy(key[4:], name)
+	return key
+}
+
+func (repo *UndoPebble) SaveUndo(height int32, names [][]byte, nodes [][]byte) error {
+
+	batch := repo.db.NewBatch()
+	defer batch.Close()
+	for i, name := range names {
+		err := batch.Set(undoKey(height, name), nodes[i], pebble.NoSync)
+		if err != nil {
+			return errors.Wrap(err, "in set")
+		}
+	}
+	return errors.Wrap(batch.Commit(pebble.NoSync), "in commit")
+}
+
+func (repo *UndoPebble) LoadUndo(height int32) ([][]byte, [][]byte, error) {
+
+	prefixIterOptions := &pebble.IterOptions{
+		LowerBound: undoKey(height, nil),
+		UpperBound: undoKey(height+1, nil),
+	}
+
+	var names, nodes [][]byte
+
+	iter := repo.db.NewIter(prefixIterOptions)
+	for iter.First(); iter.Valid(); iter.Next() {
+		// iter.Key() and iter.Value() reuse their buffers.
+		names = append(names, append([]byte(nil), iter.Key()[4:]...))
+		nodes = append(nodes, append([]byte(nil), iter.Value()...))
+	}
+
+	return names, nodes, errors.Wrap(iter.Close(), "in close")
+}
+
+func (repo *UndoPebble) DropUndo(start, end int32) error {
+	err := repo.db.DeleteRange(undoKey(start, nil), undoKey(end+1, nil), pebble.NoSync)
+	return errors.Wrap(err, "on range delete")
+}
+
+func (repo *UndoPebble) Clear() error {
+	err := repo.db.DeleteRange(undoKey(0, nil), undoKey(math.MaxInt32, nil), pebble.NoSync)
+	return errors.Wrap(err, "on range delete")
+}
+
+// Metrics returns the internal metrics of the database.
+func (repo *UndoPebble) Metrics() *pebble.Metrics {
+	return repo.db.Metrics()
+}
+
+func (repo *UndoPebble) Close() error {
+
+	err := repo.db.Flush()
+	if err != nil {
+		// if we fail to close are we going to try again later?
+		return errors.Wrap(err, "on flush")
+	}
+
+	err = repo.db.Close()
+	return errors.Wrap(err, "on close")
+}
+
+func (repo *UndoPebble) Flush() error {
+	_, err := repo.db.AsyncFlush()
+	return err
+}
@@ -17,6 +17,13 @@ type Repo interface {
 
 	DropChanges(name []byte, finalHeight int32) error
 
+	// SetChanges replaces the changes of a node with the specified ones,
+	// which must be ordered by height.
+	SetChanges(name []byte, changes []change.Change) error
+
+	// Clear removes the changes of all nodes from the repo.
+	Clear() error
+
 	// Close closes the repo.
 	Close() error
 
@@ -28,4 +35,17 @@ type Repo interface {
 	IterateAll(predicate func(name []byte) bool)
 
 	Flush() error
+
+	// Compact compacts the storage of the repo, which reclaims the space
+	// of the replaced changes.
+	Compact() error
+
+	// CompactedHeight returns the final height of the compactions of the
+	// repo, below which the nodes can't be rebuilt, or 0 when the repo was
+	// never compacted.
+	CompactedHeight() (int32, error)
+
+	// SetCompactedHeight stores the final height of the compactions of the
+	// repo.  Clear resets it.
+	SetCompactedHeight(height int32) error
 }
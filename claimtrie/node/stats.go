@@ -0,0 +1,140 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Stats is the aggregate statistics of the nodes of the claim trie.
+type Stats struct {
+	Names           int64 // Number of names with claims or supports.
+	Claims          int64 // Number of claims, including pending ones.
+	Supports        int64 // Number of supports, including pending ones.
+	PendingClaims   int64 // Number of claims waiting for their activation.
+	PendingSupports int64 // Number of supports waiting for their activation.
+
+	// Depths holds the number of names at each depth of the trie, which is
+	// the length of the names in bytes.
+	Depths map[int]int64
+}
+
+// newStats returns new empty statistics.
+func newStats() *Stats {
+	return &Stats{Depths: map[int]int64{}}
+}
+
+// add adds the contribution of the node of the passed name, which may be nil,
+// to the statistics, or removes it when sign is negative.
+func (s *Stats) add(name []byte, n *Node, sign int64) {
+	if n == nil || len(n.Claims)+len(n.Supports) == 0 {
+		return
+	}
+
+	s.Names += sign
+	s.Claims += sign * int64(len(n.Claims))
+	s.Supports += sign * int64(len(n.Supports))
+	for _, c := range n.Claims {
+		if c.Status == Accepted {
+			s.PendingClaims += sign
+		}
+	}
+	for _, c := range n.Supports {
+		if c.Status == Accepted {
+			s.PendingSupports += sign
+		}
+	}
+
+	s.Depths[len(name)] += sign
+	if s.Depths[len(name)] == 0 {
+		delete(s.Depths, len(name))
+	}
+}
+
+// clone returns a deep copy of the statistics.
+func (s *Stats) clone() *Stats {
+	c := *s
+	c.Depths = make(map[int]int64, len(s.Depths))
+	for depth, names := range s.Depths {
+		c.Depths[depth] = names
+	}
+	return &c
+}
+
+// Stats returns the aggregate statistics of the nodes at the current height.
+// They are computed from all the names the first time, which can take a while,
+// and are then kept up to date by UpdateStats and DecrementHeightTo until the
+// cache is cleared.
+func (nm *BaseManager) Stats() (*Stats, error) {
+	nm.statsMtx.Lock()
+	defer nm.statsMtx.Unlock()
+
+	if nm.stats == nil {
+		stats := newStats()
+		var err error
+		nm.repo.IterateAll(func(name []byte) bool {
+			var n *Node
+			n, err = nm.loadNodeAt(nm.height, name)
+			if err != nil {
+				return false
+			}
+			stats.add(name, n, 1)
+			return true
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "in load node")
+		}
+		nm.stats = stats
+	}
+
+	return nm.stats.clone(), nil
+}
+
+// UpdateStats replaces the contribution of the nodes of the passed names at
+// the passed height to the statistics by their contribution at the current
+// height.  The names must be unique and include every name whose node differs
+// between both heights.  Nothing is done until the statistics are requested.
+func (nm *BaseManager) UpdateStats(names [][]byte, height int32) error {
+	nm.statsMtx.Lock()
+	defer nm.statsMtx.Unlock()
+
+	if nm.stats == nil {
+		return nil
+	}
+
+	for _, name := range names {
+		prev, err := nm.NodeAt(height, name)
+		if err != nil {
+			nm.stats = nil
+			return errors.Wrap(err, "in previous node")
+		}
+		cur, err := nm.NodeAt(nm.height, name)
+		if err != nil {
+			nm.stats = nil
+			return errors.Wrap(err, "in current node")
+		}
+		nm.stats.add(name, prev, -1)
+		nm.stats.add(name, cur, 1)
+	}
+	return nil
+}
+
+// clearStats drops the statistics, which are computed again from all the names
+// the next time they are requested.
+func (nm *BaseManager) clearStats() {
+	nm.statsMtx.Lock()
+	nm.stats = nil
+	nm.statsMtx.Unlock()
+}
+
+// loadNodeAt returns the node of the name at the height rebuilt from its
+// changes without going through the cache, so scanning all the names doesn't
+// evict the nodes in use.
+func (nm *BaseManager) loadNodeAt(height int32, name []byte) (*Node, error) {
+	changes, err := nm.repo.LoadChanges(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "in load changes")
+	}
+	if nm.tempChanges != nil {
+		changes = append(changes, nm.tempChanges[string(name)]...)
+	}
+	return nm.newNodeFromChanges(changes, height)
+}
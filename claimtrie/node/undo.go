@@ -0,0 +1,235 @@
+package node
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie/change"
+)
+
+// DefaultUndoDepth is the default number of blocks whose undo records are kept
+// by a BaseManager.
+const DefaultUndoDepth = 2000
+
+// UndoRepo defines APIs for the undo records of the nodes, which hold the state
+// of the nodes changed by each block as it was before the block.  They allow
+// restoring the nodes when the block is rolled back without replaying all the
+// changes of their names.
+type UndoRepo interface {
+	// SaveUndo saves the undo record of the block at the height, which
+	// holds the serialized nodes of the passed names before the block.  An
+	// empty serialized node stands for a name without node.
+	SaveUndo(height int32, names [][]byte, nodes [][]byte) error
+
+	// LoadUndo loads the undo record of the block at the height.  If there
+	// is no record, both returned slices and error will be nil.
+	LoadUndo(height int32) (names [][]byte, nodes [][]byte, err error)
+
+	// DropUndo removes the undo records of the blocks from the start height
+	// to the end height, both included.
+	DropUndo(start, end int32) error
+
+	// Clear removes all the undo records from the repo.
+	Clear() error
+
+	// Close closes the repo.
+	Close() error
+
+	Flush() error
+}
+
+// SetUndoRepo sets the repo the undo records of the nodes are saved to, the
+// number of blocks whose records are kept, and the height of the first block
+// with a record.  Nodes are rebuilt from all their changes on rollbacks of the
+// blocks without record, and of all the blocks without an undo repo.
+func (nm *BaseManager) SetUndoRepo(repo UndoRepo, depth, startHeight int32) {
+	nm.undoRepo = repo
+	nm.undoDepth = depth
+	nm.undoStartHeight = startHeight
+}
+
+// saveUndo saves the undo record of the block at the passed height, which is
+// the next one, from the current state of the nodes of the pending changes,
+// and removes the record which became too deep to be kept.
+func (nm *BaseManager) saveUndo(height int32) error {
+	seen := make(map[string]bool, len(nm.changes))
+	names := make([][]byte, 0, len(nm.changes))
+	nodes := make([][]byte, 0, len(nm.changes))
+	for i := range nm.changes {
+		name := nm.changes[i].Name
+		if seen[string(name)] {
+			continue
+		}
+		seen[string(name)] = true
+
+		n, err := nm.NodeAt(nm.height, name)
+		if err != nil {
+			return errors.Wrap(err, "in node at")
+		}
+		var buffer bytes.Buffer
+		if n != nil {
+			n.Marshal(&buffer)
+		}
+		names = append(names, name)
+		nodes = append(nodes, buffer.Bytes())
+	}
+
+	if err := nm.undoRepo.SaveUndo(height, names, nodes); err != nil {
+		return errors.Wrap(err, "in save undo")
+	}
+	if expired := height - nm.undoDepth; expired > 0 {
+		return errors.Wrap(nm.undoRepo.DropUndo(expired, expired), "in drop undo")
+	}
+	return nil
+}
+
+// loadUndo returns the nodes at the passed height restored from the undo record
+// of the next block, keyed by name, and removes the records of the blocks above
+// the height.  Names without node are omitted.
+func (nm *BaseManager) loadUndo(height int32) (map[string]*Node, error) {
+	names, nodes, err := nm.undoRepo.LoadUndo(height + 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "in load undo")
+	}
+	if err = nm.undoRepo.DropUndo(height+1, nm.height); err != nil {
+		return nil, errors.Wrap(err, "in drop undo")
+	}
+
+	restored := make(map[string]*Node, len(names))
+	for i, name := range names {
+		if len(nodes[i]) == 0 {
+			continue
+		}
+		n := New()
+		if err := n.Unmarshal(bytes.NewBuffer(nodes[i])); err != nil {
+			return nil, errors.Wrapf(err, "in unmarshal %s", name)
+		}
+		restored[string(name)] = n
+	}
+	return restored, nil
+}
+
+// claimSerializeSize is the size of a serialized claim.
+const claimSerializeSize = chainhash.HashSize + 4 + change.ClaimIDSize + 8 + 5*4
+
+func marshalClaim(enc *bytes.Buffer, c *Claim) {
+	var temp [8]byte
+	enc.Write(c.OutPoint.Hash[:])
+	binary.BigEndian.PutUint32(temp[:4], c.OutPoint.Index)
+	enc.Write(temp[:4])
+	enc.Write(c.ClaimID[:])
+	binary.BigEndian.PutUint64(temp[:], uint64(c.Amount))
+	enc.Write(temp[:])
+	for _, v := range []int32{c.AcceptedAt, c.ActiveAt, c.VisibleAt, int32(c.Status), c.Sequence} {
+		binary.BigEndian.PutUint32(temp[:4], uint32(v))
+		enc.Write(temp[:4])
+	}
+}
+
+func unmarshalClaim(dec *bytes.Buffer, c *Claim) {
+	copy(c.OutPoint.Hash[:], dec.Next(chainhash.HashSize))
+	c.OutPoint.Index = binary.BigEndian.Uint32(dec.Next(4))
+	copy(c.ClaimID[:], dec.Next(change.ClaimIDSize))
+	c.Amount = int64(binary.BigEndian.Uint64(dec.Next(8)))
+	c.AcceptedAt = int32(binary.BigEndian.Uint32(dec.Next(4)))
+	c.ActiveAt = int32(binary.BigEndian.Uint32(dec.Next(4)))
+	c.VisibleAt = int32(binary.BigEndian.Uint32(dec.Next(4)))
+	c.Status = Status(binary.BigEndian.Uint32(dec.Next(4)))
+	c.Sequence = int32(binary.BigEndian.Uint32(dec.Next(4)))
+}
+
+// Marshal serializes the node, including the state of its claims and supports
+// at the height it was last adjusted to.
+func (n *Node) Marshal(enc *bytes.Buffer) {
+	var temp [8]byte
+	binary.BigEndian.PutUint32(temp[:4], uint32(n.TakenOverAt))
+	enc.Write(temp[:4])
+
+	// The best claim is referenced by its index in the claims.
+	best := int32(-1)
+	for i, c := range n.Claims {
+		if c == n.BestClaim {
+			best = int32(i)
+		}
+	}
+	binary.BigEndian.PutUint32(temp[:4], uint32(best))
+	enc.Write(temp[:4])
+
+	for _, list := range []ClaimList{n.Claims, n.Supports} {
+		binary.BigEndian.PutUint32(temp[:4], uint32(len(list)))
+		enc.Write(temp[:4])
+		for _, c := range list {
+			marshalClaim(enc, c)
+		}
+	}
+
+	// The keys are sorted so the encoding is deterministic.
+	keys := make([]string, 0, len(n.SupportSums))
+	for key := range n.SupportSums {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	binary.BigEndian.PutUint32(temp[:4], uint32(len(keys)))
+	enc.Write(temp[:4])
+	for _, key := range keys {
+		binary.BigEndian.PutUint16(temp[:2], uint16(len(key)))
+		enc.Write(temp[:2])
+		enc.WriteString(key)
+		binary.BigEndian.PutUint64(temp[:], uint64(n.SupportSums[key]))
+		enc.Write(temp[:])
+	}
+}
+
+// Unmarshal deserializes a node serialized by Marshal into the receiver.
+func (n *Node) Unmarshal(dec *bytes.Buffer) error {
+	if dec.Len() < 12 {
+		return errors.New("truncated node")
+	}
+	n.TakenOverAt = int32(binary.BigEndian.Uint32(dec.Next(4)))
+	best := int32(binary.BigEndian.Uint32(dec.Next(4)))
+
+	lists := []*ClaimList{&n.Claims, &n.Supports}
+	for i, list := range lists {
+		if dec.Len() < 4 {
+			return errors.New("truncated claim list")
+		}
+		count := int(binary.BigEndian.Uint32(dec.Next(4)))
+		if dec.Len() < count*claimSerializeSize {
+			return errors.New("truncated claims")
+		}
+		claims := make([]Claim, count)
+		*list = make(ClaimList, count)
+		for j := range claims {
+			unmarshalClaim(dec, &claims[j])
+			(*list)[j] = &claims[j]
+		}
+		if i == 0 && best >= 0 {
+			if int(best) >= count {
+				return errors.Errorf("invalid best claim index %d", best)
+			}
+			n.BestClaim = n.Claims[best]
+		}
+	}
+
+	if dec.Len() < 4 {
+		return errors.New("truncated support sums")
+	}
+	count := int(binary.BigEndian.Uint32(dec.Next(4)))
+	n.SupportSums = make(map[string]int64, count)
+	for i := 0; i < count; i++ {
+		if dec.Len() < 2 {
+			return errors.New("truncated support sum")
+		}
+		keySize := int(binary.BigEndian.Uint16(dec.Next(2)))
+		if dec.Len() < keySize+8 {
+			return errors.New("truncated support sum")
+		}
+		key := string(dec.Next(keySize))
+		n.SupportSums[key] = int64(binary.BigEndian.Uint64(dec.Next(8)))
+	}
+	return nil
+}
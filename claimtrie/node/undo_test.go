@@ -0,0 +1,273 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/claimtrie/node/noderepo"
+	"github.com/lbryio/lbcd/claimtrie/param"
+	"github.com/lbryio/lbcd/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newUndoManager returns a base manager keeping the undo records of the last
+// depth blocks from the one at startHeight, or none when depth is zero.
+func newUndoManager(tb testing.TB, depth, startHeight int32) *BaseManager {
+	r := require.New(tb)
+
+	repo, err := noderepo.NewPebble(tb.TempDir())
+	r.NoError(err)
+
+	m, err := NewBaseManager(repo)
+	r.NoError(err)
+	if depth > 0 {
+		undoRepo, err := noderepo.NewUndoPebble(tb.TempDir())
+		r.NoError(err)
+		m.SetUndoRepo(undoRepo, depth, startHeight)
+	}
+	return m
+}
+
+// appendSupport appends a support of the passed amount for the claim of out1
+// of name1 in the block at the passed height.
+func appendSupport(m *BaseManager, height int32, amount int64) {
+	op := wire.OutPoint{Index: uint32(height)}
+	chg := change.NewChange(change.AddSupport).SetName(name1).SetOutPoint(&op).SetHeight(height).SetAmount(amount)
+	chg.ClaimID = change.NewClaimID(*out1)
+	m.AppendChange(chg)
+}
+
+func TestNodeMarshal(t *testing.T) {
+
+	r := require.New(t)
+
+	n := New()
+	r.NoError(n.ApplyChange(change.NewChange(change.AddClaim).SetOutPoint(out1).SetHeight(11).SetAmount(3), 0))
+	r.NoError(n.ApplyChange(change.NewChange(change.AddClaim).SetOutPoint(out2).SetHeight(11).SetAmount(4), 5))
+	chg := change.NewChange(change.AddSupport).SetOutPoint(out3).SetHeight(12).SetAmount(2)
+	chg.ClaimID = n.Claims[0].ClaimID
+	r.NoError(n.ApplyChange(chg, 0))
+	n.AdjustTo(11, 12, name1)
+	r.NotNil(n.BestClaim)
+
+	var buffer bytes.Buffer
+	n.Marshal(&buffer)
+	data := append([]byte(nil), buffer.Bytes()...)
+
+	restored := New()
+	r.NoError(restored.Unmarshal(&buffer))
+	r.Equal(0, buffer.Len())
+	r.Equal(n, restored)
+
+	// Truncated nodes are rejected.
+	for i := 0; i < len(data); i++ {
+		r.Error(New().Unmarshal(bytes.NewBuffer(data[:i])), "length %d", i)
+	}
+}
+
+func TestUndoRollback(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	m := newUndoManager(t, 5, 0)
+	defer m.Close()
+
+	_, err := m.IncrementHeightTo(10, false)
+	r.NoError(err)
+
+	chg := change.NewChange(change.AddClaim).SetName(name1).SetOutPoint(out1).SetHeight(11).SetAmount(3)
+	chg.ClaimID = change.NewClaimID(*out1)
+	m.AppendChange(chg)
+	_, err = m.IncrementHeightTo(11, false)
+	r.NoError(err)
+
+	// Record the state of the node after each block changing it.
+	states := map[int32][]byte{}
+	for height := int32(12); height <= 20; height++ {
+		n, err := m.node(name1)
+		r.NoError(err)
+		var buffer bytes.Buffer
+		n.Marshal(&buffer)
+		states[height-1] = buffer.Bytes()
+
+		appendSupport(m, height, int64(height))
+		_, err = m.IncrementHeightTo(height, false)
+		r.NoError(err)
+	}
+
+	// The nodes of the blocks within the undo depth are restored from their
+	// undo records rather than from their changes.
+	for height := int32(19); height >= 15; height-- {
+		misses := m.CacheStats().Misses
+		_, err = m.DecrementHeightTo([][]byte{name1}, height)
+		r.NoError(err)
+		n, err := m.node(name1)
+		r.NoError(err)
+		r.Equal(misses, m.CacheStats().Misses)
+
+		var buffer bytes.Buffer
+		n.Marshal(&buffer)
+		r.Equal(states[height], buffer.Bytes(), "height %d", height)
+	}
+
+	// Deeper nodes are rebuilt from their changes.
+	misses := m.CacheStats().Misses
+	_, err = m.DecrementHeightTo([][]byte{name1}, 14)
+	r.NoError(err)
+	n, err := m.node(name1)
+	r.NoError(err)
+	r.Equal(misses+1, m.CacheStats().Misses)
+	var buffer bytes.Buffer
+	n.Marshal(&buffer)
+	r.Equal(states[14], buffer.Bytes())
+
+	// The undo records of the rolled back blocks were dropped.
+	names, nodes, err := m.undoRepo.LoadUndo(15)
+	r.NoError(err)
+	r.Nil(names)
+	r.Nil(nodes)
+
+	// Rolling back past the creation of the node restores its absence.
+	_, err = m.DecrementHeightTo([][]byte{name1}, 10)
+	r.NoError(err)
+	n, err = m.node(name1)
+	r.NoError(err)
+	r.Nil(n)
+}
+
+func TestUndoStartHeight(t *testing.T) {
+
+	r := require.New(t)
+
+	param.SetNetwork(wire.TestNet)
+	m := newUndoManager(t, 5, 14)
+	defer m.Close()
+
+	_, err := m.IncrementHeightTo(10, false)
+	r.NoError(err)
+	chg := change.NewChange(change.AddClaim).SetName(name1).SetOutPoint(out1).SetHeight(11).SetAmount(3)
+	chg.ClaimID = change.NewClaimID(*out1)
+	m.AppendChange(chg)
+	_, err = m.IncrementHeightTo(11, false)
+	r.NoError(err)
+
+	states := map[int32][]byte{}
+	for height := int32(12); height <= 16; height++ {
+		n, err := m.node(name1)
+		r.NoError(err)
+		var buffer bytes.Buffer
+		n.Marshal(&buffer)
+		states[height-1] = buffer.Bytes()
+
+		appendSupport(m, height, int64(height))
+		_, err = m.IncrementHeightTo(height, false)
+		r.NoError(err)
+	}
+
+	// Only the blocks from the start height have undo records.
+	for height := int32(11); height <= 16; height++ {
+		names, _, err := m.undoRepo.LoadUndo(height)
+		r.NoError(err)
+		r.Equal(height >= 14, names != nil, "height %d", height)
+	}
+
+	// The nodes are restored from the changes below the start height.
+	for height := int32(15); height >= 11; height-- {
+		_, err = m.DecrementHeightTo([][]byte{name1}, height)
+		r.NoError(err)
+		n, err := m.node(name1)
+		r.NoError(err)
+		var buffer bytes.Buffer
+		n.Marshal(&buffer)
+		r.Equal(states[height], buffer.Bytes(), "height %d", height)
+	}
+}
+
+// benchmarkDeepReorg measures rolling back depth blocks changing a name with a
+// long history, one block at a time as the chain does.
+func benchmarkDeepReorg(b *testing.B, undoDepth int32, history, depth int32) {
+	r := require.New(b)
+
+	param.SetNetwork(wire.TestNet)
+	m := newUndoManager(b, undoDepth, 0)
+	defer m.Close()
+
+	chg := change.NewChange(change.AddClaim).SetName(name1).SetOutPoint(out1).SetHeight(1).SetAmount(3)
+	chg.ClaimID = change.NewClaimID(*out1)
+	m.AppendChange(chg)
+	_, err := m.IncrementHeightTo(1, false)
+	r.NoError(err)
+	for height := int32(2); height <= history; height++ {
+		appendSupport(m, height, 1)
+		_, err = m.IncrementHeightTo(height, false)
+		r.NoError(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for height := history + 1; height <= history+depth; height++ {
+			appendSupport(m, height, 1)
+			_, err = m.IncrementHeightTo(height, false)
+			r.NoError(err)
+			_, err = m.node(name1)
+			r.NoError(err)
+		}
+		b.StartTimer()
+
+		for height := history + depth - 1; height >= history; height-- {
+			_, err = m.DecrementHeightTo([][]byte{name1}, height)
+			r.NoError(err)
+			_, err = m.node(name1)
+			r.NoError(err)
+		}
+	}
+}
+
+func BenchmarkDeepReorg(b *testing.B) {
+	for _, history := range []int32{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("replay/history%d", history), func(b *testing.B) {
+			benchmarkDeepReorg(b, 0, history, 100)
+		})
+		b.Run(fmt.Sprintf("undo/history%d", history), func(b *testing.B) {
+			benchmarkDeepReorg(b, DefaultUndoDepth, history, 100)
+		})
+	}
+}
+
+// BenchmarkIncrementHeight measures connecting blocks changing distinct names,
+// as during the initial block download, with and without saving their undo
+// records.  The records cost an extra node lookup and write per changed name,
+// which is why no records are saved for the blocks up to the last checkpoint.
+func BenchmarkIncrementHeight(b *testing.B) {
+	for _, undoDepth := range []int32{0, DefaultUndoDepth} {
+		b.Run(fmt.Sprintf("undodepth%d", undoDepth), func(b *testing.B) {
+			r := require.New(b)
+
+			param.SetNetwork(wire.TestNet)
+			m := newUndoManager(b, undoDepth, 0)
+			defer m.Close()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				height := int32(i) + 1
+				for j := 0; j < 100; j++ {
+					op := wire.OutPoint{Index: uint32(j)}
+					op.Hash[0] = byte(i)
+					op.Hash[1] = byte(i >> 8)
+					op.Hash[2] = byte(i >> 16)
+					name := []byte(fmt.Sprintf("name%d-%d", i, j))
+					chg := change.NewChange(change.AddClaim).SetName(name).SetOutPoint(&op).SetHeight(height).SetAmount(1)
+					chg.ClaimID = change.NewClaimID(op)
+					m.AppendChange(chg)
+				}
+				_, err := m.IncrementHeightTo(height, false)
+				r.NoError(err)
+			}
+		})
+	}
+}
@@ -7,13 +7,23 @@ import (
 var Normalize = normalizeGo
 var NormalizeTitle = "Normalizing strings via Go. Casefold and NFD table versions: 11.0.0 (from ICU 63.2)"
 
+// NormalizeIfNecessary returns the form of the name used for bidding by a
+// claim or support made at the given height.  Names are used as-is before the
+// normalization fork and normalized (NFD decomposed and case folded) from the
+// fork height on.
 func NormalizeIfNecessary(name []byte, height int32) []byte {
-	if height < param.ActiveParams.NormalizedNameForkHeight {
+	if !IsNormalizationActive(height) {
 		return name
 	}
 	return Normalize(name)
 }
 
+// IsNormalizationActive returns whether or not names are normalized for claims
+// and supports made at the given height.
+func IsNormalizationActive(height int32) bool {
+	return height >= param.ActiveParams.NormalizedNameForkHeight
+}
+
 func normalizeGo(value []byte) []byte {
 
 	normalized := decompose(value) // may need to hard-code the version on this
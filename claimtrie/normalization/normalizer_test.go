@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/lbryio/lbcd/claimtrie/param"
 	"github.com/stretchr/testify/require"
 )
 
@@ -31,6 +32,17 @@ func testNormalization(t *testing.T, normalize func(value []byte) []byte) {
 	r.Equal("\xE1\x84\x81\xE1\x85\xAA\xE1\x86\xB0", string(normalize([]byte("\xEA\xBD\x91"))))
 }
 
+func TestNormalizeIfNecessary(t *testing.T) {
+
+	r := require.New(t)
+
+	fork := param.ActiveParams.NormalizedNameForkHeight
+	r.False(IsNormalizationActive(fork - 1))
+	r.True(IsNormalizationActive(fork))
+	r.Equal("TESt", string(NormalizeIfNecessary([]byte("TESt"), fork-1)))
+	r.Equal("test", string(NormalizeIfNecessary([]byte("TESt"), fork)))
+}
+
 func randSeq(n int) []byte {
 	var alphabet = []rune("abcdefghijklmnopqrstuvwxyz̃ABCDEFGHIJKLMNOPQRSTUVWXYZ̃")
 
@@ -0,0 +1,86 @@
+package claimtrie
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie/merkletrie"
+	"github.com/lbryio/lbcd/claimtrie/node"
+	"github.com/lbryio/lbcd/claimtrie/param"
+	"github.com/lbryio/lbcd/wire"
+)
+
+// ClaimProof proves an active claim of a name is part of the claim trie with a
+// merkle path from the hash of the claim up to the root of the claim trie.
+type ClaimProof struct {
+	// Name is the normalized name of the claim.
+	Name []byte
+
+	// OutPoint is the outpoint of the claim.
+	OutPoint wire.OutPoint
+
+	// LastTakeoverHeight is the last takeover height of the name, which is
+	// part of the hash of the claim.
+	LastTakeoverHeight int32
+
+	// Pairs are the hashes combined with the hash of the claim, from the
+	// claims of the name up to the root.
+	Pairs []node.ProofPair
+
+	// Height and ClaimTrieRoot are the height and the root of the claim
+	// trie the claim is proven to be part of.
+	Height        int32
+	ClaimTrieRoot *chainhash.Hash
+}
+
+// Root returns the root of the claim trie computed from the claim and the
+// pairs of the proof, which matches ClaimTrieRoot for a valid proof.
+func (p *ClaimProof) Root() *chainhash.Hash {
+	return node.ComputeProofRoot(node.ClaimHash(p.OutPoint, p.LastTakeoverHeight), p.Pairs)
+}
+
+// ProveClaim returns the proof the active claim with the passed outpoint is a
+// claim of the passed normalized name in the claim trie at its current height.
+// Proofs are only available once all claims are in the merkle trie, with the
+// merkle trie kept in memory.
+func (ct *ClaimTrie) ProveClaim(name []byte, op wire.OutPoint) (*ClaimProof, error) {
+
+	if ct.height < param.ActiveParams.AllClaimsInMerkleForkHeight {
+		return nil, errors.Errorf("claim proofs are only available from height %d",
+			param.ActiveParams.AllClaimsInMerkleForkHeight)
+	}
+	trie, ok := ct.merkleTrie.(*merkletrie.RamTrie)
+	if !ok {
+		return nil, errors.New("claim proofs require the merkle trie to be kept in memory")
+	}
+
+	n, err := ct.nodeManager.NodeAt(ct.height, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "node at")
+	}
+	if n == nil {
+		return nil, errors.Errorf("name does not exist: %s", name)
+	}
+	claimPairs, ok := node.ClaimProofPath(n, op)
+	if !ok {
+		return nil, errors.Errorf("no active claim %s for name %s", op, name)
+	}
+	triePairs, ok := trie.ProofAllClaims(name)
+	if !ok {
+		return nil, errors.Errorf("name %s is not in the merkle trie", name)
+	}
+
+	proof := &ClaimProof{
+		Name:               name,
+		OutPoint:           op,
+		LastTakeoverHeight: n.TakenOverAt,
+		Pairs:              append(claimPairs, triePairs...),
+		Height:             ct.height,
+		ClaimTrieRoot:      ct.MerkleHash(),
+	}
+	if !proof.Root().IsEqual(proof.ClaimTrieRoot) {
+		return nil, errors.Errorf("proof of claim %s for name %s doesn't match the root %s",
+			op, name, proof.ClaimTrieRoot)
+	}
+	return proof, nil
+}
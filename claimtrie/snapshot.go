@@ -0,0 +1,526 @@
+package claimtrie
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/claimtrie/merkletrie"
+	"github.com/lbryio/lbcd/claimtrie/node"
+	"github.com/lbryio/lbcd/claimtrie/normalization"
+)
+
+// A snapshot holds the state of the claim trie at a given height in a portable
+// form.  It is laid out as follows, with all integers in big endian:
+//
+//	magic (8 bytes) | version (4 bytes) | height (4 bytes) | root (32 bytes)
+//	claim trie root of each height from 0 to height (32 bytes each)
+//	nodes, sorted by name, each one encoded as:
+//	  1 (1 byte) | name length (2 bytes) | name | data length (4 bytes) | changes
+//	0 (1 byte)
+//	sha256 of all of the above (32 bytes)
+//
+// Only the changes which are visible at the snapshot height are included, so
+// the snapshot of a given height is identical on every node.
+
+const (
+	// SnapshotVersion is the current version of the snapshot format.
+	SnapshotVersion = 1
+
+	// snapshotBatchSize is the number of changes or temporal entries which
+	// are written to the repos at a time during an import.
+	snapshotBatchSize = 10000
+
+	// snapshotRollbackDepth is the number of blocks below the snapshot
+	// height that an imported claim trie can be rolled back.
+	snapshotRollbackDepth = 1000
+
+	// stagingDirName is the name of the directory, next to the databases
+	// of the claim trie, which holds the claim trie staged from a snapshot.
+	stagingDirName = "claim_dbs_staging"
+)
+
+// snapshotMagic identifies claim trie snapshots.
+var snapshotMagic = [8]byte{'l', 'b', 'c', 't', 'r', 'i', 'e', 0}
+
+// SnapshotHeader describes the claim trie state contained in a snapshot.
+type SnapshotHeader struct {
+	Version       uint32
+	Height        int32
+	ClaimTrieRoot chainhash.Hash
+}
+
+// ExportSnapshot writes the state of the claim trie at the given height, which
+// must not be above the current height, to w.
+func (ct *ClaimTrie) ExportSnapshot(w io.Writer, height int32) (*SnapshotHeader, error) {
+
+	if height < 0 || height > ct.height {
+		return nil, errors.Errorf("snapshot height %d is outside of the claim trie range 0 to %d",
+			height, ct.height)
+	}
+
+	root, err := ct.blockRepo.Get(height)
+	if err != nil {
+		return nil, errors.Wrap(err, "block repo get")
+	}
+	header := &SnapshotHeader{
+		Version:       SnapshotVersion,
+		Height:        height,
+		ClaimTrieRoot: *root,
+	}
+
+	bw := bufio.NewWriter(w)
+	hasher := sha256.New()
+	enc := io.MultiWriter(bw, hasher)
+
+	var buf [8]byte
+	enc.Write(snapshotMagic[:]) // nolint : errchk
+	binary.BigEndian.PutUint32(buf[:4], header.Version)
+	enc.Write(buf[:4]) // nolint : errchk
+	binary.BigEndian.PutUint32(buf[:4], uint32(header.Height))
+	enc.Write(buf[:4])                 // nolint : errchk
+	enc.Write(header.ClaimTrieRoot[:]) // nolint : errchk
+
+	for h := int32(0); h <= height; h++ {
+		hash, err := ct.blockRepo.Get(h)
+		if err != nil {
+			return nil, errors.Wrapf(err, "block repo get at %d", h)
+		}
+		enc.Write(hash[:]) // nolint : errchk
+	}
+
+	var iterErr error
+	data := bytes.NewBuffer(nil)
+	ct.nodeRepo.IterateAll(func(name []byte) bool {
+		changes, err := ct.nodeRepo.LoadChanges(name)
+		if err != nil {
+			iterErr = errors.Wrapf(err, "load changes for %s", name)
+			return false
+		}
+
+		// Keep the changes visible at the snapshot height, the same way
+		// they are kept when the trie is rolled back to it.
+		data.Reset()
+		for i := range changes {
+			if changes[i].Height > height {
+				break
+			}
+			if changes[i].VisibleHeight > height {
+				continue
+			}
+			if err = changes[i].Marshal(data); err != nil {
+				iterErr = errors.Wrap(err, "in marshaller")
+				return false
+			}
+		}
+		if data.Len() == 0 {
+			return true
+		}
+
+		buf[0] = 1
+		enc.Write(buf[:1]) // nolint : errchk
+		binary.BigEndian.PutUint16(buf[:2], uint16(len(name)))
+		enc.Write(buf[:2]) // nolint : errchk
+		enc.Write(name)    // nolint : errchk
+		binary.BigEndian.PutUint32(buf[:4], uint32(data.Len()))
+		enc.Write(buf[:4])      // nolint : errchk
+		enc.Write(data.Bytes()) // nolint : errchk
+		return true
+	})
+	if iterErr != nil {
+		return nil, iterErr
+	}
+
+	buf[0] = 0
+	enc.Write(buf[:1])        // nolint : errchk
+	bw.Write(hasher.Sum(nil)) // nolint : errchk
+
+	return header, errors.Wrap(bw.Flush(), "in flush")
+}
+
+// ReadSnapshotHeader reads the header of a snapshot from r without verifying
+// the rest of the snapshot.
+func ReadSnapshotHeader(r io.Reader) (*SnapshotHeader, error) {
+
+	var raw [48]byte
+	if _, err := io.ReadFull(r, raw[:]); err != nil {
+		return nil, errors.Wrap(err, "reading snapshot header")
+	}
+	if !bytes.Equal(raw[:8], snapshotMagic[:]) {
+		return nil, errors.New("not a claim trie snapshot")
+	}
+
+	header := &SnapshotHeader{
+		Version: binary.BigEndian.Uint32(raw[8:12]),
+		Height:  int32(binary.BigEndian.Uint32(raw[12:16])),
+	}
+	copy(header.ClaimTrieRoot[:], raw[16:])
+
+	if header.Version != SnapshotVersion {
+		return nil, errors.Errorf("unsupported snapshot version %d", header.Version)
+	}
+	if header.Height < 0 {
+		return nil, errors.Errorf("invalid snapshot height %d", header.Height)
+	}
+	return header, nil
+}
+
+// readSnapshot reads the snapshot from r, invoking the provided callbacks for
+// each of its parts.  Any of the callbacks may be nil.  An error is returned if
+// the snapshot is malformed or doesn't match its checksum.
+func readSnapshot(r io.Reader, onHeader func(*SnapshotHeader) error,
+	onHash func(int32, *chainhash.Hash) error, onNode func([]byte, []change.Change) error) (*SnapshotHeader, error) {
+
+	br := bufio.NewReader(r)
+	hasher := sha256.New()
+	dec := io.TeeReader(br, hasher)
+
+	header, err := ReadSnapshotHeader(dec)
+	if err != nil {
+		return nil, err
+	}
+	if onHeader != nil {
+		if err = onHeader(header); err != nil {
+			return nil, err
+		}
+	}
+
+	var hash chainhash.Hash
+	for h := int32(0); h <= header.Height; h++ {
+		if _, err = io.ReadFull(dec, hash[:]); err != nil {
+			return nil, errors.Wrapf(err, "reading hash at %d", h)
+		}
+		if onHash != nil {
+			if err = onHash(h, &hash); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf [4]byte
+	var data []byte
+	for {
+		if _, err = io.ReadFull(dec, buf[:1]); err != nil {
+			return nil, errors.Wrap(err, "reading node marker")
+		}
+		if buf[0] == 0 {
+			break
+		}
+		if buf[0] != 1 {
+			return nil, errors.Errorf("invalid node marker %d", buf[0])
+		}
+
+		if _, err = io.ReadFull(dec, buf[:2]); err != nil {
+			return nil, errors.Wrap(err, "reading name length")
+		}
+		name := make([]byte, binary.BigEndian.Uint16(buf[:2]))
+		if _, err = io.ReadFull(dec, name); err != nil {
+			return nil, errors.Wrap(err, "reading name")
+		}
+
+		if _, err = io.ReadFull(dec, buf[:4]); err != nil {
+			return nil, errors.Wrapf(err, "reading data length of %s", name)
+		}
+		size := binary.BigEndian.Uint32(buf[:4])
+		if uint32(cap(data)) < size {
+			data = make([]byte, size)
+		}
+		data = data[:size]
+		if _, err = io.ReadFull(dec, data); err != nil {
+			return nil, errors.Wrapf(err, "reading data of %s", name)
+		}
+
+		if onNode == nil {
+			continue
+		}
+		var changes []change.Change
+		decoder := bytes.NewBuffer(data)
+		for decoder.Len() > 0 {
+			var chg change.Change
+			if err = chg.Unmarshal(decoder); err != nil {
+				return nil, errors.Wrapf(err, "decoding changes of %s", name)
+			}
+			chg.Name = name
+			changes = append(changes, chg)
+		}
+		if err = onNode(name, changes); err != nil {
+			return nil, err
+		}
+	}
+
+	var checksum [sha256.Size]byte
+	if _, err = io.ReadFull(br, checksum[:]); err != nil {
+		return nil, errors.Wrap(err, "reading checksum")
+	}
+	if !bytes.Equal(checksum[:], hasher.Sum(nil)) {
+		return nil, errors.New("snapshot checksum mismatch")
+	}
+	return header, nil
+}
+
+// VerifySnapshot reads the entire snapshot from r and ensures it is well
+// formed and matches its checksum.
+func VerifySnapshot(r io.Reader) (*SnapshotHeader, error) {
+	return readSnapshot(r, nil, nil, nil)
+}
+
+// ImportSnapshot replaces the state of the claim trie with the snapshot read
+// from r.  The snapshot is verified before the current state is dropped, and
+// the claim trie root rebuilt from it must match the one of the snapshot.  If
+// the import fails after the current state has been dropped, the claim trie is
+// left empty at height 0.
+func (ct *ClaimTrie) ImportSnapshot(r io.ReadSeeker) (*SnapshotHeader, error) {
+
+	header, err := VerifySnapshot(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "verifying snapshot")
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "rewinding snapshot")
+	}
+
+	if err = ct.clear(); err != nil {
+		return nil, errors.Wrap(err, "clearing claim trie")
+	}
+	if err = ct.importSnapshot(r, header); err != nil {
+		if clearErr := ct.clear(); clearErr != nil {
+			node.Warn("During claim trie clear: " + clearErr.Error())
+		}
+		return nil, err
+	}
+	return header, nil
+}
+
+// StageSnapshot imports the snapshot read from r into a new claim trie stored
+// next to the claim trie, which is left untouched and can still be used
+// meanwhile.  The staged claim trie is then either swapped in with Replace or
+// dropped with Discard.  Only one claim trie can be staged at a time.
+func (ct *ClaimTrie) StageSnapshot(r io.ReadSeeker) (*ClaimTrie, *SnapshotHeader, error) {
+
+	cfg := ct.cfg
+	cfg.DataDir = filepath.Join(ct.cfg.DataDir, stagingDirName)
+	if err := os.RemoveAll(cfg.DataDir); err != nil {
+		return nil, nil, errors.Wrap(err, "removing staged claim trie")
+	}
+	staged, err := New(cfg)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "creating staged claim trie")
+	}
+	staged.stagingDir = cfg.DataDir
+
+	header, err := staged.ImportSnapshot(r)
+	if err != nil {
+		if discardErr := staged.Discard(); discardErr != nil {
+			node.Warn("During staged claim trie discard: " + discardErr.Error())
+		}
+		return nil, nil, err
+	}
+	return staged, header, nil
+}
+
+// Replace replaces the state of the claim trie with the one of the passed claim
+// trie created by StageSnapshot, whose databases are moved in place of the
+// databases of the claim trie.  Both claim tries are closed meanwhile, and the
+// staged one must not be used afterwards.  The claim trie is left closed if its
+// databases can't be replaced or reopened.
+func (ct *ClaimTrie) Replace(staged *ClaimTrie) error {
+
+	if staged.stagingDir == "" {
+		return errors.New("the claim trie was not staged")
+	}
+
+	// The RAM trie is kept rather than rebuilt when the claim trie is
+	// reopened.
+	ramTrie, _ := staged.merkleTrie.(*merkletrie.RamTrie)
+	staged.FlushToDisk()
+	staged.Close()
+	ct.Close()
+
+	// Move the databases aside before moving the staged ones in, so the
+	// claim trie is only missing, and thus rebuilt from the blocks on the
+	// next start, if interrupted in between.
+	old := filepath.Join(staged.stagingDir, "replaced")
+	if err := os.Rename(dataDir(ct.cfg), old); err != nil {
+		return errors.Wrap(err, "moving claim trie databases")
+	}
+	if err := os.Rename(dataDir(staged.cfg), dataDir(ct.cfg)); err != nil {
+		return errors.Wrap(err, "moving staged claim trie databases")
+	}
+	if err := os.RemoveAll(staged.stagingDir); err != nil {
+		node.Warn("During replaced claim trie removal: " + err.Error())
+	}
+
+	replaced, err := open(ct.cfg, ramTrie)
+	if err != nil {
+		return errors.Wrap(err, "reopening claim trie")
+	}
+	*ct = *replaced
+	return nil
+}
+
+// Discard closes the claim trie created by StageSnapshot and deletes it.
+func (ct *ClaimTrie) Discard() error {
+
+	if ct.stagingDir == "" {
+		return errors.New("the claim trie was not staged")
+	}
+	ct.Close()
+	return errors.Wrap(os.RemoveAll(ct.stagingDir), "removing staged claim trie")
+}
+
+// importSnapshot loads the snapshot into the emptied claim trie and rebuilds
+// the merkle trie and the pending temporal entries from it.
+func (ct *ClaimTrie) importSnapshot(r io.Reader, header *SnapshotHeader) error {
+
+	node.Log("Importing the claim trie snapshot...")
+
+	var pending []change.Change
+	onHash := func(height int32, hash *chainhash.Hash) error {
+		return errors.Wrap(ct.blockRepo.Set(height, hash), "block repo set")
+	}
+	onNode := func(name []byte, changes []change.Change) error {
+		pending = append(pending, changes...)
+		if len(pending) < snapshotBatchSize {
+			return nil
+		}
+		err := ct.nodeRepo.AppendChanges(pending)
+		pending = pending[:0]
+		return errors.Wrap(err, "node repo append")
+	}
+	if _, err := readSnapshot(r, nil, onHash, onNode); err != nil {
+		return err
+	}
+	if err := ct.nodeRepo.AppendChanges(pending); err != nil {
+		return errors.Wrap(err, "node repo append")
+	}
+
+	if header.Height > 0 {
+		if _, err := ct.nodeManager.IncrementHeightTo(header.Height, false); err != nil {
+			return errors.Wrap(err, "node manager increment")
+		}
+	}
+	ct.height = header.Height
+
+	// Rebuild the merkle trie, and restore the temporal entries which
+	// AppendBlock would have recorded for the recent blocks so the claim
+	// trie can still be rolled back below the snapshot height.
+	node.Log("Building the entire claim trie in RAM...")
+	ct.claimLogger = newClaimProgressLogger("Processed", node.GetLogger())
+
+	start := ct.height - snapshotRollbackDepth
+	if start < 0 {
+		start = 0
+	}
+
+	var names [][]byte
+	var heights []int32
+	var err error
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		clone := make([]byte, len(name))
+		copy(clone, name)
+		hash, _ := ct.nodeManager.Hash(clone)
+		ct.merkleTrie.Update(clone, hash, false)
+		ct.claimLogger.LogName(name)
+
+		names, heights, err = ct.scheduleUpdates(clone, start, names, heights)
+		if err == nil && len(names) >= snapshotBatchSize {
+			err = ct.temporalRepo.SetNodesAt(names, heights)
+			names, heights = names[:0], heights[:0]
+		}
+		return err == nil
+	})
+	if err == nil {
+		err = ct.temporalRepo.SetNodesAt(names, heights)
+	}
+	if err != nil {
+		return errors.Wrap(err, "temporal repo set")
+	}
+
+	if !ct.MerkleHash().IsEqual(&header.ClaimTrieRoot) {
+		return errors.Errorf("rebuilt claim trie root %s doesn't match the snapshot root %s",
+			ct.MerkleHash(), header.ClaimTrieRoot)
+	}
+
+	node.Log("Imported the claim trie snapshot")
+	return nil
+}
+
+// scheduleUpdates appends the heights above start at which the node of the
+// given name was updated, either by a change or by the activation or expiration
+// of its claims and supports, along with its next update after the current
+// height.
+func (ct *ClaimTrie) scheduleUpdates(name []byte, start int32, names [][]byte,
+	heights []int32) ([][]byte, []int32, error) {
+
+	changes, err := ct.nodeRepo.LoadChanges(name)
+	if err != nil {
+		return names, heights, errors.Wrapf(err, "load changes for %s", name)
+	}
+
+	i := 0
+	for height := start; height <= ct.height; {
+		n, err := ct.nodeManager.NodeAt(height, name)
+		if err != nil {
+			return names, heights, errors.Wrapf(err, "node at %d for %s", height, name)
+		}
+		next := int32(math.MaxInt32)
+		if n != nil && n.NextUpdate() > height {
+			next = n.NextUpdate()
+		}
+		for i < len(changes) && changes[i].Height <= height {
+			i++
+		}
+		if i < len(changes) && changes[i].Height < next {
+			next = changes[i].Height
+		}
+		if next == math.MaxInt32 {
+			break
+		}
+
+		names = append(names, normalization.NormalizeIfNecessary(name, next))
+		heights = append(heights, next)
+		height = next
+	}
+	return names, heights, nil
+}
+
+// clear drops the entire state of the claim trie, leaving it empty at height 0.
+func (ct *ClaimTrie) clear() error {
+
+	if err := ct.nodeRepo.Clear(); err != nil {
+		return errors.Wrap(err, "node repo clear")
+	}
+	if err := ct.nodeManager.SetCompactedHeight(0); err != nil {
+		return errors.Wrap(err, "node manager compacted height")
+	}
+	if ct.undoRepo != nil {
+		if err := ct.undoRepo.Clear(); err != nil {
+			return errors.Wrap(err, "node undo repo clear")
+		}
+	}
+	if ct.height > 0 {
+		if _, err := ct.nodeManager.DecrementHeightTo(nil, 0); err != nil {
+			return errors.Wrap(err, "node manager decrement")
+		}
+		if err := ct.blockRepo.Delete(1, ct.height); err != nil {
+			return errors.Wrap(err, "block repo delete")
+		}
+	}
+	ct.nodeManager.ClearCache()
+	ct.height = 0
+
+	if _, ok := ct.merkleTrie.(*merkletrie.RamTrie); ok {
+		ct.merkleTrie = merkletrie.NewRamTrie()
+		return nil
+	}
+	return errors.Wrap(ct.merkleTrie.SetRoot(merkletrie.EmptyTrieHash), "merkle trie set root")
+}
@@ -13,9 +13,13 @@ type Pebble struct {
 	db *pebble.DB
 }
 
-func NewPebble(path string) (*Pebble, error) {
+func NewPebble(path string, configure ...func(*pebble.Options)) (*Pebble, error) {
 
-	db, err := pebble.Open(path, &pebble.Options{Cache: pebble.NewCache(16 << 20), MaxOpenFiles: 2000})
+	opts := &pebble.Options{Cache: pebble.NewCache(16 << 20), MaxOpenFiles: 2000}
+	for _, f := range configure {
+		f(opts)
+	}
+	db, err := pebble.Open(path, opts)
 	repo := &Pebble{db: db}
 
 	return repo, errors.Wrapf(err, "unable to open %s", path)
@@ -69,6 +73,11 @@ func (repo *Pebble) NodesAt(height int32) ([][]byte, error) {
 	return names, errors.Wrap(iter.Close(), "in close")
 }
 
+// Metrics returns the internal metrics of the database.
+func (repo *Pebble) Metrics() *pebble.Metrics {
+	return repo.db.Metrics()
+}
+
 func (repo *Pebble) Close() error {
 
 	err := repo.db.Flush()
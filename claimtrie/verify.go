@@ -0,0 +1,143 @@
+package claimtrie
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie/merkletrie"
+	"github.com/lbryio/lbcd/claimtrie/node"
+	"github.com/lbryio/lbcd/claimtrie/param"
+)
+
+// maxReportedNames is the maximum number of divergent names returned by
+// Verify.  All of them are repaired regardless.
+const maxReportedNames = 1000
+
+// VerifyResult reports the outcome of a claim trie consistency check.
+type VerifyResult struct {
+	// Height is the height of the claim trie that was checked.
+	Height int32
+
+	// ExpectedRoot is the claim trie root committed to by the block at
+	// Height.
+	ExpectedRoot chainhash.Hash
+
+	// StoredRoot is the root of the merkle trie before any repair.
+	StoredRoot chainhash.Hash
+
+	// ComputedRoot is the root rebuilt from the changes of every node.
+	ComputedRoot chainhash.Hash
+
+	// NamesChecked is the number of names found in the node repo.
+	NamesChecked int
+
+	// DivergentCount is the number of names whose hash in the merkle trie
+	// doesn't match the one computed from their changes, and
+	// DivergentNames holds up to maxReportedNames of them.
+	DivergentCount int
+	DivergentNames [][]byte
+
+	// Repaired is set when the merkle trie was updated to the computed
+	// node states.
+	Repaired bool
+}
+
+// Consistent returns whether the stored and computed roots both match the
+// expected root.
+func (r *VerifyResult) Consistent() bool {
+	return r.DivergentCount == 0 && r.StoredRoot == r.ExpectedRoot &&
+		r.ComputedRoot == r.ExpectedRoot
+}
+
+// Verify rebuilds the state of every node at the current height from the node
+// repo, bypassing the node cache, and compares the resulting root against the
+// expected one as well as the hash of each name against the merkle trie.
+//
+// When repair is set and the computed root matches the expected one, the
+// divergent names are updated in the merkle trie and the root of the current
+// height is stored again.  Nothing can be repaired when the computed root
+// doesn't match since the changes themselves are wrong; the claim trie has to
+// be rebuilt from the blocks instead.
+func (ct *ClaimTrie) Verify(expectedRoot *chainhash.Hash, repair bool,
+	interrupt <-chan struct{}) (*VerifyResult, error) {
+
+	result := &VerifyResult{
+		Height:       ct.height,
+		ExpectedRoot: *expectedRoot,
+		StoredRoot:   *ct.MerkleHash(),
+	}
+
+	node.Log("Verifying the entire claim trie...")
+	ct.claimLogger = newClaimProgressLogger("Verified", node.GetLogger())
+
+	// The cache may hold the states that diverged, so make sure all nodes
+	// are rebuilt from their changes.
+	ct.nodeManager.ClearCache()
+
+	computed := merkletrie.NewRamTrie()
+	var divergent [][]byte
+	var divergentHashes []*chainhash.Hash
+	ct.nodeManager.IterateNames(func(name []byte) bool {
+		if interruptRequested(interrupt) {
+			return false
+		}
+		clone := make([]byte, len(name))
+		copy(clone, name)
+
+		hash, _ := ct.nodeManager.Hash(clone)
+		computed.Update(clone, hash, false)
+		result.NamesChecked++
+		ct.claimLogger.LogName(name)
+
+		stored := ct.merkleTrie.ClaimHash(clone)
+		if (hash == nil) != (stored == nil) || (hash != nil && !hash.IsEqual(stored)) {
+			result.DivergentCount++
+			if len(result.DivergentNames) < maxReportedNames {
+				result.DivergentNames = append(result.DivergentNames, clone)
+			}
+			divergent = append(divergent, clone)
+			divergentHashes = append(divergentHashes, hash)
+		}
+		return true
+	})
+	if interruptRequested(interrupt) {
+		return nil, errors.New("claim trie verification interrupted")
+	}
+
+	if ct.height >= param.ActiveParams.AllClaimsInMerkleForkHeight {
+		result.ComputedRoot = *computed.MerkleHashAllClaims()
+	} else {
+		result.ComputedRoot = *computed.MerkleHash()
+	}
+
+	if !repair || result.Consistent() {
+		return result, nil
+	}
+	if result.ComputedRoot != result.ExpectedRoot {
+		node.Warn("The claim trie can't be repaired since the computed root " +
+			result.ComputedRoot.String() + " doesn't match the expected root " +
+			result.ExpectedRoot.String())
+		return result, nil
+	}
+
+	// A RAM trie is simply replaced by the rebuilt one, while the names
+	// which diverged are updated in a persistent one.
+	if _, ok := ct.merkleTrie.(*merkletrie.RamTrie); ok {
+		ct.merkleTrie = computed
+	} else {
+		for i, name := range divergent {
+			ct.merkleTrie.Update(name, divergentHashes[i], true)
+		}
+	}
+	if root := ct.MerkleHash(); !root.IsEqual(expectedRoot) {
+		return result, errors.Errorf("repaired claim trie root %s doesn't match the expected root %s",
+			root, expectedRoot)
+	}
+	if err := ct.blockRepo.Set(ct.height, expectedRoot); err != nil {
+		return result, errors.Wrap(err, "block repo set")
+	}
+	ct.FlushToDisk()
+	result.Repaired = true
+
+	return result, nil
+}
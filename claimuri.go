@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lbryio/lbcd/claimtrie/change"
+)
+
+const (
+	// claimURIScheme is the scheme of the claim URIs.
+	claimURIScheme = "lbry://"
+
+	// claimURIInvalidChars are the characters which can't be part of the
+	// names in claim URIs, besides the white spaces.
+	claimURIInvalidChars = "=&#:$@%?;/\\\"<>{}|^~[]`"
+)
+
+// claimURISegment is a segment of a claim URI: a name along with at most one
+// modifier selecting one of its claims.
+type claimURISegment struct {
+	name string
+
+	// claimID is a prefix of the claim ID of the claim, set with the #
+	// modifier.
+	claimID string
+
+	// sequence selects the claim by the order in which the claims of the
+	// name were created, starting at 1.  It's set with the : modifier.
+	sequence int
+
+	// amountOrder selects the claim by the order of the effective amounts
+	// of the active claims of the name, starting at 1 for the highest.
+	// It's set with the $ modifier.
+	amountOrder int
+}
+
+// String returns the segment as it appears in a claim URI.
+func (s *claimURISegment) String() string {
+	switch {
+	case s.claimID != "":
+		return s.name + "#" + s.claimID
+	case s.sequence != 0:
+		return s.name + ":" + strconv.Itoa(s.sequence)
+	case s.amountOrder != 0:
+		return s.name + "$" + strconv.Itoa(s.amountOrder)
+	}
+	return s.name
+}
+
+// claimURI is a parsed claim URI such as lbry://@channel#ab/stream.  It
+// references either a claim, which may be a channel, or a claim signed by a
+// channel.
+type claimURI struct {
+	// channel is the channel which signed the claim, if any.
+	channel *claimURISegment
+
+	// claim is the claim referenced by the URI.
+	claim *claimURISegment
+}
+
+// parseClaimURI parses the passed claim URI, whose lbry:// scheme is
+// optional.
+func parseClaimURI(uri string) (*claimURI, error) {
+	path := uri
+	if len(path) >= len(claimURIScheme) &&
+		strings.EqualFold(path[:len(claimURIScheme)], claimURIScheme) {
+
+		path = path[len(claimURIScheme):]
+	}
+	if path == "" {
+		return nil, errors.New("missing name")
+	}
+
+	segments := strings.Split(path, "/")
+	if len(segments) > 2 {
+		return nil, errors.New("too many path segments")
+	}
+	parsed := make([]*claimURISegment, 0, len(segments))
+	for _, segment := range segments {
+		s, err := parseClaimURISegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, s)
+	}
+
+	if len(parsed) == 1 {
+		return &claimURI{claim: parsed[0]}, nil
+	}
+	if !strings.HasPrefix(parsed[0].name, "@") {
+		return nil, fmt.Errorf("%q is not a channel name", parsed[0].name)
+	}
+	if strings.HasPrefix(parsed[1].name, "@") {
+		return nil, fmt.Errorf("channel %q can't be signed by a channel",
+			parsed[1].name)
+	}
+	return &claimURI{channel: parsed[0], claim: parsed[1]}, nil
+}
+
+// parseClaimURISegment parses a segment of a claim URI.
+func parseClaimURISegment(segment string) (*claimURISegment, error) {
+	name, modifier := segment, ""
+	if i := strings.IndexAny(segment, "#:$"); i >= 0 {
+		name, modifier = segment[:i], segment[i:]
+	}
+
+	for i, r := range name {
+		if unicode.IsSpace(r) ||
+			(strings.ContainsRune(claimURIInvalidChars, r) &&
+				(r != '@' || i != 0)) {
+
+			return nil, fmt.Errorf("invalid character %q in name %q",
+				r, name)
+		}
+	}
+	if name == "" || name == "@" {
+		return nil, fmt.Errorf("missing name in %q", segment)
+	}
+
+	s := &claimURISegment{name: name}
+	if modifier == "" {
+		return s, nil
+	}
+	value := modifier[1:]
+	switch modifier[0] {
+	case '#':
+		if value == "" || len(value) > 2*change.ClaimIDSize ||
+			strings.Trim(strings.ToLower(value), "0123456789abcdef") != "" {
+
+			return nil, fmt.Errorf("invalid claim ID %q", value)
+		}
+		s.claimID = strings.ToLower(value)
+
+	case ':', '$':
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || value[0] == '0' ||
+			strings.Trim(value, "0123456789") != "" {
+
+			return nil, fmt.Errorf("invalid position %q", value)
+		}
+		if modifier[0] == ':' {
+			s.sequence = n
+		} else {
+			s.amountOrder = n
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestParseClaimURI ensures claim URIs are parsed into their channel and claim
+// segments, and that malformed URIs are rejected.
+func TestParseClaimURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		channel string
+		claim   string
+	}{
+		{uri: "lbry://name", claim: "name"},
+		{uri: "name", claim: "name"},
+		{uri: "LBRY://Name#AB12", claim: "Name#ab12"},
+		{uri: "lbry://name:1", claim: "name:1"},
+		{uri: "lbry://name$2", claim: "name$2"},
+		{uri: "lbry://@channel", claim: "@channel"},
+		{uri: "lbry://@channel#a/stream:3", channel: "@channel#a", claim: "stream:3"},
+		{uri: "lbry://@channel/stream", channel: "@channel", claim: "stream"},
+		{uri: "lbry://na.me-é", claim: "na.me-é"},
+	}
+	for _, test := range tests {
+		u, err := parseClaimURI(test.uri)
+		if err != nil {
+			t.Errorf("parseClaimURI(%q): unexpected error: %v", test.uri, err)
+			continue
+		}
+		channel := ""
+		if u.channel != nil {
+			channel = u.channel.String()
+		}
+		if channel != test.channel || u.claim.String() != test.claim {
+			t.Errorf("parseClaimURI(%q): got channel %q and claim %q, "+
+				"want %q and %q", test.uri, channel, u.claim.String(),
+				test.channel, test.claim)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"lbry://",
+		"lbry://@",
+		"lbry://#ab",
+		"lbry://@a/b/c",
+		"lbry://channel/stream",
+		"lbry://@channel/@other",
+		"lbry://name#",
+		"lbry://name#xyz",
+		"lbry://name#00000000000000000000000000000000000000001",
+		"lbry://name:0",
+		"lbry://name:01",
+		"lbry://name:+1",
+		"lbry://name$-1",
+		"lbry://name$",
+		"lbry://na me",
+		"lbry://na@me",
+		"lbry://na?me",
+	}
+	for _, uri := range invalid {
+		if _, err := parseClaimURI(uri); err == nil {
+			t.Errorf("parseClaimURI(%q): unexpected success", uri)
+		}
+	}
+}
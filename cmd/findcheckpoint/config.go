@@ -21,6 +21,7 @@ const (
 	minCandidates        = 1
 	maxCandidates        = 20
 	defaultNumCandidates = 5
+	defaultInterval      = 1
 	defaultDbType        = "ffldb"
 )
 
@@ -37,7 +38,8 @@ var (
 type config struct {
 	DataDir        string `short:"b" long:"datadir" description:"Location of the lbcd data directory"`
 	DbType         string `long:"dbtype" description:"Database backend to use for the Block Chain"`
-	UseGoOutput    bool   `short:"g" long:"gooutput" description:"Display the candidates using Go syntax that is ready to insert into the btcchain checkpoint list"`
+	UseGoOutput    bool   `short:"g" long:"gooutput" description:"Display the candidates using Go syntax that is ready to insert into the chaincfg checkpoint list"`
+	Interval       int32  `short:"i" long:"interval" description:"Only consider blocks whose height is a multiple of the interval"`
 	NumCandidates  int    `short:"n" long:"numcandidates" description:"Max num of checkpoint candidates to show {1-20}"`
 	RegressionTest bool   `long:"regtest" description:"Use the regression test network"`
 	SimNet         bool   `long:"simnet" description:"Use the simulation test network"`
@@ -80,6 +82,7 @@ func loadConfig() (*config, []string, error) {
 		DataDir:       defaultDataDir,
 		DbType:        defaultDbType,
 		NumCandidates: defaultNumCandidates,
+		Interval:      defaultInterval,
 	}
 
 	// Parse command line options.
@@ -146,5 +149,15 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Validate the candidate interval.
+	if cfg.Interval < 1 {
+		str := "%s: The specified interval must be positive -- " +
+			"parsed [%v]"
+		err = fmt.Errorf(str, "loadConfig", cfg.Interval)
+		fmt.Fprintln(os.Stderr, err)
+		parser.WriteHelp(os.Stderr)
+		return nil, nil, err
+	}
+
 	return &cfg, remainingArgs, nil
 }
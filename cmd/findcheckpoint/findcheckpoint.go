@@ -91,10 +91,14 @@ func findCandidates(chain *blockchain.BlockChain, latestHash *chainhash.Hash) ([
 			fmt.Print(".")
 		}
 
-		// Determine if this block is a checkpoint candidate.
-		isCandidate, err := chain.IsCheckpointCandidate(block)
-		if err != nil {
-			return nil, err
+		// Determine if this block is a checkpoint candidate.  Only the
+		// blocks at the configured interval are considered.
+		isCandidate := block.Height()%cfg.Interval == 0
+		if isCandidate {
+			isCandidate, err = chain.IsCheckpointCandidate(block)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		// All checks passed, so this node seems like a reasonable
@@ -117,19 +121,25 @@ func findCandidates(chain *blockchain.BlockChain, latestHash *chainhash.Hash) ([
 	return candidates, nil
 }
 
-// showCandidate display a checkpoint candidate using and output format
-// determined by the configuration parameters.  The Go syntax output
-// uses the format the btcchain code expects for checkpoints added to the list.
-func showCandidate(candidateNum int, checkpoint *chaincfg.Checkpoint) {
+// showCandidates displays the checkpoint candidates, which are ordered from the
+// highest, using an output format determined by the configuration parameters.
+// The Go syntax output lists them in ascending order using the format the
+// chaincfg code expects for checkpoints added to the list.  The hash of the
+// highest candidate is also suggested for the --assumevalid option of lbcd.
+func showCandidates(candidates []*chaincfg.Checkpoint) {
 	if cfg.UseGoOutput {
-		fmt.Printf("Candidate %d -- {%d, newShaHashFromStr(\"%v\")},\n",
-			candidateNum, checkpoint.Height, checkpoint.Hash)
-		return
+		for i := len(candidates) - 1; i >= 0; i-- {
+			fmt.Printf("{%d, newHashFromStr(\"%v\")},\n",
+				candidates[i].Height, candidates[i].Hash)
+		}
+	} else {
+		for i, checkpoint := range candidates {
+			fmt.Printf("Candidate %d -- Height: %d, Hash: %v\n",
+				i+1, checkpoint.Height, checkpoint.Hash)
+		}
 	}
 
-	fmt.Printf("Candidate %d -- Height: %d, Hash: %v\n", candidateNum,
-		checkpoint.Height, checkpoint.Hash)
-
+	fmt.Printf("Suggested assumevalid: %v\n", candidates[0].Hash)
 }
 
 func main() {
@@ -179,7 +189,5 @@ func main() {
 	}
 
 	// Show the candidates.
-	for i, checkpoint := range candidates {
-		showCandidate(i+1, checkpoint)
-	}
+	showCandidates(candidates)
 }
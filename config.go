@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -22,7 +23,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/btcsuite/go-socks/socks"
 	flags "github.com/jessevdk/go-flags"
 	"github.com/lbryio/lbcd/blockchain"
 	"github.com/lbryio/lbcd/chaincfg"
@@ -31,7 +31,9 @@ import (
 	"github.com/lbryio/lbcd/database"
 	_ "github.com/lbryio/lbcd/database/ffldb"
 	"github.com/lbryio/lbcd/mempool"
+	"github.com/lbryio/lbcd/mining"
 	"github.com/lbryio/lbcd/peer"
+	"github.com/lbryio/lbcd/txscript"
 	"github.com/lbryio/lbcd/version"
 	"github.com/lbryio/lbcd/wire"
 	btcutil "github.com/lbryio/lbcutil"
@@ -57,18 +59,32 @@ const (
 	defaultBlockMaxSize          = 750000
 	defaultBlockMinWeight        = 0
 	defaultBlockMaxWeight        = 3000000
+	defaultBlockTemplateFeeDelta = 0.001
+	defaultBlockSelection        = "priority"
 	blockMaxSizeMin              = 1000
 	blockMaxSizeMax              = blockchain.MaxBlockBaseSize - 1000
 	blockMaxWeightMin            = 4000
 	blockMaxWeightMax            = blockchain.MaxBlockWeight - 4000
 	defaultGenerate              = false
 	defaultMaxOrphanTransactions = 100
+	defaultMaxMempool            = 300
 	defaultMaxOrphanTxSize       = 100000
 	defaultSigCacheMaxSize       = 100000
+	defaultClaimTrieCache        = 128
 	sampleConfigFilename         = "sample-lbcd.conf"
 	defaultTxIndex               = true
 	defaultAddrIndex             = false
+	defaultStratumPort           = "3333"
+	defaultGRPCPort              = "9247"
+	defaultPublicRPCPort         = "9248"
+	defaultPublicRPCRate         = 5.0
+	defaultPublicRPCBurst        = 20
 	defaultUpnp                  = true
+	defaultNATPMP                = true
+	defaultTorControl            = "127.0.0.1:9051"
+	defaultTorSocks              = "127.0.0.1:9050"
+	defaultTorIsolationMode      = "connection"
+	pruneMinSize                 = 1536
 )
 
 var (
@@ -101,39 +117,76 @@ func minUint32(a, b uint32) uint32 {
 //
 // See loadConfig for details on the configuration load process.
 type config struct {
+	AddAssumeUtxo        []string      `long:"addassumeutxo" description:"Add an assumed utxo set that a utxo snapshot can be loaded for with loadtxoutset.  Format: '<height>:<blockhash>:<muhash>'"`
 	AddCheckpoints       []string      `long:"addcheckpoint" description:"Add a custom checkpoint.  Format: '<height>:<hash>'"`
 	AddPeers             []string      `short:"a" long:"addpeer" description:"Add a peer to connect with at startup"`
 	AddrIndex            bool          `long:"addrindex" description:"Maintain a full address-based transaction index which makes the searchrawtransactions RPC available"`
 	AgentBlacklist       []string      `long:"agentblacklist" description:"A comma separated list of user-agent substrings which will cause lbcd to reject any peers whose user-agent contains any of the blacklisted substrings."`
+	AlertReorgDepth      uint32        `long:"alertreorgdepth" description:"Raise an alert when a reorganization disconnects at least this number of blocks from the main chain -- 0 disables the alert"`
+	AlertStallTimeout    time.Duration `long:"alertstalltimeout" description:"Raise an alert when the tip of the main chain didn't change for this long -- 0 disables the alert (e.g. 30m)"`
+	AlertWebhook         string        `long:"alertwebhook" description:"URL the chain alerts are posted to as JSON, in addition to being logged and sent to the websocket clients registered with notifyalerts"`
 	AgentWhitelist       []string      `long:"agentwhitelist" description:"A comma separated list of user-agent substrings which will cause lbcd to require all peers' user-agents to contain one of the whitelisted substrings. The blacklist is applied before the whitelist, and an empty whitelist will allow all agents that do not fail the blacklist."`
+	AssumeValid          string        `long:"assumevalid" description:"Hash of a block assumed to be valid along with its ancestors, whose scripts are not checked during header-check sync"`
 	BanDuration          time.Duration `long:"banduration" description:"How long to ban misbehaving peers.  Valid time units are {s, m, h}.  Minimum 1 second"`
 	BanThreshold         uint32        `long:"banthreshold" description:"Maximum allowed ban score before disconnecting and banning misbehaving peers."`
+	BlockCompression     bool          `long:"blockcompression" description:"Compress new blocks stored in the block database with zstd.  Blocks which were already stored remain readable either way, but a database with compressed blocks can't be opened by older versions"`
 	BlockMaxSize         uint32        `long:"blockmaxsize" description:"Maximum block size in bytes to be used when creating a block"`
 	BlockMinSize         uint32        `long:"blockminsize" description:"Mininum block size in bytes to be used when creating a block"`
 	BlockMaxWeight       uint32        `long:"blockmaxweight" description:"Maximum block weight to be used when creating a block"`
 	BlockMinWeight       uint32        `long:"blockminweight" description:"Mininum block weight to be used when creating a block"`
 	BlockPrioritySize    uint32        `long:"blockprioritysize" description:"Size in bytes for high-priority/low-fee transactions when creating a block"`
+	BlockTmplFeeDelta    float64       `long:"blocktemplatefeedelta" description:"Total fees in LBC of new transactions which trigger an immediate block template update for getblocktemplate long poll clients (0 to only update periodically)"`
+	BlockSelection       string        `long:"blockselection" description:"Strategy used to select the transactions of new blocks {priority, feerate, ancestorfeerate} -- priority fills blockprioritysize bytes with high-priority transactions before selecting by fee rate, feerate only selects by fee rate, and ancestorfeerate selects transactions along with their unconfirmed ancestors by the fee rate of the whole package"`
 	BlocksOnly           bool          `long:"blocksonly" description:"Do not accept transactions from remote peers."`
+	CheckClaimTrie       bool          `long:"checkclaimtrie" description:"Verifies the claim trie against the best block and the changes of every name on start up and then exits."`
+	ChannelIndex         bool          `long:"channelindex" description:"Maintain an index of the claims signed by each channel which makes the getclaimsbychannel RPC available"`
+	ClaimTrieCache       int64         `long:"claimtriecache" description:"Approximate memory in MiB used to cache claim trie nodes"`
+	ClaimTrieBloomBits   int           `long:"claimtriepebblebloombits" description:"Bits per key of the bloom filters of the tables written to the pebble databases of the claim trie (0 for no filters)"`
+	ClaimTriePebbleCache int64         `long:"claimtriepebblecache" description:"Size in MiB of the block cache of each pebble database of the claim trie (0 for the built-in default of each database)"`
+	ClaimTrieCompactions int           `long:"claimtriepebblecompactions" description:"Maximum number of concurrent compactions of each pebble database of the claim trie (0 for the pebble default)"`
+	ClaimTrieMemTable    int           `long:"claimtriepebblememtable" description:"Size in MiB of the memtables of the pebble databases of the claim trie (0 for the pebble default)"`
+	ClaimFilterIndex     bool          `long:"claimfilterindex" description:"Maintain a filter per block of the normalized names of the claims it touches, served to peers and by the getcfilter RPC as filter type 1"`
+	ClaimIDIndex         bool          `long:"claimidindex" description:"Maintain an index of claims by claim ID which makes the getclaimbyid RPC available"`
 	ConfigFile           string        `short:"C" long:"configfile" description:"Path to configuration file"`
 	ConnectPeers         []string      `long:"connect" description:"Connect only to the specified peers at startup"`
 	CPUProfile           string        `long:"cpuprofile" description:"Write CPU profile to the specified file"`
 	MemProfile           string        `long:"memprofile" description:"Write memory profile to the specified file"`
+	DataCarrierSize      int           `long:"datacarriersize" description:"Max number of bytes of data in standard null data (OP_RETURN) outputs"`
 	DataDir              string        `short:"b" long:"datadir" description:"Directory to store data"`
 	DbType               string        `long:"dbtype" description:"Database backend to use for the Block Chain"`
+	DBScrubInterval      time.Duration `long:"dbscrubinterval" description:"Interval at which the blocks stored in the database are re-read to detect corruption -- Corrupt blocks are fetched again from peers -- 0 only scrubs on request with the verifydb RPC (e.g. 24h)"`
 	DebugLevel           string        `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
+	DecodeClaims         bool          `long:"decodeclaims" description:"Return the metadata decoded from the values of claims instead of their hex encoding in the results of the getclaimsforname and getrawtransaction RPCs"`
 	DropAddrIndex        bool          `long:"dropaddrindex" description:"Deletes the address-based transaction index from the database on start up and then exits."`
+	DropChannelIndex     bool          `long:"dropchannelindex" description:"Deletes the claims by channel index from the database on start up and then exits."`
+	DropClaimFilterIndex bool          `long:"dropclaimfilterindex" description:"Deletes the claim name filter index from the database on start up and then exits."`
+	DropClaimIDIndex     bool          `long:"dropclaimidindex" description:"Deletes the claim ID index from the database on start up and then exits."`
 	DropCfIndex          bool          `long:"dropcfindex" description:"Deletes the index used for committed filtering (CF) support from the database on start up and then exits."`
+	DropSpentIndex       bool          `long:"dropspentindex" description:"Deletes the spent output index from the database on start up and then exits."`
 	DropTxIndex          bool          `long:"droptxindex" description:"Deletes the hash-based transaction index from the database on start up and then exits."`
+	DustRelayFee         float64       `long:"dustrelayfee" description:"The fee rate in LBC/kB used to determine whether outputs are dust, which is when spending them costs more than a third of their value -- Defaults to minrelaytxfee"`
 	ExternalIPs          []string      `long:"externalip" description:"Add an ip to the list of local addresses we claim to listen on to peers"`
 	Generate             bool          `long:"generate" description:"Generate (mine) bitcoins using the CPU"`
+	GRPCListeners        []string      `long:"grpclisten" description:"Add an interface/port to listen for gRPC connections (default port: 9247) -- NOTE: The gRPC server is disabled unless a listen address is specified and requires the RPC server"`
+	HeaderCheckSync      bool          `long:"headerchecksync" description:"Download and check the headers up to the tip of the sync peer before downloading the blocks"`
 	FreeTxRelayLimit     float64       `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
-	Listeners            []string      `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 9246, testnet: 19246, regtest: 29246)"`
+	Listeners            []string      `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 9246, testnet: 19246, regtest: 29246, signet: 49246)"`
+	ListenOnion          bool          `long:"listenonion" description:"Automatically create a Tor onion service for the listening port via the Tor control port and advertise its address to peers"`
 	LogDir               string        `long:"logdir" description:"Directory to log output."`
+	MaxClaimValueSize    int           `long:"maxclaimvaluesize" description:"Max size in bytes of the values of claims, updates and supports in standard transactions"`
+	MaxOrphanBlocks      int           `long:"maxorphanblocks" description:"Max number of blocks whose parent is unknown to keep in memory until the parent arrives"`
 	MaxOrphanTxs         int           `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
+	MaxMempool           int           `long:"maxmempool" description:"Max size of the transactions in the memory pool in megabytes -- The transactions paying the lowest fee rates are evicted when it is exceeded (0 for no limit)"`
+	MaxBloomFilterSize   int           `long:"maxpeerbloomfiltersize" description:"Max size in bytes of the bloom filter a peer may load -- Peers loading larger filters are disconnected"`
 	MaxPeers             int           `long:"maxpeers" description:"Max number of inbound and outbound peers"`
 	MiningAddrs          []string      `long:"miningaddr" description:"Add the specified payment address to the list of addresses to use for generated blocks -- At least one address is required if the generate option is set"`
+	MiningPayouts        []string      `long:"miningpayout" description:"Add the specified payment address and weight, in the form <address>:<weight>, to the list of payouts to split the coinbase of blocks generated by the CPU miner among proportionally to their weights -- Takes precedence over the mining addresses for the CPU miner"`
+	MinClaimAmount       float64       `long:"minclaimamount" description:"The minimum amount in LBC of the claim and update outputs of standard transactions, which encumber the claim trie, in addition to the dust threshold"`
 	MinRelayTxFee        float64       `long:"minrelaytxfee" description:"The minimum transaction fee in LBC/kB to be considered a non-zero fee."`
+	MinSupportAmount     float64       `long:"minsupportamount" description:"The minimum amount in LBC of the support outputs of standard transactions, which encumber the claim trie, in addition to the dust threshold"`
+	NATPMP               bool          `long:"natpmp" description:"Use NAT-PMP to map our listening port outside of NAT"`
 	DisableBanning       bool          `long:"nobanning" description:"Disable banning of misbehaving peers"`
+	NoBareMultiSig       bool          `long:"nobaremultisig" description:"Consider transactions with bare multi-signature outputs, as opposed to pay-to-script-hash ones, non-standard"`
 	NoCFilters           bool          `long:"nocfilters" description:"Disable committed filtering (CF) support"`
 	DisableCheckpoints   bool          `long:"nocheckpoints" description:"Disable built-in checkpoints.  Don't do this unless you know what you're doing."`
 	DisableDNSSeed       bool          `long:"nodnsseed" description:"Disable DNS seeding for peers"`
@@ -142,20 +195,35 @@ type config struct {
 	NoPeerBloomFilters   bool          `long:"nopeerbloomfilters" description:"Disable bloom filtering support"`
 	NoRelayPriority      bool          `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
 	NoWinService         bool          `long:"nowinservice" description:"Do not start as a background service on Windows -- NOTE: This flag only works on the command line, not in the config file"`
-	DisableRPC           bool          `long:"norpc" description:"Disable built-in RPC server -- NOTE: The RPC server is disabled by default if no rpcuser/rpcpass or rpclimituser/rpclimitpass is specified"`
+	DisableRPC           bool          `long:"norpc" description:"Disable built-in RPC server -- NOTE: The RPC server is disabled by default if no rpcuser/rpcpass, rpclimituser/rpclimitpass or rpcaccount is specified"`
 	DisableStallHandler  bool          `long:"nostalldetect" description:"Disables the stall handler system for each peer, useful in simnet/regtest integration tests frameworks"`
 	DisableTLS           bool          `long:"notls" description:"Disable TLS for the RPC server -- NOTE: This is only allowed if the RPC server is bound to localhost"`
 	OnionProxy           string        `long:"onion" description:"Connect to tor hidden services via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
 	OnionProxyPass       string        `long:"onionpass" default-mask:"-" description:"Password for onion proxy server"`
 	OnionProxyUser       string        `long:"onionuser" description:"Username for onion proxy server"`
-	Profile              string        `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
+	OutboundTargets      []string      `long:"outboundtarget" description:"Add a minimum number of outbound connections to a network, in the form <network>:<count> with a network of ipv4, ipv6 or onion (e.g. ipv6:2) -- The total number of outbound connections is raised to the sum of the targets when it exceeds the default of 8"`
+	PeerBloomFilters     []string      `long:"peerbloomfilters" description:"Only advertise and serve bloom filters (BIP0037) to inbound peers connecting to the specified listen interface/port -- Bloom filters are served to all peers when none are specified"`
+	PeerBloomWork        int           `long:"peerbloomwork" description:"Max average number of KiB per second hashed to match the bloom filter of a single peer -- Peers exceeding it are disconnected"`
+	Profile              string        `long:"profile" description:"Enable HTTP profiling on given port, which also serves the metrics of the claim trie databases at /metrics -- NOTE port must be between 1024 and 65536"`
+	PublicRPCBurst       int           `long:"publicrpcburst" description:"Max number of requests a single IP may make at once on the public RPC listeners"`
+	PublicRPCListeners   []string      `long:"publicrpclisten" description:"Add an interface/port to listen for public RPC connections which only serve read-only block and claim queries (default port: 9248) -- NOTE: The public RPC server is disabled unless a listen address is specified and requires the RPC server"`
+	PublicRPCMethods     []string      `long:"publicrpcmethod" description:"Allow the specified read-only method on the public RPC listeners instead of the default block and claim queries -- Can be specified multiple times"`
+	PublicRPCRate        float64       `long:"publicrpcrate" description:"Max number of requests per second a single IP may make on average on the public RPC listeners -- Clients authenticating with the RPC credentials are not rate limited"`
 	Proxy                string        `long:"proxy" description:"Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
 	ProxyPass            string        `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
 	ProxyUser            string        `long:"proxyuser" description:"Username for proxy server"`
+	Prune                uint64        `long:"prune" description:"Prune already validated blocks from the database.  Must specify a target size in MiB (minimum value of 1536, default value of 0 will disable pruning).  The transaction index is disabled when pruning and may not be enabled with --txindex"`
+	PruneForkDB          bool          `long:"pruneforkdb" description:"Delete the pruned stale forks from the block index stored in the database as well so they are not loaded on start up -- Requires --pruneforkdepth"`
+	PruneForkDepth       uint32        `long:"pruneforkdepth" description:"Prune the stale forks branching off the main chain more than this number of blocks below its tip from the block index (minimum value of 288, default value of 0 will disable pruning)"`
 	RegressionTest       bool          `long:"regtest" description:"Use the regression test network"`
+	Reindex              bool          `long:"reindex" description:"Rebuild the block index and the chain state, including the claim trie and the optional indexes, from the blocks stored in the database on start up -- An interrupted reindex resumes on the next start"`
+	ReindexChainState    bool          `long:"reindex-chainstate" description:"Rebuild the chain state, including the claim trie and the optional indexes, from the blocks stored in the database on start up -- An interrupted reindex resumes on the next start"`
 	RejectNonStd         bool          `long:"rejectnonstd" description:"Reject non-standard transactions regardless of the default settings for the active network."`
 	RejectReplacement    bool          `long:"rejectreplacement" description:"Reject transactions that attempt to replace existing transactions within the mempool through the Replace-By-Fee (RBF) signaling policy."`
 	RelayNonStd          bool          `long:"relaynonstd" description:"Relay non-standard transactions regardless of the default settings for the active network."`
+	RepairClaimTrie      bool          `long:"repairclaimtrie" description:"Repairs the claim trie names found to be inconsistent with --checkclaimtrie."`
+	REST                 bool          `long:"rest" description:"Serve the REST interface for blocks, transactions, headers and claims on the RPC listeners without authentication"`
+	RPCAccounts          []string      `long:"rpcaccount" description:"Add RPC credentials restricted to an access list of methods, in the form <user>:<password>:<methods> with a comma-separated list of methods allowed, * to allow all methods or methods prefixed with - to deny them (e.g. miner:pass:getblocktemplate,submitblock or reader:pass:*,-stop)"`
 	RPCCert              string        `long:"rpccert" description:"File containing the certificate file"`
 	RPCKey               string        `long:"rpckey" description:"File containing the certificate key"`
 	RPCLimitPass         string        `long:"rpclimitpass" default-mask:"-" description:"Password for limited RPC connections"`
@@ -164,28 +232,50 @@ type config struct {
 	RPCMaxClients        int           `long:"rpcmaxclients" description:"Max number of RPC clients for standard connections"`
 	RPCMaxConcurrentReqs int           `long:"rpcmaxconcurrentreqs" description:"Max number of concurrent RPC requests that may be processed concurrently"`
 	RPCMaxWebsockets     int           `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
+	RPCMetrics           bool          `long:"rpcmetrics" description:"Export the request statistics of the RPC methods at /metrics on the profile server -- Requires --profile"`
 	RPCQuirks            bool          `long:"rpcquirks" description:"Mirror some JSON-RPC quirks of Bitcoin Core -- NOTE: Discouraged unless interoperability issues need to be worked around"`
+	RPCTraceSize         int           `long:"rpctracesize" description:"Number of the most recent RPC requests whose traces are kept in memory for the getrpcstats RPC -- 0 disables the traces"`
 	RPCPass              string        `short:"P" long:"rpcpass" default-mask:"-" description:"Password for RPC connections"`
 	RPCUser              string        `short:"u" long:"rpcuser" description:"Username for RPC connections"`
+	ScriptValWorkers     int           `long:"scriptvalworkers" description:"Number of workers used to validate block scripts -- 0 selects a value based on the number of processor cores"`
 	SigCacheMaxSize      uint          `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
+	SigCachePersist      bool          `long:"sigcachepersist" description:"Save the signature verification cache to the data directory on shutdown and load it on startup to avoid verifying the same signatures again after a restart"`
 	SimNet               bool          `long:"simnet" description:"Use the simulation test network"`
 	SigNet               bool          `long:"signet" description:"Use the signet test network"`
 	SigNetChallenge      string        `long:"signetchallenge" description:"Connect to a custom signet network defined by this challenge instead of using the global default signet test network -- Can be specified multiple times"`
+	SigNetGenesis        string        `long:"signetgenesis" description:"Start the custom signet network from a genesis block with the specified timestamp, target difficulty bits and nonce formatted as <unix time>:<hex bits>:<nonce> instead of the genesis block of the global default signet network"`
 	SigNetSeedNode       []string      `long:"signetseednode" description:"Specify a seed node for the signet network instead of using the global default signet network seed nodes"`
+	SpentIndex           bool          `long:"spentindex" description:"Maintain an index of the inputs spending each output which makes the getspentinfo RPC available"`
+	StratumListeners     []string      `long:"stratumlisten" description:"Add an interface/port to serve work to miners with the Stratum protocol (default port: 3333) -- At least one miningaddr is required"`
 	TestNet3             bool          `long:"testnet" description:"Use the test network"`
+	TorControl           string        `long:"torcontrol" description:"Tor control port used to create the onion service when --listenonion is set"`
 	TorIsolation         bool          `long:"torisolation" description:"Enable Tor stream isolation by randomizing user credentials for each connection."`
-	TrickleInterval      time.Duration `long:"trickleinterval" description:"Minimum time between attempts to send new inventory to a connected peer"`
+	TorIsolationMode     string        `long:"torisolationmode" description:"How the credentials are randomized by --torisolation: connection for new credentials for each connection, or destination for the same credentials for all the connections to a destination and different ones for each destination"`
+	TorPassword          string        `long:"torpassword" default-mask:"-" description:"Password for the Tor control port -- Cookie authentication is used when not set"`
+	TrickleInterval      time.Duration `long:"trickleinterval" description:"Average time between attempts to send new inventory to an inbound peer -- Outbound peers use half of it"`
 	TxIndex              bool          `long:"txindex" description:"Maintain a full hash-based transaction index which makes all transactions available via the getrawtransaction RPC"`
 	UserAgentComments    []string      `long:"uacomment" description:"Comment to add to the user agent -- See BIP 14 for more information."`
 	Upnp                 bool          `long:"upnp" description:"Use UPnP to map our listening port outside of NAT"`
 	ShowVersion          bool          `short:"V" long:"version" description:"Display version information and exit"`
+	ViolationWeights     []string      `long:"violationweight" description:"Set the weight in percent of the ban score added for a category of violations, in the form <category>=<percent> with a category of invalidmessage, unrequesteddata, stalling or protocolabuse (e.g. protocolabuse=50) -- Stalling is tracked but never causes a ban"`
 	Whitelists           []string      `long:"whitelist" description:"Add an IP network or IP that will not be banned. (eg. 192.168.1.0/24 or ::1)"`
 	lookup               func(string) ([]net.IP, error)
 	oniondial            func(string, string, time.Duration) (net.Conn, error)
 	dial                 func(string, string, time.Duration) (net.Conn, error)
+	addAssumeUtxo        []chaincfg.AssumeUtxo
 	addCheckpoints       []chaincfg.Checkpoint
+	assumeValid          *chainhash.Hash
+	blockSelection       mining.SelectionStrategy
+	blockTmplFeeDelta    btcutil.Amount
+	dustRelayFee         btcutil.Amount
 	miningAddrs          []btcutil.Address
+	miningPayouts        []mining.Payout
+	minClaimAmount       btcutil.Amount
 	minRelayTxFee        btcutil.Amount
+	minSupportAmount     btcutil.Amount
+	outboundTargets      map[string]uint32
+	rpcAccounts          []*rpcAccount
+	violationWeights     connmgr.ViolationWeights
 	whitelists           []*net.IPNet
 }
 
@@ -386,6 +476,140 @@ func parseCheckpoints(checkpointStrings []string) ([]chaincfg.Checkpoint, error)
 	return checkpoints, nil
 }
 
+// parseAssumeUtxo parses assumed utxo sets in the
+// '<height>:<blockhash>:<muhash>' format.
+func parseAssumeUtxo(assumeUtxoStrings []string) ([]chaincfg.AssumeUtxo, error) {
+	var assumeUtxo []chaincfg.AssumeUtxo
+	for _, str := range assumeUtxoStrings {
+		parts := strings.Split(str, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("unable to parse assumed utxo set "+
+				"%q -- use the syntax <height>:<blockhash>:<muhash>",
+				str)
+		}
+
+		height, err := strconv.ParseInt(parts[0], 10, 32)
+		if err != nil || height <= 0 {
+			return nil, fmt.Errorf("unable to parse assumed utxo set "+
+				"%q due to malformed height", str)
+		}
+		hash, err := chainhash.NewHashFromStr(parts[1])
+		if err != nil || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("unable to parse assumed utxo set "+
+				"%q due to malformed block hash", str)
+		}
+		utxoSetHash, err := chainhash.NewHashFromStr(parts[2])
+		if err != nil || len(parts[2]) == 0 {
+			return nil, fmt.Errorf("unable to parse assumed utxo set "+
+				"%q due to malformed MuHash", str)
+		}
+
+		assumeUtxo = append(assumeUtxo, chaincfg.AssumeUtxo{
+			Height:      int32(height),
+			Hash:        hash,
+			UtxoSetHash: utxoSetHash,
+		})
+	}
+	return assumeUtxo, nil
+}
+
+// parseMiningPayouts parses mining payouts in the '<address>:<weight>' format
+// for the passed network.
+func parseMiningPayouts(payoutStrings []string, params *chaincfg.Params) ([]mining.Payout, error) {
+	var payouts []mining.Payout
+	for _, str := range payoutStrings {
+		parts := strings.Split(str, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unable to parse mining payout %q "+
+				"-- use the syntax <address>:<weight>", str)
+		}
+
+		addr, err := btcutil.DecodeAddress(parts[0], params)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse mining payout %q "+
+				"due to malformed address: %v", str, err)
+		}
+		if !addr.IsForNet(params) {
+			return nil, fmt.Errorf("mining payout %q is on the wrong "+
+				"network", str)
+		}
+		weight, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil || weight == 0 {
+			return nil, fmt.Errorf("unable to parse mining payout %q "+
+				"due to malformed weight", str)
+		}
+
+		payouts = append(payouts, mining.Payout{
+			Address: addr,
+			Weight:  uint32(weight),
+		})
+	}
+	return payouts, nil
+}
+
+// parseOutboundTargets parses outbound connection targets in the
+// '<network>:<count>' format into the targets keyed by network name.  The
+// counts of the targets of the same network add up.
+func parseOutboundTargets(targetStrings []string) (map[string]uint32, error) {
+	if len(targetStrings) == 0 {
+		return nil, nil
+	}
+
+	targets := make(map[string]uint32, len(targetStrings))
+	for _, str := range targetStrings {
+		parts := strings.Split(str, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unable to parse outbound target %q "+
+				"-- use the syntax <network>:<count>", str)
+		}
+
+		network := strings.ToLower(parts[0])
+		switch network {
+		case networkIPv4, networkIPv6, networkOnion:
+		default:
+			return nil, fmt.Errorf("outbound target %q has unknown "+
+				"network -- use one of %s, %s or %s", str,
+				networkIPv4, networkIPv6, networkOnion)
+		}
+		count, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse outbound target %q "+
+				"due to malformed count", str)
+		}
+		targets[network] += uint32(count)
+	}
+	return targets, nil
+}
+
+// parseGenesis parses a genesis block in the '<unix time>:<hex bits>:<nonce>'
+// format.
+func parseGenesis(genesis string) (*wire.MsgBlock, error) {
+	parts := strings.Split(genesis, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unable to parse genesis %q -- use the "+
+			"syntax <unix time>:<hex bits>:<nonce>", genesis)
+	}
+
+	timestamp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse genesis %q due to "+
+			"malformed timestamp", genesis)
+	}
+	bits, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse genesis %q due to "+
+			"malformed bits", genesis)
+	}
+	nonce, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse genesis %q due to "+
+			"malformed nonce", genesis)
+	}
+
+	return chaincfg.NewGenesisBlock(time.Unix(timestamp, 0), uint32(bits),
+		uint32(nonce)), nil
+}
+
 // filesExists reports whether the named file or directory exists.
 func fileExists(name string) bool {
 	if _, err := os.Stat(name); err != nil {
@@ -423,17 +647,24 @@ func loadConfig() (*config, []string, error) {
 		ConfigFile:           defaultConfigFile,
 		DebugLevel:           defaultLogLevel,
 		MaxPeers:             defaultMaxPeers,
+		MaxBloomFilterSize:   defaultMaxBloomFilterSize,
+		PeerBloomWork:        defaultPeerBloomWork,
 		BanDuration:          defaultBanDuration,
 		BanThreshold:         defaultBanThreshold,
 		RPCMaxClients:        defaultMaxRPCClients,
 		RPCMaxWebsockets:     defaultMaxRPCWebsockets,
 		RPCMaxConcurrentReqs: defaultMaxRPCConcurrentReqs,
+		RPCTraceSize:         defaultRPCTraceSize,
+		PublicRPCRate:        defaultPublicRPCRate,
+		PublicRPCBurst:       defaultPublicRPCBurst,
 		DataDir:              defaultDataDir,
 		LogDir:               defaultLogDir,
 		DbType:               defaultDbType,
 		RPCKey:               defaultRPCKeyFile,
 		RPCCert:              defaultRPCCertFile,
 		MinRelayTxFee:        mempool.DefaultMinRelayTxFee.ToBTC(),
+		DataCarrierSize:      mempool.DefaultMaxDataCarrierSize,
+		MaxClaimValueSize:    mempool.DefaultMaxClaimValueSize,
 		FreeTxRelayLimit:     defaultFreeTxRelayLimit,
 		TrickleInterval:      defaultTrickleInterval,
 		BlockMinSize:         defaultBlockMinSize,
@@ -441,12 +672,22 @@ func loadConfig() (*config, []string, error) {
 		BlockMinWeight:       defaultBlockMinWeight,
 		BlockMaxWeight:       defaultBlockMaxWeight,
 		BlockPrioritySize:    mempool.DefaultBlockPrioritySize,
+		BlockSelection:       defaultBlockSelection,
+		BlockTmplFeeDelta:    defaultBlockTemplateFeeDelta,
+		MaxOrphanBlocks:      blockchain.DefaultMaxOrphanBlocks,
 		MaxOrphanTxs:         defaultMaxOrphanTransactions,
+		MaxMempool:           defaultMaxMempool,
 		SigCacheMaxSize:      defaultSigCacheMaxSize,
+		ClaimTrieCache:       defaultClaimTrieCache,
 		Generate:             defaultGenerate,
 		TxIndex:              defaultTxIndex,
 		AddrIndex:            defaultAddrIndex,
 		Upnp:                 defaultUpnp,
+		NATPMP:               defaultNATPMP,
+		TorControl:           defaultTorControl,
+		TorIsolationMode:     defaultTorIsolationMode,
+		AlertReorgDepth:      defaultAlertReorgDepth,
+		AlertStallTimeout:    defaultAlertStallTimeout,
 	}
 
 	// Service options which are only added on Windows.
@@ -601,6 +842,19 @@ func loadConfig() (*config, []string, error) {
 		chainParams := chaincfg.CustomSignetParams(
 			sigNetChallenge, sigNetSeeds,
 		)
+		if cfg.SigNetGenesis != "" {
+			genesis, err := parseGenesis(cfg.SigNetGenesis)
+			if err != nil {
+				str := "%s: Invalid signet genesis: %v"
+				err := fmt.Errorf(str, funcName, err)
+				fmt.Fprintln(os.Stderr, err)
+				fmt.Fprintln(os.Stderr, usageMessage)
+				return nil, nil, err
+			}
+			chainParams = chaincfg.CustomSignetGenesisParams(
+				sigNetChallenge, sigNetSeeds, genesis,
+			)
+		}
 		activeNetParams.Params = &chainParams
 	}
 	if numNets > 1 {
@@ -706,6 +960,30 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Don't allow negative stall timeouts for the chain alerts.
+	if cfg.AlertStallTimeout < 0 {
+		str := "%s: The alertstalltimeout option may not be negative -- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.AlertStallTimeout)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The chain alerts can only be posted to an HTTP webhook.
+	if cfg.AlertWebhook != "" {
+		u, err := url.Parse(cfg.AlertWebhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+			u.Host == "" {
+
+			str := "%s: The alertwebhook option must be an http or " +
+				"https URL -- parsed [%v]"
+			err := fmt.Errorf(str, funcName, cfg.AlertWebhook)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
 	// Validate any given whitelisted IP addresses and networks.
 	if len(cfg.Whitelists) > 0 {
 		var ip net.IP
@@ -788,9 +1066,37 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Parse the RPC accounts, which must not reuse the username of another
+	// user.
+	rpcUsers := map[string]struct{}{
+		cfg.RPCUser:      {},
+		cfg.RPCLimitUser: {},
+	}
+	for _, s := range cfg.RPCAccounts {
+		account, err := parseRPCAccount(s)
+		if err != nil {
+			str := "%s: Error parsing RPC account: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		if _, ok := rpcUsers[account.user]; ok {
+			str := "%s: --rpcaccount must not specify the username " +
+				"%q of another RPC user"
+			err := fmt.Errorf(str, funcName, account.user)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		rpcUsers[account.user] = struct{}{}
+		cfg.rpcAccounts = append(cfg.rpcAccounts, account)
+	}
+
 	// The RPC server is disabled if no username or password is provided.
 	if (cfg.RPCUser == "" || cfg.RPCPass == "") &&
-		(cfg.RPCLimitUser == "" || cfg.RPCLimitPass == "") {
+		(cfg.RPCLimitUser == "" || cfg.RPCLimitPass == "") &&
+		len(cfg.rpcAccounts) == 0 {
 		cfg.DisableRPC = true
 	}
 
@@ -820,6 +1126,24 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	if cfg.RPCTraceSize < 0 {
+		str := "%s: The rpctracesize option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.RPCTraceSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --rpcmetrics exports the statistics on the profile server.
+	if cfg.RPCMetrics && cfg.Profile == "" {
+		str := "%s: the --rpcmetrics option requires --profile"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Validate the the minrelaytxfee.
 	cfg.minRelayTxFee, err = btcutil.NewAmount(cfg.MinRelayTxFee)
 	if err != nil {
@@ -830,6 +1154,82 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Validate the dustrelayfee.
+	cfg.dustRelayFee, err = btcutil.NewAmount(cfg.DustRelayFee)
+	if err == nil && cfg.dustRelayFee < 0 {
+		err = errors.New("must not be negative")
+	}
+	if err != nil {
+		str := "%s: invalid dustrelayfee: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the minclaimamount and minsupportamount.
+	for _, amount := range []struct {
+		option string
+		value  float64
+		amount *btcutil.Amount
+	}{
+		{"minclaimamount", cfg.MinClaimAmount, &cfg.minClaimAmount},
+		{"minsupportamount", cfg.MinSupportAmount, &cfg.minSupportAmount},
+	} {
+		*amount.amount, err = btcutil.NewAmount(amount.value)
+		if err == nil && *amount.amount < 0 {
+			err = errors.New("must not be negative")
+		}
+		if err != nil {
+			str := "%s: invalid %s: %v"
+			err := fmt.Errorf(str, funcName, amount.option, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	// Limit the size of the data of standard null data outputs to what a
+	// single push may hold.
+	if cfg.DataCarrierSize < 0 ||
+		cfg.DataCarrierSize > txscript.MaxScriptElementSize {
+
+		str := "%s: The datacarriersize option must be in between 0 " +
+			"and %d -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, txscript.MaxScriptElementSize,
+			cfg.DataCarrierSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Limit the size of the values of standard claims to what consensus
+	// allows.
+	if cfg.MaxClaimValueSize < 0 ||
+		cfg.MaxClaimValueSize > mempool.DefaultMaxClaimValueSize {
+
+		str := "%s: The maxclaimvaluesize option must be in between " +
+			"0 and %d -- parsed [%d]"
+		err := fmt.Errorf(str, funcName,
+			mempool.DefaultMaxClaimValueSize, cfg.MaxClaimValueSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the blocktemplatefeedelta.
+	cfg.blockTmplFeeDelta, err = btcutil.NewAmount(cfg.BlockTmplFeeDelta)
+	if err == nil && cfg.blockTmplFeeDelta < 0 {
+		err = errors.New("must not be negative")
+	}
+	if err != nil {
+		str := "%s: invalid blocktemplatefeedelta: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Limit the max block size to a sane value.
 	if cfg.BlockMaxSize < blockMaxSizeMin || cfg.BlockMaxSize >
 		blockMaxSizeMax {
@@ -866,6 +1266,36 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// The maximum memory pool size may not be negative.
+	if cfg.MaxMempool < 0 {
+		str := "%s: The maxmempool option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.MaxMempool)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Keep room for at least one orphan block.
+	if cfg.MaxOrphanBlocks < 1 {
+		str := "%s: The maxorphanblocks option may not be less than 1 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.MaxOrphanBlocks)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the block transaction selection strategy.
+	cfg.blockSelection, err = mining.ParseSelectionStrategy(cfg.BlockSelection)
+	if err != nil {
+		str := "%s: The blockselection option is invalid: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Limit the block priority and minimum block sizes to max block size.
 	cfg.BlockPrioritySize = minUint32(cfg.BlockPrioritySize, cfg.BlockMaxSize)
 	cfg.BlockMinSize = minUint32(cfg.BlockMinSize, cfg.BlockMaxSize)
@@ -932,6 +1362,192 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// The claim trie node cache must be able to hold some nodes.
+	if cfg.ClaimTrieCache < 1 {
+		str := "%s: the claimtriecache option must be at least 1 MiB " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.ClaimTrieCache)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The pebble tuning options of the claim trie can't be negative.
+	if cfg.ClaimTrieBloomBits < 0 || cfg.ClaimTriePebbleCache < 0 ||
+		cfg.ClaimTrieCompactions < 0 || cfg.ClaimTrieMemTable < 0 {
+
+		str := "%s: the claimtriepebble options must not be negative"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --repairclaimtrie requires --checkclaimtrie.
+	if cfg.RepairClaimTrie && !cfg.CheckClaimTrie {
+		err := fmt.Errorf("%s: the --repairclaimtrie option requires "+
+			"--checkclaimtrie", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --claimidindex and --dropclaimidindex do not mix.
+	if cfg.ClaimIDIndex && cfg.DropClaimIDIndex {
+		err := fmt.Errorf("%s: the --claimidindex and --dropclaimidindex "+
+			"options may not be activated at the same time",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --channelindex and --dropchannelindex do not mix.
+	if cfg.ChannelIndex && cfg.DropChannelIndex {
+		err := fmt.Errorf("%s: the --channelindex and --dropchannelindex "+
+			"options may not be activated at the same time",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --claimfilterindex and --dropclaimfilterindex do not mix.
+	if cfg.ClaimFilterIndex && cfg.DropClaimFilterIndex {
+		err := fmt.Errorf("%s: the --claimfilterindex and "+
+			"--dropclaimfilterindex options may not be activated at "+
+			"the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --spentindex and --dropspentindex do not mix.
+	if cfg.SpentIndex && cfg.DropSpentIndex {
+		err := fmt.Errorf("%s: the --spentindex and --dropspentindex "+
+			"options may not be activated at the same time",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The number of script validation workers can't be negative.
+	if cfg.ScriptValWorkers < 0 {
+		str := "%s: the scriptvalworkers option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.ScriptValWorkers)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune must leave enough block files to serve the most recent blocks
+	// and handle reorganizations.
+	if cfg.Prune != 0 && cfg.Prune < pruneMinSize {
+		err := fmt.Errorf("%s: the minimum value for --prune is %d MiB, "+
+			"got %d", funcName, pruneMinSize, cfg.Prune)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --pruneforkdepth must keep the forks a reorganization could switch to.
+	if cfg.PruneForkDepth != 0 &&
+		cfg.PruneForkDepth < blockchain.MinPruneForkDepth {
+
+		str := "%s: the minimum value for --pruneforkdepth is %d, got %d"
+		err := fmt.Errorf(str, funcName, blockchain.MinPruneForkDepth,
+			cfg.PruneForkDepth)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --pruneforkdb requires --pruneforkdepth.
+	if cfg.PruneForkDB && cfg.PruneForkDepth == 0 {
+		str := "%s: the --pruneforkdb option requires --pruneforkdepth"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and --addrindex do not mix since the address index needs
+	// the historical block data.
+	if cfg.Prune != 0 && cfg.AddrIndex {
+		err := fmt.Errorf("%s: the --prune and --addrindex options may "+
+			"not be activated at the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and --claimidindex do not mix since building the claim ID
+	// index after the fact needs the historical block data.
+	if cfg.Prune != 0 && cfg.ClaimIDIndex {
+		err := fmt.Errorf("%s: the --prune and --claimidindex options "+
+			"may not be activated at the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and --channelindex do not mix since building the claims by
+	// channel index after the fact needs the historical block data.
+	if cfg.Prune != 0 && cfg.ChannelIndex {
+		err := fmt.Errorf("%s: the --prune and --channelindex options "+
+			"may not be activated at the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and --claimfilterindex do not mix since building the claim
+	// name filter index after the fact needs the historical block data.
+	if cfg.Prune != 0 && cfg.ClaimFilterIndex {
+		err := fmt.Errorf("%s: the --prune and --claimfilterindex options "+
+			"may not be activated at the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and --spentindex do not mix since building the spent index
+	// after the fact needs the historical block data.
+	if cfg.Prune != 0 && cfg.SpentIndex {
+		err := fmt.Errorf("%s: the --prune and --spentindex options "+
+			"may not be activated at the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and --reindex do not mix since rebuilding the chain state
+	// needs all the historical block data.
+	if cfg.Prune != 0 && (cfg.Reindex || cfg.ReindexChainState) {
+		err := fmt.Errorf("%s: the --prune and --reindex options may "+
+			"not be activated at the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and --txindex do not mix since the transaction index can't
+	// serve any transactions from pruned blocks.  The index is enabled by
+	// default though, so it's only an error when it was enabled explicitly
+	// and it's turned off otherwise.
+	if cfg.Prune != 0 {
+		if parser.FindOptionByLongName("txindex").IsSet() && cfg.TxIndex {
+			err := fmt.Errorf("%s: the --prune and --txindex options "+
+				"may not be activated at the same time", funcName)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.TxIndex = false
+	}
+
 	// Check mining addresses are valid and saved parsed versions.
 	cfg.miningAddrs = make([]btcutil.Address, 0, len(cfg.MiningAddrs))
 	for _, strAddr := range cfg.MiningAddrs {
@@ -953,27 +1569,194 @@ func loadConfig() (*config, []string, error) {
 		cfg.miningAddrs = append(cfg.miningAddrs, addr)
 	}
 
-	// Ensure there is at least one mining address when the generate flag is
-	// set.
-	if cfg.Generate && len(cfg.MiningAddrs) == 0 {
+	// Check the mining payouts for syntax errors.
+	cfg.miningPayouts, err = parseMiningPayouts(cfg.MiningPayouts,
+		activeNetParams.Params)
+	if err != nil {
+		str := "%s: Error parsing mining payouts: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	cfg.outboundTargets, err = parseOutboundTargets(cfg.OutboundTargets)
+	if err != nil {
+		str := "%s: Error parsing outbound targets: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	cfg.violationWeights = connmgr.DefaultViolationWeights
+	for _, weight := range cfg.ViolationWeights {
+		err := connmgr.ParseViolationWeight(&cfg.violationWeights, weight)
+		if err != nil {
+			str := "%s: Error parsing violation weights: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	// Onion addresses can only be dialed through a proxy.
+	if cfg.outboundTargets[networkOnion] > 0 && (cfg.NoOnion ||
+		(cfg.Proxy == "" && cfg.OnionProxy == "")) {
+
+		str := "%s: the outboundtarget option for the onion network " +
+			"requires the proxy or onion option and may not be " +
+			"used with the noonion option"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Ensure there is at least one mining address or payout when the
+	// generate flag is set.
+	if cfg.Generate && len(cfg.MiningAddrs) == 0 && len(cfg.MiningPayouts) == 0 {
 		str := "%s: the generate flag is set, but there are no mining " +
-			"addresses specified "
+			"addresses or payouts specified "
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Ensure there is at least one mining address when the stratum server
+	// is enabled.
+	if len(cfg.StratumListeners) > 0 && len(cfg.MiningAddrs) == 0 {
+		str := "%s: the stratumlisten option is set, but there are no " +
+			"mining addresses specified "
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The gRPC server shares the credentials and TLS settings of the RPC
+	// server, so it can't run without it.
+	if len(cfg.GRPCListeners) > 0 && cfg.DisableRPC {
+		str := "%s: the grpclisten option requires the RPC server, " +
+			"which is disabled"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The public RPC server shares the credentials, the TLS settings and
+	// the command handlers of the RPC server, so it can't run without it.
+	if len(cfg.PublicRPCListeners) > 0 && cfg.DisableRPC {
+		str := "%s: the publicrpclisten option requires the RPC " +
+			"server, which is disabled"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The REST interface is served on the RPC listeners.
+	if cfg.REST && cfg.DisableRPC {
+		str := "%s: the rest option requires the RPC server, which " +
+			"is disabled"
 		err := fmt.Errorf(str, funcName)
 		fmt.Fprintln(os.Stderr, err)
 		fmt.Fprintln(os.Stderr, usageMessage)
 		return nil, nil, err
 	}
 
+	// Only allow read-only methods on the public RPC listeners.
+	if len(cfg.PublicRPCMethods) == 0 {
+		cfg.PublicRPCMethods = defaultPublicRPCMethods
+	}
+	for _, method := range cfg.PublicRPCMethods {
+		if _, ok := publicRPCEligible[method]; !ok {
+			str := "%s: the publicrpcmethod option does not " +
+				"allow %q -- only read-only methods may be " +
+				"served publicly"
+			err := fmt.Errorf(str, funcName, method)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	// --nopeerbloomfilters and --peerbloomfilters do not mix.
+	if cfg.NoPeerBloomFilters && len(cfg.PeerBloomFilters) > 0 {
+		err := fmt.Errorf("%s: the --nopeerbloomfilters and "+
+			"--peerbloomfilters options can not be used together",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The bloom filter limits must allow some filtering.
+	if cfg.MaxBloomFilterSize < 1 ||
+		cfg.MaxBloomFilterSize > wire.MaxFilterLoadFilterSize {
+
+		str := "%s: the maxpeerbloomfiltersize option must be between " +
+			"1 and %d -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, wire.MaxFilterLoadFilterSize,
+			cfg.MaxBloomFilterSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.PeerBloomWork < 1 {
+		str := "%s: the peerbloomwork option must be at least 1 KiB " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.PeerBloomWork)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The public RPC rate limits must allow requests.
+	if cfg.PublicRPCRate <= 0 || cfg.PublicRPCBurst < 1 {
+		str := "%s: the publicrpcrate option must be positive and " +
+			"the publicrpcburst option must be at least 1 -- " +
+			"parsed [%v, %v]"
+		err := fmt.Errorf(str, funcName, cfg.PublicRPCRate,
+			cfg.PublicRPCBurst)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Add default port to all listener addresses if needed and remove
 	// duplicate addresses.
 	cfg.Listeners = normalizeAddresses(cfg.Listeners,
 		activeNetParams.DefaultPort)
 
+	// Add default port to all bloom filter listener addresses if needed and
+	// remove duplicate addresses.
+	cfg.PeerBloomFilters = normalizeAddresses(cfg.PeerBloomFilters,
+		activeNetParams.DefaultPort)
+
 	// Add default port to all rpc listener addresses if needed and remove
 	// duplicate addresses.
 	cfg.RPCListeners = normalizeAddresses(cfg.RPCListeners,
 		activeNetParams.rpcPort)
 
+	// Add default port to all stratum listener addresses if needed and
+	// remove duplicate addresses.
+	cfg.StratumListeners = normalizeAddresses(cfg.StratumListeners,
+		defaultStratumPort)
+
+	// Add default port to all gRPC listener addresses if needed and remove
+	// duplicate addresses.
+	cfg.GRPCListeners = normalizeAddresses(cfg.GRPCListeners,
+		defaultGRPCPort)
+
+	// Add default port to all public RPC listener addresses if needed and
+	// remove duplicate addresses.
+	cfg.PublicRPCListeners = normalizeAddresses(cfg.PublicRPCListeners,
+		defaultPublicRPCPort)
+
 	// Only allow TLS to be disabled if the RPC is bound to localhost
 	// addresses.
 	if !cfg.DisableRPC && cfg.DisableTLS {
@@ -1016,6 +1799,69 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Check the assumed utxo sets for syntax errors.
+	cfg.addAssumeUtxo, err = parseAssumeUtxo(cfg.AddAssumeUtxo)
+	if err != nil {
+		str := "%s: Error parsing assumed utxo sets: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Parse the assumed valid block hash, which is only used during
+	// header-check sync.
+	if cfg.AssumeValid != "" {
+		if !cfg.HeaderCheckSync {
+			str := "%s: the --assumevalid option requires the " +
+				"--headerchecksync option"
+			err := fmt.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.assumeValid, err = chainhash.NewHashFromStr(cfg.AssumeValid)
+		if err != nil {
+			str := "%s: Error parsing assumed valid block hash: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	// An onion service requires listening for incoming connections.
+	if cfg.ListenOnion && cfg.DisableListen {
+		str := "%s: the --listenonion option requires listening for " +
+			"incoming connections"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Check the Tor control port address when an onion service is to be
+	// created.
+	if cfg.ListenOnion {
+		_, _, err := net.SplitHostPort(cfg.TorControl)
+		if err != nil {
+			str := "%s: Tor control address '%s' is invalid: %v"
+			err := fmt.Errorf(str, funcName, cfg.TorControl, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	// The peers of an onion service are on the Tor network, so route the
+	// onion addresses through the default SOCKS port of Tor unless a proxy
+	// is specified or onion addresses are disabled.
+	if cfg.ListenOnion && cfg.Proxy == "" && cfg.OnionProxy == "" &&
+		!cfg.NoOnion {
+
+		cfg.OnionProxy = defaultTorSocks
+	}
+
 	// Tor stream isolation requires either proxy or onion proxy to be set.
 	if cfg.TorIsolation && cfg.Proxy == "" && cfg.OnionProxy == "" {
 		str := "%s: Tor stream isolation requires either proxy or " +
@@ -1026,6 +1872,25 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Validate the Tor stream isolation mode, which only applies with
+	// --torisolation.
+	torIsolation := connmgr.IsolateNone
+	if cfg.TorIsolation {
+		torIsolation, err = connmgr.ParseStreamIsolation(
+			cfg.TorIsolationMode)
+		if err == nil && torIsolation == connmgr.IsolateNone {
+			err = errors.New("stream isolation can't be disabled " +
+				"with --torisolation")
+		}
+		if err != nil {
+			str := "%s: The torisolationmode option is invalid: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
 	// Setup dial and DNS resolution (lookup) functions depending on the
 	// specified options.  The default is to use the standard
 	// net.DialTimeout function as well as the system DNS resolver.  When a
@@ -1047,20 +1912,24 @@ func loadConfig() (*config, []string, error) {
 		// Tor isolation flag means proxy credentials will be overridden
 		// unless there is also an onion proxy configured in which case
 		// that one will be overridden.
-		torIsolation := false
-		if cfg.TorIsolation && cfg.OnionProxy == "" &&
+		proxyIsolation := connmgr.IsolateNone
+		if cfg.OnionProxy == "" {
+			proxyIsolation = torIsolation
+		}
+		if proxyIsolation != connmgr.IsolateNone &&
 			(cfg.ProxyUser != "" || cfg.ProxyPass != "") {
 
-			torIsolation = true
 			fmt.Fprintln(os.Stderr, "Tor isolation set -- "+
 				"overriding specified proxy user credentials")
 		}
 
-		proxy := &socks.Proxy{
-			Addr:         cfg.Proxy,
-			Username:     cfg.ProxyUser,
-			Password:     cfg.ProxyPass,
-			TorIsolation: torIsolation,
+		proxy, err := connmgr.NewProxyDialer(cfg.Proxy, cfg.ProxyUser,
+			cfg.ProxyPass, proxyIsolation)
+		if err != nil {
+			str := "%s: Unable to set up the proxy: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
 		}
 		cfg.dial = proxy.DialTimeout
 
@@ -1099,15 +1968,15 @@ func loadConfig() (*config, []string, error) {
 				"credentials ")
 		}
 
-		cfg.oniondial = func(network, addr string, timeout time.Duration) (net.Conn, error) {
-			proxy := &socks.Proxy{
-				Addr:         cfg.OnionProxy,
-				Username:     cfg.OnionProxyUser,
-				Password:     cfg.OnionProxyPass,
-				TorIsolation: cfg.TorIsolation,
-			}
-			return proxy.DialTimeout(network, addr, timeout)
+		proxy, err := connmgr.NewProxyDialer(cfg.OnionProxy,
+			cfg.OnionProxyUser, cfg.OnionProxyPass, torIsolation)
+		if err != nil {
+			str := "%s: Unable to set up the onion proxy: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
 		}
+		cfg.oniondial = proxy.DialTimeout
 
 		// When configured in bridge mode (both --onion and --proxy are
 		// configured), it means that the proxy configured by --proxy is
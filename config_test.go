@@ -29,9 +29,12 @@ type configCmdLineOnly struct {
 	NoWinService        bool     `long:"nowinservice" description:"Do not start as a background service on Windows -- NOTE: This flag only works on the command line, not in the config file"`
 	DisableStallHandler bool     `long:"nostalldetect" description:"Disables the stall handler system for each peer, useful in simnet/regtest integration tests frameworks"`
 	RegressionTest      bool     `long:"regtest" description:"Use the regression test network"`
+	Reindex             bool     `long:"reindex" description:"Rebuild the block index and the chain state, including the claim trie and the optional indexes, from the blocks stored in the database on start up -- An interrupted reindex resumes on the next start"`
+	ReindexChainState   bool     `long:"reindex-chainstate" description:"Rebuild the chain state, including the claim trie and the optional indexes, from the blocks stored in the database on start up -- An interrupted reindex resumes on the next start"`
 	SimNet              bool     `long:"simnet" description:"Use the simulation test network"`
 	SigNet              bool     `long:"signet" description:"Use the signet test network"`
 	SigNetChallenge     string   `long:"signetchallenge" description:"Connect to a custom signet network defined by this challenge instead of using the global default signet test network -- Can be specified multiple times"`
+	SigNetGenesis       string   `long:"signetgenesis" description:"Start the custom signet network from a genesis block with the specified timestamp, target difficulty bits and nonce formatted as <unix time>:<hex bits>:<nonce> instead of the genesis block of the global default signet network"`
 	SigNetSeedNode      []string `long:"signetseednode" description:"Specify a seed node for the signet network instead of using the global default signet network seed nodes"`
 	ShowVersion         bool     `short:"V" long:"version" description:"Display version information and exit"`
 }
@@ -173,3 +176,35 @@ func TestCreateDefaultConfigFile(t *testing.T) {
 		t.Error("Could not find rpcpass in generated default config file.")
 	}
 }
+
+// TestParseOutboundTargets ensures the outbound connection targets are parsed
+// and the invalid ones rejected.
+func TestParseOutboundTargets(t *testing.T) {
+	tests := []struct {
+		targets []string
+		want    map[string]uint32
+		valid   bool
+	}{
+		{nil, nil, true},
+		{[]string{"ipv4:8", "IPv6:2", "onion:2"},
+			map[string]uint32{"ipv4": 8, "ipv6": 2, "onion": 2}, true},
+		{[]string{"ipv6:1", "ipv6:2"}, map[string]uint32{"ipv6": 3}, true},
+		{[]string{"ipv4"}, nil, false},
+		{[]string{"i2p:2"}, nil, false},
+		{[]string{"ipv4:-1"}, nil, false},
+		{[]string{"ipv4:2:3"}, nil, false},
+	}
+
+	for i, test := range tests {
+		got, err := parseOutboundTargets(test.targets)
+		if (err == nil) != test.valid {
+			t.Errorf("#%d: unexpected error for %v: %v", i,
+				test.targets, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("#%d: unexpected targets - got %v, want %v", i,
+				got, test.want)
+		}
+	}
+}
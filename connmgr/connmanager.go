@@ -5,6 +5,7 @@
 package connmgr
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"net"
@@ -22,6 +23,10 @@ var (
 	//ErrDialNil is used to indicate that Dial cannot be nil in the configuration.
 	ErrDialNil = errors.New("Config: Dial cannot be nil")
 
+	// ErrBannedAddr is used to indicate that a connection request was
+	// rejected because the address is banned.
+	ErrBannedAddr = errors.New("address is banned")
+
 	// maxRetryDuration is the max duration of time retrying of a persistent
 	// connection is allowed to grow to.  This is necessary since the retry
 	// logic uses a backoff mechanism which increases the interval base times
@@ -140,8 +145,29 @@ type Config struct {
 	// to.  If nil, no new connections will be made automatically.
 	GetNewAddress func() (net.Addr, error)
 
+	// NetworkTargets is the minimum number of outbound connections to
+	// maintain to each network, keyed by the names returned by
+	// AddrNetwork.  The connections to the networks count toward
+	// TargetOutbound.  New connections are made to the network furthest
+	// below its target first, with an address from GetNetworkAddress.
+	NetworkTargets map[string]uint32
+
+	// AddrNetwork returns the name of the network of an address.  It is
+	// required for the network targets to be met.
+	AddrNetwork func(net.Addr) string
+
+	// GetNetworkAddress is a way to get an address of the named network to
+	// make a network connection to in order to meet its target.
+	// GetNewAddress is used instead when it fails or is nil.
+	GetNetworkAddress func(network string) (net.Addr, error)
+
 	// Dial connects to the address on the named network. It cannot be nil.
 	Dial func(net.Addr) (net.Conn, error)
+
+	// IsBanned is an optional callback used to determine whether or not an
+	// address is banned.  Banned addresses are never dialed and inbound
+	// connections from them are closed as soon as they are accepted.
+	IsBanned func(net.Addr) bool
 }
 
 // registerPending is used to register a pending connection attempt. By
@@ -171,6 +197,15 @@ type handleFailed struct {
 	err error
 }
 
+// pickNetwork is used to select the network of a pending connection, which is
+// the one furthest below its target or none when all targets are met.  The
+// excluded networks, which had no address to connect to, are skipped.
+type pickNetwork struct {
+	c       *ConnReq
+	exclude map[string]struct{}
+	reply   chan string
+}
+
 // ConnManager provides a manager to handle network connections.
 type ConnManager struct {
 	// The following variables must only be used atomically.
@@ -183,6 +218,10 @@ type ConnManager struct {
 	failedAttempts uint64
 	requests       chan interface{}
 	quit           chan struct{}
+
+	// evictionKey is the random key of the hashes of the network groups
+	// protected from eviction by SelectEviction.
+	evictionKey [32]byte
 }
 
 // handleFailedConn handles a connection failed due to a disconnect or any
@@ -234,6 +273,11 @@ func (cm *ConnManager) connHandler() {
 
 		// conns represents the set of all actively connected peers.
 		conns = make(map[uint64]*ConnReq, cm.cfg.TargetOutbound)
+
+		// networks holds the networks of the connected peers and the
+		// ones picked for pending conn requests, which count toward
+		// the network targets.
+		networks = make(map[uint64]string, cm.cfg.TargetOutbound)
 	)
 
 out:
@@ -263,6 +307,9 @@ out:
 				connReq.updateState(ConnEstablished)
 				connReq.conn = msg.conn
 				conns[connReq.id] = connReq
+				if cm.cfg.AddrNetwork != nil {
+					networks[connReq.id] = cm.cfg.AddrNetwork(connReq.Addr)
+				}
 				log.Debugf("Connected to %v", connReq)
 				connReq.retryCount = 0
 				cm.failedAttempts = 0
@@ -290,6 +337,7 @@ out:
 					connReq.updateState(ConnCanceled)
 					log.Debugf("Canceling: %v", connReq)
 					delete(pending, msg.id)
+					delete(networks, msg.id)
 					continue
 
 				}
@@ -299,6 +347,7 @@ out:
 				// callback.
 				log.Debugf("Disconnected from %v", connReq)
 				delete(conns, msg.id)
+				delete(networks, msg.id)
 
 				if connReq.conn != nil {
 					connReq.conn.Close()
@@ -344,7 +393,16 @@ out:
 				connReq.updateState(ConnFailing)
 				log.Debugf("Failed to connect to %v: %v",
 					connReq, msg.err)
+				delete(networks, connReq.id)
 				cm.handleFailedConn(connReq)
+
+			case pickNetwork:
+				delete(networks, msg.c.id)
+				network := cm.neededNetwork(networks, msg.exclude)
+				if network != "" {
+					networks[msg.c.id] = network
+				}
+				msg.reply <- network
 			}
 
 		case <-cm.quit:
@@ -356,6 +414,70 @@ out:
 	log.Trace("Connection handler done")
 }
 
+// neededNetwork returns the network furthest below its target given the
+// networks of the connections counting toward the targets, or an empty string
+// when all the targets are met.  Ties are broken by name and the excluded
+// networks are skipped.
+func (cm *ConnManager) neededNetwork(networks map[uint64]string,
+	exclude map[string]struct{}) string {
+
+	if len(cm.cfg.NetworkTargets) == 0 {
+		return ""
+	}
+
+	counts := make(map[string]uint32, len(cm.cfg.NetworkTargets))
+	for _, network := range networks {
+		counts[network]++
+	}
+
+	var needed string
+	var neededDeficit uint32
+	for network, target := range cm.cfg.NetworkTargets {
+		if _, ok := exclude[network]; ok || counts[network] >= target {
+			continue
+		}
+		deficit := target - counts[network]
+		if deficit > neededDeficit ||
+			(deficit == neededDeficit && network < needed) {
+
+			needed, neededDeficit = network, deficit
+		}
+	}
+	return needed
+}
+
+// newAddress returns an address for the passed connection request, which is
+// of the network furthest below its target with an address when there is one.
+func (cm *ConnManager) newAddress(c *ConnReq) (net.Addr, error) {
+	if cm.cfg.GetNetworkAddress == nil || len(cm.cfg.NetworkTargets) == 0 {
+		return cm.cfg.GetNewAddress()
+	}
+
+	var exclude map[string]struct{}
+	for {
+		reply := make(chan string, 1)
+		select {
+		case cm.requests <- pickNetwork{c, exclude, reply}:
+		case <-cm.quit:
+			return nil, errors.New("connection manager stopped")
+		}
+		network := <-reply
+		if network == "" {
+			return cm.cfg.GetNewAddress()
+		}
+
+		addr, err := cm.cfg.GetNetworkAddress(network)
+		if err == nil {
+			return addr, nil
+		}
+		log.Debugf("No address to meet the %s target: %v", network, err)
+		if exclude == nil {
+			exclude = make(map[string]struct{}, 1)
+		}
+		exclude[network] = struct{}{}
+	}
+}
+
 // NewConnReq creates a new connection request and connects to the
 // corresponding address.
 func (cm *ConnManager) NewConnReq() {
@@ -388,7 +510,7 @@ func (cm *ConnManager) NewConnReq() {
 		return
 	}
 
-	addr, err := cm.cfg.GetNewAddress()
+	addr, err := cm.newAddress(c)
 	if err != nil {
 		select {
 		case cm.requests <- handleFailed{c, err}:
@@ -439,6 +561,14 @@ func (cm *ConnManager) Connect(c *ConnReq) {
 		}
 	}
 
+	if cm.cfg.IsBanned != nil && cm.cfg.IsBanned(c.Addr) {
+		select {
+		case cm.requests <- handleFailed{c, ErrBannedAddr}:
+		case <-cm.quit:
+		}
+		return
+	}
+
 	log.Debugf("Attempting to connect to %v", c)
 
 	conn, err := cm.cfg.Dial(c.Addr)
@@ -499,6 +629,12 @@ func (cm *ConnManager) listenHandler(listener net.Listener) {
 			}
 			continue
 		}
+		if cm.cfg.IsBanned != nil && cm.cfg.IsBanned(conn.RemoteAddr()) {
+			log.Debugf("Rejecting connection from banned address %v",
+				conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
 		go cm.cfg.OnAccept(conn)
 	}
 
@@ -573,5 +709,8 @@ func New(cfg *Config) (*ConnManager, error) {
 		requests: make(chan interface{}),
 		quit:     make(chan struct{}),
 	}
+	if _, err := rand.Read(cm.evictionKey[:]); err != nil {
+		return nil, err
+	}
 	return &cm, nil
 }
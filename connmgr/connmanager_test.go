@@ -216,6 +216,53 @@ func TestTargetOutbound(t *testing.T) {
 	cmgr.Stop()
 }
 
+// TestNetworkTargets tests that the connection manager meets the outbound
+// targets of the networks before connecting to any address.
+func TestNetworkTargets(t *testing.T) {
+	targetOutbound := uint32(6)
+	targets := map[string]uint32{"ipv6": 2, "onion": 1, "i2p": 1}
+	connected := make(chan *ConnReq)
+	cmgr, err := New(&Config{
+		TargetOutbound: targetOutbound,
+		Dial:           mockDialer,
+		GetNewAddress: func() (net.Addr, error) {
+			return &mockAddr{"ipv4", "127.0.0.1:18555"}, nil
+		},
+		NetworkTargets: targets,
+		AddrNetwork: func(addr net.Addr) string {
+			return addr.Network()
+		},
+		GetNetworkAddress: func(network string) (net.Addr, error) {
+			// There are no addresses of the i2p network.
+			if network == "i2p" {
+				return nil, errors.New("no i2p address")
+			}
+			return &mockAddr{network, network + ":18555"}, nil
+		},
+		OnConnection: func(c *ConnReq, conn net.Conn) {
+			connected <- c
+		},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	cmgr.Start()
+	counts := make(map[string]uint32)
+	for i := uint32(0); i < targetOutbound; i++ {
+		c := <-connected
+		counts[c.Addr.Network()]++
+	}
+	cmgr.Stop()
+
+	want := map[string]uint32{"ipv4": 3, "ipv6": 2, "onion": 1}
+	for network, count := range want {
+		if counts[network] != count {
+			t.Errorf("unexpected %s connections - got %d, want %d",
+				network, counts[network], count)
+		}
+	}
+}
+
 // TestRetryPermanent tests that permanent connection requests are retried.
 //
 // We make a permanent connection request using Connect, disconnect it using
@@ -384,6 +431,49 @@ func TestNetworkFailure(t *testing.T) {
 	}
 }
 
+// TestBannedAddr tests that the connection manager never dials addresses
+// reported as banned.
+func TestBannedAddr(t *testing.T) {
+	var dials uint32
+	countDialer := func(addr net.Addr) (net.Conn, error) {
+		atomic.AddUint32(&dials, 1)
+		return mockDialer(addr)
+	}
+	cmgr, err := New(&Config{
+		RetryDuration: 5 * time.Millisecond,
+		Dial:          countDialer,
+		IsBanned: func(addr net.Addr) bool {
+			return true
+		},
+		OnConnection: func(c *ConnReq, conn net.Conn) {
+			t.Fatalf("banned addr: got unexpected connection - %v", c.Addr)
+		},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	cr := &ConnReq{
+		Addr: &net.TCPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: 18555,
+		},
+		Permanent: true,
+	}
+	cmgr.Start()
+	cmgr.Connect(cr)
+	time.Sleep(20 * time.Millisecond)
+	cmgr.Stop()
+	cmgr.Wait()
+	if got := atomic.LoadUint32(&dials); got != 0 {
+		t.Fatalf("banned addr: unexpected number of dials - got %v, want 0",
+			got)
+	}
+	if cr.State() != ConnFailing {
+		t.Fatalf("banned addr: want state %v, got state %v", ConnFailing,
+			cr.State())
+	}
+}
+
 // TestStopFailed tests that failed connections are ignored after connmgr is
 // stopped.
 //
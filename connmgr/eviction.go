@@ -0,0 +1,138 @@
+package connmgr
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"time"
+)
+
+const (
+	// evictProtectNetGroups is the number of inbound connections from
+	// distinct network groups protected from eviction.
+	evictProtectNetGroups = 4
+
+	// evictProtectPingTime is the number of inbound connections with the
+	// lowest ping time protected from eviction.
+	evictProtectPingTime = 8
+
+	// evictProtectUptimeDivisor is the divisor of the number of remaining
+	// inbound connections giving how many of the longest established ones
+	// are protected from eviction.
+	evictProtectUptimeDivisor = 2
+)
+
+// EvictionCandidate describes an inbound connection which may be evicted to
+// make room for a new one.
+type EvictionCandidate struct {
+	// ID identifies the connection to the caller.
+	ID uint64
+
+	// NetGroup is the network group of the address of the remote peer,
+	// such as the one returned by addrmgr.GroupKey.
+	NetGroup string
+
+	// ConnectedAt is the time the connection was established.
+	ConnectedAt time.Time
+
+	// PingTime is the last ping time of the remote peer, or zero when it
+	// isn't known yet.
+	PingTime time.Duration
+}
+
+// SelectEviction selects the inbound connection to evict among the passed ones
+// to make room for a new one when all the inbound slots are used, and returns
+// whether there is one which isn't protected.
+//
+// The connections an attacker can't easily take over are protected, which
+// makes it harder to eclipse the node by filling its inbound slots:
+//   - the longest established connection of a few network groups chosen by a
+//     keyed hash which the remote peers can't predict
+//   - the connections with the lowest ping times
+//   - half of the remaining connections, which have been established the
+//     longest
+//
+// The most recent connection of the network group with the most remaining
+// connections is then evicted.
+//
+// This function is safe for concurrent access.
+func (cm *ConnManager) SelectEviction(candidates []EvictionCandidate) (EvictionCandidate, bool) {
+	remaining := make([]EvictionCandidate, len(candidates))
+	copy(remaining, candidates)
+
+	// Protect the longest established connection of each of the network
+	// groups with the highest keyed hashes.
+	netGroupHash := func(netGroup string) uint64 {
+		h := sha256.New()
+		h.Write(cm.evictionKey[:])
+		h.Write([]byte(netGroup))
+		return binary.LittleEndian.Uint64(h.Sum(nil))
+	}
+	sort.SliceStable(remaining, func(i, j int) bool {
+		hi := netGroupHash(remaining[i].NetGroup)
+		hj := netGroupHash(remaining[j].NetGroup)
+		if hi != hj {
+			return hi > hj
+		}
+		return remaining[i].ConnectedAt.Before(remaining[j].ConnectedAt)
+	})
+	protected := 0
+	unprotected := make([]EvictionCandidate, 0, len(remaining))
+	for i, c := range remaining {
+		if protected < evictProtectNetGroups &&
+			(i == 0 || c.NetGroup != remaining[i-1].NetGroup) {
+
+			protected++
+			continue
+		}
+		unprotected = append(unprotected, c)
+	}
+	remaining = unprotected
+
+	// Protect the connections with the lowest ping times, considering
+	// unknown ones as the highest.
+	sort.SliceStable(remaining, func(i, j int) bool {
+		pi, pj := remaining[i].PingTime, remaining[j].PingTime
+		if pi == 0 || pj == 0 {
+			return pi == 0 && pj != 0
+		}
+		return pi > pj
+	})
+	remaining = protectLast(remaining, evictProtectPingTime)
+
+	// Protect the longest established connections.
+	sort.SliceStable(remaining, func(i, j int) bool {
+		return remaining[i].ConnectedAt.After(remaining[j].ConnectedAt)
+	})
+	remaining = protectLast(remaining,
+		len(remaining)/evictProtectUptimeDivisor)
+
+	if len(remaining) == 0 {
+		return EvictionCandidate{}, false
+	}
+
+	// Evict the most recent connection of the network group with the most
+	// connections, breaking ties with the group with the most recent one.
+	// The remaining connections are sorted from the most recent one, so
+	// the first connection of each group is its most recent one.
+	counts := make(map[string]int)
+	for _, c := range remaining {
+		counts[c.NetGroup]++
+	}
+	evict := remaining[0]
+	for _, c := range remaining[1:] {
+		if counts[c.NetGroup] > counts[evict.NetGroup] {
+			evict = c
+		}
+	}
+	return evict, true
+}
+
+// protectLast removes up to the passed number of connections from the end of
+// the passed ones.
+func protectLast(candidates []EvictionCandidate, n int) []EvictionCandidate {
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	return candidates[:len(candidates)-n]
+}
@@ -0,0 +1,61 @@
+package connmgr
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestSelectEviction ensures the inbound connections protected from eviction
+// are never selected, and that the most recent connection of the network group
+// with the most connections is evicted otherwise.
+func TestSelectEviction(t *testing.T) {
+	cm, err := New(&Config{Dial: mockDialer})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+
+	// There is nothing to evict when all the connections are protected.
+	now := time.Now()
+	var candidates []EvictionCandidate
+	for i := 0; i < evictProtectNetGroups+evictProtectPingTime; i++ {
+		candidates = append(candidates, EvictionCandidate{
+			ID:          uint64(i),
+			NetGroup:    fmt.Sprintf("10.%d", i),
+			ConnectedAt: now.Add(-time.Duration(i) * time.Minute),
+			PingTime:    time.Duration(i+1) * time.Millisecond,
+		})
+	}
+	if _, ok := cm.SelectEviction(candidates); ok {
+		t.Fatalf("a protected connection was selected")
+	}
+	if _, ok := cm.SelectEviction(nil); ok {
+		t.Fatalf("a connection was selected without candidates")
+	}
+
+	// Add connections from a single network group without a known ping
+	// time, which are more recent than the others.  The older half of them
+	// are protected by their uptime, and the most recent one is evicted.
+	const numSameGroup = 10
+	for i := 0; i < numSameGroup; i++ {
+		candidates = append(candidates, EvictionCandidate{
+			ID:          uint64(100 + i),
+			NetGroup:    "192.168",
+			ConnectedAt: now.Add(time.Duration(i+1) * time.Second),
+		})
+	}
+	evict, ok := cm.SelectEviction(candidates)
+	if !ok {
+		t.Fatalf("no connection was selected")
+	}
+	if evict.ID != 100+numSameGroup-1 {
+		t.Fatalf("unexpected evicted connection %d", evict.ID)
+	}
+
+	// The candidates are left untouched.
+	for i, c := range candidates[:evictProtectNetGroups] {
+		if c.ID != uint64(i) {
+			t.Fatalf("candidates were reordered")
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package connmgr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Violation identifies a category of misbehavior of a peer.
+type Violation uint8
+
+// These constants define the categories of misbehavior of peers.
+const (
+	// InvalidMessage is the category of malformed or invalid messages,
+	// such as a request for a transaction out of the range of a block.
+	InvalidMessage Violation = iota
+
+	// UnrequestedData is the category of data sent by a peer without
+	// having been requested.
+	UnrequestedData
+
+	// Stalling is the category of peers slow to provide the data requested
+	// from them, such as blocks during the initial block download.  Peers
+	// are never banned for stalling.  Instead, the score of the category
+	// tells how unreliable a peer has been lately.
+	Stalling
+
+	// ProtocolAbuse is the category of messages which are valid but are
+	// either not allowed by the negotiated protocol or sent so often that
+	// they exhaust the resources of the node.
+	ProtocolAbuse
+
+	// numViolations is the number of categories of misbehavior.
+	numViolations
+)
+
+// violationStrings is a map of categories of misbehavior back to their
+// constant names for pretty printing.
+var violationStrings = [numViolations]string{
+	InvalidMessage:  "invalidmessage",
+	UnrequestedData: "unrequesteddata",
+	Stalling:        "stalling",
+	ProtocolAbuse:   "protocolabuse",
+}
+
+// String returns the Violation as a human-readable name.
+func (v Violation) String() string {
+	if v < numViolations {
+		return violationStrings[v]
+	}
+	return fmt.Sprintf("Unknown Violation (%d)", uint8(v))
+}
+
+// Bannable returns whether the misbehavior of the category counts toward the
+// ban score of peers.
+func (v Violation) Bannable() bool {
+	return v != Stalling
+}
+
+// ViolationWeights are the weights in percent of the categories of misbehavior,
+// which scale the scores added for their violations.
+type ViolationWeights [numViolations]uint32
+
+// DefaultViolationWeights are the default weights of the categories of
+// misbehavior, which add the scores of the violations unchanged.
+var DefaultViolationWeights = ViolationWeights{100, 100, 100, 100}
+
+// Apply returns the passed score of a violation of the passed category scaled
+// by the weight of the category.
+func (w *ViolationWeights) Apply(v Violation, score uint32) uint32 {
+	return uint32(uint64(score) * uint64(w[v]) / 100)
+}
+
+// ParseViolationWeight parses a weight of a category of misbehavior given as
+// <category>=<percent> into the passed weights.
+func ParseViolationWeight(weights *ViolationWeights, s string) error {
+	fields := strings.SplitN(s, "=", 2)
+	if len(fields) != 2 {
+		return fmt.Errorf("violation weight %q is not in the form "+
+			"<category>=<percent>", s)
+	}
+
+	percent, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid percent of violation weight %q", s)
+	}
+	for v, name := range violationStrings {
+		if name == fields[0] {
+			weights[v] = uint32(percent)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown category of violation %q -- valid "+
+		"categories are %s", fields[0],
+		strings.Join(violationStrings[:], ", "))
+}
+
+// MisbehaviorScore tracks the misbehavior of a peer with a dynamic ban score
+// per category of violation.  The persistent and decaying scores are kept
+// apart for each category, so the decaying scores of the categories decay
+// independently of each other.
+//
+// Zero value: Values of type MisbehaviorScore are immediately ready for use
+// upon declaration.
+type MisbehaviorScore struct {
+	scores [numViolations]DynamicBanScore
+}
+
+// Increase increases the persistent and decaying scores of the passed category
+// by the values passed as parameters.  The resulting ban score is returned.
+//
+// This function is safe for concurrent access.
+func (s *MisbehaviorScore) Increase(v Violation, persistent, transient uint32) uint32 {
+	s.scores[v].Increase(persistent, transient)
+	return s.Int()
+}
+
+// Int returns the current ban score, the sum of the scores of the categories
+// of violations which peers can be banned for.
+//
+// This function is safe for concurrent access.
+func (s *MisbehaviorScore) Int() uint32 {
+	var score uint32
+	for v := range s.scores {
+		if Violation(v).Bannable() {
+			score += s.scores[v].Int()
+		}
+	}
+	return score
+}
+
+// Score returns the current score of the passed category of violations.
+//
+// This function is safe for concurrent access.
+func (s *MisbehaviorScore) Score(v Violation) uint32 {
+	return s.scores[v].Int()
+}
+
+// Breakdown returns the current scores of the categories of violations keyed
+// by their names.  Only the categories with a score are included.
+//
+// This function is safe for concurrent access.
+func (s *MisbehaviorScore) Breakdown() map[string]uint32 {
+	breakdown := make(map[string]uint32)
+	for v := range s.scores {
+		if score := s.scores[v].Int(); score != 0 {
+			breakdown[Violation(v).String()] = score
+		}
+	}
+	return breakdown
+}
+
+// Reset sets the scores of all the categories to zero.
+//
+// This function is safe for concurrent access.
+func (s *MisbehaviorScore) Reset() {
+	for v := range s.scores {
+		s.scores[v].Reset()
+	}
+}
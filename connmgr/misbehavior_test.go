@@ -0,0 +1,65 @@
+package connmgr
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMisbehaviorScore ensures the scores of the categories of violations are
+// tracked separately and that stalling doesn't count toward the ban score.
+func TestMisbehaviorScore(t *testing.T) {
+	var s MisbehaviorScore
+
+	if score := s.Increase(InvalidMessage, 20, 0); score != 20 {
+		t.Fatalf("unexpected ban score %d, want 20", score)
+	}
+	if score := s.Increase(ProtocolAbuse, 0, 30); score != 50 {
+		t.Fatalf("unexpected ban score %d, want 50", score)
+	}
+	if score := s.Increase(Stalling, 0, 40); score != 50 {
+		t.Fatalf("unexpected ban score %d after stalling, want 50", score)
+	}
+	if score := s.Score(Stalling); score != 40 {
+		t.Fatalf("unexpected stalling score %d, want 40", score)
+	}
+
+	want := map[string]uint32{
+		"invalidmessage": 20,
+		"protocolabuse":  30,
+		"stalling":       40,
+	}
+	if breakdown := s.Breakdown(); !reflect.DeepEqual(breakdown, want) {
+		t.Fatalf("unexpected breakdown %v, want %v", breakdown, want)
+	}
+
+	s.Reset()
+	if score := s.Int(); score != 0 {
+		t.Fatalf("unexpected ban score %d after reset, want 0", score)
+	}
+	if breakdown := s.Breakdown(); len(breakdown) != 0 {
+		t.Fatalf("unexpected breakdown %v after reset", breakdown)
+	}
+}
+
+// TestParseViolationWeight ensures the weights of the categories of violations
+// are parsed and applied properly.
+func TestParseViolationWeight(t *testing.T) {
+	weights := DefaultViolationWeights
+	if err := ParseViolationWeight(&weights, "stalling=50"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if score := weights.Apply(Stalling, 30); score != 15 {
+		t.Fatalf("unexpected weighted score %d, want 15", score)
+	}
+	if score := weights.Apply(InvalidMessage, 30); score != 30 {
+		t.Fatalf("unexpected weighted score %d, want 30", score)
+	}
+
+	for _, s := range []string{"stalling", "stalling=x", "stalling=-1",
+		"unknown=10"} {
+
+		if err := ParseViolationWeight(&weights, s); err == nil {
+			t.Fatalf("expected error parsing %q", s)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package connmgr
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/btcsuite/go-socks/socks"
+)
+
+// StreamIsolation identifies how the credentials sent to a SOCKS5 proxy are
+// chosen for each connection.  Tor isolates the streams of connections using
+// different credentials on different circuits, which makes it more difficult
+// to correlate them.
+type StreamIsolation int
+
+const (
+	// IsolateNone uses the configured credentials, if any, for all
+	// connections.
+	IsolateNone StreamIsolation = iota
+
+	// IsolateConnection uses new random credentials for each connection.
+	IsolateConnection
+
+	// IsolateDestination uses the same random credentials for all the
+	// connections to a destination, and different ones for each
+	// destination.  Reconnections to a peer then reuse its circuit while
+	// the connections to different peers still can't be correlated.
+	IsolateDestination
+)
+
+// streamIsolationStrings is a map of stream isolation modes back to their
+// constant names for pretty printing and parsing.
+var streamIsolationStrings = map[StreamIsolation]string{
+	IsolateNone:        "none",
+	IsolateConnection:  "connection",
+	IsolateDestination: "destination",
+}
+
+// String returns the StreamIsolation in human-readable form.
+func (s StreamIsolation) String() string {
+	if str, ok := streamIsolationStrings[s]; ok {
+		return str
+	}
+	return fmt.Sprintf("Unknown StreamIsolation (%d)", int(s))
+}
+
+// ParseStreamIsolation returns the stream isolation mode with the passed name.
+func ParseStreamIsolation(name string) (StreamIsolation, error) {
+	for isolation, str := range streamIsolationStrings {
+		if str == name {
+			return isolation, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown stream isolation mode %q -- supported "+
+		"modes are none, connection and destination", name)
+}
+
+// ProxyDialer dials connections through a SOCKS5 proxy, choosing the
+// credentials of each connection according to its stream isolation mode.
+type ProxyDialer struct {
+	addr      string
+	username  string
+	password  string
+	isolation StreamIsolation
+
+	// destinationKey is the secret the credentials of each destination are
+	// derived from with IsolateDestination.  It's random so the
+	// credentials can't be linked to the destinations by the proxy and
+	// change across restarts.
+	destinationKey [32]byte
+}
+
+// NewProxyDialer returns a dialer for the SOCKS5 proxy at the passed address
+// using the passed credentials and stream isolation mode.  The credentials
+// are only used with IsolateNone.
+func NewProxyDialer(addr, username, password string,
+	isolation StreamIsolation) (*ProxyDialer, error) {
+
+	d := &ProxyDialer{
+		addr:      addr,
+		username:  username,
+		password:  password,
+		isolation: isolation,
+	}
+	if isolation == IsolateDestination {
+		if _, err := rand.Read(d.destinationKey[:]); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// destinationCredentials returns the credentials of the passed destination
+// with IsolateDestination.
+func (d *ProxyDialer) destinationCredentials(addr string) (string, string) {
+	mac := hmac.New(sha256.New, d.destinationKey[:])
+	mac.Write([]byte(addr))
+	sum := mac.Sum(nil)
+	return hex.EncodeToString(sum[0:8]), hex.EncodeToString(sum[8:16])
+}
+
+// DialTimeout connects to the passed address through the proxy, using the
+// passed timeout to connect to the proxy.
+func (d *ProxyDialer) DialTimeout(network, addr string,
+	timeout time.Duration) (net.Conn, error) {
+
+	proxy := &socks.Proxy{
+		Addr:         d.addr,
+		Username:     d.username,
+		Password:     d.password,
+		TorIsolation: d.isolation == IsolateConnection,
+	}
+	if d.isolation == IsolateDestination {
+		proxy.Username, proxy.Password = d.destinationCredentials(addr)
+	}
+	return proxy.DialTimeout(network, addr, timeout)
+}
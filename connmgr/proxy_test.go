@@ -0,0 +1,158 @@
+package connmgr
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// socksCredentials starts a fake SOCKS5 proxy which reads the credentials sent
+// by each client before closing the connection, and returns its address along
+// with the channel the credentials are delivered on.  Clients which don't send
+// credentials are delivered an empty pair.
+func socksCredentials(t *testing.T) (string, <-chan [2]string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	creds := make(chan [2]string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			creds <- readSocksCredentials(conn)
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), creds
+}
+
+// readSocksCredentials reads the greeting and the username/password
+// authentication request of a SOCKS5 client.
+func readSocksCredentials(conn net.Conn) [2]string {
+	var header [2]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return [2]string{}
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return [2]string{}
+	}
+	if len(methods) < 2 {
+		return [2]string{}
+	}
+
+	// Request the username/password authentication.
+	if _, err := conn.Write([]byte{0x05, 0x02}); err != nil {
+		return [2]string{}
+	}
+	var creds [2]string
+	var version [1]byte
+	if _, err := io.ReadFull(conn, version[:]); err != nil {
+		return creds
+	}
+	for i := range creds {
+		var length [1]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return creds
+		}
+		field := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, field); err != nil {
+			return creds
+		}
+		creds[i] = string(field)
+	}
+	return creds
+}
+
+// TestProxyDialerIsolation ensures the credentials sent to the proxy follow the
+// stream isolation mode of the dialer.
+func TestProxyDialerIsolation(t *testing.T) {
+	proxyAddr, creds := socksCredentials(t)
+
+	dial := func(d *ProxyDialer, addr string) [2]string {
+		t.Helper()
+
+		// The fake proxy closes the connection once it read the
+		// credentials, so the dial always fails.
+		d.DialTimeout("tcp", addr, time.Second)
+		select {
+		case c := <-creds:
+			return c
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for the proxy credentials")
+		}
+		return [2]string{}
+	}
+
+	const addr1, addr2 = "10.0.0.1:9246", "10.0.0.2:9246"
+	none, err := NewProxyDialer(proxyAddr, "user", "pass", IsolateNone)
+	if err != nil {
+		t.Fatalf("NewProxyDialer: unexpected error: %v", err)
+	}
+	want := [2]string{"user", "pass"}
+	if got := dial(none, addr1); got != want {
+		t.Fatalf("none: got credentials %v, want %v", got, want)
+	}
+	if got := dial(none, addr2); got != want {
+		t.Fatalf("none: got credentials %v, want %v", got, want)
+	}
+
+	connection, err := NewProxyDialer(proxyAddr, "user", "pass",
+		IsolateConnection)
+	if err != nil {
+		t.Fatalf("NewProxyDialer: unexpected error: %v", err)
+	}
+	first, second := dial(connection, addr1), dial(connection, addr1)
+	if first == second || first == want {
+		t.Fatalf("connection: got credentials %v and %v for the same "+
+			"destination, want different random ones", first, second)
+	}
+
+	destination, err := NewProxyDialer(proxyAddr, "user", "pass",
+		IsolateDestination)
+	if err != nil {
+		t.Fatalf("NewProxyDialer: unexpected error: %v", err)
+	}
+	first, second = dial(destination, addr1), dial(destination, addr1)
+	other := dial(destination, addr2)
+	if first != second || first == other || first == want {
+		t.Fatalf("destination: got credentials %v and %v for the same "+
+			"destination and %v for another one", first, second,
+			other)
+	}
+
+	// Another dialer uses other credentials for the same destination.
+	destination, err = NewProxyDialer(proxyAddr, "", "", IsolateDestination)
+	if err != nil {
+		t.Fatalf("NewProxyDialer: unexpected error: %v", err)
+	}
+	if got := dial(destination, addr1); got == first {
+		t.Fatalf("destination: got the same credentials %v from "+
+			"another dialer", got)
+	}
+}
+
+// TestParseStreamIsolation ensures the stream isolation modes are parsed from
+// their names.
+func TestParseStreamIsolation(t *testing.T) {
+	for _, isolation := range []StreamIsolation{IsolateNone,
+		IsolateConnection, IsolateDestination} {
+
+		got, err := ParseStreamIsolation(isolation.String())
+		if err != nil || got != isolation {
+			t.Fatalf("ParseStreamIsolation(%q): got %v (%v), want %v",
+				isolation, got, err, isolation)
+		}
+	}
+	if _, err := ParseStreamIsolation("circuit"); err == nil {
+		t.Fatal("ParseStreamIsolation: unknown mode was accepted")
+	}
+}
@@ -0,0 +1,253 @@
+package ffldb
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lbryio/lbcd/database"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// backupDirPattern is the pattern of the name of the temporary
+	// directory the metadata is copied to while a backup is written.
+	backupDirPattern = "backup-*"
+
+	// backupBatchSize is the size of the batches of key/value pairs
+	// written to the copy of the metadata while a backup is written.
+	backupBatchSize = 4 * 1024 * 1024
+)
+
+// Backup writes a consistent snapshot of the database to the passed writer as
+// a tar archive, which can be extracted into an empty directory to restore the
+// database.  The archive holds a copy of the metadata as of the snapshot along
+// with the block files up to the write cursor of the snapshot, so the blocks
+// stored and the files pruned while the backup is written don't affect it.
+//
+// The other files stored in the directory of the database by its users, such as
+// the header file of the block index, are archived as well.  They are read as
+// they are after the snapshot is taken, so they may hold changes made after it
+// and their users must discard them when the backup is restored.
+//
+// Writes are only blocked while the snapshot is taken.  The metadata is copied
+// to a temporary leveldb database in the directory of the database before it is
+// archived, which takes about as much disk space as the metadata itself.
+//
+// This function is part of the database.DB interface implementation.
+func (db *db) Backup(w io.Writer) error {
+	// Block the writes while the snapshot is taken and the block files are
+	// opened, so none of the block files of the snapshot can be pruned
+	// before they are opened.  Open files remain readable once deleted.
+	db.writeLock.Lock()
+	tx, err := db.begin(false)
+	if err != nil {
+		db.writeLock.Unlock()
+		return err
+	}
+	defer tx.close()
+
+	lastFile, lastOffset, err := deserializeWriteRow(
+		tx.metaBucket.Get(writeLocKeyName))
+	if err != nil {
+		db.writeLock.Unlock()
+		return err
+	}
+	firstFile, files, err := db.openBackupFiles(lastFile)
+	db.writeLock.Unlock()
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(db.store.basePath, backupDirPattern)
+	if err != nil {
+		str := fmt.Sprintf("failed to create backup directory: %v", err)
+		return makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := copyMetadata(tx.snapshot, tmpDir); err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	err = filepath.Walk(tmpDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(tmpDir, path)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		name := filepath.ToSlash(filepath.Join(metadataDbName, rel))
+		return writeBackupFile(tw, name, file, info.Size())
+	})
+	if err != nil {
+		str := fmt.Sprintf("failed to write backup of metadata: %v", err)
+		return makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+
+	// Only the part of the last block file up to the write cursor belongs
+	// to the snapshot.
+	for i, file := range files {
+		fileNum := firstFile + uint32(i)
+		size := int64(lastOffset)
+		if fileNum != lastFile {
+			info, err := file.Stat()
+			if err != nil {
+				str := fmt.Sprintf("failed to stat block file %d: "+
+					"%v", fileNum, err)
+				return makeDbErr(database.ErrDriverSpecific, str, err)
+			}
+			size = info.Size()
+		}
+		name := fmt.Sprintf(blockFilenameTemplate, fileNum)
+		if err := writeBackupFile(tw, name, file, size); err != nil {
+			str := fmt.Sprintf("failed to write backup of block "+
+				"file %d: %v", fileNum, err)
+			return makeDbErr(database.ErrDriverSpecific, str, err)
+		}
+	}
+
+	if err := db.writeBackupUserFiles(tw); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		str := fmt.Sprintf("failed to write backup: %v", err)
+		return makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+	return nil
+}
+
+// openBackupFiles opens the block files up to the passed last file for reading,
+// starting with the first one which wasn't pruned.  The number of the first
+// block file is returned along with the files.
+func (db *db) openBackupFiles(lastFile uint32) (uint32, []*os.File, error) {
+	wc := db.store.writeCursor
+	wc.RLock()
+	firstFile := wc.firstFileNum
+	wc.RUnlock()
+	var files []*os.File
+	for fileNum := firstFile; fileNum <= lastFile; fileNum++ {
+		file, err := os.Open(blockFilePath(db.store.basePath, fileNum))
+		if os.IsNotExist(err) && fileNum == lastFile {
+			// The write cursor may point to the start of a block
+			// file which hasn't been created yet.
+			break
+		}
+		if err != nil {
+			str := fmt.Sprintf("failed to open block file %d: %v",
+				fileNum, err)
+			return 0, files, makeDbErr(database.ErrDriverSpecific,
+				str, err)
+		}
+		files = append(files, file)
+	}
+	return firstFile, files, nil
+}
+
+// writeBackupUserFiles writes the regular files stored in the directory of the
+// database by its users to the passed tar archive.  The block files and the
+// metadata, which are archived from the snapshot, are skipped.
+func (db *db) writeBackupUserFiles(tw *tar.Writer) error {
+	entries, err := os.ReadDir(db.store.basePath)
+	if err != nil {
+		str := fmt.Sprintf("failed to read database directory: %v", err)
+		return makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || filepath.Ext(name) == ".fdb" {
+			continue
+		}
+		err := func() error {
+			file, err := os.Open(filepath.Join(db.store.basePath, name))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			info, err := file.Stat()
+			if err != nil {
+				return err
+			}
+			return writeBackupFile(tw, name, file, info.Size())
+		}()
+		if err != nil {
+			str := fmt.Sprintf("failed to write backup of file %s: "+
+				"%v", name, err)
+			return makeDbErr(database.ErrDriverSpecific, str, err)
+		}
+	}
+	return nil
+}
+
+// copyMetadata copies all the key/value pairs of the passed snapshot to a new
+// leveldb database at the passed path.
+func copyMetadata(snapshot *dbCacheSnapshot, path string) error {
+	ldb, err := leveldb.OpenFile(path, &opt.Options{
+		Strict:      opt.DefaultStrict,
+		Compression: opt.NoCompression,
+	})
+	if err != nil {
+		return convertErr(err.Error(), err)
+	}
+
+	iter := snapshot.NewIterator(&util.Range{})
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	for ok := iter.First(); ok; ok = iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		if len(batch.Dump()) < backupBatchSize {
+			continue
+		}
+		if err := ldb.Write(batch, nil); err != nil {
+			_ = ldb.Close()
+			return convertErr(err.Error(), err)
+		}
+		batch.Reset()
+	}
+	if err := iter.Error(); err != nil {
+		_ = ldb.Close()
+		return convertErr(err.Error(), err)
+	}
+	if err := ldb.Write(batch, nil); err != nil {
+		_ = ldb.Close()
+		return convertErr(err.Error(), err)
+	}
+	if err := ldb.Close(); err != nil {
+		return convertErr(err.Error(), err)
+	}
+	return nil
+}
+
+// writeBackupFile writes the first size bytes read from the passed reader to
+// the passed tar archive as a file with the passed name.
+func writeBackupFile(tw *tar.Writer, name string, r io.Reader, size int64) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, r, size)
+	return err
+}
@@ -5,6 +5,7 @@
 package ffldb
 
 import (
+	"crypto/sha256"
 	"os"
 	"path/filepath"
 	"testing"
@@ -95,3 +96,50 @@ func BenchmarkBlock(b *testing.B) {
 	// Don't benchmark teardown.
 	b.StopTimer()
 }
+
+// benchmarkBucketScan benchmarks how long it takes to scan a bucket holding
+// many key/value pairs stored in leveldb with the cursors returned by the
+// passed function.
+func benchmarkBucketScan(b *testing.B, newCursor func(database.Bucket) database.Cursor) {
+	dbPath := filepath.Join(os.TempDir(), "ffldb-benchscan")
+	defer os.RemoveAll(dbPath)
+	db := populatePrefetchTestDB(b, dbPath, 200000)
+	defer db.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	err := db.View(func(tx database.Tx) error {
+		bucket := tx.Metadata().Bucket([]byte("prefetch"))
+		for i := 0; i < b.N; i++ {
+			c := newCursor(bucket)
+			for ok := c.First(); ok; ok = c.Next() {
+				// Simulate the processing of the values
+				// made by indexers.
+				_ = sha256.Sum256(c.Value())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// Don't benchmark teardown.
+	b.StopTimer()
+}
+
+// BenchmarkCursorScan benchmarks how long it takes to scan a bucket with a
+// regular cursor.
+func BenchmarkCursorScan(b *testing.B) {
+	benchmarkBucketScan(b, func(bucket database.Bucket) database.Cursor {
+		return bucket.Cursor()
+	})
+}
+
+// BenchmarkPrefetchCursorScan benchmarks how long it takes to scan a bucket
+// with a cursor reading ahead the key/value pairs.
+func BenchmarkPrefetchCursorScan(b *testing.B) {
+	benchmarkBucketScan(b, func(bucket database.Bucket) database.Cursor {
+		return bucket.PrefetchCursor(256)
+	})
+}
@@ -8,6 +8,8 @@
 package ffldb
 
 import (
+	"bufio"
+	"bytes"
 	"container/list"
 	"encoding/binary"
 	"fmt"
@@ -17,6 +19,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
 	"github.com/lbryio/lbcd/database"
 	"github.com/lbryio/lbcd/wire"
@@ -56,13 +59,49 @@ const (
 	//  [4:8]  File offset (4 bytes)
 	//  [8:12] Block length (4 bytes)
 	blockLocSize = 12
+
+	// compressedBlockLocSize is the number of bytes the serialized block
+	// location of a compressed block that is stored in the block index.
+	//
+	// The serialized compressed block location format is:
+	//
+	//  [0:4]   Block file (4 bytes)
+	//  [4:8]   File offset (4 bytes)
+	//  [8:12]  Block length (4 bytes)
+	//  [12:16] Uncompressed block length (4 bytes)
+	compressedBlockLocSize = 16
+
+	// compressedBlockFlag is set in the block length stored in the flat
+	// files for blocks which are compressed with zstd.
+	compressedBlockFlag uint32 = 1 << 31
 )
 
 var (
 	// castagnoli houses the Catagnoli polynomial used for CRC-32 checksums.
 	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+	// zstdEncoder and zstdDecoder compress and decompress the blocks.  They
+	// are created on first use and are safe for concurrent use.
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
 )
 
+// initZstd creates the zstd encoder and decoder used for the blocks.
+func initZstd() {
+	var err error
+	zstdEncoder, err = zstd.NewWriter(nil,
+		zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		panic(fmt.Sprintf("failed to create zstd encoder: %v", err))
+	}
+	zstdDecoder, err = zstd.NewReader(nil,
+		zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		panic(fmt.Sprintf("failed to create zstd decoder: %v", err))
+	}
+}
+
 // filer is an interface which acts very similar to a *os.File and is typically
 // implemented by it.  It exists so the test code can provide mock files for
 // properly testing corruption and file system issues.
@@ -99,6 +138,11 @@ type writeCursor struct {
 	// curOffset is the offset in the current write block file where the
 	// next new block will be written.
 	curOffset uint32
+
+	// firstFileNum is the oldest block file which hasn't been pruned.
+	// Along with curFileNum, it tracks the range of the block files on
+	// disk so pruning doesn't have to search the directory for them.
+	firstFileNum uint32
 }
 
 // blockStore houses information used to handle reading and writing blocks (and
@@ -116,6 +160,10 @@ type blockStore struct {
 	// override the value.
 	maxBlockFileSize uint32
 
+	// compress specifies whether new blocks are compressed with zstd
+	// before being written to the flat files.
+	compress bool
+
 	// The following fields are related to the flat files which hold the
 	// actual blocks.   The number of open files is limited by maxOpenFiles.
 	//
@@ -176,11 +224,23 @@ type blockStore struct {
 	deleteFileFunc    func(fileNum uint32) error
 }
 
-// blockLocation identifies a particular block file and location.
+// blockLocation identifies a particular block file and location.  The rawLen
+// field is the length of the uncompressed block for compressed blocks and zero
+// otherwise.
 type blockLocation struct {
 	blockFileNum uint32
 	fileOffset   uint32
 	blockLen     uint32
+	rawLen       uint32
+}
+
+// regionLimit returns the offset block regions must not exceed for the block at
+// the location.
+func (loc *blockLocation) regionLimit() uint32 {
+	if loc.rawLen != 0 {
+		return loc.rawLen
+	}
+	return loc.blockLen
 }
 
 // deserializeBlockLoc deserializes the passed serialized block location
@@ -192,28 +252,40 @@ type blockLocation struct {
 func deserializeBlockLoc(serializedLoc []byte) blockLocation {
 	// The serialized block location format is:
 	//
-	//  [0:4]  Block file (4 bytes)
-	//  [4:8]  File offset (4 bytes)
-	//  [8:12] Block length (4 bytes)
-	return blockLocation{
+	//  [0:4]   Block file (4 bytes)
+	//  [4:8]   File offset (4 bytes)
+	//  [8:12]  Block length (4 bytes)
+	//  [12:16] Uncompressed block length (4 bytes, compressed blocks only)
+	loc := blockLocation{
 		blockFileNum: byteOrder.Uint32(serializedLoc[0:4]),
 		fileOffset:   byteOrder.Uint32(serializedLoc[4:8]),
 		blockLen:     byteOrder.Uint32(serializedLoc[8:12]),
 	}
+	if len(serializedLoc) >= compressedBlockLocSize {
+		loc.rawLen = byteOrder.Uint32(serializedLoc[12:16])
+	}
+	return loc
 }
 
 // serializeBlockLoc returns the serialization of the passed block location.
 // This is data to be stored into the block index metadata for each block.
+// The uncompressed length is only serialized for compressed blocks so the
+// block index of uncompressed blocks remains readable by older versions.
 func serializeBlockLoc(loc blockLocation) []byte {
 	// The serialized block location format is:
 	//
-	//  [0:4]  Block file (4 bytes)
-	//  [4:8]  File offset (4 bytes)
-	//  [8:12] Block length (4 bytes)
-	var serializedData [12]byte
+	//  [0:4]   Block file (4 bytes)
+	//  [4:8]   File offset (4 bytes)
+	//  [8:12]  Block length (4 bytes)
+	//  [12:16] Uncompressed block length (4 bytes, compressed blocks only)
+	var serializedData [compressedBlockLocSize]byte
 	byteOrder.PutUint32(serializedData[0:4], loc.blockFileNum)
 	byteOrder.PutUint32(serializedData[4:8], loc.fileOffset)
 	byteOrder.PutUint32(serializedData[8:12], loc.blockLen)
+	if loc.rawLen == 0 {
+		return serializedData[:blockLocSize]
+	}
+	byteOrder.PutUint32(serializedData[12:16], loc.rawLen)
 	return serializedData[:]
 }
 
@@ -398,12 +470,30 @@ func (s *blockStore) writeData(data []byte, fieldName string) error {
 // The write cursor will also be advanced the number of bytes actually written
 // in the event of failure.
 //
+// When compression is enabled, the block is stored compressed with zstd unless
+// that doesn't make it smaller, and the compressed block flag is set in the
+// block length.
+//
 // Format: <network><block length><serialized block><checksum>
 func (s *blockStore) writeBlock(rawBlock []byte) (blockLocation, error) {
+	// Compress the block if enabled.  The uncompressed length is returned
+	// in the block location so regions can be bounds checked without
+	// having to read the block.
+	blockData := rawBlock
+	var rawLen uint32
+	if s.compress {
+		zstdOnce.Do(initZstd)
+		compressed := zstdEncoder.EncodeAll(rawBlock, nil)
+		if len(compressed) < len(rawBlock) {
+			blockData = compressed
+			rawLen = uint32(len(rawBlock))
+		}
+	}
+
 	// Compute how many bytes will be written.
 	// 4 bytes each for block network + 4 bytes for block length +
 	// length of raw block + 4 bytes for checksum.
-	blockLen := uint32(len(rawBlock))
+	blockLen := uint32(len(blockData))
 	fullLen := blockLen + 12
 
 	// Move to the next block file if adding the new block would exceed the
@@ -467,17 +557,21 @@ func (s *blockStore) writeBlock(rawBlock []byte) (blockLocation, error) {
 	_, _ = hasher.Write(scratch[:])
 
 	// Block length.
-	byteOrder.PutUint32(scratch[:], blockLen)
+	lenField := blockLen
+	if rawLen != 0 {
+		lenField |= compressedBlockFlag
+	}
+	byteOrder.PutUint32(scratch[:], lenField)
 	if err := s.writeData(scratch[:], "block length"); err != nil {
 		return blockLocation{}, err
 	}
 	_, _ = hasher.Write(scratch[:])
 
 	// Serialized block.
-	if err := s.writeData(rawBlock, "block"); err != nil {
+	if err := s.writeData(blockData, "block"); err != nil {
 		return blockLocation{}, err
 	}
-	_, _ = hasher.Write(rawBlock)
+	_, _ = hasher.Write(blockData)
 
 	// Castagnoli CRC-32 as a checksum of all the previous.
 	if err := s.writeData(hasher.Sum(nil), "checksum"); err != nil {
@@ -488,6 +582,7 @@ func (s *blockStore) writeBlock(rawBlock []byte) (blockLocation, error) {
 		blockFileNum: wc.curFileNum,
 		fileOffset:   origOffset,
 		blockLen:     fullLen,
+		rawLen:       rawLen,
 	}
 	return loc, nil
 }
@@ -500,9 +595,11 @@ func (s *blockStore) writeBlock(rawBlock []byte) (blockLocation, error) {
 // and closing files as necessary to stay within the maximum allowed open files
 // limit.
 //
+// Compressed blocks are transparently decompressed.
+//
 // Returns ErrDriverSpecific if the data fails to read for any reason and
 // ErrCorruption if the checksum of the read data doesn't match the checksum
-// read from the file.
+// read from the file or a compressed block fails to decompress.
 //
 // Format: <network><block length><serialized block><checksum>
 func (s *blockStore) readBlock(hash *chainhash.Hash, loc blockLocation) ([]byte, error) {
@@ -550,7 +647,32 @@ func (s *blockStore) readBlock(hash *chainhash.Hash, loc blockLocation) ([]byte,
 
 	// The raw block excludes the network, length of the block, and
 	// checksum.
-	return serializedData[8 : n-4], nil
+	blockData := serializedData[8 : n-4]
+	if loc.rawLen == 0 {
+		return blockData, nil
+	}
+	return decompressBlock(hash, blockData, loc.rawLen)
+}
+
+// decompressBlock decompresses the passed zstd compressed block and ensures it
+// has the expected uncompressed length.
+//
+// Returns ErrCorruption if the block fails to decompress.
+func decompressBlock(hash *chainhash.Hash, compressed []byte, rawLen uint32) ([]byte, error) {
+	zstdOnce.Do(initZstd)
+	rawBlock, err := zstdDecoder.DecodeAll(compressed, make([]byte, 0, rawLen))
+	if err != nil {
+		str := fmt.Sprintf("failed to decompress block %s: %v", hash,
+			err)
+		return nil, makeDbErr(database.ErrCorruption, str, err)
+	}
+	if uint32(len(rawBlock)) != rawLen {
+		str := fmt.Sprintf("decompressed block %s has length %d, "+
+			"want %d", hash, len(rawBlock), rawLen)
+		return nil, makeDbErr(database.ErrCorruption, str, nil)
+	}
+
+	return rawBlock, nil
 }
 
 // readBlockRegion reads the specified amount of data at the provided offset for
@@ -560,8 +682,22 @@ func (s *blockStore) readBlock(hash *chainhash.Hash, loc blockLocation) ([]byte,
 // closing files as necessary to stay within the maximum allowed open files
 // limit.
 //
+// Since compressed blocks can't be partially read, the whole block is read and
+// decompressed for them.  The hash is only used for error messages.
+//
 // Returns ErrDriverSpecific if the data fails to read for any reason.
-func (s *blockStore) readBlockRegion(loc blockLocation, offset, numBytes uint32) ([]byte, error) {
+func (s *blockStore) readBlockRegion(hash *chainhash.Hash, loc blockLocation,
+	offset, numBytes uint32) ([]byte, error) {
+
+	if loc.rawLen != 0 {
+		blockBytes, err := s.readBlock(hash, loc)
+		if err != nil {
+			return nil, err
+		}
+		endOffset := offset + numBytes
+		return blockBytes[offset:endOffset:endOffset], nil
+	}
+
 	// Get the referenced block file handle opening the file as needed.  The
 	// function also handles closing files as needed to avoid going over the
 	// max allowed open files.
@@ -713,37 +849,157 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 }
 
 // scanBlockFiles searches the database directory for all flat block files to
-// find the end of the most recent file.  This position is considered the
-// current write cursor which is also stored in the metadata.  Thus, it is used
-// to detect unexpected shutdowns in the middle of writes so the block files
-// can be reconciled.
-func scanBlockFiles(dbPath string) (int, uint32) {
-	lastFile := -1
+// find the first file and the end of the most recent file.  The end position
+// is considered the current write cursor which is also stored in the metadata.
+// Thus, it is used to detect unexpected shutdowns in the middle of writes so
+// the block files can be reconciled.  The first file is only ever non-zero
+// when old block files have been pruned.
+//
+// Both file numbers are -1 when there are no block files.
+func scanBlockFiles(dbPath string) (int, int, uint32) {
+	firstFile, lastFile := -1, -1
 	fileLen := uint32(0)
-	for i := 0; ; i++ {
-		filePath := blockFilePath(dbPath, uint32(i))
-		st, err := os.Stat(filePath)
+	files, err := filepath.Glob(filepath.Join(dbPath, "*.fdb"))
+	if err != nil {
+		return firstFile, lastFile, fileLen
+	}
+	for _, filePath := range files {
+		var fileNum uint32
+		_, err := fmt.Sscanf(filepath.Base(filePath), blockFilenameTemplate,
+			&fileNum)
 		if err != nil {
-			break
+			continue
 		}
-		lastFile = i
+		if firstFile == -1 || int(fileNum) < firstFile {
+			firstFile = int(fileNum)
+		}
+		if int(fileNum) > lastFile {
+			st, err := os.Stat(filePath)
+			if err != nil {
+				continue
+			}
+			lastFile = int(fileNum)
+			fileLen = uint32(st.Size())
+		}
+	}
 
-		fileLen = uint32(st.Size())
+	log.Tracef("Scan found block files #%d through #%d with length %d",
+		firstFile, lastFile, fileLen)
+	return firstFile, lastFile, fileLen
+}
+
+// blockFileHashes returns the hashes of the blocks stored in the passed flat
+// file number by reading the header of each block record in turn.  It is used
+// to find the blocks of the files being pruned without searching the whole
+// block index.  A file which doesn't exist anymore, such as a pruned file which
+// failed to be deleted along with a later one, holds no blocks.
+//
+// Format: <network><block length><serialized block><checksum>
+func (s *blockStore) blockFileHashes(fileNum uint32) ([]chainhash.Hash, error) {
+	file, err := os.Open(blockFilePath(s.basePath, fileNum))
+	if os.IsNotExist(err) {
+		return nil, nil
 	}
+	if err != nil {
+		str := fmt.Sprintf("failed to open block file %d: %v", fileNum,
+			err)
+		return nil, makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+	defer file.Close()
+
+	var hashes []chainhash.Hash
+	r := bufio.NewReader(file)
+	for offset := uint32(0); ; {
+		var scratch [8]byte
+		_, err := io.ReadFull(r, scratch[:])
+		if err == io.EOF {
+			return hashes, nil
+		}
+		if err != nil {
+			str := fmt.Sprintf("failed to read block record from "+
+				"file %d, offset %d: %v", fileNum, offset, err)
+			return nil, makeDbErr(database.ErrDriverSpecific, str,
+				err)
+		}
+
+		lenField := byteOrder.Uint32(scratch[4:8])
+		header, err := readRecordHeader(r, lenField)
+		if err != nil {
+			str := fmt.Sprintf("failed to read block from file %d, "+
+				"offset %d: %v", fileNum, offset, err)
+			return nil, makeDbErr(database.ErrCorruption, str, err)
+		}
+
+		hashes = append(hashes, header.BlockHash())
+		offset += lenField&^compressedBlockFlag + 12
+	}
+}
+
+// readRecordHeader reads the header of the block of the block record the passed
+// reader is positioned at, right after the block length, and skips the rest of
+// the record.  Only the header of uncompressed blocks needs to be read, while
+// compressed blocks have to be decompressed entirely.
+func readRecordHeader(r *bufio.Reader, lenField uint32) (*wire.BlockHeader, error) {
+	blockLen := lenField &^ compressedBlockFlag
+	var header wire.BlockHeader
+	if lenField&compressedBlockFlag == 0 {
+		err := header.Deserialize(io.LimitReader(r, int64(blockLen)))
+		if err != nil {
+			return nil, err
+		}
+		_, err = r.Discard(int(blockLen) + 4 - wire.MaxBlockHeaderPayload)
+		return &header, err
+	}
+
+	compressed := make([]byte, blockLen+4)
+	if _, err := io.ReadFull(r, compressed); err != nil {
+		return nil, err
+	}
+	zstdOnce.Do(initZstd)
+	rawBlock, err := zstdDecoder.DecodeAll(compressed[:blockLen], nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := header.Deserialize(bytes.NewReader(rawBlock)); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// closeFile closes the passed flat file number if it is open for reads and
+// removes it from the least recently used list.  It is used to release the file
+// handle before a pruned block file is deleted.
+func (s *blockStore) closeFile(fileNum uint32) {
+	s.obfMutex.Lock()
+	defer s.obfMutex.Unlock()
+
+	blockFile, ok := s.openBlockFiles[fileNum]
+	if !ok {
+		return
+	}
+
+	s.lruMutex.Lock()
+	if elem, ok := s.fileNumToLRUElem[fileNum]; ok {
+		s.openBlocksLRU.Remove(elem)
+		delete(s.fileNumToLRUElem, fileNum)
+	}
+	s.lruMutex.Unlock()
 
-	log.Tracef("Scan found latest block file #%d with length %d", lastFile,
-		fileLen)
-	return lastFile, fileLen
+	blockFile.Lock()
+	_ = blockFile.file.Close()
+	blockFile.Unlock()
+	delete(s.openBlockFiles, fileNum)
 }
 
 // newBlockStore returns a new block store with the current block file number
 // and offset set and all fields initialized.
-func newBlockStore(basePath string, network wire.BitcoinNet) *blockStore {
+func newBlockStore(basePath string, network wire.BitcoinNet, compress bool) *blockStore {
 	// Look for the end of the latest block to file to determine what the
 	// write cursor position is from the viewpoing of the block files on
 	// disk.
-	fileNum, fileOff := scanBlockFiles(basePath)
+	firstFile, fileNum, fileOff := scanBlockFiles(basePath)
 	if fileNum == -1 {
+		firstFile = 0
 		fileNum = 0
 		fileOff = 0
 	}
@@ -752,14 +1008,16 @@ func newBlockStore(basePath string, network wire.BitcoinNet) *blockStore {
 		network:          network,
 		basePath:         basePath,
 		maxBlockFileSize: maxBlockFileSize,
+		compress:         compress,
 		openBlockFiles:   make(map[uint32]*lockableFile),
 		openBlocksLRU:    list.New(),
 		fileNumToLRUElem: make(map[uint32]*list.Element),
 
 		writeCursor: &writeCursor{
-			curFile:    &lockableFile{},
-			curFileNum: uint32(fileNum),
-			curOffset:  fileOff,
+			curFile:      &lockableFile{},
+			curFileNum:   uint32(fileNum),
+			curOffset:    fileOff,
+			firstFileNum: uint32(firstFile),
 		},
 	}
 	store.openFileFunc = store.openFile
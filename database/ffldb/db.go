@@ -74,6 +74,11 @@ var (
 	// writeLocKeyName is the key used to store the current write file
 	// location.
 	writeLocKeyName = []byte("ffldb-writeloc")
+
+	// formatVersionKeyName is the key used to store the version of the
+	// block storage format.  It doesn't exist for databases created before
+	// block compression was introduced, which are version 1.
+	formatVersionKeyName = []byte("ffldb-version")
 )
 
 // Common error strings.
@@ -87,6 +92,16 @@ const (
 	errTxClosedStr = "database tx is closed"
 )
 
+const (
+	// currentFormatVersion is the current version of the block storage
+	// format.
+	//
+	// Version 2 introduced compressed blocks.  It is only written to the
+	// metadata once block compression is enabled so databases which never
+	// stored compressed blocks remain usable by older versions.
+	currentFormatVersion = 2
+)
+
 // bulkFetchData is allows a block location to be specified along with the
 // index it was requested from.  This in turn allows the bulk data loading
 // functions to sort the data accesses based on the location to improve
@@ -167,6 +182,27 @@ func copySlice(slice []byte) []byte {
 	return ret
 }
 
+// repositionIterator positions the passed iterator at the key following the
+// passed key in the direction given by the forwards flag.  The iterators which
+// merge two iterators use it when they change direction, since the iterator
+// which isn't the current one is then on the wrong side of the current key.
+func repositionIterator(iter iterator.Iterator, key []byte, forwards bool) {
+	switch {
+	case !iter.Seek(key):
+		if !forwards {
+			iter.Last()
+		}
+
+	case forwards:
+		if bytes.Equal(iter.Key(), key) {
+			iter.Next()
+		}
+
+	default:
+		iter.Prev()
+	}
+}
+
 // cursor is an internal type used to represent a cursor over key/value pairs
 // and nested buckets of a bucket and implements the database.Cursor interface.
 type cursor struct {
@@ -174,6 +210,9 @@ type cursor struct {
 	dbIter      iterator.Iterator
 	pendingIter iterator.Iterator
 	currentIter iterator.Iterator
+
+	// forwards is the direction the cursor was last moved in.
+	forwards bool
 }
 
 // Enforce cursor implements the database.Cursor interface.
@@ -231,6 +270,14 @@ func (c *cursor) skipPendingUpdates(forwards bool) {
 	for c.dbIter.Valid() {
 		var skip bool
 		key := c.dbIter.Key()
+
+		// Move past the whole range of keys being deleted by the
+		// transaction the key is in at once rather than key by key.
+		if i := c.bucket.tx.pendingRanges.find(key); i >= 0 {
+			c.skipRange(c.bucket.tx.pendingRanges[i], forwards)
+			continue
+		}
+
 		if c.bucket.tx.pendingRemove.Has(key) {
 			skip = true
 		} else if c.bucket.tx.pendingKeys.Has(key) {
@@ -248,6 +295,44 @@ func (c *cursor) skipPendingUpdates(forwards bool) {
 	}
 }
 
+// skipRange moves the database iterator out of the passed range of keys being
+// deleted by the transaction with a single seek in the direction given by the
+// forwards flag.
+func (c *cursor) skipRange(r util.Range, forwards bool) {
+	switch {
+	case forwards && r.Limit == nil:
+		// There are no keys after the range, so exhaust the iterator.
+		c.dbIter.Last()
+		c.dbIter.Next()
+
+	case forwards:
+		c.dbIter.Seek(r.Limit)
+
+	case c.dbIter.Seek(r.Start):
+		c.dbIter.Prev()
+
+	default:
+		c.dbIter.Last()
+	}
+}
+
+// setDirection records the direction the cursor is moving in given by the
+// forwards flag.  When it changes, the iterator which isn't the current one is
+// positioned at the key following the current one in the new direction since
+// it is on the other side of the current key.
+func (c *cursor) setDirection(forwards bool) {
+	if c.forwards == forwards {
+		return
+	}
+	c.forwards = forwards
+
+	other := c.pendingIter
+	if c.currentIter == c.pendingIter {
+		other = c.dbIter
+	}
+	repositionIterator(other, copySlice(c.currentIter.Key()), forwards)
+}
+
 // chooseIterator first skips any entries in the database iterator that are
 // being updated by the transaction and sets the current iterator to the
 // appropriate iterator depending on their validity and the order they compare
@@ -304,6 +389,7 @@ func (c *cursor) First() bool {
 	// choose the iterator that is both valid and has the smaller key.
 	c.dbIter.First()
 	c.pendingIter.First()
+	c.forwards = true
 	return c.chooseIterator(true)
 }
 
@@ -321,6 +407,7 @@ func (c *cursor) Last() bool {
 	// choose the iterator that is both valid and has the larger key.
 	c.dbIter.Last()
 	c.pendingIter.Last()
+	c.forwards = false
 	return c.chooseIterator(false)
 }
 
@@ -341,6 +428,7 @@ func (c *cursor) Next() bool {
 
 	// Move the current iterator to the next entry and choose the iterator
 	// that is both valid and has the smaller key.
+	c.setDirection(true)
 	c.currentIter.Next()
 	return c.chooseIterator(true)
 }
@@ -362,6 +450,7 @@ func (c *cursor) Prev() bool {
 
 	// Move the current iterator to the previous entry and choose the
 	// iterator that is both valid and has the larger key.
+	c.setDirection(false)
 	c.currentIter.Prev()
 	return c.chooseIterator(false)
 }
@@ -381,6 +470,7 @@ func (c *cursor) Seek(seek []byte) bool {
 	seekKey := bucketizedKey(c.bucket.id, seek)
 	c.dbIter.Seek(seekKey)
 	c.pendingIter.Seek(seekKey)
+	c.forwards = true
 	return c.chooseIterator(true)
 }
 
@@ -486,16 +576,25 @@ func cursorFinalizer(c *cursor) {
 }
 
 // newCursor returns a new cursor for the given bucket, bucket ID, and cursor
-// type.
+// type.  The key/value pairs of the database are read ahead in batches of about
+// the passed number of leveldb blocks when it is positive.
 //
 // NOTE: The caller is responsible for calling the cursorFinalizer function on
 // the returned cursor.
-func newCursor(b *bucket, bucketID []byte, cursorTyp cursorType) *cursor {
+func newCursor(b *bucket, bucketID []byte, cursorTyp cursorType, prefetchBlocks int) *cursor {
+	newDbIter := func(slice *util.Range) iterator.Iterator {
+		if prefetchBlocks > 0 {
+			return b.tx.snapshot.NewPrefetchIterator(slice,
+				prefetchBlocks)
+		}
+		return b.tx.snapshot.NewIterator(slice)
+	}
+
 	var dbIter, pendingIter iterator.Iterator
 	switch cursorTyp {
 	case ctKeys:
 		keyRange := util.BytesPrefix(bucketID)
-		dbIter = b.tx.snapshot.NewIterator(keyRange)
+		dbIter = newDbIter(keyRange)
 		pendingKeyIter := newLdbTreapIter(b.tx, keyRange)
 		pendingIter = pendingKeyIter
 
@@ -511,7 +610,7 @@ func newCursor(b *bucket, bucketID []byte, cursorTyp cursorType) *cursor {
 		copy(prefix[len(bucketIndexPrefix):], bucketID)
 		bucketRange := util.BytesPrefix(prefix)
 
-		dbIter = b.tx.snapshot.NewIterator(bucketRange)
+		dbIter = newDbIter(bucketRange)
 		pendingBucketIter := newLdbTreapIter(b.tx, bucketRange)
 		pendingIter = pendingBucketIter
 
@@ -529,8 +628,8 @@ func newCursor(b *bucket, bucketID []byte, cursorTyp cursorType) *cursor {
 		// Since both keys and buckets are needed from the database,
 		// create an individual iterator for each prefix and then create
 		// a merged iterator from them.
-		dbKeyIter := b.tx.snapshot.NewIterator(keyRange)
-		dbBucketIter := b.tx.snapshot.NewIterator(bucketRange)
+		dbKeyIter := newDbIter(keyRange)
+		dbBucketIter := newDbIter(bucketRange)
 		iters := []iterator.Iterator{dbKeyIter, dbBucketIter}
 		dbIter = iterator.NewMergedIterator(iters,
 			comparer.DefaultComparer, true)
@@ -729,15 +828,12 @@ func (b *bucket) DeleteBucket(key []byte) error {
 		childID = childIDs[len(childIDs)-1]
 		childIDs = childIDs[:len(childIDs)-1]
 
-		// Delete all keys in the nested bucket.
-		keyCursor := newCursor(b, childID, ctKeys)
-		for ok := keyCursor.First(); ok; ok = keyCursor.Next() {
-			b.tx.deleteKey(keyCursor.rawKey(), false)
-		}
-		cursorFinalizer(keyCursor)
+		// Delete all keys in the nested bucket at once.
+		keyRange := util.BytesPrefix(childID)
+		b.tx.deleteRange(keyRange.Start, keyRange.Limit)
 
 		// Iterate through all nested buckets.
-		bucketCursor := newCursor(b, childID, ctBuckets)
+		bucketCursor := newCursor(b, childID, ctBuckets, 0)
 		for ok := bucketCursor.First(); ok; ok = bucketCursor.Next() {
 			// Push the id of the nested bucket onto the stack for
 			// the next iteration.
@@ -773,7 +869,27 @@ func (b *bucket) Cursor() database.Cursor {
 
 	// Create the cursor and setup a runtime finalizer to ensure the
 	// iterators are released when the cursor is garbage collected.
-	c := newCursor(b, b.id[:], ctFull)
+	c := newCursor(b, b.id[:], ctFull, 0)
+	runtime.SetFinalizer(c, cursorFinalizer)
+	return c
+}
+
+// PrefetchCursor returns a new cursor like Cursor which reads ahead the
+// key/value pairs stored in about the passed number of blocks of the underlying
+// database in the background while it moves forward.  This speeds up long scans
+// of buckets at the cost of the memory holding the key/value pairs read ahead.
+// The cursor behaves like the ones returned by Cursor when moving backward.
+//
+// This function is part of the database.Bucket interface implementation.
+func (b *bucket) PrefetchCursor(blocks int) database.Cursor {
+	// Ensure transaction state is valid.
+	if err := b.tx.checkClosed(); err != nil {
+		return &cursor{bucket: b}
+	}
+
+	// Create the cursor and setup a runtime finalizer to ensure the
+	// iterators are released when the cursor is garbage collected.
+	c := newCursor(b, b.id[:], ctFull, blocks)
 	runtime.SetFinalizer(c, cursorFinalizer)
 	return c
 }
@@ -802,7 +918,7 @@ func (b *bucket) ForEach(fn func(k, v []byte) error) error {
 
 	// Invoke the callback for each cursor item.  Return the error returned
 	// from the callback when it is non-nil.
-	c := newCursor(b, b.id[:], ctKeys)
+	c := newCursor(b, b.id[:], ctKeys, 0)
 	defer cursorFinalizer(c)
 	for ok := c.First(); ok; ok = c.Next() {
 		err := fn(c.Key(), c.Value())
@@ -838,7 +954,7 @@ func (b *bucket) ForEachBucket(fn func(k []byte) error) error {
 
 	// Invoke the callback for each cursor item.  Return the error returned
 	// from the callback when it is non-nil.
-	c := newCursor(b, b.id[:], ctBuckets)
+	c := newCursor(b, b.id[:], ctBuckets, 0)
 	defer cursorFinalizer(c)
 	for ok := c.First(); ok; ok = c.Next() {
 		err := fn(c.Key())
@@ -942,6 +1058,50 @@ func (b *bucket) Delete(key []byte) error {
 	return nil
 }
 
+// DeleteRange removes the key/value pairs of the bucket whose keys are greater
+// than or equal to the start key and less than the end key.  A nil start key
+// starts the range at the first key of the bucket and a nil end key ends it
+// after the last one.  Nested buckets are not affected.
+//
+// The keys in the range aren't read until the transaction is committed, at
+// which point they are deleted from the underlying database directly.  This
+// makes deleting large sets of keys, such as dropping indexes, much cheaper
+// than deleting them one by one.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrTxNotWritable if attempted against a read-only transaction
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Bucket interface implementation.
+func (b *bucket) DeleteRange(start, end []byte) error {
+	// Ensure transaction state is valid.
+	if err := b.tx.checkClosed(); err != nil {
+		return err
+	}
+
+	// Ensure the transaction is writable.
+	if !b.tx.writable {
+		str := "deleting a range requires a writable database transaction"
+		return makeDbErr(database.ErrTxNotWritable, str, nil)
+	}
+
+	// The range ends with the last key of the bucket when no end key is
+	// provided.
+	rangeStart := bucketizedKey(b.id, start)
+	rangeLimit := util.BytesPrefix(b.id[:]).Limit
+	if end != nil {
+		rangeLimit = bucketizedKey(b.id, end)
+	}
+
+	// Nothing to do if the range is empty.
+	if rangeLimit != nil && bytes.Compare(rangeStart, rangeLimit) >= 0 {
+		return nil
+	}
+
+	b.tx.deleteRange(rangeStart, rangeLimit)
+	return nil
+}
+
 // pendingBlock houses a block that will be written to disk when the database
 // transaction is committed.
 type pendingBlock struct {
@@ -966,10 +1126,20 @@ type transaction struct {
 	pendingBlocks    map[chainhash.Hash]int
 	pendingBlockData []pendingBlock
 
+	// Block files that need to be deleted on commit as a result of
+	// pruning.
+	pendingPrunedFiles []uint32
+
 	// Keys that need to be stored or deleted on commit.
 	pendingKeys   *treap.Mutable
 	pendingRemove *treap.Mutable
 
+	// Ranges of keys that need to be deleted on commit.  The keys stored
+	// again after their range was deleted are in the pending keys, and
+	// the pending keys to add or delete never include the other keys in
+	// the ranges.
+	pendingRanges keyRanges
+
 	// Active iterators that need to be notified when the pending keys have
 	// been updated so the cursors can properly handle updates to the
 	// transaction state.
@@ -1037,6 +1207,9 @@ func (tx *transaction) hasKey(key []byte) bool {
 		if tx.pendingKeys.Has(key) {
 			return true
 		}
+		if tx.pendingRanges.contains(key) {
+			return false
+		}
 	}
 
 	// Consult the database cache and underlying database.
@@ -1073,6 +1246,9 @@ func (tx *transaction) fetchKey(key []byte) []byte {
 		if value := tx.pendingKeys.Get(key); value != nil {
 			return value
 		}
+		if tx.pendingRanges.contains(key) {
+			return nil
+		}
 	}
 
 	// Consult the database cache and underlying database.
@@ -1099,6 +1275,35 @@ func (tx *transaction) deleteKey(key []byte, notifyIterators bool) {
 	}
 }
 
+// deleteRange adds the range of keys from the passed start key up to the passed
+// limit key to the ranges of keys to be deleted from the database when the
+// transaction is committed.  A nil limit key ends the range at the end of the
+// key space.  Unlike deleting its keys one by one, this doesn't require reading
+// them, so the cost doesn't depend on the number of keys in the range until the
+// transaction is committed.
+//
+// NOTE: This function must only be called on a writable transaction.  Since it
+// is an internal helper function, it does not check.
+func (tx *transaction) deleteRange(start, limit []byte) {
+	// Remove the keys in the range from the lists of pending keys to be
+	// written or deleted on transaction commit since the range covers
+	// them.
+	for _, pending := range []*treap.Mutable{tx.pendingKeys, tx.pendingRemove} {
+		var keys [][]byte
+		iter := pending.Iterator(start, limit)
+		for ok := iter.First(); ok; ok = iter.Next() {
+			keys = append(keys, iter.Key())
+		}
+		for _, key := range keys {
+			pending.Delete(key)
+		}
+	}
+
+	// Add the range to the list to be deleted on transaction commit.
+	tx.pendingRanges = tx.pendingRanges.add(start, limit)
+	tx.notifyActiveIters()
+}
+
 // nextBucketID returns the next bucket ID to use for creating a new bucket.
 //
 // NOTE: This function must only be called on a writable transaction.  Since it
@@ -1189,6 +1394,63 @@ func (tx *transaction) StoreBlock(block *btcutil.Block) error {
 	return nil
 }
 
+// ReplaceBlock stores the provided block in place of the stored block with the
+// same hash.  The previously stored data is left in its block file.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrBlockNotFound if the block hash does not exist
+//   - ErrTxNotWritable if attempted against a read-only transaction
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) ReplaceBlock(block *btcutil.Block) error {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return err
+	}
+
+	// Ensure the transaction is writable.
+	if !tx.writable {
+		str := "replace block requires a writable database transaction"
+		return makeDbErr(database.ErrTxNotWritable, str, nil)
+	}
+
+	// Reject the block if there is nothing to replace.
+	blockHash := block.Hash()
+	if !tx.hasBlock(blockHash) {
+		str := fmt.Sprintf("block %s does not exist", blockHash)
+		return makeDbErr(database.ErrBlockNotFound, str, nil)
+	}
+
+	blockBytes, err := block.Bytes()
+	if err != nil {
+		str := fmt.Sprintf("failed to get serialized bytes for block %s",
+			blockHash)
+		return makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+
+	// Replace the data of a block that is still pending.  Otherwise, add
+	// the block to the pending blocks so it is written to the block files
+	// and its block index entry is overwritten with the new location when
+	// the transaction is committed.
+	if idx, ok := tx.pendingBlocks[*blockHash]; ok {
+		tx.pendingBlockData[idx].bytes = blockBytes
+		return nil
+	}
+	if tx.pendingBlocks == nil {
+		tx.pendingBlocks = make(map[chainhash.Hash]int)
+	}
+	tx.pendingBlocks[*blockHash] = len(tx.pendingBlockData)
+	tx.pendingBlockData = append(tx.pendingBlockData, pendingBlock{
+		hash:  blockHash,
+		bytes: blockBytes,
+	})
+	log.Tracef("Added replacement for block %s to pending blocks",
+		blockHash)
+
+	return nil
+}
+
 // HasBlock returns whether or not a block with the given hash exists in the
 // database.
 //
@@ -1457,17 +1719,17 @@ func (tx *transaction) FetchBlockRegion(region *database.BlockRegion) ([]byte, e
 
 	// Ensure the region is within the bounds of the block.
 	endOffset := region.Offset + region.Len
-	if endOffset < region.Offset || endOffset > location.blockLen {
+	if endOffset < region.Offset || endOffset > location.regionLimit() {
 		str := fmt.Sprintf("block %s region offset %d, length %d "+
 			"exceeds block length of %d", region.Hash,
-			region.Offset, region.Len, location.blockLen)
+			region.Offset, region.Len, location.regionLimit())
 		return nil, makeDbErr(database.ErrBlockRegionInvalid, str, nil)
 
 	}
 
 	// Read the region from the appropriate disk block file.
-	regionBytes, err := tx.db.store.readBlockRegion(location, region.Offset,
-		region.Len)
+	regionBytes, err := tx.db.store.readBlockRegion(region.Hash, location,
+		region.Offset, region.Len)
 	if err != nil {
 		return nil, err
 	}
@@ -1555,10 +1817,10 @@ func (tx *transaction) FetchBlockRegions(regions []database.BlockRegion) ([][]by
 
 		// Ensure the region is within the bounds of the block.
 		endOffset := region.Offset + region.Len
-		if endOffset < region.Offset || endOffset > location.blockLen {
+		if endOffset < region.Offset || endOffset > location.regionLimit() {
 			str := fmt.Sprintf("block %s region offset %d, length "+
 				"%d exceeds block length of %d", region.Hash,
-				region.Offset, region.Len, location.blockLen)
+				region.Offset, region.Len, location.regionLimit())
 			return nil, makeDbErr(database.ErrBlockRegionInvalid, str, nil)
 		}
 
@@ -1572,8 +1834,8 @@ func (tx *transaction) FetchBlockRegions(regions []database.BlockRegion) ([][]by
 		ri := fetchData.replyIndex
 		region := &regions[ri]
 		location := fetchData.blockLocation
-		regionBytes, err := tx.db.store.readBlockRegion(*location,
-			region.Offset, region.Len)
+		regionBytes, err := tx.db.store.readBlockRegion(region.Hash,
+			*location, region.Offset, region.Len)
 		if err != nil {
 			return nil, err
 		}
@@ -1583,6 +1845,113 @@ func (tx *transaction) FetchBlockRegions(regions []database.BlockRegion) ([][]by
 	return blockRegions, nil
 }
 
+// PruneBlocks deletes the oldest block files until the total size of the block
+// storage is at or below the provided target size in bytes and returns the
+// hashes of all blocks that were stored in the deleted files.  The block index
+// entries are removed as part of the transaction while the files themselves
+// are only deleted once the transaction has been committed.  The most recent
+// block file, which is the current write file, is never deleted.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrTxNotWritable if attempted against a read-only transaction
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) PruneBlocks(targetSize uint64) ([]chainhash.Hash, error) {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	// Ensure the transaction is writable.
+	if !tx.writable {
+		str := "prune blocks requires a writable database transaction"
+		return nil, makeDbErr(database.ErrTxNotWritable, str, nil)
+	}
+
+	maxSize := uint64(tx.db.store.maxBlockFileSize)
+	if targetSize < maxSize {
+		str := fmt.Sprintf("prune target size of %d bytes is less than "+
+			"the maximum size of a single block file (%d bytes)",
+			targetSize, maxSize)
+		return nil, makeDbErr(database.ErrDriverSpecific, str, nil)
+	}
+
+	// The files already pruned by the transaction are the oldest ones.
+	// Nothing to do when there are no files or only the write file left.
+	wc := tx.db.store.writeCursor
+	wc.RLock()
+	first := wc.firstFileNum + uint32(len(tx.pendingPrunedFiles))
+	last, lastFileSize := wc.curFileNum, wc.curOffset
+	wc.RUnlock()
+	if first >= last {
+		return nil, nil
+	}
+
+	// All files other than the last one are assumed to be full since the
+	// write cursor only moves to a new file once the previous one can't
+	// fit the next block.
+	totalSize := uint64(lastFileSize) + maxSize*uint64(last-first)
+	if totalSize <= targetSize {
+		return nil, nil
+	}
+
+	// Remove the block index entries for all blocks stored in the files
+	// that are about to be deleted.  A block can have been stored again in
+	// a later file after it was pruned, so only the entries of the blocks
+	// which are still located in the files are removed.
+	var prunedHashes []chainhash.Hash
+	numFiles := 0
+	for fileNum := first; fileNum < last && totalSize > targetSize; fileNum++ {
+		hashes, err := tx.db.store.blockFileHashes(fileNum)
+		if err != nil {
+			return nil, err
+		}
+		for i := range hashes {
+			hash := &hashes[i]
+			blockRow := tx.blockIdxBucket.Get(hash[:])
+			if blockRow == nil ||
+				deserializeBlockLoc(blockRow).blockFileNum != fileNum {
+
+				continue
+			}
+			if err := tx.blockIdxBucket.Delete(hash[:]); err != nil {
+				return nil, err
+			}
+			prunedHashes = append(prunedHashes, *hash)
+		}
+		tx.pendingPrunedFiles = append(tx.pendingPrunedFiles, fileNum)
+		numFiles++
+
+		totalSize -= maxSize
+	}
+
+	log.Debugf("Pruning %d block files containing %d blocks", numFiles,
+		len(prunedHashes))
+
+	return prunedHashes, nil
+}
+
+// BeenPruned returns whether or not block files have ever been pruned from the
+// block storage.  This is determined by the first block file on disk no longer
+// being the initial one.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) BeenPruned() (bool, error) {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return false, err
+	}
+
+	wc := tx.db.store.writeCursor
+	wc.RLock()
+	defer wc.RUnlock()
+	return wc.firstFileNum > 0, nil
+}
+
 // close marks the transaction closed then releases any pending data, the
 // underlying snapshot, the transaction read lock, and the write lock when the
 // transaction is writable.
@@ -1592,10 +1961,16 @@ func (tx *transaction) close() {
 	// Clear pending blocks that would have been written on commit.
 	tx.pendingBlocks = nil
 	tx.pendingBlockData = nil
+	tx.pendingPrunedFiles = nil
 
 	// Clear pending keys that would have been written or deleted on commit.
+	// They have been copied to the database cache on commit, so the nodes
+	// of the treaps can be recycled.
+	tx.pendingKeys.Recycle()
+	tx.pendingRemove.Recycle()
 	tx.pendingKeys = nil
 	tx.pendingRemove = nil
+	tx.pendingRanges = nil
 
 	// Release the snapshot.
 	if tx.snapshot != nil {
@@ -1666,7 +2041,27 @@ func (tx *transaction) writePendingAndCommit() error {
 
 	// Atomically update the database cache.  The cache automatically
 	// handles flushing to the underlying persistent storage database.
-	return tx.db.cache.commitTx(tx)
+	if err := tx.db.cache.commitTx(tx); err != nil {
+		return err
+	}
+
+	// Now that the block index no longer references them, delete any block
+	// files that were pruned.  Failures are only logged since the blocks
+	// are no longer reachable regardless.
+	for _, fileNum := range tx.pendingPrunedFiles {
+		tx.db.store.closeFile(fileNum)
+		if err := tx.db.store.deleteFileFunc(fileNum); err != nil {
+			log.Warnf("Failed to delete pruned block file %d: %v",
+				fileNum, err)
+		}
+	}
+	if n := len(tx.pendingPrunedFiles); n > 0 {
+		wc.Lock()
+		wc.firstFileNum = tx.pendingPrunedFiles[n-1] + 1
+		wc.Unlock()
+	}
+
+	return nil
 }
 
 // Commit commits all changes that have been made to the root metadata bucket
@@ -1898,6 +2293,17 @@ func (db *db) Update(fn func(database.Tx) error) error {
 	return tx.Commit()
 }
 
+// OnFlush registers the passed function to be called each time the database
+// cache is flushed to persistent storage, after the block files are synced and
+// before the metadata is written.
+//
+// This function is part of the database.DB interface implementation.
+func (db *db) OnFlush(fn func() error) {
+	db.writeLock.Lock()
+	db.cache.flushHooks = append(db.cache.flushHooks, fn)
+	db.writeLock.Unlock()
+}
+
 // Close cleanly shuts down the database and syncs all data.  It will block
 // until all database transactions have been finalized (rolled back or
 // committed).
@@ -1982,7 +2388,9 @@ func initDB(ldb *leveldb.DB) error {
 
 // openDB opens the database at the provided path.  database.ErrDbDoesNotExist
 // is returned if the database doesn't exist and the create flag is not set.
-func openDB(dbPath string, network wire.BitcoinNet, create bool) (database.DB, error) {
+func openDB(dbPath string, network wire.BitcoinNet, options *Options,
+	create bool) (database.DB, error) {
+
 	// Error if the database doesn't exist and the create flag is not set.
 	metadataDbPath := filepath.Join(dbPath, metadataDbName)
 	dbExists := fileExists(metadataDbPath)
@@ -2017,7 +2425,7 @@ func openDB(dbPath string, network wire.BitcoinNet, create bool) (database.DB, e
 	// according to the data that is actually on disk.  Also create the
 	// database cache which wraps the underlying leveldb database to provide
 	// write caching.
-	store := newBlockStore(dbPath, network)
+	store := newBlockStore(dbPath, network, options.CompressBlocks)
 	cache := newDbCache(ldb, store, defaultCacheSize, defaultFlushSecs)
 	pdb := &db{store: store, cache: cache}
 
@@ -85,6 +85,9 @@ type dbCacheIterator struct {
 	cacheIter     iterator.Iterator
 	currentIter   iterator.Iterator
 	released      bool
+
+	// forwards is the direction the iterator was last moved in.
+	forwards bool
 }
 
 // Enforce dbCacheIterator implements the leveldb iterator.Iterator interface.
@@ -114,6 +117,23 @@ func (iter *dbCacheIterator) skipPendingUpdates(forwards bool) {
 	}
 }
 
+// setDirection records the direction the iterator is moving in given by the
+// forwards flag.  When it changes, the iterator which isn't the current one is
+// positioned at the key following the current one in the new direction since
+// it is on the other side of the current key.
+func (iter *dbCacheIterator) setDirection(forwards bool) {
+	if iter.forwards == forwards {
+		return
+	}
+	iter.forwards = forwards
+
+	other := iter.cacheIter
+	if iter.currentIter == iter.cacheIter {
+		other = iter.dbIter
+	}
+	repositionIterator(other, copySlice(iter.currentIter.Key()), forwards)
+}
+
 // chooseIterator first skips any entries in the database iterator that are
 // being updated by the cache and sets the current iterator to the appropriate
 // iterator depending on their validity and the order they compare in while taking
@@ -163,6 +183,7 @@ func (iter *dbCacheIterator) First() bool {
 	// choose the iterator that is both valid and has the smaller key.
 	iter.dbIter.First()
 	iter.cacheIter.First()
+	iter.forwards = true
 	return iter.chooseIterator(true)
 }
 
@@ -175,6 +196,7 @@ func (iter *dbCacheIterator) Last() bool {
 	// choose the iterator that is both valid and has the larger key.
 	iter.dbIter.Last()
 	iter.cacheIter.Last()
+	iter.forwards = false
 	return iter.chooseIterator(false)
 }
 
@@ -190,6 +212,7 @@ func (iter *dbCacheIterator) Next() bool {
 
 	// Move the current iterator to the next entry and choose the iterator
 	// that is both valid and has the smaller key.
+	iter.setDirection(true)
 	iter.currentIter.Next()
 	return iter.chooseIterator(true)
 }
@@ -206,6 +229,7 @@ func (iter *dbCacheIterator) Prev() bool {
 
 	// Move the current iterator to the previous entry and choose the
 	// iterator that is both valid and has the larger key.
+	iter.setDirection(false)
 	iter.currentIter.Prev()
 	return iter.chooseIterator(false)
 }
@@ -219,6 +243,7 @@ func (iter *dbCacheIterator) Seek(key []byte) bool {
 	// then choose the iterator that is both valid and has the larger key.
 	iter.dbIter.Seek(key)
 	iter.cacheIter.Seek(key)
+	iter.forwards = true
 	return iter.chooseIterator(true)
 }
 
@@ -346,6 +371,15 @@ func (snap *dbCacheSnapshot) NewIterator(slice *util.Range) *dbCacheIterator {
 	}
 }
 
+// NewPrefetchIterator returns a new iterator for the snapshot like NewIterator
+// which reads ahead the key/value pairs of the underlying database in batches
+// of about the passed number of leveldb blocks while it moves forward.
+func (snap *dbCacheSnapshot) NewPrefetchIterator(slice *util.Range, blocks int) *dbCacheIterator {
+	iter := snap.NewIterator(slice)
+	iter.dbIter = newPrefetchIter(iter.dbIter, blocks)
+	return iter
+}
+
 // dbCache provides a database cache layer backed by an underlying database.  It
 // allows a maximum cache size and flush interval to be specified such that the
 // cache is flushed to the database when the cache size exceeds the maximum
@@ -374,11 +408,15 @@ type dbCache struct {
 	// lastFlush is the time the cache was last flushed.  It is used in
 	// conjunction with the current time and the flush interval.
 	//
+	// flushHooks are the functions registered by the users of the database
+	// to sync their files before the metadata is flushed.
+	//
 	// NOTE: These flush related fields are protected by the database write
 	// lock.
 	maxSize       uint64
 	flushInterval time.Duration
 	lastFlush     time.Time
+	flushHooks    []func() error
 
 	// The following fields hold the keys that need to be stored or deleted
 	// from the underlying database once the cache is full, enough time has
@@ -447,11 +485,41 @@ type TreapForEacher interface {
 	ForEach(func(k, v []byte) bool)
 }
 
+// deleteRange deletes all of the keys of the underlying database in the passed
+// range using the passed leveldb transaction.  Since leveldb has no native range
+// deletion, the keys are read from the state of the database before the
+// transaction and deleted one by one.
+func (c *dbCache) deleteRange(ldbTx *leveldb.Transaction, keyRange *util.Range) error {
+	iter := c.ldb.NewIterator(keyRange, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if dbErr := ldbTx.Delete(iter.Key(), nil); dbErr != nil {
+			str := fmt.Sprintf("failed to delete key %q from ldb "+
+				"transaction", iter.Key())
+			return convertErr(str, dbErr)
+		}
+	}
+	if dbErr := iter.Error(); dbErr != nil {
+		str := fmt.Sprintf("failed to read keys from %q to %q",
+			keyRange.Start, keyRange.Limit)
+		return convertErr(str, dbErr)
+	}
+	return nil
+}
+
 // commitTreaps atomically commits all of the passed pending add/update/remove
-// updates to the underlying database.
-func (c *dbCache) commitTreaps(pendingKeys, pendingRemove TreapForEacher) error {
+// updates to the underlying database.  The ranges of keys to delete are
+// deleted first, so the pending keys to add in them are kept.
+func (c *dbCache) commitTreaps(pendingRanges keyRanges, pendingKeys, pendingRemove TreapForEacher) error {
 	// Perform all leveldb updates using an atomic transaction.
 	return c.updateDB(func(ldbTx *leveldb.Transaction) error {
+		for i := range pendingRanges {
+			err := c.deleteRange(ldbTx, &pendingRanges[i])
+			if err != nil {
+				return err
+			}
+		}
+
 		var innerErr error
 		pendingKeys.ForEach(func(k, v []byte) bool {
 			if dbErr := ldbTx.Put(k, v, nil); dbErr != nil {
@@ -496,6 +564,14 @@ func (c *dbCache) flush() error {
 		return err
 	}
 
+	// Likewise, sync the files of the users of the database which the
+	// metadata refers to.
+	for _, hook := range c.flushHooks {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
+
 	// Since the cached keys to be added and removed use an immutable treap,
 	// a snapshot is simply obtaining the root of the tree under the lock
 	// which is used to atomically swap the root.
@@ -512,7 +588,7 @@ func (c *dbCache) flush() error {
 	}
 
 	// Perform all leveldb updates using an atomic transaction.
-	if err := c.commitTreaps(cachedKeys, cachedRemove); err != nil {
+	if err := c.commitTreaps(nil, cachedKeys, cachedRemove); err != nil {
 		return err
 	}
 
@@ -562,21 +638,25 @@ func (c *dbCache) needsFlush(tx *transaction) bool {
 // turn implies the database write lock will be held.
 func (c *dbCache) commitTx(tx *transaction) error {
 	// Flush the cache and write the current transaction directly to the
-	// database if a flush is needed.
-	if c.needsFlush(tx) {
+	// database if a flush is needed.  This is always the case when the
+	// transaction deletes ranges of keys since they are deleted from the
+	// underlying database rather than being tracked key by key in the
+	// cache.
+	if len(tx.pendingRanges) > 0 || c.needsFlush(tx) {
 		if err := c.flush(); err != nil {
 			return err
 		}
 
 		// Perform all leveldb updates using an atomic transaction.
-		err := c.commitTreaps(tx.pendingKeys, tx.pendingRemove)
+		err := c.commitTreaps(tx.pendingRanges, tx.pendingKeys,
+			tx.pendingRemove)
 		if err != nil {
 			return err
 		}
 
 		// Clear the transaction entries since they have been committed.
-		tx.pendingKeys = nil
-		tx.pendingRemove = nil
+		tx.pendingKeys.Recycle()
+		tx.pendingRemove.Recycle()
 		return nil
 	}
 
@@ -591,21 +671,26 @@ func (c *dbCache) commitTx(tx *transaction) error {
 	newCachedRemove := c.cachedRemove
 	c.cacheLock.RUnlock()
 
-	// Apply every key to add in the database transaction to the cache.
-	tx.pendingKeys.ForEach(func(k, v []byte) bool {
-		newCachedRemove = newCachedRemove.Delete(k)
-		newCachedKeys = newCachedKeys.Put(k, v)
-		return true
-	})
-	tx.pendingKeys = nil
+	// Load the keys to add and remove in the database transaction into
+	// immutable treaps so they can be merged into the cache at once rather
+	// than key by key.  The pending keys to add and remove are disjoint.
+	pendingKeys, err := treap.NewFromSorted(tx.pendingKeys.Iterator(nil, nil))
+	if err != nil {
+		return err
+	}
+	tx.pendingKeys.Recycle()
+	pendingRemove, err := treap.NewFromSorted(
+		tx.pendingRemove.Iterator(nil, nil))
+	if err != nil {
+		return err
+	}
+	tx.pendingRemove.Recycle()
 
-	// Apply every key to remove in the database transaction to the cache.
-	tx.pendingRemove.ForEach(func(k, v []byte) bool {
-		newCachedKeys = newCachedKeys.Delete(k)
-		newCachedRemove = newCachedRemove.Put(k, nil)
-		return true
-	})
-	tx.pendingRemove = nil
+	// Apply every key to add and remove in the database transaction to the
+	// cache.
+	newCachedKeys = newCachedKeys.Difference(pendingRemove).Union(pendingKeys)
+	newCachedRemove = newCachedRemove.Difference(pendingKeys).Union(
+		pendingRemove)
 
 	// Atomically replace the immutable treaps which hold the cached keys to
 	// add and delete.
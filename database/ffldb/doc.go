@@ -25,5 +25,19 @@ database path as a string and the block network:
 	if err != nil {
 		// Handle error
 	}
+
+An optional third parameter of type *Options enables the zstd compression of
+the blocks written to the flat files:
+
+	opts := &ffldb.Options{CompressBlocks: true}
+	db, err := database.Open("ffldb", "path/to/database", wire.MainNet, opts)
+	if err != nil {
+		// Handle error
+	}
+
+Compressed blocks are read regardless of the option, so it may be toggled
+between runs.  However, enabling it records a new version of the block storage
+format, since older versions of the driver are unable to read compressed
+blocks.
 */
 package ffldb
@@ -18,49 +18,71 @@ const (
 	dbType = "ffldb"
 )
 
+// Options houses the optional settings of the database.  They may be passed as
+// an optional third argument to the database Open and Create methods.
+type Options struct {
+	// CompressBlocks compresses the blocks written to the flat files with
+	// zstd.  Blocks which were already written are read regardless of this
+	// setting.
+	CompressBlocks bool
+}
+
 // parseArgs parses the arguments from the database Open/Create methods.
-func parseArgs(funcName string, args ...interface{}) (string, wire.BitcoinNet, error) {
-	if len(args) != 2 {
-		return "", 0, fmt.Errorf("invalid arguments to %s.%s -- "+
-			"expected database path and block network", dbType,
-			funcName)
+func parseArgs(funcName string, args ...interface{}) (string, wire.BitcoinNet,
+	*Options, error) {
+
+	if len(args) != 2 && len(args) != 3 {
+		return "", 0, nil, fmt.Errorf("invalid arguments to %s.%s -- "+
+			"expected database path, block network and optional "+
+			"options", dbType, funcName)
 	}
 
 	dbPath, ok := args[0].(string)
 	if !ok {
-		return "", 0, fmt.Errorf("first argument to %s.%s is invalid -- "+
-			"expected database path string", dbType, funcName)
+		return "", 0, nil, fmt.Errorf("first argument to %s.%s is "+
+			"invalid -- expected database path string", dbType,
+			funcName)
 	}
 
 	network, ok := args[1].(wire.BitcoinNet)
 	if !ok {
-		return "", 0, fmt.Errorf("second argument to %s.%s is invalid -- "+
-			"expected block network", dbType, funcName)
+		return "", 0, nil, fmt.Errorf("second argument to %s.%s is "+
+			"invalid -- expected block network", dbType, funcName)
+	}
+
+	opts := &Options{}
+	if len(args) == 3 {
+		opts, ok = args[2].(*Options)
+		if !ok || opts == nil {
+			return "", 0, nil, fmt.Errorf("third argument to %s.%s "+
+				"is invalid -- expected *ffldb.Options", dbType,
+				funcName)
+		}
 	}
 
-	return dbPath, network, nil
+	return dbPath, network, opts, nil
 }
 
 // openDBDriver is the callback provided during driver registration that opens
 // an existing database for use.
 func openDBDriver(args ...interface{}) (database.DB, error) {
-	dbPath, network, err := parseArgs("Open", args...)
+	dbPath, network, opts, err := parseArgs("Open", args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return openDB(dbPath, network, false)
+	return openDB(dbPath, network, opts, false)
 }
 
 // createDBDriver is the callback provided during driver registration that
 // creates, initializes, and opens a database for use.
 func createDBDriver(args ...interface{}) (database.DB, error) {
-	dbPath, network, err := parseArgs("Create", args...)
+	dbPath, network, opts, err := parseArgs("Create", args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return openDB(dbPath, network, true)
+	return openDB(dbPath, network, opts, true)
 }
 
 // useLogger is the callback provided during driver registration that sets the
@@ -5,7 +5,11 @@
 package ffldb_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -36,12 +40,14 @@ func TestCreateOpenFail(t *testing.T) {
 	// Ensure that attempting to open a database with the wrong number of
 	// parameters returns the expected error.
 	wantErr := fmt.Errorf("invalid arguments to %s.Open -- expected "+
-		"database path and block network", dbType)
-	_, err = database.Open(dbType, 1, 2, 3)
-	if err.Error() != wantErr.Error() {
-		t.Errorf("Open: did not receive expected error - got %v, "+
-			"want %v", err, wantErr)
-		return
+		"database path, block network and optional options", dbType)
+	for _, args := range [][]interface{}{{"noexist"}, {1, 2, 3, 4}} {
+		_, err = database.Open(dbType, args...)
+		if err.Error() != wantErr.Error() {
+			t.Errorf("Open: did not receive expected error - got %v, "+
+				"want %v", err, wantErr)
+			return
+		}
 	}
 
 	// Ensure that attempting to open a database with an invalid type for
@@ -66,17 +72,30 @@ func TestCreateOpenFail(t *testing.T) {
 		return
 	}
 
-	// Ensure that attempting to create a database with the wrong number of
-	// parameters returns the expected error.
-	wantErr = fmt.Errorf("invalid arguments to %s.Create -- expected "+
-		"database path and block network", dbType)
-	_, err = database.Create(dbType, 1, 2, 3)
+	// Ensure that attempting to open a database with an invalid type for
+	// the optional third parameter returns the expected error.
+	wantErr = fmt.Errorf("third argument to %s.Open is invalid -- "+
+		"expected *ffldb.Options", dbType)
+	_, err = database.Open(dbType, "noexist", blockDataNet, "invalid")
 	if err.Error() != wantErr.Error() {
-		t.Errorf("Create: did not receive expected error - got %v, "+
+		t.Errorf("Open: did not receive expected error - got %v, "+
 			"want %v", err, wantErr)
 		return
 	}
 
+	// Ensure that attempting to create a database with the wrong number of
+	// parameters returns the expected error.
+	wantErr = fmt.Errorf("invalid arguments to %s.Create -- expected "+
+		"database path, block network and optional options", dbType)
+	for _, args := range [][]interface{}{{"noexist"}, {1, 2, 3, 4}} {
+		_, err = database.Create(dbType, args...)
+		if err.Error() != wantErr.Error() {
+			t.Errorf("Create: did not receive expected error - got %v, "+
+				"want %v", err, wantErr)
+			return
+		}
+	}
+
 	// Ensure that attempting to create a database with an invalid type for
 	// the first parameter returns the expected error.
 	wantErr = fmt.Errorf("first argument to %s.Create is invalid -- "+
@@ -99,6 +118,17 @@ func TestCreateOpenFail(t *testing.T) {
 		return
 	}
 
+	// Ensure that attempting to create a database with an invalid type for
+	// the optional third parameter returns the expected error.
+	wantErr = fmt.Errorf("third argument to %s.Create is invalid -- "+
+		"expected *ffldb.Options", dbType)
+	_, err = database.Create(dbType, "noexist", blockDataNet, "invalid")
+	if err.Error() != wantErr.Error() {
+		t.Errorf("Create: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
 	// Ensure operations against a closed database return the expected
 	// error.
 	dbPath := filepath.Join(os.TempDir(), "ffldb-createfail")
@@ -284,3 +314,202 @@ func TestInterface(t *testing.T) {
 		testInterface(t, db)
 	})
 }
+
+// TestBackup ensures a backup holds a consistent snapshot of the database which
+// can be restored, excluding the changes made after it is started, along with
+// the files stored in the directory of the database by its users.
+func TestBackup(t *testing.T) {
+	t.Parallel()
+
+	blocks, err := loadBlocks(t, blockDataFile, blockDataNet)
+	if err != nil {
+		t.Fatalf("loadBlocks: Unexpected error: %v", err)
+	}
+
+	// Create a new database to run tests against.
+	dbPath := filepath.Join(os.TempDir(), "ffldb-backuptest")
+	_ = os.RemoveAll(dbPath)
+	db, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	key := []byte("key")
+	err = db.Update(func(tx database.Tx) error {
+		if err := tx.Metadata().Put(key, []byte("before")); err != nil {
+			return err
+		}
+		for _, block := range blocks[:2] {
+			if err := tx.StoreBlock(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	// The files stored in the directory of the database by its users are
+	// part of the backup.
+	userFile := filepath.Join(dbPath, "user.dat")
+	if err := os.WriteFile(userFile, []byte("user"), 0600); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v", err)
+	}
+
+	var backup bytes.Buffer
+	if err := db.Backup(&backup); err != nil {
+		t.Fatalf("Backup: unexpected error: %v", err)
+	}
+
+	// Changes made after the backup must not be part of it.
+	err = db.Update(func(tx database.Tx) error {
+		if err := tx.Metadata().Put(key, []byte("after")); err != nil {
+			return err
+		}
+		return tx.StoreBlock(blocks[2])
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	// Extract the backup and ensure it holds the snapshot.
+	restorePath := filepath.Join(os.TempDir(), "ffldb-backuptest-restore")
+	_ = os.RemoveAll(restorePath)
+	defer os.RemoveAll(restorePath)
+	tr := tar.NewReader(&backup)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: unexpected error: %v", err)
+		}
+		path := filepath.Join(restorePath, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("MkdirAll: unexpected error: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("ReadAll: unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("WriteFile: unexpected error: %v", err)
+		}
+	}
+
+	restored, err := database.Open(dbType, restorePath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to open restored database (%s) %v", dbType, err)
+	}
+	defer restored.Close()
+
+	err = restored.View(func(tx database.Tx) error {
+		if got := tx.Metadata().Get(key); string(got) != "before" {
+			return fmt.Errorf("unexpected value %q, want %q", got,
+				"before")
+		}
+		for i, block := range blocks[:3] {
+			has, err := tx.HasBlock(block.Hash())
+			if err != nil {
+				return err
+			}
+			if has != (i < 2) {
+				return fmt.Errorf("unexpected presence %v of "+
+					"block %d", has, i)
+			}
+		}
+		want, _ := blocks[1].Bytes()
+		got, err := tx.FetchBlock(blocks[1].Hash())
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, want) {
+			return fmt.Errorf("restored block mismatch")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(restorePath, "user.dat"))
+	if err != nil {
+		t.Fatalf("ReadFile: unexpected error: %v", err)
+	}
+	if string(data) != "user" {
+		t.Fatalf("restored user file holds %q, want %q", data, "user")
+	}
+}
+
+// TestOnFlush ensures the functions registered with OnFlush are only called
+// when the database cache is flushed to persistent storage, and that a failure
+// prevents the metadata from being written.
+func TestOnFlush(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(os.TempDir(), "ffldb-onflushtest")
+	_ = os.RemoveAll(dbPath)
+	db, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	var flushes int
+	var flushErr error
+	db.OnFlush(func() error {
+		flushes++
+		return flushErr
+	})
+
+	// Transactions committed to the cache don't flush it.
+	key := []byte("key")
+	err = db.Update(func(tx database.Tx) error {
+		return tx.Metadata().Put(key, []byte("value"))
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if flushes != 0 {
+		t.Fatalf("got %d flushes, want none", flushes)
+	}
+
+	// Deleting a range of keys always flushes the cache first, which must
+	// fail along with the transaction when the registered function fails.
+	flushErr = errors.New("flush failed")
+	err = db.Update(func(tx database.Tx) error {
+		if err := tx.Metadata().Put(key, []byte("new value")); err != nil {
+			return err
+		}
+		return tx.Metadata().DeleteRange([]byte("a"), []byte("b"))
+	})
+	if !errors.Is(err, flushErr) {
+		t.Fatalf("Update: got error %v, want %v", err, flushErr)
+	}
+	if flushes != 1 {
+		t.Fatalf("got %d flushes, want 1", flushes)
+	}
+	err = db.View(func(tx database.Tx) error {
+		if value := tx.Metadata().Get(key); string(value) != "value" {
+			return fmt.Errorf("got value %q, want %q", value, "value")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: unexpected error: %v", err)
+	}
+
+	// Closing the database flushes the cache.
+	flushErr = nil
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+	if flushes != 2 {
+		t.Fatalf("got %d flushes, want 2", flushes)
+	}
+}
@@ -0,0 +1,67 @@
+package ffldb
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// keyRanges is a set of disjoint ranges of keys sorted by their start keys.
+// Ranges which overlap or touch are merged when they are added, so a key
+// following a range is never the start key of the next one.  The start key of
+// a range is inclusive and its limit key is exclusive, with a nil limit key
+// standing for the end of the key space.
+type keyRanges []util.Range
+
+// limitAfter returns whether the passed limit key of a range is after the
+// passed key.
+func limitAfter(limit, key []byte) bool {
+	return limit == nil || bytes.Compare(limit, key) > 0
+}
+
+// find returns the index of the range containing the passed key, or -1 when no
+// range contains it.
+func (r keyRanges) find(key []byte) int {
+	i := sort.Search(len(r), func(i int) bool {
+		return limitAfter(r[i].Limit, key)
+	})
+	if i < len(r) && bytes.Compare(r[i].Start, key) <= 0 {
+		return i
+	}
+	return -1
+}
+
+// contains returns whether the passed key is in one of the ranges.
+func (r keyRanges) contains(key []byte) bool {
+	return r.find(key) >= 0
+}
+
+// add returns the set of ranges with the range of keys from the passed start
+// key up to the passed limit key added.  The ranges it overlaps or touches are
+// merged into it.
+func (r keyRanges) add(start, limit []byte) keyRanges {
+	// The ranges from i up to j are the ones to merge, since the ones
+	// before end before the start key and the ones after start after the
+	// limit key.
+	i := sort.Search(len(r), func(i int) bool {
+		return r[i].Limit == nil || bytes.Compare(r[i].Limit, start) >= 0
+	})
+	j := i
+	for j < len(r) && (limit == nil || bytes.Compare(r[j].Start, limit) <= 0) {
+		j++
+	}
+	if i < j {
+		if bytes.Compare(r[i].Start, start) < 0 {
+			start = r[i].Start
+		}
+		if last := r[j-1].Limit; limit != nil && limitAfter(last, limit) {
+			limit = last
+		}
+	}
+
+	merged := make(keyRanges, 0, len(r)-(j-i)+1)
+	merged = append(merged, r[:i]...)
+	merged = append(merged, util.Range{Start: start, Limit: limit})
+	return append(merged, r[j:]...)
+}
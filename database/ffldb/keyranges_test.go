@@ -0,0 +1,294 @@
+// This file is part of the ffldb package rather than the ffldb_test package as
+// it provides whitebox testing.
+
+package ffldb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbcd/database"
+)
+
+// TestKeyRanges ensures ranges of keys are merged when they overlap or touch
+// and that the ranges containing keys are found.
+func TestKeyRanges(t *testing.T) {
+	t.Parallel()
+
+	var r keyRanges
+	r = r.add([]byte("d"), []byte("f"))
+	r = r.add([]byte("a"), []byte("b"))
+	r = r.add([]byte("m"), nil)
+	r = r.add([]byte("h"), []byte("j"))
+	if len(r) != 4 {
+		t.Fatalf("got %d ranges, want 4", len(r))
+	}
+
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{key: "", want: -1},
+		{key: "a", want: 0},
+		{key: "ab", want: 0},
+		{key: "b", want: -1},
+		{key: "e", want: 1},
+		{key: "f", want: -1},
+		{key: "i", want: 2},
+		{key: "l", want: -1},
+		{key: "m", want: 3},
+		{key: "zzz", want: 3},
+	}
+	for _, test := range tests {
+		if got := r.find([]byte(test.key)); got != test.want {
+			t.Errorf("find(%q): got %d, want %d", test.key, got,
+				test.want)
+		}
+	}
+
+	// Ranges which touch or overlap others are merged with them.
+	r = r.add([]byte("b"), []byte("c"))
+	r = r.add([]byte("e"), []byte("i"))
+	r = r.add([]byte("k"), []byte("n"))
+	want := "[{a c} {d j} {k <nil>}]"
+	got := "["
+	for i, kr := range r {
+		if i > 0 {
+			got += " "
+		}
+		limit := "<nil>"
+		if kr.Limit != nil {
+			limit = string(kr.Limit)
+		}
+		got += fmt.Sprintf("{%s %s}", kr.Start, limit)
+	}
+	got += "]"
+	if got != want {
+		t.Fatalf("got ranges %s, want %s", got, want)
+	}
+}
+
+// rangeTestKey returns the key of the passed index in the test bucket created
+// by populatePrefetchTestDB.
+func rangeTestKey(i uint32) []byte {
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], i)
+	return key[:]
+}
+
+// checkBucketKeys returns an error when the keys of the passed bucket, which
+// are listed with cursors in both directions, aren't the expected ones.
+func checkBucketKeys(bucket database.Bucket, want []string) error {
+	forward, backward := cursorKeys(bucket.Cursor())
+	if fmt.Sprint(forward) != fmt.Sprint(want) {
+		return fmt.Errorf("got keys %x moving forward, want %x",
+			forward, want)
+	}
+	for i, j := 0, len(backward)-1; i < j; i, j = i+1, j-1 {
+		backward[i], backward[j] = backward[j], backward[i]
+	}
+	if fmt.Sprint(backward) != fmt.Sprint(want) {
+		return fmt.Errorf("got keys %x moving backward, want %x",
+			backward, want)
+	}
+	return nil
+}
+
+// TestDeleteRange ensures deleting ranges of keys hides them from the
+// transaction, including its cursors in both directions, keeps the keys stored
+// again afterwards, and deletes them from the underlying database on commit.
+func TestDeleteRange(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(os.TempDir(), "ffldb-deleterange")
+	defer os.RemoveAll(dbPath)
+	pdb := populatePrefetchTestDB(t, dbPath, 1000)
+	defer pdb.Close()
+
+	// The keys expected to remain after deleting the keys from 100 up to
+	// 900, deleting the key 50 and storing the key 500 again, along with
+	// the nested bucket.
+	var want []string
+	for i := uint32(0); i < 1000; i++ {
+		if (i >= 100 && i < 900 && i != 500) || i == 50 {
+			continue
+		}
+		want = append(want, string(rangeTestKey(i)))
+	}
+	want = append(want, "nested")
+
+	checkRange := func(bucket database.Bucket) error {
+		if err := checkBucketKeys(bucket, want); err != nil {
+			return err
+		}
+		if bucket.Get(rangeTestKey(200)) != nil {
+			return fmt.Errorf("deleted key 200 still exists")
+		}
+		if bucket.Get(rangeTestKey(500)) == nil {
+			return fmt.Errorf("key 500 stored again doesn't exist")
+		}
+		if bucket.Bucket([]byte("nested")) == nil {
+			return fmt.Errorf("nested bucket was deleted")
+		}
+		return nil
+	}
+
+	err := pdb.Update(func(tx database.Tx) error {
+		bucket := tx.Metadata().Bucket([]byte("prefetch"))
+
+		// Keys stored or deleted in the range before it is deleted are
+		// deleted with it.
+		if err := bucket.Put(rangeTestKey(150), []byte{1}); err != nil {
+			return err
+		}
+		if err := bucket.Delete(rangeTestKey(160)); err != nil {
+			return err
+		}
+		if err := bucket.DeleteRange(rangeTestKey(100),
+			rangeTestKey(300)); err != nil {
+
+			return err
+		}
+		if err := bucket.DeleteRange(rangeTestKey(250),
+			rangeTestKey(900)); err != nil {
+
+			return err
+		}
+		if err := bucket.Delete(rangeTestKey(50)); err != nil {
+			return err
+		}
+		if err := bucket.Put(rangeTestKey(500), []byte{1}); err != nil {
+			return err
+		}
+
+		// Empty ranges are ignored.
+		if err := bucket.DeleteRange(rangeTestKey(10),
+			rangeTestKey(10)); err != nil {
+
+			return err
+		}
+		if err := checkRange(bucket); err != nil {
+			return err
+		}
+
+		// Seeking in the range moves to the key stored again, and
+		// moving backward from it skips the rest of the range.
+		c := bucket.Cursor()
+		if !c.Seek(rangeTestKey(300)) ||
+			string(c.Key()) != string(rangeTestKey(500)) {
+
+			return fmt.Errorf("got key %x after seeking key 300, "+
+				"want key 500", c.Key())
+		}
+		if !c.Prev() || string(c.Key()) != string(rangeTestKey(99)) {
+			return fmt.Errorf("got key %x before key 500, want "+
+				"key 99", c.Key())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The keys were deleted from the underlying database, which is read
+	// directly once the database is reopened.
+	err = pdb.View(func(tx database.Tx) error {
+		return checkRange(tx.Metadata().Bucket([]byte("prefetch")))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdb.Close()
+	pdb, err = database.Open(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to open test database (%s) %v", dbType, err)
+	}
+	defer pdb.Close()
+	err = pdb.View(func(tx database.Tx) error {
+		bucket := tx.Metadata().Bucket([]byte("prefetch"))
+		if err := checkRange(bucket); err != nil {
+			return err
+		}
+
+		// Ranges can't be deleted in read-only transactions.
+		err := bucket.DeleteRange(nil, nil)
+		if !checkDbError(t, "DeleteRange", err, database.ErrTxNotWritable) {
+			return fmt.Errorf("unexpected error %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting all of the keys of the bucket keeps the nested bucket.
+	err = pdb.Update(func(tx database.Tx) error {
+		bucket := tx.Metadata().Bucket([]byte("prefetch"))
+		if err := bucket.DeleteRange(nil, nil); err != nil {
+			return err
+		}
+		return checkBucketKeys(bucket, []string{"nested"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pdb.View(func(tx database.Tx) error {
+		bucket := tx.Metadata().Bucket([]byte("prefetch"))
+		return checkBucketKeys(bucket, []string{"nested"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCursorDirectionChange ensures cursors merging the key/value pairs of the
+// database with the pending ones of the transaction return the adjacent keys
+// when they change direction.
+func TestCursorDirectionChange(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(os.TempDir(), "ffldb-cursordirection")
+	defer os.RemoveAll(dbPath)
+	pdb := populatePrefetchTestDB(t, dbPath, 10)
+	defer pdb.Close()
+
+	err := pdb.Update(func(tx database.Tx) error {
+		bucket := tx.Metadata().Bucket([]byte("prefetch"))
+
+		// Interleave pending keys with the ones of the database.
+		for i := uint32(0); i < 10; i++ {
+			key := append(rangeTestKey(i), 0)
+			if err := bucket.Put(key, []byte{1}); err != nil {
+				return err
+			}
+		}
+
+		c := bucket.Cursor()
+		moves := []struct {
+			move func() bool
+			want []byte
+		}{
+			{move: func() bool { return c.Seek(rangeTestKey(5)) },
+				want: rangeTestKey(5)},
+			{move: c.Prev, want: append(rangeTestKey(4), 0)},
+			{move: c.Next, want: rangeTestKey(5)},
+			{move: c.Next, want: append(rangeTestKey(5), 0)},
+			{move: c.Prev, want: rangeTestKey(5)},
+			{move: c.Prev, want: append(rangeTestKey(4), 0)},
+			{move: c.Next, want: rangeTestKey(5)},
+		}
+		for i, m := range moves {
+			if !m.move() || string(c.Key()) != string(m.want) {
+				return fmt.Errorf("move %d: got key %x, want %x",
+					i, c.Key(), m.want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,307 @@
+package ffldb
+
+import (
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// prefetchEntry is a key/value pair read ahead by a prefetchIter.
+type prefetchEntry struct {
+	key   []byte
+	value []byte
+}
+
+// prefetchBatch is a batch of key/value pairs read ahead by a prefetchIter
+// along with whether the underlying iterator was exhausted.
+type prefetchBatch struct {
+	entries   []prefetchEntry
+	exhausted bool
+}
+
+// prefetchIter wraps a leveldb iterator to read the key/value pairs ahead of
+// its position in the background when it moves forward, so the blocks of the
+// underlying database holding them are loaded and decoded while the caller
+// processes the current ones.  The key/value pairs are read in batches of
+// about the configured size, and a batch is always read ahead of the current
+// one until the underlying iterator is exhausted.
+//
+// Moving backward stops the prefetching, and the underlying iterator is then
+// used directly until the iterator is positioned with First or Seek again.
+//
+// The underlying iterator is only accessed by the goroutine reading a batch
+// while it is running, which is waited for before accessing it otherwise.
+type prefetchIter struct {
+	iter      iterator.Iterator
+	batchSize int
+
+	// entries holds the batch of key/value pairs the iterator is
+	// positioned in, and pos the index of the current one.
+	entries []prefetchEntry
+	pos     int
+
+	// pending receives the batch following entries when it is being read
+	// in the background.  exhausted is set when there are no key/value
+	// pairs after entries and the pending batch.
+	pending   chan prefetchBatch
+	exhausted bool
+
+	// direct is set when the underlying iterator is used directly after
+	// moving backward.
+	direct bool
+}
+
+// Enforce prefetchIter implements the leveldb iterator.Iterator interface.
+var _ iterator.Iterator = (*prefetchIter)(nil)
+
+// newPrefetchIter returns a new iterator reading ahead the key/value pairs of
+// the passed iterator in batches of about the passed number of leveldb blocks.
+func newPrefetchIter(iter iterator.Iterator, blocks int) *prefetchIter {
+	return &prefetchIter{
+		iter:      iter,
+		batchSize: blocks * opt.DefaultBlockSize,
+	}
+}
+
+// readBatch reads a batch of key/value pairs from the current position of the
+// passed iterator, which is moved after the last one.
+func readBatch(iter iterator.Iterator, batchSize int) prefetchBatch {
+	var batch prefetchBatch
+	var size int
+	var buf []byte
+	for size < batchSize {
+		if !iter.Valid() {
+			batch.exhausted = true
+			break
+		}
+
+		// The key and value must be copied since the underlying
+		// iterator reuses its buffers.  They are copied to a buffer
+		// shared by the batch to avoid an allocation per key/value
+		// pair, which is replaced when it is full.
+		key, value := iter.Key(), iter.Value()
+		entrySize := len(key) + len(value)
+		if cap(buf)-len(buf) < entrySize {
+			bufSize := batchSize - size
+			if bufSize < entrySize {
+				bufSize = entrySize
+			}
+			buf = make([]byte, 0, bufSize)
+		}
+		buf = append(buf, key...)
+		buf = append(buf, value...)
+		entry := prefetchEntry{
+			key:   buf[len(buf)-entrySize : len(buf)-len(value) : len(buf)-len(value)],
+			value: buf[len(buf)-len(value) : len(buf) : len(buf)],
+		}
+		batch.entries = append(batch.entries, entry)
+		size += entrySize
+		iter.Next()
+	}
+	return batch
+}
+
+// wait waits for the batch being read in the background, if any, and returns
+// it.
+func (iter *prefetchIter) wait() (prefetchBatch, bool) {
+	if iter.pending == nil {
+		return prefetchBatch{}, false
+	}
+	batch := <-iter.pending
+	iter.pending = nil
+	return batch, true
+}
+
+// prefetch starts reading the batch following the current one in the
+// background unless the underlying iterator is exhausted.
+func (iter *prefetchIter) prefetch() {
+	if iter.exhausted {
+		return
+	}
+	pending := make(chan prefetchBatch, 1)
+	go func(dbIter iterator.Iterator, batchSize int) {
+		pending <- readBatch(dbIter, batchSize)
+	}(iter.iter, iter.batchSize)
+	iter.pending = pending
+}
+
+// start reads the first batch from the current position of the underlying
+// iterator, starts prefetching the following one and returns whether or not
+// the iterator is positioned at a valid key/value pair.
+func (iter *prefetchIter) start() bool {
+	iter.direct = false
+	batch := readBatch(iter.iter, iter.batchSize)
+	iter.entries = batch.entries
+	iter.pos = 0
+	iter.exhausted = batch.exhausted
+	iter.prefetch()
+	return iter.Valid()
+}
+
+// stop waits for the batch being read in the background, if any, and switches
+// to using the underlying iterator directly.  The underlying iterator is left
+// at an unspecified position.
+func (iter *prefetchIter) stop() {
+	iter.wait()
+	iter.entries = nil
+	iter.pos = 0
+	iter.exhausted = false
+	iter.direct = true
+}
+
+// First positions the iterator at the first key/value pair and returns whether
+// or not the pair exists.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) First() bool {
+	iter.wait()
+	iter.iter.First()
+	return iter.start()
+}
+
+// Last positions the iterator at the last key/value pair and returns whether or
+// not the pair exists.  The key/value pairs are no longer read ahead.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Last() bool {
+	iter.stop()
+	return iter.iter.Last()
+}
+
+// Next moves the iterator one key/value pair forward and returns whether or not
+// the pair exists.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Next() bool {
+	if iter.direct {
+		return iter.iter.Next()
+	}
+
+	// Nothing to return if the iterator is exhausted.
+	if !iter.Valid() {
+		return false
+	}
+
+	iter.pos++
+	if iter.pos < len(iter.entries) {
+		return true
+	}
+
+	// Move to the batch read in the background, if any, and start reading
+	// the following one.
+	batch, ok := iter.wait()
+	if !ok {
+		iter.entries = nil
+		iter.pos = 0
+		return false
+	}
+	iter.entries = batch.entries
+	iter.pos = 0
+	iter.exhausted = batch.exhausted
+	iter.prefetch()
+	return iter.Valid()
+}
+
+// Prev moves the iterator one key/value pair backward and returns whether or
+// not the pair exists.  The key/value pairs are no longer read ahead.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Prev() bool {
+	if iter.direct {
+		return iter.iter.Prev()
+	}
+
+	// Nothing to return if the iterator is exhausted.
+	if !iter.Valid() {
+		iter.stop()
+		return false
+	}
+
+	// Position the underlying iterator at the current key before moving
+	// it backward.
+	key := iter.entries[iter.pos].key
+	iter.stop()
+	iter.iter.Seek(key)
+	return iter.iter.Prev()
+}
+
+// Seek positions the iterator at the first key/value pair that is greater than
+// or equal to the passed seek key.  Returns false if no suitable key was found.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Seek(key []byte) bool {
+	iter.wait()
+	iter.iter.Seek(key)
+	return iter.start()
+}
+
+// Valid indicates whether the iterator is positioned at a valid key/value pair.
+// It will be considered invalid when the iterator is newly created or exhausted.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Valid() bool {
+	if iter.direct {
+		return iter.iter.Valid()
+	}
+	return iter.pos < len(iter.entries)
+}
+
+// Key returns the current key the iterator is pointing to.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Key() []byte {
+	if iter.direct {
+		return iter.iter.Key()
+	}
+
+	// Nothing to return if iterator is exhausted.
+	if !iter.Valid() {
+		return nil
+	}
+	return iter.entries[iter.pos].key
+}
+
+// Value returns the current value the iterator is pointing to.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Value() []byte {
+	if iter.direct {
+		return iter.iter.Value()
+	}
+
+	// Nothing to return if iterator is exhausted.
+	if !iter.Valid() {
+		return nil
+	}
+	return iter.entries[iter.pos].value
+}
+
+// SetReleaser is only provided to satisfy the iterator interface as there is no
+// need to override it.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) SetReleaser(releaser util.Releaser) {
+}
+
+// Release waits for the batch being read in the background, if any, and
+// releases the underlying iterator.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Release() {
+	iter.wait()
+	iter.entries = nil
+	iter.pos = 0
+	iter.iter.Release()
+}
+
+// Error returns any accumulated error of the underlying iterator.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *prefetchIter) Error() error {
+	if iter.pending != nil {
+		// The underlying iterator is in use and any error will be
+		// returned once its batch is reached.
+		return nil
+	}
+	return iter.iter.Error()
+}
@@ -0,0 +1,137 @@
+// This file is part of the ffldb package rather than the ffldb_test package as
+// it provides whitebox testing.
+
+package ffldb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbcd/database"
+)
+
+// populatePrefetchTestDB creates a database at the passed path holding the
+// passed number of key/value pairs in a bucket, which are flushed to the
+// underlying leveldb database, and returns it opened.
+func populatePrefetchTestDB(tb testing.TB, dbPath string, numKeys int) database.DB {
+	tb.Helper()
+
+	_ = os.RemoveAll(dbPath)
+	pdb, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		tb.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	err = pdb.Update(func(tx database.Tx) error {
+		bucket, err := tx.Metadata().CreateBucket([]byte("prefetch"))
+		if err != nil {
+			return err
+		}
+		if _, err := bucket.CreateBucket([]byte("nested")); err != nil {
+			return err
+		}
+		value := bytes.Repeat([]byte{0xaa}, 100)
+		for i := 0; i < numKeys; i++ {
+			var key [4]byte
+			binary.BigEndian.PutUint32(key[:], uint32(i))
+			if err := bucket.Put(key[:], value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	// Reopen the database so the key/value pairs are flushed from the
+	// cache to leveldb.
+	pdb.Close()
+	pdb, err = database.Open(dbType, dbPath, blockDataNet)
+	if err != nil {
+		tb.Fatalf("Failed to open test database (%s) %v", dbType, err)
+	}
+	return pdb
+}
+
+// cursorKeys returns the keys of the passed cursor from its first one moving
+// forward, and from its last one moving backward.
+func cursorKeys(c database.Cursor) ([]string, []string) {
+	var forward, backward []string
+	for ok := c.First(); ok; ok = c.Next() {
+		forward = append(forward, string(c.Key()))
+	}
+	for ok := c.Last(); ok; ok = c.Prev() {
+		backward = append(backward, string(c.Key()))
+	}
+	return forward, backward
+}
+
+// TestPrefetchCursor ensures the cursors reading ahead the key/value pairs of
+// the underlying database return the same key/value pairs as regular cursors,
+// including the pending changes of the transaction, in both directions.
+func TestPrefetchCursor(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(os.TempDir(), "ffldb-prefetchcursor")
+	defer os.RemoveAll(dbPath)
+	pdb := populatePrefetchTestDB(t, dbPath, 1000)
+	defer pdb.Close()
+
+	err := pdb.Update(func(tx database.Tx) error {
+		bucket := tx.Metadata().Bucket([]byte("prefetch"))
+
+		// Modify the bucket in the transaction so the pending changes
+		// are merged with the key/value pairs read ahead.
+		for _, i := range []uint32{0, 1, 500, 999} {
+			var key [4]byte
+			binary.BigEndian.PutUint32(key[:], i)
+			if err := bucket.Delete(key[:]); err != nil {
+				return err
+			}
+		}
+		if err := bucket.Put([]byte{0, 0, 1, 0, 0}, []byte{1}); err != nil {
+			return err
+		}
+
+		wantForward, wantBackward := cursorKeys(bucket.Cursor())
+		if len(wantForward) != 1000-4+1+1 {
+			return fmt.Errorf("unexpected number of keys %d",
+				len(wantForward))
+		}
+		for _, blocks := range []int{1, 4, 1000} {
+			c := bucket.PrefetchCursor(blocks)
+			forward, backward := cursorKeys(c)
+			if fmt.Sprint(forward) != fmt.Sprint(wantForward) {
+				return fmt.Errorf("PrefetchCursor(%d): wrong keys "+
+					"moving forward", blocks)
+			}
+			if fmt.Sprint(backward) != fmt.Sprint(wantBackward) {
+				return fmt.Errorf("PrefetchCursor(%d): wrong keys "+
+					"moving backward", blocks)
+			}
+
+			// Moving backward in the middle of a scan continues
+			// from the current key.
+			var key [4]byte
+			binary.BigEndian.PutUint32(key[:], 300)
+			if !c.Seek(key[:]) || !c.Next() || !c.Prev() ||
+				!bytes.Equal(c.Key(), key[:]) {
+
+				return fmt.Errorf("PrefetchCursor(%d): wrong key "+
+					"%x after moving backward", blocks, c.Key())
+			}
+			if !c.Prev() || c.Value() == nil {
+				return fmt.Errorf("PrefetchCursor(%d): no value "+
+					"after moving backward", blocks)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -113,5 +113,49 @@ func reconcileDB(pdb *db, create bool) (database.DB, error) {
 		return nil, makeDbErr(database.ErrCorruption, str, nil)
 	}
 
+	if err := reconcileFormatVersion(pdb); err != nil {
+		return nil, err
+	}
+
 	return pdb, nil
 }
+
+// reconcileFormatVersion ensures the block storage format of the database is
+// supported and records the current format version in the metadata when block
+// compression is enabled, since older versions can't read compressed blocks.
+func reconcileFormatVersion(pdb *db) error {
+	version := uint32(1)
+	err := pdb.View(func(tx database.Tx) error {
+		serialized := tx.Metadata().Get(formatVersionKeyName)
+		if serialized == nil {
+			return nil
+		}
+		if len(serialized) != 4 {
+			str := "malformed block storage format version"
+			return makeDbErr(database.ErrCorruption, str, nil)
+		}
+		version = byteOrder.Uint32(serialized)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if version > currentFormatVersion {
+		str := fmt.Sprintf("block storage format version %d is newer "+
+			"than the latest supported version %d", version,
+			currentFormatVersion)
+		return makeDbErr(database.ErrDriverSpecific, str, nil)
+	}
+	if !pdb.store.compress || version == currentFormatVersion {
+		return nil
+	}
+
+	log.Infof("Upgrading block storage format from version %d to %d",
+		version, currentFormatVersion)
+	return pdb.Update(func(tx database.Tx) error {
+		var serialized [4]byte
+		byteOrder.PutUint32(serialized[:], currentFormatVersion)
+		return tx.Metadata().Put(formatVersionKeyName, serialized[:])
+	})
+}
@@ -8,6 +8,7 @@
 package ffldb
 
 import (
+	"bytes"
 	"compress/bzip2"
 	"encoding/binary"
 	"fmt"
@@ -15,8 +16,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
 	"github.com/lbryio/lbcd/database"
 	"github.com/lbryio/lbcd/wire"
 	btcutil "github.com/lbryio/lbcutil"
@@ -179,7 +182,7 @@ func TestCornerCases(t *testing.T) {
 	// directory is needed.
 	testName := "openDB: fail due to file at target location"
 	wantErrCode := database.ErrDriverSpecific
-	idb, err := openDB(dbPath, blockDataNet, true)
+	idb, err := openDB(dbPath, blockDataNet, &Options{}, true)
 	if !checkDbError(t, testName, err, wantErrCode) {
 		if err == nil {
 			idb.Close()
@@ -191,7 +194,7 @@ func TestCornerCases(t *testing.T) {
 	// Remove the file and create the database to run tests against.  It
 	// should be successful this time.
 	_ = os.RemoveAll(dbPath)
-	idb, err = openDB(dbPath, blockDataNet, true)
+	idb, err = openDB(dbPath, blockDataNet, &Options{}, true)
 	if err != nil {
 		t.Errorf("openDB: unexpected error: %v", err)
 		return
@@ -452,7 +455,7 @@ func testBlockFileErrors(tc *testContext) bool {
 		return false
 	}
 	testName = "readBlockRegion invalid file number"
-	_, err = store.readBlockRegion(invalidLoc, 0, 80)
+	_, err = store.readBlockRegion(block0Hash, invalidLoc, 0, 80)
 	if !checkDbError(tc.t, testName, err, database.ErrDriverSpecific) {
 		return false
 	}
@@ -595,6 +598,45 @@ func testCorruption(tc *testContext) bool {
 		return false
 	}
 
+	// Ensure a corrupt block can be repaired by replacing it and that
+	// only stored blocks can be replaced.
+	tc.files[0].file.(*mockFile).data[90] ^= 0x10
+	err = tc.db.Update(func(tx database.Tx) error {
+		err := tx.ReplaceBlock(tc.blocks[1])
+		if !checkDbError(tc.t, "ReplaceBlock: missing block", err,
+			database.ErrBlockNotFound) {
+
+			return errSubTestFail
+		}
+		return tx.ReplaceBlock(tc.blocks[0])
+	})
+	if err != nil {
+		if err != errSubTestFail {
+			tc.t.Errorf("Update: unexpected error: %v", err)
+		}
+		return false
+	}
+	err = tc.db.View(func(tx database.Tx) error {
+		gotBytes, err := tx.FetchBlock(block0Hash)
+		if err != nil {
+			tc.t.Errorf("FetchBlock: unexpected error after "+
+				"ReplaceBlock: %v", err)
+			return errSubTestFail
+		}
+		if !bytes.Equal(gotBytes, block0Bytes) {
+			tc.t.Errorf("FetchBlock: bytes mismatch after " +
+				"ReplaceBlock")
+			return errSubTestFail
+		}
+		return nil
+	})
+	if err != nil {
+		if err != errSubTestFail {
+			tc.t.Errorf("View: unexpected error: %v", err)
+		}
+		return false
+	}
+
 	return true
 }
 
@@ -704,3 +746,320 @@ func TestFailureScenarios(t *testing.T) {
 	// Test various corruption scenarios.
 	testCorruption(tc)
 }
+
+// TestPruneBlocks ensures that pruning the block storage deletes the oldest
+// block files, removes the associated blocks from the block index, and never
+// deletes the current write file.
+func TestPruneBlocks(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(os.TempDir(), "ffldb-pruneblocks")
+	_ = os.RemoveAll(dbPath)
+	idb, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer idb.Close()
+
+	// Change the maximum file size to a small value to force multiple flat
+	// files with the test data set.
+	const maxFileSize = 1024 // 1KiB
+	store := idb.(*db).store
+	store.maxBlockFileSize = maxFileSize
+
+	blocks, err := loadBlocks(t, blockDataFile, blockDataNet)
+	if err != nil {
+		t.Fatalf("loadBlocks: Unexpected error: %v", err)
+	}
+	err = idb.Update(func(tx database.Tx) error {
+		for _, block := range blocks {
+			if err := tx.StoreBlock(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StoreBlock: Unexpected error: %v", err)
+	}
+
+	// Nothing should have been pruned yet.
+	err = idb.View(func(tx database.Tx) error {
+		pruned, err := tx.BeenPruned()
+		if err != nil {
+			return err
+		}
+		if pruned {
+			return fmt.Errorf("BeenPruned: unexpected pruned db")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Attempting to prune to a target smaller than a single block file
+	// must fail.
+	err = idb.Update(func(tx database.Tx) error {
+		_, err := tx.PruneBlocks(maxFileSize - 1)
+		return err
+	})
+	if !checkDbError(t, "PruneBlocks: target too small", err,
+		database.ErrDriverSpecific) {
+
+		return
+	}
+
+	// Prune down to two block files worth of data.
+	var prunedHashes []chainhash.Hash
+	err = idb.Update(func(tx database.Tx) error {
+		var err error
+		prunedHashes, err = tx.PruneBlocks(maxFileSize * 2)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("PruneBlocks: Unexpected error: %v", err)
+	}
+	if len(prunedHashes) == 0 {
+		t.Fatal("PruneBlocks: no blocks were pruned")
+	}
+
+	// The oldest blocks are the ones pruned.
+	for i := range prunedHashes {
+		if prunedHashes[i] != *blocks[i].Hash() {
+			t.Fatalf("PruneBlocks: pruned block #%d is %v, want %v",
+				i, prunedHashes[i], blocks[i].Hash())
+		}
+	}
+
+	// The first file must be gone while the write file remains, and the
+	// range of the block files must be tracked without searching for them.
+	first, last, _ := scanBlockFiles(dbPath)
+	if first == 0 {
+		t.Fatal("scanBlockFiles: first block file was not deleted")
+	}
+	if uint32(last) != store.writeCursor.curFileNum {
+		t.Fatalf("scanBlockFiles: last file %d is not the write file %d",
+			last, store.writeCursor.curFileNum)
+	}
+	if uint32(first) != store.writeCursor.firstFileNum {
+		t.Fatalf("scanBlockFiles: first file %d is not the tracked "+
+			"first file %d", first, store.writeCursor.firstFileNum)
+	}
+
+	// Nothing is left to prune for the same target.
+	err = idb.Update(func(tx database.Tx) error {
+		hashes, err := tx.PruneBlocks(maxFileSize * 2)
+		if err != nil {
+			return err
+		}
+		if len(hashes) != 0 {
+			return fmt.Errorf("PruneBlocks: pruned %d more blocks",
+				len(hashes))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = idb.View(func(tx database.Tx) error {
+		pruned, err := tx.BeenPruned()
+		if err != nil {
+			return err
+		}
+		if !pruned {
+			return fmt.Errorf("BeenPruned: db not reported as pruned")
+		}
+
+		// Pruned blocks must no longer be reported as available while
+		// the most recent block must still be.
+		for i := range prunedHashes {
+			hash := &prunedHashes[i]
+			if has, _ := tx.HasBlock(hash); has {
+				return fmt.Errorf("HasBlock: pruned block %v "+
+					"still available", hash)
+			}
+		}
+		tip := blocks[len(blocks)-1].Hash()
+		if _, err := tx.FetchBlock(tip); err != nil {
+			return fmt.Errorf("FetchBlock: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBlockCompression ensures blocks stored with compression enabled are
+// transparently decompressed, including when fetching regions, that blocks
+// stored with and without compression can be read regardless of the setting,
+// and that the format version is only recorded once compression is enabled.
+func TestBlockCompression(t *testing.T) {
+	t.Parallel()
+
+	blocks, err := loadBlocks(t, blockDataFile, blockDataNet)
+	if err != nil {
+		t.Fatalf("loadBlocks: Unexpected error: %v", err)
+	}
+
+	// The blocks of the test data are too small to benefit from
+	// compression, so create a compressible block by repeating the
+	// coinbase transaction of one of them.
+	msgBlock := *blocks[1].MsgBlock()
+	msgBlock.Transactions = nil
+	for i := 0; i < 50; i++ {
+		msgBlock.AddTransaction(blocks[1].MsgBlock().Transactions[0])
+	}
+	msgBlock.Header.Nonce++
+	bigBlock := btcutil.NewBlock(&msgBlock)
+	bigBlockBytes, err := bigBlock.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: Unexpected error: %v", err)
+	}
+
+	dbPath := filepath.Join(os.TempDir(), "ffldb-blockcompression")
+	_ = os.RemoveAll(dbPath)
+	defer os.RemoveAll(dbPath)
+
+	openTestDB := func(compress bool) *db {
+		t.Helper()
+		idb, err := database.Create(dbType, dbPath, blockDataNet,
+			&Options{CompressBlocks: compress})
+		if err != nil {
+			idb, err = database.Open(dbType, dbPath, blockDataNet,
+				&Options{CompressBlocks: compress})
+		}
+		if err != nil {
+			t.Fatalf("Failed to open test database (%s) %v", dbType,
+				err)
+		}
+		return idb.(*db)
+	}
+	fetchVersion := func(pdb *db) []byte {
+		t.Helper()
+		var version []byte
+		err := pdb.View(func(tx database.Tx) error {
+			version = tx.Metadata().Get(formatVersionKeyName)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("View: Unexpected error: %v", err)
+		}
+		return version
+	}
+	storeBlocks := func(pdb *db, blocks ...*btcutil.Block) {
+		t.Helper()
+		err := pdb.Update(func(tx database.Tx) error {
+			for _, block := range blocks {
+				if err := tx.StoreBlock(block); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("StoreBlock: Unexpected error: %v", err)
+		}
+	}
+
+	// Store a block without compression, which must not record the format
+	// version.
+	pdb := openTestDB(false)
+	storeBlocks(pdb, blocks[0])
+	if version := fetchVersion(pdb); version != nil {
+		t.Fatalf("format version %x recorded without compression",
+			version)
+	}
+	pdb.Close()
+
+	// Store the compressible block with compression enabled.
+	pdb = openTestDB(true)
+	if version := fetchVersion(pdb); byteOrder.Uint32(version) !=
+		currentFormatVersion {
+
+		t.Fatalf("wrong format version %x", version)
+	}
+	storeBlocks(pdb, bigBlock, blocks[2])
+	err = pdb.View(func(tx database.Tx) error {
+		blockRow, err := tx.(*transaction).fetchBlockRow(bigBlock.Hash())
+		if err != nil {
+			return err
+		}
+		loc := deserializeBlockLoc(blockRow)
+		if loc.rawLen != uint32(len(bigBlockBytes)) {
+			return fmt.Errorf("wrong uncompressed length %d, want %d",
+				loc.rawLen, len(bigBlockBytes))
+		}
+		if loc.blockLen >= loc.rawLen {
+			return fmt.Errorf("block was not compressed - %d bytes "+
+				"stored for %d bytes", loc.blockLen, loc.rawLen)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdb.Close()
+
+	// All of the blocks must be readable once compression is disabled
+	// again, including regions of the compressed block.
+	pdb = openTestDB(false)
+	defer pdb.Close()
+	err = pdb.View(func(tx database.Tx) error {
+		for _, block := range []*btcutil.Block{blocks[0], bigBlock, blocks[2]} {
+			wantBytes, err := block.Bytes()
+			if err != nil {
+				return err
+			}
+			gotBytes, err := tx.FetchBlock(block.Hash())
+			if err != nil {
+				return fmt.Errorf("FetchBlock: %v", err)
+			}
+			if !bytes.Equal(gotBytes, wantBytes) {
+				return fmt.Errorf("FetchBlock: wrong bytes for "+
+					"block %v", block.Hash())
+			}
+		}
+
+		region := database.BlockRegion{
+			Hash:   bigBlock.Hash(),
+			Offset: uint32(len(bigBlockBytes)) - 10,
+			Len:    10,
+		}
+		regionBytes, err := tx.FetchBlockRegion(&region)
+		if err != nil {
+			return fmt.Errorf("FetchBlockRegion: %v", err)
+		}
+		if !bytes.Equal(regionBytes, bigBlockBytes[region.Offset:]) {
+			return fmt.Errorf("FetchBlockRegion: wrong bytes")
+		}
+
+		// Regions are bounds checked against the uncompressed length.
+		region.Offset++
+		_, err = tx.FetchBlockRegions([]database.BlockRegion{region})
+		if !checkDbError(t, "FetchBlockRegions", err,
+			database.ErrBlockRegionInvalid) {
+
+			return errSubTestFail
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The hashes of the blocks in the block file are read from their
+	// headers whether they are compressed or not.
+	hashes, err := pdb.store.blockFileHashes(0)
+	if err != nil {
+		t.Fatalf("blockFileHashes: Unexpected error: %v", err)
+	}
+	wantHashes := []chainhash.Hash{*blocks[0].Hash(), *bigBlock.Hash(),
+		*blocks[2].Hash()}
+	if !reflect.DeepEqual(hashes, wantHashes) {
+		t.Fatalf("blockFileHashes: got %v, want %v", hashes, wantHashes)
+	}
+}
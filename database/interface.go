@@ -8,6 +8,8 @@
 package database
 
 import (
+	"io"
+
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
 	btcutil "github.com/lbryio/lbcutil"
 )
@@ -153,6 +155,15 @@ type Bucket interface {
 	// Value functions.
 	Cursor() Cursor
 
+	// PrefetchCursor returns a new cursor like Cursor which reads ahead
+	// the key/value pairs stored in about the passed number of blocks of
+	// the underlying database in the background while it moves forward.
+	// This speeds up long scans of buckets, such as the ones made by
+	// indexers, at the cost of the memory holding the key/value pairs read
+	// ahead.  The cursor behaves like the ones returned by Cursor when
+	// moving backward.
+	PrefetchCursor(blocks int) Cursor
+
 	// Writable returns whether or not the bucket is writable.
 	Writable() bool
 
@@ -193,6 +204,22 @@ type Bucket interface {
 	//   - ErrTxNotWritable if attempted against a read-only transaction
 	//   - ErrTxClosed if the transaction has already been closed
 	Delete(key []byte) error
+
+	// DeleteRange removes the key/value pairs of the bucket whose keys are
+	// greater than or equal to the start key and less than the end key.  A
+	// nil start key starts the range at the first key of the bucket and a
+	// nil end key ends it after the last one.  Nested buckets are not
+	// affected.  Deleting an empty range does not return an error.
+	//
+	// Implementations are expected to delete the keys without reading them
+	// one by one when possible, which makes this much cheaper than Delete
+	// for large sets of keys, such as when dropping indexes.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrTxNotWritable if attempted against a read-only transaction
+	//   - ErrTxClosed if the transaction has already been closed
+	DeleteRange(start, end []byte) error
 }
 
 // BlockRegion specifies a particular region of a block identified by the
@@ -229,6 +256,20 @@ type Tx interface {
 	// Other errors are possible depending on the implementation.
 	StoreBlock(block *btcutil.Block) error
 
+	// ReplaceBlock stores the provided block in place of the stored block
+	// with the same hash.  It is intended to repair blocks whose stored
+	// data is corrupt with a copy obtained elsewhere.  The previously
+	// stored data is not reclaimed until its block file is pruned.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrBlockNotFound if the block hash does not exist
+	//   - ErrTxNotWritable if attempted against a read-only transaction
+	//   - ErrTxClosed if the transaction has already been closed
+	//
+	// Other errors are possible depending on the implementation.
+	ReplaceBlock(block *btcutil.Block) error
+
 	// HasBlock returns whether or not a block with the given hash exists
 	// in the database.
 	//
@@ -389,6 +430,26 @@ type Tx interface {
 	// implementations.
 	FetchBlockRegions(regions []BlockRegion) ([][]byte, error)
 
+	// PruneBlocks deletes the oldest block files until the total size of
+	// the block storage is at or below the provided target size in bytes
+	// and returns the hashes of all blocks that were removed.  The most
+	// recent block file is never deleted.  The files are only removed once
+	// the transaction is successfully committed.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrTxNotWritable if attempted against a read-only transaction
+	//   - ErrTxClosed if the transaction has already been closed
+	PruneBlocks(targetSize uint64) ([]chainhash.Hash, error)
+
+	// BeenPruned returns whether or not block files have ever been pruned
+	// from the block storage.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrTxClosed if the transaction has already been closed
+	BeenPruned() (bool, error)
+
 	// ******************************************************************
 	// Methods related to both atomic metadata storage and block storage.
 	// ******************************************************************
@@ -459,6 +520,19 @@ type DB interface {
 	// user-supplied function will result in a panic.
 	Update(fn func(tx Tx) error) error
 
+	// Backup writes a consistent snapshot of the entire database, both the
+	// metadata and the blocks, to the passed writer in a format specific to
+	// the backend.  The database remains usable while the backup is being
+	// written, and the changes made meanwhile aren't part of the backup.
+	Backup(w io.Writer) error
+
+	// OnFlush registers the passed function to be called each time the
+	// database syncs its data to persistent storage, before the metadata
+	// is written.  It allows the files stored outside of the database
+	// which the metadata refers to be synced along with the database
+	// rather than on every update.
+	OnFlush(fn func() error)
+
 	// Close cleanly shuts down the database and syncs all data.  It will
 	// block until all database transactions have been finalized (rolled
 	// back or committed).
@@ -0,0 +1,26 @@
+package treap
+
+// ArenaStats reports the memory usage of the arena the nodes of mutable treaps
+// are allocated from with the treap_arena build tag.
+type ArenaStats struct {
+	// Slabs is the number of slabs of nodes allocated by the arena and
+	// SlabBytes the number of bytes they take.  The memory of a slab is
+	// only reclaimed once none of its nodes are referenced anymore.
+	Slabs     uint64
+	SlabBytes uint64
+
+	// InUse is the number of nodes held by mutable treaps.
+	InUse int
+
+	// Free is the number of released nodes kept for reuse.
+	Free int
+
+	// Dropped is the number of nodes removed from mutable treaps by Delete
+	// or Reset, which are left to the garbage collector rather than
+	// released for reuse.
+	Dropped uint64
+
+	// Discarded is the number of released nodes left to the garbage
+	// collector because the free list was full.
+	Discarded uint64
+}
@@ -0,0 +1,120 @@
+package treap
+
+import (
+	"testing"
+)
+
+// benchmarkKeys returns the passed number of serialized keys.
+func benchmarkKeys(numKeys int) [][]byte {
+	keys := make([][]byte, 0, numKeys)
+	for i := 0; i < numKeys; i++ {
+		keys = append(keys, serializeUint32(uint32(i)))
+	}
+	return keys
+}
+
+// BenchmarkMutablePutRecycle benchmarks filling a mutable treap and recycling
+// it afterwards as done for the pending keys of database transactions.  Run it
+// with and without the treap_arena build tag to compare the allocation counts.
+func BenchmarkMutablePutRecycle(b *testing.B) {
+	keys := benchmarkKeys(10000)
+	testTreap := NewMutable()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			testTreap.Put(key, key)
+		}
+		testTreap.Recycle()
+	}
+}
+
+// BenchmarkMutablePutReset benchmarks filling a mutable treap and discarding
+// its nodes to the garbage collector afterwards.
+func BenchmarkMutablePutReset(b *testing.B) {
+	keys := benchmarkKeys(10000)
+	testTreap := NewMutable()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			testTreap.Put(key, key)
+		}
+		testTreap.Reset()
+	}
+}
+
+// BenchmarkImmutablePut benchmarks building an immutable treap by inserting
+// sorted keys one by one.
+func BenchmarkImmutablePut(b *testing.B) {
+	keys := benchmarkKeys(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		testTreap := NewImmutable()
+		for _, key := range keys {
+			testTreap = testTreap.Put(key, key)
+		}
+	}
+}
+
+// BenchmarkNewFromSorted benchmarks bulk loading an immutable treap from
+// sorted keys.
+func BenchmarkNewFromSorted(b *testing.B) {
+	keys := benchmarkKeys(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := NewFromSorted(newSliceIterator(keys, keys))
+		if err != nil {
+			b.Fatalf("NewFromSorted: unexpected error: %v", err)
+		}
+	}
+}
+
+// benchmarkMergeTreaps returns an immutable treap of 100000 keys and one of
+// 1000 keys spread among them to merge into it.
+func benchmarkMergeTreaps(b *testing.B) (*Immutable, *Immutable) {
+	keys := benchmarkKeys(100000)
+	base, err := NewFromSorted(newSliceIterator(keys, keys))
+	if err != nil {
+		b.Fatalf("NewFromSorted: unexpected error: %v", err)
+	}
+	pending := NewImmutable()
+	for i := 0; i < len(keys); i += 100 {
+		pending = pending.Put(keys[i], keys[i])
+	}
+	return base, pending
+}
+
+// BenchmarkImmutableMergePut benchmarks merging a treap into a larger one by
+// inserting its keys one by one.
+func BenchmarkImmutableMergePut(b *testing.B) {
+	base, pending := benchmarkMergeTreaps(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		merged := base
+		pending.ForEach(func(k, v []byte) bool {
+			merged = merged.Put(k, v)
+			return true
+		})
+	}
+}
+
+// BenchmarkImmutableUnion benchmarks merging a treap into a larger one with
+// Union.
+func BenchmarkImmutableUnion(b *testing.B) {
+	base, pending := benchmarkMergeTreaps(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		base.Union(pending)
+	}
+}
@@ -23,5 +23,22 @@ mutations.  All readers can simply use their existing pointer as a snapshot
 since the treap it points to is immutable.  This effectively provides O(1)
 snapshot capability with efficient memory usage characteristics since the old
 nodes only remain allocated until there are no longer any references to them.
+Likewise, an iterator created from an immutable treap walks exactly the version
+it was created from, no matter how many newer versions are derived from it or
+how mutable treaps are modified concurrently.
+
+When built with the treap_arena build tag, the nodes of mutable treaps are
+allocated from an arena of preallocated nodes instead of individually, and the
+Recycle method returns them to the arena for reuse.  This reduces the pressure
+on the garbage collector when large mutable treaps are repeatedly filled and
+discarded, such as during the initial block download.  Since the nodes of the
+immutable variant are shared between versions, they are always left to the
+garbage collector.  ReadArenaStats reports the memory usage of the arena,
+including the nodes of mutable treaps which were deleted or reset rather than
+recycled and are therefore left to the garbage collector as well.
+
+An immutable treap can also be bulk loaded from key/value pairs which are
+already sorted, such as the contents of a database, with NewFromSorted.  This
+builds the treap in linear time instead of inserting the pairs one by one.
 */
 package treap
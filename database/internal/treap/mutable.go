@@ -113,7 +113,7 @@ func (t *Mutable) Put(key, value []byte) {
 
 	// The node is the root of the tree if there isn't already one.
 	if t.root == nil {
-		node := newTreapNode(key, value, rand.Int())
+		node := allocTreapNode(key, value, rand.Int())
 		t.count = 1
 		t.totalSize = nodeSize(node)
 		t.root = node
@@ -145,7 +145,7 @@ func (t *Mutable) Put(key, value []byte) {
 	}
 
 	// Link the new node into the binary tree in the correct position.
-	node := newTreapNode(key, value, rand.Int())
+	node := allocTreapNode(key, value, rand.Int())
 	t.count++
 	t.totalSize += nodeSize(node)
 	parent := parents.At(0)
@@ -187,6 +187,7 @@ func (t *Mutable) Delete(key []byte) {
 	// When the only node in the tree is the root node and it is the one
 	// being deleted, there is nothing else to do besides removing it.
 	if parent == nil && node.left == nil && node.right == nil {
+		dropTreapNodes(node, 1)
 		t.root = nil
 		t.count = 0
 		t.totalSize = 0
@@ -236,6 +237,7 @@ func (t *Mutable) Delete(key []byte) {
 	} else {
 		parent.left = nil
 	}
+	dropTreapNodes(node, 1)
 	t.count--
 	t.totalSize -= nodeSize(node)
 }
@@ -264,8 +266,22 @@ func (t *Mutable) ForEach(fn func(k, v []byte) bool) {
 	}
 }
 
-// Reset efficiently removes all items in the treap.
+// Reset efficiently removes all items in the treap.  Its nodes are left to the
+// garbage collector.
 func (t *Mutable) Reset() {
+	dropTreapNodes(t.root, t.count)
+	t.count = 0
+	t.totalSize = 0
+	t.root = nil
+}
+
+// Recycle removes all items in the treap like Reset and releases its nodes for
+// reuse by other mutable treaps when built with the treap_arena build tag.
+//
+// NOTE: The nodes are zeroed when released, so no iterators on the treap may
+// be used after calling this function.
+func (t *Mutable) Recycle() {
+	freeTreapNodes(t.root)
 	t.count = 0
 	t.totalSize = 0
 	t.root = nil
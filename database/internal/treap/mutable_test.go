@@ -463,3 +463,54 @@ func TestMutableForEachStopIterator(t *testing.T) {
 			numIterated, numItems/2)
 	}
 }
+
+// TestMutableRecycle ensures that recycling an existing mutable treap empties
+// it and that it, as well as other treaps, can be filled again afterwards.
+func TestMutableRecycle(t *testing.T) {
+	t.Parallel()
+
+	// Insert a few keys.
+	numItems := 10
+	testTreap := NewMutable()
+	for i := 0; i < numItems; i++ {
+		key := serializeUint32(uint32(i))
+		testTreap.Put(key, key)
+	}
+
+	// Recycle it and ensure the treap is empty.
+	testTreap.Recycle()
+	if gotLen := testTreap.Len(); gotLen != 0 {
+		t.Fatalf("Len: unexpected length - got %d, want %d", gotLen, 0)
+	}
+	if gotSize := testTreap.Size(); gotSize != 0 {
+		t.Fatalf("Size: unexpected byte size - got %d, want 0",
+			gotSize)
+	}
+	if testTreap.Has(serializeUint32(0)) {
+		t.Fatal("Has: unexpected result - got true, want false")
+	}
+
+	// Ensure recycling an empty treap works.
+	testTreap.Recycle()
+
+	// Ensure both the recycled treap and a new one, which may reuse the
+	// released nodes, hold independent keys.
+	otherTreap := NewMutable()
+	for i := 0; i < numItems; i++ {
+		key := serializeUint32(uint32(i))
+		testTreap.Put(key, key)
+		otherTreap.Put(key, serializeUint32(uint32(i+numItems)))
+	}
+	for i := 0; i < numItems; i++ {
+		key := serializeUint32(uint32(i))
+		if gotVal := testTreap.Get(key); !bytes.Equal(gotVal, key) {
+			t.Fatalf("Get #%d: unexpected value - got %x, want %x",
+				i, gotVal, key)
+		}
+		want := serializeUint32(uint32(i + numItems))
+		if gotVal := otherTreap.Get(key); !bytes.Equal(gotVal, want) {
+			t.Fatalf("Get #%d: unexpected value - got %x, want %x",
+				i, gotVal, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !treap_arena
+// +build !treap_arena
+
+package treap
+
+// allocTreapNode returns a new node for a mutable treap from the given key,
+// value, and priority.  Without the treap_arena build tag, nodes are simply
+// allocated on the heap and left to the garbage collector.
+func allocTreapNode(key, value []byte, priority int) *treapNode {
+	return newTreapNode(key, value, priority)
+}
+
+// freeTreapNodes releases the passed node of a mutable treap and all of its
+// children once they are no longer referenced.  It is a no-op without the
+// treap_arena build tag.
+func freeTreapNodes(root *treapNode) {}
+
+// dropTreapNodes notes that the passed node of a mutable treap and its
+// descendants, count nodes in all, are left to the garbage collector.  It is a
+// no-op without the treap_arena build tag.
+func dropTreapNodes(root *treapNode, count int) {}
+
+// ReadArenaStats returns the memory usage of the arena the nodes of mutable
+// treaps are allocated from, which is always empty without the treap_arena
+// build tag.
+func ReadArenaStats() ArenaStats {
+	return ArenaStats{}
+}
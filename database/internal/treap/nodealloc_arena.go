@@ -0,0 +1,189 @@
+//go:build treap_arena
+// +build treap_arena
+
+package treap
+
+import "sync"
+
+const (
+	// arenaSlabSize is the number of nodes allocated at once when the free
+	// list of the arena is empty.
+	arenaSlabSize = 4096
+
+	// maxArenaFreeNodes is the maximum number of released nodes kept for
+	// reuse.  Nodes released beyond it are left to the garbage collector
+	// so a single large treap doesn't pin its memory forever.
+	maxArenaFreeNodes = 1 << 20
+)
+
+// nodeArena hands out mutable treap nodes from slabs of contiguous nodes and
+// keeps the released ones on a free list for reuse.  This greatly reduces the
+// number of allocations, and therefore the pressure on the garbage collector,
+// when large mutable treaps are repeatedly filled and discarded such as the
+// pending keys of the database transactions during the initial block download.
+type nodeArena struct {
+	mtx  sync.Mutex
+	slab []treapNode
+	free []*treapNode
+
+	// The counters of the memory usage reported by ReadArenaStats.
+	slabs     uint64
+	inUse     int
+	dropped   uint64
+	discarded uint64
+
+	// live holds the nodes handed out and neither released nor dropped
+	// since when it is set, which only tests do to detect nodes which are
+	// lost or released more than once.
+	live map[*treapNode]struct{}
+}
+
+// alloc returns an unused zeroed node from the arena.
+func (a *nodeArena) alloc() *treapNode {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	var node *treapNode
+	if n := len(a.free); n > 0 {
+		node = a.free[n-1]
+		a.free[n-1] = nil
+		a.free = a.free[:n-1]
+	} else {
+		if len(a.slab) == 0 {
+			a.slab = make([]treapNode, arenaSlabSize)
+			a.slabs++
+		}
+		node = &a.slab[0]
+		a.slab = a.slab[1:]
+	}
+
+	a.inUse++
+	if a.live != nil {
+		a.live[node] = struct{}{}
+	}
+	return node
+}
+
+// untrack removes the passed node from the live nodes when they are tracked.
+// It panics when the node isn't live, which means it was either not handed out
+// by the arena or already released or dropped.
+//
+// This function MUST be called with the arena lock held.
+func (a *nodeArena) untrack(node *treapNode) {
+	if a.live == nil {
+		return
+	}
+	if _, ok := a.live[node]; !ok {
+		panic("treap: node released or dropped without being live")
+	}
+	delete(a.live, node)
+}
+
+// releaseTree returns the passed node and all of its children to the arena.
+// The nodes must not be referenced anymore.
+func (a *nodeArena) releaseTree(root *treapNode) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	var parents parentStack
+	if root != nil {
+		parents.Push(root)
+	}
+	for parents.Len() > 0 {
+		node := parents.Pop()
+		if node.left != nil {
+			parents.Push(node.left)
+		}
+		if node.right != nil {
+			parents.Push(node.right)
+		}
+
+		// Clear the node so it doesn't keep the key, value, and
+		// children alive while on the free list.
+		a.untrack(node)
+		a.inUse--
+		*node = treapNode{}
+		if len(a.free) < maxArenaFreeNodes {
+			a.free = append(a.free, node)
+		} else {
+			a.discarded++
+		}
+	}
+}
+
+// dropTree notes that the passed node and its descendants, count nodes in all,
+// are left to the garbage collector.
+func (a *nodeArena) dropTree(root *treapNode, count int) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.inUse -= count
+	a.dropped += uint64(count)
+	if a.live == nil {
+		return
+	}
+
+	// Only walk the tree when the live nodes are tracked so dropping
+	// remains constant time otherwise.
+	var parents parentStack
+	if root != nil {
+		parents.Push(root)
+	}
+	for parents.Len() > 0 {
+		node := parents.Pop()
+		if node.left != nil {
+			parents.Push(node.left)
+		}
+		if node.right != nil {
+			parents.Push(node.right)
+		}
+		a.untrack(node)
+	}
+}
+
+// stats returns the memory usage of the arena.
+func (a *nodeArena) stats() ArenaStats {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	return ArenaStats{
+		Slabs:     a.slabs,
+		SlabBytes: a.slabs * arenaSlabSize * nodeFieldsSize,
+		InUse:     a.inUse,
+		Free:      len(a.free),
+		Dropped:   a.dropped,
+		Discarded: a.discarded,
+	}
+}
+
+// arena is the arena the nodes of all mutable treaps are allocated from.
+var arena nodeArena
+
+// allocTreapNode returns a new node for a mutable treap from the given key,
+// value, and priority.  The node is allocated from the arena.
+func allocTreapNode(key, value []byte, priority int) *treapNode {
+	node := arena.alloc()
+	node.key = key
+	node.value = value
+	node.priority = priority
+	return node
+}
+
+// freeTreapNodes returns the passed node of a mutable treap and all of its
+// children to the arena once they are no longer referenced.
+func freeTreapNodes(root *treapNode) {
+	arena.releaseTree(root)
+}
+
+// dropTreapNodes notes that the passed node of a mutable treap and its
+// descendants, count nodes in all, are left to the garbage collector rather
+// than returned to the arena.
+func dropTreapNodes(root *treapNode, count int) {
+	arena.dropTree(root, count)
+}
+
+// ReadArenaStats returns the memory usage of the arena the nodes of mutable
+// treaps are allocated from.
+func ReadArenaStats() ArenaStats {
+	return arena.stats()
+}
@@ -0,0 +1,163 @@
+//go:build treap_arena
+// +build treap_arena
+
+package treap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// trackArenaNodes starts tracking the nodes handed out by the arena so nodes
+// released or dropped more than once panic, and returns a function stopping
+// the tracking.  Tests tracking the nodes must not run in parallel with other
+// tests using mutable treaps.
+func trackArenaNodes() func() {
+	arena.mtx.Lock()
+	arena.live = make(map[*treapNode]struct{})
+	arena.mtx.Unlock()
+
+	return func() {
+		arena.mtx.Lock()
+		arena.live = nil
+		arena.mtx.Unlock()
+	}
+}
+
+// checkArenaNodes ensures the nodes handed out by the arena since the tracking
+// started are exactly the nodes of the passed mutable treaps, meaning no node
+// was lost without being released or dropped, and no node of the treaps was
+// released for reuse while still in use.
+func checkArenaNodes(t *testing.T, treaps ...*Mutable) {
+	t.Helper()
+
+	arena.mtx.Lock()
+	defer arena.mtx.Unlock()
+
+	reachable := make(map[*treapNode]struct{})
+	for i, treap := range treaps {
+		var parents parentStack
+		if treap.root != nil {
+			parents.Push(treap.root)
+		}
+		for parents.Len() > 0 {
+			node := parents.Pop()
+			if node.left != nil {
+				parents.Push(node.left)
+			}
+			if node.right != nil {
+				parents.Push(node.right)
+			}
+			if _, ok := arena.live[node]; !ok {
+				t.Fatalf("treap #%d holds node %p which was "+
+					"released", i, node)
+			}
+			reachable[node] = struct{}{}
+		}
+	}
+
+	for node := range arena.live {
+		if _, ok := reachable[node]; !ok {
+			t.Fatalf("node %p was lost without being released "+
+				"or dropped", node)
+		}
+	}
+}
+
+// TestArenaLeaks ensures every node handed out by the arena to mutable treaps
+// is either held by a treap or was returned to the arena or dropped by a random
+// sequence of operations.
+func TestArenaLeaks(t *testing.T) {
+	defer trackArenaNodes()()
+
+	const numTreaps = 4
+	const numKeys = 200
+	rng := rand.New(rand.NewSource(1))
+	treaps := make([]*Mutable, numTreaps)
+	for i := range treaps {
+		treaps[i] = NewMutable()
+	}
+
+	for i := 0; i < 20000; i++ {
+		treap := treaps[rng.Intn(numTreaps)]
+		key := serializeUint32(uint32(rng.Intn(numKeys)))
+		switch op := rng.Intn(100); {
+		case op < 60:
+			treap.Put(key, key)
+		case op < 97:
+			treap.Delete(key)
+		case op < 98:
+			treap.Reset()
+		default:
+			treap.Recycle()
+		}
+
+		if i%1000 == 0 {
+			checkArenaNodes(t, treaps...)
+		}
+	}
+	checkArenaNodes(t, treaps...)
+
+	for _, treap := range treaps {
+		treap.Recycle()
+	}
+	checkArenaNodes(t)
+}
+
+// TestArenaDoubleRelease ensures releasing the nodes of a treap twice, which
+// would hand the same node out twice, is detected while tracking.
+func TestArenaDoubleRelease(t *testing.T) {
+	defer trackArenaNodes()()
+
+	testTreap := NewMutable()
+	testTreap.Put(serializeUint32(0), nil)
+	root := testTreap.root
+	testTreap.Recycle()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("freeTreapNodes: released node twice without " +
+				"panicking")
+		}
+	}()
+	freeTreapNodes(root)
+}
+
+// TestArenaStats ensures the memory usage of the arena accounts for the nodes
+// of mutable treaps as they are added, recycled, and dropped.
+func TestArenaStats(t *testing.T) {
+	before := ReadArenaStats()
+
+	testTreap := NewMutable()
+	for i := 0; i < 10; i++ {
+		key := serializeUint32(uint32(i))
+		testTreap.Put(key, key)
+	}
+	stats := ReadArenaStats()
+	if got := stats.InUse - before.InUse; got != 10 {
+		t.Fatalf("InUse: got %d more nodes, want 10", got)
+	}
+	if stats.Slabs == 0 || stats.SlabBytes != stats.Slabs*arenaSlabSize*
+		nodeFieldsSize {
+
+		t.Fatalf("SlabBytes: got %d bytes for %d slabs",
+			stats.SlabBytes, stats.Slabs)
+	}
+
+	testTreap.Delete(serializeUint32(0))
+	testTreap.Delete(serializeUint32(1))
+	stats = ReadArenaStats()
+	if got := stats.Dropped - before.Dropped; got != 2 {
+		t.Fatalf("Dropped: got %d more nodes, want 2", got)
+	}
+
+	testTreap.Recycle()
+	stats = ReadArenaStats()
+	if stats.InUse != before.InUse {
+		t.Fatalf("InUse: got %d nodes, want %d", stats.InUse,
+			before.InUse)
+	}
+	if stats.Free < 8 {
+		t.Fatalf("Free: got %d nodes, want at least 8", stats.Free)
+	}
+}
@@ -0,0 +1,160 @@
+package treap
+
+import "bytes"
+
+// mergeStats tracks the nodes dropped while merging treaps so the count and
+// total size of the resulting treap can be derived from the ones of the
+// inputs.
+type mergeStats struct {
+	dropped     int
+	droppedSize uint64
+}
+
+// drop notes that the passed node isn't part of the resulting treap.
+func (s *mergeStats) drop(node *treapNode) {
+	s.dropped++
+	s.droppedSize += nodeSize(node)
+}
+
+// withChildren returns the passed node with the passed children.  The node is
+// returned as is when it already has them, and a copy of it otherwise since
+// the nodes of immutable treaps are shared.
+func withChildren(node, left, right *treapNode) *treapNode {
+	if node.left == left && node.right == right {
+		return node
+	}
+	nodeCopy := cloneTreapNode(node)
+	nodeCopy.left = left
+	nodeCopy.right = right
+	return nodeCopy
+}
+
+// splitTreap splits the passed subtree into the subtrees of the keys less than
+// and greater than the passed key, and returns them along with the node of the
+// key if it exists.  Only the nodes along the search path are copied.
+func splitTreap(node *treapNode, key []byte) (*treapNode, *treapNode, *treapNode) {
+	if node == nil {
+		return nil, nil, nil
+	}
+
+	compareResult := bytes.Compare(key, node.key)
+	switch {
+	case compareResult < 0:
+		left, match, right := splitTreap(node.left, key)
+		return left, match, withChildren(node, right, node.right)
+
+	case compareResult > 0:
+		left, match, right := splitTreap(node.right, key)
+		return withChildren(node, node.left, left), match, right
+	}
+
+	return node.left, node, node.right
+}
+
+// joinTreaps returns the subtree holding the nodes of both passed subtrees,
+// where all the keys of the left one are less than the keys of the right one.
+func joinTreaps(left, right *treapNode) *treapNode {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	// The node with the lowest priority becomes the root to maintain the
+	// min-heap.
+	if left.priority <= right.priority {
+		return withChildren(left, left.left, joinTreaps(left.right, right))
+	}
+	return withChildren(right, joinTreaps(left, right.left), right.right)
+}
+
+// unionTreaps returns the subtree holding the nodes of both passed subtrees.
+// The values of the second subtree take precedence when both hold a key.
+func unionTreaps(node, other *treapNode, stats *mergeStats) *treapNode {
+	if node == nil {
+		return other
+	}
+	if other == nil {
+		return node
+	}
+
+	// The root with the lowest priority remains the root to maintain the
+	// min-heap, and the other subtree is split around its key.
+	if node.priority <= other.priority {
+		left, match, right := splitTreap(other, node.key)
+		newLeft := unionTreaps(node.left, left, stats)
+		newRight := unionTreaps(node.right, right, stats)
+		if match == nil {
+			return withChildren(node, newLeft, newRight)
+		}
+		stats.drop(node)
+		nodeCopy := cloneTreapNode(node)
+		nodeCopy.value = match.value
+		nodeCopy.left = newLeft
+		nodeCopy.right = newRight
+		return nodeCopy
+	}
+
+	left, match, right := splitTreap(node, other.key)
+	if match != nil {
+		stats.drop(match)
+	}
+	return withChildren(other, unionTreaps(left, other.left, stats),
+		unionTreaps(right, other.right, stats))
+}
+
+// differenceTreaps returns the subtree holding the nodes of the first passed
+// subtree whose keys aren't in the second one.
+func differenceTreaps(node, other *treapNode, stats *mergeStats) *treapNode {
+	if node == nil || other == nil {
+		return node
+	}
+
+	left, match, right := splitTreap(node, other.key)
+	if match != nil {
+		stats.drop(match)
+	}
+	return joinTreaps(differenceTreaps(left, other.left, stats),
+		differenceTreaps(right, other.right, stats))
+}
+
+// Union returns the treap holding the key/value pairs of both the treap and the
+// passed one.  The values of the passed treap take precedence for the keys held
+// by both.
+//
+// Rather than inserting the pairs of the smaller treap one by one, the treaps
+// are merged recursively by splitting one of them around the root of the other,
+// which takes O(m log(n/m)) for treaps of m and n pairs where m <= n.  Neither
+// treap is modified and the unmodified nodes of both are shared with the
+// returned one.
+func (t *Immutable) Union(other *Immutable) *Immutable {
+	if other.count == 0 {
+		return t
+	}
+	if t.count == 0 {
+		return other
+	}
+
+	var stats mergeStats
+	root := unionTreaps(t.root, other.root, &stats)
+	return newImmutable(root, t.count+other.count-stats.dropped,
+		t.totalSize+other.totalSize-stats.droppedSize)
+}
+
+// Difference returns the treap holding the key/value pairs of the treap whose
+// keys aren't in the passed treap.  The values of the passed treap are ignored.
+//
+// Like Union, it takes O(m log(n/m)) for treaps of m and n pairs where m <= n
+// rather than deleting the keys one by one.  Neither treap is modified and the
+// unmodified nodes of the treap are shared with the returned one.
+func (t *Immutable) Difference(other *Immutable) *Immutable {
+	if t.count == 0 || other.count == 0 {
+		return t
+	}
+
+	var stats mergeStats
+	root := differenceTreaps(t.root, other.root, &stats)
+	return newImmutable(root, t.count-stats.dropped,
+		t.totalSize-stats.droppedSize)
+}
@@ -0,0 +1,117 @@
+package treap
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// randomImmutable returns an immutable treap holding the passed number of
+// random keys below the passed bound, with values tagged by the passed byte so
+// the treap they come from can be told apart.
+func randomImmutable(rng *rand.Rand, numItems, bound int, tag byte) *Immutable {
+	testTreap := NewImmutable()
+	for i := 0; i < numItems; i++ {
+		key := serializeUint32(uint32(rng.Intn(bound)))
+		var value []byte
+		if rng.Intn(4) != 0 {
+			value = append([]byte{tag}, key...)
+		}
+		testTreap = testTreap.Put(key, value)
+	}
+	return testTreap
+}
+
+// copyImmutable returns a copy of the passed immutable treap which doesn't share
+// any of its nodes.
+func copyImmutable(testTreap *Immutable) *Immutable {
+	treapCopy := NewImmutable()
+	testTreap.ForEach(func(k, v []byte) bool {
+		treapCopy = treapCopy.Put(k, v)
+		return true
+	})
+	return treapCopy
+}
+
+// checkSameTreap ensures the passed treap is a valid treap holding the same
+// key/value pairs as the expected one along with the same count and size.
+func checkSameTreap(t *testing.T, name string, got, want *Immutable) {
+	t.Helper()
+
+	if count := checkTreapNode(t, got.root, nil, nil); count != got.Len() {
+		t.Fatalf("%s: mismatched number of nodes - got %d, len %d", name,
+			count, got.Len())
+	}
+	if got.Len() != want.Len() {
+		t.Fatalf("%s: unexpected len - got %d, want %d", name,
+			got.Len(), want.Len())
+	}
+	if got.Size() != want.Size() {
+		t.Fatalf("%s: unexpected size - got %d, want %d", name,
+			got.Size(), want.Size())
+	}
+
+	iter := got.Iterator(nil, nil)
+	want.ForEach(func(k, v []byte) bool {
+		if !iter.Next() {
+			t.Fatalf("%s: missing key %x", name, k)
+		}
+		if !bytes.Equal(iter.Key(), k) || !bytes.Equal(iter.Value(), v) {
+			t.Fatalf("%s: unexpected pair - got %x/%x, want %x/%x",
+				name, iter.Key(), iter.Value(), k, v)
+		}
+		return true
+	})
+	if iter.Next() {
+		t.Fatalf("%s: unexpected key %x", name, iter.Key())
+	}
+}
+
+// TestImmutableUnionDifference ensures the union and difference of random
+// immutable treaps of various relative sizes and overlaps match the result of
+// putting or deleting the keys one by one, and leave the inputs unmodified.
+func TestImmutableUnionDifference(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(0))
+	tests := []struct {
+		numItems, numOther, bound int
+	}{
+		{0, 0, 10},
+		{0, 10, 10},
+		{10, 0, 10},
+		{1, 1, 1},
+		{50, 50, 100},
+		{1000, 10, 2000},
+		{10, 1000, 2000},
+		{1000, 1000, 1000000},
+		{500, 500, 300},
+	}
+	for _, test := range tests {
+		for round := 0; round < 10; round++ {
+			testTreap := randomImmutable(rng, test.numItems,
+				test.bound, 0x01)
+			other := randomImmutable(rng, test.numOther, test.bound,
+				0x02)
+			before := copyImmutable(testTreap)
+			otherBefore := copyImmutable(other)
+
+			wantUnion := testTreap
+			wantDifference := testTreap
+			other.ForEach(func(k, v []byte) bool {
+				wantUnion = wantUnion.Put(k, v)
+				wantDifference = wantDifference.Delete(k)
+				return true
+			})
+
+			checkSameTreap(t, "Union", testTreap.Union(other),
+				wantUnion)
+			checkSameTreap(t, "Difference",
+				testTreap.Difference(other), wantDifference)
+
+			// Ensure the inputs are not modified.
+			checkSameTreap(t, "input", testTreap, before)
+			checkSameTreap(t, "other input", other, otherBefore)
+		}
+	}
+}
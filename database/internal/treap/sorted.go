@@ -0,0 +1,75 @@
+package treap
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+)
+
+// SortedIterator is the interface of the sources of key/value pairs sorted by
+// key which an immutable treap can be bulk loaded from with NewFromSorted.  It
+// is satisfied by the iterators of goleveldb.
+type SortedIterator interface {
+	// Next moves the iterator to the next key/value pair, starting from
+	// before the first one.  It returns false when the iterator is
+	// exhausted.
+	Next() bool
+
+	// Key returns the key of the current key/value pair.
+	Key() []byte
+
+	// Value returns the value of the current key/value pair.
+	Value() []byte
+}
+
+// NewFromSorted returns a new immutable treap holding the key/value pairs of
+// the passed iterator, which must be sorted by strictly ascending keys.
+//
+// The treap is built in O(n) instead of the O(n log n) of inserting the pairs
+// one by one by assigning the random priority of each node as it's appended
+// and only restoring the heap property along the rightmost path of the tree,
+// which is where every new node ends up.  The resulting treap has the same
+// shape as one built with Put given the same priorities, so it has the same
+// balance guarantees.
+//
+// The keys and values are stored as is, so the iterator must not reuse their
+// memory.  An error is returned when the keys are not strictly ascending.
+func NewFromSorted(iter SortedIterator) (*Immutable, error) {
+	// The rightmost path of the tree from the root down to the last node,
+	// which holds the largest key so far.
+	var rightmost parentStack
+	var count int
+	var totalSize uint64
+	var lastKey []byte
+	for iter.Next() {
+		key, value := iter.Key(), iter.Value()
+		if count > 0 && bytes.Compare(key, lastKey) <= 0 {
+			return nil, fmt.Errorf("key %x at position %d is not "+
+				"greater than the previous key %x", key, count,
+				lastKey)
+		}
+		if value == nil {
+			value = emptySlice
+		}
+		lastKey = key
+
+		// The new node becomes the right child of the deepest node on
+		// the rightmost path with a lower priority, and the nodes
+		// below it become its left subtree.
+		node := newTreapNode(key, value, rand.Int())
+		var left *treapNode
+		for rightmost.Len() > 0 && rightmost.At(0).priority > node.priority {
+			left = rightmost.Pop()
+		}
+		node.left = left
+		if parent := rightmost.At(0); parent != nil {
+			parent.right = node
+		}
+		rightmost.Push(node)
+
+		count++
+		totalSize += nodeSize(node)
+	}
+
+	return newImmutable(rightmost.At(rightmost.Len()-1), count, totalSize), nil
+}
@@ -0,0 +1,116 @@
+package treap
+
+import (
+	"bytes"
+	"testing"
+)
+
+// sliceIterator is a SortedIterator over key/value pairs held in slices.
+type sliceIterator struct {
+	keys   [][]byte
+	values [][]byte
+	index  int
+}
+
+func newSliceIterator(keys, values [][]byte) *sliceIterator {
+	return &sliceIterator{keys: keys, values: values, index: -1}
+}
+
+func (iter *sliceIterator) Next() bool {
+	iter.index++
+	return iter.index < len(iter.keys)
+}
+
+func (iter *sliceIterator) Key() []byte   { return iter.keys[iter.index] }
+func (iter *sliceIterator) Value() []byte { return iter.values[iter.index] }
+
+// checkTreapNode ensures the passed subtree is a binary search tree with keys
+// between the passed bounds and a min-heap of the priorities, and returns its
+// number of nodes.
+func checkTreapNode(t *testing.T, node *treapNode, min, max []byte) int {
+	t.Helper()
+	if node == nil {
+		return 0
+	}
+	if (min != nil && bytes.Compare(node.key, min) <= 0) ||
+		(max != nil && bytes.Compare(node.key, max) >= 0) {
+
+		t.Fatalf("key %x is out of order", node.key)
+	}
+	for _, child := range []*treapNode{node.left, node.right} {
+		if child != nil && child.priority < node.priority {
+			t.Fatalf("child %x has a lower priority than its "+
+				"parent %x", child.key, node.key)
+		}
+	}
+	return 1 + checkTreapNode(t, node.left, min, node.key) +
+		checkTreapNode(t, node.right, node.key, max)
+}
+
+// TestNewFromSorted ensures immutable treaps bulk loaded from sorted key/value
+// pairs are valid treaps holding the pairs.
+func TestNewFromSorted(t *testing.T) {
+	t.Parallel()
+
+	for _, numItems := range []int{0, 1, 2, 1000} {
+		keys := make([][]byte, 0, numItems)
+		values := make([][]byte, 0, numItems)
+		expectedSize := uint64(0)
+		for i := 0; i < numItems; i++ {
+			key := serializeUint32(uint32(i))
+			var value []byte
+			if i%2 == 0 {
+				value = key
+			}
+			keys = append(keys, key)
+			values = append(values, value)
+			expectedSize += nodeFieldsSize + uint64(len(key)+len(value))
+		}
+
+		testTreap, err := NewFromSorted(newSliceIterator(keys, values))
+		if err != nil {
+			t.Fatalf("NewFromSorted #%d: unexpected error: %v",
+				numItems, err)
+		}
+		if gotLen := testTreap.Len(); gotLen != numItems {
+			t.Fatalf("NewFromSorted #%d: unexpected len - got %d, "+
+				"want %d", numItems, gotLen, numItems)
+		}
+		if gotSize := testTreap.Size(); gotSize != expectedSize {
+			t.Fatalf("NewFromSorted #%d: unexpected size - got %d, "+
+				"want %d", numItems, gotSize, expectedSize)
+		}
+		count := checkTreapNode(t, testTreap.root, nil, nil)
+		if count != numItems {
+			t.Fatalf("NewFromSorted #%d: unexpected number of nodes - "+
+				"got %d, want %d", numItems, count, numItems)
+		}
+		for i, key := range keys {
+			gotValue := testTreap.Get(key)
+			if gotValue == nil || !bytes.Equal(gotValue, values[i]) {
+				t.Fatalf("NewFromSorted #%d: unexpected value for "+
+					"key %x - got %x, want %x", numItems, key,
+					gotValue, values[i])
+			}
+		}
+
+		// The treap can be modified like any other.
+		testTreap = testTreap.Put(serializeUint32(uint32(numItems)), nil)
+		if gotLen := testTreap.Len(); gotLen != numItems+1 {
+			t.Fatalf("NewFromSorted #%d: unexpected len after put - "+
+				"got %d, want %d", numItems, gotLen, numItems+1)
+		}
+	}
+
+	// Keys which are not strictly ascending are rejected.
+	tests := [][][]byte{
+		{serializeUint32(1), serializeUint32(0)},
+		{serializeUint32(1), serializeUint32(1)},
+	}
+	for i, keys := range tests {
+		_, err := NewFromSorted(newSliceIterator(keys, keys))
+		if err == nil {
+			t.Fatalf("NewFromSorted #%d: unsorted keys were accepted", i)
+		}
+	}
+}
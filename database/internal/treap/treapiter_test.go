@@ -7,6 +7,7 @@ package treap
 import (
 	"bytes"
 	"encoding/binary"
+	"sync"
 	"testing"
 )
 
@@ -717,3 +718,90 @@ func TestImmutableEmptyIterator(t *testing.T) {
 		t.Fatal("Prev: iterator should be exhausted")
 	}
 }
+
+// TestImmutableIteratorSnapshot ensures iterators created from an immutable
+// treap walk exactly the version they were created from while newer versions
+// are derived from it and mutable treaps recycle their nodes concurrently.
+func TestImmutableIteratorSnapshot(t *testing.T) {
+	t.Parallel()
+
+	const numKeys = 1000
+	snapshot := NewImmutable()
+	for i := 0; i < numKeys; i += 2 {
+		key := serializeUint32(uint32(i))
+		snapshot = snapshot.Put(key, key)
+	}
+
+	// Concurrently derive new versions from the snapshot which overwrite,
+	// add, and delete keys, and fill and recycle a mutable treap with the
+	// same keys.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			newer := snapshot
+			for i := 0; i < numKeys; i++ {
+				key := serializeUint32(uint32(i))
+				if i%3 == 0 {
+					newer = newer.Delete(key)
+				} else {
+					newer = newer.Put(key, nil)
+				}
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		mutable := NewMutable()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			for i := 0; i < numKeys; i++ {
+				key := serializeUint32(uint32(i))
+				mutable.Put(key, nil)
+			}
+			for i := 0; i < numKeys; i += 3 {
+				mutable.Delete(serializeUint32(uint32(i)))
+			}
+			mutable.Recycle()
+		}
+	}()
+
+	for pass := 0; pass < 20; pass++ {
+		iter := snapshot.Iterator(nil, nil)
+		want := 0
+		for ok := iter.First(); ok; ok = iter.Next() {
+			wantKey := serializeUint32(uint32(want))
+			if !bytes.Equal(iter.Key(), wantKey) {
+				t.Fatalf("pass #%d: unexpected key - got %x, "+
+					"want %x", pass, iter.Key(), wantKey)
+			}
+			if !bytes.Equal(iter.Value(), wantKey) {
+				t.Fatalf("pass #%d: unexpected value - got %x, "+
+					"want %x", pass, iter.Value(), wantKey)
+			}
+			want += 2
+		}
+		if want != numKeys {
+			t.Fatalf("pass #%d: iterated %d keys, want %d", pass,
+				want/2, numKeys/2)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if snapshot.Len() != numKeys/2 {
+		t.Fatalf("snapshot length changed - got %d, want %d",
+			snapshot.Len(), numKeys/2)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/database"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+const (
+	// scrubBatchSize is the number of blocks read from the database by the
+	// scrubber in a single database transaction.
+	scrubBatchSize = 100
+
+	// maxRepairedHistory is the maximum number of repaired blocks that are
+	// remembered for reporting.
+	maxRepairedHistory = 100
+)
+
+// scrubStatus describes the progress and the findings of the database
+// scrubber.
+type scrubStatus struct {
+	running   bool
+	scans     int64
+	lastStart time.Time
+	lastEnd   time.Time
+	checked   int64
+	corrupt   []chainhash.Hash
+	repaired  []chainhash.Hash
+}
+
+// dbScrubber periodically re-reads the blocks of the main chain from the block
+// files to detect corruption, such as torn writes or bit rot, before the
+// blocks are requested.  Corrupt blocks are requested from peers and replaced
+// in the database once a copy matching the block header arrives.
+type dbScrubber struct {
+	db           database.DB
+	chain        *blockchain.BlockChain
+	interval     time.Duration
+	requestBlock func(hash *chainhash.Hash)
+
+	mtx       sync.Mutex
+	running   bool
+	scans     int64
+	lastStart time.Time
+	lastEnd   time.Time
+	checked   int64
+	corrupt   map[chainhash.Hash]struct{}
+	repaired  []chainhash.Hash
+
+	scanNow chan struct{}
+	wg      sync.WaitGroup
+	quit    chan struct{}
+}
+
+// newDBScrubber returns a scrubber of the blocks of the main chain of the
+// passed chain stored in the passed database.  Blocks are scrubbed every
+// interval, or only on demand when the interval is zero.  Copies of corrupt
+// blocks are requested with requestBlock.
+func newDBScrubber(db database.DB, chain *blockchain.BlockChain,
+	interval time.Duration, requestBlock func(*chainhash.Hash)) *dbScrubber {
+
+	return &dbScrubber{
+		db:           db,
+		chain:        chain,
+		interval:     interval,
+		requestBlock: requestBlock,
+		corrupt:      make(map[chainhash.Hash]struct{}),
+		scanNow:      make(chan struct{}, 1),
+		quit:         make(chan struct{}),
+	}
+}
+
+// Start begins scrubbing the database in the background.
+func (s *dbScrubber) Start() {
+	s.wg.Add(1)
+	go s.scrubHandler()
+}
+
+// Stop stops the scrubber, interrupting any scan in progress.
+func (s *dbScrubber) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// Scan requests a scan of the database to start immediately.  It returns false
+// when a scan is already in progress.
+//
+// This function is safe for concurrent access.
+func (s *dbScrubber) Scan() bool {
+	s.mtx.Lock()
+	running := s.running
+	s.mtx.Unlock()
+	if running {
+		return false
+	}
+
+	select {
+	case s.scanNow <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Status returns the progress and the findings of the scrubber.
+//
+// This function is safe for concurrent access.
+func (s *dbScrubber) Status() scrubStatus {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	status := scrubStatus{
+		running:   s.running,
+		scans:     s.scans,
+		lastStart: s.lastStart,
+		lastEnd:   s.lastEnd,
+		checked:   s.checked,
+		corrupt:   make([]chainhash.Hash, 0, len(s.corrupt)),
+		repaired:  append([]chainhash.Hash(nil), s.repaired...),
+	}
+	for hash := range s.corrupt {
+		status.corrupt = append(status.corrupt, hash)
+	}
+	return status
+}
+
+// scrubHandler scans the database every interval and whenever a scan is
+// requested.
+//
+// It must be run as a goroutine.
+func (s *dbScrubber) scrubHandler() {
+	var tick <-chan time.Time
+	if s.interval > 0 {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+out:
+	for {
+		select {
+		case <-tick:
+			s.scan()
+		case <-s.scanNow:
+			s.scan()
+		case <-s.quit:
+			break out
+		}
+	}
+	s.wg.Done()
+}
+
+// scan reads every block of the main chain from the database to verify its
+// checksum and requests copies of the corrupt ones from peers.  Blocks which
+// were pruned are skipped.
+func (s *dbScrubber) scan() {
+	s.mtx.Lock()
+	s.running = true
+	s.lastStart = time.Now()
+	s.checked = 0
+	s.mtx.Unlock()
+
+	srvrLog.Debugf("Scrubbing block database")
+
+	corrupt := make(map[chainhash.Hash]struct{})
+	bestHeight := s.chain.BestSnapshot().Height
+	interrupted := false
+	for height := int32(0); height <= bestHeight; height += scrubBatchSize {
+		select {
+		case <-s.quit:
+			interrupted = true
+		default:
+		}
+		if interrupted {
+			break
+		}
+
+		var hashes []*chainhash.Hash
+		for h := height; h < height+scrubBatchSize && h <= bestHeight; h++ {
+			hash, err := s.chain.BlockHashByHeight(h)
+			if err != nil {
+				// The chain was reorganized to a shorter chain
+				// during the scan.
+				break
+			}
+			hashes = append(hashes, hash)
+		}
+
+		err := s.db.View(func(dbTx database.Tx) error {
+			for _, hash := range hashes {
+				err := checkStoredBlock(dbTx, hash)
+				if err != nil {
+					srvrLog.Warnf("Block %v is corrupt in "+
+						"the database: %v", hash, err)
+					corrupt[*hash] = struct{}{}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			srvrLog.Errorf("Unable to scrub block database: %v", err)
+			interrupted = true
+			break
+		}
+
+		s.mtx.Lock()
+		s.checked += int64(len(hashes))
+		s.mtx.Unlock()
+	}
+
+	s.mtx.Lock()
+	s.running = false
+	if !interrupted {
+		s.scans++
+		s.lastEnd = time.Now()
+		s.corrupt = corrupt
+	} else {
+		for hash := range corrupt {
+			s.corrupt[hash] = struct{}{}
+		}
+	}
+	var request []chainhash.Hash
+	for hash := range s.corrupt {
+		request = append(request, hash)
+	}
+	checked := s.checked
+	s.mtx.Unlock()
+
+	srvrLog.Debugf("Scrubbed %d blocks, %d corrupt", checked, len(request))
+
+	for i := range request {
+		s.requestBlock(&request[i])
+	}
+}
+
+// checkStoredBlock reads the block with the passed hash from the database,
+// which verifies its checksum, and returns an error when it can't be read.
+// Blocks which aren't stored, such as pruned ones, are not considered corrupt.
+func checkStoredBlock(dbTx database.Tx, hash *chainhash.Hash) error {
+	_, err := dbTx.FetchBlock(hash)
+	if dbErr, ok := err.(database.Error); ok &&
+		dbErr.ErrorCode == database.ErrBlockNotFound {
+
+		return nil
+	}
+	return err
+}
+
+// MaybeRepair replaces the stored copy of the passed block when it was found to
+// be corrupt.  It returns whether or not the block was one of the corrupt
+// blocks, in which case the block must not be processed any further.
+//
+// This function is safe for concurrent access.
+func (s *dbScrubber) MaybeRepair(block *btcutil.Block) bool {
+	hash := block.Hash()
+	s.mtx.Lock()
+	_, ok := s.corrupt[*hash]
+	s.mtx.Unlock()
+	if !ok {
+		return false
+	}
+
+	// The block hash only commits to the header, so make sure the
+	// transactions match it before storing them.
+	if err := checkBlockContents(block); err != nil {
+		srvrLog.Warnf("Ignoring copy of corrupt block %v: %v", hash, err)
+		return true
+	}
+
+	err := s.db.Update(func(dbTx database.Tx) error {
+		return dbTx.ReplaceBlock(block)
+	})
+	if dbErr, ok := err.(database.Error); ok &&
+		dbErr.ErrorCode == database.ErrBlockNotFound {
+
+		// The block was pruned in the meantime.
+		err = nil
+	} else if err != nil {
+		srvrLog.Errorf("Unable to repair block %v: %v", hash, err)
+		return true
+	} else {
+		srvrLog.Infof("Repaired corrupt block %v", hash)
+	}
+
+	s.mtx.Lock()
+	delete(s.corrupt, *hash)
+	s.repaired = append(s.repaired, *hash)
+	if len(s.repaired) > maxRepairedHistory {
+		s.repaired = s.repaired[len(s.repaired)-maxRepairedHistory:]
+	}
+	s.mtx.Unlock()
+	return true
+}
+
+// checkBlockContents ensures the transactions of the passed block match the
+// merkle root in its header and its witness commitment.
+func checkBlockContents(block *btcutil.Block) error {
+	transactions := block.Transactions()
+	if len(transactions) == 0 {
+		return fmt.Errorf("block has no transactions")
+	}
+
+	merkles := blockchain.BuildMerkleTreeStore(transactions, false)
+	calculatedRoot := merkles[len(merkles)-1]
+	if !block.MsgBlock().Header.MerkleRoot.IsEqual(calculatedRoot) {
+		return fmt.Errorf("merkle root mismatch")
+	}
+
+	return blockchain.ValidateWitnessCommitment(block)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestCheckBlockContents ensures copies of blocks whose transactions don't
+// match their header are rejected.
+func TestCheckBlockContents(t *testing.T) {
+	genesis := chaincfg.MainNetParams.GenesisBlock
+	if err := checkBlockContents(btcutil.NewBlock(genesis)); err != nil {
+		t.Fatalf("checkBlockContents: unexpected error: %v", err)
+	}
+
+	tampered := *genesis
+	tx := genesis.Transactions[0].Copy()
+	tx.TxOut[0].Value++
+	tampered.Transactions = []*wire.MsgTx{tx}
+	if err := checkBlockContents(btcutil.NewBlock(&tampered)); err == nil {
+		t.Fatalf("checkBlockContents: tampered block accepted")
+	}
+
+	tampered.Transactions = nil
+	if err := checkBlockContents(btcutil.NewBlock(&tampered)); err == nil {
+		t.Fatalf("checkBlockContents: empty block accepted")
+	}
+}
@@ -23,17 +23,37 @@ Usage:
 
 Application Options:
 
+	    --addassumeutxo=        Add an assumed utxo set that a utxo snapshot can
+	                            be loaded for with loadtxoutset.  Format:
+	                            '<height>:<blockhash>:<muhash>'
 	    --addcheckpoint=        Add a custom checkpoint.  Format:
 	                            '<height>:<hash>'
 	-a, --addpeer=              Add a peer to connect with at startup
 	    --addrindex             Maintain a full address-based transaction index
 	                            which makes the searchrawtransactions RPC
 	                            available
+	    --alertreorgdepth=      Raise an alert when a reorganization disconnects
+	                            at least this number of blocks from the main
+	                            chain -- 0 disables the alert (default: 6)
+	    --alertstalltimeout=    Raise an alert when the tip of the main chain
+	                            didn't change for this long -- 0 disables the
+	                            alert (default: 30m0s)
+	    --alertwebhook=         URL the chain alerts are posted to as JSON, in
+	                            addition to being logged and sent to the
+	                            websocket clients registered with notifyalerts
+	    --assumevalid=          Hash of a block assumed to be valid along with
+	                            its ancestors, whose scripts are not checked
+	                            during header-check sync
 	    --banduration=          How long to ban misbehaving peers.  Valid time
 	                            units are {s, m, h}.  Minimum 1 second (default:
 	                            24h0m0s)
 	    --banthreshold=         Maximum allowed ban score before disconnecting
 	                            and banning misbehaving peers. (default: 100)
+	    --blockcompression      Compress new blocks stored in the block database
+	                            with zstd.  Blocks which were already stored
+	                            remain readable either way, but a database with
+	                            compressed blocks can't be opened by older
+	                            versions
 	    --blockmaxsize=         Maximum block size in bytes to be used when
 	                            creating a block (default: 750000)
 	    --blockminsize=         Mininum block size in bytes to be used when
@@ -45,13 +65,48 @@ Application Options:
 	    --blockprioritysize=    Size in bytes for high-priority/low-fee
 	                            transactions when creating a block (default:
 	                            50000)
+	    --blockselection=       Strategy used to select the transactions of new
+	                            blocks {priority, feerate, ancestorfeerate} --
+	                            priority fills blockprioritysize bytes with
+	                            high-priority transactions before selecting by
+	                            fee rate, feerate only selects by fee rate, and
+	                            ancestorfeerate selects transactions along with
+	                            their unconfirmed ancestors by the fee rate of
+	                            the whole package (default: priority)
 	    --blocksonly            Do not accept transactions from remote peers.
+	    --channelindex          Maintain an index of the claims signed by each
+	                            channel which makes the getclaimsbychannel RPC
+	                            available
+	    --claimfilterindex      Maintain a filter per block of the normalized
+	                            names of the claims it touches, served to peers
+	                            and by the getcfilter RPC as filter type 1
+	    --claimtriecache=       Approximate memory in MiB used to cache claim
+	                            trie nodes (default: 128)
+	    --claimtriepebblebloombits= Bits per key of the bloom filters of the
+	                            tables written to the pebble databases of the
+	                            claim trie (0 for no filters)
+	    --claimtriepebblecache= Size in MiB of the block cache of each pebble
+	                            database of the claim trie (0 for the built-in
+	                            default of each database)
+	    --claimtriepebblecompactions= Maximum number of concurrent compactions
+	                            of each pebble database of the claim trie (0 for
+	                            the pebble default)
+	    --claimtriepebblememtable= Size in MiB of the memtables of the pebble
+	                            databases of the claim trie (0 for the pebble
+	                            default)
 	-C, --configfile=           Path to configuration file
 	    --connect=              Connect only to the specified peers at startup
 	    --cpuprofile=           Write CPU profile to the specified file
+	    --datacarriersize=      Max number of bytes of data in standard null data
+	                            (OP_RETURN) outputs (default: 80)
 	-b, --datadir=              Directory to store data
 	    --dbtype=               Database backend to use for the Block Chain
 	                            (default: ffldb)
+	    --dbscrubinterval=      Interval at which the blocks stored in the
+	                            database are re-read to detect corruption --
+	                            Corrupt blocks are fetched again from peers --
+	                            0 only scrubs on request with the verifydb RPC
+	                            (e.g. 24h)
 	-d, --debuglevel=           Logging level for all subsystems {trace, debug,
 	                            info, warn, error, critical} -- You may also
 	                            specify
@@ -59,25 +114,61 @@ Application Options:
 	                            set the log level for individual subsystems --
 	                            Use show to list available subsystems (default:
 	                            info)
+	    --decodeclaims          Return the metadata decoded from the values of
+	                            claims instead of their hex encoding in the
+	                            results of the getclaimsforname and
+	                            getrawtransaction RPCs
 	    --dropaddrindex         Deletes the address-based transaction index from
 	                            the database on start up and then exits.
+	    --dropchannelindex      Deletes the claims by channel index from the
+	                            database on start up and then exits.
+	    --dropclaimfilterindex  Deletes the claim name filter index from the
+	                            database on start up and then exits.
 	    --dropcfindex           Deletes the index used for committed filtering
 	                            (CF) support from the database on start up and
 	                            then exits.
+	    --dropspentindex        Deletes the spent output index from the database
+	                            on start up and then exits.
 	    --droptxindex           Deletes the hash-based transaction index from the
 	                            database on start up and then exits.
+	    --dustrelayfee=         The fee rate in LBC/kB used to determine whether
+	                            outputs are dust, which is when spending them
+	                            costs more than a third of their value --
+	                            Defaults to minrelaytxfee
 	    --externalip=           Add an ip to the list of local addresses we claim
 	                            to listen on to peers
 	    --generate              Generate (mine) bitcoins using the CPU
+	    --grpclisten=           Add an interface/port to listen for gRPC
+	                            connections (default port: 9247) -- NOTE: The
+	                            gRPC server is disabled unless a listen address
+	                            is specified and requires the RPC server
+	    --headerchecksync       Download and check the headers up to the tip of
+	                            the sync peer before downloading the blocks
 	    --limitfreerelay=       Limit relay of transactions with no transaction
 	                            fee to the given amount in thousands of bytes per
 	                            minute (default: 15)
 	    --listen=               Add an interface/port to listen for connections
 	                            (default all interfaces port: 9246, testnet:
-	                            19246, regtest: 29246, signet: 39246)
+	                            19246, regtest: 29246, signet: 49246)
+	    --listenonion           Automatically create a Tor onion service for the
+	                            listening port via the Tor control port and
+	                            advertise its address to peers
 	    --logdir=               Directory to log output
+	    --maxmempool=           Max size of the transactions in the memory pool
+	                            in megabytes -- The transactions paying the
+	                            lowest fee rates are evicted when it is exceeded
+	                            (0 for no limit) (default: 300)
+	    --maxclaimvaluesize=    Max size in bytes of the values of claims,
+	                            updates and supports in standard transactions
+	                            (default: 8192)
+	    --maxorphanblocks=      Max number of blocks whose parent is unknown to
+	                            keep in memory until the parent arrives
+	                            (default: 100)
 	    --maxorphantx=          Max number of orphan transactions to keep in
 	                            memory (default: 100)
+	    --maxpeerbloomfiltersize= Max size in bytes of the bloom filter a peer
+	                            may load -- Peers loading larger filters are
+	                            disconnected (default: 36000)
 	    --maxpeers=             Max number of inbound and outbound peers
 	                            (default: 125)
 	    --memprofile=           Write memory profile to the specified file
@@ -85,9 +176,26 @@ Application Options:
 	                            addresses to use for generated blocks -- At least
 	                            one address is required if the generate option is
 	                            set
+	    --miningpayout=         Add the specified payment address and weight, in
+	                            the form <address>:<weight>, to the list of
+	                            payouts to split the coinbase of blocks generated
+	                            by the CPU miner among proportionally to their
+	                            weights -- Takes precedence over the mining
+	                            addresses for the CPU miner
+	    --minclaimamount=       The minimum amount in LBC of the claim and update
+	                            outputs of standard transactions, which encumber
+	                            the claim trie, in addition to the dust threshold
 	    --minrelaytxfee=        The minimum transaction fee in BTC/kB to be
 	                            considered a non-zero fee. (default: 1e-05)
+	    --minsupportamount=     The minimum amount in LBC of the support outputs
+	                            of standard transactions, which encumber the
+	                            claim trie, in addition to the dust threshold
+	    --natpmp                Use NAT-PMP to map our listening port outside of
+	                            NAT
 	    --nobanning             Disable banning of misbehaving peers
+	    --nobaremultisig        Consider transactions with bare multi-signature
+	                            outputs, as opposed to pay-to-script-hash ones,
+	                            non-standard
 	    --nocfilters            Disable committed filtering (CF) support
 	    --nocheckpoints         Disable built-in checkpoints.  Don't do this
 	                            unless you know what you're doing.
@@ -102,23 +210,81 @@ Application Options:
 	                            have high priority for relaying
 	    --norpc                 Disable built-in RPC server -- NOTE: The RPC
 	                            server is disabled by default if no
-	                            rpcuser/rpcpass or rpclimituser/rpclimitpass is
-	                            specified
+	                            rpcuser/rpcpass, rpclimituser/rpclimitpass or
+	                            rpcaccount is specified
 	    --notls                 Disable TLS for the RPC server
 	    --onion=                Connect to tor hidden services via SOCKS5 proxy
 	                            (eg. 127.0.0.1:9050)
 	    --onionpass=            Password for onion proxy server
 	    --onionuser=            Username for onion proxy server
-	    --profile=              Enable HTTP profiling on given port -- NOTE port
+	    --outboundtarget=       Add a minimum number of outbound connections to
+	                            a network, in the form <network>:<count> with a
+	                            network of ipv4, ipv6 or onion (e.g. ipv6:2) --
+	                            The total number of outbound connections is
+	                            raised to the sum of the targets when it
+	                            exceeds the default of 8
+	    --peerbloomfilters=     Only advertise and serve bloom filters (BIP0037)
+	                            to inbound peers connecting to the specified
+	                            listen interface/port -- Bloom filters are
+	                            served to all peers when none are specified
+	    --peerbloomwork=        Max average number of KiB per second hashed to
+	                            match the bloom filter of a single peer -- Peers
+	                            exceeding it are disconnected (default: 32768)
+	    --profile=              Enable HTTP profiling on given port, which also
+	                            serves the metrics of the claim trie databases
+	                            in the Prometheus format at /metrics -- NOTE port
 	                            must be between 1024 and 65536
 	    --proxy=                Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)
 	    --proxypass=            Password for proxy server
 	    --proxyuser=            Username for proxy server
+	    --pruneforkdb           Delete the pruned stale forks from the block
+	                            index stored in the database as well so they
+	                            are not loaded on start up -- Requires
+	                            --pruneforkdepth
+	    --pruneforkdepth=       Prune the stale forks branching off the main
+	                            chain more than this number of blocks below its
+	                            tip from the block index (minimum value of 288,
+	                            default value of 0 will disable pruning)
+	    --publicrpcburst=       Max number of requests a single IP may make at
+	                            once on the public RPC listeners (default: 20)
+	    --publicrpclisten=      Add an interface/port to listen for public RPC
+	                            connections which only serve read-only block and
+	                            claim queries (default port: 9248) -- NOTE: The
+	                            public RPC server is disabled unless a listen
+	                            address is specified and requires the RPC server
+	    --publicrpcmethod=      Allow the specified read-only method on the
+	                            public RPC listeners instead of the default block
+	                            and claim queries -- Can be specified multiple
+	                            times
+	    --publicrpcrate=        Max number of requests per second a single IP may
+	                            make on average on the public RPC listeners --
+	                            Clients authenticating with the RPC credentials
+	                            are not rate limited (default: 5)
 	    --regtest               Use the regression test network
+	    --reindex               Rebuild the block index and the chain state,
+	                            including the claim trie and the optional
+	                            indexes, from the blocks stored in the database
+	                            on start up -- An interrupted reindex resumes on
+	                            the next start
+	    --reindex-chainstate    Rebuild the chain state, including the claim trie
+	                            and the optional indexes, from the blocks stored
+	                            in the database on start up -- An interrupted
+	                            reindex resumes on the next start
 	    --rejectnonstd          Reject non-standard transactions regardless of
 	                            the default settings for the active network.
 	    --relaynonstd           Relay non-standard transactions regardless of the
 	                            default settings for the active network.
+	    --rest                  Serve the REST interface for blocks,
+	                            transactions, headers and claims on the RPC
+	                            listeners without authentication
+	    --rpcaccount=           Add RPC credentials restricted to an access list
+	                            of methods, in the form
+	                            <user>:<password>:<methods> with a
+	                            comma-separated list of methods allowed, * to
+	                            allow all methods or methods prefixed with - to
+	                            deny them (e.g.
+	                            miner:pass:getblocktemplate,submitblock or
+	                            reader:pass:*,-stop)
 	    --rpccert=              File containing the certificate file
 	    --rpckey=               File containing the certificate key
 	    --rpclimitpass=         Password for limited RPC connections
@@ -131,19 +297,44 @@ Application Options:
 	                            processed concurrently (default: 20)
 	    --rpcmaxwebsockets=     Max number of RPC websocket connections (default:
 	                            25)
+	    --rpcmetrics            Export the request statistics of the RPC methods
+	                            at /metrics on the profile server -- Requires
+	                            --profile
 	    --rpcquirks             Mirror some JSON-RPC quirks of Bitcoin Core --
 	                            NOTE: Discouraged unless interoperability issues
 	                            need to be worked around
+	    --rpctracesize=         Number of the most recent RPC requests whose
+	                            traces are kept in memory for the getrpcstats
+	                            RPC -- 0 disables the traces (default: 1000)
 	-P, --rpcpass=              Password for RPC connections
 	-u, --rpcuser=              Username for RPC connections
 	    --sigcachemaxsize=      The maximum number of entries in the signature
 	                            verification cache (default: 100000)
+	    --sigcachepersist       Save the signature verification cache to the
+	                            data directory on shutdown and load it on
+	                            startup to avoid verifying the same signatures
+	                            again after a restart
 	    --simnet                Use the simulation test network
+	    --spentindex            Maintain an index of the inputs spending each
+	                            output which makes the getspentinfo RPC
+	                            available
 	    --testnet               Use the test network
+	    --torcontrol=           Tor control port used to create the onion
+	                            service when --listenonion is set (default:
+	                            127.0.0.1:9051)
 	    --torisolation          Enable Tor stream isolation by randomizing user
 	                            credentials for each connection.
-	    --trickleinterval=      Minimum time between attempts to send new
-	                            inventory to a connected peer (default: 10s)
+	    --torisolationmode=     How the credentials are randomized by
+	                            --torisolation: connection for new credentials
+	                            for each connection, or destination for the same
+	                            credentials for all the connections to a
+	                            destination and different ones for each
+	                            destination (default: connection)
+	    --torpassword=          Password for the Tor control port -- Cookie
+	                            authentication is used when not set
+	    --trickleinterval=      Average time between attempts to send new
+	                            inventory to an inbound peer -- Outbound peers
+	                            use half of it (default: 10s)
 	    --txindex               Maintain a full hash-based transaction index
 	                            which makes all transactions available via the
 	                            getrawtransaction RPC
@@ -151,6 +342,12 @@ Application Options:
 	                            for more information.
 	    --upnp                  Use UPnP to map our listening port outside of NAT
 	-V, --version               Display version information and exit
+	    --violationweight=      Set the weight in percent of the ban score added
+	                            for a category of violations, in the form
+	                            <category>=<percent> with a category of
+	                            invalidmessage, unrequesteddata, stalling or
+	                            protocolabuse (e.g. protocolabuse=50) --
+	                            Stalling is tracked but never causes a ban
 	    --whitelist=            Add an IP network or IP that will not be banned.
 	                            (eg. 192.168.1.0/24 or ::1)
 
@@ -33,9 +33,29 @@ const (
 	DefaultFeeRateStep float64 = 1.05
 
 	// defaultDecay is the default value used to decay old transactions from the
-	// estimator.
+	// estimator. It is the decay of the long horizon.
 	defaultDecay float64 = 0.998
 
+	// shortDecay is the value used to decay old transactions from the short
+	// horizon, which has a half life of about 18 blocks.
+	shortDecay float64 = 0.962
+
+	// mediumDecay is the value used to decay old transactions from the medium
+	// horizon, which has a half life of about 144 blocks.
+	mediumDecay float64 = 0.9952
+
+	// shortMaxTarget and mediumMaxTarget are the highest confirmation
+	// targets estimated using the short and medium horizons respectively.
+	shortMaxTarget  int32 = 12
+	mediumMaxTarget int32 = 24
+
+	// halfTargetSuccessPct, targetSuccessPct and doubleTargetSuccessPct are
+	// the success percentages required by smart fee estimates at half, at
+	// exactly and at double the confirmation target.
+	halfTargetSuccessPct   = 0.6
+	targetSuccessPct       = 0.85
+	doubleTargetSuccessPct = 0.95
+
 	// maxAllowedBucketFees is an upper bound of how many bucket fees can be
 	// used in the estimator. This is verified during estimator initialization
 	// and database loading.
@@ -65,6 +85,44 @@ var (
 	dbKeyMaxConfirms  = []byte("maxConfirms")
 	dbKeyBestHeight   = []byte("bestHeight")
 	dbKeyBucketPrefix = []byte{0x01, 0x70, 0x1d, 0x00}
+
+	dbKeyShortBucketPrefix  = []byte{0x01, 0x70, 0x1d, 0x01}
+	dbKeyMediumBucketPrefix = []byte{0x01, 0x70, 0x1d, 0x02}
+
+	// horizonDecays are the decays applied to each horizon on every block.
+	horizonDecays = [numHorizons]float64{shortDecay, mediumDecay, defaultDecay}
+
+	// dbKeyHorizonPrefixes are the bucket key prefixes of each horizon.  The
+	// long horizon uses the bucket prefix of the version 1 database.
+	dbKeyHorizonPrefixes = [numHorizons][]byte{dbKeyShortBucketPrefix,
+		dbKeyMediumBucketPrefix, dbKeyBucketPrefix}
+)
+
+// feeHorizon identifies one of the sets of confirmation statistics tracked by
+// the estimator.  Each horizon decays old transactions at a different rate, so
+// the short horizon quickly reacts to changes in fee rates while the long
+// horizon gives more stable estimates for higher confirmation targets.
+type feeHorizon int
+
+const (
+	shortHorizon feeHorizon = iota
+	mediumHorizon
+	longHorizon
+	numHorizons
+)
+
+// EstimateMode defines the modes of smart fee estimation.
+type EstimateMode int
+
+const (
+	// EstimateConservative requests an estimate which also considers the
+	// long horizon, so it is less responsive to short term drops in fee
+	// rates.
+	EstimateConservative EstimateMode = iota
+
+	// EstimateEconomical requests an estimate which is more responsive to
+	// short term drops in fee rates.
+	EstimateEconomical
 )
 
 // ErrTargetConfTooLarge is the type of error returned when an user of the
@@ -149,8 +207,9 @@ type Estimator struct {
 	// bucketFeeBounds are the upper bounds for each individual fee bucket.
 	bucketFeeBounds []feeRate
 
-	// buckets are the confirmed tx count and fee sum by bucket fee.
-	buckets []txConfirmStatBucket
+	// buckets are the confirmed tx count and fee sum by bucket fee for each
+	// horizon.
+	buckets [numHorizons][]txConfirmStatBucket
 
 	// memPool are the mempool transaction count and fee sum by bucket fee.
 	memPool []txConfirmStatBucket
@@ -159,7 +218,6 @@ type Estimator struct {
 	memPoolTxs map[chainhash.Hash]memPoolTxDesc
 
 	maxConfirms int32
-	decay       float64
 	bestHeight  int32
 	db          *leveldb.DB
 	lock        sync.RWMutex
@@ -185,7 +243,6 @@ func NewEstimator(cfg *EstimatorConfig) (*Estimator, error) {
 			"maximum allowed (%d)", cfg.MaxConfirms, maxAllowedConfirms)
 	}
 
-	decay := defaultDecay
 	maxConfirms := cfg.MaxConfirms
 	max := float64(cfg.MaxBucketFee)
 	var bucketFees []feeRate
@@ -207,21 +264,13 @@ func NewEstimator(cfg *EstimatorConfig) (*Estimator, error) {
 	nbBuckets := len(bucketFees)
 	res := &Estimator{
 		bucketFeeBounds: bucketFees,
-		buckets:         make([]txConfirmStatBucket, nbBuckets),
-		memPool:         make([]txConfirmStatBucket, nbBuckets),
+		memPool:         newConfirmStatBuckets(nbBuckets, int32(maxConfirms)),
 		maxConfirms:     int32(maxConfirms),
-		decay:           decay,
 		memPoolTxs:      make(map[chainhash.Hash]memPoolTxDesc),
 		bestHeight:      -1,
 	}
-
-	for i := range bucketFees {
-		res.buckets[i] = txConfirmStatBucket{
-			confirmed: make([]txConfirmStatBucketCount, maxConfirms),
-		}
-		res.memPool[i] = txConfirmStatBucket{
-			confirmed: make([]txConfirmStatBucketCount, maxConfirms),
-		}
+	for h := range res.buckets {
+		res.buckets[h] = newConfirmStatBuckets(nbBuckets, int32(maxConfirms))
 	}
 
 	if cfg.DatabaseFile != "" {
@@ -241,8 +290,21 @@ func NewEstimator(cfg *EstimatorConfig) (*Estimator, error) {
 	return res, nil
 }
 
-// DumpBuckets returns the internal estimator state as a string.
+// newConfirmStatBuckets returns nbBuckets empty buckets tracking maxConfirms
+// confirmation ranges.
+func newConfirmStatBuckets(nbBuckets int, maxConfirms int32) []txConfirmStatBucket {
+	buckets := make([]txConfirmStatBucket, nbBuckets)
+	for i := range buckets {
+		buckets[i].confirmed = make([]txConfirmStatBucketCount, maxConfirms)
+	}
+	return buckets
+}
+
+// DumpBuckets returns the internal estimator state of the long horizon as a
+// string.
 func (stats *Estimator) DumpBuckets() string {
+	buckets := stats.buckets[longHorizon]
+
 	res := "          |"
 	for c := 0; c < int(stats.maxConfirms); c++ {
 		if c == int(stats.maxConfirms)-1 {
@@ -258,10 +320,10 @@ func (stats *Estimator) DumpBuckets() string {
 		res += fmt.Sprintf("%10.8f", stats.bucketFeeBounds[i]/1e8)
 		for c := 0; c < int(stats.maxConfirms); c++ {
 			avg := float64(0)
-			count := stats.buckets[i].confirmed[c].txCount
-			if stats.buckets[i].confirmed[c].txCount > 0 {
-				avg = stats.buckets[i].confirmed[c].feeSum /
-					stats.buckets[i].confirmed[c].txCount / 1e8
+			count := buckets[i].confirmed[c].txCount
+			if buckets[i].confirmed[c].txCount > 0 {
+				avg = buckets[i].confirmed[c].feeSum /
+					buckets[i].confirmed[c].txCount / 1e8
 			}
 
 			res += fmt.Sprintf("| %.8f %6.1f", avg, count)
@@ -291,6 +353,9 @@ func (stats *Estimator) DumpBuckets() string {
 // deleting it) so that the new parameters are used. In the future it might be
 // possible to load from a different set of configuration parameters.
 //
+// Databases of version 1 only stored the long horizon, so they are upgraded by
+// starting the short and medium horizons empty.
+//
 // The current code does not currently save mempool information, since saving
 // information in the estimator without saving the corresponding data in the
 // mempool itself could result in transactions lingering in the mempool
@@ -302,7 +367,7 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 
 	// Database version is currently hardcoded here as this is the only
 	// place that uses it.
-	currentDbVersion := []byte{1}
+	currentDbVersion := []byte{2}
 
 	version, err := stats.db.Get(dbKeyVersion, nil)
 	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
@@ -346,7 +411,8 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 		return nil
 	}
 
-	if !bytes.Equal(currentDbVersion, version) {
+	upgradeV1 := bytes.Equal(version, []byte{1})
+	if !upgradeV1 && !bytes.Equal(currentDbVersion, version) {
 		return fmt.Errorf("incompatible database version: %d", version)
 	}
 
@@ -402,10 +468,48 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 		}
 	}
 
-	fileBuckets := make([]txConfirmStatBucket, fileNbBucketFees)
+	var fileBuckets [numHorizons][]txConfirmStatBucket
+	for h := range fileBuckets {
+		fileBuckets[h] = newConfirmStatBuckets(fileNbBucketFees,
+			fileMaxConfirms)
+		if upgradeV1 && feeHorizon(h) != longHorizon {
+			continue
+		}
+		err = stats.loadBuckets(dbKeyHorizonPrefixes[h], fileBuckets[h],
+			fileMaxConfirms)
+		if err != nil {
+			return err
+		}
+	}
+
+	stats.bucketFeeBounds = fileBucketFees
+	stats.buckets = fileBuckets
+	stats.maxConfirms = fileMaxConfirms
+
+	if upgradeV1 {
+		err = stats.updateDatabase()
+		if err != nil {
+			return fmt.Errorf("error upgrading estimator db: %v", err)
+		}
+		err = stats.db.Put(dbKeyVersion, currentDbVersion, nil)
+		if err != nil {
+			return fmt.Errorf("error writing estimator db version: %v",
+				err)
+		}
+		log.Infof("Upgraded fee estimator database to version %d",
+			currentDbVersion[0])
+	}
+
+	log.Debug("Loaded fee estimator database")
 
-	iter := stats.db.NewIterator(ldbutil.BytesPrefix(dbKeyBucketPrefix), nil)
-	err = nil
+	return nil
+}
+
+// loadBuckets reads the buckets stored under the passed key prefix into
+// buckets, which must have room for every bucket index stored in the database.
+func (stats *Estimator) loadBuckets(prefix []byte, buckets []txConfirmStatBucket, maxConfirms int32) error {
+	iter := stats.db.NewIterator(ldbutil.BytesPrefix(prefix), nil)
+	var err error
 	var fbytes [8]byte
 	for iter.Next() {
 		key := iter.Key()
@@ -415,13 +519,13 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 			break
 		}
 		idx := int(int32(dbByteOrder.Uint32(key[4:])))
-		if (idx >= len(fileBuckets)) || (idx < 0) {
+		if (idx >= len(buckets)) || (idx < 0) {
 			err = fmt.Errorf("wrong bucket index read from db (%d vs %d)",
-				idx, len(fileBuckets))
+				idx, len(buckets))
 			break
 		}
 		value := iter.Value()
-		if len(value) != 8+8+int(fileMaxConfirms)*16 {
+		if len(value) != 8+8+int(maxConfirms)*16 {
 			err = errors.New("wrong size of data in bucket read from db")
 			break
 		}
@@ -435,12 +539,11 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 			return math.Float64frombits(dbByteOrder.Uint64(fbytes[:]))
 		}
 
-		fileBuckets[idx].confirmCount = readf()
-		fileBuckets[idx].feeSum = readf()
-		fileBuckets[idx].confirmed = make([]txConfirmStatBucketCount, fileMaxConfirms)
-		for i := range fileBuckets[idx].confirmed {
-			fileBuckets[idx].confirmed[i].txCount = readf()
-			fileBuckets[idx].confirmed[i].feeSum = readf()
+		buckets[idx].confirmCount = readf()
+		buckets[idx].feeSum = readf()
+		for i := range buckets[idx].confirmed {
+			buckets[idx].confirmed[i].txCount = readf()
+			buckets[idx].confirmed[i].feeSum = readf()
 		}
 	}
 	iter.Release()
@@ -452,11 +555,6 @@ func (stats *Estimator) loadFromDatabase(replaceBuckets bool) error {
 		return fmt.Errorf("error on bucket iterator: %v", err)
 	}
 
-	stats.bucketFeeBounds = fileBucketFees
-	stats.buckets = fileBuckets
-	stats.maxConfirms = fileMaxConfirms
-	log.Debug("Loaded fee estimator database")
-
 	return nil
 }
 
@@ -472,7 +570,6 @@ func (stats *Estimator) updateDatabase() error {
 	buf := bytes.NewBuffer(nil)
 
 	var key [8]byte
-	copy(key[:], dbKeyBucketPrefix)
 	var fbytes [8]byte
 	writef := func(f float64) {
 		dbByteOrder.PutUint64(fbytes[:], math.Float64bits(f))
@@ -482,16 +579,19 @@ func (stats *Estimator) updateDatabase() error {
 		}
 	}
 
-	for i, b := range stats.buckets {
-		dbByteOrder.PutUint32(key[4:], uint32(i))
-		buf.Reset()
-		writef(b.confirmCount)
-		writef(b.feeSum)
-		for _, c := range b.confirmed {
-			writef(c.txCount)
-			writef(c.feeSum)
+	for h, buckets := range stats.buckets {
+		copy(key[:], dbKeyHorizonPrefixes[h])
+		for i, b := range buckets {
+			dbByteOrder.PutUint32(key[4:], uint32(i))
+			buf.Reset()
+			writef(b.confirmCount)
+			writef(b.feeSum)
+			for _, c := range b.confirmed {
+				writef(c.txCount)
+				writef(c.feeSum)
+			}
+			batch.Put(key[:], buf.Bytes())
 		}
-		batch.Put(key[:], buf.Bytes())
 	}
 
 	var bestHeightBytes [8]byte
@@ -538,14 +638,17 @@ func (stats *Estimator) updateMovingAverages(newHeight int32) {
 
 	// decay the existing stats so that, over time, we rely on more up to date
 	// information regarding fees.
-	for b := 0; b < len(stats.buckets); b++ {
-		bucket := &stats.buckets[b]
-		bucket.feeSum *= stats.decay
-		bucket.confirmCount *= stats.decay
-		for c := 0; c < len(bucket.confirmed); c++ {
-			conf := &bucket.confirmed[c]
-			conf.feeSum *= stats.decay
-			conf.txCount *= stats.decay
+	for h, buckets := range stats.buckets {
+		decay := horizonDecays[h]
+		for b := 0; b < len(buckets); b++ {
+			bucket := &buckets[b]
+			bucket.feeSum *= decay
+			bucket.confirmCount *= decay
+			for c := 0; c < len(bucket.confirmed); c++ {
+				conf := &bucket.confirmed[c]
+				conf.feeSum *= decay
+				conf.txCount *= decay
+			}
 		}
 	}
 
@@ -594,20 +697,23 @@ func (stats *Estimator) newMemPoolTx(bucketIdx int32, fees feeRate) {
 func (stats *Estimator) newMinedTx(blocksToConfirm int32, rate feeRate) {
 	bucketIdx := stats.lowerBucket(rate)
 	confirmIdx := stats.confirmRange(blocksToConfirm)
-	bucket := &stats.buckets[bucketIdx]
-
-	// increase the counts for all confirmation ranges starting at the first
-	// confirmIdx because it took at least `blocksToConfirm` for this tx to be
-	// mined. This is used to simplify the bucket selection during estimation,
-	// so that we only need to check a single confirmation range (instead of
-	// iterating to sum all confirmations with <= `minConfs`).
-	for c := int(confirmIdx); c < len(bucket.confirmed); c++ {
-		conf := &bucket.confirmed[c]
-		conf.feeSum += float64(rate)
-		conf.txCount++
+	for h := range stats.buckets {
+		bucket := &stats.buckets[h][bucketIdx]
+
+		// increase the counts for all confirmation ranges starting at the
+		// first confirmIdx because it took at least `blocksToConfirm` for
+		// this tx to be mined. This is used to simplify the bucket
+		// selection during estimation, so that we only need to check a
+		// single confirmation range (instead of iterating to sum all
+		// confirmations with <= `minConfs`).
+		for c := int(confirmIdx); c < len(bucket.confirmed); c++ {
+			conf := &bucket.confirmed[c]
+			conf.feeSum += float64(rate)
+			conf.txCount++
+		}
+		bucket.confirmCount++
+		bucket.feeSum += float64(rate)
 	}
-	bucket.confirmCount++
-	bucket.feeSum += float64(rate)
 }
 
 func (stats *Estimator) removeFromMemPool(blocksInMemPool int32, rate feeRate) {
@@ -633,7 +739,7 @@ func (stats *Estimator) removeFromMemPool(blocksInMemPool int32, rate feeRate) {
 }
 
 // estimateMedianFee estimates the median fee rate for the current recorded
-// statistics such that at least successPct transactions have been mined on all
+// statistics of the given horizon such that at least successPct transactions have been mined on all
 // tracked fee rate buckets with fee >= to the median.
 // In other words, this is the median fee of the lowest bucket such that it and
 // all higher fee buckets have >= successPct transactions confirmed in at most
@@ -643,7 +749,7 @@ func (stats *Estimator) removeFromMemPool(blocksInMemPool int32, rate feeRate) {
 // or there are not enough recorded statistics to derive a successful estimate
 // (eg: confirmation tracking has only started or there was a period of very few
 // transactions). In those situations, the appropriate error is returned.
-func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64) (feeRate, error) {
+func (stats *Estimator) estimateMedianFee(horizon feeHorizon, targetConfs int32, successPct float64) (feeRate, error) {
 	if targetConfs <= 0 {
 		return 0, errors.New("target confirmation range cannot be <= 0")
 	}
@@ -658,7 +764,8 @@ func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64)
 			ReqConfirms: targetConfs}
 	}
 
-	startIdx := len(stats.buckets) - 1
+	buckets := stats.buckets[horizon]
+	startIdx := len(buckets) - 1
 	confirmRangeIdx := stats.confirmRange(targetConfs)
 
 	var totalTxs, confirmedTxs float64
@@ -667,8 +774,8 @@ func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64)
 	curBucketsEnd := startIdx
 
 	for b := startIdx; b >= 0; b-- {
-		totalTxs += stats.buckets[b].confirmCount
-		confirmedTxs += stats.buckets[b].confirmed[confirmRangeIdx].txCount
+		totalTxs += buckets[b].confirmCount
+		confirmedTxs += buckets[b].confirmed[confirmRangeIdx].txCount
 
 		// Add the mempool (unconfirmed) transactions to the total tx count
 		// since a very large mempool for the given bucket might mean that
@@ -693,17 +800,17 @@ func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64)
 
 	txCount := float64(0)
 	for b := bestBucketsStt; b <= bestBucketsEnd; b++ {
-		txCount += stats.buckets[b].confirmCount
+		txCount += buckets[b].confirmCount
 	}
 	if txCount <= 0 {
 		return 0, ErrNotEnoughTxsForEstimate
 	}
 	txCount /= 2
 	for b := bestBucketsStt; b <= bestBucketsEnd; b++ {
-		if stats.buckets[b].confirmCount < txCount {
-			txCount -= stats.buckets[b].confirmCount
+		if buckets[b].confirmCount < txCount {
+			txCount -= buckets[b].confirmCount
 		} else {
-			median := stats.buckets[b].feeSum / stats.buckets[b].confirmCount
+			median := buckets[b].feeSum / buckets[b].confirmCount
 			return feeRate(median), nil
 		}
 	}
@@ -711,6 +818,101 @@ func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64)
 	return 0, errors.New("this isn't supposed to be reached")
 }
 
+// horizonMaxTarget returns the highest confirmation target estimated using the
+// given horizon.
+func (stats *Estimator) horizonMaxTarget(horizon feeHorizon) int32 {
+	maxTarget := stats.maxConfirms
+	switch horizon {
+	case shortHorizon:
+		maxTarget = shortMaxTarget
+	case mediumHorizon:
+		maxTarget = mediumMaxTarget
+	}
+	if maxTarget > stats.maxConfirms {
+		return stats.maxConfirms
+	}
+	return maxTarget
+}
+
+// estimateCombinedFee estimates the fee rate for the given confirmation target
+// and success percentage using the shortest horizon tracking the target.  When
+// checkShorterHorizons is set, the estimates of the shorter horizons at their
+// highest targets are also considered and the highest fee rate is returned,
+// since they react faster to rising fee rates.
+func (stats *Estimator) estimateCombinedFee(targetConfs int32, successPct float64, checkShorterHorizons bool) (feeRate, error) {
+	horizon := shortHorizon
+	for horizon < longHorizon && targetConfs > stats.horizonMaxTarget(horizon) {
+		horizon++
+	}
+
+	rate, err := stats.estimateMedianFee(horizon, targetConfs, successPct)
+	if !checkShorterHorizons {
+		return rate, err
+	}
+	for h := shortHorizon; h < horizon; h++ {
+		shortRate, shortErr := stats.estimateMedianFee(h,
+			stats.horizonMaxTarget(h), successPct)
+		if shortErr == nil && (err != nil || shortRate > rate) {
+			rate, err = shortRate, nil
+		}
+	}
+	return rate, err
+}
+
+// estimateSmartFee estimates the fee rate for the given confirmation target
+// the same way as CBlockPolicyEstimator::estimateSmartFee of Bitcoin Core.
+// It returns the highest of the estimates at half the target with a 60%
+// success rate, at the target with an 85% success rate and at double the
+// target with a 95% success rate.  Conservative estimates also account for the
+// long horizon at double the target.
+func (stats *Estimator) estimateSmartFee(targetConfs int32, mode EstimateMode) (feeRate, error) {
+	var best feeRate
+	var found bool
+	consider := func(rate feeRate, err error) {
+		if err == nil && (!found || rate > best) {
+			best, found = rate, true
+		}
+	}
+
+	halfTarget := targetConfs / 2
+	if halfTarget < 1 {
+		halfTarget = 1
+	}
+	consider(stats.estimateCombinedFee(halfTarget, halfTargetSuccessPct, true))
+
+	rate, err := stats.estimateCombinedFee(targetConfs, targetSuccessPct, true)
+	consider(rate, err)
+
+	doubleTarget := targetConfs * 2
+	if doubleTarget > stats.maxConfirms {
+		doubleTarget = stats.maxConfirms
+	}
+	consider(stats.estimateCombinedFee(doubleTarget, doubleTargetSuccessPct,
+		mode == EstimateConservative))
+	if mode == EstimateConservative {
+		consider(stats.estimateMedianFee(longHorizon, doubleTarget,
+			doubleTargetSuccessPct))
+	}
+
+	if !found {
+		return 0, err
+	}
+	return best, nil
+}
+
+// amount rounds the passed fee rate to an amount which is never lower than the
+// minimum bucket fee.
+func (stats *Estimator) amount(rate feeRate) lbcutil.Amount {
+	rate = feeRate(math.Round(float64(rate)))
+	if rate < stats.bucketFeeBounds[0] {
+		// Prevent our public facing api to ever return something lower than the
+		// minimum fee
+		rate = stats.bucketFeeBounds[0]
+	}
+
+	return lbcutil.Amount(rate)
+}
+
 // EstimateFee is the public version of estimateMedianFee. It calculates the
 // suggested fee for a transaction to be confirmed in at most `targetConf`
 // blocks after publishing with a high degree of certainty.
@@ -719,21 +921,53 @@ func (stats *Estimator) estimateMedianFee(targetConfs int32, successPct float64)
 // until concurrent modifications to the internal database state are complete.
 func (stats *Estimator) EstimateFee(targetConfs int32) (lbcutil.Amount, error) {
 	stats.lock.RLock()
-	rate, err := stats.estimateMedianFee(targetConfs, 0.95)
+	rate, err := stats.estimateMedianFee(longHorizon, targetConfs,
+		doubleTargetSuccessPct)
 	stats.lock.RUnlock()
 
 	if err != nil {
 		return 0, err
 	}
 
-	rate = feeRate(math.Round(float64(rate)))
-	if rate < stats.bucketFeeBounds[0] {
-		// Prevent our public facing api to ever return something lower than the
-		// minimum fee
-		rate = stats.bucketFeeBounds[0]
+	return stats.amount(rate), nil
+}
+
+// EstimateSmartFee calculates the suggested fee rate for a transaction to be
+// confirmed within targetConfs blocks using the given estimation mode.  A
+// target of 1 is estimated as 2 and targets higher than the tracked
+// confirmation ranges are capped.  When there is not enough data for the
+// target, increasingly higher targets are tried, so the target the estimate is
+// valid for is returned along with the fee rate.
+//
+// This function is safe to be called from multiple goroutines but might block
+// until concurrent modifications to the internal database state are complete.
+func (stats *Estimator) EstimateSmartFee(targetConfs int32, mode EstimateMode) (lbcutil.Amount, int32, error) {
+	if targetConfs <= 0 {
+		return 0, 0, errors.New("target confirmation range cannot be <= 0")
+	}
+
+	stats.lock.RLock()
+	defer stats.lock.RUnlock()
+
+	// Transactions can't reliably be expected in the very next block, so
+	// estimate for at least two blocks.
+	if targetConfs == 1 {
+		targetConfs = 2
+	}
+	if targetConfs > stats.maxConfirms {
+		targetConfs = stats.maxConfirms
+	}
+
+	err := ErrNotEnoughTxsForEstimate
+	for target := targetConfs; target <= stats.maxConfirms; target++ {
+		var rate feeRate
+		rate, err = stats.estimateSmartFee(target, mode)
+		if err == nil {
+			return stats.amount(rate), target, nil
+		}
 	}
 
-	return lbcutil.Amount(rate), nil
+	return 0, 0, err
 }
 
 // Enable establishes the current best height of the blockchain after
@@ -0,0 +1,180 @@
+// Copyright (c) 2018-2020 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package fees
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	ldbutil "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// testEstimatorConfig returns the configuration of the estimators used in the
+// tests, which is backed by the passed database file when it's not empty.
+func testEstimatorConfig(dbFile string) *EstimatorConfig {
+	return &EstimatorConfig{
+		MaxConfirms:  DefaultMaxConfirmations,
+		MinBucketFee: 1000,
+		MaxBucketFee: 100000,
+		FeeRateStep:  DefaultFeeRateStep,
+		DatabaseFile: dbFile,
+	}
+}
+
+// addMinedTxs records n transactions of the passed fee rate mined after
+// blocksToConfirm blocks in the passed horizon only.
+func addMinedTxs(stats *Estimator, horizon feeHorizon, blocksToConfirm int32,
+	rate feeRate, n float64) {
+
+	bucket := &stats.buckets[horizon][stats.lowerBucket(rate)]
+	for c := stats.confirmRange(blocksToConfirm); c < stats.maxConfirms; c++ {
+		bucket.confirmed[c].txCount += n
+		bucket.confirmed[c].feeSum += float64(rate) * n
+	}
+	bucket.confirmCount += n
+	bucket.feeSum += float64(rate) * n
+}
+
+// TestEstimatorUpgradeV1 ensures a database of version 1, which only stored the
+// long horizon, is upgraded by keeping the long horizon and starting the short
+// and medium horizons empty, and that the upgraded database loads again.
+func TestEstimatorUpgradeV1(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "feesdb")
+	stats, err := NewEstimator(testEstimatorConfig(dbFile))
+	if err != nil {
+		t.Fatalf("NewEstimator: unexpected error: %v", err)
+	}
+	addMinedTxs(stats, longHorizon, 2, 50000, 10)
+	addMinedTxs(stats, shortHorizon, 1, 10000, 10)
+	if err := stats.updateDatabase(); err != nil {
+		t.Fatalf("updateDatabase: unexpected error: %v", err)
+	}
+	wantLong := stats.buckets[longHorizon]
+	wantEmpty := newConfirmStatBuckets(len(stats.bucketFeeBounds),
+		stats.maxConfirms)
+
+	// Turn the database into a version 1 database by dropping the short and
+	// medium horizons.
+	for _, prefix := range [][]byte{dbKeyShortBucketPrefix,
+		dbKeyMediumBucketPrefix} {
+
+		iter := stats.db.NewIterator(ldbutil.BytesPrefix(prefix), nil)
+		for iter.Next() {
+			if err := stats.db.Delete(iter.Key(), nil); err != nil {
+				t.Fatalf("Delete: unexpected error: %v", err)
+			}
+		}
+		iter.Release()
+	}
+	if err := stats.db.Put(dbKeyVersion, []byte{1}, nil); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	stats.Close()
+
+	// The upgrade is persisted, so loading the database once more gives the
+	// same horizons.
+	for i := 0; i < 2; i++ {
+		stats, err = NewEstimator(testEstimatorConfig(dbFile))
+		if err != nil {
+			t.Fatalf("NewEstimator #%d: unexpected error: %v", i, err)
+		}
+		version, err := stats.db.Get(dbKeyVersion, nil)
+		if err != nil || !bytes.Equal(version, []byte{2}) {
+			t.Fatalf("#%d: got version %v (err %v), want 2", i,
+				version, err)
+		}
+		if !reflect.DeepEqual(stats.buckets[longHorizon], wantLong) {
+			t.Fatalf("#%d: long horizon changed by the upgrade", i)
+		}
+		for _, h := range []feeHorizon{shortHorizon, mediumHorizon} {
+			if !reflect.DeepEqual(stats.buckets[h], wantEmpty) {
+				t.Fatalf("#%d: horizon %d isn't empty after the "+
+					"upgrade", i, h)
+			}
+		}
+
+		// Only the long horizon tracks data, so the estimates are
+		// for the lowest target whose double target uses it.
+		rate, target, err := stats.EstimateSmartFee(2,
+			EstimateEconomical)
+		if err != nil {
+			t.Fatalf("#%d: EstimateSmartFee: unexpected error: %v",
+				i, err)
+		}
+		if rate != 50000 || target != mediumMaxTarget/2+1 {
+			t.Fatalf("#%d: got estimate %v for target %d, want %v "+
+				"for target %d", i, int64(rate), target, 50000,
+				mediumMaxTarget/2+1)
+		}
+		stats.Close()
+	}
+}
+
+// TestEstimateSmartFeeModes ensures conservative estimates account for the
+// long horizon while economical estimates follow the short horizon, so they
+// react to a drop of the fee rates.
+func TestEstimateSmartFeeModes(t *testing.T) {
+	stats, err := NewEstimator(testEstimatorConfig(""))
+	if err != nil {
+		t.Fatalf("NewEstimator: unexpected error: %v", err)
+	}
+
+	_, _, err = stats.EstimateSmartFee(4, EstimateEconomical)
+	if err != ErrNotEnoughTxsForEstimate {
+		t.Fatalf("EstimateSmartFee: unexpected error without data: %v",
+			err)
+	}
+	if _, _, err := stats.EstimateSmartFee(0, EstimateEconomical); err == nil {
+		t.Fatal("EstimateSmartFee: expected error for target 0")
+	}
+
+	// Fee rates dropped recently, so the short horizon only tracks lower
+	// fee rates than the long one.
+	addMinedTxs(stats, shortHorizon, 1, 10000, 10)
+	addMinedTxs(stats, mediumHorizon, 1, 10000, 10)
+	addMinedTxs(stats, longHorizon, 1, 50000, 10)
+
+	tests := []struct {
+		target     int32
+		mode       EstimateMode
+		wantRate   int64
+		wantTarget int32
+	}{
+		{target: 4, mode: EstimateEconomical, wantRate: 10000,
+			wantTarget: 4},
+		{target: 4, mode: EstimateConservative, wantRate: 50000,
+			wantTarget: 4},
+
+		// Estimates are for at least two blocks.
+		{target: 1, mode: EstimateEconomical, wantRate: 10000,
+			wantTarget: 2},
+
+		// Targets using the long horizon give the same estimate in both
+		// modes.
+		{target: 30, mode: EstimateEconomical, wantRate: 50000,
+			wantTarget: 30},
+		{target: 30, mode: EstimateConservative, wantRate: 50000,
+			wantTarget: 30},
+
+		// Targets higher than tracked are capped.
+		{target: 100, mode: EstimateEconomical, wantRate: 50000,
+			wantTarget: int32(DefaultMaxConfirmations)},
+	}
+	for i, test := range tests {
+		rate, target, err := stats.EstimateSmartFee(test.target,
+			test.mode)
+		if err != nil {
+			t.Fatalf("#%d: EstimateSmartFee: unexpected error: %v", i,
+				err)
+		}
+		if int64(rate) != test.wantRate || target != test.wantTarget {
+			t.Fatalf("#%d: got estimate %v for target %d, want %v "+
+				"for target %d", i, int64(rate), target,
+				test.wantRate, test.wantTarget)
+		}
+	}
+}
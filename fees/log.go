@@ -13,6 +13,11 @@ import (
 // The default amount of logging is none.
 var log btclog.Logger
 
+// The default amount of logging is none.
+func init() {
+	DisableLog()
+}
+
 // DisableLog disables all library log output.  Logging output is disabled
 // by default until either UseLogger or SetLogWriter are called.
 func DisableLog() {
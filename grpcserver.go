@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	lbcdrpc "github.com/lbryio/lbcd/rpc/proto"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// blockSubscriberBufferSize is the number of block notifications queued for a
+// SubscribeBlocks stream before the subscriber is dropped for being too slow.
+const blockSubscriberBufferSize = 100
+
+// grpcMethods maps the full names of the gRPC methods to the equivalent
+// JSON-RPC methods, which determine whether limited users may call them.
+var grpcMethods = map[string]string{
+	"/lbcd.rpc.Lbcd/GetBlock":          "getblock",
+	"/lbcd.rpc.Lbcd/GetRawTransaction": "getrawtransaction",
+	"/lbcd.rpc.Lbcd/GetClaimsForName":  "getclaimsforname",
+	"/lbcd.rpc.Lbcd/SubscribeBlocks":   "notifyblocks",
+}
+
+// grpcServer provides a gRPC interface to a subset of the JSON-RPC API.  It
+// shares the credentials, the TLS settings and the command handlers of the
+// RPC server.
+type grpcServer struct {
+	lbcdrpc.UnimplementedLbcdServer
+
+	started   int32
+	shutdown  int32
+	rpc       *rpcServer
+	server    *grpc.Server
+	listeners []net.Listener
+	wg        sync.WaitGroup
+	quit      chan struct{}
+
+	subscribersMtx sync.Mutex
+	subscribers    map[chan *lbcdrpc.BlockNotification]struct{}
+}
+
+// newGRPCServer returns a new gRPC server serving on the passed listeners.
+// TLS is disabled when tlsConfig is nil.
+func newGRPCServer(rpc *rpcServer, listeners []net.Listener,
+	tlsConfig *tls.Config) *grpcServer {
+
+	g := &grpcServer{
+		rpc:         rpc,
+		listeners:   listeners,
+		quit:        make(chan struct{}),
+		subscribers: make(map[chan *lbcdrpc.BlockNotification]struct{}),
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(g.unaryAuthInterceptor),
+		grpc.StreamInterceptor(g.streamAuthInterceptor),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	g.server = grpc.NewServer(opts...)
+	lbcdrpc.RegisterLbcdServer(g.server, g)
+
+	rpc.cfg.Chain.Subscribe(g.handleBlockchainNotification)
+
+	return g
+}
+
+// Start begins serving gRPC requests on the listeners of the server.
+func (g *grpcServer) Start() {
+	if atomic.AddInt32(&g.started, 1) != 1 {
+		return
+	}
+
+	for _, listener := range g.listeners {
+		g.wg.Add(1)
+		go func(listener net.Listener) {
+			grpcLog.Infof("gRPC server listening on %s", listener.Addr())
+			if err := g.server.Serve(listener); err != nil {
+				grpcLog.Errorf("gRPC server on %s stopped: %v",
+					listener.Addr(), err)
+			}
+			g.wg.Done()
+		}(listener)
+	}
+}
+
+// Stop closes the listeners and the connections of the server, ending the
+// block subscriptions.
+func (g *grpcServer) Stop() {
+	if atomic.AddInt32(&g.shutdown, 1) != 1 {
+		grpcLog.Infof("gRPC server is already in the process of shutting " +
+			"down")
+		return
+	}
+
+	grpcLog.Warnf("gRPC server shutting down")
+	close(g.quit)
+	g.server.Stop()
+	g.wg.Wait()
+	grpcLog.Infof("gRPC server shutdown complete")
+}
+
+// authorize ensures the client of the passed context authenticated with the
+// RPC credentials and is allowed to call the passed method.
+func (g *grpcServer) authorize(ctx context.Context, fullMethod string) error {
+	var authhdr []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		authhdr = md.Get("authorization")
+	}
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+
+	_, isAdmin, methods, err := g.rpc.checkAuthHeader(authhdr, remoteAddr, true)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, "authentication failure")
+	}
+	if methods != nil {
+		if _, ok := methods[grpcMethods[fullMethod]]; !ok {
+			return status.Error(codes.PermissionDenied,
+				"user not authorized for this method")
+		}
+	} else if !isAdmin {
+		if _, ok := rpcLimited[grpcMethods[fullMethod]]; !ok {
+			return status.Error(codes.PermissionDenied,
+				"limited user not authorized for this method")
+		}
+	}
+
+	return nil
+}
+
+// unaryAuthInterceptor authorizes the unary calls before handling them.
+func (g *grpcServer) unaryAuthInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	if err := g.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuthInterceptor authorizes the streaming calls before handling them.
+func (g *grpcServer) streamAuthInterceptor(srv interface{},
+	stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+
+	if err := g.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// GetBlock returns a block of the main chain by hash or height.
+func (g *grpcServer) GetBlock(ctx context.Context,
+	req *lbcdrpc.GetBlockRequest) (*lbcdrpc.GetBlockResponse, error) {
+
+	chain := g.rpc.cfg.Chain
+
+	var hash *chainhash.Hash
+	switch block := req.Block.(type) {
+	case *lbcdrpc.GetBlockRequest_Hash:
+		var err error
+		hash, err = chainhash.NewHashFromStr(block.Hash)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"invalid block hash %q: %v", block.Hash, err)
+		}
+
+	case *lbcdrpc.GetBlockRequest_Height:
+		var err error
+		hash, err = chain.BlockHashByHeight(block.Height)
+		if err != nil {
+			return nil, status.Errorf(codes.NotFound,
+				"no block at height %d", block.Height)
+		}
+
+	default:
+		return nil, status.Error(codes.InvalidArgument,
+			"a block hash or height is required")
+	}
+
+	height, err := chain.BlockHeightByHash(hash)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "block %v is not in "+
+			"the main chain", hash)
+	}
+
+	verbosity := 0
+	result, err := handleGetBlock(g.rpc, &btcjson.GetBlockCmd{
+		Hash:      hash.String(),
+		Verbosity: &verbosity,
+	}, ctx.Done())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	blkBytes, err := hex.DecodeString(result.(string))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	var header wire.BlockHeader
+	if err := header.Deserialize(bytes.NewReader(blkBytes)); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	best := chain.BestSnapshot()
+	return &lbcdrpc.GetBlockResponse{
+		Hash:          hash.String(),
+		Height:        height,
+		Confirmations: int64(best.Height - height + 1),
+		Header:        grpcBlockHeader(&header),
+		Block:         blkBytes,
+	}, nil
+}
+
+// GetRawTransaction returns a transaction of the mempool, or of the main chain
+// when the transaction index is enabled.
+func (g *grpcServer) GetRawTransaction(ctx context.Context,
+	req *lbcdrpc.GetRawTransactionRequest) (*lbcdrpc.GetRawTransactionResponse,
+	error) {
+
+	verbose := true
+	result, err := handleGetRawTransaction(g.rpc,
+		&btcjson.GetRawTransactionCmd{Txid: req.Txid, Verbose: &verbose},
+		ctx.Done())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	rawTxn := result.(*btcjson.TxRawResult)
+	txBytes, err := hex.DecodeString(rawTxn.Hex)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &lbcdrpc.GetRawTransactionResponse{
+		Transaction:   txBytes,
+		BlockHash:     rawTxn.BlockHash,
+		Confirmations: rawTxn.Confirmations,
+	}, nil
+}
+
+// GetClaimsForName returns the claims of a name at a block of the main chain.
+func (g *grpcServer) GetClaimsForName(ctx context.Context,
+	req *lbcdrpc.GetClaimsForNameRequest) (*lbcdrpc.GetClaimsForNameResponse,
+	error) {
+
+	result, err := handleGetClaimsForName(g.rpc, &btcjson.GetClaimsForNameCmd{
+		Name:          req.Name,
+		HashOrHeight:  &req.HashOrHeight,
+		IncludeValues: &req.IncludeValues,
+	}, ctx.Done())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	claims := result.(btcjson.GetClaimsForNameResult)
+
+	resp := &lbcdrpc.GetClaimsForNameResponse{
+		Hash:               claims.Hash,
+		Height:             claims.Height,
+		LastTakeoverHeight: claims.LastTakeoverHeight,
+		NormalizedName:     claims.NormalizedName,
+		Claims:             make([]*lbcdrpc.Claim, 0, len(claims.Claims)),
+	}
+	for _, c := range claims.Claims {
+		value, err := hex.DecodeString(c.Value)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		claim := &lbcdrpc.Claim{
+			ClaimId:         c.ClaimID,
+			Txid:            c.TXID,
+			N:               c.N,
+			Bid:             c.Bid,
+			Sequence:        c.Sequence,
+			Height:          c.Height,
+			ValidAtHeight:   c.ValidAtHeight,
+			Amount:          c.Amount,
+			EffectiveAmount: c.EffectiveAmount,
+			Address:         c.Address,
+			Value:           value,
+		}
+		for _, s := range c.Supports {
+			value, err := hex.DecodeString(s.Value)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			claim.Supports = append(claim.Supports, &lbcdrpc.Support{
+				Txid:          s.TXID,
+				N:             s.N,
+				Height:        s.Height,
+				ValidAtHeight: s.ValidAtHeight,
+				Amount:        s.Amount,
+				Address:       s.Address,
+				Value:         value,
+			})
+		}
+		resp.Claims = append(resp.Claims, claim)
+	}
+
+	return resp, nil
+}
+
+// SubscribeBlocks streams a notification whenever a block is connected to or
+// disconnected from the main chain.  Subscribers which don't keep up with the
+// notifications are dropped.
+func (g *grpcServer) SubscribeBlocks(req *lbcdrpc.SubscribeBlocksRequest,
+	stream lbcdrpc.Lbcd_SubscribeBlocksServer) error {
+
+	ntfns := make(chan *lbcdrpc.BlockNotification, blockSubscriberBufferSize)
+	g.subscribersMtx.Lock()
+	g.subscribers[ntfns] = struct{}{}
+	g.subscribersMtx.Unlock()
+
+	defer func() {
+		g.subscribersMtx.Lock()
+		delete(g.subscribers, ntfns)
+		g.subscribersMtx.Unlock()
+	}()
+
+	for {
+		select {
+		case ntfn, ok := <-ntfns:
+			if !ok {
+				return status.Error(codes.ResourceExhausted,
+					"subscriber is too slow to receive the "+
+						"block notifications")
+			}
+			if err := stream.Send(ntfn); err != nil {
+				return err
+			}
+
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+
+		case <-g.quit:
+			return status.Error(codes.Unavailable, "server is "+
+				"shutting down")
+		}
+	}
+}
+
+// notifySubscribers sends the passed block notification to the block
+// subscribers.  The subscribers whose queue is full are dropped.
+func (g *grpcServer) notifySubscribers(ntfn *lbcdrpc.BlockNotification) {
+	g.subscribersMtx.Lock()
+	defer g.subscribersMtx.Unlock()
+
+	for ntfns := range g.subscribers {
+		select {
+		case ntfns <- ntfn:
+		default:
+			grpcLog.Warnf("Dropping slow block subscriber")
+			delete(g.subscribers, ntfns)
+			close(ntfns)
+		}
+	}
+}
+
+// handleBlockchainNotification notifies the block subscribers of the blocks
+// connected to and disconnected from the main chain.
+func (g *grpcServer) handleBlockchainNotification(notification *blockchain.Notification) {
+	var connected bool
+	switch notification.Type {
+	case blockchain.NTBlockConnected:
+		connected = true
+	case blockchain.NTBlockDisconnected:
+	default:
+		return
+	}
+
+	block, ok := notification.Data.(*btcutil.Block)
+	if !ok {
+		grpcLog.Warnf("Chain notification is not a block.")
+		return
+	}
+
+	g.notifySubscribers(&lbcdrpc.BlockNotification{
+		Connected: connected,
+		Hash:      block.Hash().String(),
+		Height:    block.Height(),
+		Header:    grpcBlockHeader(&block.MsgBlock().Header),
+	})
+}
+
+// grpcBlockHeader converts the passed block header to its gRPC message.
+func grpcBlockHeader(header *wire.BlockHeader) *lbcdrpc.BlockHeader {
+	return &lbcdrpc.BlockHeader{
+		Version:    header.Version,
+		PrevBlock:  header.PrevBlock.String(),
+		MerkleRoot: header.MerkleRoot.String(),
+		ClaimTrie:  header.ClaimTrie.String(),
+		Timestamp:  header.Timestamp.Unix(),
+		Bits:       header.Bits,
+		Nonce:      header.Nonce,
+	}
+}
+
+// grpcError converts an error returned by a JSON-RPC command handler to a gRPC
+// status error.
+func grpcError(err error) error {
+	rpcErr, ok := err.(*btcjson.RPCError)
+	if !ok {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	code := codes.Unknown
+	switch rpcErr.Code {
+	case btcjson.ErrRPCBlockNotFound:
+		code = codes.NotFound
+	case btcjson.ErrRPCInvalidParameter, btcjson.ErrRPCDecodeHexString:
+		code = codes.InvalidArgument
+	case btcjson.ErrRPCClientInInitialDownload:
+		code = codes.Unavailable
+	case btcjson.ErrRPCInternal.Code:
+		code = codes.Internal
+	}
+	return status.Error(code, rpcErr.Message)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/lbryio/lbcd/btcjson"
+	lbcdrpc "github.com/lbryio/lbcd/rpc/proto"
+)
+
+// TestGRPCMethods ensures every gRPC method is mapped to a JSON-RPC method,
+// which determines whether limited users are allowed to call it.
+func TestGRPCMethods(t *testing.T) {
+	desc := lbcdrpc.Lbcd_ServiceDesc
+	var names []string
+	for _, method := range desc.Methods {
+		names = append(names, method.MethodName)
+	}
+	for _, stream := range desc.Streams {
+		names = append(names, stream.StreamName)
+	}
+
+	for _, name := range names {
+		fullMethod := "/" + desc.ServiceName + "/" + name
+		rpcMethod, ok := grpcMethods[fullMethod]
+		if !ok {
+			t.Errorf("gRPC method %s is not mapped to a JSON-RPC "+
+				"method", fullMethod)
+			continue
+		}
+		_, isHandler := rpcHandlers[rpcMethod]
+		_, isWsHandler := wsHandlers[rpcMethod]
+		if !isHandler && !isWsHandler {
+			t.Errorf("gRPC method %s is mapped to unknown JSON-RPC "+
+				"method %s", fullMethod, rpcMethod)
+		}
+	}
+}
+
+// TestGRPCError ensures the errors of the JSON-RPC handlers are converted to
+// the expected gRPC status codes.
+func TestGRPCError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{&btcjson.RPCError{Code: btcjson.ErrRPCBlockNotFound}, codes.NotFound},
+		{&btcjson.RPCError{Code: btcjson.ErrRPCInvalidParameter},
+			codes.InvalidArgument},
+		{&btcjson.RPCError{Code: btcjson.ErrRPCDecodeHexString},
+			codes.InvalidArgument},
+		{&btcjson.RPCError{Code: btcjson.ErrRPCClientInInitialDownload},
+			codes.Unavailable},
+		{btcjson.ErrRPCInternal, codes.Internal},
+		{&btcjson.RPCError{Code: btcjson.ErrRPCMisc}, codes.Unknown},
+	}
+
+	for i, test := range tests {
+		got := status.Code(grpcError(test.err))
+		if got != test.code {
+			t.Errorf("test #%d: wrong code - got %v, want %v", i, got,
+				test.code)
+		}
+	}
+}
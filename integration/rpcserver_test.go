@@ -230,6 +230,9 @@ func testGetBlockStats(r *rpctest.Harness, t *testing.T) {
 				"avgfee":              int64(0),
 				"avgfeerate":          int64(0),
 				"avgtxsize":           int64(0),
+				"abandons":            int64(0),
+				"claims":              int64(0),
+				"claimupdates":        int64(0),
 				"feerate_percentiles": []int64{0, 0, 0, 0, 0},
 				"ins":                 int64(0),
 				"maxfee":              int64(0),
@@ -240,6 +243,7 @@ func testGetBlockStats(r *rpctest.Harness, t *testing.T) {
 				"minfee":              int64(0),
 				"mintxsize":           int64(0),
 				"outs":                int64(1),
+				"supports":            int64(0),
 				"swtotal_size":        int64(0),
 				"swtotal_weight":      int64(0),
 				"swtxs":               int64(0),
@@ -310,6 +314,12 @@ func testGetBlockStats(r *rpctest.Harness, t *testing.T) {
 				result = blockStats.AverageFeeRate
 			case "avgtxsize":
 				result = blockStats.AverageTxSize
+			case "abandons":
+				result = blockStats.Abandons
+			case "claims":
+				result = blockStats.Claims
+			case "claimupdates":
+				result = blockStats.ClaimUpdates
 			case "feerate_percentiles":
 				result = blockStats.FeeratePercentiles
 			case "blockhash":
@@ -338,6 +348,8 @@ func testGetBlockStats(r *rpctest.Harness, t *testing.T) {
 				result = blockStats.MinTxSize
 			case "outs":
 				result = blockStats.Outs
+			case "supports":
+				result = blockStats.Supports
 			case "swtotal_size":
 				result = blockStats.SegWitTotalSize
 			case "swtotal_weight":
@@ -15,9 +15,11 @@ import (
 	"runtime/debug"
 	"runtime/pprof"
 
+	"github.com/lbryio/lbcd/blockchain"
 	"github.com/lbryio/lbcd/blockchain/indexers"
 	"github.com/lbryio/lbcd/claimtrie/param"
 	"github.com/lbryio/lbcd/database"
+	"github.com/lbryio/lbcd/database/ffldb"
 	"github.com/lbryio/lbcd/limits"
 	"github.com/lbryio/lbcd/version"
 
@@ -159,6 +161,38 @@ func btcdMain(serverChan chan<- *server) error {
 
 		return nil
 	}
+	if cfg.DropClaimIDIndex {
+		if err := indexers.DropClaimIDIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
+	if cfg.DropChannelIndex {
+		if err := indexers.DropClaimChannelIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
+	if cfg.DropClaimFilterIndex {
+		if err := indexers.DropClaimFilterIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
+	if cfg.DropSpentIndex {
+		if err := indexers.DropSpentIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
 	if cfg.DropCfIndex {
 		if err := indexers.DropCfIndex(db, interrupt); err != nil {
 			btcdLog.Errorf("%v", err)
@@ -168,6 +202,15 @@ func btcdMain(serverChan chan<- *server) error {
 		return nil
 	}
 
+	// Reset the chain state to rebuild it from the stored blocks if
+	// requested.
+	if cfg.Reindex || cfg.ReindexChainState {
+		if err := resetChainState(db, cfg.Reindex, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+	}
+
 	param.SetNetwork(activeNetParams.Params.Net) // prep the claimtrie params
 
 	go logMemoryUsage()
@@ -181,21 +224,59 @@ func btcdMain(serverChan chan<- *server) error {
 			cfg.Listeners, err)
 		return err
 	}
-	defer func() {
-		btcdLog.Infof("Gracefully shutting down the server...")
-		server.Stop()
-		server.WaitForShutdown()
-		srvrLog.Infof("Server shutdown complete")
-		// TODO: tie into the sync manager for shutdown instead
+
+	// Check the claim trie and exit if requested.
+	if cfg.CheckClaimTrie {
+		err := checkClaimTrie(server.chain, cfg.RepairClaimTrie, interrupt)
 		if ct := server.chain.ClaimTrie(); ct != nil {
 			ct.Close()
 		}
-	}()
+		return err
+	}
+
+	// Export the metrics of the claim trie databases, and the statistics of
+	// the RPC methods when requested, on the profile server.
+	if cfg.Profile != "" {
+		var rpc *rpcServer
+		if cfg.RPCMetrics {
+			rpc = server.rpcServer
+		}
+		http.DefaultServeMux.Handle("/metrics",
+			metricsHandler(server.chain, rpc))
+	}
+
+	// The claim trie is closed, which flushes its repos, only once the
+	// server stopped, so every block connected to the chain is also
+	// applied to the claim trie.  The chain is closed before it, which
+	// saves the progress of the validation of the blocks below a loaded
+	// utxo snapshot.
+	lc := newLifecycle()
+	defer lc.Shutdown()
+	if ct := server.chain.ClaimTrie(); ct != nil {
+		lc.OnShutdown("claim trie", ct.Close)
+	}
+	lc.OnShutdown("chain", func() {
+		if err := server.chain.Close(); err != nil {
+			btcdLog.Errorf("Unable to close the chain: %v", err)
+		}
+	})
+
 	server.Start()
+	lc.OnShutdown("server", func() {
+		btcdLog.Infof("Gracefully shutting down the server...")
+		server.Stop()
+		server.WaitForShutdown()
+		srvrLog.Infof("Server shutdown complete")
+	})
 	if serverChan != nil {
 		serverChan <- server
 	}
 
+	// The block index is loaded and the RPC server is listening by now, so
+	// let the service manager know lbcd is serving.  The notification that
+	// lbcd is stopping is sent first thing on shutdown.
+	lc.OnShutdown("service manager notifications", sdNotifyReadyAndWatch())
+
 	// Wait until the interrupt signal is received from an OS signal or
 	// shutdown is requested through one of the subsystems such as the RPC
 	// server.
@@ -203,6 +284,71 @@ func btcdMain(serverChan chan<- *server) error {
 	return nil
 }
 
+// resetChainState drops the optional indexes and removes the claim trie, then
+// resets the chain state so they are all rebuilt as the stored blocks are
+// connected again while the chain is loaded.  The block index is also rebuilt
+// from the stored blocks when rebuildIndex is set.
+func resetChainState(db database.DB, rebuildIndex bool, interrupt <-chan struct{}) error {
+	// NOTE: The address index must be dropped before the tx index since
+	// it relies on it.
+	drops := []func(database.DB, <-chan struct{}) error{
+		indexers.DropAddrIndex,
+		indexers.DropTxIndex,
+		indexers.DropClaimIDIndex,
+		indexers.DropClaimChannelIndex,
+		indexers.DropSpentIndex,
+		indexers.DropClaimFilterIndex,
+		indexers.DropCfIndex,
+	}
+	for _, drop := range drops {
+		if err := drop(db, interrupt); err != nil {
+			return err
+		}
+	}
+
+	claimTrieDir := filepath.Join(cfg.DataDir, "claim_dbs")
+	btcdLog.Infof("Removing the claim trie in %s", claimTrieDir)
+	if err := os.RemoveAll(claimTrieDir); err != nil {
+		return err
+	}
+
+	return blockchain.ResetChainState(db, activeNetParams.Params,
+		rebuildIndex, interrupt)
+}
+
+// checkClaimTrie verifies the claim trie at the tip of the chain and logs the
+// result, optionally repairing the names which diverged.  An error is returned
+// when the claim trie is left inconsistent.
+func checkClaimTrie(chain *blockchain.BlockChain, repair bool, interrupt <-chan struct{}) error {
+	result, err := chain.VerifyClaimTrie(repair, interrupt)
+	if err != nil {
+		btcdLog.Errorf("Unable to verify the claim trie: %v", err)
+		return err
+	}
+
+	btcdLog.Infof("Verified %d claim trie names at height %d: expected root "+
+		"%v, stored root %v, computed root %v", result.NamesChecked,
+		result.Height, result.ExpectedRoot, result.StoredRoot,
+		result.ComputedRoot)
+	for _, name := range result.DivergentNames {
+		btcdLog.Warnf("Claim trie name %q diverges from its changes", name)
+	}
+	switch {
+	case result.Consistent():
+		btcdLog.Infof("The claim trie is consistent")
+		return nil
+
+	case result.Repaired:
+		btcdLog.Infof("Repaired %d claim trie names", result.DivergentCount)
+		return nil
+	}
+
+	err = fmt.Errorf("the claim trie is inconsistent with %d divergent names",
+		result.DivergentCount)
+	btcdLog.Errorf("%v", err)
+	return err
+}
+
 // dbPath returns the path to the block database given a database type.
 func blockDbPath(dbType string) string {
 	// The database name is based on the database type.
@@ -214,6 +360,19 @@ func blockDbPath(dbType string) string {
 	return dbPath
 }
 
+// headerFilePath returns the path to the file the block headers are stored in
+// given a database type, or an empty string when they are stored in the
+// database.  The file is kept in the directory of the database so it's part of
+// its backups.
+func headerFilePath(dbType string) string {
+	// The memdb backend does not persist anything, so the headers are kept
+	// in it as well.
+	if dbType == "memdb" {
+		return ""
+	}
+	return filepath.Join(blockDbPath(dbType), "headers.dat")
+}
+
 // warnMultipleDBs shows a warning if multiple block database types are detected.
 // This is not a situation most users want.  It is handy for development however
 // to support multiple side-by-side databases.
@@ -270,7 +429,8 @@ func loadBlockDB() (database.DB, error) {
 	// The database name is based on the database type.
 	dbPath := blockDbPath(cfg.DbType)
 	btcdLog.Infof("Loading block database from '%s'", dbPath)
-	db, err := database.Open(cfg.DbType, dbPath, activeNetParams.Net)
+	dbOpts := &ffldb.Options{CompressBlocks: cfg.BlockCompression}
+	db, err := database.Open(cfg.DbType, dbPath, activeNetParams.Net, dbOpts)
 	if err != nil {
 		// Return the error if it's not because the database doesn't
 		// exist.
@@ -285,7 +445,8 @@ func loadBlockDB() (database.DB, error) {
 		if err != nil {
 			return nil, err
 		}
-		db, err = database.Create(cfg.DbType, dbPath, activeNetParams.Net)
+		db, err = database.Create(cfg.DbType, dbPath,
+			activeNetParams.Net, dbOpts)
 		if err != nil {
 			return nil, err
 		}
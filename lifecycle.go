@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// shutdownStepWarnInterval is the interval at which a warning is logged while
+// a shutdown step is still running, to point out the subsystem holding up the
+// shutdown.
+var shutdownStepWarnInterval = 30 * time.Second
+
+// shutdownStep is a step of an orderly shutdown, which stops a subsystem.
+type shutdownStep struct {
+	name string
+	stop func()
+}
+
+// lifecycle coordinates the shutdown of subsystems which depend on each other.
+// The step stopping each subsystem is registered once it is started, and the
+// steps are run one at a time in the reverse order on shutdown.  This way each
+// subsystem drains its in-flight work while the subsystems it depends on are
+// still running.  For example, the claim trie is only closed once the sync
+// manager completed the block it was processing, so the claim trie can't be
+// left behind the block index.
+type lifecycle struct {
+	mtx      sync.Mutex
+	steps    []shutdownStep
+	shutdown bool
+	done     chan struct{}
+}
+
+// newLifecycle returns a new lifecycle without any registered step.
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		done: make(chan struct{}),
+	}
+}
+
+// OnShutdown registers the passed function to be run on shutdown to stop the
+// named subsystem.  It is run before the functions registered earlier.  The
+// function is run immediately when the shutdown already started.
+//
+// This function is safe for concurrent access.
+func (l *lifecycle) OnShutdown(name string, stop func()) {
+	l.mtx.Lock()
+	if l.shutdown {
+		l.mtx.Unlock()
+		l.runStep(shutdownStep{name: name, stop: stop})
+		return
+	}
+	l.steps = append(l.steps, shutdownStep{name: name, stop: stop})
+	l.mtx.Unlock()
+}
+
+// Shutdown runs the registered shutdown steps in the reverse order they were
+// registered.  It returns once all the steps completed, including when the
+// shutdown was started by another caller.
+//
+// This function is safe for concurrent access.
+func (l *lifecycle) Shutdown() {
+	l.mtx.Lock()
+	if l.shutdown {
+		l.mtx.Unlock()
+		<-l.done
+		return
+	}
+	l.shutdown = true
+	steps := l.steps
+	l.steps = nil
+	l.mtx.Unlock()
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		l.runStep(steps[i])
+	}
+	close(l.done)
+}
+
+// Wait blocks until the shutdown completed.
+func (l *lifecycle) Wait() {
+	<-l.done
+}
+
+// runStep runs the passed shutdown step, logging a warning periodically for as
+// long as it runs.
+func (l *lifecycle) runStep(step shutdownStep) {
+	btcdLog.Debugf("Stopping %s", step.name)
+	stopped := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(shutdownStepWarnInterval)
+		defer ticker.Stop()
+		start := time.Now()
+		for {
+			select {
+			case <-ticker.C:
+				btcdLog.Warnf("Still waiting for the %s to stop "+
+					"after %v", step.name,
+					time.Since(start).Truncate(time.Second))
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	step.stop()
+	close(stopped)
+	btcdLog.Debugf("Stopped %s", step.name)
+}
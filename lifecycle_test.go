@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestLifecycle ensures the shutdown steps are run once in the reverse order
+// they were registered, and that concurrent callers wait for the shutdown to
+// complete.
+func TestLifecycle(t *testing.T) {
+	lc := newLifecycle()
+
+	var mtx sync.Mutex
+	var stopped []string
+	step := func(name string) func() {
+		return func() {
+			mtx.Lock()
+			stopped = append(stopped, name)
+			mtx.Unlock()
+		}
+	}
+
+	// The second step only completes once the concurrent call to Shutdown
+	// started waiting.
+	release := make(chan struct{})
+	lc.OnShutdown("claim trie", step("claim trie"))
+	lc.OnShutdown("sync manager", func() {
+		<-release
+		step("sync manager")()
+	})
+	lc.OnShutdown("rpc server", step("rpc server"))
+
+	done := make(chan struct{})
+	go func() {
+		lc.Shutdown()
+		close(done)
+	}()
+	waited := make(chan struct{})
+	go func() {
+		lc.Shutdown()
+		close(waited)
+	}()
+
+	select {
+	case <-waited:
+		t.Fatal("Shutdown returned before the steps completed")
+	case <-done:
+		t.Fatal("Shutdown returned before the steps completed")
+	default:
+	}
+	close(release)
+	<-done
+	<-waited
+	lc.Wait()
+
+	want := []string{"rpc server", "sync manager", "claim trie"}
+	if !reflect.DeepEqual(stopped, want) {
+		t.Fatalf("unexpected steps - got %v, want %v", stopped, want)
+	}
+
+	// Steps registered once the shutdown started are run immediately.
+	lc.OnShutdown("late", step("late"))
+	want = append(want, "late")
+	if !reflect.DeepEqual(stopped, want) {
+		t.Fatalf("unexpected steps - got %v, want %v", stopped, want)
+	}
+}
@@ -20,8 +20,11 @@ import (
 	"github.com/lbryio/lbcd/mempool"
 	"github.com/lbryio/lbcd/mining"
 	"github.com/lbryio/lbcd/mining/cpuminer"
+	"github.com/lbryio/lbcd/mining/stratum"
 	"github.com/lbryio/lbcd/netsync"
 	"github.com/lbryio/lbcd/peer"
+	"github.com/lbryio/lbcd/portmap"
+	"github.com/lbryio/lbcd/torcontrol"
 	"github.com/lbryio/lbcd/txscript"
 
 	"github.com/btcsuite/btclog"
@@ -57,6 +60,7 @@ var (
 	logRotator *rotator.Rotator
 
 	adxrLog = backendLog.Logger("ADXR")
+	alrtLog = backendLog.Logger("ALRT")
 	amgrLog = backendLog.Logger("AMGR")
 	bcdbLog = backendLog.Logger("BCDB")
 	btcdLog = backendLog.Logger("MAIN")
@@ -64,14 +68,17 @@ var (
 	cmgrLog = backendLog.Logger("CMGR")
 	discLog = backendLog.Logger("DISC")
 	feesLog = backendLog.Logger("FEES")
+	grpcLog = backendLog.Logger("GRPC")
 	indxLog = backendLog.Logger("INDX")
 	lbryLog = backendLog.Logger("LBRY")
 	minrLog = backendLog.Logger("MINR")
 	peerLog = backendLog.Logger("PEER")
+	pmapLog = backendLog.Logger("PMAP")
 	rpcsLog = backendLog.Logger("RPCS")
 	scrpLog = backendLog.Logger("SCRP")
 	srvrLog = backendLog.Logger("SRVR")
 	syncLog = backendLog.Logger("SYNC")
+	torcLog = backendLog.Logger("TORC")
 	txmpLog = backendLog.Logger("TXMP")
 )
 
@@ -89,26 +96,33 @@ func init() {
 	netsync.UseLogger(syncLog)
 	node.UseLogger(lbryLog)
 	peer.UseLogger(peerLog)
+	portmap.UseLogger(pmapLog)
+	stratum.UseLogger(minrLog)
+	torcontrol.UseLogger(torcLog)
 	txscript.UseLogger(scrpLog)
 }
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
 var subsystemLoggers = map[string]btclog.Logger{
 	"ADXR": adxrLog,
+	"ALRT": alrtLog,
 	"AMGR": amgrLog,
 	"BCDB": bcdbLog,
 	"CHAN": chanLog,
 	"CMGR": cmgrLog,
 	"DISC": discLog,
+	"GRPC": grpcLog,
 	"INDX": indxLog,
 	"LBRY": lbryLog,
 	"MAIN": btcdLog,
 	"MINR": minrLog,
 	"PEER": peerLog,
+	"PMAP": pmapLog,
 	"RPCS": rpcsLog,
 	"SCRP": scrpLog,
 	"SRVR": srvrLog,
 	"SYNC": syncLog,
+	"TORC": torcLog,
 	"TXMP": txmpLog,
 }
 
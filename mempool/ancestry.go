@@ -0,0 +1,253 @@
+package mempool
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// packageInfo aggregates the number, virtual size and fees of a transaction in
+// the pool along with either its ancestors or its descendants in the pool.
+type packageInfo struct {
+	count int64
+	size  int64
+	fees  int64
+}
+
+// add adds the passed transaction to the aggregate.
+func (p *packageInfo) add(txD *TxDesc) {
+	p.count++
+	p.size += txD.vsize
+	p.fees += txD.Fee
+}
+
+// sub removes the passed transaction from the aggregate.
+func (p *packageInfo) sub(txD *TxDesc) {
+	p.count--
+	p.size -= txD.vsize
+	p.fees -= txD.Fee
+}
+
+// addPackageInfo initializes the package info of the passed transaction, which
+// was just added to the pool, and updates the one of its ancestors.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) addPackageInfo(txD *TxDesc) {
+	ancestors := mp.txAncestors(txD.Tx, nil)
+
+	// The transaction may be added back to the pool after transactions
+	// spending it, such as when the block including it is disconnected.
+	// The ancestors of those transactions change in ways which aren't
+	// worth tracking incrementally, so the package info of all the
+	// related transactions is recalculated instead.
+	descendants := mp.txDescendants(txD.Tx, nil)
+	if len(descendants) != 0 {
+		related := descendants
+		for hash, tx := range ancestors {
+			related[hash] = tx
+		}
+		related[*txD.Tx.Hash()] = txD.Tx
+		mp.recalcPackageInfo(related)
+		return
+	}
+
+	txD.ancestors = packageInfo{}
+	txD.ancestors.add(txD)
+	txD.descendants = packageInfo{}
+	txD.descendants.add(txD)
+	for hash := range ancestors {
+		ancestor := mp.pool[hash]
+		txD.ancestors.add(ancestor)
+		ancestor.descendants.add(txD)
+		mp.fixEviction(ancestor)
+	}
+}
+
+// removePackageInfo updates the package info of the passed ancestors and
+// descendants of the passed transaction, which was just removed from the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) removePackageInfo(txD *TxDesc, ancestors,
+	descendants map[chainhash.Hash]*btcutil.Tx) {
+
+	// Transactions are usually removed along with their descendants, which
+	// are removed first, so there is nothing else to update.  When they
+	// are removed on their own, such as when they are included in a block,
+	// the package info of the related transactions is recalculated as
+	// their ancestors may no longer be related to their descendants.
+	if len(descendants) != 0 {
+		related := descendants
+		for hash, tx := range ancestors {
+			related[hash] = tx
+		}
+		mp.recalcPackageInfo(related)
+		return
+	}
+
+	for hash := range ancestors {
+		if ancestor, ok := mp.pool[hash]; ok {
+			ancestor.descendants.sub(txD)
+			mp.fixEviction(ancestor)
+		}
+	}
+}
+
+// recalcPackageInfo recalculates the package info of the passed transactions
+// from their ancestors and descendants in the pool.  The transactions which are
+// not in the pool are ignored.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) recalcPackageInfo(txns map[chainhash.Hash]*btcutil.Tx) {
+	ancestorCache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	descendantCache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	for hash := range txns {
+		txD, ok := mp.pool[hash]
+		if !ok {
+			continue
+		}
+
+		txD.ancestors = packageInfo{}
+		txD.ancestors.add(txD)
+		for ancestorHash := range mp.txAncestors(txD.Tx, ancestorCache) {
+			txD.ancestors.add(mp.pool[ancestorHash])
+		}
+
+		txD.descendants = packageInfo{}
+		txD.descendants.add(txD)
+		for descendantHash := range mp.txDescendants(txD.Tx, descendantCache) {
+			txD.descendants.add(mp.pool[descendantHash])
+		}
+		if txD.evictIndex >= 0 {
+			mp.fixEviction(txD)
+		}
+	}
+}
+
+// mempoolEntry returns the passed transaction of the pool as a fully populated
+// btcjson result.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) mempoolEntry(txD *TxDesc) *btcjson.GetMempoolEntryResult {
+	tx := txD.Tx
+	fee := btcutil.Amount(txD.Fee).ToBTC()
+	ancestorFees := btcutil.Amount(txD.ancestors.fees).ToBTC()
+	descendantFees := btcutil.Amount(txD.descendants.fees).ToBTC()
+	entry := &btcjson.GetMempoolEntryResult{
+		VSize:           int32(txD.vsize),
+		Size:            int32(tx.MsgTx().SerializeSize()),
+		Weight:          blockchain.GetTransactionWeight(tx),
+		Fee:             fee,
+		ModifiedFee:     fee,
+		Time:            txD.Added.Unix(),
+		Height:          int64(txD.Height),
+		DescendantCount: txD.descendants.count,
+		DescendantSize:  txD.descendants.size,
+		DescendantFees:  descendantFees,
+		AncestorCount:   txD.ancestors.count,
+		AncestorSize:    txD.ancestors.size,
+		AncestorFees:    ancestorFees,
+		WTxId:           tx.WitnessHash().String(),
+		Fees: btcjson.MempoolFees{
+			Base:       fee,
+			Modified:   fee,
+			Ancestor:   ancestorFees,
+			Descendant: descendantFees,
+		},
+		Depends: make([]string, 0),
+		SpentBy: make([]string, 0),
+	}
+
+	// List the parents and children of the transaction in the pool.  A
+	// transaction may spend several outputs of the same parent.
+	seen := make(map[chainhash.Hash]struct{})
+	for _, txIn := range tx.MsgTx().TxIn {
+		hash := txIn.PreviousOutPoint.Hash
+		if _, ok := seen[hash]; ok || !mp.haveTransaction(&hash) {
+			continue
+		}
+		seen[hash] = struct{}{}
+		entry.Depends = append(entry.Depends, hash.String())
+	}
+	op := wire.OutPoint{Hash: *tx.Hash()}
+	for i := range tx.MsgTx().TxOut {
+		op.Index = uint32(i)
+		child, ok := mp.outpoints[op]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[*child.Hash()]; ok {
+			continue
+		}
+		seen[*child.Hash()] = struct{}{}
+		entry.SpentBy = append(entry.SpentBy, child.Hash().String())
+	}
+	sort.Strings(entry.Depends)
+	sort.Strings(entry.SpentBy)
+
+	return entry
+}
+
+// MempoolEntry returns the transaction with the passed hash in the pool as a
+// fully populated btcjson result, including the aggregated info of its
+// ancestors and descendants in the pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolEntry(txHash *chainhash.Hash) (*btcjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, ok := mp.pool[*txHash]
+	if !ok {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+	return mp.mempoolEntry(txD), nil
+}
+
+// MempoolAncestors returns the in-pool ancestors of the transaction with the
+// passed hash in the pool as fully populated btcjson results keyed by their
+// hash.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolAncestors(txHash *chainhash.Hash) (map[string]*btcjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, ok := mp.pool[*txHash]
+	if !ok {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+	return mp.mempoolEntries(mp.txAncestors(txD.Tx, nil)), nil
+}
+
+// MempoolDescendants returns the in-pool descendants of the transaction with
+// the passed hash in the pool as fully populated btcjson results keyed by their
+// hash.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolDescendants(txHash *chainhash.Hash) (map[string]*btcjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, ok := mp.pool[*txHash]
+	if !ok {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+	return mp.mempoolEntries(mp.txDescendants(txD.Tx, nil)), nil
+}
+
+// mempoolEntries returns the passed transactions of the pool as fully
+// populated btcjson results keyed by their hash.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) mempoolEntries(txns map[chainhash.Hash]*btcutil.Tx) map[string]*btcjson.GetMempoolEntryResult {
+	entries := make(map[string]*btcjson.GetMempoolEntryResult, len(txns))
+	for hash := range txns {
+		entries[hash.String()] = mp.mempoolEntry(mp.pool[hash])
+	}
+	return entries
+}
@@ -0,0 +1,143 @@
+package mempool
+
+import (
+	"container/heap"
+	"math"
+	"time"
+
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// rollingFeeHalfLife is the time it takes for the minimum fee rate raised by
+// evictions from a full memory pool to decay by half.
+const rollingFeeHalfLife = time.Hour * 12
+
+// descendantFeeRate returns the fee rate in satoshi/kB of the passed transaction
+// counting its descendants in the pool.
+func descendantFeeRate(txD *TxDesc) float64 {
+	return float64(txD.descendants.fees) * 1000 / float64(txD.descendants.size)
+}
+
+// evictionHeap is a min-heap of the transactions of the pool ordered by their
+// fee rate counting their descendants, so the package to evict from a full pool
+// is found without scanning it.  Each transaction records its index in the heap
+// so it's fixed in place when its descendants change.
+type evictionHeap []*TxDesc
+
+// Len returns the number of transactions in the heap.  It is part of the
+// heap.Interface implementation.
+func (h evictionHeap) Len() int {
+	return len(h)
+}
+
+// Less returns whether the transaction with index i pays a lower fee rate
+// counting its descendants than the one with index j.  It is part of the
+// heap.Interface implementation.
+func (h evictionHeap) Less(i, j int) bool {
+	return descendantFeeRate(h[i]) < descendantFeeRate(h[j])
+}
+
+// Swap swaps the transactions at the passed indices in the heap.  It is part
+// of the heap.Interface implementation.
+func (h evictionHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].evictIndex = i
+	h[j].evictIndex = j
+}
+
+// Push pushes the passed transaction onto the heap.  It is part of the
+// heap.Interface implementation.
+func (h *evictionHeap) Push(x interface{}) {
+	txD := x.(*TxDesc)
+	txD.evictIndex = len(*h)
+	*h = append(*h, txD)
+}
+
+// Pop removes the last transaction of the heap and returns it.  It is part of
+// the heap.Interface implementation.
+func (h *evictionHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	txD := old[n-1]
+	old[n-1] = nil
+	txD.evictIndex = -1
+	*h = old[:n-1]
+	return txD
+}
+
+// fixEviction restores the order of the eviction heap once the descendants of
+// the passed transaction of the pool changed.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) fixEviction(txD *TxDesc) {
+	heap.Fix(&mp.evictHeap, txD.evictIndex)
+}
+
+// rollingMinFee returns the minimum fee rate in satoshi/kB raised by the most
+// recent eviction from the pool, decayed as of the passed time.  It drops to
+// zero once it decays below half the minimum relay fee.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) rollingMinFee(now time.Time) btcutil.Amount {
+	if mp.rollingFee == 0 {
+		return 0
+	}
+
+	elapsed := now.Sub(mp.rollingFeeTime)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	fee := mp.rollingFee * math.Pow(0.5, float64(elapsed)/
+		float64(rollingFeeHalfLife))
+	if fee < float64(mp.cfg.Policy.MinRelayTxFee)/2 {
+		return 0
+	}
+	return btcutil.Amount(fee)
+}
+
+// minFeeRate returns the minimum fee rate in satoshi/kB transactions must pay
+// to be accepted into the pool, which is the greater of the minimum relay fee
+// and the rolling minimum fee of a full pool.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) minFeeRate(now time.Time) btcutil.Amount {
+	rolling := mp.rollingMinFee(now)
+	if rolling > mp.cfg.Policy.MinRelayTxFee {
+		return rolling
+	}
+	return mp.cfg.Policy.MinRelayTxFee
+}
+
+// trimToSize evicts the transaction with the lowest fee rate counting its
+// descendants, along with the descendants, until the serialized size of the
+// transactions in the pool is within the configured maximum.  The transaction
+// is taken from the top of the eviction heap, which is kept ordered as the
+// package info of the transactions changes.  Each eviction raises the rolling
+// minimum fee to the fee rate of the evicted transactions plus the minimum
+// relay fee so transactions which would be evicted right away are no longer
+// accepted.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) trimToSize() {
+	maxSize := mp.cfg.Policy.MaxPoolSize
+	if maxSize <= 0 {
+		return
+	}
+
+	for mp.poolStats.totalBytes > maxSize && len(mp.evictHeap) > 0 {
+		worst := mp.evictHeap[0].Tx
+		worstRate := descendantFeeRate(mp.evictHeap[0])
+
+		now := time.Now()
+		newFee := worstRate + float64(mp.cfg.Policy.MinRelayTxFee)
+		if newFee > float64(mp.rollingMinFee(now)) {
+			mp.rollingFee = newFee
+			mp.rollingFeeTime = now
+		}
+
+		log.Debugf("Evicting transaction %v and its descendants "+
+			"(fee_rate=%.0f sat/kb) from the full mempool",
+			worst.Hash(), worstRate)
+		mp.removeTransaction(worst, true)
+	}
+}
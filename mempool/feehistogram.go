@@ -0,0 +1,97 @@
+package mempool
+
+import (
+	"sort"
+
+	"github.com/lbryio/lbcd/btcjson"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// feeHistogramBounds are the lower bounds in dewies per virtual byte of the
+// fee rate buckets of the fee histogram.  They grow roughly geometrically so
+// the histogram stays meaningful from the minimum relay fee up to the fee
+// rates paid under heavy congestion.
+var feeHistogramBounds = [...]float64{
+	0, 1, 2, 3, 4, 5, 6, 8, 10, 12, 15, 20, 25, 30, 40, 50, 60, 70, 80,
+	100, 120, 150, 200, 250, 300, 400, 500, 700, 1000, 1500, 2000, 3000,
+	5000, 10000,
+}
+
+// feeBucket aggregates the transactions of the pool whose fee rate falls in a
+// bucket of the fee histogram.
+type feeBucket struct {
+	count int64
+	vsize int64
+	fees  int64
+}
+
+// feeHistogramBucket returns the index of the bucket of the fee histogram the
+// transaction of the passed descriptor falls in, which is based on its own
+// fee rate.
+func feeHistogramBucket(txD *TxDesc) int {
+	feeRate := float64(txD.Fee) / float64(txD.vsize)
+	return sort.Search(len(feeHistogramBounds), func(i int) bool {
+		return feeHistogramBounds[i] > feeRate
+	}) - 1
+}
+
+// addToFeeHistogram adds the transaction of the passed descriptor to the fee
+// histogram.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) addToFeeHistogram(txD *TxDesc) {
+	bucket := &mp.feeHistogram[feeHistogramBucket(txD)]
+	bucket.count++
+	bucket.vsize += txD.vsize
+	bucket.fees += txD.Fee
+}
+
+// removeFromFeeHistogram removes the transaction of the passed descriptor from
+// the fee histogram.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) removeFromFeeHistogram(txD *TxDesc) {
+	bucket := &mp.feeHistogram[feeHistogramBucket(txD)]
+	bucket.count--
+	bucket.vsize -= txD.vsize
+	bucket.fees -= txD.Fee
+}
+
+// FeeHistogram returns the histogram of the fee rates of the transactions in
+// the pool, which aggregates them by buckets of fee rates weighted by their
+// virtual size.  Only the buckets with transactions are returned, from the
+// highest fee rates to the lowest, so the cumulated virtual size of the
+// buckets tells how deep in the pool a fee rate is.  The histogram is
+// maintained as transactions enter and leave the pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) FeeHistogram() *btcjson.GetMempoolFeeHistogramResult {
+	mp.mtx.RLock()
+	histogram := mp.feeHistogram
+	mp.mtx.RUnlock()
+
+	result := &btcjson.GetMempoolFeeHistogramResult{
+		Buckets: []btcjson.MempoolFeeBucket{},
+	}
+	for i := len(histogram) - 1; i >= 0; i-- {
+		bucket := &histogram[i]
+		if bucket.count == 0 {
+			continue
+		}
+
+		var maxFeeRate float64
+		if i+1 < len(feeHistogramBounds) {
+			maxFeeRate = feeHistogramBounds[i+1]
+		}
+		result.Buckets = append(result.Buckets, btcjson.MempoolFeeBucket{
+			MinFeeRate: feeHistogramBounds[i],
+			MaxFeeRate: maxFeeRate,
+			Count:      bucket.count,
+			VSize:      bucket.vsize,
+			Fees:       btcutil.Amount(bucket.fees).ToBTC(),
+		})
+		result.Count += bucket.count
+		result.VSize += bucket.vsize
+	}
+	return result
+}
@@ -5,6 +5,7 @@
 package mempool
 
 import (
+	"container/heap"
 	"container/list"
 	"fmt"
 	"math"
@@ -91,7 +92,7 @@ type Config struct {
 	IsDeploymentActive func(deploymentID uint32) (bool, error)
 
 	// SigCache defines a signature cache to use.
-	SigCache *txscript.SigCache
+	SigCache txscript.SignatureCache
 
 	// HashCache defines the transaction hash mid-state cache to use.
 	HashCache *txscript.HashCache
@@ -155,6 +156,23 @@ type Policy struct {
 	// transactions using the Replace-By-Fee (RBF) signaling policy into
 	// the mempool.
 	RejectReplacement bool
+
+	// MaxPoolSize is the maximum serialized size in bytes of the
+	// transactions in the pool.  The transactions with the lowest fee rates are evicted
+	// when it is exceeded.  Zero means no limit.
+	MaxPoolSize int64
+
+	// Standard houses the rules deciding whether a transaction is standard.
+	// The rules of DefaultStandardPolicy are used when it is nil.
+	Standard *StandardPolicy
+}
+
+// standardPolicy returns the rules deciding whether a transaction is standard.
+func (p *Policy) standardPolicy() *StandardPolicy {
+	if p.Standard == nil {
+		return DefaultStandardPolicy()
+	}
+	return p.Standard
 }
 
 // aggregateInfo tracks aggregated serialized size, memory usage, and fees
@@ -174,6 +192,19 @@ type TxDesc struct {
 	// StartingPriority is the priority of the transaction when it was added
 	// to the pool.
 	StartingPriority float64
+
+	// vsize is the virtual size of the transaction.
+	vsize int64
+
+	// ancestors and descendants aggregate the transaction along with its
+	// ancestors and descendants in the pool respectively.  They are
+	// protected by the mempool lock.
+	ancestors   packageInfo
+	descendants packageInfo
+
+	// evictIndex is the index of the transaction in the eviction heap of
+	// the pool, or -1 when it's not in the pool.
+	evictIndex int
 }
 
 func (txD *TxDesc) incr(info *aggregateInfo) {
@@ -236,8 +267,32 @@ type TxPool struct {
 	// stats are aggregated over pool, orphans, etc.
 	stats aggregateInfo
 
+	// poolStats are aggregated over the pool alone to enforce its maximum
+	// size.
+	poolStats aggregateInfo
+
+	// feeHistogram aggregates the transactions of the pool by buckets of
+	// fee rates with the lower bounds in feeHistogramBounds.
+	feeHistogram [len(feeHistogramBounds)]feeBucket
+
+	// rollingFee is the minimum fee rate in satoshi/kB raised by the most
+	// recent eviction from a full pool at rollingFeeTime.  It decays over
+	// time as reported by rollingMinFee.
+	rollingFee     float64
+	rollingFeeTime time.Time
+
+	// evictHeap orders the transactions of the pool by their fee rate
+	// counting their descendants to pick the ones evicted from a full
+	// pool.
+	evictHeap evictionHeap
+
 	// unbroadcast is a set of transactions yet to be broadcast.
 	unbroadcast map[chainhash.Hash]bool
+
+	// lowFeeTxns holds recently rejected transactions which didn't pay
+	// enough fees on their own so they can be accepted along with a child
+	// paying for them.
+	lowFeeTxns map[chainhash.Hash]*btcutil.Tx
 }
 
 // Ensure the TxPool type implements the mining.TxSource interface.
@@ -541,14 +596,23 @@ func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers bool) {
 			mp.cfg.AddrIndex.RemoveUnconfirmedTx(txHash)
 		}
 
+		// Find the related transactions before the transaction is
+		// removed so their package info can be updated afterwards.
+		ancestors := mp.txAncestors(tx, nil)
+		descendants := mp.txDescendants(tx, nil)
+
 		// Mark the referenced outpoints as unspent by the pool.
 		for _, txIn := range txDesc.Tx.MsgTx().TxIn {
 			delete(mp.outpoints, txIn.PreviousOutPoint)
 		}
 		delete(mp.pool, *txHash)
+		heap.Remove(&mp.evictHeap, txDesc.evictIndex)
+		mp.removePackageInfo(txDesc, ancestors, descendants)
 
 		// Update stats.
 		txDesc.decr(&mp.stats)
+		txDesc.decr(&mp.poolStats)
+		mp.removeFromFeeHistogram(txDesc)
 
 		// Inform associated fee estimator that the transaction has been removed
 		// from the mempool
@@ -610,12 +674,16 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *btcutil
 			FeePerKB: fee * 1000 / GetTxVirtualSize(tx),
 		},
 		StartingPriority: mining.CalcPriority(tx.MsgTx(), utxoView, height),
+		vsize:            GetTxVirtualSize(tx),
+		evictIndex:       -1,
 	}
 
 	mp.pool[*tx.Hash()] = txD
 	for _, txIn := range tx.MsgTx().TxIn {
 		mp.outpoints[txIn.PreviousOutPoint] = tx
 	}
+	mp.addPackageInfo(txD)
+	heap.Push(&mp.evictHeap, txD)
 	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 
 	// Add unconfirmed address index entries associated with the transaction
@@ -633,6 +701,8 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *btcutil
 
 	// Update stats.
 	txD.incr(&mp.stats)
+	txD.incr(&mp.poolStats)
+	mp.addToFeeHistogram(txD)
 
 	return txD
 }
@@ -849,10 +919,10 @@ func (mp *TxPool) CheckSpend(op wire.OutPoint) *btcutil.Tx {
 // fetchInputUtxos loads utxo details about the input transactions referenced by
 // the passed transaction.  First, it loads the details form the viewpoint of
 // the main chain, then it adjusts them based upon the contents of the
-// transaction pool.
+// transaction pool and, when provided, the passed package transactions.
 //
 // This function MUST be called with the mempool lock held (for reads).
-func (mp *TxPool) fetchInputUtxos(tx *btcutil.Tx) (*blockchain.UtxoViewpoint, error) {
+func (mp *TxPool) fetchInputUtxos(tx *btcutil.Tx, pkgTxns map[chainhash.Hash]*btcutil.Tx) (*blockchain.UtxoViewpoint, error) {
 	utxoView, err := mp.cfg.FetchUtxoView(tx)
 	if err != nil {
 		return nil, err
@@ -871,6 +941,12 @@ func (mp *TxPool) fetchInputUtxos(tx *btcutil.Tx) (*blockchain.UtxoViewpoint, er
 			// safe to call without bounds checking here.
 			utxoView.AddTxOut(poolTxDesc.Tx, prevOut.Index,
 				mining.UnminedHeight)
+			continue
+		}
+
+		if pkgTx, exists := pkgTxns[prevOut.Hash]; exists {
+			utxoView.AddTxOut(pkgTx, prevOut.Index,
+				mining.UnminedHeight)
 		}
 	}
 
@@ -989,12 +1065,49 @@ func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
 	return conflicts, nil
 }
 
-// maybeAcceptTransaction is the internal function which implements the public
-// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
-// more details.
+// MempoolAcceptResult holds the result of checking whether a transaction
+// would be accepted into the memory pool.
+type MempoolAcceptResult struct {
+	// TxFee is the fee paid by the transaction.
+	TxFee btcutil.Amount
+
+	// TxSize is the virtual size of the transaction.
+	TxSize int64
+
+	// Conflicts is the set of transactions in the pool that would be
+	// replaced (via RBF) if the transaction were accepted.
+	Conflicts map[chainhash.Hash]*btcutil.Tx
+
+	// MissingParents is the set of unknown or spent transactions referenced
+	// by the inputs of the transaction.  The transaction is an orphan when
+	// it is non-empty.
+	MissingParents []*chainhash.Hash
+
+	// utxoView is the set of unspent outputs referenced by the inputs of
+	// the transaction.
+	utxoView *blockchain.UtxoViewpoint
+
+	// bestHeight is the best chain height the checks were performed at.
+	bestHeight int32
+}
+
+// checkMempoolAcceptance performs every policy and consensus check required
+// for the passed transaction to be accepted into the memory pool without
+// actually adding it.  The pkgTxns parameter is optional and, when non-nil,
+// provides additional unconfirmed transactions whose outputs the passed
+// transaction is allowed to spend as if they were already in the pool.  When
+// deferFees is set, the relay fee, priority and rate limiting checks are
+// skipped so the caller can evaluate the fees of a package as a whole.
 //
-// This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejectDupOrphans bool) ([]*chainhash.Hash, *TxDesc, error) {
+// When the transaction is an orphan, the returned result only has its
+// MissingParents field populated.
+//
+// This function MUST be called with the mempool lock held (for writes when
+// rateLimit is set, otherwise for reads).
+func (mp *TxPool) checkMempoolAcceptance(tx *btcutil.Tx, isNew, rateLimit,
+	rejectDupOrphans bool, pkgTxns map[chainhash.Hash]*btcutil.Tx,
+	deferFees bool) (*MempoolAcceptResult, error) {
+
 	txHash := tx.Hash()
 
 	// If a transaction has witness data, and segwit isn't active yet, If
@@ -1003,7 +1116,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	if tx.MsgTx().HasWitness() {
 		segwitActive, err := mp.cfg.IsDeploymentActive(chaincfg.DeploymentSegwit)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		if !segwitActive {
@@ -1015,7 +1128,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			}
 			str := fmt.Sprintf("transaction %v has witness data, "+
 				"but segwit isn't active yet%s", txHash, simnetHint)
-			return nil, nil, txRuleError(wire.RejectNonstandard, str)
+			return nil, txRuleError(wire.RejectNonstandard, str)
 		}
 	}
 
@@ -1027,7 +1140,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		mp.isOrphanInPool(txHash)) {
 
 		str := fmt.Sprintf("already have transaction %v", txHash)
-		return nil, nil, txRuleError(wire.RejectDuplicate, str)
+		return nil, txRuleError(wire.RejectDuplicate, str)
 	}
 
 	// Perform preliminary sanity checks on the transaction.  This makes
@@ -1036,16 +1149,16 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	err := blockchain.CheckTransactionSanity(tx, true)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// A standalone transaction must not be a coinbase transaction.
 	if blockchain.IsCoinBase(tx) {
 		str := fmt.Sprintf("transaction %v is an individual coinbase",
 			txHash)
-		return nil, nil, txRuleError(wire.RejectInvalid, str)
+		return nil, txRuleError(wire.RejectInvalid, str)
 	}
 
 	// Get the current height of the main chain.  A standalone transaction
@@ -1059,9 +1172,9 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	// Don't allow non-standard transactions if the network parameters
 	// forbid their acceptance.
 	if !mp.cfg.Policy.AcceptNonStd {
-		err = checkTransactionStandard(tx, nextBlockHeight,
-			medianTimePast, mp.cfg.Policy.MinRelayTxFee,
-			mp.cfg.Policy.MaxTxVersion)
+		err = mp.cfg.Policy.standardPolicy().checkTransactionStandard(
+			tx, nextBlockHeight, medianTimePast,
+			mp.cfg.Policy.MinRelayTxFee, mp.cfg.Policy.MaxTxVersion)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -1072,7 +1185,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			}
 			str := fmt.Sprintf("transaction %v is not standard: %v",
 				txHash, err)
-			return nil, nil, txRuleError(rejectCode, str)
+			return nil, txRuleError(rejectCode, str)
 		}
 	}
 
@@ -1087,19 +1200,19 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	// spend data and prevents double spends.
 	isReplacement, err := mp.checkPoolDoubleSpend(tx)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Fetch all of the unspent transaction outputs referenced by the inputs
 	// to this transaction.  This function also attempts to fetch the
 	// transaction itself to be used for detecting a duplicate transaction
 	// without needing to do a separate lookup.
-	utxoView, err := mp.fetchInputUtxos(tx)
+	utxoView, err := mp.fetchInputUtxos(tx, pkgTxns)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Don't allow the transaction if it exists in the main chain and is
@@ -1109,7 +1222,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		prevOut.Index = uint32(txOutIdx)
 		entry := utxoView.LookupEntry(prevOut)
 		if entry != nil && !entry.IsSpent() {
-			return nil, nil, txRuleError(wire.RejectDuplicate,
+			return nil, txRuleError(wire.RejectDuplicate,
 				"transaction already exists")
 		}
 		utxoView.RemoveEntry(prevOut)
@@ -1131,7 +1244,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		}
 	}
 	if len(missingParents) > 0 {
-		return missingParents, nil, nil
+		return &MempoolAcceptResult{MissingParents: missingParents}, nil
 	}
 
 	// Don't allow the transaction into the mempool unless its sequence
@@ -1140,13 +1253,13 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	sequenceLock, err := mp.cfg.CalcSequenceLock(tx, utxoView)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 	if !blockchain.SequenceLockActive(sequenceLock, nextBlockHeight,
 		medianTimePast) {
-		return nil, nil, txRuleError(wire.RejectNonstandard,
+		return nil, txRuleError(wire.RejectNonstandard,
 			"transaction's sequence locks on inputs not met")
 	}
 
@@ -1158,9 +1271,9 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		utxoView, mp.cfg.ChainParams)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Don't allow transactions with non-standard inputs if the network
@@ -1177,7 +1290,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			}
 			str := fmt.Sprintf("transaction %v has a non-standard "+
 				"input: %v", txHash, err)
-			return nil, nil, txRuleError(rejectCode, str)
+			return nil, txRuleError(rejectCode, str)
 		}
 	}
 
@@ -1194,14 +1307,84 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	sigOpCost, err := blockchain.GetSigOpCost(tx, false, utxoView, true, true)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 	if sigOpCost > mp.cfg.Policy.MaxSigOpCostPerTx {
 		str := fmt.Sprintf("transaction %v sigop cost is too high: %d > %d",
 			txHash, sigOpCost, mp.cfg.Policy.MaxSigOpCostPerTx)
-		return nil, nil, txRuleError(wire.RejectNonstandard, str)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	serializedSize := GetTxVirtualSize(tx)
+
+	// The relay fee checks are deferred to the caller when the fees are
+	// evaluated for a package of transactions as a whole.
+	if !deferFees {
+		err = mp.checkRelayFee(tx, utxoView, txFee, serializedSize,
+			nextBlockHeight, isNew, rateLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// If the transaction has any conflicts, and we've made it this far, then
+	// we're processing a potential replacement.
+	var conflicts map[chainhash.Hash]*btcutil.Tx
+	if isReplacement {
+		conflicts, err = mp.validateReplacement(tx, txFee)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Verify crypto signatures for each input and reject the transaction if
+	// any don't verify.
+	err = blockchain.ValidateTransactionScripts(tx, utxoView,
+		txscript.StandardVerifyFlags, mp.cfg.SigCache,
+		mp.cfg.HashCache)
+	if err != nil {
+		if cerr, ok := err.(blockchain.RuleError); ok {
+			return nil, chainRuleError(cerr)
+		}
+		return nil, err
+	}
+
+	return &MempoolAcceptResult{
+		TxFee:      btcutil.Amount(txFee),
+		TxSize:     serializedSize,
+		Conflicts:  conflicts,
+		utxoView:   utxoView,
+		bestHeight: bestHeight,
+	}, nil
+}
+
+// checkRelayFee checks that the passed transaction pays enough fees, or has
+// enough priority, to be relayed and rate limits free transactions when the
+// rateLimit flag is set.  The isNew flag exempts transactions which are being
+// added back to the memory pool from disconnected blocks from the priority
+// check.
+//
+// This function MUST be called with the mempool lock held (for writes when
+// rateLimit is set, otherwise for reads).
+func (mp *TxPool) checkRelayFee(tx *btcutil.Tx, utxoView *blockchain.UtxoViewpoint,
+	txFee, serializedSize int64, nextBlockHeight int32, isNew, rateLimit bool) error {
+
+	txHash := tx.Hash()
+
+	// Don't allow transactions paying less than the minimum fee of a full
+	// pool since they would be evicted right away.  Transactions which are
+	// being added back to the memory pool from disconnected blocks are
+	// exempted.
+	if rollingFee := mp.rollingMinFee(time.Now()); isNew && rollingFee > 0 {
+		minFee := calcMinRequiredTxRelayFee(serializedSize, rollingFee)
+		if txFee < minFee {
+			str := fmt.Sprintf("transaction %v has %d fees which is "+
+				"under the mempool minimum fee of %d", txHash,
+				txFee, minFee)
+			return txRuleError(wire.RejectInsufficientFee, str)
+		}
 	}
 
 	// Don't allow transactions with fees too low to get into a mined block.
@@ -1215,14 +1398,13 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	// which is more desirable.  Therefore, as long as the size of the
 	// transaction does not exceed 1000 less than the reserved space for
 	// high-priority transactions, don't require a fee for it.
-	serializedSize := GetTxVirtualSize(tx)
 	minFee := calcMinRequiredTxRelayFee(serializedSize,
 		mp.cfg.Policy.MinRelayTxFee)
 	if serializedSize >= (DefaultBlockPrioritySize-1000) && txFee < minFee {
 		str := fmt.Sprintf("transaction %v has %d fees which is under "+
 			"the required amount of %d", txHash, txFee,
 			minFee)
-		return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+		return txRuleError(wire.RejectInsufficientFee, str)
 	}
 
 	// Require that free transactions have sufficient priority to be mined
@@ -1236,7 +1418,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			str := fmt.Sprintf("transaction %v has insufficient "+
 				"priority (%g <= %g)", txHash,
 				currentPriority, mining.MinHighPriority)
-			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+			return txRuleError(wire.RejectInsufficientFee, str)
 		}
 	}
 
@@ -1254,7 +1436,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		if mp.pennyTotal >= mp.cfg.Policy.FreeTxRelayLimit*10*1000 {
 			str := fmt.Sprintf("transaction %v has been rejected "+
 				"by the rate limiter due to low fees", txHash)
-			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+			return txRuleError(wire.RejectInsufficientFee, str)
 		}
 		oldTotal := mp.pennyTotal
 
@@ -1264,43 +1446,51 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			mp.cfg.Policy.FreeTxRelayLimit*10*1000)
 	}
 
-	// If the transaction has any conflicts, and we've made it this far, then
-	// we're processing a potential replacement.
-	var conflicts map[chainhash.Hash]*btcutil.Tx
-	if isReplacement {
-		conflicts, err = mp.validateReplacement(tx, txFee)
-		if err != nil {
-			return nil, nil, err
-		}
-	}
+	return nil
+}
 
-	// Verify crypto signatures for each input and reject the transaction if
-	// any don't verify.
-	err = blockchain.ValidateTransactionScripts(tx, utxoView,
-		txscript.StandardVerifyFlags, mp.cfg.SigCache,
-		mp.cfg.HashCache)
+// maybeAcceptTransaction is the internal function which implements the public
+// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
+// more details.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejectDupOrphans bool) ([]*chainhash.Hash, *TxDesc, error) {
+	txHash := tx.Hash()
+
+	result, err := mp.checkMempoolAcceptance(tx, isNew, rateLimit,
+		rejectDupOrphans, nil, false)
 	if err != nil {
-		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
-		}
 		return nil, nil, err
 	}
+	if len(result.MissingParents) > 0 {
+		return result.MissingParents, nil, nil
+	}
 
 	// Now that we've deemed the transaction as valid, we can add it to the
 	// mempool. If it ended up replacing any transactions, we'll remove them
 	// first.
-	for _, conflict := range conflicts {
+	for _, conflict := range result.Conflicts {
 		log.Debugf("Replacing transaction %v (fee_rate=%v sat/kb) "+
 			"with %v (fee_rate=%v sat/kb)\n", conflict.Hash(),
 			mp.pool[*conflict.Hash()].FeePerKB, tx.Hash(),
-			txFee*1000/serializedSize)
+			int64(result.TxFee)*1000/result.TxSize)
 
 		// The conflict set should already include the descendants for
 		// each one, so we don't need to remove the redeemers within
 		// this call as they'll be removed eventually.
 		mp.removeTransaction(conflict, false)
 	}
-	txD := mp.addTransaction(utxoView, tx, bestHeight, txFee)
+	txD := mp.addTransaction(result.utxoView, tx, result.bestHeight,
+		int64(result.TxFee))
+
+	// Make room for the transaction when the pool is full.  The transaction
+	// itself is rejected when it pays the lowest fee rate.
+	mp.trimToSize()
+	if !mp.isTransactionInPool(txHash) {
+		str := fmt.Sprintf("transaction %v was not accepted since the "+
+			"mempool is full", txHash)
+		return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
 
 	log.Debugf("Accepted transaction %v (pool size: %v)", txHash,
 		len(mp.pool))
@@ -1328,6 +1518,74 @@ func (mp *TxPool) MaybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit bool)
 	return hashes, txD, err
 }
 
+// CheckMempoolAcceptance runs the passed transaction through the same policy
+// and consensus checks MaybeAcceptTransaction performs without adding it to
+// the memory pool, rate limiting it, or relaying it.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) CheckMempoolAcceptance(tx *btcutil.Tx) (*MempoolAcceptResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	return mp.checkMempoolAcceptance(tx, true, false, true, nil, false)
+}
+
+// CheckPackageAcceptance behaves like CheckMempoolAcceptance for each of the
+// passed transactions in order, except that each transaction may also spend
+// the outputs of any earlier transaction in the package that passed its own
+// checks.  Transactions within the package may not spend the same outputs.
+//
+// The returned slices are parallel to the passed transactions.  A nil error
+// with a nil result means the transaction was not checked because one of its
+// in-package parents was rejected.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) CheckPackageAcceptance(txns []*btcutil.Tx) ([]*MempoolAcceptResult, []error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	results := make([]*MempoolAcceptResult, len(txns))
+	errs := make([]error, len(txns))
+	accepted := make(map[chainhash.Hash]*btcutil.Tx, len(txns))
+	rejected := make(map[chainhash.Hash]struct{})
+	spent := make(map[wire.OutPoint]*chainhash.Hash)
+	for i, tx := range txns {
+		var skip bool
+		for _, txIn := range tx.MsgTx().TxIn {
+			prevOut := txIn.PreviousOutPoint
+			if _, ok := rejected[prevOut.Hash]; ok {
+				skip = true
+				break
+			}
+			if spender, ok := spent[prevOut]; ok {
+				str := fmt.Sprintf("output %v already spent by "+
+					"transaction %v in the package", prevOut,
+					spender)
+				errs[i] = txRuleError(wire.RejectDuplicate, str)
+				break
+			}
+		}
+		if skip || errs[i] != nil {
+			rejected[*tx.Hash()] = struct{}{}
+			continue
+		}
+
+		results[i], errs[i] = mp.checkMempoolAcceptance(tx, true,
+			false, true, accepted, false)
+		if errs[i] != nil || len(results[i].MissingParents) > 0 {
+			rejected[*tx.Hash()] = struct{}{}
+			continue
+		}
+
+		accepted[*tx.Hash()] = tx
+		for _, txIn := range tx.MsgTx().TxIn {
+			spent[txIn.PreviousOutPoint] = tx.Hash()
+		}
+	}
+
+	return results, errs
+}
+
 // processOrphans is the internal function which implements the public
 // ProcessOrphans.  See the comment for ProcessOrphans for more details.
 //
@@ -1451,6 +1709,17 @@ func (mp *TxPool) ProcessTransaction(tx *btcutil.Tx, allowOrphan, rateLimit bool
 	missingParents, txD, err := mp.maybeAcceptTransaction(tx, true, rateLimit,
 		true)
 	if err != nil {
+		// A transaction paying too low fees may still be accepted along
+		// with an orphan child paying for it.  Otherwise remember it in
+		// case such a child shows up later.
+		if isInsufficientFeeError(err) {
+			if acceptedTxs := mp.maybeAcceptWithOrphanChild(tx,
+				rateLimit); acceptedTxs != nil {
+
+				return acceptedTxs, nil
+			}
+			mp.addLowFeeTx(tx)
+		}
 		return nil, err
 	}
 
@@ -1470,6 +1739,14 @@ func (mp *TxPool) ProcessTransaction(tx *btcutil.Tx, allowOrphan, rateLimit bool
 		return acceptedTxs, nil
 	}
 
+	// The transaction may be a child paying for parents which were
+	// rejected for paying too low fees on their own.
+	if acceptedTxs := mp.maybeAcceptWithLowFeeParents(tx, missingParents,
+		rateLimit); acceptedTxs != nil {
+
+		return acceptedTxs, nil
+	}
+
 	// The transaction is an orphan (has inputs missing).  Reject
 	// it if the flag to allow orphans is not set.
 	if !allowOrphan {
@@ -1574,17 +1851,31 @@ func (mp *TxPool) MempoolInfo() *btcjson.GetMempoolInfoResult {
 	mp.mtx.RLock()
 	policy := mp.cfg.Policy
 	stats := mp.stats
+	minFee := mp.minFeeRate(time.Now())
 	unbroadcastCount := int64(len(mp.unbroadcast))
 	mp.mtx.RUnlock()
 
+	standard := policy.standardPolicy()
 	ret := &btcjson.GetMempoolInfoResult{
 		Size:             stats.totalCount,
 		Usage:            stats.totalMem,
 		Bytes:            stats.totalBytes,
 		TotalFee:         btcutil.Amount(stats.totalFee).ToBTC(),
-		MemPoolMinFee:    btcutil.Amount(calcMinRequiredTxRelayFee(1000, policy.MinRelayTxFee)).ToBTC(),
+		MaxMempool:       policy.MaxPoolSize,
+		MemPoolMinFee:    btcutil.Amount(calcMinRequiredTxRelayFee(1000, minFee)).ToBTC(),
 		MinRelayTxFee:    policy.MinRelayTxFee.ToBTC(),
 		UnbroadcastCount: unbroadcastCount,
+		Policy: btcjson.MempoolPolicyResult{
+			AcceptNonStd:       policy.AcceptNonStd,
+			MaxTxVersion:       policy.MaxTxVersion,
+			DustRelayFee:       standard.dustRelayFee(policy.MinRelayTxFee).ToBTC(),
+			DataCarrierSize:    standard.MaxDataCarrierSize,
+			MaxClaimValueSize:  standard.MaxClaimValueSize,
+			MinClaimAmount:     standard.MinClaimAmount.ToBTC(),
+			MinSupportAmount:   standard.MinSupportAmount.ToBTC(),
+			PermitBareMultiSig: standard.PermitBareMultiSig,
+			RejectReplacement:  policy.RejectReplacement,
+		},
 	}
 
 	return ret
@@ -1601,45 +1892,8 @@ func (mp *TxPool) RawMempoolVerbose() map[string]*btcjson.GetMempoolEntryResult
 	result := make(map[string]*btcjson.GetMempoolEntryResult,
 		len(mp.pool))
 
-	for _, desc := range mp.pool {
-		// Calculate the current priority based on the inputs to
-		// the transaction.  Use zero if one or more of the
-		// input transactions can't be found for some reason.
-		tx := desc.Tx
-
-		mpd := &btcjson.GetMempoolEntryResult{
-			VSize:           int32(GetTxVirtualSize(tx)),
-			Size:            int32(tx.MsgTx().SerializeSize()),
-			Weight:          blockchain.GetTransactionWeight(tx),
-			Fee:             btcutil.Amount(desc.Fee).ToBTC(),
-			ModifiedFee:     btcutil.Amount(desc.Fee).ToBTC(), // TODO, Deprecated
-			Time:            desc.Added.Unix(),
-			Height:          int64(desc.Height),
-			DescendantCount: 1,                                // TODO
-			DescendantSize:  GetTxVirtualSize(tx),             // TODO
-			DescendantFees:  btcutil.Amount(desc.Fee).ToBTC(), // TODO, Deprecated
-			AncestorCount:   1,                                // TODO
-			AncestorSize:    GetTxVirtualSize(tx),             // TODO
-			AncestorFees:    btcutil.Amount(desc.Fee).ToBTC(), // TODO, Deprecated
-			WTxId:           desc.Tx.WitnessHash().String(),
-			Fees: btcjson.MempoolFees{
-				Base:       btcutil.Amount(desc.Fee).ToBTC(),
-				Modified:   btcutil.Amount(desc.Fee).ToBTC(), // TODO
-				Ancestor:   btcutil.Amount(desc.Fee).ToBTC(), // TODO
-				Descendant: btcutil.Amount(desc.Fee).ToBTC(), // TODO
-			},
-			Depends: make([]string, 0), // TODO
-			SpentBy: make([]string, 0), // TODO
-		}
-		for _, txIn := range tx.MsgTx().TxIn {
-			hash := &txIn.PreviousOutPoint.Hash
-			if mp.haveTransaction(hash) {
-				mpd.Depends = append(mpd.Depends,
-					hash.String())
-			}
-		}
-
-		result[tx.Hash().String()] = mpd
+	for hash, desc := range mp.pool {
+		result[hash.String()] = mp.mempoolEntry(desc)
 	}
 
 	return result
@@ -1664,5 +1918,6 @@ func New(cfg *Config) *TxPool {
 		nextExpireScan: time.Now().Add(orphanExpireScanInterval),
 		outpoints:      make(map[wire.OutPoint]*btcutil.Tx),
 		unbroadcast:    make(map[chainhash.Hash]bool),
+		lowFeeTxns:     make(map[chainhash.Hash]*btcutil.Tx),
 	}
 }
@@ -7,6 +7,7 @@ package mempool
 import (
 	"encoding/hex"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/lbryio/lbcd/blockchain"
 	"github.com/lbryio/lbcd/btcec"
+	"github.com/lbryio/lbcd/btcjson"
 	"github.com/lbryio/lbcd/chaincfg"
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
 	"github.com/lbryio/lbcd/txscript"
@@ -1458,6 +1460,244 @@ func TestAncestorsDescendants(t *testing.T) {
 	}
 }
 
+// checkPackageInfo ensures the package info tracked for every transaction in
+// the pool matches the one calculated from its ancestors and descendants, and
+// that the eviction heap holds every transaction of the pool in order.
+func checkPackageInfo(t *testing.T, mp *TxPool) {
+	t.Helper()
+
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+	for hash, txD := range mp.pool {
+		var ancestors, descendants packageInfo
+		ancestors.add(txD)
+		for ancestorHash := range mp.txAncestors(txD.Tx, nil) {
+			ancestors.add(mp.pool[ancestorHash])
+		}
+		descendants.add(txD)
+		for descendantHash := range mp.txDescendants(txD.Tx, nil) {
+			descendants.add(mp.pool[descendantHash])
+		}
+		if txD.ancestors != ancestors {
+			t.Fatalf("unexpected ancestors of %v: got %+v, want %+v",
+				hash, txD.ancestors, ancestors)
+		}
+		if txD.descendants != descendants {
+			t.Fatalf("unexpected descendants of %v: got %+v, want %+v",
+				hash, txD.descendants, descendants)
+		}
+	}
+
+	if len(mp.evictHeap) != len(mp.pool) {
+		t.Fatalf("eviction heap holds %d transactions, want %d",
+			len(mp.evictHeap), len(mp.pool))
+	}
+	for i, txD := range mp.evictHeap {
+		if mp.pool[*txD.Tx.Hash()] != txD || txD.evictIndex != i {
+			t.Fatalf("transaction %v at index %d of the eviction "+
+				"heap has index %d", txD.Tx.Hash(), i, txD.evictIndex)
+		}
+		if i > 0 && mp.evictHeap.Less(i, (i-1)/2) {
+			t.Fatalf("transaction %v at index %d of the eviction "+
+				"heap pays less than its parent", txD.Tx.Hash(), i)
+		}
+	}
+}
+
+// TestPackageInfo ensures the number, size and fees of the ancestors and
+// descendants of the transactions in the pool are tracked as transactions are
+// added and removed, including when a transaction is removed or added back
+// while its descendants remain in the pool, and that they are reported in the
+// mempool entries.
+func TestPackageInfo(t *testing.T) {
+	t.Parallel()
+
+	harness, outputs, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	mp := harness.txPool
+
+	// Create the same chain of unconfirmed transactions as in
+	// TestAncestorsDescendants, with distinct fees.
+	a := ctx.addSignedTx(outputs[:1], 2, 1000, false, false)
+	b := ctx.addSignedTx([]spendableOutput{txOutToSpendableOut(a, 0)}, 1,
+		2000, false, false)
+	c := ctx.addSignedTx([]spendableOutput{txOutToSpendableOut(a, 1)}, 1,
+		3000, false, false)
+	d := ctx.addSignedTx([]spendableOutput{txOutToSpendableOut(c, 0)}, 1,
+		4000, false, false)
+	e := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(b, 0), txOutToSpendableOut(d, 0),
+	}, 1, 5000, false, false)
+	checkPackageInfo(t, mp)
+
+	entry, err := mp.MempoolEntry(a.Hash())
+	if err != nil {
+		t.Fatalf("MempoolEntry: unexpected error: %v", err)
+	}
+	if entry.AncestorCount != 1 || entry.DescendantCount != 5 ||
+		entry.Fees.Descendant != btcutil.Amount(15000).ToBTC() {
+
+		t.Fatalf("unexpected package info of A: %+v", entry)
+	}
+	wantSpentBy := []string{b.Hash().String(), c.Hash().String()}
+	sort.Strings(wantSpentBy)
+	if !reflect.DeepEqual(entry.SpentBy, wantSpentBy) {
+		t.Fatalf("unexpected spending transactions of A: got %v, "+
+			"want %v", entry.SpentBy, wantSpentBy)
+	}
+
+	entry, err = mp.MempoolEntry(d.Hash())
+	if err != nil {
+		t.Fatalf("MempoolEntry: unexpected error: %v", err)
+	}
+	wantSize := GetTxVirtualSize(a) + GetTxVirtualSize(c) +
+		GetTxVirtualSize(d)
+	if entry.AncestorCount != 3 || entry.AncestorSize != wantSize ||
+		entry.Fees.Ancestor != btcutil.Amount(8000).ToBTC() ||
+		entry.DescendantCount != 2 {
+
+		t.Fatalf("unexpected package info of D: %+v", entry)
+	}
+
+	ancestors, err := mp.MempoolAncestors(e.Hash())
+	if err != nil {
+		t.Fatalf("MempoolAncestors: unexpected error: %v", err)
+	}
+	if len(ancestors) != 4 || ancestors[a.Hash().String()] == nil {
+		t.Fatalf("unexpected ancestors of E: %v", ancestors)
+	}
+	descendants, err := mp.MempoolDescendants(c.Hash())
+	if err != nil {
+		t.Fatalf("MempoolDescendants: unexpected error: %v", err)
+	}
+	if len(descendants) != 2 || descendants[e.Hash().String()] == nil {
+		t.Fatalf("unexpected descendants of C: %v", descendants)
+	}
+
+	// Removing A on its own, as when it is included in a block, leaves its
+	// descendants without ancestors through it.
+	mp.RemoveTransaction(a, false)
+	checkPackageInfo(t, mp)
+	if _, err := mp.MempoolEntry(a.Hash()); err == nil {
+		t.Fatalf("MempoolEntry: removed transaction found")
+	}
+
+	// Adding A back, as when the block including it is disconnected,
+	// relates it to its descendants again.
+	if _, err := mp.ProcessTransaction(a, false, false, 0); err != nil {
+		t.Fatalf("ProcessTransaction: unexpected error: %v", err)
+	}
+	checkPackageInfo(t, mp)
+	entry, err = mp.MempoolEntry(a.Hash())
+	if err != nil {
+		t.Fatalf("MempoolEntry: unexpected error: %v", err)
+	}
+	if entry.DescendantCount != 5 {
+		t.Fatalf("unexpected package info of A: %+v", entry)
+	}
+
+	// Removing C along with its descendants only leaves A and B.
+	mp.RemoveTransaction(c, true)
+	checkPackageInfo(t, mp)
+	entry, err = mp.MempoolEntry(a.Hash())
+	if err != nil {
+		t.Fatalf("MempoolEntry: unexpected error: %v", err)
+	}
+	if entry.DescendantCount != 2 ||
+		entry.Fees.Descendant != btcutil.Amount(3000).ToBTC() {
+
+		t.Fatalf("unexpected package info of A: %+v", entry)
+	}
+}
+
+// TestTrimToSize ensures that the transactions paying the lowest fee rate,
+// counting their descendants, are evicted when the pool exceeds its maximum
+// size and that the minimum fee rate of the pool is raised accordingly.
+func TestTrimToSize(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+
+	// A pays a low fee but its child C pays for both, so B pays the lowest
+	// fee rate counting descendants.
+	coinbase := ctx.addCoinbaseTx(4)
+	a := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 500,
+		false, false,
+	)
+	b := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 1000,
+		false, false,
+	)
+	c := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(a, 0)}, 1, 5000,
+		false, false,
+	)
+
+	// Cap the pool slightly above its current size so the next transaction
+	// evicts B, leaving room for signatures of different sizes.
+	txPool.cfg.Policy.MaxPoolSize = txPool.poolStats.totalBytes + 10
+	d := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 2)}, 1, 3000,
+		false, false,
+	)
+	testPoolMembership(ctx, a, false, true)
+	testPoolMembership(ctx, b, false, false)
+	testPoolMembership(ctx, c, false, true)
+	testPoolMembership(ctx, d, false, true)
+	checkPackageInfo(t, txPool)
+
+	// The minimum fee rate is raised to the fee rate of B plus the minimum
+	// relay fee.
+	bRate := 1000 * 1000 / GetTxVirtualSize(b)
+	wantMinFee := btcutil.Amount(bRate) + txPool.cfg.Policy.MinRelayTxFee
+	minFee := txPool.minFeeRate(time.Now())
+	if minFee < wantMinFee-1 || minFee > wantMinFee+1 {
+		t.Fatalf("unexpected minimum fee rate: want %v, got %v",
+			wantMinFee, minFee)
+	}
+	info := txPool.MempoolInfo()
+	if info.MemPoolMinFee != minFee.ToBTC() {
+		t.Fatalf("unexpected mempoolminfee: want %v, got %v",
+			minFee.ToBTC(), info.MemPoolMinFee)
+	}
+	if info.MaxMempool != txPool.cfg.Policy.MaxPoolSize {
+		t.Fatalf("unexpected maxmempool: want %v, got %v",
+			txPool.cfg.Policy.MaxPoolSize, info.MaxMempool)
+	}
+
+	// Transactions paying less than the minimum fee rate are rejected.
+	e, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 3)}, 1, 1000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = txPool.ProcessTransaction(e, true, false, 0)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("expected insufficient fee rejection, got %v", err)
+	}
+	testPoolMembership(ctx, e, false, false)
+
+	// The minimum fee rate decays back to the minimum relay fee.
+	later := time.Now().Add(rollingFeeHalfLife * 10)
+	if minFee := txPool.minFeeRate(later); minFee !=
+		txPool.cfg.Policy.MinRelayTxFee {
+
+		t.Fatalf("unexpected decayed minimum fee rate: want %v, "+
+			"got %v", txPool.cfg.Policy.MinRelayTxFee, minFee)
+	}
+}
+
 // TestRBF tests the different cases required for a transaction to properly
 // replace its conflicts given that they all signal replacement.
 func TestRBF(t *testing.T) {
@@ -1857,3 +2097,261 @@ func TestRBF(t *testing.T) {
 		}
 	}
 }
+
+// TestCheckPackageAcceptance ensures that a chain of transactions can be
+// tested for acceptance as a package without modifying the pool, and that the
+// same transactions tested individually are reported as missing parents.
+func TestCheckPackageAcceptance(t *testing.T) {
+	t.Parallel()
+
+	harness, outputs, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	const txChainLength = 3
+	chainedTxns, err := harness.CreateTxChain(outputs[0], txChainLength)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+
+	// Testing a child on its own should report its parent as missing.
+	result, err := harness.txPool.CheckMempoolAcceptance(chainedTxns[1])
+	if err != nil {
+		t.Fatalf("CheckMempoolAcceptance: unexpected error: %v", err)
+	}
+	if len(result.MissingParents) != 1 ||
+		*result.MissingParents[0] != *chainedTxns[0].Hash() {
+
+		t.Fatalf("CheckMempoolAcceptance: unexpected missing parents "+
+			"%v", result.MissingParents)
+	}
+
+	// Testing the entire chain as a package should succeed for every
+	// transaction.
+	results, errs := harness.txPool.CheckPackageAcceptance(chainedTxns)
+	for i, tx := range chainedTxns {
+		if errs[i] != nil {
+			t.Fatalf("CheckPackageAcceptance: unexpected error for "+
+				"tx %d: %v", i, errs[i])
+		}
+		if results[i] == nil || len(results[i].MissingParents) != 0 {
+			t.Fatalf("CheckPackageAcceptance: tx %d was not "+
+				"accepted", i)
+		}
+
+		// Ensure the transaction was not added to either pool.
+		testPoolMembership(tc, tx, false, false)
+	}
+
+	// A package that omits the first parent must skip its descendants.
+	results, errs = harness.txPool.CheckPackageAcceptance(chainedTxns[1:])
+	if errs[0] != nil || len(results[0].MissingParents) != 1 {
+		t.Fatalf("CheckPackageAcceptance: expected missing parent for "+
+			"first tx, got result %v, err %v", results[0], errs[0])
+	}
+	if results[1] != nil || errs[1] != nil {
+		t.Fatalf("CheckPackageAcceptance: expected descendant to be "+
+			"skipped, got result %v, err %v", results[1], errs[1])
+	}
+}
+
+// TestProcessPackage ensures a child paying enough fees allows its parent,
+// which doesn't pay enough fees on its own, into the pool both when submitted
+// as a package and when the child arrives after the parent was rejected.
+func TestProcessPackage(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	// Require free transactions to have enough priority so transactions
+	// spending unconfirmed outputs without fees are rejected.
+	harness.txPool.cfg.Policy.DisableRelayPriority = false
+
+	coinbase := tc.addCoinbaseTx(4)
+	createPackage := func(idx uint32, childFee btcutil.Amount) (*btcutil.Tx, *btcutil.Tx) {
+		t.Helper()
+
+		funding := tc.addSignedTx([]spendableOutput{
+			txOutToSpendableOut(coinbase, idx),
+		}, 1, 1000, false, false)
+		parent, err := harness.CreateSignedTx([]spendableOutput{
+			txOutToSpendableOut(funding, 0),
+		}, 1, 0, false)
+		if err != nil {
+			t.Fatalf("unable to create parent: %v", err)
+		}
+		child, err := harness.CreateSignedTx([]spendableOutput{
+			txOutToSpendableOut(parent, 0),
+		}, 1, childFee, false)
+		if err != nil {
+			t.Fatalf("unable to create child: %v", err)
+		}
+		return parent, child
+	}
+
+	parent, child := createPackage(0, 0)
+	_, err = harness.txPool.ProcessTransaction(parent, false, false, 0)
+	if !isInsufficientFeeError(err) {
+		t.Fatalf("ProcessTransaction: expected insufficient fee error, "+
+			"got %v", err)
+	}
+
+	// The package must be sorted and must pay for itself.
+	_, err = harness.txPool.ProcessPackage([]*btcutil.Tx{child, parent}, false)
+	if err == nil {
+		t.Fatalf("ProcessPackage: expected error for unsorted package")
+	}
+	_, err = harness.txPool.ProcessPackage([]*btcutil.Tx{parent, child}, false)
+	if !isInsufficientFeeError(err) {
+		t.Fatalf("ProcessPackage: expected insufficient fee error, "+
+			"got %v", err)
+	}
+	testPoolMembership(tc, parent, false, false)
+	testPoolMembership(tc, child, false, false)
+
+	// A child paying for both transactions gets the package accepted.
+	parent, child = createPackage(1, 100000)
+	acceptedTxs, err := harness.txPool.ProcessPackage(
+		[]*btcutil.Tx{parent, child}, false)
+	if err != nil {
+		t.Fatalf("ProcessPackage: unexpected error: %v", err)
+	}
+	if len(acceptedTxs) != 2 || acceptedTxs[0].Tx != parent ||
+		acceptedTxs[1].Tx != child {
+
+		t.Fatalf("ProcessPackage: unexpected accepted transactions %v",
+			acceptedTxs)
+	}
+	testPoolMembership(tc, parent, false, true)
+	testPoolMembership(tc, child, false, true)
+
+	// A child relayed after its parent was rejected for paying too low
+	// fees is accepted along with the parent.
+	parent, child = createPackage(2, 100000)
+	_, err = harness.txPool.ProcessTransaction(parent, true, false, 0)
+	if !isInsufficientFeeError(err) {
+		t.Fatalf("ProcessTransaction: expected insufficient fee error, "+
+			"got %v", err)
+	}
+	acceptedTxs, err = harness.txPool.ProcessTransaction(child, true, false, 0)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: unexpected error: %v", err)
+	}
+	if len(acceptedTxs) != 2 {
+		t.Fatalf("ProcessTransaction: got %d accepted transactions, "+
+			"want 2", len(acceptedTxs))
+	}
+	testPoolMembership(tc, parent, false, true)
+	testPoolMembership(tc, child, false, true)
+
+	// A parent paying too low fees is accepted along with a child that
+	// arrived first and was stored as an orphan.
+	parent, child = createPackage(3, 100000)
+	_, err = harness.txPool.ProcessTransaction(child, true, false, 0)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: unexpected error: %v", err)
+	}
+	testPoolMembership(tc, child, true, false)
+	acceptedTxs, err = harness.txPool.ProcessTransaction(parent, true, false, 0)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: unexpected error: %v", err)
+	}
+	if len(acceptedTxs) != 2 {
+		t.Fatalf("ProcessTransaction: got %d accepted transactions, "+
+			"want 2", len(acceptedTxs))
+	}
+	testPoolMembership(tc, parent, false, true)
+	testPoolMembership(tc, child, false, true)
+}
+
+// TestFeeHistogram ensures the fee histogram of the pool tracks the fee rates
+// of the transactions as they enter and leave the pool.
+func TestFeeHistogram(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	mp := harness.txPool
+
+	// checkHistogram ensures each of the passed transactions is counted in
+	// the bucket of its fee rate and that the buckets hold nothing else.
+	checkHistogram := func(txns ...*btcutil.Tx) {
+		t.Helper()
+
+		histogram := mp.FeeHistogram()
+		var wantVSize int64
+		remaining := make([]btcjson.MempoolFeeBucket, len(histogram.Buckets))
+		copy(remaining, histogram.Buckets)
+		for _, tx := range txns {
+			desc := mp.pool[*tx.Hash()]
+			feeRate := float64(desc.Fee) / float64(desc.vsize)
+			found := false
+			for i := range remaining {
+				bucket := &remaining[i]
+				if feeRate < bucket.MinFeeRate || (bucket.MaxFeeRate != 0 &&
+					feeRate >= bucket.MaxFeeRate) {
+
+					continue
+				}
+				bucket.Count--
+				bucket.VSize -= desc.vsize
+				found = true
+			}
+			if !found {
+				t.Fatalf("fee rate %v of %v has no bucket in %+v",
+					feeRate, tx.Hash(), histogram.Buckets)
+			}
+			wantVSize += desc.vsize
+		}
+		for _, bucket := range remaining {
+			if bucket.Count != 0 || bucket.VSize != 0 {
+				t.Fatalf("unexpected histogram %+v", histogram.Buckets)
+			}
+		}
+		if histogram.Count != int64(len(txns)) || histogram.VSize != wantVSize {
+			t.Fatalf("unexpected histogram totals %d txns of %d vbytes, "+
+				"want %d txns of %d vbytes", histogram.Count,
+				histogram.VSize, len(txns), wantVSize)
+		}
+		for i := 1; i < len(histogram.Buckets); i++ {
+			if histogram.Buckets[i].MinFeeRate >=
+				histogram.Buckets[i-1].MinFeeRate {
+
+				t.Fatalf("buckets are not sorted by decreasing fee "+
+					"rate: %+v", histogram.Buckets)
+			}
+		}
+	}
+	checkHistogram()
+
+	coinbase := ctx.addCoinbaseTx(4)
+	spend := func(i uint32, fee btcutil.Amount) *btcutil.Tx {
+		return ctx.addSignedTx([]spendableOutput{
+			txOutToSpendableOut(coinbase, i),
+		}, 1, fee, false, false)
+	}
+	a := spend(0, 1000)
+	b := spend(1, 1100)
+	c := spend(2, 50000)
+	d := spend(3, 5000000)
+	checkHistogram(a, b, c, d)
+	if n := len(mp.FeeHistogram().Buckets); n != 3 {
+		t.Fatalf("unexpected number of buckets %d, want 3", n)
+	}
+
+	mp.RemoveTransaction(c, false)
+	checkHistogram(a, b, d)
+	mp.RemoveTransaction(a, false)
+	mp.RemoveTransaction(b, false)
+	mp.RemoveTransaction(d, false)
+	checkHistogram()
+}
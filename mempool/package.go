@@ -0,0 +1,293 @@
+package mempool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+const (
+	// MaxPackageCount is the maximum number of transactions allowed in a
+	// package.
+	MaxPackageCount = 25
+
+	// maxLowFeeTxns is the maximum number of transactions rejected for
+	// paying insufficient fees that are kept around so they can be
+	// accepted later as part of a package with a child paying for them.
+	maxLowFeeTxns = 100
+)
+
+// checkPackageTopology ensures the passed transactions form a child-with-
+// parents package: the last transaction is the child, every other transaction
+// is a parent spent directly by the child, transactions appear after all of
+// their in-package parents, and no two transactions spend the same output.
+func checkPackageTopology(txns []*btcutil.Tx) error {
+	if len(txns) == 0 || len(txns) > MaxPackageCount {
+		str := fmt.Sprintf("package must contain between 1 and %d "+
+			"transactions", MaxPackageCount)
+		return txRuleError(wire.RejectInvalid, str)
+	}
+
+	seen := make(map[chainhash.Hash]struct{}, len(txns))
+	spent := make(map[wire.OutPoint]struct{})
+	for _, tx := range txns {
+		txHash := tx.Hash()
+		if _, ok := seen[*txHash]; ok {
+			str := fmt.Sprintf("package contains duplicate "+
+				"transaction %v", txHash)
+			return txRuleError(wire.RejectInvalid, str)
+		}
+
+		for _, txIn := range tx.MsgTx().TxIn {
+			prevOut := txIn.PreviousOutPoint
+			if _, ok := spent[prevOut]; ok {
+				str := fmt.Sprintf("output %v is spent more "+
+					"than once in the package", prevOut)
+				return txRuleError(wire.RejectDuplicate, str)
+			}
+			spent[prevOut] = struct{}{}
+		}
+		seen[*txHash] = struct{}{}
+	}
+
+	// Every transaction must only spend in-package transactions that come
+	// before it, which also rules out cycles.
+	position := make(map[chainhash.Hash]int, len(txns))
+	for i, tx := range txns {
+		position[*tx.Hash()] = i
+	}
+	for i, tx := range txns {
+		for _, txIn := range tx.MsgTx().TxIn {
+			j, ok := position[txIn.PreviousOutPoint.Hash]
+			if ok && j >= i {
+				str := fmt.Sprintf("package transaction %v is "+
+					"not sorted after its parent %v",
+					tx.Hash(), txns[j].Hash())
+				return txRuleError(wire.RejectInvalid, str)
+			}
+		}
+	}
+
+	// Every transaction other than the child must be one of its parents.
+	child := txns[len(txns)-1]
+	parents := make(map[chainhash.Hash]struct{})
+	for _, txIn := range child.MsgTx().TxIn {
+		parents[txIn.PreviousOutPoint.Hash] = struct{}{}
+	}
+	for _, tx := range txns[:len(txns)-1] {
+		if _, ok := parents[*tx.Hash()]; !ok {
+			str := fmt.Sprintf("package transaction %v is not a "+
+				"parent of the child %v", tx.Hash(), child.Hash())
+			return txRuleError(wire.RejectInvalid, str)
+		}
+	}
+
+	return nil
+}
+
+// isInsufficientFeeError returns whether or not the passed error is a rule
+// error rejecting a transaction for paying too low fees.
+func isInsufficientFeeError(err error) bool {
+	rerr, ok := err.(RuleError)
+	if !ok {
+		return false
+	}
+	txErr, ok := rerr.Err.(TxRuleError)
+	return ok && txErr.RejectCode == wire.RejectInsufficientFee
+}
+
+// processPackage is the internal function which implements the public
+// ProcessPackage.  See the comment for ProcessPackage for more details.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) processPackage(txns []*btcutil.Tx, rateLimit bool) ([]*TxDesc, error) {
+	if err := checkPackageTopology(txns); err != nil {
+		return nil, err
+	}
+
+	// Check every transaction that is not already in the pool, deferring
+	// the fee checks of those which don't pay for themselves to the
+	// package as a whole.
+	pkgTxns := make(map[chainhash.Hash]*btcutil.Tx, len(txns))
+	results := make([]*MempoolAcceptResult, len(txns))
+	var needPackageFee bool
+	var pkgFee, pkgSize int64
+	for i, tx := range txns {
+		if mp.isTransactionInPool(tx.Hash()) {
+			continue
+		}
+
+		result, err := mp.checkMempoolAcceptance(tx, true, rateLimit,
+			false, pkgTxns, false)
+		if isInsufficientFeeError(err) {
+			needPackageFee = true
+			result, err = mp.checkMempoolAcceptance(tx, true, false,
+				false, pkgTxns, true)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(result.MissingParents) > 0 {
+			str := fmt.Sprintf("package transaction %v references "+
+				"outputs of unknown or fully-spent transaction %v",
+				tx.Hash(), result.MissingParents[0])
+			return nil, txRuleError(wire.RejectDuplicate, str)
+		}
+		if len(result.Conflicts) > 0 {
+			str := fmt.Sprintf("package transaction %v may not "+
+				"replace transactions in the pool", tx.Hash())
+			return nil, txRuleError(wire.RejectDuplicate, str)
+		}
+
+		results[i] = result
+		pkgTxns[*tx.Hash()] = tx
+		pkgFee += int64(result.TxFee)
+		pkgSize += result.TxSize
+	}
+
+	// The package must pay the minimum relay fee for its combined size
+	// when any transaction in it didn't pay for itself.  The minimum fee of
+	// a full pool applies as well.
+	if needPackageFee {
+		minFee := calcMinRequiredTxRelayFee(pkgSize,
+			mp.minFeeRate(time.Now()))
+		if pkgFee < minFee {
+			str := fmt.Sprintf("package has %d fees which is under "+
+				"the required amount of %d for its size of %d",
+				pkgFee, minFee, pkgSize)
+			return nil, txRuleError(wire.RejectInsufficientFee, str)
+		}
+	}
+
+	// Add the transactions to the pool, parents first, along with any
+	// orphans that depend on them.
+	var acceptedTxs []*TxDesc
+	for i, tx := range txns {
+		result := results[i]
+		if result == nil {
+			continue
+		}
+
+		txD := mp.addTransaction(result.utxoView, tx, result.bestHeight,
+			int64(result.TxFee))
+		mp.removeOrphan(tx, false)
+		delete(mp.lowFeeTxns, *tx.Hash())
+		acceptedTxs = append(acceptedTxs, txD)
+
+		log.Debugf("Accepted package transaction %v (pool size: %v)",
+			tx.Hash(), len(mp.pool))
+	}
+	numPkgTxs := len(acceptedTxs)
+	for _, txD := range acceptedTxs[:numPkgTxs] {
+		acceptedTxs = append(acceptedTxs, mp.processOrphans(txD.Tx)...)
+	}
+
+	// Make room for the package when the pool is full and only report the
+	// transactions which were not evicted to do so.
+	mp.trimToSize()
+	remaining := acceptedTxs[:0]
+	for _, txD := range acceptedTxs {
+		if mp.isTransactionInPool(txD.Tx.Hash()) {
+			remaining = append(remaining, txD)
+		}
+	}
+
+	return remaining, nil
+}
+
+// ProcessPackage evaluates the passed child-with-parents package for
+// acceptance into the memory pool as a whole.  The last transaction is the
+// child and every other transaction must be one of its parents, sorted so
+// that parents come before their children.  Parents that are already in the
+// pool are skipped.
+//
+// Transactions which do not pay enough fees to be accepted on their own are
+// accepted when the fees of the package cover the minimum relay fee for the
+// combined size of its new transactions.  This allows a child to pay for its
+// parents.
+//
+// It returns a slice of transactions added to the mempool, parents first,
+// followed by any orphans accepted as a result.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) ProcessPackage(txns []*btcutil.Tx, rateLimit bool) ([]*TxDesc, error) {
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	return mp.processPackage(txns, rateLimit)
+}
+
+// addLowFeeTx remembers a transaction that was rejected for paying too low
+// fees so it can be accepted later as the parent in a package.  A random entry
+// is evicted when the cache is full.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) addLowFeeTx(tx *btcutil.Tx) {
+	if len(mp.lowFeeTxns) >= maxLowFeeTxns {
+		for hash := range mp.lowFeeTxns {
+			delete(mp.lowFeeTxns, hash)
+			break
+		}
+	}
+	mp.lowFeeTxns[*tx.Hash()] = tx
+}
+
+// maybeAcceptWithLowFeeParents attempts to accept the passed orphan as the
+// child in a package together with those of its missing parents that were
+// previously rejected for paying too low fees.  It returns nil when any of the
+// missing parents is unknown or the package is rejected.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) maybeAcceptWithLowFeeParents(child *btcutil.Tx,
+	missingParents []*chainhash.Hash, rateLimit bool) []*TxDesc {
+
+	txns := make([]*btcutil.Tx, 0, len(missingParents)+1)
+	added := make(map[chainhash.Hash]struct{}, len(missingParents))
+	for _, hash := range missingParents {
+		if _, ok := added[*hash]; ok {
+			continue
+		}
+		parent, ok := mp.lowFeeTxns[*hash]
+		if !ok {
+			return nil
+		}
+		txns = append(txns, parent)
+		added[*hash] = struct{}{}
+	}
+	txns = append(txns, child)
+
+	acceptedTxs, err := mp.processPackage(txns, rateLimit)
+	if err != nil {
+		log.Debugf("Rejected package with child %v: %v", child.Hash(),
+			err)
+		return nil
+	}
+	return acceptedTxs
+}
+
+// maybeAcceptWithOrphanChild attempts to accept the passed transaction, which
+// was rejected for paying too low fees, as the parent in a package together
+// with an orphan spending it.  It returns nil when no such package is
+// accepted.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) maybeAcceptWithOrphanChild(parent *btcutil.Tx, rateLimit bool) []*TxDesc {
+	prevOut := wire.OutPoint{Hash: *parent.Hash()}
+	for txOutIdx := range parent.MsgTx().TxOut {
+		prevOut.Index = uint32(txOutIdx)
+		for _, child := range mp.orphansByPrev[prevOut] {
+			acceptedTxs, err := mp.processPackage(
+				[]*btcutil.Tx{parent, child}, rateLimit)
+			if err != nil {
+				log.Debugf("Rejected package with child %v: %v",
+					child.Hash(), err)
+				continue
+			}
+			return acceptedTxs
+		}
+	}
+	return nil
+}
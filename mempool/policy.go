@@ -54,8 +54,110 @@ const (
 	// in a multi-signature transaction output script for it to be
 	// considered standard.
 	maxStandardMultiSigKeys = 3
+
+	// DefaultMaxDataCarrierSize is the default maximum number of bytes of
+	// data pushed in a standard null data (OP_RETURN) output script.
+	DefaultMaxDataCarrierSize = txscript.MaxDataCarrierSize
+
+	// DefaultMaxClaimValueSize is the default maximum size of the value of
+	// a standard claim script, which is the largest allowed by consensus.
+	DefaultMaxClaimValueSize = txscript.MaxClaimScriptSize
 )
 
+// StandardPolicy houses the configurable rules deciding whether a transaction
+// is standard.  Only standard transactions are accepted into the memory pool
+// and relayed unless non-standard transactions are accepted.
+type StandardPolicy struct {
+	// DustRelayFee is the fee rate in Satoshi/kB used to determine whether
+	// an output is dust, which is when spending it costs more than a third
+	// of its value.  Zero means the minimum relay fee is used.
+	DustRelayFee btcutil.Amount
+
+	// MaxDataCarrierSize is the maximum number of bytes of data pushed in
+	// a null data (OP_RETURN) output script.
+	MaxDataCarrierSize int
+
+	// MaxClaimValueSize is the maximum size of the value of the claim,
+	// update and support scripts.
+	MaxClaimValueSize int
+
+	// MinClaimAmount is the minimum amount of the claim and update outputs,
+	// which encumber the claim trie as long as they're unspent.  It
+	// applies in addition to the dust threshold.  Zero means only the dust
+	// threshold applies.
+	MinClaimAmount btcutil.Amount
+
+	// MinSupportAmount is the minimum amount of the support outputs, which
+	// encumber the claim trie as long as they're unspent.  It applies in
+	// addition to the dust threshold.  Zero means only the dust threshold
+	// applies.
+	MinSupportAmount btcutil.Amount
+
+	// PermitBareMultiSig defines whether bare multi-signature output
+	// scripts, as opposed to pay-to-script-hash ones, are standard.
+	PermitBareMultiSig bool
+}
+
+// DefaultStandardPolicy returns the standardness rules used when none are
+// configured.
+func DefaultStandardPolicy() *StandardPolicy {
+	return &StandardPolicy{
+		MaxDataCarrierSize: DefaultMaxDataCarrierSize,
+		MaxClaimValueSize:  DefaultMaxClaimValueSize,
+		PermitBareMultiSig: true,
+	}
+}
+
+// dustRelayFee returns the fee rate used to determine dust outputs given the
+// passed minimum relay fee.
+func (p *StandardPolicy) dustRelayFee(minRelayTxFee btcutil.Amount) btcutil.Amount {
+	if p.DustRelayFee == 0 {
+		return minRelayTxFee
+	}
+	return p.DustRelayFee
+}
+
+// minClaimScriptAmount returns the minimum amount of the outputs with the
+// passed claim script along with the kind of claim script they hold.
+func (p *StandardPolicy) minClaimScriptAmount(cs *txscript.ClaimScript) (btcutil.Amount, string) {
+	switch cs.Opcode {
+	case txscript.OP_SUPPORTCLAIM:
+		return p.MinSupportAmount, "support"
+	case txscript.OP_UPDATECLAIM:
+		return p.MinClaimAmount, "update"
+	default:
+		return p.MinClaimAmount, "claim"
+	}
+}
+
+// nullDataSize returns the number of bytes of data pushed in the passed script
+// when it is a null data script, which is an OP_RETURN optionally followed by
+// a single data push.  It returns false for any other script.
+//
+// Unlike the null data script class of txscript, the size of the data isn't
+// limited so the configured limit can be enforced instead.
+func nullDataSize(pkScript []byte) (int, bool) {
+	if len(pkScript) < 1 || pkScript[0] != txscript.OP_RETURN {
+		return 0, false
+	}
+	if len(pkScript) == 1 {
+		return 0, true
+	}
+
+	const scriptVersion = 0
+	tokenizer := txscript.MakeScriptTokenizer(scriptVersion, pkScript[1:])
+	if !tokenizer.Next() || !tokenizer.Done() {
+		return 0, false
+	}
+	op := tokenizer.Opcode()
+	isSmallInt := op == txscript.OP_0 ||
+		(op >= txscript.OP_1 && op <= txscript.OP_16)
+	if !isSmallInt && op > txscript.OP_PUSHDATA4 {
+		return 0, false
+	}
+	return len(tokenizer.Data()), true
+}
+
 // calcMinRequiredTxRelayFee returns the minimum transaction fee required for a
 // transaction with the passed serialized size to be accepted into the memory
 // pool and relayed.
@@ -80,6 +182,30 @@ func calcMinRequiredTxRelayFee(serializedSize int64, minRelayTxFee btcutil.Amoun
 	return minFee
 }
 
+// stripClaimScriptPrefixes returns the passed public key script stripped of its
+// claim script prefix, if any.  The lock time prefix of time-locked claims is
+// stripped as well when the claims are paid to a pay-to-pubkey-hash or a
+// pay-to-pubkey script.  Other scripts are not recognized behind a lock time
+// prefix since the script engine only treats pay-to-script-hash scripts and
+// witness programs as such when they are the whole public key script, so the
+// script is returned with its lock time prefix and is non-standard.
+func stripClaimScriptPrefixes(pkScript []byte) []byte {
+	script := txscript.StripClaimScriptPrefix(pkScript)
+	if len(script) == len(pkScript) {
+		return pkScript
+	}
+
+	lock := txscript.ExtractTimeLock(script)
+	if lock == nil {
+		return script
+	}
+	switch txscript.GetScriptClass(script[lock.Size:]) {
+	case txscript.PubKeyHashTy, txscript.PubKeyTy:
+		return script[lock.Size:]
+	}
+	return script
+}
+
 // checkInputsStandard performs a series of checks on a transaction's inputs
 // to ensure they are "standard".  A standard transaction input within the
 // context of this function is one whose referenced public key script is of a
@@ -100,7 +226,7 @@ func checkInputsStandard(tx *btcutil.Tx, utxoView *blockchain.UtxoViewpoint) err
 		// they have already been checked prior to calling this
 		// function.
 		entry := utxoView.LookupEntry(txIn.PreviousOutPoint)
-		originPkScript := txscript.StripClaimScriptPrefix(entry.PkScript())
+		originPkScript := stripClaimScriptPrefixes(entry.PkScript())
 		switch txscript.GetScriptClass(originPkScript) {
 		case txscript.ScriptHashTy:
 			numSigOps := txscript.GetPreciseSigOpCount(
@@ -288,8 +414,8 @@ func IsDust(txOut *wire.TxOut, minRelayTxFee btcutil.Amount) bool {
 // finalized, conforming to more stringent size constraints, having scripts
 // of recognized forms, and not containing "dust" outputs (those that are
 // so small it costs more to process them than they are worth).
-func checkTransactionStandard(tx *btcutil.Tx, height int32,
-	medianTimePast time.Time, minRelayTxFee btcutil.Amount,
+func (p *StandardPolicy) checkTransactionStandard(tx *btcutil.Tx,
+	height int32, medianTimePast time.Time, minRelayTxFee btcutil.Amount,
 	maxTxVersion int32) error {
 
 	// The transaction must be a currently supported version.
@@ -344,10 +470,55 @@ func checkTransactionStandard(tx *btcutil.Tx, height int32,
 	// None of the output public key scripts can be a non-standard script or
 	// be "dust" (except when the script is a null data script).
 	numNullDataOutputs := 0
+	dustRelayFee := p.dustRelayFee(minRelayTxFee)
 	for i, txOut := range msgTx.TxOut {
-		pkScript := txscript.StripClaimScriptPrefix(txOut.PkScript)
+		// The values of claim scripts must not exceed the maximum size
+		// allowed for a standard transaction.
+		cs, err := txscript.ExtractClaimScript(txOut.PkScript)
+		if err == nil && len(cs.Value) > p.MaxClaimValueSize {
+			str := fmt.Sprintf("transaction output %d: claim "+
+				"value size of %d bytes is larger than max "+
+				"allowed size of %d bytes", i, len(cs.Value),
+				p.MaxClaimValueSize)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+
+		// The claim and support outputs must not be below the minimum
+		// amount configured for them since they encumber the claim
+		// trie.
+		if err == nil {
+			minAmount, kind := p.minClaimScriptAmount(cs)
+			if txOut.Value < int64(minAmount) {
+				str := fmt.Sprintf("transaction output %d: %s "+
+					"amount of %d is below the minimum of %d",
+					i, kind, txOut.Value, minAmount)
+				return txRuleError(wire.RejectDust, str)
+			}
+		}
+
+		pkScript := stripClaimScriptPrefixes(txOut.PkScript)
 		scriptClass := txscript.GetScriptClass(pkScript)
-		err := checkPkScriptStandard(pkScript, scriptClass)
+
+		// The size of the data of null data scripts is limited by the
+		// policy rather than by the script class.
+		if size, ok := nullDataSize(pkScript); ok {
+			if size > p.MaxDataCarrierSize {
+				str := fmt.Sprintf("transaction output %d: "+
+					"null data size of %d bytes is larger "+
+					"than max allowed size of %d bytes", i,
+					size, p.MaxDataCarrierSize)
+				return txRuleError(wire.RejectNonstandard, str)
+			}
+			scriptClass = txscript.NullDataTy
+		}
+
+		if scriptClass == txscript.MultiSigTy && !p.PermitBareMultiSig {
+			str := fmt.Sprintf("transaction output %d: bare "+
+				"multi-signature script", i)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+
+		err = checkPkScriptStandard(pkScript, scriptClass)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -365,7 +536,7 @@ func checkTransactionStandard(tx *btcutil.Tx, height int32,
 		// "dust".
 		if scriptClass == txscript.NullDataTy {
 			numNullDataOutputs++
-		} else if IsDust(txOut, minRelayTxFee) {
+		} else if IsDust(txOut, dustRelayFee) {
 			str := fmt.Sprintf("transaction output %d: payment "+
 				"of %d is dust", i, txOut.Value)
 			return txRuleError(wire.RejectDust, str)
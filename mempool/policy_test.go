@@ -469,8 +469,9 @@ func TestCheckTransactionStandard(t *testing.T) {
 	pastMedianTime := time.Now()
 	for _, test := range tests {
 		// Ensure standardness is as expected.
-		err := checkTransactionStandard(btcutil.NewTx(&test.tx),
-			test.height, pastMedianTime, DefaultMinRelayTxFee, 1)
+		err := DefaultStandardPolicy().checkTransactionStandard(
+			btcutil.NewTx(&test.tx), test.height, pastMedianTime,
+			DefaultMinRelayTxFee, 1)
 		if err == nil && test.isStandard {
 			// Test passes since function returned standard for a
 			// transaction which is intended to be standard.
@@ -510,3 +511,204 @@ func TestCheckTransactionStandard(t *testing.T) {
 		}
 	}
 }
+
+// TestStandardPolicy ensures the configurable standardness rules are applied
+// to the outputs of transactions.
+func TestStandardPolicy(t *testing.T) {
+	pk, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("NewPrivateKey: unexpected error: %v", err)
+	}
+	addrHash := [20]byte{0x01}
+	addr, err := btcutil.NewAddressPubKeyHash(addrHash[:],
+		&chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	p2pkhScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	multiSigScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_1).
+		AddData(pk.PubKey().SerializeCompressed()).AddOp(txscript.OP_1).
+		AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		t.Fatalf("Script: unexpected error: %v", err)
+	}
+	nullDataScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_RETURN).AddData(bytes.Repeat([]byte{0x01}, 100)).
+		Script()
+	if err != nil {
+		t.Fatalf("Script: unexpected error: %v", err)
+	}
+	claimScript, err := txscript.NewClaimNameScript([]byte("name"),
+		bytes.Repeat([]byte{0x01}, 1000), p2pkhScript)
+	if err != nil {
+		t.Fatalf("NewClaimNameScript: unexpected error: %v", err)
+	}
+	supportScript, err := txscript.NewSupportClaimScript([]byte("name"),
+		bytes.Repeat([]byte{0x02}, 20), nil, p2pkhScript)
+	if err != nil {
+		t.Fatalf("NewSupportClaimScript: unexpected error: %v", err)
+	}
+	timeLock := func(lockOp byte, pkScript []byte) []byte {
+		script, err := txscript.NewScriptBuilder().AddInt64(500000).
+			AddOp(lockOp).AddOp(txscript.OP_DROP).Script()
+		if err != nil {
+			t.Fatalf("Script: unexpected error: %v", err)
+		}
+		return append(script, pkScript...)
+	}
+	timeLockedClaim := func(lockOp byte, pkScript []byte) []byte {
+		script, err := txscript.NewClaimNameScript([]byte("name"),
+			[]byte("value"), timeLock(lockOp, pkScript))
+		if err != nil {
+			t.Fatalf("NewClaimNameScript: unexpected error: %v", err)
+		}
+		return script
+	}
+	p2shScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_HASH160).
+		AddData(addrHash[:]).AddOp(txscript.OP_EQUAL).Script()
+	if err != nil {
+		t.Fatalf("Script: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		policy     StandardPolicy
+		txOut      wire.TxOut
+		isStandard bool
+	}{
+		{
+			name:       "bare multisig permitted",
+			policy:     *DefaultStandardPolicy(),
+			txOut:      wire.TxOut{Value: 1000, PkScript: multiSigScript},
+			isStandard: true,
+		},
+		{
+			name:       "bare multisig not permitted",
+			policy:     StandardPolicy{MaxDataCarrierSize: 80, MaxClaimValueSize: 1000},
+			txOut:      wire.TxOut{Value: 1000, PkScript: multiSigScript},
+			isStandard: false,
+		},
+		{
+			name:       "null data larger than default limit",
+			policy:     *DefaultStandardPolicy(),
+			txOut:      wire.TxOut{PkScript: nullDataScript},
+			isStandard: false,
+		},
+		{
+			name:       "null data within raised limit",
+			policy:     StandardPolicy{MaxDataCarrierSize: 100},
+			txOut:      wire.TxOut{PkScript: nullDataScript},
+			isStandard: true,
+		},
+		{
+			name:       "empty null data with no data allowed",
+			policy:     StandardPolicy{},
+			txOut:      wire.TxOut{PkScript: []byte{txscript.OP_RETURN}},
+			isStandard: true,
+		},
+		{
+			name:       "claim value within limit",
+			policy:     StandardPolicy{MaxClaimValueSize: 1000},
+			txOut:      wire.TxOut{Value: 1000000, PkScript: claimScript},
+			isStandard: true,
+		},
+		{
+			name:       "claim value larger than limit",
+			policy:     StandardPolicy{MaxClaimValueSize: 999},
+			txOut:      wire.TxOut{Value: 1000000, PkScript: claimScript},
+			isStandard: false,
+		},
+		{
+			name:       "claim amount at minimum",
+			policy:     StandardPolicy{MaxClaimValueSize: 1000, MinClaimAmount: 1000000},
+			txOut:      wire.TxOut{Value: 1000000, PkScript: claimScript},
+			isStandard: true,
+		},
+		{
+			name:       "claim amount below minimum",
+			policy:     StandardPolicy{MaxClaimValueSize: 1000, MinClaimAmount: 1000001},
+			txOut:      wire.TxOut{Value: 1000000, PkScript: claimScript},
+			isStandard: false,
+		},
+		{
+			name:       "claim amount below support minimum",
+			policy:     StandardPolicy{MaxClaimValueSize: 1000, MinSupportAmount: 1000001},
+			txOut:      wire.TxOut{Value: 1000000, PkScript: claimScript},
+			isStandard: true,
+		},
+		{
+			name:       "support amount below minimum",
+			policy:     StandardPolicy{MinSupportAmount: 1000001},
+			txOut:      wire.TxOut{Value: 1000000, PkScript: supportScript},
+			isStandard: false,
+		},
+		{
+			name:       "support amount below claim minimum",
+			policy:     StandardPolicy{MinClaimAmount: 1000001},
+			txOut:      wire.TxOut{Value: 1000000, PkScript: supportScript},
+			isStandard: true,
+		},
+		{
+			name:   "claim time-locked with CLTV",
+			policy: *DefaultStandardPolicy(),
+			txOut: wire.TxOut{Value: 1000000, PkScript: timeLockedClaim(
+				txscript.OP_CHECKLOCKTIMEVERIFY, p2pkhScript)},
+			isStandard: true,
+		},
+		{
+			name:   "claim time-locked with CSV",
+			policy: *DefaultStandardPolicy(),
+			txOut: wire.TxOut{Value: 1000000, PkScript: timeLockedClaim(
+				txscript.OP_CHECKSEQUENCEVERIFY, p2pkhScript)},
+			isStandard: true,
+		},
+		{
+			name:   "time-locked claim paid to pay-to-script-hash",
+			policy: *DefaultStandardPolicy(),
+			txOut: wire.TxOut{Value: 1000000, PkScript: timeLockedClaim(
+				txscript.OP_CHECKLOCKTIMEVERIFY, p2shScript)},
+			isStandard: false,
+		},
+		{
+			name:   "time-locked output without claim",
+			policy: *DefaultStandardPolicy(),
+			txOut: wire.TxOut{Value: 1000000, PkScript: timeLock(
+				txscript.OP_CHECKLOCKTIMEVERIFY, p2pkhScript)},
+			isStandard: false,
+		},
+		{
+			name:       "output above dust with minimum relay fee",
+			policy:     StandardPolicy{},
+			txOut:      wire.TxOut{Value: 1000, PkScript: p2pkhScript},
+			isStandard: true,
+		},
+		{
+			name:       "output dust with raised dust relay fee",
+			policy:     StandardPolicy{DustRelayFee: 10000},
+			txOut:      wire.TxOut{Value: 1000, PkScript: p2pkhScript},
+			isStandard: false,
+		},
+	}
+
+	prevOut := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 1}
+	for _, test := range tests {
+		tx := wire.MsgTx{
+			Version: 1,
+			TxIn: []*wire.TxIn{{
+				PreviousOutPoint: prevOut,
+				Sequence:         wire.MaxTxInSequenceNum,
+			}},
+			TxOut: []*wire.TxOut{&test.txOut},
+		}
+		err := test.policy.checkTransactionStandard(btcutil.NewTx(&tx),
+			300000, time.Now(), DefaultMinRelayTxFee, 1)
+		if isStandard := err == nil; isStandard != test.isStandard {
+			t.Errorf("checkTransactionStandard (%s): got standard "+
+				"%v, want %v: %v", test.name, isStandard,
+				test.isStandard, err)
+		}
+	}
+}
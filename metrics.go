@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/claimtrie"
+)
+
+// repoMetric describes a metric of the pebble databases of the claim trie
+// exported in the Prometheus text format.
+type repoMetric struct {
+	name   string
+	kind   string
+	help   string
+	metric func(m *claimtrie.RepoMetrics) float64
+}
+
+// repoMetrics is the metrics of the pebble databases of the claim trie which
+// are exported, labeled by repo.
+var repoMetrics = []repoMetric{
+	{"lbcd_claimtrie_repo_disk_size_bytes", "gauge",
+		"Disk space used by the pebble database of the repo.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.DiskSpaceUsage()) }},
+	{"lbcd_claimtrie_repo_block_cache_size_bytes", "gauge",
+		"Size of the block cache of the repo.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.BlockCache.Size) }},
+	{"lbcd_claimtrie_repo_block_cache_hits_total", "counter",
+		"Hits of the block cache of the repo.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.BlockCache.Hits) }},
+	{"lbcd_claimtrie_repo_block_cache_misses_total", "counter",
+		"Misses of the block cache of the repo.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.BlockCache.Misses) }},
+	{"lbcd_claimtrie_repo_memtable_size_bytes", "gauge",
+		"Size of the memtables of the repo.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.MemTable.Size) }},
+	{"lbcd_claimtrie_repo_flushes_total", "counter",
+		"Flushes of the memtables of the repo.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.Flush.Count) }},
+	{"lbcd_claimtrie_repo_compactions_total", "counter",
+		"Compactions of the repo.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.Compact.Count) }},
+	{"lbcd_claimtrie_repo_compaction_debt_bytes", "gauge",
+		"Estimated bytes to compact for the repo to reach a stable state.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.Compact.EstimatedDebt) }},
+	{"lbcd_claimtrie_repo_read_amplification", "gauge",
+		"Read amplification of the repo.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.ReadAmp()) }},
+	{"lbcd_claimtrie_repo_filter_hits_total", "counter",
+		"Lookups of the repo avoided by bloom filters.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.Filter.Hits) }},
+	{"lbcd_claimtrie_repo_filter_misses_total", "counter",
+		"Lookups of the repo not avoided by bloom filters.",
+		func(m *claimtrie.RepoMetrics) float64 { return float64(m.Filter.Misses) }},
+}
+
+// metricsHandler returns a handler serving the metrics of the pebble databases
+// of the claim trie of the passed chain in the Prometheus text format, along
+// with the statistics of the methods of the passed RPC server when it's not
+// nil.
+func metricsHandler(chain *blockchain.BlockChain, rpc *rpcServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var metrics []claimtrie.RepoMetrics
+		if ct := chain.ClaimTrie(); ct != nil {
+			metrics = ct.RepoMetrics()
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		bw := bufio.NewWriter(w)
+		for _, metric := range repoMetrics {
+			fmt.Fprintf(bw, "# HELP %s %s\n", metric.name, metric.help)
+			fmt.Fprintf(bw, "# TYPE %s %s\n", metric.name, metric.kind)
+			for i := range metrics {
+				fmt.Fprintf(bw, "%s{repo=%q} %v\n", metric.name,
+					metrics[i].Repo, metric.metric(&metrics[i]))
+			}
+		}
+		if rpc != nil {
+			rpc.rpcStats.writeMetrics(bw)
+		}
+		bw.Flush()
+	})
+}
@@ -61,6 +61,11 @@ type Config struct {
 	// blocks.  Each generated block will randomly choose one of them.
 	MiningAddrs []btcutil.Address
 
+	// Payouts is a list of payouts to split the coinbase of the generated
+	// blocks among instead of paying it to one of the mining addresses.
+	// It can be changed with SetPayouts.
+	Payouts []mining.Payout
+
 	// ProcessBlock defines the function to call with any solved blocks.
 	// It typically must run the provided block through the same set of
 	// rules and handling as any other block coming from the network.
@@ -104,6 +109,9 @@ type CPUMiner struct {
 	updateHashes      chan uint64
 	speedMonitorQuit  chan struct{}
 	quit              chan struct{}
+
+	payoutsMtx sync.Mutex
+	payouts    []mining.Payout
 }
 
 // speedMonitor handles tracking the number of hashes per second the mining
@@ -189,12 +197,41 @@ func (m *CPUMiner) submitBlock(block *btcutil.Block) bool {
 	}
 
 	// The block was accepted.
-	coinbaseTx := block.MsgBlock().Transactions[0].TxOut[0]
+	var amount int64
+	for _, txOut := range block.MsgBlock().Transactions[0].TxOut {
+		amount += txOut.Value
+	}
 	log.Infof("Block submitted via CPU miner accepted (hash %s, "+
-		"amount %v)", block.Hash(), btcutil.Amount(coinbaseTx.Value))
+		"amount %v)", block.Hash(), btcutil.Amount(amount))
 	return true
 }
 
+// newBlockTemplate returns a new block template using the available
+// transactions in the memory pool as a source of transactions to potentially
+// include in the block.  Its coinbase pays to the passed address, or when it's
+// nil, is split among the current payouts or paid to a mining address chosen
+// at random when there are none.
+func (m *CPUMiner) newBlockTemplate(payToAddr btcutil.Address) (*mining.BlockTemplate, error) {
+	if payToAddr != nil {
+		return m.g.NewBlockTemplate(payToAddr)
+	}
+
+	m.payoutsMtx.Lock()
+	payouts := m.payouts
+	m.payoutsMtx.Unlock()
+	if len(payouts) > 0 {
+		return m.g.NewBlockTemplateWithPayouts(payouts)
+	}
+
+	// Choose a payment address at random.
+	if len(m.cfg.MiningAddrs) == 0 {
+		return nil, errors.New("no payment addresses or payouts")
+	}
+	rand.Seed(time.Now().UnixNano())
+	payToAddr = m.cfg.MiningAddrs[rand.Intn(len(m.cfg.MiningAddrs))]
+	return m.g.NewBlockTemplate(payToAddr)
+}
+
 // solveBlock attempts to find some combination of a nonce, extra nonce, and
 // current timestamp which makes the passed block hash to a value less than the
 // target difficulty.  The timestamp is updated periodically and the passed
@@ -334,14 +371,10 @@ out:
 			continue
 		}
 
-		// Choose a payment address at random.
-		rand.Seed(time.Now().UnixNano())
-		payToAddr := m.cfg.MiningAddrs[rand.Intn(len(m.cfg.MiningAddrs))]
-
 		// Create a new block template using the available transactions
 		// in the memory pool as a source of transactions to potentially
 		// include in the block.
-		template, err := m.g.NewBlockTemplate(payToAddr)
+		template, err := m.newBlockTemplate(nil)
 		m.submitBlockLock.Unlock()
 		if err != nil {
 			errStr := fmt.Sprintf("Failed to create new block "+
@@ -539,10 +572,35 @@ func (m *CPUMiner) NumWorkers() int32 {
 	return int32(m.numWorkers)
 }
 
+// SetPayouts sets the payouts to split the coinbase of the generated blocks
+// among, in the order of the payouts.  No payouts causes the coinbase of each
+// block to be paid to one of the configured mining addresses chosen at random.
+// The new payouts apply to the next block templates.
+//
+// This function is safe for concurrent access.
+func (m *CPUMiner) SetPayouts(payouts []mining.Payout) {
+	m.payoutsMtx.Lock()
+	m.payouts = append([]mining.Payout(nil), payouts...)
+	m.payoutsMtx.Unlock()
+}
+
+// Payouts returns the payouts the coinbase of the generated blocks is split
+// among.
+//
+// This function is safe for concurrent access.
+func (m *CPUMiner) Payouts() []mining.Payout {
+	m.payoutsMtx.Lock()
+	defer m.payoutsMtx.Unlock()
+
+	return append([]mining.Payout(nil), m.payouts...)
+}
+
 // GenerateNBlocks generates the requested number of blocks. It is self
 // contained in that it creates block templates and attempts to solve them while
 // detecting when it is performing stale work and reacting accordingly by
 // generating a new block template.  When a block is solved, it is submitted.
+// The coinbase of the blocks pays to the passed address, or when it's nil, is
+// split among the current payouts or paid to one of the mining addresses.
 // The function returns a list of the hashes of generated blocks.
 func (m *CPUMiner) GenerateNBlocks(n uint32, payToAddr btcutil.Address) ([]*chainhash.Hash, error) {
 	m.Lock()
@@ -588,15 +646,10 @@ func (m *CPUMiner) GenerateNBlocks(n uint32, payToAddr btcutil.Address) ([]*chai
 		m.submitBlockLock.Lock()
 		curHeight := m.g.BestSnapshot().Height
 
-		// Choose a payment address at random.
-		rand.Seed(time.Now().UnixNano())
-		if payToAddr == nil {
-			payToAddr = m.cfg.MiningAddrs[rand.Intn(len(m.cfg.MiningAddrs))]
-		}
 		// Create a new block template using the available transactions
 		// in the memory pool as a source of transactions to potentially
 		// include in the block.
-		template, err := m.g.NewBlockTemplate(payToAddr)
+		template, err := m.newBlockTemplate(payToAddr)
 		m.submitBlockLock.Unlock()
 		if err != nil {
 			errStr := fmt.Sprintf("Failed to create new block "+
@@ -639,5 +692,6 @@ func New(cfg *Config) *CPUMiner {
 		updateNumWorkers:  make(chan struct{}),
 		queryHashesPerSec: make(chan float64),
 		updateHashes:      make(chan uint64, 512),
+		payouts:           append([]mining.Payout(nil), cfg.Payouts...),
 	}
 }
@@ -7,7 +7,9 @@ package mining
 import (
 	"bytes"
 	"container/heap"
+	"errors"
 	"fmt"
+	"math/bits"
 	"time"
 
 	"github.com/lbryio/lbcd/blockchain"
@@ -81,6 +83,13 @@ type txPrioItem struct {
 	priority float64
 	feePerKB int64
 
+	// rank and packageFeePerKB are the position of the transaction in the
+	// selection order and the fee per kilobyte of the package of
+	// transactions it is selected with when selecting by ancestor fee
+	// rate.  See orderByAncestorFeeRate.
+	rank            int
+	packageFeePerKB int64
+
 	// dependsOn holds a map of transaction hashes which this one depends
 	// on.  It will only be set when the transaction references other
 	// transactions in the source pool and hence must come after them in
@@ -165,6 +174,12 @@ func txPQByFee(pq *txPriorityQueue, i, j int) bool {
 	return pq.items[i].feePerKB > pq.items[j].feePerKB
 }
 
+// txPQByRank sorts a txPriorityQueue by the rank of the transactions, which
+// selects the packages of transactions ordered by orderByAncestorFeeRate.
+func txPQByRank(pq *txPriorityQueue, i, j int) bool {
+	return pq.items[i].rank < pq.items[j].rank
+}
+
 // newTxPriorityQueue returns a new transaction priority queue that reserves the
 // passed amount of space for the elements.  The new priority queue uses either
 // the txPQByPriority or the txPQByFee compare function depending on the
@@ -244,32 +259,50 @@ func standardCoinbaseScript(nextBlockHeight int32, extraNonce uint64) ([]byte, e
 		Script()
 }
 
+// Payout is a payment address paid a share of the coinbase of a block template
+// proportional to its weight relative to the total weight of all the payouts
+// of the template.
+type Payout struct {
+	Address btcutil.Address
+	Weight  uint32
+}
+
+// splitCoinbaseValue sets the values of the payout outputs of the passed
+// coinbase transaction, which are its first outputs, so they split the passed
+// total value proportionally to the weights of the payouts.  The remainder of
+// the integer division goes to the first output.
+func splitCoinbaseValue(tx *wire.MsgTx, payouts []Payout, total int64) {
+	if len(payouts) == 0 {
+		tx.TxOut[0].Value = total
+		return
+	}
+
+	var totalWeight uint64
+	for _, payout := range payouts {
+		totalWeight += uint64(payout.Weight)
+	}
+	remaining := total
+	for i, payout := range payouts {
+		// The product is computed in 128 bits so it can't overflow,
+		// and the quotient fits in 64 bits since it's at most the
+		// total.
+		hi, lo := bits.Mul64(uint64(total), uint64(payout.Weight))
+		quo, _ := bits.Div64(hi, lo, totalWeight)
+		value := int64(quo)
+		tx.TxOut[i].Value = value
+		remaining -= value
+	}
+	tx.TxOut[0].Value += remaining
+}
+
 // createCoinbaseTx returns a coinbase transaction paying an appropriate subsidy
-// based on the passed block height to the provided address.  When the address
-// is nil, the coinbase transaction will instead be redeemable by anyone.
+// based on the passed block height split among the provided payouts.  When
+// there are no payouts, the coinbase transaction will instead be redeemable by
+// anyone.
 //
 // See the comment for NewBlockTemplate for more information about why the nil
 // address handling is useful.
-func createCoinbaseTx(params *chaincfg.Params, coinbaseScript []byte, nextBlockHeight int32, addr btcutil.Address) (*btcutil.Tx, error) {
-	// Create the script to pay to the provided payment address if one was
-	// specified.  Otherwise create a script that allows the coinbase to be
-	// redeemable by anyone.
-	var pkScript []byte
-	if addr != nil {
-		var err error
-		pkScript, err = txscript.PayToAddrScript(addr)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		var err error
-		scriptBuilder := txscript.NewScriptBuilder()
-		pkScript, err = scriptBuilder.AddOp(txscript.OP_TRUE).Script()
-		if err != nil {
-			return nil, err
-		}
-	}
-
+func createCoinbaseTx(params *chaincfg.Params, coinbaseScript []byte, nextBlockHeight int32, payouts []Payout) (*btcutil.Tx, error) {
 	tx := wire.NewMsgTx(wire.TxVersion)
 	tx.AddTxIn(&wire.TxIn{
 		// Coinbase transactions have no inputs, so previous outpoint is
@@ -279,10 +312,28 @@ func createCoinbaseTx(params *chaincfg.Params, coinbaseScript []byte, nextBlockH
 		SignatureScript: coinbaseScript,
 		Sequence:        wire.MaxTxInSequenceNum,
 	})
-	tx.AddTxOut(&wire.TxOut{
-		Value:    blockchain.CalcBlockSubsidy(nextBlockHeight, params),
-		PkScript: pkScript,
-	})
+
+	// Create the scripts to pay to the provided payment addresses if any
+	// were specified.  Otherwise create a script that allows the coinbase
+	// to be redeemable by anyone.
+	if len(payouts) == 0 {
+		pkScript, err := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_TRUE).Script()
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxOut(&wire.TxOut{PkScript: pkScript})
+	}
+	for _, payout := range payouts {
+		pkScript, err := txscript.PayToAddrScript(payout.Address)
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxOut(&wire.TxOut{PkScript: pkScript})
+	}
+	splitCoinbaseValue(tx, payouts,
+		blockchain.CalcBlockSubsidy(nextBlockHeight, params))
+
 	return btcutil.NewTx(tx), nil
 }
 
@@ -351,7 +402,7 @@ type BlkTmplGenerator struct {
 	txSource    TxSource
 	chain       *blockchain.BlockChain
 	timeSource  blockchain.MedianTimeSource
-	sigCache    *txscript.SigCache
+	sigCache    txscript.SignatureCache
 	hashCache   *txscript.HashCache
 }
 
@@ -364,7 +415,7 @@ type BlkTmplGenerator struct {
 func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 	txSource TxSource, chain *blockchain.BlockChain,
 	timeSource blockchain.MedianTimeSource,
-	sigCache *txscript.SigCache,
+	sigCache txscript.SignatureCache,
 	hashCache *txscript.HashCache) *BlkTmplGenerator {
 
 	return &BlkTmplGenerator{
@@ -395,6 +446,14 @@ func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 // higher fee per kilobyte are preferred.  Finally, the block generation related
 // policy settings are all taken into account.
 //
+// The above describes the SelectPriority selection strategy.  With the
+// SelectFeeRate strategy, there is no high-priority area and the transactions
+// are only prioritized by fee per kilobyte.  With the SelectAncestorFeeRate
+// strategy, each transaction is prioritized by the fee per kilobyte of the
+// package made of the transaction and its ancestors in the source pool which
+// haven't been selected yet, and the whole package is selected at once, so
+// transactions paying high fees to get their ancestors mined are preferred.
+//
 // Transactions which only spend outputs from other transactions already in the
 // block chain are immediately added to a priority queue which either
 // prioritizes based on the priority (then fee per kilobyte) or the fee per
@@ -441,12 +500,36 @@ func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 //	|  <= policy.BlockMinSize)          |   |
 //	 -----------------------------------  --
 func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcutil.Address) (*BlockTemplate, error) {
+	var payouts []Payout
+	if payToAddress != nil {
+		payouts = []Payout{{Address: payToAddress, Weight: 1}}
+	}
+	return g.NewBlockTemplateWithPayouts(payouts)
+}
+
+// NewBlockTemplateWithPayouts returns a new block template like
+// NewBlockTemplate, except its coinbase, including the fees of the selected
+// transactions, is split among the passed payouts proportionally to their
+// weights, in the order of the payouts.  The coinbase is redeemable by anyone
+// when there are no payouts, and the weights must not all be zero.
+func (g *BlkTmplGenerator) NewBlockTemplateWithPayouts(payouts []Payout) (*BlockTemplate, error) {
+	var totalWeight uint64
+	for _, payout := range payouts {
+		if payout.Address == nil {
+			return nil, errors.New("payout without an address")
+		}
+		totalWeight += uint64(payout.Weight)
+	}
+	if len(payouts) > 0 && totalWeight == 0 {
+		return nil, errors.New("the total weight of the payouts is zero")
+	}
+
 	// Extend the most recently known best block.
 	best := g.chain.BestSnapshot()
 	nextBlockHeight := best.Height + 1
 
 	// Create a standard coinbase transaction paying to the provided
-	// address.  NOTE: The coinbase value will be updated to include the
+	// payouts.  NOTE: The coinbase value will be updated to include the
 	// fees from the selected transactions later after they have actually
 	// been selected.  It is created here to detect any errors early
 	// before potentially doing a lot of work below.  The extra nonce helps
@@ -459,7 +542,7 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcutil.Address) (*Bloc
 		return nil, err
 	}
 	coinbaseTx, err := createCoinbaseTx(g.chainParams, coinbaseScript,
-		nextBlockHeight, payToAddress)
+		nextBlockHeight, payouts)
 	if err != nil {
 		return nil, err
 	}
@@ -471,10 +554,21 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcutil.Address) (*Bloc
 	// number of items that are available for the priority queue.  Also,
 	// choose the initial sort order for the priority queue based on whether
 	// or not there is an area allocated for high-priority transactions.
+	// The transactions are only sorted by fee per kilobyte with the other
+	// selection strategies.
 	sourceTxns := g.txSource.MiningDescs()
-	sortedByFee := g.policy.BlockPrioritySize == 0
+	sortedByFee := g.policy.Selection != SelectPriority ||
+		g.policy.BlockPrioritySize == 0
 	priorityQueue := newTxPriorityQueue(len(sourceTxns), sortedByFee)
 
+	// prioItems holds all of the transactions which are candidates for
+	// inclusion, including the ones with dependencies, so they can be
+	// ordered by ancestor fee rate.
+	var prioItems []*txPrioItem
+	if g.policy.Selection == SelectAncestorFeeRate {
+		prioItems = make([]*txPrioItem, 0, len(sourceTxns))
+	}
+
 	// Create a slice to hold the transactions to be included in the
 	// generated block with reserved space.  Also create a utxo view to
 	// house all of the input transactions so multiple lookups can be
@@ -578,6 +672,9 @@ mempoolLoop:
 		// Calculate the fee in Satoshi/kB.
 		prioItem.feePerKB = txDesc.FeePerKB
 		prioItem.fee = txDesc.Fee
+		if prioItems != nil {
+			prioItems = append(prioItems, prioItem)
+		}
 
 		// Add the transaction to the priority queue to mark it ready
 		// for inclusion in the block unless it has dependencies.
@@ -591,6 +688,15 @@ mempoolLoop:
 		mergeUtxoView(blockUtxos, utxos)
 	}
 
+	// Order the transactions by the fee per kilobyte of their packages
+	// when selecting by ancestor fee rate.  The order keeps dependencies
+	// first, so transactions are still added to the priority queue once
+	// their dependencies are included.
+	if g.policy.Selection == SelectAncestorFeeRate {
+		orderByAncestorFeeRate(prioItems)
+		priorityQueue.SetLessFunc(txPQByRank)
+	}
+
 	log.Tracef("Priority queue len %d, dependers len %d",
 		priorityQueue.Len(), len(dependers))
 
@@ -694,14 +800,19 @@ mempoolLoop:
 		}
 
 		// Skip free transactions once the block is larger than the
-		// minimum block size.
+		// minimum block size.  Transactions selected by ancestor fee
+		// rate are judged by the fee of their package.
+		feePerKB := prioItem.feePerKB
+		if g.policy.Selection == SelectAncestorFeeRate {
+			feePerKB = prioItem.packageFeePerKB
+		}
 		if sortedByFee &&
-			prioItem.feePerKB < int64(g.policy.TxMinFreeFee) &&
+			feePerKB < int64(g.policy.TxMinFreeFee) &&
 			blockPlusTxWeight >= g.policy.BlockMinWeight {
 
 			log.Tracef("Skipping tx %s with feePerKB %d "+
 				"< TxMinFreeFee %d and block weight %d >= "+
-				"minBlockWeight %d", tx.Hash(), prioItem.feePerKB,
+				"minBlockWeight %d", tx.Hash(), feePerKB,
 				g.policy.TxMinFreeFee, blockPlusTxWeight,
 				g.policy.BlockMinWeight)
 			logSkippedDeps(tx, deps)
@@ -795,14 +906,16 @@ mempoolLoop:
 	blockWeight -= wire.MaxVarIntPayload -
 		(uint32(wire.VarIntSerializeSize(uint64(len(blockTxns)))) *
 			blockchain.WitnessScaleFactor)
-	coinbaseTx.MsgTx().TxOut[0].Value += totalFees
+	splitCoinbaseValue(coinbaseTx.MsgTx(), payouts,
+		blockchain.CalcBlockSubsidy(nextBlockHeight, g.chainParams)+totalFees)
 	txFees[0] = -totalFees
 
 	// If segwit is active and we included transactions with witness data,
 	// then we'll need to include a commitment to the witness data in an
-	// OP_RETURN output within the coinbase transaction.
+	// OP_RETURN output within the coinbase transaction.  Signet blocks
+	// always include it since it holds their signet solution.
 	var witnessCommitment []byte
-	if witnessIncluded {
+	if witnessIncluded || len(g.chainParams.SignetChallenge) > 0 {
 		witnessCommitment = AddWitnessCommitment(coinbaseTx, blockTxns)
 	}
 
@@ -862,7 +975,7 @@ mempoolLoop:
 		Fees:              txFees,
 		SigOpCosts:        txSigOpCosts,
 		Height:            nextBlockHeight,
-		ValidPayAddress:   payToAddress != nil,
+		ValidPayAddress:   len(payouts) > 0,
 		WitnessCommitment: witnessCommitment,
 	}, nil
 }
@@ -5,10 +5,17 @@
 package mining
 
 import (
+	"bytes"
 	"container/heap"
+	"math"
 	"math/rand"
 	"testing"
 
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
 	btcutil "github.com/lbryio/lbcutil"
 )
 
@@ -108,3 +115,173 @@ func TestTxFeePrioHeap(t *testing.T) {
 		highest = prioItem
 	}
 }
+
+// TestCreateCoinbaseTx ensures coinbase transactions split their value among
+// the payouts proportionally to their weights.
+func TestCreateCoinbaseTx(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	newAddr := func(b byte) btcutil.Address {
+		addr, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{b}, 20),
+			params)
+		if err != nil {
+			t.Fatalf("NewAddressPubKeyHash: %v", err)
+		}
+		return addr
+	}
+	addrA, addrB := newAddr(0x01), newAddr(0x02)
+	subsidy := blockchain.CalcBlockSubsidy(1, params)
+
+	tests := []struct {
+		name    string
+		payouts []Payout
+		total   int64
+		values  []int64
+	}{{
+		name:   "no payouts",
+		total:  1000,
+		values: []int64{1000},
+	}, {
+		name:    "single payout",
+		payouts: []Payout{{Address: addrA, Weight: 5}},
+		total:   1000,
+		values:  []int64{1000},
+	}, {
+		name: "weighted payouts",
+		payouts: []Payout{
+			{Address: addrA, Weight: 1},
+			{Address: addrB, Weight: 3},
+		},
+		total:  1000,
+		values: []int64{250, 750},
+	}, {
+		name: "remainder to the first payout",
+		payouts: []Payout{
+			{Address: addrA, Weight: 1},
+			{Address: addrB, Weight: 1},
+			{Address: addrA, Weight: 1},
+		},
+		total:  1000,
+		values: []int64{334, 333, 333},
+	}, {
+		name: "zero weight",
+		payouts: []Payout{
+			{Address: addrA, Weight: 0},
+			{Address: addrB, Weight: 1},
+		},
+		total:  1000,
+		values: []int64{0, 1000},
+	}, {
+		name: "large weights",
+		payouts: []Payout{
+			{Address: addrA, Weight: math.MaxUint32},
+			{Address: addrB, Weight: math.MaxUint32},
+		},
+		total:  btcutil.MaxSatoshi,
+		values: []int64{btcutil.MaxSatoshi / 2, btcutil.MaxSatoshi / 2},
+	}}
+
+	for _, test := range tests {
+		tx, err := createCoinbaseTx(params, nil, 1, test.payouts)
+		if err != nil {
+			t.Fatalf("%s: createCoinbaseTx: %v", test.name, err)
+		}
+		msgTx := tx.MsgTx()
+		if len(msgTx.TxOut) != len(test.values) {
+			t.Fatalf("%s: unexpected number of outputs - got %d, "+
+				"want %d", test.name, len(msgTx.TxOut),
+				len(test.values))
+		}
+		var total int64
+		for _, txOut := range msgTx.TxOut {
+			total += txOut.Value
+		}
+		if total != subsidy {
+			t.Fatalf("%s: unexpected coinbase value - got %d, want %d",
+				test.name, total, subsidy)
+		}
+		for i, payout := range test.payouts {
+			pkScript, err := txscript.PayToAddrScript(payout.Address)
+			if err != nil {
+				t.Fatalf("%s: PayToAddrScript: %v", test.name, err)
+			}
+			if !bytes.Equal(msgTx.TxOut[i].PkScript, pkScript) {
+				t.Fatalf("%s: output %d doesn't pay to %v", test.name,
+					i, payout.Address)
+			}
+		}
+
+		splitCoinbaseValue(msgTx, test.payouts, test.total)
+		for i, want := range test.values {
+			if got := msgTx.TxOut[i].Value; got != want {
+				t.Fatalf("%s: unexpected value of output %d - got "+
+					"%d, want %d", test.name, i, got, want)
+			}
+		}
+	}
+}
+
+// TestOrderByAncestorFeeRate ensures transactions are ranked by the fee per
+// kilobyte of their ancestor packages with their ancestors first, and that
+// transactions with missing ancestors aren't ranked.
+func TestOrderByAncestorFeeRate(t *testing.T) {
+	// newItem returns an item for a transaction of roughly 60 bytes which
+	// pays the passed fee and spends outputs of the passed parents.
+	newItem := func(fee int64, parents ...*txPrioItem) *txPrioItem {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.LockTime = uint32(fee)
+		item := &txPrioItem{fee: fee, rank: -1}
+		if len(parents) > 0 {
+			item.dependsOn = make(map[chainhash.Hash]struct{})
+		}
+		for _, parent := range parents {
+			tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(parent.tx.Hash(), 0),
+				nil, nil))
+			item.dependsOn[*parent.tx.Hash()] = struct{}{}
+		}
+		if len(parents) == 0 {
+			tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		}
+		tx.AddTxOut(wire.NewTxOut(0, nil))
+		item.tx = btcutil.NewTx(tx)
+		return item
+	}
+
+	// The parent pays almost nothing, but its child pays enough for the
+	// package to beat the independent transaction, which itself beats the
+	// parent alone.
+	parent := newItem(1)
+	child := newItem(10000, parent)
+	independent := newItem(3000)
+	orphan := newItem(20000)
+	orphan.dependsOn = map[chainhash.Hash]struct{}{{0x01}: {}}
+	orphanChild := newItem(30000, orphan)
+
+	orderByAncestorFeeRate([]*txPrioItem{orphanChild, independent, child,
+		orphan, parent})
+
+	for _, test := range []struct {
+		name string
+		item *txPrioItem
+		rank int
+	}{
+		{"parent", parent, 0},
+		{"child", child, 1},
+		{"independent", independent, 2},
+		{"orphan", orphan, -1},
+		{"orphan child", orphanChild, -1},
+	} {
+		if test.item.rank != test.rank {
+			t.Errorf("%s: got rank %d, want %d", test.name,
+				test.item.rank, test.rank)
+		}
+	}
+
+	// The parent is selected at the fee rate of its package.
+	if parent.packageFeePerKB != child.packageFeePerKB ||
+		parent.packageFeePerKB <= independent.packageFeePerKB {
+
+		t.Errorf("unexpected package fee rates: parent %d, child %d, "+
+			"independent %d", parent.packageFeePerKB,
+			child.packageFeePerKB, independent.packageFeePerKB)
+	}
+}
@@ -5,6 +5,8 @@
 package mining
 
 import (
+	"fmt"
+
 	"github.com/lbryio/lbcd/blockchain"
 	"github.com/lbryio/lbcd/wire"
 	btcutil "github.com/lbryio/lbcutil"
@@ -17,6 +19,56 @@ const (
 	UnminedHeight = 0x7fffffff
 )
 
+// SelectionStrategy identifies how the transactions of block templates are
+// selected among the transactions of the source pool.
+type SelectionStrategy uint8
+
+// These constants define the strategies used to select the transactions of
+// block templates.
+const (
+	// SelectPriority fills the first BlockPrioritySize bytes of the block
+	// with the transactions with the highest priority, which favors old
+	// and large inputs, and then selects the transactions by fee per
+	// kilobyte.
+	SelectPriority SelectionStrategy = iota
+
+	// SelectFeeRate selects the transactions by fee per kilobyte only,
+	// ignoring BlockPrioritySize.
+	SelectFeeRate
+
+	// SelectAncestorFeeRate selects packages made of a transaction along
+	// with its ancestors in the source pool by the fee per kilobyte of the
+	// whole package, so a transaction paying a high fee also gets its low
+	// fee ancestors included.  This maximizes the fees of the block when
+	// transactions spend unconfirmed outputs.
+	SelectAncestorFeeRate
+)
+
+// selectionStrategyStrings maps the selection strategies to their names.
+var selectionStrategyStrings = map[SelectionStrategy]string{
+	SelectPriority:        "priority",
+	SelectFeeRate:         "feerate",
+	SelectAncestorFeeRate: "ancestorfeerate",
+}
+
+// String returns the SelectionStrategy as a human-readable name.
+func (s SelectionStrategy) String() string {
+	if name, ok := selectionStrategyStrings[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown SelectionStrategy (%d)", uint8(s))
+}
+
+// ParseSelectionStrategy returns the selection strategy with the passed name.
+func ParseSelectionStrategy(name string) (SelectionStrategy, error) {
+	for s, sName := range selectionStrategyStrings {
+		if sName == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown selection strategy %q", name)
+}
+
 // Policy houses the policy (configuration parameters) which is used to control
 // the generation of block templates.  See the documentation for
 // NewBlockTemplate for more details on each of these parameters are used.
@@ -41,6 +93,10 @@ type Policy struct {
 	// transactions to be used when generating a block template.
 	BlockPrioritySize uint32
 
+	// Selection is the strategy used to select the transactions of the
+	// block template.
+	Selection SelectionStrategy
+
 	// TxMinFreeFee is the minimum fee in Satoshi/1000 bytes that is
 	// required for a transaction to be treated as free for mining purposes
 	// (block template generation).
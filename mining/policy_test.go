@@ -166,3 +166,20 @@ func TestCalcPriority(t *testing.T) {
 		}
 	}
 }
+
+// TestParseSelectionStrategy ensures the selection strategies round trip
+// through their names.
+func TestParseSelectionStrategy(t *testing.T) {
+	for _, s := range []SelectionStrategy{SelectPriority, SelectFeeRate,
+		SelectAncestorFeeRate} {
+
+		got, err := ParseSelectionStrategy(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseSelectionStrategy(%q): got %v, %v", s,
+				got, err)
+		}
+	}
+	if _, err := ParseSelectionStrategy("nosuchstrategy"); err == nil {
+		t.Errorf("ParseSelectionStrategy: expected error")
+	}
+}
@@ -0,0 +1,217 @@
+package mining
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+// txPackage tracks a transaction along with its ancestors in the source pool
+// which haven't been selected yet while ordering transactions by the fee per
+// kilobyte of their ancestor packages.
+type txPackage struct {
+	item *txPrioItem
+
+	// ancestors holds the ancestors of the transaction in the source pool
+	// which haven't been selected yet, and descendants the transactions
+	// which have the transaction among their ancestors.
+	ancestors   map[chainhash.Hash]*txPackage
+	descendants []*txPackage
+
+	// numAncestors is the total number of ancestors of the transaction in
+	// the source pool.  It sorts packages in dependency order since a
+	// transaction always has more ancestors than any of its ancestors.
+	numAncestors int
+
+	// size is the virtual size of the transaction, and fee and packageSize
+	// the fee and the virtual size of the whole package.
+	size        int64
+	fee         int64
+	packageSize int64
+
+	// gen is incremented each time the package changes, which invalidates
+	// the entries of the package already in the queue.
+	gen      int
+	selected bool
+}
+
+// feePerKB returns the fee per kilobyte of the whole package.
+func (p *txPackage) feePerKB() int64 {
+	return p.fee * 1000 / p.packageSize
+}
+
+// txPackageEntry is an entry of a txPackageQueue, which snapshots the fee per
+// kilobyte of a package as of the generation of the package it was pushed at.
+type txPackageEntry struct {
+	pkg      *txPackage
+	feePerKB int64
+	gen      int
+}
+
+// txPackageQueue implements a priority queue of txPackageEntry elements which
+// pops the package with the highest fee per kilobyte first.
+type txPackageQueue []txPackageEntry
+
+// Len returns the number of entries in the queue.  It is part of the
+// heap.Interface implementation.
+func (pq txPackageQueue) Len() int {
+	return len(pq)
+}
+
+// Less returns whether the entry with index i should be popped before the
+// entry with index j.  Packages with the same fee per kilobyte are popped from
+// the smallest to the largest.  It is part of the heap.Interface
+// implementation.
+func (pq txPackageQueue) Less(i, j int) bool {
+	if pq[i].feePerKB == pq[j].feePerKB {
+		return pq[i].pkg.packageSize < pq[j].pkg.packageSize
+	}
+	return pq[i].feePerKB > pq[j].feePerKB
+}
+
+// Swap swaps the entries at the passed indices in the queue.  It is part of
+// the heap.Interface implementation.
+func (pq txPackageQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+}
+
+// Push pushes the passed entry onto the queue.  It is part of the
+// heap.Interface implementation.
+func (pq *txPackageQueue) Push(x interface{}) {
+	*pq = append(*pq, x.(txPackageEntry))
+}
+
+// Pop removes the entry with the highest fee per kilobyte from the queue and
+// returns it.  It is part of the heap.Interface implementation.
+func (pq *txPackageQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	entry := old[n-1]
+	*pq = old[:n-1]
+	return entry
+}
+
+// push pushes an entry for the current generation of the passed package.
+func (pq *txPackageQueue) push(pkg *txPackage) {
+	heap.Push(pq, txPackageEntry{
+		pkg:      pkg,
+		feePerKB: pkg.feePerKB(),
+		gen:      pkg.gen,
+	})
+}
+
+// orderByAncestorFeeRate sets the rank of the passed transactions so that
+// sorting them by rank selects the transactions along with their ancestors in
+// the source pool by the fee per kilobyte of these packages, from the highest
+// to the lowest.  The rank of a transaction is always higher than the ranks of
+// its ancestors.  The fee per kilobyte of each transaction is set to the one of
+// the package it is selected with, so a low fee transaction is treated like the
+// high fee descendant paying for it.
+//
+// Transactions which depend on transactions of the source pool which aren't
+// among the passed transactions can't be included in a block and aren't
+// ranked.
+func orderByAncestorFeeRate(items []*txPrioItem) {
+	packages := make(map[chainhash.Hash]*txPackage, len(items))
+	for _, item := range items {
+		weight := blockchain.GetTransactionWeight(item.tx)
+		size := (weight + blockchain.WitnessScaleFactor - 1) /
+			blockchain.WitnessScaleFactor
+		packages[*item.tx.Hash()] = &txPackage{
+			item: item,
+			size: size,
+		}
+	}
+
+	// Collect the ancestors of every transaction, leaving out the ones
+	// with missing ancestors.
+	var collect func(pkg *txPackage) bool
+	collect = func(pkg *txPackage) bool {
+		if pkg.ancestors != nil {
+			return true
+		}
+		ancestors := make(map[chainhash.Hash]*txPackage)
+		for hash := range pkg.item.dependsOn {
+			parent, ok := packages[hash]
+			if !ok || !collect(parent) {
+				delete(packages, *pkg.item.tx.Hash())
+				return false
+			}
+			ancestors[hash] = parent
+			for ancestorHash, ancestor := range parent.ancestors {
+				ancestors[ancestorHash] = ancestor
+			}
+		}
+		pkg.ancestors = ancestors
+		return true
+	}
+	for _, item := range items {
+		if pkg, ok := packages[*item.tx.Hash()]; ok {
+			collect(pkg)
+		}
+	}
+
+	queue := make(txPackageQueue, 0, len(packages))
+	for _, pkg := range packages {
+		pkg.numAncestors = len(pkg.ancestors)
+		pkg.fee = pkg.item.fee
+		pkg.packageSize = pkg.size
+		for _, ancestor := range pkg.ancestors {
+			pkg.fee += ancestor.item.fee
+			pkg.packageSize += ancestor.size
+			ancestor.descendants = append(ancestor.descendants, pkg)
+		}
+		queue = append(queue, txPackageEntry{
+			pkg:      pkg,
+			feePerKB: pkg.feePerKB(),
+		})
+	}
+	heap.Init(&queue)
+
+	rank := 0
+	for queue.Len() > 0 {
+		entry := heap.Pop(&queue).(txPackageEntry)
+		pkg := entry.pkg
+		if pkg.selected || entry.gen != pkg.gen {
+			continue
+		}
+
+		// Select the remaining ancestors in dependency order followed
+		// by the transaction itself.
+		selection := make([]*txPackage, 0, len(pkg.ancestors)+1)
+		for _, ancestor := range pkg.ancestors {
+			selection = append(selection, ancestor)
+		}
+		sort.Slice(selection, func(i, j int) bool {
+			return selection[i].numAncestors < selection[j].numAncestors
+		})
+		selection = append(selection, pkg)
+
+		// The descendants of the selected transactions no longer need
+		// them to be included, so their packages shrink accordingly.
+		updated := make(map[*txPackage]struct{})
+		for _, selected := range selection {
+			selected.selected = true
+			selected.item.rank = rank
+			selected.item.packageFeePerKB = entry.feePerKB
+			rank++
+
+			hash := *selected.item.tx.Hash()
+			for _, descendant := range selected.descendants {
+				if descendant.selected {
+					continue
+				}
+				delete(descendant.ancestors, hash)
+				descendant.fee -= selected.item.fee
+				descendant.packageSize -= selected.size
+				updated[descendant] = struct{}{}
+			}
+		}
+		for descendant := range updated {
+			descendant.gen++
+			queue.push(descendant)
+		}
+	}
+}
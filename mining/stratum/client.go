@@ -0,0 +1,287 @@
+package stratum
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRequestSize is the maximum size of a request line sent by a miner.
+	maxRequestSize = 4096
+
+	// idleTimeout is the duration of inactivity before a miner is
+	// disconnected.
+	idleTimeout = 10 * time.Minute
+
+	// writeTimeout is the maximum duration to write a message to a miner.
+	writeTimeout = 30 * time.Second
+
+	// sendQueueSize is the number of messages queued for a miner before it
+	// is considered too slow and disconnected.
+	sendQueueSize = 32
+)
+
+// Error codes of the stratum protocol.
+const (
+	errCodeOther          = 20
+	errCodeJobNotFound    = 21
+	errCodeDuplicateShare = 22
+	errCodeLowDifficulty  = 23
+	errCodeUnauthorized   = 24
+	errCodeNotSubscribed  = 25
+	errCodeMethodNotFound = -32601
+	errCodeInvalidParams  = -32602
+)
+
+// stratumError is an error returned to a miner in a response.  It is encoded as
+// an array of the error code, the message and a null traceback.
+type stratumError struct {
+	code    int
+	message string
+}
+
+// MarshalJSON encodes the error as expected by the stratum miners.
+func (e *stratumError) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{e.code, e.message, nil})
+}
+
+var (
+	errStaleJob       = &stratumError{errCodeJobNotFound, "Job not found"}
+	errDuplicateShare = &stratumError{errCodeDuplicateShare, "Duplicate share"}
+	errLowDifficulty  = &stratumError{errCodeLowDifficulty, "Low difficulty share"}
+	errUnauthorized   = &stratumError{errCodeUnauthorized, "Unauthorized worker"}
+	errNotSubscribed  = &stratumError{errCodeNotSubscribed, "Not subscribed"}
+	errInvalidParams  = &stratumError{errCodeInvalidParams, "Invalid params"}
+	errUnknownMethod  = &stratumError{errCodeMethodNotFound, "Method not found"}
+)
+
+// request is a JSON-RPC request sent by a miner.
+type request struct {
+	ID     interface{}       `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// response is a JSON-RPC response sent to a miner.
+type response struct {
+	ID     interface{}   `json:"id"`
+	Result interface{}   `json:"result"`
+	Error  *stratumError `json:"error"`
+}
+
+// notification is a JSON-RPC notification sent to a miner.
+type notification struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// client is a miner connected to the stratum server.
+type client struct {
+	server      *Server
+	conn        net.Conn
+	extraNonce1 []byte
+	sendQueue   chan []byte
+	quit        chan struct{}
+
+	mtx        sync.Mutex
+	subscribed bool
+	authorized bool
+	difficulty float64
+}
+
+// isSubscribed returns whether the miner subscribed to job notifications.
+func (c *client) isSubscribed() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.subscribed
+}
+
+// inHandler reads and handles the requests of the miner until the connection
+// is closed.
+func (c *client) inHandler() {
+	go c.outHandler()
+	defer close(c.quit)
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, maxRequestSize), maxRequestSize)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				log.Debugf("Can't read from stratum client %s: %v",
+					c.conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		var req request
+		err := json.Unmarshal(scanner.Bytes(), &req)
+		if err != nil {
+			log.Debugf("Invalid request from stratum client %s: %v",
+				c.conn.RemoteAddr(), err)
+			return
+		}
+
+		result, rerr := c.handleRequest(&req)
+		c.queueMessage(&response{ID: req.ID, Result: result, Error: rerr})
+
+		// Only notify jobs once the subscription response is queued, and
+		// send the current one right away.
+		if req.Method == "mining.subscribe" && rerr == nil {
+			c.mtx.Lock()
+			c.subscribed = true
+			c.mtx.Unlock()
+
+			if j, difficulty := c.server.currentJob(); j != nil {
+				c.notifyJob(j, difficulty, true)
+			}
+		}
+	}
+}
+
+// outHandler writes the queued messages to the miner.  It must be run as a
+// goroutine.
+func (c *client) outHandler() {
+	for {
+		select {
+		case msg := <-c.sendQueue:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			_, err := c.conn.Write(msg)
+			if err != nil {
+				log.Debugf("Can't write to stratum client %s: %v",
+					c.conn.RemoteAddr(), err)
+				c.conn.Close()
+				return
+			}
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// queueMessage queues the passed message to be sent to the miner.  The miner
+// is disconnected when it doesn't keep up with the messages.
+func (c *client) queueMessage(msg interface{}) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		log.Errorf("Can't marshal stratum message: %v", err)
+		return
+	}
+	b = append(b, '\n')
+
+	select {
+	case c.sendQueue <- b:
+	default:
+		log.Debugf("Disconnecting slow stratum client %s",
+			c.conn.RemoteAddr())
+		c.conn.Close()
+	}
+}
+
+// notifyJob sends the passed job to the miner, preceded by its difficulty when
+// it changed.
+func (c *client) notifyJob(j *job, difficulty float64, cleanJobs bool) {
+	c.mtx.Lock()
+	difficultyChanged := c.difficulty != difficulty
+	c.difficulty = difficulty
+	c.mtx.Unlock()
+
+	if difficultyChanged {
+		c.queueMessage(&notification{
+			Method: "mining.set_difficulty",
+			Params: []interface{}{difficulty},
+		})
+	}
+	c.queueMessage(&notification{
+		Method: "mining.notify",
+		Params: j.notifyParams(cleanJobs),
+	})
+}
+
+// handleRequest handles the passed request and returns its result.
+func (c *client) handleRequest(req *request) (interface{}, *stratumError) {
+	switch req.Method {
+	case "mining.subscribe":
+		subscriptionID := hex.EncodeToString(c.extraNonce1)
+		return []interface{}{
+			[][]string{
+				{"mining.set_difficulty", subscriptionID},
+				{"mining.notify", subscriptionID},
+			},
+			hex.EncodeToString(c.extraNonce1),
+			extraNonce2Size,
+		}, nil
+
+	case "mining.authorize":
+		// Solo miners are paid to the configured mining addresses, so
+		// any worker is authorized.
+		c.mtx.Lock()
+		c.authorized = true
+		c.mtx.Unlock()
+		return true, nil
+
+	case "mining.extranonce.subscribe":
+		// The extra nonce never changes for a connection.
+		return true, nil
+
+	case "mining.submit":
+		return c.handleSubmit(req.Params)
+	}
+
+	return nil, errUnknownMethod
+}
+
+// handleSubmit handles a mining.submit request with the worker name, the job
+// ID, the second extra nonce, the timestamp and the nonce as parameters.
+func (c *client) handleSubmit(params []json.RawMessage) (interface{}, *stratumError) {
+	c.mtx.Lock()
+	subscribed, authorized := c.subscribed, c.authorized
+	c.mtx.Unlock()
+	if !subscribed {
+		return nil, errNotSubscribed
+	}
+	if !authorized {
+		return nil, errUnauthorized
+	}
+
+	if len(params) < 5 {
+		return nil, errInvalidParams
+	}
+	var fields [5]string
+	for i := range fields {
+		if err := json.Unmarshal(params[i], &fields[i]); err != nil {
+			return nil, errInvalidParams
+		}
+	}
+
+	j := c.server.lookupJob(fields[1])
+	if j == nil {
+		return nil, errStaleJob
+	}
+	extraNonce2, err := hex.DecodeString(fields[2])
+	if err != nil || len(extraNonce2) != extraNonce2Size {
+		return nil, errInvalidParams
+	}
+	timestamp, err := parseUint32(fields[3])
+	if err != nil {
+		return nil, errInvalidParams
+	}
+	nonce, err := parseUint32(fields[4])
+	if err != nil {
+		return nil, errInvalidParams
+	}
+
+	if serr := c.server.submit(j, c.extraNonce1, extraNonce2, timestamp,
+		nonce); serr != nil {
+
+		return nil, serr
+	}
+	return true, nil
+}
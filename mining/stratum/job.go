@@ -0,0 +1,241 @@
+package stratum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/mining"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+)
+
+const (
+	// extraNonce1Size is the number of bytes of the extra nonce assigned to
+	// each client on subscription.
+	extraNonce1Size = 4
+
+	// extraNonce2Size is the number of bytes of the extra nonce the miners
+	// roll themselves.
+	extraNonce2Size = 4
+
+	// extraNonceSize is the total number of bytes reserved for the extra
+	// nonces in the coinbase script.
+	extraNonceSize = extraNonce1Size + extraNonce2Size
+
+	// outPointSize is the serialized size of a transaction outpoint.
+	outPointSize = chainhash.HashSize + 4
+)
+
+// job is a unit of work sent to the miners with a mining.notify message.  It
+// wraps a block template whose coinbase is split around the extra nonces so
+// the miners can build the coinbase and merkle root themselves.
+type job struct {
+	id       string
+	template *mining.BlockTemplate
+	target   *big.Int
+
+	// coinb1 and coinb2 are the serialized coinbase transaction, without
+	// witness data, before and after the extra nonces.
+	coinb1 []byte
+	coinb2 []byte
+
+	// scriptPrefix and scriptSuffix are the coinbase script before and
+	// after the extra nonces.
+	scriptPrefix []byte
+	scriptSuffix []byte
+
+	// merkleBranch are the hashes to combine with the coinbase hash, in
+	// order, to compute the merkle root of the block.
+	merkleBranch []*chainhash.Hash
+
+	// submissions tracks the solutions already submitted for the job in
+	// order to reject duplicates.
+	submissions map[string]struct{}
+}
+
+// newJob returns a job for the passed block template.  The coinbase script of
+// the template is replaced by one reserving room for the extra nonces.
+func newJob(id string, template *mining.BlockTemplate) (*job, error) {
+	block := template.Block
+	coinbase := block.Transactions[0].Copy()
+
+	// The coinbase script starts with the block height as required by
+	// BIP0034 followed by the extra nonces and the coinbase flags.
+	scriptPrefix, err := txscript.NewScriptBuilder().
+		AddInt64(int64(template.Height)).
+		AddData(make([]byte, extraNonceSize)).Script()
+	if err != nil {
+		return nil, err
+	}
+	scriptPrefix = scriptPrefix[:len(scriptPrefix)-extraNonceSize]
+	scriptSuffix, err := txscript.NewScriptBuilder().
+		AddData([]byte(mining.CoinbaseFlags)).Script()
+	if err != nil {
+		return nil, err
+	}
+	script := make([]byte, 0, len(scriptPrefix)+extraNonceSize+
+		len(scriptSuffix))
+	script = append(script, scriptPrefix...)
+	script = append(script, make([]byte, extraNonceSize)...)
+	script = append(script, scriptSuffix...)
+	if len(script) > blockchain.MaxCoinbaseScriptLen {
+		return nil, fmt.Errorf("coinbase transaction script length "+
+			"of %d is out of range (max: %d)", len(script),
+			blockchain.MaxCoinbaseScriptLen)
+	}
+	coinbase.TxIn[0].SignatureScript = script
+
+	var buf bytes.Buffer
+	err = coinbase.SerializeNoWitness(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	// The extra nonces follow the transaction version, the input count,
+	// the previous outpoint, the script length and the script prefix.
+	offset := 4 + wire.VarIntSerializeSize(uint64(len(coinbase.TxIn))) +
+		outPointSize + wire.VarIntSerializeSize(uint64(len(script))) +
+		len(scriptPrefix)
+	serialized := buf.Bytes()
+
+	j := &job{
+		id:           id,
+		template:     template,
+		target:       blockchain.CompactToBig(block.Header.Bits),
+		coinb1:       serialized[:offset],
+		coinb2:       serialized[offset+extraNonceSize:],
+		scriptPrefix: scriptPrefix,
+		scriptSuffix: scriptSuffix,
+		submissions:  make(map[string]struct{}),
+	}
+
+	txHashes := make([]*chainhash.Hash, 0, len(block.Transactions)-1)
+	for _, tx := range block.Transactions[1:] {
+		hash := tx.TxHash()
+		txHashes = append(txHashes, &hash)
+	}
+	j.merkleBranch = merkleBranch(txHashes)
+
+	return j, nil
+}
+
+// merkleBranch returns the hashes needed to compute the merkle root of a block
+// from the hash of its coinbase given the hashes of the other transactions.
+func merkleBranch(txHashes []*chainhash.Hash) []*chainhash.Hash {
+	var branch []*chainhash.Hash
+
+	// The first entry of each level is the unknown coinbase side of the
+	// tree, so only the entries at its right are combined.
+	level := append([]*chainhash.Hash{nil}, txHashes...)
+	for len(level) > 1 {
+		branch = append(branch, level[1])
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		next := []*chainhash.Hash{nil}
+		for i := 2; i < len(level); i += 2 {
+			next = append(next, blockchain.HashMerkleBranches(level[i],
+				level[i+1]))
+		}
+		level = next
+	}
+
+	return branch
+}
+
+// merkleRoot returns the merkle root of the block of the job with the passed
+// coinbase hash.
+func (j *job) merkleRoot(coinbaseHash *chainhash.Hash) *chainhash.Hash {
+	root := coinbaseHash
+	for _, hash := range j.merkleBranch {
+		root = blockchain.HashMerkleBranches(root, hash)
+	}
+	return root
+}
+
+// solve returns the block of the job solved with the passed extra nonces,
+// timestamp and nonce.  The template of the job is left untouched.
+func (j *job) solve(extraNonce1, extraNonce2 []byte, timestamp, nonce uint32) *wire.MsgBlock {
+	tmpl := j.template.Block
+	coinbase := tmpl.Transactions[0].Copy()
+
+	// Rebuild the coinbase script with the extra nonces in place of the
+	// reserved bytes.
+	script := make([]byte, 0, len(j.scriptPrefix)+extraNonceSize+
+		len(j.scriptSuffix))
+	script = append(script, j.scriptPrefix...)
+	script = append(script, extraNonce1...)
+	script = append(script, extraNonce2...)
+	script = append(script, j.scriptSuffix...)
+	coinbase.TxIn[0].SignatureScript = script
+
+	msgBlock := &wire.MsgBlock{
+		Header:       tmpl.Header,
+		Transactions: make([]*wire.MsgTx, 0, len(tmpl.Transactions)),
+	}
+	msgBlock.Transactions = append(msgBlock.Transactions, coinbase)
+	msgBlock.Transactions = append(msgBlock.Transactions,
+		tmpl.Transactions[1:]...)
+
+	coinbaseHash := coinbase.TxHash()
+	msgBlock.Header.MerkleRoot = *j.merkleRoot(&coinbaseHash)
+	msgBlock.Header.Timestamp = time.Unix(int64(timestamp), 0)
+	msgBlock.Header.Nonce = nonce
+
+	return msgBlock
+}
+
+// notifyParams returns the parameters of the mining.notify message for the
+// job.  Like the LBRY pools, the claimtrie root follows the previous block
+// hash since it is part of the block header.
+func (j *job) notifyParams(cleanJobs bool) []interface{} {
+	header := &j.template.Block.Header
+	branch := make([]string, 0, len(j.merkleBranch))
+	for _, hash := range j.merkleBranch {
+		branch = append(branch, hex.EncodeToString(hash[:]))
+	}
+
+	return []interface{}{
+		j.id,
+		swapHashWords(&header.PrevBlock),
+		swapHashWords(&header.ClaimTrie),
+		hex.EncodeToString(j.coinb1),
+		hex.EncodeToString(j.coinb2),
+		branch,
+		formatUint32(uint32(header.Version)),
+		formatUint32(header.Bits),
+		formatUint32(uint32(header.Timestamp.Unix())),
+		cleanJobs,
+	}
+}
+
+// swapHashWords returns the hex encoding of the passed hash with the bytes of
+// each 32-bit word swapped as expected by the stratum miners.
+func swapHashWords(hash *chainhash.Hash) string {
+	var b [chainhash.HashSize]byte
+	for i := 0; i < chainhash.HashSize; i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = hash[i+3], hash[i+2], hash[i+1],
+			hash[i]
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// formatUint32 returns the big-endian hex encoding of the passed value.
+func formatUint32(v uint32) string {
+	return fmt.Sprintf("%08x", v)
+}
+
+// parseUint32 parses a big-endian hex encoded 32-bit value.
+func parseUint32(s string) (uint32, error) {
+	if len(s) != 8 {
+		return 0, fmt.Errorf("invalid length for 32-bit value %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	return uint32(v), err
+}
@@ -0,0 +1,130 @@
+package stratum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/mining"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// testTemplate returns a block template with a coinbase followed by numTxs
+// transactions.
+func testTemplate(numTxs int) *mining.BlockTemplate {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{},
+		wire.MaxPrevOutIndex), []byte{0x51}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+
+	block := wire.NewMsgBlock(&wire.BlockHeader{
+		Version:   1,
+		PrevBlock: chainhash.Hash{0x01},
+		ClaimTrie: chainhash.Hash{0x02},
+		Bits:      0x207fffff,
+	})
+	_ = block.AddTransaction(coinbase)
+	for i := 0; i < numTxs; i++ {
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{0x03},
+			uint32(i)), nil, nil))
+		tx.AddTxOut(wire.NewTxOut(int64(i), []byte{0x51}))
+		_ = block.AddTransaction(tx)
+	}
+
+	return &mining.BlockTemplate{Block: block, Height: 1000}
+}
+
+// TestJobSolve ensures the coinbase sent to the miners and the merkle branch
+// result in the same block as the one submitted for all transaction counts.
+func TestJobSolve(t *testing.T) {
+	extraNonce1 := []byte{0x01, 0x02, 0x03, 0x04}
+	extraNonce2 := []byte{0x05, 0x06, 0x07, 0x08}
+
+	for numTxs := 0; numTxs < 10; numTxs++ {
+		j, err := newJob("1", testTemplate(numTxs))
+		if err != nil {
+			t.Fatalf("%d txs: newJob: unexpected error: %v", numTxs, err)
+		}
+
+		msgBlock := j.solve(extraNonce1, extraNonce2, 1600000000, 42)
+		if msgBlock.Header.Nonce != 42 ||
+			msgBlock.Header.Timestamp.Unix() != 1600000000 {
+
+			t.Fatalf("%d txs: nonce or timestamp not set", numTxs)
+		}
+
+		// The coinbase built by the miners must be the one of the block.
+		var serialized []byte
+		serialized = append(serialized, j.coinb1...)
+		serialized = append(serialized, extraNonce1...)
+		serialized = append(serialized, extraNonce2...)
+		serialized = append(serialized, j.coinb2...)
+		var buf bytes.Buffer
+		_ = msgBlock.Transactions[0].SerializeNoWitness(&buf)
+		if !bytes.Equal(serialized, buf.Bytes()) {
+			t.Fatalf("%d txs: coinbase mismatch\n got: %x\nwant: %x",
+				numTxs, serialized, buf.Bytes())
+		}
+
+		// The merkle root computed from the branch must match the one
+		// of the full tree.
+		block := btcutil.NewBlock(msgBlock)
+		merkles := blockchain.BuildMerkleTreeStore(block.Transactions(),
+			false)
+		if msgBlock.Header.MerkleRoot != *merkles[len(merkles)-1] {
+			t.Fatalf("%d txs: merkle root mismatch - got %v, want %v",
+				numTxs, msgBlock.Header.MerkleRoot,
+				merkles[len(merkles)-1])
+		}
+
+		// The template itself must be left untouched.
+		if !bytes.Equal(j.template.Block.Transactions[0].TxIn[0].SignatureScript,
+			[]byte{0x51}) {
+
+			t.Fatalf("%d txs: template coinbase modified", numTxs)
+		}
+	}
+}
+
+// TestNotifyParams ensures the mining.notify parameters are encoded as
+// expected by the miners.
+func TestNotifyParams(t *testing.T) {
+	j, err := newJob("a", testTemplate(1))
+	if err != nil {
+		t.Fatalf("newJob: unexpected error: %v", err)
+	}
+
+	params := j.notifyParams(true)
+	if len(params) != 10 {
+		t.Fatalf("wrong number of params - got %d, want 10", len(params))
+	}
+	wantPrevHash := "00000001" + "00000000000000000000000000000000" +
+		"000000000000000000000000"
+	if params[1] != wantPrevHash {
+		t.Fatalf("wrong previous hash - got %v, want %v", params[1],
+			wantPrevHash)
+	}
+	wantClaimTrie := "00000002" + "00000000000000000000000000000000" +
+		"000000000000000000000000"
+	if params[2] != wantClaimTrie {
+		t.Fatalf("wrong claimtrie - got %v, want %v", params[2],
+			wantClaimTrie)
+	}
+	if params[3] != hex.EncodeToString(j.coinb1) ||
+		params[4] != hex.EncodeToString(j.coinb2) {
+
+		t.Fatalf("wrong coinbase parts - got %v %v", params[3],
+			params[4])
+	}
+	if params[6] != "00000001" || params[7] != "207fffff" {
+		t.Fatalf("wrong version or bits - got %v %v", params[6],
+			params[7])
+	}
+	if params[9] != true {
+		t.Fatalf("clean jobs not set")
+	}
+}
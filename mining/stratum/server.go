@@ -0,0 +1,373 @@
+package stratum
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/mining"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+const (
+	// jobCheckInterval is the interval at which the server checks whether
+	// the current job is stale.
+	jobCheckInterval = time.Second
+
+	// jobRegenerateInterval is the minimum time between two jobs for the
+	// same previous block when the memory pool has been updated.
+	jobRegenerateInterval = time.Minute
+
+	// maxJobs is the number of recent jobs solutions are accepted for.
+	maxJobs = 16
+)
+
+// Config is a descriptor containing the stratum server configuration.
+type Config struct {
+	// ChainParams identifies which chain parameters the server is
+	// associated with.
+	ChainParams *chaincfg.Params
+
+	// BlockTemplateGenerator identifies the instance to use in order to
+	// generate the block templates sent to the miners.
+	BlockTemplateGenerator *mining.BlkTmplGenerator
+
+	// MiningAddrs is a list of payment addresses to use for the generated
+	// blocks.  Each job randomly chooses one of them.
+	MiningAddrs []btcutil.Address
+
+	// ProcessBlock defines the function to call with any solved blocks.
+	// It typically must run the provided block through the same set of
+	// rules and handling as any other block coming from the network.
+	ProcessBlock func(*btcutil.Block, blockchain.BehaviorFlags) (bool, error)
+
+	// IsCurrent defines the function to use to obtain whether or not the
+	// block chain is current.  No work is sent to the miners until it is
+	// since any solved blocks would be on a side chain.
+	IsCurrent func() bool
+
+	// Listeners defines a slice of listeners for which the server will
+	// accept miner connections.
+	Listeners []net.Listener
+}
+
+// Server serves work from the block template generator to miners using the
+// Stratum v1 protocol and submits the blocks they solve.  It is meant for solo
+// miners, so all submitted solutions are checked against the block target.
+type Server struct {
+	cfg      Config
+	g        *mining.BlkTmplGenerator
+	started  int32
+	shutdown int32
+	wg       sync.WaitGroup
+	quit     chan struct{}
+
+	// submitBlockLock serializes the submission of solved blocks.
+	submitBlockLock sync.Mutex
+
+	mtx             sync.Mutex
+	clients         map[*client]struct{}
+	jobs            map[string]*job
+	jobOrder        []string
+	curJob          *job
+	lastTxUpdate    time.Time
+	lastGenerated   time.Time
+	nextJobID       uint64
+	nextExtraNonce1 uint32
+}
+
+// Start begins accepting miner connections and generating jobs.
+func (s *Server) Start() {
+	if atomic.AddInt32(&s.started, 1) != 1 {
+		return
+	}
+
+	log.Trace("Starting stratum server")
+	for _, listener := range s.cfg.Listeners {
+		s.wg.Add(1)
+		go s.listenHandler(listener)
+	}
+
+	s.wg.Add(1)
+	go s.jobHandler()
+}
+
+// Stop gracefully shuts down the server by closing the listeners and the
+// connections of all miners.
+func (s *Server) Stop() {
+	if atomic.AddInt32(&s.shutdown, 1) != 1 {
+		log.Infof("Stratum server is already in the process of shutting " +
+			"down")
+		return
+	}
+
+	log.Warnf("Stratum server shutting down")
+	for _, listener := range s.cfg.Listeners {
+		err := listener.Close()
+		if err != nil {
+			log.Errorf("Problem shutting down stratum: %v", err)
+		}
+	}
+	close(s.quit)
+
+	s.mtx.Lock()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mtx.Unlock()
+
+	s.wg.Wait()
+	log.Infof("Stratum server shutdown complete")
+}
+
+// listenHandler accepts miner connections on the passed listener.  It must be
+// run as a goroutine.
+func (s *Server) listenHandler(listener net.Listener) {
+	log.Infof("Stratum server listening on %s", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			// Only log the error if not forcibly shutting down.
+			if atomic.LoadInt32(&s.shutdown) == 0 {
+				log.Errorf("Can't accept stratum connection: %v",
+					err)
+			}
+			break
+		}
+
+		c := s.newClient(conn)
+		s.wg.Add(1)
+		go func() {
+			c.inHandler()
+			s.removeClient(c)
+			s.wg.Done()
+		}()
+	}
+	s.wg.Done()
+	log.Tracef("Stratum listener done for %s", listener.Addr())
+}
+
+// newClient registers a client for the passed connection and assigns it a
+// unique first extra nonce.
+func (s *Server) newClient(conn net.Conn) *client {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	extraNonce1 := make([]byte, extraNonce1Size)
+	binary.BigEndian.PutUint32(extraNonce1, s.nextExtraNonce1)
+	s.nextExtraNonce1++
+
+	c := &client{
+		server:      s,
+		conn:        conn,
+		extraNonce1: extraNonce1,
+		sendQueue:   make(chan []byte, sendQueueSize),
+		quit:        make(chan struct{}),
+	}
+	s.clients[c] = struct{}{}
+	log.Debugf("New stratum client %s", conn.RemoteAddr())
+	return c
+}
+
+// removeClient unregisters the passed client.
+func (s *Server) removeClient(c *client) {
+	s.mtx.Lock()
+	delete(s.clients, c)
+	s.mtx.Unlock()
+
+	c.conn.Close()
+	log.Debugf("Stratum client %s disconnected", c.conn.RemoteAddr())
+}
+
+// jobHandler periodically checks whether the current job is stale due to a new
+// block or new transactions and notifies the miners with a new one.  It must be
+// run as a goroutine.
+func (s *Server) jobHandler() {
+	ticker := time.NewTicker(jobCheckInterval)
+	defer ticker.Stop()
+
+out:
+	for {
+		select {
+		case <-ticker.C:
+			s.updateJob()
+
+		case <-s.quit:
+			break out
+		}
+	}
+
+	s.wg.Done()
+	log.Trace("Stratum job handler done")
+}
+
+// updateJob generates a new job when the best block has changed, or when the
+// memory pool has been updated and it has been long enough since the current
+// job was generated, and notifies the subscribed miners about it.
+func (s *Server) updateJob() {
+	// No point in sending work before the chain is synced.
+	best := s.g.BestSnapshot()
+	if best.Height != 0 && !s.cfg.IsCurrent() {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	lastTxUpdate := s.g.TxSource().LastUpdated()
+	cleanJobs := s.curJob == nil ||
+		s.curJob.template.Block.Header.PrevBlock != best.Hash
+	if !cleanJobs && (lastTxUpdate == s.lastTxUpdate ||
+		time.Since(s.lastGenerated) < jobRegenerateInterval) {
+
+		return
+	}
+
+	// Hold the submission lock so the template isn't built on a block in
+	// the process of becoming stale.
+	payToAddr := s.cfg.MiningAddrs[rand.Intn(len(s.cfg.MiningAddrs))]
+	s.submitBlockLock.Lock()
+	template, err := s.g.NewBlockTemplate(payToAddr)
+	s.submitBlockLock.Unlock()
+	if err != nil {
+		log.Errorf("Failed to create new block template: %v", err)
+		return
+	}
+
+	s.nextJobID++
+	j, err := newJob(strconv.FormatUint(s.nextJobID, 16), template)
+	if err != nil {
+		log.Errorf("Failed to create stratum job: %v", err)
+		return
+	}
+
+	// Solutions for the jobs of previous blocks can't be accepted anymore.
+	if cleanJobs {
+		s.jobs = make(map[string]*job)
+		s.jobOrder = s.jobOrder[:0]
+	}
+	if len(s.jobOrder) == maxJobs {
+		delete(s.jobs, s.jobOrder[0])
+		s.jobOrder = s.jobOrder[1:]
+	}
+	s.jobs[j.id] = j
+	s.jobOrder = append(s.jobOrder, j.id)
+	s.curJob = j
+	s.lastTxUpdate = lastTxUpdate
+	s.lastGenerated = time.Now()
+
+	log.Debugf("New stratum job %s at height %d (%d transactions, clean "+
+		"%v)", j.id, template.Height, len(template.Block.Transactions),
+		cleanJobs)
+
+	for c := range s.clients {
+		if c.isSubscribed() {
+			c.notifyJob(j, s.difficulty(j), cleanJobs)
+		}
+	}
+}
+
+// currentJob returns the current job along with its difficulty, or nil when
+// no job has been generated yet.
+func (s *Server) currentJob() (*job, float64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.curJob == nil {
+		return nil, 0
+	}
+	return s.curJob, s.difficulty(s.curJob)
+}
+
+// lookupJob returns the job with the passed ID if solutions are still accepted
+// for it.
+func (s *Server) lookupJob(id string) *job {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.jobs[id]
+}
+
+// difficulty returns the share difficulty of the passed job, which is its
+// block difficulty relative to the proof of work limit of the chain.
+func (s *Server) difficulty(j *job) float64 {
+	max := blockchain.CompactToBig(s.cfg.ChainParams.PowLimitBits)
+	diff, _ := new(big.Rat).SetFrac(max, j.target).Float64()
+	return diff
+}
+
+// submit checks the passed solution for the job and submits the solved block.
+// It returns a stratum error when the solution is rejected.
+func (s *Server) submit(j *job, extraNonce1, extraNonce2 []byte, timestamp, nonce uint32) *stratumError {
+	key := string(extraNonce1) + string(extraNonce2) +
+		formatUint32(timestamp) + formatUint32(nonce)
+	s.mtx.Lock()
+	_, dup := j.submissions[key]
+	j.submissions[key] = struct{}{}
+	s.mtx.Unlock()
+	if dup {
+		return errDuplicateShare
+	}
+
+	msgBlock := j.solve(extraNonce1, extraNonce2, timestamp, nonce)
+	powHash := msgBlock.Header.BlockPoWHash()
+	if blockchain.HashToBig(&powHash).Cmp(j.target) > 0 {
+		return errLowDifficulty
+	}
+
+	s.submitBlockLock.Lock()
+	defer s.submitBlockLock.Unlock()
+
+	// Ensure the block is not stale since a new block could have shown up
+	// since the job was sent.
+	if msgBlock.Header.PrevBlock != s.g.BestSnapshot().Hash {
+		log.Debugf("Block submitted via stratum with previous block %s "+
+			"is stale", msgBlock.Header.PrevBlock)
+		return errStaleJob
+	}
+
+	// Process this block using the same rules as blocks coming from other
+	// nodes.  This will in turn relay it to the network like normal.
+	block := btcutil.NewBlock(msgBlock)
+	isOrphan, err := s.cfg.ProcessBlock(block, blockchain.BFNone)
+	if err != nil {
+		// Anything other than a rule violation is an unexpected error,
+		// so log that error as an internal error.
+		if _, ok := err.(blockchain.RuleError); !ok {
+			log.Errorf("Unexpected error while processing block "+
+				"submitted via stratum: %v", err)
+		} else {
+			log.Infof("Block submitted via stratum rejected: %v",
+				err)
+		}
+		return &stratumError{code: errCodeOther, message: err.Error()}
+	}
+	if isOrphan {
+		log.Debugf("Block submitted via stratum is an orphan")
+		return errStaleJob
+	}
+
+	coinbaseTx := msgBlock.Transactions[0].TxOut[0]
+	log.Infof("Block submitted via stratum accepted (hash %s, amount %v)",
+		block.Hash(), btcutil.Amount(coinbaseTx.Value))
+	return nil
+}
+
+// New returns a new stratum server for the provided configuration.  Use Start
+// to begin serving miners.
+func New(cfg *Config) *Server {
+	return &Server{
+		cfg:     *cfg,
+		g:       cfg.BlockTemplateGenerator,
+		quit:    make(chan struct{}),
+		clients: make(map[*client]struct{}),
+		jobs:    make(map[string]*job),
+	}
+}
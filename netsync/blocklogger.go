@@ -9,9 +9,14 @@ import (
 	"time"
 
 	"github.com/btcsuite/btclog"
+	"github.com/lbryio/lbcd/blockchain"
 	btcutil "github.com/lbryio/lbcutil"
 )
 
+// txRateWeight is the weight of the transactions per second of the last
+// logging period in the average rate used to estimate the time remaining.
+const txRateWeight = 0.3
+
 // blockProgressLogger provides periodic logging for other services in order
 // to show users progress of certain "actions" involving some or all current
 // blocks. Ex: syncing to best chain, indexing all blocks, etc.
@@ -20,6 +25,11 @@ type blockProgressLogger struct {
 	receivedLogTx     int64
 	lastBlockLogTime  time.Time
 
+	// txRate is the moving average of the transactions processed per
+	// second over the logging periods.
+	txRate float64
+
+	chain           *blockchain.BlockChain
 	subsystemLogger btclog.Logger
 	progressAction  string
 	sync.Mutex
@@ -29,11 +39,17 @@ type blockProgressLogger struct {
 // The progress message is templated as follows:
 //
 //	{progressAction} {numProcessed} {blocks|block} in the last {timePeriod}
-//	({numTxs}, height {lastBlockHeight}, {lastBlockTimeStamp})
-func newBlockProgressLogger(progressMessage string, logger btclog.Logger) *blockProgressLogger {
+//	({numTxs}, height {lastBlockHeight}, {lastBlockTimeStamp},
+//	progress {progress}, {blockSpeed} blocks/s, {txSpeed} tx/s)
+//
+// The progress is the estimated fraction of the passed chain verified.
+func newBlockProgressLogger(progressMessage string, chain *blockchain.BlockChain,
+	logger btclog.Logger) *blockProgressLogger {
+
 	return &blockProgressLogger{
 		lastBlockLogTime: time.Now(),
 		progressAction:   progressMessage,
+		chain:            chain,
 		subsystemLogger:  logger,
 	}
 }
@@ -67,9 +83,19 @@ func (b *blockProgressLogger) LogBlockHeight(block *btcutil.Block) {
 	if b.receivedLogTx == 1 {
 		txStr = "transaction"
 	}
-	b.subsystemLogger.Infof("%s %d %s in the last %s (%d %s, height %d, %s)",
-		b.progressAction, b.receivedLogBlocks, blockStr, tDuration, b.receivedLogTx,
-		txStr, block.Height(), block.MsgBlock().Header.Timestamp)
+	seconds := duration.Seconds()
+	txSpeed := float64(b.receivedLogTx) / seconds
+	if b.txRate == 0 {
+		b.txRate = txSpeed
+	} else {
+		b.txRate += txRateWeight * (txSpeed - b.txRate)
+	}
+	b.subsystemLogger.Infof("%s %d %s in the last %s (%d %s, height %d, %s, "+
+		"progress %.2f%%, %.1f blocks/s, %.1f tx/s)", b.progressAction,
+		b.receivedLogBlocks, blockStr, tDuration, b.receivedLogTx, txStr,
+		block.Height(), block.MsgBlock().Header.Timestamp,
+		b.chain.VerificationProgress()*100,
+		float64(b.receivedLogBlocks)/seconds, txSpeed)
 
 	b.receivedLogBlocks = 0
 	b.receivedLogTx = 0
@@ -79,3 +105,18 @@ func (b *blockProgressLogger) LogBlockHeight(block *btcutil.Block) {
 func (b *blockProgressLogger) SetLastLogTime(time time.Time) {
 	b.lastBlockLogTime = time
 }
+
+// TimeRemaining returns an estimate of the time left to process the remaining
+// transactions of the chain at the average rate they were processed at so far.
+// It returns zero when there is no estimate.
+func (b *blockProgressLogger) TimeRemaining() time.Duration {
+	b.Lock()
+	txRate := b.txRate
+	b.Unlock()
+
+	remaining := b.chain.RemainingTxns()
+	if txRate <= 0 || remaining <= 0 {
+		return 0
+	}
+	return time.Duration(remaining / txRate * float64(time.Second))
+}
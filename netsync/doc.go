@@ -6,8 +6,15 @@
 Package netsync implements a concurrency safe block syncing protocol. The
 SyncManager communicates with connected peers to perform an initial block
 download, keep the chain and unconfirmed transaction pool in sync, and announce
-new blocks connected to the chain. Currently the sync manager selects a single
-sync peer that it downloads all blocks from until it is up to date with the
-longest chain the sync peer is aware of.
+new blocks connected to the chain. The sync manager selects a single sync peer
+that it downloads the headers up to the next checkpoint from. The blocks of
+those headers are then downloaded in parallel from all of the candidate peers,
+within a window following the next block to connect which is also bounded by
+the size of the blocks received ahead of their turn, and the requests of the
+peers which stall the download are reassigned to the others. Past the final
+checkpoint, all blocks are downloaded from the sync peer until it is up to date
+with the longest chain the sync peer is aware of. When the chain state was
+loaded from a utxo snapshot, the blocks below the snapshot are then downloaded
+from the candidate peers and validated in order in the background.
 */
 package netsync
@@ -8,6 +8,7 @@ import (
 	"github.com/lbryio/lbcd/blockchain"
 	"github.com/lbryio/lbcd/chaincfg"
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/connmgr"
 	"github.com/lbryio/lbcd/fees"
 	"github.com/lbryio/lbcd/mempool"
 	"github.com/lbryio/lbcd/peer"
@@ -26,6 +27,11 @@ type PeerNotifier interface {
 	RelayInventory(invVect *wire.InvVect, data interface{})
 
 	TransactionConfirmed(tx *btcutil.Tx)
+
+	// PenalizePeer increases the decaying score of the passed category of
+	// violations of the peer.  Peers are never banned for stalling, so
+	// slow block providers are penalized without being banned.
+	PenalizePeer(p *peer.Peer, violation connmgr.Violation, transient uint32, reason string)
 }
 
 // Config is a configuration struct used to initialize a new SyncManager.
@@ -38,5 +44,12 @@ type Config struct {
 	DisableCheckpoints bool
 	MaxPeers           int
 
+	// HeaderCheckSync downloads and checks the headers up to the tip of
+	// the sync peer before downloading the blocks.  The scripts of the
+	// block AssumeValid and of its ancestors aren't checked when its header
+	// is part of them.
+	HeaderCheckSync bool
+	AssumeValid     *chainhash.Hash
+
 	FeeEstimator *fees.Estimator
 }
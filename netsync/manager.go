@@ -6,8 +6,11 @@ package netsync
 
 import (
 	"container/list"
+	"errors"
+	"fmt"
 	"math/rand"
 	"net"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +18,7 @@ import (
 	"github.com/lbryio/lbcd/blockchain"
 	"github.com/lbryio/lbcd/chaincfg"
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/connmgr"
 	"github.com/lbryio/lbcd/database"
 	"github.com/lbryio/lbcd/fees"
 	"github.com/lbryio/lbcd/mempool"
@@ -24,10 +28,51 @@ import (
 )
 
 const (
-	// minInFlightBlocks is the minimum number of blocks that should be
-	// in the request queue for headers-first mode before requesting
-	// more.
-	minInFlightBlocks = 10
+	// blockDownloadWindow is the maximum distance past the next block to
+	// connect of the blocks requested in headers-first mode.
+	blockDownloadWindow = 1024
+
+	// maxPendingBlockBytes is the maximum serialized size of the blocks
+	// received ahead of their turn in headers-first mode, which are held in
+	// memory until then.  No blocks past the next block to connect are
+	// requested while it is exceeded, so the memory used by the parallel
+	// download is bounded by it along with the blocks in flight.
+	maxPendingBlockBytes = 128 * 1024 * 1024
+
+	// maxBlocksInFlightPerPeer is the maximum number of blocks requested
+	// from a single peer at a time in headers-first mode.
+	maxBlocksInFlightPerPeer = 16
+
+	// blockStallTimeout is the time after which the peer which was asked
+	// for the next block to connect is considered to stall the download
+	// while the blocks following it keep arriving from other peers.
+	blockStallTimeout = 10 * time.Second
+
+	// blockRequestTimeout is the time after which a block requested in
+	// headers-first mode is requested from another peer.
+	blockRequestTimeout = time.Minute
+
+	// blockStallBackoff is the time during which a peer which stalled the
+	// download isn't asked for any more blocks in headers-first mode.
+	blockStallBackoff = time.Minute
+
+	// blockStallPenalty is the decaying misbehavior score added to a peer
+	// each time it stalls the block download.  Peers are never banned for
+	// stalling.
+	blockStallPenalty = 10
+
+	// blockStallCheckInterval is the interval at which the blocks requested
+	// in headers-first mode are checked for stalls.
+	blockStallCheckInterval = 2 * time.Second
+
+	// maxConsecutiveBlockStalls is the number of times in a row a peer can
+	// stall the block download in headers-first mode, without delivering
+	// any of its blocks in time in between, before it is disconnected.
+	maxConsecutiveBlockStalls = 3
+
+	// snapshotDownloadWindow is the maximum number of blocks below a loaded
+	// utxo snapshot requested or held until their turn to be validated.
+	snapshotDownloadWindow = 64
 
 	// maxRejectedTxns is the maximum number of rejected transactions
 	// hashes to store in memory.
@@ -48,11 +93,20 @@ const (
 	// stallSampleInterval the interval at which we will check to see if our
 	// sync has stalled.
 	stallSampleInterval = 30 * time.Second
+
+	// maxHighBandwidthPeers is the maximum number of peers which are asked
+	// to announce new blocks with cmpctblock messages (BIP0152
+	// high-bandwidth mode).
+	maxHighBandwidthPeers = 3
 )
 
 // zeroHash is the zero value hash (all zeros).  It is defined as a convenience.
 var zeroHash chainhash.Hash
 
+// ErrShutdown is returned when a request can't be served because the sync
+// manager is shutting down.
+var ErrShutdown = errors.New("sync manager is shutting down")
+
 // newPeerMsg signifies a newly connected peer to the block handler.
 type newPeerMsg struct {
 	peer *peerpkg.Peer
@@ -73,6 +127,22 @@ type invMsg struct {
 	peer *peerpkg.Peer
 }
 
+// cmpctBlockMsg packages a bitcoin cmpctblock message and the peer it came
+// from together so the block handler has access to that information.
+type cmpctBlockMsg struct {
+	cmpctBlock *wire.MsgCmpctBlock
+	peer       *peerpkg.Peer
+	reply      chan struct{}
+}
+
+// blockTxnMsg packages a bitcoin blocktxn message and the peer it came from
+// together so the block handler has access to that information.
+type blockTxnMsg struct {
+	blockTxn *wire.MsgBlockTxn
+	peer     *peerpkg.Peer
+	reply    chan struct{}
+}
+
 // headersMsg packages a bitcoin headers message and the peer it came from
 // together so the block handler has access to that information.
 type headersMsg struct {
@@ -106,6 +176,12 @@ type getSyncPeerMsg struct {
 	reply chan int32
 }
 
+// getPeerSyncStatsMsg is a message type to be sent across the message channel
+// for retrieving the block download statistics of the peers.
+type getPeerSyncStatsMsg struct {
+	reply chan map[int32]PeerSyncStats
+}
+
 // processBlockResponse is a response sent to the reply channel of a
 // processBlockMsg.
 type processBlockResponse struct {
@@ -139,6 +215,20 @@ type pauseMsg struct {
 	unpause <-chan struct{}
 }
 
+// resetSyncMsg is a message type to be sent across the message channel for
+// restarting the sync from the current best block after the chain state was
+// replaced.
+type resetSyncMsg struct{}
+
+// fetchBlockMsg is a message type to be sent across the message channel for
+// requesting a block of the chain from a specific peer on demand, such as a
+// block whose data was pruned.
+type fetchBlockMsg struct {
+	hash  *chainhash.Hash
+	peer  *peerpkg.Peer
+	reply chan error
+}
+
 // headerNode is used as a node in a list of headers that are linked together
 // between checkpoints.
 type headerNode struct {
@@ -146,6 +236,29 @@ type headerNode struct {
 	hash   *chainhash.Hash
 }
 
+// blockRequest is a block requested from a peer during the parallel download
+// of headers-first mode.
+type blockRequest struct {
+	node      *headerNode
+	peer      *peerpkg.Peer
+	requested time.Time
+}
+
+// snapshotRequest is a block below a loaded utxo snapshot requested from a
+// peer to be validated.
+type snapshotRequest struct {
+	peer      *peerpkg.Peer
+	requested time.Time
+}
+
+// partialBlock is a block reconstructed from a compact block which is waiting
+// on the transactions requested with a getblocktxn message.
+type partialBlock struct {
+	hash    chainhash.Hash
+	block   *wire.MsgBlock
+	missing []uint32
+}
+
 // peerSyncState stores additional information that the SyncManager tracks
 // about a peer.
 type peerSyncState struct {
@@ -153,6 +266,39 @@ type peerSyncState struct {
 	requestQueue    []*wire.InvVect
 	requestedTxns   map[chainhash.Hash]struct{}
 	requestedBlocks map[chainhash.Hash]struct{}
+	partialBlock    *partialBlock
+
+	// stalledUntil is the time until which the peer isn't asked for more
+	// blocks in headers-first mode after stalling the download.
+	stalledUntil time.Time
+
+	// These fields track the block download statistics of the peer.  See
+	// PeerSyncStats.
+	blocksReceived    uint64
+	blocksReassigned  uint64
+	blockStalls       uint32
+	consecutiveStalls uint32
+}
+
+// PeerSyncStats houses the statistics of the blocks downloaded from a peer by
+// the sync manager.
+type PeerSyncStats struct {
+	// BlocksInFlight is the number of blocks requested from the peer which
+	// haven't been received yet.
+	BlocksInFlight int
+
+	// BlocksReceived is the number of requested blocks received from the
+	// peer.
+	BlocksReceived uint64
+
+	// BlocksReassigned is the number of blocks requested from the peer
+	// which were requested from other peers instead because the peer was
+	// too slow to deliver them or didn't have them.
+	BlocksReassigned uint64
+
+	// BlockStalls is the number of times the peer stalled the block
+	// download in headers-first mode.
+	BlockStalls uint32
 }
 
 // limitAdd is a helper function for maps that require a maximum limit by
@@ -191,6 +337,11 @@ type SyncManager struct {
 	wg             sync.WaitGroup
 	quit           chan struct{}
 
+	// stopped is closed once the block handler exited, so the callers
+	// waiting for the reply to a message which was never handled don't
+	// wait forever.
+	stopped chan struct{}
+
 	// These fields should only be accessed from the blockHandler thread
 	rejectedTxns     map[chainhash.Hash]struct{}
 	requestedTxns    map[chainhash.Hash]struct{}
@@ -199,11 +350,46 @@ type SyncManager struct {
 	peerStates       map[*peerpkg.Peer]*peerSyncState
 	lastProgressTime time.Time
 
-	// The following fields are used for headers-first mode.
+	// highBandwidthPeers are the peers which were asked to announce new
+	// blocks with cmpctblock messages, ordered by the time they last
+	// provided a new block.
+	highBandwidthPeers []*peerpkg.Peer
+
+	// fetchedBlocks are the blocks requested on demand, along with the
+	// peer they were requested from.
+	fetchedBlocks map[chainhash.Hash]*peerpkg.Peer
+
+	// The blocks below a loaded utxo snapshot are downloaded from the sync
+	// candidates once the chain is current, and validated in order.  The
+	// ones which arrive ahead of their turn are held in snapshotBlocks.
+	snapshotRequests map[chainhash.Hash]*snapshotRequest
+	snapshotBlocks   map[chainhash.Hash]*blockMsg
+
+	// The following fields are used for headers-first mode.  The blocks
+	// are downloaded in parallel from all of the sync candidates, so the
+	// ones which arrive ahead of their turn are held in pendingBlocks, and
+	// the ones whose request failed are queued in refetchHeaders to be
+	// requested again before the ones following startHeader.
 	headersFirstMode bool
 	headerList       *list.List
 	startHeader      *list.Element
 	nextCheckpoint   *chaincfg.Checkpoint
+	blockRequests    map[chainhash.Hash]*blockRequest
+	pendingBlocks    map[chainhash.Hash]*blockMsg
+	pendingBytes     int
+	refetchHeaders   []*headerNode
+
+	// The following fields are used for header-check sync, which is
+	// headers-first mode with the headers downloaded and checked up to the
+	// tip of the sync peer rather than the next checkpoint.  headersToTip
+	// is set while they are downloaded, headerChain checks them in the
+	// context of the previous ones, and assumeValidHeight is the height
+	// of the header of the block assumed to be valid once it is received.
+	headerCheckSync   bool
+	headersToTip      bool
+	headerChain       *blockchain.HeaderChain
+	assumeValid       *chainhash.Hash
+	assumeValidHeight int32
 
 	// An optional fee estimator.
 	feeEstimator *fees.Estimator
@@ -213,13 +399,18 @@ type SyncManager struct {
 // syncing from a new peer.
 func (sm *SyncManager) resetHeaderState(newestHash *chainhash.Hash, newestHeight int32) {
 	sm.headersFirstMode = false
+	sm.headersToTip = false
+	sm.headerChain = nil
+	sm.assumeValidHeight = 0
 	sm.headerList.Init()
 	sm.startHeader = nil
+	sm.clearBlockRequests()
 
-	// When there is a next checkpoint, add an entry for the latest known
-	// block into the header pool.  This allows the next downloaded header
-	// to prove it links to the chain properly.
-	if sm.nextCheckpoint != nil {
+	// When there is a next checkpoint or the headers are checked up to the
+	// tip, add an entry for the latest known block into the header pool.
+	// This allows the next downloaded header to prove it links to the
+	// chain properly.
+	if sm.nextCheckpoint != nil || sm.headerCheckSync {
 		node := headerNode{height: newestHeight, hash: newestHash}
 		sm.headerList.PushBack(&node)
 	}
@@ -253,6 +444,33 @@ func (sm *SyncManager) findNextHeaderCheckpoint(height int32) *chaincfg.Checkpoi
 	return nextCheckpoint
 }
 
+// checkpointAt returns the checkpoint at the passed height, or nil when there
+// is none.
+func (sm *SyncManager) checkpointAt(height int32) *chaincfg.Checkpoint {
+	checkpoints := sm.chain.Checkpoints()
+	for i := range checkpoints {
+		if checkpoints[i].Height == height {
+			return &checkpoints[i]
+		}
+	}
+	return nil
+}
+
+// headerBlockFlags returns the behavior flags to process the block of the
+// header at the passed height with in headers-first mode.  The blocks up to the
+// latest checkpoint need less validation, and the ones up to the block assumed
+// to be valid don't have their scripts checked.
+func (sm *SyncManager) headerBlockFlags(height int32) blockchain.BehaviorFlags {
+	checkpoint := sm.chain.LatestCheckpoint()
+	if checkpoint != nil && height <= checkpoint.Height {
+		return blockchain.BFFastAdd
+	}
+	if height <= sm.assumeValidHeight {
+		return blockchain.BFAssumeValid
+	}
+	return blockchain.BFNone
+}
+
 // startSync will choose the best peer among the available candidate peers to
 // download/sync the blockchain from.  When syncing is already running, it
 // simply returns.  It also examines the candidates for any which are no longer
@@ -358,7 +576,32 @@ func (sm *SyncManager) startSync() {
 		// and fully validate them.  Finally, regression test mode does
 		// not support the headers-first approach so do normal block
 		// downloads when in regression test mode.
-		if sm.nextCheckpoint != nil &&
+		//
+		// With header-check sync, the headers are downloaded and
+		// checked up to the tip of the peer instead, so the blocks are
+		// only downloaded once the peer has proven its chain has a
+		// valid proof of work.
+		var headerChain *blockchain.HeaderChain
+		if sm.headerCheckSync {
+			headerChain, err = sm.chain.NewHeaderChain(&best.Hash)
+			if err != nil {
+				log.Errorf("Unable to check the headers "+
+					"extending the best block: %v", err)
+			}
+		}
+		if headerChain != nil &&
+			bestPeer.LastBlock() > best.Height &&
+			sm.chainParams != &chaincfg.RegressionNetParams {
+
+			sm.resetHeaderState(&best.Hash, best.Height)
+			bestPeer.PushGetHeadersMsg(locator, &zeroHash)
+			sm.headersFirstMode = true
+			sm.headersToTip = true
+			sm.headerChain = headerChain
+			log.Infof("Downloading and checking headers for "+
+				"blocks %d to %d from peer %s", best.Height+1,
+				bestPeer.LastBlock(), bestPeer.Addr())
+		} else if sm.nextCheckpoint != nil &&
 			best.Height < sm.nextCheckpoint.Height &&
 			sm.chainParams != &chaincfg.RegressionNetParams {
 
@@ -437,9 +680,12 @@ func (sm *SyncManager) handleNewPeerMsg(peer *peerpkg.Peer) {
 		requestedBlocks: make(map[chainhash.Hash]struct{}),
 	}
 
-	// Start syncing by choosing the best candidate if needed.
+	// Start syncing by choosing the best candidate if needed, or have the
+	// new peer take part in the block download of headers-first mode.
 	if isSyncCandidate && sm.syncPeer == nil {
 		sm.startSync()
+	} else if isSyncCandidate && sm.headersFirstMode {
+		sm.fetchHeaderBlocks()
 	}
 }
 
@@ -469,6 +715,8 @@ func (sm *SyncManager) handleStallSample() {
 	}
 
 	sm.clearRequestedState(state)
+	go sm.peerNotifier.PenalizePeer(sm.syncPeer, connmgr.Stalling,
+		blockStallPenalty, "stalled the sync")
 
 	disconnectSyncPeer := sm.shouldDCStalledSyncPeer()
 	sm.updateSyncPeer(disconnectSyncPeer)
@@ -510,9 +758,36 @@ func (sm *SyncManager) handleDonePeerMsg(peer *peerpkg.Peer) {
 	// Remove the peer from the list of candidate peers.
 	delete(sm.peerStates, peer)
 
+	// Remove the peer from the high-bandwidth compact block peers.
+	for i, p := range sm.highBandwidthPeers {
+		if p == peer {
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers[:i],
+				sm.highBandwidthPeers[i+1:]...)
+			break
+		}
+	}
+
 	log.Infof("Lost peer %s", peer)
 
 	sm.clearRequestedState(state)
+	for hash, p := range sm.fetchedBlocks {
+		if p == peer {
+			delete(sm.fetchedBlocks, hash)
+		}
+	}
+	for hash, req := range sm.snapshotRequests {
+		if req.peer == peer {
+			delete(sm.snapshotRequests, hash)
+		}
+	}
+	sm.fetchSnapshotBlocks()
+
+	// Request the blocks which were in flight from the other peers when
+	// downloading the blocks in headers-first mode.
+	if sm.headersFirstMode && peer != sm.syncPeer {
+		sm.requeueBlockRequests(peer)
+		sm.fetchHeaderBlocks()
+	}
 
 	if peer == sm.syncPeer {
 		// Update the sync peer. The server has already disconnected the
@@ -554,8 +829,11 @@ func (sm *SyncManager) updateSyncPeer(dcSyncPeer bool) {
 	}
 
 	// Reset any header state before we choose our next active sync peer.
+	// The next checkpoint may be the tip of the headers of the current
+	// sync peer in header-check sync, so it's found again.
 	if sm.headersFirstMode {
 		best := sm.chain.BestSnapshot()
+		sm.nextCheckpoint = sm.findNextHeaderCheckpoint(best.Height)
 		sm.resetHeaderState(&best.Hash, best.Height)
 	}
 
@@ -563,6 +841,54 @@ func (sm *SyncManager) updateSyncPeer(dcSyncPeer bool) {
 	sm.startSync()
 }
 
+// handleResetSyncMsg restarts the sync from the current best block, dropping
+// the blocks requested and the headers downloaded for the previous one.
+func (sm *SyncManager) handleResetSyncMsg() {
+	for _, state := range sm.peerStates {
+		state.requestedBlocks = make(map[chainhash.Hash]struct{})
+	}
+	sm.requestedBlocks = make(map[chainhash.Hash]struct{})
+	sm.fetchedBlocks = make(map[chainhash.Hash]*peerpkg.Peer)
+	sm.snapshotRequests = make(map[chainhash.Hash]*snapshotRequest)
+
+	best := sm.chain.BestSnapshot()
+	sm.nextCheckpoint = sm.findNextHeaderCheckpoint(best.Height)
+	sm.resetHeaderState(&best.Hash, best.Height)
+
+	log.Infof("Restarting the sync from block %v (height %d)", best.Hash,
+		best.Height)
+
+	sm.syncPeer = nil
+	sm.startSync()
+}
+
+// handleFetchBlockMsg requests the block of the passed hash from the passed
+// peer on demand.
+func (sm *SyncManager) handleFetchBlockMsg(hash *chainhash.Hash, peer *peerpkg.Peer) error {
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		return fmt.Errorf("peer %s is not fully connected", peer)
+	}
+	if p, exists := sm.fetchedBlocks[*hash]; exists {
+		return fmt.Errorf("block %v is already being fetched from %s",
+			hash, p)
+	}
+
+	iv := wire.NewInvVect(wire.InvTypeBlock, hash)
+	if peer.IsWitnessEnabled() {
+		iv.Type = wire.InvTypeWitnessBlock
+	}
+	gdmsg := wire.NewMsgGetDataSizeHint(1)
+	gdmsg.AddInvVect(iv)
+
+	sm.fetchedBlocks[*hash] = peer
+	state.requestedBlocks[*hash] = struct{}{}
+	peer.QueueMessage(gdmsg, nil)
+
+	log.Infof("Fetching block %v from %s", hash, peer)
+	return nil
+}
+
 // handleTxMsg handles transaction messages from all peers.
 func (sm *SyncManager) handleTxMsg(tmsg *txMsg) {
 	peer := tmsg.peer
@@ -651,6 +977,29 @@ func (sm *SyncManager) current() bool {
 	return true
 }
 
+// requestOrphanParents asks the sync candidate peers for the blocks leading from
+// the best chain to the root of the orphan chain containing the passed block,
+// so the orphans are connected once the blocks arrive.  It is used for the
+// orphan blocks which weren't received from a peer, such as the blocks
+// submitted by miners which may arrive before their parents while blocks
+// propagate.
+func (sm *SyncManager) requestOrphanParents(hash *chainhash.Hash) {
+	orphanRoot := sm.chain.GetOrphanRoot(hash)
+	locator, err := sm.chain.LatestBlockLocator()
+	if err != nil {
+		log.Warnf("Failed to get block locator for the latest block: "+
+			"%v", err)
+		return
+	}
+
+	for peer, state := range sm.peerStates {
+		if !state.syncCandidate || !peer.Connected() {
+			continue
+		}
+		peer.PushGetBlocksMsg(locator, orphanRoot)
+	}
+}
+
 // handleBlockMsg handles block messages from all peers.
 func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	peer := bmsg.peer
@@ -676,6 +1025,111 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
+	// Remove block from request maps. Either chain will know about it and
+	// so we shouldn't have any more instances of trying to fetch it, or we
+	// will fail the insert and thus we'll retry next time we get an inv.
+	delete(state.requestedBlocks, *blockHash)
+	delete(sm.requestedBlocks, *blockHash)
+	state.blocksReceived++
+	if state.partialBlock != nil && state.partialBlock.hash == *blockHash {
+		state.partialBlock = nil
+	}
+
+	// The blocks fetched on demand whose data was pruned are stored as is
+	// since they are already part of the chain.  The other ones, such as
+	// blocks ahead of the chain, are processed as usual.
+	if _, exists := sm.fetchedBlocks[*blockHash]; exists {
+		delete(sm.fetchedBlocks, *blockHash)
+		if sm.chain.IsBlockPruned(blockHash) {
+			err := sm.chain.StorePrunedBlock(bmsg.block)
+			if err != nil {
+				log.Warnf("Failed to store block %v fetched "+
+					"from %s: %v", blockHash, peer, err)
+				return
+			}
+			log.Infof("Stored block %v fetched from %s",
+				blockHash, peer)
+			return
+		}
+	}
+
+	// The blocks below a loaded utxo snapshot are validated in order
+	// rather than processed.  The ones delivered after they were requested
+	// from another peer are ignored.
+	if _, exists := sm.snapshotRequests[*blockHash]; exists {
+		delete(sm.snapshotRequests, *blockHash)
+		sm.snapshotBlocks[*blockHash] = bmsg
+		sm.validateSnapshotBlocks()
+		return
+	}
+	if sm.chain.UtxoSnapshotHeight() != 0 && sm.chain.IsBlockPruned(blockHash) {
+		log.Debugf("Ignoring block %v below the utxo snapshot from %s",
+			blockHash, peer)
+		return
+	}
+
+	// Nothing more to do than processing the block when not in
+	// headers-first mode, besides requesting the blocks below a loaded
+	// utxo snapshot once the chain is current.
+	if !sm.headersFirstMode {
+		sm.processBlockMsg(bmsg)
+		sm.fetchSnapshotBlocks()
+		return
+	}
+
+	// In headers-first mode, the blocks are downloaded from several peers
+	// in parallel, so they can arrive out of order.  Hold the blocks which
+	// are ahead of the next block to connect until their turn, and ignore
+	// the ones which were also requested from another peer after a stall
+	// and have already been received.
+	if _, exists := sm.pendingBlocks[*blockHash]; exists {
+		return
+	}
+	req, isHeaderBlock := sm.blockRequests[*blockHash]
+	if isHeaderBlock && req.peer == peer {
+		// The peer delivered the block before it was reassigned, so it
+		// is no longer considered to persistently stall.
+		state.consecutiveStalls = 0
+	}
+	delete(sm.blockRequests, *blockHash)
+	if sm.removeRefetchHeader(blockHash) {
+		isHeaderBlock = true
+	}
+	if !isHeaderBlock {
+		haveBlock, err := sm.chain.HaveBlock(blockHash)
+		if err == nil && haveBlock {
+			return
+		}
+	}
+	if isHeaderBlock && !sm.isNextHeaderBlock(blockHash) {
+		sm.pendingBlocks[*blockHash] = bmsg
+		sm.pendingBytes += bmsg.block.MsgBlock().SerializeSize()
+		sm.fetchHeaderBlocks()
+		return
+	}
+
+	// Process the block along with the following ones which were received
+	// ahead of their turn, and then request more blocks.
+	connected := sm.processHeaderBlock(bmsg)
+	for connected && sm.headersFirstMode {
+		next := sm.takeNextPendingBlock()
+		if next == nil {
+			break
+		}
+		connected = sm.processHeaderBlock(next)
+	}
+	if sm.headersFirstMode {
+		sm.fetchHeaderBlocks()
+	}
+}
+
+// processBlockMsg processes the block of the passed message, which was sent by
+// the peer of the message, and returns whether it was connected to the chain
+// or accepted as a side chain block.
+func (sm *SyncManager) processBlockMsg(bmsg *blockMsg) bool {
+	peer := bmsg.peer
+	blockHash := bmsg.block.Hash()
+
 	// When in headers-first mode, if the block matches the hash of the
 	// first header in the list of headers that are being fetched, it's
 	// eligible for less validation since the headers have already been
@@ -690,7 +1144,7 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		if firstNodeEl != nil {
 			firstNode := firstNodeEl.Value.(*headerNode)
 			if blockHash.IsEqual(firstNode.hash) {
-				behaviorFlags |= blockchain.BFFastAdd
+				behaviorFlags |= sm.headerBlockFlags(firstNode.height)
 				if firstNode.hash.IsEqual(sm.nextCheckpoint.Hash) {
 					isCheckpointBlock = true
 				} else {
@@ -700,12 +1154,6 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
-	// Remove block from request maps. Either chain will know about it and
-	// so we shouldn't have any more instances of trying to fetch it, or we
-	// will fail the insert and thus we'll retry next time we get an inv.
-	delete(state.requestedBlocks, *blockHash)
-	delete(sm.requestedBlocks, *blockHash)
-
 	// Process the block to include validation, best chain selection, orphan
 	// handling, etc.
 	_, isOrphan, err := sm.chain.ProcessBlock(bmsg.block, behaviorFlags)
@@ -730,7 +1178,7 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		// send it.
 		code, reason := mempool.ErrToRejectErr(err)
 		peer.PushRejectMsg(wire.CmdBlock, code, reason, blockHash, false)
-		return
+		return false
 	}
 
 	// Meta-data about the new block this peer is reporting. We use this
@@ -776,7 +1224,9 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 			peer.PushGetBlocksMsg(locator, orphanRoot)
 		}
 	} else {
-		if peer == sm.syncPeer {
+		// All of the peers the blocks are downloaded from contribute
+		// to the progress of the sync in headers-first mode.
+		if peer == sm.syncPeer || sm.headersFirstMode {
 			sm.lastProgressTime = time.Now()
 		}
 
@@ -792,6 +1242,12 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 
 		// Clear the rejected transactions.
 		sm.rejectedTxns = make(map[chainhash.Hash]struct{})
+
+		// Ask the peers which recently provided new blocks first to
+		// announce them with compact blocks.
+		if sm.current() {
+			sm.updateHighBandwidthPeers(peer)
+		}
 	}
 
 	// Update the block height for this peer. But only send a message to
@@ -806,20 +1262,17 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
-	// Nothing more to do if we aren't in headers-first mode.
-	if !sm.headersFirstMode {
-		return
+	// Nothing more to do if we aren't in headers-first mode or if the
+	// block is not a checkpoint.  More blocks are requested using the
+	// header list by the caller.
+	if !sm.headersFirstMode || !isCheckpointBlock {
+		return !isOrphan
 	}
 
-	// This is headers-first mode, so if the block is not a checkpoint
-	// request more blocks using the header list when the request queue is
-	// getting short.
-	if !isCheckpointBlock {
-		if sm.startHeader != nil &&
-			len(state.requestedBlocks) < minInFlightBlocks {
-			sm.fetchHeaderBlocks()
-		}
-		return
+	// The headers and the remaining blocks are requested from the sync
+	// peer, so wait for a new one to be selected when it is gone.
+	if sm.syncPeer == nil {
+		return true
 	}
 
 	// This is headers-first mode and the block is a checkpoint.  When
@@ -831,16 +1284,16 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	sm.nextCheckpoint = sm.findNextHeaderCheckpoint(prevHeight)
 	if sm.nextCheckpoint != nil {
 		locator := blockchain.BlockLocator([]*chainhash.Hash{prevHash})
-		err := peer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
+		err := sm.syncPeer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
 		if err != nil {
 			log.Warnf("Failed to send getheaders message to "+
-				"peer %s: %v", peer.Addr(), err)
-			return
+				"peer %s: %v", sm.syncPeer.Addr(), err)
+			return true
 		}
 		log.Infof("Downloading headers for blocks %d to %d from "+
 			"peer %s", prevHeight+1, sm.nextCheckpoint.Height,
 			sm.syncPeer.Addr())
-		return
+		return true
 	}
 
 	// This is headers-first mode, the block is a checkpoint, and there are
@@ -848,129 +1301,788 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	// from the block after this one up to the end of the chain (zero hash).
 	sm.headersFirstMode = false
 	sm.headerList.Init()
+	sm.clearBlockRequests()
 	log.Infof("Reached the final checkpoint -- switching to normal mode")
 	locator := blockchain.BlockLocator([]*chainhash.Hash{blockHash})
-	err = peer.PushGetBlocksMsg(locator, &zeroHash)
+	err = sm.syncPeer.PushGetBlocksMsg(locator, &zeroHash)
 	if err != nil {
 		log.Warnf("Failed to send getblocks message to peer %s: %v",
-			peer.Addr(), err)
-		return
+			sm.syncPeer.Addr(), err)
 	}
+	return true
 }
 
-// fetchHeaderBlocks creates and sends a request to the syncPeer for the next
-// list of blocks to be downloaded based on the current list of headers.
-func (sm *SyncManager) fetchHeaderBlocks() {
-	// Nothing to do if there is no start header.
-	if sm.startHeader == nil {
-		log.Warnf("fetchHeaderBlocks called with no start header")
+// handleCmpctBlockMsg handles cmpctblock messages from all peers.  The block is
+// reconstructed from the transactions in the memory pool and the ones which
+// are missing are requested with a getblocktxn message.
+func (sm *SyncManager) handleCmpctBlockMsg(cmsg *cmpctBlockMsg) {
+	peer := cmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warnf("Received cmpctblock message from unknown peer %s", peer)
 		return
 	}
 
-	// Build up a getdata request for the list of blocks the headers
-	// describe.  The size hint will be limited to wire.MaxInvPerMsg by
-	// the function, so no need to double check it here.
-	gdmsg := wire.NewMsgGetDataSizeHint(uint(sm.headerList.Len()))
-	numRequested := 0
-	for e := sm.startHeader; e != nil; e = e.Next() {
-		node, ok := e.Value.(*headerNode)
-		if !ok {
-			log.Warn("Header list node type is not a headerNode")
-			continue
-		}
+	// Ignore unrequested compact blocks while syncing for the same reason
+	// block invs are ignored.
+	msg := cmsg.cmpctBlock
+	blockHash := msg.BlockHash()
+	_, requested := state.requestedBlocks[blockHash]
+	if !requested && (sm.headersFirstMode || !sm.current()) {
+		return
+	}
 
-		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
-		haveInv, err := sm.haveInventory(iv)
-		if err != nil {
-			log.Warnf("Unexpected failure when checking for "+
-				"existing inventory during header block "+
-				"fetch: %v", err)
-		}
-		if !haveInv {
-			syncPeerState := sm.peerStates[sm.syncPeer]
+	haveBlock, err := sm.chain.HaveBlock(&blockHash)
+	if err != nil {
+		log.Errorf("Failed to check for existing block %v: %v",
+			blockHash, err)
+		return
+	}
+	if haveBlock {
+		delete(state.requestedBlocks, blockHash)
+		delete(sm.requestedBlocks, blockHash)
+		return
+	}
+
+	// Ensure the header is valid before going through the memory pool.
+	header := btcutil.NewBlock(wire.NewMsgBlock(&msg.Header))
+	err = blockchain.CheckProofOfWork(header, sm.chainParams.PowLimit)
+	if err != nil {
+		log.Warnf("Got cmpctblock %v with invalid proof of work from "+
+			"%s -- disconnecting", blockHash, peer.Addr())
+		peer.Disconnect()
+		return
+	}
 
-			sm.requestedBlocks[*node.hash] = struct{}{}
-			syncPeerState.requestedBlocks[*node.hash] = struct{}{}
+	// Fetch the full block when it can't be reconstructed, which is the
+	// case when its parent is unknown so the orphan handling kicks in.
+	haveParent, err := sm.chain.HaveBlock(&msg.Header.PrevBlock)
+	if err != nil || !haveParent ||
+		peer.CmpctBlockVersion() != wire.CmpctBlockVersion2 {
 
-			// If we're fetching from a witness enabled peer
-			// post-fork, then ensure that we receive all the
-			// witness data in the blocks.
-			if sm.syncPeer.IsWitnessEnabled() {
-				iv.Type = wire.InvTypeWitnessBlock
-			}
+		sm.requestFullBlock(peer, state, &blockHash)
+		return
+	}
 
-			gdmsg.AddInvVect(iv)
-			numRequested++
-		}
-		sm.startHeader = e.Next()
-		if numRequested >= wire.MaxInvPerMsg/99 {
-			break
-		}
+	block, missing, err := reconstructBlock(msg, sm.txMemPool.TxDescs())
+	if err != nil {
+		log.Warnf("Got invalid cmpctblock %v from %s: %v -- "+
+			"disconnecting", blockHash, peer.Addr(), err)
+		peer.Disconnect()
+		return
 	}
-	if len(gdmsg.InvList) > 0 {
-		sm.syncPeer.QueueMessage(gdmsg, nil)
+
+	// Replace any block still waiting on transactions from the peer.
+	if state.partialBlock != nil {
+		delete(state.requestedBlocks, state.partialBlock.hash)
+		delete(sm.requestedBlocks, state.partialBlock.hash)
+		state.partialBlock = nil
+	}
+
+	limitAdd(sm.requestedBlocks, blockHash, maxRequestedBlocks)
+	limitAdd(state.requestedBlocks, blockHash, maxRequestedBlocks)
+
+	if len(missing) > 0 {
+		log.Debugf("Requesting %d of %d transactions of cmpctblock %v "+
+			"from %s", len(missing), len(block.Transactions),
+			blockHash, peer)
+		state.partialBlock = &partialBlock{
+			hash:    blockHash,
+			block:   block,
+			missing: missing,
+		}
+		peer.QueueMessage(wire.NewMsgGetBlockTxn(&blockHash, missing), nil)
+		return
 	}
+
+	sm.processCmpctBlock(peer, state, block)
 }
 
-// handleHeadersMsg handles block header messages from all peers.  Headers are
-// requested when performing a headers-first sync.
-func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
-	peer := hmsg.peer
-	_, exists := sm.peerStates[peer]
+// handleBlockTxnMsg handles blocktxn messages from all peers.  The transactions
+// complete the block reconstructed from the last compact block of the peer.
+func (sm *SyncManager) handleBlockTxnMsg(bmsg *blockTxnMsg) {
+	peer := bmsg.peer
+	state, exists := sm.peerStates[peer]
 	if !exists {
-		log.Warnf("Received headers message from unknown peer %s", peer)
+		log.Warnf("Received blocktxn message from unknown peer %s", peer)
 		return
 	}
 
-	// The remote peer is misbehaving if we didn't request headers.
-	msg := hmsg.headers
-	numHeaders := len(msg.Headers)
-	if !sm.headersFirstMode {
-		log.Warnf("Got %d unrequested headers from %s -- "+
-			"disconnecting", numHeaders, peer.Addr())
-		peer.Disconnect()
+	msg := bmsg.blockTxn
+	partial := state.partialBlock
+	if partial == nil || partial.hash != msg.BlockHash {
+		log.Debugf("Ignoring unrequested blocktxn %v from %s",
+			msg.BlockHash, peer)
 		return
 	}
+	state.partialBlock = nil
 
-	// Nothing to do for an empty headers message.
-	if numHeaders == 0 {
+	if len(msg.Transactions) != len(partial.missing) {
+		log.Debugf("Got %d transactions for cmpctblock %v from %s "+
+			"instead of %d", len(msg.Transactions), partial.hash, peer,
+			len(partial.missing))
+		sm.requestFullBlock(peer, state, &partial.hash)
 		return
 	}
 
-	// Process all of the received headers ensuring each one connects to the
-	// previous and that checkpoints match.
-	receivedCheckpoint := false
-	var finalHash *chainhash.Hash
-	for _, blockHeader := range msg.Headers {
-		blockHash := blockHeader.BlockHash()
-		finalHash = &blockHash
+	for i, index := range partial.missing {
+		partial.block.Transactions[index] = msg.Transactions[i]
+	}
+	sm.processCmpctBlock(peer, state, partial.block)
+}
 
-		// Ensure there is a previous header to compare against.
-		prevNodeEl := sm.headerList.Back()
-		if prevNodeEl == nil {
-			log.Warnf("Header list does not contain a previous" +
-				"element as expected -- disconnecting peer")
-			peer.Disconnect()
-			return
+// processCmpctBlock processes a block reconstructed from a compact block like
+// any other block from the peer.  Short ID collisions with transactions that
+// are not part of the block can't be detected until the merkle root is
+// checked, so the full block is requested when it doesn't match.
+func (sm *SyncManager) processCmpctBlock(peer *peerpkg.Peer, state *peerSyncState,
+	msgBlock *wire.MsgBlock) {
+
+	block := btcutil.NewBlock(msgBlock)
+	merkles := blockchain.BuildMerkleTreeStore(block.Transactions(), false)
+	if !merkles[len(merkles)-1].IsEqual(&msgBlock.Header.MerkleRoot) {
+		log.Debugf("Reconstructed cmpctblock %v from %s has a bad "+
+			"merkle root -- requesting full block", block.Hash(),
+			peer)
+		sm.requestFullBlock(peer, state, block.Hash())
+		return
+	}
+
+	sm.handleBlockMsg(&blockMsg{block: block, peer: peer})
+}
+
+// requestFullBlock requests the passed block from the peer with a getdata
+// message.
+func (sm *SyncManager) requestFullBlock(peer *peerpkg.Peer, state *peerSyncState,
+	blockHash *chainhash.Hash) {
+
+	limitAdd(sm.requestedBlocks, *blockHash, maxRequestedBlocks)
+	limitAdd(state.requestedBlocks, *blockHash, maxRequestedBlocks)
+
+	invType := wire.InvTypeBlock
+	if peer.IsWitnessEnabled() {
+		invType = wire.InvTypeWitnessBlock
+	}
+	gdmsg := wire.NewMsgGetData()
+	gdmsg.AddInvVect(wire.NewInvVect(invType, blockHash))
+	peer.QueueMessage(gdmsg, nil)
+}
+
+// reconstructBlock reconstructs the block of the passed version 2 compact
+// block using the passed memory pool transactions.  The indexes of the
+// transactions which could not be determined are returned along with the
+// block, whose transactions are nil at those indexes.
+func reconstructBlock(msg *wire.MsgCmpctBlock, txDescs []*mempool.TxDesc) (
+	*wire.MsgBlock, []uint32, error) {
+
+	numTxns := len(msg.ShortIDs) + len(msg.PrefilledTxs)
+	if numTxns == 0 {
+		return nil, nil, errors.New("no transactions")
+	}
+
+	block := wire.NewMsgBlock(&msg.Header)
+	block.Transactions = make([]*wire.MsgTx, numTxns)
+	for _, ptx := range msg.PrefilledTxs {
+		if int(ptx.Index) >= numTxns {
+			return nil, nil, fmt.Errorf("prefilled transaction "+
+				"index %d out of range", ptx.Index)
 		}
+		block.Transactions[ptx.Index] = ptx.Tx
+	}
 
-		// Ensure the header properly connects to the previous one and
-		// add it to the list of headers.
-		node := headerNode{hash: &blockHash}
-		prevNode := prevNodeEl.Value.(*headerNode)
-		if prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
-			node.height = prevNode.height + 1
-			e := sm.headerList.PushBack(&node)
-			if sm.startHeader == nil {
-				sm.startHeader = e
-			}
+	// Assign the short IDs to the remaining indexes in order.  Short IDs
+	// which are not unique can't be matched, so their transactions are
+	// requested instead.
+	indexes := make(map[uint64]int, len(msg.ShortIDs))
+	next := 0
+	for _, id := range msg.ShortIDs {
+		for block.Transactions[next] != nil {
+			next++
+		}
+		if _, exists := indexes[id]; exists {
+			indexes[id] = -1
 		} else {
-			log.Warnf("Received block header that does not "+
-				"properly connect to the chain from peer %s "+
-				"-- disconnecting", peer.Addr())
-			peer.Disconnect()
+			indexes[id] = next
+		}
+		next++
+	}
+
+	key := msg.ShortIDKey()
+	for _, txDesc := range txDescs {
+		id := wire.ShortTxID(&key, txDesc.Tx.WitnessHash())
+		index, exists := indexes[id]
+		if !exists || index == -1 {
+			continue
+		}
+
+		// Request the transaction when several of the memory pool
+		// transactions match its short ID.
+		if block.Transactions[index] != nil {
+			block.Transactions[index] = nil
+			indexes[id] = -1
+			continue
+		}
+		block.Transactions[index] = txDesc.Tx.MsgTx()
+	}
+
+	var missing []uint32
+	for i, tx := range block.Transactions {
+		if tx == nil {
+			missing = append(missing, uint32(i))
+		}
+	}
+	return block, missing, nil
+}
+
+// updateHighBandwidthPeers records the passed peer as the most recent provider
+// of a new block and asks it to announce new blocks with cmpctblock messages
+// when it isn't already doing so.  The least recent of the high-bandwidth peers
+// is asked to stop when there are too many of them.
+func (sm *SyncManager) updateHighBandwidthPeers(peer *peerpkg.Peer) {
+	if peer.CmpctBlockVersion() != wire.CmpctBlockVersion2 {
+		return
+	}
+
+	for i, p := range sm.highBandwidthPeers {
+		if p == peer {
+			copy(sm.highBandwidthPeers[i:], sm.highBandwidthPeers[i+1:])
+			sm.highBandwidthPeers[len(sm.highBandwidthPeers)-1] = peer
+			return
+		}
+	}
+
+	if len(sm.highBandwidthPeers) >= maxHighBandwidthPeers {
+		oldest := sm.highBandwidthPeers[0]
+		sm.highBandwidthPeers = sm.highBandwidthPeers[1:]
+		oldest.QueueMessage(wire.NewMsgSendCmpct(false,
+			wire.CmpctBlockVersion2), nil)
+	}
+
+	log.Debugf("Requesting high-bandwidth compact blocks from %s", peer)
+	sm.highBandwidthPeers = append(sm.highBandwidthPeers, peer)
+	peer.QueueMessage(wire.NewMsgSendCmpct(true, wire.CmpctBlockVersion2), nil)
+}
+
+// fetchHeaderBlocks requests the blocks of the headers which are within the
+// download window from all of the sync candidates, up to
+// maxBlocksInFlightPerPeer blocks at a time from each of them.  The blocks
+// whose request failed are requested again before the following ones.  Only
+// the next block to connect is requested while the blocks received ahead of
+// their turn exceed maxPendingBlockBytes.
+func (sm *SyncManager) fetchHeaderBlocks() {
+	// Nothing to do until the headers up to the next checkpoint are known,
+	// which is when the first entry of the list is the next block to
+	// connect rather than the latest known block.
+	if !sm.headersFirstMode || sm.nextCheckpoint == nil {
+		return
+	}
+	front := sm.headerList.Front()
+	if front == nil {
+		return
+	}
+	frontHeight := front.Value.(*headerNode).height
+	if frontHeight <= sm.chain.BestSnapshot().Height {
+		return
+	}
+	maxHeight := frontHeight + blockDownloadWindow
+
+	inFlight := make(map[*peerpkg.Peer]int)
+	for _, req := range sm.blockRequests {
+		inFlight[req.peer]++
+	}
+	now := time.Now()
+	requests := make(map[*peerpkg.Peer]*wire.MsgGetData)
+	requestBlock := func(node *headerNode) bool {
+		peer := sm.selectBlockPeer(node.height, inFlight, now)
+		if peer == nil {
+			return false
+		}
+
+		gdmsg, ok := requests[peer]
+		if !ok {
+			gdmsg = wire.NewMsgGetDataSizeHint(maxBlocksInFlightPerPeer)
+			requests[peer] = gdmsg
+		}
+
+		// If we're fetching from a witness enabled peer post-fork, then
+		// ensure that we receive all the witness data in the blocks.
+		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
+		if peer.IsWitnessEnabled() {
+			iv.Type = wire.InvTypeWitnessBlock
+		}
+		gdmsg.AddInvVect(iv)
+
+		sm.requestedBlocks[*node.hash] = struct{}{}
+		sm.peerStates[peer].requestedBlocks[*node.hash] = struct{}{}
+		sm.blockRequests[*node.hash] = &blockRequest{
+			node:      node,
+			peer:      peer,
+			requested: now,
+		}
+		inFlight[peer]++
+		return true
+	}
+
+	// Request the blocks whose request failed first since the ones closest
+	// to the next block to connect are holding up the others.
+	for len(sm.refetchHeaders) > 0 {
+		node := sm.refetchHeaders[0]
+		if sm.needHeaderBlock(node) && !requestBlock(node) {
+			break
+		}
+		sm.refetchHeaders = sm.refetchHeaders[1:]
+	}
+
+	for sm.startHeader != nil {
+		node, ok := sm.startHeader.Value.(*headerNode)
+		if !ok {
+			log.Warn("Header list node type is not a headerNode")
+			sm.startHeader = sm.startHeader.Next()
+			continue
+		}
+		if node.height > maxHeight || (node.height > frontHeight &&
+			sm.pendingBytes >= maxPendingBlockBytes) {
+
+			break
+		}
+		if sm.needHeaderBlock(node) && !requestBlock(node) {
+			break
+		}
+		sm.startHeader = sm.startHeader.Next()
+	}
+
+	for peer, gdmsg := range requests {
+		log.Debugf("Requesting %d blocks from peer %s",
+			len(gdmsg.InvList), peer)
+		peer.QueueMessage(gdmsg, nil)
+	}
+}
+
+// needHeaderBlock returns whether the block of the passed header still has to
+// be requested.
+func (sm *SyncManager) needHeaderBlock(node *headerNode) bool {
+	if _, exists := sm.blockRequests[*node.hash]; exists {
+		return false
+	}
+	if _, exists := sm.pendingBlocks[*node.hash]; exists {
+		return false
+	}
+
+	iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
+	haveInv, err := sm.haveInventory(iv)
+	if err != nil {
+		log.Warnf("Unexpected failure when checking for existing "+
+			"inventory during header block fetch: %v", err)
+	}
+	return !haveInv
+}
+
+// selectBlockPeer returns the sync candidate to request the block at the passed
+// height from, or nil when none of them is able to serve it.  The peers with
+// the fewest blocks in flight are preferred, while the ones which recently
+// stalled the download are only used when no other peer is available.
+func (sm *SyncManager) selectBlockPeer(height int32,
+	inFlight map[*peerpkg.Peer]int, now time.Time) *peerpkg.Peer {
+
+	var best *peerpkg.Peer
+	var bestStalled bool
+	for peer, state := range sm.peerStates {
+		if !state.syncCandidate || !peer.Connected() ||
+			peer.LastBlock() < height ||
+			inFlight[peer] >= maxBlocksInFlightPerPeer {
+
+			continue
+		}
+
+		stalled := now.Before(state.stalledUntil)
+		switch {
+		case best == nil:
+		case bestStalled && !stalled:
+		case bestStalled == stalled && inFlight[peer] < inFlight[best]:
+		default:
+			continue
+		}
+		best = peer
+		bestStalled = stalled
+	}
+
+	return best
+}
+
+// isNextHeaderBlock returns whether the passed block is the next one to connect
+// in headers-first mode.
+func (sm *SyncManager) isNextHeaderBlock(hash *chainhash.Hash) bool {
+	front := sm.headerList.Front()
+	return front != nil && front.Value.(*headerNode).hash.IsEqual(hash)
+}
+
+// takeNextPendingBlock removes and returns the next block to connect in
+// headers-first mode when it has already been received, or nil otherwise.
+func (sm *SyncManager) takeNextPendingBlock() *blockMsg {
+	front := sm.headerList.Front()
+	if front == nil {
+		return nil
+	}
+	hash := front.Value.(*headerNode).hash
+	bmsg, exists := sm.pendingBlocks[*hash]
+	if !exists {
+		return nil
+	}
+	delete(sm.pendingBlocks, *hash)
+	sm.pendingBytes -= bmsg.block.MsgBlock().SerializeSize()
+	return bmsg
+}
+
+// processHeaderBlock processes the passed block received in headers-first mode
+// and returns whether it was connected.  When the next block to connect is
+// rejected, its header is put back at the front of the list and the block is
+// requested again.
+func (sm *SyncManager) processHeaderBlock(bmsg *blockMsg) bool {
+	front := sm.headerList.Front()
+	if front == nil {
+		return sm.processBlockMsg(bmsg)
+	}
+	node := front.Value.(*headerNode)
+	if !node.hash.IsEqual(bmsg.block.Hash()) {
+		return sm.processBlockMsg(bmsg)
+	}
+
+	connected := sm.processBlockMsg(bmsg)
+	if !connected && sm.headersFirstMode {
+		if sm.headerList.Front() != front {
+			sm.headerList.PushFront(node)
+		}
+		sm.refetchHeaders = append(sm.refetchHeaders, node)
+	}
+	return connected
+}
+
+// requeueBlockRequests queues the blocks requested from the passed peer in
+// headers-first mode to be requested from other peers.  The blocks remain in
+// the requested blocks of the peer so they are still accepted if it sends them
+// later on.
+func (sm *SyncManager) requeueBlockRequests(peer *peerpkg.Peer) {
+	state := sm.peerStates[peer]
+	for hash, req := range sm.blockRequests {
+		if req.peer != peer {
+			continue
+		}
+		delete(sm.blockRequests, hash)
+		sm.refetchHeaders = append(sm.refetchHeaders, req.node)
+		if state != nil {
+			state.blocksReassigned++
+		}
+	}
+	sort.Slice(sm.refetchHeaders, func(i, j int) bool {
+		return sm.refetchHeaders[i].height < sm.refetchHeaders[j].height
+	})
+}
+
+// removeRefetchHeader removes the header of the passed block from the headers
+// whose block has to be requested again and returns whether it was found.
+func (sm *SyncManager) removeRefetchHeader(hash *chainhash.Hash) bool {
+	for i, node := range sm.refetchHeaders {
+		if node.hash.IsEqual(hash) {
+			sm.refetchHeaders = append(sm.refetchHeaders[:i],
+				sm.refetchHeaders[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// clearBlockRequests forgets about all of the blocks requested and received
+// ahead of their turn in headers-first mode.
+func (sm *SyncManager) clearBlockRequests() {
+	for hash, req := range sm.blockRequests {
+		if state, exists := sm.peerStates[req.peer]; exists {
+			delete(state.requestedBlocks, hash)
+		}
+		delete(sm.requestedBlocks, hash)
+	}
+	sm.blockRequests = make(map[chainhash.Hash]*blockRequest)
+	sm.pendingBlocks = make(map[chainhash.Hash]*blockMsg)
+	sm.pendingBytes = 0
+	sm.refetchHeaders = nil
+}
+
+// handleBlockRequestStalls detects the peers which stall the parallel block
+// download of headers-first mode.  The blocks requested from a peer which
+// didn't deliver one of them in time are requested from other peers, and the
+// peer isn't asked for more blocks for a while.  A peer which holds up the next
+// block to connect while the following ones keep arriving is disconnected,
+// unless it's the sync peer which is still needed for the headers.  Any peer,
+// including the sync peer, which stalls maxConsecutiveBlockStalls times in a
+// row is disconnected, which rotates the sync peer when needed.
+func (sm *SyncManager) handleBlockRequestStalls() {
+	if atomic.LoadInt32(&sm.shutdown) != 0 || !sm.headersFirstMode {
+		return
+	}
+
+	now := time.Now()
+	stalled := make(map[*peerpkg.Peer]struct{})
+	front := sm.headerList.Front()
+	if front != nil && len(sm.pendingBlocks) > 0 {
+		hash := front.Value.(*headerNode).hash
+		req, exists := sm.blockRequests[*hash]
+		if exists && now.Sub(req.requested) > blockStallTimeout {
+			if req.peer != sm.syncPeer {
+				log.Infof("Peer %s stalled the download of block "+
+					"%v -- disconnecting", req.peer, hash)
+				if state, exists := sm.peerStates[req.peer]; exists {
+					state.blockStalls++
+				}
+				sm.requeueBlockRequests(req.peer)
+				req.peer.Disconnect()
+			} else {
+				stalled[req.peer] = struct{}{}
+			}
+		}
+	}
+	for _, req := range sm.blockRequests {
+		if now.Sub(req.requested) > blockRequestTimeout {
+			stalled[req.peer] = struct{}{}
+		}
+	}
+	if len(stalled) == 0 {
+		return
+	}
+
+	for peer := range stalled {
+		state, exists := sm.peerStates[peer]
+		if exists {
+			state.blockStalls++
+			state.consecutiveStalls++
+			if state.consecutiveStalls >= maxConsecutiveBlockStalls {
+				log.Infof("Peer %s stalled the block download %d "+
+					"times in a row -- disconnecting", peer,
+					state.consecutiveStalls)
+				sm.requeueBlockRequests(peer)
+				peer.Disconnect()
+				continue
+			}
+			state.stalledUntil = now.Add(blockStallBackoff)
+		}
+		log.Debugf("Peer %s stalled the block download -- requesting "+
+			"its blocks from other peers", peer)
+		sm.requeueBlockRequests(peer)
+		go sm.peerNotifier.PenalizePeer(peer, connmgr.Stalling,
+			blockStallPenalty, "stalled the block download")
+	}
+	sm.fetchHeaderBlocks()
+}
+
+// fetchSnapshotBlocks requests the next blocks below a loaded utxo snapshot to
+// validate from the sync candidates once the chain is current, up to
+// snapshotDownloadWindow blocks ahead of the next one to validate.
+func (sm *SyncManager) fetchSnapshotBlocks() {
+	if atomic.LoadInt32(&sm.shutdown) != 0 || sm.headersFirstMode ||
+		!sm.current() {
+
+		return
+	}
+	hashes := sm.chain.SnapshotValidationBlocks(snapshotDownloadWindow)
+	if len(hashes) == 0 {
+		return
+	}
+
+	inFlight := make(map[*peerpkg.Peer]int)
+	for _, req := range sm.snapshotRequests {
+		inFlight[req.peer]++
+	}
+	height := sm.chain.UtxoSnapshotHeight()
+	now := time.Now()
+	requests := make(map[*peerpkg.Peer]*wire.MsgGetData)
+	for i := range hashes {
+		hash := &hashes[i]
+		if _, exists := sm.snapshotRequests[*hash]; exists {
+			continue
+		}
+		if _, exists := sm.snapshotBlocks[*hash]; exists {
+			continue
+		}
+		peer := sm.selectBlockPeer(height, inFlight, now)
+		if peer == nil {
+			break
+		}
+
+		gdmsg, ok := requests[peer]
+		if !ok {
+			gdmsg = wire.NewMsgGetDataSizeHint(maxBlocksInFlightPerPeer)
+			requests[peer] = gdmsg
+		}
+		iv := wire.NewInvVect(wire.InvTypeBlock, hash)
+		if peer.IsWitnessEnabled() {
+			iv.Type = wire.InvTypeWitnessBlock
+		}
+		gdmsg.AddInvVect(iv)
+
+		sm.requestedBlocks[*hash] = struct{}{}
+		sm.peerStates[peer].requestedBlocks[*hash] = struct{}{}
+		sm.snapshotRequests[*hash] = &snapshotRequest{
+			peer:      peer,
+			requested: now,
+		}
+		inFlight[peer]++
+	}
+
+	for peer, gdmsg := range requests {
+		log.Debugf("Requesting %d blocks below the utxo snapshot from "+
+			"peer %s", len(gdmsg.InvList), peer)
+		peer.QueueMessage(gdmsg, nil)
+	}
+}
+
+// validateSnapshotBlocks validates the blocks below a loaded utxo snapshot
+// which were received, in order, and then requests more of them.  A block which
+// fails to be validated is requested again, unless its validation shows the
+// chain state loaded from the snapshot is invalid, in which case no more blocks
+// are requested.
+func (sm *SyncManager) validateSnapshotBlocks() {
+	for {
+		hashes := sm.chain.SnapshotValidationBlocks(1)
+		if len(hashes) == 0 {
+			sm.snapshotBlocks = make(map[chainhash.Hash]*blockMsg)
 			return
 		}
+		bmsg, exists := sm.snapshotBlocks[hashes[0]]
+		if !exists {
+			break
+		}
+		delete(sm.snapshotBlocks, hashes[0])
+
+		err := sm.chain.ValidateSnapshotBlock(bmsg.block)
+		if err != nil {
+			log.Warnf("Failed to validate block %v below the utxo "+
+				"snapshot from %s: %v", hashes[0], bmsg.peer, err)
+			break
+		}
+	}
+	sm.fetchSnapshotBlocks()
+}
+
+// handleSnapshotRequestStalls requests the blocks below a loaded utxo snapshot
+// which weren't delivered in time from other peers, and doesn't ask the peers
+// they were requested from for more blocks for a while.
+func (sm *SyncManager) handleSnapshotRequestStalls() {
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		return
+	}
+
+	now := time.Now()
+	for hash, req := range sm.snapshotRequests {
+		if now.Sub(req.requested) <= blockRequestTimeout {
+			continue
+		}
+		delete(sm.snapshotRequests, hash)
+		if state, exists := sm.peerStates[req.peer]; exists {
+			state.stalledUntil = now.Add(blockStallBackoff)
+		}
+		log.Debugf("Peer %s stalled the download of block %v below "+
+			"the utxo snapshot", req.peer, hash)
+	}
+	sm.fetchSnapshotBlocks()
+}
+
+// handleHeadersMsg handles block header messages from all peers.  Headers are
+// requested when performing a headers-first sync.
+func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
+	peer := hmsg.peer
+	_, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warnf("Received headers message from unknown peer %s", peer)
+		return
+	}
+
+	// The remote peer is misbehaving if we didn't request headers.
+	msg := hmsg.headers
+	numHeaders := len(msg.Headers)
+	if !sm.headersFirstMode {
+		log.Warnf("Got %d unrequested headers from %s -- "+
+			"disconnecting", numHeaders, peer.Addr())
+		peer.Disconnect()
+		return
+	}
+
+	// With header-check sync, only the headers of the sync peer are
+	// downloaded up to its tip.
+	if sm.headersToTip && peer != sm.syncPeer {
+		return
+	}
+
+	// Nothing to do for an empty headers message, unless it means there
+	// are no more headers up to the tip of the sync peer.
+	if numHeaders == 0 {
+		if sm.headersToTip {
+			sm.finishHeaderCheck(peer)
+		}
+		return
+	}
+
+	// Process all of the received headers ensuring each one connects to the
+	// previous and that checkpoints match.
+	receivedCheckpoint := false
+	var finalHash *chainhash.Hash
+	for _, blockHeader := range msg.Headers {
+		blockHash := blockHeader.BlockHash()
+		finalHash = &blockHash
+
+		// Ensure there is a previous header to compare against.
+		prevNodeEl := sm.headerList.Back()
+		if prevNodeEl == nil {
+			log.Warnf("Header list does not contain a previous" +
+				"element as expected -- disconnecting peer")
+			peer.Disconnect()
+			return
+		}
+
+		// Ensure the header properly connects to the previous one and
+		// add it to the list of headers.
+		node := headerNode{hash: &blockHash}
+		prevNode := prevNodeEl.Value.(*headerNode)
+		if prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
+			node.height = prevNode.height + 1
+			e := sm.headerList.PushBack(&node)
+			if sm.startHeader == nil {
+				sm.startHeader = e
+			}
+		} else {
+			log.Warnf("Received block header that does not "+
+				"properly connect to the chain from peer %s "+
+				"-- disconnecting", peer.Addr())
+			peer.Disconnect()
+			return
+		}
+
+		// With header-check sync, check the header in the context of
+		// the previous ones along with any checkpoint at its height,
+		// and record the height of the block assumed to be valid.
+		if sm.headersToTip {
+			if err := sm.headerChain.Connect(blockHeader); err != nil {
+				log.Warnf("Received invalid block header %s "+
+					"from peer %s -- disconnecting: %v",
+					node.hash, peer.Addr(), err)
+				peer.Disconnect()
+				return
+			}
+			checkpoint := sm.checkpointAt(node.height)
+			if checkpoint != nil && !node.hash.IsEqual(checkpoint.Hash) {
+				log.Warnf("Block header at height %d/hash "+
+					"%s from peer %s does NOT match "+
+					"expected checkpoint hash of %s -- "+
+					"disconnecting", node.height,
+					node.hash, peer.Addr(), checkpoint.Hash)
+				peer.Disconnect()
+				return
+			}
+			if sm.assumeValid != nil && node.hash.IsEqual(sm.assumeValid) {
+				sm.assumeValidHeight = node.height
+				log.Infof("Received the header of the block "+
+					"assumed to be valid at height %d",
+					node.height)
+			}
+			continue
+		}
 
 		// Verify the header at the next checkpoint height matches.
 		if node.height == sm.nextCheckpoint.Height {
@@ -1008,6 +2120,25 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 		return
 	}
 
+	// With header-check sync, the tip of the sync peer is reached when it
+	// sends fewer headers than the maximum, otherwise request the next
+	// batch of headers starting from the latest known header.  Each batch
+	// counts as progress since it may take a while to reach the tip.
+	if sm.headersToTip {
+		sm.lastProgressTime = time.Now()
+		if numHeaders < wire.MaxBlockHeadersPerMsg {
+			sm.finishHeaderCheck(peer)
+			return
+		}
+		locator := blockchain.BlockLocator([]*chainhash.Hash{finalHash})
+		err := peer.PushGetHeadersMsg(locator, &zeroHash)
+		if err != nil {
+			log.Warnf("Failed to send getheaders message to "+
+				"peer %s: %v", peer.Addr(), err)
+		}
+		return
+	}
+
 	// This header is not a checkpoint, so request the next batch of
 	// headers starting from the latest known header and ending with the
 	// next checkpoint.
@@ -1020,6 +2151,47 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 	}
 }
 
+// finishHeaderCheck switches to fetching the blocks of the headers downloaded
+// and checked up to the tip of the passed sync peer in header-check sync.  The
+// last header is used as the next checkpoint, so normal mode is resumed once
+// its block is connected.
+func (sm *SyncManager) finishHeaderCheck(peer *peerpkg.Peer) {
+	sm.headersToTip = false
+
+	// Since the first entry of the list is always the final block that is
+	// already in the database, there are no blocks to fetch when it is the
+	// only one.
+	last := sm.headerList.Back().Value.(*headerNode)
+	if sm.headerList.Len() == 1 {
+		sm.nextCheckpoint = sm.findNextHeaderCheckpoint(last.height)
+		sm.headersFirstMode = false
+		sm.headerList.Init()
+		log.Infof("No new block headers from peer %s -- switching to "+
+			"normal mode", peer.Addr())
+		locator := blockchain.BlockLocator([]*chainhash.Hash{last.hash})
+		err := peer.PushGetBlocksMsg(locator, &zeroHash)
+		if err != nil {
+			log.Warnf("Failed to send getblocks message to peer "+
+				"%s: %v", peer.Addr(), err)
+		}
+		return
+	}
+
+	if sm.assumeValid != nil && sm.assumeValidHeight == 0 {
+		log.Infof("The header of the block assumed to be valid %s "+
+			"wasn't received -- checking all scripts", sm.assumeValid)
+	}
+	sm.nextCheckpoint = &chaincfg.Checkpoint{
+		Height: last.height,
+		Hash:   last.hash,
+	}
+	sm.headerList.Remove(sm.headerList.Front())
+	log.Infof("Received %v checked block headers: Fetching blocks",
+		sm.headerList.Len())
+	sm.progressLogger.SetLastLogTime(time.Now())
+	sm.fetchHeaderBlocks()
+}
+
 // handleNotFoundMsg handles notfound messages from all peers.
 func (sm *SyncManager) handleNotFoundMsg(nfmsg *notFoundMsg) {
 	peer := nfmsg.peer
@@ -1028,6 +2200,7 @@ func (sm *SyncManager) handleNotFoundMsg(nfmsg *notFoundMsg) {
 		log.Warnf("Received notfound message from unknown peer %s", peer)
 		return
 	}
+	refetch, refetchSnapshot := false, false
 	for _, inv := range nfmsg.notFound.InvList {
 		// verify the hash was actually announced by the peer
 		// before deleting from the global requested maps.
@@ -1039,6 +2212,33 @@ func (sm *SyncManager) handleNotFoundMsg(nfmsg *notFoundMsg) {
 				delete(state.requestedBlocks, inv.Hash)
 				delete(sm.requestedBlocks, inv.Hash)
 			}
+			if p, exists := sm.fetchedBlocks[inv.Hash]; exists &&
+				p == peer {
+
+				log.Infof("Peer %s does not have block %v "+
+					"fetched on demand", peer, inv.Hash)
+				delete(sm.fetchedBlocks, inv.Hash)
+			}
+			if req, exists := sm.snapshotRequests[inv.Hash]; exists &&
+				req.peer == peer {
+
+				delete(sm.snapshotRequests, inv.Hash)
+				state.stalledUntil = time.Now().Add(blockStallBackoff)
+				refetchSnapshot = true
+			}
+
+			// Request the block from another peer in headers-first
+			// mode, and don't ask this one for more blocks for a
+			// while since it lacks some of them.
+			if req, exists := sm.blockRequests[inv.Hash]; exists &&
+				req.peer == peer {
+
+				delete(sm.blockRequests, inv.Hash)
+				sm.refetchHeaders = append(sm.refetchHeaders, req.node)
+				state.blocksReassigned++
+				state.stalledUntil = time.Now().Add(blockStallBackoff)
+				refetch = true
+			}
 
 		case wire.InvTypeWitnessTx:
 			fallthrough
@@ -1049,6 +2249,17 @@ func (sm *SyncManager) handleNotFoundMsg(nfmsg *notFoundMsg) {
 			}
 		}
 	}
+
+	if refetch {
+		sort.Slice(sm.refetchHeaders, func(i, j int) bool {
+			return sm.refetchHeaders[i].height <
+				sm.refetchHeaders[j].height
+		})
+		sm.fetchHeaderBlocks()
+	}
+	if refetchSnapshot {
+		sm.fetchSnapshotBlocks()
+	}
 }
 
 // haveInventory returns whether or not the inventory represented by the passed
@@ -1266,6 +2477,15 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 					iv.Type = wire.InvTypeWitnessBlock
 				}
 
+				// Request new blocks as compact blocks once
+				// the chain is current since most of their
+				// transactions are likely in the mempool.
+				if sm.current() && peer.CmpctBlockVersion() ==
+					wire.CmpctBlockVersion2 {
+
+					iv.Type = wire.InvTypeCmpctBlock
+				}
+
 				gdmsg.AddInvVect(iv)
 				numRequested++
 			}
@@ -1309,6 +2529,8 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 func (sm *SyncManager) blockHandler() {
 	stallTicker := time.NewTicker(stallSampleInterval)
 	defer stallTicker.Stop()
+	blockStallTicker := time.NewTicker(blockStallCheckInterval)
+	defer blockStallTicker.Stop()
 
 out:
 	for {
@@ -1326,6 +2548,14 @@ out:
 				sm.handleBlockMsg(msg)
 				msg.reply <- struct{}{}
 
+			case *cmpctBlockMsg:
+				sm.handleCmpctBlockMsg(msg)
+				msg.reply <- struct{}{}
+
+			case *blockTxnMsg:
+				sm.handleBlockTxnMsg(msg)
+				msg.reply <- struct{}{}
+
 			case *invMsg:
 				sm.handleInvMsg(msg)
 
@@ -1345,6 +2575,9 @@ out:
 				}
 				msg.reply <- peerID
 
+			case getPeerSyncStatsMsg:
+				msg.reply <- sm.peerSyncStats()
+
 			case processBlockMsg:
 				_, isOrphan, err := sm.chain.ProcessBlock(
 					msg.block, msg.flags)
@@ -1353,6 +2586,13 @@ out:
 						isOrphan: false,
 						err:      err,
 					}
+					continue
+				}
+
+				// Keep the orphan until its parents, which
+				// are requested from the peers, arrive.
+				if isOrphan {
+					sm.requestOrphanParents(msg.block.Hash())
 				}
 
 				msg.reply <- processBlockResponse{
@@ -1367,6 +2607,13 @@ out:
 				// Wait until the sender unpauses the manager.
 				<-msg.unpause
 
+			case resetSyncMsg:
+				sm.handleResetSyncMsg()
+
+			case fetchBlockMsg:
+				msg.reply <- sm.handleFetchBlockMsg(msg.hash,
+					msg.peer)
+
 			default:
 				log.Warnf("Invalid message type in block "+
 					"handler: %T", msg)
@@ -1375,15 +2622,65 @@ out:
 		case <-stallTicker.C:
 			sm.handleStallSample()
 
+		case <-blockStallTicker.C:
+			sm.handleBlockRequestStalls()
+			sm.handleSnapshotRequestStalls()
+
 		case <-sm.quit:
 			break out
 		}
 	}
 
+	// The block being processed when shutting down was completed above,
+	// but the messages left in the queue are not processed anymore, so
+	// release their senders.
+	sm.drainMsgs()
+
 	sm.wg.Done()
 	log.Trace("Block handler done")
 }
 
+// drainMsgs removes all the messages from the queue without handling them,
+// replying to those with a reply channel as if they were ignored.
+func (sm *SyncManager) drainMsgs() {
+	for {
+		select {
+		case m := <-sm.msgChan:
+			switch msg := m.(type) {
+			case *txMsg:
+				msg.reply <- struct{}{}
+
+			case *blockMsg:
+				msg.reply <- struct{}{}
+
+			case *cmpctBlockMsg:
+				msg.reply <- struct{}{}
+
+			case *blockTxnMsg:
+				msg.reply <- struct{}{}
+
+			case getSyncPeerMsg:
+				msg.reply <- 0
+
+			case getPeerSyncStatsMsg:
+				msg.reply <- nil
+
+			case processBlockMsg:
+				msg.reply <- processBlockResponse{err: ErrShutdown}
+
+			case isCurrentMsg:
+				msg.reply <- false
+
+			case fetchBlockMsg:
+				msg.reply <- ErrShutdown
+			}
+
+		default:
+			return
+		}
+	}
+}
+
 // handleBlockchainNotification handles notifications from blockchain.  It does
 // things such as request orphan block parents and relay accepted blocks to
 // connected peers.
@@ -1475,7 +2772,7 @@ func (sm *SyncManager) NewPeer(peer *peerpkg.Peer) {
 	if atomic.LoadInt32(&sm.shutdown) != 0 {
 		return
 	}
-	sm.msgChan <- &newPeerMsg{peer: peer}
+	sm.send(&newPeerMsg{peer: peer})
 }
 
 // QueueTx adds the passed transaction message and peer to the block handling
@@ -1488,7 +2785,9 @@ func (sm *SyncManager) QueueTx(tx *btcutil.Tx, peer *peerpkg.Peer, done chan str
 		return
 	}
 
-	sm.msgChan <- &txMsg{tx: tx, peer: peer, reply: done}
+	if !sm.send(&txMsg{tx: tx, peer: peer, reply: done}) {
+		done <- struct{}{}
+	}
 }
 
 // QueueBlock adds the passed block message and peer to the block handling
@@ -1501,7 +2800,39 @@ func (sm *SyncManager) QueueBlock(block *btcutil.Block, peer *peerpkg.Peer, done
 		return
 	}
 
-	sm.msgChan <- &blockMsg{block: block, peer: peer, reply: done}
+	if !sm.send(&blockMsg{block: block, peer: peer, reply: done}) {
+		done <- struct{}{}
+	}
+}
+
+// QueueCmpctBlock adds the passed cmpctblock message and peer to the block
+// handling queue. Responds to the done channel argument after the cmpctblock
+// message is processed.
+func (sm *SyncManager) QueueCmpctBlock(cmpctBlock *wire.MsgCmpctBlock, peer *peerpkg.Peer, done chan struct{}) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	if !sm.send(&cmpctBlockMsg{cmpctBlock: cmpctBlock, peer: peer, reply: done}) {
+		done <- struct{}{}
+	}
+}
+
+// QueueBlockTxn adds the passed blocktxn message and peer to the block
+// handling queue. Responds to the done channel argument after the blocktxn
+// message is processed.
+func (sm *SyncManager) QueueBlockTxn(blockTxn *wire.MsgBlockTxn, peer *peerpkg.Peer, done chan struct{}) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	if !sm.send(&blockTxnMsg{blockTxn: blockTxn, peer: peer, reply: done}) {
+		done <- struct{}{}
+	}
 }
 
 // QueueInv adds the passed inv message and peer to the block handling queue.
@@ -1512,7 +2843,7 @@ func (sm *SyncManager) QueueInv(inv *wire.MsgInv, peer *peerpkg.Peer) {
 		return
 	}
 
-	sm.msgChan <- &invMsg{inv: inv, peer: peer}
+	sm.send(&invMsg{inv: inv, peer: peer})
 }
 
 // QueueHeaders adds the passed headers message and peer to the block handling
@@ -1524,7 +2855,7 @@ func (sm *SyncManager) QueueHeaders(headers *wire.MsgHeaders, peer *peerpkg.Peer
 		return
 	}
 
-	sm.msgChan <- &headersMsg{headers: headers, peer: peer}
+	sm.send(&headersMsg{headers: headers, peer: peer})
 }
 
 // QueueNotFound adds the passed notfound message and peer to the block handling
@@ -1536,7 +2867,7 @@ func (sm *SyncManager) QueueNotFound(notFound *wire.MsgNotFound, peer *peerpkg.P
 		return
 	}
 
-	sm.msgChan <- &notFoundMsg{notFound: notFound, peer: peer}
+	sm.send(&notFoundMsg{notFound: notFound, peer: peer})
 }
 
 // DonePeer informs the blockmanager that a peer has disconnected.
@@ -1546,7 +2877,7 @@ func (sm *SyncManager) DonePeer(peer *peerpkg.Peer) {
 		return
 	}
 
-	sm.msgChan <- &donePeerMsg{peer: peer}
+	sm.send(&donePeerMsg{peer: peer})
 }
 
 // Start begins the core block handler which processes block and inv messages.
@@ -1573,31 +2904,107 @@ func (sm *SyncManager) Stop() error {
 	log.Infof("Sync manager shutting down")
 	close(sm.quit)
 	sm.wg.Wait()
+	close(sm.stopped)
 	return nil
 }
 
+// send queues the passed message for the block handler.  It returns false
+// without queuing the message when the sync manager is shutting down, so the
+// callers never block on a block handler which exited.
+func (sm *SyncManager) send(msg interface{}) bool {
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		return false
+	}
+
+	select {
+	case sm.msgChan <- msg:
+		return true
+	case <-sm.quit:
+		return false
+	}
+}
+
 // SyncPeerID returns the ID of the current sync peer, or 0 if there is none.
 func (sm *SyncManager) SyncPeerID() int32 {
-	reply := make(chan int32)
-	sm.msgChan <- getSyncPeerMsg{reply: reply}
-	return <-reply
+	reply := make(chan int32, 1)
+	if !sm.send(getSyncPeerMsg{reply: reply}) {
+		return 0
+	}
+	select {
+	case id := <-reply:
+		return id
+	case <-sm.stopped:
+		return 0
+	}
+}
+
+// peerSyncStats returns the block download statistics of the peers keyed by
+// their IDs.
+func (sm *SyncManager) peerSyncStats() map[int32]PeerSyncStats {
+	stats := make(map[int32]PeerSyncStats, len(sm.peerStates))
+	for peer, state := range sm.peerStates {
+		stats[peer.ID()] = PeerSyncStats{
+			BlocksInFlight:   len(state.requestedBlocks),
+			BlocksReceived:   state.blocksReceived,
+			BlocksReassigned: state.blocksReassigned,
+			BlockStalls:      state.blockStalls,
+		}
+	}
+	return stats
+}
+
+// PeerSyncStats returns the block download statistics of the peers known to
+// the sync manager keyed by their IDs.
+func (sm *SyncManager) PeerSyncStats() map[int32]PeerSyncStats {
+	reply := make(chan map[int32]PeerSyncStats, 1)
+	if !sm.send(getPeerSyncStatsMsg{reply: reply}) {
+		return nil
+	}
+	select {
+	case stats := <-reply:
+		return stats
+	case <-sm.stopped:
+		return nil
+	}
 }
 
 // ProcessBlock makes use of ProcessBlock on an internal instance of a block
-// chain.
+// chain.  ErrShutdown is returned when the block can't be processed because
+// the sync manager is shutting down.
 func (sm *SyncManager) ProcessBlock(block *btcutil.Block, flags blockchain.BehaviorFlags) (bool, error) {
 	reply := make(chan processBlockResponse, 1)
-	sm.msgChan <- processBlockMsg{block: block, flags: flags, reply: reply}
-	response := <-reply
-	return response.isOrphan, response.err
+	if !sm.send(processBlockMsg{block: block, flags: flags, reply: reply}) {
+		return false, ErrShutdown
+	}
+	select {
+	case response := <-reply:
+		return response.isOrphan, response.err
+	case <-sm.stopped:
+		return false, ErrShutdown
+	}
 }
 
 // IsCurrent returns whether or not the sync manager believes it is synced with
 // the connected peers.
 func (sm *SyncManager) IsCurrent() bool {
-	reply := make(chan bool)
-	sm.msgChan <- isCurrentMsg{reply: reply}
-	return <-reply
+	reply := make(chan bool, 1)
+	if !sm.send(isCurrentMsg{reply: reply}) {
+		return false
+	}
+	select {
+	case current := <-reply:
+		return current
+	case <-sm.stopped:
+		return false
+	}
+}
+
+// TimeRemaining returns an estimate of the time left to process the remaining
+// blocks of the chain, or zero when there is no estimate.
+//
+// This function is safe for concurrent access.
+func (sm *SyncManager) TimeRemaining() time.Duration {
+	return sm.progressLogger.TimeRemaining()
 }
 
 // Pause pauses the sync manager until the returned channel is closed.
@@ -1606,27 +3013,59 @@ func (sm *SyncManager) IsCurrent() bool {
 // message sender should avoid pausing the sync manager for long durations.
 func (sm *SyncManager) Pause() chan<- struct{} {
 	c := make(chan struct{})
-	sm.msgChan <- pauseMsg{c}
+	sm.send(pauseMsg{c})
 	return c
 }
 
+// FetchBlock requests the block of the passed hash from the passed peer.  It
+// returns once the request is sent, and the block is stored when it is
+// received if its data was pruned, or processed as usual otherwise.
+func (sm *SyncManager) FetchBlock(hash *chainhash.Hash, peer *peerpkg.Peer) error {
+	reply := make(chan error, 1)
+	if !sm.send(fetchBlockMsg{hash: hash, peer: peer, reply: reply}) {
+		return ErrShutdown
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-sm.stopped:
+		return ErrShutdown
+	}
+}
+
+// ResetSync restarts the sync from the current best block of the chain.  It
+// must be called after the chain state is replaced, such as when a utxo
+// snapshot is loaded, since the sync in progress is based on the previous
+// best block.
+func (sm *SyncManager) ResetSync() {
+	sm.send(resetSyncMsg{})
+}
+
 // New constructs a new SyncManager. Use Start to begin processing asynchronous
 // block, tx, and inv updates.
 func New(config *Config) (*SyncManager, error) {
 	sm := SyncManager{
-		peerNotifier:    config.PeerNotifier,
-		chain:           config.Chain,
-		txMemPool:       config.TxMemPool,
-		chainParams:     config.ChainParams,
-		rejectedTxns:    make(map[chainhash.Hash]struct{}),
-		requestedTxns:   make(map[chainhash.Hash]struct{}),
-		requestedBlocks: make(map[chainhash.Hash]struct{}),
-		peerStates:      make(map[*peerpkg.Peer]*peerSyncState),
-		progressLogger:  newBlockProgressLogger("Processed", log),
-		msgChan:         make(chan interface{}, config.MaxPeers*3),
-		headerList:      list.New(),
-		quit:            make(chan struct{}),
-		feeEstimator:    config.FeeEstimator,
+		peerNotifier:     config.PeerNotifier,
+		chain:            config.Chain,
+		txMemPool:        config.TxMemPool,
+		chainParams:      config.ChainParams,
+		rejectedTxns:     make(map[chainhash.Hash]struct{}),
+		requestedTxns:    make(map[chainhash.Hash]struct{}),
+		requestedBlocks:  make(map[chainhash.Hash]struct{}),
+		peerStates:       make(map[*peerpkg.Peer]*peerSyncState),
+		progressLogger:   newBlockProgressLogger("Processed", config.Chain, log),
+		msgChan:          make(chan interface{}, config.MaxPeers*3),
+		headerList:       list.New(),
+		blockRequests:    make(map[chainhash.Hash]*blockRequest),
+		pendingBlocks:    make(map[chainhash.Hash]*blockMsg),
+		quit:             make(chan struct{}),
+		stopped:          make(chan struct{}),
+		fetchedBlocks:    make(map[chainhash.Hash]*peerpkg.Peer),
+		feeEstimator:     config.FeeEstimator,
+		snapshotRequests: make(map[chainhash.Hash]*snapshotRequest),
+		snapshotBlocks:   make(map[chainhash.Hash]*blockMsg),
+		headerCheckSync:  config.HeaderCheckSync,
+		assumeValid:      config.AssumeValid,
 	}
 
 	best := sm.chain.BestSnapshot()
@@ -1639,6 +3078,9 @@ func New(config *Config) (*SyncManager, error) {
 	} else {
 		log.Info("Checkpoints are disabled")
 	}
+	if sm.headerCheckSync {
+		log.Info("Header-check sync is enabled")
+	}
 
 	sm.chain.Subscribe(sm.handleBlockchainNotification)
 
@@ -0,0 +1,727 @@
+package netsync
+
+import (
+	"container/list"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/connmgr"
+	"github.com/lbryio/lbcd/database"
+	_ "github.com/lbryio/lbcd/database/ffldb"
+	"github.com/lbryio/lbcd/fees"
+	"github.com/lbryio/lbcd/mempool"
+	peerpkg "github.com/lbryio/lbcd/peer"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// testPeerNotifier is a PeerNotifier which ignores all of the notifications.
+type testPeerNotifier struct{}
+
+func (testPeerNotifier) AnnounceNewTransactions([]*mempool.TxDesc) {}
+
+func (testPeerNotifier) UpdatePeerHeights(*chainhash.Hash, int32,
+	*peerpkg.Peer) {
+}
+
+func (testPeerNotifier) RelayInventory(*wire.InvVect, interface{}) {}
+
+func (testPeerNotifier) TransactionConfirmed(*btcutil.Tx) {}
+
+func (testPeerNotifier) PenalizePeer(*peerpkg.Peer, connmgr.Violation,
+	uint32, string) {
+}
+
+// testConn is a connection between two test peers with TCP addresses.
+type testConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+// RemoteAddr returns the remote address of the connection.
+func (c testConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// newSyncManagerHarness returns a sync manager on top of a new chain of the
+// regression test network which only contains the genesis block.
+func newSyncManagerHarness(t *testing.T) *SyncManager {
+	t.Helper()
+
+	params := chaincfg.RegressionNetParams
+	db, err := database.Create("ffldb", filepath.Join(t.TempDir(), "db"),
+		params.Net)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  blockchain.NewMedianTime(),
+	})
+	if err != nil {
+		t.Fatalf("unable to create chain: %v", err)
+	}
+	estimator, err := fees.NewEstimator(&fees.EstimatorConfig{
+		MaxConfirms:  fees.DefaultMaxConfirmations,
+		MinBucketFee: 1000,
+		MaxBucketFee: 100000,
+		FeeRateStep:  fees.DefaultFeeRateStep,
+	})
+	if err != nil {
+		t.Fatalf("unable to create fee estimator: %v", err)
+	}
+
+	sm, err := New(&Config{
+		PeerNotifier:       testPeerNotifier{},
+		Chain:              chain,
+		TxMemPool:          mempool.New(&mempool.Config{ChainParams: &params}),
+		ChainParams:        &params,
+		DisableCheckpoints: true,
+		MaxPeers:           8,
+		FeeEstimator:       estimator,
+	})
+	if err != nil {
+		t.Fatalf("unable to create sync manager: %v", err)
+	}
+	return sm
+}
+
+// generateBlocks returns the passed number of blocks extending the tip of the
+// chain of the sync manager without processing them.
+func generateBlocks(t *testing.T, sm *SyncManager, n int) []*btcutil.Block {
+	t.Helper()
+
+	best := sm.chain.BestSnapshot()
+	prevHeader, err := sm.chain.HeaderByHash(&best.Hash)
+	if err != nil {
+		t.Fatalf("unable to fetch tip header: %v", err)
+	}
+
+	blocks := make([]*btcutil.Block, 0, n)
+	for i := 0; i < n; i++ {
+		block := generateBlock(t, sm, &prevHeader, best.Height+int32(i)+1)
+		blocks = append(blocks, block)
+		prevHeader = block.MsgBlock().Header
+	}
+	return blocks
+}
+
+// generateBlock returns a block at the passed height extending the block of the
+// passed header a minute later, with a coinbase paying to an OP_TRUE script
+// followed by the passed transactions.
+func generateBlock(t *testing.T, sm *SyncManager, prevHeader *wire.BlockHeader,
+	height int32, txns ...*wire.MsgTx) *btcutil.Block {
+
+	t.Helper()
+
+	params := sm.chainParams
+	sigScript, err := txscript.NewScriptBuilder().
+		AddInt64(int64(height)).AddInt64(0).Script()
+	if err != nil {
+		t.Fatalf("unable to build coinbase script: %v", err)
+	}
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{},
+		wire.MaxPrevOutIndex), sigScript, nil))
+	coinbase.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(
+		height, params), []byte{txscript.OP_TRUE}))
+
+	msgBlock := wire.NewMsgBlock(&wire.BlockHeader{
+		Version:   4,
+		PrevBlock: prevHeader.BlockHash(),
+		Timestamp: prevHeader.Timestamp.Add(time.Minute),
+		Bits:      params.PowLimitBits,
+	})
+	txs := []*btcutil.Tx{btcutil.NewTx(coinbase)}
+	msgBlock.AddTransaction(coinbase)
+	for _, tx := range txns {
+		txs = append(txs, btcutil.NewTx(tx))
+		msgBlock.AddTransaction(tx)
+	}
+	merkles := blockchain.BuildMerkleTreeStore(txs, false)
+	msgBlock.Header.MerkleRoot = *merkles[len(merkles)-1]
+
+	target := blockchain.CompactToBig(params.PowLimitBits)
+	for {
+		hash := msgBlock.Header.BlockPoWHash()
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			break
+		}
+		msgBlock.Header.Nonce++
+	}
+
+	block := btcutil.NewBlock(msgBlock)
+	block.SetHeight(height)
+	return block
+}
+
+// addSyncCandidate returns a new peer connected to a remote peer at the passed
+// height, and adds it to the sync candidates of the sync manager.  The blocks
+// requested from the remote peer are sent to the getData channel.
+func addSyncCandidate(t *testing.T, sm *SyncManager, height int32,
+	getData chan<- *wire.MsgGetData) *peerpkg.Peer {
+
+	t.Helper()
+
+	verAck := make(chan struct{}, 2)
+	remoteCfg := &peerpkg.Config{
+		ChainParams:    sm.chainParams,
+		Services:       wire.SFNodeNetwork,
+		AllowSelfConns: true,
+		Listeners: peerpkg.MessageListeners{
+			OnGetData: func(p *peerpkg.Peer, msg *wire.MsgGetData) {
+				if getData != nil {
+					getData <- msg
+				}
+			},
+			OnVerAck: func(*peerpkg.Peer, *wire.MsgVerAck) {
+				verAck <- struct{}{}
+			},
+		},
+	}
+	localCfg := &peerpkg.Config{
+		ChainParams:    sm.chainParams,
+		Services:       wire.SFNodeNetwork,
+		AllowSelfConns: true,
+		Listeners: peerpkg.MessageListeners{
+			OnVerAck: func(*peerpkg.Peer, *wire.MsgVerAck) {
+				verAck <- struct{}{}
+			},
+		},
+	}
+
+	localConn, remoteConn := net.Pipe()
+	remote := peerpkg.NewInboundPeer(remoteCfg)
+	remote.AssociateConnection(testConn{Conn: remoteConn,
+		remote: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9246}})
+	local, err := peerpkg.NewOutboundPeer(localCfg, "10.0.0.2:9246")
+	if err != nil {
+		t.Fatalf("unable to create peer: %v", err)
+	}
+	local.AssociateConnection(testConn{Conn: localConn,
+		remote: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 9246}})
+	t.Cleanup(func() {
+		local.Disconnect()
+		remote.Disconnect()
+	})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-verAck:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the peers to connect")
+		}
+	}
+
+	local.UpdateLastBlockHeight(height)
+	sm.peerStates[local] = &peerSyncState{
+		syncCandidate:   true,
+		requestedTxns:   make(map[chainhash.Hash]struct{}),
+		requestedBlocks: make(map[chainhash.Hash]struct{}),
+	}
+	return local
+}
+
+// startHeadersFirst puts the sync manager in headers-first mode with the
+// headers of the passed blocks, the last of which is the next checkpoint.
+func startHeadersFirst(sm *SyncManager, blocks []*btcutil.Block) {
+	sm.headersFirstMode = true
+	sm.headerList = list.New()
+	for _, block := range blocks {
+		sm.headerList.PushBack(&headerNode{
+			height: block.Height(),
+			hash:   block.Hash(),
+		})
+	}
+	sm.startHeader = sm.headerList.Front()
+	last := blocks[len(blocks)-1]
+	sm.nextCheckpoint = &chaincfg.Checkpoint{
+		Height: last.Height(),
+		Hash:   last.Hash(),
+	}
+}
+
+// requestedFrom returns the number of blocks requested from each peer in
+// headers-first mode.
+func requestedFrom(sm *SyncManager) map[*peerpkg.Peer]int {
+	requested := make(map[*peerpkg.Peer]int)
+	for _, req := range sm.blockRequests {
+		requested[req.peer]++
+	}
+	return requested
+}
+
+// deliverBlocks passes the passed blocks to the sync manager as if they were
+// sent by the peers they were requested from.
+func deliverBlocks(t *testing.T, sm *SyncManager, blocks []*btcutil.Block) {
+	t.Helper()
+
+	for _, block := range blocks {
+		req, ok := sm.blockRequests[*block.Hash()]
+		if !ok {
+			t.Fatalf("block %d wasn't requested", block.Height())
+		}
+		sm.handleBlockMsg(&blockMsg{block: block, peer: req.peer})
+	}
+}
+
+// TestHeaderBlocksOutOfOrder ensures the blocks downloaded in parallel in
+// headers-first mode are requested from all of the sync candidates and are
+// connected in order regardless of the order they arrive in.
+func TestHeaderBlocksOutOfOrder(t *testing.T) {
+	sm := newSyncManagerHarness(t)
+	blocks := generateBlocks(t, sm, 20)
+	peer1 := addSyncCandidate(t, sm, 20, nil)
+	peer2 := addSyncCandidate(t, sm, 20, nil)
+
+	startHeadersFirst(sm, blocks)
+	sm.fetchHeaderBlocks()
+	if len(sm.blockRequests) != len(blocks) {
+		t.Fatalf("got %d block requests, want %d",
+			len(sm.blockRequests), len(blocks))
+	}
+	requested := requestedFrom(sm)
+	if requested[peer1] == 0 || requested[peer2] == 0 ||
+		requested[peer1] > maxBlocksInFlightPerPeer ||
+		requested[peer2] > maxBlocksInFlightPerPeer {
+
+		t.Fatalf("unexpected blocks requested from the peers: %d and %d",
+			requested[peer1], requested[peer2])
+	}
+
+	// The blocks following the next one to connect are held until it
+	// arrives.
+	reversed := make([]*btcutil.Block, 0, len(blocks)-1)
+	for i := len(blocks) - 1; i > 0; i-- {
+		reversed = append(reversed, blocks[i])
+	}
+	deliverBlocks(t, sm, reversed)
+	if height := sm.chain.BestSnapshot().Height; height != 0 {
+		t.Fatalf("got best height %d before the next block arrived, "+
+			"want 0", height)
+	}
+	if len(sm.pendingBlocks) != len(reversed) || sm.pendingBytes == 0 {
+		t.Fatalf("got %d pending blocks (%d bytes), want %d",
+			len(sm.pendingBlocks), sm.pendingBytes, len(reversed))
+	}
+
+	deliverBlocks(t, sm, blocks[:1])
+	if height := sm.chain.BestSnapshot().Height; height != 20 {
+		t.Fatalf("got best height %d, want 20", height)
+	}
+	if len(sm.pendingBlocks) != 0 || sm.pendingBytes != 0 {
+		t.Fatalf("got %d pending blocks (%d bytes) once connected",
+			len(sm.pendingBlocks), sm.pendingBytes)
+	}
+}
+
+// TestHeaderBlocksPendingBytes ensures only the next block to connect is
+// requested while the blocks received ahead of their turn exceed
+// maxPendingBlockBytes.
+func TestHeaderBlocksPendingBytes(t *testing.T) {
+	sm := newSyncManagerHarness(t)
+	blocks := generateBlocks(t, sm, 4)
+	addSyncCandidate(t, sm, 4, nil)
+
+	startHeadersFirst(sm, blocks)
+	sm.pendingBytes = maxPendingBlockBytes
+	sm.fetchHeaderBlocks()
+	if _, ok := sm.blockRequests[*blocks[0].Hash()]; !ok ||
+		len(sm.blockRequests) != 1 {
+
+		t.Fatalf("got %d block requests, want the next block only",
+			len(sm.blockRequests))
+	}
+
+	sm.pendingBytes = 0
+	sm.fetchHeaderBlocks()
+	if len(sm.blockRequests) != len(blocks) {
+		t.Fatalf("got %d block requests, want %d",
+			len(sm.blockRequests), len(blocks))
+	}
+}
+
+// TestHeaderBlocksPeerDisconnect ensures the blocks in flight from a peer
+// which disconnects in headers-first mode are requested from the other peers.
+func TestHeaderBlocksPeerDisconnect(t *testing.T) {
+	sm := newSyncManagerHarness(t)
+	blocks := generateBlocks(t, sm, 8)
+	peer1 := addSyncCandidate(t, sm, 8, nil)
+	getData := make(chan *wire.MsgGetData, 1)
+	peer2 := addSyncCandidate(t, sm, 8, getData)
+
+	startHeadersFirst(sm, blocks)
+	sm.fetchHeaderBlocks()
+	reassigned := requestedFrom(sm)[peer1]
+	if reassigned == 0 {
+		t.Fatal("no blocks requested from the first peer")
+	}
+	select {
+	case <-getData:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the blocks to be requested")
+	}
+
+	peer1.Disconnect()
+	sm.handleDonePeerMsg(peer1)
+	requested := requestedFrom(sm)
+	if requested[peer2] != len(blocks) {
+		t.Fatalf("got %d blocks requested from the remaining peer, "+
+			"want %d", requested[peer2], len(blocks))
+	}
+	select {
+	case msg := <-getData:
+		if len(msg.InvList) != reassigned {
+			t.Fatalf("got %d blocks requested again, want %d",
+				len(msg.InvList), reassigned)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the blocks to be requested again")
+	}
+
+	deliverBlocks(t, sm, blocks)
+	if height := sm.chain.BestSnapshot().Height; height != 8 {
+		t.Fatalf("got best height %d, want 8", height)
+	}
+}
+
+// TestHeaderBlocksStalled ensures the blocks requested from a peer which
+// doesn't deliver them in time are requested from the other peers, and that
+// the stalling peer isn't asked for more blocks for a while.
+func TestHeaderBlocksStalled(t *testing.T) {
+	sm := newSyncManagerHarness(t)
+	blocks := generateBlocks(t, sm, 8)
+	peer1 := addSyncCandidate(t, sm, 8, nil)
+	peer2 := addSyncCandidate(t, sm, 8, nil)
+
+	startHeadersFirst(sm, blocks)
+	sm.fetchHeaderBlocks()
+	var stalled []*btcutil.Block
+	for _, block := range blocks {
+		if sm.blockRequests[*block.Hash()].peer == peer1 {
+			stalled = append(stalled, block)
+		}
+	}
+	if len(stalled) == 0 {
+		t.Fatal("no blocks requested from the first peer")
+	}
+
+	past := time.Now().Add(-blockRequestTimeout - time.Second)
+	for _, req := range sm.blockRequests {
+		if req.peer == peer1 {
+			req.requested = past
+		}
+	}
+	sm.handleBlockRequestStalls()
+
+	requested := requestedFrom(sm)
+	if requested[peer1] != 0 || requested[peer2] != len(blocks) {
+		t.Fatalf("got %d and %d blocks requested from the peers after "+
+			"the stall, want 0 and %d", requested[peer1],
+			requested[peer2], len(blocks))
+	}
+	state := sm.peerStates[peer1]
+	if !time.Now().Before(state.stalledUntil) || state.blockStalls != 1 ||
+		state.blocksReassigned != uint64(len(stalled)) {
+
+		t.Fatalf("unexpected state of the stalling peer: %+v", state)
+	}
+	if !peer1.Connected() {
+		t.Fatal("peer disconnected after a single stall")
+	}
+
+	// The stalling peer is still allowed to deliver its blocks late, which
+	// are then no longer expected from the other peer.
+	for _, block := range stalled {
+		sm.handleBlockMsg(&blockMsg{block: block, peer: peer1})
+		if _, ok := sm.blockRequests[*block.Hash()]; ok {
+			t.Fatalf("block %d still expected after its late "+
+				"delivery", block.Height())
+		}
+	}
+	var remaining []*btcutil.Block
+	for _, block := range blocks {
+		if _, ok := sm.blockRequests[*block.Hash()]; ok {
+			remaining = append(remaining, block)
+		}
+	}
+	deliverBlocks(t, sm, remaining)
+	if height := sm.chain.BestSnapshot().Height; height != 8 {
+		t.Fatalf("got best height %d, want 8", height)
+	}
+}
+
+// TestHeaderBlocksPersistentStall ensures a peer which stalls the block download
+// maxConsecutiveBlockStalls times in a row is disconnected, that delivering a
+// block in time in between resets the count, and that the statistics of the
+// peer are reported.
+func TestHeaderBlocksPersistentStall(t *testing.T) {
+	sm := newSyncManagerHarness(t)
+	blocks := generateBlocks(t, sm, 20)
+	peer1 := addSyncCandidate(t, sm, 20, nil)
+	peer2 := addSyncCandidate(t, sm, 20, nil)
+	startHeadersFirst(sm, blocks)
+
+	// stall requests blocks from both peers again, and lets the ones of
+	// the first peer time out.  It returns the number of blocks which
+	// were requested from the first peer.
+	stall := func() int {
+		t.Helper()
+
+		sm.peerStates[peer1].stalledUntil = time.Time{}
+		sm.requeueBlockRequests(peer2)
+		sm.fetchHeaderBlocks()
+		requested := requestedFrom(sm)[peer1]
+		if requested == 0 {
+			t.Fatal("no blocks requested from the first peer")
+		}
+		past := time.Now().Add(-blockRequestTimeout - time.Second)
+		for _, req := range sm.blockRequests {
+			if req.peer == peer1 {
+				req.requested = past
+			}
+		}
+		sm.handleBlockRequestStalls()
+		return requested
+	}
+
+	state := sm.peerStates[peer1]
+	reassigned := stall()
+	if state.consecutiveStalls != 1 {
+		t.Fatalf("got %d consecutive stalls, want 1",
+			state.consecutiveStalls)
+	}
+
+	// Delivering a block in time resets the consecutive stalls.
+	sm.peerStates[peer1].stalledUntil = time.Time{}
+	sm.requeueBlockRequests(peer2)
+	sm.fetchHeaderBlocks()
+	var delivered *btcutil.Block
+	for _, block := range blocks {
+		req, ok := sm.blockRequests[*block.Hash()]
+		if ok && req.peer == peer1 {
+			delivered = block
+			break
+		}
+	}
+	if delivered == nil {
+		t.Fatal("no blocks requested from the first peer")
+	}
+	sm.handleBlockMsg(&blockMsg{block: delivered, peer: peer1})
+	if state.consecutiveStalls != 0 {
+		t.Fatalf("got %d consecutive stalls after a delivery, want 0",
+			state.consecutiveStalls)
+	}
+
+	for i := 1; i <= maxConsecutiveBlockStalls; i++ {
+		reassigned += stall()
+		if connected := peer1.Connected(); connected !=
+			(i < maxConsecutiveBlockStalls) {
+
+			t.Fatalf("stall %d: got peer connected %v", i, connected)
+		}
+	}
+	if requested := requestedFrom(sm); requested[peer1] != 0 {
+		t.Fatalf("got %d blocks still requested from the disconnected "+
+			"peer", requested[peer1])
+	}
+
+	stats := sm.peerSyncStats()[peer1.ID()]
+	want := PeerSyncStats{
+		BlocksInFlight:   len(state.requestedBlocks),
+		BlocksReceived:   1,
+		BlocksReassigned: uint64(reassigned),
+		BlockStalls:      maxConsecutiveBlockStalls + 1,
+	}
+	if stats != want {
+		t.Fatalf("got peer stats %+v, want %+v", stats, want)
+	}
+}
+
+// startHeaderCheck puts the sync manager in header-check sync mode with the
+// passed peer as the sync peer, as startSync does when the peer is ahead.
+func startHeaderCheck(t *testing.T, sm *SyncManager, peer *peerpkg.Peer) {
+	t.Helper()
+
+	best := sm.chain.BestSnapshot()
+	headerChain, err := sm.chain.NewHeaderChain(&best.Hash)
+	if err != nil {
+		t.Fatalf("unable to create header chain: %v", err)
+	}
+	sm.headerCheckSync = true
+	sm.resetHeaderState(&best.Hash, best.Height)
+	sm.headersFirstMode = true
+	sm.headersToTip = true
+	sm.headerChain = headerChain
+	sm.syncPeer = peer
+}
+
+// headersOf returns a headers message with the headers of the passed blocks.
+func headersOf(blocks []*btcutil.Block) *wire.MsgHeaders {
+	msg := wire.NewMsgHeaders()
+	for _, block := range blocks {
+		header := block.MsgBlock().Header
+		msg.AddBlockHeader(&header)
+	}
+	return msg
+}
+
+// TestHeaderBlockFlags ensures the blocks of the headers up to the latest
+// checkpoint are processed with BFFastAdd, and the following ones up to the
+// block assumed to be valid with BFAssumeValid.
+func TestHeaderBlockFlags(t *testing.T) {
+	params := chaincfg.RegressionNetParams
+	db, err := database.Create("ffldb", filepath.Join(t.TempDir(), "db"),
+		params.Net)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer db.Close()
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: &params,
+		Checkpoints: []chaincfg.Checkpoint{
+			{Height: 10, Hash: &chainhash.Hash{0x01}},
+		},
+		TimeSource: blockchain.NewMedianTime(),
+	})
+	if err != nil {
+		t.Fatalf("unable to create chain: %v", err)
+	}
+
+	sm := &SyncManager{chain: chain, assumeValidHeight: 20}
+	tests := []struct {
+		height int32
+		want   blockchain.BehaviorFlags
+	}{
+		{height: 1, want: blockchain.BFFastAdd},
+		{height: 10, want: blockchain.BFFastAdd},
+		{height: 11, want: blockchain.BFAssumeValid},
+		{height: 20, want: blockchain.BFAssumeValid},
+		{height: 21, want: blockchain.BFNone},
+	}
+	for _, test := range tests {
+		if got := sm.headerBlockFlags(test.height); got != test.want {
+			t.Errorf("headerBlockFlags(%d): got %v, want %v",
+				test.height, got, test.want)
+		}
+	}
+
+	// Without a block assumed to be valid, the blocks after the latest
+	// checkpoint are fully validated.
+	sm.assumeValidHeight = 0
+	if got := sm.headerBlockFlags(11); got != blockchain.BFNone {
+		t.Errorf("headerBlockFlags(11) without assumevalid: got %v, "+
+			"want %v", got, blockchain.BFNone)
+	}
+}
+
+// TestHeaderCheckAssumeValid ensures the scripts of the blocks up to the one
+// assumed to be valid aren't checked once its header is received in
+// header-check sync mode, while the scripts of the following blocks are.
+func TestHeaderCheckAssumeValid(t *testing.T) {
+	// The second block moves the coinbase output of the first one to an
+	// OP_0 script, and the third one spends it with an empty signature
+	// script, which fails the script checks.
+	sm := newSyncManagerHarness(t)
+	blocks := generateBlocks(t, sm, 1)
+	falseTx := wire.NewMsgTx(1)
+	falseTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{
+		Hash: *blocks[0].Transactions()[0].Hash(),
+	}, nil, nil))
+	falseTx.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(1,
+		sm.chainParams), []byte{txscript.OP_0}))
+	blocks = append(blocks, generateBlock(t, sm,
+		&blocks[0].MsgBlock().Header, 2, falseTx))
+	spendTx := wire.NewMsgTx(1)
+	spendTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: falseTx.TxHash()},
+		nil, nil))
+	spendTx.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(1,
+		sm.chainParams), []byte{txscript.OP_TRUE}))
+	blocks = append(blocks, generateBlock(t, sm,
+		&blocks[1].MsgBlock().Header, 3, spendTx))
+
+	tests := []struct {
+		name        string
+		assumeValid *btcutil.Block
+		wantHeight  int32
+	}{
+		{name: "assumevalid tip", assumeValid: blocks[2], wantHeight: 3},
+		{name: "assumevalid below", assumeValid: blocks[1],
+			wantHeight: 2},
+	}
+	for _, test := range tests {
+		sm := newSyncManagerHarness(t)
+		sm.chainParams.CoinbaseMaturity = 1
+		sm.assumeValid = test.assumeValid.Hash()
+		peer := addSyncCandidate(t, sm, 3, nil)
+
+		startHeaderCheck(t, sm, peer)
+		sm.handleHeadersMsg(&headersMsg{headers: headersOf(blocks),
+			peer: peer})
+		if sm.assumeValidHeight != test.assumeValid.Height() {
+			t.Fatalf("%s: got assumevalid height %d, want %d",
+				test.name, sm.assumeValidHeight,
+				test.assumeValid.Height())
+		}
+		if len(sm.blockRequests) != len(blocks) {
+			t.Fatalf("%s: got %d block requests, want %d",
+				test.name, len(sm.blockRequests), len(blocks))
+		}
+
+		deliverBlocks(t, sm, blocks)
+		height := sm.chain.BestSnapshot().Height
+		if height != test.wantHeight {
+			t.Errorf("%s: got best height %d, want %d", test.name,
+				height, test.wantHeight)
+		}
+	}
+}
+
+// TestHeaderCheckContext ensures the headers received in header-check sync mode
+// are checked in the context of the previous ones, and that the sync peer is
+// disconnected when they don't pass the checks.
+func TestHeaderCheckContext(t *testing.T) {
+	sm := newSyncManagerHarness(t)
+	blocks := generateBlocks(t, sm, 12)
+	peer := addSyncCandidate(t, sm, 13, nil)
+
+	// The last header has a valid proof of work but a timestamp before
+	// the median time of the previous blocks.
+	header := wire.BlockHeader{
+		Version:   4,
+		PrevBlock: *blocks[len(blocks)-1].Hash(),
+		Timestamp: blocks[0].MsgBlock().Header.Timestamp,
+		Bits:      sm.chainParams.PowLimitBits,
+	}
+	target := blockchain.CompactToBig(header.Bits)
+	for {
+		hash := header.BlockPoWHash()
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			break
+		}
+		header.Nonce++
+	}
+
+	startHeaderCheck(t, sm, peer)
+	msg := headersOf(blocks)
+	msg.AddBlockHeader(&header)
+	sm.handleHeadersMsg(&headersMsg{headers: msg, peer: peer})
+	if peer.Connected() {
+		t.Fatal("peer sending an invalid header wasn't disconnected")
+	}
+	if !sm.headersToTip || len(sm.blockRequests) != 0 {
+		t.Fatalf("invalid header was accepted -- checking headers %v, "+
+			"%d block requests", sm.headersToTip,
+			len(sm.blockRequests))
+	}
+}
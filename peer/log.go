@@ -207,6 +207,22 @@ func messageSummary(msg wire.Message) string {
 		return fmt.Sprintf("stop_hash=%v, num_filter_hashes=%d",
 			msg.StopHash, len(msg.FilterHashes))
 
+	case *wire.MsgSendCmpct:
+		return fmt.Sprintf("announce %v, ver %d", msg.Announce,
+			msg.Version)
+
+	case *wire.MsgCmpctBlock:
+		return fmt.Sprintf("hash %s, %d short ids, %d prefilled tx",
+			msg.BlockHash(), len(msg.ShortIDs), len(msg.PrefilledTxs))
+
+	case *wire.MsgGetBlockTxn:
+		return fmt.Sprintf("hash %s, %d indexes", msg.BlockHash,
+			len(msg.Indexes))
+
+	case *wire.MsgBlockTxn:
+		return fmt.Sprintf("hash %s, %d tx", msg.BlockHash,
+			len(msg.Transactions))
+
 	case *wire.MsgReject:
 		// Ensure the variable length strings don't contain any
 		// characters which are even remotely dangerous such as HTML
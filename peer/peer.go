@@ -14,6 +14,7 @@ import (
 	"math/rand"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,16 +30,21 @@ import (
 
 const (
 	// MaxProtocolVersion is the max protocol version the peer supports.
-	MaxProtocolVersion = wire.FeeFilterVersion
+	MaxProtocolVersion = wire.AddrV2Version
 
-	// DefaultTrickleInterval is the min time between attempts to send an
-	// inv message to a peer.
+	// DefaultTrickleInterval is the average time between attempts to send
+	// an inv message to an inbound peer.
 	DefaultTrickleInterval = 10 * time.Second
 
 	// MinAcceptableProtocolVersion is the lowest protocol version that a
 	// connected peer may support.
 	MinAcceptableProtocolVersion = wire.MultipleAddressVersion
 
+	// OtherMsgCommand is the command under which the bytes of the messages
+	// which couldn't be decoded are accounted for in the per message
+	// statistics.
+	OtherMsgCommand = "*other*"
+
 	// outputBufferSize is the number of elements the output channels use.
 	outputBufferSize = 50
 
@@ -102,6 +108,9 @@ type MessageListeners struct {
 	// OnAddr is invoked when a peer receives an addr bitcoin message.
 	OnAddr func(p *Peer, msg *wire.MsgAddr)
 
+	// OnAddrV2 is invoked when a peer receives an addrv2 bitcoin message.
+	OnAddrV2 func(p *Peer, msg *wire.MsgAddrV2)
+
 	// OnPing is invoked when a peer receives a ping bitcoin message.
 	OnPing func(p *Peer, msg *wire.MsgPing)
 
@@ -118,6 +127,7 @@ type MessageListeners struct {
 	OnTx func(p *Peer, msg *wire.MsgTx)
 
 	// OnBlock is invoked when a peer receives a block bitcoin message.
+	// The block is decoded while it is received, so buf is always nil.
 	OnBlock func(p *Peer, msg *wire.MsgBlock, buf []byte)
 
 	// OnCFilter is invoked when a peer receives a cfilter bitcoin message.
@@ -197,6 +207,22 @@ type MessageListeners struct {
 	// message.
 	OnSendHeaders func(p *Peer, msg *wire.MsgSendHeaders)
 
+	// OnSendCmpct is invoked when a peer receives a sendcmpct bitcoin
+	// message.
+	OnSendCmpct func(p *Peer, msg *wire.MsgSendCmpct)
+
+	// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin
+	// message.
+	OnCmpctBlock func(p *Peer, msg *wire.MsgCmpctBlock)
+
+	// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin
+	// message.
+	OnGetBlockTxn func(p *Peer, msg *wire.MsgGetBlockTxn)
+
+	// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin
+	// message.
+	OnBlockTxn func(p *Peer, msg *wire.MsgBlockTxn)
+
 	// OnRead is invoked when a peer receives a bitcoin message.  It
 	// consists of the number of bytes read, the message, and whether or not
 	// an error in the read occurred.  Typically, callers will opt to use
@@ -268,10 +294,18 @@ type Config struct {
 	// messages.
 	Listeners MessageListeners
 
-	// TrickleInterval is the duration of the ticker which trickles down the
-	// inventory to a peer.
+	// TrickleInterval is the average time between attempts to trickle down
+	// the inventory to an inbound peer.  Inventory is trickled twice as
+	// often to outbound peers.  The times are random, following a Poisson
+	// process, to make it harder to determine the origin of transactions.
 	TrickleInterval time.Duration
 
+	// InboundTrickleTimer, when set, schedules the times inventory is
+	// trickled to inbound peers instead of TrickleInterval.  It should be
+	// shared by all the inbound peers so they receive the inventory at the
+	// same times.
+	InboundTrickleTimer *TrickleTimer
+
 	// AllowSelfConns is only used to allow the tests to bypass the self
 	// connection detecting and disconnect logic since they intentionally
 	// do so for testing purposes.
@@ -370,23 +404,25 @@ type stallControlMsg struct {
 
 // StatsSnap is a snapshot of peer stats at a point in time.
 type StatsSnap struct {
-	ID             int32
-	Addr           string
-	Services       wire.ServiceFlag
-	LastSend       time.Time
-	LastRecv       time.Time
-	BytesSent      uint64
-	BytesRecv      uint64
-	ConnTime       time.Time
-	TimeOffset     int64
-	Version        uint32
-	UserAgent      string
-	Inbound        bool
-	StartingHeight int32
-	LastBlock      int32
-	LastPingNonce  uint64
-	LastPingTime   time.Time
-	LastPingMicros int64
+	ID              int32
+	Addr            string
+	Services        wire.ServiceFlag
+	LastSend        time.Time
+	LastRecv        time.Time
+	BytesSent       uint64
+	BytesRecv       uint64
+	BytesSentPerMsg map[string]uint64
+	BytesRecvPerMsg map[string]uint64
+	ConnTime        time.Time
+	TimeOffset      int64
+	Version         uint32
+	UserAgent       string
+	Inbound         bool
+	StartingHeight  int32
+	LastBlock       int32
+	LastPingNonce   uint64
+	LastPingTime    time.Time
+	LastPingMicros  int64
 }
 
 // HashFunc is a function which returns a block hash, height and error
@@ -446,6 +482,7 @@ type Peer struct {
 
 	flagsMtx             sync.Mutex // protects the peer flags below
 	na                   *wire.NetAddress
+	naV2                 *wire.NetAddressV2
 	id                   int32
 	userAgent            string
 	services             wire.ServiceFlag
@@ -453,6 +490,9 @@ type Peer struct {
 	advertisedProtoVer   uint32 // protocol version advertised by remote
 	protocolVersion      uint32 // negotiated protocol version
 	sendHeadersPreferred bool   // peer sent a sendheaders message
+	cmpctBlockVersion    uint64 // compact block version sent by peer
+	cmpctBlockAnnounce   bool   // peer wants cmpctblock announcements
+	wantsAddrV2          bool   // peer sent a sendaddrv2 message
 	verAckReceived       bool
 	witnessEnabled       bool
 
@@ -477,6 +517,8 @@ type Peer struct {
 	lastPingNonce      uint64    // Set to nonce if we have a pending ping.
 	lastPingTime       time.Time // Time we sent last ping.
 	lastPingMicros     int64     // Time for last ping to return.
+	bytesSentPerMsg    map[string]uint64
+	bytesRecvPerMsg    map[string]uint64
 
 	stallControl  chan stallControlMsg
 	outputQueue   chan outMsg
@@ -532,6 +574,14 @@ func (p *Peer) AddKnownInventory(invVect *wire.InvVect) {
 	p.knownInventory.Add(invVect)
 }
 
+// IsKnownInventory returns whether the passed inventory is in the cache of
+// known inventory for the peer.
+//
+// This function is safe for concurrent access.
+func (p *Peer) IsKnownInventory(invVect *wire.InvVect) bool {
+	return p.knownInventory.Contains(invVect)
+}
+
 // StatsSnapshot returns a snapshot of the current peer flags and statistics.
 //
 // This function is safe for concurrent access.
@@ -548,23 +598,25 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 
 	// Get a copy of all relevant flags and stats.
 	statsSnap := &StatsSnap{
-		ID:             id,
-		Addr:           addr,
-		UserAgent:      userAgent,
-		Services:       services,
-		LastSend:       p.LastSend(),
-		LastRecv:       p.LastRecv(),
-		BytesSent:      p.BytesSent(),
-		BytesRecv:      p.BytesReceived(),
-		ConnTime:       p.timeConnected,
-		TimeOffset:     p.timeOffset,
-		Version:        protocolVersion,
-		Inbound:        p.inbound,
-		StartingHeight: p.startingHeight,
-		LastBlock:      p.lastBlock,
-		LastPingNonce:  p.lastPingNonce,
-		LastPingMicros: p.lastPingMicros,
-		LastPingTime:   p.lastPingTime,
+		ID:              id,
+		Addr:            addr,
+		UserAgent:       userAgent,
+		Services:        services,
+		LastSend:        p.LastSend(),
+		LastRecv:        p.LastRecv(),
+		BytesSent:       p.BytesSent(),
+		BytesRecv:       p.BytesReceived(),
+		BytesSentPerMsg: copyMsgBytes(p.bytesSentPerMsg),
+		BytesRecvPerMsg: copyMsgBytes(p.bytesRecvPerMsg),
+		ConnTime:        p.timeConnected,
+		TimeOffset:      p.timeOffset,
+		Version:         protocolVersion,
+		Inbound:         p.inbound,
+		StartingHeight:  p.startingHeight,
+		LastBlock:       p.lastBlock,
+		LastPingNonce:   p.lastPingNonce,
+		LastPingMicros:  p.lastPingMicros,
+		LastPingTime:    p.lastPingTime,
 	}
 
 	p.statsMtx.RUnlock()
@@ -593,6 +645,24 @@ func (p *Peer) NA() *wire.NetAddress {
 	return na
 }
 
+// NAV2 returns the peer network address as a NetAddressV2, which unlike NA
+// is able to describe the address of a Tor v3 onion service peer.
+//
+// This function is safe for concurrent access.
+func (p *Peer) NAV2() *wire.NetAddressV2 {
+	p.flagsMtx.Lock()
+	na, naV2 := p.na, p.naV2
+	p.flagsMtx.Unlock()
+
+	if naV2 != nil {
+		return naV2
+	}
+	if na == nil {
+		return nil
+	}
+	return wire.NetAddressV2FromLegacy(na)
+}
+
 // Addr returns the peer address.
 //
 // This function is safe for concurrent access.
@@ -760,6 +830,27 @@ func (p *Peer) BytesReceived() uint64 {
 	return atomic.LoadUint64(&p.bytesReceived)
 }
 
+// copyMsgBytes returns a copy of the passed map of the number of bytes of the
+// messages of each command.
+func copyMsgBytes(msgBytes map[string]uint64) map[string]uint64 {
+	msgBytesCopy := make(map[string]uint64, len(msgBytes))
+	for command, n := range msgBytes {
+		msgBytesCopy[command] = n
+	}
+	return msgBytesCopy
+}
+
+// StatsCommand returns the command under which the bytes of the passed message
+// are accounted for in the per message statistics.  Messages which couldn't be
+// decoded, such as the ones with an unknown command, are accounted for under
+// OtherMsgCommand.
+func StatsCommand(msg wire.Message) string {
+	if msg == nil {
+		return OtherMsgCommand
+	}
+	return msg.Command()
+}
+
 // TimeConnected returns the time at which the peer connected.
 //
 // This function is safe for concurrent access.
@@ -808,6 +899,32 @@ func (p *Peer) WantsHeaders() bool {
 	return sendHeadersPreferred
 }
 
+// CmpctBlockVersion returns the highest compact block version supported by
+// both the peer and this package, or zero when the peer has not signalled
+// support for compact blocks with a sendcmpct message.
+//
+// This function is safe for concurrent access.
+func (p *Peer) CmpctBlockVersion() uint64 {
+	p.flagsMtx.Lock()
+	cmpctBlockVersion := p.cmpctBlockVersion
+	p.flagsMtx.Unlock()
+
+	return cmpctBlockVersion
+}
+
+// WantsCmpctBlocks returns if the peer wants new blocks to be announced with
+// cmpctblock messages instead of inventory vectors or headers (BIP0152
+// high-bandwidth mode).
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	wantsCmpctBlocks := p.cmpctBlockVersion != 0 && p.cmpctBlockAnnounce
+	p.flagsMtx.Unlock()
+
+	return wantsCmpctBlocks
+}
+
 // IsWitnessEnabled returns true if the peer has signalled that it supports
 // segregated witness.
 //
@@ -856,6 +973,58 @@ func (p *Peer) PushAddrMsg(addresses []*wire.NetAddress) ([]*wire.NetAddress, er
 	return msg.AddrList, nil
 }
 
+// WantsAddrV2 returns if the peer signaled support for addrv2 messages with a
+// sendaddrv2 message during the version negotiation.
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsAddrV2() bool {
+	p.flagsMtx.Lock()
+	wantsAddrV2 := p.wantsAddrV2
+	p.flagsMtx.Unlock()
+
+	return wantsAddrV2
+}
+
+// PushAddrV2Msg sends an addrv2 message to the connected peer using the
+// provided addresses.  Like PushAddrMsg, the addresses are randomized and
+// truncated when there are more than the maximum allowed.  It returns the
+// addresses which were sent, or an error when the peer didn't signal support
+// for addrv2 messages.
+//
+// This function is safe for concurrent access.
+func (p *Peer) PushAddrV2Msg(addresses []*wire.NetAddressV2) ([]*wire.NetAddressV2, error) {
+	if !p.WantsAddrV2() {
+		return nil, fmt.Errorf("peer %s doesn't support addrv2 messages",
+			p)
+	}
+
+	addressCount := len(addresses)
+
+	// Nothing to send.
+	if addressCount == 0 {
+		return nil, nil
+	}
+
+	msg := wire.NewMsgAddrV2()
+	msg.AddrList = make([]*wire.NetAddressV2, addressCount)
+	copy(msg.AddrList, addresses)
+
+	// Randomize the addresses sent if there are more than the maximum allowed.
+	if addressCount > wire.MaxAddrPerMsg {
+		// Shuffle the address list.
+		for i := 0; i < wire.MaxAddrPerMsg; i++ {
+			j := i + rand.Intn(addressCount-i)
+			msg.AddrList[i], msg.AddrList[j] = msg.AddrList[j], msg.AddrList[i]
+		}
+
+		// Truncate it to the maximum size.
+		msg.AddrList = msg.AddrList[:wire.MaxAddrPerMsg]
+	}
+
+	p.QueueMessage(msg, nil)
+	return msg.AddrList, nil
+}
+
 // PushGetBlocksMsg sends a getblocks message for the provided block locator
 // and stop hash.  It will ignore back-to-back duplicate requests.
 //
@@ -952,12 +1121,6 @@ func (p *Peer) PushGetHeadersMsg(locator blockchain.BlockLocator, stopHash *chai
 //
 // This function is safe for concurrent access.
 func (p *Peer) PushRejectMsg(command string, code wire.RejectCode, reason string, hash *chainhash.Hash, wait bool) {
-	// Don't bother sending the reject message if the protocol version
-	// is too low.
-	if p.VersionKnown() && p.ProtocolVersion() < wire.RejectVersion {
-		return
-	}
-
 	msg := wire.NewMsgReject(command, code, reason)
 	if command == wire.CmdTx || command == wire.CmdBlock {
 		if hash == nil {
@@ -1018,9 +1181,14 @@ func (p *Peer) handlePongMsg(msg *wire.MsgPong) {
 
 // readMessage reads the next bitcoin message from the peer with logging.
 func (p *Peer) readMessage(encoding wire.MessageEncoding) (wire.Message, []byte, error) {
-	n, msg, buf, err := wire.ReadMessageWithEncodingN(p.conn,
+	n, msg, buf, err := wire.ReadMessageStreamN(p.conn,
 		p.ProtocolVersion(), p.cfg.ChainParams.Net, encoding)
 	atomic.AddUint64(&p.bytesReceived, uint64(n))
+	if n > 0 {
+		p.statsMtx.Lock()
+		p.bytesRecvPerMsg[StatsCommand(msg)] += uint64(n)
+		p.statsMtx.Unlock()
+	}
 	if p.cfg.Listeners.OnRead != nil {
 		p.cfg.Listeners.OnRead(p, n, msg, err)
 	}
@@ -1084,6 +1252,11 @@ func (p *Peer) writeMessage(msg wire.Message, enc wire.MessageEncoding) error {
 	n, err := wire.WriteMessageWithEncodingN(p.conn, msg,
 		p.ProtocolVersion(), p.cfg.ChainParams.Net, enc)
 	atomic.AddUint64(&p.bytesSent, uint64(n))
+	if n > 0 {
+		p.statsMtx.Lock()
+		p.bytesSentPerMsg[StatsCommand(msg)] += uint64(n)
+		p.statsMtx.Unlock()
+	}
 	if p.cfg.Listeners.OnWrite != nil {
 		p.cfg.Listeners.OnWrite(p, n, msg, err)
 	}
@@ -1165,12 +1338,18 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msgCmd st
 		pendingResponses[wire.CmdInv] = deadline
 
 	case wire.CmdGetData:
-		// Expects a block, merkleblock, tx, or notfound message.
+		// Expects a block, cmpctblock, merkleblock, tx, or notfound
+		// message.
 		pendingResponses[wire.CmdBlock] = deadline
+		pendingResponses[wire.CmdCmpctBlock] = deadline
 		pendingResponses[wire.CmdMerkleBlock] = deadline
 		pendingResponses[wire.CmdTx] = deadline
 		pendingResponses[wire.CmdNotFound] = deadline
 
+	case wire.CmdGetBlockTxn:
+		// Expects a blocktxn message.
+		pendingResponses[wire.CmdBlockTxn] = deadline
+
 	case wire.CmdGetHeaders:
 		// Expects a headers message.  Use a longer deadline since it
 		// can take a while for the remote peer to load all of the
@@ -1228,12 +1407,15 @@ out:
 				switch msgCmd := msg.message.Command(); msgCmd {
 				case wire.CmdBlock:
 					fallthrough
+				case wire.CmdCmpctBlock:
+					fallthrough
 				case wire.CmdMerkleBlock:
 					fallthrough
 				case wire.CmdTx:
 					fallthrough
 				case wire.CmdNotFound:
 					delete(pendingResponses, wire.CmdBlock)
+					delete(pendingResponses, wire.CmdCmpctBlock)
 					delete(pendingResponses, wire.CmdMerkleBlock)
 					delete(pendingResponses, wire.CmdTx)
 					delete(pendingResponses, wire.CmdNotFound)
@@ -1414,6 +1596,17 @@ out:
 				p.cfg.Listeners.OnAddr(p, msg)
 			}
 
+		case *wire.MsgAddrV2:
+			if p.cfg.Listeners.OnAddrV2 != nil {
+				p.cfg.Listeners.OnAddrV2(p, msg)
+			}
+
+		case *wire.MsgSendAddrV2:
+			// The support for addrv2 messages must be signaled
+			// during the version negotiation.
+			log.Debugf("Ignoring sendaddrv2 message received after "+
+				"verack from %s", p)
+
 		case *wire.MsgPing:
 			p.handlePingMsg(msg)
 			if p.cfg.Listeners.OnPing != nil {
@@ -1540,6 +1733,42 @@ out:
 				p.cfg.Listeners.OnSendHeaders(p, msg)
 			}
 
+		case *wire.MsgSendCmpct:
+			// Peers may send a sendcmpct message for every version
+			// they support, so keep the highest one we support.
+			// The announcement preference may change at any time.
+			p.flagsMtx.Lock()
+			if msg.Version >= wire.CmpctBlockVersion1 &&
+				msg.Version <= wire.CmpctBlockVersion2 {
+
+				if msg.Version > p.cmpctBlockVersion {
+					p.cmpctBlockVersion = msg.Version
+				}
+				if msg.Version == p.cmpctBlockVersion {
+					p.cmpctBlockAnnounce = msg.Announce
+				}
+			}
+			p.flagsMtx.Unlock()
+
+			if p.cfg.Listeners.OnSendCmpct != nil {
+				p.cfg.Listeners.OnSendCmpct(p, msg)
+			}
+
+		case *wire.MsgCmpctBlock:
+			if p.cfg.Listeners.OnCmpctBlock != nil {
+				p.cfg.Listeners.OnCmpctBlock(p, msg)
+			}
+
+		case *wire.MsgGetBlockTxn:
+			if p.cfg.Listeners.OnGetBlockTxn != nil {
+				p.cfg.Listeners.OnGetBlockTxn(p, msg)
+			}
+
+		case *wire.MsgBlockTxn:
+			if p.cfg.Listeners.OnBlockTxn != nil {
+				p.cfg.Listeners.OnBlockTxn(p, msg)
+			}
+
 		default:
 			log.Debugf("Received unhandled message of type %v "+
 				"from %v", rmsg.Command(), p)
@@ -1560,6 +1789,20 @@ out:
 	log.Tracef("Peer input handler done for %s", p)
 }
 
+// nextTrickleDelay returns the random delay until inventory is trickled to the
+// peer next.  Inbound peers follow the shared schedule of the inbound trickle
+// timer when one is configured.
+func (p *Peer) nextTrickleDelay() time.Duration {
+	if !p.inbound {
+		return poissonDelay(p.cfg.TrickleInterval / outboundTrickleDivisor)
+	}
+	if p.cfg.InboundTrickleTimer != nil {
+		now := time.Now()
+		return p.cfg.InboundTrickleTimer.Next(now).Sub(now)
+	}
+	return poissonDelay(p.cfg.TrickleInterval)
+}
+
 // queueHandler handles the queuing of outgoing data for the peer. This runs as
 // a muxer for various sources of input so we can ensure that server and peer
 // handlers will not block on us sending a message.  That data is then passed on
@@ -1567,8 +1810,8 @@ out:
 func (p *Peer) queueHandler() {
 	pendingMsgs := list.New()
 	invSendQueue := list.New()
-	trickleTicker := time.NewTicker(p.cfg.TrickleInterval)
-	defer trickleTicker.Stop()
+	trickleTimer := time.NewTimer(p.nextTrickleDelay())
+	defer trickleTimer.Stop()
 
 	// We keep the waiting flag so that we know if we have a message queued
 	// to the outHandler or not.  We could use the presence of a head of
@@ -1629,7 +1872,9 @@ out:
 				}
 			}
 
-		case <-trickleTicker.C:
+		case <-trickleTimer.C:
+			trickleTimer.Reset(p.nextTrickleDelay())
+
 			// Don't send anything if we're disconnecting or there
 			// is no queued inventory.
 			// version is known if send queue has any entries.
@@ -1638,24 +1883,34 @@ out:
 				continue
 			}
 
-			// Create and send as many inv messages as needed to
-			// drain the inventory send queue.
-			invMsg := wire.NewMsgInvSizeHint(uint(invSendQueue.Len()))
+			// Drain the inventory send queue, skipping inventory
+			// that became known after the initial check.
+			invList := make([]*wire.InvVect, 0, invSendQueue.Len())
 			for e := invSendQueue.Front(); e != nil; e = invSendQueue.Front() {
 				iv := invSendQueue.Remove(e).(*wire.InvVect)
-
-				// Don't send inventory that became known after
-				// the initial check.
 				if p.knownInventory.Contains(iv) {
 					continue
 				}
+				invList = append(invList, iv)
+			}
 
+			// Shuffle the inventory so its order doesn't reveal
+			// the order it was received in.
+			rand.Shuffle(len(invList), func(i, j int) {
+				invList[i], invList[j] = invList[j], invList[i]
+			})
+
+			// Create and send as many inv messages as needed to
+			// send the inventory.
+			invMsg := wire.NewMsgInvSizeHint(uint(len(invList)))
+			for i, iv := range invList {
 				invMsg.AddInvVect(iv)
 				if len(invMsg.InvList) >= maxInvTrickleSize {
 					waiting = queuePacket(
 						outMsg{msg: invMsg},
 						pendingMsgs, waiting)
-					invMsg = wire.NewMsgInvSizeHint(uint(invSendQueue.Len()))
+					invMsg = wire.NewMsgInvSizeHint(
+						uint(len(invList) - i - 1))
 				}
 
 				// Add the inventory that is being relayed to
@@ -1841,9 +2096,39 @@ func (p *Peer) QueueMessageWithEncoding(msg wire.Message, doneChan chan<- struct
 		}
 		return
 	}
+
+	// Don't send messages the peer doesn't support given the negotiated
+	// protocol version and the services it advertised.
+	if !p.supportsMessage(msg) {
+		log.Debugf("Not sending %v to %s which does not support it",
+			msg.Command(), p)
+		if doneChan != nil {
+			go func() {
+				doneChan <- struct{}{}
+			}()
+		}
+		return
+	}
 	p.outputQueue <- outMsg{msg: msg, encoding: encoding, doneChan: doneChan}
 }
 
+// supportsMessage returns whether the passed message can be sent to the peer
+// according to the requirements it was registered with in the wire package.
+// All messages are assumed to be supported until the version of the peer is
+// known.
+//
+// This function is safe for concurrent access.
+func (p *Peer) supportsMessage(msg wire.Message) bool {
+	p.flagsMtx.Lock()
+	versionKnown := p.versionKnown
+	protocolVersion := p.protocolVersion
+	services := p.services
+	p.flagsMtx.Unlock()
+
+	return !versionKnown || wire.MessageSupported(msg.Command(),
+		protocolVersion, services)
+}
+
 // QueueInventory adds the passed inventory to the inventory send queue which
 // might not be sent right away, rather it is trickled to the peer in batches.
 // Inventory that the peer is already known to have is ignored.
@@ -1991,11 +2276,27 @@ func (p *Peer) readRemoteVersionMsg() error {
 // This method is to be used as part of the version negotiation upon a new
 // connection.
 func (p *Peer) readRemoteVerAckMsg() error {
-	// Read the next message from the wire.
+	// Read the next message from the wire, taking note of the support for
+	// addrv2 messages which is signaled before the verack message.
 	remoteMsg, _, err := p.readMessage(wire.LatestEncoding)
 	if err != nil {
 		return err
 	}
+	for {
+		if _, ok := remoteMsg.(*wire.MsgSendAddrV2); !ok {
+			break
+		}
+		if p.ProtocolVersion() >= wire.AddrV2Version {
+			p.flagsMtx.Lock()
+			p.wantsAddrV2 = true
+			p.flagsMtx.Unlock()
+		}
+
+		remoteMsg, _, err = p.readMessage(wire.LatestEncoding)
+		if err != nil {
+			return err
+		}
+	}
 
 	// It should be a verack message, otherwise send a reject message to the
 	// peer explaining why.
@@ -2092,14 +2393,26 @@ func (p *Peer) writeLocalVersionMsg() error {
 	return p.writeMessage(localVerMsg, wire.LatestEncoding)
 }
 
+// writeSendAddrV2Msg signals the support for addrv2 messages to the remote peer
+// when the negotiated protocol version allows it.
+func (p *Peer) writeSendAddrV2Msg() error {
+	msg := wire.NewMsgSendAddrV2()
+	if !p.supportsMessage(msg) {
+		return nil
+	}
+
+	return p.writeMessage(msg, wire.LatestEncoding)
+}
+
 // negotiateInboundProtocol performs the negotiation protocol for an inbound
 // peer. The events should occur in the following order, otherwise an error is
 // returned:
 //
 //  1. Remote peer sends their version.
 //  2. We send our version.
-//  3. We send our verack.
-//  4. Remote peer sends their verack.
+//  3. We send our sendaddrv2 if the protocol version supports it.
+//  4. We send our verack.
+//  5. Remote peer sends their verack, optionally preceded by sendaddrv2.
 func (p *Peer) negotiateInboundProtocol() error {
 	if err := p.readRemoteVersionMsg(); err != nil {
 		return err
@@ -2109,6 +2422,10 @@ func (p *Peer) negotiateInboundProtocol() error {
 		return err
 	}
 
+	if err := p.writeSendAddrV2Msg(); err != nil {
+		return err
+	}
+
 	err := p.writeMessage(wire.NewMsgVerAck(), wire.LatestEncoding)
 	if err != nil {
 		return err
@@ -2123,8 +2440,9 @@ func (p *Peer) negotiateInboundProtocol() error {
 //
 //  1. We send our version.
 //  2. Remote peer sends their version.
-//  3. Remote peer sends their verack.
-//  4. We send our verack.
+//  3. Remote peer sends their verack, optionally preceded by sendaddrv2.
+//  4. We send our sendaddrv2 if the protocol version supports it.
+//  5. We send our verack.
 func (p *Peer) negotiateOutboundProtocol() error {
 	if err := p.writeLocalVersionMsg(); err != nil {
 		return err
@@ -2138,6 +2456,10 @@ func (p *Peer) negotiateOutboundProtocol() error {
 		return err
 	}
 
+	if err := p.writeSendAddrV2Msg(); err != nil {
+		return err
+	}
+
 	return p.writeMessage(wire.NewMsgVerAck(), wire.LatestEncoding)
 }
 
@@ -2263,6 +2585,8 @@ func newPeerBase(origCfg *Config, inbound bool) *Peer {
 		cfg:             cfg, // Copy so caller can't mutate.
 		services:        cfg.Services,
 		protocolVersion: cfg.ProtocolVersion,
+		bytesSentPerMsg: make(map[string]uint64),
+		bytesRecvPerMsg: make(map[string]uint64),
 	}
 	return &p
 }
@@ -2288,7 +2612,17 @@ func NewOutboundPeer(cfg *Config, addr string) (*Peer, error) {
 		return nil, err
 	}
 
-	if cfg.HostToNetAddress != nil {
+	// Tor v3 onion service addresses can't be represented by a legacy
+	// address, so the peer uses an unroutable one like the peers connected
+	// through a proxy.
+	if strings.HasSuffix(host, ".onion") && len(host) > 22 {
+		na, err := wire.NewNetAddressTorV3(host, uint16(port), 0)
+		if err != nil {
+			return nil, err
+		}
+		p.naV2 = na
+		p.na = wire.NewNetAddressIPPort(net.IPv4zero, uint16(port), 0)
+	} else if cfg.HostToNetAddress != nil {
 		na, err := cfg.HostToNetAddress(host, uint16(port), 0)
 		if err != nil {
 			return nil, err
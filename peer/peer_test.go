@@ -9,6 +9,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -214,6 +215,23 @@ func testPeer(t *testing.T, p *peer.Peer, s peerStats) {
 		t.Errorf("testPeer: wrong LastRecv - got %v, want %v", p.LastRecv(), stats.LastRecv)
 		return
 	}
+
+	// The handshake consists of a version and a verack message in each
+	// direction.
+	wantPerMsg := map[string]uint64{
+		wire.CmdVersion: s.wantBytesSent - 24,
+		wire.CmdVerAck:  24,
+	}
+	if !reflect.DeepEqual(stats.BytesSentPerMsg, wantPerMsg) {
+		t.Errorf("testPeer: wrong BytesSentPerMsg - got %v, want %v",
+			stats.BytesSentPerMsg, wantPerMsg)
+		return
+	}
+	if !reflect.DeepEqual(stats.BytesRecvPerMsg, wantPerMsg) {
+		t.Errorf("testPeer: wrong BytesRecvPerMsg - got %v, want %v",
+			stats.BytesRecvPerMsg, wantPerMsg)
+		return
+	}
 }
 
 // TestPeerConnection tests connection between inbound and outbound peers.
@@ -447,12 +465,24 @@ func TestPeerListeners(t *testing.T) {
 			OnSendHeaders: func(p *peer.Peer, msg *wire.MsgSendHeaders) {
 				ok <- msg
 			},
+			OnSendCmpct: func(p *peer.Peer, msg *wire.MsgSendCmpct) {
+				ok <- msg
+			},
+			OnCmpctBlock: func(p *peer.Peer, msg *wire.MsgCmpctBlock) {
+				ok <- msg
+			},
+			OnGetBlockTxn: func(p *peer.Peer, msg *wire.MsgGetBlockTxn) {
+				ok <- msg
+			},
+			OnBlockTxn: func(p *peer.Peer, msg *wire.MsgBlockTxn) {
+				ok <- msg
+			},
 		},
 		UserAgentName:     "peer",
 		UserAgentVersion:  "1.0",
 		UserAgentComments: []string{"comment"},
 		ChainParams:       &chaincfg.MainNetParams,
-		Services:          wire.SFNodeBloom,
+		Services:          wire.SFNodeBloom | wire.SFNodeCF,
 		TrickleInterval:   time.Second * 10,
 		AllowSelfConns:    true,
 	}
@@ -597,6 +627,23 @@ func TestPeerListeners(t *testing.T) {
 			"OnSendHeaders",
 			wire.NewMsgSendHeaders(),
 		},
+		{
+			"OnSendCmpct",
+			wire.NewMsgSendCmpct(true, wire.CmpctBlockVersion2),
+		},
+		{
+			"OnCmpctBlock",
+			wire.NewMsgCmpctBlock(wire.NewMsgBlock(&wire.BlockHeader{}), 1,
+				wire.CmpctBlockVersion2),
+		},
+		{
+			"OnGetBlockTxn",
+			wire.NewMsgGetBlockTxn(&chainhash.Hash{}, []uint32{1}),
+		},
+		{
+			"OnBlockTxn",
+			wire.NewMsgBlockTxn(&chainhash.Hash{}),
+		},
 	}
 	t.Logf("Running %d tests", len(tests))
 	for _, test := range tests {
@@ -758,6 +805,39 @@ func TestOutboundPeer(t *testing.T) {
 	p2.Disconnect()
 }
 
+// TestOutboundPeerTorV3 ensures outbound peers to Tor v3 onion services are
+// created without resolving the host and report their address with NAV2.
+func TestOutboundPeerTorV3(t *testing.T) {
+	const host = "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion"
+	peerCfg := &peer.Config{
+		HostToNetAddress: func(string, uint16, wire.ServiceFlag) (*wire.NetAddress, error) {
+			return nil, errors.New("unexpected host lookup")
+		},
+		ChainParams: &chaincfg.MainNetParams,
+	}
+	p, err := peer.NewOutboundPeer(peerCfg, net.JoinHostPort(host, "9246"))
+	if err != nil {
+		t.Fatalf("NewOutboundPeer: unexpected err: %v", err)
+	}
+
+	if na := p.NAV2(); na.Network != wire.NetworkTorV3 || na.Host() != host ||
+		na.Port != 9246 {
+
+		t.Fatalf("NAV2: wrong address %v", na)
+	}
+	if na := p.NA(); !na.IP.IsUnspecified() {
+		t.Fatalf("NA: unexpected routable address %v", na.IP)
+	}
+
+	// Malformed Tor v3 addresses are rejected.
+	_, err = peer.NewOutboundPeer(peerCfg, net.JoinHostPort(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczae.onion",
+		"9246"))
+	if err == nil {
+		t.Fatal("NewOutboundPeer: unexpected success with a bad checksum")
+	}
+}
+
 // Tests that the node disconnects from peers with an unsupported protocol
 // version.
 func TestUnsupportedVersionPeer(t *testing.T) {
@@ -989,3 +1069,88 @@ func TestUpdateLastBlockHeight(t *testing.T) {
 			remotePeerHeight+1)
 	}
 }
+
+// TestUnsupportedMessages ensures messages queued for a peer which negotiated a
+// protocol version or advertised services not supporting them are not sent.
+func TestUnsupportedMessages(t *testing.T) {
+	verack := make(chan struct{}, 2)
+	received := make(chan wire.Message, 3)
+	peerCfg := peer.Config{
+		Listeners: peer.MessageListeners{
+			OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+				verack <- struct{}{}
+			},
+			OnFeeFilter: func(p *peer.Peer, msg *wire.MsgFeeFilter) {
+				received <- msg
+			},
+			OnFilterLoad: func(p *peer.Peer, msg *wire.MsgFilterLoad) {
+				received <- msg
+			},
+			OnSendHeaders: func(p *peer.Peer, msg *wire.MsgSendHeaders) {
+				received <- msg
+			},
+		},
+		UserAgentName:    "peer",
+		UserAgentVersion: "1.0",
+		ChainParams:      &chaincfg.MainNetParams,
+		Services:         0,
+		AllowSelfConns:   true,
+	}
+
+	// The remote peer only supports messages up to sendheaders and doesn't
+	// advertise bloom filtering support.
+	remotePeerCfg := peerCfg
+	remotePeerCfg.ProtocolVersion = wire.SendHeadersVersion
+	inConn, outConn := pipe(
+		&conn{laddr: "10.0.0.1:9108", raddr: "10.0.0.2:9108"},
+		&conn{laddr: "10.0.0.2:9108", raddr: "10.0.0.1:9108"},
+	)
+	localPeer, err := peer.NewOutboundPeer(&peerCfg, inConn.laddr)
+	if err != nil {
+		t.Fatalf("NewOutboundPeer: unexpected err: %v\n", err)
+	}
+	localPeer.AssociateConnection(outConn)
+	inPeer := peer.NewInboundPeer(&remotePeerCfg)
+	inPeer.AssociateConnection(inConn)
+
+	// Wait for the veracks from the initial protocol version negotiation.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-verack:
+		case <-time.After(time.Second):
+			t.Fatal("verack timeout")
+		}
+	}
+	if pver := localPeer.ProtocolVersion(); pver != wire.SendHeadersVersion {
+		t.Fatalf("wrong negotiated protocol version - got %d, want %d",
+			pver, wire.SendHeadersVersion)
+	}
+
+	// Queue the unsupported messages before a supported one, which must be
+	// the first one received since the queue is processed in order.
+	msgs := []wire.Message{
+		wire.NewMsgFeeFilter(1000),
+		wire.NewMsgFilterLoad(nil, 10, 0, wire.BloomUpdateNone),
+		wire.NewMsgSendHeaders(),
+	}
+	for _, msg := range msgs {
+		done := make(chan struct{})
+		localPeer.QueueMessage(msg, done)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("queue %v timeout", msg.Command())
+		}
+	}
+	select {
+	case msg := <-received:
+		if msg.Command() != wire.CmdSendHeaders {
+			t.Fatalf("unsupported message %v was sent", msg.Command())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sendheaders timeout")
+	}
+
+	localPeer.Disconnect()
+	inPeer.Disconnect()
+}
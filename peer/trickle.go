@@ -0,0 +1,59 @@
+package peer
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// outboundTrickleDivisor is the divisor of the trickle interval giving the
+// average interval between inventory trickles to outbound peers.  Outbound
+// peers are chosen by the local peer and therefore less likely to be spying on
+// the origin of transactions than inbound peers, so inventory is trickled to
+// them more often.
+const outboundTrickleDivisor = 2
+
+// poissonDelay returns a random delay following an exponential distribution
+// with the passed mean, so the times it separates form a Poisson process.
+// Unlike fixed delays, these don't let a remote peer learn when the local peer
+// received inventory by observing when it is relayed.
+func poissonDelay(mean time.Duration) time.Duration {
+	return time.Duration(rand.ExpFloat64() * float64(mean))
+}
+
+// TrickleTimer schedules the times inventory is trickled as a Poisson process
+// shared by a set of peers.  It is meant to be shared by all the inbound peers
+// so that an attacker making many inbound connections gets to see the
+// inventory relayed to all of them at the same times, instead of at times
+// independently drawn for each connection which would let it estimate when the
+// local peer received the inventory, and whether it originated it, by taking
+// the earliest of them.
+type TrickleTimer struct {
+	mtx  sync.Mutex
+	mean time.Duration
+	next time.Time
+}
+
+// NewTrickleTimer returns a new trickle timer scheduling trickles separated by
+// the passed mean interval on average.  A non-positive interval is replaced by
+// DefaultTrickleInterval.
+func NewTrickleTimer(mean time.Duration) *TrickleTimer {
+	if mean <= 0 {
+		mean = DefaultTrickleInterval
+	}
+	return &TrickleTimer{mean: mean}
+}
+
+// Next returns the time of the next trickle after the passed time, scheduling
+// a new one when the previous one has passed.
+//
+// This function is safe for concurrent access.
+func (t *TrickleTimer) Next(now time.Time) time.Time {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if !now.Before(t.next) {
+		t.next = now.Add(poissonDelay(t.mean))
+	}
+	return t.next
+}
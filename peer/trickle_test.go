@@ -0,0 +1,55 @@
+package peer
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPoissonDelay ensures the random trickle delays average to the requested
+// mean interval.
+func TestPoissonDelay(t *testing.T) {
+	const mean = 10 * time.Second
+	const samples = 10000
+
+	var total time.Duration
+	for i := 0; i < samples; i++ {
+		delay := poissonDelay(mean)
+		if delay < 0 {
+			t.Fatalf("negative delay %v", delay)
+		}
+		total += delay
+	}
+
+	// The standard deviation of the average of the samples is 1% of the
+	// mean, so a 5% tolerance makes the test practically deterministic.
+	average := total / samples
+	if average < mean*95/100 || average > mean*105/100 {
+		t.Fatalf("average delay %v is too far from %v", average, mean)
+	}
+}
+
+// TestTrickleTimer ensures the trickle timer returns the same next trickle time
+// to all the callers until it has passed.
+func TestTrickleTimer(t *testing.T) {
+	timer := NewTrickleTimer(time.Minute)
+	now := time.Now()
+	next := timer.Next(now)
+	if next.Before(now) {
+		t.Fatalf("next trickle %v before %v", next, now)
+	}
+
+	// Other callers get the same time until it has passed.
+	if got := timer.Next(now.Add(next.Sub(now) / 2)); !got.Equal(next) {
+		t.Fatalf("next trickle changed before it passed: got %v, "+
+			"want %v", got, next)
+	}
+
+	// A new time is scheduled once it has passed.
+	if got := timer.Next(next); got.Before(next) {
+		t.Fatalf("next trickle %v before the previous one %v", got,
+			next)
+	}
+	if got := timer.Next(next.Add(time.Hour)); !got.After(next) {
+		t.Fatalf("next trickle %v not rescheduled after %v", got, next)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+	"github.com/lbryio/lbcutil/bloom"
+)
+
+const (
+	// defaultMaxBloomFilterSize is the default maximum size in bytes of
+	// the bloom filter a peer may load.
+	defaultMaxBloomFilterSize = wire.MaxFilterLoadFilterSize
+
+	// defaultPeerBloomWork is the default number of KiB a peer may have
+	// hashed per second on average to match its bloom filter.
+	defaultPeerBloomWork = 32 << 10
+
+	// peerBloomWorkBurst is the number of seconds of bloom filter work a
+	// peer may use at once, such as when requesting a burst of merkle
+	// blocks.
+	peerBloomWorkBurst = 10
+)
+
+// peerBloomFilter is the BIP0037 bloom filter loaded by a peer.  It accounts
+// for the work spent matching transactions against the filter, which is the
+// number of bytes hashed by each of its hash functions, and limits the average
+// work per second so peers can't monopolize the CPU with expensive filters.
+type peerBloomFilter struct {
+	*bloom.Filter
+
+	// work is the total work spent matching the filter.  It must be used
+	// atomically.
+	work uint64
+
+	mtx       sync.Mutex
+	hashFuncs uint32
+	rate      float64
+	burst     float64
+	budget    tokenBucket
+}
+
+// newPeerBloomFilter returns an unloaded bloom filter allowing rate bytes per
+// second to be hashed on average to match transactions.
+func newPeerBloomFilter(rate float64) *peerBloomFilter {
+	return &peerBloomFilter{
+		Filter: bloom.LoadFilter(nil),
+		rate:   rate,
+		burst:  rate * peerBloomWorkBurst,
+		budget: tokenBucket{tokens: rate * peerBloomWorkBurst, last: time.Now()},
+	}
+}
+
+// Reload replaces the filter with the passed one.
+//
+// This function is safe for concurrent access.
+func (f *peerBloomFilter) Reload(msg *wire.MsgFilterLoad) {
+	f.mtx.Lock()
+	f.hashFuncs = msg.HashFuncs
+	f.mtx.Unlock()
+
+	f.Filter.Reload(msg)
+}
+
+// charge accounts for matching size bytes of data against the filter and
+// returns whether or not the average work of the filter is within its limit.
+//
+// This function is safe for concurrent access.
+func (f *peerBloomFilter) charge(size int) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	work := uint64(f.hashFuncs) * uint64(size)
+	atomic.AddUint64(&f.work, work)
+	return f.budget.take(float64(work), f.rate, f.burst, time.Now())
+}
+
+// Work returns the total work spent matching the filter in bytes hashed.
+//
+// This function is safe for concurrent access.
+func (f *peerBloomFilter) Work() uint64 {
+	return atomic.LoadUint64(&f.work)
+}
+
+// bloomWorkExceeded disconnects the peer for using more than its share of CPU
+// to match transactions against its bloom filter.
+func (sp *serverPeer) bloomWorkExceeded() {
+	peerLog.Debugf("%s exceeded the bloom filter work limit -- "+
+		"disconnecting", sp)
+	sp.Disconnect()
+}
+
+// matchTxAndUpdate returns whether or not the passed transaction matches the
+// bloom filter of the peer, updating the filter as requested by the peer.
+// Peers exceeding the work limit of their filter are disconnected and no longer
+// match any transaction.
+func (sp *serverPeer) matchTxAndUpdate(tx *btcutil.Tx) bool {
+	if !sp.filter.charge(tx.MsgTx().SerializeSize()) {
+		sp.bloomWorkExceeded()
+		return false
+	}
+	return sp.filter.MatchTxAndUpdate(tx)
+}
+
+// bloomListener is a listen address on which bloom filters are served.  A nil
+// ip matches all the local addresses.
+type bloomListener struct {
+	ip   net.IP
+	port string
+}
+
+// parseBloomListeners parses the passed listen addresses on which bloom filters
+// are served.  The addresses must have a port.
+func parseBloomListeners(addrs []string) ([]bloomListener, error) {
+	listeners := make([]bloomListener, 0, len(addrs))
+	for _, addr := range addrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ip := net.ParseIP(host)
+		if ip != nil && ip.IsUnspecified() {
+			ip = nil
+		}
+		listeners = append(listeners, bloomListener{ip: ip, port: port})
+	}
+	return listeners, nil
+}
+
+// inboundServices returns the services advertised to an inbound peer connected
+// to the passed local address.  Bloom filters are only advertised and served
+// on the configured bloom filter listeners, if any.
+func (s *server) inboundServices(localAddr net.Addr) wire.ServiceFlag {
+	if len(s.bloomListeners) == 0 {
+		return s.services
+	}
+
+	host, port, err := net.SplitHostPort(localAddr.String())
+	if err != nil {
+		return s.services
+	}
+	ip := net.ParseIP(host)
+	for _, l := range s.bloomListeners {
+		if l.port == port && (l.ip == nil || l.ip.Equal(ip)) {
+			return s.services | wire.SFNodeBloom
+		}
+	}
+	return s.services
+}
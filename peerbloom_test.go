@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/lbryio/lbcd/wire"
+)
+
+// TestPeerBloomFilterWork ensures the work spent matching a bloom filter is
+// accounted for by its number of hash functions and limited on average.
+func TestPeerBloomFilterWork(t *testing.T) {
+	f := newPeerBloomFilter(100)
+	f.Reload(wire.NewMsgFilterLoad([]byte{0xff}, 5, 0, wire.BloomUpdateNone))
+
+	// The whole burst of work is available at once, and no more.
+	if !f.charge(100 * peerBloomWorkBurst / 5) {
+		t.Fatalf("charge: burst rejected")
+	}
+	if f.charge(1000) {
+		t.Fatalf("charge: work allowed beyond burst")
+	}
+	want := uint64(100*peerBloomWorkBurst + 5000)
+	if work := f.Work(); work != want {
+		t.Fatalf("Work: got %d, want %d", work, want)
+	}
+}
+
+// TestInboundServices ensures bloom filters are only advertised to the inbound
+// peers of the bloom filter listeners when there are any.
+func TestInboundServices(t *testing.T) {
+	listeners, err := parseBloomListeners([]string{
+		"0.0.0.0:9246", "127.0.0.1:19246",
+	})
+	if err != nil {
+		t.Fatalf("parseBloomListeners: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		addr  string
+		bloom bool
+	}{
+		{"10.0.0.1:9246", true},
+		{"127.0.0.1:19246", true},
+		{"10.0.0.1:19246", false},
+		{"127.0.0.1:29246", false},
+	}
+	s := &server{services: wire.SFNodeNetwork, bloomListeners: listeners}
+	for _, test := range tests {
+		addr, err := net.ResolveTCPAddr("tcp", test.addr)
+		if err != nil {
+			t.Fatalf("ResolveTCPAddr: unexpected error: %v", err)
+		}
+		services := s.inboundServices(addr)
+		if bloom := services&wire.SFNodeBloom != 0; bloom != test.bloom {
+			t.Errorf("inboundServices(%s): got bloom %v, want %v",
+				test.addr, bloom, test.bloom)
+		}
+	}
+
+	// All peers are served bloom filters without bloom filter listeners.
+	s = &server{services: wire.SFNodeNetwork | wire.SFNodeBloom}
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1}
+	if s.inboundServices(addr)&wire.SFNodeBloom == 0 {
+		t.Errorf("inboundServices: bloom filters not advertised")
+	}
+}
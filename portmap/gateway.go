@@ -0,0 +1,92 @@
+package portmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// routeTableFile is the file the Linux kernel exposes its IPv4 routing table
+// in.
+const routeTableFile = "/proc/net/route"
+
+// rtfGateway is the flag of the routes of the routing table going through a
+// gateway.
+const rtfGateway = 0x2
+
+// errNoGateway is returned when the default gateway of the local host can't be
+// determined.
+var errNoGateway = errors.New("no default gateway found")
+
+// defaultGateway returns the IPv4 address of the default gateway of the local
+// host.  It is read from the routing table when it is available, which is only
+// the case on Linux, and otherwise guessed as the first address of the network
+// of the first private IPv4 address of the local host, which is the one used by
+// most home routers.
+func defaultGateway() (net.IP, error) {
+	if f, err := os.Open(routeTableFile); err == nil {
+		defer f.Close()
+		return parseRouteTable(f)
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP.To4()
+		if ip == nil || !ip.IsPrivate() {
+			continue
+		}
+		gateway := ip.Mask(ipNet.Mask)
+		gateway[3] |= 1
+		if gateway.Equal(ip) {
+			continue
+		}
+		return gateway, nil
+	}
+	return nil, errNoGateway
+}
+
+// parseRouteTable returns the gateway of the default route of the passed
+// routing table in the format of /proc/net/route.
+func parseRouteTable(r io.Reader) (net.IP, error) {
+	scanner := bufio.NewScanner(r)
+
+	// Skip the header line.
+	scanner.Scan()
+	for scanner.Scan() {
+		// The fields are the interface, destination, gateway and flags
+		// followed by others, with the addresses in hexadecimal in the
+		// byte order of the host, which is assumed to be little-endian.
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] != "00000000" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 16)
+		if err != nil || flags&rtfGateway == 0 {
+			continue
+		}
+		gateway, err := hex.DecodeString(fields[2])
+		if err != nil || len(gateway) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(gateway))
+		return ip, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errNoGateway
+}
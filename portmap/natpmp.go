@@ -0,0 +1,196 @@
+package portmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// natpmpPort is the port NAT-PMP gateways listen on.
+	natpmpPort = 5351
+
+	// natpmpVersion is the version of the NAT-PMP protocol.
+	natpmpVersion = 0
+
+	// natpmpOpExternalAddress and natpmpOpMapTCP are the opcodes of the
+	// requests for the external address of the gateway and for a TCP port
+	// mapping.  The opcodes of the responses have their high bit set.
+	natpmpOpExternalAddress = 0
+	natpmpOpMapTCP          = 2
+	natpmpOpResponse        = 128
+
+	// natpmpInitialTimeout is the duration to wait for the first response
+	// of the gateway, which is doubled on every retransmission of the
+	// request as recommended by RFC 6886.
+	natpmpInitialTimeout = 250 * time.Millisecond
+
+	// natpmpMaxTries is the maximum number of times a request is sent.  It
+	// is lower than the one of RFC 6886 so a gateway not supporting the
+	// protocol doesn't delay discovering one supporting UPnP for a minute.
+	natpmpMaxTries = 4
+)
+
+// natpmpResultCodes describes the result codes of the NAT-PMP responses as
+// defined by RFC 6886.
+var natpmpResultCodes = map[uint16]string{
+	1: "unsupported version",
+	2: "not authorized",
+	3: "network failure",
+	4: "out of resources",
+	5: "unsupported opcode",
+}
+
+// natpmpError describes a NAT-PMP response with an unsuccessful result code.
+type natpmpError uint16
+
+// Error satisfies the error interface and prints human-readable errors.
+func (e natpmpError) Error() string {
+	if desc, ok := natpmpResultCodes[uint16(e)]; ok {
+		return "NAT-PMP error: " + desc
+	}
+	return fmt.Sprintf("NAT-PMP error: unknown result code %d", uint16(e))
+}
+
+// natpmpNAT talks to a gateway with the NAT-PMP protocol defined by RFC 6886.
+type natpmpNAT struct {
+	gateway *net.UDPAddr
+}
+
+// Enforce natpmpNAT implements the NAT interface.
+var _ NAT = (*natpmpNAT)(nil)
+
+// NewNATPMP returns a NAT for the NAT-PMP gateway with the passed address.
+// The gateway isn't contacted until the NAT is used.
+func NewNATPMP(gateway net.IP) NAT {
+	return &natpmpNAT{
+		gateway: &net.UDPAddr{IP: gateway, Port: natpmpPort},
+	}
+}
+
+// DiscoverNATPMP returns a NAT for the default gateway of the local host once
+// it answered a NAT-PMP request.
+func DiscoverNATPMP() (NAT, error) {
+	gateway, err := defaultGateway()
+	if err != nil {
+		return nil, err
+	}
+	nat := NewNATPMP(gateway)
+	if _, err := nat.ExternalAddress(); err != nil {
+		return nil, err
+	}
+	return nat, nil
+}
+
+// request sends the passed request to the gateway, retransmitting it until a
+// response to it of at least the passed size is received, and returns the
+// response.  An error is returned when the result code of the response isn't
+// successful.
+func (n *natpmpNAT) request(req []byte, size int) ([]byte, error) {
+	// The connection only receives the datagrams of the gateway, so the
+	// responses of other hosts are ignored as required by RFC 6886.
+	conn, err := net.DialUDP("udp", nil, n.gateway)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	op := req[1] | natpmpOpResponse
+	buf := make([]byte, 16)
+	timeout := natpmpInitialTimeout
+	for i := 0; i < natpmpMaxTries; i++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+		for {
+			nr, err := conn.Read(buf)
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			// Ignore the responses to other requests.
+			if nr < 4 || buf[0] != natpmpVersion || buf[1] != op {
+				continue
+			}
+			if result := binary.BigEndian.Uint16(buf[2:4]); result != 0 {
+				return nil, natpmpError(result)
+			}
+			if nr < size {
+				return nil, errors.New("NAT-PMP response too short")
+			}
+			return buf[:nr], nil
+		}
+		timeout *= 2
+	}
+	return nil, fmt.Errorf("no NAT-PMP response from %s", n.gateway.IP)
+}
+
+// Protocol returns the name of the protocol used to talk to the gateway.
+//
+// This is part of the NAT interface implementation.
+func (n *natpmpNAT) Protocol() string {
+	return ProtocolNATPMP
+}
+
+// ExternalAddress returns the external address of the gateway.
+//
+// This is part of the NAT interface implementation.
+func (n *natpmpNAT) ExternalAddress() (net.IP, error) {
+	resp, err := n.request([]byte{natpmpVersion, natpmpOpExternalAddress}, 12)
+	if err != nil {
+		return nil, err
+	}
+	return net.IPv4(resp[8], resp[9], resp[10], resp[11]), nil
+}
+
+// mapTCP requests a mapping of the passed ports with the passed lifetime in
+// seconds, and returns the mapped external port and granted lifetime.
+func (n *natpmpNAT) mapTCP(externalPort, internalPort uint16,
+	lifetime uint32) (uint16, uint32, error) {
+
+	req := make([]byte, 12)
+	req[0] = natpmpVersion
+	req[1] = natpmpOpMapTCP
+	binary.BigEndian.PutUint16(req[4:6], internalPort)
+	binary.BigEndian.PutUint16(req[6:8], externalPort)
+	binary.BigEndian.PutUint32(req[8:12], lifetime)
+	resp, err := n.request(req, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	return binary.BigEndian.Uint16(resp[10:12]),
+		binary.BigEndian.Uint32(resp[12:16]), nil
+}
+
+// AddPortMapping maps the passed external port of the gateway to the passed
+// internal one.  The gateway may map another external port and grant another
+// lifetime, which are returned.  NAT-PMP mappings have no description.
+//
+// This is part of the NAT interface implementation.
+func (n *natpmpNAT) AddPortMapping(externalPort, internalPort uint16,
+	description string, lifetime time.Duration) (uint16, time.Duration, error) {
+
+	mappedPort, granted, err := n.mapTCP(externalPort, internalPort,
+		uint32(lifetime/time.Second))
+	if err != nil {
+		return 0, 0, err
+	}
+	return mappedPort, time.Duration(granted) * time.Second, nil
+}
+
+// DeletePortMapping removes the mapping of the passed internal port, which is
+// requested with a zero external port and lifetime as defined by RFC 6886.
+//
+// This is part of the NAT interface implementation.
+func (n *natpmpNAT) DeletePortMapping(externalPort, internalPort uint16) error {
+	_, _, err := n.mapTCP(0, internalPort, 0)
+	return err
+}
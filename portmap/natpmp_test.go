@@ -0,0 +1,124 @@
+package portmap
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNATPMP is a fake NAT-PMP gateway which maps the requested ports to the
+// next one and grants half the requested lifetimes.
+type fakeNATPMP struct {
+	conn *net.UDPConn
+
+	// result is the result code of the mapping responses.
+	result uint16
+}
+
+// serve answers the requests received by the fake gateway until its
+// connection is closed.  A response to another request is sent first to
+// ensure such responses are ignored.
+func (f *fakeNATPMP) serve() {
+	buf := make([]byte, 16)
+	for {
+		n, addr, err := f.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if n < 2 {
+			continue
+		}
+
+		var resp []byte
+		switch buf[1] {
+		case natpmpOpExternalAddress:
+			resp = make([]byte, 12)
+			copy(resp[8:], net.IPv4(203, 0, 113, 7).To4())
+
+		case natpmpOpMapTCP:
+			f.conn.WriteToUDP([]byte{0, 128, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4},
+				addr)
+			resp = make([]byte, 16)
+			binary.BigEndian.PutUint16(resp[2:4], f.result)
+			copy(resp[8:10], buf[4:6])
+			external := binary.BigEndian.Uint16(buf[6:8])
+			lifetime := binary.BigEndian.Uint32(buf[8:12])
+			if lifetime != 0 {
+				external++
+			}
+			binary.BigEndian.PutUint16(resp[10:12], external)
+			binary.BigEndian.PutUint32(resp[12:16], lifetime/2)
+		}
+		resp[1] = buf[1] | natpmpOpResponse
+		f.conn.WriteToUDP(resp, addr)
+	}
+}
+
+// TestNATPMP ensures the NAT-PMP requests are encoded as expected and their
+// responses decoded, including unsuccessful ones.
+func TestNATPMP(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: unexpected error: %v", err)
+	}
+	defer conn.Close()
+	gateway := &fakeNATPMP{conn: conn}
+	go gateway.serve()
+
+	nat := &natpmpNAT{gateway: conn.LocalAddr().(*net.UDPAddr)}
+	if nat.Protocol() != ProtocolNATPMP {
+		t.Fatalf("unexpected protocol %q", nat.Protocol())
+	}
+	ip, err := nat.ExternalAddress()
+	if err != nil {
+		t.Fatalf("ExternalAddress: unexpected error: %v", err)
+	}
+	if !ip.Equal(net.IPv4(203, 0, 113, 7)) {
+		t.Fatalf("ExternalAddress: got %v, want 203.0.113.7", ip)
+	}
+
+	port, lifetime, err := nat.AddPortMapping(9246, 9246, "", time.Hour)
+	if err != nil {
+		t.Fatalf("AddPortMapping: unexpected error: %v", err)
+	}
+	if port != 9247 || lifetime != 30*time.Minute {
+		t.Fatalf("AddPortMapping: got port %d and lifetime %v, want "+
+			"port 9247 and lifetime 30m0s", port, lifetime)
+	}
+	if err := nat.DeletePortMapping(port, 9246); err != nil {
+		t.Fatalf("DeletePortMapping: unexpected error: %v", err)
+	}
+
+	gateway.result = 2
+	_, _, err = nat.AddPortMapping(9246, 9246, "", time.Hour)
+	if err != natpmpError(2) {
+		t.Fatalf("AddPortMapping: got error %v, want %v", err,
+			natpmpError(2))
+	}
+}
+
+// TestParseRouteTable ensures the default gateway is found in routing tables
+// in the format of /proc/net/route.
+func TestParseRouteTable(t *testing.T) {
+	const header = "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\t" +
+		"Metric\tMask\t\tMTU\tWindow\tIRTT\n"
+	table := header +
+		"eth0\t0000A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n" +
+		"eth0\t00000000\t0101A8C0\t0003\t0\t0\t0\t00000000\t0\t0\t0\n"
+	gateway, err := parseRouteTable(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("parseRouteTable: unexpected error: %v", err)
+	}
+	if !gateway.Equal(net.IPv4(192, 168, 1, 1)) {
+		t.Fatalf("parseRouteTable: got %v, want 192.168.1.1", gateway)
+	}
+
+	table = header +
+		"eth0\t0000A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n"
+	if _, err := parseRouteTable(strings.NewReader(table)); err != errNoGateway {
+		t.Fatalf("parseRouteTable: got error %v, want %v", err,
+			errNoGateway)
+	}
+}
@@ -0,0 +1,369 @@
+/*
+Package portmap maps the listening port of the node on the NAT gateway of the
+local network so peers outside of it are able to connect.
+
+Both NAT-PMP, as defined by RFC 6886, and UPnP Internet Gateway Devices are
+supported.  The Manager discovers a gateway supporting either of them, maps the
+port and renews the lease of the mapping before it expires.  When the gateway
+stops answering, it is discovered again, so the mapping is recreated after the
+gateway restarts or is replaced.  The current status of the mapping is logged
+whenever it changes and can be queried at any time.
+*/
+package portmap
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// ProtocolNATPMP and ProtocolUPnP are the names of the protocols used
+	// to talk to gateways.
+	ProtocolNATPMP = "natpmp"
+	ProtocolUPnP   = "upnp"
+
+	// StateDiscovering, StateMapped and StateUnmapped are the states of the
+	// port mapping.  The port is unmapped when the last attempt to map it
+	// failed, which is retried periodically.
+	StateDiscovering = "discovering"
+	StateMapped      = "mapped"
+	StateUnmapped    = "unmapped"
+
+	// DefaultLifetime is the default lifetime of the port mappings.
+	DefaultLifetime = 20 * time.Minute
+
+	// retryInterval is the duration to wait before trying again to map the
+	// port after an attempt failed.
+	retryInterval = 5 * time.Minute
+
+	// minRenewInterval is the minimum duration to wait before renewing a
+	// mapping, in case the gateway grants a very short lifetime.
+	minRenewInterval = 30 * time.Second
+)
+
+// protocolNames maps the protocols used to talk to gateways to their names in
+// the log messages.
+var protocolNames = map[string]string{
+	ProtocolNATPMP: "NAT-PMP",
+	ProtocolUPnP:   "UPnP",
+}
+
+// NAT is a gateway able to map its external ports to the local host.
+type NAT interface {
+	// Protocol returns the name of the protocol used to talk to the
+	// gateway, which is one of the Protocol constants.
+	Protocol() string
+
+	// ExternalAddress returns the address of the gateway outside of the
+	// NAT.
+	ExternalAddress() (net.IP, error)
+
+	// AddPortMapping maps the passed external TCP port of the gateway to
+	// the passed internal port of the local host with the passed
+	// description for the passed lifetime.  The gateway may map another
+	// external port and grant another lifetime, which are returned.
+	AddPortMapping(externalPort, internalPort uint16, description string,
+		lifetime time.Duration) (uint16, time.Duration, error)
+
+	// DeletePortMapping removes the mapping of the passed external TCP
+	// port of the gateway to the passed internal port.
+	DeletePortMapping(externalPort, internalPort uint16) error
+}
+
+// Config is the configuration of a Manager.
+type Config struct {
+	// InternalPort is the local port to map.  The same external port is
+	// requested from the gateway.
+	InternalPort uint16
+
+	// Description describes the mapping on the gateways supporting it.
+	Description string
+
+	// Lifetime is the requested lifetime of the mapping.  It defaults to
+	// DefaultLifetime.
+	Lifetime time.Duration
+
+	// NATPMP and UPnP enable discovering gateways supporting the
+	// respective protocols.  NAT-PMP is tried first when both are enabled.
+	NATPMP bool
+	UPnP   bool
+
+	// DiscoverNATPMP and DiscoverUPnP discover a gateway supporting the
+	// respective protocols.  They default to the functions of this
+	// package of the same names.
+	DiscoverNATPMP func() (NAT, error)
+	DiscoverUPnP   func() (NAT, error)
+
+	// OnMapped is called with the external address and port of the mapping
+	// when it is established and whenever they change.
+	OnMapped func(ip net.IP, port uint16)
+}
+
+// Status describes the current state of the port mapping.
+type Status struct {
+	// State is one of the State constants.
+	State string
+
+	// Protocol is the protocol used to talk to the gateway, or empty when
+	// no gateway was found.
+	Protocol string
+
+	// ExternalIP and ExternalPort are the external address and port of the
+	// mapping when the port is mapped.
+	ExternalIP   net.IP
+	ExternalPort uint16
+
+	// InternalPort is the local port which is mapped.
+	InternalPort uint16
+
+	// Expires is when the lease of the mapping expires, which is renewed
+	// before then.
+	Expires time.Time
+
+	// Err is the error of the last attempt to map the port when it failed.
+	Err error
+}
+
+// Manager maps a local port on the gateway of the local network and renews
+// the mapping until it is stopped.
+type Manager struct {
+	started int32
+	stopped int32
+
+	cfg Config
+
+	// nat is the gateway the port is mapped on, and externalPort the
+	// external port requested from it.  They are only accessed by the
+	// goroutine of the manager.
+	nat          NAT
+	externalPort uint16
+
+	mtx    sync.RWMutex
+	status Status
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a new port mapping manager with the passed configuration.
+func New(cfg *Config) *Manager {
+	m := &Manager{
+		cfg:          *cfg,
+		externalPort: cfg.InternalPort,
+		status: Status{
+			State:        StateDiscovering,
+			InternalPort: cfg.InternalPort,
+		},
+		quit: make(chan struct{}),
+	}
+	if m.cfg.Lifetime == 0 {
+		m.cfg.Lifetime = DefaultLifetime
+	}
+	if m.cfg.DiscoverNATPMP == nil {
+		m.cfg.DiscoverNATPMP = DiscoverNATPMP
+	}
+	if m.cfg.DiscoverUPnP == nil {
+		m.cfg.DiscoverUPnP = DiscoverUPnP
+	}
+	return m
+}
+
+// Start begins mapping the port in the background.
+func (m *Manager) Start() {
+	if atomic.AddInt32(&m.started, 1) != 1 {
+		return
+	}
+
+	m.wg.Add(1)
+	go m.mapHandler()
+}
+
+// Stop stops renewing the mapping and removes it from the gateway.  It blocks
+// until the mapping is removed.
+func (m *Manager) Stop() {
+	if atomic.AddInt32(&m.stopped, 1) != 1 {
+		return
+	}
+
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Status returns the current status of the port mapping.
+//
+// This function is safe for concurrent access.
+func (m *Manager) Status() Status {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.status
+}
+
+// mapHandler maps the port right away and then renews the mapping before its
+// lease expires, until the manager is stopped.  It must be run as a goroutine.
+func (m *Manager) mapHandler() {
+	timer := time.NewTimer(0)
+out:
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(m.update())
+
+		case <-m.quit:
+			break out
+		}
+	}
+
+	timer.Stop()
+	m.unmap()
+	m.wg.Done()
+}
+
+// update maps the port, discovering a gateway first when needed, and returns
+// the duration to wait before updating the mapping again.
+func (m *Manager) update() time.Duration {
+	if m.nat == nil {
+		nat, err := m.discover()
+		if err != nil {
+			m.setUnmapped(err)
+			return retryInterval
+		}
+		m.nat = nat
+	}
+
+	port, lifetime, err := m.nat.AddPortMapping(m.externalPort,
+		m.cfg.InternalPort, m.cfg.Description, m.cfg.Lifetime)
+	var ip net.IP
+	if err == nil {
+		ip, err = m.nat.ExternalAddress()
+	}
+	if err != nil {
+		m.setUnmapped(err)
+
+		// The gateway may have been restarted or replaced, so it is
+		// discovered again on the next attempt.
+		m.nat = nil
+		return retryInterval
+	}
+
+	// Keep requesting the external port mapped by the gateway, as RFC
+	// 6886 recommends, so the external address doesn't change.
+	m.externalPort = port
+	m.setMapped(ip, port, lifetime)
+
+	renew := lifetime / 2
+	if renew < minRenewInterval {
+		renew = minRenewInterval
+	}
+	return renew
+}
+
+// discover returns the first gateway found supporting one of the enabled
+// protocols.
+func (m *Manager) discover() (NAT, error) {
+	var errs []string
+	if m.cfg.NATPMP {
+		nat, err := m.cfg.DiscoverNATPMP()
+		if err == nil {
+			return nat, nil
+		}
+		log.Debugf("NAT-PMP gateway discovery failed: %v", err)
+		errs = append(errs, "NAT-PMP: "+err.Error())
+	}
+	if m.cfg.UPnP {
+		nat, err := m.cfg.DiscoverUPnP()
+		if err == nil {
+			return nat, nil
+		}
+		log.Debugf("UPnP gateway discovery failed: %v", err)
+		errs = append(errs, "UPnP: "+err.Error())
+	}
+	return nil, errors.New("no gateway found (" + strings.Join(errs, ", ") + ")")
+}
+
+// setMapped updates the status of the mapping after it was established or
+// renewed with the passed external address and port and lifetime.
+func (m *Manager) setMapped(ip net.IP, port uint16, lifetime time.Duration) {
+	protocol := m.nat.Protocol()
+
+	m.mtx.Lock()
+	prev := m.status
+	m.status = Status{
+		State:        StateMapped,
+		Protocol:     protocol,
+		ExternalIP:   ip,
+		ExternalPort: port,
+		InternalPort: m.cfg.InternalPort,
+		Expires:      time.Now().Add(lifetime),
+	}
+	m.mtx.Unlock()
+
+	external := net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+	if prev.State == StateMapped && prev.Protocol == protocol &&
+		prev.ExternalIP.Equal(ip) && prev.ExternalPort == port {
+
+		log.Debugf("Renewed %s port mapping of %s for %v",
+			protocolNames[protocol], external, lifetime)
+		return
+	}
+
+	log.Infof("Mapped port %d to external address %s via %s",
+		m.cfg.InternalPort, external, protocolNames[protocol])
+	if m.cfg.OnMapped != nil {
+		m.cfg.OnMapped(ip, port)
+	}
+}
+
+// setUnmapped updates the status of the mapping after an attempt to establish
+// or renew it failed with the passed error.
+func (m *Manager) setUnmapped(err error) {
+	var protocol string
+	if m.nat != nil {
+		protocol = m.nat.Protocol()
+	}
+
+	m.mtx.Lock()
+	prev := m.status
+	m.status = Status{
+		State:        StateUnmapped,
+		Protocol:     protocol,
+		InternalPort: m.cfg.InternalPort,
+		Err:          err,
+	}
+	m.mtx.Unlock()
+
+	switch prev.State {
+	case StateMapped:
+		external := net.JoinHostPort(prev.ExternalIP.String(),
+			strconv.Itoa(int(prev.ExternalPort)))
+		log.Warnf("Lost %s port mapping of %s: %v",
+			protocolNames[prev.Protocol], external, err)
+
+	case StateDiscovering:
+		log.Infof("Unable to map port %d: %v", m.cfg.InternalPort, err)
+
+	default:
+		log.Debugf("Unable to map port %d: %v", m.cfg.InternalPort, err)
+	}
+}
+
+// unmap removes the mapping from the gateway when the port is mapped.
+func (m *Manager) unmap() {
+	status := m.Status()
+	if status.State != StateMapped || m.nat == nil {
+		return
+	}
+
+	err := m.nat.DeletePortMapping(status.ExternalPort, m.cfg.InternalPort)
+	if err != nil {
+		log.Warnf("Unable to remove %s port mapping: %v",
+			protocolNames[status.Protocol], err)
+		return
+	}
+	log.Debugf("Removed %s port mapping of port %d",
+		protocolNames[status.Protocol], m.cfg.InternalPort)
+}
@@ -0,0 +1,155 @@
+package portmap
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNAT is a fake gateway recording the mapped ports.
+type fakeNAT struct {
+	mtx      sync.Mutex
+	mappings map[uint16]uint16
+	err      error
+}
+
+func (n *fakeNAT) Protocol() string {
+	return ProtocolUPnP
+}
+
+func (n *fakeNAT) ExternalAddress() (net.IP, error) {
+	return net.IPv4(198, 51, 100, 1), nil
+}
+
+func (n *fakeNAT) AddPortMapping(externalPort, internalPort uint16,
+	description string, lifetime time.Duration) (uint16, time.Duration, error) {
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.err != nil {
+		return 0, 0, n.err
+	}
+	n.mappings[externalPort] = internalPort
+	return externalPort, lifetime, nil
+}
+
+func (n *fakeNAT) DeletePortMapping(externalPort, internalPort uint16) error {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	delete(n.mappings, externalPort)
+	return nil
+}
+
+// TestManagerUpdate ensures the manager discovers gateways with the enabled
+// protocols, maps the port and renews the mapping before it expires, and
+// discovers the gateway again after it stops answering.
+func TestManagerUpdate(t *testing.T) {
+	nat := &fakeNAT{mappings: make(map[uint16]uint16)}
+	var natpmpTries, upnpTries int
+	var mapped []uint16
+	m := New(&Config{
+		InternalPort: 9246,
+		Lifetime:     10 * time.Minute,
+		NATPMP:       true,
+		UPnP:         true,
+		DiscoverNATPMP: func() (NAT, error) {
+			natpmpTries++
+			return nil, errors.New("no response")
+		},
+		DiscoverUPnP: func() (NAT, error) {
+			upnpTries++
+			if upnpTries == 1 {
+				return nil, errors.New("no gateway")
+			}
+			return nat, nil
+		},
+		OnMapped: func(ip net.IP, port uint16) {
+			mapped = append(mapped, port)
+		},
+	})
+	if m.Status().State != StateDiscovering {
+		t.Fatalf("unexpected initial state %q", m.Status().State)
+	}
+
+	// No gateway is found on the first attempt, which is retried later.
+	if wait := m.update(); wait != retryInterval {
+		t.Fatalf("update: got wait %v, want %v", wait, retryInterval)
+	}
+	status := m.Status()
+	if status.State != StateUnmapped || status.Err == nil {
+		t.Fatalf("unexpected status %+v after failed discovery", status)
+	}
+
+	// The port is mapped once a gateway is found, and the mapping renewed
+	// at half its lifetime.
+	if wait := m.update(); wait != 5*time.Minute {
+		t.Fatalf("update: got wait %v, want 5m0s", wait)
+	}
+	status = m.Status()
+	if status.State != StateMapped || status.Protocol != ProtocolUPnP ||
+		status.ExternalPort != 9246 || status.Err != nil ||
+		!status.ExternalIP.Equal(net.IPv4(198, 51, 100, 1)) {
+
+		t.Fatalf("unexpected status %+v after mapping", status)
+	}
+	if natpmpTries != 2 || upnpTries != 2 {
+		t.Fatalf("unexpected discovery attempts: %d NAT-PMP and %d "+
+			"UPnP", natpmpTries, upnpTries)
+	}
+
+	// Renewing the mapping doesn't discover the gateway again nor report
+	// the mapping again.
+	m.update()
+	if upnpTries != 2 || len(mapped) != 1 {
+		t.Fatalf("unexpected %d discoveries and %d mappings after "+
+			"renewal", upnpTries, len(mapped))
+	}
+
+	// The gateway is discovered again once it stops answering.
+	nat.err = errors.New("connection refused")
+	if wait := m.update(); wait != retryInterval {
+		t.Fatalf("update: got wait %v, want %v", wait, retryInterval)
+	}
+	if m.Status().State != StateUnmapped || m.nat != nil {
+		t.Fatalf("unexpected status %+v after failed renewal",
+			m.Status())
+	}
+	nat.err = nil
+	m.update()
+	if upnpTries != 3 || len(mapped) != 2 {
+		t.Fatalf("unexpected %d discoveries and %d mappings after "+
+			"recovery", upnpTries, len(mapped))
+	}
+}
+
+// TestManagerStop ensures the mapping is removed from the gateway when the
+// manager is stopped.
+func TestManagerStop(t *testing.T) {
+	nat := &fakeNAT{mappings: make(map[uint16]uint16)}
+	mapped := make(chan uint16, 1)
+	m := New(&Config{
+		InternalPort: 9246,
+		NATPMP:       true,
+		DiscoverNATPMP: func() (NAT, error) {
+			return nat, nil
+		},
+		OnMapped: func(ip net.IP, port uint16) {
+			mapped <- port
+		},
+	})
+	m.Start()
+	select {
+	case <-mapped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("port not mapped")
+	}
+	m.Stop()
+
+	nat.mtx.Lock()
+	defer nat.mtx.Unlock()
+	if len(nat.mappings) != 0 {
+		t.Fatalf("mapping not removed: %v", nat.mappings)
+	}
+}
@@ -0,0 +1,498 @@
+package portmap
+
+// Upnp code taken from Taipei Torrent license is below:
+// Copyright (c) 2010 Jack Palevich. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//    * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Just enough UPnP to be able to forward ports
+//
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// upnpDiscoverTimeout is the maximum duration to wait for a gateway to
+	// answer a discovery request.
+	upnpDiscoverTimeout = 3 * time.Second
+
+	// upnpRequestTimeout is the maximum duration of the HTTP requests to
+	// the gateway.
+	upnpRequestTimeout = 10 * time.Second
+
+	// upnpOnlyPermanentLeases is the error code returned by gateways which
+	// don't support port mappings with a lease duration.
+	upnpOnlyPermanentLeases = 725
+)
+
+// upnpServiceTypes are the types of the services of a gateway which are able
+// to map ports, in order of preference.
+var upnpServiceTypes = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+// upnpClient is the HTTP client used to talk to UPnP gateways.
+var upnpClient = &http.Client{Timeout: upnpRequestTimeout}
+
+type upnpNAT struct {
+	serviceURL  string
+	serviceType string
+	ourIP       string
+}
+
+// Enforce upnpNAT implements the NAT interface.
+var _ NAT = (*upnpNAT)(nil)
+
+// DiscoverUPnP searches the local network for a UPnP gateway and returns a NAT
+// for it.
+func DiscoverUPnP() (NAT, error) {
+	ssdp, err := net.ResolveUDPAddr("udp4", "239.255.255.250:1900")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	socket := conn.(*net.UDPConn)
+	defer socket.Close()
+
+	err = socket.SetDeadline(time.Now().Add(upnpDiscoverTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	st := "ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n"
+	buf := bytes.NewBufferString(
+		"M-SEARCH * HTTP/1.1\r\n" +
+			"HOST: 239.255.255.250:1900\r\n" +
+			st +
+			"MAN: \"ssdp:discover\"\r\n" +
+			"MX: 2\r\n\r\n")
+	message := buf.Bytes()
+	answerBytes := make([]byte, 1024)
+	for i := 0; i < 3; i++ {
+		_, err = socket.WriteToUDP(message, ssdp)
+		if err != nil {
+			return nil, err
+		}
+		n, _, err := socket.ReadFromUDP(answerBytes)
+		if err != nil {
+			// All the attempts share the deadline of the socket,
+			// so there is no point in trying again once it is
+			// reached.
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			continue
+		}
+		answer := string(answerBytes[0:n])
+		if !strings.Contains(answer, "\r\n"+st) {
+			continue
+		}
+		// HTTP header field names are case-insensitive.
+		// http://www.w3.org/Protocols/rfc2616/rfc2616-sec4.html#sec4.2
+		locString := "\r\nlocation: "
+		locIndex := strings.Index(strings.ToLower(answer), locString)
+		if locIndex < 0 {
+			continue
+		}
+		loc := answer[locIndex+len(locString):]
+		endIndex := strings.Index(loc, "\r\n")
+		if endIndex < 0 {
+			continue
+		}
+		locURL := strings.TrimSpace(loc[0:endIndex])
+		serviceURL, serviceType, err := getServiceURL(locURL)
+		if err != nil {
+			return nil, err
+		}
+		serviceIP, err := getServiceIP(serviceURL)
+		if err != nil {
+			return nil, err
+		}
+		ourIP, err := getOurIP(serviceIP)
+		if err != nil {
+			return nil, err
+		}
+		return &upnpNAT{
+			serviceURL:  serviceURL,
+			serviceType: serviceType,
+			ourIP:       ourIP,
+		}, nil
+	}
+	return nil, errors.New("UPnP gateway discovery failed")
+}
+
+// service represents the Service type in an UPnP xml description.
+// Only the parts we care about are present and thus the xml may have more
+// fields than present in the structure.
+type service struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// deviceList represents the deviceList type in an UPnP xml description.
+// Only the parts we care about are present and thus the xml may have more
+// fields than present in the structure.
+type deviceList struct {
+	XMLName xml.Name `xml:"deviceList"`
+	Device  []device `xml:"device"`
+}
+
+// serviceList represents the serviceList type in an UPnP xml description.
+// Only the parts we care about are present and thus the xml may have more
+// fields than present in the structure.
+type serviceList struct {
+	XMLName xml.Name  `xml:"serviceList"`
+	Service []service `xml:"service"`
+}
+
+// device represents the device type in an UPnP xml description.
+// Only the parts we care about are present and thus the xml may have more
+// fields than present in the structure.
+type device struct {
+	XMLName     xml.Name    `xml:"device"`
+	DeviceType  string      `xml:"deviceType"`
+	DeviceList  deviceList  `xml:"deviceList"`
+	ServiceList serviceList `xml:"serviceList"`
+}
+
+// specVersion represents the specVersion in a UPnP xml description.
+// Only the parts we care about are present and thus the xml may have more
+// fields than present in the structure.
+type specVersion struct {
+	XMLName xml.Name `xml:"specVersion"`
+	Major   int      `xml:"major"`
+	Minor   int      `xml:"minor"`
+}
+
+// root represents the Root document for a UPnP xml description.
+// Only the parts we care about are present and thus the xml may have more
+// fields than present in the structure.
+type root struct {
+	XMLName     xml.Name `xml:"root"`
+	SpecVersion specVersion
+	Device      device
+}
+
+// getChildDevice searches the children of device for a device with one of the
+// given types, which are versions of the same device.
+func getChildDevice(d *device, deviceTypes ...string) *device {
+	for i := range d.DeviceList.Device {
+		for _, deviceType := range deviceTypes {
+			if d.DeviceList.Device[i].DeviceType == deviceType {
+				return &d.DeviceList.Device[i]
+			}
+		}
+	}
+	return nil
+}
+
+// getChildService searches the service list of device for a service with the
+// given type.
+func getChildService(d *device, serviceType string) *service {
+	for i := range d.ServiceList.Service {
+		if d.ServiceList.Service[i].ServiceType == serviceType {
+			return &d.ServiceList.Service[i]
+		}
+	}
+	return nil
+}
+
+// getServiceIP returns the host of the passed service URL.
+func getServiceIP(serviceURL string) (string, error) {
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return "", err
+	}
+	return u.Hostname(), nil
+}
+
+// getOurIP returns the local IP that is on the same subnet as the serviceIP.
+func getOurIP(serviceIP string) (string, error) {
+	_, serviceNet, err := net.ParseCIDR(serviceIP + "/24")
+	if err != nil {
+		return "", err
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err != nil {
+			continue
+		}
+		if serviceNet.Contains(ip) {
+			return ip.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no local address on the network of the UPnP "+
+		"gateway %s", serviceIP)
+}
+
+// getServiceURL parses the xml description at the given root url to find the
+// url and type of the service to be used for port forwarding.
+func getServiceURL(rootURL string) (string, string, error) {
+	r, err := upnpClient.Get(rootURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Body.Close()
+	if r.StatusCode >= 400 {
+		return "", "", errors.New(fmt.Sprint(r.StatusCode))
+	}
+	var root root
+	err = xml.NewDecoder(r.Body).Decode(&root)
+	if err != nil {
+		return "", "", err
+	}
+	a := &root.Device
+	if a.DeviceType != "urn:schemas-upnp-org:device:InternetGatewayDevice:1" &&
+		a.DeviceType != "urn:schemas-upnp-org:device:InternetGatewayDevice:2" {
+
+		return "", "", errors.New("no InternetGatewayDevice")
+	}
+	b := getChildDevice(a, "urn:schemas-upnp-org:device:WANDevice:1",
+		"urn:schemas-upnp-org:device:WANDevice:2")
+	if b == nil {
+		return "", "", errors.New("no WANDevice")
+	}
+	c := getChildDevice(b, "urn:schemas-upnp-org:device:WANConnectionDevice:1",
+		"urn:schemas-upnp-org:device:WANConnectionDevice:2")
+	if c == nil {
+		return "", "", errors.New("no WANConnectionDevice")
+	}
+	for _, serviceType := range upnpServiceTypes {
+		d := getChildService(c, serviceType)
+		if d == nil {
+			continue
+		}
+		return combineURL(rootURL, d.ControlURL), serviceType, nil
+	}
+	return "", "", errors.New("no WANIPConnection or WANPPPConnection")
+}
+
+// combineURL resolves subURL against rootURL.  Absolute control URLs are
+// returned as is.
+func combineURL(rootURL, subURL string) string {
+	base, err := url.Parse(rootURL)
+	if err != nil {
+		return subURL
+	}
+	ref, err := url.Parse(subURL)
+	if err != nil {
+		return subURL
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// soapBody represents the <s:Body> element in a SOAP reply.
+// fields we don't care about are elided.
+type soapBody struct {
+	XMLName xml.Name `xml:"Body"`
+	Data    []byte   `xml:",innerxml"`
+}
+
+// soapEnvelope represents the <s:Envelope> element in a SOAP reply.
+// fields we don't care about are elided.
+type soapEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Body    soapBody `xml:"Body"`
+}
+
+// upnpError describes an error returned by a UPnP gateway in a SOAP fault.
+type upnpError struct {
+	Function    string `xml:"-"`
+	Code        int    `xml:"Body>Fault>detail>UPnPError>errorCode"`
+	Description string `xml:"Body>Fault>detail>UPnPError>errorDescription"`
+}
+
+// Error satisfies the error interface and prints human-readable errors.
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("UPnP error %d for %s: %s", e.Code, e.Function,
+		e.Description)
+}
+
+// soapRequest performs a soap request with the given parameters and returns
+// the xml replied stripped of the soap headers. in the case that the request is
+// unsuccessful the an error is returned, which is an upnpError when the
+// gateway describes it.
+func soapRequest(url, serviceType, function, message string) ([]byte, error) {
+	fullMessage := "<?xml version=\"1.0\" ?>" +
+		"<s:Envelope xmlns:s=\"http://schemas.xmlsoap.org/soap/envelope/\" s:encodingStyle=\"http://schemas.xmlsoap.org/soap/encoding/\">\r\n" +
+		"<s:Body>" + message + "</s:Body></s:Envelope>"
+
+	req, err := http.NewRequest("POST", url, strings.NewReader(fullMessage))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml ; charset=\"utf-8\"")
+	req.Header.Set("User-Agent", "Darwin/10.0.0, UPnP/1.0, MiniUPnPc/1.3")
+	req.Header.Set("SOAPAction", "\""+serviceType+"#"+function+"\"")
+	req.Header.Set("Connection", "Close")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Pragma", "no-cache")
+
+	r, err := upnpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode >= 400 {
+		upnpErr := &upnpError{Function: function}
+		if xml.Unmarshal(body, upnpErr) == nil && upnpErr.Code != 0 {
+			return nil, upnpErr
+		}
+		return nil, errors.New("Error " + strconv.Itoa(r.StatusCode) +
+			" for " + function)
+	}
+	var reply soapEnvelope
+	err = xml.Unmarshal(body, &reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply.Body.Data, nil
+}
+
+// getExternalIPAddressResponse represents the XML response to a
+// GetExternalIPAddress SOAP request.
+type getExternalIPAddressResponse struct {
+	XMLName           xml.Name `xml:"GetExternalIPAddressResponse"`
+	ExternalIPAddress string   `xml:"NewExternalIPAddress"`
+}
+
+// Protocol returns the name of the protocol used to talk to the gateway.
+//
+// This is part of the NAT interface implementation.
+func (n *upnpNAT) Protocol() string {
+	return ProtocolUPnP
+}
+
+// ExternalAddress fetches the external IP from the UPnP gateway.
+//
+// This is part of the NAT interface implementation.
+func (n *upnpNAT) ExternalAddress() (net.IP, error) {
+	message := "<u:GetExternalIPAddress xmlns:u=\"" + n.serviceType + "\"/>\r\n"
+	response, err := soapRequest(n.serviceURL, n.serviceType,
+		"GetExternalIPAddress", message)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply getExternalIPAddressResponse
+	err = xml.Unmarshal(response, &reply)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := net.ParseIP(reply.ExternalIPAddress)
+	if addr == nil {
+		return nil, errors.New("unable to parse ip address")
+	}
+	return addr, nil
+}
+
+// AddPortMapping sets up a port forwarding from the UPnP gateway to the local
+// machine with the given ports.  Gateways which only support permanent port
+// mappings are asked for one instead, which is renewed the same way.
+//
+// This is part of the NAT interface implementation.
+func (n *upnpNAT) AddPortMapping(externalPort, internalPort uint16,
+	description string, lifetime time.Duration) (uint16, time.Duration, error) {
+
+	err := n.addPortMapping(externalPort, internalPort, description,
+		int(lifetime/time.Second))
+	if upnpErr, ok := err.(*upnpError); ok &&
+		upnpErr.Code == upnpOnlyPermanentLeases {
+
+		err = n.addPortMapping(externalPort, internalPort, description, 0)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The gateway either maps the requested port or returns an error, so
+	// there is no mapped port to look up.
+	return externalPort, lifetime, nil
+}
+
+// addPortMapping sends an AddPortMapping request for the given ports with a
+// lease duration of the given number of seconds.
+func (n *upnpNAT) addPortMapping(externalPort, internalPort uint16,
+	description string, leaseDuration int) error {
+
+	message := "<u:AddPortMapping xmlns:u=\"" + n.serviceType + "\">\r\n" +
+		"<NewRemoteHost></NewRemoteHost><NewExternalPort>" +
+		strconv.Itoa(int(externalPort)) + "</NewExternalPort>" +
+		"<NewProtocol>TCP</NewProtocol>" +
+		"<NewInternalPort>" + strconv.Itoa(int(internalPort)) +
+		"</NewInternalPort><NewInternalClient>" + n.ourIP +
+		"</NewInternalClient><NewEnabled>1</NewEnabled>" +
+		"<NewPortMappingDescription>" + description +
+		"</NewPortMappingDescription><NewLeaseDuration>" +
+		strconv.Itoa(leaseDuration) + "</NewLeaseDuration>" +
+		"</u:AddPortMapping>"
+
+	_, err := soapRequest(n.serviceURL, n.serviceType, "AddPortMapping",
+		message)
+	return err
+}
+
+// DeletePortMapping removes a port forwarding from the UPnP gateway to the
+// local machine with the given ports.
+//
+// This is part of the NAT interface implementation.
+func (n *upnpNAT) DeletePortMapping(externalPort, internalPort uint16) error {
+	message := "<u:DeletePortMapping xmlns:u=\"" + n.serviceType + "\">\r\n" +
+		"<NewRemoteHost></NewRemoteHost><NewExternalPort>" +
+		strconv.Itoa(int(externalPort)) + "</NewExternalPort>" +
+		"<NewProtocol>TCP</NewProtocol></u:DeletePortMapping>"
+
+	_, err := soapRequest(n.serviceURL, n.serviceType, "DeletePortMapping",
+		message)
+	return err
+}
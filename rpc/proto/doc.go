@@ -0,0 +1,16 @@
+/*
+Package lbcdrpc contains the protobuf definitions and the generated code of
+the gRPC interface of lbcd.
+
+The gRPC server is an optional alternative to the JSON-RPC server for clients
+which prefer typed messages and streaming.  It is enabled with the --grpclisten
+option and shares the credentials and TLS settings of the RPC server, which
+clients pass as a basic authorization in the "authorization" metadata.
+
+Regenerate the code after changing lbcd.proto with:
+
+	go generate ./rpc/proto
+*/
+package lbcdrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative lbcd.proto
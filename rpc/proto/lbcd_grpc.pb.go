@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.12
+// source: lbcd.proto
+
+package lbcdrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// LbcdClient is the client API for Lbcd service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LbcdClient interface {
+	// GetBlock returns a block of the main chain by hash or height.
+	GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error)
+	// GetRawTransaction returns a transaction of the mempool, or of the main
+	// chain when the transaction index is enabled.
+	GetRawTransaction(ctx context.Context, in *GetRawTransactionRequest, opts ...grpc.CallOption) (*GetRawTransactionResponse, error)
+	// GetClaimsForName returns the claims of a name at a block of the main
+	// chain.
+	GetClaimsForName(ctx context.Context, in *GetClaimsForNameRequest, opts ...grpc.CallOption) (*GetClaimsForNameResponse, error)
+	// SubscribeBlocks streams a notification whenever a block is connected to
+	// or disconnected from the main chain.
+	SubscribeBlocks(ctx context.Context, in *SubscribeBlocksRequest, opts ...grpc.CallOption) (Lbcd_SubscribeBlocksClient, error)
+}
+
+type lbcdClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLbcdClient(cc grpc.ClientConnInterface) LbcdClient {
+	return &lbcdClient{cc}
+}
+
+func (c *lbcdClient) GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*GetBlockResponse, error) {
+	out := new(GetBlockResponse)
+	err := c.cc.Invoke(ctx, "/lbcd.rpc.Lbcd/GetBlock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lbcdClient) GetRawTransaction(ctx context.Context, in *GetRawTransactionRequest, opts ...grpc.CallOption) (*GetRawTransactionResponse, error) {
+	out := new(GetRawTransactionResponse)
+	err := c.cc.Invoke(ctx, "/lbcd.rpc.Lbcd/GetRawTransaction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lbcdClient) GetClaimsForName(ctx context.Context, in *GetClaimsForNameRequest, opts ...grpc.CallOption) (*GetClaimsForNameResponse, error) {
+	out := new(GetClaimsForNameResponse)
+	err := c.cc.Invoke(ctx, "/lbcd.rpc.Lbcd/GetClaimsForName", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lbcdClient) SubscribeBlocks(ctx context.Context, in *SubscribeBlocksRequest, opts ...grpc.CallOption) (Lbcd_SubscribeBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Lbcd_ServiceDesc.Streams[0], "/lbcd.rpc.Lbcd/SubscribeBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &lbcdSubscribeBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Lbcd_SubscribeBlocksClient interface {
+	Recv() (*BlockNotification, error)
+	grpc.ClientStream
+}
+
+type lbcdSubscribeBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *lbcdSubscribeBlocksClient) Recv() (*BlockNotification, error) {
+	m := new(BlockNotification)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LbcdServer is the server API for Lbcd service.
+// All implementations must embed UnimplementedLbcdServer
+// for forward compatibility
+type LbcdServer interface {
+	// GetBlock returns a block of the main chain by hash or height.
+	GetBlock(context.Context, *GetBlockRequest) (*GetBlockResponse, error)
+	// GetRawTransaction returns a transaction of the mempool, or of the main
+	// chain when the transaction index is enabled.
+	GetRawTransaction(context.Context, *GetRawTransactionRequest) (*GetRawTransactionResponse, error)
+	// GetClaimsForName returns the claims of a name at a block of the main
+	// chain.
+	GetClaimsForName(context.Context, *GetClaimsForNameRequest) (*GetClaimsForNameResponse, error)
+	// SubscribeBlocks streams a notification whenever a block is connected to
+	// or disconnected from the main chain.
+	SubscribeBlocks(*SubscribeBlocksRequest, Lbcd_SubscribeBlocksServer) error
+	mustEmbedUnimplementedLbcdServer()
+}
+
+// UnimplementedLbcdServer must be embedded to have forward compatible implementations.
+type UnimplementedLbcdServer struct {
+}
+
+func (UnimplementedLbcdServer) GetBlock(context.Context, *GetBlockRequest) (*GetBlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlock not implemented")
+}
+func (UnimplementedLbcdServer) GetRawTransaction(context.Context, *GetRawTransactionRequest) (*GetRawTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRawTransaction not implemented")
+}
+func (UnimplementedLbcdServer) GetClaimsForName(context.Context, *GetClaimsForNameRequest) (*GetClaimsForNameResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClaimsForName not implemented")
+}
+func (UnimplementedLbcdServer) SubscribeBlocks(*SubscribeBlocksRequest, Lbcd_SubscribeBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeBlocks not implemented")
+}
+func (UnimplementedLbcdServer) mustEmbedUnimplementedLbcdServer() {}
+
+// UnsafeLbcdServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LbcdServer will
+// result in compilation errors.
+type UnsafeLbcdServer interface {
+	mustEmbedUnimplementedLbcdServer()
+}
+
+func RegisterLbcdServer(s grpc.ServiceRegistrar, srv LbcdServer) {
+	s.RegisterService(&Lbcd_ServiceDesc, srv)
+}
+
+func _Lbcd_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LbcdServer).GetBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lbcd.rpc.Lbcd/GetBlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LbcdServer).GetBlock(ctx, req.(*GetBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lbcd_GetRawTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRawTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LbcdServer).GetRawTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lbcd.rpc.Lbcd/GetRawTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LbcdServer).GetRawTransaction(ctx, req.(*GetRawTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lbcd_GetClaimsForName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetClaimsForNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LbcdServer).GetClaimsForName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lbcd.rpc.Lbcd/GetClaimsForName",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LbcdServer).GetClaimsForName(ctx, req.(*GetClaimsForNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lbcd_SubscribeBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeBlocksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LbcdServer).SubscribeBlocks(m, &lbcdSubscribeBlocksServer{stream})
+}
+
+type Lbcd_SubscribeBlocksServer interface {
+	Send(*BlockNotification) error
+	grpc.ServerStream
+}
+
+type lbcdSubscribeBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *lbcdSubscribeBlocksServer) Send(m *BlockNotification) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Lbcd_ServiceDesc is the grpc.ServiceDesc for Lbcd service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Lbcd_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lbcd.rpc.Lbcd",
+	HandlerType: (*LbcdServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBlock",
+			Handler:    _Lbcd_GetBlock_Handler,
+		},
+		{
+			MethodName: "GetRawTransaction",
+			Handler:    _Lbcd_GetRawTransaction_Handler,
+		},
+		{
+			MethodName: "GetClaimsForName",
+			Handler:    _Lbcd_GetClaimsForName_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeBlocks",
+			Handler:       _Lbcd_SubscribeBlocks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "lbcd.proto",
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// rpcAccount is a set of RPC credentials restricted to the methods of its
+// access list, in addition to the admin and limited credentials.
+type rpcAccount struct {
+	user    string
+	authsha [sha256.Size]byte
+
+	// methods is the set of methods the account may call.
+	methods map[string]struct{}
+}
+
+// rpcMethodExists returns whether the passed method is served over HTTP POST
+// or websockets.
+func rpcMethodExists(method string) bool {
+	if _, ok := rpcHandlers[method]; ok {
+		return true
+	}
+	_, ok := wsHandlers[method]
+	return ok
+}
+
+// parseRPCAccount parses an RPC account given as <user>:<password>:<methods>,
+// where methods is a comma-separated access list.  The entries of the list
+// are either the name of a method allowed, * to allow all the methods, or the
+// name of a method prefixed with - to deny it.  When the list only denies
+// methods, all the other methods are allowed.  The password may contain
+// colons since the user and the access list are split off at the first and
+// last colons.
+func parseRPCAccount(s string) (*rpcAccount, error) {
+	first := strings.Index(s, ":")
+	last := strings.LastIndex(s, ":")
+	if first <= 0 || first == last {
+		return nil, fmt.Errorf("RPC account %q is not in the form "+
+			"<user>:<password>:<methods>", s)
+	}
+	user, pass, list := s[:first], s[first+1:last], s[last+1:]
+	if pass == "" {
+		return nil, fmt.Errorf("RPC account %q has an empty password",
+			user)
+	}
+
+	var allowAll bool
+	allowed := make(map[string]struct{})
+	denied := make(map[string]struct{})
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			allowAll = true
+			continue
+		}
+
+		deny := strings.HasPrefix(entry, "-")
+		method := strings.TrimPrefix(entry, "-")
+		if !rpcMethodExists(method) {
+			return nil, fmt.Errorf("RPC account %q refers to "+
+				"unknown method %q", user, method)
+		}
+		if deny {
+			denied[method] = struct{}{}
+		} else {
+			allowed[method] = struct{}{}
+		}
+	}
+	if len(allowed) == 0 && len(denied) == 0 && !allowAll {
+		return nil, fmt.Errorf("RPC account %q allows no methods", user)
+	}
+
+	// Deny lists apply to all the methods unless methods are explicitly
+	// allowed.
+	if allowAll || len(allowed) == 0 {
+		for method := range rpcHandlers {
+			allowed[method] = struct{}{}
+		}
+		for method := range wsHandlers {
+			allowed[method] = struct{}{}
+		}
+	}
+	for method := range denied {
+		delete(allowed, method)
+	}
+
+	login := user + ":" + pass
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
+	return &rpcAccount{
+		user:    user,
+		authsha: sha256.Sum256([]byte(auth)),
+		methods: allowed,
+	}, nil
+}
+
+// accountMethods returns the set of methods the RPC account matching the
+// passed hash of the Authorization header may call, and whether an account
+// matches.
+//
+// This check is time-constant.
+func (s *rpcServer) accountMethods(authsha *[sha256.Size]byte) (map[string]struct{}, bool) {
+	var methods map[string]struct{}
+	for _, account := range s.accounts {
+		cmp := subtle.ConstantTimeCompare(authsha[:], account.authsha[:])
+		if cmp == 1 {
+			methods = account.methods
+		}
+	}
+	return methods, methods != nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+// TestParseRPCAccount ensures the access lists of RPC accounts are parsed into
+// the set of methods the accounts may call.
+func TestParseRPCAccount(t *testing.T) {
+	miner, err := parseRPCAccount("miner:p:a:ss:getblocktemplate,submitblock")
+	if err != nil {
+		t.Fatalf("parseRPCAccount: unexpected error: %v", err)
+	}
+	if miner.user != "miner" || len(miner.methods) != 2 {
+		t.Fatalf("parseRPCAccount: got user %q with %d methods",
+			miner.user, len(miner.methods))
+	}
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("miner:p:a:ss"))
+	if miner.authsha != sha256.Sum256([]byte(auth)) {
+		t.Fatalf("parseRPCAccount: mismatched credentials")
+	}
+
+	// Deny lists apply to all the methods, including the websocket ones.
+	reader, err := parseRPCAccount("reader:pass:-stop,-generate")
+	if err != nil {
+		t.Fatalf("parseRPCAccount: unexpected error: %v", err)
+	}
+	for method, want := range map[string]bool{
+		"getblockcount": true,
+		"notifyblocks":  true,
+		"stop":          false,
+		"generate":      false,
+	} {
+		if _, ok := reader.methods[method]; ok != want {
+			t.Errorf("parseRPCAccount: method %q allowed: got %v, "+
+				"want %v", method, ok, want)
+		}
+	}
+
+	for _, s := range []string{
+		"user:pass",
+		":pass:*",
+		"user::*",
+		"user:pass:",
+		"user:pass:nosuchmethod",
+		"user:pass:-nosuchmethod",
+	} {
+		if _, err := parseRPCAccount(s); err == nil {
+			t.Errorf("parseRPCAccount(%q): expected error", s)
+		}
+	}
+}
+
+// TestRPCAccountAuth ensures the credentials of RPC accounts authenticate and
+// restrict the requests to the methods of their access lists.
+func TestRPCAccountAuth(t *testing.T) {
+	account, err := parseRPCAccount("miner:pass:getblocktemplate")
+	if err != nil {
+		t.Fatalf("parseRPCAccount: unexpected error: %v", err)
+	}
+	s := &rpcServer{accounts: []*rpcAccount{account}}
+
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("miner:pass"))
+	authenticated, isAdmin, methods, err := s.checkAuthHeader(
+		[]string{auth}, "127.0.0.1:1234", true)
+	if err != nil || !authenticated || !isAdmin {
+		t.Fatalf("checkAuthHeader: got %v, %v, %v", authenticated,
+			isAdmin, err)
+	}
+	if _, ok := methods["getblocktemplate"]; !ok || len(methods) != 1 {
+		t.Fatalf("checkAuthHeader: unexpected methods %v", methods)
+	}
+
+	auth = "Basic " + base64.StdEncoding.EncodeToString([]byte("miner:wrong"))
+	authsha := sha256.Sum256([]byte(auth))
+	if _, ok := s.accountMethods(&authsha); ok {
+		t.Fatalf("accountMethods: wrong password matched the account")
+	}
+
+	req := &btcjson.Request{
+		Jsonrpc: btcjson.RpcVersion1,
+		Method:  "stop",
+		Params:  []json.RawMessage{},
+		ID:      1,
+	}
+	var resp btcjson.Response
+	if err := json.Unmarshal(s.processRequest(req, "", isAdmin, methods, nil),
+		&resp); err != nil {
+
+		t.Fatalf("unable to unmarshal reply: %v", err)
+	}
+	if resp.Error == nil ||
+		resp.Error.Code != btcjson.ErrRPCMethodNotFound.Code {
+
+		t.Fatalf("processRequest: got error %v, want %v", resp.Error,
+			btcjson.ErrRPCMethodNotFound)
+	}
+}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"net"
 	"sync/atomic"
 	"time"
 
@@ -50,7 +51,16 @@ func (p *rpcPeer) IsTxRelayDisabled() bool {
 // This function is safe for concurrent access and is part of the rpcserverPeer
 // interface implementation.
 func (p *rpcPeer) BanScore() uint32 {
-	return (*serverPeer)(p).banScore.Int()
+	return (*serverPeer)(p).misbehavior.Int()
+}
+
+// MisbehaviorScores returns the current scores of the categories of violations
+// of the peer keyed by their names.
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) MisbehaviorScores() map[string]uint32 {
+	return (*serverPeer)(p).misbehavior.Breakdown()
 }
 
 // FeeFilter returns the requested current minimum fee rate for which
@@ -62,6 +72,15 @@ func (p *rpcPeer) FeeFilter() int64 {
 	return atomic.LoadInt64(&(*serverPeer)(p).feeFilter)
 }
 
+// BloomWork returns the number of bytes hashed to match the bloom filters
+// loaded by the peer.
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) BloomWork() uint64 {
+	return (*serverPeer)(p).filter.Work()
+}
+
 // rpcConnManager provides a connection manager for use with the RPC server and
 // implements the rpcserverConnManager interface.
 type rpcConnManager struct {
@@ -165,6 +184,15 @@ func (cm *rpcConnManager) NetTotals() (uint64, uint64) {
 	return cm.server.NetTotals()
 }
 
+// NetTotalsPerMsg returns the bytes received and sent across the network for
+// all peers by message command.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) NetTotalsPerMsg() (map[string]uint64, map[string]uint64) {
+	return cm.server.NetTotalsPerMsg()
+}
+
 // ConnectedPeers returns an array consisting of all connected peers.
 //
 // This function is safe for concurrent access and is part of the
@@ -182,55 +210,54 @@ func (cm *rpcConnManager) ConnectedPeers() []rpcserverPeer {
 	return peers
 }
 
-// BannedPeers returns a map consisting of all banned host with banned period.
+// BannedPeers returns a map consisting of all banned addresses and subnets
+// with their banned period.
 //
 // This function is safe for concurrent access and is part of the
 // rpcserverConnManager interface implementation.
 func (cm *rpcConnManager) BannedPeers() map[string]bannedPeriod {
-	replyChan := make(chan map[string]bannedPeriod)
-	cm.server.query <- listBannedPeersMsg{reply: replyChan}
-	return <-replyChan
+	return cm.server.banManager.Banned()
 }
 
-// SetBan removes the peer associated with the provided address from the
-// list of persistent peers.
+// SetBan bans the provided subnet for the given period and disconnects all
+// connected peers within it.
 //
 // This function is safe for concurrent access and is part of the
 // rpcserverConnManager interface implementation.
-func (cm *rpcConnManager) SetBan(addr string, since, until time.Time) error {
-	replyChan := make(chan error)
-	cm.server.query <- setBanMsg{
-		addr:  addr,
-		since: since,
-		until: until,
-		reply: replyChan,
+func (cm *rpcConnManager) SetBan(subnet *net.IPNet, since, until time.Time) error {
+	err := cm.server.banManager.Ban(subnet, since, until)
+	if err != nil {
+		return err
 	}
-	return <-replyChan
+
+	replyChan := make(chan []*serverPeer)
+	cm.server.query <- getPeersMsg{reply: replyChan}
+	for _, sp := range <-replyChan {
+		host, _, err := net.SplitHostPort(sp.Addr())
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil && subnet.Contains(ip) {
+			sp.Disconnect()
+		}
+	}
+	return nil
 }
 
-// RemoveBan removes a host from banned list.
+// RemoveBan removes the ban of the provided subnet.
 //
 // This function is safe for concurrent access and is part of the
 // rpcserverConnManager interface implementation.
-func (cm *rpcConnManager) RemoveBan(addr string) error {
-	replyChan := make(chan error)
-	cm.server.query <- removeBanMsg{
-		addr:  addr,
-		reply: replyChan,
-	}
-	return <-replyChan
+func (cm *rpcConnManager) RemoveBan(subnet *net.IPNet) error {
+	return cm.server.banManager.Unban(subnet)
 }
 
-// ClearBanned removes all banned host with banned period.
+// ClearBanned removes all bans.
 //
 // This function is safe for concurrent access and is part of the
 // rpcserverConnManager interface implementation.
 func (cm *rpcConnManager) ClearBanned() error {
-	replyChan := make(chan error)
-	cm.server.query <- clearBannedMsg{
-		reply: replyChan,
-	}
-	return <-replyChan
+	return cm.server.banManager.Clear()
 }
 
 // PersistentPeers returns an array consisting of all the added persistent
@@ -320,6 +347,24 @@ func (b *rpcSyncMgr) Pause() chan<- struct{} {
 	return b.syncMgr.Pause()
 }
 
+// FetchBlock requests the block of the passed hash from the passed peer on
+// demand.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverSyncManager interface implementation.
+func (b *rpcSyncMgr) FetchBlock(hash *chainhash.Hash, p *peer.Peer) error {
+	return b.syncMgr.FetchBlock(hash, p)
+}
+
+// ResetSync restarts the sync from the current best block after the chain
+// state was replaced.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverSyncManager interface implementation.
+func (b *rpcSyncMgr) ResetSync() {
+	b.syncMgr.ResetSync()
+}
+
 // SyncPeerID returns the peer that is currently the peer being used to sync
 // from.
 //
@@ -329,6 +374,24 @@ func (b *rpcSyncMgr) SyncPeerID() int32 {
 	return b.syncMgr.SyncPeerID()
 }
 
+// PeerSyncStats returns the block download statistics of the peers keyed by
+// their IDs.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverSyncManager interface implementation.
+func (b *rpcSyncMgr) PeerSyncStats() map[int32]netsync.PeerSyncStats {
+	return b.syncMgr.PeerSyncStats()
+}
+
+// TimeRemaining returns an estimate of the time left to sync the chain or zero
+// when there is no estimate.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverSyncManager interface implementation.
+func (b *rpcSyncMgr) TimeRemaining() time.Duration {
+	return b.syncMgr.TimeRemaining()
+}
+
 // LocateBlocks returns the hashes of the blocks after the first known block in
 // the provided locators until the provided stop hash or the current tip is
 // reached, up to a max of wire.MaxBlockHeadersPerMsg hashes.
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestAddressBalance ensures the balance of an address only counts the
+// unspent outputs paying to it and splits out the amounts held in claims and
+// supports.
+func TestAddressBalance(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	addr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	other, err := btcutil.NewAddressPubKeyHash(append(make([]byte, 19), 1),
+		params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	pkh, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	otherPkh, err := txscript.PayToAddrScript(other)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	claimScript, err := txscript.NewClaimNameScript([]byte("name"),
+		[]byte("value"), pkh)
+	if err != nil {
+		t.Fatalf("NewClaimNameScript: unexpected error: %v", err)
+	}
+	supportScript, err := txscript.NewSupportClaimScript([]byte("name"),
+		make([]byte, 20), nil, pkh)
+	if err != nil {
+		t.Fatalf("NewSupportClaimScript: unexpected error: %v", err)
+	}
+
+	// Outputs 0, 2 and 3 are unspent, output 1 is spent, output 4 was never
+	// in the utxo set and output 5 pays to another address.
+	mtx := wire.NewMsgTx(wire.TxVersion)
+	mtx.AddTxOut(wire.NewTxOut(1e8, pkh))
+	mtx.AddTxOut(wire.NewTxOut(2e8, pkh))
+	mtx.AddTxOut(wire.NewTxOut(3e8, claimScript))
+	mtx.AddTxOut(wire.NewTxOut(4e8, supportScript))
+	mtx.AddTxOut(wire.NewTxOut(5e8, pkh))
+	mtx.AddTxOut(wire.NewTxOut(6e8, otherPkh))
+	txHash := mtx.TxHash()
+	fetchUtxo := func(op wire.OutPoint) (*blockchain.UtxoEntry, error) {
+		if op.Hash != txHash {
+			t.Fatalf("unexpected outpoint %v", op)
+		}
+		switch op.Index {
+		case 0, 2, 3:
+			return blockchain.NewUtxoEntry(mtx.TxOut[op.Index], 1,
+				false), nil
+		case 1:
+			entry := blockchain.NewUtxoEntry(mtx.TxOut[op.Index], 1,
+				false)
+			entry.Spend()
+			return entry, nil
+		case 4:
+			return nil, nil
+		}
+		t.Fatalf("unexpected lookup of output %d", op.Index)
+		return nil, nil
+	}
+
+	var b addressBalance
+	err = b.addTx(mtx, addr.EncodeAddress(), params, fetchUtxo)
+	if err != nil {
+		t.Fatalf("addTx: unexpected error: %v", err)
+	}
+	want := addressBalance{
+		received: 15e8,
+		balance:  8e8,
+		claims:   3e8,
+		supports: 4e8,
+	}
+	if b != want {
+		t.Fatalf("got balance %+v, want %+v", b, want)
+	}
+}
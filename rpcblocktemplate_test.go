@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+// TestGbtFeeDeltaLongPoll ensures getblocktemplate long poll clients are only
+// notified of a stale template before gbtRegenerateSeconds pass once the new
+// transactions pay at least the fee delta.
+func TestGbtFeeDeltaLongPoll(t *testing.T) {
+	tests := []struct {
+		name       string
+		feeDelta   int64
+		fees       []int64
+		wantNotify bool
+	}{
+		{"below fee delta", 1000, []int64{400, 500}, false},
+		{"fee delta reached", 1000, []int64{400, 600}, true},
+		{"fee delta disabled", 0, []int64{1e8}, false},
+	}
+	for _, test := range tests {
+		state := newGbtWorkState(blockchain.NewMedianTime(), 0)
+		state.feeDelta = test.feeDelta
+		state.prevHash = &chainhash.Hash{1}
+		state.lastGenerated = time.Now()
+
+		state.Lock()
+		c := state.templateUpdateChan(state.prevHash,
+			state.lastGenerated.UnixNano())
+		state.Unlock()
+
+		var total int64
+		for _, fee := range test.fees {
+			total += fee
+			state.NotifyMempoolTx(time.Now(), fee)
+		}
+
+		// The fees are added asynchronously.
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			state.Lock()
+			newTxFees := state.newTxFees
+			state.Unlock()
+			if newTxFees == total {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: got new transaction fees %d, want %d",
+					test.name, newTxFees, total)
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		var notified bool
+		select {
+		case <-c:
+			notified = true
+		default:
+		}
+		if notified != test.wantNotify {
+			t.Fatalf("%s: got notified %v, want %v", test.name,
+				notified, test.wantNotify)
+		}
+	}
+}
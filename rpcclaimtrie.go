@@ -3,25 +3,199 @@ package main
 import (
 	"bytes"
 	"encoding/hex"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/lbryio/lbcd/blockchain/indexers"
 	"github.com/lbryio/lbcd/btcjson"
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie"
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/claimtrie/metadata"
 	"github.com/lbryio/lbcd/claimtrie/node"
 	"github.com/lbryio/lbcd/claimtrie/normalization"
 	"github.com/lbryio/lbcd/database"
 	"github.com/lbryio/lbcd/txscript"
 	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+	"github.com/lbryio/lbcutil/base58"
 )
 
 var claimtrieHandlers = map[string]commandHandler{
-	"getchangesinblock":     handleGetChangesInBlock,
-	"getclaimsforname":      handleGetClaimsForName,
-	"getclaimsfornamebyid":  handleGetClaimsForNameByID,
-	"getclaimsfornamebybid": handleGetClaimsForNameByBid,
-	"getclaimsfornamebyseq": handleGetClaimsForNameBySeq,
-	"normalize":             handleGetNormalized,
+	"compactclaimtrie":         handleCompactClaimTrie,
+	"exportclaimtrie":          handleExportClaimTrie,
+	"getchangesinblock":        handleGetChangesInBlock,
+	"getclaimbyid":             handleGetClaimByID,
+	"getclaimsbychannel":       handleGetClaimsByChannel,
+	"getclaimsforname":         handleGetClaimsForName,
+	"getclaimsfornamebyid":     handleGetClaimsForNameByID,
+	"getclaimsfornamebybid":    handleGetClaimsForNameByBid,
+	"getclaimsfornamebyseq":    handleGetClaimsForNameBySeq,
+	"getclaimtrieinfo":         handleGetClaimTrieInfo,
+	"getclaimtrierootatheight": handleGetClaimTrieRootAtHeight,
+	"importclaimtrie":          handleImportClaimTrie,
+	"normalize":                handleGetNormalized,
+	"normalizename":            handleNormalizeName,
+	"resolve":                  handleResolve,
+	"simulateclaimtakeover":    handleSimulateClaimTakeover,
+	"verifyclaimtrie":          handleVerifyClaimTrie,
+}
+
+func snapshotPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(cfg.DataDir, path)
+}
+
+func handleExportClaimTrie(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+
+	c := cmd.(*btcjson.ExportClaimTrieCmd)
+	height := s.cfg.Chain.BestSnapshot().Height
+	if c.Height != nil {
+		height = *c.Height
+	}
+
+	path := snapshotPath(c.Path)
+	if _, err := os.Stat(path); err == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Snapshot file already exists: " + path,
+		}
+	}
+
+	// Write to a temporary file first so an interrupted export doesn't
+	// leave a partial snapshot behind.
+	tempPath := path + ".incomplete"
+	f, err := os.Create(tempPath)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to create snapshot file: " + err.Error(),
+		}
+	}
+	header, err := s.cfg.Chain.ExportClaimTrieSnapshot(f, height)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to export the claim trie: " + err.Error(),
+		}
+	}
+
+	return btcjson.ClaimTrieSnapshotResult{
+		Path:          path,
+		Height:        header.Height,
+		ClaimTrieRoot: header.ClaimTrieRoot.String(),
+	}, nil
+}
+
+func handleImportClaimTrie(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+
+	c := cmd.(*btcjson.ImportClaimTrieCmd)
+	path := snapshotPath(c.Path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Unable to open snapshot file: " + err.Error(),
+		}
+	}
+	defer f.Close()
+
+	header, err := s.cfg.Chain.ImportClaimTrieSnapshot(f)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to import the claim trie: " + err.Error(),
+		}
+	}
+
+	return btcjson.ClaimTrieSnapshotResult{
+		Path:          path,
+		Height:        header.Height,
+		ClaimTrieRoot: header.ClaimTrieRoot.String(),
+	}, nil
+}
+
+func handleVerifyClaimTrie(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+
+	c := cmd.(*btcjson.VerifyClaimTrieCmd)
+	result, err := s.cfg.Chain.VerifyClaimTrie(*c.Repair, closeChan)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to verify the claim trie: " + err.Error(),
+		}
+	}
+
+	names := make([]string, 0, len(result.DivergentNames))
+	for _, name := range result.DivergentNames {
+		names = append(names, string(name))
+	}
+
+	return btcjson.VerifyClaimTrieResult{
+		Height:         result.Height,
+		ExpectedRoot:   result.ExpectedRoot.String(),
+		StoredRoot:     result.StoredRoot.String(),
+		ComputedRoot:   result.ComputedRoot.String(),
+		NamesChecked:   result.NamesChecked,
+		DivergentCount: result.DivergentCount,
+		DivergentNames: names,
+		Consistent:     result.Consistent(),
+		Repaired:       result.Repaired,
+	}, nil
+}
+
+// nodeRepoSize returns the disk space used by the node repo of the claim trie.
+func nodeRepoSize(s *rpcServer) uint64 {
+	for _, m := range s.cfg.Chain.ClaimTrie().RepoMetrics() {
+		if m.Repo == "node" {
+			return m.DiskSpaceUsage()
+		}
+	}
+	return 0
+}
+
+func handleCompactClaimTrie(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+
+	c := cmd.(*btcjson.CompactClaimTrieCmd)
+	finalHeight := s.cfg.Chain.BestSnapshot().Height - claimtrie.CompactRollbackDepth
+	if c.FinalHeight != nil {
+		finalHeight = *c.FinalHeight
+	}
+
+	sizeBefore := nodeRepoSize(s)
+	result, err := s.cfg.Chain.CompactClaimTrie(finalHeight, closeChan)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to compact the claim trie: " + err.Error(),
+		}
+	}
+
+	return btcjson.CompactClaimTrieResult{
+		Height:         result.Height,
+		FinalHeight:    result.FinalHeight,
+		NamesChecked:   result.NamesChecked,
+		NamesCompacted: result.NamesCompacted,
+		ChangesRemoved: result.ChangesRemoved,
+		SizeBefore:     sizeBefore,
+		SizeAfter:      nodeRepoSize(s),
+	}, nil
 }
 
 func handleGetChangesInBlock(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
@@ -125,6 +299,151 @@ func handleGetClaimsForName(s *rpcServer, cmd interface{}, _ <-chan struct{}) (i
 	}, nil
 }
 
+func handleGetClaimByID(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+
+	if s.cfg.ClaimIDIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Claim ID index must be enabled (--claimidindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetClaimByIDCmd)
+	if len(c.ClaimID) != 2*change.ClaimIDSize {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Claim ID must be 40 hex characters: " + c.ClaimID,
+		}
+	}
+	id, err := change.NewIDFromString(c.ClaimID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.ClaimID)
+	}
+
+	entry, err := s.cfg.ClaimIDIndex.ClaimByID(id)
+	if err != nil {
+		context := "Failed to retrieve claim ID index entry"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if entry == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "No claim found with ID " + c.ClaimID,
+		}
+	}
+
+	result := btcjson.GetClaimByIDResult{
+		ClaimID: id.String(),
+		Name:    string(entry.Name),
+		Status:  entry.Status.String(),
+		TXID:    entry.OutPoint.Hash.String(),
+		N:       entry.OutPoint.Index,
+		Height:  entry.Height,
+	}
+	if entry.Status != indexers.ClaimIDStatusActive {
+		return result, nil
+	}
+
+	// The index only knows the name of the claim, so look up the rest of
+	// its details in the node for that name.
+	result.Claim, err = claimInTrie(s, entry.Name, id, c.IncludeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// claimInTrie returns the claim with the passed name and claim ID as it stands
+// in the trie at the tip, or nil when it isn't in the trie.
+func claimInTrie(s *rpcServer, name []byte, id change.ClaimID, includeValues *bool) (*btcjson.ClaimResult, error) {
+	height := s.cfg.Chain.BestSnapshot().Height
+	_, n, err := s.cfg.Chain.GetClaimsForName(height, string(name))
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Message: " + err.Error(),
+		}
+	}
+	for i := range n.Claims {
+		if n.Claims[i].ClaimID != id {
+			continue
+		}
+		cr, err := toClaimResult(s, int32(i), n, includeValues)
+		if err != nil {
+			return nil, err
+		}
+		return &cr, nil
+	}
+	return nil, nil
+}
+
+func handleGetClaimsByChannel(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+
+	if s.cfg.ChannelIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Claims by channel index must be enabled (--channelindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetClaimsByChannelCmd)
+	if len(c.ChannelID) != 2*change.ClaimIDSize {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Channel ID must be 40 hex characters: " + c.ChannelID,
+		}
+	}
+	channelID, err := change.NewIDFromString(c.ChannelID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.ChannelID)
+	}
+
+	numRequested := 100
+	if c.Count != nil {
+		numRequested = *c.Count
+		if numRequested < 0 {
+			numRequested = 1
+		}
+	}
+	var numToSkip int
+	if c.Skip != nil && *c.Skip > 0 {
+		numToSkip = *c.Skip
+	}
+
+	entries, total, err := s.cfg.ChannelIndex.ClaimsByChannel(channelID,
+		uint32(numToSkip), uint32(numRequested))
+	if err != nil {
+		context := "Failed to retrieve claims by channel index entries"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	result := btcjson.GetClaimsByChannelResult{
+		ChannelID: channelID.String(),
+		Total:     total,
+		Claims:    make([]btcjson.ClaimByChannelResult, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		cr := btcjson.ClaimByChannelResult{
+			ClaimID: entry.ClaimID.String(),
+			Name:    string(entry.Name),
+			TXID:    entry.OutPoint.Hash.String(),
+			N:       entry.OutPoint.Index,
+			Height:  entry.Height,
+		}
+		if c.IncludeValues != nil && *c.IncludeValues {
+			cr.Claim, err = claimInTrie(s, entry.Name, entry.ClaimID,
+				c.IncludeValues)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.Claims = append(result.Claims, cr)
+	}
+
+	return result, nil
+}
+
 func handleGetClaimsForNameByID(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
 
 	c := cmd.(*btcjson.GetClaimsForNameByIDCmd)
@@ -241,10 +560,317 @@ func handleGetClaimsForNameBySeq(s *rpcServer, cmd interface{}, _ <-chan struct{
 	}, nil
 }
 
+func handleSimulateClaimTakeover(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+
+	c := cmd.(*btcjson.SimulateClaimTakeoverCmd)
+	if len(c.Stakes) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "At least one claim or support must be simulated",
+		}
+	}
+
+	// The hypothetical claims and supports are given outpoints of a null
+	// transaction hash, which can't collide with those of real ones.
+	changes := make([]change.Change, len(c.Stakes))
+	for i, stake := range c.Stakes {
+		if stake.Amount <= 0 {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Stake amounts must be positive",
+			}
+		}
+		op := wire.OutPoint{Index: uint32(i)}
+		changes[i] = change.Change{
+			Type:     change.AddClaim,
+			OutPoint: op,
+			ClaimID:  change.NewClaimID(op),
+			Amount:   stake.Amount,
+		}
+		if stake.ClaimID != nil {
+			id, err := change.NewIDFromString(*stake.ClaimID)
+			if err != nil || len(*stake.ClaimID) != 2*change.ClaimIDSize {
+				return nil, &btcjson.RPCError{
+					Code:    btcjson.ErrRPCInvalidParameter,
+					Message: "Claim ID must be 40 hex characters: " + *stake.ClaimID,
+				}
+			}
+			changes[i].Type = change.AddSupport
+			changes[i].ClaimID = id
+		}
+	}
+
+	// The controlling claim at the tip, if any, tells whether the
+	// simulated outcome is a takeover.
+	var previous string
+	best := s.cfg.Chain.BestSnapshot()
+	_, current, err := s.cfg.Chain.GetClaimsForName(best.Height, c.Name)
+	if err == nil && current.HasActiveBestClaim() {
+		previous = current.BestClaim.ClaimID.String()
+	}
+
+	var height int32
+	if c.Height != nil {
+		height = *c.Height
+	}
+	name, n, height, err := s.cfg.Chain.SimulateClaims(c.Name, changes, height)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Message: " + err.Error(),
+		}
+	}
+
+	result := btcjson.SimulateClaimTakeoverResult{
+		NormalizedName:             name,
+		Height:                     height,
+		LastTakeoverHeight:         n.TakenOverAt,
+		PreviousControllingClaimID: previous,
+	}
+	if n.HasActiveBestClaim() {
+		result.ControllingClaimID = n.BestClaim.ClaimID.String()
+	}
+	result.Takeover = result.ControllingClaimID != previous
+
+	simulated := map[wire.OutPoint]bool{}
+	for _, chg := range changes {
+		simulated[chg.OutPoint] = true
+	}
+	for _, list := range []node.ClaimList{n.Claims, n.Supports} {
+		for _, stake := range list {
+			if !simulated[stake.OutPoint] {
+				continue
+			}
+			result.Stakes = append(result.Stakes, btcjson.SimulatedStakeResult{
+				ClaimID:       stake.ClaimID.String(),
+				TXID:          stake.OutPoint.Hash.String(),
+				N:             stake.OutPoint.Index,
+				IsSupport:     changes[stake.OutPoint.Index].Type == change.AddSupport,
+				Amount:        stake.Amount,
+				Height:        stake.AcceptedAt,
+				ValidAtHeight: stake.ActiveAt,
+			})
+		}
+	}
+	for i := range n.Claims {
+		cr, err := toClaimResult(s, int32(i), n, nil)
+		if err != nil {
+			return nil, err
+		}
+		result.Claims = append(result.Claims, cr)
+	}
+
+	return result, nil
+}
+
+func handleResolve(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+
+	c := cmd.(*btcjson.ResolveCmd)
+	uri, err := parseClaimURI(c.URI)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid URI " + c.URI + ": " + err.Error(),
+		}
+	}
+	if uri.channel != nil && s.cfg.ChannelIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Claims by channel index must be enabled (--channelindex)",
+		}
+	}
+
+	best := s.cfg.Chain.BestSnapshot()
+	result := btcjson.ResolveResult{
+		URI:    c.URI,
+		Hash:   best.Hash.String(),
+		Height: best.Height,
+	}
+
+	// The claims of a channel are limited to those signed by the channel,
+	// according to the claims by channel index.
+	var signed map[change.ClaimID]bool
+	if uri.channel != nil {
+		channel, root, err := resolveClaim(s, best.Height, uri.channel,
+			nil, c.IncludeValues)
+		if err != nil {
+			return nil, err
+		}
+		result.Channel = channel
+		result.ClaimTrieRoot = root
+
+		signed, err = claimsSignedByChannel(s, best.Height,
+			channel.Claim.ClaimID, uri.claim.name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	claim, root, err := resolveClaim(s, best.Height, uri.claim, signed,
+		c.IncludeValues)
+	if err != nil {
+		return nil, err
+	}
+	result.Claim = *claim
+	if root != "" {
+		result.ClaimTrieRoot = root
+	}
+
+	return result, nil
+}
+
+// claimsSignedByChannel returns the IDs of the claims of the passed name signed
+// by the passed channel according to the claims by channel index.
+func claimsSignedByChannel(s *rpcServer, height int32, channelID string, name string) (map[change.ClaimID]bool, error) {
+	id, err := change.NewIDFromString(channelID)
+	if err != nil {
+		return nil, rpcDecodeHexError(channelID)
+	}
+	entries, _, err := s.cfg.ChannelIndex.ClaimsByChannel(id, 0, math.MaxUint32)
+	if err != nil {
+		context := "Failed to retrieve claims by channel index entries"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	normalizedName := normalization.NormalizeIfNecessary([]byte(name), height)
+	signed := map[change.ClaimID]bool{}
+	for _, entry := range entries {
+		if bytes.Equal(normalization.NormalizeIfNecessary(entry.Name, height), normalizedName) {
+			signed[entry.ClaimID] = true
+		}
+	}
+	return signed, nil
+}
+
+// resolveClaim returns the claim of the passed segment of a claim URI at the
+// passed height, which must be the tip, along with the root of the claim trie
+// it was proven to be part of.  The claim is looked up among the passed claims
+// unless they are nil.  Without modifiers, that's the controlling claim of the
+// name, or the active claim with the highest effective amount for the claims
+// of a channel.
+func resolveClaim(s *rpcServer, height int32, segment *claimURISegment,
+	candidates map[change.ClaimID]bool, includeValues *bool) (*btcjson.ResolvedClaimResult, string, error) {
+
+	notFound := func() error {
+		return &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "No claim found for " + segment.String(),
+		}
+	}
+
+	name, n, err := s.cfg.Chain.GetClaimsForName(height, segment.name)
+	if err != nil {
+		return nil, "", notFound()
+	}
+
+	// The claims are sorted by bid, so the active ones come first.
+	var claims []int
+	for i, claim := range n.Claims {
+		if candidates == nil || candidates[claim.ClaimID] {
+			claims = append(claims, i)
+		}
+	}
+	byCreation := func() {
+		sort.SliceStable(claims, func(i, j int) bool {
+			a, b := n.Claims[claims[i]], n.Claims[claims[j]]
+			if a.AcceptedAt != b.AcceptedAt {
+				return a.AcceptedAt < b.AcceptedAt
+			}
+			return a.Sequence < b.Sequence
+		})
+	}
+
+	selected := -1
+	switch {
+	case segment.claimID != "":
+		byCreation()
+		for _, i := range claims {
+			if strings.HasPrefix(n.Claims[i].ClaimID.String(), segment.claimID) {
+				selected = i
+				break
+			}
+		}
+
+	case segment.sequence != 0:
+		byCreation()
+		if segment.sequence <= len(claims) {
+			selected = claims[segment.sequence-1]
+		}
+
+	default:
+		var active []int
+		for _, i := range claims {
+			if n.Claims[i].Status == node.Activated {
+				active = append(active, i)
+			}
+		}
+		switch {
+		case segment.amountOrder != 0:
+			if segment.amountOrder <= len(active) {
+				selected = active[segment.amountOrder-1]
+			}
+		case candidates == nil:
+			if n.HasActiveBestClaim() {
+				for _, i := range active {
+					if n.Claims[i].ClaimID == n.BestClaim.ClaimID {
+						selected = i
+					}
+				}
+			}
+		case len(active) > 0:
+			selected = active[0]
+		}
+	}
+	if selected < 0 {
+		return nil, "", notFound()
+	}
+
+	cr, err := toClaimResult(s, int32(selected), n, includeValues)
+	if err != nil {
+		return nil, "", err
+	}
+	result := &btcjson.ResolvedClaimResult{
+		NormalizedName:     name,
+		LastTakeoverHeight: n.TakenOverAt,
+		Claim:              cr,
+	}
+
+	// Only the active claims are part of the claim trie.
+	claim := n.Claims[selected]
+	if claim.Status != node.Activated {
+		return result, "", nil
+	}
+	proof, err := s.cfg.Chain.ProveClaim(name, claim.OutPoint)
+	if err != nil {
+		return nil, "", &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to prove the claim: " + err.Error(),
+		}
+	}
+	if proof.Height != height {
+		return nil, "", &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The best chain changed while resolving the URI",
+		}
+	}
+	result.Proof = &btcjson.ClaimProofResult{
+		Pairs: make([]btcjson.ClaimProofPairResult, 0, len(proof.Pairs)),
+	}
+	for _, pair := range proof.Pairs {
+		result.Proof.Pairs = append(result.Proof.Pairs, btcjson.ClaimProofPairResult{
+			Odd:  pair.Odd,
+			Hash: pair.Hash.String(),
+		})
+	}
+
+	return result, proof.ClaimTrieRoot.String(), nil
+}
+
 func toClaimResult(s *rpcServer, i int32, n *node.Node, includeValues *bool) (btcjson.ClaimResult, error) {
 	claim := n.Claims[i]
 	address, value, err := lookupValue(s, claim.OutPoint, includeValues)
 	supports, err := toSupportResults(s, i, n, includeValues)
+	hexValue, claimMetadata := claimValueResult(value)
 	effectiveAmount := n.SupportSums[claim.ClaimID.Key()] // should only be active supports
 	if claim.Status == node.Activated {
 		effectiveAmount += claim.Amount
@@ -261,7 +887,8 @@ func toClaimResult(s *rpcServer, i int32, n *node.Node, includeValues *bool) (bt
 		Sequence:        claim.Sequence,
 		Supports:        supports,
 		Address:         address,
-		Value:           value,
+		Value:           hexValue,
+		Metadata:        claimMetadata,
 	}, err
 }
 
@@ -280,7 +907,7 @@ func toSupportResults(s *rpcServer, i int32, n *node.Node, includeValues *bool)
 				Height:        sup.AcceptedAt,
 				ValidAtHeight: sup.ActiveAt,
 				Amount:        sup.Amount,
-				Value:         value,
+				Value:         hex.EncodeToString(value),
 				Address:       address,
 			})
 		}
@@ -288,14 +915,14 @@ func toSupportResults(s *rpcServer, i int32, n *node.Node, includeValues *bool)
 	return results, nil
 }
 
-func lookupValue(s *rpcServer, outpoint wire.OutPoint, includeValues *bool) (string, string, error) {
+func lookupValue(s *rpcServer, outpoint wire.OutPoint, includeValues *bool) (string, []byte, error) {
 	if includeValues == nil || !*includeValues {
-		return "", "", nil
+		return "", nil, nil
 	}
 	// TODO: maybe use addrIndex if the txIndex is not available
 
 	if s.cfg.TxIndex == nil {
-		return "", "", &btcjson.RPCError{
+		return "", nil, &btcjson.RPCError{
 			Code: btcjson.ErrRPCNoTxInfo,
 			Message: "The transaction index must be " +
 				"enabled to query the blockchain " +
@@ -307,10 +934,10 @@ func lookupValue(s *rpcServer, outpoint wire.OutPoint, includeValues *bool) (str
 	blockRegion, err := s.cfg.TxIndex.TxBlockRegion(txHash)
 	if err != nil {
 		context := "Failed to retrieve transaction location"
-		return "", "", internalRPCError(err.Error(), context)
+		return "", nil, internalRPCError(err.Error(), context)
 	}
 	if blockRegion == nil {
-		return "", "", rpcNoTxInfoError(txHash)
+		return "", nil, rpcNoTxInfoError(txHash)
 	}
 
 	// Load the raw transaction bytes from the database.
@@ -321,7 +948,7 @@ func lookupValue(s *rpcServer, outpoint wire.OutPoint, includeValues *bool) (str
 		return err
 	})
 	if err != nil {
-		return "", "", rpcNoTxInfoError(txHash)
+		return "", nil, rpcNoTxInfoError(txHash)
 	}
 
 	// Deserialize the transaction
@@ -329,18 +956,18 @@ func lookupValue(s *rpcServer, outpoint wire.OutPoint, includeValues *bool) (str
 	err = msgTx.Deserialize(bytes.NewReader(txBytes))
 	if err != nil {
 		context := "Failed to deserialize transaction"
-		return "", "", internalRPCError(err.Error(), context)
+		return "", nil, internalRPCError(err.Error(), context)
 	}
 
 	txo := msgTx.TxOut[outpoint.Index]
 	cs, err := txscript.ExtractClaimScript(txo.PkScript)
 	if err != nil {
 		context := "Failed to decode the claim script"
-		return "", "", internalRPCError(err.Error(), context)
+		return "", nil, internalRPCError(err.Error(), context)
 	}
 
 	_, addresses, _, _ := txscript.ExtractPkScriptAddrs(txo.PkScript[cs.Size:], s.cfg.ChainParams)
-	return addresses[0].EncodeAddress(), hex.EncodeToString(cs.Value), nil
+	return addresses[0].EncodeAddress(), cs.Value, nil
 }
 
 func handleGetNormalized(_ *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
@@ -350,3 +977,244 @@ func handleGetNormalized(_ *rpcServer, cmd interface{}, _ <-chan struct{}) (inte
 	}
 	return r, nil
 }
+
+// handleGetClaimTrieInfo returns the height of the claim trie along with the
+// size and the effectiveness of its node cache, the aggregate statistics of its
+// nodes and the metrics of the pebble databases of its repos.
+func handleGetClaimTrieInfo(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+	stats := s.cfg.Chain.ClaimTrie().CacheStats()
+
+	trieStats, err := s.cfg.Chain.ClaimTrieStats()
+	if err != nil {
+		context := "Failed to compute the claim trie statistics"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	depths := make([]btcjson.ClaimTrieDepthInfo, 0, len(trieStats.Depths))
+	for depth, names := range trieStats.Depths {
+		depths = append(depths, btcjson.ClaimTrieDepthInfo{
+			Depth: depth,
+			Names: names,
+		})
+	}
+	sort.Slice(depths, func(i, j int) bool {
+		return depths[i].Depth < depths[j].Depth
+	})
+
+	var hitRate float64
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		hitRate = float64(stats.Hits) / float64(lookups)
+	}
+
+	repos := make([]btcjson.ClaimTrieRepoInfo, 0, 4)
+	for _, m := range s.cfg.Chain.ClaimTrie().RepoMetrics() {
+		repos = append(repos, btcjson.ClaimTrieRepoInfo{
+			Repo:             m.Repo,
+			DiskSize:         m.DiskSpaceUsage(),
+			BlockCacheSize:   m.BlockCache.Size,
+			BlockCacheHits:   m.BlockCache.Hits,
+			BlockCacheMisses: m.BlockCache.Misses,
+			MemTableSize:     m.MemTable.Size,
+			Flushes:          m.Flush.Count,
+			Compactions:      m.Compact.Count,
+			CompactionDebt:   m.Compact.EstimatedDebt,
+			ReadAmp:          m.ReadAmp(),
+			FilterHits:       m.Filter.Hits,
+			FilterMisses:     m.Filter.Misses,
+		})
+	}
+
+	return &btcjson.GetClaimTrieInfoResult{
+		Height:         s.cfg.Chain.BestSnapshot().Height,
+		CacheEntries:   stats.Entries,
+		CacheSize:      stats.Size,
+		CacheBudget:    stats.Budget,
+		CacheHits:      stats.Hits,
+		CacheMisses:    stats.Misses,
+		CacheHitRate:   hitRate,
+		CacheEvictions: stats.Evictions,
+
+		Names:           trieStats.Names,
+		Claims:          trieStats.Claims,
+		Supports:        trieStats.Supports,
+		PendingClaims:   trieStats.PendingClaims,
+		PendingSupports: trieStats.PendingSupports,
+		Depths:          depths,
+
+		Repos: repos,
+	}, nil
+}
+
+// handleGetClaimTrieRootAtHeight returns the claimtrie root computed for the
+// block at the requested height of the main chain along with the root committed
+// to by its header, so the commitment can be audited without rolling back.
+func handleGetClaimTrieRootAtHeight(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetClaimTrieRootAtHeightCmd)
+
+	best := s.cfg.Chain.BestSnapshot()
+	if c.Height < 0 || c.Height > best.Height {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCOutOfRange,
+			Message: "Block height out of range",
+		}
+	}
+
+	hash, err := s.cfg.Chain.BlockHashByHeight(c.Height)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Unable to locate a block at height " + strconv.Itoa(int(c.Height)) + ": " + err.Error(),
+		}
+	}
+	header, err := s.cfg.Chain.HeaderByHash(hash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Unable to load the header of block " + hash.String() + ": " + err.Error(),
+		}
+	}
+
+	root, err := s.cfg.Chain.ClaimTrieRootAt(c.Height)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to retrieve the claim trie root: " + err.Error(),
+		}
+	}
+
+	return &btcjson.GetClaimTrieRootAtHeightResult{
+		Height:          c.Height,
+		Hash:            hash.String(),
+		ClaimTrieRoot:   root.String(),
+		HeaderClaimTrie: header.ClaimTrie.String(),
+		Matches:         *root == header.ClaimTrie,
+	}, nil
+}
+
+// handleNormalizeName returns the form of the name used for bidding by claims
+// made at the requested height, which defaults to the next block.
+func handleNormalizeName(s *rpcServer, cmd interface{}, _ <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.NormalizeNameCmd)
+
+	height := s.cfg.Chain.BestSnapshot().Height + 1
+	if c.Height != nil {
+		if *c.Height < 0 {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "height must not be negative",
+			}
+		}
+		height = *c.Height
+	}
+
+	r := btcjson.NormalizeNameResult{
+		Name:           c.Name,
+		NormalizedName: string(normalization.NormalizeIfNecessary([]byte(c.Name), height)),
+		Height:         height,
+		Normalized:     normalization.IsNormalizationActive(height),
+	}
+	return r, nil
+}
+
+// createVoutClaim returns a JSON object for the claim, update or support
+// created by the passed output, or nil when its claim script can't be decoded.
+func createVoutClaim(outpoint *wire.OutPoint, pkScript []byte) *btcjson.VoutClaimResult {
+	cs, err := txscript.ExtractClaimScript(pkScript)
+	if err != nil {
+		return nil
+	}
+
+	var id change.ClaimID
+	if cs.Opcode == txscript.OP_CLAIMNAME {
+		id = change.NewClaimID(*outpoint)
+	} else {
+		copy(id[:], cs.ClaimID)
+	}
+
+	value, claimMetadata := claimValueResult(cs.Value)
+	result := &btcjson.VoutClaimResult{
+		Name:     string(cs.Name),
+		ClaimID:  id.String(),
+		Value:    value,
+		Metadata: claimMetadata,
+	}
+
+	// Claims paid to a time-locked script can't be updated or abandoned
+	// until the lock time.
+	if lock := txscript.ExtractTimeLock(pkScript[cs.Size:]); lock != nil {
+		lockType := "checklocktimeverify"
+		if lock.Opcode == txscript.OP_CHECKSEQUENCEVERIFY {
+			lockType = "checksequenceverify"
+		}
+		result.TimeLock = &btcjson.ClaimTimeLockResult{
+			Type:     lockType,
+			LockTime: lock.LockTime,
+		}
+	}
+	return result
+}
+
+// claimValueResult returns the hex encoding of the passed claim value or, when
+// the decoding of claims is enabled, the metadata decoded from it.  The value
+// is still returned hex-encoded when it can't be decoded, such as the values of
+// supports and those using legacy formats.
+func claimValueResult(value []byte) (string, *btcjson.ClaimMetadataResult) {
+	if !cfg.DecodeClaims || len(value) == 0 {
+		return hex.EncodeToString(value), nil
+	}
+	claim, err := metadata.Decode(value)
+	if err != nil {
+		return hex.EncodeToString(value), nil
+	}
+
+	result := &btcjson.ClaimMetadataResult{
+		Type:        string(claim.Type),
+		Title:       claim.Title,
+		Description: claim.Description,
+		Thumbnail:   claim.Thumbnail,
+		Tags:        claim.Tags,
+	}
+	if claim.SigningChannelID != nil {
+		result.SigningChannelID = claim.SigningChannelID.String()
+	}
+	if claim.Repost != nil {
+		result.RepostedClaimID = claim.Repost.String()
+	}
+	for _, id := range claim.Collection {
+		result.Collection = append(result.Collection, id.String())
+	}
+	if stream := claim.Stream; stream != nil {
+		result.Stream = &btcjson.StreamMetadataResult{
+			MediaType:   stream.MediaType,
+			FileName:    stream.FileName,
+			FileSize:    stream.FileSize,
+			Author:      stream.Author,
+			License:     stream.License,
+			LicenseURL:  stream.LicenseURL,
+			ReleaseTime: stream.ReleaseTime,
+		}
+		if fee := stream.Fee; fee != nil {
+			// Fees in USD are expressed in cents, and in the smallest
+			// units of the other currencies.
+			unit := float64(btcutil.SatoshiPerBitcoin)
+			if fee.Currency == "USD" {
+				unit = 100
+			}
+			result.Stream.Fee = &btcjson.ClaimFeeResult{
+				Currency: fee.Currency,
+				Amount:   float64(fee.Amount) / unit,
+			}
+			if len(fee.Address) != 0 {
+				result.Stream.Fee.Address = base58.Encode(fee.Address)
+			}
+		}
+	}
+	if channel := claim.Channel; channel != nil {
+		result.Channel = &btcjson.ChannelMetadataResult{
+			PublicKey:  hex.EncodeToString(channel.PublicKey),
+			Email:      channel.Email,
+			WebsiteURL: channel.WebsiteURL,
+			Cover:      channel.Cover,
+		}
+	}
+	return "", result
+}
@@ -0,0 +1,186 @@
+package rpcclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/wire"
+)
+
+// ErrBlockStreamInProgress is an error to describe the condition where the
+// caller is trying to stream a block which the client is already streaming.
+var ErrBlockStreamInProgress = errors.New("the block is already being streamed")
+
+// blockStream reassembles a block streamed with the getblockstream command
+// from the blockchunk notifications received before its reply.
+type blockStream struct {
+	data     []byte
+	received int32
+	err      error
+}
+
+// addChunk adds the passed chunk to the stream.  Chunks are sent in order, so a
+// chunk at offset zero restarts the stream, which happens when the request is
+// sent again after a reconnect.
+func (s *blockStream) addChunk(ntfn *btcjson.BlockChunkNtfn) {
+	if ntfn.Offset == 0 {
+		s.data = nil
+		s.received = 0
+		s.err = nil
+	}
+	if s.err != nil {
+		return
+	}
+
+	chunk, err := hex.DecodeString(ntfn.Data)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if s.data == nil {
+		if ntfn.Size < 0 || ntfn.Size > wire.MaxBlockPayload {
+			s.err = fmt.Errorf("invalid block size %d", ntfn.Size)
+			return
+		}
+		s.data = make([]byte, ntfn.Size)
+	}
+	if ntfn.Offset != s.received || ntfn.Size != int32(len(s.data)) ||
+		len(chunk) > len(s.data)-int(s.received) {
+
+		s.err = fmt.Errorf("unexpected block chunk at offset %d of "+
+			"size %d", ntfn.Offset, len(chunk))
+		return
+	}
+	s.received += int32(copy(s.data[s.received:], chunk))
+}
+
+// handleBlockChunk adds the chunk of the passed blockchunk notification to the
+// stream of its block.  It returns false when the block isn't being streamed.
+func (c *Client) handleBlockChunk(ntfn *rawNotification) bool {
+	if len(ntfn.Params) != 4 {
+		return false
+	}
+	var chunk btcjson.BlockChunkNtfn
+	fields := []interface{}{&chunk.Hash, &chunk.Offset, &chunk.Size,
+		&chunk.Data}
+	for i, field := range fields {
+		if err := json.Unmarshal(ntfn.Params[i], field); err != nil {
+			return false
+		}
+	}
+	hash, err := chainhash.NewHashFromStr(chunk.Hash)
+	if err != nil {
+		return false
+	}
+
+	c.blockStreamsLock.Lock()
+	defer c.blockStreamsLock.Unlock()
+	stream, ok := c.blockStreams[*hash]
+	if !ok {
+		return false
+	}
+	stream.addChunk(&chunk)
+	return true
+}
+
+// FutureGetBlockStreamResult is a future promise to deliver the result of a
+// GetBlockStreamAsync RPC invocation (or an applicable error).
+type FutureGetBlockStreamResult struct {
+	client   *Client
+	hash     chainhash.Hash
+	stream   *blockStream
+	Response chan *Response
+}
+
+// Receive waits for the Response promised by the future and returns the block
+// reassembled from the chunks streamed by the server.
+func (r FutureGetBlockStreamResult) Receive() (*wire.MsgBlock, error) {
+	res, err := ReceiveFuture(r.Response)
+	if r.stream == nil {
+		return nil, err
+	}
+
+	r.client.blockStreamsLock.Lock()
+	if r.client.blockStreams[r.hash] == r.stream {
+		delete(r.client.blockStreams, r.hash)
+	}
+	r.client.blockStreamsLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetBlockStreamResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	// The reply is sent after all chunks, so the stream is complete.
+	stream := r.stream
+	if stream.err != nil {
+		return nil, stream.err
+	}
+	if result.Size != int32(len(stream.data)) ||
+		stream.received != result.Size {
+
+		return nil, fmt.Errorf("incomplete block stream: received %d "+
+			"of %d bytes", stream.received, result.Size)
+	}
+
+	var msgBlock wire.MsgBlock
+	err = msgBlock.Deserialize(bytes.NewReader(stream.data))
+	if err != nil {
+		return nil, err
+	}
+	return &msgBlock, nil
+}
+
+// GetBlockStreamAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See GetBlockStream for the blocking version and more details.
+//
+// NOTE: This is an lbcd extension and requires a websocket connection.
+func (c *Client) GetBlockStreamAsync(blockHash *chainhash.Hash, chunkSize *int32) FutureGetBlockStreamResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return FutureGetBlockStreamResult{
+			Response: newFutureError(ErrWebsocketsRequired),
+		}
+	}
+
+	stream := &blockStream{}
+	c.blockStreamsLock.Lock()
+	if _, ok := c.blockStreams[*blockHash]; ok {
+		c.blockStreamsLock.Unlock()
+		return FutureGetBlockStreamResult{
+			Response: newFutureError(ErrBlockStreamInProgress),
+		}
+	}
+	c.blockStreams[*blockHash] = stream
+	c.blockStreamsLock.Unlock()
+
+	cmd := btcjson.NewGetBlockStreamCmd(blockHash.String(), chunkSize)
+	return FutureGetBlockStreamResult{
+		client:   c,
+		hash:     *blockHash,
+		stream:   stream,
+		Response: c.SendCmd(cmd),
+	}
+}
+
+// GetBlockStream returns a raw block from the server given its hash.  Unlike
+// GetBlock, the server streams the block in chunks of at most chunkSize bytes,
+// or its default when nil, which are reassembled as they are received instead
+// of decoding the whole block from a single JSON string.
+//
+// NOTE: This is an lbcd extension and requires a websocket connection.
+func (c *Client) GetBlockStream(blockHash *chainhash.Hash, chunkSize *int32) (*wire.MsgBlock, error) {
+	return c.GetBlockStreamAsync(blockHash, chunkSize).Receive()
+}
@@ -0,0 +1,54 @@
+package rpcclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+// TestBlockStreamAddChunk ensures block streams are reassembled from their
+// chunks, restart on a chunk at offset zero and reject unexpected chunks.
+func TestBlockStreamAddChunk(t *testing.T) {
+	t.Parallel()
+
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05}
+	chunk := func(offset, end int) *btcjson.BlockChunkNtfn {
+		return btcjson.NewBlockChunkNtfn("00", int32(offset),
+			int32(len(data)), hex.EncodeToString(data[offset:end]))
+	}
+
+	var stream blockStream
+	stream.addChunk(chunk(0, 2))
+	stream.addChunk(chunk(2, 4))
+
+	// Chunks at offset zero restart the stream.
+	stream.addChunk(chunk(0, 2))
+	stream.addChunk(chunk(2, 4))
+	stream.addChunk(chunk(4, 5))
+	if stream.err != nil {
+		t.Fatalf("unexpected error: %v", stream.err)
+	}
+	if stream.received != int32(len(data)) || !bytes.Equal(stream.data, data) {
+		t.Fatalf("unexpected stream data %x (%d bytes received), want %x",
+			stream.data, stream.received, data)
+	}
+
+	// Chunks out of order are rejected.
+	stream = blockStream{}
+	stream.addChunk(chunk(0, 2))
+	stream.addChunk(chunk(4, 5))
+	if stream.err == nil {
+		t.Fatalf("chunk out of order was accepted")
+	}
+
+	// Chunks exceeding the block size are rejected.
+	stream = blockStream{}
+	stream.addChunk(chunk(0, 2))
+	stream.addChunk(btcjson.NewBlockChunkNtfn("00", 2, int32(len(data)),
+		"0102030405"))
+	if stream.err == nil {
+		t.Fatalf("chunk exceeding the block size was accepted")
+	}
+}
@@ -406,7 +406,7 @@ func (r FutureGetChainTipsResult) Receive() ([]btcjson.GetChainTipsResult, error
 //
 // See GetChainTips for the blocking version and more details.
 func (c *Client) GetChainTipsAsync() FutureGetChainTipsResult {
-	cmd := btcjson.NewGetChainTipsCmd()
+	cmd := btcjson.NewGetChainTipsCmd(btcjson.Bool(false))
 	return c.SendCmd(cmd)
 }
 
@@ -423,6 +423,45 @@ func (c *Client) GetChainTips() ([]btcjson.GetChainTipsResult, error) {
 	return c.GetChainTipsAsync().Receive()
 }
 
+// FutureGetChainTipsVerboseResult is a future promise to deliver the result of
+// a GetChainTipsVerboseAsync RPC invocation (or an applicable error).
+type FutureGetChainTipsVerboseResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the chain
+// tips along with statistics about the stale forks of the block index.
+func (r FutureGetChainTipsVerboseResult) Receive() (*btcjson.GetChainTipsVerboseResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var chainTips btcjson.GetChainTipsVerboseResult
+	err = json.Unmarshal(res, &chainTips)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chainTips, nil
+}
+
+// GetChainTipsVerboseAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetChainTipsVerbose for the blocking version and more details.
+func (c *Client) GetChainTipsVerboseAsync() FutureGetChainTipsVerboseResult {
+	cmd := btcjson.NewGetChainTipsCmd(btcjson.Bool(true))
+	return c.SendCmd(cmd)
+}
+
+// GetChainTipsVerbose returns the same chain tips as GetChainTips along with
+// statistics about the stale forks of the block index and their pruning.
+//
+// NOTE: This is an lbcd extension.
+func (c *Client) GetChainTipsVerbose() (*btcjson.GetChainTipsVerboseResult, error) {
+	return c.GetChainTipsVerboseAsync().Receive()
+}
+
 // FutureGetDifficultyResult is a future promise to deliver the result of a
 // GetDifficultyAsync RPC invocation (or an applicable error).
 type FutureGetDifficultyResult chan *Response
@@ -728,6 +767,105 @@ func (c *Client) GetBlockHeaderVerbose(blockHash *chainhash.Hash) (*btcjson.GetB
 	return c.GetBlockHeaderVerboseAsync(blockHash).Receive()
 }
 
+// FutureGetBlockHeadersResult is a future promise to deliver the result of a
+// GetBlockHeadersAsync RPC invocation (or an applicable error).
+type FutureGetBlockHeadersResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// consecutive blockheaders requested from the server.
+func (r FutureGetBlockHeadersResult) Receive() ([]wire.BlockHeader, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a string.
+	var bhHex string
+	err = json.Unmarshal(res, &bhHex)
+	if err != nil {
+		return nil, err
+	}
+
+	serializedBHs, err := hex.DecodeString(bhHex)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deserialize the concatenated blockheaders and return them.
+	var bhs []wire.BlockHeader
+	reader := bytes.NewReader(serializedBHs)
+	for reader.Len() > 0 {
+		var bh wire.BlockHeader
+		err = bh.Deserialize(reader)
+		if err != nil {
+			return nil, err
+		}
+		bhs = append(bhs, bh)
+	}
+
+	return bhs, nil
+}
+
+// GetBlockHeadersAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetBlockHeaders for the blocking version and more details.
+func (c *Client) GetBlockHeadersAsync(start btcjson.HashOrHeight, count int32) FutureGetBlockHeadersResult {
+	cmd := btcjson.NewGetBlockHeadersCmd(start, &count, btcjson.Bool(false))
+	return c.SendCmd(cmd)
+}
+
+// GetBlockHeaders returns up to count consecutive blockheaders of the main
+// chain from the server, starting with the block of the given hash or height.
+//
+// See GetBlockHeadersVerbose to retrieve data structures with information about
+// the blocks instead.
+func (c *Client) GetBlockHeaders(start btcjson.HashOrHeight, count int32) ([]wire.BlockHeader, error) {
+	return c.GetBlockHeadersAsync(start, count).Receive()
+}
+
+// FutureGetBlockHeadersVerboseResult is a future promise to deliver the result
+// of a GetBlockHeadersVerboseAsync RPC invocation (or an applicable error).
+type FutureGetBlockHeadersVerboseResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the data
+// structures of the consecutive blockheaders requested from the server.
+func (r FutureGetBlockHeadersVerboseResult) Receive() ([]btcjson.GetBlockHeaderVerboseResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as an array of objects.
+	var bhs []btcjson.GetBlockHeaderVerboseResult
+	err = json.Unmarshal(res, &bhs)
+	if err != nil {
+		return nil, err
+	}
+
+	return bhs, nil
+}
+
+// GetBlockHeadersVerboseAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetBlockHeadersVerbose for the blocking version and more details.
+func (c *Client) GetBlockHeadersVerboseAsync(start btcjson.HashOrHeight, count int32) FutureGetBlockHeadersVerboseResult {
+	cmd := btcjson.NewGetBlockHeadersCmd(start, &count, btcjson.Bool(true))
+	return c.SendCmd(cmd)
+}
+
+// GetBlockHeadersVerbose returns data structures with information about up to
+// count consecutive blockheaders of the main chain from the server, starting
+// with the block of the given hash or height.
+//
+// See GetBlockHeaders to retrieve the blockheaders instead.
+func (c *Client) GetBlockHeadersVerbose(start btcjson.HashOrHeight, count int32) ([]btcjson.GetBlockHeaderVerboseResult, error) {
+	return c.GetBlockHeadersVerboseAsync(start, count).Receive()
+}
+
 // FutureGetMempoolEntryResult is a future promise to deliver the result of a
 // GetMempoolEntryAsync RPC invocation (or an applicable error).
 type FutureGetMempoolEntryResult chan *Response
@@ -767,6 +905,167 @@ func (c *Client) GetMempoolEntry(txHash string) (*btcjson.GetMempoolEntryResult,
 	return c.GetMempoolEntryAsync(txHash).Receive()
 }
 
+// FutureGetMempoolFeeHistogramResult is a future promise to deliver the result
+// of a GetMempoolFeeHistogramAsync RPC invocation (or an applicable error).
+type FutureGetMempoolFeeHistogramResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// histogram of the fee rates of the transactions in the memory pool.
+func (r FutureGetMempoolFeeHistogramResult) Receive() (*btcjson.GetMempoolFeeHistogramResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var histogram btcjson.GetMempoolFeeHistogramResult
+	err = json.Unmarshal(res, &histogram)
+	if err != nil {
+		return nil, err
+	}
+
+	return &histogram, nil
+}
+
+// GetMempoolFeeHistogramAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolFeeHistogram for the blocking version and more details.
+func (c *Client) GetMempoolFeeHistogramAsync() FutureGetMempoolFeeHistogramResult {
+	cmd := btcjson.NewGetMempoolFeeHistogramCmd()
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolFeeHistogram returns the histogram of the fee rates of the
+// transactions in the memory pool, weighted by their virtual size.
+func (c *Client) GetMempoolFeeHistogram() (*btcjson.GetMempoolFeeHistogramResult, error) {
+	return c.GetMempoolFeeHistogramAsync().Receive()
+}
+
+// FutureGetMempoolRelativesResult is a future promise to deliver the result of
+// a GetMempoolAncestorsAsync or GetMempoolDescendantsAsync RPC invocation (or
+// an applicable error).
+type FutureGetMempoolRelativesResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the hashes
+// of the in-mempool ancestors or descendants of the transaction.
+func (r FutureGetMempoolRelativesResult) Receive() ([]*chainhash.Hash, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the result as an array of strings.
+	var txHashStrs []string
+	err = json.Unmarshal(res, &txHashStrs)
+	if err != nil {
+		return nil, err
+	}
+
+	txHashes := make([]*chainhash.Hash, 0, len(txHashStrs))
+	for _, hashStr := range txHashStrs {
+		txHash, err := chainhash.NewHashFromStr(hashStr)
+		if err != nil {
+			return nil, err
+		}
+		txHashes = append(txHashes, txHash)
+	}
+
+	return txHashes, nil
+}
+
+// FutureGetMempoolRelativesVerboseResult is a future promise to deliver the
+// result of a GetMempoolAncestorsVerboseAsync or
+// GetMempoolDescendantsVerboseAsync RPC invocation (or an applicable error).
+type FutureGetMempoolRelativesVerboseResult chan *Response
+
+// Receive waits for the Response promised by the future and returns a map of
+// transaction hashes to the mempool entries of the in-mempool ancestors or
+// descendants of the transaction.
+func (r FutureGetMempoolRelativesVerboseResult) Receive() (map[string]btcjson.GetMempoolEntryResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]btcjson.GetMempoolEntryResult
+	err = json.Unmarshal(res, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetMempoolAncestorsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolAncestors for the blocking version and more details.
+func (c *Client) GetMempoolAncestorsAsync(txHash string) FutureGetMempoolRelativesResult {
+	cmd := btcjson.NewGetMempoolAncestorsCmd(txHash, btcjson.Bool(false))
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolAncestors returns the hashes of the in-mempool ancestors of the
+// transaction in the memory pool given its hash.
+//
+// See GetMempoolAncestorsVerbose to retrieve their mempool entries instead.
+func (c *Client) GetMempoolAncestors(txHash string) ([]*chainhash.Hash, error) {
+	return c.GetMempoolAncestorsAsync(txHash).Receive()
+}
+
+// GetMempoolAncestorsVerboseAsync returns an instance of a type that can be
+// used to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolAncestorsVerbose for the blocking version and more details.
+func (c *Client) GetMempoolAncestorsVerboseAsync(txHash string) FutureGetMempoolRelativesVerboseResult {
+	cmd := btcjson.NewGetMempoolAncestorsCmd(txHash, btcjson.Bool(true))
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolAncestorsVerbose returns a map of transaction hashes to the
+// mempool entries of the in-mempool ancestors of the transaction in the memory
+// pool given its hash.
+func (c *Client) GetMempoolAncestorsVerbose(txHash string) (map[string]btcjson.GetMempoolEntryResult, error) {
+	return c.GetMempoolAncestorsVerboseAsync(txHash).Receive()
+}
+
+// GetMempoolDescendantsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolDescendants for the blocking version and more details.
+func (c *Client) GetMempoolDescendantsAsync(txHash string) FutureGetMempoolRelativesResult {
+	cmd := btcjson.NewGetMempoolDescendantsCmd(txHash, btcjson.Bool(false))
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolDescendants returns the hashes of the in-mempool descendants of
+// the transaction in the memory pool given its hash.
+//
+// See GetMempoolDescendantsVerbose to retrieve their mempool entries instead.
+func (c *Client) GetMempoolDescendants(txHash string) ([]*chainhash.Hash, error) {
+	return c.GetMempoolDescendantsAsync(txHash).Receive()
+}
+
+// GetMempoolDescendantsVerboseAsync returns an instance of a type that can be
+// used to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolDescendantsVerbose for the blocking version and more details.
+func (c *Client) GetMempoolDescendantsVerboseAsync(txHash string) FutureGetMempoolRelativesVerboseResult {
+	cmd := btcjson.NewGetMempoolDescendantsCmd(txHash, btcjson.Bool(true))
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolDescendantsVerbose returns a map of transaction hashes to the
+// mempool entries of the in-mempool descendants of the transaction in the
+// memory pool given its hash.
+func (c *Client) GetMempoolDescendantsVerbose(txHash string) (map[string]btcjson.GetMempoolEntryResult, error) {
+	return c.GetMempoolDescendantsVerboseAsync(txHash).Receive()
+}
+
 // FutureGetRawMempoolResult is a future promise to deliver the result of a
 // GetRawMempoolAsync RPC invocation (or an applicable error).
 type FutureGetRawMempoolResult chan *Response
@@ -1194,6 +1493,39 @@ func (c *Client) InvalidateBlock(blockHash *chainhash.Hash) error {
 	return c.InvalidateBlockAsync(blockHash).Receive()
 }
 
+// FutureReconsiderBlockResult is a future promise to deliver the result of a
+// ReconsiderBlockAsync RPC invocation (or an applicable error).
+type FutureReconsiderBlockResult chan *Response
+
+// Receive waits for the Response promised by the future and returns an error
+// if the block could not be reconsidered.
+func (r FutureReconsiderBlockResult) Receive() error {
+	_, err := ReceiveFuture(r)
+
+	return err
+}
+
+// ReconsiderBlockAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See ReconsiderBlock for the blocking version and more details.
+func (c *Client) ReconsiderBlockAsync(blockHash *chainhash.Hash) FutureReconsiderBlockResult {
+	hash := ""
+	if blockHash != nil {
+		hash = blockHash.String()
+	}
+
+	cmd := btcjson.NewReconsiderBlockCmd(hash)
+	return c.SendCmd(cmd)
+}
+
+// ReconsiderBlock removes the invalid status from a specific block and its
+// descendants and reorganizes to the best valid chain.
+func (c *Client) ReconsiderBlock(blockHash *chainhash.Hash) error {
+	return c.ReconsiderBlockAsync(blockHash).Receive()
+}
+
 // FutureGetCFilterResult is a future promise to deliver the result of a
 // GetCFilterAsync RPC invocation (or an applicable error).
 type FutureGetCFilterResult chan *Response
@@ -0,0 +1,392 @@
+package rpcclient
+
+import (
+	"encoding/json"
+
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+// FutureGetClaimsForNameResult is a future promise to deliver the result of a
+// GetClaimsForNameAsync RPC invocation, or of one of its variants selecting the
+// claims by ID, bid or sequence (or an applicable error).
+type FutureGetClaimsForNameResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// claims of the name.
+func (r FutureGetClaimsForNameResult) Receive() (*btcjson.GetClaimsForNameResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetClaimsForNameResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetClaimsForNameAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetClaimsForName for the blocking version and more details.
+func (c *Client) GetClaimsForNameAsync(name string, hashOrHeight *string,
+	includeValues *bool) FutureGetClaimsForNameResult {
+
+	cmd := btcjson.NewGetClaimsForNameCmd(name, hashOrHeight, includeValues)
+	return c.SendCmd(cmd)
+}
+
+// GetClaimsForName returns the claims of the name in the claim trie at the
+// block of the optional hash or height, or at the best block when it is nil.
+// The values and addresses of the claims are included when includeValues is
+// true.
+func (c *Client) GetClaimsForName(name string, hashOrHeight *string,
+	includeValues *bool) (*btcjson.GetClaimsForNameResult, error) {
+
+	return c.GetClaimsForNameAsync(name, hashOrHeight, includeValues).Receive()
+}
+
+// GetClaimsForNameByIDAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetClaimsForNameByID for the blocking version and more details.
+func (c *Client) GetClaimsForNameByIDAsync(name string, partialClaimIDs []string,
+	hashOrHeight *string, includeValues *bool) FutureGetClaimsForNameResult {
+
+	cmd := btcjson.NewGetClaimsForNameByIDCmd(name, partialClaimIDs,
+		hashOrHeight, includeValues)
+	return c.SendCmd(cmd)
+}
+
+// GetClaimsForNameByID returns the claims of the name whose claim IDs start
+// with one of the partial claim IDs.  See GetClaimsForName for the other
+// parameters.
+func (c *Client) GetClaimsForNameByID(name string, partialClaimIDs []string,
+	hashOrHeight *string, includeValues *bool) (*btcjson.GetClaimsForNameResult, error) {
+
+	return c.GetClaimsForNameByIDAsync(name, partialClaimIDs, hashOrHeight,
+		includeValues).Receive()
+}
+
+// GetClaimsForNameByBidAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetClaimsForNameByBid for the blocking version and more details.
+func (c *Client) GetClaimsForNameByBidAsync(name string, bids []int32,
+	hashOrHeight *string, includeValues *bool) FutureGetClaimsForNameResult {
+
+	cmd := btcjson.NewGetClaimsForNameByBidCmd(name, bids, hashOrHeight,
+		includeValues)
+	return c.SendCmd(cmd)
+}
+
+// GetClaimsForNameByBid returns the claims of the name at the bids, which are
+// their positions when the claims are ordered by effective amount.  See
+// GetClaimsForName for the other parameters.
+func (c *Client) GetClaimsForNameByBid(name string, bids []int32,
+	hashOrHeight *string, includeValues *bool) (*btcjson.GetClaimsForNameResult, error) {
+
+	return c.GetClaimsForNameByBidAsync(name, bids, hashOrHeight,
+		includeValues).Receive()
+}
+
+// GetClaimsForNameBySeqAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetClaimsForNameBySeq for the blocking version and more details.
+func (c *Client) GetClaimsForNameBySeqAsync(name string, sequences []int32,
+	hashOrHeight *string, includeValues *bool) FutureGetClaimsForNameResult {
+
+	cmd := btcjson.NewGetClaimsForNameBySeqCmd(name, sequences, hashOrHeight,
+		includeValues)
+	return c.SendCmd(cmd)
+}
+
+// GetClaimsForNameBySeq returns the claims of the name with the sequences,
+// which are the order in which the claims were made.  See GetClaimsForName for
+// the other parameters.
+func (c *Client) GetClaimsForNameBySeq(name string, sequences []int32,
+	hashOrHeight *string, includeValues *bool) (*btcjson.GetClaimsForNameResult, error) {
+
+	return c.GetClaimsForNameBySeqAsync(name, sequences, hashOrHeight,
+		includeValues).Receive()
+}
+
+// FutureGetClaimByIDResult is a future promise to deliver the result of a
+// GetClaimByIDAsync RPC invocation (or an applicable error).
+type FutureGetClaimByIDResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the most
+// recent output of the claim.
+func (r FutureGetClaimByIDResult) Receive() (*btcjson.GetClaimByIDResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetClaimByIDResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetClaimByIDAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See GetClaimByID for the blocking version and more details.
+func (c *Client) GetClaimByIDAsync(claimID string, includeValues *bool) FutureGetClaimByIDResult {
+	cmd := btcjson.NewGetClaimByIDCmd(claimID, includeValues)
+	return c.SendCmd(cmd)
+}
+
+// GetClaimByID returns the most recent output of the claim with the full claim
+// ID, along with the claim as it stands in the trie while it is active.
+//
+// NOTE: This is an lbcd extension which requires the server to run with the
+// claim ID index.
+func (c *Client) GetClaimByID(claimID string, includeValues *bool) (*btcjson.GetClaimByIDResult, error) {
+	return c.GetClaimByIDAsync(claimID, includeValues).Receive()
+}
+
+// FutureGetClaimsByChannelResult is a future promise to deliver the result of
+// a GetClaimsByChannelAsync RPC invocation (or an applicable error).
+type FutureGetClaimsByChannelResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the claims
+// signed by the channel.
+func (r FutureGetClaimsByChannelResult) Receive() (*btcjson.GetClaimsByChannelResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetClaimsByChannelResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetClaimsByChannelAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetClaimsByChannel for the blocking version and more details.
+func (c *Client) GetClaimsByChannelAsync(channelID string, skip, count *int,
+	includeValues *bool) FutureGetClaimsByChannelResult {
+
+	cmd := btcjson.NewGetClaimsByChannelCmd(channelID, skip, count,
+		includeValues)
+	return c.SendCmd(cmd)
+}
+
+// GetClaimsByChannel returns a page of the unspent claims signed by the channel
+// with the full claim ID, from the most recently created or updated to the
+// oldest, along with the total number of such claims.
+//
+// NOTE: This is an lbcd extension which requires the server to run with the
+// claims by channel index.
+func (c *Client) GetClaimsByChannel(channelID string, skip, count *int,
+	includeValues *bool) (*btcjson.GetClaimsByChannelResult, error) {
+
+	return c.GetClaimsByChannelAsync(channelID, skip, count,
+		includeValues).Receive()
+}
+
+// FutureGetChangesInBlockResult is a future promise to deliver the result of a
+// GetChangesInBlockAsync RPC invocation (or an applicable error).
+type FutureGetChangesInBlockResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the names
+// changed by the block.
+func (r FutureGetChangesInBlockResult) Receive() (*btcjson.GetChangesInBlockResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetChangesInBlockResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetChangesInBlockAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetChangesInBlock for the blocking version and more details.
+func (c *Client) GetChangesInBlockAsync(hashOrHeight *string) FutureGetChangesInBlockResult {
+	cmd := btcjson.NewGetChangesInBlockCmd(hashOrHeight)
+	return c.SendCmd(cmd)
+}
+
+// GetChangesInBlock returns the names whose claims were changed by the block
+// of the optional hash or height, or by the best block when it is nil.
+func (c *Client) GetChangesInBlock(hashOrHeight *string) (*btcjson.GetChangesInBlockResult, error) {
+	return c.GetChangesInBlockAsync(hashOrHeight).Receive()
+}
+
+// FutureGetClaimTrieInfoResult is a future promise to deliver the result of a
+// GetClaimTrieInfoAsync RPC invocation (or an applicable error).
+type FutureGetClaimTrieInfoResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// statistics of the claim trie.
+func (r FutureGetClaimTrieInfoResult) Receive() (*btcjson.GetClaimTrieInfoResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetClaimTrieInfoResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetClaimTrieInfoAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetClaimTrieInfo for the blocking version and more details.
+func (c *Client) GetClaimTrieInfoAsync() FutureGetClaimTrieInfoResult {
+	cmd := btcjson.NewGetClaimTrieInfoCmd()
+	return c.SendCmd(cmd)
+}
+
+// GetClaimTrieInfo returns the statistics of the claim trie and of the
+// databases backing it.
+func (c *Client) GetClaimTrieInfo() (*btcjson.GetClaimTrieInfoResult, error) {
+	return c.GetClaimTrieInfoAsync().Receive()
+}
+
+// FutureGetClaimTrieRootAtHeightResult is a future promise to deliver the
+// result of a GetClaimTrieRootAtHeightAsync RPC invocation (or an applicable
+// error).
+type FutureGetClaimTrieRootAtHeightResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the root of
+// the claim trie at the requested height.
+func (r FutureGetClaimTrieRootAtHeightResult) Receive() (*btcjson.GetClaimTrieRootAtHeightResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetClaimTrieRootAtHeightResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetClaimTrieRootAtHeightAsync returns an instance of a type that can be used
+// to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetClaimTrieRootAtHeight for the blocking version and more details.
+func (c *Client) GetClaimTrieRootAtHeightAsync(height int32) FutureGetClaimTrieRootAtHeightResult {
+	cmd := btcjson.NewGetClaimTrieRootAtHeightCmd(height)
+	return c.SendCmd(cmd)
+}
+
+// GetClaimTrieRootAtHeight returns the root of the claim trie computed at the
+// height, along with whether it matches the one committed to by the header of
+// the block at that height.
+func (c *Client) GetClaimTrieRootAtHeight(height int32) (*btcjson.GetClaimTrieRootAtHeightResult, error) {
+	return c.GetClaimTrieRootAtHeightAsync(height).Receive()
+}
+
+// FutureNormalizeNameResult is a future promise to deliver the result of a
+// NormalizeNameAsync RPC invocation (or an applicable error).
+type FutureNormalizeNameResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// normalized name.
+func (r FutureNormalizeNameResult) Receive() (*btcjson.NormalizeNameResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.NormalizeNameResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// NormalizeNameAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See NormalizeName for the blocking version and more details.
+func (c *Client) NormalizeNameAsync(name string, height *int32) FutureNormalizeNameResult {
+	cmd := btcjson.NewNormalizeNameCmd(name, height)
+	return c.SendCmd(cmd)
+}
+
+// NormalizeName returns the name as it is stored in the claim trie for claims
+// made at the optional height, or in the next block when it is nil.
+func (c *Client) NormalizeName(name string, height *int32) (*btcjson.NormalizeNameResult, error) {
+	return c.NormalizeNameAsync(name, height).Receive()
+}
+
+// FutureSimulateClaimTakeoverResult is a future promise to deliver the result
+// of a SimulateClaimTakeoverAsync RPC invocation (or an applicable error).
+type FutureSimulateClaimTakeoverResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// outcome of the simulated stakes.
+func (r FutureSimulateClaimTakeoverResult) Receive() (*btcjson.SimulateClaimTakeoverResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.SimulateClaimTakeoverResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SimulateClaimTakeoverAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See SimulateClaimTakeover for the blocking version and more details.
+func (c *Client) SimulateClaimTakeoverAsync(name string,
+	stakes []btcjson.SimulatedStake, height *int32) FutureSimulateClaimTakeoverResult {
+
+	cmd := btcjson.NewSimulateClaimTakeoverCmd(name, stakes, height)
+	return c.SendCmd(cmd)
+}
+
+// SimulateClaimTakeover returns the controlling claim of the name, and whether
+// it changes, at the optional height if the hypothetical stakes were made in the
+// next block.  The height defaults to the one at which all the stakes are
+// active when it is nil.
+func (c *Client) SimulateClaimTakeover(name string,
+	stakes []btcjson.SimulatedStake, height *int32) (*btcjson.SimulateClaimTakeoverResult, error) {
+
+	return c.SimulateClaimTakeoverAsync(name, stakes, height).Receive()
+}
@@ -57,6 +57,21 @@ the Receive method on the returned instance will either return the result
 immediately if it has already arrived, or block until it has.  This is useful
 since it provides the caller with greater control over concurrency.
 
+# Cancellation and Deadlines
+
+Every RPC, in both its synchronous and asynchronous form, can be cancelled or
+given a deadline by issuing it through a client derived with WithContext.  The
+derived client shares the connection of the original client, so it is cheap to
+create one per call:
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	block, err := client.WithContext(ctx).GetBlock(blockHash)
+
+When the context is done before the reply is received, the call returns the
+error of the context.  In HTTP POST mode, the underlying HTTP request is aborted
+as well.
+
 # Notifications
 
 The first important part of notifications is to realize that they will only
@@ -73,6 +88,14 @@ Notifications are exposed by the client through the use of callback handlers
 which are setup via a NotificationHandlers instance that is specified by the
 caller when creating the client.
 
+Alternatively, strongly-typed handlers can be registered for each type of
+notification with the generic OnEvent function at any time, without passing
+NotificationHandlers to New.  For example:
+
+	unregister := rpcclient.OnEvent(client, func(e rpcclient.ClaimTrieChanged) {
+		fmt.Println(e.Height, e.Names)
+	})
+
 It is important that these notification handlers complete quickly since they
 are intentionally in the main read loop and will block further reads until
 they complete.  This provides the caller with the flexibility to decide what to
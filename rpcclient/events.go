@@ -0,0 +1,573 @@
+package rpcclient
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// Event is a notification of the server delivered to the handlers registered
+// with OnEvent.  Each type of notification known to this package has its own
+// event type, such as BlockConnected or ClaimTrieChanged.
+type Event interface {
+	// NotificationMethod returns the method of the notification which
+	// delivers the event.
+	NotificationMethod() string
+
+	// parseParams returns the event carried by the parameters of a
+	// notification.
+	parseParams(params []json.RawMessage) (Event, error)
+}
+
+// OnEvent registers the passed function as a handler of the events of type E,
+// which are delivered with the notifications of the matching method.  The
+// handlers are invoked in the order they were registered, after the handler of
+// the notification set in NotificationHandlers, if any.  It returns a function
+// which unregisters the handler.
+//
+// The notifications still require registration with the server, for example
+// with NotifyBlocks for the BlockConnected events, which also works for a client
+// created without NotificationHandlers once a handler is registered.
+//
+// NOTE: Like the notification handlers, the handlers must NOT directly call any
+// blocking calls on the client instance since the input reader goroutine blocks
+// until they have completed.
+//
+// For example, the following logs the names changed by the blocks connected to
+// the best chain:
+//
+//	rpcclient.OnEvent(client, func(e rpcclient.ClaimTrieChanged) {
+//		log.Printf("block %d changed %v", e.Height, e.Names)
+//	})
+func OnEvent[E Event](c *Client, fn func(E)) func() {
+	var zero E
+	handler := &eventHandler{
+		parse: zero.parseParams,
+		fn:    func(e Event) { fn(e.(E)) },
+	}
+	c.events.add(zero.NotificationMethod(), handler)
+	return func() {
+		c.events.remove(zero.NotificationMethod(), handler)
+	}
+}
+
+// eventHandler is a handler registered with OnEvent along with the parser of
+// the events it handles.
+type eventHandler struct {
+	parse func(params []json.RawMessage) (Event, error)
+	fn    func(Event)
+}
+
+// eventHandlers houses the handlers registered with OnEvent by notification
+// method.
+type eventHandlers struct {
+	mtx      sync.RWMutex
+	handlers map[string][]*eventHandler
+}
+
+// add registers the handler for the notifications of the passed method.
+//
+// This function is safe for concurrent access.
+func (h *eventHandlers) add(method string, handler *eventHandler) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if h.handlers == nil {
+		h.handlers = make(map[string][]*eventHandler)
+	}
+	h.handlers[method] = append(h.handlers[method], handler)
+}
+
+// remove unregisters the handler of the notifications of the passed method.
+//
+// This function is safe for concurrent access.
+func (h *eventHandlers) remove(method string, handler *eventHandler) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	handlers := h.handlers[method]
+	for i, registered := range handlers {
+		if registered != handler {
+			continue
+		}
+
+		// Copy the remaining handlers so a dispatch in progress keeps
+		// iterating over the previous ones.
+		remaining := make([]*eventHandler, 0, len(handlers)-1)
+		remaining = append(remaining, handlers[:i]...)
+		remaining = append(remaining, handlers[i+1:]...)
+		if len(remaining) == 0 {
+			delete(h.handlers, method)
+		} else {
+			h.handlers[method] = remaining
+		}
+		return
+	}
+}
+
+// registered returns whether any handler is registered.
+//
+// This function is safe for concurrent access.
+func (h *eventHandlers) registered() bool {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	return len(h.handlers) != 0
+}
+
+// dispatch delivers the event carried by the passed notification to the
+// handlers registered for its method.
+//
+// This function is safe for concurrent access.
+func (h *eventHandlers) dispatch(ntfn *rawNotification) {
+	h.mtx.RLock()
+	handlers := h.handlers[ntfn.Method]
+	h.mtx.RUnlock()
+
+	if len(handlers) == 0 {
+		return
+	}
+
+	event, err := handlers[0].parse(ntfn.Params)
+	if err != nil {
+		log.Warnf("Received invalid %s notification: %v", ntfn.Method,
+			err)
+		return
+	}
+	for _, handler := range handlers {
+		handler.fn(event)
+	}
+}
+
+// notificationsEnabled returns whether the client is interested in
+// notifications, either through the notification handlers it was created
+// with or through the handlers registered with OnEvent.
+func (c *Client) notificationsEnabled() bool {
+	return c.ntfnHandlers != nil || c.events.registered()
+}
+
+// BlockConnected is the event of a block connected to the longest (best)
+// chain.  It's delivered once NotifyBlocks has been called.
+//
+// Deprecated: Use FilteredBlockConnected instead.
+type BlockConnected struct {
+	Hash   *chainhash.Hash
+	Height int32
+	Time   time.Time
+}
+
+// NotificationMethod returns the method of the block connected notification.
+func (BlockConnected) NotificationMethod() string {
+	return btcjson.BlockConnectedNtfnMethod
+}
+
+func (BlockConnected) parseParams(params []json.RawMessage) (Event, error) {
+	hash, height, t, err := parseChainNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return BlockConnected{Hash: hash, Height: height, Time: t}, nil
+}
+
+// FilteredBlockConnected is the event of a block connected to the longest
+// (best) chain along with its transactions relevant to the transaction filter
+// of the client.  It's delivered once NotifyBlocks has been called.
+type FilteredBlockConnected struct {
+	Height       int32
+	Header       *wire.BlockHeader
+	Transactions []*btcutil.Tx
+}
+
+// NotificationMethod returns the method of the filtered block connected
+// notification.
+func (FilteredBlockConnected) NotificationMethod() string {
+	return btcjson.FilteredBlockConnectedNtfnMethod
+}
+
+func (FilteredBlockConnected) parseParams(params []json.RawMessage) (Event, error) {
+	height, header, txs, err := parseFilteredBlockConnectedParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return FilteredBlockConnected{
+		Height:       height,
+		Header:       header,
+		Transactions: txs,
+	}, nil
+}
+
+// BlockDisconnected is the event of a block disconnected from the longest
+// (best) chain.  It's delivered once NotifyBlocks has been called.
+//
+// Deprecated: Use FilteredBlockDisconnected instead.
+type BlockDisconnected struct {
+	Hash   *chainhash.Hash
+	Height int32
+	Time   time.Time
+}
+
+// NotificationMethod returns the method of the block disconnected
+// notification.
+func (BlockDisconnected) NotificationMethod() string {
+	return btcjson.BlockDisconnectedNtfnMethod
+}
+
+func (BlockDisconnected) parseParams(params []json.RawMessage) (Event, error) {
+	hash, height, t, err := parseChainNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return BlockDisconnected{Hash: hash, Height: height, Time: t}, nil
+}
+
+// FilteredBlockDisconnected is the event of a block disconnected from the
+// longest (best) chain.  It's delivered once NotifyBlocks has been called.
+type FilteredBlockDisconnected struct {
+	Height int32
+	Header *wire.BlockHeader
+}
+
+// NotificationMethod returns the method of the filtered block disconnected
+// notification.
+func (FilteredBlockDisconnected) NotificationMethod() string {
+	return btcjson.FilteredBlockDisconnectedNtfnMethod
+}
+
+func (FilteredBlockDisconnected) parseParams(params []json.RawMessage) (Event, error) {
+	height, header, err := parseFilteredBlockDisconnectedParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return FilteredBlockDisconnected{Height: height, Header: header}, nil
+}
+
+// ChainAlert is the event of an anomaly of the block chain detected by the
+// server.  It's delivered once NotifyAlerts has been called.
+type ChainAlert struct {
+	Alert *btcjson.ChainAlertNtfn
+}
+
+// NotificationMethod returns the method of the chain alert notification.
+func (ChainAlert) NotificationMethod() string {
+	return btcjson.ChainAlertNtfnMethod
+}
+
+func (ChainAlert) parseParams(params []json.RawMessage) (Event, error) {
+	alert, err := parseChainAlertParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return ChainAlert{Alert: alert}, nil
+}
+
+// ClaimActivated is the event of the claims activated by a block connected to
+// the longest (best) chain.  It's delivered once NotifyClaimActivated has been
+// called.
+type ClaimActivated struct {
+	Hash   *chainhash.Hash
+	Height int32
+	Claims []btcjson.ClaimStateChange
+}
+
+// NotificationMethod returns the method of the claim activated notification.
+func (ClaimActivated) NotificationMethod() string {
+	return btcjson.ClaimActivatedNtfnMethod
+}
+
+func (ClaimActivated) parseParams(params []json.RawMessage) (Event, error) {
+	hash, height, claims, err := parseClaimStateChangedParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return ClaimActivated{Hash: hash, Height: height, Claims: claims}, nil
+}
+
+// ClaimExpired is the event of the claims expired by a block connected to the
+// longest (best) chain.  It's delivered once NotifyClaimExpired has been
+// called.
+type ClaimExpired struct {
+	Hash   *chainhash.Hash
+	Height int32
+	Claims []btcjson.ClaimStateChange
+}
+
+// NotificationMethod returns the method of the claim expired notification.
+func (ClaimExpired) NotificationMethod() string {
+	return btcjson.ClaimExpiredNtfnMethod
+}
+
+func (ClaimExpired) parseParams(params []json.RawMessage) (Event, error) {
+	hash, height, claims, err := parseClaimStateChangedParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return ClaimExpired{Hash: hash, Height: height, Claims: claims}, nil
+}
+
+// ClaimTrieChanged is the event of a block connected to the longest (best)
+// chain with the root of the claim trie after the block and the names whose
+// nodes were changed by the block.  It's delivered once NotifyClaimTrie has
+// been called.
+type ClaimTrieChanged struct {
+	Hash          *chainhash.Hash
+	Height        int32
+	ClaimTrieRoot *chainhash.Hash
+	Names         []string
+}
+
+// NotificationMethod returns the method of the claim trie changed
+// notification.
+func (ClaimTrieChanged) NotificationMethod() string {
+	return btcjson.ClaimTrieChangedNtfnMethod
+}
+
+func (ClaimTrieChanged) parseParams(params []json.RawMessage) (Event, error) {
+	hash, height, root, names, err := parseClaimTrieChangedParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return ClaimTrieChanged{
+		Hash:          hash,
+		Height:        height,
+		ClaimTrieRoot: root,
+		Names:         names,
+	}, nil
+}
+
+// RecvTx is the event of a transaction receiving funds to a registered address.
+// It's delivered once NotifyReceived, Rescan or RescanEndHeight has been
+// called.
+//
+// Deprecated: Use RelevantTxAccepted instead.
+type RecvTx struct {
+	Tx    *btcutil.Tx
+	Block *btcjson.BlockDetails
+}
+
+// NotificationMethod returns the method of the recvtx notification.
+func (RecvTx) NotificationMethod() string {
+	return btcjson.RecvTxNtfnMethod
+}
+
+func (RecvTx) parseParams(params []json.RawMessage) (Event, error) {
+	tx, block, err := parseChainTxNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return RecvTx{Tx: tx, Block: block}, nil
+}
+
+// RedeemingTx is the event of a transaction spending a registered outpoint.
+// It's delivered once NotifySpent, Rescan or RescanEndHeight has been called.
+//
+// Deprecated: Use RelevantTxAccepted instead.
+type RedeemingTx struct {
+	Tx    *btcutil.Tx
+	Block *btcjson.BlockDetails
+}
+
+// NotificationMethod returns the method of the redeemingtx notification.
+func (RedeemingTx) NotificationMethod() string {
+	return btcjson.RedeemingTxNtfnMethod
+}
+
+func (RedeemingTx) parseParams(params []json.RawMessage) (Event, error) {
+	tx, block, err := parseChainTxNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return RedeemingTx{Tx: tx, Block: block}, nil
+}
+
+// Shutdown is the event of the server shutting down, before it closes the
+// connection.  It's delivered regardless of the registered notifications.
+type Shutdown struct{}
+
+// NotificationMethod returns the method of the shutdown notification.
+func (Shutdown) NotificationMethod() string {
+	return btcjson.ShutdownNtfnMethod
+}
+
+func (Shutdown) parseParams(params []json.RawMessage) (Event, error) {
+	if len(params) != 0 {
+		return nil, wrongNumParams(len(params))
+	}
+	return Shutdown{}, nil
+}
+
+// RelevantTxAccepted is the event of an unmined transaction passing the
+// transaction filter of the client.
+type RelevantTxAccepted struct {
+	Transaction []byte
+}
+
+// NotificationMethod returns the method of the relevant transaction accepted
+// notification.
+func (RelevantTxAccepted) NotificationMethod() string {
+	return btcjson.RelevantTxAcceptedNtfnMethod
+}
+
+func (RelevantTxAccepted) parseParams(params []json.RawMessage) (Event, error) {
+	transaction, err := parseRelevantTxAcceptedParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return RelevantTxAccepted{Transaction: transaction}, nil
+}
+
+// RescanFinished is the event of a rescan started with Rescan or
+// RescanEndHeight which finished.
+//
+// Deprecated: Not used with RescanBlocks.
+type RescanFinished struct {
+	Hash   *chainhash.Hash
+	Height int32
+	Time   time.Time
+}
+
+// NotificationMethod returns the method of the rescan finished notification.
+func (RescanFinished) NotificationMethod() string {
+	return btcjson.RescanFinishedNtfnMethod
+}
+
+func (RescanFinished) parseParams(params []json.RawMessage) (Event, error) {
+	hash, height, t, err := parseRescanProgressParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return RescanFinished{Hash: hash, Height: height, Time: t}, nil
+}
+
+// RescanProgress is the event of the progress of a rescan started with Rescan
+// or RescanEndHeight.
+//
+// Deprecated: Not used with RescanBlocks.
+type RescanProgress struct {
+	Hash   *chainhash.Hash
+	Height int32
+	Time   time.Time
+}
+
+// NotificationMethod returns the method of the rescan progress notification.
+func (RescanProgress) NotificationMethod() string {
+	return btcjson.RescanProgressNtfnMethod
+}
+
+func (RescanProgress) parseParams(params []json.RawMessage) (Event, error) {
+	hash, height, t, err := parseRescanProgressParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return RescanProgress{Hash: hash, Height: height, Time: t}, nil
+}
+
+// TxAccepted is the event of a transaction accepted into the memory pool.  It's
+// delivered once NotifyNewTransactions has been called with the verbose flag
+// set to false.
+type TxAccepted struct {
+	Hash   *chainhash.Hash
+	Amount btcutil.Amount
+}
+
+// NotificationMethod returns the method of the transaction accepted
+// notification.
+func (TxAccepted) NotificationMethod() string {
+	return btcjson.TxAcceptedNtfnMethod
+}
+
+func (TxAccepted) parseParams(params []json.RawMessage) (Event, error) {
+	hash, amount, err := parseTxAcceptedNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return TxAccepted{Hash: hash, Amount: amount}, nil
+}
+
+// TxAcceptedVerbose is the event of a transaction accepted into the memory
+// pool.  It's delivered once NotifyNewTransactions has been called with the
+// verbose flag set to true.
+type TxAcceptedVerbose struct {
+	Details *btcjson.TxRawResult
+}
+
+// NotificationMethod returns the method of the verbose transaction accepted
+// notification.
+func (TxAcceptedVerbose) NotificationMethod() string {
+	return btcjson.TxAcceptedVerboseNtfnMethod
+}
+
+func (TxAcceptedVerbose) parseParams(params []json.RawMessage) (Event, error) {
+	details, err := parseTxAcceptedVerboseNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return TxAcceptedVerbose{Details: details}, nil
+}
+
+// BtcdConnected is the event of a wallet server connecting to or disconnecting
+// from lbcd.
+type BtcdConnected struct {
+	Connected bool
+}
+
+// NotificationMethod returns the method of the lbcd connected notification.
+func (BtcdConnected) NotificationMethod() string {
+	return btcjson.BtcdConnectedNtfnMethod
+}
+
+func (BtcdConnected) parseParams(params []json.RawMessage) (Event, error) {
+	connected, err := parseBtcdConnectedNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return BtcdConnected{Connected: connected}, nil
+}
+
+// AccountBalance is the event of an account balance update of a wallet server.
+type AccountBalance struct {
+	Account   string
+	Balance   btcutil.Amount
+	Confirmed bool
+}
+
+// NotificationMethod returns the method of the account balance notification.
+func (AccountBalance) NotificationMethod() string {
+	return btcjson.AccountBalanceNtfnMethod
+}
+
+func (AccountBalance) parseParams(params []json.RawMessage) (Event, error) {
+	account, balance, confirmed, err := parseAccountBalanceNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return AccountBalance{
+		Account:   account,
+		Balance:   balance,
+		Confirmed: confirmed,
+	}, nil
+}
+
+// WalletLockState is the event of a wallet server locking or unlocking a
+// wallet.
+type WalletLockState struct {
+	Account string
+	Locked  bool
+}
+
+// NotificationMethod returns the method of the wallet lock state notification.
+func (WalletLockState) NotificationMethod() string {
+	return btcjson.WalletLockStateNtfnMethod
+}
+
+func (WalletLockState) parseParams(params []json.RawMessage) (Event, error) {
+	account, locked, err := parseWalletLockStateNtfnParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return WalletLockState{Account: account, Locked: locked}, nil
+}
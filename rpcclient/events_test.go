@@ -0,0 +1,110 @@
+package rpcclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+// testNotification returns the raw notification of the passed command.
+func testNotification(t *testing.T, cmd interface{}) *rawNotification {
+	t.Helper()
+
+	marshalled, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, cmd)
+	if err != nil {
+		t.Fatalf("MarshalCmd: unexpected error: %v", err)
+	}
+	var ntfn rawNotification
+	if err := json.Unmarshal(marshalled, &ntfn); err != nil {
+		t.Fatalf("unable to unmarshal the notification: %v", err)
+	}
+	return &ntfn
+}
+
+// TestOnEvent ensures the events are delivered to the handlers registered with
+// OnEvent after the notification handlers, and no longer once unregistered.
+func TestOnEvent(t *testing.T) {
+	var calls []string
+	c := &Client{clientState: &clientState{
+		ntfnHandlers: &NotificationHandlers{
+			OnClaimTrieChanged: func(*chainhash.Hash, int32,
+				*chainhash.Hash, []string) {
+
+				calls = append(calls, "handlers")
+			},
+		},
+		ntfnState: newNotificationState(),
+	}}
+
+	var got []ClaimTrieChanged
+	unregister := OnEvent(c, func(e ClaimTrieChanged) {
+		calls = append(calls, "event")
+		got = append(got, e)
+	})
+	OnEvent(c, func(e BlockConnected) {
+		t.Fatalf("unexpected block connected event %+v", e)
+	})
+
+	hash := chainhash.DoubleHashH([]byte("block"))
+	root := chainhash.DoubleHashH([]byte("root"))
+	ntfn := testNotification(t, btcjson.NewClaimTrieChangedNtfn(
+		hash.String(), 10, root.String(), []string{"a", "b"}))
+	c.handleNotification(ntfn)
+	if len(calls) != 2 || calls[0] != "handlers" || calls[1] != "event" {
+		t.Fatalf("got calls %v, want the notification handler then "+
+			"the event handler", calls)
+	}
+	e := got[0]
+	if *e.Hash != hash || e.Height != 10 || *e.ClaimTrieRoot != root ||
+		len(e.Names) != 2 || e.Names[0] != "a" || e.Names[1] != "b" {
+
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	// Invalid notifications aren't delivered.
+	ntfn.Params = ntfn.Params[:1]
+	c.handleNotification(ntfn)
+	if len(got) != 1 {
+		t.Fatalf("got %d events after an invalid notification, want 1",
+			len(got))
+	}
+
+	unregister()
+	c.handleNotification(testNotification(t,
+		btcjson.NewClaimTrieChangedNtfn(hash.String(), 11,
+			root.String(), nil)))
+	if len(got) != 1 {
+		t.Fatalf("got %d events after unregistering, want 1", len(got))
+	}
+}
+
+// TestOnEventWithoutHandlers ensures a client created without notification
+// handlers is interested in notifications once an event handler is
+// registered.
+func TestOnEventWithoutHandlers(t *testing.T) {
+	c := &Client{clientState: &clientState{
+		ntfnState: newNotificationState(),
+	}}
+	if c.notificationsEnabled() {
+		t.Fatal("notifications enabled without handlers")
+	}
+
+	var got []Shutdown
+	unregister := OnEvent(c, func(e Shutdown) { got = append(got, e) })
+	if !c.notificationsEnabled() {
+		t.Fatal("notifications disabled with an event handler")
+	}
+	c.handleNotification(&rawNotification{
+		Method: btcjson.ShutdownNtfnMethod,
+	})
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+
+	unregister()
+	if c.notificationsEnabled() {
+		t.Fatal("notifications enabled once the handler was unregistered")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/lbryio/lbcd/btcjson"
 	"github.com/lbryio/lbcd/wire"
 	"github.com/lbryio/lbcutil"
 )
@@ -11,6 +12,15 @@ type eventBlockConected struct {
 	txns   []*lbcutil.Tx
 }
 
+type eventBlockDisconnected struct {
+	height int32
+	header *wire.BlockHeader
+}
+
+type eventTxAccepted struct {
+	tx *btcjson.TxRawResult
+}
+
 type adapter struct {
 	*bridge
 }
@@ -18,3 +28,11 @@ type adapter struct {
 func (a *adapter) onFilteredBlockConnected(height int32, header *wire.BlockHeader, txns []*lbcutil.Tx) {
 	a.eventCh <- &eventBlockConected{height, header, txns}
 }
+
+func (a *adapter) onFilteredBlockDisconnected(height int32, header *wire.BlockHeader) {
+	a.eventCh <- &eventBlockDisconnected{height, header}
+}
+
+func (a *adapter) onTxAcceptedVerbose(tx *btcjson.TxRawResult) {
+	a.eventCh <- &eventTxAccepted{tx}
+}
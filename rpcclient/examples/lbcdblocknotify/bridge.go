@@ -6,17 +6,27 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"os"
-	"os/exec"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/rpcclient"
+)
+
+// direction tells whether a block was connected to or orphaned from the chain.
+type direction int
+
+const (
+	blockConnected direction = 1
+	blockOrphaned  direction = -1
 )
 
 type bridge struct {
 	ctx context.Context
 
+	chain recentChain
+
 	prevJobContext context.Context
 	prevJobCancel  context.CancelFunc
 
@@ -24,21 +34,34 @@ type bridge struct {
 	errorc  chan error
 	wg      sync.WaitGroup
 
-	stratum *stratumClient
+	stratums []*stratumClient
+
+	// pool is used to look up the number of transactions of the blocks
+	// for the custom command.
+	pool *rpcclient.Pool
+
+	customCmd commandTemplate
 
-	customCmd string
+	// claimCmd and claimWebhook are notified of the claims entering the
+	// mempool whose name starts with one of claimPrefixes, or all of them
+	// when there are no prefixes.  The claims are queued on claimCh.
+	claimCmd      commandTemplate
+	claimWebhook  string
+	claimPrefixes []string
+	claimCh       chan *claimInfo
 }
 
-func newBridge(stratumServer, stratumPass, coinid string) *bridge {
+func newBridge(stratumServers []string, stratumPass, coinid string) *bridge {
 
 	s := &bridge{
 		ctx:     context.Background(),
 		eventCh: make(chan interface{}),
 		errorc:  make(chan error),
+		claimCh: make(chan *claimInfo, maxPendingClaims),
 	}
 
-	if len(stratumServer) > 0 {
-		s.stratum = newStratumClient(stratumServer, stratumPass, coinid)
+	for _, server := range stratumServers {
+		s.stratums = append(s.stratums, newStratumClient(server, stratumPass, coinid))
 	}
 
 	return s
@@ -46,25 +69,24 @@ func newBridge(stratumServer, stratumPass, coinid string) *bridge {
 
 func (b *bridge) start() {
 
-	if b.stratum != nil {
-		backoff := time.Second
-		for {
-			err := b.stratum.dial()
-			if err == nil {
-				break
-			}
-			log.Printf("WARN: stratum.dial() error: %s, retry in %s", err, backoff)
-			time.Sleep(backoff)
-			if backoff < 60*time.Second {
-				backoff += time.Second
-			}
+	// Unreachable stratum servers are dialed again when notified, so they
+	// don't hold up the others.
+	for _, c := range b.stratums {
+		if err := c.dial(); err != nil {
+			log.Printf("WARN: stratum.dial() %s error: %s", c.server, err)
 		}
 	}
 
+	go b.notifyClaims()
+
 	for e := range b.eventCh {
 		switch e := e.(type) {
 		case *eventBlockConected:
 			b.handleFilteredBlockConnected(e)
+		case *eventBlockDisconnected:
+			b.handleFilteredBlockDisconnected(e)
+		case *eventTxAccepted:
+			b.handleTxAccepted(e)
 		default:
 			b.errorc <- fmt.Errorf("unknown event type: %T", e)
 			return
@@ -74,12 +96,92 @@ func (b *bridge) start() {
 
 func (b *bridge) handleFilteredBlockConnected(e *eventBlockConected) {
 
+	hash := e.header.BlockHash()
+	if !*quiet {
+		log.Printf("Block connected: %s (%d) %v", hash, e.height, e.header.Timestamp)
+	}
+
+	info := &blockInfo{
+		Hash:     hash.String(),
+		Height:   e.height,
+		PrevHash: e.header.PrevBlock.String(),
+		TxCount:  b.txCount(&hash),
+		Time:     e.header.Timestamp.Unix(),
+	}
+
+	// Blocks replaced without a disconnect notification are orphaned first,
+	// so work built on them is thrown away.
+	connected := recentBlock{hash: hash, height: e.height, info: info}
+	for _, blk := range b.chain.connect(connected, e.header.PrevBlock) {
+		log.Printf("Block orphaned by reorg: %s (%d)", blk.hash, blk.height)
+		b.notify(blockOrphaned, blk.info)
+	}
+
+	b.notify(blockConnected, info)
+}
+
+func (b *bridge) handleFilteredBlockDisconnected(e *eventBlockDisconnected) {
+
+	hash := e.header.BlockHash()
 	if !*quiet {
-		log.Printf("Block connected: %s (%d) %v", e.header.BlockHash(), e.height, e.header.Timestamp)
+		log.Printf("Block disconnected: %s (%d) %v", hash, e.height, e.header.Timestamp)
 	}
 
-	hash := e.header.BlockHash().String()
-	height := e.height
+	info := b.chain.disconnect(hash)
+	if info == nil {
+		info = &blockInfo{
+			Hash:     hash.String(),
+			Height:   e.height,
+			PrevHash: e.header.PrevBlock.String(),
+			TxCount:  b.txCount(&hash),
+			Time:     e.header.Timestamp.Unix(),
+		}
+	}
+	b.notify(blockOrphaned, info)
+}
+
+func (b *bridge) handleTxAccepted(e *eventTxAccepted) {
+
+	for _, info := range extractClaims(e.tx, b.claimPrefixes) {
+		if !*quiet {
+			log.Printf("Claim accepted to mempool: %s %q %s (%s:%d)", info.Type, info.Name, info.ClaimID, info.TxID, info.Vout)
+		}
+
+		// Drop the claim rather than holding up the block notifications
+		// when the command or webhook can't keep up.
+		select {
+		case b.claimCh <- info:
+		default:
+			log.Printf("WARN: too many pending claims, dropping claim %s:%d", info.TxID, info.Vout)
+		}
+	}
+}
+
+// txCount returns the number of transactions of the block, or -1 if it can't
+// be looked up.  It's only looked up for the custom command.
+func (b *bridge) txCount(hash *chainhash.Hash) int {
+	if b.customCmd == nil || b.pool == nil {
+		return -1
+	}
+
+	count := -1
+	err := b.pool.Do(func(c *rpcclient.Client) error {
+		block, err := c.GetBlockVerbose(hash)
+		if err != nil {
+			return err
+		}
+		count = len(block.Tx)
+		return nil
+	})
+	if err != nil {
+		log.Printf("WARN: can't get transaction count of block %s: %s", hash, err)
+	}
+	return count
+}
+
+// notify cancels the jobs on the previous notification, and starts the jobs
+// of the stratum server and custom command for the block.
+func (b *bridge) notify(dir direction, info *blockInfo) {
 
 	// Cancel jobs on previous block. It's safe if they are already done.
 	if b.prevJobContext != nil {
@@ -98,43 +200,53 @@ func (b *bridge) handleFilteredBlockConnected(e *eventBlockConected) {
 	ctx, cancel := context.WithCancel(b.ctx)
 	b.prevJobContext, b.prevJobCancel = ctx, cancel
 
-	if len(b.customCmd) > 0 {
-		go b.execCustomCommand(ctx, hash, height)
+	if b.customCmd != nil {
+		cmdInfo := *info
+		cmdInfo.Direction = int(dir)
+		b.wg.Add(1)
+		go b.execCustomCommand(ctx, &cmdInfo)
 	}
 
-	// Send stratum update block message
-	if b.stratum != nil {
-		go b.stratumUpdateBlock(ctx, hash, height)
+	// Send stratum update block message to all the servers concurrently.
+	msg := stratumUpdateBlockMsg(*stratumPass, *coinid, info.Hash)
+	if dir == blockOrphaned {
+		msg = stratumOrphanBlockMsg(*stratumPass, *coinid, info.Hash)
+	}
+	for _, c := range b.stratums {
+		b.wg.Add(1)
+		go b.stratumUpdateBlock(ctx, c, msg, info.Height)
 	}
 }
 
-func (s *bridge) stratumUpdateBlock(ctx context.Context, hash string, height int32) {
-	s.wg.Add(1)
+func (s *bridge) stratumUpdateBlock(ctx context.Context, c *stratumClient, msg string, height int32) {
 	defer s.wg.Done()
 
-	backoff := time.Second
+	// Each server keeps its own backoff, which is reset once it's reachable.
 	retry := func(err error) {
-		if backoff < 60*time.Second {
-			backoff += time.Second
+		backoff := c.failed(err)
+		log.Printf("WARN: stratum.send() %s on block %d error: %s, retry in %s", c.server, height, err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if err := c.dial(); err != nil {
+			log.Printf("WARN: stratum.dial() %s error: %s", c.server, err)
 		}
-		log.Printf("WARN: stratum.send() on block %d error: %s", height, err)
-		time.Sleep(backoff)
-		s.stratum.dial()
 	}
 
-	msg := stratumUpdateBlockMsg(*stratumPass, *coinid, hash)
-
 	for {
-		switch err := s.stratum.send(ctx, msg); {
+		switch err := c.send(ctx, msg); {
 		case err == nil:
+			c.notified(height)
 			return
 		case errors.Is(err, context.Canceled):
-			log.Printf("INFO: stratum.send() on block %d: %s.", height, err)
+			log.Printf("INFO: stratum.send() %s on block %d: %s.", c.server, height, err)
 			return
 		case errors.Is(err, syscall.EPIPE):
-			errClose := s.stratum.conn.Close()
+			errClose := c.close()
 			if errClose != nil {
-				log.Printf("WARN: stratum.conn.Close() on block %d: %s.", height, errClose)
+				log.Printf("WARN: stratum.conn.Close() %s on block %d: %s.", c.server, height, errClose)
 			}
 			retry(err)
 		case errors.Is(err, net.ErrClosed):
@@ -143,30 +255,13 @@ func (s *bridge) stratumUpdateBlock(ctx context.Context, hash string, height int
 			retry(err)
 		}
 	}
-
 }
 
-func (s *bridge) execCustomCommand(ctx context.Context, hash string, height int32) {
-	s.wg.Add(1)
+func (s *bridge) execCustomCommand(ctx context.Context, info *blockInfo) {
 	defer s.wg.Done()
 
-	cmd := strings.ReplaceAll(s.customCmd, "%s", hash)
-	err := doExecCustomCommand(ctx, cmd)
-	if err != nil {
-		log.Printf("ERROR: execCustomCommand on block %s(%d): %s", hash, height, err)
-	}
-}
-
-func doExecCustomCommand(ctx context.Context, cmd string) error {
-	strs := strings.Split(cmd, " ")
-	path, err := exec.LookPath(strs[0])
-	if errors.Is(err, exec.ErrDot) {
-		err = nil
-	}
+	err := execCommand(ctx, s.customCmd, info)
 	if err != nil {
-		return err
+		log.Printf("ERROR: execCustomCommand on block %s(%d): %s", info.Hash, info.Height, err)
 	}
-	c := exec.CommandContext(ctx, path, strs[1:]...)
-	c.Stdout = os.Stdout
-	return c.Run()
 }
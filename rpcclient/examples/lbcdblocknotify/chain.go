@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+// maxRecentBlocks is the number of most recent blocks remembered to detect
+// reorganizations.
+const maxRecentBlocks = 10
+
+type recentBlock struct {
+	hash   chainhash.Hash
+	height int32
+
+	// info describes the block to the custom command when it's orphaned.
+	info *blockInfo
+}
+
+// recentChain keeps track of the most recent blocks of the chain as notified
+// by lbcd, so blocks which are replaced without a disconnect notification, such
+// as when switching to another lbcd server, are detected.
+type recentChain struct {
+	blocks []recentBlock
+}
+
+// connect records the connected block, whose parent is prevHash, and returns
+// the blocks it replaces, tip first.
+func (c *recentChain) connect(blk recentBlock, prevHash chainhash.Hash) []recentBlock {
+
+	var stale []recentBlock
+
+	// Blocks at the same height or above were replaced.
+	for len(c.blocks) > 0 && c.blocks[len(c.blocks)-1].height >= blk.height {
+		stale = append(stale, c.blocks[len(c.blocks)-1])
+		c.blocks = c.blocks[:len(c.blocks)-1]
+	}
+
+	// The remaining blocks are not ancestors if the tip isn't the parent.
+	// Forget them since it's unknown where the chains fork.
+	if len(c.blocks) > 0 && c.blocks[len(c.blocks)-1].hash != prevHash {
+		for i := len(c.blocks) - 1; i >= 0; i-- {
+			stale = append(stale, c.blocks[i])
+		}
+		c.blocks = c.blocks[:0]
+	}
+
+	c.blocks = append(c.blocks, blk)
+	if len(c.blocks) > maxRecentBlocks {
+		c.blocks = c.blocks[len(c.blocks)-maxRecentBlocks:]
+	}
+
+	return stale
+}
+
+// disconnect forgets the disconnected block, and returns its info if it was
+// remembered.
+func (c *recentChain) disconnect(hash chainhash.Hash) *blockInfo {
+
+	for i := len(c.blocks) - 1; i >= 0; i-- {
+		if c.blocks[i].hash == hash {
+			info := c.blocks[i].info
+			c.blocks = c.blocks[:i]
+			return info
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/txscript"
+)
+
+const (
+	// maxPendingClaims is the number of claims waiting for the custom
+	// command or the webhook before new ones are dropped.
+	maxPendingClaims = 1000
+
+	// webhookTimeout is the maximum time the webhook may take to answer.
+	webhookTimeout = 10 * time.Second
+)
+
+// claimInfo describes a claim, update or support entering the mempool to the
+// claim command, both as the data of its template and as environment
+// variables, and to the webhook as JSON.  The name is only passed to the
+// command as an environment variable since anyone can choose it.
+type claimInfo struct {
+	TxID    string  `json:"txid"`
+	Vout    uint32  `json:"vout"`
+	Type    string  `json:"type"` // claimname, updateclaim or supportclaim.
+	Name    string  `json:"name"`
+	ClaimID string  `json:"claimid"`
+	Amount  float64 `json:"amount"` // Amount in LBC.
+}
+
+// environ returns the environment variables describing the claim.
+func (i *claimInfo) environ() []string {
+	return []string{
+		"LBCD_CLAIM_TXID=" + i.TxID,
+		"LBCD_CLAIM_VOUT=" + strconv.FormatUint(uint64(i.Vout), 10),
+		"LBCD_CLAIM_TYPE=" + i.Type,
+		"LBCD_CLAIM_NAME=" + i.Name,
+		"LBCD_CLAIM_ID=" + i.ClaimID,
+		"LBCD_CLAIM_AMOUNT=" + strconv.FormatFloat(i.Amount, 'f', -1, 64),
+	}
+}
+
+// claimArgs holds the fields of a claim which are substituted in the arguments
+// of the claim command.  They are either numbers or hexadecimal strings, so
+// they can't be taken for options of the command.
+type claimArgs struct {
+	TxID    string
+	Vout    uint32
+	Type    string
+	ClaimID string
+	Amount  float64
+}
+
+// templateData returns the data of the claim command template, which leaves
+// out the name of the claim.
+func (i *claimInfo) templateData() interface{} {
+	return &claimArgs{
+		TxID:    i.TxID,
+		Vout:    i.Vout,
+		Type:    i.Type,
+		ClaimID: i.ClaimID,
+		Amount:  i.Amount,
+	}
+}
+
+// extractClaims returns the claims, updates and supports created by the
+// outputs of the transaction whose name starts with one of the prefixes, or
+// all of them when there are no prefixes.
+func extractClaims(tx *btcjson.TxRawResult, prefixes []string) []*claimInfo {
+
+	var claims []*claimInfo
+	for _, vout := range tx.Vout {
+		if vout.Claim == nil || !hasPrefix(vout.Claim.Name, prefixes) {
+			continue
+		}
+		script, err := hex.DecodeString(vout.ScriptPubKey.Hex)
+		if err != nil || len(script) == 0 {
+			continue
+		}
+		claims = append(claims, &claimInfo{
+			TxID:    tx.Txid,
+			Vout:    vout.N,
+			Type:    txscript.ClaimScriptType(script[0]).String(),
+			Name:    vout.Claim.Name,
+			ClaimID: vout.Claim.ClaimID,
+			Amount:  vout.Value,
+		})
+	}
+	return claims
+}
+
+// hasPrefix returns whether the name starts with one of the prefixes, or true
+// when there are no prefixes.
+func hasPrefix(name string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// postWebhook posts the claim as JSON to the webhook URL.
+func postWebhook(ctx context.Context, url string, info *claimInfo) error {
+	body, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url,
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// notifyClaims runs the claim command and posts to the webhook for each of the
+// pending claims in turn, so a burst of claims doesn't spawn as many jobs.
+func (b *bridge) notifyClaims() {
+
+	for info := range b.claimCh {
+		if b.claimCmd != nil {
+			err := execCommand(b.ctx, b.claimCmd, info)
+			if err != nil {
+				log.Printf("ERROR: execClaimCommand on claim %s:%d: %s", info.TxID, info.Vout, err)
+			}
+		}
+		if len(b.claimWebhook) > 0 {
+			err := postWebhook(b.ctx, b.claimWebhook, info)
+			if err != nil {
+				log.Printf("ERROR: postWebhook on claim %s:%d: %s", info.TxID, info.Vout, err)
+			}
+		}
+	}
+}
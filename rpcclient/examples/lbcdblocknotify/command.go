@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// blockInfo describes the block of a notification to the custom command, both
+// as the data of its template and as environment variables.
+type blockInfo struct {
+	Hash      string
+	Height    int32
+	PrevHash  string
+	TxCount   int   // Number of transactions, or -1 when unknown.
+	Time      int64 // Block timestamp in seconds since the epoch.
+	Direction int   // 1 when connected, -1 when orphaned.
+}
+
+// environ returns the environment variables describing the block.
+func (i *blockInfo) environ() []string {
+	return []string{
+		"LBCD_BLOCK_HASH=" + i.Hash,
+		"LBCD_BLOCK_HEIGHT=" + strconv.Itoa(int(i.Height)),
+		"LBCD_BLOCK_PREVHASH=" + i.PrevHash,
+		"LBCD_BLOCK_TXCOUNT=" + strconv.Itoa(i.TxCount),
+		"LBCD_BLOCK_TIME=" + strconv.FormatInt(i.Time, 10),
+		"LBCD_BLOCK_DIRECTION=" + strconv.Itoa(i.Direction),
+	}
+}
+
+// templateData returns the data of the command template for the block.
+func (i *blockInfo) templateData() interface{} {
+	return i
+}
+
+// commandInfo describes the subject of a notification to a custom command.
+type commandInfo interface {
+	// environ returns the environment variables describing the subject.
+	environ() []string
+
+	// templateData returns the data of the command template, which only
+	// holds the fields that are safe to pass as arguments.
+	templateData() interface{}
+}
+
+// commandTemplate is a custom command with a template for each of its words,
+// so the values substituted in a word never split it into more arguments.
+type commandTemplate []*template.Template
+
+// parseCommand parses the custom command template, whose words are separated by
+// whitespace outside of the template actions.  Commands without template
+// actions keep using %s for the block hash and %d for the direction.  The
+// template is checked against the passed info, so the fields which can't be
+// substituted are rejected right away.
+func parseCommand(cmd string, info commandInfo) (commandTemplate, error) {
+	if !strings.Contains(cmd, "{{") {
+		cmd = strings.ReplaceAll(cmd, "%s", "{{.Hash}}")
+		cmd = strings.ReplaceAll(cmd, "%d", "{{.Direction}}")
+	}
+
+	words := splitCommand(cmd)
+	if len(words) == 0 {
+		return nil, errors.New("empty command")
+	}
+	tmpl := make(commandTemplate, 0, len(words))
+	for i, word := range words {
+		t, err := template.New(fmt.Sprintf("arg%d", i)).
+			Option("missingkey=error").Parse(word)
+		if err != nil {
+			return nil, err
+		}
+		tmpl = append(tmpl, t)
+	}
+	if _, err := tmpl.args(info); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// splitCommand splits the command into words separated by whitespace, except
+// for the whitespace inside the template actions.
+func splitCommand(cmd string) []string {
+	var words []string
+	var word strings.Builder
+	inAction := false
+	for i := 0; i < len(cmd); i++ {
+		switch {
+		case strings.HasPrefix(cmd[i:], "{{"):
+			inAction = true
+			word.WriteString("{{")
+			i++
+			continue
+		case strings.HasPrefix(cmd[i:], "}}"):
+			inAction = false
+			word.WriteString("}}")
+			i++
+			continue
+		case !inAction && strings.ContainsRune(" \t\r\n\v\f", rune(cmd[i])):
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteByte(cmd[i])
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// args renders each word of the command as one argument for the info.
+func (t commandTemplate) args(info commandInfo) ([]string, error) {
+	data := info.templateData()
+	args := make([]string, 0, len(t))
+	for _, tmpl := range t {
+		var arg strings.Builder
+		if err := tmpl.Execute(&arg, data); err != nil {
+			return nil, err
+		}
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// execCommand runs the custom command for the block or claim, with its info
+// both substituted in its arguments and passed as environment variables.
+func execCommand(ctx context.Context, tmpl commandTemplate, info commandInfo) error {
+	strs, err := tmpl.args(info)
+	if err != nil {
+		return err
+	}
+	if len(strs) == 0 || strs[0] == "" {
+		return errors.New("empty command")
+	}
+	path, err := exec.LookPath(strs[0])
+	if errors.Is(err, exec.ErrDot) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	c := exec.CommandContext(ctx, path, strs[1:]...)
+	c.Env = append(os.Environ(), info.environ()...)
+	c.Stdout = os.Stdout
+	return c.Run()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runCommand runs the command for the info and returns its output.
+func runCommand(t *testing.T, tmpl commandTemplate, info commandInfo) string {
+	t.Helper()
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+	defer out.Close()
+
+	stdout := os.Stdout
+	os.Stdout = out
+	err = execCommand(context.Background(), tmpl, info)
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("execCommand: unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("ReadFile: unexpected error: %v", err)
+	}
+	return string(data)
+}
+
+// TestParseCommand ensures the commands are split into words outside of the
+// template actions, and the fields which can't be substituted are rejected.
+func TestParseCommand(t *testing.T) {
+	block := &blockInfo{Hash: "hash", Height: 10, Direction: -1}
+	tests := []struct {
+		cmd  string
+		info commandInfo
+		want []string
+	}{
+		{
+			cmd:  "echo  {{ .Hash }}\t{{.Height}}-{{ if eq .Direction -1 }}orphaned{{ end }}",
+			info: block,
+			want: []string{"echo", "hash", "10-orphaned"},
+		},
+		{
+			cmd:  "echo %s %d",
+			info: block,
+			want: []string{"echo", "hash", "-1"},
+		},
+		{
+			cmd:  "echo {{.Type}} {{.ClaimID}}",
+			info: &claimInfo{Type: "claimname", ClaimID: "id"},
+			want: []string{"echo", "claimname", "id"},
+		},
+	}
+	for _, test := range tests {
+		tmpl, err := parseCommand(test.cmd, test.info)
+		if err != nil {
+			t.Fatalf("parseCommand(%q): unexpected error: %v", test.cmd, err)
+		}
+		args, err := tmpl.args(test.info)
+		if err != nil {
+			t.Fatalf("args(%q): unexpected error: %v", test.cmd, err)
+		}
+		if !reflect.DeepEqual(args, test.want) {
+			t.Fatalf("parseCommand(%q): got args %q, want %q", test.cmd,
+				args, test.want)
+		}
+	}
+
+	for _, cmd := range []string{"", " \t", "echo {{.Name}}", "echo {{.Unknown}}", "echo {{"} {
+		if _, err := parseCommand(cmd, &claimInfo{}); err == nil {
+			t.Fatalf("parseCommand(%q): unexpected success", cmd)
+		}
+	}
+}
+
+// TestExecCommandArgs ensures the values substituted in the arguments of the
+// command never split them, and the claim name, which anyone can choose, is
+// only passed as an environment variable.
+func TestExecCommandArgs(t *testing.T) {
+	tmpl, err := parseCommand("printf [%s] {{.Hash}} {{.Direction}}", &blockInfo{})
+	if err != nil {
+		t.Fatalf("parseCommand: unexpected error: %v", err)
+	}
+	got := runCommand(t, tmpl, &blockInfo{Hash: "a b\t-n", Direction: -1})
+	if want := "[a b\t-n][-1]"; got != want {
+		t.Fatalf("got output %q, want %q", got, want)
+	}
+
+	tmpl, err = parseCommand("env", &claimInfo{})
+	if err != nil {
+		t.Fatalf("parseCommand: unexpected error: %v", err)
+	}
+	name := "-rf --output /tmp/x name"
+	got = runCommand(t, tmpl, &claimInfo{Name: name, Type: "claimname"})
+	if !strings.Contains(got, "\nLBCD_CLAIM_NAME="+name+"\n") {
+		t.Fatalf("LBCD_CLAIM_NAME=%s not found in the environment:\n%s",
+			name, got)
+	}
+}
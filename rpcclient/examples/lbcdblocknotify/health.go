@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// serveHealth serves the health of the stratum servers notified by the bridge
+// as JSON on addr.
+func serveHealth(addr string, b *bridge) {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		health := make([]stratumHealth, 0, len(b.stratums))
+		for _, c := range b.stratums {
+			health = append(health, c.health())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			log.Printf("WARN: health response error: %s", err)
+		}
+	})
+
+	go func() {
+		err := http.ListenAndServe(addr, mux)
+		b.errorc <- err
+	}()
+}
@@ -3,51 +3,73 @@ package main
 import (
 	"io/ioutil"
 	"log"
-	"path/filepath"
+	"strings"
 
 	"github.com/lbryio/lbcd/rpcclient"
 )
 
-func newLbcdClient(server, user, pass string, notls bool, adpt adapter) *rpcclient.Client {
+func newLbcdPool(servers, user, pass string, notls, notifyTxs bool, adpt adapter) *rpcclient.Pool {
 
 	ntfnHandlers := rpcclient.NotificationHandlers{
-		OnFilteredBlockConnected: adpt.onFilteredBlockConnected,
-	}
-
-	// Config lbcd RPC client with websockets.
-	connCfg := &rpcclient.ConnConfig{
-		Host:       server,
-		Endpoint:   "ws",
-		User:       user,
-		Pass:       pass,
-		DisableTLS: true,
+		OnFilteredBlockConnected:    adpt.onFilteredBlockConnected,
+		OnFilteredBlockDisconnected: adpt.onFilteredBlockDisconnected,
+		OnTxAcceptedVerbose:         adpt.onTxAcceptedVerbose,
 	}
 
+	var cert []byte
 	if !notls {
-		cert, err := ioutil.ReadFile(filepath.Join(lbcdHomeDir, "rpc.cert"))
+		var err error
+		cert, err = ioutil.ReadFile(*rpccert)
 		if err != nil {
 			log.Fatalf("can't read lbcd certificate: %s", err)
 		}
-		connCfg.Certificates = cert
-		connCfg.DisableTLS = false
 	}
 
-	client, err := rpcclient.New(connCfg, &ntfnHandlers)
+	// Config a lbcd RPC client with websockets for each of the servers.
+	var configs []*rpcclient.ConnConfig
+	for _, server := range strings.Split(servers, ",") {
+		configs = append(configs, &rpcclient.ConnConfig{
+			Host:         strings.TrimSpace(server),
+			Endpoint:     "ws",
+			User:         user,
+			Pass:         pass,
+			Certificates: cert,
+			DisableTLS:   notls,
+		})
+	}
+
+	// The pool load-balances requests across the healthy servers, and
+	// moves the notifications to another server when the current one
+	// becomes unreachable.
+	pool, err := rpcclient.NewPool(configs, &ntfnHandlers)
 	if err != nil {
-		log.Fatalf("can't create rpc client: %s", err)
+		log.Fatalf("can't create rpc pool: %s", err)
 	}
 
 	// Register for block connect and disconnect notifications.
-	if err = client.NotifyBlocks(); err != nil {
+	if err = pool.NotifyBlocks(); err != nil {
 		log.Fatalf("can't register block notification: %s", err)
 	}
 
+	// Register for the transactions accepted to the mempool, with their
+	// details so the claims can be told apart without further requests.
+	if notifyTxs {
+		if err = pool.NotifyNewTransactions(true); err != nil {
+			log.Fatalf("can't register transaction notification: %s", err)
+		}
+	}
+
 	// Get the current block count.
-	blockCount, err := client.GetBlockCount()
+	var blockCount int64
+	err = pool.Do(func(c *rpcclient.Client) error {
+		var err error
+		blockCount, err = c.GetBlockCount()
+		return err
+	})
 	if err != nil {
 		log.Fatalf("can't get block count: %s", err)
 	}
 	log.Printf("Current block count: %d", blockCount)
 
-	return client
+	return pool
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -15,51 +16,107 @@ var (
 	defaultCert = filepath.Join(lbcdHomeDir, "rpc.cert")
 )
 var (
-	coinid        = flag.String("coinid", "1425", "Coin ID")
-	stratumServer = flag.String("stratum", "", "Stratum server")
-	stratumPass   = flag.String("stratumpass", "", "Stratum server password")
-	rpcserver     = flag.String("rpcserver", "localhost:9245", "LBCD RPC server")
-	rpcuser       = flag.String("rpcuser", "rpcuser", "LBCD RPC username")
-	rpcpass       = flag.String("rpcpass", "rpcpass", "LBCD RPC password")
-	rpccert       = flag.String("rpccert", defaultCert, "LBCD RPC certificate")
-	notls         = flag.Bool("notls", false, "Connect to LBCD with TLS disabled")
-	run           = flag.String("run", "", "Run custom shell command")
-	quiet         = flag.Bool("quiet", false, "Do not print logs")
+	coinid      = flag.String("coinid", "1425", "Coin ID")
+	stratumPass = flag.String("stratumpass", "", "Stratum server password")
+	rpcserver   = flag.String("rpcserver", "localhost:9245", "LBCD RPC servers (comma separated)")
+	rpcuser     = flag.String("rpcuser", "rpcuser", "LBCD RPC username")
+	rpcpass     = flag.String("rpcpass", "rpcpass", "LBCD RPC password")
+	rpccert     = flag.String("rpccert", defaultCert, "LBCD RPC certificate")
+	notls       = flag.Bool("notls", false, "Connect to LBCD with TLS disabled")
+	run         = flag.String("run", "", "Run custom command, templated with {{.Hash}}, {{.Height}}, {{.PrevHash}}, {{.TxCount}}, {{.Time}} and {{.Direction}} (1 when connected, -1 when orphaned)")
+	quiet       = flag.Bool("quiet", false, "Do not print logs")
+	health      = flag.String("health", "", "Serve the stratum servers health over HTTP on this address")
+
+	claimRun     = flag.String("claimrun", "", "Run custom command for the claims, updates and supports entering the mempool, templated with {{.TxID}}, {{.Vout}}, {{.Type}}, {{.ClaimID}} and {{.Amount}}, with the name in $LBCD_CLAIM_NAME")
+	claimWebhook = flag.String("claimwebhook", "", "POST the claims, updates and supports entering the mempool as JSON to this URL")
+	claimPrefix  = flag.String("claimprefix", "", "Only notify the claims whose name starts with one of these prefixes (comma separated)")
 )
 
+var stratumServers serverList
+
+func init() {
+	flag.Var(&stratumServers, "stratum", "Stratum server (comma separated, may be repeated)")
+}
+
+// serverList is a list of servers which may be specified multiple times, each
+// with a comma separated list.
+type serverList []string
+
+func (l *serverList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *serverList) Set(value string) error {
+	for _, server := range strings.Split(value, ",") {
+		if server = strings.TrimSpace(server); len(server) > 0 {
+			*l = append(*l, server)
+		}
+	}
+	return nil
+}
+
 func main() {
 
 	flag.Parse()
 
 	// Setup notification handler
-	b := newBridge(*stratumServer, *stratumPass, *coinid)
+	b := newBridge(stratumServers, *stratumPass, *coinid)
 
 	if len(*run) > 0 {
-		// Check if ccommand exists.
-		strs := strings.Split(*run, " ")
-		cmd := strs[0]
-		_, err := exec.LookPath(cmd)
+		// Check if ccommand exists, unless it's templated.
+		strs := strings.Fields(*run)
+		if len(strs) > 0 && !strings.Contains(strs[0], "{{") {
+			_, err := exec.LookPath(strs[0])
+			if err != nil {
+				log.Fatalf("ERROR: %s not found: %s", strs[0], err)
+			}
+		}
+		tmpl, err := parseCommand(*run, &blockInfo{})
 		if err != nil {
-			log.Fatalf("ERROR: %s not found: %s", cmd, err)
+			log.Fatalf("ERROR: invalid command template: %s", err)
 		}
-		b.customCmd = *run
+		b.customCmd = tmpl
 	}
 
-	// Start the eventt handler.
-	go b.start()
+	// The mempool is only watched for claims when they are notified.
+	if len(*claimRun) > 0 {
+		tmpl, err := parseCommand(*claimRun, &claimInfo{})
+		if err != nil {
+			log.Fatalf("ERROR: invalid claim command template: %s", err)
+		}
+		b.claimCmd = tmpl
+	}
+	b.claimWebhook = *claimWebhook
+	for _, prefix := range strings.Split(*claimPrefix, ",") {
+		if prefix = strings.TrimSpace(prefix); len(prefix) > 0 {
+			b.claimPrefixes = append(b.claimPrefixes, prefix)
+		}
+	}
+	notifyTxs := b.claimCmd != nil || len(b.claimWebhook) > 0
+
+	if len(*health) > 0 {
+		serveHealth(*health, b)
+	}
 
 	// Adaptater receives lbcd notifications, and emit events.
 	adpt := adapter{b}
 
-	client := newLbcdClient(*rpcserver, *rpcuser, *rpcpass, *notls, adpt)
+	pool := newLbcdPool(*rpcserver, *rpcuser, *rpcpass, *notls, notifyTxs, adpt)
+	b.pool = pool
+
+	// Start the eventt handler once the pool is set, so the block info
+	// can be looked up.
+	go b.start()
 
 	go func() {
 		err := <-b.errorc
-		log.Fatalf("ERROR: %s", err)
-		client.Shutdown()
+		log.Printf("ERROR: %s", err)
+		pool.Shutdown()
+		pool.WaitForShutdown()
+		os.Exit(1)
 	}()
 
-	// Wait until the client either shuts down gracefully (or the user
+	// Wait until the pool either shuts down gracefully (or the user
 	// terminates the process with Ctrl+C).
-	client.WaitForShutdown()
+	pool.WaitForShutdown()
 }
@@ -4,13 +4,37 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync"
+	"time"
+)
+
+const (
+	minStratumBackoff = time.Second
+	maxStratumBackoff = 60 * time.Second
 )
 
 type stratumClient struct {
 	server string
 	passwd string
 	coinid string
-	conn   *net.TCPConn
+
+	mtx        sync.Mutex
+	conn       *net.TCPConn
+	backoff    time.Duration
+	lastNotify time.Time
+	lastHeight int32
+	lastError  string
+	failures   int
+}
+
+// stratumHealth reports how notifying a stratum server is going.
+type stratumHealth struct {
+	Server     string    `json:"server"`
+	Connected  bool      `json:"connected"`
+	LastNotify time.Time `json:"lastnotify"`
+	LastHeight int32     `json:"lastheight"`
+	LastError  string    `json:"lasterror,omitempty"`
+	Failures   int       `json:"failures"`
 }
 
 func newStratumClient(server, passwd, coinid string) *stratumClient {
@@ -31,11 +55,32 @@ func (c *stratumClient) dial() error {
 	if err != nil {
 		return fmt.Errorf("dial tcp: %w", err)
 	}
+
+	c.mtx.Lock()
+	prev := c.conn
 	c.conn = conn
+	c.mtx.Unlock()
+
+	if prev != nil {
+		prev.Close()
+	}
 
 	return nil
 }
 
+func (c *stratumClient) close() error {
+
+	c.mtx.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.mtx.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
 func (c *stratumClient) send(ctx context.Context, msg string) error {
 
 	select {
@@ -44,13 +89,72 @@ func (c *stratumClient) send(ctx context.Context, msg string) error {
 	default:
 	}
 
-	_, err := c.conn.Write([]byte(msg))
+	c.mtx.Lock()
+	conn := c.conn
+	c.mtx.Unlock()
+
+	if conn == nil {
+		return net.ErrClosed
+	}
+
+	_, err := conn.Write([]byte(msg))
 
 	return err
 }
 
+// notified records a successful notification of the block at height, and
+// resets the backoff.
+func (c *stratumClient) notified(height int32) {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.backoff = 0
+	c.lastNotify = time.Now()
+	c.lastHeight = height
+	c.lastError = ""
+	c.failures = 0
+}
+
+// failed records a failed notification, and returns how long to wait before
+// retrying.  The backoff increases from 1s to 60s maximum.
+func (c *stratumClient) failed(err error) time.Duration {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.backoff < maxStratumBackoff {
+		c.backoff += minStratumBackoff
+	}
+	c.lastError = err.Error()
+	c.failures++
+
+	return c.backoff
+}
+
+func (c *stratumClient) health() stratumHealth {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return stratumHealth{
+		Server:     c.server,
+		Connected:  c.conn != nil,
+		LastNotify: c.lastNotify,
+		LastHeight: c.lastHeight,
+		LastError:  c.lastError,
+		Failures:   c.failures,
+	}
+}
+
 func stratumUpdateBlockMsg(stratumPass, coinid, blockHash string) string {
 
 	return fmt.Sprintf(`{"id":1,"method":"mining.update_block","params":[%q,%s,%q]}`,
 		stratumPass, coinid, blockHash)
 }
+
+func stratumOrphanBlockMsg(stratumPass, coinid, blockHash string) string {
+
+	return fmt.Sprintf(`{"id":1,"method":"mining.orphan_block","params":[%q,%s,%q]}`,
+		stratumPass, coinid, blockHash)
+}
@@ -472,3 +472,45 @@ func (c *Client) VersionAsync() FutureVersionResult {
 func (c *Client) Version() (map[string]btcjson.VersionResult, error) {
 	return c.VersionAsync().Receive()
 }
+
+// FutureGetRPCStatsResult is a future promise to deliver the result of a
+// GetRPCStatsAsync RPC invocation (or an applicable error).
+type FutureGetRPCStatsResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// request statistics of the RPC server.
+func (r FutureGetRPCStatsResult) Receive() (*btcjson.GetRPCStatsResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats btcjson.GetRPCStatsResult
+	err = json.Unmarshal(res, &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// GetRPCStatsAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetRPCStats for the blocking version and more details.
+//
+// NOTE: This is an lbcd extension.
+func (c *Client) GetRPCStatsAsync(count int) FutureGetRPCStatsResult {
+	cmd := btcjson.NewGetRPCStatsCmd(&count)
+	return c.SendCmd(cmd)
+}
+
+// GetRPCStats returns the request statistics of the RPC methods since the start
+// of the server and of the clients of the most recent requests, along with the
+// traces of the passed number of most recent requests.
+//
+// NOTE: This is an lbcd extension.
+func (c *Client) GetRPCStats(count int) (*btcjson.GetRPCStatsResult, error) {
+	return c.GetRPCStatsAsync(count).Receive()
+}
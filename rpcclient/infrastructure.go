@@ -7,6 +7,7 @@ package rpcclient
 import (
 	"bytes"
 	"container/list"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -29,6 +30,7 @@ import (
 	"github.com/btcsuite/websocket"
 	"github.com/lbryio/lbcd/btcjson"
 	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
 )
 
 var (
@@ -72,6 +74,12 @@ var (
 	// client having already connected to the RPC server.
 	ErrClientAlreadyConnected = errors.New("websocket client has already " +
 		"connected")
+
+	// ErrBatchRequiresHTTPPost is an error to describe the condition of
+	// sending a batch request with a client which has been configured to
+	// use websockets instead of HTTP POST mode.
+	ErrBatchRequiresHTTPPost = errors.New("http post mode is required to " +
+		"send batch requests")
 )
 
 const (
@@ -100,6 +108,14 @@ type jsonRequest struct {
 	cmd            interface{}
 	marshalledJSON []byte
 	responseChan   chan *Response
+
+	// ctx is the context of the client which sent the request.  The
+	// request is abandoned when it's done.
+	ctx context.Context
+
+	// batch indicates the request is a JSON-RPC 2.0 batch request, whose
+	// reply is an array of responses.
+	batch bool
 }
 
 // BackendVersion represents the version of the backend the client is currently
@@ -130,7 +146,23 @@ const (
 // result of the invocation at some future time.  Invoking the Receive method on
 // the returned future will block until the result is available if it's not
 // already.
+//
+// Requests can be cancelled and given deadlines by issuing them through a
+// client derived with WithContext.  For example, the following aborts a
+// GetBlock call when ctx is done:
+//
+//	block, err := client.WithContext(ctx).GetBlock(hash)
 type Client struct {
+	*clientState
+
+	// ctx is the context of the requests sent by the client.  It is nil
+	// for clients which weren't derived with WithContext.
+	ctx context.Context
+}
+
+// clientState houses the connection state of a Client, which is shared by all
+// of the clients derived from it with WithContext.
+type clientState struct {
 	id uint64 // atomic, so must stay 64-bit aligned
 
 	// config holds the connection configuration assoiated with this client.
@@ -176,6 +208,11 @@ type Client struct {
 	ntfnHandlers  *NotificationHandlers
 	ntfnStateLock sync.Mutex
 	ntfnState     *notificationState
+	events        eventHandlers
+
+	// Blocks being streamed with getblockstream by hash.
+	blockStreamsLock sync.Mutex
+	blockStreams     map[chainhash.Hash]*blockStream
 
 	// Networking infrastructure.
 	sendChan        chan []byte
@@ -186,6 +223,29 @@ type Client struct {
 	wg              sync.WaitGroup
 }
 
+// WithContext returns a client sharing the connection of c which sends its
+// requests with the passed context.  Requests of the returned client that are
+// still in flight when the context is done are abandoned and their futures
+// return the error of the context.  In HTTP POST mode, the underlying HTTP
+// request is aborted as well.
+//
+// Shutting down or disconnecting the returned client affects the connection
+// shared with c.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	if ctx == nil {
+		panic("rpcclient: nil context")
+	}
+	return &Client{clientState: c.clientState, ctx: ctx}
+}
+
+// context returns the context of the requests sent by the client.
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // NextID returns the next id to be used when sending a JSON-RPC message.  This
 // ID allows responses to be associated with particular requests per the
 // JSON-RPC specification.  Typically the consumer of the client does not need
@@ -263,7 +323,7 @@ func (c *Client) removeAllRequests() {
 // to automatically re-establish registered notifications on reconnects.
 func (c *Client) trackRegisteredNtfns(cmd interface{}) {
 	// Nothing to do if the caller is not interested in notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return
 	}
 
@@ -271,9 +331,21 @@ func (c *Client) trackRegisteredNtfns(cmd interface{}) {
 	defer c.ntfnStateLock.Unlock()
 
 	switch bcmd := cmd.(type) {
+	case *btcjson.NotifyAlertsCmd:
+		c.ntfnState.notifyAlerts = true
+
 	case *btcjson.NotifyBlocksCmd:
 		c.ntfnState.notifyBlocks = true
 
+	case *btcjson.NotifyClaimActivatedCmd:
+		c.ntfnState.notifyClaimActivated = true
+
+	case *btcjson.NotifyClaimExpiredCmd:
+		c.ntfnState.notifyClaimExpired = true
+
+	case *btcjson.NotifyClaimTrieCmd:
+		c.ntfnState.notifyClaimTrie = true
+
 	case *btcjson.NotifyNewTransactionsCmd:
 		if bcmd.Verbose != nil && *bcmd.Verbose {
 			c.ntfnState.notifyNewTxVerbose = true
@@ -291,6 +363,61 @@ func (c *Client) trackRegisteredNtfns(cmd interface{}) {
 		for _, addr := range bcmd.Addresses {
 			c.ntfnState.notifyReceived[addr] = struct{}{}
 		}
+
+	case *btcjson.LoadTxFilterCmd:
+		if bcmd.Reload {
+			c.ntfnState.txFilterAddrs = make(map[string]struct{})
+			c.ntfnState.txFilterOutPoints =
+				make(map[btcjson.OutPoint]struct{})
+			c.ntfnState.txFilterDescriptors =
+				make(map[string]struct{})
+			c.ntfnState.txFilterClaimNames = make(map[string]struct{})
+		}
+		for _, addr := range bcmd.Addresses {
+			c.ntfnState.txFilterAddrs[addr] = struct{}{}
+		}
+		for _, op := range bcmd.OutPoints {
+			c.ntfnState.txFilterOutPoints[op] = struct{}{}
+		}
+		if bcmd.Descriptors != nil {
+			for _, desc := range *bcmd.Descriptors {
+				c.ntfnState.txFilterDescriptors[desc] = struct{}{}
+			}
+		}
+		if bcmd.ClaimNames != nil {
+			for _, name := range *bcmd.ClaimNames {
+				c.ntfnState.txFilterClaimNames[name] = struct{}{}
+			}
+		}
+
+	case *btcjson.StopNotifyAlertsCmd:
+		c.ntfnState.notifyAlerts = false
+
+	case *btcjson.StopNotifyBlocksCmd:
+		c.ntfnState.notifyBlocks = false
+
+	case *btcjson.StopNotifyClaimActivatedCmd:
+		c.ntfnState.notifyClaimActivated = false
+
+	case *btcjson.StopNotifyClaimExpiredCmd:
+		c.ntfnState.notifyClaimExpired = false
+
+	case *btcjson.StopNotifyClaimTrieCmd:
+		c.ntfnState.notifyClaimTrie = false
+
+	case *btcjson.StopNotifyNewTransactionsCmd:
+		c.ntfnState.notifyNewTx = false
+		c.ntfnState.notifyNewTxVerbose = false
+
+	case *btcjson.StopNotifySpentCmd:
+		for _, op := range bcmd.OutPoints {
+			delete(c.ntfnState.notifySpent, op)
+		}
+
+	case *btcjson.StopNotifyReceivedCmd:
+		for _, addr := range bcmd.Addresses {
+			delete(c.ntfnState.notifyReceived, addr)
+		}
 	}
 }
 
@@ -403,6 +530,11 @@ func (c *Client) handleMessage(msg []byte) {
 		}
 		// Deliver the notification.
 		log.Tracef("Received notification [%s]", in.Method)
+		if ntfn.Method == btcjson.BlockChunkNtfnMethod &&
+			c.handleBlockChunk(ntfn) {
+
+			return
+		}
 		c.handleNotification(in.rawNotification)
 		return
 	}
@@ -429,13 +561,15 @@ func (c *Client) handleMessage(msg []byte) {
 		return
 	}
 
-	// Since the command was successful, examine it to see if it's a
+	// When the command was successful, examine it to see if it's a
 	// notification, and if is, add it to the notification state so it
 	// can automatically be re-established on reconnect.
-	c.trackRegisteredNtfns(request.cmd)
+	result, err := in.rawResponse.result()
+	if err == nil {
+		c.trackRegisteredNtfns(request.cmd)
+	}
 
 	// Deliver the response.
-	result, err := in.rawResponse.result()
 	request.responseChan <- &Response{result: result, err: err}
 }
 
@@ -555,7 +689,7 @@ func (c *Client) sendMessage(marshalledJSON []byte) {
 // on reconnect by the resendRequests function.
 func (c *Client) reregisterNtfns() error {
 	// Nothing to do if the caller is not interested in notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return nil
 	}
 
@@ -571,6 +705,14 @@ func (c *Client) reregisterNtfns() error {
 	stateCopy := c.ntfnState.Copy()
 	c.ntfnStateLock.Unlock()
 
+	// Reregister notifyalerts if needed.
+	if stateCopy.notifyAlerts {
+		log.Debugf("Reregistering [notifyalerts]")
+		if err := c.NotifyAlerts(); err != nil {
+			return err
+		}
+	}
+
 	// Reregister notifyblocks if needed.
 	if stateCopy.notifyBlocks {
 		log.Debugf("Reregistering [notifyblocks]")
@@ -579,6 +721,30 @@ func (c *Client) reregisterNtfns() error {
 		}
 	}
 
+	// Reregister notifyclaimactivated if needed.
+	if stateCopy.notifyClaimActivated {
+		log.Debugf("Reregistering [notifyclaimactivated]")
+		if err := c.NotifyClaimActivated(); err != nil {
+			return err
+		}
+	}
+
+	// Reregister notifyclaimexpired if needed.
+	if stateCopy.notifyClaimExpired {
+		log.Debugf("Reregistering [notifyclaimexpired]")
+		if err := c.NotifyClaimExpired(); err != nil {
+			return err
+		}
+	}
+
+	// Reregister notifyclaimtrie if needed.
+	if stateCopy.notifyClaimTrie {
+		log.Debugf("Reregistering [notifyclaimtrie]")
+		if err := c.NotifyClaimTrie(); err != nil {
+			return err
+		}
+	}
+
 	// Reregister notifynewtransactions if needed.
 	if stateCopy.notifyNewTx || stateCopy.notifyNewTxVerbose {
 		log.Debugf("Reregistering [notifynewtransactions] (verbose=%v)",
@@ -617,6 +783,46 @@ func (c *Client) reregisterNtfns() error {
 		}
 	}
 
+	// Reload the transaction filter with all of the previously loaded
+	// addresses, outpoints, descriptors and claim names in one command if
+	// needed.
+	if len(stateCopy.txFilterAddrs) > 0 ||
+		len(stateCopy.txFilterOutPoints) > 0 ||
+		len(stateCopy.txFilterDescriptors) > 0 ||
+		len(stateCopy.txFilterClaimNames) > 0 {
+
+		addresses := make([]string, 0, len(stateCopy.txFilterAddrs))
+		for addr := range stateCopy.txFilterAddrs {
+			addresses = append(addresses, addr)
+		}
+		outpoints := make([]btcjson.OutPoint, 0,
+			len(stateCopy.txFilterOutPoints))
+		for op := range stateCopy.txFilterOutPoints {
+			outpoints = append(outpoints, op)
+		}
+		descriptors := make([]string, 0,
+			len(stateCopy.txFilterDescriptors))
+		for desc := range stateCopy.txFilterDescriptors {
+			descriptors = append(descriptors, desc)
+		}
+		claimNames := make([]string, 0, len(stateCopy.txFilterClaimNames))
+		for name := range stateCopy.txFilterClaimNames {
+			claimNames = append(claimNames, name)
+		}
+		log.Debugf("Reloading [loadtxfilter] with %d addresses, %d "+
+			"outpoints, %d descriptors and %d claim names",
+			len(addresses), len(outpoints), len(descriptors),
+			len(claimNames))
+		cmd := btcjson.NewLoadTxFilterCmd(true, addresses, outpoints)
+		if len(descriptors) > 0 || len(claimNames) > 0 {
+			cmd.Descriptors = &descriptors
+			cmd.ClaimNames = &claimNames
+		}
+		if _, err := ReceiveFuture(c.SendCmd(cmd)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -637,6 +843,9 @@ func (c *Client) resendRequests() {
 		c.Disconnect()
 		return
 	}
+	if c.ntfnHandlers != nil && c.ntfnHandlers.OnResubscribed != nil {
+		go c.ntfnHandlers.OnResubscribed()
+	}
 
 	// Since it's possible to block on send and more requests might be
 	// added by the caller while resending, make a copy of all of the
@@ -772,10 +981,12 @@ func (c *Client) handleSendPostMessage(jReq *jsonRequest) {
 	var backoff time.Duration
 	var httpResponse *http.Response
 	tries := 10
+out:
 	for i := 0; tries == 0 || i < tries; i++ {
 		bodyReader := bytes.NewReader(jReq.marshalledJSON)
 		var httpReq *http.Request
-		httpReq, err = http.NewRequest("POST", url, bodyReader)
+		httpReq, err = http.NewRequestWithContext(jReq.ctx, "POST",
+			url, bodyReader)
 		if err != nil {
 			jReq.responseChan <- &Response{result: nil, err: err}
 			return
@@ -797,12 +1008,23 @@ func (c *Client) handleSendPostMessage(jReq *jsonRequest) {
 
 		httpResponse, err = c.httpClient.Do(httpReq)
 		if err != nil {
+			// Don't retry requests which were abandoned.
+			if ctxErr := jReq.ctx.Err(); ctxErr != nil {
+				err = ctxErr
+				break
+			}
+
 			backoff = requestRetryInterval * time.Duration(i+1)
 			if backoff > time.Minute {
 				backoff = time.Minute
 			}
 			log.Debugf("Failed command [%s] with id %d attempt %d. Retrying in %v... \n", jReq.method, jReq.id, i, backoff)
-			time.Sleep(backoff)
+			select {
+			case <-time.After(backoff):
+			case <-jReq.ctx.Done():
+				err = jReq.ctx.Err()
+				break out
+			}
 			continue
 		}
 		defer httpResponse.Body.Close()
@@ -824,7 +1046,7 @@ func (c *Client) handleSendPostMessage(jReq *jsonRequest) {
 	// Try to unmarshal the response as a regular JSON-RPC response.
 	var resp rawResponse
 	var batchResponse json.RawMessage
-	if c.batch {
+	if jReq.batch {
 		err = json.Unmarshal(respBytes, &batchResponse)
 	} else {
 		err = json.Unmarshal(respBytes, &resp)
@@ -839,7 +1061,7 @@ func (c *Client) handleSendPostMessage(jReq *jsonRequest) {
 		return
 	}
 	var res []byte
-	if c.batch {
+	if jReq.batch {
 		// errors must be dealt with downstream since a whole request cannot
 		// "error out" other than through the status code error handled above
 		res, err = batchResponse, nil
@@ -912,6 +1134,32 @@ func newFutureError(err error) chan *Response {
 	return responseChan
 }
 
+// futureWithContext returns a future for the reply to the passed request which
+// is delivered the error of the context of the request instead when it's done
+// before the reply is received.  The request is no longer tracked in that
+// case.
+func (c *Client) futureWithContext(jReq *jsonRequest) chan *Response {
+	done := jReq.ctx.Done()
+	if done == nil {
+		return jReq.responseChan
+	}
+
+	future := make(chan *Response, 1)
+	go func() {
+		select {
+		case r := <-jReq.responseChan:
+			future <- r
+
+		case <-done:
+			if !c.config.HTTPPostMode {
+				c.removeRequest(jReq.id)
+			}
+			future <- &Response{err: jReq.ctx.Err()}
+		}
+	}()
+	return future
+}
+
 // ReceiveFuture receives from the passed futureResult channel to extract a
 // reply or any errors.  The examined errors include an error in the
 // futureResult and the error in the reply from the server.  This will block
@@ -992,11 +1240,12 @@ func (c *Client) SendCmd(cmd interface{}) chan *Response {
 		cmd:            cmd,
 		marshalledJSON: marshalledJSON,
 		responseChan:   responseChan,
+		ctx:            c.context(),
 	}
 
 	c.sendRequest(jReq)
 
-	return responseChan
+	return c.futureWithContext(jReq)
 }
 
 // sendCmdAndWait sends the passed command to the associated server, waits
@@ -1394,8 +1643,9 @@ func dial(config *ConnConfig) (*websocket.Conn, error) {
 
 // New creates a new RPC client based on the provided connection configuration
 // details.  The notification handlers parameter may be nil if you are not
-// interested in receiving notifications and will be ignored if the
-// configuration is set to run in HTTP POST mode.
+// interested in receiving notifications, or receive them through the handlers
+// registered with OnEvent, and will be ignored if the configuration is set to
+// run in HTTP POST mode.
 func New(config *ConnConfig, ntfnHandlers *NotificationHandlers) (*Client, error) {
 	// Either open a websocket connection or create an HTTP client depending
 	// on the HTTP POST mode.  Also, set the notification handlers to nil
@@ -1424,7 +1674,7 @@ func New(config *ConnConfig, ntfnHandlers *NotificationHandlers) (*Client, error
 		}
 	}
 
-	client := &Client{
+	client := &Client{clientState: &clientState{
 		config:          config,
 		wsConn:          wsConn,
 		httpClient:      httpClient,
@@ -1434,12 +1684,13 @@ func New(config *ConnConfig, ntfnHandlers *NotificationHandlers) (*Client, error
 		batchList:       list.New(),
 		ntfnHandlers:    ntfnHandlers,
 		ntfnState:       newNotificationState(),
+		blockStreams:    make(map[chainhash.Hash]*blockStream),
 		sendChan:        make(chan []byte, sendBufferSize),
 		sendPostChan:    make(chan *jsonRequest, sendPostBufferSize),
 		connEstablished: connEstablished,
 		disconnect:      make(chan struct{}),
 		shutdown:        make(chan struct{}),
-	}
+	}}
 
 	// Default network is mainnet, no parameters are necessary but if mainnet
 	// is specified it will be the param
@@ -1648,6 +1899,8 @@ func (c *Client) sendAsync() FutureGetBulkResult {
 		cmd:            nil,
 		marshalledJSON: marshalledRequest,
 		responseChan:   responseChan,
+		batch:          true,
+		ctx:            c.context(),
 	}
 	c.sendPostRequest(&request)
 	return responseChan
@@ -1673,23 +1926,96 @@ func (c *Client) Send() error {
 	}
 
 	for iter := c.batchList.Front(); iter != nil; iter = iter.Next() {
-		var requestError error
 		request := iter.Value.(*jsonRequest)
-		individualResult := result[request.id]
-		fullResult, err := json.Marshal(individualResult.Result)
-		if err != nil {
-			return err
+		request.responseChan <- batchResponse(result, request.id)
+	}
+	return nil
+}
+
+// batchResponse returns the response of the request with the passed id from
+// the results of a batch request.
+func batchResponse(result BulkResult, id uint64) *Response {
+	individualResult, ok := result[id]
+	if !ok {
+		return &Response{err: fmt.Errorf("no response for batched "+
+			"request with id %d", id)}
+	}
+
+	fullResult, err := json.Marshal(individualResult.Result)
+	if err != nil {
+		return &Response{err: err}
+	}
+
+	var requestError error
+	if individualResult.Error != nil {
+		requestError = individualResult.Error
+	}
+
+	return &Response{result: fullResult, err: requestError}
+}
+
+// SendBatch sends the passed commands to the server in a single JSON-RPC 2.0
+// batch request and waits for the reply.  It returns a future for each command,
+// in the same order, holding the response to that command.  The futures can be
+// converted to the future types of their commands to receive typed results,
+// for example FutureGetRawTransactionResult(futures[i]).Receive().
+//
+// Unlike Send, this does not require a client created with NewBatch, but the
+// client must run in HTTP POST mode.
+func (c *Client) SendBatch(cmds ...interface{}) []chan *Response {
+	futures := make([]chan *Response, len(cmds))
+	if !c.config.HTTPPostMode {
+		for i := range futures {
+			futures[i] = newFutureError(ErrBatchRequiresHTTPPost)
 		}
+		return futures
+	}
 
-		if individualResult.Error != nil {
-			requestError = individualResult.Error
+	// Marshal each command as a JSON-RPC 2.0 request.  The commands which
+	// fail to marshal are given their error and left out of the batch.
+	ids := make([]uint64, len(cmds))
+	marshalledRequest := []byte("[")
+	for i, cmd := range cmds {
+		ids[i] = c.NextID()
+		marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion2,
+			ids[i], cmd)
+		if err != nil {
+			futures[i] = newFutureError(err)
+			continue
 		}
+		if len(marshalledRequest) > 1 {
+			marshalledRequest = append(marshalledRequest, ',')
+		}
+		marshalledRequest = append(marshalledRequest, marshalledJSON...)
+	}
+	marshalledRequest = append(marshalledRequest, ']')
+
+	// There is nothing to send when all the commands failed to marshal.
+	if len(marshalledRequest) == 2 {
+		return futures
+	}
+
+	responseChan := make(chan *Response, 1)
+	c.sendPostRequest(&jsonRequest{
+		id:             c.NextID(),
+		method:         "batch",
+		marshalledJSON: marshalledRequest,
+		responseChan:   responseChan,
+		batch:          true,
+		ctx:            c.context(),
+	})
+	result, err := FutureGetBulkResult(responseChan).Receive()
 
-		result := Response{
-			result: fullResult,
-			err:    requestError,
+	for i := range futures {
+		if futures[i] != nil {
+			continue
 		}
-		request.responseChan <- &result
+		futures[i] = make(chan *Response, 1)
+		if err != nil {
+			futures[i] <- &Response{err: err}
+			continue
+		}
+		futures[i] <- batchResponse(result, ids[i])
 	}
-	return nil
+	return futures
 }
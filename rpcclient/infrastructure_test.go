@@ -0,0 +1,225 @@
+package rpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+// TestSendBatch ensures the commands passed to SendBatch are sent in a single
+// batch request and their responses are delivered to the matching futures,
+// including the errors of the individual commands.
+func TestSendBatch(t *testing.T) {
+	var numRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+
+		numRequests++
+		var requests []btcjson.Request
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			t.Errorf("unable to decode batch request: %v", err)
+			return
+		}
+
+		// Reply in reverse order to ensure the responses are matched by
+		// id.
+		var replies []json.RawMessage
+		for i := len(requests) - 1; i >= 0; i-- {
+			req := requests[i]
+			var result interface{}
+			var jsonErr *btcjson.RPCError
+			switch req.Method {
+			case "getblockcount":
+				result = 100
+			default:
+				jsonErr = btcjson.NewRPCError(
+					btcjson.ErrRPCMethodNotFound.Code,
+					"Method not found")
+			}
+			reply, err := btcjson.MarshalResponse(req.Jsonrpc, req.ID,
+				result, jsonErr)
+			if err != nil {
+				t.Errorf("unable to marshal reply: %v", err)
+				return
+			}
+			replies = append(replies, reply)
+		}
+		json.NewEncoder(w).Encode(replies)
+	}))
+	defer server.Close()
+
+	client, err := New(&ConnConfig{
+		Host:         strings.TrimPrefix(server.URL, "http://"),
+		User:         "user",
+		Pass:         "pass",
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	defer client.Shutdown()
+
+	futures := client.SendBatch(btcjson.NewGetBlockCountCmd(),
+		btcjson.NewGetDifficultyCmd(), btcjson.NewGetBlockCountCmd())
+	if numRequests != 1 {
+		t.Fatalf("SendBatch: sent %d requests, want 1", numRequests)
+	}
+	if len(futures) != 3 {
+		t.Fatalf("SendBatch: got %d futures, want 3", len(futures))
+	}
+
+	for _, i := range []int{0, 2} {
+		count, err := FutureGetBlockCountResult(futures[i]).Receive()
+		if err != nil {
+			t.Fatalf("future #%d: unexpected error: %v", i, err)
+		}
+		if count != 100 {
+			t.Fatalf("future #%d: got block count %d, want 100", i,
+				count)
+		}
+	}
+
+	_, err = FutureGetDifficultyResult(futures[1]).Receive()
+	rpcErr, ok := err.(*btcjson.RPCError)
+	if !ok || rpcErr.Code != btcjson.ErrRPCMethodNotFound.Code {
+		t.Fatalf("future #1: unexpected error: %v", err)
+	}
+}
+
+// TestWithContext ensures requests sent through a client derived with
+// WithContext are aborted when the context is done without affecting the
+// other requests of the client.
+func TestWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+
+		var req btcjson.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("unable to decode request: %v", err)
+			return
+		}
+
+		// Hang on getdifficulty until the client gives up.
+		if req.Method == "getdifficulty" {
+			<-r.Context().Done()
+			return
+		}
+
+		reply, err := btcjson.MarshalResponse(req.Jsonrpc, req.ID, 100,
+			nil)
+		if err != nil {
+			t.Errorf("unable to marshal reply: %v", err)
+			return
+		}
+		w.Write(reply)
+	}))
+	defer server.Close()
+
+	client, err := New(&ConnConfig{
+		Host:         strings.TrimPrefix(server.URL, "http://"),
+		User:         "user",
+		Pass:         "pass",
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	defer client.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		100*time.Millisecond)
+	defer cancel()
+	_, err = client.WithContext(ctx).GetDifficulty()
+	if err != context.DeadlineExceeded {
+		t.Fatalf("GetDifficulty: got error %v, want %v", err,
+			context.DeadlineExceeded)
+	}
+
+	// The client keeps serving requests after the abandoned one.
+	count, err := client.GetBlockCount()
+	if err != nil {
+		t.Fatalf("GetBlockCount: unexpected error: %v", err)
+	}
+	if count != 100 {
+		t.Fatalf("GetBlockCount: got %d, want 100", count)
+	}
+
+	// Requests with a context which is already done fail immediately.
+	cancel()
+	_, err = client.WithContext(ctx).GetBlockCount()
+	if err != context.Canceled && err != context.DeadlineExceeded {
+		t.Fatalf("GetBlockCount: unexpected error: %v", err)
+	}
+}
+
+// TestTrackRegisteredNtfns ensures the notification state replayed on reconnect
+// follows the registrations, the reloads of the transaction filter and the
+// cancellations of the client.
+func TestTrackRegisteredNtfns(t *testing.T) {
+	c := &Client{clientState: &clientState{
+		ntfnHandlers: &NotificationHandlers{},
+		ntfnState:    newNotificationState(),
+	}}
+	op1 := btcjson.OutPoint{Hash: strings.Repeat("01", 32), Index: 1}
+	op2 := btcjson.OutPoint{Hash: strings.Repeat("02", 32), Index: 2}
+
+	c.trackRegisteredNtfns(btcjson.NewNotifyBlocksCmd())
+	c.trackRegisteredNtfns(btcjson.NewNotifyClaimTrieCmd())
+	c.trackRegisteredNtfns(btcjson.NewNotifySpentCmd(
+		[]btcjson.OutPoint{op1, op2}))
+	c.trackRegisteredNtfns(btcjson.NewLoadTxFilterCmd(false,
+		[]string{"addr1"}, []btcjson.OutPoint{op1}))
+	c.trackRegisteredNtfns(btcjson.NewLoadTxFilterCmd(false,
+		[]string{"addr2"}, nil))
+	loadCmd := btcjson.NewLoadTxFilterCmd(false, nil, nil)
+	loadCmd.Descriptors = &[]string{"pkh(02aa)"}
+	loadCmd.ClaimNames = &[]string{"@channel"}
+	c.trackRegisteredNtfns(loadCmd)
+
+	state := c.ntfnState.Copy()
+	if !state.notifyBlocks || !state.notifyClaimTrie {
+		t.Fatalf("notifications were not tracked")
+	}
+	if len(state.txFilterAddrs) != 2 || len(state.txFilterOutPoints) != 1 {
+		t.Fatalf("unexpected transaction filter - %d addresses, %d "+
+			"outpoints", len(state.txFilterAddrs),
+			len(state.txFilterOutPoints))
+	}
+	if len(state.txFilterDescriptors) != 1 ||
+		len(state.txFilterClaimNames) != 1 {
+
+		t.Fatalf("unexpected transaction filter - %d descriptors, %d "+
+			"claim names", len(state.txFilterDescriptors),
+			len(state.txFilterClaimNames))
+	}
+
+	// Reloading the transaction filter replaces it, and cancelled
+	// notifications are no longer replayed.
+	c.trackRegisteredNtfns(btcjson.NewLoadTxFilterCmd(true,
+		[]string{"addr3"}, nil))
+	c.trackRegisteredNtfns(btcjson.NewStopNotifyBlocksCmd())
+	c.trackRegisteredNtfns(btcjson.NewStopNotifySpentCmd(
+		[]btcjson.OutPoint{op1}))
+
+	state = c.ntfnState.Copy()
+	if state.notifyBlocks || !state.notifyClaimTrie {
+		t.Fatalf("notifications were not cancelled")
+	}
+	if _, ok := state.notifySpent[op2]; !ok || len(state.notifySpent) != 1 {
+		t.Fatalf("unexpected spent outpoints %v", state.notifySpent)
+	}
+	if _, ok := state.txFilterAddrs["addr3"]; !ok ||
+		len(state.txFilterAddrs) != 1 || len(state.txFilterOutPoints) != 0 ||
+		len(state.txFilterDescriptors) != 0 {
+
+		t.Fatalf("transaction filter was not reloaded")
+	}
+}
@@ -162,7 +162,7 @@ func (r FutureSetGenerateResult) Receive() error {
 //
 // See SetGenerate for the blocking version and more details.
 func (c *Client) SetGenerateAsync(enable bool, numCPUs int) FutureSetGenerateResult {
-	cmd := btcjson.NewSetGenerateCmd(enable, &numCPUs)
+	cmd := btcjson.NewSetGenerateCmd(enable, &numCPUs, nil)
 	return c.SendCmd(cmd)
 }
 
@@ -171,6 +171,32 @@ func (c *Client) SetGenerate(enable bool, numCPUs int) error {
 	return c.SetGenerateAsync(enable, numCPUs).Receive()
 }
 
+// SetGeneratePayoutsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See SetGeneratePayouts for the blocking version and more details.
+func (c *Client) SetGeneratePayoutsAsync(enable bool, numCPUs int,
+	payouts map[btcutil.Address]uint32) FutureSetGenerateResult {
+
+	convertedPayouts := make(map[string]uint32, len(payouts))
+	for addr, weight := range payouts {
+		convertedPayouts[addr.EncodeAddress()] = weight
+	}
+	cmd := btcjson.NewSetGenerateCmd(enable, &numCPUs, &convertedPayouts)
+	return c.SendCmd(cmd)
+}
+
+// SetGeneratePayouts sets the server to generate coins (mine) or not like
+// SetGenerate, and sets the payment addresses to split the coinbase of the
+// generated blocks among proportionally to their weights.  No payouts cause
+// the blocks to be paid to the mining addresses the server is configured with.
+func (c *Client) SetGeneratePayouts(enable bool, numCPUs int,
+	payouts map[btcutil.Address]uint32) error {
+
+	return c.SetGeneratePayoutsAsync(enable, numCPUs, payouts).Receive()
+}
+
 // FutureGetHashesPerSecResult is a future promise to deliver the result of a
 // GetHashesPerSecAsync RPC invocation (or an applicable error).
 type FutureGetHashesPerSecResult chan *Response
@@ -331,6 +357,47 @@ func (c *Client) GetNetworkHashPS3(blocks, height int) (int64, error) {
 	return c.GetNetworkHashPS3Async(blocks, height).Receive()
 }
 
+// FutureGetNextDifficultyResult is a future promise to deliver the result of a
+// GetNextDifficultyAsync RPC invocation (or an applicable error).
+type FutureGetNextDifficultyResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// difficulty required for the next block.
+func (r FutureGetNextDifficultyResult) Receive() (*btcjson.GetNextDifficultyResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a getnextdifficulty result object.
+	var result btcjson.GetNextDifficultyResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetNextDifficultyAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetNextDifficulty for the blocking version and more details.
+func (c *Client) GetNextDifficultyAsync() FutureGetNextDifficultyResult {
+	cmd := btcjson.NewGetNextDifficultyCmd(nil)
+	return c.SendCmd(cmd)
+}
+
+// GetNextDifficulty returns the difficulty required for a block mined on top
+// of the best block at the current time as determined by the difficulty
+// retarget rules.
+//
+// NOTE: This is an lbcd extension.
+func (c *Client) GetNextDifficulty() (*btcjson.GetNextDifficultyResult, error) {
+	return c.GetNextDifficultyAsync().Receive()
+}
+
 // FutureGetWork is a future promise to deliver the result of a
 // GetWorkAsync RPC invocation (or an applicable error).
 type FutureGetWork chan *Response
@@ -497,3 +564,54 @@ func (c *Client) GetBlockTemplateAsync(req *btcjson.TemplateRequest) FutureGetBl
 func (c *Client) GetBlockTemplate(req *btcjson.TemplateRequest) (*btcjson.GetBlockTemplateResult, error) {
 	return c.GetBlockTemplateAsync(req).Receive()
 }
+
+// FutureGetBlockTemplateProposalResponse is a future promise to deliver the
+// result of a GetBlockTemplateProposalAsync RPC invocation (or an applicable
+// error).
+type FutureGetBlockTemplateProposalResponse chan *Response
+
+// Receive waits for the Response promised by the future and returns the result
+// of the validation of the proposed block.
+func (r FutureGetBlockTemplateProposalResponse) Receive() (*btcjson.GetBlockTemplateProposalResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a getblocktemplate proposal result object.
+	var result btcjson.GetBlockTemplateProposalResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetBlockTemplateProposalAsync returns an instance of a type that can be used
+// to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetBlockTemplateProposal for the blocking version and more details.
+func (c *Client) GetBlockTemplateProposalAsync(block *btcutil.Block) FutureGetBlockTemplateProposalResponse {
+	blockBytes, err := block.Bytes()
+	if err != nil {
+		return newFutureError(err)
+	}
+
+	cmd := btcjson.NewGetBlockTemplateCmd(&btcjson.TemplateRequest{
+		Mode:    "proposal",
+		Data:    hex.EncodeToString(blockBytes),
+		Verbose: true,
+	})
+	return c.SendCmd(cmd)
+}
+
+// GetBlockTemplateProposal asks the server to fully validate the passed block,
+// including its claimtrie root, without relaying it, and returns whether it was
+// accepted along with the reason it was rejected otherwise.
+//
+// NOTE: This is an lbcd extension.
+func (c *Client) GetBlockTemplateProposal(block *btcutil.Block) (*btcjson.GetBlockTemplateProposalResult, error) {
+	return c.GetBlockTemplateProposalAsync(block).Receive()
+}
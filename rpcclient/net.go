@@ -452,7 +452,7 @@ func (r FutureGetNetTotalsResult) Receive() (*btcjson.GetNetTotalsResult, error)
 //
 // See GetNetTotals for the blocking version and more details.
 func (c *Client) GetNetTotalsAsync() FutureGetNetTotalsResult {
-	cmd := btcjson.NewGetNetTotalsCmd()
+	cmd := btcjson.NewGetNetTotalsCmd(nil)
 	return c.SendCmd(cmd)
 }
 
@@ -460,3 +460,19 @@ func (c *Client) GetNetTotalsAsync() FutureGetNetTotalsResult {
 func (c *Client) GetNetTotals() (*btcjson.GetNetTotalsResult, error) {
 	return c.GetNetTotalsAsync().Receive()
 }
+
+// GetNetTotalsVerboseAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetNetTotalsVerbose for the blocking version and more details.
+func (c *Client) GetNetTotalsVerboseAsync() FutureGetNetTotalsResult {
+	cmd := btcjson.NewGetNetTotalsCmd(btcjson.Bool(true))
+	return c.SendCmd(cmd)
+}
+
+// GetNetTotalsVerbose returns network traffic statistics including the bytes
+// received and sent by message command.
+func (c *Client) GetNetTotalsVerbose() (*btcjson.GetNetTotalsResult, error) {
+	return c.GetNetTotalsVerboseAsync().Receive()
+}
@@ -32,17 +32,29 @@ var (
 // registered notification so the state can be automatically re-established on
 // reconnect.
 type notificationState struct {
-	notifyBlocks       bool
-	notifyNewTx        bool
-	notifyNewTxVerbose bool
-	notifyReceived     map[string]struct{}
-	notifySpent        map[btcjson.OutPoint]struct{}
+	notifyAlerts         bool
+	notifyBlocks         bool
+	notifyClaimActivated bool
+	notifyClaimExpired   bool
+	notifyClaimTrie      bool
+	notifyNewTx          bool
+	notifyNewTxVerbose   bool
+	notifyReceived       map[string]struct{}
+	notifySpent          map[btcjson.OutPoint]struct{}
+	txFilterAddrs        map[string]struct{}
+	txFilterOutPoints    map[btcjson.OutPoint]struct{}
+	txFilterDescriptors  map[string]struct{}
+	txFilterClaimNames   map[string]struct{}
 }
 
 // Copy returns a deep copy of the receiver.
 func (s *notificationState) Copy() *notificationState {
 	var stateCopy notificationState
+	stateCopy.notifyAlerts = s.notifyAlerts
 	stateCopy.notifyBlocks = s.notifyBlocks
+	stateCopy.notifyClaimActivated = s.notifyClaimActivated
+	stateCopy.notifyClaimExpired = s.notifyClaimExpired
+	stateCopy.notifyClaimTrie = s.notifyClaimTrie
 	stateCopy.notifyNewTx = s.notifyNewTx
 	stateCopy.notifyNewTxVerbose = s.notifyNewTxVerbose
 	stateCopy.notifyReceived = make(map[string]struct{})
@@ -53,6 +65,22 @@ func (s *notificationState) Copy() *notificationState {
 	for op := range s.notifySpent {
 		stateCopy.notifySpent[op] = struct{}{}
 	}
+	stateCopy.txFilterAddrs = make(map[string]struct{})
+	for addr := range s.txFilterAddrs {
+		stateCopy.txFilterAddrs[addr] = struct{}{}
+	}
+	stateCopy.txFilterOutPoints = make(map[btcjson.OutPoint]struct{})
+	for op := range s.txFilterOutPoints {
+		stateCopy.txFilterOutPoints[op] = struct{}{}
+	}
+	stateCopy.txFilterDescriptors = make(map[string]struct{})
+	for desc := range s.txFilterDescriptors {
+		stateCopy.txFilterDescriptors[desc] = struct{}{}
+	}
+	stateCopy.txFilterClaimNames = make(map[string]struct{})
+	for name := range s.txFilterClaimNames {
+		stateCopy.txFilterClaimNames[name] = struct{}{}
+	}
 
 	return &stateCopy
 }
@@ -60,8 +88,12 @@ func (s *notificationState) Copy() *notificationState {
 // newNotificationState returns a new notification state ready to be populated.
 func newNotificationState() *notificationState {
 	return &notificationState{
-		notifyReceived: make(map[string]struct{}),
-		notifySpent:    make(map[btcjson.OutPoint]struct{}),
+		notifyReceived:      make(map[string]struct{}),
+		notifySpent:         make(map[btcjson.OutPoint]struct{}),
+		txFilterAddrs:       make(map[string]struct{}),
+		txFilterOutPoints:   make(map[btcjson.OutPoint]struct{}),
+		txFilterDescriptors: make(map[string]struct{}),
+		txFilterClaimNames:  make(map[string]struct{}),
 	}
 }
 
@@ -90,6 +122,13 @@ type NotificationHandlers struct {
 	// notification handlers, and is safe for blocking client requests.
 	OnClientConnected func()
 
+	// OnResubscribed is invoked once the notifications registered before
+	// the client was disconnected, including the transaction filter loaded
+	// with LoadTxFilter, have been registered again after reconnecting to
+	// the RPC server.  This callback is run async with the rest of the
+	// notification handlers, and is safe for blocking client requests.
+	OnResubscribed func()
+
 	// OnBlockConnected is invoked when a block is connected to the longest
 	// (best) chain.  It will only be invoked if a preceding call to
 	// NotifyBlocks has been made to register for the notification and the
@@ -121,6 +160,39 @@ type NotificationHandlers struct {
 	// OnBlockDisconnected: it receives the block's height and header.
 	OnFilteredBlockDisconnected func(height int32, header *wire.BlockHeader)
 
+	// OnChainAlert is invoked when the server detects an anomaly of the
+	// block chain, such as a large reorganization, an invalid block with
+	// valid proof of work or a stalled chain tip.  It will only be invoked
+	// if a preceding call to NotifyAlerts has been made to register for the
+	// notification and the function is non-nil.
+	OnChainAlert func(alert *btcjson.ChainAlertNtfn)
+
+	// OnClaimActivated is invoked when a block connected to the longest
+	// (best) chain activated claims, either at their activation height or
+	// due to a takeover.  It receives the block's hash and height along with
+	// the activated claims.  It will only be invoked if a preceding call to
+	// NotifyClaimActivated has been made to register for the notification
+	// and the function is non-nil.
+	OnClaimActivated func(hash *chainhash.Hash, height int32,
+		claims []btcjson.ClaimStateChange)
+
+	// OnClaimExpired is invoked when a block connected to the longest (best)
+	// chain expired claims.  It receives the block's hash and height along
+	// with the expired claims.  It will only be invoked if a preceding call
+	// to NotifyClaimExpired has been made to register for the notification
+	// and the function is non-nil.
+	OnClaimExpired func(hash *chainhash.Hash, height int32,
+		claims []btcjson.ClaimStateChange)
+
+	// OnClaimTrieChanged is invoked when a block is connected to the longest
+	// (best) chain.  It receives the block's hash and height, the root of
+	// the claim trie after the block and the names whose nodes were changed
+	// by the block.  It will only be invoked if a preceding call to
+	// NotifyClaimTrie has been made to register for the notification and
+	// the function is non-nil.
+	OnClaimTrieChanged func(hash *chainhash.Hash, height int32,
+		claimTrieRoot *chainhash.Hash, names []string)
+
 	// OnRecvTx is invoked when a transaction that receives funds to a
 	// registered address is received into the memory pool and also
 	// connected to the longest (best) chain.  It will only be invoked if a
@@ -144,6 +216,11 @@ type NotificationHandlers struct {
 	// Deprecated: Use OnRelevantTxAccepted instead.
 	OnRedeemingTx func(transaction *btcutil.Tx, details *btcjson.BlockDetails)
 
+	// OnShutdown is invoked when the server notifies it is shutting down,
+	// before it closes the connection.  It will be invoked regardless of
+	// the registered notifications when the function is non-nil.
+	OnShutdown func()
+
 	// OnRelevantTxAccepted is invoked when an unmined transaction passes
 	// the client's transaction filter.
 	//
@@ -211,6 +288,10 @@ type NotificationHandlers struct {
 // delivers the notification to the appropriate On<X> handler registered with
 // the client.
 func (c *Client) handleNotification(ntfn *rawNotification) {
+	// Deliver the notification to the handlers registered with OnEvent
+	// once the notification handler was invoked.
+	defer c.events.dispatch(ntfn)
+
 	// Ignore the notification if the client is not interested in any
 	// notifications.
 	if c.ntfnHandlers == nil {
@@ -290,6 +371,78 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 		c.ntfnHandlers.OnFilteredBlockDisconnected(blockHeight,
 			blockHeader)
 
+	// OnChainAlert
+	case btcjson.ChainAlertNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnChainAlert == nil {
+			return
+		}
+
+		alert, err := parseChainAlertParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid chain alert notification: "+
+				"%v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnChainAlert(alert)
+
+	// OnClaimActivated
+	case btcjson.ClaimActivatedNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnClaimActivated == nil {
+			return
+		}
+
+		blockHash, blockHeight, claims, err :=
+			parseClaimStateChangedParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid claim activated "+
+				"notification: %v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnClaimActivated(blockHash, blockHeight, claims)
+
+	// OnClaimExpired
+	case btcjson.ClaimExpiredNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnClaimExpired == nil {
+			return
+		}
+
+		blockHash, blockHeight, claims, err :=
+			parseClaimStateChangedParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid claim expired "+
+				"notification: %v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnClaimExpired(blockHash, blockHeight, claims)
+
+	// OnClaimTrieChanged
+	case btcjson.ClaimTrieChangedNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnClaimTrieChanged == nil {
+			return
+		}
+
+		blockHash, blockHeight, claimTrieRoot, names, err :=
+			parseClaimTrieChangedParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid claim trie changed "+
+				"notification: %v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnClaimTrieChanged(blockHash, blockHeight,
+			claimTrieRoot, names)
+
 	// OnRecvTx
 	case btcjson.RecvTxNtfnMethod:
 		// Ignore the notification if the client is not interested in
@@ -324,6 +477,22 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 
 		c.ntfnHandlers.OnRedeemingTx(tx, block)
 
+	// OnShutdown
+	case btcjson.ShutdownNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnShutdown == nil {
+			return
+		}
+
+		if len(ntfn.Params) != 0 {
+			log.Warnf("Received invalid shutdown notification: %v",
+				wrongNumParams(len(ntfn.Params)))
+			return
+		}
+
+		c.ntfnHandlers.OnShutdown()
+
 	// OnRelevantTxAccepted
 	case btcjson.RelevantTxAcceptedNtfnMethod:
 		// Ignore the notification if the client is not interested in
@@ -525,6 +694,111 @@ func parseChainNtfnParams(params []json.RawMessage) (*chainhash.Hash,
 	return blockHash, blockHeight, blockTime, nil
 }
 
+// parseChainAlertParams parses out the parameters included in a chainalert
+// notification.
+func parseChainAlertParams(params []json.RawMessage) (*btcjson.ChainAlertNtfn, error) {
+	if len(params) != 5 {
+		return nil, wrongNumParams(len(params))
+	}
+
+	var alert btcjson.ChainAlertNtfn
+	fields := []interface{}{&alert.Type, &alert.Hash, &alert.Height,
+		&alert.Time, &alert.Message}
+	for i, field := range fields {
+		if err := json.Unmarshal(params[i], field); err != nil {
+			return nil, err
+		}
+	}
+	return &alert, nil
+}
+
+// parseClaimStateChangedParams parses out the parameters included in a
+// claimactivated or claimexpired notification.
+func parseClaimStateChangedParams(params []json.RawMessage) (*chainhash.Hash,
+	int32, []btcjson.ClaimStateChange, error) {
+
+	if len(params) != 3 {
+		return nil, 0, nil, wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as a string.
+	var blockHashStr string
+	err := json.Unmarshal(params[0], &blockHashStr)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	// Unmarshal second parameter as an integer.
+	var blockHeight int32
+	err = json.Unmarshal(params[1], &blockHeight)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	// Unmarshal third parameter as a slice of claims.
+	var claims []btcjson.ClaimStateChange
+	err = json.Unmarshal(params[2], &claims)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	blockHash, err := chainhash.NewHashFromStr(blockHashStr)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	return blockHash, blockHeight, claims, nil
+}
+
+// parseClaimTrieChangedParams parses out the parameters included in a
+// claimtriechanged notification.
+func parseClaimTrieChangedParams(params []json.RawMessage) (*chainhash.Hash,
+	int32, *chainhash.Hash, []string, error) {
+
+	if len(params) != 4 {
+		return nil, 0, nil, nil, wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as a string.
+	var blockHashStr string
+	err := json.Unmarshal(params[0], &blockHashStr)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+
+	// Unmarshal second parameter as an integer.
+	var blockHeight int32
+	err = json.Unmarshal(params[1], &blockHeight)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+
+	// Unmarshal third parameter as a string.
+	var claimTrieRootStr string
+	err = json.Unmarshal(params[2], &claimTrieRootStr)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+
+	// Unmarshal fourth parameter as a slice of strings.
+	var names []string
+	err = json.Unmarshal(params[3], &names)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+
+	blockHash, err := chainhash.NewHashFromStr(blockHashStr)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+	claimTrieRoot, err := chainhash.NewHashFromStr(claimTrieRootStr)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+
+	return blockHash, blockHeight, claimTrieRoot, names, nil
+}
+
 // parseFilteredBlockConnectedParams parses out the parameters included in a
 // filteredblockconnected notification.
 //
@@ -880,7 +1154,7 @@ func (c *Client) NotifyBlocksAsync() FutureNotifyBlocksResult {
 
 	// Ignore the notification if the client is not interested in
 	// notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return newNilFutureResult()
 	}
 
@@ -902,6 +1176,202 @@ func (c *Client) NotifyBlocks() error {
 	return c.NotifyBlocksAsync().Receive()
 }
 
+// FutureNotifyAlertsResult is a future promise to deliver the result of a
+// NotifyAlertsAsync RPC invocation (or an applicable error).
+type FutureNotifyAlertsResult chan *Response
+
+// Receive waits for the Response promised by the future and returns an error
+// if the registration was not successful.
+func (r FutureNotifyAlertsResult) Receive() error {
+	_, err := ReceiveFuture(r)
+	return err
+}
+
+// NotifyAlertsAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See NotifyAlerts for the blocking version and more details.
+//
+// NOTE: This is a lbcd extension and requires a websocket connection.
+func (c *Client) NotifyAlertsAsync() FutureNotifyAlertsResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return newFutureError(ErrWebsocketsRequired)
+	}
+
+	// Ignore the notification if the client is not interested in
+	// notifications.
+	if !c.notificationsEnabled() {
+		return newNilFutureResult()
+	}
+
+	cmd := btcjson.NewNotifyAlertsCmd()
+	return c.SendCmd(cmd)
+}
+
+// NotifyAlerts registers the client to receive a notification whenever the
+// server detects an anomaly of the block chain.  The notifications are
+// delivered to the notification handlers associated with the client.  Calling
+// this function has no effect if there are no notification handlers and will
+// result in an error if the client is configured to run in HTTP POST mode.
+//
+// The notifications delivered as a result of this call will be via
+// OnChainAlert.
+//
+// NOTE: This is a lbcd extension and requires a websocket connection.
+func (c *Client) NotifyAlerts() error {
+	return c.NotifyAlertsAsync().Receive()
+}
+
+// FutureNotifyClaimActivatedResult is a future promise to deliver the result of a
+// NotifyClaimActivatedAsync RPC invocation (or an applicable error).
+type FutureNotifyClaimActivatedResult chan *Response
+
+// Receive waits for the Response promised by the future and returns an error
+// if the registration was not successful.
+func (r FutureNotifyClaimActivatedResult) Receive() error {
+	_, err := ReceiveFuture(r)
+	return err
+}
+
+// NotifyClaimActivatedAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See NotifyClaimActivated for the blocking version and more details.
+//
+// NOTE: This is a lbcd extension and requires a websocket connection.
+func (c *Client) NotifyClaimActivatedAsync() FutureNotifyClaimActivatedResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return newFutureError(ErrWebsocketsRequired)
+	}
+
+	// Ignore the notification if the client is not interested in
+	// notifications.
+	if !c.notificationsEnabled() {
+		return newNilFutureResult()
+	}
+
+	cmd := btcjson.NewNotifyClaimActivatedCmd()
+	return c.SendCmd(cmd)
+}
+
+// NotifyClaimActivated registers the client to receive a notification with the
+// claims activated by a block, either at their activation height or due to a
+// takeover, whenever a block is connected to the main chain.  The
+// notifications are delivered to the notification handlers associated with
+// the client.  Calling this function has no effect if there are no
+// notification handlers and will result in an error if the client is
+// configured to run in HTTP POST mode.
+//
+// The notifications delivered as a result of this call will be via
+// OnClaimActivated.
+//
+// NOTE: This is a lbcd extension and requires a websocket connection.
+func (c *Client) NotifyClaimActivated() error {
+	return c.NotifyClaimActivatedAsync().Receive()
+}
+
+// FutureNotifyClaimExpiredResult is a future promise to deliver the result of a
+// NotifyClaimExpiredAsync RPC invocation (or an applicable error).
+type FutureNotifyClaimExpiredResult chan *Response
+
+// Receive waits for the Response promised by the future and returns an error
+// if the registration was not successful.
+func (r FutureNotifyClaimExpiredResult) Receive() error {
+	_, err := ReceiveFuture(r)
+	return err
+}
+
+// NotifyClaimExpiredAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See NotifyClaimExpired for the blocking version and more details.
+//
+// NOTE: This is a lbcd extension and requires a websocket connection.
+func (c *Client) NotifyClaimExpiredAsync() FutureNotifyClaimExpiredResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return newFutureError(ErrWebsocketsRequired)
+	}
+
+	// Ignore the notification if the client is not interested in
+	// notifications.
+	if !c.notificationsEnabled() {
+		return newNilFutureResult()
+	}
+
+	cmd := btcjson.NewNotifyClaimExpiredCmd()
+	return c.SendCmd(cmd)
+}
+
+// NotifyClaimExpired registers the client to receive a notification with the
+// claims expired by a block whenever a block is connected to the main chain.
+// The notifications are delivered to the notification handlers associated with
+// the client.  Calling this function has no effect if there are no
+// notification handlers and will result in an error if the client is
+// configured to run in HTTP POST mode.
+//
+// The notifications delivered as a result of this call will be via
+// OnClaimExpired.
+//
+// NOTE: This is a lbcd extension and requires a websocket connection.
+func (c *Client) NotifyClaimExpired() error {
+	return c.NotifyClaimExpiredAsync().Receive()
+}
+
+// FutureNotifyClaimTrieResult is a future promise to deliver the result of a
+// NotifyClaimTrieAsync RPC invocation (or an applicable error).
+type FutureNotifyClaimTrieResult chan *Response
+
+// Receive waits for the Response promised by the future and returns an error
+// if the registration was not successful.
+func (r FutureNotifyClaimTrieResult) Receive() error {
+	_, err := ReceiveFuture(r)
+	return err
+}
+
+// NotifyClaimTrieAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See NotifyClaimTrie for the blocking version and more details.
+//
+// NOTE: This is a lbcd extension and requires a websocket connection.
+func (c *Client) NotifyClaimTrieAsync() FutureNotifyClaimTrieResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return newFutureError(ErrWebsocketsRequired)
+	}
+
+	// Ignore the notification if the client is not interested in
+	// notifications.
+	if !c.notificationsEnabled() {
+		return newNilFutureResult()
+	}
+
+	cmd := btcjson.NewNotifyClaimTrieCmd()
+	return c.SendCmd(cmd)
+}
+
+// NotifyClaimTrie registers the client to receive a notification with the new
+// claim trie root and the names whose nodes changed whenever a block is
+// connected to the main chain.  The notifications are delivered to the
+// notification handlers associated with the client.  Calling this function has
+// no effect if there are no notification handlers and will result in an error
+// if the client is configured to run in HTTP POST mode.
+//
+// The notifications delivered as a result of this call will be via
+// OnClaimTrieChanged.
+//
+// NOTE: This is a lbcd extension and requires a websocket connection.
+func (c *Client) NotifyClaimTrie() error {
+	return c.NotifyClaimTrieAsync().Receive()
+}
+
 // FutureNotifySpentResult is a future promise to deliver the result of a
 // NotifySpentAsync RPC invocation (or an applicable error).
 //
@@ -926,7 +1396,7 @@ func (c *Client) notifySpentInternal(outpoints []btcjson.OutPoint) FutureNotifyS
 
 	// Ignore the notification if the client is not interested in
 	// notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return newNilFutureResult()
 	}
 
@@ -960,7 +1430,7 @@ func (c *Client) NotifySpentAsync(outpoints []*wire.OutPoint) FutureNotifySpentR
 
 	// Ignore the notification if the client is not interested in
 	// notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return newNilFutureResult()
 	}
 
@@ -1014,7 +1484,7 @@ func (c *Client) NotifyNewTransactionsAsync(verbose bool) FutureNotifyNewTransac
 
 	// Ignore the notification if the client is not interested in
 	// notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return newNilFutureResult()
 	}
 
@@ -1061,7 +1531,7 @@ func (c *Client) notifyReceivedInternal(addresses []string) FutureNotifyReceived
 
 	// Ignore the notification if the client is not interested in
 	// notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return newNilFutureResult()
 	}
 
@@ -1087,7 +1557,7 @@ func (c *Client) NotifyReceivedAsync(addresses []btcutil.Address) FutureNotifyRe
 
 	// Ignore the notification if the client is not interested in
 	// notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return newNilFutureResult()
 	}
 
@@ -1162,7 +1632,7 @@ func (c *Client) RescanAsync(startBlock *chainhash.Hash,
 
 	// Ignore the notification if the client is not interested in
 	// notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return newNilFutureResult()
 	}
 
@@ -1243,7 +1713,7 @@ func (c *Client) RescanEndBlockAsync(startBlock *chainhash.Hash,
 
 	// Ignore the notification if the client is not interested in
 	// notifications.
-	if c.ntfnHandlers == nil {
+	if !c.notificationsEnabled() {
 		return newNilFutureResult()
 	}
 
@@ -1355,3 +1825,32 @@ func (c *Client) LoadTxFilterAsync(reload bool, addresses []btcutil.Address,
 func (c *Client) LoadTxFilter(reload bool, addresses []btcutil.Address, outPoints []wire.OutPoint) error {
 	return c.LoadTxFilterAsync(reload, addresses, outPoints).Receive()
 }
+
+// LoadTxFilterDescriptorsAsync returns an instance of a type that can be used
+// to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See LoadTxFilterDescriptors for the blocking version and more details.
+//
+// NOTE: This is an lbcd extension and requires a websocket connection.
+func (c *Client) LoadTxFilterDescriptorsAsync(reload bool, descriptors,
+	claimNames []string) FutureLoadTxFilterResult {
+
+	cmd := btcjson.NewLoadTxFilterCmd(reload, []string{},
+		[]btcjson.OutPoint{})
+	cmd.Descriptors = &descriptors
+	cmd.ClaimNames = &claimNames
+	return c.SendCmd(cmd)
+}
+
+// LoadTxFilterDescriptors loads, reloads, or adds output descriptors and claim
+// names to a websocket client's transaction filter.  Outputs matching a
+// descriptor, ignoring their claim script prefix, and the claims, updates and
+// supports of the names are relevant to the filter, like the outputs paying to
+// its addresses.
+//
+// NOTE: This is an lbcd extension and requires a websocket connection.
+func (c *Client) LoadTxFilterDescriptors(reload bool, descriptors, claimNames []string) error {
+	return c.LoadTxFilterDescriptorsAsync(reload, descriptors,
+		claimNames).Receive()
+}
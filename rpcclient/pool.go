@@ -0,0 +1,424 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+const (
+	// poolHealthCheckInterval is the time between health checks of the
+	// endpoints of a Pool.
+	poolHealthCheckInterval = 10 * time.Second
+
+	// poolHealthCheckTimeout is the maximum time an endpoint may take to
+	// answer a health check before it is considered unhealthy.
+	poolHealthCheckTimeout = 5 * time.Second
+)
+
+var (
+	// ErrNoHealthyEndpoints is an error to describe the condition where
+	// none of the endpoints of a Pool are currently reachable.
+	ErrNoHealthyEndpoints = errors.New("no healthy endpoints available")
+
+	// ErrPoolShutdown is an error to describe the condition where a Pool
+	// is used after it has been shutdown.
+	ErrPoolShutdown = errors.New("the pool has been shutdown")
+
+	// errHealthCheckTimeout is returned when an endpoint does not answer a
+	// health check in time.
+	errHealthCheckTimeout = errors.New("health check timed out")
+)
+
+// poolMember houses a single endpoint of a Pool along with its health.
+type poolMember struct {
+	config  *ConnConfig
+	client  *Client
+	healthy bool
+}
+
+// Pool manages clients connected to several redundant RPC servers.  Requests
+// are spread across the endpoints which are currently healthy, and the health
+// of every endpoint is periodically checked with a getbestblock request.
+//
+// Websocket notifications are only registered with a single endpoint at a
+// time.  When that endpoint becomes unhealthy, the registrations made through
+// Subscribe are transparently replayed on another healthy endpoint.
+type Pool struct {
+	ntfnHandlers *NotificationHandlers
+
+	mtx      sync.Mutex
+	members  []*poolMember
+	next     int
+	notifier int // index of the member receiving notifications or -1
+
+	// subscriptions holds the notification registrations which are
+	// replayed on failover.  subMtx serializes registrations with the
+	// replay so none of them are lost.
+	subMtx        sync.Mutex
+	subscriptions []func(*Client) error
+
+	shutdownOnce sync.Once
+	quit         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewPool creates a pool of clients for the passed connection configurations
+// and starts monitoring their health.  Endpoints which can't be reached yet are
+// retried in the background, however an error is returned when none of them
+// can be reached.
+//
+// The notification handlers are shared by all of the clients, but notifications
+// are only registered with one of them at a time, so each notification is only
+// delivered once.  The OnClientConnected handler is invoked whenever a new
+// endpoint takes over the delivery of notifications.
+func NewPool(configs []*ConnConfig, ntfnHandlers *NotificationHandlers) (*Pool, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("rpcclient.NewPool: no endpoints provided")
+	}
+
+	p := &Pool{
+		ntfnHandlers: ntfnHandlers,
+		notifier:     -1,
+		quit:         make(chan struct{}),
+	}
+	for _, config := range configs {
+		m := &poolMember{config: config}
+		client, err := p.newMemberClient(config)
+		if err != nil {
+			p.shutdownMembers()
+			return nil, err
+		}
+		m.client = client
+		p.members = append(p.members, m)
+	}
+
+	p.checkHealth()
+	if p.numHealthy() == 0 {
+		p.shutdownMembers()
+		return nil, ErrNoHealthyEndpoints
+	}
+
+	p.wg.Add(1)
+	go p.healthHandler()
+	return p, nil
+}
+
+// newMemberClient creates a client for the passed configuration.  Websocket
+// clients are created without connecting so the pool can retry unreachable
+// endpoints on its own schedule.
+func (p *Pool) newMemberClient(config *ConnConfig) (*Client, error) {
+	var ntfnHandlers *NotificationHandlers
+	if p.ntfnHandlers != nil {
+		// The pool itself reports when an endpoint takes over the
+		// notifications.
+		handlers := *p.ntfnHandlers
+		handlers.OnClientConnected = nil
+		ntfnHandlers = &handlers
+	}
+
+	configCopy := *config
+	if !configCopy.HTTPPostMode {
+		configCopy.DisableConnectOnNew = true
+	}
+	return New(&configCopy, ntfnHandlers)
+}
+
+// numHealthy returns the number of healthy members.
+func (p *Pool) numHealthy() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var n int
+	for _, m := range p.members {
+		if m.healthy {
+			n++
+		}
+	}
+	return n
+}
+
+// pingMember checks the health of the passed client by requesting the best
+// block, connecting it first when needed.
+func pingMember(client *Client) error {
+	if !client.config.HTTPPostMode {
+		err := client.Connect(1)
+		if err != nil && err != ErrClientAlreadyConnected {
+			return err
+		}
+		if client.Disconnected() {
+			return ErrClientDisconnect
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		poolHealthCheckTimeout)
+	defer cancel()
+
+	_, _, err := client.WithContext(ctx).GetBestBlock()
+	if err == context.DeadlineExceeded {
+		return errHealthCheckTimeout
+	}
+	return err
+}
+
+// checkHealth checks every member and fails the notifications over to another
+// member when the current notifier is no longer healthy.
+func (p *Pool) checkHealth() {
+	p.mtx.Lock()
+	members := make([]*poolMember, len(p.members))
+	copy(members, p.members)
+	p.mtx.Unlock()
+
+	var wg sync.WaitGroup
+	healthy := make([]bool, len(members))
+	for i, m := range members {
+		wg.Add(1)
+		go func(i int, client *Client, host string) {
+			defer wg.Done()
+			err := pingMember(client)
+			if err != nil {
+				log.Debugf("Pool endpoint %s is unhealthy: %v", host,
+					err)
+			}
+			healthy[i] = err == nil
+		}(i, m.client, m.config.Host)
+	}
+	wg.Wait()
+
+	p.mtx.Lock()
+	for i, m := range members {
+		switch {
+		case healthy[i] && !m.healthy:
+			log.Infof("Pool endpoint %s is healthy", m.config.Host)
+		case !healthy[i] && m.healthy:
+			log.Infof("Pool endpoint %s is unhealthy", m.config.Host)
+		}
+		m.healthy = healthy[i]
+	}
+	needsFailover := p.notifier == -1 || !p.members[p.notifier].healthy
+	p.mtx.Unlock()
+
+	if needsFailover {
+		p.failover()
+	}
+}
+
+// failover moves the notification registrations to a healthy member.  The
+// client of the previous notifier is replaced so it doesn't restore its
+// registrations once it reconnects, which would duplicate notifications.
+func (p *Pool) failover() {
+	if p.ntfnHandlers == nil {
+		return
+	}
+
+	p.subMtx.Lock()
+	defer p.subMtx.Unlock()
+
+	p.mtx.Lock()
+	if old := p.notifier; old != -1 {
+		m := p.members[old]
+		client, err := p.newMemberClient(m.config)
+		if err == nil {
+			m.client.Shutdown()
+			m.client = client
+			m.healthy = false
+		}
+		p.notifier = -1
+	}
+	for i, m := range p.members {
+		if m.healthy && !m.config.HTTPPostMode {
+			p.notifier = i
+			break
+		}
+	}
+	if p.notifier == -1 {
+		p.mtx.Unlock()
+		return
+	}
+	m := p.members[p.notifier]
+	client := m.client
+	subscriptions := p.subscriptions
+	p.mtx.Unlock()
+
+	log.Infof("Pool notifications are now delivered by %s", m.config.Host)
+	for _, register := range subscriptions {
+		if err := register(client); err != nil {
+			log.Warnf("Failed to register notifications with %s: %v",
+				m.config.Host, err)
+		}
+	}
+
+	if p.ntfnHandlers.OnClientConnected != nil {
+		go p.ntfnHandlers.OnClientConnected()
+	}
+}
+
+// healthHandler periodically checks the health of the members until the pool
+// is shutdown.  It must be run as a goroutine.
+func (p *Pool) healthHandler() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(poolHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth()
+
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// Client returns the client of the next healthy endpoint in round-robin order
+// so requests are spread across all of the healthy endpoints.
+func (p *Pool) Client() (*Client, error) {
+	select {
+	case <-p.quit:
+		return nil, ErrPoolShutdown
+	default:
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for range p.members {
+		m := p.members[p.next]
+		p.next = (p.next + 1) % len(p.members)
+		if m.healthy {
+			return m.client, nil
+		}
+	}
+	return nil, ErrNoHealthyEndpoints
+}
+
+// Do invokes fn with the client of a healthy endpoint.  When fn fails with an
+// error other than one returned by the RPC server, the endpoint is marked as
+// unhealthy and fn is retried with the next healthy endpoint.
+func (p *Pool) Do(fn func(*Client) error) error {
+	err := ErrNoHealthyEndpoints
+	for range p.members {
+		var client *Client
+		client, err = p.Client()
+		if err != nil {
+			return err
+		}
+
+		err = fn(client)
+		var rpcErr *btcjson.RPCError
+		if err == nil || errors.As(err, &rpcErr) {
+			return err
+		}
+		p.markUnhealthy(client)
+	}
+	return err
+}
+
+// markUnhealthy marks the member with the passed client as unhealthy until its
+// next successful health check.
+func (p *Pool) markUnhealthy(client *Client) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, m := range p.members {
+		if m.client == client {
+			m.healthy = false
+		}
+	}
+}
+
+// Subscribe registers notifications with the endpoint currently delivering
+// them by invoking register with its client.  The registration is replayed
+// whenever another endpoint takes over the delivery of notifications.
+func (p *Pool) Subscribe(register func(*Client) error) error {
+	if p.ntfnHandlers == nil {
+		return nil
+	}
+
+	p.subMtx.Lock()
+	defer p.subMtx.Unlock()
+
+	p.mtx.Lock()
+	p.subscriptions = append(p.subscriptions, register)
+	var client *Client
+	if p.notifier != -1 {
+		client = p.members[p.notifier].client
+	}
+	p.mtx.Unlock()
+
+	// The registration will be made once an endpoint becomes healthy.
+	if client == nil {
+		return nil
+	}
+	return register(client)
+}
+
+// NotifyBlocks registers for block connected and disconnected notifications
+// with the endpoint delivering notifications.
+//
+// See Client.NotifyBlocks for more details.
+func (p *Pool) NotifyBlocks() error {
+	return p.Subscribe(func(c *Client) error {
+		return c.NotifyBlocks()
+	})
+}
+
+// NotifyClaimTrie registers for claim trie changed notifications with the
+// endpoint delivering notifications.
+//
+// See Client.NotifyClaimTrie for more details.
+func (p *Pool) NotifyClaimTrie() error {
+	return p.Subscribe(func(c *Client) error {
+		return c.NotifyClaimTrie()
+	})
+}
+
+// NotifyNewTransactions registers for transaction accepted notifications with
+// the endpoint delivering notifications.
+//
+// See Client.NotifyNewTransactions for more details.
+func (p *Pool) NotifyNewTransactions(verbose bool) error {
+	return p.Subscribe(func(c *Client) error {
+		return c.NotifyNewTransactions(verbose)
+	})
+}
+
+// shutdownMembers shuts down the clients of all members.
+func (p *Pool) shutdownMembers() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, m := range p.members {
+		m.client.Shutdown()
+	}
+}
+
+// Shutdown stops monitoring the endpoints and shuts down all of the clients.
+func (p *Pool) Shutdown() {
+	p.shutdownOnce.Do(func() {
+		close(p.quit)
+		p.wg.Wait()
+		p.shutdownMembers()
+	})
+}
+
+// WaitForShutdown blocks until the pool and all of its clients have finished
+// shutting down.
+func (p *Pool) WaitForShutdown() {
+	p.wg.Wait()
+
+	p.mtx.Lock()
+	members := make([]*poolMember, len(p.members))
+	copy(members, p.members)
+	p.mtx.Unlock()
+
+	for _, m := range members {
+		m.client.WaitForShutdown()
+	}
+}
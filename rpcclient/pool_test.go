@@ -0,0 +1,368 @@
+package rpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/websocket"
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+// mockEndpoint is an RPC server answering the requests made by a Pool in both
+// HTTP POST and websocket mode.  It can be made to fail the requests or to
+// drop its connections to exercise the failover of the pool.
+type mockEndpoint struct {
+	server *httptest.Server
+
+	mtx     sync.Mutex
+	failing bool
+	down    bool
+	conns   []*websocket.Conn
+	methods []string
+}
+
+// newMockEndpoint starts a new mock endpoint.
+func newMockEndpoint() *mockEndpoint {
+	e := &mockEndpoint{}
+	e.server = httptest.NewServer(http.HandlerFunc(e.serveHTTP))
+	return e
+}
+
+// config returns the connection configuration of the endpoint.
+func (e *mockEndpoint) config(httpPostMode bool) *ConnConfig {
+	return &ConnConfig{
+		Host:         strings.TrimPrefix(e.server.URL, "http://"),
+		Endpoint:     "ws",
+		User:         "user",
+		Pass:         "pass",
+		DisableTLS:   true,
+		HTTPPostMode: httpPostMode,
+	}
+}
+
+// setFailing makes the endpoint answer every request with an HTTP error.
+func (e *mockEndpoint) setFailing(failing bool) {
+	e.mtx.Lock()
+	e.failing = failing
+	e.mtx.Unlock()
+}
+
+// setDown drops the connections of the endpoint and refuses new ones while
+// it is down.
+func (e *mockEndpoint) setDown(down bool) {
+	e.mtx.Lock()
+	e.down = down
+	conns := e.conns
+	if down {
+		e.conns = nil
+	}
+	e.mtx.Unlock()
+
+	if down {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+}
+
+// received returns the number of requests received with the passed method.
+func (e *mockEndpoint) received(method string) int {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	var n int
+	for _, m := range e.methods {
+		if m == method {
+			n++
+		}
+	}
+	return n
+}
+
+// reply returns the marshalled response to the passed request.
+func (e *mockEndpoint) reply(req *btcjson.Request) []byte {
+	e.mtx.Lock()
+	e.methods = append(e.methods, req.Method)
+	e.mtx.Unlock()
+
+	var result interface{}
+	if req.Method == "getbestblock" {
+		result = &btcjson.GetBestBlockResult{
+			Hash:   strings.Repeat("00", 32),
+			Height: 1,
+		}
+	}
+	reply, _ := btcjson.MarshalResponse(btcjson.RpcVersion1, req.ID,
+		result, nil)
+	return reply
+}
+
+func (e *mockEndpoint) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mtx.Lock()
+	failing, down := e.failing, e.down
+	e.mtx.Unlock()
+
+	switch {
+	case down:
+		// Drop the connection without answering.
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+		return
+
+	case failing:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+
+	case r.URL.Path != "/ws":
+		var req btcjson.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(e.reply(&req))
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r, nil, 0, 0)
+	if err != nil {
+		return
+	}
+	e.mtx.Lock()
+	e.conns = append(e.conns, conn)
+	e.mtx.Unlock()
+	defer conn.Close()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req btcjson.Request
+		if err := json.Unmarshal(msg, &req); err != nil {
+			return
+		}
+		err = conn.WriteMessage(websocket.TextMessage, e.reply(&req))
+		if err != nil {
+			return
+		}
+	}
+}
+
+// isHealthy returns whether the member with the passed index is healthy.
+func (p *Pool) isHealthy(i int) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return p.members[i].healthy
+}
+
+// TestPoolClientRoundRobin ensures the pool spreads requests across the
+// healthy endpoints and skips the unhealthy ones.
+func TestPoolClientRoundRobin(t *testing.T) {
+	if _, err := NewPool(nil, nil); err == nil {
+		t.Fatal("NewPool: expected error for no endpoints")
+	}
+
+	clients := []*Client{{}, {}, {}}
+	p := &Pool{notifier: -1, quit: make(chan struct{})}
+	for _, c := range clients {
+		p.members = append(p.members, &poolMember{
+			config:  &ConnConfig{},
+			client:  c,
+			healthy: true,
+		})
+	}
+	p.markUnhealthy(clients[1])
+
+	want := []*Client{clients[0], clients[2], clients[0], clients[2]}
+	for i, w := range want {
+		got, err := p.Client()
+		if err != nil {
+			t.Fatalf("Client #%d: unexpected error: %v", i, err)
+		}
+		if got != w {
+			t.Fatalf("Client #%d: got unexpected client", i)
+		}
+	}
+
+	p.markUnhealthy(clients[0])
+	p.markUnhealthy(clients[2])
+	if _, err := p.Client(); err != ErrNoHealthyEndpoints {
+		t.Fatalf("Client: unexpected error: %v", err)
+	}
+
+	close(p.quit)
+	if _, err := p.Client(); err != ErrPoolShutdown {
+		t.Fatalf("Client: unexpected error: %v", err)
+	}
+}
+
+// TestPoolDoFailover ensures requests failing with an endpoint which errors or
+// disconnects are retried with another endpoint, and that the endpoint is only
+// used again once it passes a health check.
+func TestPoolDoFailover(t *testing.T) {
+	endpoints := []*mockEndpoint{newMockEndpoint(), newMockEndpoint()}
+	var configs []*ConnConfig
+	for _, e := range endpoints {
+		defer e.server.Close()
+		configs = append(configs, e.config(true))
+	}
+	p, err := NewPool(configs, nil)
+	if err != nil {
+		t.Fatalf("NewPool: unexpected error: %v", err)
+	}
+	defer p.Shutdown()
+
+	getBestBlock := func(c *Client) error {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			time.Second)
+		defer cancel()
+		_, _, err := c.WithContext(ctx).GetBestBlock()
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		fail     func(e *mockEndpoint)
+		recovers func(e *mockEndpoint)
+	}{
+		{
+			name:     "error",
+			fail:     func(e *mockEndpoint) { e.setFailing(true) },
+			recovers: func(e *mockEndpoint) { e.setFailing(false) },
+		},
+		{
+			name:     "disconnect",
+			fail:     func(e *mockEndpoint) { e.setDown(true) },
+			recovers: func(e *mockEndpoint) { e.setDown(false) },
+		},
+	}
+	for _, test := range tests {
+		test.fail(endpoints[0])
+
+		// Every request succeeds with the remaining endpoint.
+		for i := 0; i < 2; i++ {
+			if err := p.Do(getBestBlock); err != nil {
+				t.Fatalf("%s: Do #%d: unexpected error: %v",
+					test.name, i, err)
+			}
+		}
+		if p.isHealthy(0) || !p.isHealthy(1) {
+			t.Fatalf("%s: failing endpoint wasn't marked as "+
+				"unhealthy", test.name)
+		}
+
+		// The endpoint is healthy again once it recovers.
+		test.recovers(endpoints[0])
+		p.checkHealth()
+		if !p.isHealthy(0) {
+			t.Fatalf("%s: recovered endpoint is unhealthy",
+				test.name)
+		}
+	}
+
+	// Requests fail once none of the endpoints are healthy.
+	for _, e := range endpoints {
+		e.setFailing(true)
+	}
+	if err := p.Do(getBestBlock); err == nil {
+		t.Fatal("Do: expected error without healthy endpoints")
+	}
+	if _, err := p.Client(); err != ErrNoHealthyEndpoints {
+		t.Fatalf("Client: unexpected error: %v", err)
+	}
+}
+
+// TestPoolSubscribeFailover ensures the notification registrations made with
+// Subscribe are replayed with another endpoint once the endpoint delivering
+// notifications disconnects, and aren't restored once it reconnects.
+func TestPoolSubscribeFailover(t *testing.T) {
+	endpoints := []*mockEndpoint{newMockEndpoint(), newMockEndpoint()}
+	var configs []*ConnConfig
+	for _, e := range endpoints {
+		defer e.server.Close()
+		configs = append(configs, e.config(false))
+	}
+	connected := make(chan struct{}, 2)
+	p, err := NewPool(configs, &NotificationHandlers{
+		OnClientConnected: func() { connected <- struct{}{} },
+	})
+	if err != nil {
+		t.Fatalf("NewPool: unexpected error: %v", err)
+	}
+	defer func() {
+		p.Shutdown()
+		p.WaitForShutdown()
+	}()
+
+	waitConnected := func() {
+		t.Helper()
+		select {
+		case <-connected:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for a notifier to connect")
+		}
+	}
+	waitConnected()
+
+	if err := p.NotifyBlocks(); err != nil {
+		t.Fatalf("NotifyBlocks: unexpected error: %v", err)
+	}
+	if err := p.NotifyClaimTrie(); err != nil {
+		t.Fatalf("NotifyClaimTrie: unexpected error: %v", err)
+	}
+	for _, method := range []string{"notifyblocks", "notifyclaimtrie"} {
+		if n := endpoints[0].received(method); n != 1 {
+			t.Fatalf("first endpoint got %d %s requests, want 1",
+				n, method)
+		}
+		if n := endpoints[1].received(method); n != 0 {
+			t.Fatalf("second endpoint got %d %s requests, want 0",
+				n, method)
+		}
+	}
+
+	// Wait for the client of the notifier to notice the disconnect before
+	// checking the health of the endpoints.
+	p.mtx.Lock()
+	client := p.members[0].client
+	p.mtx.Unlock()
+	endpoints[0].setDown(true)
+	deadline := time.Now().Add(5 * time.Second)
+	for !client.Disconnected() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for the client to disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.checkHealth()
+	waitConnected()
+
+	for _, method := range []string{"notifyblocks", "notifyclaimtrie"} {
+		if n := endpoints[1].received(method); n != 1 {
+			t.Fatalf("second endpoint got %d %s requests after "+
+				"failover, want 1", n, method)
+		}
+	}
+
+	// The registrations aren't restored with the first endpoint once it
+	// reconnects, which would duplicate the notifications.
+	endpoints[0].setDown(false)
+	p.checkHealth()
+	if !p.isHealthy(0) {
+		t.Fatal("reconnected endpoint is unhealthy")
+	}
+	for _, method := range []string{"notifyblocks", "notifyclaimtrie"} {
+		if n := endpoints[0].received(method); n != 1 {
+			t.Fatalf("first endpoint got %d %s requests after "+
+				"reconnecting, want 1", n, method)
+		}
+	}
+}
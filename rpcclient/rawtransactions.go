@@ -887,3 +887,214 @@ func (c *Client) DecodeScriptAsync(serializedScript []byte) FutureDecodeScriptRe
 func (c *Client) DecodeScript(serializedScript []byte) (*btcjson.DecodeScriptResult, error) {
 	return c.DecodeScriptAsync(serializedScript).Receive()
 }
+
+// FutureTestMempoolAcceptResult is a future promise to deliver the result
+// of a TestMempoolAccept RPC invocation (or an applicable error).
+type FutureTestMempoolAcceptResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// response from TestMempoolAccept.
+func (r FutureTestMempoolAcceptResult) Receive() (
+	[]*btcjson.TestMempoolAcceptResult, error) {
+
+	response, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal as an array of TestMempoolAcceptResult items.
+	var results []*btcjson.TestMempoolAcceptResult
+
+	err = json.Unmarshal(response, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// TestMempoolAcceptAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See TestMempoolAccept for the blocking version and more details.
+func (c *Client) TestMempoolAcceptAsync(txns []*wire.MsgTx,
+	maxFeeRate float64) FutureTestMempoolAcceptResult {
+
+	// Iterate all the transactions and turn them into hex strings.
+	rawTxns := make([]string, 0, len(txns))
+	for _, tx := range txns {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+
+		rawTxns = append(rawTxns, hex.EncodeToString(buf.Bytes()))
+	}
+
+	cmd := btcjson.NewTestMempoolAcceptCmd(rawTxns, &maxFeeRate)
+
+	return c.SendCmd(cmd)
+}
+
+// TestMempoolAccept returns result of mempool acceptance tests indicating if
+// raw transaction(s) would be accepted by mempool.
+//
+// If multiple transactions are passed in, later transactions may spend the
+// outputs of earlier ones. This checks if transactions violate the consensus
+// or policy rules.
+//
+// maxFeeRate is expressed in LBC/kvB. A value of 0 disables the check.
+func (c *Client) TestMempoolAccept(txns []*wire.MsgTx,
+	maxFeeRate float64) ([]*btcjson.TestMempoolAcceptResult, error) {
+
+	return c.TestMempoolAcceptAsync(txns, maxFeeRate).Receive()
+}
+
+// FutureSubmitPackageResult is a future promise to deliver the result of a
+// SubmitPackage RPC invocation (or an applicable error).
+type FutureSubmitPackageResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// response from SubmitPackage.
+func (r FutureSubmitPackageResult) Receive() (*btcjson.SubmitPackageResult, error) {
+	response, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.SubmitPackageResult
+	err = json.Unmarshal(response, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// SubmitPackageAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See SubmitPackage for the blocking version and more details.
+func (c *Client) SubmitPackageAsync(txns []*wire.MsgTx) FutureSubmitPackageResult {
+	// Iterate all the transactions and turn them into hex strings.
+	rawTxns := make([]string, 0, len(txns))
+	for _, tx := range txns {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+
+		rawTxns = append(rawTxns, hex.EncodeToString(buf.Bytes()))
+	}
+
+	cmd := btcjson.NewSubmitPackageCmd(rawTxns)
+
+	return c.SendCmd(cmd)
+}
+
+// SubmitPackage submits a child transaction along with its unconfirmed parents
+// to be accepted into the memory pool together and relayed.  Parents which do
+// not pay enough fees on their own are accepted when the package as a whole
+// pays the minimum relay fee.
+//
+// The child must be the last transaction and parents must come before the
+// transactions spending them.
+func (c *Client) SubmitPackage(txns []*wire.MsgTx) (*btcjson.SubmitPackageResult, error) {
+	return c.SubmitPackageAsync(txns).Receive()
+}
+
+// FutureGetAddressTxIDsResult is a future promise to deliver the result of the
+// GetAddressTxIDsAsync RPC invocation (or an applicable error).
+type FutureGetAddressTxIDsResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the hashes
+// of the transactions involving the address.
+func (r FutureGetAddressTxIDsResult) Receive() ([]*chainhash.Hash, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal as an array of strings.
+	var txids []string
+	err = json.Unmarshal(res, &txids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the string slice into a slice of hashes.
+	txHashes := make([]*chainhash.Hash, 0, len(txids))
+	for _, txid := range txids {
+		txHash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return nil, err
+		}
+		txHashes = append(txHashes, txHash)
+	}
+
+	return txHashes, nil
+}
+
+// GetAddressTxIDsAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetAddressTxIDs for the blocking version and more details.
+func (c *Client) GetAddressTxIDsAsync(address btcutil.Address, skip, count int, reverse bool) FutureGetAddressTxIDsResult {
+	addr := address.EncodeAddress()
+	cmd := btcjson.NewGetAddressTxIDsCmd(addr, &skip, &count, &reverse)
+	return c.SendCmd(cmd)
+}
+
+// GetAddressTxIDs returns the hashes of transactions that involve the passed
+// address, including those paying to claim, support and update outputs.
+//
+// NOTE: This is a lbcd extension and requires the address index to be enabled.
+func (c *Client) GetAddressTxIDs(address btcutil.Address, skip, count int, reverse bool) ([]*chainhash.Hash, error) {
+	return c.GetAddressTxIDsAsync(address, skip, count, reverse).Receive()
+}
+
+// FutureGetAddressBalanceResult is a future promise to deliver the result of
+// the GetAddressBalanceAsync RPC invocation (or an applicable error).
+type FutureGetAddressBalanceResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// balance of the address.
+func (r FutureGetAddressBalanceResult) Receive() (*btcjson.GetAddressBalanceResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var result btcjson.GetAddressBalanceResult
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetAddressBalanceAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetAddressBalance for the blocking version and more details.
+func (c *Client) GetAddressBalanceAsync(address btcutil.Address) FutureGetAddressBalanceResult {
+	cmd := btcjson.NewGetAddressBalanceCmd(address.EncodeAddress())
+	return c.SendCmd(cmd)
+}
+
+// GetAddressBalance returns the confirmed balance of the passed address,
+// including the amounts held in claim and support outputs.
+//
+// NOTE: This is a lbcd extension and requires the address index to be enabled.
+func (c *Client) GetAddressBalance(address btcutil.Address) (*btcjson.GetAddressBalanceResult, error) {
+	return c.GetAddressBalanceAsync(address).Receive()
+}
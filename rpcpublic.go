@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// publicRPCPruneInterval is the interval at which the rate limiter of the
+// public RPC server forgets clients that haven't made requests recently.
+const publicRPCPruneInterval = time.Minute
+
+// publicRPCMaxBodySize is the maximum size of the body of a request to the
+// public RPC server.  It is enough for batches of queries and for decoding
+// most transactions.
+const publicRPCMaxBodySize = 256 * 1024
+
+// publicRPCEligible is the set of read-only methods that may be allowed on the
+// public RPC server.  None of them change the state of the server or expose
+// information about its peers.
+var publicRPCEligible = map[string]struct{}{
+	"decoderawtransaction":   {},
+	"decodescript":           {},
+	"estimatefee":            {},
+	"getbestblock":           {},
+	"getbestblockhash":       {},
+	"getblock":               {},
+	"getblockchaininfo":      {},
+	"getblockcount":          {},
+	"getblockhash":           {},
+	"getblockheader":         {},
+	"getblockheaders":        {},
+	"getblockstats":          {},
+	"getcfilter":             {},
+	"getcfilterheader":       {},
+	"getchaintips":           {},
+	"getchangesinblock":      {},
+	"getclaimbyid":           {},
+	"getclaimsbychannel":     {},
+	"getclaimsforname":       {},
+	"getclaimsfornamebybid":  {},
+	"getclaimsfornamebyid":   {},
+	"getclaimsfornamebyseq":  {},
+	"getdifficulty":          {},
+	"getmempoolancestors":    {},
+	"getmempooldescendants":  {},
+	"getmempoolentry":        {},
+	"getmempoolfeehistogram": {},
+	"getnextdifficulty":      {},
+	"getrawmempool":          {},
+	"getrawtransaction":      {},
+	"getspentinfo":           {},
+	"gettxout":               {},
+	"normalizename":          {},
+	"resolve":                {},
+	"validateaddress":        {},
+}
+
+// defaultPublicRPCMethods is the list of methods allowed on the public RPC
+// server when none are configured.  It covers the block and claim queries.
+var defaultPublicRPCMethods = []string{
+	"getbestblockhash",
+	"getblock",
+	"getblockcount",
+	"getblockhash",
+	"getblockheader",
+	"getblockheaders",
+	"getchangesinblock",
+	"getclaimbyid",
+	"getclaimsbychannel",
+	"getclaimsforname",
+	"getclaimsfornamebybid",
+	"getclaimsfornamebyid",
+	"getclaimsfornamebyseq",
+	"getrawtransaction",
+	"normalizename",
+	"resolve",
+}
+
+// tokenBucket tracks the requests of a single client of a rateLimiter.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket with rate tokens per second elapsed since it was last
+// refilled, up to burst tokens, and then takes n tokens from it.  It returns
+// whether or not the bucket held enough of them.  No tokens are taken when it
+// didn't.
+func (b *tokenBucket) take(n, rate, burst float64, now time.Time) bool {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * rate
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+	}
+
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// rateLimiter limits the rate of requests per client with a token bucket for
+// each of them.  Buckets hold up to burst tokens and are refilled with rate
+// tokens per second.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mtx     sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter returns a rate limiter allowing clients to make rate requests
+// per second on average and up to burst requests at once.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Allow takes n tokens from the bucket of the passed client and returns whether
+// or not it held enough of them.  No tokens are taken when it didn't.
+//
+// This function is safe for concurrent access.
+func (l *rateLimiter) Allow(client string, n int, now time.Time) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+
+	return bucket.take(float64(n), l.rate, l.burst, now)
+}
+
+// Prune removes the buckets that have been refilled by the passed time since
+// they are indistinguishable from the bucket of a new client.
+//
+// This function is safe for concurrent access.
+func (l *rateLimiter) Prune(now time.Time) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for client, bucket := range l.buckets {
+		elapsed := now.Sub(bucket.last).Seconds()
+		if bucket.tokens+elapsed*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// publicRPCServer serves the methods of an allowlist over JSON-RPC to the
+// public.  Requests are rate limited per IP address unless the client
+// authenticates with the credentials of the RPC server, and are handled by the
+// command handlers of the RPC server.
+type publicRPCServer struct {
+	started    int32
+	shutdown   int32
+	numClients int32
+	rpc        *rpcServer
+	listeners  []net.Listener
+	methods    map[string]struct{}
+	limiter    *rateLimiter
+	wg         sync.WaitGroup
+	quit       chan struct{}
+}
+
+// newPublicRPCServer returns a new public RPC server serving the passed
+// methods on the passed listeners.
+func newPublicRPCServer(rpc *rpcServer, listeners []net.Listener,
+	methods []string, rate float64, burst int) *publicRPCServer {
+
+	allowed := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		allowed[method] = struct{}{}
+	}
+
+	return &publicRPCServer{
+		rpc:       rpc,
+		listeners: listeners,
+		methods:   allowed,
+		limiter:   newRateLimiter(rate, burst),
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start begins serving public RPC requests on the listeners of the server.
+func (p *publicRPCServer) Start() {
+	if atomic.AddInt32(&p.started, 1) != 1 {
+		return
+	}
+
+	rpcServeMux := http.NewServeMux()
+	httpServer := &http.Server{
+		Handler: rpcServeMux,
+
+		// Timeout connections which don't complete the initial
+		// handshake within the allowed timeframe.
+		ReadTimeout: time.Second * rpcAuthTimeoutSeconds,
+	}
+	rpcServeMux.HandleFunc("/", p.handleRequest)
+	for i := range restHandlers {
+		h := &restHandlers[i]
+		if _, ok := p.methods[h.method]; !ok {
+			continue
+		}
+		rpcServeMux.HandleFunc(h.path, func(w http.ResponseWriter, r *http.Request) {
+			p.handleREST(w, r, h)
+		})
+	}
+
+	for _, listener := range p.listeners {
+		p.wg.Add(1)
+		go func(listener net.Listener) {
+			rpcsLog.Infof("Public RPC server listening on %s",
+				listener.Addr())
+			httpServer.Serve(listener)
+			rpcsLog.Tracef("Public RPC listener done for %s",
+				listener.Addr())
+			p.wg.Done()
+		}(listener)
+	}
+
+	p.wg.Add(1)
+	go p.pruneHandler()
+}
+
+// Stop closes the listeners of the server.
+func (p *publicRPCServer) Stop() {
+	if atomic.AddInt32(&p.shutdown, 1) != 1 {
+		rpcsLog.Infof("Public RPC server is already in the process of " +
+			"shutting down")
+		return
+	}
+
+	rpcsLog.Warnf("Public RPC server shutting down")
+	for _, listener := range p.listeners {
+		if err := listener.Close(); err != nil {
+			rpcsLog.Errorf("Problem shutting down public rpc: %v", err)
+		}
+	}
+	close(p.quit)
+	p.wg.Wait()
+	rpcsLog.Infof("Public RPC server shutdown complete")
+}
+
+// pruneHandler periodically forgets the clients of the rate limiter that
+// haven't made requests recently.
+//
+// It must be run as a goroutine.
+func (p *publicRPCServer) pruneHandler() {
+	ticker := time.NewTicker(publicRPCPruneInterval)
+	defer ticker.Stop()
+
+out:
+	for {
+		select {
+		case now := <-ticker.C:
+			p.limiter.Prune(now)
+		case <-p.quit:
+			break out
+		}
+	}
+	p.wg.Done()
+}
+
+// handleRequest authenticates and rate limits a JSON-RPC request before
+// handing it to the RPC server restricted to the allowed methods.  Each entry
+// of a batched request counts as one request for rate limiting.
+func (p *publicRPCServer) handleRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Connection", "close")
+	w.Header().Set("Content-Type", "application/json")
+	r.Close = true
+
+	// Limit the number of connections to max allowed.
+	if p.limitConnections(w, r.RemoteAddr) {
+		return
+	}
+	defer atomic.AddInt32(&p.numClients, -1)
+
+	// Credentials are optional, but must be valid when provided.
+	authenticated, _, _, err := p.rpc.checkAuth(r, false)
+	if err != nil {
+		jsonAuthFail(w)
+		return
+	}
+
+	// Count the request before reading its body so clients exceeding the
+	// rate limit can't make the server read large bodies.
+	if !authenticated && p.rateLimited(w, r.RemoteAddr, 1) {
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body,
+		publicRPCMaxBodySize))
+	r.Body.Close()
+	if err != nil {
+		errCode := http.StatusBadRequest
+		if _, ok := err.(*http.MaxBytesError); ok {
+			errCode = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, fmt.Sprintf("%d error reading JSON message: %v",
+			errCode, err), errCode)
+		return
+	}
+
+	// The remaining entries of a batched request are counted once it has
+	// been read.
+	if !authenticated && bytes.HasPrefix(body, batchedRequestPrefix) {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err == nil &&
+			len(batch) > 1 {
+
+			if p.rateLimited(w, r.RemoteAddr, len(batch)-1) {
+				return
+			}
+		}
+	}
+
+	// Read and respond to the request.
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	p.rpc.jsonRPCRead(w, r, false, p.methods)
+}
+
+// handleREST authenticates and rate limits a REST request before handing it to
+// the passed endpoint of the RPC server.  It counts as one request for rate
+// limiting.
+func (p *publicRPCServer) handleREST(w http.ResponseWriter, r *http.Request, h *restHandler) {
+	w.Header().Set("Connection", "close")
+	r.Close = true
+
+	// Limit the number of connections to max allowed.
+	if p.limitConnections(w, r.RemoteAddr) {
+		return
+	}
+	defer atomic.AddInt32(&p.numClients, -1)
+
+	// Credentials are optional, but must be valid when provided.
+	authenticated, _, _, err := p.rpc.checkAuth(r, false)
+	if err != nil {
+		jsonAuthFail(w)
+		return
+	}
+	if !authenticated && p.rateLimited(w, r.RemoteAddr, 1) {
+		return
+	}
+
+	p.rpc.handleREST(w, r, h)
+}
+
+// limitConnections counts a new client of the server and replies with an error
+// when it exceeds the maximum number of clients, in which case it returns true
+// and the client is no longer counted.
+func (p *publicRPCServer) limitConnections(w http.ResponseWriter, remoteAddr string) bool {
+	if int(atomic.AddInt32(&p.numClients, 1)) > cfg.RPCMaxClients {
+		atomic.AddInt32(&p.numClients, -1)
+		rpcsLog.Debugf("Max public RPC clients exceeded [%d] - "+
+			"disconnecting client %s", cfg.RPCMaxClients,
+			remoteAddr)
+		http.Error(w, "503 Too busy.  Try again later.",
+			http.StatusServiceUnavailable)
+		return true
+	}
+	return false
+}
+
+// rateLimited counts the passed number of requests of the client at the remote
+// address and replies with an error when they exceed its rate limit, in which
+// case it returns true.
+func (p *publicRPCServer) rateLimited(w http.ResponseWriter, remoteAddr string, numRequests int) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	if !p.limiter.Allow(host, numRequests, time.Now()) {
+		rpcsLog.Debugf("Public RPC rate limit exceeded by %s", host)
+		http.Error(w, "429 Too many requests.  Try again later.",
+			http.StatusTooManyRequests)
+		return true
+	}
+	return false
+}
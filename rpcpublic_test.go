@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+// TestRateLimiter ensures the rate limiter allows bursts, refills over time
+// and tracks clients independently.
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 3)
+	now := time.Now()
+
+	// The whole burst is available at once, and no more.
+	if !l.Allow("a", 3, now) {
+		t.Fatalf("Allow: burst rejected")
+	}
+	if l.Allow("a", 1, now) {
+		t.Fatalf("Allow: request allowed with empty bucket")
+	}
+
+	// Other clients have their own bucket.
+	if !l.Allow("b", 1, now) {
+		t.Fatalf("Allow: request of other client rejected")
+	}
+
+	// Two tokens are refilled every second, up to the burst.
+	now = now.Add(time.Second)
+	if !l.Allow("a", 2, now) {
+		t.Fatalf("Allow: refilled tokens rejected")
+	}
+	if l.Allow("a", 1, now) {
+		t.Fatalf("Allow: request allowed beyond refill")
+	}
+	now = now.Add(time.Hour)
+	if l.Allow("a", 4, now) {
+		t.Fatalf("Allow: request allowed beyond burst")
+	}
+
+	// Requests rejected for lack of tokens take none.
+	if !l.Allow("a", 3, now) {
+		t.Fatalf("Allow: rejected request took tokens")
+	}
+
+	// Pruning forgets the refilled buckets only.
+	l.Prune(now.Add(time.Second))
+	if _, ok := l.buckets["a"]; !ok {
+		t.Fatalf("Prune: removed bucket that isn't refilled")
+	}
+	if _, ok := l.buckets["b"]; ok {
+		t.Fatalf("Prune: kept refilled bucket")
+	}
+}
+
+// TestPublicRPCMethods ensures methods missing from the allowlist are rejected
+// and that the default allowlist only holds read-only methods.
+func TestPublicRPCMethods(t *testing.T) {
+	for _, method := range defaultPublicRPCMethods {
+		if _, ok := publicRPCEligible[method]; !ok {
+			t.Errorf("default public method %q is not eligible",
+				method)
+		}
+		if _, ok := rpcHandlers[method]; !ok {
+			t.Errorf("default public method %q has no handler",
+				method)
+		}
+	}
+
+	s := &rpcServer{}
+	methods := map[string]struct{}{"getblockcount": {}}
+	for _, isAdmin := range []bool{false, true} {
+		req := &btcjson.Request{
+			Jsonrpc: btcjson.RpcVersion1,
+			Method:  "stop",
+			Params:  []json.RawMessage{},
+			ID:      1,
+		}
+		reply := s.processRequest(req, "", isAdmin, methods, nil)
+
+		var resp btcjson.Response
+		if err := json.Unmarshal(reply, &resp); err != nil {
+			t.Fatalf("unable to unmarshal reply: %v", err)
+		}
+		if resp.Error == nil ||
+			resp.Error.Code != btcjson.ErrRPCMethodNotFound.Code {
+
+			t.Fatalf("processRequest: got error %v, want %v",
+				resp.Error, btcjson.ErrRPCMethodNotFound)
+		}
+	}
+}
+
+// countingReader is a request body recording whether it was read.
+type countingReader struct {
+	io.Reader
+	read bool
+}
+
+// Read records the read and reads from the underlying reader.
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.read = true
+	return r.Reader.Read(p)
+}
+
+// TestPublicRPCRequestLimits ensures the public RPC server rejects oversized
+// bodies, and rejects the requests of clients exceeding their rate limit
+// without reading their body.
+func TestPublicRPCRequestLimits(t *testing.T) {
+	defer func(c *config) { cfg = c }(cfg)
+	cfg = &config{RPCMaxClients: 10}
+
+	p := newPublicRPCServer(&rpcServer{}, nil, []string{"getblockcount"},
+		0.001, 2)
+	send := func(body string) (int, bool) {
+		reader := &countingReader{Reader: strings.NewReader(body)}
+		r := httptest.NewRequest("POST", "/", reader)
+		r.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		p.handleRequest(w, r)
+		return w.Code, reader.read
+	}
+
+	body := strings.Repeat(" ", publicRPCMaxBodySize+1)
+	if code, _ := send(body); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("got status %d for oversized body, want %d", code,
+			http.StatusRequestEntityTooLarge)
+	}
+
+	// The remaining entries of a batch are counted once it is read.
+	request := `{"jsonrpc":"1.0","id":1,"method":"getblockcount"}`
+	batch := "[" + request + "," + request + "," + request + "]"
+	if code, _ := send(batch); code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d for batch exceeding the burst, want %d",
+			code, http.StatusTooManyRequests)
+	}
+
+	code, read := send(request)
+	if code != http.StatusTooManyRequests || read {
+		t.Fatalf("got status %d (body read %v) once rate limited, "+
+			"want %d without reading the body", code, read,
+			http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/wire"
+)
+
+// restFormat is the output format of a REST request, given by the extension of
+// its path.
+type restFormat string
+
+const (
+	// restBinary outputs the serialized bytes of the requested object.
+	restBinary restFormat = "bin"
+
+	// restHex outputs the serialized bytes hex-encoded on a single line.
+	restHex restFormat = "hex"
+
+	// restJSON outputs the verbose JSON-RPC result for the object.
+	restJSON restFormat = "json"
+)
+
+// restHandler describes a REST endpoint of the RPC server.  Requests take the
+// form
+//
+//	<path><param>.<format>[?<query>]
+//
+// and are answered by the handler, which returns either the serialized bytes
+// of the requested object for the binary and hex formats, or a value to be
+// marshalled for the JSON format.
+type restHandler struct {
+	path    string
+	method  string // JSON-RPC method serving the same data
+	formats []restFormat
+	handler func(s *rpcServer, param string, format restFormat, query url.Values) (interface{}, error)
+
+	// immutable is set when the serialized bytes returned for a param
+	// never change, so the binary and hex responses can be cached
+	// forever.
+	immutable bool
+}
+
+// restHandlers lists the REST endpoints of the RPC server.
+var restHandlers = []restHandler{
+	{
+		path:      "/rest/block/",
+		method:    "getblock",
+		formats:   []restFormat{restBinary, restHex, restJSON},
+		handler:   handleRESTBlock,
+		immutable: true,
+	},
+	{
+		path:      "/rest/block/notxdetails/",
+		method:    "getblock",
+		formats:   []restFormat{restBinary, restHex, restJSON},
+		handler:   handleRESTBlockNoTxDetails,
+		immutable: true,
+	},
+	{
+		path:    "/rest/tx/",
+		method:  "getrawtransaction",
+		formats: []restFormat{restBinary, restHex, restJSON},
+		handler: handleRESTTx,
+	},
+	{
+		path:    "/rest/headers/",
+		method:  "getblockheaders",
+		formats: []restFormat{restBinary, restHex, restJSON},
+		handler: handleRESTHeaders,
+	},
+	{
+		path:    "/rest/claim/name/",
+		method:  "getclaimsforname",
+		formats: []restFormat{restJSON},
+		handler: handleRESTClaimName,
+	},
+}
+
+// authorized returns whether a client authenticated with the passed rights may
+// use the endpoint, which requires it to be allowed to call the JSON-RPC method
+// serving the same data.  When methods is not nil, it holds the only methods
+// the client may call regardless of isAdmin, like for the JSON-RPC requests.
+func (h *restHandler) authorized(isAdmin bool, methods map[string]struct{}) bool {
+	if methods != nil {
+		_, ok := methods[h.method]
+		return ok
+	}
+	if isAdmin {
+		return true
+	}
+	_, ok := rpcLimited[h.method]
+	return ok
+}
+
+// checkRESTAuth checks the authentication of a REST request like the JSON-RPC
+// requests are checked when the REST interface isn't enabled, and whether the
+// client may use the endpoint.  It replies with the failure and returns false
+// when either check fails.
+func (s *rpcServer) checkRESTAuth(w http.ResponseWriter, r *http.Request, h *restHandler) bool {
+	_, isAdmin, methods, err := s.checkAuth(r, true)
+	if err != nil {
+		jsonAuthFail(w)
+		return false
+	}
+	if !h.authorized(isAdmin, methods) {
+		restError(w, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	return true
+}
+
+// restStatusCode returns the HTTP status code of the responses to the REST
+// requests failing with the passed error.
+func restStatusCode(err error) int {
+	rpcErr, ok := err.(*btcjson.RPCError)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	switch rpcErr.Code {
+	case btcjson.ErrRPCInvalidParameter, btcjson.ErrRPCDecodeHexString:
+		return http.StatusBadRequest
+	case btcjson.ErrRPCBlockNotFound, btcjson.ErrRPCOutOfRange:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// restError replies to a REST request with the passed status code and message.
+func restError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "text/plain")
+	http.Error(w, fmt.Sprintf("%d %s", code, message), code)
+}
+
+// handleREST serves a REST request with the passed endpoint.
+func (s *rpcServer) handleREST(w http.ResponseWriter, r *http.Request, h *restHandler) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		restError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Split the param from the output format.
+	param := strings.TrimPrefix(r.URL.Path, h.path)
+	var format restFormat
+	if dot := strings.LastIndexByte(param, '.'); dot >= 0 {
+		param, format = param[:dot], restFormat(param[dot+1:])
+	}
+	supported := false
+	names := make([]string, 0, len(h.formats))
+	for _, f := range h.formats {
+		supported = supported || f == format
+		names = append(names, string(f))
+	}
+	if !supported {
+		restError(w, http.StatusBadRequest, "Output format not found "+
+			"(available: "+strings.Join(names, ", ")+")")
+		return
+	}
+
+	result, err := h.handler(s, param, format, r.URL.Query())
+	if err != nil {
+		restError(w, restStatusCode(err), err.Error())
+		return
+	}
+
+	var body []byte
+	switch format {
+	case restBinary:
+		body = result.([]byte)
+		w.Header().Set("Content-Type", "application/octet-stream")
+
+	case restHex:
+		body = []byte(hex.EncodeToString(result.([]byte)) + "\n")
+		w.Header().Set("Content-Type", "text/plain")
+
+	case restJSON:
+		msg, err := json.Marshal(result)
+		if err != nil {
+			restError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		body = append(msg, '\n')
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if h.immutable && format != restJSON {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	w.Write(body)
+}
+
+// restHexResult decodes the hex-encoded result of a JSON-RPC handler into the
+// serialized bytes returned to REST requests.
+func restHexResult(result interface{}) ([]byte, error) {
+	serialized, err := hex.DecodeString(result.(string))
+	if err != nil {
+		context := "Failed to decode result"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return serialized, nil
+}
+
+// handleRESTBlock serves the block of the passed hash, including the details
+// of its transactions in the JSON format.
+func handleRESTBlock(s *rpcServer, param string, format restFormat, query url.Values) (interface{}, error) {
+	return restBlock(s, param, format, 2)
+}
+
+// handleRESTBlockNoTxDetails serves the block of the passed hash, with only the
+// hashes of its transactions in the JSON format.
+func handleRESTBlockNoTxDetails(s *rpcServer, param string, format restFormat, query url.Values) (interface{}, error) {
+	return restBlock(s, param, format, 1)
+}
+
+// restBlock serves the block of the passed hash with the getblock handler at
+// the passed verbosity for the JSON format.
+func restBlock(s *rpcServer, hash string, format restFormat, verbosity int) (interface{}, error) {
+	if format != restJSON {
+		verbosity = 0
+	}
+	result, err := handleGetBlock(s, btcjson.NewGetBlockCmd(hash,
+		&verbosity), nil)
+	if err != nil || format == restJSON {
+		return result, err
+	}
+	return restHexResult(result)
+}
+
+// handleRESTTx serves the transaction of the passed hash from the memory pool
+// or, with the transaction index, from the block database.
+func handleRESTTx(s *rpcServer, param string, format restFormat, query url.Values) (interface{}, error) {
+	verbose := format == restJSON
+	result, err := handleGetRawTransaction(s,
+		btcjson.NewGetRawTransactionCmd(param, &verbose), nil)
+	if err != nil || verbose {
+		return result, err
+	}
+	return restHexResult(result)
+}
+
+// handleRESTHeaders serves the headers of up to count consecutive blocks of
+// the main chain, starting at the block of the passed hash or height.  The
+// count defaults to the maximum of 2000 headers.
+func handleRESTHeaders(s *rpcServer, param string, format restFormat, query url.Values) (interface{}, error) {
+	// Block hashes are told apart from heights by their length.
+	var hashOrHeight interface{} = param
+	if len(param) != chainhash.MaxHashStringSize {
+		height, err := strconv.ParseInt(param, 10, 32)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid block hash or height: " + param,
+			}
+		}
+		hashOrHeight = int(height)
+	}
+
+	count := int32(wire.MaxBlockHeadersPerMsg)
+	if str := query.Get("count"); str != "" {
+		c, err := strconv.ParseInt(str, 10, 32)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid count: " + str,
+			}
+		}
+		count = int32(c)
+	}
+
+	hashes, headers, startHeight, err := fetchBlockHeaders(s,
+		hashOrHeight, count)
+	if err != nil {
+		return nil, err
+	}
+	if format == restJSON {
+		return blockHeadersVerboseResults(s, hashes, headers,
+			startHeight), nil
+	}
+
+	var headersBuf bytes.Buffer
+	headersBuf.Grow(len(headers) * wire.MaxBlockHeaderPayload)
+	for i := range headers {
+		if err := headers[i].Serialize(&headersBuf); err != nil {
+			context := "Failed to serialize block header"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	}
+	return headersBuf.Bytes(), nil
+}
+
+// handleRESTClaimName serves the claims of the passed name, at the block of
+// the hash or height of the optional block query parameter or else at the
+// best block.  The values of the claims are included when the values query
+// parameter is true.
+func handleRESTClaimName(s *rpcServer, param string, format restFormat, query url.Values) (interface{}, error) {
+	var includeValues bool
+	if str := query.Get("values"); str != "" {
+		var err error
+		includeValues, err = strconv.ParseBool(str)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid values flag: " + str,
+			}
+		}
+	}
+	hashOrHeight := query.Get("block")
+
+	return handleGetClaimsForName(s, &btcjson.GetClaimsForNameCmd{
+		Name:          param,
+		HashOrHeight:  &hashOrHeight,
+		IncludeValues: &includeValues,
+	}, nil)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRESTBadRequests ensures malformed REST requests are rejected before
+// looking up the chain.
+func TestRESTBadRequests(t *testing.T) {
+	s := &rpcServer{}
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodPost, "/rest/headers/0.bin", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/rest/headers/0", http.StatusBadRequest},
+		{http.MethodGet, "/rest/headers/0.xml", http.StatusBadRequest},
+		{http.MethodGet, "/rest/headers/tip.hex", http.StatusBadRequest},
+		{http.MethodGet, "/rest/headers/0.hex?count=x", http.StatusBadRequest},
+		{http.MethodGet, "/rest/headers/0.hex?count=0", http.StatusBadRequest},
+		{http.MethodGet, "/rest/headers/0.hex?count=2001", http.StatusBadRequest},
+		{http.MethodGet, "/rest/block/xyz.bin", http.StatusBadRequest},
+		{http.MethodGet, "/rest/tx/xyz.hex", http.StatusBadRequest},
+		{http.MethodGet, "/rest/claim/name/one.bin", http.StatusBadRequest},
+		{http.MethodGet, "/rest/claim/name/one.json?values=x", http.StatusBadRequest},
+	}
+
+	for i, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		w := httptest.NewRecorder()
+		s.handleREST(w, r, restHandlerFor(test.path))
+		if w.Code != test.code {
+			t.Errorf("handleREST #%d (%s %s): got status %d, want "+
+				"%d", i, test.method, test.path, w.Code, test.code)
+		}
+	}
+}
+
+// restHandlerFor returns the REST endpoint serving the passed path, which is
+// the one with the longest matching path like the serve mux finds.
+func restHandlerFor(path string) *restHandler {
+	var h *restHandler
+	for i := range restHandlers {
+		if strings.HasPrefix(path, restHandlers[i].path) &&
+			(h == nil || len(restHandlers[i].path) > len(h.path)) {
+
+			h = &restHandlers[i]
+		}
+	}
+	return h
+}
+
+// TestRESTLimitedUser ensures limited users may only use the REST endpoints
+// serving the data of the methods they may call.
+func TestRESTLimitedUser(t *testing.T) {
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	s := &rpcServer{limitauthsha: sha256.Sum256([]byte(auth))}
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/rest/block/00.bin", http.StatusOK},
+		{"/rest/tx/00.hex", http.StatusOK},
+		{"/rest/headers/00.json", http.StatusOK},
+		{"/rest/claim/name/one.json", http.StatusForbidden},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		r.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		ok := s.checkRESTAuth(w, r, restHandlerFor(test.path))
+		if ok != (test.code == http.StatusOK) || w.Code != test.code {
+			t.Errorf("checkRESTAuth #%d (%s): got %v with status %d, "+
+				"want status %d", i, test.path, ok, w.Code,
+				test.code)
+		}
+	}
+}
+
+// TestRESTAccount ensures RPC accounts may only use the REST endpoints serving
+// the data of the methods of their access lists.
+func TestRESTAccount(t *testing.T) {
+	account, err := parseRPCAccount("explorer:pass:getblock,getclaimsforname")
+	if err != nil {
+		t.Fatalf("parseRPCAccount: unexpected error: %v", err)
+	}
+	s := &rpcServer{accounts: []*rpcAccount{account}}
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("explorer:pass"))
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/rest/block/00.bin", http.StatusOK},
+		{"/rest/block/notxdetails/00.json", http.StatusOK},
+		{"/rest/tx/00.hex", http.StatusForbidden},
+		{"/rest/headers/00.json", http.StatusForbidden},
+		{"/rest/claim/name/one.json", http.StatusOK},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		r.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		ok := s.checkRESTAuth(w, r, restHandlerFor(test.path))
+		if ok != (test.code == http.StatusOK) || w.Code != test.code {
+			t.Errorf("checkRESTAuth #%d (%s): got %v with status %d, "+
+				"want status %d", i, test.path, ok, w.Code,
+				test.code)
+		}
+	}
+}
@@ -6,6 +6,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -21,6 +22,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -36,12 +38,15 @@ import (
 	"github.com/lbryio/lbcd/btcjson"
 	"github.com/lbryio/lbcd/chaincfg"
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie/change"
 	"github.com/lbryio/lbcd/database"
 	"github.com/lbryio/lbcd/fees"
 	"github.com/lbryio/lbcd/mempool"
 	"github.com/lbryio/lbcd/mining"
 	"github.com/lbryio/lbcd/mining/cpuminer"
+	"github.com/lbryio/lbcd/netsync"
 	"github.com/lbryio/lbcd/peer"
+	"github.com/lbryio/lbcd/portmap"
 	"github.com/lbryio/lbcd/txscript"
 	"github.com/lbryio/lbcd/version"
 	"github.com/lbryio/lbcd/wire"
@@ -135,23 +140,30 @@ type commandHandler func(*rpcServer, interface{}, <-chan struct{}) (interface{},
 var rpcHandlers map[string]commandHandler
 var rpcHandlersBeforeInit = map[string]commandHandler{
 	"addnode":                handleAddNode,
+	"backupchainstate":       handleBackupChainState,
 	"clearbanned":            handleClearBanned,
 	"createrawtransaction":   handleCreateRawTransaction,
 	"debuglevel":             handleDebugLevel,
+	"debugscript":            handleDebugScript,
 	"decoderawtransaction":   handleDecodeRawTransaction,
 	"decodescript":           handleDecodeScript,
+	"dumptxoutset":           handleDumpTxOutSet,
 	"estimatefee":            handleEstimateFee,
 	"estimatesmartfee":       handleEstimateSmartFee,
 	"generate":               handleGenerate,
 	"generatetoaddress":      handleGenerateToAddress,
 	"getaddednodeinfo":       handleGetAddedNodeInfo,
+	"getaddressbalance":      handleGetAddressBalance,
+	"getaddresstxids":        handleGetAddressTxIDs,
 	"getbestblock":           handleGetBestBlock,
 	"getbestblockhash":       handleGetBestBlockHash,
 	"getblock":               handleGetBlock,
 	"getblockchaininfo":      handleGetBlockChainInfo,
 	"getblockcount":          handleGetBlockCount,
+	"getblockfrompeer":       handleGetBlockFromPeer,
 	"getblockhash":           handleGetBlockHash,
 	"getblockheader":         handleGetBlockHeader,
+	"getblockheaders":        handleGetBlockHeaders,
 	"getblockstats":          handleGetBlockStats,
 	"getblocktemplate":       handleGetBlockTemplate,
 	"getcfilter":             handleGetCFilter,
@@ -164,33 +176,47 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"gethashespersec":        handleGetHashesPerSec,
 	"getheaders":             handleGetHeaders,
 	"getinfo":                handleGetInfo,
+	"getmempoolancestors":    handleGetMempoolAncestors,
+	"getmempooldescendants":  handleGetMempoolDescendants,
 	"getmempoolentry":        handleGetMempoolEntry,
+	"getmempoolfeehistogram": handleGetMempoolFeeHistogram,
 	"getmempoolinfo":         handleGetMempoolInfo,
 	"getmininginfo":          handleGetMiningInfo,
 	"getnettotals":           handleGetNetTotals,
 	"getnetworkhashps":       handleGetNetworkHashPS,
+	"getnextdifficulty":      handleGetNextDifficulty,
 	"getnetworkinfo":         handleGetNetworkInfo,
 	"getnodeaddresses":       handleGetNodeAddresses,
 	"getpeerinfo":            handleGetPeerInfo,
 	"getrawmempool":          handleGetRawMempool,
 	"getrawtransaction":      handleGetRawTransaction,
+	"getrpcstats":            handleGetRPCStats,
+	"getspentinfo":           handleGetSpentInfo,
 	"gettxout":               handleGetTxOut,
+	"gettxoutsetinfo":        handleGetTxOutSetInfo,
+	"getvalidationinfo":      handleGetValidationInfo,
 	"help":                   handleHelp,
 	"invalidateblock":        handleInvalidateBlock,
 	"listbanned":             handleListBanned,
+	"loadtxoutset":           handleLoadTxOutSet,
 	"node":                   handleNode,
 	"ping":                   handlePing,
 	"reconsiderblock":        handleReconsiderBlock,
+	"scantxoutset":           handleScanTxOutSet,
 	"searchrawtransactions":  handleSearchRawTransactions,
 	"sendrawtransaction":     handleSendRawTransaction,
 	"setban":                 handleSetBan,
 	"setgenerate":            handleSetGenerate,
+	"setvalidationworkers":   handleSetValidationWorkers,
 	"signmessagewithprivkey": handleSignMessageWithPrivKey,
 	"stop":                   handleStop,
 	"submitblock":            handleSubmitBlock,
+	"submitpackage":          handleSubmitPackage,
+	"testmempoolaccept":      handleTestMempoolAccept,
 	"uptime":                 handleUptime,
 	"validateaddress":        handleValidateAddress,
 	"verifychain":            handleVerifyChain,
+	"verifydb":               handleVerifyDB,
 	"verifymessage":          handleVerifyMessage,
 	"version":                handleVersion,
 }
@@ -215,7 +241,6 @@ var rpcAskWallet = map[string]struct{}{
 	"getreceivedbyaccount":   {},
 	"getreceivedbyaddress":   {},
 	"gettransaction":         {},
-	"gettxoutsetinfo":        {},
 	"getunconfirmedbalance":  {},
 	"getwalletinfo":          {},
 	"importprivkey":          {},
@@ -252,6 +277,7 @@ var rpcUnimplemented = map[string]struct{}{
 // Commands that are available to a limited user
 var rpcLimited = map[string]struct{}{
 	// Websockets commands
+	"getblockstream":        {},
 	"loadtxfilter":          {},
 	"notifyblocks":          {},
 	"notifynewtransactions": {},
@@ -265,34 +291,43 @@ var rpcLimited = map[string]struct{}{
 	"help": {},
 
 	// HTTP/S-only commands
-	"createrawtransaction":  {},
-	"decoderawtransaction":  {},
-	"decodescript":          {},
-	"estimatefee":           {},
-	"getbestblock":          {},
-	"getbestblockhash":      {},
-	"getblock":              {},
-	"getblockcount":         {},
-	"getblockhash":          {},
-	"getblockheader":        {},
-	"getcfilter":            {},
-	"getcfilterheader":      {},
-	"getcurrentnet":         {},
-	"getdifficulty":         {},
-	"getheaders":            {},
-	"getinfo":               {},
-	"getnettotals":          {},
-	"getnetworkhashps":      {},
-	"getrawmempool":         {},
-	"getrawtransaction":     {},
-	"gettxout":              {},
-	"searchrawtransactions": {},
-	"sendrawtransaction":    {},
-	"submitblock":           {},
-	"uptime":                {},
-	"validateaddress":       {},
-	"verifymessage":         {},
-	"version":               {},
+	"createrawtransaction":   {},
+	"decoderawtransaction":   {},
+	"decodescript":           {},
+	"estimatefee":            {},
+	"getbestblock":           {},
+	"getaddresstxids":        {},
+	"getbestblockhash":       {},
+	"getblock":               {},
+	"getblockcount":          {},
+	"getblockhash":           {},
+	"getblockheader":         {},
+	"getblockheaders":        {},
+	"getcfilter":             {},
+	"getcfilterheader":       {},
+	"getcurrentnet":          {},
+	"getdifficulty":          {},
+	"getheaders":             {},
+	"getinfo":                {},
+	"getnettotals":           {},
+	"getnetworkhashps":       {},
+	"getnextdifficulty":      {},
+	"getmempoolancestors":    {},
+	"getmempooldescendants":  {},
+	"getmempoolfeehistogram": {},
+	"getrawmempool":          {},
+	"getrawtransaction":      {},
+	"getspentinfo":           {},
+	"gettxout":               {},
+	"searchrawtransactions":  {},
+	"sendrawtransaction":     {},
+	"submitblock":            {},
+	"submitpackage":          {},
+	"testmempoolaccept":      {},
+	"uptime":                 {},
+	"validateaddress":        {},
+	"verifymessage":          {},
+	"version":                {},
 }
 
 // builderScript is a convenience function which is used for hard-coded scripts
@@ -347,6 +382,14 @@ func rpcNoTxInfoError(txHash *chainhash.Hash) *btcjson.RPCError {
 			txHash))
 }
 
+// rpcBlockPrunedError is a convenience function for returning a nicely
+// formatted RPC error which indicates the data for the provided block hash has
+// been pruned.
+func rpcBlockPrunedError(blockHash *chainhash.Hash) *btcjson.RPCError {
+	return btcjson.NewRPCError(btcjson.ErrRPCMisc,
+		fmt.Sprintf("Block not available (pruned data): %v", blockHash))
+}
+
 // gbtWorkState houses state that is used in between multiple RPC invocations to
 // getblocktemplate.
 type gbtWorkState struct {
@@ -358,17 +401,34 @@ type gbtWorkState struct {
 	template      *mining.BlockTemplate
 	notifyMap     map[chainhash.Hash]map[int64]chan struct{}
 	timeSource    blockchain.MedianTimeSource
+
+	// newTxFees is the sum of the fees of the transactions added to the
+	// memory pool since the template was generated.  A new template is
+	// generated right away once it reaches feeDelta, which disables the
+	// behavior when zero.
+	newTxFees int64
+	feeDelta  int64
 }
 
 // newGbtWorkState returns a new instance of a gbtWorkState with all internal
 // fields initialized and ready to use.
-func newGbtWorkState(timeSource blockchain.MedianTimeSource) *gbtWorkState {
+func newGbtWorkState(timeSource blockchain.MedianTimeSource, feeDelta btcutil.Amount) *gbtWorkState {
 	return &gbtWorkState{
 		notifyMap:  make(map[chainhash.Hash]map[int64]chan struct{}),
 		timeSource: timeSource,
+		feeDelta:   int64(feeDelta),
 	}
 }
 
+// feeDeltaReached returns whether the fees of the transactions added to the
+// memory pool since the template was generated warrant a new template without
+// waiting for gbtRegenerateSeconds.
+//
+// This function MUST be called with the state locked.
+func (state *gbtWorkState) feeDeltaReached() bool {
+	return state.feeDelta > 0 && state.newTxFees >= state.feeDelta
+}
+
 // handleUnimplemented is the handler for commands that should ultimately be
 // supported but are not yet implemented.
 func handleUnimplemented(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -413,6 +473,63 @@ func handleAddNode(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 	return nil, nil
 }
 
+// handleBackupChainState handles backupchainstate commands.
+func handleBackupChainState(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.BackupChainStateCmd)
+
+	path := c.Destination
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.DataDir, path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Backup file already exists: " + path,
+		}
+	}
+
+	// Write to a temporary file first so an interrupted backup doesn't
+	// leave a partial archive behind.
+	tempPath := path + ".incomplete"
+	f, err := os.Create(tempPath)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to create backup file: " + err.Error(),
+		}
+	}
+	w := bufio.NewWriter(f)
+	err = s.cfg.DB.Backup(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	var size int64
+	if err == nil {
+		size, err = f.Seek(0, io.SeekCurrent)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: "Unable to back up the chain state: " + err.Error(),
+		}
+	}
+
+	return &btcjson.BackupChainStateResult{
+		Path: path,
+		Size: size,
+	}, nil
+}
+
 // handleClearBanned handles clearbanned commands.
 func handleClearBanned(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 
@@ -705,6 +822,122 @@ func handleDebugLevel(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return "Done.", nil
 }
 
+// debugScriptConsensusFlags are the script flags enforced by the consensus rules
+// once all the script soft forks are active, which debugscript uses unless the
+// standardness checks are requested.
+const debugScriptConsensusFlags = txscript.ScriptBip16 |
+	txscript.ScriptVerifyDERSignatures |
+	txscript.ScriptVerifyCheckLockTimeVerify |
+	txscript.ScriptVerifyCheckSequenceVerify |
+	txscript.ScriptVerifyWitness |
+	txscript.ScriptStrictMultiSig
+
+// handleDebugScript implements the debugscript command.
+func handleDebugScript(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DebugScriptCmd)
+
+	// Deserialize the transaction.
+	hexStr := c.HexTx
+	if len(hexStr)%2 != 0 {
+		hexStr = "0" + hexStr
+	}
+	serializedTx, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+	var mtx wire.MsgTx
+	err = mtx.Deserialize(bytes.NewReader(serializedTx))
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "TX decode failed: " + err.Error(),
+		}
+	}
+	if c.Index >= uint32(len(mtx.TxIn)) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Input index %d out of range -- "+
+				"the transaction has %d inputs", c.Index,
+				len(mtx.TxIn)),
+		}
+	}
+
+	// Look up the output spent by the input in the mempool and the utxo
+	// set unless it is provided.
+	var pkScript []byte
+	var amount int64
+	if c.ScriptPubKey != nil {
+		pkScript, err = hex.DecodeString(*c.ScriptPubKey)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.ScriptPubKey)
+		}
+	} else {
+		prevOut := mtx.TxIn[c.Index].PreviousOutPoint
+		var txOut *wire.TxOut
+		if tx, err := s.cfg.TxMemPool.FetchTransaction(&prevOut.Hash); err == nil &&
+			prevOut.Index < uint32(len(tx.MsgTx().TxOut)) {
+
+			txOut = tx.MsgTx().TxOut[prevOut.Index]
+		} else if entry, err := s.cfg.Chain.FetchUtxoEntry(prevOut); err == nil &&
+			entry != nil && !entry.IsSpent() {
+
+			txOut = wire.NewTxOut(entry.Amount(), entry.PkScript())
+		}
+		if txOut == nil {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCNoTxInfo,
+				Message: fmt.Sprintf("Output %v spent by the "+
+					"input not found -- provide its "+
+					"scriptpubkey", prevOut),
+			}
+		}
+		pkScript = txOut.PkScript
+		amount = txOut.Value
+	}
+	if c.Amount != nil {
+		value, err := btcutil.NewAmount(*c.Amount)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid amount: " + err.Error(),
+			}
+		}
+		amount = int64(value)
+	}
+
+	flags := txscript.ScriptFlags(debugScriptConsensusFlags)
+	if c.Standard == nil || *c.Standard {
+		flags = txscript.StandardVerifyFlags
+	}
+	steps, err := txscript.TraceScript(pkScript, &mtx, int(c.Index), flags,
+		amount)
+
+	result := &btcjson.DebugScriptResult{
+		Valid: err == nil,
+		Steps: make([]btcjson.DebugScriptStep, 0, len(steps)),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	for _, step := range steps {
+		resultStep := btcjson.DebugScriptStep{
+			Script:   step.ScriptIndex,
+			Opcode:   step.OpcodeIndex,
+			Asm:      step.Opcode,
+			Stack:    witnessToHex(step.Stack),
+			AltStack: witnessToHex(step.AltStack),
+		}
+		if resultStep.Stack == nil {
+			resultStep.Stack = []string{}
+		}
+		if step.Err != nil {
+			resultStep.Error = step.Err.Error()
+		}
+		result.Steps = append(result.Steps, resultStep)
+	}
+	return result, nil
+}
+
 // witnessToHex formats the passed witness stack as a slice of hex-encoded
 // strings to be used in a JSON response.
 func witnessToHex(witness wire.TxWitness) []string {
@@ -758,34 +991,64 @@ func createVinList(mtx *wire.MsgTx) []btcjson.Vin {
 	return vinList
 }
 
+// createScriptPubKeyResult returns a JSON object for the passed public key
+// script.
+func createScriptPubKeyResult(pkScript []byte, chainParams *chaincfg.Params) btcjson.ScriptPubKeyResult {
+	// The disassembled string will contain [error] inline if the script
+	// doesn't fully parse, so ignore the error here.
+	disbuf, _ := txscript.DisasmString(pkScript)
+
+	script := txscript.StripClaimScriptPrefix(pkScript)
+
+	// The addresses of time-locked claims are the ones of the script the
+	// claims are paid to once the lock time passed.
+	addrScript := script
+	if len(script) < len(pkScript) {
+		if lock := txscript.ExtractTimeLock(script); lock != nil {
+			addrScript = script[lock.Size:]
+		}
+	}
+
+	// Ignore the error here since an error means the script couldn't parse
+	// and there is no additional information about it anyways.
+	scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(addrScript, chainParams)
+
+	encodedAddrs := make([]string, len(addrs))
+	for j, addr := range addrs {
+		encodedAddrs[j] = addr.EncodeAddress()
+	}
+
+	var result btcjson.ScriptPubKeyResult
+	result.Addresses = encodedAddrs
+	result.Asm = disbuf
+	result.Hex = hex.EncodeToString(pkScript)
+	result.ReqSigs = int32(reqSigs)
+
+	if len(script) < len(pkScript) {
+		result.IsClaim = pkScript[0] == txscript.OP_CLAIMNAME || pkScript[0] == txscript.OP_UPDATECLAIM
+		result.IsSupport = pkScript[0] == txscript.OP_SUPPORTCLAIM
+		result.SubType = scriptClass.String()
+		result.Type = txscript.ScriptClass.String(0)
+	} else {
+		result.Type = scriptClass.String()
+	}
+
+	return result
+}
+
 // createVoutList returns a slice of JSON objects for the outputs of the passed
 // transaction.
 func createVoutList(mtx *wire.MsgTx, chainParams *chaincfg.Params, filterAddrMap map[string]struct{}) []btcjson.Vout {
+	var txHash *chainhash.Hash
 	voutList := make([]btcjson.Vout, 0, len(mtx.TxOut))
 	for i, v := range mtx.TxOut {
-		// The disassembled string will contain [error] inline if the
-		// script doesn't fully parse, so ignore the error here.
-		disbuf, _ := txscript.DisasmString(v.PkScript)
-
-		script := txscript.StripClaimScriptPrefix(v.PkScript)
-
-		// Ignore the error here since an error means the script
-		// couldn't parse and there is no additional information about
-		// it anyways.
-		scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(script, chainParams)
+		scriptPubKey := createScriptPubKeyResult(v.PkScript, chainParams)
 
-		// Encode the addresses while checking if the address passes the
-		// filter when needed.
+		// Check if one of the addresses passes the filter when needed.
 		passesFilter := len(filterAddrMap) == 0
-		encodedAddrs := make([]string, len(addrs))
-		for j, addr := range addrs {
-			encodedAddr := addr.EncodeAddress()
-			encodedAddrs[j] = encodedAddr
-
-			// No need to check the map again if the filter already
-			// passes.
+		for _, encodedAddr := range scriptPubKey.Addresses {
 			if passesFilter {
-				continue
+				break
 			}
 			if _, exists := filterAddrMap[encodedAddr]; exists {
 				passesFilter = true
@@ -799,22 +1062,19 @@ func createVoutList(mtx *wire.MsgTx, chainParams *chaincfg.Params, filterAddrMap
 		var vout btcjson.Vout
 		vout.N = uint32(i)
 		vout.Value = btcutil.Amount(v.Value).ToBTC()
-		vout.ScriptPubKey.Addresses = encodedAddrs
-		vout.ScriptPubKey.Asm = disbuf
-		vout.ScriptPubKey.Hex = hex.EncodeToString(v.PkScript)
-		vout.ScriptPubKey.ReqSigs = int32(reqSigs)
-
-		if len(script) < len(v.PkScript) {
-			vout.ScriptPubKey.IsClaim = v.PkScript[0] == txscript.OP_CLAIMNAME || v.PkScript[0] == txscript.OP_UPDATECLAIM
-			vout.ScriptPubKey.IsSupport = v.PkScript[0] == txscript.OP_SUPPORTCLAIM
-			vout.ScriptPubKey.SubType = scriptClass.String()
-			vout.ScriptPubKey.Type = txscript.ScriptClass.String(0)
-		} else {
-			vout.ScriptPubKey.Type = scriptClass.String()
+		vout.ScriptPubKey = scriptPubKey
+
+		// The claim ID of a new claim is derived from its outpoint, so
+		// the transaction hash is only computed when needed.
+		if scriptPubKey.IsClaim || scriptPubKey.IsSupport {
+			if txHash == nil {
+				hash := mtx.TxHash()
+				txHash = &hash
+			}
+			vout.Claim = createVoutClaim(wire.NewOutPoint(txHash,
+				uint32(i)), v.PkScript)
 		}
 
-		// TODO here: isclaim, issupport, subtype,
-
 		voutList = append(voutList, vout)
 	}
 
@@ -856,6 +1116,106 @@ func createTxRawResult(chainParams *chaincfg.Params, mtx *wire.MsgTx,
 	return txReply, nil
 }
 
+// createVinPrevOutResult returns a JSON object for an output spent by an
+// input.  The height is only set when known.
+func createVinPrevOutResult(amount int64, pkScript []byte, height int32,
+	isCoinBase bool, chainParams *chaincfg.Params) *btcjson.VinPrevOutResult {
+
+	return &btcjson.VinPrevOutResult{
+		Generated:    isCoinBase,
+		Height:       height,
+		Value:        btcutil.Amount(amount).ToBTC(),
+		ScriptPubKey: createScriptPubKeyResult(pkScript, chainParams),
+	}
+}
+
+// setBlockVinPrevOuts sets the outputs spent by the inputs of the passed
+// transactions of a block from the spend journal of the block.
+func setBlockVinPrevOuts(rawTxns []btcjson.TxRawResult,
+	stxos []blockchain.SpentTxOut, chainParams *chaincfg.Params) error {
+
+	// The spend journal holds the spent outputs in the order of the inputs
+	// of all of the transactions of the block except the coinbase.
+	stxoIdx := 0
+	for i := 1; i < len(rawTxns); i++ {
+		vinList := rawTxns[i].Vin
+		for j := range vinList {
+			if stxoIdx >= len(stxos) {
+				return fmt.Errorf("spend journal of block has %d "+
+					"entries for more inputs", len(stxos))
+			}
+			stxo := &stxos[stxoIdx]
+			vinList[j].PrevOut = createVinPrevOutResult(stxo.Amount,
+				stxo.PkScript, stxo.Height, stxo.IsCoinBase,
+				chainParams)
+			stxoIdx++
+		}
+	}
+	if stxoIdx != len(stxos) {
+		return fmt.Errorf("spend journal of block has %d entries for %d "+
+			"inputs", len(stxos), stxoIdx)
+	}
+
+	return nil
+}
+
+// fetchVinPrevOut returns the output spent by the passed outpoint by checking
+// the memory pool, the utxo set and then the transaction index.  It returns nil
+// when the output isn't available.
+func fetchVinPrevOut(s *rpcServer, outpoint *wire.OutPoint) *btcjson.VinPrevOutResult {
+	params := s.cfg.ChainParams
+
+	// The outputs of unconfirmed transactions are only in the memory pool.
+	originTx, err := s.cfg.TxMemPool.FetchTransaction(&outpoint.Hash)
+	if err == nil {
+		txOuts := originTx.MsgTx().TxOut
+		if outpoint.Index >= uint32(len(txOuts)) {
+			return nil
+		}
+		txOut := txOuts[outpoint.Index]
+		return createVinPrevOutResult(txOut.Value, txOut.PkScript, 0,
+			false, params)
+	}
+
+	// The outputs spent by unconfirmed transactions are still unspent.
+	entry, err := s.cfg.Chain.FetchUtxoEntry(*outpoint)
+	if err == nil && entry != nil && !entry.IsSpent() {
+		return createVinPrevOutResult(entry.Amount(), entry.PkScript(),
+			entry.BlockHeight(), entry.IsCoinBase(), params)
+	}
+
+	// Otherwise, the output was spent in a block, so look up the
+	// transaction which created it when the transaction index is enabled.
+	if s.cfg.TxIndex == nil {
+		return nil
+	}
+	blockRegion, err := s.cfg.TxIndex.TxBlockRegion(&outpoint.Hash)
+	if err != nil || blockRegion == nil {
+		return nil
+	}
+	var txBytes []byte
+	err = s.cfg.DB.View(func(dbTx database.Tx) error {
+		var err error
+		txBytes, err = dbTx.FetchBlockRegion(blockRegion)
+		return err
+	})
+	if err != nil {
+		return nil
+	}
+	var msgTx wire.MsgTx
+	err = msgTx.Deserialize(bytes.NewReader(txBytes))
+	if err != nil || outpoint.Index >= uint32(len(msgTx.TxOut)) {
+		return nil
+	}
+	height, err := s.cfg.Chain.BlockHeightByHash(blockRegion.Hash)
+	if err != nil {
+		height = 0
+	}
+	txOut := msgTx.TxOut[outpoint.Index]
+	return createVinPrevOutResult(txOut.Value, txOut.PkScript, height,
+		blockchain.IsCoinBaseTx(&msgTx), params)
+}
+
 // handleDecodeRawTransaction handles decoderawtransaction commands.
 func handleDecodeRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.DecodeRawTransactionCmd)
@@ -937,6 +1297,55 @@ func handleDecodeScript(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	return reply, nil
 }
 
+// handleDumpTxOutSet implements the dumptxoutset command.
+func handleDumpTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DumpTxOutSetCmd)
+
+	path := snapshotPath(c.Path)
+	if _, err := os.Stat(path); err == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Snapshot file already exists: " + path,
+		}
+	}
+
+	// Write to a temporary file first so an interrupted dump doesn't leave
+	// a partial snapshot behind.
+	tempPath := path + ".incomplete"
+	f, err := os.Create(tempPath)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to create snapshot file: " + err.Error(),
+		}
+	}
+	info, err := s.cfg.Chain.DumpUtxoSnapshot(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to dump the utxo set: " + err.Error(),
+		}
+	}
+
+	return &btcjson.DumpTxOutSetResult{
+		CoinsWritten: info.TxOuts,
+		BaseHash:     info.Hash.String(),
+		BaseHeight:   info.Height,
+		Path:         path,
+		MuHash:       info.MuHash.String(),
+	}, nil
+}
+
 // handleEstimateFee handles estimatefee commands.
 func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.EstimateFeeCmd)
@@ -970,44 +1379,61 @@ func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 
 // handleEstimateSmartFee implements the estimatesmartfee command.
 //
-// The default estimation mode when unset is assumed as "conservative". As of
-// 2018-12, the only supported mode is "conservative".
+// The default estimation mode when unset is assumed as "conservative".
 func handleEstimateSmartFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.EstimateSmartFeeCmd)
 
-	mode := btcjson.EstimateModeConservative
-	if c.EstimateMode != nil {
-		mode = *c.EstimateMode
+	if s.cfg.FeeEstimator == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInternal.Code,
+			Message: "Fee estimation disabled",
+		}
 	}
 
-	if mode != btcjson.EstimateModeConservative {
+	if c.ConfTarget <= 0 {
 		return nil, &btcjson.RPCError{
-			Code: btcjson.ErrRPCInvalidParameter,
-			Message: "Only the default and conservative modes " +
-				"are supported for smart fee estimation at the moment",
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Parameter ConfTarget must be positive",
+		}
+	}
+
+	mode := fees.EstimateConservative
+	if c.EstimateMode != nil {
+		switch *c.EstimateMode {
+		case btcjson.EstimateModeUnset, btcjson.EstimateModeConservative:
+		case btcjson.EstimateModeEconomical:
+			mode = fees.EstimateEconomical
+		default:
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid estimate mode parameter",
+			}
 		}
 	}
 
-	fee, err := s.cfg.FeeEstimator.EstimateFee(int32(c.ConfTarget))
+	fee, blocks, err := s.cfg.FeeEstimator.EstimateSmartFee(int32(c.ConfTarget),
+		mode)
 	if err != nil {
-		return nil, internalRPCError(err.Error(), "Could not estimate fee")
+		return &btcjson.EstimateSmartFeeResult{
+			Errors: []string{"Insufficient data or no feerate found"},
+		}, nil
 	}
 
-	feeRate := float64(fee) / btcutil.SatoshiPerBitcoin
+	feeRate := fee.ToBTC()
 	return &btcjson.EstimateSmartFeeResult{
 		FeeRate: &feeRate,
-		Blocks:  c.ConfTarget,
+		Blocks:  int64(blocks),
 	}, nil
 }
 
 func handleGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if there are no addresses to pay the
 	// created blocks to.
-	if len(cfg.miningAddrs) == 0 {
+	if len(cfg.miningAddrs) == 0 && len(s.cfg.CPUMiner.Payouts()) == 0 {
 		return nil, &btcjson.RPCError{
 			Code: btcjson.ErrRPCInternal.Code,
 			Message: "No payment addresses specified " +
-				"via --miningaddr",
+				"via --miningaddr or --miningpayout",
 		}
 	}
 
@@ -1211,6 +1637,236 @@ func handleGetBestBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	return result, nil
 }
 
+// addrIndexRequiredError returns the error reported by commands which require
+// the address index when it is not enabled.
+func addrIndexRequiredError() *btcjson.RPCError {
+	return &btcjson.RPCError{
+		Code:    btcjson.ErrRPCMisc,
+		Message: "Address index must be enabled (--addrindex)",
+	}
+}
+
+// decodeIndexedAddress decodes the passed address for use with the address
+// index.
+func decodeIndexedAddress(s *rpcServer, address string) (btcutil.Address, error) {
+	addr, err := btcutil.DecodeAddress(address, s.cfg.ChainParams)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid address or key: " + err.Error(),
+		}
+	}
+	return addr, nil
+}
+
+const (
+	// addressBalanceBatchSize is the number of address index entries
+	// loaded at a time when computing the balance of an address.
+	addressBalanceBatchSize = 1000
+
+	// addressBalanceMaxTxns is the maximum number of transactions scanned
+	// when computing the balance of an address.
+	addressBalanceMaxTxns = 100000
+)
+
+// addressBalance tallies the amounts of the outputs paying to an address.
+type addressBalance struct {
+	received btcutil.Amount
+	balance  btcutil.Amount
+	claims   btcutil.Amount
+	supports btcutil.Amount
+}
+
+// addTx adds the outputs of the passed transaction paying to the encoded
+// address to the tallies.  The fetchUtxo function returns the utxo entry of an
+// output, which is nil when the output is not in the utxo set.
+func (b *addressBalance) addTx(mtx *wire.MsgTx, encodedAddr string,
+	params *chaincfg.Params,
+	fetchUtxo func(wire.OutPoint) (*blockchain.UtxoEntry, error)) error {
+
+	txHash := mtx.TxHash()
+	for i, txOut := range mtx.TxOut {
+		// Claim scripts are stripped down to the script they pay to when
+		// extracting the addresses.
+		_, addrs, _, _ := txscript.ExtractPkScriptAddrs(txOut.PkScript,
+			params)
+		if !containsAddress(addrs, encodedAddr) {
+			continue
+		}
+		value := btcutil.Amount(txOut.Value)
+		b.received += value
+
+		entry, err := fetchUtxo(wire.OutPoint{Hash: txHash, Index: uint32(i)})
+		if err != nil {
+			return err
+		}
+		if entry == nil || entry.IsSpent() {
+			continue
+		}
+		b.balance += value
+
+		cs, err := txscript.ExtractClaimScript(txOut.PkScript)
+		if err != nil {
+			continue
+		}
+		if cs.Opcode == txscript.OP_SUPPORTCLAIM {
+			b.supports += value
+		} else {
+			b.claims += value
+		}
+	}
+	return nil
+}
+
+// handleGetAddressBalance implements the getaddressbalance command.
+func handleGetAddressBalance(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	addrIndex := s.cfg.AddrIndex
+	if addrIndex == nil {
+		return nil, addrIndexRequiredError()
+	}
+
+	c := cmd.(*btcjson.GetAddressBalanceCmd)
+	addr, err := decodeIndexedAddress(s, c.Address)
+	if err != nil {
+		return nil, err
+	}
+	encodedAddr := addr.EncodeAddress()
+
+	// Walk every confirmed transaction involving the address in batches
+	// and tally the outputs paying to it.  Outputs which are still in the
+	// utxo set count towards the balance.  The database transaction is
+	// closed before querying the utxo set to avoid nesting them.  The scan
+	// is capped to keep a heavily used address from tying up the server.
+	var b addressBalance
+	var numToSkip uint32
+	for {
+		select {
+		case <-closeChan:
+			return nil, ErrClientQuit
+		default:
+		}
+		if numToSkip >= addressBalanceMaxTxns {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCMisc,
+				Message: fmt.Sprintf("Address has more than %d "+
+					"transactions", addressBalanceMaxTxns),
+			}
+		}
+
+		var serializedTxns [][]byte
+		err := s.cfg.DB.View(func(dbTx database.Tx) error {
+			regions, _, err := addrIndex.TxRegionsForAddress(dbTx,
+				addr, numToSkip, addressBalanceBatchSize, false)
+			if err != nil {
+				return err
+			}
+			serializedTxns, err = dbTx.FetchBlockRegions(regions)
+			return err
+		})
+		if err != nil {
+			context := "Failed to load address index entries"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		for _, serializedTx := range serializedTxns {
+			var mtx wire.MsgTx
+			err := mtx.Deserialize(bytes.NewReader(serializedTx))
+			if err != nil {
+				context := "Failed to deserialize transaction"
+				return nil, internalRPCError(err.Error(), context)
+			}
+			err = b.addTx(&mtx, encodedAddr, s.cfg.ChainParams,
+				s.cfg.Chain.FetchUtxoEntry)
+			if err != nil {
+				context := "Failed to retrieve utxo entry"
+				return nil, internalRPCError(err.Error(), context)
+			}
+		}
+
+		if len(serializedTxns) < addressBalanceBatchSize {
+			break
+		}
+		numToSkip += uint32(len(serializedTxns))
+	}
+
+	return &btcjson.GetAddressBalanceResult{
+		Balance:  b.balance.ToBTC(),
+		Received: b.received.ToBTC(),
+		Claims:   b.claims.ToBTC(),
+		Supports: b.supports.ToBTC(),
+	}, nil
+}
+
+// containsAddress returns whether any of the passed addresses encodes to the
+// provided address string.
+func containsAddress(addrs []btcutil.Address, encodedAddr string) bool {
+	for _, addr := range addrs {
+		if addr.EncodeAddress() == encodedAddr {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGetAddressTxIDs implements the getaddresstxids command.
+func handleGetAddressTxIDs(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if s.cfg.AddrIndex == nil {
+		return nil, addrIndexRequiredError()
+	}
+
+	c := cmd.(*btcjson.GetAddressTxIDsCmd)
+	addr, err := decodeIndexedAddress(s, c.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	numRequested := 100
+	if c.Count != nil {
+		numRequested = *c.Count
+		if numRequested < 0 {
+			numRequested = 1
+		}
+	}
+	if numRequested == 0 {
+		return []string{}, nil
+	}
+
+	var numToSkip int
+	if c.Skip != nil && *c.Skip > 0 {
+		numToSkip = *c.Skip
+	}
+
+	var reverse bool
+	if c.Reverse != nil {
+		reverse = *c.Reverse
+	}
+
+	addressTxns, err := fetchTxnsForAddress(s, addr, numToSkip, numRequested,
+		reverse)
+	if err != nil {
+		return nil, err
+	}
+
+	txids := make([]string, 0, len(addressTxns))
+	for i := range addressTxns {
+		rtx := &addressTxns[i]
+		if rtx.tx != nil {
+			txids = append(txids, rtx.tx.Hash().String())
+			continue
+		}
+
+		var mtx wire.MsgTx
+		err := mtx.Deserialize(bytes.NewReader(rtx.txBytes))
+		if err != nil {
+			context := "Failed to deserialize transaction"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		txids = append(txids, mtx.TxHash().String())
+	}
+
+	return txids, nil
+}
+
 // handleGetBestBlockHash implements the getbestblockhash command.
 func handleGetBestBlockHash(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	best := s.cfg.Chain.BestSnapshot()
@@ -1253,6 +1909,9 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 		return err
 	})
 	if err != nil {
+		if s.cfg.Chain.IsBlockPruned(hash) {
+			return nil, rpcBlockPrunedError(hash)
+		}
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCBlockNotFound,
 			Message: "Block not found: " + err.Error(),
@@ -1338,6 +1997,22 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 		}
 		rawTxns[i] = *rawTxn
 	}
+
+	// At verbosity level 3, include the outputs spent by the inputs from
+	// the spend journal, which only exists for the blocks of the main
+	// chain.
+	if *c.Verbosity >= 3 && s.cfg.Chain.MainChainHasBlock(hash) {
+		stxos, err := s.cfg.Chain.FetchSpendJournal(blk)
+		if err != nil {
+			context := "Failed to fetch spend journal"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		err = setBlockVinPrevOuts(rawTxns, stxos, params)
+		if err != nil {
+			context := "Failed to set previous outputs"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	}
 	base.TxCount = len(rawTxns)
 	blockReply := btcjson.GetBlockVerboseTxResult{
 		GetBlockVerboseResultBase: base,
@@ -1381,10 +2056,23 @@ func handleGetBlockChainInfo(s *rpcServer, cmd interface{}, closeChan <-chan str
 		BestBlockHash: chainSnapshot.Hash.String(),
 		Difficulty:    getDifficultyRatio(chainSnapshot.Bits, params),
 		MedianTime:    chainSnapshot.MedianTime.Unix(),
-		Pruned:        false,
+		Pruned:        chain.IsPruned(),
 		SoftForks: &btcjson.SoftForks{
 			Bip9SoftForks: make(map[string]*btcjson.Bip9SoftForkDescription),
 		},
+		VerificationProgress: chain.VerificationProgress(),
+	}
+
+	// Estimate the time left to sync during the initial block download.
+	if !s.cfg.SyncMgr.IsCurrent() {
+		chainInfo.InitialBlockDownload = true
+		remaining := s.cfg.SyncMgr.TimeRemaining()
+		chainInfo.EstimatedTimeRemaining = int64(remaining / time.Second)
+	}
+
+	// Report the lowest block with data available when pruning.
+	if chainInfo.Pruned {
+		chainInfo.PruneHeight = chain.PruneHeight()
 	}
 
 	// Next, populate the response with information describing the current
@@ -1488,6 +2176,60 @@ func handleGetBlockCount(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	return int64(best.Height), nil
 }
 
+// handleGetBlockFromPeer implements the getblockfrompeer command.
+func handleGetBlockFromPeer(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockFromPeerCmd)
+
+	hash, err := chainhash.NewHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+	if _, err := s.cfg.Chain.HeaderByHash(hash); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block header missing: " + c.BlockHash,
+		}
+	}
+	var haveBlock bool
+	err = s.cfg.DB.View(func(dbTx database.Tx) error {
+		var err error
+		haveBlock, err = dbTx.HasBlock(hash)
+		return err
+	})
+	if err != nil {
+		context := "Failed to check for block data"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if haveBlock {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Block already downloaded: " + c.BlockHash,
+		}
+	}
+
+	var fetchPeer *peer.Peer
+	for _, p := range s.cfg.ConnMgr.ConnectedPeers() {
+		if p.ToPeer().ID() == c.PeerID {
+			fetchPeer = p.ToPeer()
+			break
+		}
+	}
+	if fetchPeer == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Peer %d does not exist", c.PeerID),
+		}
+	}
+
+	if err := s.cfg.SyncMgr.FetchBlock(hash, fetchPeer); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Failed to fetch block: " + err.Error(),
+		}
+	}
+	return nil, nil
+}
+
 // handleGetBlockHash implements the getblockhash command.
 func handleGetBlockHash(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetBlockHashCmd)
@@ -1552,32 +2294,168 @@ func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct
 		nextHashString = nextHash.String()
 	}
 
+	return blockHeaderVerboseResult(s, c.Hash, &blockHeader, blockHeight,
+		best.Height, nextHashString), nil
+}
+
+// blockHeaderVerboseResult returns the verbose result of the getblockheader
+// command for the passed header of the main chain.
+func blockHeaderVerboseResult(s *rpcServer, hash string, blockHeader *wire.BlockHeader,
+	blockHeight, bestHeight int32, nextHash string) btcjson.GetBlockHeaderVerboseResult {
+
 	params := s.cfg.ChainParams
-	blockHeaderReply := btcjson.GetBlockHeaderVerboseResult{
-		Hash:          c.Hash,
-		Confirmations: int64(1 + best.Height - blockHeight),
+	return btcjson.GetBlockHeaderVerboseResult{
+		Hash:          hash,
+		Confirmations: int64(1 + bestHeight - blockHeight),
 		Height:        blockHeight,
 		Version:       blockHeader.Version,
 		VersionHex:    fmt.Sprintf("%08x", blockHeader.Version),
 		MerkleRoot:    blockHeader.MerkleRoot.String(),
-		NextHash:      nextHashString,
+		NextHash:      nextHash,
 		PreviousHash:  blockHeader.PrevBlock.String(),
 		Nonce:         uint64(blockHeader.Nonce),
 		Time:          blockHeader.Timestamp.Unix(),
 		Bits:          strconv.FormatInt(int64(blockHeader.Bits), 16),
 		Difficulty:    getDifficultyRatio(blockHeader.Bits, params),
 	}
-	return blockHeaderReply, nil
+}
+
+// fetchBlockHeaders returns the hashes and headers of up to count consecutive
+// blocks of the main chain, starting with the block of the passed hash or
+// height, along with the height of the first block.
+func fetchBlockHeaders(s *rpcServer, hashOrHeight interface{}, count int32) ([]chainhash.Hash, []wire.BlockHeader, int32, error) {
+	if count < 1 || count > wire.MaxBlockHeadersPerMsg {
+		return nil, nil, 0, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Count must be between 1 and %d",
+				wire.MaxBlockHeadersPerMsg),
+		}
+	}
+
+	// Determine the height of the first block.
+	var startHeight int32
+	switch v := hashOrHeight.(type) {
+	case int:
+		startHeight = int32(v)
+		if _, err := s.cfg.Chain.BlockHashByHeight(startHeight); err != nil {
+			return nil, nil, 0, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCOutOfRange,
+				Message: "Block number out of range",
+			}
+		}
+	case string:
+		hash, err := chainhash.NewHashFromStr(v)
+		if err != nil {
+			return nil, nil, 0, rpcDecodeHexError(v)
+		}
+		startHeight, err = s.cfg.Chain.BlockHeightByHash(hash)
+		if err != nil {
+			return nil, nil, 0, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found in the main chain",
+			}
+		}
+	default:
+		return nil, nil, 0, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Expected a block hash or height",
+		}
+	}
+
+	hashes, err := s.cfg.Chain.HeightRange(startHeight, startHeight+count)
+	if err != nil {
+		context := "Failed to fetch block hashes"
+		return nil, nil, 0, internalRPCError(err.Error(), context)
+	}
+	headers := make([]wire.BlockHeader, 0, len(hashes))
+	for i := range hashes {
+		header, err := s.cfg.Chain.HeaderByHash(&hashes[i])
+		if err != nil {
+			context := "Failed to fetch block header"
+			return nil, nil, 0, internalRPCError(err.Error(), context)
+		}
+		headers = append(headers, header)
+	}
+	return hashes, headers, startHeight, nil
+}
+
+// handleGetBlockHeaders implements the getblockheaders command.
+func handleGetBlockHeaders(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockHeadersCmd)
+
+	count := int32(wire.MaxBlockHeadersPerMsg)
+	if c.Count != nil {
+		count = *c.Count
+	}
+	hashes, headers, startHeight, err := fetchBlockHeaders(s,
+		c.HashOrHeight.Value, count)
+	if err != nil {
+		return nil, err
+	}
+
+	// When the verbose flag isn't set, simply return the concatenated
+	// serialized block headers as a hex-encoded string.
+	if c.Verbose == nil || !*c.Verbose {
+		var headersBuf bytes.Buffer
+		for i := range headers {
+			if err := headers[i].Serialize(&headersBuf); err != nil {
+				context := "Failed to serialize block header"
+				return nil, internalRPCError(err.Error(), context)
+			}
+		}
+		return hex.EncodeToString(headersBuf.Bytes()), nil
+	}
+
+	// The verbose flag is set, so generate the JSON objects and return
+	// them.
+	return blockHeadersVerboseResults(s, hashes, headers, startHeight), nil
+}
+
+// blockHeadersVerboseResults returns the verbose results of consecutive block
+// headers of the main chain starting at the passed height.  The next block of
+// the last header is only known when it is still in the main chain.
+func blockHeadersVerboseResults(s *rpcServer, hashes []chainhash.Hash, headers []wire.BlockHeader, startHeight int32) []btcjson.GetBlockHeaderVerboseResult {
+	best := s.cfg.Chain.BestSnapshot()
+	results := make([]btcjson.GetBlockHeaderVerboseResult, 0, len(headers))
+	for i := range headers {
+		height := startHeight + int32(i)
+		var nextHashString string
+		if i+1 < len(hashes) {
+			nextHashString = hashes[i+1].String()
+		} else if height < best.Height {
+			nextHash, err := s.cfg.Chain.BlockHashByHeight(height + 1)
+			if err == nil {
+				nextHashString = nextHash.String()
+			}
+		}
+		results = append(results, blockHeaderVerboseResult(s,
+			hashes[i].String(), &headers[i], height, best.Height,
+			nextHashString))
+	}
+	return results
 }
 
 // handleGetChainTips implements the getchaintips command.
 func handleGetChainTips(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetChainTipsCmd)
 	tips := s.cfg.Chain.ChainTips()
 	results := make([]btcjson.GetChainTipsResult, 0, len(tips))
 	for _, tip := range tips {
 		results = append(results, btcjson.GetChainTipsResult(tip))
 	}
-	return results, nil
+	if c.Verbose == nil || !*c.Verbose {
+		return results, nil
+	}
+
+	stats := s.cfg.Chain.ForkPruneStats()
+	return &btcjson.GetChainTipsVerboseResult{
+		Tips:            results,
+		IndexNodes:      int64(stats.IndexNodes),
+		StaleNodes:      int64(stats.StaleNodes),
+		PruneForkDepth:  stats.Depth,
+		PrunedNodes:     stats.PrunedNodes,
+		LastPruneHeight: stats.LastPruneHeight,
+	}, nil
 }
 
 // handleGetBlockStats implements the getblockstats command.
@@ -1622,6 +2500,9 @@ func handleGetBlockStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 		return err
 	})
 	if err != nil {
+		if s.cfg.Chain.IsBlockPruned(hash) {
+			return nil, rpcBlockPrunedError(hash)
+		}
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCBlockNotFound,
 			Message: "Block not found",
@@ -1651,33 +2532,126 @@ func handleGetBlockStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	calcFees := statsSet["avgfee"] || statsSet["avgfeerate"] || statsSet["maxfee"] || statsSet["maxfeerate"] ||
 		statsSet["medianfee"] || statsSet["totalfee"] || statsSet["feerate_percentiles"]
 
-	if calcFees && s.cfg.TxIndex == nil {
+	calcUndo := statsSet["utxo_size_inc"] || statsSet["abandons"]
+
+	// The outputs spent by blocks in the main chain are loaded from their
+	// spend journal, which provides the fees of their transactions without
+	// requiring the transaction index.
+	var stxos []blockchain.SpentTxOut
+	haveUndo := false
+	if s.cfg.Chain.MainChainHasBlock(hash) {
+		stxos, err = s.cfg.Chain.FetchSpendJournal(blk)
+		haveUndo = err == nil
+	}
+	haveFees := haveUndo || s.cfg.TxIndex != nil
+
+	if calcFees && !haveFees {
 		return nil, &btcjson.RPCError{
 			Code: btcjson.ErrRPCNoTxInfo,
 			Message: "The transaction index must be " +
 				"enabled to obtain fee statistics " +
+				"of blocks not in the main chain " +
 				"(specify --txindex)",
 		}
 	}
+	if calcUndo && !haveUndo {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Undo data is not available for this block",
+		}
+	}
 
 	txs := blk.Transactions()
 	txCount := len(txs)
 	var inputCount, outputCount int
-	var totalOutputValue int64
+	var totalOutputValue, utxoSizeInc int64
+	var claimCount, updateCount, supportCount, abandonCount int64
+	claimNames := make(map[string]struct{})
+	stxoIdx := 0
 
 	// Create a map of transaction statistics.
 	txStats := make([]map[string]interface{}, txCount)
 	for i, tx := range txs {
-		size := tx.MsgTx().SerializeSize()
-		witnessSize := size - tx.MsgTx().SerializeSizeStripped()
-		weight := int64(tx.MsgTx().SerializeSizeStripped()*4 + witnessSize)
+		msgTx := tx.MsgTx()
+		size := msgTx.SerializeSize()
+		witnessSize := size - msgTx.SerializeSizeStripped()
+		weight := int64(msgTx.SerializeSizeStripped()*4 + witnessSize)
+		isCoinBase := blockchain.IsCoinBaseTx(msgTx)
+
+		// Count the claims, updates and supports created by the
+		// transaction, and the size of the outputs added to the utxo
+		// set.
+		var outValue int64
+		updated := make(map[change.ClaimID]struct{})
+		for _, txOut := range msgTx.TxOut {
+			outValue += txOut.Value
+			if !txscript.IsUnspendable(txOut.PkScript) {
+				utxoSizeInc += utxoEntrySize(txOut.PkScript)
+			}
+
+			cs, err := txscript.ExtractClaimScript(txOut.PkScript)
+			if err != nil {
+				continue
+			}
+			switch cs.Opcode {
+			case txscript.OP_CLAIMNAME:
+				claimCount++
+				claimNames[string(cs.Name)] = struct{}{}
+			case txscript.OP_UPDATECLAIM:
+				updateCount++
+				var id change.ClaimID
+				copy(id[:], cs.ClaimID)
+				updated[id] = struct{}{}
+			case txscript.OP_SUPPORTCLAIM:
+				supportCount++
+			}
+		}
+
+		// Spent claims which aren't updated by the same transaction are
+		// abandoned, as are spent supports.
+		var inValue int64
+		if !isCoinBase && haveUndo {
+			for _, txIn := range msgTx.TxIn {
+				if stxoIdx >= len(stxos) {
+					context := "Failed to load spent outputs"
+					return nil, internalRPCError("spend "+
+						"journal is too short", context)
+				}
+				stxo := &stxos[stxoIdx]
+				stxoIdx++
+				inValue += stxo.Amount
+				utxoSizeInc -= utxoEntrySize(stxo.PkScript)
+
+				cs, err := txscript.ExtractClaimScript(stxo.PkScript)
+				if err != nil {
+					continue
+				}
+				var id change.ClaimID
+				switch cs.Opcode {
+				case txscript.OP_CLAIMNAME:
+					id = change.NewClaimID(txIn.PreviousOutPoint)
+				case txscript.OP_UPDATECLAIM:
+					copy(id[:], cs.ClaimID)
+				default:
+					abandonCount++
+					continue
+				}
+				if _, ok := updated[id]; !ok {
+					abandonCount++
+				}
+			}
+		}
 
 		var fee, feeRate int64
-		if (calcFees || allStats) && s.cfg.TxIndex != nil && !blockchain.IsCoinBaseTx(tx.MsgTx()) {
-			fee, err = calculateFee(tx, s.cfg.TxIndex, s.cfg.DB)
-			if err != nil {
-				context := "Failed to calculate fees"
-				return nil, internalRPCError(err.Error(), context)
+		if (calcFees || allStats) && haveFees && !isCoinBase {
+			if haveUndo {
+				fee = inValue - outValue
+			} else {
+				fee, err = calculateFee(tx, s.cfg.TxIndex, s.cfg.DB)
+				if err != nil {
+					context := "Failed to calculate fees"
+					return nil, internalRPCError(err.Error(), context)
+				}
 			}
 			if weight != 0 {
 				feeRate = fee * 4 / weight
@@ -1823,6 +2797,16 @@ func handleGetBlockStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 		blockHash = blk.Hash().String()
 	}
 
+	var newClaimNames *[]string
+	if allStats || statsSet["claimnames"] {
+		names := make([]string, 0, len(claimNames))
+		for name := range claimNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		newClaimNames = &names
+	}
+
 	medianTime, err := medianBlockTime(blk.Hash(), s.cfg.Chain)
 	if err != nil {
 		context := "Failed to obtain block median time"
@@ -1856,13 +2840,18 @@ func handleGetBlockStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 		"totalfee":       totalFees,
 		"txs":            int64(len(txs)),
 		"utxo_increase":  int64(outputCount - (inputCount - 1)),
+		"utxo_size_inc":  utxoSizeInc,
+		"claims":         claimCount,
+		"claimupdates":   updateCount,
+		"supports":       supportCount,
+		"abandons":       abandonCount,
 	}
 
 	// This function determines whether a statistic goes into the
 	// final result, except for blockhash and feerate_percentiles
 	// which are handled separately.
 	resultFilter := func(stat string) *int64 {
-		if allStats && s.cfg.TxIndex == nil {
+		if allStats && !haveFees {
 			// There are no fee statistics to send.
 			excludedStats := []string{"avgfee", "avgfeerate", "maxfee", "maxfeerate", "medianfee", "minfee", "minfeerate"}
 			for _, excluded := range excludedStats {
@@ -1871,6 +2860,12 @@ func handleGetBlockStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 				}
 			}
 		}
+		if allStats && !haveUndo {
+			// There are no statistics of spent outputs to send.
+			if stat == "utxo_size_inc" || stat == "abandons" {
+				return nil
+			}
+		}
 		if allStats || statsSet[stat] {
 			if value, ok := resultMap[stat]; ok {
 				return &value
@@ -1909,10 +2904,24 @@ func handleGetBlockStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 		Txs:                resultFilter("txs"),
 		UTXOIncrease:       resultFilter("utxo_increase"),
 		UTXOSizeIncrease:   resultFilter("utxo_size_inc"),
+		Claims:             resultFilter("claims"),
+		ClaimUpdates:       resultFilter("claimupdates"),
+		Supports:           resultFilter("supports"),
+		Abandons:           resultFilter("abandons"),
+		ClaimNames:         newClaimNames,
 	}
 	return result, nil
 }
 
+// utxoEntrySize returns the size an output with the passed public key script
+// adds to the utxo set, which includes the serialized output and the overhead
+// of its outpoint, height and coinbase flag.
+func utxoEntrySize(pkScript []byte) int64 {
+	const perUtxoOverhead = 41
+	return int64(8+wire.VarIntSerializeSize(uint64(len(pkScript)))+
+		len(pkScript)) + perUtxoOverhead
+}
+
 // calculateFee returns the fee of a transaction.
 func calculateFee(tx *btcutil.Tx, txIndex *indexers.TxIndex, db database.DB) (int64, error) {
 	var inValue, outValue int64
@@ -1982,14 +2991,15 @@ func medianBlockTime(blockHash *chainhash.Hash, chain *blockchain.BlockChain) (*
 // encodeTemplateID encodes the passed details into an ID that can be used to
 // uniquely identify a block template.
 func encodeTemplateID(prevHash *chainhash.Hash, lastGenerated time.Time) string {
-	return fmt.Sprintf("%s-%d", prevHash.String(), lastGenerated.Unix())
+	return fmt.Sprintf("%s-%d", prevHash.String(), lastGenerated.UnixNano())
 }
 
 // decodeTemplateID decodes an ID that is used to uniquely identify a block
 // template.  This is mainly used as a mechanism to track when to update clients
 // that are using long polling for block templates.  The ID consists of the
-// previous block hash for the associated template and the time the associated
-// template was generated.
+// previous block hash for the associated template and the time in nanoseconds
+// the associated template was generated, so templates regenerated within the
+// same second have distinct IDs.
 func decodeTemplateID(templateID string) (*chainhash.Hash, int64, error) {
 	fields := strings.Split(templateID, "-")
 	if len(fields) != 2 {
@@ -2041,9 +3051,9 @@ func (state *gbtWorkState) notifyLongPollers(latestHash *chainhash.Hash, lastGen
 	// Notify anything that is waiting for a block template update from a
 	// block template generated before the most recently generated block
 	// template.
-	lastGeneratedUnix := lastGenerated.Unix()
+	lastGeneratedNano := lastGenerated.UnixNano()
 	for lastGen, c := range channels {
-		if lastGen < lastGeneratedUnix {
+		if lastGen < lastGeneratedNano {
 			close(c)
 			delete(channels, lastGen)
 		}
@@ -2069,10 +3079,11 @@ func (state *gbtWorkState) NotifyBlockConnected(blockHash *chainhash.Hash) {
 }
 
 // NotifyMempoolTx uses the new last updated time for the transaction memory
-// pool to notify any long poll clients with a new block template when their
-// existing block template is stale due to enough time passing and the contents
-// of the memory pool changing.
-func (state *gbtWorkState) NotifyMempoolTx(lastUpdated time.Time) {
+// pool and the fee of the new transaction to notify any long poll clients with
+// a new block template when their existing block template is stale due to
+// enough time passing or enough fees being added with the contents of the
+// memory pool changing.
+func (state *gbtWorkState) NotifyMempoolTx(lastUpdated time.Time, fee int64) {
 	go func() {
 		state.Lock()
 		defer state.Unlock()
@@ -2083,8 +3094,9 @@ func (state *gbtWorkState) NotifyMempoolTx(lastUpdated time.Time) {
 			return
 		}
 
-		if time.Now().After(state.lastGenerated.Add(time.Second *
-			gbtRegenerateSeconds)) {
+		state.newTxFees += fee
+		if state.feeDeltaReached() || time.Now().After(
+			state.lastGenerated.Add(time.Second*gbtRegenerateSeconds)) {
 
 			state.notifyLongPollers(state.prevHash, lastUpdated)
 		}
@@ -2122,7 +3134,8 @@ func (state *gbtWorkState) templateUpdateChan(prevHash *chainhash.Hash, lastGene
 // updateBlockTemplate creates or updates a block template for the work state.
 // A new block template will be generated when the current best block has
 // changed or the transactions in the memory pool have been updated and it has
-// been long enough since the last template was generated.  Otherwise, the
+// been long enough since the last template was generated or the new
+// transactions pay enough fees.  Otherwise, the
 // timestamp for the existing block template is updated (and possibly the
 // difficulty on testnet per the consesus rules).  Finally, if the
 // useCoinbaseValue flag is false and the existing block template does not
@@ -2140,8 +3153,9 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 
 	// Generate a new block template when the current best block has
 	// changed or the transactions in the memory pool have been updated and
-	// it has been at least gbtRegenerateSecond since the last template was
-	// generated.
+	// either it has been at least gbtRegenerateSecond since the last
+	// template was generated or the new transactions pay at least the fee
+	// delta.
 	var msgBlock *wire.MsgBlock
 	var targetDifficulty string
 	latestHash := &s.cfg.Chain.BestSnapshot().Hash
@@ -2149,8 +3163,9 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 	if template == nil || state.prevHash == nil ||
 		!state.prevHash.IsEqual(latestHash) ||
 		(state.lastTxUpdate != lastTxUpdate &&
-			time.Now().After(state.lastGenerated.Add(time.Second*
-				gbtRegenerateSeconds))) {
+			(state.feeDeltaReached() ||
+				time.Now().After(state.lastGenerated.Add(time.Second*
+					gbtRegenerateSeconds)))) {
 
 		// Reset the previous best hash the block template was generated
 		// against so any errors below cause the next invocation to try
@@ -2193,6 +3208,7 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		state.lastTxUpdate = lastTxUpdate
 		state.prevHash = latestHash
 		state.minTimestamp = minTimestamp
+		state.newTxFees = 0
 
 		rpcsLog.Debugf("Generated block template (timestamp %v, "+
 			"target %s, merkle root %s)",
@@ -2365,6 +3381,10 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 	} else {
 		reply.Rules = append(reply.Rules, "segwit")
 	}
+	if challenge := activeNetParams.SignetChallenge; len(challenge) > 0 {
+		reply.SignetChallenge = hex.EncodeToString(challenge)
+		reply.Rules = append(reply.Rules, "!signet")
+	}
 
 	if useCoinbaseValue {
 		reply.CoinbaseAux = gbtCoinbaseAux
@@ -2447,7 +3467,7 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	// template as this means the provided template is stale.
 	prevTemplateHash := &state.template.Block.Header.PrevBlock
 	if !prevHash.IsEqual(prevTemplateHash) ||
-		lastGenerated != state.lastGenerated.Unix() {
+		lastGenerated != state.lastGenerated.UnixNano() {
 
 		// Include whether or not it is valid to submit work against the
 		// old block template depending on whether or not a solution has
@@ -2686,6 +3706,8 @@ func chainErrToGBTErrString(err error) string {
 		return "bad-prevblk"
 	case blockchain.ErrPrevBlockNotBest:
 		return "inconclusive-not-best-prvblk"
+	case blockchain.ErrBadClaimTrie:
+		return "bad-claimtrie"
 	}
 
 	return "rejected: " + err.Error()
@@ -2731,11 +3753,20 @@ func handleGetBlockTemplateProposal(s *rpcServer, request *btcjson.TemplateReque
 	expectedPrevHash := s.cfg.Chain.BestSnapshot().Hash
 	prevHash := &block.MsgBlock().Header.PrevBlock
 	if !expectedPrevHash.IsEqual(prevHash) {
+		if request.Verbose {
+			return &btcjson.GetBlockTemplateProposalResult{
+				Reason: "bad-prevblk",
+				Code:   blockchain.ErrPrevBlockNotBest.String(),
+				Message: fmt.Sprintf("previous block %v is not the "+
+					"current tip %v", prevHash, expectedPrevHash),
+			}, nil
+		}
 		return "bad-prevblk", nil
 	}
 
 	if err := s.cfg.Chain.CheckConnectBlockTemplate(block); err != nil {
-		if _, ok := err.(blockchain.RuleError); !ok {
+		ruleErr, ok := err.(blockchain.RuleError)
+		if !ok {
 			errStr := fmt.Sprintf("Failed to process block proposal: %v", err)
 			rpcsLog.Error(errStr)
 			return nil, &btcjson.RPCError{
@@ -2745,9 +3776,19 @@ func handleGetBlockTemplateProposal(s *rpcServer, request *btcjson.TemplateReque
 		}
 
 		rpcsLog.Infof("Rejected block proposal: %v", err)
+		if request.Verbose {
+			return &btcjson.GetBlockTemplateProposalResult{
+				Reason:  chainErrToGBTErrString(err),
+				Code:    ruleErr.ErrorCode.String(),
+				Message: ruleErr.Description,
+			}, nil
+		}
 		return chainErrToGBTErrString(err), nil
 	}
 
+	if request.Verbose {
+		return &btcjson.GetBlockTemplateProposalResult{Accepted: true}, nil
+	}
 	return nil, nil
 }
 
@@ -2778,22 +3819,40 @@ func handleGetBlockTemplate(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	}
 }
 
-// handleGetCFilter implements the getcfilter command.
-func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if s.cfg.CfIndex == nil {
+// rpcFilterIndex returns the index maintaining the filters of the passed type,
+// or an error when filters of the type aren't maintained.
+func rpcFilterIndex(s *rpcServer, filterType wire.FilterType) (filterIndexer, error) {
+	index := filterIndex(s.cfg.CfIndex, s.cfg.ClaimFilterIndex, filterType)
+	if index != nil {
+		return index, nil
+	}
+
+	if filterType == wire.GCSFilterClaimName {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCNoCFIndex,
-			Message: "The CF index must be enabled for this command",
+			Message: "Claim filter index must be enabled (--claimfilterindex)",
 		}
 	}
+	return nil, &btcjson.RPCError{
+		Code:    btcjson.ErrRPCNoCFIndex,
+		Message: "The CF index must be enabled for this command",
+	}
+}
 
+// handleGetCFilter implements the getcfilter command.
+func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetCFilterCmd)
+	index, err := rpcFilterIndex(s, c.FilterType)
+	if err != nil {
+		return nil, err
+	}
+
 	hash, err := chainhash.NewHashFromStr(c.Hash)
 	if err != nil {
 		return nil, rpcDecodeHexError(c.Hash)
 	}
 
-	filterBytes, err := s.cfg.CfIndex.FilterByBlockHash(hash, c.FilterType)
+	filterBytes, err := index.FilterByBlockHash(hash, c.FilterType)
 	if err != nil {
 		rpcsLog.Debugf("Could not find committed filter for %v: %v",
 			hash, err)
@@ -2809,20 +3868,18 @@ func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 
 // handleGetCFilterHeader implements the getcfilterheader command.
 func handleGetCFilterHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	if s.cfg.CfIndex == nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCNoCFIndex,
-			Message: "The CF index must be enabled for this command",
-		}
+	c := cmd.(*btcjson.GetCFilterHeaderCmd)
+	index, err := rpcFilterIndex(s, c.FilterType)
+	if err != nil {
+		return nil, err
 	}
 
-	c := cmd.(*btcjson.GetCFilterHeaderCmd)
 	hash, err := chainhash.NewHashFromStr(c.Hash)
 	if err != nil {
 		return nil, rpcDecodeHexError(c.Hash)
 	}
 
-	headerBytes, err := s.cfg.CfIndex.FilterHeaderByBlockHash(hash, c.FilterType)
+	headerBytes, err := index.FilterHeaderByBlockHash(hash, c.FilterType)
 	if len(headerBytes) > 0 {
 		rpcsLog.Debugf("Found header of committed filter for %v", hash)
 	} else {
@@ -2924,59 +3981,75 @@ func handleGetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 	return ret, nil
 }
 
+// handleGetMempoolFeeHistogram implements the getmempoolfeehistogram command.
+func handleGetMempoolFeeHistogram(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return s.cfg.TxMemPool.FeeHistogram(), nil
+}
+
 // handleGetMempoolInfo implements the getmempoolinfo command.
 func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	return s.cfg.TxMemPool.MempoolInfo(), nil
 }
 
-// handleGetMempoolEntry implements the getmempoolentry command.
-func handleGetMempoolEntry(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+// handleGetMempoolAncestors implements the getmempoolancestors command.
+func handleGetMempoolAncestors(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolAncestorsCmd)
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
 
-	c := cmd.(*btcjson.GetMempoolEntryCmd)
+	ancestors, err := s.cfg.TxMemPool.MempoolAncestors(txHash)
+	if err != nil {
+		return nil, rpcNoTxInfoError(txHash)
+	}
+	return mempoolEntriesResult(ancestors, *c.Verbose), nil
+}
+
+// handleGetMempoolDescendants implements the getmempooldescendants command.
+func handleGetMempoolDescendants(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolDescendantsCmd)
 	txHash, err := chainhash.NewHashFromStr(c.TxID)
 	if err != nil {
-		if err != nil {
-			return nil, rpcDecodeHexError(c.TxID)
-		}
+		return nil, rpcDecodeHexError(c.TxID)
 	}
 
-	mp := s.cfg.TxMemPool
-	for _, desc := range mp.TxDescs() {
-		tx := desc.Tx
-		if tx.Hash().IsEqual(txHash) {
-			continue
-		}
-		desc.Tx.WitnessHash()
-
-		ret := &btcjson.GetMempoolEntryResult{
-			VSize:           int32(mempool.GetTxVirtualSize(tx)),
-			Size:            int32(tx.MsgTx().SerializeSize()),
-			Weight:          blockchain.GetTransactionWeight(tx),
-			Fee:             btcutil.Amount(desc.Fee).ToBTC(),
-			ModifiedFee:     btcutil.Amount(desc.Fee).ToBTC(), // TODO, Deprecated
-			Time:            desc.Added.Unix(),
-			Height:          int64(desc.Height),
-			DescendantCount: 1,                                // TODO
-			DescendantSize:  mempool.GetTxVirtualSize(tx),     // TODO
-			DescendantFees:  btcutil.Amount(desc.Fee).ToBTC(), // TODO, Deprecated
-			AncestorCount:   1,                                // TODO
-			AncestorSize:    mempool.GetTxVirtualSize(tx),     // TODO
-			AncestorFees:    btcutil.Amount(desc.Fee).ToBTC(), // TODO, Deprecated
-			WTxId:           desc.Tx.WitnessHash().String(),
-			Fees: btcjson.MempoolFees{
-				Base:       btcutil.Amount(desc.Fee).ToBTC(),
-				Modified:   btcutil.Amount(desc.Fee).ToBTC(), // TODO
-				Ancestor:   btcutil.Amount(desc.Fee).ToBTC(), // TODO
-				Descendant: btcutil.Amount(desc.Fee).ToBTC(), // TODO
-			},
-			Depends: make([]string, 0), // TODO
-			SpentBy: make([]string, 0), // TODO
-		}
+	descendants, err := s.cfg.TxMemPool.MempoolDescendants(txHash)
+	if err != nil {
+		return nil, rpcNoTxInfoError(txHash)
+	}
+	return mempoolEntriesResult(descendants, *c.Verbose), nil
+}
+
+// mempoolEntriesResult returns the passed mempool entries as the result of the
+// getmempoolancestors and getmempooldescendants commands, which is either the
+// entries keyed by their transaction hash when verbose, or the sorted hashes.
+func mempoolEntriesResult(entries map[string]*btcjson.GetMempoolEntryResult, verbose bool) interface{} {
+	if verbose {
+		return entries
+	}
+
+	hashes := make([]string, 0, len(entries))
+	for hash := range entries {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	return hashes
+}
 
-		return ret, nil
+// handleGetMempoolEntry implements the getmempoolentry command.
+func handleGetMempoolEntry(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolEntryCmd)
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
 	}
 
-	return nil, rpcNoTxInfoError(txHash)
+	entry, err := s.cfg.TxMemPool.MempoolEntry(txHash)
+	if err != nil {
+		return nil, rpcNoTxInfoError(txHash)
+	}
+	return entry, nil
 }
 
 // handleGetMiningInfo implements the getmininginfo command. We only return the
@@ -3017,12 +4090,18 @@ func handleGetMiningInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 
 // handleGetNetTotals implements the getnettotals command.
 func handleGetNetTotals(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetNetTotalsCmd)
+
 	totalBytesRecv, totalBytesSent := s.cfg.ConnMgr.NetTotals()
 	reply := &btcjson.GetNetTotalsResult{
 		TotalBytesRecv: totalBytesRecv,
 		TotalBytesSent: totalBytesSent,
 		TimeMillis:     time.Now().UTC().UnixNano() / int64(time.Millisecond),
 	}
+	if c.Verbose != nil && *c.Verbose {
+		reply.BytesRecvPerMsg, reply.BytesSentPerMsg =
+			s.cfg.ConnMgr.NetTotalsPerMsg()
+	}
 	return reply, nil
 }
 
@@ -3075,49 +4154,49 @@ func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	rpcsLog.Debugf("Calculating network hashes per second from %d to %d",
 		startHeight, endHeight)
 
-	// Find the min and max block timestamps as well as calculate the total
-	// amount of work that happened between the start and end blocks.
-	var minTimestamp, maxTimestamp time.Time
-	totalWork := big.NewInt(0)
-	for curHeight := startHeight; curHeight <= endHeight; curHeight++ {
-		hash, err := s.cfg.Chain.BlockHashByHeight(curHeight)
-		if err != nil {
-			context := "Failed to fetch block hash"
-			return nil, internalRPCError(err.Error(), context)
-		}
-
-		// Fetch the header from chain.
-		header, err := s.cfg.Chain.HeaderByHash(hash)
-		if err != nil {
-			context := "Failed to fetch block header"
-			return nil, internalRPCError(err.Error(), context)
-		}
+	hashesPerSec, err := s.cfg.Chain.CalcNetworkHashPS(startHeight, endHeight)
+	if err != nil {
+		context := "Failed to calculate network hashes per second"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return hashesPerSec.Int64(), nil
+}
 
-		if curHeight == startHeight {
-			minTimestamp = header.Timestamp
-			maxTimestamp = minTimestamp
-		} else {
-			totalWork.Add(totalWork, blockchain.CalcWork(header.Bits))
+// handleGetNextDifficulty implements the getnextdifficulty command.
+func handleGetNextDifficulty(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetNextDifficultyCmd)
 
-			if minTimestamp.After(header.Timestamp) {
-				minTimestamp = header.Timestamp
-			}
-			if maxTimestamp.Before(header.Timestamp) {
-				maxTimestamp = header.Timestamp
-			}
-		}
+	// Project the difficulty of a block mined now by default.
+	timestamp := s.cfg.TimeSource.AdjustedTime()
+	if c.Timestamp != nil {
+		timestamp = time.Unix(*c.Timestamp, 0)
 	}
 
-	// Calculate the difference in seconds between the min and max block
-	// timestamps and avoid division by zero in the case where there is no
-	// time difference.
-	timeDiff := int64(maxTimestamp.Sub(minTimestamp) / time.Second)
-	if timeDiff == 0 {
-		return int64(0), nil
+	retarget, err := s.cfg.Chain.ProjectNextDifficulty(timestamp)
+	if err != nil {
+		context := "Failed to project the next difficulty"
+		return nil, internalRPCError(err.Error(), context)
 	}
 
-	hashesPerSec := new(big.Int).Div(totalWork, big.NewInt(timeDiff))
-	return hashesPerSec.Int64(), nil
+	params := s.cfg.ChainParams
+	difficulty := getDifficultyRatio(retarget.Bits, params)
+	currentDifficulty := getDifficultyRatio(retarget.PrevBits, params)
+	var change float64
+	if currentDifficulty != 0 {
+		change = (difficulty/currentDifficulty - 1) * 100
+	}
+
+	return &btcjson.GetNextDifficultyResult{
+		Height:            retarget.Height,
+		Bits:              strconv.FormatInt(int64(retarget.Bits), 16),
+		Target:            fmt.Sprintf("%064x", blockchain.CompactToBig(retarget.Bits)),
+		Difficulty:        difficulty,
+		CurrentDifficulty: currentDifficulty,
+		Change:            change,
+		ActualTimespan:    int64(retarget.ActualTimespan / time.Second),
+		AdjustedTimespan:  int64(retarget.AdjustedTimespan / time.Second),
+		TargetTimespan:    int64(params.TargetTimespan / time.Second),
+	}, nil
 }
 
 // handleGetNodeAddresses implements the getnodeaddresses command.
@@ -3179,6 +4258,19 @@ func handleGetNetworkInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 		onionProxy = cfg.OnionProxy
 	}
 
+	// Count the peers of each network.  The networks outbound peers are
+	// connected to are reachable.
+	inbound := make(map[string]int32)
+	outbound := make(map[string]int32)
+	for _, p := range s.cfg.ConnMgr.ConnectedPeers() {
+		network := addrNetworkName(p.ToPeer().Addr())
+		if p.ToPeer().Inbound() {
+			inbound[network]++
+		} else {
+			outbound[network]++
+		}
+	}
+
 	var warnings string
 	unknownRulesWarned := s.cfg.Chain.GetWarnings()
 	if unknownRulesWarned {
@@ -3206,22 +4298,32 @@ func handleGetNetworkInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 		NetworkActive:   true,
 		Networks: []btcjson.NetworksResult{
 			{
-				Name:      "ipv4",
-				Reachable: ipv4Reachable,
-				Proxy:     cfg.Proxy,
+				Name:           networkIPv4,
+				Reachable:      ipv4Reachable || outbound[networkIPv4] > 0,
+				Proxy:          cfg.Proxy,
+				InboundPeers:   inbound[networkIPv4],
+				OutboundPeers:  outbound[networkIPv4],
+				OutboundTarget: cfg.outboundTargets[networkIPv4],
 			},
 			{
-				Name:      "ipv6",
-				Reachable: ipv6Reachable,
-				Proxy:     cfg.Proxy,
+				Name:           networkIPv6,
+				Reachable:      ipv6Reachable || outbound[networkIPv6] > 0,
+				Proxy:          cfg.Proxy,
+				InboundPeers:   inbound[networkIPv6],
+				OutboundPeers:  outbound[networkIPv6],
+				OutboundTarget: cfg.outboundTargets[networkIPv6],
 			},
 			{
-				Name: "onion",
+				Name: networkOnion,
 
 				ProxyRandomizeCredentials: cfg.TorIsolation,
 
-				Proxy:     onionProxy,
-				Reachable: cfg.Proxy != "" || cfg.OnionProxy != "",
+				Proxy: onionProxy,
+				Reachable: !cfg.NoOnion &&
+					(cfg.Proxy != "" || cfg.OnionProxy != ""),
+				InboundPeers:   inbound[networkOnion],
+				OutboundPeers:  outbound[networkOnion],
+				OutboundTarget: cfg.outboundTargets[networkOnion],
 			},
 		},
 		RelayFee:   cfg.MinRelayTxFee,
@@ -3229,6 +4331,24 @@ func handleGetNetworkInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 		TimeOffset: timeOffset,
 		Warnings:   warnings,
 	}
+	if s.cfg.PortMapper != nil {
+		status := s.cfg.PortMapper.Status()
+		reply.PortMapping = &btcjson.PortMappingResult{
+			Status:       status.State,
+			Protocol:     status.Protocol,
+			ExternalPort: status.ExternalPort,
+			InternalPort: status.InternalPort,
+		}
+		if status.ExternalIP != nil {
+			reply.PortMapping.ExternalAddress = status.ExternalIP.String()
+		}
+		if !status.Expires.IsZero() {
+			reply.PortMapping.LeaseExpires = status.Expires.Unix()
+		}
+		if status.Err != nil {
+			reply.PortMapping.Error = status.Err.Error()
+		}
+	}
 	return reply, nil
 }
 
@@ -3236,30 +4356,41 @@ func handleGetNetworkInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	peers := s.cfg.ConnMgr.ConnectedPeers()
 	syncPeerID := s.cfg.SyncMgr.SyncPeerID()
+	syncStats := s.cfg.SyncMgr.PeerSyncStats()
 	infos := make([]*btcjson.GetPeerInfoResult, 0, len(peers))
 	for _, p := range peers {
 		statsSnap := p.ToPeer().StatsSnapshot()
 		info := &btcjson.GetPeerInfoResult{
-			ID:             statsSnap.ID,
-			Addr:           statsSnap.Addr,
-			AddrLocal:      p.ToPeer().LocalAddr().String(),
-			Services:       fmt.Sprintf("%08d", uint64(statsSnap.Services)),
-			RelayTxes:      !p.IsTxRelayDisabled(),
-			LastSend:       statsSnap.LastSend.Unix(),
-			LastRecv:       statsSnap.LastRecv.Unix(),
-			BytesSent:      statsSnap.BytesSent,
-			BytesRecv:      statsSnap.BytesRecv,
-			ConnTime:       statsSnap.ConnTime.Unix(),
-			PingTime:       float64(statsSnap.LastPingMicros),
-			TimeOffset:     statsSnap.TimeOffset,
-			Version:        statsSnap.Version,
-			SubVer:         statsSnap.UserAgent,
-			Inbound:        statsSnap.Inbound,
-			StartingHeight: statsSnap.StartingHeight,
-			CurrentHeight:  statsSnap.LastBlock,
-			BanScore:       int32(p.BanScore()),
-			FeeFilter:      p.FeeFilter(),
-			SyncNode:       statsSnap.ID == syncPeerID,
+			ID:              statsSnap.ID,
+			Addr:            statsSnap.Addr,
+			AddrLocal:       p.ToPeer().LocalAddr().String(),
+			Services:        fmt.Sprintf("%08d", uint64(statsSnap.Services)),
+			RelayTxes:       !p.IsTxRelayDisabled(),
+			LastSend:        statsSnap.LastSend.Unix(),
+			LastRecv:        statsSnap.LastRecv.Unix(),
+			BytesSent:       statsSnap.BytesSent,
+			BytesRecv:       statsSnap.BytesRecv,
+			BytesSentPerMsg: statsSnap.BytesSentPerMsg,
+			BytesRecvPerMsg: statsSnap.BytesRecvPerMsg,
+			ConnTime:        statsSnap.ConnTime.Unix(),
+			PingTime:        float64(statsSnap.LastPingMicros),
+			TimeOffset:      statsSnap.TimeOffset,
+			Version:         statsSnap.Version,
+			SubVer:          statsSnap.UserAgent,
+			Inbound:         statsSnap.Inbound,
+			StartingHeight:  statsSnap.StartingHeight,
+			CurrentHeight:   statsSnap.LastBlock,
+			BanScore:        int32(p.BanScore()),
+			Misbehavior:     p.MisbehaviorScores(),
+			FeeFilter:       p.FeeFilter(),
+			BloomWork:       p.BloomWork(),
+			SyncNode:        statsSnap.ID == syncPeerID,
+		}
+		if stats, ok := syncStats[statsSnap.ID]; ok {
+			info.BlocksInFlight = stats.BlocksInFlight
+			info.BlocksReceived = stats.BlocksReceived
+			info.BlocksReassigned = stats.BlocksReassigned
+			info.BlockStalls = stats.BlockStalls
 		}
 		if p.ToPeer().LastPingNonce() != 0 {
 			wait := float64(time.Since(statsSnap.LastPingTime).Nanoseconds())
@@ -3407,9 +4538,52 @@ func handleGetRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan str
 	if err != nil {
 		return nil, err
 	}
+
+	// Include the outputs spent by the inputs when they are available.
+	if !blockchain.IsCoinBaseTx(mtx) {
+		for i, txIn := range mtx.TxIn {
+			rawTxn.Vin[i].PrevOut = fetchVinPrevOut(s,
+				&txIn.PreviousOutPoint)
+		}
+	}
 	return *rawTxn, nil
 }
 
+// handleGetSpentInfo implements the getspentinfo command.
+func handleGetSpentInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if s.cfg.SpentIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Spent index must be enabled (--spentindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetSpentInfoCmd)
+	txHash, err := chainhash.NewHashFromStr(c.Txid)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Txid)
+	}
+
+	outPoint := wire.OutPoint{Hash: *txHash, Index: c.Vout}
+	entry, err := s.cfg.SpentIndex.SpentBy(&outPoint)
+	if err != nil {
+		context := "Failed to retrieve spent index entry"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if entry == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoTxInfo,
+			Message: "No spending transaction found for " + outPoint.String(),
+		}
+	}
+
+	return &btcjson.GetSpentInfoResult{
+		Txid:   entry.TxHash.String(),
+		Vin:    entry.InputIndex,
+		Height: entry.Height,
+	}, nil
+}
+
 // handleGetTxOut handles gettxout commands.
 func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetTxOutCmd)
@@ -3527,6 +4701,57 @@ func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return txOutReply, nil
 }
 
+// handleGetTxOutSetInfo handles gettxoutsetinfo commands.
+func handleGetTxOutSetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	stats, err := s.cfg.Chain.UtxoSetStats()
+	if err != nil {
+		context := "Failed to compute utxo set statistics"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return &btcjson.GetTxOutSetInfoResult{
+		Height:      int64(stats.Height),
+		BestBlock:   stats.Hash,
+		TxOuts:      stats.TxOuts,
+		BogoSize:    stats.BogoSize,
+		MuHash:      stats.MuHash,
+		TotalAmount: btcutil.Amount(stats.TotalAmount),
+	}, nil
+}
+
+// handleGetRPCStats implements the getrpcstats command.
+func handleGetRPCStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetRPCStatsCmd)
+	if *c.Count < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "count must not be negative",
+		}
+	}
+	return s.rpcStats.result(*c.Count), nil
+}
+
+// handleGetValidationInfo implements the getvalidationinfo command.
+func handleGetValidationInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	scheduler := s.cfg.Chain.ValidationScheduler()
+	if scheduler == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Script validation scheduler is not available",
+		}
+	}
+	stats := scheduler.Stats()
+	return &btcjson.GetValidationInfoResult{
+		Workers:          int32(stats.Workers),
+		PendingHigh:      stats.PendingHigh,
+		PendingLow:       stats.PendingLow,
+		BatchesValidated: stats.BatchesValidated,
+		InputsValidated:  stats.InputsValidated,
+		ValidationTimeMs: stats.ValidationTime.Milliseconds(),
+		InputsPerSecond:  stats.InputsPerSecond(),
+	}, nil
+}
+
 // handleInvalidateBlock implements the invalidateblock command
 func handleInvalidateBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.InvalidateBlockCmd)
@@ -3628,6 +4853,44 @@ func handleListBanned(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return reply, nil
 }
 
+// handleLoadTxOutSet implements the loadtxoutset command.
+func handleLoadTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.LoadTxOutSetCmd)
+
+	path := snapshotPath(c.Path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Unable to open snapshot file: " + err.Error(),
+		}
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "Unable to read snapshot file")
+	}
+
+	info, err := s.cfg.Chain.LoadUtxoSnapshot(f, fi.Size(), nil)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Unable to load the utxo set: " + err.Error(),
+		}
+	}
+
+	// The blocks requested from peers are based on the previous best block,
+	// so start over from the block of the snapshot.
+	s.cfg.SyncMgr.ResetSync()
+
+	return &btcjson.LoadTxOutSetResult{
+		CoinsLoaded: info.TxOuts,
+		TipHash:     info.Hash.String(),
+		BaseHeight:  info.Height,
+		Path:        path,
+	}, nil
+}
+
 // handlePing implements the ping command.
 func handlePing(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Ask server to ping \o_
@@ -3843,6 +5106,94 @@ func createVinListPrevOut(s *rpcServer, mtx *wire.MsgTx, chainParams *chaincfg.P
 	return vinList, nil
 }
 
+// fetchTxnsForAddress returns the transactions involving the passed address
+// from both the address index and the mempool, skipping and limiting the
+// results as requested.  Mempool transactions come first when reverse is set
+// and last otherwise.
+//
+// The address index must be enabled when calling this function.
+func fetchTxnsForAddress(s *rpcServer, addr btcutil.Address, numToSkip, numRequested int, reverse bool) ([]retrievedTx, error) {
+	addrIndex := s.cfg.AddrIndex
+
+	// Add transactions from mempool first if client asked for reverse
+	// order.  Otherwise, they will be added last (as needed depending on
+	// the requested counts).
+	//
+	// NOTE: This code doesn't sort by dependency.  This might be something
+	// to do in the future for the client's convenience, or leave it to the
+	// client.
+	numSkipped := uint32(0)
+	addressTxns := make([]retrievedTx, 0, numRequested)
+	if reverse {
+		// Transactions in the mempool are not in a block header yet,
+		// so the block header field in the retieved transaction struct
+		// is left nil.
+		mpTxns, mpSkipped := fetchMempoolTxnsForAddress(s, addr,
+			uint32(numToSkip), uint32(numRequested))
+		numSkipped += mpSkipped
+		for _, tx := range mpTxns {
+			addressTxns = append(addressTxns, retrievedTx{tx: tx})
+		}
+	}
+
+	// Fetch transactions from the database in the desired order if more are
+	// needed.
+	if len(addressTxns) < numRequested {
+		err := s.cfg.DB.View(func(dbTx database.Tx) error {
+			regions, dbSkipped, err := addrIndex.TxRegionsForAddress(
+				dbTx, addr, uint32(numToSkip)-numSkipped,
+				uint32(numRequested-len(addressTxns)), reverse)
+			if err != nil {
+				return err
+			}
+
+			// Load the raw transaction bytes from the database.
+			serializedTxns, err := dbTx.FetchBlockRegions(regions)
+			if err != nil {
+				return err
+			}
+
+			// Add the transaction and the hash of the block it is
+			// contained in to the list.  Note that the transaction
+			// is left serialized here since the caller might have
+			// requested non-verbose output and hence there would be
+			// no point in deserializing it just to reserialize it
+			// later.
+			for i, serializedTx := range serializedTxns {
+				addressTxns = append(addressTxns, retrievedTx{
+					txBytes: serializedTx,
+					blkHash: regions[i].Hash,
+				})
+			}
+			numSkipped += dbSkipped
+
+			return nil
+		})
+		if err != nil {
+			context := "Failed to load address index entries"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+	}
+
+	// Add transactions from mempool last if client did not request reverse
+	// order and the number of results is still under the number requested.
+	if !reverse && len(addressTxns) < numRequested {
+		// Transactions in the mempool are not in a block header yet,
+		// so the block header field in the retieved transaction struct
+		// is left nil.
+		mpTxns, mpSkipped := fetchMempoolTxnsForAddress(s, addr,
+			uint32(numToSkip)-numSkipped, uint32(numRequested-
+				len(addressTxns)))
+		numSkipped += mpSkipped
+		for _, tx := range mpTxns {
+			addressTxns = append(addressTxns, retrievedTx{tx: tx})
+		}
+	}
+
+	return addressTxns, nil
+}
+
 // fetchMempoolTxnsForAddress queries the address index for all unconfirmed
 // transactions that involve the provided address.  The results will be limited
 // by the number to skip and the number requested.
@@ -3865,14 +5216,10 @@ func fetchMempoolTxnsForAddress(s *rpcServer, addr btcutil.Address, numToSkip, n
 }
 
 // handleSearchRawTransactions implements the searchrawtransactions command.
-func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Respond with an error if the address index is not enabled.
-	addrIndex := s.cfg.AddrIndex
-	if addrIndex == nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "Address index must be enabled (--addrindex)",
-		}
+func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the address index is not enabled.
+	if s.cfg.AddrIndex == nil {
+		return nil, addrIndexRequiredError()
 	}
 
 	// Override the flag for including extra previous output information in
@@ -3896,12 +5243,9 @@ func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan
 
 	// Attempt to decode the supplied address.
 	params := s.cfg.ChainParams
-	addr, err := btcutil.DecodeAddress(c.Address, params)
+	addr, err := decodeIndexedAddress(s, c.Address)
 	if err != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidAddressOrKey,
-			Message: "Invalid address or key: " + err.Error(),
-		}
+		return nil, err
 	}
 
 	// Override the default number of requested entries if needed.  Also,
@@ -3933,80 +5277,10 @@ func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan
 		reverse = *c.Reverse
 	}
 
-	// Add transactions from mempool first if client asked for reverse
-	// order.  Otherwise, they will be added last (as needed depending on
-	// the requested counts).
-	//
-	// NOTE: This code doesn't sort by dependency.  This might be something
-	// to do in the future for the client's convenience, or leave it to the
-	// client.
-	numSkipped := uint32(0)
-	addressTxns := make([]retrievedTx, 0, numRequested)
-	if reverse {
-		// Transactions in the mempool are not in a block header yet,
-		// so the block header field in the retieved transaction struct
-		// is left nil.
-		mpTxns, mpSkipped := fetchMempoolTxnsForAddress(s, addr,
-			uint32(numToSkip), uint32(numRequested))
-		numSkipped += mpSkipped
-		for _, tx := range mpTxns {
-			addressTxns = append(addressTxns, retrievedTx{tx: tx})
-		}
-	}
-
-	// Fetch transactions from the database in the desired order if more are
-	// needed.
-	if len(addressTxns) < numRequested {
-		err = s.cfg.DB.View(func(dbTx database.Tx) error {
-			regions, dbSkipped, err := addrIndex.TxRegionsForAddress(
-				dbTx, addr, uint32(numToSkip)-numSkipped,
-				uint32(numRequested-len(addressTxns)), reverse)
-			if err != nil {
-				return err
-			}
-
-			// Load the raw transaction bytes from the database.
-			serializedTxns, err := dbTx.FetchBlockRegions(regions)
-			if err != nil {
-				return err
-			}
-
-			// Add the transaction and the hash of the block it is
-			// contained in to the list.  Note that the transaction
-			// is left serialized here since the caller might have
-			// requested non-verbose output and hence there would be
-			// no point in deserializing it just to reserialize it
-			// later.
-			for i, serializedTx := range serializedTxns {
-				addressTxns = append(addressTxns, retrievedTx{
-					txBytes: serializedTx,
-					blkHash: regions[i].Hash,
-				})
-			}
-			numSkipped += dbSkipped
-
-			return nil
-		})
-		if err != nil {
-			context := "Failed to load address index entries"
-			return nil, internalRPCError(err.Error(), context)
-		}
-
-	}
-
-	// Add transactions from mempool last if client did not request reverse
-	// order and the number of results is still under the number requested.
-	if !reverse && len(addressTxns) < numRequested {
-		// Transactions in the mempool are not in a block header yet,
-		// so the block header field in the retieved transaction struct
-		// is left nil.
-		mpTxns, mpSkipped := fetchMempoolTxnsForAddress(s, addr,
-			uint32(numToSkip)-numSkipped, uint32(numRequested-
-				len(addressTxns)))
-		numSkipped += mpSkipped
-		for _, tx := range mpTxns {
-			addressTxns = append(addressTxns, retrievedTx{tx: tx})
-		}
+	addressTxns, err := fetchTxnsForAddress(s, addr, numToSkip, numRequested,
+		reverse)
+	if err != nil {
+		return nil, err
 	}
 
 	// Address has never been used if neither source yielded any results.
@@ -4235,11 +5509,11 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 func handleSetBan(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SetBanCmd)
 
-	addr := net.ParseIP(c.Addr)
-	if addr == nil {
+	subnet, err := parseBanSubnet(c.Addr)
+	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCInvalidParameter,
-			Message: "invalid addr for setban",
+			Message: "invalid addr for setban: " + err.Error(),
 		}
 	}
 
@@ -4252,20 +5526,11 @@ func handleSetBan(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (int
 		until = time.Unix(int64(*c.BanTime), 0)
 	}
 
-	var err error
 	switch c.SubCmd {
 	case "add":
-		err = s.cfg.ConnMgr.SetBan(addr.String(), since, until)
-		addr := addr.String()
-		peers := s.cfg.ConnMgr.ConnectedPeers()
-		for _, peer := range peers {
-			p := peer.ToPeer()
-			if p.NA().IP.String() == addr {
-				p.Disconnect()
-			}
-		}
+		err = s.cfg.ConnMgr.SetBan(subnet, since, until)
 	case "remove":
-		err = s.cfg.ConnMgr.RemoveBan(addr.String())
+		err = s.cfg.ConnMgr.RemoveBan(subnet)
 	default:
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCInvalidParameter,
@@ -4300,16 +5565,49 @@ func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 		generate = false
 	}
 
+	// Replace the payouts the generated blocks are split among when they
+	// are provided.  They are sorted by address since the order of the
+	// JSON object is lost.
+	if c.Payouts != nil {
+		addrs := make([]string, 0, len(*c.Payouts))
+		for addr := range *c.Payouts {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+
+		payouts := make([]mining.Payout, 0, len(addrs))
+		for _, strAddr := range addrs {
+			addr, err := btcutil.DecodeAddress(strAddr, s.cfg.ChainParams)
+			if err != nil || !addr.IsForNet(s.cfg.ChainParams) {
+				return nil, rpcInvalidAddressOrKeyError(strAddr,
+					"Invalid address or key: "+strAddr)
+			}
+			weight := (*c.Payouts)[strAddr]
+			if weight == 0 {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: "Invalid zero weight for payout " +
+						strAddr,
+				}
+			}
+			payouts = append(payouts, mining.Payout{
+				Address: addr,
+				Weight:  weight,
+			})
+		}
+		s.cfg.CPUMiner.SetPayouts(payouts)
+	}
+
 	if !generate {
 		s.cfg.CPUMiner.Stop()
 	} else {
 		// Respond with an error if there are no addresses to pay the
 		// created blocks to.
-		if len(cfg.miningAddrs) == 0 {
+		if len(cfg.miningAddrs) == 0 && len(s.cfg.CPUMiner.Payouts()) == 0 {
 			return nil, &btcjson.RPCError{
 				Code: btcjson.ErrRPCInternal.Code,
 				Message: "No payment addresses specified " +
-					"via --miningaddr",
+					"via --miningaddr, --miningpayout or payouts",
 			}
 		}
 
@@ -4320,6 +5618,29 @@ func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return nil, nil
 }
 
+// handleSetValidationWorkers implements the setvalidationworkers command.
+func handleSetValidationWorkers(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SetValidationWorkersCmd)
+
+	// A value of zero selects the default number of workers.
+	if c.Workers < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Number of workers cannot be negative",
+		}
+	}
+
+	scheduler := s.cfg.Chain.ValidationScheduler()
+	if scheduler == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Script validation scheduler is not available",
+		}
+	}
+	scheduler.SetWorkers(int(c.Workers))
+	return nil, nil
+}
+
 // Text used to signify that a signed message follows and to prevent
 // inadvertently signing a transaction.
 const messageSignatureHeader = "Bitcoin Signed Message:\n"
@@ -4399,16 +5720,231 @@ func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 
 	// Process this block using the same rules as blocks coming from other
 	// nodes.  This will in turn relay it to the network like normal.
-	_, err = s.cfg.SyncMgr.SubmitBlock(block, blockchain.BFNone)
+	isOrphan, err := s.cfg.SyncMgr.SubmitBlock(block, blockchain.BFNone)
 	if err != nil {
 		rpcsLog.Infof("Rejected block %s via submitblock: %s", block.Hash(), err)
 		return fmt.Sprintf("rejected: %s", err.Error()), nil
 	}
 
+	// Blocks submitted before their parent arrives are kept in the orphan
+	// pool and connected once the parent, which is requested from the
+	// peers, arrives.
+	if isOrphan {
+		rpcsLog.Infof("Accepted orphan block %s via submitblock, "+
+			"requesting its parents", block.Hash())
+		return nil, nil
+	}
+
 	rpcsLog.Infof("Accepted block %s via submitblock", block.Hash())
 	return nil, nil
 }
 
+// handleSubmitPackage implements the submitpackage command.
+func handleSubmitPackage(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SubmitPackageCmd)
+
+	if len(c.RawTxns) == 0 || len(c.RawTxns) > mempool.MaxPackageCount {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Array must contain between 1 and "+
+				"%d transactions", mempool.MaxPackageCount),
+		}
+	}
+
+	txns := make([]*btcutil.Tx, 0, len(c.RawTxns))
+	for _, hexStr := range c.RawTxns {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		serializedTx, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		var msgTx wire.MsgTx
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDeserialization,
+				Message: "TX decode failed: " + err.Error(),
+			}
+		}
+		txns = append(txns, btcutil.NewTx(&msgTx))
+	}
+
+	acceptedTxs, err := s.cfg.TxMemPool.ProcessPackage(txns, false)
+	if err != nil {
+		if _, ok := err.(mempool.RuleError); !ok {
+			rpcsLog.Errorf("Failed to process package: %v", err)
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCTxError,
+				Message: "Package rejected: " + err.Error(),
+			}
+		}
+
+		rpcsLog.Debugf("Rejected package: %v", err)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCTxRejected,
+			Message: "Package rejected: " + err.Error(),
+		}
+	}
+
+	s.cfg.ConnMgr.RelayTransactions(acceptedTxs)
+	s.NotifyNewTransactions(acceptedTxs)
+
+	// The package transactions newly added to the pool come first in the
+	// accepted list and their combined fee rate is the effective fee rate
+	// of each of them.
+	inPackage := make(map[chainhash.Hash]struct{}, len(txns))
+	for _, tx := range txns {
+		inPackage[*tx.Hash()] = struct{}{}
+	}
+	var pkgFee, pkgSize int64
+	var effectiveIncludes []string
+	for _, txD := range acceptedTxs {
+		if _, ok := inPackage[*txD.Tx.Hash()]; !ok {
+			continue
+		}
+		pkgFee += txD.Fee
+		pkgSize += mempool.GetTxVirtualSize(txD.Tx)
+		effectiveIncludes = append(effectiveIncludes,
+			txD.Tx.WitnessHash().String())
+
+		// Keep track of the submitted transactions so that they can be
+		// rebroadcast if they don't make their way into a block.
+		s.cfg.TxMemPool.AddUnbroadcastTx(txD.Tx.Hash())
+	}
+
+	result := &btcjson.SubmitPackageResult{
+		PackageMsg: "success",
+		TxResults:  make(map[string]btcjson.SubmitPackageTxResult, len(txns)),
+	}
+	for _, tx := range txns {
+		txResult := btcjson.SubmitPackageTxResult{
+			Txid:  tx.Hash().String(),
+			Vsize: int32(mempool.GetTxVirtualSize(tx)),
+		}
+		for _, txD := range acceptedTxs {
+			if txD.Tx.Hash().IsEqual(tx.Hash()) {
+				txResult.Fees = &btcjson.TestMempoolAcceptFees{
+					Base: btcutil.Amount(txD.Fee).ToBTC(),
+					EffectiveFeeRate: btcutil.Amount(pkgFee).ToBTC() *
+						1000 / float64(pkgSize),
+					EffectiveIncludes: effectiveIncludes,
+				}
+				break
+			}
+		}
+		result.TxResults[tx.WitnessHash().String()] = txResult
+	}
+
+	return result, nil
+}
+
+// maxTestMempoolAcceptTxns is the maximum number of raw transactions that may
+// be passed to a single testmempoolaccept request.
+const maxTestMempoolAcceptTxns = 25
+
+// handleTestMempoolAccept implements the testmempoolaccept command.
+func handleTestMempoolAccept(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.TestMempoolAcceptCmd)
+
+	// Limit the number of txns that can be included in a single request.
+	if len(c.RawTxns) > maxTestMempoolAcceptTxns {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Array must contain between 1 and "+
+				"%d transactions", maxTestMempoolAcceptTxns),
+		}
+	}
+	if len(c.RawTxns) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Array must contain at least one transaction",
+		}
+	}
+
+	var maxFeeRate float64
+	if c.MaxFeeRate != nil {
+		maxFeeRate = *c.MaxFeeRate
+	}
+	if maxFeeRate < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "maxfeerate cannot be negative",
+		}
+	}
+
+	// Decode every transaction up front so a malformed entry rejects the
+	// entire request, matching the behavior of sendrawtransaction.
+	txns := make([]*btcutil.Tx, 0, len(c.RawTxns))
+	for _, hexStr := range c.RawTxns {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		serializedTx, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		var msgTx wire.MsgTx
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDeserialization,
+				Message: "TX decode failed: " + err.Error(),
+			}
+		}
+		txns = append(txns, btcutil.NewTx(&msgTx))
+	}
+
+	results := make([]*btcjson.TestMempoolAcceptResult, 0, len(txns))
+	acceptResults, errs := s.cfg.TxMemPool.CheckPackageAcceptance(txns)
+	for i, tx := range txns {
+		result := &btcjson.TestMempoolAcceptResult{
+			Txid:  tx.Hash().String(),
+			Wtxid: tx.WitnessHash().String(),
+		}
+		results = append(results, result)
+
+		acceptResult, err := acceptResults[i], errs[i]
+		switch {
+		case err != nil:
+			if _, ok := err.(mempool.RuleError); !ok {
+				rpcsLog.Errorf("Failed to check transaction %v: %v",
+					tx.Hash(), err)
+				return nil, internalRPCError(err.Error(),
+					"Failed to check transaction")
+			}
+			result.RejectReason = err.Error()
+			continue
+
+		case acceptResult == nil:
+			result.PackageError = "package-parent-rejected"
+			continue
+
+		case len(acceptResult.MissingParents) > 0:
+			result.RejectReason = "missing-inputs"
+			continue
+		}
+
+		feeRate := acceptResult.TxFee.ToBTC() * 1000 /
+			float64(acceptResult.TxSize)
+		if maxFeeRate != 0 && feeRate > maxFeeRate {
+			result.RejectReason = "max-fee-exceeded"
+			continue
+		}
+
+		result.Allowed = true
+		result.Vsize = int32(acceptResult.TxSize)
+		result.Fees = &btcjson.TestMempoolAcceptFees{
+			Base:              acceptResult.TxFee.ToBTC(),
+			EffectiveFeeRate:  feeRate,
+			EffectiveIncludes: []string{result.Wtxid},
+		}
+	}
+
+	return results, nil
+}
+
 // handleUptime implements the uptime command.
 func handleUptime(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	return time.Now().Unix() - s.cfg.StartupTime, nil
@@ -4513,6 +6049,37 @@ func handleVerifyChain(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return err == nil, nil
 }
 
+// handleVerifyDB implements the verifydb command.
+func handleVerifyDB(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.VerifyDBCmd)
+
+	if c.Scan != nil && *c.Scan {
+		s.cfg.DBScrubber.Scan()
+	}
+
+	status := s.cfg.DBScrubber.Status()
+	result := &btcjson.VerifyDBResult{
+		Running:       status.running,
+		Scans:         status.scans,
+		BlocksChecked: status.checked,
+		Corrupt:       make([]string, 0, len(status.corrupt)),
+		Repaired:      make([]string, 0, len(status.repaired)),
+	}
+	if !status.lastStart.IsZero() {
+		result.LastScanStart = status.lastStart.Unix()
+	}
+	if !status.lastEnd.IsZero() {
+		result.LastScanEnd = status.lastEnd.Unix()
+	}
+	for _, hash := range status.corrupt {
+		result.Corrupt = append(result.Corrupt, hash.String())
+	}
+	for _, hash := range status.repaired {
+		result.Repaired = append(result.Repaired, hash.String())
+	}
+	return result, nil
+}
+
 // handleVerifyMessage implements the verifymessage command.
 func handleVerifyMessage(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.VerifyMessageCmd)
@@ -4598,15 +6165,18 @@ type rpcServer struct {
 	cfg                    rpcserverConfig
 	authsha                [sha256.Size]byte
 	limitauthsha           [sha256.Size]byte
+	accounts               []*rpcAccount
 	ntfnMgr                *wsNotificationManager
 	numClients             int32
 	statusLines            map[int]string
 	statusLock             sync.RWMutex
 	wg                     sync.WaitGroup
 	gbtWorkState           *gbtWorkState
+	utxoScan               *utxoScanState
 	helpCacher             *helpCacher
 	requestProcessShutdown chan struct{}
 	feeEstimator           *fees.Estimator
+	rpcStats               *rpcStats
 	quit                   chan int
 }
 
@@ -4704,7 +6274,8 @@ func (s *rpcServer) NotifyNewTransactions(txns []*mempool.TxDesc) {
 
 		// Potentially notify any getblocktemplate long poll clients
 		// about stale block templates due to the new transaction.
-		s.gbtWorkState.NotifyMempoolTx(s.cfg.TxMemPool.LastUpdated())
+		s.gbtWorkState.NotifyMempoolTx(s.cfg.TxMemPool.LastUpdated(),
+			txD.Fee)
 	}
 }
 
@@ -4752,17 +6323,26 @@ func (s *rpcServer) decrementClients() {
 // The first bool return value signifies auth success (true if successful) and
 // the second bool return value specifies whether the user can change the state
 // of the server (true) or whether the user is limited (false). The second is
-// always false if the first is.
-func (s *rpcServer) checkAuth(r *http.Request, require bool) (bool, bool, error) {
-	authhdr := r.Header["Authorization"]
+// always false if the first is.  The map returned is the set of methods the
+// user is restricted to when authenticated with the credentials of an RPC
+// account, and is nil otherwise.
+func (s *rpcServer) checkAuth(r *http.Request, require bool) (bool, bool, map[string]struct{}, error) {
+	return s.checkAuthHeader(r.Header["Authorization"], r.RemoteAddr, require)
+}
+
+// checkAuthHeader checks the passed values of the Authorization header sent by
+// the client at remoteAddr.  It returns the same values as checkAuth.
+func (s *rpcServer) checkAuthHeader(authhdr []string, remoteAddr string,
+	require bool) (bool, bool, map[string]struct{}, error) {
+
 	if len(authhdr) <= 0 {
 		if require {
 			rpcsLog.Warnf("RPC authentication failure from %s",
-				r.RemoteAddr)
-			return false, false, errors.New("auth failure")
+				remoteAddr)
+			return false, false, nil, errors.New("auth failure")
 		}
 
-		return false, false, nil
+		return false, false, nil, nil
 	}
 
 	authsha := sha256.Sum256([]byte(authhdr[0]))
@@ -4771,18 +6351,24 @@ func (s *rpcServer) checkAuth(r *http.Request, require bool) (bool, bool, error)
 	// are probably expected to have a higher volume of calls
 	limitcmp := subtle.ConstantTimeCompare(authsha[:], s.limitauthsha[:])
 	if limitcmp == 1 {
-		return true, false, nil
+		return true, false, nil, nil
 	}
 
 	// Check for admin-level auth
 	cmp := subtle.ConstantTimeCompare(authsha[:], s.authsha[:])
 	if cmp == 1 {
-		return true, true, nil
+		return true, true, nil, nil
+	}
+
+	// Accounts may call any of the methods of their access lists, which
+	// are enforced separately from the limited methods.
+	if methods, ok := s.accountMethods(&authsha); ok {
+		return true, true, methods, nil
 	}
 
-	// Request's auth doesn't match either user
-	rpcsLog.Warnf("RPC authentication failure from %s", r.RemoteAddr)
-	return false, false, errors.New("auth failure")
+	// Request's auth doesn't match any user
+	rpcsLog.Warnf("RPC authentication failure from %s", remoteAddr)
+	return false, false, nil, errors.New("auth failure")
 }
 
 // parsedRPCCmd represents a JSON-RPC request object that has been parsed into
@@ -4794,6 +6380,10 @@ type parsedRPCCmd struct {
 	method  string
 	cmd     interface{}
 	err     *btcjson.RPCError
+
+	// client is the host of the client which sent the request, which the
+	// request is traced with.
+	client string
 }
 
 // standardCmdResult checks that a parsed command is a standard Bitcoin JSON-RPC
@@ -4818,7 +6408,22 @@ func (s *rpcServer) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct
 	return nil, btcjson.ErrRPCMethodNotFound
 handled:
 
-	return handler(s, cmd.cmd, closeChan)
+	start := time.Now()
+	result, err := handler(s, cmd.cmd, closeChan)
+	s.traceRequest(cmd, start, err)
+	return result, err
+}
+
+// traceRequest records the trace of the passed request, which started being
+// served at the passed time and failed with the passed error if not nil.
+func (s *rpcServer) traceRequest(cmd *parsedRPCCmd, start time.Time, err error) {
+	s.rpcStats.record(rpcTrace{
+		method:   cmd.method,
+		client:   cmd.client,
+		start:    start,
+		duration: time.Since(start),
+		err:      err,
+	})
 }
 
 // parseCmd parses a JSON-RPC request object into known concrete command.  The
@@ -4871,13 +6476,22 @@ func createMarshalledReply(rpcVersion btcjson.RPCVersion, id interface{}, result
 }
 
 // processRequest determines the incoming request type (single or batched),
-// parses it and returns a marshalled response.
-func (s *rpcServer) processRequest(request *btcjson.Request, isAdmin bool, closeChan <-chan struct{}) []byte {
+// parses it and returns a marshalled response.  When methods is not nil, only
+// the methods it contains may be called regardless of isAdmin.  The request is
+// traced with the passed client host.
+func (s *rpcServer) processRequest(request *btcjson.Request, client string,
+	isAdmin bool, methods map[string]struct{},
+	closeChan <-chan struct{}) []byte {
+
 	var result interface{}
 	var err error
 	var jsonErr *btcjson.RPCError
 
-	if !isAdmin {
+	if methods != nil {
+		if _, ok := methods[request.Method]; !ok {
+			jsonErr = btcjson.ErrRPCMethodNotFound
+		}
+	} else if !isAdmin {
 		if _, ok := rpcLimited[request.Method]; !ok {
 			jsonErr = internalRPCError("limited user not "+
 				"authorized for this method", "")
@@ -4907,6 +6521,7 @@ func (s *rpcServer) processRequest(request *btcjson.Request, isAdmin bool, close
 		// Attempt to parse the JSON-RPC request into a known
 		// concrete command.
 		parsedCmd := parseCmd(request)
+		parsedCmd.client = client
 		if parsedCmd.err != nil {
 			jsonErr = parsedCmd.err
 		} else {
@@ -4934,8 +6549,11 @@ func (s *rpcServer) processRequest(request *btcjson.Request, isAdmin bool, close
 	return msg
 }
 
-// jsonRPCRead handles reading and responding to RPC messages.
-func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin bool) {
+// jsonRPCRead handles reading and responding to RPC messages.  The methods
+// parameter restricts the methods that may be called as for processRequest.
+func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request,
+	isAdmin bool, methods map[string]struct{}) {
+
 	if atomic.LoadInt32(&s.shutdown) != 0 {
 		return
 	}
@@ -4989,6 +6607,7 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 	var results []json.RawMessage
 	var batchSize int
 	var batchedRequest bool
+	client := rpcClientHost(r.RemoteAddr)
 
 	// Determine request type
 	if bytes.HasPrefix(body, batchedRequestPrefix) {
@@ -5034,7 +6653,8 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 			if req.ID == nil && !(cfg.RPCQuirks && req.Jsonrpc == "") {
 				return
 			}
-			resp = s.processRequest(&req, isAdmin, closeChan)
+			resp = s.processRequest(&req, client, isAdmin,
+				methods, closeChan)
 		}
 
 		if resp != nil {
@@ -5123,7 +6743,8 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 						continue
 					}
 
-					resp = s.processRequest(&req, isAdmin, closeChan)
+					resp = s.processRequest(&req, client,
+						isAdmin, methods, closeChan)
 					if resp != nil {
 						results = append(results, resp)
 					}
@@ -5208,19 +6829,43 @@ func (s *rpcServer) Start() {
 		// Keep track of the number of connected clients.
 		s.incrementClients()
 		defer s.decrementClients()
-		_, isAdmin, err := s.checkAuth(r, true)
+		_, isAdmin, methods, err := s.checkAuth(r, true)
 		if err != nil {
 			jsonAuthFail(w)
 			return
 		}
 
 		// Read and respond to the request.
-		s.jsonRPCRead(w, r, isAdmin)
+		s.jsonRPCRead(w, r, isAdmin, methods)
 	})
 
+	// REST endpoints, which are authenticated like the JSON-RPC requests
+	// unless the REST interface is enabled.
+	for i := range restHandlers {
+		h := &restHandlers[i]
+		rpcServeMux.HandleFunc(h.path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Connection", "close")
+			r.Close = true
+
+			// Limit the number of connections to max allowed.
+			if s.limitConnections(w, r.RemoteAddr) {
+				return
+			}
+
+			// Keep track of the number of connected clients.
+			s.incrementClients()
+			defer s.decrementClients()
+			if !cfg.REST && !s.checkRESTAuth(w, r, h) {
+				return
+			}
+
+			s.handleREST(w, r, h)
+		})
+	}
+
 	// Websocket endpoint.
 	rpcServeMux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		authenticated, isAdmin, err := s.checkAuth(r, false)
+		authenticated, isAdmin, methods, err := s.checkAuth(r, false)
 		if err != nil {
 			jsonAuthFail(w)
 			return
@@ -5237,7 +6882,8 @@ func (s *rpcServer) Start() {
 			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
 			return
 		}
-		s.WebsocketHandler(ws, r.RemoteAddr, authenticated, isAdmin)
+		s.WebsocketHandler(ws, r.RemoteAddr, authenticated, isAdmin,
+			methods)
 	})
 
 	for _, listener := range s.cfg.Listeners {
@@ -5293,9 +6939,18 @@ type rpcserverPeer interface {
 	// the peer is to being banned.
 	BanScore() uint32
 
+	// MisbehaviorScores returns the current scores of the categories of
+	// violations of the peer keyed by their names, including the ones which
+	// don't count toward the ban score.
+	MisbehaviorScores() map[string]uint32
+
 	// FeeFilter returns the requested current minimum fee rate for which
 	// transactions should be announced.
 	FeeFilter() int64
+
+	// BloomWork returns the number of bytes hashed to match the bloom
+	// filters loaded by the peer.
+	BloomWork() uint64
 }
 
 // rpcserverConnManager represents a connection manager for use with the RPC
@@ -5338,19 +6993,25 @@ type rpcserverConnManager interface {
 	// network for all peers.
 	NetTotals() (uint64, uint64)
 
+	// NetTotalsPerMsg returns the bytes received and sent across the
+	// network for all peers by message command.
+	NetTotalsPerMsg() (map[string]uint64, map[string]uint64)
+
 	// ConnectedPeers returns an array consisting of all connected peers.
 	ConnectedPeers() []rpcserverPeer
 
-	// BannedPeers returns an array consisting of all Banned peers.
+	// BannedPeers returns a map consisting of all banned addresses and
+	// subnets with their banned period.
 	BannedPeers() map[string]bannedPeriod
 
-	// SetBan add the addr to the ban list.
-	SetBan(addr string, since time.Time, until time.Time) error
+	// SetBan bans the subnet for the given period and disconnects all
+	// connected peers within it.
+	SetBan(subnet *net.IPNet, since time.Time, until time.Time) error
 
-	// RemoveBan remove the subnet from the ban list.
-	RemoveBan(subnet string) error
+	// RemoveBan removes the subnet from the ban list.
+	RemoveBan(subnet *net.IPNet) error
 
-	// ClearBanned removes all banned IPs.
+	// ClearBanned removes all bans.
 	ClearBanned() error
 
 	// PersistentPeers returns an array consisting of all the persistent
@@ -5391,10 +7052,26 @@ type rpcserverSyncManager interface {
 	// Pause pauses the sync manager until the returned channel is closed.
 	Pause() chan<- struct{}
 
+	// ResetSync restarts the sync from the current best block after the
+	// chain state was replaced.
+	ResetSync()
+
+	// FetchBlock requests the block of the passed hash from the passed
+	// peer, which is stored once received when its data was pruned.
+	FetchBlock(hash *chainhash.Hash, p *peer.Peer) error
+
 	// SyncPeerID returns the ID of the peer that is currently the peer being
 	// used to sync from or 0 if there is none.
 	SyncPeerID() int32
 
+	// PeerSyncStats returns the block download statistics of the peers
+	// keyed by their IDs.
+	PeerSyncStats() map[int32]netsync.PeerSyncStats
+
+	// TimeRemaining returns an estimate of the time left to sync the chain
+	// or zero when there is no estimate.
+	TimeRemaining() time.Duration
+
 	// LocateHeaders returns the headers of the blocks after the first known
 	// block in the provided locators until the provided stop hash or the
 	// current tip is reached, up to a max of wire.MaxBlockHeadersPerMsg
@@ -5446,16 +7123,28 @@ type rpcserverConfig struct {
 
 	// These fields define any optional indexes the RPC server can make use
 	// of to provide additional data when queried.
-	TxIndex   *indexers.TxIndex
-	AddrIndex *indexers.AddrIndex
-	CfIndex   *indexers.CfIndex
+	TxIndex          *indexers.TxIndex
+	AddrIndex        *indexers.AddrIndex
+	CfIndex          *indexers.CfIndex
+	ClaimIDIndex     *indexers.ClaimIDIndex
+	ChannelIndex     *indexers.ClaimChannelIndex
+	SpentIndex       *indexers.SpentIndex
+	ClaimFilterIndex *indexers.ClaimFilterIndex
 
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
 	FeeEstimator *fees.Estimator
 
+	// DBScrubber verifies the blocks stored in the database in the
+	// background.
+	DBScrubber *dbScrubber
+
 	// Services represents the services supported by this node.
 	Services wire.ServiceFlag
+
+	// PortMapper maps the listening port on the NAT gateway of the local
+	// network.  It is nil when NAT-PMP and UPnP are disabled.
+	PortMapper *portmap.Manager
 }
 
 // newRPCServer returns a new instance of the rpcServer struct.
@@ -5463,10 +7152,12 @@ func newRPCServer(config *rpcserverConfig) (*rpcServer, error) {
 	rpc := rpcServer{
 		cfg:                    *config,
 		statusLines:            make(map[int]string),
-		gbtWorkState:           newGbtWorkState(config.TimeSource),
+		gbtWorkState:           newGbtWorkState(config.TimeSource, cfg.blockTmplFeeDelta),
+		utxoScan:               newUtxoScanState(),
 		helpCacher:             newHelpCacher(),
 		requestProcessShutdown: make(chan struct{}),
 		feeEstimator:           config.FeeEstimator,
+		rpcStats:               newRPCStats(cfg.RPCTraceSize),
 		quit:                   make(chan int),
 	}
 	if cfg.RPCUser != "" && cfg.RPCPass != "" {
@@ -5479,6 +7170,7 @@ func newRPCServer(config *rpcserverConfig) (*rpcServer, error) {
 		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
 		rpc.limitauthsha = sha256.Sum256([]byte(auth))
 	}
+	rpc.accounts = cfg.rpcAccounts
 	rpc.ntfnMgr = newWsNotificationManager(&rpc)
 	rpc.cfg.Chain.Subscribe(rpc.handleBlockchainNotification)
 
@@ -5508,8 +7200,24 @@ func (s *rpcServer) handleBlockchainNotification(notification *blockchain.Notifi
 			break
 		}
 
-		// Notify registered websocket clients of incoming block.
-		s.ntfnMgr.NotifyBlockConnected(block)
+		// Notify registered websocket clients of incoming block.  The
+		// names changed by the block are collected now rather than when
+		// the clients are notified, by which time a reorganization may
+		// have replaced the block, but only when a client registered for
+		// claim trie updates.
+		var names []string
+		if s.ntfnMgr.HasClaimTrieClients() {
+			var err error
+			names, err = s.cfg.Chain.GetNamesChangedInBlock(block.Height())
+			if err != nil {
+				rpcsLog.Errorf("Failed to load names changed in "+
+					"block %v: %v", block.Hash(), err)
+			}
+			if names == nil {
+				names = []string{}
+			}
+		}
+		s.ntfnMgr.NotifyBlockConnected(block, names)
 
 	case blockchain.NTBlockDisconnected:
 		block, ok := notification.Data.(*btcutil.Block)
@@ -29,11 +29,42 @@ var helpDescsEnUS = map[string]string{
 	"debuglevel--result0":    "The string 'Done.'",
 	"debuglevel--result1":    "The list of subsystems",
 
+	// DebugScriptCmd help.
+	"debugscript--synopsis": "Executes the scripts of an input of a transaction and returns the state of the script engine after each opcode, " +
+		"which shows why the input fails the standardness or consensus checks.",
+	"debugscript-hextx":        "Serialized, hex-encoded transaction",
+	"debugscript-index":        "The index of the input to execute",
+	"debugscript-scriptpubkey": "The hex-encoded public key script of the output spent by the input, looked up in the mempool and the utxo set by default",
+	"debugscript-amount":       "The amount in LBC of the output spent by the input, only used by witness inputs",
+	"debugscript-standard":     "Apply the standardness checks of the mempool instead of the consensus checks only",
+
+	// DebugScriptResult help.
+	"debugscriptresult-valid": "Whether the input is valid",
+	"debugscriptresult-error": "The reason the input is invalid",
+	"debugscriptresult-steps": "The opcodes executed with the state of the script engine after each",
+
+	// DebugScriptStep help.
+	"debugscriptstep-script":   "The index of the script of the opcode: 0 for the signature script, 1 for the public key script and higher for redeem and witness scripts",
+	"debugscriptstep-opcode":   "The index of the opcode in its script",
+	"debugscriptstep-asm":      "The disassembly of the opcode",
+	"debugscriptstep-stack":    "The hex-encoded items of the data stack after the opcode, the top of the stack last",
+	"debugscriptstep-altstack": "The hex-encoded items of the alternate stack after the opcode, the top of the stack last",
+	"debugscriptstep-error":    "The error the opcode failed with",
+
 	// AddNodeCmd help.
 	"addnode--synopsis": "Attempts to add or remove a persistent peer.",
 	"addnode-addr":      "IP address and port of the peer to operate on",
 	"addnode-subcmd":    "'add' to add a persistent peer, 'remove' to remove a persistent peer, or 'onetry' to try a single connection to a peer",
 
+	// BackupChainStateCmd help.
+	"backupchainstate--synopsis": "Writes a consistent snapshot of the block database, both its metadata and its block files, to a tar archive while the node keeps running.\n" +
+		"The archive can be extracted into an empty directory to restore the database.  The claim trie isn't part of the backup and is rewound or rebuilt on start up to match the restored chain.",
+	"backupchainstate-destination": "The path of the archive, relative to the data directory unless absolute, which must not exist yet",
+
+	// BackupChainStateResult help.
+	"backupchainstateresult-path": "The path of the archive",
+	"backupchainstateresult-size": "The size of the archive in bytes",
+
 	// NodeCmd help.
 	"node--synopsis":     "Attempts to add or remove a peer.",
 	"node-subcmd":        "'disconnect' to remove all matching non-persistent peers, 'remove' to remove a persistent peer, or 'connect' to connect to a peer",
@@ -57,7 +88,7 @@ var helpDescsEnUS = map[string]string{
 	"createrawtransaction--result0":       "Hex-encoded bytes of the serialized transaction",
 
 	// ClearBannedCmd help.
-	"clearbanned--synopsis": "Clear all banned IPs.",
+	"clearbanned--synopsis": "Clear all banned IPs and subnets.",
 
 	// ScriptSig help.
 	"scriptsig-asm": "Disassembly of the script",
@@ -83,6 +114,13 @@ var helpDescsEnUS = map[string]string{
 	"vin-scriptSig":   "The signature script used to redeem the origin transaction as a JSON object (non-coinbase txns only)",
 	"vin-txinwitness": "The witness used to redeem the input encoded as a string array of its items",
 	"vin-sequence":    "The script sequence number",
+	"vin-prevout":     "The output spent by the input (getblock verbosity 3 and getrawtransaction only)",
+
+	// VinPrevOutResult help.
+	"vinprevoutresult-generated":    "Whether the output was created by a coinbase transaction",
+	"vinprevoutresult-height":       "The height of the block containing the transaction which created the output, when known",
+	"vinprevoutresult-value":        "The amount in LBC",
+	"vinprevoutresult-scriptPubKey": "The public key script of the output as a JSON object",
 
 	// ScriptPubKeyResult help.
 	"scriptpubkeyresult-asm":       "Disassembly of the script",
@@ -97,6 +135,51 @@ var helpDescsEnUS = map[string]string{
 	"vout-value":        "The amount in LBC",
 	"vout-n":            "The index of this transaction output",
 	"vout-scriptPubKey": "The public key script used to pay coins as a JSON object",
+	"vout-claim":        "The claim, update or support created by the output",
+
+	// VoutClaimResult help.
+	"voutclaimresult-name":     "The name of the claim",
+	"voutclaimresult-claimid":  "The ID of the claim",
+	"voutclaimresult-value":    "The hex-encoded value of the claim (supports may have none), unless its metadata is decoded",
+	"voutclaimresult-metadata": "The metadata decoded from the value of the claim when --decodeclaims is enabled",
+	"voutclaimresult-timelock": "The lock time of the script the claim is paid to, which prevents the claim from being updated or abandoned until then",
+
+	// ClaimTimeLockResult help.
+	"claimtimelockresult-type":     "The type of the lock time (checklocktimeverify or checksequenceverify)",
+	"claimtimelockresult-locktime": "The absolute lock time for checklocktimeverify, or the relative lock time encoded like input sequence numbers for checksequenceverify",
+
+	// ClaimMetadataResult help.
+	"claimmetadataresult-type":             "The type of the claim (stream, channel, collection or repost)",
+	"claimmetadataresult-signingchannelid": "The claim ID of the channel which signed the claim (the signature isn't verified)",
+	"claimmetadataresult-title":            "The title of the claim",
+	"claimmetadataresult-description":      "The description of the claim",
+	"claimmetadataresult-thumbnail":        "The URL of the thumbnail of the claim",
+	"claimmetadataresult-tags":             "The tags of the claim",
+	"claimmetadataresult-stream":           "The metadata of the file of a stream",
+	"claimmetadataresult-channel":          "The metadata of a channel",
+	"claimmetadataresult-collection":       "The claim IDs of the claims in a collection",
+	"claimmetadataresult-repostedclaimid":  "The claim ID of the claim reposted by a repost",
+
+	// StreamMetadataResult help.
+	"streammetadataresult-mediatype":   "The media type of the file",
+	"streammetadataresult-filename":    "The name of the file",
+	"streammetadataresult-filesize":    "The size of the file in bytes",
+	"streammetadataresult-author":      "The author of the content",
+	"streammetadataresult-license":     "The license of the content",
+	"streammetadataresult-licenseurl":  "The URL of the license of the content",
+	"streammetadataresult-releasetime": "The release time of the content in seconds since 1 Jan 1970 GMT",
+	"streammetadataresult-fee":         "The price asked to download the file",
+
+	// ClaimFeeResult help.
+	"claimfeeresult-currency": "The currency of the fee (LBC, BTC or USD)",
+	"claimfeeresult-address":  "The address the fee is paid to",
+	"claimfeeresult-amount":   "The amount of the fee in the currency",
+
+	// ChannelMetadataResult help.
+	"channelmetadataresult-publickey":  "The hex-encoded public key of the channel",
+	"channelmetadataresult-email":      "The email of the channel",
+	"channelmetadataresult-websiteurl": "The URL of the website of the channel",
+	"channelmetadataresult-cover":      "The URL of the cover image of the channel",
 
 	// TxRawDecodeResult help.
 	"txrawdecoderesult-txid":     "The hash of the transaction",
@@ -120,6 +203,18 @@ var helpDescsEnUS = map[string]string{
 	"decodescript--synopsis": "Returns a JSON object with information about the provided hex-encoded script.",
 	"decodescript-hexscript": "Hex-encoded script",
 
+	// DumpTxOutSetCmd help.
+	"dumptxoutset--synopsis": "Write a snapshot of the unspent transaction output set and the claim trie at the best block to a file on the server.\n" +
+		"Another node can load the snapshot with loadtxoutset once the block and MuHash reported here are added to its assumed utxo sets with --addassumeutxo.",
+	"dumptxoutset-path": "The file to write the snapshot to, relative to the data directory unless absolute; it must not exist yet",
+
+	// DumpTxOutSetResult help.
+	"dumptxoutsetresult-coins_written": "The number of unspent transaction outputs written",
+	"dumptxoutsetresult-base_hash":     "The hash of the block the snapshot is for",
+	"dumptxoutsetresult-base_height":   "The height of the block the snapshot is for",
+	"dumptxoutsetresult-path":          "The path of the snapshot file",
+	"dumptxoutsetresult-muhash":        "The MuHash3072 of the unspent transaction output set",
+
 	// EstimateFeeCmd help.
 	"estimatefee--synopsis": "Estimate the fee per kilobyte in satoshis " +
 		"required for a transaction to be mined before a certain number of " +
@@ -129,14 +224,21 @@ var helpDescsEnUS = map[string]string{
 	"estimatefee--result0": "Estimated fee per kilobyte in satoshis necessary for a block to " +
 		"be mined in the next NumBlocks blocks.",
 
-	"estimatesmartfee--synopsis": "Estimate the fee per kilobyte in satoshis " +
-		"required for a transaction to be mined before a certain number of " +
-		"blocks have been generated. Same as estimatefee presently.",
+	"estimatesmartfee--synopsis": "Estimate the fee per kilobyte required for a " +
+		"transaction to be mined within a certain number of blocks, based on " +
+		"the confirmation times of the transactions seen in the memory pool.",
 	"estimatesmartfee-conftarget": "The maximum number of blocks which can be " +
 		"generated before the transaction is mined.",
-	"estimatesmartfee-estimatemode": "Unused at present.",
-	"estimatesmartfee--result0": "Estimated fee per kilobyte in satoshis necessary for a block to " +
-		"be mined in the next ConfTarget blocks.",
+	"estimatesmartfee-estimatemode": "The estimation mode, either ECONOMICAL or " +
+		"CONSERVATIVE. Conservative estimates are less responsive to short term " +
+		"drops in fee rates.",
+
+	// EstimateSmartFeeResult help.
+	"estimatesmartfeeresult-feerate": "Estimated fee rate in LBC/kB, omitted " +
+		"when no estimate could be made",
+	"estimatesmartfeeresult-errors": "Errors encountered during processing",
+	"estimatesmartfeeresult-blocks": "The number of blocks the estimate is valid " +
+		"for, which may be higher than the requested target",
 
 	// GenerateCmd help
 	"generate--synopsis": "Generates a set number of blocks (simnet or regtest only) and returns a JSON\n" +
@@ -165,6 +267,26 @@ var helpDescsEnUS = map[string]string{
 	"getbestblockresult-hash":   "Hex-encoded bytes of the best block hash",
 	"getbestblockresult-height": "Height of the best block",
 
+	// GetAddressBalanceCmd help.
+	"getaddressbalance--synopsis": "Returns the confirmed balance of an address, including the amounts held in claim and support outputs.\n" +
+		"The address index must be enabled (--addrindex) and addresses with more than 100000 transactions are refused.",
+	"getaddressbalance-address": "The address to query",
+
+	// GetAddressBalanceResult help.
+	"getaddressbalanceresult-balance":  "The total amount of the unspent outputs paying to the address",
+	"getaddressbalanceresult-received": "The total amount ever received by the address",
+	"getaddressbalanceresult-claims":   "The portion of the balance held in claim outputs",
+	"getaddressbalanceresult-supports": "The portion of the balance held in support outputs",
+
+	// GetAddressTxIDsCmd help.
+	"getaddresstxids--synopsis": "Returns the hashes of transactions involving the passed address, including claim, support and update outputs.\n" +
+		"The address index must be enabled (--addrindex).",
+	"getaddresstxids-address":  "The address to query",
+	"getaddresstxids-skip":     "The number of leading transactions to leave out of the final response",
+	"getaddresstxids-count":    "The maximum number of transactions to return",
+	"getaddresstxids-reverse":  "Specifies that the transactions should be returned in reverse chronological order",
+	"getaddresstxids--result0": "The hashes of the transactions",
+
 	// GetBestBlockCmd help.
 	"getbestblock--synopsis": "Get block height and hash of best block in the main chain.",
 	"getbestblock--result0":  "Get block height and hash of best block in the main chain.",
@@ -176,7 +298,7 @@ var helpDescsEnUS = map[string]string{
 	// GetBlockCmd help.
 	"getblock--synopsis":   "Returns information about a block given its hash.",
 	"getblock-hash":        "The hash of the block",
-	"getblock-verbosity":   "Specifies whether the block data should be returned as a hex-encoded string (0), as parsed data with a slice of TXIDs (1), or as parsed data with parsed transaction data (2) ",
+	"getblock-verbosity":   "Specifies whether the block data should be returned as a hex-encoded string (0), as parsed data with a slice of TXIDs (1), as parsed data with parsed transaction data (2), or as parsed data with parsed transaction data including the outputs spent by the inputs (3)",
 	"getblock--condition0": "verbosity=0",
 	"getblock--condition1": "verbosity=1",
 	"getblock--result0":    "Hex-encoded bytes of the serialized block",
@@ -185,23 +307,24 @@ var helpDescsEnUS = map[string]string{
 	"getblockchaininfo--synopsis": "Returns information about the current blockchain state and the status of any active soft-fork deployments.",
 
 	// GetBlockChainInfoResult help.
-	"getblockchaininforesult-chain":                "The name of the chain the daemon is on (testnet, mainnet, etc)",
-	"getblockchaininforesult-blocks":               "The number of blocks in the best known chain",
-	"getblockchaininforesult-headers":              "The number of headers that we've gathered for in the best known chain",
-	"getblockchaininforesult-bestblockhash":        "The block hash for the latest block in the main chain",
-	"getblockchaininforesult-difficulty":           "The current chain difficulty",
-	"getblockchaininforesult-mediantime":           "The median time from the PoV of the best block in the chain",
-	"getblockchaininforesult-verificationprogress": "An estimate for how much of the best chain we've verified",
-	"getblockchaininforesult-pruned":               "A bool that indicates if the node is pruned or not",
-	"getblockchaininforesult-pruneheight":          "The lowest block retained in the current pruned chain",
-	"getblockchaininforesult-chainwork":            "The total cumulative work in the best chain",
-	"getblockchaininforesult-size_on_disk":         "The estimated size of the block and undo files on disk",
-	"getblockchaininforesult-initialblockdownload": "Estimate of whether this node is in Initial Block Download mode",
-	"getblockchaininforesult-softforks":            "The status of the super-majority soft-forks",
-	"getblockchaininforesult-unifiedsoftforks":     "The status of the super-majority soft-forks used by bitcoind on or after v0.19.0",
+	"getblockchaininforesult-chain":                  "The name of the chain the daemon is on (testnet, mainnet, etc)",
+	"getblockchaininforesult-blocks":                 "The number of blocks in the best known chain",
+	"getblockchaininforesult-headers":                "The number of headers that we've gathered for in the best known chain",
+	"getblockchaininforesult-bestblockhash":          "The block hash for the latest block in the main chain",
+	"getblockchaininforesult-difficulty":             "The current chain difficulty",
+	"getblockchaininforesult-mediantime":             "The median time from the PoV of the best block in the chain",
+	"getblockchaininforesult-verificationprogress":   "An estimate for how much of the best chain we've verified",
+	"getblockchaininforesult-pruned":                 "A bool that indicates if the node is pruned or not",
+	"getblockchaininforesult-pruneheight":            "The lowest block retained in the current pruned chain",
+	"getblockchaininforesult-chainwork":              "The total cumulative work in the best chain",
+	"getblockchaininforesult-size_on_disk":           "The estimated size of the block and undo files on disk",
+	"getblockchaininforesult-initialblockdownload":   "Estimate of whether this node is in Initial Block Download mode",
+	"getblockchaininforesult-estimatedtimeremaining": "The estimated number of seconds left in the Initial Block Download, omitted when there is no estimate",
+	"getblockchaininforesult-softforks":              "The status of the super-majority soft-forks",
+	"getblockchaininforesult-unifiedsoftforks":       "The status of the super-majority soft-forks used by bitcoind on or after v0.19.0",
 
 	// GetBlockStatsCmd help.
-	"getblockstats--synopsis":    "Returns statistics about a block given its hash or height. --txindex must be enabled for fee and feerate statistics.",
+	"getblockstats--synopsis":    "Returns statistics about a block given its hash or height. --txindex must be enabled for fee and feerate statistics of blocks not in the main chain.",
 	"getblockstats-hashorheight": "The hash or height of the block",
 	"hashorheight-value":         "The hash or height of the block",
 	"getblockstats-stats":        "Selected statistics",
@@ -235,7 +358,12 @@ var helpDescsEnUS = map[string]string{
 	"getblockstatsresult-totalfee":            "The total of fees",
 	"getblockstatsresult-txs":                 "The number of transactions (excluding coinbase)",
 	"getblockstatsresult-utxo_increase":       "The increase/decrease in the number of unspent outputs",
-	"getblockstatsresult-utxo_size_inc":       "The increase/decrease in size for the utxo index",
+	"getblockstatsresult-utxo_size_inc":       "The increase/decrease in size for the utxo index (main chain blocks only)",
+	"getblockstatsresult-claims":              "The number of new claims",
+	"getblockstatsresult-claimupdates":        "The number of claim updates",
+	"getblockstatsresult-supports":            "The number of new supports",
+	"getblockstatsresult-abandons":            "The number of abandoned claims and supports (main chain blocks only)",
+	"getblockstatsresult-claimnames":          "The distinct names of the new claims",
 
 	// SoftForkDescription help.
 	"softforkdescription-reject":  "The current activation status of the softfork",
@@ -314,6 +442,13 @@ var helpDescsEnUS = map[string]string{
 	"getblockcount--synopsis": "Returns the number of blocks in the longest block chain.",
 	"getblockcount--result0":  "The current block count",
 
+	// GetBlockFromPeerCmd help.
+	"getblockfrompeer--synopsis": "Requests a block known by its header from a peer, such as a block whose data was pruned.\n" +
+		"It returns once the request is sent, and the block is stored when it is received, so it can be queried without resyncing.\n" +
+		"The data of a stored block is subject to be pruned again.",
+	"getblockfrompeer-blockhash": "The hash of the block to fetch",
+	"getblockfrompeer-peerid":    "The id of the peer to fetch the block from, as listed by getpeerinfo",
+
 	// GetBlockHashCmd help.
 	"getblockhash--synopsis": "Returns hash of the block in best block chain at the given height.",
 	"getblockhash-index":     "The block height",
@@ -327,6 +462,15 @@ var helpDescsEnUS = map[string]string{
 	"getblockheader--condition1": "verbose=true",
 	"getblockheader--result0":    "The block header hash",
 
+	// GetBlockHeadersCmd help.
+	"getblockheaders--synopsis":    "Returns consecutive block headers of the main chain starting with the block of the given hash or height.",
+	"getblockheaders-hashorheight": "The hash or height of the first block",
+	"getblockheaders-count":        "The maximum number of headers to return, up to 2000 -- fewer are returned when the tip of the chain is reached",
+	"getblockheaders-verbose":      "Specifies the block headers are returned as JSON objects instead of a hex-encoded string",
+	"getblockheaders--condition0":  "verbose=false",
+	"getblockheaders--condition1":  "verbose=true",
+	"getblockheaders--result0":     "The concatenated serialized block headers",
+
 	// GetBlockHeaderVerboseResult help.
 	"getblockheaderverboseresult-hash":              "The hash of the block (same as provided)",
 	"getblockheaderverboseresult-confirmations":     "The number of confirmations",
@@ -353,6 +497,7 @@ var helpDescsEnUS = map[string]string{
 	"templaterequest-data":         "Hex-encoded block data (only for mode=proposal)",
 	"templaterequest-workid":       "The server provided workid if provided in block template (not applicable)",
 	"templaterequest-rules":        "Specific block rules that are to be enforced e.g. '[\"segwit\"]",
+	"templaterequest-verbose":      "Return an object describing the result of a proposal instead of a reject reason string (only for mode=proposal)",
 
 	// GetBlockTemplateResultTx help.
 	"getblocktemplateresulttx-data":    "Hex-encoded transaction data (byte-for-byte)",
@@ -391,6 +536,7 @@ var helpDescsEnUS = map[string]string{
 	"getblocktemplateresult-capabilities":               "List of server capabilities including 'proposal' to indicate support for block proposals",
 	"getblocktemplateresult-reject-reason":              "Reason the proposal was invalid as-is (only applies to proposal responses)",
 	"getblocktemplateresult-default_witness_commitment": "The witness commitment itself. Will be populated if the block has witness data",
+	"getblocktemplateresult-signet_challenge":           "The challenge blocks must be signed with as defined by BIP0325, only on signet networks",
 	"getblocktemplateresult-weightlimit":                "The current limit on the max allowed weight of a block",
 	"getblocktemplateresult-rules":                      "Rules that are required to process the output",
 	"getblocktemplateresult-claimtrie":                  "The hash of the root of the claim trie - a necessary block header",
@@ -403,6 +549,13 @@ var helpDescsEnUS = map[string]string{
 	"getblocktemplate--condition1": "mode=proposal, rejected",
 	"getblocktemplate--condition2": "mode=proposal, accepted",
 	"getblocktemplate--result1":    "An error string which represents why the proposal was rejected or nothing if accepted",
+	"getblocktemplate--condition3": "mode=proposal, verbose=true",
+
+	// GetBlockTemplateProposalResult help.
+	"getblocktemplateproposalresult-accepted": "Whether the proposal was accepted",
+	"getblocktemplateproposalresult-reason":   "The BIP0023 reason the proposal was rejected",
+	"getblocktemplateproposalresult-code":     "The code of the consensus rule the proposal violates",
+	"getblocktemplateproposalresult-message":  "A description of why the proposal was rejected",
 
 	// GetChainTips help.
 	"getchaintips--synopsis": "Returns information about all known chain tips the in the block tree.\n\n" +
@@ -412,6 +565,9 @@ var helpDescsEnUS = map[string]string{
 		"headers-only: The block or one of its ancestors does not have the full block data available which also means the block can't be validated or connected.\n" +
 		"valid-fork: The block is fully validated which implies it was probably part of the main chain at one point and was reorganized.\n" +
 		"valid-headers: The full block data is available and the header is valid, but the block was never validated which implies it was probably never part of the main chain.",
+	"getchaintips-verbose":     "Returns a JSON object including statistics about the stale forks of the block index when true or an array of chain tips when false",
+	"getchaintips--condition0": "verbose=false",
+	"getchaintips--condition1": "verbose=true",
 
 	// GetChainTipsResult help.
 	"getchaintipsresult-height":    "The height of the chain tip",
@@ -420,15 +576,23 @@ var helpDescsEnUS = map[string]string{
 	"getchaintipsresult-status":    "The status of the chain (active, invalid, headers-only, valid-fork, valid-headers)",
 	"getchaintipsresults--result0": "test",
 
+	// GetChainTipsVerboseResult help.
+	"getchaintipsverboseresult-tips":            "The known chain tips",
+	"getchaintipsverboseresult-indexnodes":      "The number of blocks in the block index",
+	"getchaintipsverboseresult-stalenodes":      "The number of blocks in the block index which are not part of the main chain",
+	"getchaintipsverboseresult-pruneforkdepth":  "The depth below the main chain tip beyond which stale forks are pruned from the block index (0 when disabled)",
+	"getchaintipsverboseresult-prunednodes":     "The number of stale fork blocks pruned from the block index since the start",
+	"getchaintipsverboseresult-lastpruneheight": "The main chain height when stale forks were last pruned",
+
 	// GetCFilterCmd help.
 	"getcfilter--synopsis":  "Returns a block's committed filter given its hash.",
-	"getcfilter-filtertype": "The type of filter to return (0=regular)",
+	"getcfilter-filtertype": "The type of filter to return (0=regular, 1=claim names, requires --claimfilterindex)",
 	"getcfilter-hash":       "The hash of the block",
 	"getcfilter--result0":   "The block's committed filter",
 
 	// GetCFilterHeaderCmd help.
 	"getcfilterheader--synopsis":  "Returns a block's compact filter header given its hash.",
-	"getcfilterheader-filtertype": "The type of filter header to return (0=regular)",
+	"getcfilterheader-filtertype": "The type of filter header to return (0=regular, 1=claim names, requires --claimfilterindex)",
 	"getcfilterheader-hash":       "The hash of the block",
 	"getcfilterheader--result0":   "The block's gcs filter header",
 
@@ -498,10 +662,23 @@ var helpDescsEnUS = map[string]string{
 	"getmempoolinforesult-bytes":            "Size in bytes of the mempool",
 	"getmempoolinforesult-size":             "Number of transactions in the mempool",
 	"getmempoolinforesult-usage":            "Total memory usage for the mempool",
+	"getmempoolinforesult-maxmempool":       "Maximum size in bytes of the mempool transactions, zero for no limit",
 	"getmempoolinforesult-total_fee":        "Total fees for the mempool in LBC, ignoring modified fees through prioritizetransaction",
 	"getmempoolinforesult-mempoolminfee":    "Minimum fee rate in LBC/kvB for tx to be accepted. Is the maximum of minrelaytxfee and minimum mempool fee",
 	"getmempoolinforesult-minrelaytxfee":    "Current minimum relay fee for transactions",
 	"getmempoolinforesult-unbroadcastcount": "Current number of transactions that haven't passed initial broadcast yet",
+	"getmempoolinforesult-policy":           "Effective policy deciding which transactions are accepted into the mempool",
+
+	// MempoolPolicyResult help.
+	"mempoolpolicyresult-acceptnonstd":       "Whether non-standard transactions are accepted",
+	"mempoolpolicyresult-maxtxversion":       "Maximum version of standard transactions",
+	"mempoolpolicyresult-dustrelayfee":       "Fee rate in LBC/kB used to determine dust outputs",
+	"mempoolpolicyresult-datacarriersize":    "Maximum number of bytes of data in standard null data (OP_RETURN) outputs",
+	"mempoolpolicyresult-maxclaimvaluesize":  "Maximum size in bytes of the values of standard claim scripts",
+	"mempoolpolicyresult-minclaimamount":     "Minimum amount in LBC of standard claim and update outputs, in addition to the dust threshold",
+	"mempoolpolicyresult-minsupportamount":   "Minimum amount in LBC of standard support outputs, in addition to the dust threshold",
+	"mempoolpolicyresult-permitbaremultisig": "Whether bare multi-signature outputs are standard",
+	"mempoolpolicyresult-rejectreplacement":  "Whether replacement transactions signaling Replace-By-Fee are rejected",
 
 	// GetMiningInfoResult help.
 	"getmininginforesult-blocks":             "Height of the latest best block",
@@ -522,7 +699,45 @@ var helpDescsEnUS = map[string]string{
 	"mempoolfees-ancestor":   "Modified fees (see above) of in-mempool ancestors (including this one) in LBC",
 	"mempoolfees-descendant": "modified fees (see above) of in-mempool descendants (including this one) in LBC",
 
+	// GetMempoolAncestorsCmd help.
+	"getmempoolancestors--synopsis":       "Returns the in-mempool ancestors of a transaction in the memory pool.",
+	"getmempoolancestors-txid":            "The hash of the transaction",
+	"getmempoolancestors-verbose":         "Returns JSON objects keyed by transaction hash when true or an array of transaction hashes when false",
+	"getmempoolancestors--condition0":     "verbose=false",
+	"getmempoolancestors--condition1":     "verbose=true",
+	"getmempoolancestors--result0":        "Array of transaction hashes",
+	"getmempoolancestors--result1--desc":  "Object of in-mempool ancestors",
+	"getmempoolancestors--result1--key":   "The hash of the transaction",
+	"getmempoolancestors--result1--value": "The mempool entry of the transaction, as returned by getmempoolentry",
+
+	// GetMempoolDescendantsCmd help.
+	"getmempooldescendants--synopsis":       "Returns the in-mempool descendants of a transaction in the memory pool.",
+	"getmempooldescendants-txid":            "The hash of the transaction",
+	"getmempooldescendants-verbose":         "Returns JSON objects keyed by transaction hash when true or an array of transaction hashes when false",
+	"getmempooldescendants--condition0":     "verbose=false",
+	"getmempooldescendants--condition1":     "verbose=true",
+	"getmempooldescendants--result0":        "Array of transaction hashes",
+	"getmempooldescendants--result1--desc":  "Object of in-mempool descendants",
+	"getmempooldescendants--result1--key":   "The hash of the transaction",
+	"getmempooldescendants--result1--value": "The mempool entry of the transaction, as returned by getmempoolentry",
+
 	// GetMempoolEntryCmd help.
+	// GetMempoolFeeHistogramCmd help.
+	"getmempoolfeehistogram--synopsis": "Returns the histogram of the fee rates of the transactions in the mempool, weighted by their virtual size.\n" +
+		"The cumulated virtual size of the buckets, from the highest fee rates, tells how deep in the mempool a fee rate is.",
+
+	// GetMempoolFeeHistogramResult help.
+	"getmempoolfeehistogramresult-buckets": "The buckets of fee rates with transactions, highest fee rates first",
+	"getmempoolfeehistogramresult-count":   "Number of transactions in the mempool",
+	"getmempoolfeehistogramresult-vsize":   "Total virtual size of the transactions in the mempool",
+
+	// MempoolFeeBucket help.
+	"mempoolfeebucket-minfeerate": "Lower bound of the fee rates of the bucket in dewies/vB, included",
+	"mempoolfeebucket-maxfeerate": "Upper bound of the fee rates of the bucket in dewies/vB, excluded, omitted for the last bucket",
+	"mempoolfeebucket-count":      "Number of transactions in the bucket",
+	"mempoolfeebucket-vsize":      "Total virtual size of the transactions in the bucket",
+	"mempoolfeebucket-fees":       "Total fees of the transactions in the bucket in LBC",
+
 	"getmempoolentry--synopsis": "Returns mempool data for given transaction.",
 	"getmempoolentry-txid":      "The hash of the transaction",
 
@@ -554,6 +769,21 @@ var helpDescsEnUS = map[string]string{
 	"getnetworkhashps-height":    "Perform estimate ending with this height or -1 for current best chain block height",
 	"getnetworkhashps--result0":  "Estimated hashes per second",
 
+	// GetNextDifficultyCmd help.
+	"getnextdifficulty--synopsis": "Returns the difficulty required for the next block as determined by the difficulty retarget rules.",
+	"getnextdifficulty-timestamp": "The timestamp of the next block in seconds since 1 Jan 1970 GMT, which only matters for networks which reduce the difficulty when no block was mined for a while (default: the current time)",
+
+	// GetNextDifficultyResult help.
+	"getnextdifficultyresult-height":            "The height of the next block",
+	"getnextdifficultyresult-bits":              "The difficulty bits required for the next block",
+	"getnextdifficultyresult-target":            "The hex-encoded target the hash of the next block must not exceed",
+	"getnextdifficultyresult-difficulty":        "The proof-of-work difficulty required for the next block as a multiple of the minimum difficulty",
+	"getnextdifficultyresult-currentdifficulty": "The proof-of-work difficulty of the best block as a multiple of the minimum difficulty",
+	"getnextdifficultyresult-change":            "The change of the difficulty in percent",
+	"getnextdifficultyresult-actualtimespan":    "The time in seconds the blocks of the retarget interval ending at the best block took, unless the network reduces the difficulty when no block was mined for a while",
+	"getnextdifficultyresult-adjustedtimespan":  "The dampened and limited timespan in seconds the difficulty is adjusted by, unless the network reduces the difficulty when no block was mined for a while",
+	"getnextdifficultyresult-targettimespan":    "The desired timespan in seconds of a retarget interval",
+
 	// GetNetworkInfo help.
 	"getnetworkinfo--synopsis":       "Returns an object containing various state info regarding P2P networking.",
 	"getnetworkinfo--result0--desc":  "GetNetworkInfo object",
@@ -573,15 +803,34 @@ var helpDescsEnUS = map[string]string{
 	"getnetworkinforesult-relayfee":        "Minimum relay fee for transactions in BTC/kB",
 	"getnetworkinforesult-incrementalfee":  "Minimum fee increment for mempool limiting or BIP 125 replacement in BTC/kB",
 	"getnetworkinforesult-localaddresses":  "List of local addresses",
+	"getnetworkinforesult-portmapping":     "Status of the mapping of the listening port on the NAT gateway (only when NAT-PMP or UPnP is enabled)",
 	"getnetworkinforesult-warnings":        "Any network and blockchain warnings",
 
+	// PortMappingResult help.
+	"portmappingresult-status":          "The state of the mapping (discovering, mapped or unmapped)",
+	"portmappingresult-protocol":        "The protocol used to talk to the gateway (natpmp or upnp)",
+	"portmappingresult-externaladdress": "The external address of the gateway when the port is mapped",
+	"portmappingresult-externalport":    "The external port of the mapping when the port is mapped",
+	"portmappingresult-internalport":    "The local port which is mapped",
+	"portmappingresult-leaseexpires":    "The time the lease of the mapping expires in seconds since 1 Jan 1970 GMT, which is renewed before then",
+	"portmappingresult-error":           "The error of the last attempt to map the port when it failed",
+
 	// GetNetTotalsCmd help.
 	"getnettotals--synopsis": "Returns a JSON object containing network traffic statistics.",
+	"getnettotals-verbose":   "Also return the bytes received and sent by message command",
 
 	// GetNetTotalsResult help.
-	"getnettotalsresult-totalbytesrecv": "Total bytes received",
-	"getnettotalsresult-totalbytessent": "Total bytes sent",
-	"getnettotalsresult-timemillis":     "Number of milliseconds since 1 Jan 1970 GMT",
+	"getnettotalsresult-totalbytesrecv":           "Total bytes received",
+	"getnettotalsresult-totalbytessent":           "Total bytes sent",
+	"getnettotalsresult-timemillis":               "Number of milliseconds since 1 Jan 1970 GMT",
+	"getnettotalsresult-bytesrecv_per_msg":        "Total bytes received by message command, with the bytes of undecodable messages under *other* (only when verbose is true)",
+	"getnettotalsresult-bytesrecv_per_msg--key":   "command",
+	"getnettotalsresult-bytesrecv_per_msg--value": "Number of bytes received",
+	"getnettotalsresult-bytesrecv_per_msg--desc":  "Total bytes received by message command (only when verbose is true)",
+	"getnettotalsresult-bytessent_per_msg":        "Total bytes sent by message command (only when verbose is true)",
+	"getnettotalsresult-bytessent_per_msg--key":   "command",
+	"getnettotalsresult-bytessent_per_msg--value": "Number of bytes sent",
+	"getnettotalsresult-bytessent_per_msg--desc":  "Total bytes sent by message command (only when verbose is true)",
 
 	// GetNodeAddressesResult help.
 	"getnodeaddressesresult-time":     "Timestamp in seconds since epoch (Jan 1 1970 GMT) keeping track of when the node was last seen",
@@ -595,27 +844,44 @@ var helpDescsEnUS = map[string]string{
 	"getnodeaddresses--result0":  "List of node addresses",
 
 	// GetPeerInfoResult help.
-	"getpeerinforesult-id":             "A unique node ID",
-	"getpeerinforesult-addr":           "The ip address and port of the peer",
-	"getpeerinforesult-addrlocal":      "Local address",
-	"getpeerinforesult-services":       "Services bitmask which represents the services supported by the peer",
-	"getpeerinforesult-relaytxes":      "Peer has requested transactions be relayed to it",
-	"getpeerinforesult-lastsend":       "Time the last message was received in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-lastrecv":       "Time the last message was sent in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-bytessent":      "Total bytes sent",
-	"getpeerinforesult-bytesrecv":      "Total bytes received",
-	"getpeerinforesult-conntime":       "Time the connection was made in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-timeoffset":     "The time offset of the peer",
-	"getpeerinforesult-pingtime":       "Number of microseconds the last ping took",
-	"getpeerinforesult-pingwait":       "Number of microseconds a queued ping has been waiting for a response",
-	"getpeerinforesult-version":        "The protocol version of the peer",
-	"getpeerinforesult-subver":         "The user agent of the peer",
-	"getpeerinforesult-inbound":        "Whether or not the peer is an inbound connection",
-	"getpeerinforesult-startingheight": "The latest block height the peer knew about when the connection was established",
-	"getpeerinforesult-currentheight":  "The current height of the peer",
-	"getpeerinforesult-banscore":       "The ban score",
-	"getpeerinforesult-feefilter":      "The requested minimum fee a transaction must have to be announced to the peer",
-	"getpeerinforesult-syncnode":       "Whether or not the peer is the sync peer",
+	"getpeerinforesult-id":                       "A unique node ID",
+	"getpeerinforesult-addr":                     "The ip address and port of the peer",
+	"getpeerinforesult-addrlocal":                "Local address",
+	"getpeerinforesult-services":                 "Services bitmask which represents the services supported by the peer",
+	"getpeerinforesult-relaytxes":                "Peer has requested transactions be relayed to it",
+	"getpeerinforesult-lastsend":                 "Time the last message was received in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-lastrecv":                 "Time the last message was sent in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-bytessent":                "Total bytes sent",
+	"getpeerinforesult-bytesrecv":                "Total bytes received",
+	"getpeerinforesult-conntime":                 "Time the connection was made in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-timeoffset":               "The time offset of the peer",
+	"getpeerinforesult-pingtime":                 "Number of microseconds the last ping took",
+	"getpeerinforesult-pingwait":                 "Number of microseconds a queued ping has been waiting for a response",
+	"getpeerinforesult-version":                  "The protocol version of the peer",
+	"getpeerinforesult-subver":                   "The user agent of the peer",
+	"getpeerinforesult-inbound":                  "Whether or not the peer is an inbound connection",
+	"getpeerinforesult-startingheight":           "The latest block height the peer knew about when the connection was established",
+	"getpeerinforesult-currentheight":            "The current height of the peer",
+	"getpeerinforesult-banscore":                 "The ban score",
+	"getpeerinforesult-misbehavior":              "The scores of the categories of violations of the peer, including stalling which doesn't count toward the ban score",
+	"getpeerinforesult-misbehavior--key":         "category",
+	"getpeerinforesult-misbehavior--value":       "The score of the category (invalidmessage, unrequesteddata, stalling or protocolabuse)",
+	"getpeerinforesult-misbehavior--desc":        "The scores of the categories of violations",
+	"getpeerinforesult-feefilter":                "The requested minimum fee a transaction must have to be announced to the peer",
+	"getpeerinforesult-bloomwork":                "The number of bytes hashed to match the bloom filters loaded by the peer",
+	"getpeerinforesult-syncnode":                 "Whether or not the peer is the sync peer",
+	"getpeerinforesult-blocksinflight":           "The number of blocks requested from the peer which haven't been received yet",
+	"getpeerinforesult-blocksreceived":           "The number of requested blocks received from the peer",
+	"getpeerinforesult-blocksreassigned":         "The number of blocks requested from the peer which were requested from other peers instead because the peer was too slow or didn't have them",
+	"getpeerinforesult-blockstalls":              "The number of times the peer stalled the block download during the initial sync",
+	"getpeerinforesult-bytessent_per_msg":        "Total bytes sent by message command",
+	"getpeerinforesult-bytessent_per_msg--key":   "command",
+	"getpeerinforesult-bytessent_per_msg--value": "Number of bytes sent",
+	"getpeerinforesult-bytessent_per_msg--desc":  "Total bytes sent by message command",
+	"getpeerinforesult-bytesrecv_per_msg":        "Total bytes received by message command, with the bytes of undecodable messages under *other*",
+	"getpeerinforesult-bytesrecv_per_msg--key":   "command",
+	"getpeerinforesult-bytesrecv_per_msg--value": "Number of bytes received",
+	"getpeerinforesult-bytesrecv_per_msg--desc":  "Total bytes received by message command",
 
 	// GetPeerInfoCmd help.
 	"getpeerinfo--synopsis": "Returns data about each connected network peer as an array of json objects.",
@@ -631,6 +897,37 @@ var helpDescsEnUS = map[string]string{
 	"getrawmempoolverboseresult-vsize":            "The virtual size of a transaction",
 	"getrawmempoolverboseresult-weight":           "The transaction's weight (between vsize*4-3 and vsize*4)",
 
+	// GetRPCStatsCmd help.
+	"getrpcstats--synopsis": "Returns the request statistics of the RPC methods since the start, the statistics of the clients of the most recent requests and the traces of these requests.",
+	"getrpcstats-count":     "The number of the most recent requests to return the traces of",
+
+	// GetRPCStatsResult help.
+	"getrpcstatsresult-methods": "The statistics of the methods since the start, from the highest to the lowest total time",
+	"getrpcstatsresult-clients": "The statistics of the clients of the requests kept in memory, from the highest to the lowest total time",
+	"getrpcstatsresult-recent":  "The traces of the most recent requests, from the newest to the oldest",
+
+	// RPCMethodStats help.
+	"rpcmethodstats-method":  "The name of the method",
+	"rpcmethodstats-calls":   "The number of requests served",
+	"rpcmethodstats-errors":  "The number of requests which failed",
+	"rpcmethodstats-totalms": "The total time spent serving the requests in milliseconds",
+	"rpcmethodstats-avgms":   "The average time spent serving a request in milliseconds",
+	"rpcmethodstats-maxms":   "The longest time spent serving a request in milliseconds",
+
+	// RPCClientStats help.
+	"rpcclientstats-client":    "The host of the client",
+	"rpcclientstats-calls":     "The number of requests of the client",
+	"rpcclientstats-errors":    "The number of requests of the client which failed",
+	"rpcclientstats-totalms":   "The total time spent serving the requests of the client in milliseconds",
+	"rpcclientstats-topmethod": "The method the most time was spent serving for the client",
+
+	// RPCRequestTrace help.
+	"rpcrequesttrace-method":     "The name of the method",
+	"rpcrequesttrace-client":     "The host of the client",
+	"rpcrequesttrace-time":       "The time the request was received in seconds since 1 Jan 1970 GMT",
+	"rpcrequesttrace-durationms": "The time spent serving the request in milliseconds",
+	"rpcrequesttrace-error":      "The error the request failed with, if any",
+
 	// GetRawMempoolCmd help.
 	"getrawmempool--synopsis":   "Returns information about all of the transactions currently in the memory pool.",
 	"getrawmempool-verbose":     "Returns JSON object when true or an array of transaction hashes when false",
@@ -646,6 +943,16 @@ var helpDescsEnUS = map[string]string{
 	"getrawtransaction--condition1": "verbose=true",
 	"getrawtransaction--result0":    "Hex-encoded bytes of the serialized transaction",
 
+	// GetSpentInfoCmd help.
+	"getspentinfo--synopsis": "Returns the input spending a transaction output in the main chain; requires --spentindex.",
+	"getspentinfo-txid":      "The hash of the transaction",
+	"getspentinfo-vout":      "The index of the output",
+
+	// GetSpentInfoResult help.
+	"getspentinforesult-txid":   "The hash of the transaction spending the output",
+	"getspentinforesult-vin":    "The index of the input spending the output",
+	"getspentinforesult-height": "The height of the block containing the spending transaction",
+
 	// GetTxOutResult help.
 	"gettxoutresult-bestblock":     "The block hash that contains the transaction output",
 	"gettxoutresult-confirmations": "The number of confirmations",
@@ -660,6 +967,34 @@ var helpDescsEnUS = map[string]string{
 	"gettxout-vout":           "The index of the output",
 	"gettxout-includemempool": "Include the mempool when true",
 
+	// GetTxOutSetInfoCmd help.
+	"gettxoutsetinfo--synopsis": "Returns statistics about the unspent transaction output set.\n" +
+		"The first call scans the entire set, which can take a while.\n" +
+		"The statistics are then maintained as blocks are connected and disconnected so later calls return immediately.",
+
+	// GetTxOutSetInfoResult help.
+	"gettxoutsetinforesult-height":            "The height of the block the statistics are for",
+	"gettxoutsetinforesult-bestblock":         "The hash of the block the statistics are for",
+	"gettxoutsetinforesult-transactions":      "Not reported by lbcd",
+	"gettxoutsetinforesult-txouts":            "The number of unspent transaction outputs",
+	"gettxoutsetinforesult-bogosize":          "A database independent estimate of the size of the set",
+	"gettxoutsetinforesult-hash_serialized_2": "Not reported by lbcd, see muhash instead",
+	"gettxoutsetinforesult-muhash":            "The MuHash3072 of the set",
+	"gettxoutsetinforesult-disk_size":         "Not reported by lbcd",
+	"gettxoutsetinforesult-total_amount":      "The total amount of the unspent transaction outputs in LBC",
+
+	// GetValidationInfoCmd help.
+	"getvalidationinfo--synopsis": "Returns the number of script validation workers along with script validation throughput metrics.",
+
+	// GetValidationInfoResult help.
+	"getvalidationinforesult-workers":          "The number of script validation workers",
+	"getvalidationinforesult-pendinghigh":      "The number of high priority validation batches in progress",
+	"getvalidationinforesult-pendinglow":       "The number of low priority validation batches in progress",
+	"getvalidationinforesult-batchesvalidated": "The total number of batches of inputs validated",
+	"getvalidationinforesult-inputsvalidated":  "The total number of transaction inputs validated",
+	"getvalidationinforesult-validationtimems": "The total time spent validating batches in milliseconds",
+	"getvalidationinforesult-inputspersecond":  "The average number of inputs validated per second of validation time",
+
 	// HelpCmd help.
 	"help--synopsis":   "Returns a list of all commands or help for a specified command.",
 	"help-command":     "The command to retrieve help for",
@@ -669,27 +1004,75 @@ var helpDescsEnUS = map[string]string{
 	"help--result1":    "Help for specified command",
 
 	// InvalidateBlockCmd
-	"invalidateblock--synopsis": "Invalidate a block.",
+	"invalidateblock--synopsis": "Permanently marks a block as invalid, as if it violated a consensus rule.\n" +
+		"When the block is in the main chain, the chain and the claimtrie are rolled back to its parent.",
 	"invalidateblock-blockhash": "Hash of the block you want to invalidate",
 
+	// LoadTxOutSetCmd help.
+	"loadtxoutset--synopsis": "Load a snapshot written by dumptxoutset into a node which hasn't synced any block yet, and start syncing from the block of the snapshot.\n" +
+		"The block and the MuHash of the unspent outputs of the snapshot must match one of the assumed utxo sets of the network or added with --addassumeutxo.\n" +
+		"The blocks below the snapshot are assumed to be valid until they are downloaded and validated in the background once the node is synced, which ends by checking the snapshot against them.\n" +
+		"They are reported as pruned, and optional indexes can't be enabled.",
+	"loadtxoutset-path": "The snapshot file, relative to the data directory unless absolute",
+
+	// LoadTxOutSetResult help.
+	"loadtxoutsetresult-coins_loaded": "The number of unspent transaction outputs loaded",
+	"loadtxoutsetresult-tip_hash":     "The hash of the block the snapshot is for, which is the new best block",
+	"loadtxoutsetresult-base_height":  "The height of the block the snapshot is for",
+	"loadtxoutsetresult-path":         "The path of the snapshot file",
+
 	// PingCmd help.
 	"ping--synopsis": "Queues a ping to be sent to each connected peer.\n" +
 		"Ping times are provided by getpeerinfo via the pingtime and pingwait fields.",
 
 	// ListBannedCmd help.
-	"listbanned--synopsis": "List all banned IPs.",
+	"listbanned--synopsis": "List all banned IPs and subnets.",
 
 	// ListBannedResult help.
-	"listbannedresult-address":        "The IP of the banned node.",
+	"listbannedresult-address":        "The banned IP or subnet.",
 	"listbannedresult-ban_created":    "The UNIX epoch time the ban was created.",
 	"listbannedresult-banned_until":   "The UNIX epoch time the ban expires.",
 	"listbannedresult-ban_duration":   "The duration of the ban, in seconds.",
 	"listbannedresult-time_remaining": "The time remaining on the ban, in seconds",
 
 	// ReconsiderBlockCmd
-	"reconsiderblock--synopsis": "Reconsider a block for validation.",
+	"reconsiderblock--synopsis": "Removes the invalid status of a block and its descendants and reorganizes to the best valid chain.\n" +
+		"This can be used to undo the effects of invalidateblock.",
 	"reconsiderblock-blockhash": "Hash of the block you want to reconsider",
 
+	// ScanTxOutSetCmd help.
+	"scantxoutset--synopsis": "Scans the unspent transaction output set for the outputs matching the scan objects without requiring the address index.\n" +
+		"Scan objects are output descriptors, addresses or claim IDs.\n" +
+		"The supported descriptors are addr(ADDR), raw(HEX), pk(KEY), pkh(KEY), wpkh(KEY), combo(KEY), sh(SCRIPT), wsh(SCRIPT) and claimid(ID), where KEY is a hex encoded public key.\n" +
+		"The claim script prefix of outputs is ignored when matching their script, so claims and supports paying to a scanned address are found too.",
+	"scantxoutset-action":      "The action to perform: \"start\" a scan, \"abort\" the scan in progress or report the \"status\" of the scan in progress",
+	"scantxoutset-scanobjects": "The descriptors, addresses and claim IDs to scan for, required by the start action",
+	"scantxoutset--condition0": "action=start",
+	"scantxoutset--condition1": "action=status",
+	"scantxoutset--condition2": "action=abort",
+	"scantxoutset--result2":    "Whether or not a scan in progress was aborted",
+
+	// ScanTxOutSetResult help.
+	"scantxoutsetresult-success":      "Whether or not the scan completed without being aborted",
+	"scantxoutsetresult-txouts":       "The number of unspent transaction outputs scanned",
+	"scantxoutsetresult-height":       "The height of the block the unspent transaction output set was scanned at",
+	"scantxoutsetresult-bestblock":    "The hash of the block the unspent transaction output set was scanned at",
+	"scantxoutsetresult-unspents":     "The matching unspent transaction outputs",
+	"scantxoutsetresult-total_amount": "The total amount of the matching unspent transaction outputs in LBC",
+
+	// ScanTxOutSetUnspent help.
+	"scantxoutsetunspent-txid":         "The hash of the transaction of the output",
+	"scantxoutsetunspent-vout":         "The index of the output in its transaction",
+	"scantxoutsetunspent-scriptPubKey": "The hex-encoded public key script of the output",
+	"scantxoutsetunspent-desc":         "The scan object matching the output",
+	"scantxoutsetunspent-amount":       "The amount of the output in LBC",
+	"scantxoutsetunspent-height":       "The height of the block containing the output",
+	"scantxoutsetunspent-coinbase":     "Whether or not the output was created by a coinbase transaction",
+	"scantxoutsetunspent-claim":        "The claim, update or support created by the output, if any",
+
+	// ScanTxOutSetStatusResult help.
+	"scantxoutsetstatusresult-progress": "The approximate percentage of the unspent transaction output set scanned, null when no scan is in progress",
+
 	// SearchRawTransactionsCmd help.
 	"searchrawtransactions--synopsis": "Returns raw data for transactions involving the passed address.\n" +
 		"Returned transactions are pulled from both the database, and transactions currently in the mempool.\n" +
@@ -715,16 +1098,24 @@ var helpDescsEnUS = map[string]string{
 	"allowhighfeesormaxfeerate-value": "Either the boolean value for the allowhighfees parameter in bitcoind < v0.19.0 or the numerical value for the maxfeerate field in bitcoind v0.19.0 and later",
 
 	// SetBanCmd help.
-	"setban--synopsis": "Add or remove an IP from the banned list. (Currently, subnet is not supported.)",
-	"setban-addr":      "The IP to ban. (Currently, subnet is not supported.)",
+	"setban--synopsis": "Add or remove an IP or subnet from the banned list.  Bans are persisted across restarts.",
+	"setban-addr":      "The IP or subnet (in CIDR notation, e.g. 192.168.0.0/24) to ban.",
 	"setban-subcmd":    "'add' to add an IP to the list, 'remove' to remove an IP from the list",
 	"setban-bantime":   "Time in seconds the IP is banned (0 or empty means using the default time of 24h which can also be overwritten by the -bantime startup argument)",
 	"setban-absolute":  "If set, the bantime must be an absolute timestamp expressed in UNIX epoch time; default to false.",
 
 	// SetGenerateCmd help.
-	"setgenerate--synopsis":    "Set the server to generate coins (mine) or not.",
-	"setgenerate-generate":     "Use true to enable generation, false to disable it",
-	"setgenerate-genproclimit": "The number of processors (cores) to limit generation to or -1 for default",
+	"setgenerate--synopsis":      "Set the server to generate coins (mine) or not.",
+	"setgenerate-generate":       "Use true to enable generation, false to disable it",
+	"setgenerate-genproclimit":   "The number of processors (cores) to limit generation to or -1 for default",
+	"setgenerate-payouts":        "JSON object with the payment addresses to split the coinbase of the generated blocks among as keys and their weights as values, replacing the current payouts (an empty object pays the blocks to the addresses specified via --miningaddr)",
+	"setgenerate-payouts--key":   "address",
+	"setgenerate-payouts--value": "weight",
+	"setgenerate-payouts--desc":  "The payment address as the key and its positive weight as the value",
+
+	// SetValidationWorkersCmd help.
+	"setvalidationworkers--synopsis": "Set the number of workers used to validate block scripts.",
+	"setvalidationworkers-workers":   "The number of workers or 0 to use the default based on the number of processor cores",
 
 	// SignMessageWithPrivKeyCmd help.
 	"signmessagewithprivkey--synopsis": "Sign a message with the private key of an address",
@@ -769,6 +1160,20 @@ var helpDescsEnUS = map[string]string{
 	"verifychain-checkdepth": "The number of blocks to check",
 	"verifychain--result0":   "Whether or not the chain verified",
 
+	// VerifyDBCmd help.
+	"verifydb--synopsis": "Returns the status of the scrubber which re-reads the blocks stored in the database to detect corruption.\n" +
+		"Corrupt blocks are fetched again from peers and replaced in the database.",
+	"verifydb-scan": "Start a scan of the database unless one is already running",
+
+	// VerifyDBResult help.
+	"verifydbresult-running":       "Whether or not a scan is in progress",
+	"verifydbresult-scans":         "The number of completed scans",
+	"verifydbresult-lastscanstart": "The time the last scan started in seconds since 1 Jan 1970 GMT, 0 if none",
+	"verifydbresult-lastscanend":   "The time the last completed scan ended in seconds since 1 Jan 1970 GMT, 0 if none",
+	"verifydbresult-blockschecked": "The number of blocks checked by the current or last scan",
+	"verifydbresult-corrupt":       "The hashes of the blocks found to be corrupt which weren't repaired yet",
+	"verifydbresult-repaired":      "The hashes of the most recently repaired blocks",
+
 	// VerifyMessageCmd help.
 	"verifymessage--synopsis": "Verify a signed message.",
 	"verifymessage-address":   "The bitcoin address to use for the signature",
@@ -782,12 +1187,36 @@ var helpDescsEnUS = map[string]string{
 	"session--synopsis":       "Return details regarding a websocket client's current connection session.",
 	"sessionresult-sessionid": "The unique session ID for a client's websocket connection.",
 
+	// NotifyAlertsCmd help.
+	"notifyalerts--synopsis": "Request a chainalert notification whenever an anomaly of the block chain is detected, such as a large reorganization, an invalid block with valid proof of work or a stalled chain tip.",
+
+	// StopNotifyAlertsCmd help.
+	"stopnotifyalerts--synopsis": "Cancel registered chainalert notifications.",
+
 	// NotifyBlocksCmd help.
 	"notifyblocks--synopsis": "Request notifications for whenever a block is connected or disconnected from the main (best) chain.",
 
 	// StopNotifyBlocksCmd help.
 	"stopnotifyblocks--synopsis": "Cancel registered notifications for whenever a block is connected or disconnected from the main (best) chain.",
 
+	// NotifyClaimActivatedCmd help.
+	"notifyclaimactivated--synopsis": "Request a claimactivated notification with the claims activated by a block, either at their activation height or due to a takeover, whenever a block is connected to the main (best) chain.",
+
+	// StopNotifyClaimActivatedCmd help.
+	"stopnotifyclaimactivated--synopsis": "Cancel registered claimactivated notifications.",
+
+	// NotifyClaimExpiredCmd help.
+	"notifyclaimexpired--synopsis": "Request a claimexpired notification with the claims expired by a block whenever a block is connected to the main (best) chain.",
+
+	// StopNotifyClaimExpiredCmd help.
+	"stopnotifyclaimexpired--synopsis": "Cancel registered claimexpired notifications.",
+
+	// NotifyClaimTrieCmd help.
+	"notifyclaimtrie--synopsis": "Request a claimtriechanged notification with the new claim trie root and the names whose nodes changed whenever a block is connected to the main (best) chain.",
+
+	// StopNotifyClaimTrieCmd help.
+	"stopnotifyclaimtrie--synopsis": "Cancel registered claimtriechanged notifications.",
+
 	// NotifyNewTransactionsCmd help.
 	"notifynewtransactions--synopsis": "Send either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
 	"notifynewtransactions-verbose":   "Specifies which type of notification to receive. If verbose is true, then the caller receives txacceptedverbose, otherwise the caller receives txaccepted",
@@ -817,10 +1246,12 @@ var helpDescsEnUS = map[string]string{
 	"stopnotifyspent-outpoints": "List of transaction outpoints to stop monitoring.",
 
 	// LoadTxFilterCmd help.
-	"loadtxfilter--synopsis": "Load, add to, or reload a websocket client's transaction filter for mempool transactions, new blocks and rescanblocks.",
-	"loadtxfilter-reload":    "Load a new filter instead of adding data to an existing one",
-	"loadtxfilter-addresses": "Array of addresses to add to the transaction filter",
-	"loadtxfilter-outpoints": "Array of outpoints to add to the transaction filter",
+	"loadtxfilter--synopsis":   "Load, add to, or reload a websocket client's transaction filter for mempool transactions, new blocks and rescanblocks.",
+	"loadtxfilter-reload":      "Load a new filter instead of adding data to an existing one",
+	"loadtxfilter-addresses":   "Array of addresses to add to the transaction filter",
+	"loadtxfilter-outpoints":   "Array of outpoints to add to the transaction filter",
+	"loadtxfilter-descriptors": "Array of output descriptors such as pkh(KEY) whose outputs are added to the transaction filter, ignoring any claim script prefix",
+	"loadtxfilter-claimnames":  "Array of claim names whose claims, updates and supports are added to the transaction filter",
 
 	// Rescan help.
 	"rescan--synopsis": "Rescan block chain for transactions to addresses.\n" +
@@ -841,6 +1272,57 @@ var helpDescsEnUS = map[string]string{
 	"rescannedblock-hash":         "Hash of the matching block.",
 	"rescannedblock-transactions": "List of matching transactions, serialized and hex-encoded.",
 
+	// GetBlockStreamCmd help.
+	"getblockstream--synopsis": "Stream the serialized block with the given hash as blockchunk notifications.\n" +
+		"Each chunk is sent once the previous one has been written to the connection, and this call returns once all chunks have been sent.",
+	"getblockstream-hash":      "The hash of the block",
+	"getblockstream-chunksize": "The maximum number of bytes of the block in each chunk, between 1024 and 4194304",
+
+	// GetBlockStreamResult help.
+	"getblockstreamresult-hash":   "The hash of the block",
+	"getblockstreamresult-size":   "The size of the serialized block in bytes",
+	"getblockstreamresult-chunks": "The number of blockchunk notifications sent",
+
+	// TestMempoolAcceptCmd help.
+	// SubmitPackageCmd help.
+	"submitpackage--synopsis": "Submits a package of raw transactions to the memory pool and relays them.\n" +
+		"The package must consist of a child transaction with its unconfirmed parents, with the child last and parents sorted before the transactions spending them.\n" +
+		"Parents which do not pay enough fees on their own are accepted when the package as a whole pays the minimum relay fee.",
+	"submitpackage-rawtxns": "An array of hex strings of raw transactions",
+
+	// SubmitPackageResult help.
+	"submitpackageresult-package_msg":       "The transaction package result message, \"success\" when the package was accepted",
+	"submitpackageresult-tx-results":        "Transaction results keyed by wtxid",
+	"submitpackageresult-tx-results--key":   "wtxid",
+	"submitpackageresult-tx-results--value": "An object describing the result for the transaction",
+	"submitpackageresult-tx-results--desc":  "Transaction results keyed by wtxid",
+
+	// SubmitPackageTxResult help.
+	"submitpackagetxresult-txid":  "The transaction hash in hex",
+	"submitpackagetxresult-vsize": "Virtual transaction size as defined in BIP 141",
+	"submitpackagetxresult-fees":  "Transaction fees (only present if the transaction was added to the memory pool by this package)",
+
+	"testmempoolaccept--synopsis": "Returns result of mempool acceptance tests indicating if raw transaction(s) would be accepted by mempool.\n" +
+		"If multiple transactions are passed in, later transactions may spend the outputs of earlier ones.\n" +
+		"This checks if transactions violate the consensus or policy rules.\n" +
+		"The transactions are neither added to the mempool nor relayed.",
+	"testmempoolaccept-rawtxns":    "An array of hex strings of raw transactions",
+	"testmempoolaccept-maxfeerate": "Reject transactions whose fee rate is higher than the specified value, expressed in LBC/kvB; 0 disables the check",
+
+	// TestMempoolAcceptResult help.
+	"testmempoolacceptresult-txid":          "The transaction hash in hex",
+	"testmempoolacceptresult-wtxid":         "The transaction witness hash in hex",
+	"testmempoolacceptresult-package-error": "Package validation error, if any (only possible if rawtxns contained more than one transaction)",
+	"testmempoolacceptresult-allowed":       "Whether this transaction would be accepted to the mempool and pass client-specified maxfeerate",
+	"testmempoolacceptresult-vsize":         "Virtual transaction size as defined in BIP 141",
+	"testmempoolacceptresult-fees":          "Transaction fees (only present if 'allowed' is true)",
+	"testmempoolacceptresult-reject-reason": "Rejection string (only present when 'allowed' is false)",
+
+	// TestMempoolAcceptFees help.
+	"testmempoolacceptfees-base":               "Transaction fee in LBC",
+	"testmempoolacceptfees-effective-feerate":  "The effective feerate in LBC per KvB",
+	"testmempoolacceptfees-effective-includes": "Transactions whose fees and vsizes are included in effective-feerate",
+
 	// Uptime help.
 	"uptime--synopsis": "Returns the total uptime of the server.",
 	"uptime--result0":  "The number of seconds that the server has been running",
@@ -889,6 +1371,89 @@ var helpDescsEnUS = map[string]string{
 	"getclaimsfornameresult-lasttakeoverheight": "Height of the most recent name takeover",
 	"getclaimsfornameresult-hash":               "Hash of the requested block",
 
+	"exportclaimtrie--synopsis": "Write a snapshot of the claim trie at a height of the main chain to a file on the server, which another node can import to skip rebuilding its claim trie",
+	"exportclaimtrie-path":      "The file to write the snapshot to, relative to the data directory unless absolute; it must not exist yet",
+	"exportclaimtrie-height":    "The height of the snapshot (default: the best height)",
+
+	"importclaimtrie--synopsis": "Replace the claim trie with a snapshot read from a file on the server and rebuild the claim trie data above its height; the snapshot must match the claim trie root committed to by the main chain; the current claim trie stays in use until the imported one has caught up with the main chain",
+	"importclaimtrie-path":      "The file to read the snapshot from, relative to the data directory unless absolute",
+
+	"claimtriesnapshotresult-path":          "The absolute path of the snapshot file",
+	"claimtriesnapshotresult-height":        "The height of the snapshot",
+	"claimtriesnapshotresult-claimtrieroot": "The claim trie root hash of the snapshot",
+
+	"compactclaimtrie--synopsis":   "Remove the changes of the claims and supports spent before the last takeover of each name below the final height, keeping the names whose state would change otherwise, and compact the node repo of the claim trie.  The claim trie can't be rolled back below the final height afterwards, and the claims of the names can't be queried below it: getclaimsforname and its variants fail for the heights below the highest final height of the compactions",
+	"compactclaimtrie-finalheight": "The height below which the obsolete changes are removed, at least 1000 blocks below the best block (default: 1000 blocks below the best block)",
+
+	"compactclaimtrieresult-height":         "The height of the compacted claim trie",
+	"compactclaimtrieresult-finalheight":    "The height below which the obsolete changes were removed",
+	"compactclaimtrieresult-nameschecked":   "The number of names checked",
+	"compactclaimtrieresult-namescompacted": "The number of names whose changes were rewritten",
+	"compactclaimtrieresult-changesremoved": "The number of changes removed",
+	"compactclaimtrieresult-sizebefore":     "The disk space used by the node repo before the compaction in bytes",
+	"compactclaimtrieresult-sizeafter":      "The disk space used by the node repo after the compaction in bytes",
+
+	"verifyclaimtrie--synopsis": "Rebuild the state of every name from its changes and compare the resulting claim trie root with the one committed to by the best block, reporting the names whose hash in the claim trie diverges",
+	"verifyclaimtrie-repair":    "Update the divergent names in the claim trie when the rebuilt root matches the best block",
+
+	"verifyclaimtrieresult-height":         "The height of the verified claim trie",
+	"verifyclaimtrieresult-expectedroot":   "The claim trie root committed to by the best block",
+	"verifyclaimtrieresult-storedroot":     "The root of the claim trie before any repair",
+	"verifyclaimtrieresult-computedroot":   "The root rebuilt from the changes of every name",
+	"verifyclaimtrieresult-nameschecked":   "The number of names checked",
+	"verifyclaimtrieresult-divergentcount": "The number of names whose hash in the claim trie diverges from their changes",
+	"verifyclaimtrieresult-divergentnames": "Up to 1000 of the divergent names",
+	"verifyclaimtrieresult-consistent":     "Whether the stored and rebuilt roots match the best block and no name diverges",
+	"verifyclaimtrieresult-repaired":       "Whether the divergent names were repaired",
+
+	"getclaimbyid--synopsis":     "Look up the most recent output of a claim by its claim ID; requires --claimidindex",
+	"getclaimbyid-claimid":       "The full 40 character claim ID",
+	"getclaimbyid-includevalues": "Return the metadata and address",
+	"getclaimbyidresult-claimid": "20-byte hash of TXID:N of the original claim",
+	"getclaimbyidresult-name":    "The name of the claim as given in its most recent output",
+	"getclaimbyidresult-status":  "Either active when the most recent output is unspent or abandoned when it was spent without an update",
+	"getclaimbyidresult-txid":    "The hash of the transaction with the most recent output of the claim",
+	"getclaimbyidresult-n":       "The output (TXO) index",
+	"getclaimbyidresult-height":  "The height of the block containing the most recent output of the claim",
+	"getclaimbyidresult-claim":   "The claim as it stands in the trie at the tip, omitted when it is no longer in the trie",
+
+	"getclaimsbychannel--synopsis":       "List the unspent claims signed by a channel, from the most recently created or updated to the oldest; requires --channelindex",
+	"getclaimsbychannel-channelid":       "The full 40 character claim ID of the channel",
+	"getclaimsbychannel-skip":            "The number of leading claims to skip",
+	"getclaimsbychannel-count":           "The maximum number of claims to return",
+	"getclaimsbychannel-includevalues":   "Return the claims as they stand in the trie along with their metadata and address",
+	"getclaimsbychannelresult-channelid": "20-byte hash of TXID:N of the original claim of the channel",
+	"getclaimsbychannelresult-total":     "The total number of unspent claims signed by the channel",
+	"getclaimsbychannelresult-claims":    "The claims signed by the channel",
+	"claimbychannelresult-claimid":       "20-byte hash of TXID:N of the original claim",
+	"claimbychannelresult-name":          "The name of the claim as given in its current output",
+	"claimbychannelresult-txid":          "The hash of the transaction with the current output of the claim",
+	"claimbychannelresult-n":             "The output (TXO) index",
+	"claimbychannelresult-height":        "The height of the block containing the current output of the claim",
+	"claimbychannelresult-claim":         "The claim as it stands in the trie at the tip, only set when values are requested and the claim is in the trie",
+
+	"simulateclaimtakeover--synopsis":                        "Compute which claim would control a name at a future height if hypothetical claims and supports were added by the next block, using the activation delays of the claim trie",
+	"simulateclaimtakeover-name":                             "The name to simulate the claims and supports on",
+	"simulateclaimtakeover-stakes":                           "The hypothetical claims and supports",
+	"simulateclaimtakeover-height":                           "The height of the outcome; it must not be below the next block (default: the height at which all of the hypothetical claims and supports are active)",
+	"simulatedstake-amount":                                  "The amount of the claim or support in sats",
+	"simulatedstake-claimid":                                 "The claim ID of the claim to support; a new claim is simulated when it is omitted",
+	"simulatedstakeresult-claimid":                           "The claim ID of the new claim or of the supported claim",
+	"simulatedstakeresult-txid":                              "The null transaction hash of the hypothetical outputs",
+	"simulatedstakeresult-n":                                 "The index of the stake in the passed list",
+	"simulatedstakeresult-issupport":                         "Whether the stake is a support",
+	"simulatedstakeresult-amount":                            "The amount of the stake in sats",
+	"simulatedstakeresult-height":                            "The height of the next block, which adds the stake",
+	"simulatedstakeresult-validatheight":                     "The height at which the stake becomes active",
+	"simulateclaimtakeoverresult-normalizedname":             "The name used for bidding at the next block",
+	"simulateclaimtakeoverresult-height":                     "The height of the simulated outcome",
+	"simulateclaimtakeoverresult-lasttakeoverheight":         "The height of the most recent takeover at the height of the outcome",
+	"simulateclaimtakeoverresult-controllingclaimid":         "The claim ID of the claim controlling the name at the height of the outcome, omitted when none does",
+	"simulateclaimtakeoverresult-previouscontrollingclaimid": "The claim ID of the claim controlling the name at the tip, omitted when none does",
+	"simulateclaimtakeoverresult-takeover":                   "Whether the controlling claim at the height of the outcome differs from the one at the tip",
+	"simulateclaimtakeoverresult-stakes":                     "The hypothetical claims and supports as they stand at the height of the outcome",
+	"simulateclaimtakeoverresult-claims":                     "All the claims on the name at the height of the outcome, sorted by bid",
+
 	"getchangesinblock--synopsis":    "Returns a list of names affected by a given block",
 	"getchangesinblockresult-names":  "Names that changed (or were at least checked for change) on the given height",
 	"getchangesinblockresult-height": "Height that was requested",
@@ -903,7 +1468,8 @@ var helpDescsEnUS = map[string]string{
 	"supportresult-amount":        "LBC staked",
 	"supportresult-height":        "The height when the stake was created or updated",
 	"supportresult-validatheight": "The height when the stake becomes valid",
-	"claimresult-value":           "This is the metadata given as part of the claim",
+	"claimresult-value":           "This is the metadata given as part of the claim, hex-encoded unless it is decoded",
+	"claimresult-metadata":        "The metadata decoded from the value of the claim when --decodeclaims is enabled",
 	"claimresult-txid":            "The hash of the transaction",
 	"claimresult-n":               "The output (TXO) index",
 	"claimresult-address":         "The destination address for the claim",
@@ -923,10 +1489,74 @@ var helpDescsEnUS = map[string]string{
 	"generatetoaddress-numblocks":    "The number of blocks to mine",
 	"getchangesinblock-hashorheight": "The requested height or block hash whose changes are of interest",
 
+	"getclaimtrieinfo--synopsis":             "Returns the height of the claim trie along with the size and hit rate of its node cache, the statistics of its names, claims and supports and the metrics of the pebble databases of its repos. The first call computes the statistics from all the names, which can take a while",
+	"getclaimtrieinforesult-height":          "The height of the best block",
+	"getclaimtrieinforesult-cacheentries":    "The number of cached claim trie nodes",
+	"getclaimtrieinforesult-cachesize":       "The approximate memory used by the cached nodes in bytes",
+	"getclaimtrieinforesult-cachebudget":     "The approximate memory the cached nodes may use in bytes",
+	"getclaimtrieinforesult-cachehits":       "The number of node lookups served from the cache",
+	"getclaimtrieinforesult-cachemisses":     "The number of node lookups that rebuilt the node from its changes",
+	"getclaimtrieinforesult-cachehitrate":    "The fraction of node lookups served from the cache",
+	"getclaimtrieinforesult-cacheevictions":  "The number of nodes evicted from the cache to stay within its budget",
+	"getclaimtrieinforesult-names":           "The number of names with claims or supports",
+	"getclaimtrieinforesult-claims":          "The number of claims, including the ones waiting for their activation",
+	"getclaimtrieinforesult-supports":        "The number of supports, including the ones waiting for their activation",
+	"getclaimtrieinforesult-pendingclaims":   "The number of claims waiting for their activation",
+	"getclaimtrieinforesult-pendingsupports": "The number of supports waiting for their activation",
+	"getclaimtrieinforesult-depths":          "The number of names at each depth of the trie",
+	"claimtriedepthinfo-depth":               "The depth in the trie, which is the length of the names in bytes",
+	"claimtriedepthinfo-names":               "The number of names at the depth",
+	"getclaimtrieinforesult-repos":           "The metrics of the pebble databases of the claim trie repos",
+	"claimtrierepoinfo-repo":                 "The name of the repo",
+	"claimtrierepoinfo-disksize":             "The disk space used by the database in bytes",
+	"claimtrierepoinfo-blockcachesize":       "The memory used by the block cache in bytes",
+	"claimtrierepoinfo-blockcachehits":       "The number of block cache hits",
+	"claimtrierepoinfo-blockcachemisses":     "The number of block cache misses",
+	"claimtrierepoinfo-memtablesize":         "The memory allocated by the memtables in bytes",
+	"claimtrierepoinfo-flushes":              "The number of memtable flushes",
+	"claimtrierepoinfo-compactions":          "The number of compactions",
+	"claimtrierepoinfo-compactiondebt":       "The estimated number of bytes left to compact for the database to reach a stable state",
+	"claimtrierepoinfo-readamp":              "The read amplification of the database",
+	"claimtrierepoinfo-filterhits":           "The number of data block reads avoided by the bloom filters",
+	"claimtrierepoinfo-filtermisses":         "The number of bloom filter checks which didn't avoid a data block read",
+
 	"normalize--synopsis": "Used to show how lbcd will normalize a string",
 	"normalize--result0":  "The normalized name",
 	"normalize-name":      "The string to be normalized",
 
+	"getclaimtrierootatheight--synopsis":             "Returns the claim trie root computed for the block at the given height of the main chain, as stored when the block was connected, along with the root committed to by its header",
+	"getclaimtrierootatheight-height":                "The height of the block",
+	"getclaimtrierootatheightresult-height":          "The height of the block",
+	"getclaimtrierootatheightresult-hash":            "The hash of the block",
+	"getclaimtrierootatheightresult-claimtrieroot":   "The claim trie root computed for the block",
+	"getclaimtrierootatheightresult-headerclaimtrie": "The claim trie root committed to by the header of the block",
+	"getclaimtrierootatheightresult-matches":         "Whether the computed root matches the header",
+
+	"normalizename--synopsis":            "Returns the form of a name that lbcd uses for bidding by claims made at the given height",
+	"normalizename-name":                 "The name to be normalized",
+	"normalizename-height":               "The height of the claim; names are only normalized from the normalization fork height on (default: the next block)",
+	"normalizenameresult-name":           "The name as passed in",
+	"normalizenameresult-normalizedname": "The name used for bidding at the given height",
+	"normalizenameresult-height":         "The height used for normalization",
+	"normalizenameresult-normalized":     "Whether names are normalized at the given height",
+
+	"resolve--synopsis":                      "Resolve a claim URI, such as lbry://name, lbry://name#claimid, lbry://name:sequence, lbry://name$amountorder or lbry://@channel/name, to its claim at the tip along with the proof the claim is part of the claim trie.  A claim ID prefix selects the earliest claim with a matching ID, a sequence the claim created in that position, an amount order the active claim in that position by effective amount, and no modifier the controlling claim.  The claims of a channel are those signed by the channel according to the claims by channel index (--channelindex), and the active one with the highest effective amount is selected without a modifier",
+	"resolve-uri":                            "The claim URI; the lbry:// scheme is optional",
+	"resolve-includevalues":                  "Return the metadata and address of the claims",
+	"resolveresult-uri":                      "The URI as passed in",
+	"resolveresult-hash":                     "Hash of the block at the tip",
+	"resolveresult-height":                   "Height of the block at the tip",
+	"resolveresult-claimtrieroot":            "The claim trie root at the tip, which the proofs lead to, omitted when no claim was proven",
+	"resolveresult-channel":                  "The channel which signed the claim, omitted when the URI has no channel",
+	"resolveresult-claim":                    "The claim referenced by the URI",
+	"resolvedclaimresult-normalizedname":     "The name used for bidding by the claim",
+	"resolvedclaimresult-lasttakeoverheight": "Height of the most recent name takeover, which is part of the hash of the claim",
+	"resolvedclaimresult-claim":              "The claim",
+	"resolvedclaimresult-proof":              "The proof the claim is part of the claim trie, omitted when the claim isn't active yet",
+	"claimproofresult-pairs":                 "The hashes combined with the hash of the claim, from the claims of the name up to the root of the claim trie",
+	"claimproofpairresult-odd":               "Whether the hash is combined on the left of the hash being proven",
+	"claimproofpairresult-hash":              "The hash combined with the hash being proven",
+
 	"getblockverboseresult-getblockverboseresultbase": "",
 	"prevout-issupport": "Previous output created a support",
 	"prevout-isclaim":   "Previous output created or updated a claim",
@@ -937,28 +1567,35 @@ var helpDescsEnUS = map[string]string{
 // pointer to the type (or nil to indicate no return value).
 var rpcResultTypes = map[string][]interface{}{
 	"addnode":                nil,
+	"backupchainstate":       {(*btcjson.BackupChainStateResult)(nil)},
 	"clearbanned":            nil,
 	"createrawtransaction":   {(*string)(nil)},
 	"debuglevel":             {(*string)(nil), (*string)(nil)},
+	"debugscript":            {(*btcjson.DebugScriptResult)(nil)},
 	"decoderawtransaction":   {(*btcjson.TxRawDecodeResult)(nil)},
 	"decodescript":           {(*btcjson.DecodeScriptResult)(nil)},
+	"dumptxoutset":           {(*btcjson.DumpTxOutSetResult)(nil)},
 	"estimatefee":            {(*float64)(nil)},
-	"estimatesmartfee":       {(*float64)(nil)},
+	"estimatesmartfee":       {(*btcjson.EstimateSmartFeeResult)(nil)},
 	"generate":               {(*[]string)(nil)},
 	"generatetoaddress":      {(*[]string)(nil)},
 	"getaddednodeinfo":       {(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
 	"getbestblock":           {(*btcjson.GetBestBlockResult)(nil)},
+	"getaddressbalance":      {(*btcjson.GetAddressBalanceResult)(nil)},
+	"getaddresstxids":        {(*[]string)(nil)},
 	"getbestblockhash":       {(*string)(nil)},
 	"getblock":               {(*string)(nil), (*btcjson.GetBlockVerboseResult)(nil)},
 	"getblockchaininfo":      {(*btcjson.GetBlockChainInfoResult)(nil)},
 	"getblockcount":          {(*int64)(nil)},
+	"getblockfrompeer":       nil,
 	"getblockhash":           {(*string)(nil)},
 	"getblockheader":         {(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
+	"getblockheaders":        {(*string)(nil), (*[]btcjson.GetBlockHeaderVerboseResult)(nil)},
 	"getblockstats":          {(*btcjson.GetBlockStatsResult)(nil)},
-	"getblocktemplate":       {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
+	"getblocktemplate":       {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil, (*btcjson.GetBlockTemplateProposalResult)(nil)},
 	"getcfilter":             {(*string)(nil)},
 	"getcfilterheader":       {(*string)(nil)},
-	"getchaintips":           {(*[]btcjson.GetChainTipsResult)(nil)},
+	"getchaintips":           {(*[]btcjson.GetChainTipsResult)(nil), (*btcjson.GetChainTipsVerboseResult)(nil)},
 	"getconnectioncount":     {(*int32)(nil)},
 	"getcurrentnet":          {(*uint32)(nil)},
 	"getdifficulty":          {(*float64)(nil)},
@@ -966,41 +1603,64 @@ var rpcResultTypes = map[string][]interface{}{
 	"gethashespersec":        {(*float64)(nil)},
 	"getheaders":             {(*[]string)(nil)},
 	"getinfo":                {(*btcjson.InfoChainResult)(nil)},
+	"getmempoolancestors":    {(*[]string)(nil), (*map[string]btcjson.GetMempoolEntryResult)(nil)},
+	"getmempooldescendants":  {(*[]string)(nil), (*map[string]btcjson.GetMempoolEntryResult)(nil)},
 	"getmempoolentry":        {(*btcjson.GetMempoolEntryResult)(nil)},
+	"getmempoolfeehistogram": {(*btcjson.GetMempoolFeeHistogramResult)(nil)},
 	"getmempoolinfo":         {(*btcjson.GetMempoolInfoResult)(nil)},
 	"getmininginfo":          {(*btcjson.GetMiningInfoResult)(nil)},
 	"getnettotals":           {(*btcjson.GetNetTotalsResult)(nil)},
 	"getnetworkhashps":       {(*int64)(nil)},
+	"getnextdifficulty":      {(*btcjson.GetNextDifficultyResult)(nil)},
 	"getnetworkinfo":         {(*map[string]btcjson.GetNetworkInfoResult)(nil)},
 	"getnodeaddresses":       {(*[]btcjson.GetNodeAddressesResult)(nil)},
 	"getpeerinfo":            {(*[]btcjson.GetPeerInfoResult)(nil)},
 	"getrawmempool":          {(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
+	"getrpcstats":            {(*btcjson.GetRPCStatsResult)(nil)},
 	"getrawtransaction":      {(*string)(nil), (*btcjson.TxRawResult)(nil)},
+	"getspentinfo":           {(*btcjson.GetSpentInfoResult)(nil)},
 	"gettxout":               {(*btcjson.GetTxOutResult)(nil)},
+	"gettxoutsetinfo":        {(*btcjson.GetTxOutSetInfoResult)(nil)},
+	"getvalidationinfo":      {(*btcjson.GetValidationInfoResult)(nil)},
 	"help":                   {(*string)(nil), (*string)(nil)},
 	"invalidateblock":        nil,
 	"listbanned":             {(*[]btcjson.ListBannedResult)(nil)},
+	"loadtxoutset":           {(*btcjson.LoadTxOutSetResult)(nil)},
 	"node":                   nil,
 	"ping":                   nil,
 	"reconsiderblock":        nil,
+	"scantxoutset":           {(*btcjson.ScanTxOutSetResult)(nil), (*btcjson.ScanTxOutSetStatusResult)(nil), (*bool)(nil)},
 	"searchrawtransactions":  {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
 	"sendrawtransaction":     {(*string)(nil)},
 	"setban":                 nil,
 	"setgenerate":            nil,
+	"setvalidationworkers":   nil,
 	"signmessagewithprivkey": {(*string)(nil)},
 	"stop":                   {(*string)(nil)},
 	"submitblock":            {nil, (*string)(nil)},
+	"submitpackage":          {(*btcjson.SubmitPackageResult)(nil)},
+	"testmempoolaccept":      {(*[]btcjson.TestMempoolAcceptResult)(nil)},
 	"uptime":                 {(*int64)(nil)},
 	"validateaddress":        {(*btcjson.ValidateAddressChainResult)(nil)},
 	"verifychain":            {(*bool)(nil)},
+	"verifydb":               {(*btcjson.VerifyDBResult)(nil)},
 	"verifymessage":          {(*bool)(nil)},
 	"version":                {(*map[string]btcjson.VersionResult)(nil)},
 
 	// Websocket commands.
+	"getblockstream":            {(*btcjson.GetBlockStreamResult)(nil)},
 	"loadtxfilter":              nil,
 	"session":                   {(*btcjson.SessionResult)(nil)},
+	"notifyalerts":              nil,
+	"stopnotifyalerts":          nil,
 	"notifyblocks":              nil,
 	"stopnotifyblocks":          nil,
+	"notifyclaimactivated":      nil,
+	"stopnotifyclaimactivated":  nil,
+	"notifyclaimexpired":        nil,
+	"stopnotifyclaimexpired":    nil,
+	"notifyclaimtrie":           nil,
+	"stopnotifyclaimtrie":       nil,
 	"notifynewtransactions":     nil,
 	"stopnotifynewtransactions": nil,
 	"notifyreceived":            nil,
@@ -1011,12 +1671,23 @@ var rpcResultTypes = map[string][]interface{}{
 	"rescanblocks":              {(*[]btcjson.RescannedBlock)(nil)},
 
 	// ClaimTrie
-	"getclaimsforname":      {(*btcjson.GetClaimsForNameResult)(nil)},
-	"getclaimsfornamebyid":  {(*btcjson.GetClaimsForNameResult)(nil)},
-	"getclaimsfornamebybid": {(*btcjson.GetClaimsForNameResult)(nil)},
-	"getclaimsfornamebyseq": {(*btcjson.GetClaimsForNameResult)(nil)},
-	"normalize":             {(*string)(nil)},
-	"getchangesinblock":     {(*btcjson.GetChangesInBlockResult)(nil)},
+	"compactclaimtrie":         {(*btcjson.CompactClaimTrieResult)(nil)},
+	"exportclaimtrie":          {(*btcjson.ClaimTrieSnapshotResult)(nil)},
+	"importclaimtrie":          {(*btcjson.ClaimTrieSnapshotResult)(nil)},
+	"verifyclaimtrie":          {(*btcjson.VerifyClaimTrieResult)(nil)},
+	"getclaimtrierootatheight": {(*btcjson.GetClaimTrieRootAtHeightResult)(nil)},
+	"getclaimbyid":             {(*btcjson.GetClaimByIDResult)(nil)},
+	"getclaimsbychannel":       {(*btcjson.GetClaimsByChannelResult)(nil)},
+	"getclaimsforname":         {(*btcjson.GetClaimsForNameResult)(nil)},
+	"getclaimsfornamebyid":     {(*btcjson.GetClaimsForNameResult)(nil)},
+	"getclaimsfornamebybid":    {(*btcjson.GetClaimsForNameResult)(nil)},
+	"getclaimsfornamebyseq":    {(*btcjson.GetClaimsForNameResult)(nil)},
+	"normalize":                {(*string)(nil)},
+	"normalizename":            {(*btcjson.NormalizeNameResult)(nil)},
+	"resolve":                  {(*btcjson.ResolveResult)(nil)},
+	"simulateclaimtakeover":    {(*btcjson.SimulateClaimTakeoverResult)(nil)},
+	"getclaimtrieinfo":         {(*btcjson.GetClaimTrieInfoResult)(nil)},
+	"getchangesinblock":        {(*btcjson.GetChangesInBlockResult)(nil)},
 }
 
 // helpCacher provides a concurrent safe type that provides help and usage for
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbcd/btcjson"
+)
+
+// defaultRPCTraceSize is the default number of the most recent RPC requests
+// whose traces are kept in memory.
+const defaultRPCTraceSize = 1000
+
+// rpcTrace describes an RPC request served by the RPC server.
+type rpcTrace struct {
+	method   string
+	client   string
+	start    time.Time
+	duration time.Duration
+	err      error
+}
+
+// rpcMethodStats holds the statistics of the requests served for an RPC
+// method since the start of the server.
+type rpcMethodStats struct {
+	calls    uint64
+	errors   uint64
+	duration time.Duration
+	maxTime  time.Duration
+}
+
+// rpcStats traces the requests served by the RPC server.  It keeps the traces
+// of the most recent requests in a ring buffer, which shows the callers behind
+// the recent load, and accumulates the statistics of each method since the
+// start of the server.
+type rpcStats struct {
+	mtx     sync.Mutex
+	traces  []rpcTrace
+	next    int
+	full    bool
+	methods map[string]*rpcMethodStats
+}
+
+// newRPCStats returns a new RPC request tracer which keeps the traces of the
+// passed number of most recent requests.
+func newRPCStats(traceSize int) *rpcStats {
+	return &rpcStats{
+		traces:  make([]rpcTrace, traceSize),
+		methods: make(map[string]*rpcMethodStats),
+	}
+}
+
+// rpcClientHost returns the host of the passed remote address of an RPC client
+// so the requests of a client are grouped regardless of its connections.
+func rpcClientHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// record adds the trace of a served request.  It does nothing when the tracer
+// is nil.
+//
+// This function is safe for concurrent access.
+func (s *rpcStats) record(trace rpcTrace) {
+	if s == nil {
+		return
+	}
+
+	rpcsLog.Tracef("RPC request method=%s client=%s duration=%v error=%v",
+		trace.method, trace.client, trace.duration, trace.err)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stats, ok := s.methods[trace.method]
+	if !ok {
+		stats = new(rpcMethodStats)
+		s.methods[trace.method] = stats
+	}
+	stats.calls++
+	if trace.err != nil {
+		stats.errors++
+	}
+	stats.duration += trace.duration
+	if trace.duration > stats.maxTime {
+		stats.maxTime = trace.duration
+	}
+
+	if len(s.traces) == 0 {
+		return
+	}
+	s.traces[s.next] = trace
+	s.next++
+	if s.next == len(s.traces) {
+		s.next = 0
+		s.full = true
+	}
+}
+
+// recentTraces returns the traces of the requests held by the ring buffer from
+// the most recent to the oldest one.
+//
+// This function MUST be called with the mutex held.
+func (s *rpcStats) recentTraces() []rpcTrace {
+	count := s.next
+	if s.full {
+		count = len(s.traces)
+	}
+	traces := make([]rpcTrace, 0, count)
+	for i := 1; i <= count; i++ {
+		idx := (s.next - i + len(s.traces)) % len(s.traces)
+		traces = append(traces, s.traces[idx])
+	}
+	return traces
+}
+
+// milliseconds returns the passed duration in fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// result returns the statistics of the methods since the start of the server,
+// the statistics of the clients of the requests held by the ring buffer, and
+// the passed number of most recent requests.  The methods and clients are
+// sorted by the time spent serving them, from the highest to the lowest.
+//
+// This function is safe for concurrent access.
+func (s *rpcStats) result(count int) *btcjson.GetRPCStatsResult {
+	result := &btcjson.GetRPCStatsResult{
+		Methods: []btcjson.RPCMethodStats{},
+		Clients: []btcjson.RPCClientStats{},
+		Recent:  []btcjson.RPCRequestTrace{},
+	}
+	if s == nil {
+		return result
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for method, stats := range s.methods {
+		result.Methods = append(result.Methods, btcjson.RPCMethodStats{
+			Method:  method,
+			Calls:   stats.calls,
+			Errors:  stats.errors,
+			TotalMs: milliseconds(stats.duration),
+			AvgMs:   milliseconds(stats.duration) / float64(stats.calls),
+			MaxMs:   milliseconds(stats.maxTime),
+		})
+	}
+	sort.Slice(result.Methods, func(i, j int) bool {
+		return result.Methods[i].TotalMs > result.Methods[j].TotalMs
+	})
+
+	// Aggregate the traces of each client along with the time spent
+	// serving each of its methods to find its most expensive one.
+	type clientStats struct {
+		btcjson.RPCClientStats
+		methods map[string]time.Duration
+	}
+	traces := s.recentTraces()
+	clients := make(map[string]*clientStats)
+	for i := range traces {
+		trace := &traces[i]
+		stats, ok := clients[trace.client]
+		if !ok {
+			stats = &clientStats{
+				RPCClientStats: btcjson.RPCClientStats{
+					Client: trace.client,
+				},
+				methods: make(map[string]time.Duration),
+			}
+			clients[trace.client] = stats
+		}
+		stats.Calls++
+		if trace.err != nil {
+			stats.Errors++
+		}
+		stats.TotalMs += milliseconds(trace.duration)
+		stats.methods[trace.method] += trace.duration
+		if stats.methods[trace.method] >
+			stats.methods[stats.TopMethod] {
+
+			stats.TopMethod = trace.method
+		}
+	}
+	for _, stats := range clients {
+		result.Clients = append(result.Clients, stats.RPCClientStats)
+	}
+	sort.Slice(result.Clients, func(i, j int) bool {
+		return result.Clients[i].TotalMs > result.Clients[j].TotalMs
+	})
+
+	if count > len(traces) {
+		count = len(traces)
+	}
+	for _, trace := range traces[:count] {
+		var errStr string
+		if trace.err != nil {
+			errStr = trace.err.Error()
+		}
+		result.Recent = append(result.Recent, btcjson.RPCRequestTrace{
+			Method:     trace.method,
+			Client:     trace.client,
+			Time:       trace.start.Unix(),
+			DurationMs: milliseconds(trace.duration),
+			Error:      errStr,
+		})
+	}
+
+	return result
+}
+
+// writeMetrics writes the statistics of the methods since the start of the
+// server in the Prometheus text format.
+//
+// This function is safe for concurrent access.
+func (s *rpcStats) writeMetrics(w *bufio.Writer) {
+	if s == nil {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	methods := make([]string, 0, len(s.methods))
+	for method := range s.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	metrics := []struct {
+		name   string
+		help   string
+		metric func(stats *rpcMethodStats) float64
+	}{
+		{"lbcd_rpc_requests_total", "RPC requests served for the method.",
+			func(stats *rpcMethodStats) float64 { return float64(stats.calls) }},
+		{"lbcd_rpc_request_errors_total", "RPC requests for the method which failed.",
+			func(stats *rpcMethodStats) float64 { return float64(stats.errors) }},
+		{"lbcd_rpc_request_duration_seconds_total", "Time spent serving the RPC requests for the method.",
+			func(stats *rpcMethodStats) float64 { return stats.duration.Seconds() }},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s counter\n", metric.name)
+		for _, method := range methods {
+			fmt.Fprintf(w, "%s{method=%q} %v\n", metric.name, method,
+				metric.metric(s.methods[method]))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRPCStats ensures the RPC request tracer keeps the most recent traces,
+// accumulates the statistics of the methods since the start and aggregates
+// the statistics of the clients of the recent requests.
+func TestRPCStats(t *testing.T) {
+	s := newRPCStats(3)
+	start := time.Unix(1700000000, 0)
+	traces := []rpcTrace{
+		{"getblock", "10.0.0.1", start, 10 * time.Millisecond, nil},
+		{"getclaimsforname", "10.0.0.2", start, 300 * time.Millisecond, nil},
+		{"getblock", "10.0.0.2", start, 30 * time.Millisecond,
+			errors.New("block not found")},
+		{"getclaimsforname", "10.0.0.2", start, 100 * time.Millisecond, nil},
+	}
+	for _, trace := range traces {
+		s.record(trace)
+	}
+
+	result := s.result(2)
+	if len(result.Methods) != 2 {
+		t.Fatalf("got %d methods, want 2", len(result.Methods))
+	}
+	top := result.Methods[0]
+	if top.Method != "getclaimsforname" || top.Calls != 2 ||
+		top.Errors != 0 || top.TotalMs != 400 || top.AvgMs != 200 ||
+		top.MaxMs != 300 {
+
+		t.Fatalf("unexpected stats of the top method: %+v", top)
+	}
+	if getblock := result.Methods[1]; getblock.Calls != 2 ||
+		getblock.Errors != 1 || getblock.TotalMs != 40 {
+
+		t.Fatalf("unexpected stats of getblock: %+v", getblock)
+	}
+
+	// The first request was dropped from the ring buffer, so only the
+	// second client is left.
+	if len(result.Clients) != 1 {
+		t.Fatalf("got %d clients, want 1", len(result.Clients))
+	}
+	client := result.Clients[0]
+	if client.Client != "10.0.0.2" || client.Calls != 3 ||
+		client.Errors != 1 || client.TotalMs != 430 ||
+		client.TopMethod != "getclaimsforname" {
+
+		t.Fatalf("unexpected stats of the client: %+v", client)
+	}
+
+	if len(result.Recent) != 2 {
+		t.Fatalf("got %d recent requests, want 2", len(result.Recent))
+	}
+	if result.Recent[0].DurationMs != 100 ||
+		result.Recent[1].Error != "block not found" {
+
+		t.Fatalf("unexpected recent requests: %+v", result.Recent)
+	}
+
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	s.writeMetrics(w)
+	w.Flush()
+	want := `lbcd_rpc_requests_total{method="getclaimsforname"} 2`
+	if !strings.Contains(b.String(), want) {
+		t.Fatalf("metrics missing %q:\n%s", want, b.String())
+	}
+
+	// Without traces, the statistics of the methods are still collected.
+	s = newRPCStats(0)
+	s.record(traces[0])
+	result = s.result(10)
+	if len(result.Methods) != 1 || len(result.Recent) != 0 {
+		t.Fatalf("unexpected result without traces: %+v", result)
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lbryio/lbcd/blockchain"
+	"github.com/lbryio/lbcd/btcec"
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// utxoScanState tracks the scan of the utxo set started by the scantxoutset
+// command.  Only one scan may run at a time.
+type utxoScanState struct {
+	mtx      sync.Mutex
+	running  bool
+	progress float64
+	abort    chan struct{}
+}
+
+// newUtxoScanState returns the state of a utxo set scanner with no scan
+// running.
+func newUtxoScanState() *utxoScanState {
+	return &utxoScanState{}
+}
+
+// begin marks a scan as running and returns a channel closed when the scan is
+// aborted.  It returns false when a scan is already running.
+func (u *utxoScanState) begin() (<-chan struct{}, bool) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	if u.running {
+		return nil, false
+	}
+	u.running = true
+	u.progress = 0
+	u.abort = make(chan struct{})
+	return u.abort, true
+}
+
+// end marks the running scan as done.
+func (u *utxoScanState) end() {
+	u.mtx.Lock()
+	u.running = false
+	u.mtx.Unlock()
+}
+
+// setProgress records the fraction of the utxo set scanned by the running scan.
+func (u *utxoScanState) setProgress(progress float64) {
+	u.mtx.Lock()
+	u.progress = progress
+	u.mtx.Unlock()
+}
+
+// status returns the fraction of the utxo set scanned by the running scan, if
+// any.
+func (u *utxoScanState) status() (float64, bool) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	return u.progress, u.running
+}
+
+// requestAbort aborts the running scan.  It returns false when no scan is
+// running.
+func (u *utxoScanState) requestAbort() bool {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	if !u.running {
+		return false
+	}
+	select {
+	case <-u.abort:
+	default:
+		close(u.abort)
+	}
+	return true
+}
+
+// utxoScanMatcher matches unspent transaction outputs against the scan objects
+// of the scantxoutset command.  The claim script prefix of outputs, if any, is
+// ignored when matching their public key script.
+type utxoScanMatcher struct {
+	scripts  map[string]string
+	claimIDs map[change.ClaimID]string
+}
+
+// newUtxoScanMatcher returns a matcher for the passed scan objects, which are
+// output descriptors, addresses or claim IDs.
+//
+// The supported descriptors are addr(ADDR), raw(HEX), pk(KEY), pkh(KEY),
+// wpkh(KEY), combo(KEY), sh(SCRIPT), wsh(SCRIPT) and claimid(ID), where KEY is
+// a hex encoded public key.  Descriptor checksums are ignored.
+func newUtxoScanMatcher(objects []string, params *chaincfg.Params) (*utxoScanMatcher, error) {
+	m := &utxoScanMatcher{
+		scripts:  make(map[string]string),
+		claimIDs: make(map[change.ClaimID]string),
+	}
+	for _, object := range objects {
+		desc := object
+		if i := strings.LastIndexByte(desc, '#'); i >= 0 {
+			desc = desc[:i]
+		}
+
+		switch {
+		case strings.HasPrefix(desc, "claimid(") && strings.HasSuffix(desc, ")"):
+			id, err := parseScanClaimID(desc[len("claimid(") : len(desc)-1])
+			if err != nil {
+				return nil, err
+			}
+			m.claimIDs[id] = object
+
+		case strings.HasSuffix(desc, ")"):
+			scripts, err := parseScanDescriptor(desc, params, true)
+			if err != nil {
+				return nil, err
+			}
+			for _, script := range scripts {
+				m.scripts[string(script)] = object
+			}
+
+		default:
+			// Bare addresses and claim IDs.
+			addr, err := btcutil.DecodeAddress(desc, params)
+			if err == nil && addr.IsForNet(params) {
+				script, err := txscript.PayToAddrScript(addr)
+				if err != nil {
+					return nil, err
+				}
+				m.scripts[string(script)] = object
+				continue
+			}
+			id, err := parseScanClaimID(desc)
+			if err != nil {
+				return nil, fmt.Errorf("scan object %q is not a "+
+					"descriptor, address or claim ID", object)
+			}
+			m.claimIDs[id] = object
+		}
+	}
+	return m, nil
+}
+
+// parseScanClaimID parses a hex encoded claim ID.
+func parseScanClaimID(s string) (change.ClaimID, error) {
+	if len(s) != 2*change.ClaimIDSize {
+		return change.ClaimID{}, fmt.Errorf("claim ID %q must be %d "+
+			"hex characters", s, 2*change.ClaimIDSize)
+	}
+	return change.NewIDFromString(s)
+}
+
+// parseScanDescriptor returns the public key scripts described by the passed
+// output descriptor.  Descriptors which only describe a single script, such as
+// combo and addr, are only allowed at the top level.
+func parseScanDescriptor(desc string, params *chaincfg.Params, top bool) ([][]byte, error) {
+	open := strings.IndexByte(desc, '(')
+	if open < 0 || !strings.HasSuffix(desc, ")") {
+		return nil, fmt.Errorf("descriptor %q is malformed", desc)
+	}
+	fn, arg := desc[:open], desc[open+1:len(desc)-1]
+
+	switch fn {
+	case "addr":
+		if !top {
+			return nil, fmt.Errorf("addr() is only allowed at the top level")
+		}
+		addr, err := btcutil.DecodeAddress(arg, params)
+		if err != nil || !addr.IsForNet(params) {
+			return nil, fmt.Errorf("address %q is invalid", arg)
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{script}, nil
+
+	case "raw":
+		if !top {
+			return nil, fmt.Errorf("raw() is only allowed at the top level")
+		}
+		script, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, fmt.Errorf("script %q is not hex", arg)
+		}
+		return [][]byte{script}, nil
+
+	case "pk", "pkh", "wpkh", "combo":
+		key, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, fmt.Errorf("public key %q is not hex", arg)
+		}
+		if _, err := btcec.ParsePubKey(key, btcec.S256()); err != nil {
+			return nil, fmt.Errorf("public key %q is invalid: %v",
+				arg, err)
+		}
+		compressed := len(key) == btcec.PubKeyBytesLenCompressed
+		hash := btcutil.Hash160(key)
+
+		pk, _ := txscript.NewScriptBuilder().AddData(key).
+			AddOp(txscript.OP_CHECKSIG).Script()
+		pkh, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).
+			AddOp(txscript.OP_HASH160).AddData(hash).
+			AddOp(txscript.OP_EQUALVERIFY).
+			AddOp(txscript.OP_CHECKSIG).Script()
+		wpkh, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_0).
+			AddData(hash).Script()
+
+		switch fn {
+		case "pk":
+			return [][]byte{pk}, nil
+		case "pkh":
+			return [][]byte{pkh}, nil
+		case "wpkh":
+			if !compressed {
+				return nil, fmt.Errorf("wpkh() requires a " +
+					"compressed public key")
+			}
+			return [][]byte{wpkh}, nil
+		}
+
+		if !top {
+			return nil, fmt.Errorf("combo() is only allowed at the top level")
+		}
+		scripts := [][]byte{pk, pkh}
+		if compressed {
+			scripts = append(scripts, wpkh, payToScriptHash(wpkh))
+		}
+		return scripts, nil
+
+	case "sh", "wsh":
+		inner, err := parseScanDescriptor(arg, params, false)
+		if err != nil {
+			return nil, err
+		}
+		if fn == "sh" {
+			return [][]byte{payToScriptHash(inner[0])}, nil
+		}
+		hash := sha256.Sum256(inner[0])
+		script, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_0).
+			AddData(hash[:]).Script()
+		return [][]byte{script}, nil
+	}
+
+	return nil, fmt.Errorf("descriptor function %q is not supported", fn)
+}
+
+// payToScriptHash returns the pay-to-script-hash public key script of the
+// passed redeem script.
+func payToScriptHash(redeemScript []byte) []byte {
+	script, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160(redeemScript)).
+		AddOp(txscript.OP_EQUAL).Script()
+	return script
+}
+
+// desc returns the scan object matching the passed output, if any.
+func (m *utxoScanMatcher) desc(outpoint *wire.OutPoint, pkScript []byte) (string, bool) {
+	if len(m.claimIDs) > 0 {
+		cs, err := txscript.ExtractClaimScript(pkScript)
+		if err == nil {
+			var id change.ClaimID
+			if cs.Opcode == txscript.OP_CLAIMNAME {
+				id = change.NewClaimID(*outpoint)
+			} else {
+				copy(id[:], cs.ClaimID)
+			}
+			if object, ok := m.claimIDs[id]; ok {
+				return object, true
+			}
+		}
+	}
+
+	object, ok := m.scripts[string(txscript.StripClaimScriptPrefix(pkScript))]
+	return object, ok
+}
+
+// match returns whether or not the passed output matches a scan object.
+func (m *utxoScanMatcher) match(outpoint *wire.OutPoint, pkScript []byte) bool {
+	_, ok := m.desc(outpoint, pkScript)
+	return ok
+}
+
+// handleScanTxOutSet implements the scantxoutset command.
+func handleScanTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ScanTxOutSetCmd)
+
+	switch c.Action {
+	case btcjson.ScanTxOutSetStatus:
+		progress, running := s.utxoScan.status()
+		if !running {
+			return nil, nil
+		}
+		return &btcjson.ScanTxOutSetStatusResult{
+			Progress: progress * 100,
+		}, nil
+
+	case btcjson.ScanTxOutSetAbort:
+		return s.utxoScan.requestAbort(), nil
+
+	case btcjson.ScanTxOutSetStart:
+	default:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Invalid action %q", c.Action),
+		}
+	}
+
+	if c.ScanObjects == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "scanobjects argument is required for the start action",
+		}
+	}
+	matcher, err := newUtxoScanMatcher(*c.ScanObjects, s.cfg.ChainParams)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: err.Error(),
+		}
+	}
+
+	abort, ok := s.utxoScan.begin()
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Scan already in progress, use action " +
+				"\"abort\" or \"status\"",
+		}
+	}
+	defer s.utxoScan.end()
+
+	// Abort the scan when requested or when the client disconnects.
+	interrupt := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-abort:
+		case <-closeChan:
+		case <-done:
+			return
+		}
+		close(interrupt)
+	}()
+
+	scan, err := s.cfg.Chain.ScanUtxoSet(matcher.match,
+		s.utxoScan.setProgress, interrupt)
+	if err != nil {
+		select {
+		case <-interrupt:
+			return &btcjson.ScanTxOutSetResult{Success: false}, nil
+		default:
+		}
+		context := "Failed to scan the utxo set"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return createScanTxOutSetResult(scan, matcher), nil
+}
+
+// createScanTxOutSetResult returns the result of the scantxoutset command for
+// the passed scan.
+func createScanTxOutSetResult(scan *blockchain.UtxoScanResult,
+	matcher *utxoScanMatcher) *btcjson.ScanTxOutSetResult {
+
+	result := &btcjson.ScanTxOutSetResult{
+		Success:   true,
+		TxOuts:    scan.TxOuts,
+		Height:    scan.Height,
+		BestBlock: scan.Hash.String(),
+		Unspents:  make([]btcjson.ScanTxOutSetUnspent, 0, len(scan.Matches)),
+	}
+	var total int64
+	for i := range scan.Matches {
+		match := &scan.Matches[i]
+		desc, _ := matcher.desc(&match.OutPoint, match.PkScript)
+		result.Unspents = append(result.Unspents, btcjson.ScanTxOutSetUnspent{
+			TxID:         match.OutPoint.Hash.String(),
+			Vout:         match.OutPoint.Index,
+			ScriptPubKey: hex.EncodeToString(match.PkScript),
+			Desc:         desc,
+			Amount:       btcutil.Amount(match.Amount).ToBTC(),
+			Height:       match.Height,
+			Coinbase:     match.IsCoinBase,
+			Claim:        createVoutClaim(&match.OutPoint, match.PkScript),
+		})
+		total += match.Amount
+	}
+	result.TotalAmount = btcutil.Amount(total).ToBTC()
+	return result
+}
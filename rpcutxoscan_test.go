@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/lbryio/lbcd/btcec"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/claimtrie/change"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestUtxoScanMatcher ensures the scan objects of the scantxoutset command
+// match the expected outputs.
+func TestUtxoScanMatcher(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("NewPrivateKey: unexpected error: %v", err)
+	}
+	pubKey := hex.EncodeToString(privKey.PubKey().SerializeCompressed())
+	addr, err := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(privKey.PubKey().SerializeCompressed()), params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	pkh, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+
+	// A claim paying to the address, whose claim ID is derived from its
+	// outpoint.
+	claimOutPoint := wire.OutPoint{Hash: [32]byte{1}, Index: 2}
+	claimScript, err := txscript.ClaimNameScript("name", "value")
+	if err != nil {
+		t.Fatalf("ClaimNameScript: unexpected error: %v", err)
+	}
+	// Replace the trailing OP_TRUE by the payment script.
+	claimScript = append(claimScript[:len(claimScript)-1], pkh...)
+	claimID := change.NewClaimID(claimOutPoint)
+
+	tests := []struct {
+		objects  []string
+		outpoint wire.OutPoint
+		script   []byte
+		desc     string
+	}{
+		{[]string{addr.String()}, wire.OutPoint{}, pkh, addr.String()},
+		{[]string{"addr(" + addr.String() + ")#abcdefgh"}, wire.OutPoint{},
+			pkh, "addr(" + addr.String() + ")#abcdefgh"},
+		{[]string{"combo(" + pubKey + ")"}, wire.OutPoint{}, pkh,
+			"combo(" + pubKey + ")"},
+		{[]string{"pkh(" + pubKey + ")"}, claimOutPoint, claimScript,
+			"pkh(" + pubKey + ")"},
+		{[]string{claimID.String()}, claimOutPoint, claimScript,
+			claimID.String()},
+		{[]string{"claimid(" + claimID.String() + ")"}, claimOutPoint,
+			claimScript, "claimid(" + claimID.String() + ")"},
+		{[]string{"wpkh(" + pubKey + ")"}, wire.OutPoint{}, pkh, ""},
+		{[]string{claimID.String()}, wire.OutPoint{}, claimScript, ""},
+	}
+	for i, test := range tests {
+		m, err := newUtxoScanMatcher(test.objects, params)
+		if err != nil {
+			t.Fatalf("#%d newUtxoScanMatcher: unexpected error: %v",
+				i, err)
+		}
+		desc, ok := m.desc(&test.outpoint, test.script)
+		if ok != (test.desc != "") || desc != test.desc {
+			t.Errorf("#%d desc: got %q, %v, want %q", i, desc, ok,
+				test.desc)
+		}
+	}
+
+	// Malformed scan objects are rejected.
+	for _, object := range []string{
+		"nonsense", "pkh(00)", "sh(addr(" + addr.String() + "))",
+		"multi(1," + pubKey + ")", "claimid(00)",
+	} {
+		if _, err := newUtxoScanMatcher([]string{object}, params); err == nil {
+			t.Errorf("newUtxoScanMatcher(%q): expected error", object)
+		}
+	}
+}
@@ -17,7 +17,9 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/btcsuite/websocket"
@@ -25,6 +27,8 @@ import (
 	"github.com/lbryio/lbcd/btcjson"
 	"github.com/lbryio/lbcd/chaincfg"
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
+	"github.com/lbryio/lbcd/claimtrie"
+	"github.com/lbryio/lbcd/claimtrie/normalization"
 	"github.com/lbryio/lbcd/database"
 	"github.com/lbryio/lbcd/txscript"
 	"github.com/lbryio/lbcd/wire"
@@ -39,6 +43,17 @@ const (
 	// handler since notifications have their own queuing mechanism
 	// independent of the send channel buffer.
 	websocketSendBufferSize = 50
+
+	// defaultBlockChunkSize, minBlockChunkSize and maxBlockChunkSize are
+	// the default and bounds of the chunk size in bytes of the blocks
+	// streamed with the getblockstream command.
+	defaultBlockChunkSize = 256 * 1024
+	minBlockChunkSize     = 1024
+	maxBlockChunkSize     = 4 * 1024 * 1024
+
+	// shutdownNtfnTimeout is the maximum duration the clients are given to
+	// receive the shutdown notification before they are disconnected.
+	shutdownNtfnTimeout = 2 * time.Second
 )
 
 type semaphore chan struct{}
@@ -64,13 +79,22 @@ type wsCommandHandler func(*wsClient, interface{}) (interface{}, error)
 var wsHandlers map[string]wsCommandHandler
 var wsHandlersBeforeInit = map[string]wsCommandHandler{
 	"loadtxfilter":              handleLoadTxFilter,
+	"getblockstream":            handleGetBlockStream,
 	"help":                      handleWebsocketHelp,
+	"notifyalerts":              handleNotifyAlerts,
 	"notifyblocks":              handleNotifyBlocks,
+	"notifyclaimactivated":      handleNotifyClaimActivated,
+	"notifyclaimexpired":        handleNotifyClaimExpired,
+	"notifyclaimtrie":           handleNotifyClaimTrie,
 	"notifynewtransactions":     handleNotifyNewTransactions,
 	"notifyreceived":            handleNotifyReceived,
 	"notifyspent":               handleNotifySpent,
 	"session":                   handleSession,
+	"stopnotifyalerts":          handleStopNotifyAlerts,
 	"stopnotifyblocks":          handleStopNotifyBlocks,
+	"stopnotifyclaimactivated":  handleStopNotifyClaimActivated,
+	"stopnotifyclaimexpired":    handleStopNotifyClaimExpired,
+	"stopnotifyclaimtrie":       handleStopNotifyClaimTrie,
 	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
 	"stopnotifyspent":           handleStopNotifySpent,
 	"stopnotifyreceived":        handleStopNotifyReceived,
@@ -84,7 +108,7 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 // server handler which runs each new connection in a new goroutine thereby
 // satisfying the requirement.
 func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
-	authenticated bool, isAdmin bool) {
+	authenticated bool, isAdmin bool, methods map[string]struct{}) {
 
 	// Clear the read deadline that was set before the websocket hijacked
 	// the connection.
@@ -103,7 +127,8 @@ func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
 	// Create a new websocket client to handle the new websocket connection
 	// and wait for it to shutdown.  Once it has shutdown (and hence
 	// disconnected), remove it and any notifications it registered for.
-	client, err := newWebsocketClient(s, conn, remoteAddr, authenticated, isAdmin)
+	client, err := newWebsocketClient(s, conn, remoteAddr, authenticated,
+		isAdmin, methods)
 	if err != nil {
 		rpcsLog.Errorf("Failed to serve client %s: %v", remoteAddr, err)
 		conn.Close()
@@ -139,6 +164,11 @@ type wsNotificationManager struct {
 	// Access channel for current number of connected clients.
 	numClients chan int
 
+	// numClaimTrieClients is the number of clients registered for claim
+	// trie updates.  It is only changed by notificationHandler and must be
+	// accessed atomically.
+	numClaimTrieClients int32
+
 	// Shutdown handling
 	wg   sync.WaitGroup
 	quit chan struct{}
@@ -199,16 +229,22 @@ func (m *wsNotificationManager) queueHandler() {
 	m.wg.Done()
 }
 
-// NotifyBlockConnected passes a block newly-connected to the best chain
-// to the notification manager for block and transaction notification
-// processing.
-func (m *wsNotificationManager) NotifyBlockConnected(block *btcutil.Block) {
+// NotifyBlockConnected passes a block newly-connected to the best chain, along
+// with the names of the claim trie nodes it changed, to the notification
+// manager for block and transaction notification processing.  The names are
+// nil when they weren't collected since no client was registered for claim
+// trie updates, in which case no claim trie notification is sent for the
+// block.
+func (m *wsNotificationManager) NotifyBlockConnected(block *btcutil.Block,
+	changedNames []string) {
+
 	// As NotifyBlockConnected will be called by the block manager
 	// and the RPC server may no longer be running, use a select
 	// statement to unblock enqueuing the notification once the RPC
 	// server has begun shutting down.
+	n := &notificationBlockConnected{block: block, changedNames: changedNames}
 	select {
-	case m.queueNotification <- (*notificationBlockConnected)(block):
+	case m.queueNotification <- n:
 	case <-m.quit:
 	}
 }
@@ -226,6 +262,19 @@ func (m *wsNotificationManager) NotifyBlockDisconnected(block *btcutil.Block) {
 	}
 }
 
+// NotifyChainAlert passes an anomaly of the block chain detected by the chain
+// alerter to the notification manager for alert notification processing.
+func (m *wsNotificationManager) NotifyChainAlert(alert *chainAlert) {
+	// As NotifyChainAlert will be called by the chain alerter and the RPC
+	// server may no longer be running, use a select statement to unblock
+	// enqueuing the notification once the RPC server has begun shutting
+	// down.
+	select {
+	case m.queueNotification <- (*notificationChainAlert)(alert):
+	case <-m.quit:
+	}
+}
+
 // NotifyMempoolTx passes a transaction accepted by mempool to the
 // notification manager for transaction notification processing.  If
 // isNew is true, the tx is is a new transaction, rather than one
@@ -266,6 +315,13 @@ type wsClientFilter struct {
 
 	// Outpoints of unspent outputs.
 	unspent map[wire.OutPoint]struct{}
+
+	// Public key scripts compiled from output descriptors, which are
+	// matched without the claim script prefix of outputs.
+	scripts map[string]struct{}
+
+	// Normalized names of the claims and supports to match.
+	claimNames map[string]struct{}
 }
 
 // newWSClientFilter creates a new, empty wsClientFilter struct to be used
@@ -280,6 +336,8 @@ func newWSClientFilter(addresses []string, unspentOutPoints []wire.OutPoint, par
 		uncompressedPubKeys: map[[65]byte]struct{}{},
 		otherAddresses:      map[string]struct{}{},
 		unspent:             make(map[wire.OutPoint]struct{}, len(unspentOutPoints)),
+		scripts:             map[string]struct{}{},
+		claimNames:          map[string]struct{}{},
 	}
 
 	for _, s := range addresses {
@@ -444,9 +502,62 @@ func (f *wsClientFilter) removeUnspentOutPoint(op *wire.OutPoint) {
 	delete(f.unspent, *op)
 }
 
+// addScript adds a public key script compiled from an output descriptor to the
+// wsClientFilter.
+func (f *wsClientFilter) addScript(script []byte) {
+	f.scripts[string(script)] = struct{}{}
+}
+
+// addClaimName adds a claim name to the wsClientFilter.
+func (f *wsClientFilter) addClaimName(name string) {
+	f.claimNames[string(normalization.Normalize([]byte(name)))] = struct{}{}
+}
+
+// existsOutput returns true if the passed public key script matches a script
+// or claim name added to the wsClientFilter.  Addresses are matched separately
+// with existsAddress.
+func (f *wsClientFilter) existsOutput(pkScript []byte) bool {
+	if len(f.scripts) > 0 {
+		_, ok := f.scripts[string(txscript.StripClaimScriptPrefix(pkScript))]
+		if ok {
+			return true
+		}
+	}
+	if len(f.claimNames) > 0 {
+		cs, err := txscript.ExtractClaimScript(pkScript)
+		if err == nil {
+			_, ok := f.claimNames[string(normalization.Normalize(cs.Name))]
+			return ok
+		}
+	}
+	return false
+}
+
+// parseFilterDescriptors returns the public key scripts described by the passed
+// output descriptors, which support the same functions as the scan objects of
+// the scantxoutset command.  Descriptor checksums are ignored.
+func parseFilterDescriptors(descriptors []string, params *chaincfg.Params) ([][]byte, error) {
+	var scripts [][]byte
+	for _, desc := range descriptors {
+		if i := strings.LastIndexByte(desc, '#'); i >= 0 {
+			desc = desc[:i]
+		}
+		descScripts, err := parseScanDescriptor(desc, params, true)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, descScripts...)
+	}
+	return scripts, nil
+}
+
 // Notification types
-type notificationBlockConnected btcutil.Block
+type notificationBlockConnected struct {
+	block        *btcutil.Block
+	changedNames []string
+}
 type notificationBlockDisconnected btcutil.Block
+type notificationChainAlert chainAlert
 type notificationTxAcceptedByMempool struct {
 	isNew bool
 	tx    *btcutil.Tx
@@ -455,8 +566,16 @@ type notificationTxAcceptedByMempool struct {
 // Notification control requests
 type notificationRegisterClient wsClient
 type notificationUnregisterClient wsClient
+type notificationRegisterAlerts wsClient
+type notificationUnregisterAlerts wsClient
 type notificationRegisterBlocks wsClient
 type notificationUnregisterBlocks wsClient
+type notificationRegisterClaimTrie wsClient
+type notificationUnregisterClaimTrie wsClient
+type notificationRegisterClaimActivated wsClient
+type notificationUnregisterClaimActivated wsClient
+type notificationRegisterClaimExpired wsClient
+type notificationUnregisterClaimExpired wsClient
 type notificationRegisterNewMempoolTxs wsClient
 type notificationUnregisterNewMempoolTxs wsClient
 type notificationRegisterSpent struct {
@@ -490,6 +609,10 @@ func (m *wsNotificationManager) notificationHandler() {
 	// Where possible, the quit channel is used as the unique id for a client
 	// since it is quite a bit more efficient than using the entire struct.
 	blockNotifications := make(map[chan struct{}]*wsClient)
+	alertNotifications := make(map[chan struct{}]*wsClient)
+	claimTrieNotifications := make(map[chan struct{}]*wsClient)
+	claimActivatedNotifications := make(map[chan struct{}]*wsClient)
+	claimExpiredNotifications := make(map[chan struct{}]*wsClient)
 	txNotifications := make(map[chan struct{}]*wsClient)
 	watchedOutPoints := make(map[wire.OutPoint]map[chan struct{}]*wsClient)
 	watchedAddrs := make(map[string]map[chan struct{}]*wsClient)
@@ -504,7 +627,7 @@ out:
 			}
 			switch n := n.(type) {
 			case *notificationBlockConnected:
-				block := (*btcutil.Block)(n)
+				block := n.block
 
 				// Skip iterating through all txs if no
 				// tx notification requests exist.
@@ -522,6 +645,21 @@ out:
 						block)
 				}
 
+				if len(claimTrieNotifications) != 0 &&
+					n.changedNames != nil {
+
+					m.notifyClaimTrieChanged(claimTrieNotifications,
+						block, n.changedNames)
+				}
+
+				if len(claimActivatedNotifications) != 0 ||
+					len(claimExpiredNotifications) != 0 {
+
+					m.notifyClaimStateChanged(
+						claimActivatedNotifications,
+						claimExpiredNotifications, block)
+				}
+
 			case *notificationBlockDisconnected:
 				block := (*btcutil.Block)(n)
 
@@ -532,6 +670,12 @@ out:
 						block)
 				}
 
+			case *notificationChainAlert:
+				if len(alertNotifications) != 0 {
+					m.notifyChainAlert(alertNotifications,
+						(*chainAlert)(n))
+				}
+
 			case *notificationTxAcceptedByMempool:
 				if n.isNew && len(txNotifications) != 0 {
 					m.notifyForNewTx(txNotifications, n.tx)
@@ -547,6 +691,42 @@ out:
 				wsc := (*wsClient)(n)
 				delete(blockNotifications, wsc.quit)
 
+			case *notificationRegisterAlerts:
+				wsc := (*wsClient)(n)
+				alertNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterAlerts:
+				wsc := (*wsClient)(n)
+				delete(alertNotifications, wsc.quit)
+
+			case *notificationRegisterClaimTrie:
+				wsc := (*wsClient)(n)
+				claimTrieNotifications[wsc.quit] = wsc
+				atomic.StoreInt32(&m.numClaimTrieClients,
+					int32(len(claimTrieNotifications)))
+
+			case *notificationUnregisterClaimTrie:
+				wsc := (*wsClient)(n)
+				delete(claimTrieNotifications, wsc.quit)
+				atomic.StoreInt32(&m.numClaimTrieClients,
+					int32(len(claimTrieNotifications)))
+
+			case *notificationRegisterClaimActivated:
+				wsc := (*wsClient)(n)
+				claimActivatedNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterClaimActivated:
+				wsc := (*wsClient)(n)
+				delete(claimActivatedNotifications, wsc.quit)
+
+			case *notificationRegisterClaimExpired:
+				wsc := (*wsClient)(n)
+				claimExpiredNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterClaimExpired:
+				wsc := (*wsClient)(n)
+				delete(claimExpiredNotifications, wsc.quit)
+
 			case *notificationRegisterClient:
 				wsc := (*wsClient)(n)
 				clients[wsc.quit] = wsc
@@ -556,6 +736,12 @@ out:
 				// Remove any requests made by the client as well as
 				// the client itself.
 				delete(blockNotifications, wsc.quit)
+				delete(alertNotifications, wsc.quit)
+				delete(claimTrieNotifications, wsc.quit)
+				atomic.StoreInt32(&m.numClaimTrieClients,
+					int32(len(claimTrieNotifications)))
+				delete(claimActivatedNotifications, wsc.quit)
+				delete(claimExpiredNotifications, wsc.quit)
 				delete(txNotifications, wsc.quit)
 				for k := range wsc.spentRequests {
 					op := k
@@ -598,12 +784,47 @@ out:
 		}
 	}
 
+	m.notifyShutdown(clients)
 	for _, c := range clients {
 		c.Disconnect()
 	}
 	m.wg.Done()
 }
 
+// notifyShutdown sends the shutdown notification to all the passed websocket
+// clients, and waits until it was written to their connection or the clients
+// fail to receive it in time.
+func (m *wsNotificationManager) notifyShutdown(clients map[chan struct{}]*wsClient) {
+	if len(clients) == 0 {
+		return
+	}
+
+	marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil,
+		btcjson.NewShutdownNtfn())
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal shutdown notification: %v", err)
+		return
+	}
+
+	// The notification is sent from a goroutine per client since the send
+	// blocks while the send queue of the client is full.
+	done := make(chan bool, len(clients))
+	for _, c := range clients {
+		go c.SendMessage(marshalledJSON, done)
+	}
+
+	timeout := time.After(shutdownNtfnTimeout)
+	for range clients {
+		select {
+		case <-done:
+		case <-timeout:
+			rpcsLog.Warnf("Timed out sending the shutdown notification " +
+				"to websocket clients")
+			return
+		}
+	}
+}
+
 // NumClients returns the number of clients actively being served.
 func (m *wsNotificationManager) NumClients() (n int) {
 	select {
@@ -625,6 +846,61 @@ func (m *wsNotificationManager) UnregisterBlockUpdates(wsc *wsClient) {
 	m.queueNotification <- (*notificationUnregisterBlocks)(wsc)
 }
 
+// RegisterAlertUpdates requests chain alert notifications to the passed
+// websocket client.
+func (m *wsNotificationManager) RegisterAlertUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterAlerts)(wsc)
+}
+
+// UnregisterAlertUpdates removes chain alert notifications for the passed
+// websocket client.
+func (m *wsNotificationManager) UnregisterAlertUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterAlerts)(wsc)
+}
+
+// RegisterClaimTrieUpdates requests claim trie update notifications to the
+// passed websocket client.
+func (m *wsNotificationManager) RegisterClaimTrieUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterClaimTrie)(wsc)
+}
+
+// HasClaimTrieClients returns whether any client is registered for claim trie
+// update notifications, so the names changed by a connected block only need to
+// be collected when there is a client to notify of them.
+func (m *wsNotificationManager) HasClaimTrieClients() bool {
+	return atomic.LoadInt32(&m.numClaimTrieClients) != 0
+}
+
+// UnregisterClaimTrieUpdates removes claim trie update notifications for the
+// passed websocket client.
+func (m *wsNotificationManager) UnregisterClaimTrieUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterClaimTrie)(wsc)
+}
+
+// RegisterClaimActivatedUpdates requests claim activated notifications to the
+// passed websocket client.
+func (m *wsNotificationManager) RegisterClaimActivatedUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterClaimActivated)(wsc)
+}
+
+// UnregisterClaimActivatedUpdates removes claim activated notifications for
+// the passed websocket client.
+func (m *wsNotificationManager) UnregisterClaimActivatedUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterClaimActivated)(wsc)
+}
+
+// RegisterClaimExpiredUpdates requests claim expired notifications to the
+// passed websocket client.
+func (m *wsNotificationManager) RegisterClaimExpiredUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterClaimExpired)(wsc)
+}
+
+// UnregisterClaimExpiredUpdates removes claim expired notifications for the
+// passed websocket client.
+func (m *wsNotificationManager) UnregisterClaimExpiredUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterClaimExpired)(wsc)
+}
+
 // subscribedClients returns the set of all websocket client quit channels that
 // are registered to receive notifications regarding tx, either due to tx
 // spending a watched output or outputting to a watched address.  Matching
@@ -655,13 +931,10 @@ func (m *wsNotificationManager) subscribedClients(tx *btcutil.Tx,
 	}
 
 	for i, output := range msgTx.TxOut {
-		_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		// Outputs without addresses may still match the scripts and
+		// claim names of filters.
+		_, addrs, _, _ := txscript.ExtractPkScriptAddrs(
 			output.PkScript, m.server.cfg.ChainParams)
-		if err != nil {
-			// Clients are not able to subscribe to
-			// nonstandard or non-address outputs.
-			continue
-		}
 		for quitChan, wsc := range clients {
 			wsc.Lock()
 			filter := wsc.filterData
@@ -670,15 +943,19 @@ func (m *wsNotificationManager) subscribedClients(tx *btcutil.Tx,
 				continue
 			}
 			filter.mu.Lock()
+			relevant := filter.existsOutput(output.PkScript)
 			for _, a := range addrs {
 				if filter.existsAddress(a) {
-					subscribed[quitChan] = struct{}{}
-					op := wire.OutPoint{
-						Hash:  *tx.Hash(),
-						Index: uint32(i),
-					}
-					filter.addUnspentOutPoint(&op)
+					relevant = true
+				}
+			}
+			if relevant {
+				subscribed[quitChan] = struct{}{}
+				op := wire.OutPoint{
+					Hash:  *tx.Hash(),
+					Index: uint32(i),
 				}
+				filter.addUnspentOutPoint(&op)
 			}
 			filter.mu.Unlock()
 		}
@@ -706,6 +983,104 @@ func (*wsNotificationManager) notifyBlockConnected(clients map[chan struct{}]*ws
 	}
 }
 
+// notifyChainAlert notifies websocket clients that have registered for chain
+// alerts when the chain alerter detects an anomaly of the block chain.
+func (*wsNotificationManager) notifyChainAlert(clients map[chan struct{}]*wsClient,
+	alert *chainAlert) {
+
+	ntfn := btcjson.NewChainAlertNtfn(alert.Type, alert.Hash, alert.Height,
+		alert.Time, alert.Message)
+	marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal chain alert notification: "+
+			"%v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyClaimTrieChanged notifies websocket clients that have registered for
+// claim trie updates when a block is connected to the main chain.  The
+// notification carries the new claim trie root along with the names whose
+// nodes were changed by the block.
+func (m *wsNotificationManager) notifyClaimTrieChanged(clients map[chan struct{}]*wsClient,
+	block *btcutil.Block, names []string) {
+
+	ntfn := btcjson.NewClaimTrieChangedNtfn(block.Hash().String(),
+		block.Height(), block.MsgBlock().Header.ClaimTrie.String(), names)
+	marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal claim trie changed "+
+			"notification: %v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyClaimStateChanged notifies websocket clients that have registered for
+// claim activated or claim expired updates when a connected block activated or
+// expired claims.  No notification is sent for blocks which didn't change the
+// state of any claim.
+func (m *wsNotificationManager) notifyClaimStateChanged(activatedClients,
+	expiredClients map[chan struct{}]*wsClient, block *btcutil.Block) {
+
+	activated, expired, err := m.server.cfg.Chain.GetClaimStateChangesInBlock(
+		block.Height())
+	if err != nil {
+		rpcsLog.Errorf("Failed to load claim state changes in block %v: "+
+			"%v", block.Hash(), err)
+		return
+	}
+
+	if len(activated) != 0 && len(activatedClients) != 0 {
+		ntfn := btcjson.NewClaimActivatedNtfn(block.Hash().String(),
+			block.Height(), claimStateChangesToJSON(activated))
+		marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+		if err != nil {
+			rpcsLog.Errorf("Failed to marshal claim activated "+
+				"notification: %v", err)
+			return
+		}
+		for _, wsc := range activatedClients {
+			wsc.QueueNotification(marshalledJSON)
+		}
+	}
+
+	if len(expired) != 0 && len(expiredClients) != 0 {
+		ntfn := btcjson.NewClaimExpiredNtfn(block.Hash().String(),
+			block.Height(), claimStateChangesToJSON(expired))
+		marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+		if err != nil {
+			rpcsLog.Errorf("Failed to marshal claim expired "+
+				"notification: %v", err)
+			return
+		}
+		for _, wsc := range expiredClients {
+			wsc.QueueNotification(marshalledJSON)
+		}
+	}
+}
+
+// claimStateChangesToJSON converts the passed claim state changes to their
+// JSON representation used by the claim notifications.
+func claimStateChangesToJSON(changes []claimtrie.ClaimStateChange) []btcjson.ClaimStateChange {
+	claims := make([]btcjson.ClaimStateChange, 0, len(changes))
+	for _, change := range changes {
+		claims = append(claims, btcjson.ClaimStateChange{
+			Name:    change.Name,
+			ClaimID: change.Claim.ClaimID.String(),
+			TxID:    change.Claim.OutPoint.Hash.String(),
+			N:       change.Claim.OutPoint.Index,
+			Amount:  change.Claim.Amount,
+		})
+	}
+	return claims
+}
+
 // notifyBlockDisconnected notifies websocket clients that have registered for
 // block updates when a block is disconnected from the main chain (due to a
 // reorganize).
@@ -1268,6 +1643,11 @@ type wsClient struct {
 	// false means its access is only to the limited set of RPC calls.
 	isAdmin bool
 
+	// methods is the set of methods the client is restricted to when it
+	// authenticated with the credentials of an RPC account, and is nil
+	// otherwise.
+	methods map[string]struct{}
+
 	// sessionID is a random ID generated for each client when connected.
 	// These IDs may be queried by a client using the session RPC.  A change
 	// to the session ID indicates that the client reconnected.
@@ -1300,6 +1680,21 @@ type wsClient struct {
 	wg                sync.WaitGroup
 }
 
+// authorized returns whether the client may call the passed method, which is
+// any method for admin clients, the limited methods for limited clients and the
+// methods of the access list for clients using the credentials of an account.
+func (c *wsClient) authorized(method string) bool {
+	if c.methods != nil {
+		_, ok := c.methods[method]
+		return ok
+	}
+	if !c.isAdmin {
+		_, ok := rpcLimited[method]
+		return ok
+	}
+	return true
+}
+
 // inHandler handles all incoming messages for the websocket connection.  It
 // must be run as a goroutine.
 func (c *wsClient) inHandler() {
@@ -1377,6 +1772,7 @@ out:
 			}
 
 			cmd := parseCmd(&req)
+			cmd.client = rpcClientHost(c.addr)
 			if cmd.err != nil {
 				// Only process requests from authenticated clients
 				if !c.authenticated {
@@ -1415,12 +1811,14 @@ out:
 				authSha := sha256.Sum256([]byte(auth))
 				cmp := subtle.ConstantTimeCompare(authSha[:], c.server.authsha[:])
 				limitcmp := subtle.ConstantTimeCompare(authSha[:], c.server.limitauthsha[:])
-				if cmp != 1 && limitcmp != 1 {
+				methods, isAccount := c.server.accountMethods(&authSha)
+				if cmp != 1 && limitcmp != 1 && !isAccount {
 					rpcsLog.Warnf("Auth failure.")
 					break out
 				}
 				c.authenticated = true
-				c.isAdmin = cmp == 1
+				c.isAdmin = cmp == 1 || isAccount
+				c.methods = methods
 
 				// Marshal and send response.
 				reply, err = createMarshalledReply(cmd.jsonrpc, cmd.id, nil, nil)
@@ -1435,22 +1833,20 @@ out:
 
 			// Check if the client is using limited RPC credentials and
 			// error when not authorized to call the supplied RPC.
-			if !c.isAdmin {
-				if _, ok := rpcLimited[req.Method]; !ok {
-					jsonErr := &btcjson.RPCError{
-						Code:    btcjson.ErrRPCInvalidParams.Code,
-						Message: "limited user not authorized for this method",
-					}
-					// Marshal and send response.
-					reply, err = createMarshalledReply("", req.ID, nil, jsonErr)
-					if err != nil {
-						rpcsLog.Errorf("Failed to marshal parse failure "+
-							"reply: %v", err)
-						continue
-					}
-					c.SendMessage(reply, nil)
+			if !c.authorized(req.Method) {
+				jsonErr := &btcjson.RPCError{
+					Code:    btcjson.ErrRPCInvalidParams.Code,
+					Message: "limited user not authorized for this method",
+				}
+				// Marshal and send response.
+				reply, err = createMarshalledReply("", req.ID, nil, jsonErr)
+				if err != nil {
+					rpcsLog.Errorf("Failed to marshal parse failure "+
+						"reply: %v", err)
 					continue
 				}
+				c.SendMessage(reply, nil)
+				continue
 			}
 
 			// Asynchronously handle the request.  A semaphore is used to
@@ -1611,6 +2007,7 @@ out:
 						}
 
 						cmd := parseCmd(&req)
+						cmd.client = rpcClientHost(c.addr)
 						if cmd.err != nil {
 							// Only process requests from authenticated clients
 							if !c.authenticated {
@@ -1652,13 +2049,15 @@ out:
 							authSha := sha256.Sum256([]byte(auth))
 							cmp := subtle.ConstantTimeCompare(authSha[:], c.server.authsha[:])
 							limitcmp := subtle.ConstantTimeCompare(authSha[:], c.server.limitauthsha[:])
-							if cmp != 1 && limitcmp != 1 {
+							methods, isAccount := c.server.accountMethods(&authSha)
+							if cmp != 1 && limitcmp != 1 && !isAccount {
 								rpcsLog.Warnf("Auth failure.")
 								break out
 							}
 
 							c.authenticated = true
-							c.isAdmin = cmp == 1
+							c.isAdmin = cmp == 1 || isAccount
+							c.methods = methods
 
 							// Marshal and send response.
 							reply, err = createMarshalledReply(cmd.jsonrpc, cmd.id, nil, nil)
@@ -1676,25 +2075,23 @@ out:
 
 						// Check if the client is using limited RPC credentials and
 						// error when not authorized to call the supplied RPC.
-						if !c.isAdmin {
-							if _, ok := rpcLimited[req.Method]; !ok {
-								jsonErr := &btcjson.RPCError{
-									Code:    btcjson.ErrRPCInvalidParams.Code,
-									Message: "limited user not authorized for this method",
-								}
-								// Marshal and send response.
-								reply, err = createMarshalledReply(req.Jsonrpc, req.ID, nil, jsonErr)
-								if err != nil {
-									rpcsLog.Errorf("Failed to marshal parse failure "+
-										"reply: %v", err)
-									continue
-								}
-
-								if reply != nil {
-									results = append(results, reply)
-								}
+						if !c.authorized(req.Method) {
+							jsonErr := &btcjson.RPCError{
+								Code:    btcjson.ErrRPCInvalidParams.Code,
+								Message: "limited user not authorized for this method",
+							}
+							// Marshal and send response.
+							reply, err = createMarshalledReply(req.Jsonrpc, req.ID, nil, jsonErr)
+							if err != nil {
+								rpcsLog.Errorf("Failed to marshal parse failure "+
+									"reply: %v", err)
 								continue
 							}
+
+							if reply != nil {
+								results = append(results, reply)
+							}
+							continue
 						}
 
 						// Lookup the websocket extension for the command, if it doesn't
@@ -1702,7 +2099,9 @@ out:
 						var resp interface{}
 						wsHandler, ok := wsHandlers[cmd.method]
 						if ok {
+							start := time.Now()
 							resp, err = wsHandler(c, cmd.cmd)
+							c.server.traceRequest(cmd, start, err)
 						} else {
 							resp, err = c.server.standardCmdResult(cmd, nil)
 						}
@@ -1773,7 +2172,9 @@ func (c *wsClient) serviceRequest(r *parsedRPCCmd) {
 	// exist fallback to handling the command as a standard command.
 	wsHandler, ok := wsHandlers[r.method]
 	if ok {
+		start := time.Now()
 		result, err = wsHandler(c, r.cmd)
+		c.server.traceRequest(r, start, err)
 	} else {
 		result, err = c.server.standardCmdResult(r, nil)
 	}
@@ -1991,7 +2392,8 @@ func (c *wsClient) WaitForShutdown() {
 // incoming and outgoing messages in separate goroutines complete with queuing
 // and asynchrous handling for long-running operations.
 func newWebsocketClient(server *rpcServer, conn *websocket.Conn,
-	remoteAddr string, authenticated bool, isAdmin bool) (*wsClient, error) {
+	remoteAddr string, authenticated bool, isAdmin bool,
+	methods map[string]struct{}) (*wsClient, error) {
 
 	sessionID, err := wire.RandomUint64()
 	if err != nil {
@@ -2003,6 +2405,7 @@ func newWebsocketClient(server *rpcServer, conn *websocket.Conn,
 		addr:              remoteAddr,
 		authenticated:     authenticated,
 		isAdmin:           isAdmin,
+		methods:           methods,
 		sessionID:         sessionID,
 		server:            server,
 		addrRequests:      make(map[string]struct{}),
@@ -2062,6 +2465,84 @@ func handleWebsocketHelp(wsc *wsClient, icmd interface{}) (interface{}, error) {
 	return help, nil
 }
 
+// handleGetBlockStream implements the getblockstream command extension for
+// websocket connections.  The raw block is sent as blockchunk notifications
+// before the reply, and each chunk is only queued once the previous one has
+// been written to the connection so slow clients don't cause the whole block
+// to be buffered as JSON.
+func handleGetBlockStream(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.GetBlockStreamCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	chunkSize := int32(defaultBlockChunkSize)
+	if cmd.ChunkSize != nil {
+		chunkSize = *cmd.ChunkSize
+	}
+	if chunkSize < minBlockChunkSize || chunkSize > maxBlockChunkSize {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Chunk size must be between %d and "+
+				"%d", minBlockChunkSize, maxBlockChunkSize),
+		}
+	}
+
+	hash, err := chainhash.NewHashFromStr(cmd.Hash)
+	if err != nil {
+		return nil, rpcDecodeHexError(cmd.Hash)
+	}
+	var blkBytes []byte
+	err = wsc.server.cfg.DB.View(func(dbTx database.Tx) error {
+		var err error
+		blkBytes, err = dbTx.FetchBlock(hash)
+		return err
+	})
+	if err != nil {
+		if wsc.server.cfg.Chain.IsBlockPruned(hash) {
+			return nil, rpcBlockPrunedError(hash)
+		}
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found: " + err.Error(),
+		}
+	}
+
+	size := int32(len(blkBytes))
+	chunks := int32(0)
+	done := make(chan bool, 1)
+	for offset := int32(0); offset < size; offset += chunkSize {
+		end := offset + chunkSize
+		if end > size {
+			end = size
+		}
+		ntfn := btcjson.NewBlockChunkNtfn(cmd.Hash, offset, size,
+			hex.EncodeToString(blkBytes[offset:end]))
+		marshalled, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+		if err != nil {
+			rpcsLog.Errorf("Failed to marshal block chunk: %v", err)
+			return nil, btcjson.ErrRPCInternal
+		}
+
+		wsc.SendMessage(marshalled, done)
+		select {
+		case sent := <-done:
+			if !sent {
+				return nil, ErrClientQuit
+			}
+		case <-wsc.quit:
+			return nil, ErrClientQuit
+		}
+		chunks++
+	}
+
+	return &btcjson.GetBlockStreamResult{
+		Hash:   cmd.Hash,
+		Size:   size,
+		Chunks: chunks,
+	}, nil
+}
+
 // handleLoadTxFilter implements the loadtxfilter command extension for
 // websocket connections.
 //
@@ -2086,6 +2567,22 @@ func handleLoadTxFilter(wsc *wsClient, icmd interface{}) (interface{}, error) {
 
 	params := wsc.server.cfg.ChainParams
 
+	var scripts [][]byte
+	if cmd.Descriptors != nil {
+		var err error
+		scripts, err = parseFilterDescriptors(*cmd.Descriptors, params)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: err.Error(),
+			}
+		}
+	}
+	var claimNames []string
+	if cmd.ClaimNames != nil {
+		claimNames = *cmd.ClaimNames
+	}
+
 	wsc.Lock()
 	if cmd.Reload || wsc.filterData == nil {
 		wsc.filterData = newWSClientFilter(cmd.Addresses, outPoints,
@@ -2104,6 +2601,19 @@ func handleLoadTxFilter(wsc *wsClient, icmd interface{}) (interface{}, error) {
 		wsc.filterData.mu.Unlock()
 	}
 
+	wsc.Lock()
+	filter := wsc.filterData
+	wsc.Unlock()
+
+	filter.mu.Lock()
+	for _, script := range scripts {
+		filter.addScript(script)
+	}
+	for _, name := range claimNames {
+		filter.addClaimName(name)
+	}
+	filter.mu.Unlock()
+
 	return nil, nil
 }
 
@@ -2127,6 +2637,62 @@ func handleStopNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error
 	return nil, nil
 }
 
+// handleNotifyClaimTrie implements the notifyclaimtrie command extension for
+// websocket connections.
+func handleNotifyClaimTrie(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterClaimTrieUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyClaimTrie implements the stopnotifyclaimtrie command
+// extension for websocket connections.
+func handleStopNotifyClaimTrie(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterClaimTrieUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyClaimActivated implements the notifyclaimactivated command
+// extension for websocket connections.
+func handleNotifyClaimActivated(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterClaimActivatedUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyClaimActivated implements the stopnotifyclaimactivated
+// command extension for websocket connections.
+func handleStopNotifyClaimActivated(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterClaimActivatedUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyAlerts implements the notifyalerts command extension for
+// websocket connections.
+func handleNotifyAlerts(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterAlertUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyAlerts implements the stopnotifyalerts command extension for
+// websocket connections.
+func handleStopNotifyAlerts(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterAlertUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyClaimExpired implements the notifyclaimexpired command extension
+// for websocket connections.
+func handleNotifyClaimExpired(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterClaimExpiredUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyClaimExpired implements the stopnotifyclaimexpired command
+// extension for websocket connections.
+func handleStopNotifyClaimExpired(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterClaimExpiredUpdates(wsc)
+	return nil, nil
+}
+
 // handleNotifySpent implements the notifyspent command extension for
 // websocket connections.
 func handleNotifySpent(wsc *wsClient, icmd interface{}) (interface{}, error) {
@@ -2458,28 +3024,29 @@ func rescanBlockFilter(filter *wsClientFilter, block *btcutil.Block, params *cha
 
 		// Scan outputs.
 		for i, output := range msgTx.TxOut {
-			_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			_, addrs, _, _ := txscript.ExtractPkScriptAddrs(
 				output.PkScript, params)
-			if err != nil {
-				continue
-			}
+			relevant := filter.existsOutput(output.PkScript)
 			for _, a := range addrs {
-				if !filter.existsAddress(a) {
-					continue
+				if filter.existsAddress(a) {
+					relevant = true
 				}
+			}
+			if !relevant {
+				continue
+			}
 
-				op := wire.OutPoint{
-					Hash:  *tx.Hash(),
-					Index: uint32(i),
-				}
-				filter.addUnspentOutPoint(&op)
+			op := wire.OutPoint{
+				Hash:  *tx.Hash(),
+				Index: uint32(i),
+			}
+			filter.addUnspentOutPoint(&op)
 
-				if !added {
-					transactions = append(
-						transactions,
-						txHexString(msgTx))
-					added = true
-				}
+			if !added {
+				transactions = append(
+					transactions,
+					txHexString(msgTx))
+				added = true
 			}
 		}
 	}
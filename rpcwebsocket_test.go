@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbcd/btcec"
+	"github.com/lbryio/lbcd/btcjson"
+	"github.com/lbryio/lbcd/chaincfg"
+	"github.com/lbryio/lbcd/txscript"
+	"github.com/lbryio/lbcd/wire"
+	btcutil "github.com/lbryio/lbcutil"
+)
+
+// TestWSClientFilterOutputs ensures the output descriptors and claim names of
+// transaction filters match the expected outputs, and that the matched outputs
+// are watched for spends by rescanBlockFilter.
+func TestWSClientFilterOutputs(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("NewPrivateKey: unexpected error: %v", err)
+	}
+	pubKey := privKey.PubKey().SerializeCompressed()
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	pkh, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	claimScript, err := txscript.NewClaimNameScript([]byte("@Channel"),
+		[]byte("value"), []byte{txscript.OP_TRUE})
+	if err != nil {
+		t.Fatalf("NewClaimNameScript: unexpected error: %v", err)
+	}
+	claimPkh, err := txscript.NewClaimNameScript([]byte("other"),
+		[]byte("value"), pkh)
+	if err != nil {
+		t.Fatalf("NewClaimNameScript: unexpected error: %v", err)
+	}
+
+	descriptor := "wpkh(" + hex.EncodeToString(pubKey) + ")#abcdefgh"
+	scripts, err := parseFilterDescriptors([]string{
+		"pkh(" + hex.EncodeToString(pubKey) + ")", descriptor,
+	}, params)
+	if err != nil {
+		t.Fatalf("parseFilterDescriptors: unexpected error: %v", err)
+	}
+	if _, err := parseFilterDescriptors([]string{"pkh(00)"}, params); err == nil {
+		t.Fatalf("parseFilterDescriptors: invalid descriptor accepted")
+	}
+
+	filter := newWSClientFilter(nil, nil, params)
+	for _, script := range scripts {
+		filter.addScript(script)
+	}
+	filter.addClaimName("@channel")
+
+	tests := []struct {
+		name   string
+		script []byte
+		want   bool
+	}{
+		{"descriptor", pkh, true},
+		{"descriptor with claim prefix", claimPkh, true},
+		{"normalized claim name", claimScript, true},
+		{"unrelated", []byte{txscript.OP_TRUE}, false},
+	}
+	for _, test := range tests {
+		if got := filter.existsOutput(test.script); got != test.want {
+			t.Errorf("existsOutput %s: got %v, want %v", test.name,
+				got, test.want)
+		}
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: [32]byte{1}}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1, []byte{txscript.OP_TRUE}))
+	tx.AddTxOut(wire.NewTxOut(1, claimScript))
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{wire.NewMsgTx(wire.TxVersion), tx},
+	})
+	if txs := rescanBlockFilter(filter, block, params); len(txs) != 1 {
+		t.Fatalf("rescanBlockFilter: got %d transactions, want 1",
+			len(txs))
+	}
+	op := wire.OutPoint{Hash: tx.TxHash(), Index: 1}
+	if !filter.existsUnspentOutPoint(&op) {
+		t.Fatalf("matched output %v is not watched", op)
+	}
+}
+
+// waitClaimTrieClients waits until whether any client is registered for claim
+// trie updates matches the passed value, since the registrations are handled
+// asynchronously.
+func waitClaimTrieClients(t *testing.T, m *wsNotificationManager, want bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); m.HasClaimTrieClients() != want; {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for HasClaimTrieClients to be %v",
+				want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestNotifyClaimTrieChanged ensures the claimtriechanged notification carries
+// the names passed along with the connected block rather than looking them up
+// once the notification is processed, and that it isn't sent for the blocks
+// whose names weren't collected.
+func TestNotifyClaimTrieChanged(t *testing.T) {
+	m := newWsNotificationManager(nil)
+	m.Start()
+	wsc := &wsClient{
+		quit:     make(chan struct{}),
+		ntfnChan: make(chan []byte, 1),
+	}
+	m.AddClient(wsc)
+	defer func() {
+		m.RemoveClient(wsc)
+		for m.NumClients() != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		m.Shutdown()
+		m.WaitForShutdown()
+	}()
+	if m.HasClaimTrieClients() {
+		t.Fatal("HasClaimTrieClients: unexpected registered client")
+	}
+	m.RegisterClaimTrieUpdates(wsc)
+	waitClaimTrieClients(t, m, true)
+
+	msgBlock := wire.NewMsgBlock(&wire.BlockHeader{
+		ClaimTrie: [32]byte{1},
+	})
+	block := btcutil.NewBlock(msgBlock)
+	block.SetHeight(9)
+	m.NotifyBlockConnected(block, nil)
+	block = btcutil.NewBlock(msgBlock)
+	block.SetHeight(10)
+	m.NotifyBlockConnected(block, []string{"a", "b"})
+
+	var marshalled []byte
+	select {
+	case marshalled = <-wsc.ntfnChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the claim trie notification")
+	}
+	var ntfn btcjson.Request
+	if err := json.Unmarshal(marshalled, &ntfn); err != nil {
+		t.Fatalf("unable to unmarshal notification: %v", err)
+	}
+	if ntfn.Method != btcjson.ClaimTrieChangedNtfnMethod {
+		t.Fatalf("got method %q, want %q", ntfn.Method,
+			btcjson.ClaimTrieChangedNtfnMethod)
+	}
+	if len(ntfn.Params) != 4 {
+		t.Fatalf("got %d params, want 4", len(ntfn.Params))
+	}
+	var height int32
+	var names []string
+	if err := json.Unmarshal(ntfn.Params[1], &height); err != nil {
+		t.Fatalf("unable to unmarshal height: %v", err)
+	}
+	if err := json.Unmarshal(ntfn.Params[3], &names); err != nil {
+		t.Fatalf("unable to unmarshal names: %v", err)
+	}
+	if height != 10 || len(names) != 2 || names[0] != "a" ||
+		names[1] != "b" {
+
+		t.Fatalf("got height %d and names %v, want 10 and [a b]",
+			height, names)
+	}
+
+	m.UnregisterClaimTrieUpdates(wsc)
+	waitClaimTrieClients(t, m, false)
+}
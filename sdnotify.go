@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// These constants define the states sent to the service manager.
+const (
+	sdNotifyReady    = "READY=1"
+	sdNotifyStopping = "STOPPING=1"
+	sdNotifyWatchdog = "WATCHDOG=1"
+)
+
+// sdNotify sends the passed state to the service manager, such as systemd,
+// through the socket named by the NOTIFY_SOCKET environment variable.  It
+// returns whether the state was sent, which is never the case when lbcd isn't
+// run by a service manager expecting notifications.
+func sdNotify(state string) (bool, error) {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return false, nil
+	}
+
+	// Abstract sockets, which are given with a leading @, are handled by
+	// the net package.
+	socketAddr := &net.UnixAddr{Name: socketPath, Net: "unixgram"}
+	conn, err := net.DialUnix(socketAddr.Net, nil, socketAddr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sdWatchdogInterval returns the interval at which the service manager expects
+// the watchdog to be kept alive, which is zero when the watchdog is disabled or
+// meant for another process.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" &&
+		pid != strconv.Itoa(os.Getpid()) {
+
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// sdNotifyReadyAndWatch notifies the service manager that lbcd is ready, and
+// keeps the watchdog of the service manager alive until the returned function
+// is called, which notifies the service manager that lbcd is stopping.
+func sdNotifyReadyAndWatch() func() {
+	if sent, err := sdNotify(sdNotifyReady); err != nil {
+		btcdLog.Warnf("Unable to notify the service manager: %v", err)
+	} else if sent {
+		btcdLog.Debugf("Notified the service manager lbcd is ready")
+	}
+
+	quit := make(chan struct{})
+	if interval := sdWatchdogInterval(); interval > 0 {
+		// Ping the watchdog twice per interval as recommended so a
+		// late ping doesn't get the service restarted.
+		go func() {
+			ticker := time.NewTicker(interval / 2)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					_, err := sdNotify(sdNotifyWatchdog)
+					if err != nil {
+						btcdLog.Warnf("Unable to keep the "+
+							"watchdog alive: %v", err)
+					}
+				case <-quit:
+					return
+				}
+			}
+		}()
+	}
+
+	return func() {
+		close(quit)
+		if _, err := sdNotify(sdNotifyStopping); err != nil {
+			btcdLog.Warnf("Unable to notify the service manager: %v",
+				err)
+		}
+	}
+}
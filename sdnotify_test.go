@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestSdNotify ensures states are sent to the socket named by NOTIFY_SOCKET
+// and that nothing is sent without it.
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := sdNotify(sdNotifyReady)
+	if sent || err != nil {
+		t.Fatalf("sdNotify: got %v, %v without socket", sent, err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram",
+		&net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unable to listen on unix datagram socket: %v", err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socketPath)
+	sent, err = sdNotify(sdNotifyReady)
+	if !sent || err != nil {
+		t.Fatalf("sdNotify: got %v, %v", sent, err)
+	}
+
+	buf := make([]byte, 64)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("unable to read notification: %v", err)
+	}
+	if got := string(buf[:n]); got != sdNotifyReady {
+		t.Fatalf("mismatched notification: got %q, want %q", got,
+			sdNotifyReady)
+	}
+}
+
+// TestSdWatchdogInterval ensures the watchdog interval is only enabled for this
+// process.
+func TestSdWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{"", "", 0},
+		{"invalid", "", 0},
+		{"30000000", "", 30 * time.Second},
+		{"30000000", strconv.Itoa(os.Getpid()), 30 * time.Second},
+		{"30000000", strconv.Itoa(os.Getpid() + 1), 0},
+	}
+	for _, test := range tests {
+		t.Setenv("WATCHDOG_USEC", test.usec)
+		t.Setenv("WATCHDOG_PID", test.pid)
+		if got := sdWatchdogInterval(); got != test.want {
+			t.Errorf("sdWatchdogInterval(%q, %q): got %v, want %v",
+				test.usec, test.pid, got, test.want)
+		}
+	}
+}
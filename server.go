@@ -15,6 +15,7 @@ import (
 	"math"
 	"net"
 	"path"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
@@ -36,8 +37,11 @@ import (
 	"github.com/lbryio/lbcd/mempool"
 	"github.com/lbryio/lbcd/mining"
 	"github.com/lbryio/lbcd/mining/cpuminer"
+	"github.com/lbryio/lbcd/mining/stratum"
 	"github.com/lbryio/lbcd/netsync"
 	"github.com/lbryio/lbcd/peer"
+	"github.com/lbryio/lbcd/portmap"
+	"github.com/lbryio/lbcd/torcontrol"
 	"github.com/lbryio/lbcd/txscript"
 	"github.com/lbryio/lbcd/version"
 	"github.com/lbryio/lbcd/wire"
@@ -63,6 +67,20 @@ const (
 	// retries when connecting to persistent peers.  It is adjusted by the
 	// number of retries such that there is a retry backoff.
 	connectionRetryInterval = time.Second * 5
+
+	// maxCmpctBlockDepth is the maximum depth of the blocks which are sent
+	// as compact blocks when requested.  Deeper blocks are sent in full
+	// since their transactions are unlikely to be in the memory pool of
+	// the peer.
+	maxCmpctBlockDepth = 5
+
+	// maxBlockTxnDepth is the maximum depth of the blocks whose
+	// transactions are served with blocktxn messages.
+	maxBlockTxnDepth = 10
+
+	// onionPrivateKeyFilename is the name of the file in the data directory
+	// the private key of the onion service is saved to.
+	onionPrivateKeyFilename = "onion_v3_private_key"
 )
 
 var (
@@ -156,18 +174,12 @@ type updatePeerHeightsMsg struct {
 	originPeer *peer.Peer
 }
 
-type bannedPeriod struct {
-	since time.Time
-	until time.Time
-}
-
 // peerState maintains state of inbound, persistent, outbound peers as well
-// as banned peers and outbound groups.
+// as outbound groups.
 type peerState struct {
 	inboundPeers    map[int32]*serverPeer
 	outboundPeers   map[int32]*serverPeer
 	persistentPeers map[int32]*serverPeer
-	banned          map[string]bannedPeriod
 	outboundGroups  map[string]int
 }
 
@@ -197,6 +209,51 @@ func (ps *peerState) forAllPeers(closure func(sp *serverPeer)) {
 	ps.forAllOutboundPeers(closure)
 }
 
+// anchors returns the addresses of the outbound peers to reconnect to first
+// after a restart, which are the full nodes connected the longest that weren't
+// manually added.
+func (ps *peerState) anchors() []*wire.NetAddressV2 {
+	var peers []*serverPeer
+	for _, sp := range ps.outboundPeers {
+		if sp.VerAckReceived() &&
+			sp.Services()&wire.SFNodeNetwork == wire.SFNodeNetwork {
+
+			peers = append(peers, sp)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].TimeConnected().Before(peers[j].TimeConnected())
+	})
+
+	var addrs []*wire.NetAddressV2
+	for _, sp := range peers {
+		if len(addrs) == addrmgr.MaxAnchors {
+			break
+		}
+		addrs = append(addrs, sp.NAV2())
+	}
+	return addrs
+}
+
+// evictionCandidates returns the inbound peers which may be evicted to make
+// room for a new one.  Whitelisted peers are never evicted.
+func (ps *peerState) evictionCandidates() []connmgr.EvictionCandidate {
+	var candidates []connmgr.EvictionCandidate
+	for id, sp := range ps.inboundPeers {
+		if sp.isWhitelisted {
+			continue
+		}
+		candidates = append(candidates, connmgr.EvictionCandidate{
+			ID:          uint64(id),
+			NetGroup:    addrmgr.GroupKeyV2(sp.NAV2()),
+			ConnectedAt: sp.TimeConnected(),
+			PingTime: time.Duration(sp.LastPingMicros()) *
+				time.Microsecond,
+		})
+	}
+	return candidates
+}
+
 // cfHeaderKV is a tuple of a filter header and its associated block hash. The
 // struct is used to cache cfcheckpt responses.
 type cfHeaderKV struct {
@@ -218,13 +275,19 @@ type server struct {
 	chainParams          *chaincfg.Params
 	addrManager          *addrmgr.AddrManager
 	connManager          *connmgr.ConnManager
-	sigCache             *txscript.SigCache
+	sigCache             txscript.SignatureCache
+	diskSigCache         *txscript.DiskSigCache
 	hashCache            *txscript.HashCache
 	rpcServer            *rpcServer
+	grpcServer           *grpcServer
+	publicRPCServer      *publicRPCServer
+	dbScrubber           *dbScrubber
+	chainAlerter         *chainAlerter
 	syncManager          *netsync.SyncManager
 	chain                *blockchain.BlockChain
 	txMemPool            *mempool.TxPool
 	cpuMiner             *cpuminer.CPUMiner
+	stratumServer        *stratum.Server
 	modifyRebroadcastInv chan interface{}
 	newPeers             chan *serverPeer
 	donePeers            chan *serverPeer
@@ -235,18 +298,24 @@ type server struct {
 	peerHeightsUpdate    chan updatePeerHeightsMsg
 	wg                   sync.WaitGroup
 	quit                 chan struct{}
-	nat                  NAT
+	lifecycle            *lifecycle
 	db                   database.DB
+	banManager           *banManager
 	timeSource           blockchain.MedianTimeSource
 	services             wire.ServiceFlag
+	bloomListeners       []bloomListener
 
 	// The following fields are used for optional indexes.  They will be nil
 	// if the associated index is not enabled.  These fields are set during
 	// initial creation of the server and never changed afterwards, so they
 	// do not need to be protected for concurrent access.
-	txIndex   *indexers.TxIndex
-	addrIndex *indexers.AddrIndex
-	cfIndex   *indexers.CfIndex
+	txIndex          *indexers.TxIndex
+	addrIndex        *indexers.AddrIndex
+	cfIndex          *indexers.CfIndex
+	claimIDIndex     *indexers.ClaimIDIndex
+	channelIndex     *indexers.ClaimChannelIndex
+	spentIndex       *indexers.SpentIndex
+	claimFilterIndex *indexers.ClaimFilterIndex
 
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
@@ -264,6 +333,28 @@ type server struct {
 	// agentWhitelist is a list of whitelisted user agent substrings, no
 	// whitelisting will be applied if the list is empty or nil.
 	agentWhitelist []string
+
+	// inboundTrickle schedules the times inventory is trickled to all the
+	// inbound peers.
+	inboundTrickle *peer.TrickleTimer
+
+	// portMapper maps the listening port on the NAT gateway of the local
+	// network when the --natpmp or --upnp options are set.
+	portMapper *portmap.Manager
+
+	// torController creates the onion service of the server when the
+	// --listenonion option is set.  The address of the service is nil until
+	// it has been created, and is protected by onionMtx.
+	torController *torcontrol.Controller
+	onionService  *wire.NetAddressV2
+	onionMtx      sync.RWMutex
+
+	// bytesRecvPerMsg and bytesSentPerMsg are the total bytes received
+	// from and sent to all peers since start by message command.  They are
+	// protected by msgBytesMtx.
+	bytesRecvPerMsg map[string]uint64
+	bytesSentPerMsg map[string]uint64
+	msgBytesMtx     sync.Mutex
 }
 
 // serverPeer extends the peer to maintain state shared by the server and
@@ -277,15 +368,16 @@ type serverPeer struct {
 	connReq        *connmgr.ConnReq
 	server         *server
 	persistent     bool
+	services       wire.ServiceFlag
 	continueHash   *chainhash.Hash
 	relayMtx       sync.Mutex
 	disableRelayTx bool
 	sentAddrs      bool
 	isWhitelisted  bool
-	filter         *bloom.Filter
+	filter         *peerBloomFilter
 	addressesMtx   sync.RWMutex
 	knownAddresses map[string]struct{}
-	banScore       connmgr.DynamicBanScore
+	misbehavior    connmgr.MisbehaviorScore
 	quit           chan struct{}
 	// The following chans are used to sync blockmanager and server.
 	txProcessed    chan struct{}
@@ -298,7 +390,8 @@ func newServerPeer(s *server, isPersistent bool) *serverPeer {
 	return &serverPeer{
 		server:         s,
 		persistent:     isPersistent,
-		filter:         bloom.LoadFilter(nil),
+		services:       s.services,
+		filter:         newPeerBloomFilter(float64(cfg.PeerBloomWork) * 1024),
 		knownAddresses: make(map[string]struct{}),
 		quit:           make(chan struct{}),
 		txProcessed:    make(chan struct{}, 1),
@@ -331,6 +424,25 @@ func (sp *serverPeer) addressKnown(na *wire.NetAddress) bool {
 	return exists
 }
 
+// addKnownAddressesV2 adds the given addrv2 addresses to the set of known
+// addresses to the peer to prevent sending duplicate addresses.
+func (sp *serverPeer) addKnownAddressesV2(addresses []*wire.NetAddressV2) {
+	sp.addressesMtx.Lock()
+	for _, na := range addresses {
+		sp.knownAddresses[addrmgr.NetAddressKeyV2(na)] = struct{}{}
+	}
+	sp.addressesMtx.Unlock()
+}
+
+// addressKnownV2 true if the given addrv2 address is already known to the
+// peer.
+func (sp *serverPeer) addressKnownV2(na *wire.NetAddressV2) bool {
+	sp.addressesMtx.RLock()
+	_, exists := sp.knownAddresses[addrmgr.NetAddressKeyV2(na)]
+	sp.addressesMtx.RUnlock()
+	return exists
+}
+
 // setDisableRelayTx toggles relaying of transactions for the given peer.
 // It is safe for concurrent access.
 func (sp *serverPeer) setDisableRelayTx(disable bool) {
@@ -369,12 +481,32 @@ func (sp *serverPeer) pushAddrMsg(addresses []*wire.NetAddress) {
 	sp.addKnownAddresses(known)
 }
 
-// addBanScore increases the persistent and decaying ban score fields by the
-// values passed as parameters. If the resulting score exceeds half of the ban
+// pushAddrV2Msg sends an addrv2 message to the connected peer using the
+// provided addresses.
+func (sp *serverPeer) pushAddrV2Msg(addresses []*wire.NetAddressV2) {
+	// Filter addresses already known to the peer.
+	addrs := make([]*wire.NetAddressV2, 0, len(addresses))
+	for _, addr := range addresses {
+		if !sp.addressKnownV2(addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	known, err := sp.PushAddrV2Msg(addrs)
+	if err != nil {
+		peerLog.Errorf("Can't push addrv2 message to %s: %v", sp.Peer, err)
+		sp.Disconnect()
+		return
+	}
+	sp.addKnownAddressesV2(known)
+}
+
+// addMisbehavior increases the persistent and decaying scores of the passed
+// category of violations by the values passed as parameters, scaled by the
+// weight of the category. If the resulting ban score exceeds half of the ban
 // threshold, a warning is logged including the reason provided. Further, if
 // the score is above the ban threshold, the peer will be banned and
-// disconnected.
-func (sp *serverPeer) addBanScore(persistent, transient uint32, reason string) bool {
+// disconnected.  Stalling is only tracked and never causes a ban.
+func (sp *serverPeer) addMisbehavior(violation connmgr.Violation, persistent, transient uint32, reason string) bool {
 	// No warning is logged and no score is calculated if banning is disabled.
 	if cfg.DisableBanning {
 		return false
@@ -384,18 +516,25 @@ func (sp *serverPeer) addBanScore(persistent, transient uint32, reason string) b
 		return false
 	}
 
+	persistent = cfg.violationWeights.Apply(violation, persistent)
+	transient = cfg.violationWeights.Apply(violation, transient)
 	warnThreshold := cfg.BanThreshold >> 1
 	if transient == 0 && persistent == 0 {
 		// The score is not being increased, but a warning message is still
 		// logged if the score is above the warn threshold.
-		score := sp.banScore.Int()
+		score := sp.misbehavior.Int()
 		if score > warnThreshold {
 			peerLog.Warnf("Misbehaving peer %s: %s -- ban score is %d, "+
 				"it was not increased this time", sp, reason, score)
 		}
 		return false
 	}
-	score := sp.banScore.Increase(persistent, transient)
+	score := sp.misbehavior.Increase(violation, persistent, transient)
+	if !violation.Bannable() {
+		peerLog.Debugf("Peer %s: %s -- %v score increased to %d", sp,
+			reason, violation, sp.misbehavior.Score(violation))
+		return false
+	}
 	if score > warnThreshold {
 		peerLog.Warnf("Misbehaving peer %s: %s -- ban score increased to %d", sp, reason, score)
 		if score > cfg.BanThreshold {
@@ -433,7 +572,7 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 	// it is updated regardless in the case a new minimum protocol version is
 	// enforced and the remote node has not upgraded yet.
 	isInbound := sp.Inbound()
-	remoteAddr := sp.NA()
+	remoteAddr := sp.NAV2()
 	addrManager := sp.server.addrManager
 	if !cfg.SimNet && !isInbound {
 		addrManager.SetServices(remoteAddr, msg.Services)
@@ -491,6 +630,15 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 // OnVerAck is invoked when a peer receives a verack bitcoin message and is used
 // to kick start communication with them.
 func (sp *serverPeer) OnVerAck(_ *peer.Peer, _ *wire.MsgVerAck) {
+	// Signal support for compact blocks including witness data.  New
+	// blocks are only requested to be announced with them once the peer
+	// has provided a block.  The message is dropped by the peer when the
+	// negotiated protocol version doesn't support it.
+	if sp.IsWitnessEnabled() {
+		sp.QueueMessage(wire.NewMsgSendCmpct(false,
+			wire.CmpctBlockVersion2), nil)
+	}
+
 	sp.server.AddPeer(sp)
 }
 
@@ -499,9 +647,9 @@ func (sp *serverPeer) OnVerAck(_ *peer.Peer, _ *wire.MsgVerAck) {
 // pool up to the maximum inventory allowed per message.  When the peer has a
 // bloom filter loaded, the contents are filtered accordingly.
 func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
-	// Only allow mempool requests if the server has bloom filtering
-	// enabled.
-	if sp.server.services&wire.SFNodeBloom != wire.SFNodeBloom {
+	// Only allow mempool requests if bloom filtering is enabled for the
+	// peer.
+	if sp.services&wire.SFNodeBloom != wire.SFNodeBloom {
 		peerLog.Debugf("peer %v sent mempool request with bloom "+
 			"filtering disabled -- disconnecting", sp)
 		sp.Disconnect()
@@ -512,7 +660,7 @@ func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 	// The ban score accumulates and passes the ban threshold if a burst of
 	// mempool messages comes from a peer. The score decays each minute to
 	// half of its value.
-	if sp.addBanScore(0, 33, "mempool") {
+	if sp.addMisbehavior(connmgr.ProtocolAbuse, 0, 33, "mempool") {
 		return
 	}
 
@@ -529,7 +677,7 @@ func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 		// Either add all transactions when there is no bloom filter,
 		// or only the transactions that match the filter when there is
 		// one.
-		if !sp.filter.IsLoaded() || sp.filter.MatchTxAndUpdate(txDesc.Tx) {
+		if !sp.filter.IsLoaded() || sp.matchTxAndUpdate(txDesc.Tx) {
 			iv := wire.NewInvVect(wire.InvTypeTx, txDesc.Tx.Hash())
 			invMsg.AddInvVect(iv)
 			if len(invMsg.InvList)+1 > wire.MaxInvPerMsg {
@@ -582,6 +730,12 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 	iv := wire.NewInvVect(wire.InvTypeBlock, block.Hash())
 	sp.AddKnownInventory(iv)
 
+	// Blocks requested to repair the corrupt copy in the database are
+	// already part of the chain, so don't process them again.
+	if sp.server.dbScrubber.MaybeRepair(block) {
+		return
+	}
+
 	// Queue the block up to be handled by the block
 	// manager and intentionally block further receives
 	// until the bitcoin block is fully processed and known
@@ -597,6 +751,62 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 	<-sp.blockProcessed
 }
 
+// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin message.
+// It blocks until the block has been reconstructed and, when none of its
+// transactions are missing, fully processed.
+func (sp *serverPeer) OnCmpctBlock(_ *peer.Peer, msg *wire.MsgCmpctBlock) {
+	// Add the block to the known inventory for the peer.
+	blockHash := msg.BlockHash()
+	iv := wire.NewInvVect(wire.InvTypeBlock, &blockHash)
+	sp.AddKnownInventory(iv)
+
+	sp.server.syncManager.QueueCmpctBlock(msg, sp.Peer, sp.blockProcessed)
+	<-sp.blockProcessed
+}
+
+// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin message.  It
+// blocks until the block completed by the transactions has been fully
+// processed.
+func (sp *serverPeer) OnBlockTxn(_ *peer.Peer, msg *wire.MsgBlockTxn) {
+	sp.server.syncManager.QueueBlockTxn(msg, sp.Peer, sp.blockProcessed)
+	<-sp.blockProcessed
+}
+
+// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin message.
+// It responds with the requested transactions of the block, or with the full
+// block when it is too deep in the chain.
+func (sp *serverPeer) OnGetBlockTxn(_ *peer.Peer, msg *wire.MsgGetBlockTxn) {
+	chain := sp.server.chain
+	block, err := chain.BlockByHash(&msg.BlockHash)
+	if err != nil {
+		peerLog.Debugf("Unable to fetch requested block %v for %v: %v",
+			msg.BlockHash, sp, err)
+		return
+	}
+
+	encoding := wire.BaseEncoding
+	if sp.IsWitnessEnabled() {
+		encoding = wire.WitnessEncoding
+	}
+
+	if chain.BestSnapshot().Height-block.Height() >= maxBlockTxnDepth {
+		sp.QueueMessageWithEncoding(block.MsgBlock(), nil, encoding)
+		return
+	}
+
+	txns := block.MsgBlock().Transactions
+	blockTxn := wire.NewMsgBlockTxn(&msg.BlockHash)
+	for _, index := range msg.Indexes {
+		if int(index) >= len(txns) {
+			sp.addMisbehavior(connmgr.InvalidMessage, 100, 0,
+				"getblocktxn index out of range")
+			return
+		}
+		blockTxn.AddTransaction(txns[index])
+	}
+	sp.QueueMessageWithEncoding(blockTxn, nil, encoding)
+}
+
 // OnInv is invoked when a peer receives an inv bitcoin message and is
 // used to examine the inventory being advertised by the remote peer and react
 // accordingly.  We pass the message down to blockmanager which will call
@@ -654,7 +864,8 @@ func (sp *serverPeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
 	// bursts of small requests are not penalized as that would potentially ban
 	// peers performing IBD.
 	// This incremental score decays each minute to half of its value.
-	if sp.addBanScore(0, uint32(length)*99/wire.MaxInvPerMsg, "getdata") {
+	if sp.addMisbehavior(connmgr.ProtocolAbuse, 0,
+		uint32(length)*99/wire.MaxInvPerMsg, "getdata") {
 		return
 	}
 
@@ -684,6 +895,8 @@ func (sp *serverPeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
 			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan, wire.WitnessEncoding)
 		case wire.InvTypeBlock:
 			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
+		case wire.InvTypeCmpctBlock:
+			err = sp.server.pushCmpctBlockMsg(sp, &iv.Hash, c, waitChan)
 		case wire.InvTypeFilteredWitnessBlock:
 			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.WitnessEncoding)
 		case wire.InvTypeFilteredBlock:
@@ -795,6 +1008,32 @@ func (sp *serverPeer) OnGetHeaders(_ *peer.Peer, msg *wire.MsgGetHeaders) {
 	sp.QueueMessage(&wire.MsgHeaders{Headers: blockHeaders}, nil)
 }
 
+// filterIndexer provides the filters of a type along with their hashes and
+// headers.  It is implemented by the indexes of the types of filters served.
+type filterIndexer interface {
+	FilterByBlockHash(*chainhash.Hash, wire.FilterType) ([]byte, error)
+	FiltersByBlockHashes([]*chainhash.Hash, wire.FilterType) ([][]byte, error)
+	FilterHeaderByBlockHash(*chainhash.Hash, wire.FilterType) ([]byte, error)
+	FilterHeadersByBlockHashes([]*chainhash.Hash, wire.FilterType) ([][]byte, error)
+	FilterHashesByBlockHashes([]*chainhash.Hash, wire.FilterType) ([][]byte, error)
+}
+
+// filterIndex returns the index maintaining the filters of the passed type, or
+// nil when filters of the type aren't maintained.
+func filterIndex(cfIndex *indexers.CfIndex, claimFilterIndex *indexers.ClaimFilterIndex,
+	filterType wire.FilterType) filterIndexer {
+
+	// The indexes are checked against nil before being converted to the
+	// interface so a disabled index results in a nil interface.
+	switch {
+	case filterType == wire.GCSFilterRegular && cfIndex != nil:
+		return cfIndex
+	case filterType == wire.GCSFilterClaimName && claimFilterIndex != nil:
+		return claimFilterIndex
+	}
+	return nil
+}
+
 // OnGetCFilters is invoked when a peer receives a getcfilters bitcoin message.
 func (sp *serverPeer) OnGetCFilters(_ *peer.Peer, msg *wire.MsgGetCFilters) {
 	// Ignore getcfilters requests if not in sync.
@@ -804,12 +1043,10 @@ func (sp *serverPeer) OnGetCFilters(_ *peer.Peer, msg *wire.MsgGetCFilters) {
 
 	// We'll also ensure that the remote party is requesting a set of
 	// filters that we actually currently maintain.
-	switch msg.FilterType {
-	case wire.GCSFilterRegular:
-		break
-
-	default:
-		peerLog.Debug("Filter request for unknown filter: %v",
+	index := filterIndex(sp.server.cfIndex, sp.server.claimFilterIndex,
+		msg.FilterType)
+	if index == nil {
+		peerLog.Debugf("Filter request for unknown filter: %v",
 			msg.FilterType)
 		return
 	}
@@ -829,7 +1066,7 @@ func (sp *serverPeer) OnGetCFilters(_ *peer.Peer, msg *wire.MsgGetCFilters) {
 		hashPtrs[i] = &hashes[i]
 	}
 
-	filters, err := sp.server.cfIndex.FiltersByBlockHashes(
+	filters, err := index.FiltersByBlockHashes(
 		hashPtrs, msg.FilterType,
 	)
 	if err != nil {
@@ -860,12 +1097,10 @@ func (sp *serverPeer) OnGetCFHeaders(_ *peer.Peer, msg *wire.MsgGetCFHeaders) {
 
 	// We'll also ensure that the remote party is requesting a set of
 	// headers for filters that we actually currently maintain.
-	switch msg.FilterType {
-	case wire.GCSFilterRegular:
-		break
-
-	default:
-		peerLog.Debug("Filter request for unknown headers for "+
+	index := filterIndex(sp.server.cfIndex, sp.server.claimFilterIndex,
+		msg.FilterType)
+	if index == nil {
+		peerLog.Debugf("Filter request for unknown headers for "+
 			"filter: %v", msg.FilterType)
 		return
 	}
@@ -904,7 +1139,7 @@ func (sp *serverPeer) OnGetCFHeaders(_ *peer.Peer, msg *wire.MsgGetCFHeaders) {
 	}
 
 	// Fetch the raw filter hash bytes from the database for all blocks.
-	filterHashes, err := sp.server.cfIndex.FilterHashesByBlockHashes(
+	filterHashes, err := index.FilterHashesByBlockHashes(
 		hashPtrs, msg.FilterType,
 	)
 	if err != nil {
@@ -921,7 +1156,7 @@ func (sp *serverPeer) OnGetCFHeaders(_ *peer.Peer, msg *wire.MsgGetCFHeaders) {
 
 		// Fetch the raw committed filter header bytes from the
 		// database.
-		headerBytes, err := sp.server.cfIndex.FilterHeaderByBlockHash(
+		headerBytes, err := index.FilterHeaderByBlockHash(
 			prevBlockHash, msg.FilterType)
 		if err != nil {
 			peerLog.Errorf("Error retrieving CF header: %v", err)
@@ -977,12 +1212,10 @@ func (sp *serverPeer) OnGetCFCheckpt(_ *peer.Peer, msg *wire.MsgGetCFCheckpt) {
 
 	// We'll also ensure that the remote party is requesting a set of
 	// checkpoints for filters that we actually currently maintain.
-	switch msg.FilterType {
-	case wire.GCSFilterRegular:
-		break
-
-	default:
-		peerLog.Debug("Filter request for unknown checkpoints for "+
+	index := filterIndex(sp.server.cfIndex, sp.server.claimFilterIndex,
+		msg.FilterType)
+	if index == nil {
+		peerLog.Debugf("Filter request for unknown checkpoints for "+
 			"filter: %v", msg.FilterType)
 		return
 	}
@@ -1074,7 +1307,7 @@ func (sp *serverPeer) OnGetCFCheckpt(_ *peer.Peer, msg *wire.MsgGetCFCheckpt) {
 	for i := forkIdx; i < len(blockHashes); i++ {
 		blockHashPtrs = append(blockHashPtrs, &blockHashes[i])
 	}
-	filterHeaders, err := sp.server.cfIndex.FilterHeadersByBlockHashes(
+	filterHeaders, err := index.FilterHeadersByBlockHashes(
 		blockHashPtrs, msg.FilterType,
 	)
 	if err != nil {
@@ -1119,17 +1352,17 @@ func (sp *serverPeer) OnGetCFCheckpt(_ *peer.Peer, msg *wire.MsgGetCFCheckpt) {
 	sp.QueueMessage(checkptMsg, nil)
 }
 
-// enforceNodeBloomFlag disconnects the peer if the server is not configured to
-// allow bloom filters.  Additionally, if the peer has negotiated to a protocol
-// version  that is high enough to observe the bloom filter service support bit,
-// it will be banned since it is intentionally violating the protocol.
+// enforceNodeBloomFlag disconnects the peer if bloom filters were not advertised
+// to it.  Additionally, if the peer has negotiated to a protocol version  that
+// is high enough to observe the bloom filter service support bit, it will be
+// banned since it is intentionally violating the protocol.
 func (sp *serverPeer) enforceNodeBloomFlag(cmd string) bool {
-	if sp.server.services&wire.SFNodeBloom != wire.SFNodeBloom {
+	if sp.services&wire.SFNodeBloom != wire.SFNodeBloom {
 		// Ban the peer if the protocol version is high enough that the
 		// peer is knowingly violating the protocol and banning is
 		// enabled.
 		//
-		// NOTE: Even though the addBanScore function already examines
+		// NOTE: Even though the addMisbehavior function already examines
 		// whether or not banning is enabled, it is checked here as well
 		// to ensure the violation is logged and the peer is
 		// disconnected regardless.
@@ -1138,7 +1371,7 @@ func (sp *serverPeer) enforceNodeBloomFlag(cmd string) bool {
 
 			// Disconnect the peer regardless of whether it was
 			// banned.
-			sp.addBanScore(100, 0, cmd)
+			sp.addMisbehavior(connmgr.ProtocolAbuse, 100, 0, cmd)
 			sp.Disconnect()
 			return false
 		}
@@ -1216,7 +1449,7 @@ func (sp *serverPeer) OnFilterClear(_ *peer.Peer, msg *wire.MsgFilterClear) {
 // message and it used to load a bloom filter that should be used for
 // delivering merkle blocks and associated transactions that match the filter.
 // The peer will be disconnected if the server is not configured to allow bloom
-// filters.
+// filters or if the filter is larger than allowed.
 func (sp *serverPeer) OnFilterLoad(_ *peer.Peer, msg *wire.MsgFilterLoad) {
 	// Disconnect and/or ban depending on the node bloom services flag and
 	// negotiated protocol version.
@@ -1224,6 +1457,15 @@ func (sp *serverPeer) OnFilterLoad(_ *peer.Peer, msg *wire.MsgFilterLoad) {
 		return
 	}
 
+	if len(msg.Filter) > cfg.MaxBloomFilterSize {
+		peerLog.Debugf("%s sent a filterload request with a %d byte "+
+			"filter, more than the %d bytes allowed -- "+
+			"disconnecting", sp, len(msg.Filter),
+			cfg.MaxBloomFilterSize)
+		sp.Disconnect()
+		return
+	}
+
 	sp.setDisableRelayTx(false)
 
 	sp.filter.Reload(msg)
@@ -1258,11 +1500,14 @@ func (sp *serverPeer) OnGetAddr(_ *peer.Peer, msg *wire.MsgGetAddr) {
 	}
 	sp.sentAddrs = true
 
-	// Get the current known addresses from the address manager.
-	addrCache := sp.server.addrManager.AddressCache()
-
-	// Push the addresses.
-	sp.pushAddrMsg(addrCache)
+	// Push the current known addresses from the address manager, including
+	// the ones which can't be represented by legacy addresses when the peer
+	// supports addrv2 messages.
+	if sp.WantsAddrV2() {
+		sp.pushAddrV2Msg(sp.server.addrManager.AddressCacheV2())
+		return
+	}
+	sp.pushAddrMsg(sp.server.addrManager.AddressCache())
 }
 
 // OnAddr is invoked when a peer receives an addr bitcoin message and is
@@ -1315,16 +1560,59 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 	sp.server.addrManager.AddAddresses(msg.AddrList, sp.NA())
 }
 
+// OnAddrV2 is invoked when a peer receives an addrv2 bitcoin message and is
+// used to notify the server about advertised addresses, including the ones of
+// networks which can't be represented by legacy addresses.
+func (sp *serverPeer) OnAddrV2(_ *peer.Peer, msg *wire.MsgAddrV2) {
+	// Ignore addresses when running on the simulation test network.  This
+	// helps prevent the network from becoming another public test network
+	// since it will not be able to learn about other peers that have not
+	// specifically been provided.
+	if cfg.SimNet {
+		return
+	}
+
+	// A message that has no addresses is invalid.
+	if len(msg.AddrList) == 0 {
+		peerLog.Errorf("Command [%s] from %s does not contain any addresses",
+			msg.Command(), sp.Peer)
+		sp.Disconnect()
+		return
+	}
+
+	for _, na := range msg.AddrList {
+		// Don't add more address if we're disconnecting.
+		if !sp.Connected() {
+			return
+		}
+
+		// Set the timestamp to 5 days ago if it's more than 24 hours
+		// in the future so this address is one of the first to be
+		// removed when space is needed.
+		now := time.Now()
+		if na.Timestamp.After(now.Add(time.Minute * 10)) {
+			na.Timestamp = now.Add(-1 * time.Hour * 24 * 5)
+		}
+	}
+
+	// Add addresses to known addresses for this peer and to the server
+	// address manager, which ignores the addresses of unknown networks.
+	sp.addKnownAddressesV2(msg.AddrList)
+	sp.server.addrManager.AddAddressesV2(msg.AddrList, sp.NA())
+}
+
 // OnRead is invoked when a peer receives a message and it is used to update
 // the bytes received by the server.
 func (sp *serverPeer) OnRead(_ *peer.Peer, bytesRead int, msg wire.Message, err error) {
 	sp.server.AddBytesReceived(uint64(bytesRead))
+	sp.server.addMsgBytesReceived(peer.StatsCommand(msg), uint64(bytesRead))
 }
 
 // OnWrite is invoked when a peer sends a message and it is used to update
 // the bytes sent by the server.
 func (sp *serverPeer) OnWrite(_ *peer.Peer, bytesWritten int, msg wire.Message, err error) {
 	sp.server.AddBytesSent(uint64(bytesWritten))
+	sp.server.addMsgBytesSent(peer.StatsCommand(msg), uint64(bytesWritten))
 }
 
 // OnNotFound is invoked when a peer sends a notfound message.
@@ -1353,7 +1641,7 @@ func (sp *serverPeer) OnNotFound(p *peer.Peer, msg *wire.MsgNotFound) {
 	if numBlocks > 0 {
 		blockStr := pickNoun(uint64(numBlocks), "block", "blocks")
 		reason := fmt.Sprintf("%d %v not found on %s", numBlocks, blockStr, sp)
-		if sp.addBanScore(20, 0, reason) {
+		if sp.addMisbehavior(connmgr.ProtocolAbuse, 20, 0, reason) {
 			return // once they fail to return us five block requests they're gone for good
 		}
 	}
@@ -1364,7 +1652,7 @@ func (sp *serverPeer) OnNotFound(p *peer.Peer, msg *wire.MsgNotFound) {
 		if numBlocks+numTxns < wire.MaxInvPerMsg { // if our message is full then it is likely followed by another one that isn't
 			txStr := pickNoun(uint64(numTxns), "transaction", "transactions")
 			reason := fmt.Sprintf("%d %v not found on %s", numTxns, txStr, sp)
-			if sp.addBanScore(0, 20, reason) {
+			if sp.addMisbehavior(connmgr.ProtocolAbuse, 0, 20, reason) {
 				return // if they fail us five times in one minute, they're gone -- hitting them at new-block should be rare
 			}
 		}
@@ -1545,6 +1833,42 @@ func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan cha
 	return nil
 }
 
+// pushCmpctBlockMsg sends a cmpctblock message for the provided block hash to
+// the connected peer.  The full block is sent instead when the peer does not
+// support compact blocks or the block is too deep in the chain for the peer to
+// reconstruct it from its memory pool.  An error is returned if the block hash
+// is not known.
+func (s *server) pushCmpctBlockMsg(sp *serverPeer, hash *chainhash.Hash,
+	doneChan chan<- struct{}, waitChan <-chan struct{}) error {
+
+	encoding := wire.BaseEncoding
+	if sp.IsWitnessEnabled() {
+		encoding = wire.WitnessEncoding
+	}
+
+	version := sp.CmpctBlockVersion()
+	block, err := s.chain.BlockByHash(hash)
+	if err != nil || version == 0 ||
+		s.chain.BestSnapshot().Height-block.Height() >= maxCmpctBlockDepth {
+
+		return s.pushBlockMsg(sp, hash, doneChan, waitChan, encoding)
+	}
+
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		return s.pushBlockMsg(sp, hash, doneChan, waitChan, encoding)
+	}
+	msg := wire.NewMsgCmpctBlock(block.MsgBlock(), nonce, version)
+
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+
+	sp.QueueMessageWithEncoding(msg, doneChan, encoding)
+	return nil
+}
+
 // pushMerkleBlockMsg sends a merkleblock message for the provided block hash to
 // the connected peer.  Since a merkle block requires the peer to have a filter
 // loaded, this call will simply be ignored if there is no filter loaded.  An
@@ -1573,8 +1897,16 @@ func (s *server) pushMerkleBlockMsg(sp *serverPeer, hash *chainhash.Hash,
 	}
 
 	// Generate a merkle block by filtering the requested block according
-	// to the filter for the peer.
-	merkle, matchedTxIndices := bloom.NewMerkleBlock(blk, sp.filter)
+	// to the filter for the peer, unless the peer already used more than
+	// its share of filter work.
+	if !sp.filter.charge(blk.MsgBlock().SerializeSize()) {
+		sp.bloomWorkExceeded()
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return nil
+	}
+	merkle, matchedTxIndices := bloom.NewMerkleBlock(blk, sp.filter.Filter)
 
 	// Once we have fetched data wait for any previous operation to finish.
 	if waitChan != nil {
@@ -1661,22 +1993,23 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		sp.Disconnect()
 		return false
 	}
-	if ban, ok := state.banned[host]; ok {
-		if time.Now().Before(ban.until) {
+	if ip := net.ParseIP(host); ip != nil {
+		if ban, ok := s.banManager.IsBanned(ip); ok {
 			srvrLog.Infof("Peer %s is banned for another %v - disconnecting",
 				host, time.Until(ban.until))
 			sp.Disconnect()
 			return false
 		}
-
-		srvrLog.Infof("Peer %s is no longer banned", host)
-		delete(state.banned, host)
 	}
 
 	// TODO: Check for max peers from a single IP.
 
-	// Limit max number of total peers.
-	if state.Count() >= cfg.MaxPeers {
+	// Limit max number of total peers.  An inbound peer which isn't
+	// protected from eviction is evicted to make room for new inbound
+	// peers when possible.
+	if state.Count() >= cfg.MaxPeers && !(sp.Inbound() &&
+		s.evictInboundPeer(state)) {
+
 		srvrLog.Infof("Max peers reached [%d] - disconnecting peer %s",
 			cfg.MaxPeers, sp)
 		sp.Disconnect()
@@ -1690,7 +2023,7 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	if sp.Inbound() {
 		state.inboundPeers[sp.ID()] = sp
 	} else {
-		state.outboundGroups[addrmgr.GroupKey(sp.NA())]++
+		state.outboundGroups[addrmgr.GroupKeyV2(sp.NAV2())]++
 		if sp.persistent {
 			state.persistentPeers[sp.ID()] = sp
 		} else {
@@ -1701,7 +2034,7 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	// Update the address' last seen time if the peer has acknowledged
 	// our version and has sent us its version as well.
 	if sp.VerAckReceived() && sp.VersionKnown() && sp.NA() != nil {
-		s.addrManager.Connected(sp.NA())
+		s.addrManager.Connected(sp.NAV2())
 	}
 
 	// Signal the sync manager this peer is a new sync candidate.
@@ -1724,6 +2057,10 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 				addresses := []*wire.NetAddress{lna}
 				sp.pushAddrMsg(addresses)
 			}
+
+			// Advertise the onion service as well to the peers
+			// able to relay its address.
+			s.pushOnionService(sp)
 		}
 
 		// Request known addresses if the server address manager needs
@@ -1735,12 +2072,29 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		}
 
 		// Mark the address as a known good address.
-		s.addrManager.Good(sp.NA())
+		s.addrManager.Good(sp.NAV2())
 	}
 
 	return true
 }
 
+// evictInboundPeer disconnects an inbound peer selected by the connection
+// manager to make room for a new one, and returns whether there was one to
+// evict.  It is invoked from the peerHandler goroutine.
+func (s *server) evictInboundPeer(state *peerState) bool {
+	evict, ok := s.connManager.SelectEviction(state.evictionCandidates())
+	if !ok {
+		return false
+	}
+
+	sp := state.inboundPeers[int32(evict.ID)]
+	srvrLog.Debugf("Evicting inbound peer %s to make room for a new one",
+		sp)
+	delete(state.inboundPeers, sp.ID())
+	sp.Disconnect()
+	return true
+}
+
 // handleDonePeerMsg deals with peers that have signalled they are done.  It is
 // invoked from the peerHandler goroutine.
 func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
@@ -1767,7 +2121,7 @@ func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
 
 	if _, ok := list[sp.ID()]; ok {
 		if !sp.Inbound() && sp.VersionKnown() {
-			state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+			state.outboundGroups[addrmgr.GroupKeyV2(sp.NAV2())]--
 		}
 		delete(list, sp.ID())
 		srvrLog.Debugf("Removed peer %s", sp)
@@ -1787,21 +2141,52 @@ func (s *server) handleBanPeerMsg(state *peerState, sp *serverPeer) {
 	srvrLog.Infof("Banned peer %s (%s) for %v", host, direction,
 		cfg.BanDuration)
 
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return
+	}
 	since := time.Now()
-	state.banned[host] = bannedPeriod{
-		since: since,
-		until: since.Add(cfg.BanDuration),
+	err = s.banManager.Ban(singleHostSubnet(ip), since,
+		since.Add(cfg.BanDuration))
+	if err != nil {
+		srvrLog.Errorf("Unable to persist ban of peer %s: %v", host, err)
 	}
 }
 
 // handleRelayInvMsg deals with relaying inventory to peers that are not already
 // known to have it.  It is invoked from the peerHandler goroutine.
 func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
+	// The compact block sent to the peers which asked for new blocks to be
+	// announced with them is only created when needed.
+	var cmpctBlock *wire.MsgCmpctBlock
+	var cmpctBlockErr error
+
 	state.forAllPeers(func(sp *serverPeer) {
 		if !sp.Connected() {
 			return
 		}
 
+		// If the inventory is a block and the peer wants compact
+		// blocks, send it a cmpctblock message directly unless it
+		// already knows about the block.
+		if msg.invVect.Type == wire.InvTypeBlock && sp.WantsCmpctBlocks() &&
+			sp.CmpctBlockVersion() == wire.CmpctBlockVersion2 {
+
+			if sp.IsKnownInventory(msg.invVect) {
+				return
+			}
+			if cmpctBlock == nil && cmpctBlockErr == nil {
+				cmpctBlock, cmpctBlockErr = s.newCmpctBlock(
+					&msg.invVect.Hash)
+			}
+			if cmpctBlockErr == nil {
+				sp.AddKnownInventory(msg.invVect)
+				sp.QueueMessageWithEncoding(cmpctBlock, nil,
+					wire.WitnessEncoding)
+				return
+			}
+		}
+
 		// If the inventory is a block and the peer prefers headers,
 		// generate and send a headers message instead of an inventory
 		// message.
@@ -1847,7 +2232,7 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 			// Don't relay the transaction if there is a bloom
 			// filter loaded and the transaction doesn't match it.
 			if sp.filter.IsLoaded() {
-				if !sp.filter.MatchTxAndUpdate(txD.Tx) {
+				if !sp.matchTxAndUpdate(txD.Tx) {
 					return
 				}
 			}
@@ -1860,6 +2245,24 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 	})
 }
 
+// newCmpctBlock returns a version 2 cmpctblock message with a random nonce for
+// the passed block hash.
+func (s *server) newCmpctBlock(hash *chainhash.Hash) (*wire.MsgCmpctBlock, error) {
+	block, err := s.chain.BlockByHash(hash)
+	if err != nil {
+		peerLog.Debugf("Unable to fetch block %v to relay as compact "+
+			"block: %v", hash, err)
+		return nil, err
+	}
+
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewMsgCmpctBlock(block.MsgBlock(), nonce,
+		wire.CmpctBlockVersion2), nil
+}
+
 // handleBroadcastMsg deals with broadcasting messages to peers.  It is invoked
 // from the peerHandler goroutine.
 func (s *server) handleBroadcastMsg(state *peerState, bmsg *broadcastMsg) {
@@ -1886,25 +2289,11 @@ type getPeersMsg struct {
 	reply chan []*serverPeer
 }
 
-type listBannedPeersMsg struct {
-	reply chan map[string]bannedPeriod
-}
-
-type setBanMsg struct {
-	addr  string
-	since time.Time
-	until time.Time
-	reply chan error
-}
-
-type removeBanMsg struct {
-	addr  string
-	reply chan error
+type getServerPeerMsg struct {
+	peer  *peer.Peer
+	reply chan *serverPeer
 }
 
-type clearBannedMsg struct {
-	reply chan error
-}
 type getOutboundGroup struct {
 	key   string
 	reply chan int
@@ -1953,28 +2342,14 @@ func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 		})
 		msg.reply <- peers
 
-	case listBannedPeersMsg:
-		banned := map[string]bannedPeriod{}
-		for host, ban := range state.banned {
-			banned[host] = ban
-		}
-		msg.reply <- banned
-
-	case setBanMsg:
-		ban := bannedPeriod{
-			since: msg.since,
-			until: msg.until,
-		}
-		state.banned[msg.addr] = ban
-		msg.reply <- nil
-
-	case removeBanMsg:
-		delete(state.banned, msg.addr)
-		msg.reply <- nil
-
-	case clearBannedMsg:
-		state.banned = map[string]bannedPeriod{}
-		msg.reply <- nil
+	case getServerPeerMsg:
+		var found *serverPeer
+		state.forAllPeers(func(sp *serverPeer) {
+			if sp.Peer == msg.peer {
+				found = sp
+			}
+		})
+		msg.reply <- found
 
 	case connectNodeMsg:
 		// TODO: duplicate oneshots?
@@ -2010,7 +2385,7 @@ func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 		found := disconnectPeer(state.persistentPeers, msg.cmp, func(sp *serverPeer) {
 			// Keep group counts ok since we remove from
 			// the list now.
-			state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+			state.outboundGroups[addrmgr.GroupKeyV2(sp.NAV2())]--
 		})
 
 		if found {
@@ -2046,7 +2421,7 @@ func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 		found = disconnectPeer(state.outboundPeers, msg.cmp, func(sp *serverPeer) {
 			// Keep group counts ok since we remove from
 			// the list now.
-			state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+			state.outboundGroups[addrmgr.GroupKeyV2(sp.NAV2())]--
 		})
 		if found {
 			// If there are multiple outbound connections to the same
@@ -2054,7 +2429,7 @@ func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 			// peers are found.
 			for found {
 				found = disconnectPeer(state.outboundPeers, msg.cmp, func(sp *serverPeer) {
-					state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+					state.outboundGroups[addrmgr.GroupKeyV2(sp.NAV2())]--
 				})
 			}
 			msg.reply <- nil
@@ -2100,6 +2475,9 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnBlock:        sp.OnBlock,
 			OnInv:          sp.OnInv,
 			OnHeaders:      sp.OnHeaders,
+			OnCmpctBlock:   sp.OnCmpctBlock,
+			OnBlockTxn:     sp.OnBlockTxn,
+			OnGetBlockTxn:  sp.OnGetBlockTxn,
 			OnGetData:      sp.OnGetData,
 			OnGetBlocks:    sp.OnGetBlocks,
 			OnGetHeaders:   sp.OnGetHeaders,
@@ -2112,6 +2490,7 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnFilterLoad:   sp.OnFilterLoad,
 			OnGetAddr:      sp.OnGetAddr,
 			OnAddr:         sp.OnAddr,
+			OnAddrV2:       sp.OnAddrV2,
 			OnRead:         sp.OnRead,
 			OnWrite:        sp.OnWrite,
 			OnNotFound:     sp.OnNotFound,
@@ -2129,10 +2508,11 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 		UserAgentVersion:    userAgentVersion,
 		UserAgentComments:   cfg.UserAgentComments,
 		ChainParams:         sp.server.chainParams,
-		Services:            sp.server.services,
+		Services:            sp.services,
 		DisableRelayTx:      cfg.BlocksOnly,
 		ProtocolVersion:     peer.MaxProtocolVersion,
 		TrickleInterval:     cfg.TrickleInterval,
+		InboundTrickleTimer: sp.server.inboundTrickle,
 		DisableStallHandler: cfg.DisableStallHandler,
 	}
 }
@@ -2144,6 +2524,7 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 func (s *server) inboundPeerConnected(conn net.Conn) {
 	sp := newServerPeer(s, false)
 	sp.isWhitelisted = isWhitelisted(conn.RemoteAddr())
+	sp.services = s.inboundServices(conn.LocalAddr())
 	sp.Peer = peer.NewInboundPeer(newPeerConfig(sp))
 	sp.AssociateConnection(conn)
 	go s.peerDoneHandler(sp)
@@ -2213,7 +2594,6 @@ func (s *server) peerHandler() {
 		inboundPeers:    make(map[int32]*serverPeer),
 		persistentPeers: make(map[int32]*serverPeer),
 		outboundPeers:   make(map[int32]*serverPeer),
-		banned:          make(map[string]bannedPeriod),
 		outboundGroups:  make(map[string]int),
 	}
 
@@ -2263,6 +2643,10 @@ out:
 			s.handleQuery(state, qmsg)
 
 		case <-s.quit:
+			// Remember the longest connected outbound peers to
+			// reconnect to them first on the next start.
+			s.addrManager.SetAnchors(state.anchors())
+
 			// Disconnect all peers on server shutdown.
 			state.forAllPeers(func(sp *serverPeer) {
 				srvrLog.Tracef("Shutdown peer %s", sp)
@@ -2337,6 +2721,35 @@ func (s *server) OutboundGroupCount(key string) int {
 	return <-replyChan
 }
 
+// requestBlock requests the block with the passed hash from a connected full
+// node peer.  It is used to fetch copies of blocks that are corrupt in the
+// database.
+func (s *server) requestBlock(hash *chainhash.Hash) {
+	replyChan := make(chan []*serverPeer)
+	select {
+	case s.query <- getPeersMsg{reply: replyChan}:
+	case <-s.quit:
+		return
+	}
+	peers := <-replyChan
+
+	for _, sp := range peers {
+		if !sp.Connected() || sp.Services()&wire.SFNodeNetwork == 0 {
+			continue
+		}
+
+		invType := wire.InvTypeBlock
+		if sp.IsWitnessEnabled() {
+			invType = wire.InvTypeWitnessBlock
+		}
+		gdmsg := wire.NewMsgGetData()
+		gdmsg.AddInvVect(wire.NewInvVect(invType, hash))
+		sp.QueueMessage(gdmsg, nil)
+		return
+	}
+	srvrLog.Debugf("No peer to request block %v from", hash)
+}
+
 // AddBytesSent adds the passed number of bytes to the total bytes sent counter
 // for the server.  It is safe for concurrent access.
 func (s *server) AddBytesSent(bytesSent uint64) {
@@ -2356,6 +2769,46 @@ func (s *server) NetTotals() (uint64, uint64) {
 		atomic.LoadUint64(&s.bytesSent)
 }
 
+// addMsgBytesSent adds the passed number of bytes to the total bytes sent
+// counter of the passed message command.  It is safe for concurrent access.
+func (s *server) addMsgBytesSent(command string, bytesSent uint64) {
+	if bytesSent == 0 {
+		return
+	}
+	s.msgBytesMtx.Lock()
+	s.bytesSentPerMsg[command] += bytesSent
+	s.msgBytesMtx.Unlock()
+}
+
+// addMsgBytesReceived adds the passed number of bytes to the total bytes
+// received counter of the passed message command.  It is safe for concurrent
+// access.
+func (s *server) addMsgBytesReceived(command string, bytesReceived uint64) {
+	if bytesReceived == 0 {
+		return
+	}
+	s.msgBytesMtx.Lock()
+	s.bytesRecvPerMsg[command] += bytesReceived
+	s.msgBytesMtx.Unlock()
+}
+
+// NetTotalsPerMsg returns the bytes received and sent across the network for
+// all peers by message command.  It is safe for concurrent access.
+func (s *server) NetTotalsPerMsg() (map[string]uint64, map[string]uint64) {
+	s.msgBytesMtx.Lock()
+	defer s.msgBytesMtx.Unlock()
+
+	recv := make(map[string]uint64, len(s.bytesRecvPerMsg))
+	for command, n := range s.bytesRecvPerMsg {
+		recv[command] = n
+	}
+	sent := make(map[string]uint64, len(s.bytesSentPerMsg))
+	for command, n := range s.bytesSentPerMsg {
+		sent[command] = n
+	}
+	return recv, sent
+}
+
 // UpdatePeerHeights updates the heights of all peers who have have announced
 // the latest connected main chain block, or a recognized orphan. These height
 // updates allow us to dynamically refresh peer heights, ensuring sync peer
@@ -2368,6 +2821,29 @@ func (s *server) UpdatePeerHeights(latestBlkHash *chainhash.Hash, latestHeight i
 	}
 }
 
+// PenalizePeer increases the decaying score of the passed category of
+// violations of the passed peer, which is banned when its ban score exceeds
+// the ban threshold.  Peers are never banned for stalling.
+//
+// This function is safe for concurrent access and is part of the
+// netsync.PeerNotifier interface implementation.
+func (s *server) PenalizePeer(p *peer.Peer, violation connmgr.Violation, transient uint32, reason string) {
+	reply := make(chan *serverPeer, 1)
+	select {
+	case s.query <- getServerPeerMsg{peer: p, reply: reply}:
+	case <-s.quit:
+		return
+	}
+
+	select {
+	case sp := <-reply:
+		if sp != nil {
+			sp.addMisbehavior(violation, 0, transient, reason)
+		}
+	case <-s.quit:
+	}
+}
+
 // rebroadcastHandler keeps track of user submitted inventories that we have
 // sent out but have not yet made it into a block. We periodically rebroadcast
 // them in case our peers restarted or otherwise lost track of them.
@@ -2433,14 +2909,43 @@ func (s *server) Start() {
 
 	srvrLog.Trace("Starting server")
 
+	// The subsystems register the step stopping them as they are started,
+	// so they are stopped in the reverse order.  The script validation
+	// scheduler, the signature cache and the fee estimator are used as
+	// blocks are processed, so they are only stopped or saved once the
+	// sync manager stopped.
+	s.lifecycle.OnShutdown("script validation scheduler",
+		s.chain.ValidationScheduler().Stop)
+	if s.diskSigCache != nil {
+		s.lifecycle.OnShutdown("signature cache", func() {
+			if err := s.diskSigCache.Save(); err != nil {
+				srvrLog.Errorf("Unable to save the signature "+
+					"cache: %v", err)
+			}
+		})
+	}
+	s.lifecycle.OnShutdown("fee estimator", s.feeEstimator.Close)
+
 	// Start the peer handler which in turn starts the address and block
-	// managers.
+	// managers.  Once the remaining goroutines are signaled to quit, the
+	// peer handler disconnects all peers and stops the sync manager, which
+	// completes the block it is processing first.
 	s.wg.Add(1)
 	go s.peerHandler()
 
-	if s.nat != nil {
+	if s.torController != nil {
 		s.wg.Add(1)
-		go s.upnpUpdateThread()
+		go s.torUpdateThread()
+	}
+	s.lifecycle.OnShutdown("peers and sync manager", func() {
+		close(s.quit)
+		s.wg.Wait()
+	})
+
+	// Stopping the port mapper removes the mapping.
+	if s.portMapper != nil {
+		s.portMapper.Start()
+		s.lifecycle.OnShutdown("port mapper", s.portMapper.Stop)
 	}
 
 	if !cfg.DisableRPC {
@@ -2450,17 +2955,49 @@ func (s *server) Start() {
 		// the RPC server are rebroadcast until being included in a block.
 		go s.rebroadcastHandler()
 
+		// Stopping the RPC server notifies the websocket clients.
 		s.rpcServer.Start()
+		s.lifecycle.OnShutdown("RPC server", func() {
+			s.rpcServer.Stop()
+		})
 	}
 
-	// Start the CPU miner if generation is enabled.
+	// Start the gRPC server if it's enabled.
+	if s.grpcServer != nil {
+		s.grpcServer.Start()
+		s.lifecycle.OnShutdown("gRPC server", s.grpcServer.Stop)
+	}
+
+	// Start the public RPC server if it's enabled.
+	if s.publicRPCServer != nil {
+		s.publicRPCServer.Start()
+		s.lifecycle.OnShutdown("public RPC server",
+			s.publicRPCServer.Stop)
+	}
+
+	// Start the CPU miner if generation is enabled.  It is stopped either
+	// way since it can also be started through the RPC server.
 	if cfg.Generate {
 		s.cpuMiner.Start()
 	}
+	s.lifecycle.OnShutdown("CPU miner", s.cpuMiner.Stop)
+
+	// Start the stratum server if it's enabled.
+	if s.stratumServer != nil {
+		s.stratumServer.Start()
+		s.lifecycle.OnShutdown("stratum server", s.stratumServer.Stop)
+	}
+
+	s.dbScrubber.Start()
+	s.lifecycle.OnShutdown("database scrubber", s.dbScrubber.Stop)
+
+	s.chainAlerter.Start()
+	s.lifecycle.OnShutdown("chain alerter", s.chainAlerter.Stop)
 }
 
-// Stop gracefully shuts down the server by stopping and disconnecting all
-// peers and the main listener.
+// Stop gracefully shuts down the server by stopping its subsystems in the
+// reverse order they were started, which stops the RPC servers before
+// disconnecting all peers and draining the sync manager.
 func (s *server) Stop() error {
 	// Make sure this only happens once.
 	if atomic.AddInt32(&s.shutdown, 1) != 1 {
@@ -2469,25 +3006,13 @@ func (s *server) Stop() error {
 	}
 
 	srvrLog.Warnf("Server shutting down")
-
-	// Stop the CPU miner if needed
-	s.cpuMiner.Stop()
-
-	// Shutdown the RPC server if it's not disabled.
-	if !cfg.DisableRPC {
-		s.rpcServer.Stop()
-	}
-
-	s.feeEstimator.Close()
-
-	// Signal the remaining goroutines to quit.
-	close(s.quit)
+	s.lifecycle.Shutdown()
 	return nil
 }
 
-// WaitForShutdown blocks until the main listener and peer handlers are stopped.
+// WaitForShutdown blocks until all the subsystems of the server are stopped.
 func (s *server) WaitForShutdown() {
-	s.wg.Wait()
+	s.lifecycle.Wait()
 }
 
 // ScheduleShutdown schedules a server shutdown after the specified duration.
@@ -2574,44 +3099,52 @@ func parseListeners(addrs []string) ([]net.Addr, error) {
 	return netAddrs, nil
 }
 
-func (s *server) upnpUpdateThread() {
-	// Go off immediately to prevent code duplication, thereafter we renew
-	// lease every 15 minutes.
-	timer := time.NewTimer(0 * time.Second)
-	lport, _ := strconv.ParseInt(activeNetParams.DefaultPort, 10, 16)
-	first := true
+// torUpdateThread creates the onion service of the server through the Tor
+// control port, and recreates it whenever the control connection is lost
+// since the service only lives as long as that connection.
+func (s *server) torUpdateThread() {
+	// Go off immediately, thereafter check the control connection every
+	// minute.
+	timer := time.NewTimer(0)
 out:
 	for {
 		select {
 		case <-timer.C:
-			// TODO: pick external port  more cleverly
-			// TODO: know which ports we are listening to on an external net.
-			// TODO: if specific listen port doesn't work then ask for wildcard
-			// listen port?
-			// XXX this assumes timeout is in seconds.
-			listenPort, err := s.nat.AddPortMapping("tcp", int(lport), int(lport),
-				"lbcd listen port", 20*60)
+			if s.onionAddress() != nil {
+				err := s.torController.Ping()
+				if err == nil {
+					timer.Reset(time.Minute)
+					continue
+				}
+
+				srvrLog.Warnf("Lost connection to the Tor control "+
+					"port: %v", err)
+				s.torController.Stop()
+				s.setOnionAddress(nil)
+			}
+
+			host, err := s.torController.Start()
 			if err != nil {
-				srvrLog.Warnf("can't add UPnP port mapping: %v", err)
+				srvrLog.Warnf("Unable to create Tor onion service: %v",
+					err)
+				timer.Reset(time.Minute)
+				continue
 			}
-			if first && err == nil {
-				// TODO: look this up periodically to see if upnp domain changed
-				// and so did ip.
-				externalip, err := s.nat.GetExternalAddress()
-				if err != nil {
-					srvrLog.Warnf("UPnP can't get external address: %v", err)
-					continue out
-				}
-				na := wire.NewNetAddressIPPort(externalip, uint16(listenPort),
-					s.services)
-				err = s.addrManager.AddLocalAddress(na, addrmgr.UpnpPrio)
-				if err != nil {
-					// XXX DeletePortMapping?
-				}
-				srvrLog.Warnf("Successfully bound via UPnP to %s", addrmgr.NetAddressKey(na))
-				first = false
+			port, _ := strconv.ParseUint(activeNetParams.DefaultPort, 10, 16)
+			na, err := wire.NewNetAddressTorV3(host, uint16(port),
+				s.services)
+			if err != nil {
+				srvrLog.Warnf("Unexpected Tor onion service address: %v",
+					err)
+				s.torController.Stop()
+				timer.Reset(time.Minute)
+				continue
 			}
-			timer.Reset(time.Minute * 15)
+			s.setOnionAddress(na)
+			srvrLog.Infof("Listening on Tor onion service %s", na)
+
+			timer.Reset(time.Minute)
+
 		case <-s.quit:
 			break out
 		}
@@ -2619,47 +3152,144 @@ out:
 
 	timer.Stop()
 
-	if err := s.nat.DeletePortMapping("tcp", int(lport), int(lport)); err != nil {
-		srvrLog.Warnf("unable to remove UPnP port mapping: %v", err)
-	} else {
-		srvrLog.Debugf("successfully disestablished UPnP port mapping")
+	if err := s.torController.Stop(); err != nil {
+		srvrLog.Warnf("Unable to remove Tor onion service: %v", err)
 	}
 
 	s.wg.Done()
 }
 
-// setupRPCListeners returns a slice of listeners that are configured for use
-// with the RPC server depending on the configuration settings for listen
-// addresses and TLS.
-func setupRPCListeners() ([]net.Listener, error) {
+// onionAddress returns the address of the onion service of the server, or nil
+// when it hasn't been created.
+//
+// This function is safe for concurrent access.
+func (s *server) onionAddress() *wire.NetAddressV2 {
+	s.onionMtx.RLock()
+	defer s.onionMtx.RUnlock()
+
+	return s.onionService
+}
+
+// setOnionAddress sets the address of the onion service of the server.
+//
+// This function is safe for concurrent access.
+func (s *server) setOnionAddress(na *wire.NetAddressV2) {
+	s.onionMtx.Lock()
+	s.onionService = na
+	s.onionMtx.Unlock()
+}
+
+// pushOnionService advertises the onion service of the server to the peer
+// when it has been created and the peer supports addrv2 messages.
+func (s *server) pushOnionService(sp *serverPeer) {
+	onion := s.onionAddress()
+	if onion == nil || !sp.WantsAddrV2() {
+		return
+	}
+
+	na := *onion
+	na.Timestamp = time.Unix(time.Now().Unix(), 0)
+	sp.pushAddrV2Msg([]*wire.NetAddressV2{&na})
+}
+
+// The names of the networks outbound connection targets may be set for.
+const (
+	networkIPv4  = "ipv4"
+	networkIPv6  = "ipv6"
+	networkOnion = "onion"
+)
+
+// networkName returns the name of the passed network for the outbound
+// connection targets, or an empty string for the networks without targets.
+func networkName(network wire.NetworkID) string {
+	switch network {
+	case wire.NetworkIPv4:
+		return networkIPv4
+	case wire.NetworkIPv6:
+		return networkIPv6
+	case wire.NetworkTorV2, wire.NetworkTorV3:
+		return networkOnion
+	}
+	return ""
+}
+
+// addrNetworkName returns the name of the network of the passed address in
+// the host:port form for the outbound connection targets.
+func addrNetworkName(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if strings.HasSuffix(host, ".onion") {
+		return networkOnion
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return networkIPv6
+	}
+	return networkIPv4
+}
+
+// isDialableNetwork returns whether or not the addresses of the passed network
+// can be connected to.  The Tor addresses are dialed through the onion proxy.
+func isDialableNetwork(network wire.NetworkID) bool {
+	switch network {
+	case wire.NetworkIPv4, wire.NetworkIPv6, wire.NetworkTorV2,
+		wire.NetworkTorV3:
+
+		return true
+	}
+	return false
+}
+
+// onionServiceTarget returns the local address the connections to the onion
+// service are forwarded to, which is the address of the first listener using
+// the loopback interface when it listens on all interfaces.
+func onionServiceTarget(listeners []net.Listener) string {
+	addr, ok := listeners[0].Addr().(*net.TCPAddr)
+	if !ok {
+		return listeners[0].Addr().String()
+	}
+
+	ip := addr.IP
+	switch {
+	case ip == nil || ip.Equal(net.IPv4zero):
+		ip = net.IPv4(127, 0, 0, 1)
+	case ip.Equal(net.IPv6unspecified):
+		ip = net.IPv6loopback
+	}
+
+	return net.JoinHostPort(ip.String(), strconv.Itoa(addr.Port))
+}
+
+// listenPort returns the port of the first of the passed listeners, falling
+// back to the default port of the active network.
+func listenPort(listeners []net.Listener) uint16 {
+	if addr, ok := listeners[0].Addr().(*net.TCPAddr); ok {
+		return uint16(addr.Port)
+	}
+	port, _ := strconv.ParseUint(activeNetParams.DefaultPort, 10, 16)
+	return uint16(port)
+}
+
+// setupRPCListeners returns a slice of listeners for the passed addresses that
+// are configured for use with the RPC server depending on the configuration
+// settings for TLS.
+func setupRPCListeners(addrs []string) ([]net.Listener, error) {
 	// Setup TLS if not disabled.
 	listenFunc := net.Listen
 	if !cfg.DisableTLS {
-		// Generate the TLS cert and key file if both don't already
-		// exist.
-		if !fileExists(cfg.RPCKey) && !fileExists(cfg.RPCCert) {
-			err := genCertPair(cfg.RPCCert, cfg.RPCKey)
-			if err != nil {
-				return nil, err
-			}
-		}
-		keypair, err := tls.LoadX509KeyPair(cfg.RPCCert, cfg.RPCKey)
+		tlsConfig, err := rpcTLSConfig()
 		if err != nil {
 			return nil, err
 		}
 
-		tlsConfig := tls.Config{
-			Certificates: []tls.Certificate{keypair},
-			MinVersion:   tls.VersionTLS12,
-		}
-
 		// Change the standard net.Listen function to the tls one.
 		listenFunc = func(net string, laddr string) (net.Listener, error) {
-			return tls.Listen(net, laddr, &tlsConfig)
+			return tls.Listen(net, laddr, tlsConfig)
 		}
 	}
 
-	netAddrs, err := parseListeners(cfg.RPCListeners)
+	netAddrs, err := parseListeners(addrs)
 	if err != nil {
 		return nil, err
 	}
@@ -2677,6 +3307,70 @@ func setupRPCListeners() ([]net.Listener, error) {
 	return listeners, nil
 }
 
+// rpcTLSConfig returns the TLS configuration of the RPC server, generating its
+// certificate and key files if both don't already exist.
+func rpcTLSConfig() (*tls.Config, error) {
+	if !fileExists(cfg.RPCKey) && !fileExists(cfg.RPCCert) {
+		err := genCertPair(cfg.RPCCert, cfg.RPCKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	keypair, err := tls.LoadX509KeyPair(cfg.RPCCert, cfg.RPCKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{keypair},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// setupGRPCListeners returns a slice of listeners that are configured for use
+// with the gRPC server depending on the configuration settings for listen
+// addresses.  TLS is handled by the gRPC server itself.
+func setupGRPCListeners() ([]net.Listener, error) {
+	netAddrs, err := parseListeners(cfg.GRPCListeners)
+	if err != nil {
+		return nil, err
+	}
+
+	listeners := make([]net.Listener, 0, len(netAddrs))
+	for _, addr := range netAddrs {
+		listener, err := net.Listen(addr.Network(), addr.String())
+		if err != nil {
+			grpcLog.Warnf("Can't listen on %s: %v", addr, err)
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// setupStratumListeners returns a slice of listeners that are configured for
+// use with the stratum server depending on the configuration settings for
+// listen addresses.
+func setupStratumListeners() ([]net.Listener, error) {
+	netAddrs, err := parseListeners(cfg.StratumListeners)
+	if err != nil {
+		return nil, err
+	}
+
+	listeners := make([]net.Listener, 0, len(netAddrs))
+	for _, addr := range netAddrs {
+		listener, err := net.Listen(addr.Network(), addr.String())
+		if err != nil {
+			minrLog.Warnf("Can't listen on %s: %v", addr, err)
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
 // newServer returns a new btcd server configured to listen on addr for the
 // bitcoin network type specified by chainParams.  Use start to begin accepting
 // connections from peers.
@@ -2686,21 +3380,34 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 
 	startupTime := time.Now()
 
+	// Bloom filters are only advertised to the inbound peers of the bloom
+	// filter listeners when there are any.
 	services := defaultServices
-	if cfg.NoPeerBloomFilters {
+	if cfg.NoPeerBloomFilters || len(cfg.PeerBloomFilters) > 0 {
 		services &^= wire.SFNodeBloom
 	}
+	bloomListeners, err := parseBloomListeners(cfg.PeerBloomFilters)
+	if err != nil {
+		return nil, err
+	}
 	if cfg.NoCFilters {
 		services &^= wire.SFNodeCF
 	}
+	if cfg.Prune != 0 {
+		services &^= wire.SFNodeNetwork
+		services |= wire.SFNodeNetworkLimited
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, btcdLookup)
 
+	banMgr, err := newBanManager(db)
+	if err != nil {
+		return nil, err
+	}
+
 	var listeners []net.Listener
-	var nat NAT
 	if !cfg.DisableListen {
-		var err error
-		listeners, nat, err = initListeners(amgr, listenAddrs, services)
+		listeners, err = initListeners(amgr, listenAddrs, services)
 		if err != nil {
 			return nil, err
 		}
@@ -2726,17 +3433,68 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		relayInv:             make(chan relayMsg, cfg.MaxPeers),
 		broadcast:            make(chan broadcastMsg, cfg.MaxPeers),
 		quit:                 make(chan struct{}),
+		lifecycle:            newLifecycle(),
 		modifyRebroadcastInv: make(chan interface{}),
 		peerHeightsUpdate:    make(chan updatePeerHeightsMsg),
-		nat:                  nat,
+		inboundTrickle:       peer.NewTrickleTimer(cfg.TrickleInterval),
 		db:                   db,
+		banManager:           banMgr,
 		timeSource:           blockchain.NewMedianTime(),
 		services:             services,
-		sigCache:             txscript.NewSigCache(cfg.SigCacheMaxSize),
+		bloomListeners:       bloomListeners,
 		hashCache:            txscript.NewHashCache(cfg.SigCacheMaxSize),
 		cfCheckptCaches:      make(map[wire.FilterType][]cfHeaderKV),
 		agentBlacklist:       agentBlacklist,
 		agentWhitelist:       agentWhitelist,
+		bytesRecvPerMsg:      make(map[string]uint64),
+		bytesSentPerMsg:      make(map[string]uint64),
+	}
+
+	// Use a signature cache persisted across restarts if requested.
+	if cfg.SigCachePersist {
+		s.diskSigCache = txscript.NewDiskSigCache(cfg.SigCacheMaxSize,
+			filepath.Join(cfg.DataDir, "sigcache.dat"))
+		if err := s.diskSigCache.Load(); err != nil {
+			srvrLog.Warnf("Unable to load the signature cache: %v", err)
+		}
+		s.sigCache = s.diskSigCache
+	} else {
+		s.sigCache = txscript.NewSigCache(cfg.SigCacheMaxSize)
+	}
+
+	if cfg.ListenOnion {
+		port, _ := strconv.ParseUint(activeNetParams.DefaultPort, 10, 16)
+		keyFile := filepath.Join(cfg.DataDir, onionPrivateKeyFilename)
+		s.torController = torcontrol.New(&torcontrol.Config{
+			Addr:           cfg.TorControl,
+			Password:       cfg.TorPassword,
+			PrivateKeyFile: keyFile,
+			VirtualPort:    uint16(port),
+			Target:         onionServiceTarget(listeners),
+		})
+	}
+
+	// Map the listening port on the NAT gateway of the local network unless
+	// the external addresses are specified, and advertise the external
+	// address of the mapping to peers.
+	if len(listeners) != 0 && len(cfg.ExternalIPs) == 0 &&
+		(cfg.NATPMP || cfg.Upnp) && !cfg.RegressionTest && !cfg.SimNet {
+
+		s.portMapper = portmap.New(&portmap.Config{
+			InternalPort: listenPort(listeners),
+			Description:  "lbcd listen port",
+			NATPMP:       cfg.NATPMP,
+			UPnP:         cfg.Upnp,
+			OnMapped: func(ip net.IP, port uint16) {
+				na := wire.NewNetAddressIPPort(ip, port, s.services)
+				err := s.addrManager.AddLocalAddress(na,
+					addrmgr.UpnpPrio)
+				if err != nil {
+					srvrLog.Warnf("Skipping mapped address %s: %v",
+						addrmgr.NetAddressKey(na), err)
+				}
+			},
+		})
 	}
 
 	// Create the transaction and address indexes if needed.
@@ -2765,11 +3523,31 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		s.addrIndex = indexers.NewAddrIndex(db, chainParams)
 		indexes = append(indexes, s.addrIndex)
 	}
+	if cfg.ClaimIDIndex {
+		indxLog.Info("Claim ID index is enabled")
+		s.claimIDIndex = indexers.NewClaimIDIndex(db)
+		indexes = append(indexes, s.claimIDIndex)
+	}
+	if cfg.ChannelIndex {
+		indxLog.Info("Claims by channel index is enabled")
+		s.channelIndex = indexers.NewClaimChannelIndex(db)
+		indexes = append(indexes, s.channelIndex)
+	}
+	if cfg.SpentIndex {
+		indxLog.Info("Spent index is enabled")
+		s.spentIndex = indexers.NewSpentIndex(db)
+		indexes = append(indexes, s.spentIndex)
+	}
 	if !cfg.NoCFilters {
 		indxLog.Info("Committed filter index is enabled")
 		s.cfIndex = indexers.NewCfIndex(db, chainParams)
 		indexes = append(indexes, s.cfIndex)
 	}
+	if cfg.ClaimFilterIndex {
+		indxLog.Info("Claim name filter index is enabled")
+		s.claimFilterIndex = indexers.NewClaimFilterIndex(db)
+		indexes = append(indexes, s.claimFilterIndex)
+	}
 
 	// Create an index manager if any of the optional indexes are enabled.
 	var indexManager blockchain.IndexManager
@@ -2783,28 +3561,57 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		checkpoints = mergeCheckpoints(s.chainParams.Checkpoints, cfg.addCheckpoints)
 	}
 
-	var err error
-
 	claimTrieCfg := claimtrieconfig.DefaultConfig
 	claimTrieCfg.DataDir = cfg.DataDir
 	claimTrieCfg.Interrupt = interrupt
+	claimTrieCfg.NodeCacheBudget = cfg.ClaimTrieCache << 20
+	if len(checkpoints) > 0 {
+		// The blocks up to the last checkpoint are never rolled back,
+		// so they don't need undo records.
+		last := checkpoints[len(checkpoints)-1].Height
+		claimTrieCfg.NodeUndoStartHeight = last + 1
+	}
+	claimTrieCfg.PebbleTuning = claimtrieconfig.PebbleTuning{
+		CacheSize:                cfg.ClaimTriePebbleCache << 20,
+		MemTableSize:             cfg.ClaimTrieMemTable << 20,
+		MaxConcurrentCompactions: cfg.ClaimTrieCompactions,
+		BloomFilterBits:          cfg.ClaimTrieBloomBits,
+	}
 
 	ct, err := claimtrie.New(claimTrieCfg)
 	if err != nil {
 		return nil, err
 	}
 
+	// The claim scripts of the blocks below a loaded utxo snapshot are
+	// processed with a claim trie of their own while they are validated,
+	// which doesn't need to be rolled back.
+	snapshotClaimTrieCfg := claimTrieCfg
+	snapshotClaimTrieCfg.DataDir = filepath.Join(cfg.DataDir,
+		"snapshotvalidation")
+	snapshotClaimTrieCfg.NodeUndoDepth = 0
+
 	// Create a new block chain instance with the appropriate configuration.
+	valScheduler := blockchain.NewValidationScheduler(cfg.ScriptValWorkers)
+	valScheduler.Start()
 	s.chain, err = blockchain.New(&blockchain.Config{
-		DB:           s.db,
-		Interrupt:    interrupt,
-		ChainParams:  s.chainParams,
-		Checkpoints:  checkpoints,
-		TimeSource:   s.timeSource,
-		SigCache:     s.sigCache,
-		IndexManager: indexManager,
-		HashCache:    s.hashCache,
-		ClaimTrie:    ct,
+		DB:                  s.db,
+		Interrupt:           interrupt,
+		ChainParams:         s.chainParams,
+		Checkpoints:         checkpoints,
+		TimeSource:          s.timeSource,
+		SigCache:            s.sigCache,
+		IndexManager:        indexManager,
+		HashCache:           s.hashCache,
+		ValidationScheduler: valScheduler,
+		Prune:               cfg.Prune * 1024 * 1024,
+		PruneForkDepth:      int32(cfg.PruneForkDepth),
+		PruneForkDB:         cfg.PruneForkDB,
+		HeaderFile:          headerFilePath(cfg.DbType),
+		MaxOrphanBlocks:     cfg.MaxOrphanBlocks,
+		AssumeUtxo:          cfg.addAssumeUtxo,
+		ClaimTrie:           ct,
+		SnapshotClaimTrie:   &snapshotClaimTrieCfg,
 	})
 	if err != nil {
 		return nil, err
@@ -2841,6 +3648,15 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			MinRelayTxFee:        cfg.minRelayTxFee,
 			MaxTxVersion:         2,
 			RejectReplacement:    cfg.RejectReplacement,
+			MaxPoolSize:          int64(cfg.MaxMempool) * 1000000,
+			Standard: &mempool.StandardPolicy{
+				DustRelayFee:       cfg.dustRelayFee,
+				MaxDataCarrierSize: cfg.DataCarrierSize,
+				MaxClaimValueSize:  cfg.MaxClaimValueSize,
+				MinClaimAmount:     cfg.minClaimAmount,
+				MinSupportAmount:   cfg.minSupportAmount,
+				PermitBareMultiSig: !cfg.NoBareMultiSig,
+			},
 		},
 		ChainParams:    chainParams,
 		FetchUtxoView:  s.chain.FetchUtxoView,
@@ -2866,6 +3682,8 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		DisableCheckpoints: cfg.DisableCheckpoints,
 		MaxPeers:           cfg.MaxPeers,
 		FeeEstimator:       s.feeEstimator,
+		HeaderCheckSync:    cfg.HeaderCheckSync,
+		AssumeValid:        cfg.assumeValid,
 	})
 	if err != nil {
 		return nil, err
@@ -2882,6 +3700,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		BlockMinSize:      cfg.BlockMinSize,
 		BlockMaxSize:      cfg.BlockMaxSize,
 		BlockPrioritySize: cfg.BlockPrioritySize,
+		Selection:         cfg.blockSelection,
 		TxMinFreeFee:      cfg.minRelayTxFee,
 	}
 	blockTemplateGenerator := mining.NewBlkTmplGenerator(&policy,
@@ -2891,11 +3710,31 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		ChainParams:            chainParams,
 		BlockTemplateGenerator: blockTemplateGenerator,
 		MiningAddrs:            cfg.miningAddrs,
+		Payouts:                cfg.miningPayouts,
 		ProcessBlock:           s.syncManager.ProcessBlock,
 		ConnectedCount:         s.ConnectedCount,
 		IsCurrent:              s.syncManager.IsCurrent,
 	})
 
+	if len(cfg.StratumListeners) > 0 {
+		stratumListeners, err := setupStratumListeners()
+		if err != nil {
+			return nil, err
+		}
+		if len(stratumListeners) == 0 {
+			return nil, errors.New("STRATUM: No valid listen address")
+		}
+
+		s.stratumServer = stratum.New(&stratum.Config{
+			ChainParams:            chainParams,
+			BlockTemplateGenerator: blockTemplateGenerator,
+			MiningAddrs:            cfg.miningAddrs,
+			ProcessBlock:           s.syncManager.ProcessBlock,
+			IsCurrent:              s.syncManager.IsCurrent,
+			Listeners:              stratumListeners,
+		})
+	}
+
 	// Only setup a function to return new addresses to connect to when
 	// not running in connect-only mode.  The simulation network is always
 	// in connect-only mode since it is only intended to connect to
@@ -2903,21 +3742,35 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	// discovered peers in order to prevent it from becoming a public test
 	// network.
 	var newAddressFunc func() (net.Addr, error)
+	var networkAddressFunc func(network string) (net.Addr, error)
 	if !cfg.SimNet && len(cfg.ConnectPeers) == 0 {
-		newAddressFunc = func() (net.Addr, error) {
+		// getAddress returns an address of the named network, or of
+		// any network when the name is empty.
+		getAddress := func(network string) (net.Addr, error) {
 			for tries := 0; tries < 100; tries++ {
 				addr := s.addrManager.GetAddress()
 				if addr == nil {
 					break
 				}
 
+				// Skip the addresses of the networks that can't be
+				// dialed, which are only stored to be relayed, and
+				// of the networks other than the requested one.
+				na := addr.NetAddress()
+				if !isDialableNetwork(na.Network) {
+					continue
+				}
+				if network != "" && networkName(na.Network) != network {
+					continue
+				}
+
 				// Address will not be invalid, local or unroutable
 				// because addrmanager rejects those on addition.
 				// Just check that we don't already have an address
 				// in the same group so that we are not connecting
 				// to the same network segment at the expense of
 				// others.
-				key := addrmgr.GroupKey(addr.NetAddress())
+				key := addrmgr.GroupKeyV2(na)
 				if s.OutboundGroupCount(key) != 0 {
 					continue
 				}
@@ -2929,24 +3782,50 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 				}
 
 				// allow nondefault ports after 50 failed tries.
-				if tries < 50 && fmt.Sprintf("%d", addr.NetAddress().Port) !=
+				if tries < 50 && fmt.Sprintf("%d", na.Port) !=
 					activeNetParams.DefaultPort {
 					continue
 				}
 
 				// Mark an attempt for the valid address.
-				s.addrManager.Attempt(addr.NetAddress())
+				s.addrManager.Attempt(na)
 
-				addrString := addrmgr.NetAddressKey(addr.NetAddress())
+				addrString := addrmgr.NetAddressKeyV2(na)
 				return addrStringToNetAddr(addrString)
 			}
 
 			return nil, errors.New("no valid connect address")
 		}
+
+		newAddressFunc = func() (net.Addr, error) {
+			// Reconnect to the anchors saved on shutdown first.
+			for {
+				na := s.addrManager.TakeAnchor()
+				if na == nil {
+					break
+				}
+				if !isDialableNetwork(na.Network) {
+					continue
+				}
+				s.addrManager.Attempt(na)
+				return addrStringToNetAddr(addrmgr.NetAddressKeyV2(na))
+			}
+
+			return getAddress("")
+		}
+		networkAddressFunc = getAddress
 	}
 
-	// Create a connection manager.
+	// Create a connection manager.  The outbound connections must be
+	// enough to meet the targets of all the networks.
 	targetOutbound := defaultTargetOutbound
+	var networksTarget int
+	for _, target := range cfg.outboundTargets {
+		networksTarget += int(target)
+	}
+	if networksTarget > targetOutbound {
+		targetOutbound = networksTarget
+	}
 	if cfg.MaxPeers < targetOutbound {
 		targetOutbound = cfg.MaxPeers
 	}
@@ -2955,9 +3834,15 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		OnAccept:       s.inboundPeerConnected,
 		RetryDuration:  connectionRetryInterval,
 		TargetOutbound: uint32(targetOutbound),
-		Dial:           btcdDial,
-		OnConnection:   s.outboundPeerConnected,
-		GetNewAddress:  newAddressFunc,
+		NetworkTargets: cfg.outboundTargets,
+		AddrNetwork: func(addr net.Addr) string {
+			return addrNetworkName(addr.String())
+		},
+		GetNetworkAddress: networkAddressFunc,
+		Dial:              btcdDial,
+		OnConnection:      s.outboundPeerConnected,
+		GetNewAddress:     newAddressFunc,
+		IsBanned:          s.banManager.IsAddrBanned,
 	})
 	if err != nil {
 		return nil, err
@@ -2981,10 +3866,13 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		})
 	}
 
+	s.dbScrubber = newDBScrubber(db, s.chain, cfg.DBScrubInterval,
+		s.requestBlock)
+
 	if !cfg.DisableRPC {
 		// Setup listeners for the configured RPC listen addresses and
 		// TLS settings.
-		rpcListeners, err := setupRPCListeners()
+		rpcListeners, err := setupRPCListeners(cfg.RPCListeners)
 		if err != nil {
 			return nil, err
 		}
@@ -2993,23 +3881,29 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		}
 
 		s.rpcServer, err = newRPCServer(&rpcserverConfig{
-			Listeners:    rpcListeners,
-			StartupTime:  startupTime.Unix(),
-			ConnMgr:      &rpcConnManager{&s},
-			AddrMgr:      amgr,
-			SyncMgr:      &rpcSyncMgr{&s, s.syncManager},
-			TimeSource:   s.timeSource,
-			Chain:        s.chain,
-			ChainParams:  chainParams,
-			DB:           db,
-			TxMemPool:    s.txMemPool,
-			Generator:    blockTemplateGenerator,
-			CPUMiner:     s.cpuMiner,
-			TxIndex:      s.txIndex,
-			AddrIndex:    s.addrIndex,
-			CfIndex:      s.cfIndex,
-			FeeEstimator: s.feeEstimator,
-			Services:     s.services,
+			Listeners:        rpcListeners,
+			StartupTime:      startupTime.Unix(),
+			ConnMgr:          &rpcConnManager{&s},
+			AddrMgr:          amgr,
+			SyncMgr:          &rpcSyncMgr{&s, s.syncManager},
+			TimeSource:       s.timeSource,
+			Chain:            s.chain,
+			ChainParams:      chainParams,
+			DB:               db,
+			TxMemPool:        s.txMemPool,
+			Generator:        blockTemplateGenerator,
+			CPUMiner:         s.cpuMiner,
+			TxIndex:          s.txIndex,
+			AddrIndex:        s.addrIndex,
+			CfIndex:          s.cfIndex,
+			ClaimIDIndex:     s.claimIDIndex,
+			ChannelIndex:     s.channelIndex,
+			SpentIndex:       s.spentIndex,
+			ClaimFilterIndex: s.claimFilterIndex,
+			FeeEstimator:     s.feeEstimator,
+			DBScrubber:       s.dbScrubber,
+			Services:         s.services,
+			PortMapper:       s.portMapper,
 		})
 		if err != nil {
 			return nil, err
@@ -3020,19 +3914,65 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			<-s.rpcServer.RequestedProcessShutdown()
 			shutdownRequestChannel <- struct{}{}
 		}()
+
+		if len(cfg.GRPCListeners) > 0 {
+			grpcListeners, err := setupGRPCListeners()
+			if err != nil {
+				return nil, err
+			}
+			if len(grpcListeners) == 0 {
+				return nil, errors.New("GRPC: No valid listen address")
+			}
+
+			var tlsConfig *tls.Config
+			if !cfg.DisableTLS {
+				tlsConfig, err = rpcTLSConfig()
+				if err != nil {
+					return nil, err
+				}
+			}
+			s.grpcServer = newGRPCServer(s.rpcServer, grpcListeners,
+				tlsConfig)
+		}
+
+		if len(cfg.PublicRPCListeners) > 0 {
+			publicListeners, err := setupRPCListeners(
+				cfg.PublicRPCListeners)
+			if err != nil {
+				return nil, err
+			}
+			if len(publicListeners) == 0 {
+				return nil, errors.New("Public RPCS: No valid " +
+					"listen address")
+			}
+
+			s.publicRPCServer = newPublicRPCServer(s.rpcServer,
+				publicListeners, cfg.PublicRPCMethods,
+				cfg.PublicRPCRate, cfg.PublicRPCBurst)
+		}
+	}
+
+	// Send the chain alerts to the websocket clients as well when the RPC
+	// server is enabled.
+	var alertHooks []func(*chainAlert)
+	if s.rpcServer != nil {
+		alertHooks = append(alertHooks, s.rpcServer.ntfnMgr.NotifyChainAlert)
 	}
+	s.chainAlerter = newChainAlerter(s.chain.BestSnapshot(),
+		int32(cfg.AlertReorgDepth), cfg.AlertStallTimeout,
+		cfg.AlertWebhook, alertHooks...)
+	s.chain.Subscribe(s.chainAlerter.handleBlockchainNotification)
 
 	return &s, nil
 }
 
 // initListeners initializes the configured net listeners and adds any bound
-// addresses to the address manager. Returns the listeners and a NAT interface,
-// which is non-nil if UPnP is in use.
-func initListeners(amgr *addrmgr.AddrManager, listenAddrs []string, services wire.ServiceFlag) ([]net.Listener, NAT, error) {
+// addresses to the address manager. Returns the listeners.
+func initListeners(amgr *addrmgr.AddrManager, listenAddrs []string, services wire.ServiceFlag) ([]net.Listener, error) {
 	// Listen for TCP connections at the configured addresses
 	netAddrs, err := parseListeners(listenAddrs)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	listeners := make([]net.Listener, 0, len(netAddrs))
@@ -3045,13 +3985,12 @@ func initListeners(amgr *addrmgr.AddrManager, listenAddrs []string, services wir
 		listeners = append(listeners, listener)
 	}
 
-	var nat NAT
 	if len(cfg.ExternalIPs) != 0 {
 		defaultPort, err := strconv.ParseUint(activeNetParams.DefaultPort, 10, 16)
 		if err != nil {
 			srvrLog.Errorf("Can not parse default port %s for active chain: %v",
 				activeNetParams.DefaultPort, err)
-			return nil, nil, err
+			return nil, err
 		}
 
 		for _, sip := range cfg.ExternalIPs {
@@ -3081,20 +4020,6 @@ func initListeners(amgr *addrmgr.AddrManager, listenAddrs []string, services wir
 			}
 		}
 	} else {
-		if cfg.Upnp && !cfg.RegressionTest && !cfg.SimNet {
-			var err error
-			nat, err = Discover()
-			if err != nil {
-				srvrLog.Infof("Can't discover UPnP-enabled device: %v", err)
-			} else {
-				address, err := nat.GetExternalAddress()
-				if err == nil && address != nil {
-					srvrLog.Infof("UPnP successfully registered on %s", address.String())
-				}
-			}
-			// nil nat here is fine, just means no upnp on network.
-		}
-
 		// Add bound addresses to address manager to be advertised to peers.
 		for _, listener := range listeners {
 			addr := listener.Addr().String()
@@ -3105,7 +4030,7 @@ func initListeners(amgr *addrmgr.AddrManager, listenAddrs []string, services wir
 		}
 	}
 
-	return listeners, nat, nil
+	return listeners, nil
 }
 
 // addrStringToNetAddr takes an address in the form of 'host:port' and returns
@@ -0,0 +1,411 @@
+/*
+Package torcontrol implements a minimal Tor control protocol client used to
+create an onion service for the listening port of the node.
+
+The controller authenticates with the control port using the first supported
+method among a hashed password, no authentication, SAFECOOKIE and COOKIE, and
+then creates a v3 onion service with ADD_ONION.  The private key of the service
+is saved so it keeps the same address across restarts.  The onion service only
+lives as long as the control connection, which is why the controller keeps it
+open until it is stopped.
+*/
+package torcontrol
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// dialTimeout is the maximum duration to connect to the control port.
+	dialTimeout = 10 * time.Second
+
+	// cookieSize is the size of the authentication cookie of Tor.
+	cookieSize = 32
+
+	// keyTypeV3 is the key type of v3 onion services.
+	keyTypeV3 = "ED25519-V3"
+
+	// statusOK is the status code of successful replies.
+	statusOK = 250
+)
+
+var (
+	// safeCookieServerKey and safeCookieClientKey are the HMAC keys of the
+	// SAFECOOKIE authentication as defined by the Tor control protocol.
+	safeCookieServerKey = []byte("Tor safe cookie authentication " +
+		"server-to-controller hash")
+	safeCookieClientKey = []byte("Tor safe cookie authentication " +
+		"controller-to-server hash")
+
+	// ErrNotStarted is returned when the controller is used before it was
+	// successfully started.
+	ErrNotStarted = errors.New("tor controller not started")
+)
+
+// Config is the configuration of a Controller.
+type Config struct {
+	// Addr is the address of the Tor control port.
+	Addr string
+
+	// Password is the password of the control port when it is protected by
+	// HashedControlPassword.  Cookie authentication is used otherwise.
+	Password string
+
+	// PrivateKeyFile is the file the private key of the onion service is
+	// saved to, so the service keeps the same address across restarts.
+	// When empty, a new address is created every time.
+	PrivateKeyFile string
+
+	// VirtualPort is the port of the onion service advertised to peers.
+	VirtualPort uint16
+
+	// Target is the local address connections to the onion service are
+	// forwarded to.
+	Target string
+
+	// Dial connects to the control port.  It defaults to net.DialTimeout.
+	Dial func(network, addr string, timeout time.Duration) (net.Conn, error)
+}
+
+// Controller creates and maintains an onion service through the Tor control
+// port.  It is not safe for concurrent access.
+type Controller struct {
+	cfg       Config
+	conn      *textproto.Conn
+	serviceID string
+}
+
+// New returns a new controller with the passed configuration.
+func New(cfg *Config) *Controller {
+	c := &Controller{cfg: *cfg}
+	if c.cfg.Dial == nil {
+		c.cfg.Dial = net.DialTimeout
+	}
+	return c
+}
+
+// Start connects to the control port, authenticates and creates the onion
+// service.  It returns the onion address of the service.
+func (c *Controller) Start() (string, error) {
+	if c.conn != nil {
+		return "", errors.New("tor controller already started")
+	}
+
+	conn, err := c.cfg.Dial("tcp", c.cfg.Addr, dialTimeout)
+	if err != nil {
+		return "", err
+	}
+	c.conn = textproto.NewConn(conn)
+
+	serviceID, err := c.start()
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return "", err
+	}
+	c.serviceID = serviceID
+
+	log.Debugf("Created onion service %s.onion forwarding to %s",
+		serviceID, c.cfg.Target)
+
+	return serviceID + ".onion", nil
+}
+
+// start authenticates with the control port and creates the onion service.
+func (c *Controller) start() (string, error) {
+	info, err := c.protocolInfo()
+	if err != nil {
+		return "", err
+	}
+	if err := c.authenticate(info); err != nil {
+		return "", err
+	}
+
+	return c.addOnion()
+}
+
+// Ping checks the control connection is still alive, which is required for
+// the onion service to be reachable.
+func (c *Controller) Ping() error {
+	if c.conn == nil {
+		return ErrNotStarted
+	}
+
+	_, err := c.command("GETINFO version")
+	return err
+}
+
+// Stop removes the onion service and closes the control connection.
+func (c *Controller) Stop() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	_, err := c.command("DEL_ONION %s", c.serviceID)
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	c.conn = nil
+	c.serviceID = ""
+
+	return err
+}
+
+// command sends a command to the control port and returns the lines of its
+// successful reply.
+func (c *Controller) command(format string, args ...interface{}) ([]string,
+	error) {
+
+	id, err := c.conn.Cmd(format, args...)
+	if err != nil {
+		return nil, err
+	}
+	c.conn.StartResponse(id)
+	defer c.conn.EndResponse(id)
+
+	_, msg, err := c.conn.ReadResponse(statusOK)
+	if err != nil {
+		cmd := strings.SplitN(format, " ", 2)[0]
+		return nil, fmt.Errorf("tor control command %s failed: %v", cmd,
+			err)
+	}
+
+	return strings.Split(msg, "\n"), nil
+}
+
+// protocolInfo returns the authentication methods and cookie file supported
+// by the control port.
+func (c *Controller) protocolInfo() (map[string]string, error) {
+	lines, err := c.command("PROTOCOLINFO 1")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "AUTH ") {
+			return parseKeyValues(strings.TrimPrefix(line, "AUTH "))
+		}
+	}
+
+	return nil, errors.New("tor control port didn't report any " +
+		"authentication method")
+}
+
+// authenticate authenticates with the control port using the best method
+// supported by both sides.
+func (c *Controller) authenticate(info map[string]string) error {
+	methods := make(map[string]bool)
+	for _, method := range strings.Split(info["METHODS"], ",") {
+		methods[method] = true
+	}
+
+	switch {
+	case c.cfg.Password != "":
+		if !methods["HASHEDPASSWORD"] {
+			return errors.New("tor control port doesn't support " +
+				"password authentication")
+		}
+		_, err := c.command("AUTHENTICATE %s", quote(c.cfg.Password))
+		return err
+
+	case methods["NULL"]:
+		_, err := c.command("AUTHENTICATE")
+		return err
+
+	case methods["SAFECOOKIE"]:
+		cookie, err := readCookie(info["COOKIEFILE"])
+		if err != nil {
+			return err
+		}
+		return c.authenticateSafeCookie(cookie)
+
+	case methods["COOKIE"]:
+		cookie, err := readCookie(info["COOKIEFILE"])
+		if err != nil {
+			return err
+		}
+		_, err = c.command("AUTHENTICATE %x", cookie)
+		return err
+	}
+
+	return fmt.Errorf("no supported tor authentication method in %q",
+		info["METHODS"])
+}
+
+// authenticateSafeCookie authenticates with the control port using the
+// SAFECOOKIE challenge-response method, which proves to each side that the
+// other knows the cookie without revealing it.
+func (c *Controller) authenticateSafeCookie(cookie []byte) error {
+	clientNonce := make([]byte, 32)
+	if _, err := rand.Read(clientNonce); err != nil {
+		return err
+	}
+
+	lines, err := c.command("AUTHCHALLENGE SAFECOOKIE %x", clientNonce)
+	if err != nil {
+		return err
+	}
+	challenge, err := parseKeyValues(strings.TrimPrefix(lines[0],
+		"AUTHCHALLENGE "))
+	if err != nil {
+		return err
+	}
+	serverHash, err := hex.DecodeString(challenge["SERVERHASH"])
+	if err != nil {
+		return fmt.Errorf("invalid tor server hash: %v", err)
+	}
+	serverNonce, err := hex.DecodeString(challenge["SERVERNONCE"])
+	if err != nil {
+		return fmt.Errorf("invalid tor server nonce: %v", err)
+	}
+
+	message := make([]byte, 0, len(cookie)+len(clientNonce)+len(serverNonce))
+	message = append(message, cookie...)
+	message = append(message, clientNonce...)
+	message = append(message, serverNonce...)
+	if !hmac.Equal(serverHash, computeHMAC(safeCookieServerKey, message)) {
+		return errors.New("tor control port failed the cookie challenge")
+	}
+
+	_, err = c.command("AUTHENTICATE %x",
+		computeHMAC(safeCookieClientKey, message))
+	return err
+}
+
+// addOnion creates the onion service, reusing the saved private key if any,
+// and returns its service ID.
+func (c *Controller) addOnion() (string, error) {
+	key := "NEW:" + keyTypeV3
+	if c.cfg.PrivateKeyFile != "" {
+		savedKey, err := os.ReadFile(c.cfg.PrivateKeyFile)
+		switch {
+		case err == nil:
+			key = strings.TrimSpace(string(savedKey))
+		case !os.IsNotExist(err):
+			return "", err
+		}
+	}
+
+	lines, err := c.command("ADD_ONION %s Port=%d,%s", key,
+		c.cfg.VirtualPort, c.cfg.Target)
+	if err != nil {
+		return "", err
+	}
+
+	var serviceID, privateKey string
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "ServiceID="):
+			serviceID = strings.TrimPrefix(line, "ServiceID=")
+		case strings.HasPrefix(line, "PrivateKey="):
+			privateKey = strings.TrimPrefix(line, "PrivateKey=")
+		}
+	}
+	if serviceID == "" {
+		return "", errors.New("tor control port didn't return the " +
+			"onion service ID")
+	}
+
+	if privateKey != "" && c.cfg.PrivateKeyFile != "" {
+		err := os.WriteFile(c.cfg.PrivateKeyFile, []byte(privateKey+"\n"),
+			0600)
+		if err != nil {
+			log.Warnf("Unable to save the onion service private key: %v",
+				err)
+		}
+	}
+
+	return serviceID, nil
+}
+
+// readCookie reads the authentication cookie of Tor from the passed file.
+func readCookie(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("tor control port didn't report its " +
+			"cookie file")
+	}
+	cookie, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read tor cookie: %v", err)
+	}
+	if len(cookie) != cookieSize {
+		return nil, fmt.Errorf("invalid tor cookie size %d in %s",
+			len(cookie), path)
+	}
+
+	return cookie, nil
+}
+
+// computeHMAC returns the HMAC-SHA256 of the message with the passed key.
+func computeHMAC(key, message []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// quote returns the passed string as a quoted string of the control protocol.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// parseKeyValues parses the space separated KEY=VALUE pairs of a reply line,
+// where the values may be quoted strings.
+func parseKeyValues(line string) (map[string]string, error) {
+	values := make(map[string]string)
+	for {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("malformed tor reply %q", line)
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+
+		if !strings.HasPrefix(line, `"`) {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			values[key] = line[:end]
+			line = line[end:]
+			continue
+		}
+
+		// Find the closing quote, skipping the escaped characters.
+		end := 1
+		for ; end < len(line) && line[end] != '"'; end++ {
+			if line[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(line) {
+			return nil, fmt.Errorf("unterminated quoted string in tor "+
+				"reply %q", line)
+		}
+		value, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed quoted string in tor "+
+				"reply %q: %v", line, err)
+		}
+		values[key] = value
+		line = line[end+1:]
+	}
+
+	return values, nil
+}
@@ -0,0 +1,235 @@
+package torcontrol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testServiceID = "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad"
+
+// fakeTor is a fake Tor control port which supports the SAFECOOKIE and hashed
+// password authentications.
+type fakeTor struct {
+	cookieFile string
+	cookie     []byte
+	password   string
+
+	// commands records the commands received by the fake control port.
+	commands chan string
+}
+
+// serve answers the commands received on the passed connection.
+func (f *fakeTor) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	var clientNonce, serverNonce []byte
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		f.commands <- line
+
+		fields := strings.Fields(line)
+		var reply string
+		switch fields[0] {
+		case "PROTOCOLINFO":
+			reply = fmt.Sprintf("250-PROTOCOLINFO 1\r\n"+
+				"250-AUTH METHODS=COOKIE,SAFECOOKIE,HASHEDPASSWORD "+
+				"COOKIEFILE=%q\r\n250-VERSION Tor=\"0.4.8.9\"\r\n"+
+				"250 OK\r\n", f.cookieFile)
+
+		case "AUTHCHALLENGE":
+			clientNonce, _ = hex.DecodeString(fields[2])
+			serverNonce = bytes.Repeat([]byte{0x42}, 32)
+			message := append(append(append([]byte{}, f.cookie...),
+				clientNonce...), serverNonce...)
+			reply = fmt.Sprintf("250 AUTHCHALLENGE SERVERHASH=%x "+
+				"SERVERNONCE=%x\r\n", computeHMAC(safeCookieServerKey,
+				message), serverNonce)
+
+		case "AUTHENTICATE":
+			var want string
+			if f.password != "" {
+				want = quote(f.password)
+			} else {
+				message := append(append(append([]byte{}, f.cookie...),
+					clientNonce...), serverNonce...)
+				want = hex.EncodeToString(computeHMAC(
+					safeCookieClientKey, message))
+			}
+			if len(fields) == 2 && fields[1] == want {
+				reply = "250 OK\r\n"
+			} else {
+				reply = "515 Authentication failed\r\n"
+			}
+
+		case "ADD_ONION":
+			reply = "250-ServiceID=" + testServiceID + "\r\n"
+			if strings.HasPrefix(fields[1], "NEW:") {
+				reply += "250-PrivateKey=ED25519-V3:secret\r\n"
+			}
+			reply += "250 OK\r\n"
+
+		case "GETINFO", "DEL_ONION":
+			reply = "250 OK\r\n"
+
+		default:
+			reply = "510 Unrecognized command\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// dial returns a dial function connecting to the fake control port.
+func (f *fakeTor) dial(string, string, time.Duration) (net.Conn, error) {
+	client, server := net.Pipe()
+	go f.serve(server)
+	return client, nil
+}
+
+// expectCommand ensures the next command received by the fake control port is
+// the expected one.
+func (f *fakeTor) expectCommand(t *testing.T, want string) {
+	t.Helper()
+
+	select {
+	case cmd := <-f.commands:
+		if cmd != want {
+			t.Fatalf("unexpected command - got %q, want %q", cmd, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for command %q", want)
+	}
+}
+
+// TestControllerSafeCookie ensures the controller authenticates with the
+// SAFECOOKIE method, creates an onion service, and reuses the saved private key
+// on the next start.
+func TestControllerSafeCookie(t *testing.T) {
+	dir := t.TempDir()
+	tor := &fakeTor{
+		cookieFile: filepath.Join(dir, "control_auth_cookie"),
+		cookie:     bytes.Repeat([]byte{0x01}, cookieSize),
+		commands:   make(chan string, 10),
+	}
+	if err := os.WriteFile(tor.cookieFile, tor.cookie, 0600); err != nil {
+		t.Fatalf("unable to write cookie: %v", err)
+	}
+
+	keyFile := filepath.Join(dir, "onion_v3_private_key")
+	c := New(&Config{
+		PrivateKeyFile: keyFile,
+		VirtualPort:    9246,
+		Target:         "127.0.0.1:9246",
+		Dial:           tor.dial,
+	})
+	host, err := c.Start()
+	if err != nil {
+		t.Fatalf("Start: unexpected error: %v", err)
+	}
+	if host != testServiceID+".onion" {
+		t.Fatalf("Start: wrong onion address %q", host)
+	}
+	tor.expectCommand(t, "PROTOCOLINFO 1")
+	<-tor.commands // AUTHCHALLENGE
+	<-tor.commands // AUTHENTICATE
+	tor.expectCommand(t, "ADD_ONION NEW:ED25519-V3 Port=9246,127.0.0.1:9246")
+
+	savedKey, err := os.ReadFile(keyFile)
+	if err != nil {
+		t.Fatalf("unable to read saved private key: %v", err)
+	}
+	if string(savedKey) != "ED25519-V3:secret\n" {
+		t.Fatalf("wrong saved private key %q", savedKey)
+	}
+
+	if err := c.Ping(); err != nil {
+		t.Fatalf("Ping: unexpected error: %v", err)
+	}
+	tor.expectCommand(t, "GETINFO version")
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop: unexpected error: %v", err)
+	}
+	tor.expectCommand(t, "DEL_ONION "+testServiceID)
+	if err := c.Ping(); err != ErrNotStarted {
+		t.Fatalf("Ping: wrong error after stop - got %v, want %v", err,
+			ErrNotStarted)
+	}
+
+	// The saved private key must be used on restart.
+	if _, err := c.Start(); err != nil {
+		t.Fatalf("Start: unexpected error on restart: %v", err)
+	}
+	<-tor.commands // PROTOCOLINFO
+	<-tor.commands // AUTHCHALLENGE
+	<-tor.commands // AUTHENTICATE
+	tor.expectCommand(t, "ADD_ONION ED25519-V3:secret "+
+		"Port=9246,127.0.0.1:9246")
+}
+
+// TestControllerPassword ensures the controller authenticates with the hashed
+// password method when a password is configured, and fails to start when the
+// password is wrong.
+func TestControllerPassword(t *testing.T) {
+	tor := &fakeTor{
+		password: `pa"ss`,
+		commands: make(chan string, 10),
+	}
+
+	c := New(&Config{
+		Password:    `pa"ss`,
+		VirtualPort: 9246,
+		Target:      "127.0.0.1:9246",
+		Dial:        tor.dial,
+	})
+	if _, err := c.Start(); err != nil {
+		t.Fatalf("Start: unexpected error: %v", err)
+	}
+	tor.expectCommand(t, "PROTOCOLINFO 1")
+	tor.expectCommand(t, `AUTHENTICATE "pa\"ss"`)
+	c.Stop()
+
+	c = New(&Config{
+		Password:    "wrong",
+		VirtualPort: 9246,
+		Target:      "127.0.0.1:9246",
+		Dial:        tor.dial,
+	})
+	if _, err := c.Start(); err == nil {
+		t.Fatal("Start: unexpected success with a wrong password")
+	}
+}
+
+// TestParseKeyValues ensures the key value pairs of the replies are parsed
+// properly including quoted strings.
+func TestParseKeyValues(t *testing.T) {
+	values, err := parseKeyValues(`METHODS=COOKIE,SAFECOOKIE ` +
+		`COOKIEFILE="/var/lib/tor/control \"auth\" cookie"`)
+	if err != nil {
+		t.Fatalf("parseKeyValues: unexpected error: %v", err)
+	}
+	if values["METHODS"] != "COOKIE,SAFECOOKIE" {
+		t.Errorf("wrong METHODS %q", values["METHODS"])
+	}
+	if values["COOKIEFILE"] != `/var/lib/tor/control "auth" cookie` {
+		t.Errorf("wrong COOKIEFILE %q", values["COOKIEFILE"])
+	}
+
+	if _, err := parseKeyValues(`COOKIEFILE="unterminated`); err == nil {
+		t.Error("parseKeyValues: unexpected success with unterminated " +
+			"quoted string")
+	}
+}
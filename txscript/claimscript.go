@@ -2,6 +2,7 @@ package txscript
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 	"unicode/utf8"
 )
@@ -36,6 +37,13 @@ const (
 
 	// ErrInvalidClaimName is returned when the claim name is invalid.
 	ErrInvalidClaimName
+
+	// ErrInvalidClaimID is returned when the claim ID is invalid.
+	ErrInvalidClaimID
+
+	// ErrNonCanonicalClaimScript is returned when a claim script does not
+	// push its data with the smallest direct push.
+	ErrNonCanonicalClaimScript
 )
 
 func claimScriptError(c ErrorCode, desc string) Error {
@@ -44,23 +52,241 @@ func claimScriptError(c ErrorCode, desc string) Error {
 
 // ClaimNameScript creates a claim name script.
 func ClaimNameScript(name string, value string) ([]byte, error) {
-	return NewScriptBuilder().AddOp(OP_CLAIMNAME).AddData([]byte(name)).AddData([]byte(value)).
-		AddOp(OP_2DROP).AddOp(OP_DROP).AddOp(OP_TRUE).Script()
+	return NewClaimNameScript([]byte(name), []byte(value), []byte{OP_TRUE})
 }
 
 // ClaimSupportScript creates a support claim script.
 func ClaimSupportScript(name string, claimID []byte, value []byte) ([]byte, error) {
-	builder := NewScriptBuilder().AddOp(OP_SUPPORTCLAIM).AddData([]byte(name)).AddData(claimID)
-	if len(value) > 0 {
-		return builder.addData(value).AddOp(OP_2DROP).AddOp(OP_2DROP).AddOp(OP_TRUE).Script()
-	}
-	return builder.AddOp(OP_2DROP).AddOp(OP_DROP).AddOp(OP_TRUE).Script()
+	return NewSupportClaimScript([]byte(name), claimID, value, []byte{OP_TRUE})
 }
 
 // ClaimUpdateScript creates an update claim script.
 func ClaimUpdateScript(name string, claimID []byte, value string) ([]byte, error) {
-	return NewScriptBuilder().AddOp(OP_UPDATECLAIM).AddData([]byte(name)).AddData(claimID).AddData([]byte(value)).
-		AddOp(OP_2DROP).AddOp(OP_2DROP).AddOp(OP_TRUE).Script()
+	return NewUpdateClaimScript([]byte(name), claimID, []byte(value), []byte{OP_TRUE})
+}
+
+// appendClaimPushData appends the smallest direct push of the passed data to
+// the script.  Unlike the script builder, small integers are not pushed with
+// OP_1 through OP_16 since claim scripts are parsed as data pushes only.
+func appendClaimPushData(script, data []byte) []byte {
+	switch n := len(data); {
+	case n == 0:
+		script = append(script, OP_0)
+	case n <= OP_DATA_75:
+		script = append(script, byte(n))
+	case n <= 0xff:
+		script = append(script, OP_PUSHDATA1, byte(n))
+	default:
+		script = append(script, OP_PUSHDATA2, byte(n), byte(n>>8))
+	}
+	return append(script, data...)
+}
+
+// newClaimScript returns the claim script with the passed opcode pushing the
+// passed data followed by the passed public key script.
+func newClaimScript(c ErrorCode, opcode byte, pkScript []byte, name []byte, data ...[]byte) ([]byte, error) {
+	if len(name) > MaxClaimNameSize {
+		str := fmt.Sprintf("name size %d exceeds limit %d", len(name), MaxClaimNameSize)
+		return nil, claimScriptError(c, str)
+	}
+
+	script := []byte{opcode}
+	script = appendClaimPushData(script, name)
+	for _, d := range data {
+		if len(d) > MaxClaimScriptSize {
+			str := fmt.Sprintf("script size exceeds limit %d", MaxClaimScriptSize)
+			return nil, claimScriptError(c, str)
+		}
+		script = appendClaimPushData(script, d)
+	}
+
+	// The claim name and data are dropped in pairs: OP_2DROP OP_DROP for
+	// two pushes, and OP_2DROP OP_2DROP for three.
+	if len(data) == 1 {
+		script = append(script, OP_2DROP, OP_DROP)
+	} else {
+		script = append(script, OP_2DROP, OP_2DROP)
+	}
+	if len(script) > MaxClaimScriptSize {
+		str := fmt.Sprintf("script size %d exceeds limit %d", len(script), MaxClaimScriptSize)
+		return nil, claimScriptError(c, str)
+	}
+
+	return append(script, pkScript...), nil
+}
+
+// validateClaimID ensures the passed claim ID has the length of claim IDs.
+func validateClaimID(claimID []byte) error {
+	if len(claimID) != ClaimIDLength {
+		str := fmt.Sprintf("expect claim id length %d, instead of %d", ClaimIDLength, len(claimID))
+		return claimScriptError(ErrInvalidClaimID, str)
+	}
+	return nil
+}
+
+// NewClaimNameScript returns a script claiming the passed name with the passed
+// value, which is paid to the passed public key script:
+//
+//	OP_CLAIMNAME <Name> <Value> OP_2DROP OP_DROP <pkScript>
+func NewClaimNameScript(name, value, pkScript []byte) ([]byte, error) {
+	return newClaimScript(ErrInvalidClaimNameScript, OP_CLAIMNAME, pkScript, name, value)
+}
+
+// NewSupportClaimScript returns a script supporting the claim with the passed
+// name and claim ID, which is paid to the passed public key script.  The value
+// is optional:
+//
+//	OP_SUPPORTCLAIM <Name> <ClaimID>         OP_2DROP OP_DROP  <pkScript>
+//	OP_SUPPORTCLAIM <Name> <ClaimID> <Value> OP_2DROP OP_2DROP <pkScript>
+func NewSupportClaimScript(name, claimID, value, pkScript []byte) ([]byte, error) {
+	if err := validateClaimID(claimID); err != nil {
+		return nil, err
+	}
+	if len(value) == 0 {
+		return newClaimScript(ErrInvalidClaimSupportScript, OP_SUPPORTCLAIM, pkScript, name, claimID)
+	}
+	return newClaimScript(ErrInvalidClaimSupportScript, OP_SUPPORTCLAIM, pkScript, name, claimID, value)
+}
+
+// NewUpdateClaimScript returns a script updating the claim with the passed
+// name and claim ID to the passed value, which is paid to the passed public
+// key script:
+//
+//	OP_UPDATECLAIM <Name> <ClaimID> <Value> OP_2DROP OP_2DROP <pkScript>
+func NewUpdateClaimScript(name, claimID, value, pkScript []byte) ([]byte, error) {
+	if err := validateClaimID(claimID); err != nil {
+		return nil, err
+	}
+	return newClaimScript(ErrInvalidClaimUpdateScript, OP_UPDATECLAIM, pkScript, name, claimID, value)
+}
+
+// DecodeClaimID returns the claim ID encoded by the passed string as it's
+// pushed in claim scripts.  The string is the hex encoding of the claim ID in
+// reverse byte order, as claim IDs are displayed.
+func DecodeClaimID(s string) ([]byte, error) {
+	claimID, err := hex.DecodeString(s)
+	if err != nil {
+		str := fmt.Sprintf("claim id %q is not hex encoded: %v", s, err)
+		return nil, claimScriptError(ErrInvalidClaimID, str)
+	}
+	if err := validateClaimID(claimID); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(claimID)-1; i < j; i, j = i+1, j-1 {
+		claimID[i], claimID[j] = claimID[j], claimID[i]
+	}
+	return claimID, nil
+}
+
+// EncodeClaimID returns the string encoding of the passed claim ID as it's
+// pushed in claim scripts.  It's the inverse of DecodeClaimID.
+func EncodeClaimID(claimID []byte) string {
+	reversed := make([]byte, len(claimID))
+	for i, b := range claimID {
+		reversed[len(claimID)-1-i] = b
+	}
+	return hex.EncodeToString(reversed)
+}
+
+// ClaimScriptType identifies the type of a claim script.
+type ClaimScriptType byte
+
+// Claim script types.
+const (
+	ClaimNameTy    ClaimScriptType = OP_CLAIMNAME    // Claim a name.
+	SupportClaimTy ClaimScriptType = OP_SUPPORTCLAIM // Support a claim.
+	UpdateClaimTy  ClaimScriptType = OP_UPDATECLAIM  // Update a claim.
+)
+
+// claimScriptTypeToName houses the human-readable strings which describe each
+// claim script type.
+var claimScriptTypeToName = map[ClaimScriptType]string{
+	ClaimNameTy:    "claimname",
+	SupportClaimTy: "supportclaim",
+	UpdateClaimTy:  "updateclaim",
+}
+
+// String implements the Stringer interface by returning the name of the enum
+// claim script type.  If the enum is invalid then "Invalid" will be returned.
+func (t ClaimScriptType) String() string {
+	if s, ok := claimScriptTypeToName[t]; ok {
+		return s
+	}
+	return "Invalid"
+}
+
+// ParsedClaimScript is a parsed claim script.  Unlike ClaimScript, its fields
+// are copied from the script and remain valid when the script is modified.
+type ParsedClaimScript struct {
+	// Type is the type of the claim script.
+	Type ClaimScriptType
+
+	// Name is the claimed name.
+	Name []byte
+
+	// ClaimID is the ID of the supported or updated claim, as it's pushed
+	// in the script.  It's nil for claim name scripts, whose claim ID is
+	// derived from the outpoint of the output.
+	ClaimID []byte
+
+	// Value is the value of the claim or support, which is nil for
+	// supports without a value.
+	Value []byte
+
+	// PkScript is the public key script the output is paid to.
+	PkScript []byte
+}
+
+// ClaimIDString returns the string encoding of the claim ID of the script, or
+// an empty string for claim name scripts.
+func (p *ParsedClaimScript) ClaimIDString() string {
+	if p.ClaimID == nil {
+		return ""
+	}
+	return EncodeClaimID(p.ClaimID)
+}
+
+// copyBytes returns a copy of the passed bytes, keeping nil as is.
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// ParseClaimScript parses the passed claim script into its parts.  In addition
+// to the checks of ExtractClaimScript, the data of the script must be pushed
+// with the smallest direct push, as done by NewClaimNameScript,
+// NewSupportClaimScript and NewUpdateClaimScript.
+func ParseClaimScript(script []byte) (*ParsedClaimScript, error) {
+	cs, err := ExtractClaimScript(script)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenizer := MakeScriptTokenizer(claimScriptVersion, script[:cs.Size])
+	tokenizer.Next()
+	for tokenizer.Next() {
+		op := tokenizer.Opcode()
+		if op == OP_2DROP || op == OP_DROP {
+			break
+		}
+		canonical := appendClaimPushData(nil, tokenizer.Data())
+		if op != canonical[0] {
+			str := fmt.Sprintf("data push of %d bytes encoded with opcode %s "+
+				"instead of %s", len(tokenizer.Data()), opcodeArray[op].name,
+				opcodeArray[canonical[0]].name)
+			return nil, claimScriptError(ErrNonCanonicalClaimScript, str)
+		}
+	}
+
+	return &ParsedClaimScript{
+		Type:     ClaimScriptType(cs.Opcode),
+		Name:     copyBytes(cs.Name),
+		ClaimID:  copyBytes(cs.ClaimID),
+		Value:    copyBytes(cs.Value),
+		PkScript: copyBytes(script[cs.Size:]),
+	}, nil
 }
 
 // ClaimScript represents of one of the ClaimNameScript, ClaimSupportScript, and ClaimUpdateScript.
@@ -228,3 +454,71 @@ func AllClaimsAreSane(script []byte, enforceSoftFork bool) error {
 
 	return nil
 }
+
+// TimeLock is the lock time prefix of a public key script, which prevents the
+// output from being spent until the lock time:
+//
+//	<LockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP <pkScript>
+//	<Sequence> OP_CHECKSEQUENCEVERIFY OP_DROP <pkScript>
+//
+// Some tooling pays claims to such scripts, which prevents the claims from
+// being updated or abandoned until the lock time.
+type TimeLock struct {
+	// Opcode is either OP_CHECKLOCKTIMEVERIFY or OP_CHECKSEQUENCEVERIFY.
+	Opcode byte
+
+	// LockTime is the absolute lock time for OP_CHECKLOCKTIMEVERIFY, or
+	// the relative lock time encoded like the sequence numbers of inputs
+	// for OP_CHECKSEQUENCEVERIFY.
+	LockTime int64
+
+	// Size is the size of the prefix in bytes.
+	Size int
+}
+
+// ExtractTimeLock returns the lock time prefix of the passed script, or nil
+// when it doesn't have one.  The lock time must be a non-negative number pushed
+// with the smallest push, and the prefix must be followed by the script the
+// output is paid to.
+func ExtractTimeLock(script []byte) *TimeLock {
+	tokenizer := MakeScriptTokenizer(0, script)
+	if !tokenizer.Next() {
+		return nil
+	}
+
+	var lockTime int64
+	op, data := tokenizer.Opcode(), tokenizer.Data()
+	switch {
+	case isSmallInt(op):
+		lockTime = int64(asSmallInt(op))
+
+	case op <= OP_PUSHDATA4 && isCanonicalPush(op, data):
+		// The lock times are 5 bytes numbers to allow for the full
+		// range of the unsigned 32-bit lock times.
+		n, err := makeScriptNum(data, true, 5)
+		if err != nil || n < 0 {
+			return nil
+		}
+		lockTime = int64(n)
+
+	default:
+		return nil
+	}
+
+	if !tokenizer.Next() {
+		return nil
+	}
+	lockOp := tokenizer.Opcode()
+	if lockOp != OP_CHECKLOCKTIMEVERIFY && lockOp != OP_CHECKSEQUENCEVERIFY {
+		return nil
+	}
+	if !tokenizer.Next() || tokenizer.Opcode() != OP_DROP || tokenizer.Done() {
+		return nil
+	}
+
+	return &TimeLock{
+		Opcode:   lockOp,
+		LockTime: lockTime,
+		Size:     int(tokenizer.ByteIndex()),
+	}
+}
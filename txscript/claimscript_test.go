@@ -3,6 +3,8 @@ package txscript
 import (
 	"testing"
 
+	"github.com/lbryio/lbcd/chaincfg"
+	btcutil "github.com/lbryio/lbcutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -65,6 +67,39 @@ func TestCreationParseLoopSupport(t *testing.T) {
 
 }
 
+func TestExtractClaimScriptAddrs(t *testing.T) {
+	r := require.New(t)
+
+	params := &chaincfg.MainNetParams
+	addr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+	r.NoError(err)
+	pkScript, err := PayToAddrScript(addr)
+	r.NoError(err)
+
+	claimID := []byte("12345123451234512345")
+	prefixes := make([][]byte, 0, 3)
+	for _, build := range []func() ([]byte, error){
+		func() ([]byte, error) { return ClaimNameScript("tester", "value") },
+		func() ([]byte, error) { return ClaimSupportScript("tester", claimID, nil) },
+		func() ([]byte, error) { return ClaimUpdateScript("tester", claimID, "value") },
+	} {
+		script, err := build()
+		r.NoError(err)
+		// Replace the trailing OP_TRUE with the script paying to the address.
+		prefixes = append(prefixes, script[:len(script)-1])
+	}
+
+	for _, prefix := range prefixes {
+		script := append(append([]byte{}, prefix...), pkScript...)
+		class, addrs, reqSigs, err := ExtractPkScriptAddrs(script, params)
+		r.NoError(err)
+		r.Equal(PubKeyHashTy, class)
+		r.Equal(1, reqSigs)
+		r.Len(addrs, 1)
+		r.Equal(addr.EncodeAddress(), addrs[0].EncodeAddress())
+	}
+}
+
 func TestInvalidChars(t *testing.T) {
 	r := require.New(t)
 
@@ -78,3 +113,153 @@ func TestInvalidChars(t *testing.T) {
 		r.Error(AllClaimsAreSane(script, true))
 	}
 }
+
+func TestNewClaimScripts(t *testing.T) {
+	r := require.New(t)
+
+	params := &chaincfg.MainNetParams
+	addr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), params)
+	r.NoError(err)
+	pkScript, err := PayToAddrScript(addr)
+	r.NoError(err)
+
+	claimID, err := DecodeClaimID("0102030405060708090a0b0c0d0e0f1011121314")
+	r.NoError(err)
+	r.Equal(byte(0x14), claimID[0])
+	r.Equal("0102030405060708090a0b0c0d0e0f1011121314", EncodeClaimID(claimID))
+
+	tests := []struct {
+		name     string
+		build    func() ([]byte, error)
+		typ      ClaimScriptType
+		claimID  []byte
+		value    []byte
+		expected string
+	}{
+		{
+			name:     "claim",
+			build:    func() ([]byte, error) { return NewClaimNameScript([]byte("a"), []byte{0x01}, pkScript) },
+			typ:      ClaimNameTy,
+			value:    []byte{0x01},
+			expected: "claimname",
+		},
+		{
+			name:     "support",
+			build:    func() ([]byte, error) { return NewSupportClaimScript([]byte("a"), claimID, nil, pkScript) },
+			typ:      SupportClaimTy,
+			claimID:  claimID,
+			expected: "supportclaim",
+		},
+		{
+			name:     "support with value",
+			build:    func() ([]byte, error) { return NewSupportClaimScript([]byte("a"), claimID, []byte("v"), pkScript) },
+			typ:      SupportClaimTy,
+			claimID:  claimID,
+			value:    []byte("v"),
+			expected: "supportclaim",
+		},
+		{
+			name:     "update",
+			build:    func() ([]byte, error) { return NewUpdateClaimScript([]byte("a"), claimID, []byte("v"), pkScript) },
+			typ:      UpdateClaimTy,
+			claimID:  claimID,
+			value:    []byte("v"),
+			expected: "updateclaim",
+		},
+	}
+
+	for _, test := range tests {
+		script, err := test.build()
+		r.NoError(err, test.name)
+		p, err := ParseClaimScript(script)
+		r.NoError(err, test.name)
+		r.Equal(test.typ, p.Type, test.name)
+		r.Equal(test.expected, p.Type.String(), test.name)
+		r.Equal([]byte("a"), p.Name, test.name)
+		r.Equal(test.claimID, p.ClaimID, test.name)
+		r.Equal(test.value, p.Value, test.name)
+		r.Equal(pkScript, p.PkScript, test.name)
+
+		// The parsed script doesn't share memory with the script.
+		script[2] = 'b'
+		r.Equal([]byte("a"), p.Name, test.name)
+	}
+}
+
+func TestNewClaimScriptsInvalid(t *testing.T) {
+	r := require.New(t)
+
+	claimID := []byte("12345123451234512345")
+	longName := make([]byte, MaxClaimNameSize+1)
+	longValue := make([]byte, MaxClaimScriptSize)
+
+	_, err := NewClaimNameScript(longName, nil, nil)
+	r.True(IsErrorCode(err, ErrInvalidClaimNameScript))
+	_, err = NewClaimNameScript([]byte("a"), longValue, nil)
+	r.True(IsErrorCode(err, ErrInvalidClaimNameScript))
+	_, err = NewSupportClaimScript([]byte("a"), claimID[1:], nil, nil)
+	r.True(IsErrorCode(err, ErrInvalidClaimID))
+	_, err = NewUpdateClaimScript([]byte("a"), append(claimID, 0), nil, nil)
+	r.True(IsErrorCode(err, ErrInvalidClaimID))
+
+	_, err = DecodeClaimID("zz")
+	r.True(IsErrorCode(err, ErrInvalidClaimID))
+	_, err = DecodeClaimID("0102")
+	r.True(IsErrorCode(err, ErrInvalidClaimID))
+
+	// The script builder pushes the value 1 with OP_1, which isn't a
+	// canonical claim script push.
+	script, err := NewScriptBuilder().AddOp(OP_CLAIMNAME).AddData([]byte("a")).
+		AddData([]byte{0x01}).AddOp(OP_2DROP).AddOp(OP_DROP).AddOp(OP_TRUE).Script()
+	r.NoError(err)
+	_, err = ExtractClaimScript(script)
+	r.NoError(err)
+	_, err = ParseClaimScript(script)
+	r.True(IsErrorCode(err, ErrNonCanonicalClaimScript))
+
+	_, err = ParseClaimScript([]byte{OP_TRUE})
+	r.True(IsErrorCode(err, ErrNotClaimScript))
+}
+
+func TestExtractTimeLock(t *testing.T) {
+
+	r := require.New(t)
+
+	pkScript := []byte{OP_TRUE}
+	tests := []struct {
+		lockTime int64
+		lockOp   byte
+	}{
+		{0, OP_CHECKLOCKTIMEVERIFY},
+		{16, OP_CHECKSEQUENCEVERIFY},
+		{500000, OP_CHECKLOCKTIMEVERIFY},
+		{0xffffffff, OP_CHECKLOCKTIMEVERIFY},
+	}
+	for _, test := range tests {
+		prefix, err := NewScriptBuilder().AddInt64(test.lockTime).
+			AddOp(test.lockOp).AddOp(OP_DROP).Script()
+		r.NoError(err)
+		lock := ExtractTimeLock(append(prefix, pkScript...))
+		r.NotNil(lock)
+		r.Equal(test.lockOp, lock.Opcode)
+		r.Equal(test.lockTime, lock.LockTime)
+		r.Equal(len(prefix), lock.Size)
+
+		// The prefix must be followed by the script paid to.
+		r.Nil(ExtractTimeLock(prefix))
+	}
+
+	// Negative lock times, lock times pushed with a non-canonical push and
+	// other prefixes are not time locks.
+	invalid := [][]byte{
+		{OP_1NEGATE, OP_CHECKLOCKTIMEVERIFY, OP_DROP, OP_TRUE},
+		{OP_DATA_1, 0x01, OP_CHECKLOCKTIMEVERIFY, OP_DROP, OP_TRUE},
+		{OP_DATA_2, 0x01, 0x00, OP_CHECKLOCKTIMEVERIFY, OP_DROP, OP_TRUE},
+		{OP_1, OP_CHECKSIG, OP_DROP, OP_TRUE},
+		{OP_1, OP_CHECKLOCKTIMEVERIFY, OP_TRUE},
+		{OP_DUP, OP_CHECKLOCKTIMEVERIFY, OP_DROP, OP_TRUE},
+	}
+	for _, script := range invalid {
+		r.Nil(ExtractTimeLock(script))
+	}
+}
@@ -150,7 +150,7 @@ type Engine struct {
 	txIdx     int
 	version   uint16
 	bip16     bool
-	sigCache  *SigCache
+	sigCache  SignatureCache
 	hashCache *TxSigHashes
 
 	// The following fields handle keeping track of the current execution state
@@ -200,6 +200,11 @@ type Engine struct {
 	witnessVersion  int
 	witnessProgram  []byte
 	inputAmount     int64
+
+	// trace records the state of the engine after each opcode executed
+	// when tracing is enabled.
+	tracing bool
+	trace   []TraceStep
 }
 
 // hasFlag returns whether the script engine instance has the passed flag set.
@@ -781,7 +786,14 @@ func (vm *Engine) Execute() (err error) {
 			return fmt.Sprintf("stepping %v", dis)
 		}))
 
+		var step *TraceStep
+		if vm.tracing {
+			step = vm.newTraceStep()
+		}
 		done, err = vm.Step()
+		if step != nil {
+			vm.recordTraceStep(step, err)
+		}
 		if err != nil {
 			return err
 		}
@@ -1117,7 +1129,7 @@ func (vm *Engine) SetAltStack(data [][]byte) {
 // transaction, and input index.  The flags modify the behavior of the script
 // engine according to the description provided by each flag.
 func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags,
-	sigCache *SigCache, hashCache *TxSigHashes, inputAmount int64) (*Engine, error) {
+	sigCache SignatureCache, hashCache *TxSigHashes, inputAmount int64) (*Engine, error) {
 	const scriptVersion = 0
 
 	// The provided transaction input index must refer to a valid input.
@@ -11,6 +11,24 @@ import (
 	"github.com/lbryio/lbcd/chaincfg/chainhash"
 )
 
+// SignatureCache is the interface of the caches of valid signatures used by the
+// script engine to avoid verifying the same signatures more than once.  It is
+// implemented by SigCache, which is kept in memory, and DiskSigCache, which
+// persists its entries across restarts.
+type SignatureCache interface {
+	// Exists returns whether a valid signature 'sig' over 'sigHash' for
+	// public key 'pubKey' was added to the cache.
+	Exists(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) bool
+
+	// Add adds a valid signature 'sig' over 'sigHash' for public key
+	// 'pubKey' to the cache.
+	Add(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey)
+}
+
+// Ensure SigCache implements the SignatureCache interface.  A nil SigCache
+// is valid and caches nothing, so it can be passed as a SignatureCache.
+var _ SignatureCache = (*SigCache)(nil)
+
 // sigCacheEntry represents an entry in the SigCache. Entries within the
 // SigCache are keyed according to the sigHash of the signature. In the
 // scenario of a cache-hit (according to the sigHash), an additional comparison
@@ -56,6 +74,10 @@ func NewSigCache(maxEntries uint) *SigCache {
 // NOTE: This function is safe for concurrent access. Readers won't be blocked
 // unless there exists a writer, adding an entry to the SigCache.
 func (s *SigCache) Exists(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) bool {
+	if s == nil {
+		return false
+	}
+
 	s.RLock()
 	entry, ok := s.validSigs[sigHash]
 	s.RUnlock()
@@ -71,6 +93,10 @@ func (s *SigCache) Exists(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *
 // NOTE: This function is safe for concurrent access. Writers will block
 // simultaneous readers until function execution has concluded.
 func (s *SigCache) Add(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) {
+	if s == nil {
+		return
+	}
+
 	s.Lock()
 	defer s.Unlock()
 
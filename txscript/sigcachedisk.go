@@ -0,0 +1,188 @@
+package txscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lbryio/lbcd/btcec"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+const (
+	// diskSigCacheVersion is the version of the format of the files of
+	// DiskSigCache.
+	diskSigCacheVersion = 1
+
+	// diskSigCacheHeaderSize is the size of the header of the files of
+	// DiskSigCache, which holds the version and the number of entries.
+	diskSigCacheHeaderSize = 8
+)
+
+// DiskSigCache is a SigCache whose entries are persisted in a file, so the
+// signatures validated before a restart don't have to be verified again when
+// the mempool is reloaded or the blocks close to the tip are connected.
+//
+// The file is only read by Load and written by Save, so the entries added since
+// the last call to Save are lost when the process doesn't exit cleanly.  It is
+// protected by a checksum and ignored when corrupted.
+type DiskSigCache struct {
+	*SigCache
+	path string
+}
+
+// Ensure DiskSigCache implements the SignatureCache interface.
+var _ SignatureCache = (*DiskSigCache)(nil)
+
+// NewDiskSigCache returns a new empty signature cache holding up to the passed
+// number of entries which is persisted in the file at the passed path.  Load
+// must be called to read the entries saved in the file.
+func NewDiskSigCache(maxEntries uint, path string) *DiskSigCache {
+	return &DiskSigCache{
+		SigCache: NewSigCache(maxEntries),
+		path:     path,
+	}
+}
+
+// Load adds the entries saved in the file of the cache, up to the maximum
+// number of entries of the cache.  A missing file is not an error, and no
+// entries are added when the file is invalid.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *DiskSigCache) Load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	validSigs, err := deserializeSigCacheEntries(data, s.maxEntries)
+	if err != nil {
+		return fmt.Errorf("invalid signature cache file %s: %w", s.path,
+			err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for sigHash, entry := range validSigs {
+		if uint(len(s.validSigs)) >= s.maxEntries {
+			break
+		}
+		s.validSigs[sigHash] = entry
+	}
+	return nil
+}
+
+// Save writes the entries of the cache to its file, replacing the entries saved
+// before.  The file is replaced atomically, so the previous entries are kept
+// when an error occurs.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *DiskSigCache) Save() error {
+	s.RLock()
+	data := serializeSigCacheEntries(s.validSigs)
+	s.RUnlock()
+
+	tmpPath := s.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// serializeSigCacheEntries returns the serialization of the passed entries of a
+// signature cache.  The entries are serialized as the signature hash, the
+// compressed public key and the length prefixed DER encoded signature, after a
+// header holding the version of the format and the number of entries, and
+// followed by the SHA256 checksum of the data.
+func serializeSigCacheEntries(validSigs map[chainhash.Hash]sigCacheEntry) []byte {
+	var buf bytes.Buffer
+	var header [diskSigCacheHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], diskSigCacheVersion)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(validSigs)))
+	buf.Write(header[:])
+	for sigHash, entry := range validSigs {
+		sig := entry.sig.Serialize()
+		buf.Write(sigHash[:])
+		buf.Write(entry.pubKey.SerializeCompressed())
+		buf.WriteByte(byte(len(sig)))
+		buf.Write(sig)
+	}
+	checksum := sha256.Sum256(buf.Bytes())
+	buf.Write(checksum[:])
+	return buf.Bytes()
+}
+
+// deserializeSigCacheEntries returns up to the passed number of entries of a
+// signature cache serialized with serializeSigCacheEntries.
+func deserializeSigCacheEntries(data []byte, maxEntries uint) (map[chainhash.Hash]sigCacheEntry, error) {
+	if len(data) < diskSigCacheHeaderSize+sha256.Size {
+		return nil, errors.New("file is truncated")
+	}
+	payload := data[:len(data)-sha256.Size]
+	checksum := sha256.Sum256(payload)
+	if !bytes.Equal(checksum[:], data[len(payload):]) {
+		return nil, errors.New("checksum mismatch")
+	}
+	version := binary.LittleEndian.Uint32(payload[0:4])
+	if version != diskSigCacheVersion {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+	count := binary.LittleEndian.Uint32(payload[4:8])
+	if uint(count) > maxEntries {
+		count = uint32(maxEntries)
+	}
+
+	validSigs := make(map[chainhash.Hash]sigCacheEntry, count)
+	r := bytes.NewReader(payload[diskSigCacheHeaderSize:])
+	for i := uint32(0); i < count; i++ {
+		var sigHash chainhash.Hash
+		var pubKeyBytes [btcec.PubKeyBytesLenCompressed]byte
+		var sigLen [1]byte
+		_, err := io.ReadFull(r, sigHash[:])
+		if err == nil {
+			_, err = io.ReadFull(r, pubKeyBytes[:])
+		}
+		if err == nil {
+			_, err = io.ReadFull(r, sigLen[:])
+		}
+		sigBytes := make([]byte, sigLen[0])
+		if err == nil {
+			_, err = io.ReadFull(r, sigBytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("entry %d is truncated", i)
+		}
+
+		pubKey, err := btcec.ParsePubKey(pubKeyBytes[:], btcec.S256())
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		sig, err := btcec.ParseDERSignature(sigBytes, btcec.S256())
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		validSigs[sigHash] = sigCacheEntry{sig: sig, pubKey: pubKey}
+	}
+	return validSigs, nil
+}
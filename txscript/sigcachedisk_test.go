@@ -0,0 +1,79 @@
+package txscript
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbcd/btcec"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+// TestDiskSigCacheSaveLoad ensures the entries of a persisted signature cache
+// are restored by a new cache, up to its maximum number of entries, and that
+// corrupted files are ignored.
+func TestDiskSigCacheSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sigcache.dat")
+
+	// Loading a cache without a file succeeds without any entries.
+	sigCache := NewDiskSigCache(10, path)
+	if err := sigCache.Load(); err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+
+	type triplet struct {
+		msg *chainhash.Hash
+		sig *btcec.Signature
+		key *btcec.PublicKey
+	}
+	var entries []triplet
+	for i := 0; i < 10; i++ {
+		msg, sig, key, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		sigCache.Add(*msg, sig, key)
+		entries = append(entries, triplet{msg, sig, key})
+	}
+	if err := sigCache.Save(); err != nil {
+		t.Fatalf("Save: unexpected error: %v", err)
+	}
+
+	loaded := NewDiskSigCache(10, path)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	for i, entry := range entries {
+		if !loaded.Exists(*entry.msg, entry.sig, entry.key) {
+			t.Fatalf("entry #%d not found in the loaded cache", i)
+		}
+	}
+
+	// Smaller caches only load up to their maximum number of entries.
+	loaded = NewDiskSigCache(4, path)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if len(loaded.validSigs) != 4 {
+		t.Fatalf("unexpected number of loaded entries - got %d, want 4",
+			len(loaded.validSigs))
+	}
+
+	// Corrupted files are rejected without loading any entry.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: unexpected error: %v", err)
+	}
+	data[diskSigCacheHeaderSize] ^= 0x01
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v", err)
+	}
+	loaded = NewDiskSigCache(10, path)
+	if err := loaded.Load(); err == nil {
+		t.Fatalf("corrupted file was loaded")
+	}
+	if len(loaded.validSigs) != 0 {
+		t.Fatalf("%d entries loaded from a corrupted file",
+			len(loaded.validSigs))
+	}
+}
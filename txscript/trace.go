@@ -0,0 +1,103 @@
+package txscript
+
+import (
+	"strings"
+
+	"github.com/lbryio/lbcd/wire"
+)
+
+// TraceStep is the state of the script engine after executing an opcode while
+// tracing.
+type TraceStep struct {
+	// ScriptIndex is the index of the script the opcode belongs to.  Index
+	// 0 is the signature script and 1 is the public key script.  Higher
+	// indexes are the redeem script of pay-to-script-hash inputs and the
+	// witness script of witness inputs.
+	ScriptIndex int
+
+	// OpcodeIndex is the index of the opcode in its script.
+	OpcodeIndex int
+
+	// Opcode is the disassembly of the opcode along with its data.
+	Opcode string
+
+	// Stack and AltStack are the contents of the data and alternate stacks
+	// once the opcode executed, where the last item is the top of the
+	// stack.  The alternate stack is cleared at the end of each script.
+	Stack    [][]byte
+	AltStack [][]byte
+
+	// Err is the error the opcode failed with, if any.  It is only set
+	// for the last step of a trace.  The stacks are left as they were
+	// when the opcode failed.
+	Err error
+}
+
+// EnableTrace enables the tracing of the execution of the scripts, which
+// records the state of the engine after each opcode executed by Execute.  The
+// steps recorded are returned by Trace.
+func (vm *Engine) EnableTrace() {
+	vm.tracing = true
+}
+
+// Trace returns the steps recorded while executing the scripts with tracing
+// enabled, which includes the opcode which failed when the execution failed.
+// It returns nil when tracing isn't enabled.
+func (vm *Engine) Trace() []TraceStep {
+	return vm.trace
+}
+
+// newTraceStep returns a trace step for the opcode which will be executed next
+// when Step is called.
+func (vm *Engine) newTraceStep() *TraceStep {
+	step := &TraceStep{
+		ScriptIndex: vm.scriptIdx,
+		OpcodeIndex: vm.opcodeIdx,
+	}
+
+	// Parse the next opcode in a copy of the current tokenizer to avoid
+	// mutating the current one.  Parse failures are reported by Step.
+	if vm.checkValidPC() == nil {
+		peekTokenizer := vm.tokenizer
+		if peekTokenizer.Next() {
+			var buf strings.Builder
+			disasmOpcode(&buf, peekTokenizer.op, peekTokenizer.Data(),
+				false)
+			step.Opcode = buf.String()
+		}
+	}
+	return step
+}
+
+// recordTraceStep completes the passed trace step with the current state of the
+// engine and the passed error returned by Step, and records it.
+func (vm *Engine) recordTraceStep(step *TraceStep, err error) {
+	step.Stack = vm.GetStack()
+	step.AltStack = vm.GetAltStack()
+	step.Err = err
+	vm.trace = append(vm.trace, *step)
+}
+
+// TraceScript executes the scripts of the passed input of the transaction
+// spending the passed public key script with tracing enabled.  The steps
+// recorded are returned along with the result of the execution, so the opcode
+// which failed and the stacks at that point can be inspected when the input
+// doesn't validate.  The error may also come from the final check of the stack
+// once all the scripts executed successfully.  The input amount is only used by
+// witness inputs.
+func TraceScript(scriptPubKey []byte, tx *wire.MsgTx, txIdx int,
+	flags ScriptFlags, inputAmount int64) ([]TraceStep, error) {
+
+	var hashCache *TxSigHashes
+	if flags&ScriptVerifyWitness == ScriptVerifyWitness {
+		hashCache = NewTxSigHashes(tx)
+	}
+	vm, err := NewEngine(scriptPubKey, tx, txIdx, flags, nil, hashCache,
+		inputAmount)
+	if err != nil {
+		return nil, err
+	}
+	vm.EnableTrace()
+	err = vm.Execute()
+	return vm.Trace(), err
+}
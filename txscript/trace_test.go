@@ -0,0 +1,56 @@
+package txscript
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lbryio/lbcd/wire"
+)
+
+// TestTraceScript ensures the steps recorded while tracing follow the opcodes
+// executed along with the stacks they leave, including the opcode which fails.
+func TestTraceScript(t *testing.T) {
+	t.Parallel()
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, mustParseShortForm("1 2"), nil))
+	tx.AddTxOut(wire.NewTxOut(0, nil))
+
+	steps, err := TraceScript(mustParseShortForm("ADD 3 EQUAL"), tx, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("TraceScript: unexpected error: %v", err)
+	}
+	wantOpcodes := []string{"OP_1", "OP_2", "OP_ADD", "OP_3", "OP_EQUAL"}
+	if len(steps) != len(wantOpcodes) {
+		t.Fatalf("TraceScript: got %d steps, want %d", len(steps),
+			len(wantOpcodes))
+	}
+	for i, step := range steps {
+		if step.Opcode != wantOpcodes[i] || step.Err != nil {
+			t.Fatalf("step %d: got opcode %q with error %v, want %q",
+				i, step.Opcode, step.Err, wantOpcodes[i])
+		}
+	}
+	if steps[2].ScriptIndex != 1 || steps[2].OpcodeIndex != 0 {
+		t.Fatalf("step 2: got script %d opcode %d, want script 1 "+
+			"opcode 0", steps[2].ScriptIndex, steps[2].OpcodeIndex)
+	}
+	if want := [][]byte{{3}}; !reflect.DeepEqual(steps[2].Stack, want) {
+		t.Fatalf("step 2: got stack %x, want %x", steps[2].Stack, want)
+	}
+
+	// The trace ends with the opcode which failed.
+	steps, err = TraceScript(mustParseShortForm("ADD 4 EQUALVERIFY 1"), tx,
+		0, 0, 0)
+	if !IsErrorCode(err, ErrEqualVerify) {
+		t.Fatalf("TraceScript: got error %v, want %v", err,
+			ErrEqualVerify)
+	}
+	last := steps[len(steps)-1]
+	if last.Opcode != "OP_EQUALVERIFY" || !IsErrorCode(last.Err,
+		ErrEqualVerify) {
+
+		t.Fatalf("last step: got opcode %q with error %v", last.Opcode,
+			last.Err)
+	}
+}
@@ -139,6 +139,27 @@ from a remote peer is:
 		// Log and handle the error
 	}
 
+# Registering Messages
+
+The messages which can be read are looked up by command in a registry, along
+with the minimum protocol version and the services a peer must have negotiated
+for them to be sent to it.  New message types are added with RegisterMessage,
+and MessageSupported reports whether a message can be sent to a peer:
+
+	err := wire.RegisterMessage(wire.MessageSpec{
+		Command:            "mymsg",
+		MinProtocolVersion: wire.ProtocolVersion,
+		RequiredServices:   wire.SFNodeNetwork,
+		New:                func() wire.Message { return &MyMsg{} },
+	})
+	if err != nil {
+		// Log and handle the error
+	}
+
+	if wire.MessageSupported("mymsg", pver, services) {
+		// Send the message
+	}
+
 # Errors
 
 Errors returned by this package are either the raw errors provided by underlying
@@ -6,6 +6,7 @@ package wire
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"unicode/utf8"
@@ -58,6 +59,11 @@ const (
 	CmdCFHeaders    = "cfheaders"
 	CmdCFCheckpt    = "cfcheckpt"
 	CmdSendAddrV2   = "sendaddrv2"
+	CmdAddrV2       = "addrv2"
+	CmdSendCmpct    = "sendcmpct"
+	CmdCmpctBlock   = "cmpctblock"
+	CmdGetBlockTxn  = "getblocktxn"
+	CmdBlockTxn     = "blocktxn"
 )
 
 // MessageEncoding represents the wire message encoding format to be used.
@@ -92,102 +98,11 @@ type Message interface {
 // makeEmptyMessage creates a message of the appropriate concrete type based
 // on the command.
 func makeEmptyMessage(command string) (Message, error) {
-	var msg Message
-	switch command {
-	case CmdVersion:
-		msg = &MsgVersion{}
-
-	case CmdVerAck:
-		msg = &MsgVerAck{}
-
-	case CmdSendAddrV2:
-		msg = &MsgSendAddrV2{}
-
-	case CmdGetAddr:
-		msg = &MsgGetAddr{}
-
-	case CmdAddr:
-		msg = &MsgAddr{}
-
-	case CmdGetBlocks:
-		msg = &MsgGetBlocks{}
-
-	case CmdBlock:
-		msg = &MsgBlock{}
-
-	case CmdInv:
-		msg = &MsgInv{}
-
-	case CmdGetData:
-		msg = &MsgGetData{}
-
-	case CmdNotFound:
-		msg = &MsgNotFound{}
-
-	case CmdTx:
-		msg = &MsgTx{}
-
-	case CmdPing:
-		msg = &MsgPing{}
-
-	case CmdPong:
-		msg = &MsgPong{}
-
-	case CmdGetHeaders:
-		msg = &MsgGetHeaders{}
-
-	case CmdHeaders:
-		msg = &MsgHeaders{}
-
-	case CmdAlert:
-		msg = &MsgAlert{}
-
-	case CmdMemPool:
-		msg = &MsgMemPool{}
-
-	case CmdFilterAdd:
-		msg = &MsgFilterAdd{}
-
-	case CmdFilterClear:
-		msg = &MsgFilterClear{}
-
-	case CmdFilterLoad:
-		msg = &MsgFilterLoad{}
-
-	case CmdMerkleBlock:
-		msg = &MsgMerkleBlock{}
-
-	case CmdReject:
-		msg = &MsgReject{}
-
-	case CmdSendHeaders:
-		msg = &MsgSendHeaders{}
-
-	case CmdFeeFilter:
-		msg = &MsgFeeFilter{}
-
-	case CmdGetCFilters:
-		msg = &MsgGetCFilters{}
-
-	case CmdGetCFHeaders:
-		msg = &MsgGetCFHeaders{}
-
-	case CmdGetCFCheckpt:
-		msg = &MsgGetCFCheckpt{}
-
-	case CmdCFilter:
-		msg = &MsgCFilter{}
-
-	case CmdCFHeaders:
-		msg = &MsgCFHeaders{}
-
-	case CmdCFCheckpt:
-		msg = &MsgCFCheckpt{}
-
-	default:
+	spec, ok := LookupMessage(command)
+	if !ok {
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
-	return msg, nil
+	return spec.New(), nil
 }
 
 // messageHeader defines the header structure for all bitcoin protocol messages.
@@ -344,6 +259,27 @@ func WriteMessageWithEncodingN(w io.Writer, msg Message, pver uint32,
 func ReadMessageWithEncodingN(r io.Reader, pver uint32, btcnet BitcoinNet,
 	enc MessageEncoding) (int, Message, []byte, error) {
 
+	return readMessage(r, pver, btcnet, enc, false)
+}
+
+// ReadMessageStreamN reads, validates, and parses the next bitcoin Message from
+// r like ReadMessageWithEncodingN, except the messages implementing the
+// StreamDecoder interface, such as blocks and transactions, are decoded
+// directly from r as their payload is read instead of after buffering the
+// whole payload.  This reduces the peak memory used when receiving large
+// blocks.  No raw bytes are returned for those messages.
+func ReadMessageStreamN(r io.Reader, pver uint32, btcnet BitcoinNet,
+	enc MessageEncoding) (int, Message, []byte, error) {
+
+	return readMessage(r, pver, btcnet, enc, true)
+}
+
+// readMessage reads, validates, and parses the next bitcoin Message from r,
+// decoding the messages implementing the StreamDecoder interface directly from
+// r when stream is set.
+func readMessage(r io.Reader, pver uint32, btcnet BitcoinNet,
+	enc MessageEncoding, stream bool) (int, Message, []byte, error) {
+
 	totalBytes := 0
 	n, hdr, err := readMessageHeader(r)
 	totalBytes += n
@@ -395,6 +331,31 @@ func ReadMessageWithEncodingN(r io.Reader, pver uint32, btcnet BitcoinNet,
 		return totalBytes, nil, nil, messageError("ReadMessage", str)
 	}
 
+	// Decode the payload while reading it when streaming.  The rest of the
+	// payload is always consumed to keep the stream in sync, and the
+	// checksum is verified once it has been fully read.
+	if decoder, ok := msg.(StreamDecoder); stream && ok {
+		sr := newStreamReader(r, hdr.length, sha256.New())
+		err := decoder.BtcDecodeStream(sr, hdr.length, pver, enc)
+		if discardErr := sr.discard(); err == nil {
+			err = discardErr
+		}
+		totalBytes += sr.n
+		if err != nil {
+			return totalBytes, nil, nil, err
+		}
+
+		checksum := sr.checksum()
+		if !bytes.Equal(checksum, hdr.checksum[:]) {
+			str := fmt.Sprintf("payload checksum failed - header "+
+				"indicates %v, but actual checksum is %v.",
+				hdr.checksum, checksum)
+			return totalBytes, nil, nil, messageError("ReadMessage", str)
+		}
+
+		return totalBytes, msg, nil, nil
+	}
+
 	// Read payload.
 	payload := make([]byte, hdr.length)
 	n, err = io.ReadFull(r, payload)
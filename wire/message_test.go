@@ -51,6 +51,7 @@ func TestMessage(t *testing.T) {
 	msgVerack := NewMsgVerAck()
 	msgGetAddr := NewMsgGetAddr()
 	msgAddr := NewMsgAddr()
+	msgAddrV2 := NewMsgAddrV2()
 	msgGetBlocks := NewMsgGetBlocks(&chainhash.Hash{})
 	msgBlock := &blockOne
 	msgInv := NewMsgInv()
@@ -76,6 +77,11 @@ func TestMessage(t *testing.T) {
 		[]byte("payload"))
 	msgCFHeaders := NewMsgCFHeaders()
 	msgCFCheckpt := NewMsgCFCheckpt(GCSFilterRegular, &chainhash.Hash{}, 0)
+	msgSendCmpct := NewMsgSendCmpct(true, CmpctBlockVersion2)
+	msgCmpctBlock := NewMsgCmpctBlock(&blockOne, 123123, CmpctBlockVersion2)
+	msgGetBlockTxn := NewMsgGetBlockTxn(&chainhash.Hash{}, []uint32{0, 2})
+	msgBlockTxn := NewMsgBlockTxn(&chainhash.Hash{})
+	msgBlockTxn.AddTransaction(msgTx)
 
 	tests := []struct {
 		in     Message    // Value to encode
@@ -88,6 +94,7 @@ func TestMessage(t *testing.T) {
 		{msgVerack, msgVerack, pver, MainNet, 24},
 		{msgGetAddr, msgGetAddr, pver, MainNet, 24},
 		{msgAddr, msgAddr, pver, MainNet, 25},
+		{msgAddrV2, msgAddrV2, pver, MainNet, 25},
 		{msgGetBlocks, msgGetBlocks, pver, MainNet, 61},
 		{msgBlock, msgBlock, pver, MainNet, 271},
 		{msgInv, msgInv, pver, MainNet, 25},
@@ -111,6 +118,10 @@ func TestMessage(t *testing.T) {
 		{msgCFilter, msgCFilter, pver, MainNet, 65},
 		{msgCFHeaders, msgCFHeaders, pver, MainNet, 90},
 		{msgCFCheckpt, msgCFCheckpt, pver, MainNet, 58},
+		{msgSendCmpct, msgSendCmpct, pver, MainNet, 33},
+		{msgCmpctBlock, msgCmpctBlock, pver, MainNet, 281},
+		{msgGetBlockTxn, msgGetBlockTxn, pver, MainNet, 59},
+		{msgBlockTxn, msgBlockTxn, pver, MainNet, 67},
 	}
 
 	t.Logf("Running %d tests", len(tests))
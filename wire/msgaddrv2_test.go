@@ -0,0 +1,156 @@
+package wire
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestAddrV2Wire tests the MsgAddrV2 wire encode and decode for addresses of
+// the various networks.
+func TestAddrV2Wire(t *testing.T) {
+	pver := ProtocolVersion
+
+	torV3, err := NewNetAddressTorV3(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion",
+		9246, SFNodeNetwork)
+	if err != nil {
+		t.Fatalf("NewNetAddressTorV3: unexpected error: %v", err)
+	}
+
+	msg := NewMsgAddrV2()
+	msg.AddAddress(NetAddressV2FromLegacy(NewNetAddressIPPort(
+		net.ParseIP("127.0.0.1"), 9246, SFNodeNetwork)))
+	msg.AddAddress(NetAddressV2FromLegacy(NewNetAddressIPPort(
+		net.ParseIP("2001:db8::1"), 9246, SFNodeNetwork|SFNodeWitness)))
+	msg.AddAddress(torV3)
+	msg.AddAddress(&NetAddressV2{
+		Timestamp: time.Unix(0x495fab29, 0),
+		Network:   NetworkID(42),
+		Addr:      []byte{0x01, 0x02, 0x03},
+		Port:      1,
+	})
+
+	var buf bytes.Buffer
+	err = msg.BtcEncode(&buf, pver, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcEncode: unexpected error: %v", err)
+	}
+	var decoded MsgAddrV2
+	err = decoded.BtcDecode(bytes.NewReader(buf.Bytes()), pver, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcDecode: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, msg) {
+		t.Fatalf("BtcDecode: mismatched message - got %v, want %v",
+			spew.Sdump(&decoded), spew.Sdump(msg))
+	}
+
+	// Only the IP addresses have a legacy representation.
+	if na := decoded.AddrList[0].ToLegacy(); na == nil ||
+		!na.IP.Equal(net.ParseIP("127.0.0.1")) {
+
+		t.Fatalf("ToLegacy: wrong IPv4 address %v", spew.Sdump(na))
+	}
+	if decoded.AddrList[2].ToLegacy() != nil {
+		t.Fatal("ToLegacy: unexpected legacy Tor v3 address")
+	}
+	if decoded.AddrList[1].Network != NetworkIPv6 {
+		t.Fatalf("unexpected network %v for IPv6 address",
+			decoded.AddrList[1].Network)
+	}
+}
+
+// TestAddrV2WireErrors ensures the addresses of known networks with an invalid
+// size are rejected.
+func TestAddrV2WireErrors(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgAddrV2()
+	msg.AddAddress(NewNetAddressV2(NetworkIPv4, []byte{1, 2, 3}, 9246,
+		SFNodeNetwork))
+
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcEncode: unexpected error: %v", err)
+	}
+	var decoded MsgAddrV2
+	err = decoded.BtcDecode(bytes.NewReader(buf.Bytes()), pver, BaseEncoding)
+	if _, ok := err.(*MessageError); !ok {
+		t.Fatalf("BtcDecode: wrong error - got %v, want MessageError",
+			err)
+	}
+}
+
+// TestNetAddressTorV3 ensures Tor v3 onion service addresses round trip and
+// invalid ones are rejected.
+func TestNetAddressTorV3(t *testing.T) {
+	host := "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion"
+	na, err := NewNetAddressTorV3(host, 9246, SFNodeNetwork)
+	if err != nil {
+		t.Fatalf("NewNetAddressTorV3: unexpected error: %v", err)
+	}
+	if na.Host() != host {
+		t.Fatalf("Host: wrong host - got %v, want %v", na.Host(), host)
+	}
+	if na.String() != host+":9246" {
+		t.Fatalf("String: wrong address - got %v", na.String())
+	}
+
+	tests := []string{
+		// Bad checksum.
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczae.onion",
+		// Tor v2 address.
+		"expyuzz4wqqyqhjn.onion",
+		// Not base32.
+		"not-an-onion-address.onion",
+	}
+	for _, test := range tests {
+		if _, err := NewNetAddressTorV3(test, 9246, 0); err == nil {
+			t.Errorf("NewNetAddressTorV3(%q): unexpected success", test)
+		}
+	}
+}
+
+// TestNetAddressV2FromHost ensures the hosts returned by Host are parsed back
+// to the same addresses for all the known networks.
+func TestNetAddressV2FromHost(t *testing.T) {
+	torV3, err := NewNetAddressTorV3(
+		"duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion",
+		9246, SFNodeNetwork)
+	if err != nil {
+		t.Fatalf("NewNetAddressTorV3: unexpected error: %v", err)
+	}
+	tests := []*NetAddressV2{
+		NewNetAddressV2(NetworkIPv4, []byte{127, 0, 0, 1}, 9246, 0),
+		NewNetAddressV2(NetworkIPv6, net.ParseIP("2001:db8::1"), 9246, 0),
+		NewNetAddressV2(NetworkTorV2, bytes.Repeat([]byte{0x42}, 10), 9246,
+			0),
+		torV3,
+		NewNetAddressV2(NetworkI2P, bytes.Repeat([]byte{0xab}, 32), 0, 0),
+		NewNetAddressV2(NetworkCJDNS, net.ParseIP("fc00::1"), 9246, 0),
+	}
+	for _, test := range tests {
+		na, err := NetAddressV2FromHost(test.Network, test.Host(),
+			test.Port, test.Services)
+		if err != nil {
+			t.Errorf("NetAddressV2FromHost(%v): unexpected error: %v",
+				test, err)
+			continue
+		}
+		if na.Network != test.Network || !bytes.Equal(na.Addr, test.Addr) {
+			t.Errorf("NetAddressV2FromHost: mismatched address - "+
+				"got %v, want %v", na, test)
+		}
+	}
+
+	if _, err := NetAddressV2FromHost(NetworkIPv4, "2001:db8::1", 0, 0); err == nil {
+		t.Error("NetAddressV2FromHost: unexpected success for an IPv6 " +
+			"host of the IPv4 network")
+	}
+}
@@ -80,6 +80,11 @@ func (msg *MsgBlock) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) er
 			"[count %d, max %d]", txCount, maxTxPerBlock)
 		return messageError("MsgBlock.BtcDecode", str)
 	}
+	err = checkRemaining(r, txCount, minTxPayload, "MsgBlock.BtcDecode",
+		"transactions")
+	if err != nil {
+		return err
+	}
 
 	msg.Transactions = make([]*MsgTx, 0, txCount)
 	for i := uint64(0); i < txCount; i++ {
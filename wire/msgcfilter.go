@@ -17,6 +17,10 @@ type FilterType uint8
 const (
 	// GCSFilterRegular is the regular filter type.
 	GCSFilterRegular FilterType = iota
+
+	// GCSFilterClaimName is the filter type of the filters matching the
+	// normalized names of the claims touched by a block.
+	GCSFilterClaimName
 )
 
 const (
@@ -0,0 +1,253 @@
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/aead/siphash"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+const (
+	// ShortTxIDSize is the number of bytes used to encode a short
+	// transaction ID in a cmpctblock message.
+	ShortTxIDSize = 6
+
+	// shortTxIDMask masks a siphash digest down to a short transaction ID.
+	shortTxIDMask = 1<<(ShortTxIDSize*8) - 1
+
+	// maxCmpctBlockIndex is the maximum transaction index which may be
+	// referenced by the cmpctblock and getblocktxn messages.
+	maxCmpctBlockIndex = 0xffff
+)
+
+// PrefilledTx is a transaction sent in full within a cmpctblock message along
+// with its index in the block.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCmpctBlock implements the Message interface and represents a bitcoin
+// cmpctblock message.  It is used to relay a block as its header along with
+// short IDs of its transactions, so a peer can reconstruct the block from the
+// transactions in its memory pool (BIP0152).  The transactions the receiver is
+// unlikely to have, such as the coinbase, are prefilled.
+//
+// The prefilled transactions must be sorted by their index in the block.
+//
+// This message was not added until protocol versions starting with
+// ShortIDsBlocksVersion.
+type MsgCmpctBlock struct {
+	Header       BlockHeader
+	Nonce        uint64
+	ShortIDs     []uint64
+	PrefilledTxs []PrefilledTx
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	err := readBlockHeader(r, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+
+	err = readElement(r, &msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	// Prevent more short IDs than could possibly fit into a block.
+	if count > maxCmpctBlockIndex+1 {
+		str := fmt.Sprintf("too many short ids for message "+
+			"[count %v, max %v]", count, maxCmpctBlockIndex+1)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	var buf [8]byte
+	msg.ShortIDs = make([]uint64, count)
+	for i := range msg.ShortIDs {
+		_, err := io.ReadFull(r, buf[:ShortTxIDSize])
+		if err != nil {
+			return err
+		}
+		msg.ShortIDs[i] = littleEndian.Uint64(buf[:])
+	}
+
+	count, err = ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count+uint64(len(msg.ShortIDs)) > maxCmpctBlockIndex+1 {
+		str := fmt.Sprintf("too many prefilled transactions for "+
+			"message [count %v, max %v]", count,
+			maxCmpctBlockIndex+1-len(msg.ShortIDs))
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	// The indexes are differentially encoded, so each one is relative to
+	// the index following the previous transaction.
+	msg.PrefilledTxs = make([]PrefilledTx, count)
+	next := uint64(0)
+	for i := range msg.PrefilledTxs {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		index := next + diff
+		if diff > maxCmpctBlockIndex || index > maxCmpctBlockIndex {
+			str := fmt.Sprintf("prefilled transaction index %v "+
+				"out of range", index)
+			return messageError("MsgCmpctBlock.BtcDecode", str)
+		}
+
+		tx := MsgTx{}
+		err = tx.BtcDecode(r, pver, enc)
+		if err != nil {
+			return err
+		}
+		msg.PrefilledTxs[i] = PrefilledTx{Index: uint32(index), Tx: &tx}
+		next = index + 1
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcEncode", str)
+	}
+
+	err := writeBlockHeader(w, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+
+	err = writeElement(w, msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.ShortIDs)))
+	if err != nil {
+		return err
+	}
+
+	var buf [8]byte
+	for _, id := range msg.ShortIDs {
+		littleEndian.PutUint64(buf[:], id)
+		_, err := w.Write(buf[:ShortTxIDSize])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.PrefilledTxs)))
+	if err != nil {
+		return err
+	}
+
+	next := uint32(0)
+	for _, ptx := range msg.PrefilledTxs {
+		if ptx.Index < next {
+			str := fmt.Sprintf("prefilled transaction index %v is "+
+				"not sorted", ptx.Index)
+			return messageError("MsgCmpctBlock.BtcEncode", str)
+		}
+		err = WriteVarInt(w, pver, uint64(ptx.Index-next))
+		if err != nil {
+			return err
+		}
+		err = ptx.Tx.BtcEncode(w, pver, enc)
+		if err != nil {
+			return err
+		}
+		next = ptx.Index + 1
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCmpctBlock) Command() string {
+	return CmdCmpctBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint32 {
+	// The prefilled transactions can make the message as large as a
+	// block.
+	return MaxBlockPayload
+}
+
+// BlockHash computes the block identifier hash for the block of the compact
+// block.
+func (msg *MsgCmpctBlock) BlockHash() chainhash.Hash {
+	return msg.Header.BlockHash()
+}
+
+// ShortIDKey returns the siphash key used to compute the short transaction IDs
+// of the compact block.  It is the first 16 bytes of the single SHA256 of the
+// block header followed by the nonce.
+func (msg *MsgCmpctBlock) ShortIDKey() [siphash.KeySize]byte {
+	buf := bytes.NewBuffer(make([]byte, 0, MaxBlockHeaderPayload+8))
+	_ = writeBlockHeader(buf, 0, &msg.Header)
+	_ = writeElement(buf, msg.Nonce)
+
+	var key [siphash.KeySize]byte
+	copy(key[:], chainhash.HashB(buf.Bytes()))
+	return key
+}
+
+// ShortTxID returns the short transaction ID of the passed transaction hash
+// for the given siphash key.  See MsgCmpctBlock.ShortIDKey.
+func ShortTxID(key *[siphash.KeySize]byte, hash *chainhash.Hash) uint64 {
+	return siphash.Sum64(hash[:], key) & shortTxIDMask
+}
+
+// NewMsgCmpctBlock returns a new bitcoin cmpctblock message for the passed
+// block that conforms to the Message interface.  The coinbase transaction is
+// prefilled and the remaining transactions are sent as short IDs derived with
+// the passed nonce according to the compact block version.
+func NewMsgCmpctBlock(block *MsgBlock, nonce uint64, version uint64) *MsgCmpctBlock {
+	msg := &MsgCmpctBlock{
+		Header: block.Header,
+		Nonce:  nonce,
+	}
+	if len(block.Transactions) == 0 {
+		return msg
+	}
+
+	msg.PrefilledTxs = []PrefilledTx{{Index: 0, Tx: block.Transactions[0]}}
+	msg.ShortIDs = make([]uint64, 0, len(block.Transactions)-1)
+	key := msg.ShortIDKey()
+	for _, tx := range block.Transactions[1:] {
+		var hash chainhash.Hash
+		if version == CmpctBlockVersion2 {
+			hash = tx.WitnessHash()
+		} else {
+			hash = tx.TxHash()
+		}
+		msg.ShortIDs = append(msg.ShortIDs, ShortTxID(&key, &hash))
+	}
+	return msg
+}
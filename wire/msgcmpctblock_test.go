@@ -0,0 +1,168 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/aead/siphash"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+// TestCmpctBlockShortIDs tests the short transaction IDs of the MsgCmpctBlock
+// created from a block.
+func TestCmpctBlockShortIDs(t *testing.T) {
+	block := NewMsgBlock(&blockOne.Header)
+	_ = block.AddTransaction(blockOne.Transactions[0])
+	tx := NewMsgTx(1)
+	tx.AddTxIn(NewTxIn(&OutPoint{Index: 1}, []byte{0x51}, [][]byte{{0x01}}))
+	tx.AddTxOut(NewTxOut(1, []byte{0x51}))
+	_ = block.AddTransaction(tx)
+
+	for _, version := range []uint64{CmpctBlockVersion1, CmpctBlockVersion2} {
+		msg := NewMsgCmpctBlock(block, 0x0102030405060708, version)
+
+		// The coinbase must be prefilled.
+		if len(msg.PrefilledTxs) != 1 || msg.PrefilledTxs[0].Index != 0 ||
+			msg.PrefilledTxs[0].Tx != block.Transactions[0] {
+			t.Fatalf("version %d: unexpected prefilled txs %v",
+				version, spew.Sdump(msg.PrefilledTxs))
+		}
+
+		// The short ID key is the first 16 bytes of the SHA256 of the
+		// header followed by the nonce.
+		var buf bytes.Buffer
+		_ = block.Header.Serialize(&buf)
+		buf.Write([]byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01})
+		var wantKey [siphash.KeySize]byte
+		copy(wantKey[:], chainhash.HashB(buf.Bytes()))
+		key := msg.ShortIDKey()
+		if key != wantKey {
+			t.Fatalf("version %d: wrong key - got %x, want %x",
+				version, key, wantKey)
+		}
+
+		hash := tx.TxHash()
+		if version == CmpctBlockVersion2 {
+			hash = tx.WitnessHash()
+		}
+		wantID := siphash.Sum64(hash[:], &wantKey) & 0xffffffffffff
+		if len(msg.ShortIDs) != 1 || msg.ShortIDs[0] != wantID {
+			t.Fatalf("version %d: wrong short ids - got %x, want %x",
+				version, msg.ShortIDs, wantID)
+		}
+	}
+}
+
+// TestCmpctBlockWire tests the MsgCmpctBlock wire encode and decode.
+func TestCmpctBlockWire(t *testing.T) {
+	tx := NewMsgTx(1)
+	msg := &MsgCmpctBlock{
+		Header:   blockOne.Header,
+		Nonce:    1,
+		ShortIDs: []uint64{0x060504030201},
+		PrefilledTxs: []PrefilledTx{
+			{Index: 0, Tx: tx},
+			{Index: 3, Tx: tx},
+		},
+	}
+
+	var hdr bytes.Buffer
+	_ = blockOne.Header.Serialize(&hdr)
+	txBytes := []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	want := append(hdr.Bytes(),
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Nonce
+		0x01,                               // Varint for number of short IDs
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, // Short ID
+		0x02, // Varint for number of prefilled txs
+		0x00, // Differential index 0
+	)
+	want = append(want, txBytes...)
+	want = append(want, 0x02) // Differential index 3
+	want = append(want, txBytes...)
+
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcEncode: unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("BtcEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(want))
+	}
+
+	var readmsg MsgCmpctBlock
+	err = readmsg.BtcDecode(bytes.NewReader(want), ProtocolVersion,
+		BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcDecode: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Fatalf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Unsorted prefilled transactions can't be encoded.
+	msg.PrefilledTxs[0], msg.PrefilledTxs[1] = msg.PrefilledTxs[1],
+		msg.PrefilledTxs[0]
+	err = msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding)
+	if _, ok := err.(*MessageError); !ok {
+		t.Fatalf("BtcEncode: expected MessageError for unsorted "+
+			"prefilled txs, got %v", err)
+	}
+
+	// The message is invalid before ShortIDsBlocksVersion.
+	err = msg.BtcEncode(&buf, FeeFilterVersion, BaseEncoding)
+	if _, ok := err.(*MessageError); !ok {
+		t.Fatalf("BtcEncode: expected MessageError for old protocol "+
+			"version, got %v", err)
+	}
+}
+
+// TestGetBlockTxnWire tests the MsgGetBlockTxn wire encode and decode.
+func TestGetBlockTxnWire(t *testing.T) {
+	hash := chainhash.Hash{0x01}
+	msg := NewMsgGetBlockTxn(&hash, []uint32{1, 2, 300})
+
+	want := append(hash[:],
+		0x03,             // Varint for number of indexes
+		0x01,             // Differential index 1
+		0x00,             // Differential index 2
+		0xfd, 0x29, 0x01, // Differential index 300
+	)
+
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcEncode: unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("BtcEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(want))
+	}
+
+	var readmsg MsgGetBlockTxn
+	err = readmsg.BtcDecode(bytes.NewReader(want), ProtocolVersion,
+		BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcDecode: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Fatalf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Indexes which overflow the maximum allowed index are rejected.
+	overflow := append(hash[:],
+		0x02,             // Varint for number of indexes
+		0xfd, 0xff, 0xff, // Differential index 0xffff
+		0x00, // Differential index 0x10000
+	)
+	err = readmsg.BtcDecode(bytes.NewReader(overflow), ProtocolVersion,
+		BaseEncoding)
+	if _, ok := err.(*MessageError); !ok {
+		t.Fatalf("BtcDecode: expected MessageError for index "+
+			"overflow, got %v", err)
+	}
+}
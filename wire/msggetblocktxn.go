@@ -0,0 +1,129 @@
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+// MsgGetBlockTxn implements the Message interface and represents a bitcoin
+// getblocktxn message.  It is used to request the transactions of a compact
+// block which could not be found in the memory pool by their index in the
+// block (BIP0152).  The peer responds with a blocktxn message.
+//
+// The indexes must be sorted in ascending order.
+//
+// This message was not added until protocol versions starting with
+// ShortIDsBlocksVersion.
+type MsgGetBlockTxn struct {
+	BlockHash chainhash.Hash
+	Indexes   []uint32
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+
+	err := readElement(r, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxCmpctBlockIndex+1 {
+		str := fmt.Sprintf("too many indexes for message "+
+			"[count %v, max %v]", count, maxCmpctBlockIndex+1)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+
+	// The indexes are differentially encoded, so each one is relative to
+	// the index following the previous one.
+	msg.Indexes = make([]uint32, count)
+	next := uint64(0)
+	for i := range msg.Indexes {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		index := next + diff
+		if diff > maxCmpctBlockIndex || index > maxCmpctBlockIndex {
+			str := fmt.Sprintf("transaction index %v out of range",
+				index)
+			return messageError("MsgGetBlockTxn.BtcDecode", str)
+		}
+		msg.Indexes[i] = uint32(index)
+		next = index + 1
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcEncode", str)
+	}
+
+	err := writeElement(w, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.Indexes)))
+	if err != nil {
+		return err
+	}
+
+	next := uint32(0)
+	for _, index := range msg.Indexes {
+		if index < next {
+			str := fmt.Sprintf("transaction index %v is not sorted",
+				index)
+			return messageError("MsgGetBlockTxn.BtcEncode", str)
+		}
+		err = WriteVarInt(w, pver, uint64(index-next))
+		if err != nil {
+			return err
+		}
+		next = index + 1
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetBlockTxn) Command() string {
+	return CmdGetBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	// Block hash + num indexes (varInt) + max allowed indexes, each of
+	// which fits in a 3 byte varint.
+	return chainhash.HashSize + MaxVarIntPayload +
+		(maxCmpctBlockIndex+1)*3
+}
+
+// NewMsgGetBlockTxn returns a new bitcoin getblocktxn message that conforms to
+// the Message interface using the passed parameters.  See MsgGetBlockTxn for
+// details.
+func NewMsgGetBlockTxn(blockHash *chainhash.Hash, indexes []uint32) *MsgGetBlockTxn {
+	return &MsgGetBlockTxn{
+		BlockHash: *blockHash,
+		Indexes:   indexes,
+	}
+}
@@ -480,6 +480,11 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error
 			maxTxInPerMessage)
 		return messageError("MsgTx.BtcDecode", str)
 	}
+	err = checkRemaining(r, count, minTxInPayload, "MsgTx.BtcDecode",
+		"input transactions")
+	if err != nil {
+		return err
+	}
 
 	// returnScriptBuffers is a closure that returns any script buffers that
 	// were borrowed from the pool when there are any deserialization
@@ -543,6 +548,12 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error
 			maxTxOutPerMessage)
 		return messageError("MsgTx.BtcDecode", str)
 	}
+	err = checkRemaining(r, count, MinTxOutPayload, "MsgTx.BtcDecode",
+		"output transactions")
+	if err != nil {
+		returnScriptBuffers()
+		return err
+	}
 
 	// Deserialize the outputs.
 	txOuts := make([]TxOut, count)
@@ -582,6 +593,12 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error
 					witCount, maxWitnessItemsPerInput)
 				return messageError("MsgTx.BtcDecode", str)
 			}
+			err = checkRemaining(r, witCount, 1, "MsgTx.BtcDecode",
+				"witness items")
+			if err != nil {
+				returnScriptBuffers()
+				return err
+			}
 
 			// Then for witCount number of stack items, each item
 			// has a varint length prefix, followed by the witness
@@ -964,6 +981,10 @@ func readScript(r io.Reader, pver uint32, maxAllowed uint32, fieldName string) (
 			"[count %d, max %d]", fieldName, count, maxAllowed)
 		return nil, messageError("readScript", str)
 	}
+	err = checkRemaining(r, count, 1, "readScript", fieldName+" bytes")
+	if err != nil {
+		return nil, err
+	}
 
 	b := scriptPool.Borrow(count)
 	_, err = io.ReadFull(r, b)
@@ -0,0 +1,368 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// NetworkID identifies the network of an address in an addrv2 message as
+// defined by BIP0155.
+type NetworkID uint8
+
+const (
+	// NetworkIPv4 identifies an IPv4 address.
+	NetworkIPv4 NetworkID = 1
+
+	// NetworkIPv6 identifies an IPv6 address.
+	NetworkIPv6 NetworkID = 2
+
+	// NetworkTorV2 identifies a Tor v2 onion service address.
+	NetworkTorV2 NetworkID = 3
+
+	// NetworkTorV3 identifies a Tor v3 onion service address.
+	NetworkTorV3 NetworkID = 4
+
+	// NetworkI2P identifies an I2P address.
+	NetworkI2P NetworkID = 5
+
+	// NetworkCJDNS identifies a CJDNS address.
+	NetworkCJDNS NetworkID = 6
+)
+
+// networkAddrSizes maps the known networks to the size of their addresses.
+var networkAddrSizes = map[NetworkID]int{
+	NetworkIPv4:  net.IPv4len,
+	NetworkIPv6:  net.IPv6len,
+	NetworkTorV2: 10,
+	NetworkTorV3: 32,
+	NetworkI2P:   32,
+	NetworkCJDNS: net.IPv6len,
+}
+
+// String returns the NetworkID in human-readable form.
+func (n NetworkID) String() string {
+	switch n {
+	case NetworkIPv4:
+		return "ipv4"
+	case NetworkIPv6:
+		return "ipv6"
+	case NetworkTorV2:
+		return "torv2"
+	case NetworkTorV3:
+		return "torv3"
+	case NetworkI2P:
+		return "i2p"
+	case NetworkCJDNS:
+		return "cjdns"
+	}
+	return fmt.Sprintf("Unknown NetworkID (%d)", uint8(n))
+}
+
+// MaxNetAddressV2AddrSize is the maximum size of the address of a NetAddressV2.
+const MaxNetAddressV2AddrSize = 512
+
+// maxNetAddressV2Payload is the max payload size for a bitcoin NetAddressV2.
+// Timestamp 4 bytes + services 9 bytes + network 1 byte + address length 3
+// bytes + address + port 2 bytes.
+const maxNetAddressV2Payload = 4 + MaxVarIntPayload + 1 + 3 +
+	MaxNetAddressV2AddrSize + 2
+
+// onionCatPrefix is the IPv6 prefix used to encode Tor v2 addresses in legacy
+// addresses.
+var onionCatPrefix = []byte{0xfd, 0x87, 0xd8, 0x7e, 0xeb, 0x43}
+
+// torV3Version is the version byte of Tor v3 onion service addresses.
+const torV3Version = 0x03
+
+// onionEncoding is the base32 encoding of onion service addresses.
+var onionEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NetAddressV2 defines information about a peer on the network as relayed by
+// the addrv2 message (BIP0155).  Unlike NetAddress, it is able to describe
+// peers of networks whose addresses don't fit in an IPv6 address such as Tor
+// v3 onion services.
+type NetAddressV2 struct {
+	// Last time the address was seen.
+	Timestamp time.Time
+
+	// Bitfield which identifies the services supported by the address.
+	Services ServiceFlag
+
+	// Network identifies the network of the address.
+	Network NetworkID
+
+	// Addr is the address in the encoding of its network, such as the
+	// public key of a Tor v3 onion service.
+	Addr []byte
+
+	// Port the peer is using.  This is encoded in big endian on the wire
+	// which differs from most everything else.
+	Port uint16
+}
+
+// HasService returns whether the specified service is supported by the address.
+func (na *NetAddressV2) HasService(service ServiceFlag) bool {
+	return na.Services&service == service
+}
+
+// Host returns the host of the address, which is the onion domain for Tor
+// onion services.
+func (na *NetAddressV2) Host() string {
+	switch na.Network {
+	case NetworkIPv4, NetworkIPv6, NetworkCJDNS:
+		return net.IP(na.Addr).String()
+
+	case NetworkTorV2:
+		return strings.ToLower(onionEncoding.EncodeToString(na.Addr)) +
+			".onion"
+
+	case NetworkTorV3:
+		var b bytes.Buffer
+		b.Write(na.Addr)
+		b.Write(torV3Checksum(na.Addr))
+		b.WriteByte(torV3Version)
+		return strings.ToLower(onionEncoding.EncodeToString(b.Bytes())) +
+			".onion"
+
+	case NetworkI2P:
+		return strings.ToLower(onionEncoding.EncodeToString(na.Addr)) +
+			".b32.i2p"
+	}
+
+	return fmt.Sprintf("%x", na.Addr)
+}
+
+// String returns the host and port of the address.
+func (na *NetAddressV2) String() string {
+	return net.JoinHostPort(na.Host(), strconv.Itoa(int(na.Port)))
+}
+
+// ToLegacy returns the address as a legacy NetAddress, or nil when its network
+// can't be represented by an IPv6 address.
+func (na *NetAddressV2) ToLegacy() *NetAddress {
+	var ip net.IP
+	switch na.Network {
+	case NetworkIPv4, NetworkIPv6:
+		ip = net.IP(na.Addr)
+
+	case NetworkTorV2:
+		ip = make(net.IP, 0, net.IPv6len)
+		ip = append(ip, onionCatPrefix...)
+		ip = append(ip, na.Addr...)
+
+	default:
+		return nil
+	}
+
+	return NewNetAddressTimestamp(na.Timestamp, na.Services, ip, na.Port)
+}
+
+// NetAddressV2FromLegacy returns the passed legacy NetAddress as a
+// NetAddressV2.
+func NetAddressV2FromLegacy(na *NetAddress) *NetAddressV2 {
+	var network NetworkID
+	var addr []byte
+	if ip4 := na.IP.To4(); ip4 != nil {
+		network = NetworkIPv4
+		addr = ip4
+	} else if len(na.IP) == net.IPv6len &&
+		bytes.HasPrefix(na.IP, onionCatPrefix) {
+
+		network = NetworkTorV2
+		addr = na.IP[len(onionCatPrefix):]
+	} else {
+		network = NetworkIPv6
+		addr = na.IP.To16()
+	}
+
+	return &NetAddressV2{
+		Timestamp: na.Timestamp,
+		Services:  na.Services,
+		Network:   network,
+		Addr:      append([]byte(nil), addr...),
+		Port:      na.Port,
+	}
+}
+
+// NewNetAddressV2 returns a new NetAddressV2 using the provided network,
+// address, port, and supported services with the timestamp set to the current
+// time.
+func NewNetAddressV2(network NetworkID, addr []byte, port uint16,
+	services ServiceFlag) *NetAddressV2 {
+
+	return &NetAddressV2{
+		Timestamp: time.Unix(time.Now().Unix(), 0),
+		Services:  services,
+		Network:   network,
+		Addr:      addr,
+		Port:      port,
+	}
+}
+
+// NetAddressV2FromHost returns a new NetAddressV2 of the passed network for the
+// host as returned by Host, the port, and the supported services with the
+// timestamp set to the current time.  An error is returned when the host isn't
+// a valid address of the network.
+func NetAddressV2FromHost(network NetworkID, host string, port uint16,
+	services ServiceFlag) (*NetAddressV2, error) {
+
+	var addr []byte
+	switch network {
+	case NetworkIPv4, NetworkIPv6, NetworkCJDNS:
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid %v address %q", network,
+				host)
+		}
+		if network == NetworkIPv4 {
+			addr = ip.To4()
+		} else {
+			addr = ip.To16()
+		}
+
+	case NetworkTorV2:
+		host = strings.TrimSuffix(strings.ToLower(host), ".onion")
+		decoded, err := onionEncoding.DecodeString(strings.ToUpper(host))
+		if err != nil {
+			return nil, fmt.Errorf("invalid onion address %q: %v",
+				host, err)
+		}
+		addr = decoded
+
+	case NetworkTorV3:
+		return NewNetAddressTorV3(host, port, services)
+
+	case NetworkI2P:
+		host = strings.TrimSuffix(strings.ToLower(host), ".b32.i2p")
+		decoded, err := onionEncoding.DecodeString(strings.ToUpper(host))
+		if err != nil {
+			return nil, fmt.Errorf("invalid i2p address %q: %v", host,
+				err)
+		}
+		addr = decoded
+
+	default:
+		return nil, fmt.Errorf("unsupported network %v", network)
+	}
+
+	if len(addr) != networkAddrSizes[network] {
+		return nil, fmt.Errorf("invalid %v address %q", network, host)
+	}
+
+	return NewNetAddressV2(network, addr, port, services), nil
+}
+
+// NewNetAddressTorV3 returns a new NetAddressV2 for the passed Tor v3 onion
+// service host, with or without the .onion suffix.  An error is returned when
+// the host isn't a valid Tor v3 onion service address.
+func NewNetAddressTorV3(host string, port uint16,
+	services ServiceFlag) (*NetAddressV2, error) {
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".onion")
+	decoded, err := onionEncoding.DecodeString(strings.ToUpper(host))
+	if err != nil {
+		return nil, fmt.Errorf("invalid onion address %q: %v", host, err)
+	}
+	if len(decoded) != 32+2+1 || decoded[34] != torV3Version {
+		return nil, fmt.Errorf("invalid onion address %q: not a v3 "+
+			"address", host)
+	}
+	pubKey := decoded[:32]
+	if !bytes.Equal(decoded[32:34], torV3Checksum(pubKey)) {
+		return nil, fmt.Errorf("invalid onion address %q: bad checksum",
+			host)
+	}
+
+	return NewNetAddressV2(NetworkTorV3, pubKey, port, services), nil
+}
+
+// torV3Checksum returns the checksum of the Tor v3 onion service address with
+// the passed public key.
+func torV3Checksum(pubKey []byte) []byte {
+	h := sha3.New256()
+	h.Write([]byte(".onion checksum"))
+	h.Write(pubKey)
+	h.Write([]byte{torV3Version})
+	return h.Sum(nil)[:2]
+}
+
+// readNetAddressV2 reads an encoded NetAddressV2 from r.  Addresses of unknown
+// networks are decoded as is so they can be ignored by the caller, while the
+// ones of known networks with an invalid size are rejected.
+func readNetAddressV2(r io.Reader, pver uint32, na *NetAddressV2) error {
+	var timestamp uint32
+	err := readElement(r, &timestamp)
+	if err != nil {
+		return err
+	}
+	na.Timestamp = time.Unix(int64(timestamp), 0)
+
+	services, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	na.Services = ServiceFlag(services)
+
+	network, err := binarySerializer.Uint8(r)
+	if err != nil {
+		return err
+	}
+	na.Network = NetworkID(network)
+
+	na.Addr, err = ReadVarBytes(r, pver, MaxNetAddressV2AddrSize,
+		"NetAddressV2.Addr")
+	if err != nil {
+		return err
+	}
+	if size, ok := networkAddrSizes[na.Network]; ok && len(na.Addr) != size {
+		str := fmt.Sprintf("invalid %v address size [size %v, want %v]",
+			na.Network, len(na.Addr), size)
+		return messageError("readNetAddressV2", str)
+	}
+
+	var port [2]byte
+	_, err = io.ReadFull(r, port[:])
+	if err != nil {
+		return err
+	}
+	na.Port = binary.BigEndian.Uint16(port[:])
+
+	return nil
+}
+
+// writeNetAddressV2 serializes a NetAddressV2 to w.
+func writeNetAddressV2(w io.Writer, pver uint32, na *NetAddressV2) error {
+	err := writeElement(w, uint32(na.Timestamp.Unix()))
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(na.Services))
+	if err != nil {
+		return err
+	}
+
+	err = binarySerializer.PutUint8(w, uint8(na.Network))
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, na.Addr)
+	if err != nil {
+		return err
+	}
+
+	var port [2]byte
+	binary.BigEndian.PutUint16(port[:], na.Port)
+	_, err = w.Write(port[:])
+	return err
+}
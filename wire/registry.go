@@ -0,0 +1,158 @@
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MessageSpec describes a message type registered with RegisterMessage, along
+// with what a peer must have negotiated for the message to be sent to it.
+type MessageSpec struct {
+	// Command is the command of the message in the message header.
+	Command string
+
+	// MinProtocolVersion is the minimum protocol version negotiated with a
+	// peer for the message to be sent to it.
+	MinProtocolVersion uint32
+
+	// RequiredServices are the services a peer must advertise for the
+	// message to be sent to it.
+	RequiredServices ServiceFlag
+
+	// New returns a new empty message of the type, which is used to decode
+	// the messages read with the command.
+	New func() Message
+}
+
+// Supported returns whether the message can be sent to a peer which negotiated
+// the passed protocol version and advertised the passed services.
+func (spec *MessageSpec) Supported(pver uint32, services ServiceFlag) bool {
+	return pver >= spec.MinProtocolVersion &&
+		services&spec.RequiredServices == spec.RequiredServices
+}
+
+var (
+	// messageRegistryMtx protects messageRegistry.
+	messageRegistryMtx sync.RWMutex
+
+	// messageRegistry holds the registered message types by command.
+	messageRegistry = make(map[string]*MessageSpec)
+)
+
+// RegisterMessage registers a message type, which allows the messages with its
+// command to be read and the peers to only be sent the message when they
+// negotiated a protocol version and advertised services supporting it.  An
+// error is returned when the command is invalid or already registered.
+//
+// This function is safe for concurrent access.
+func RegisterMessage(spec MessageSpec) error {
+	if len(spec.Command) == 0 || len(spec.Command) > CommandSize {
+		return fmt.Errorf("command [%s] must be between 1 and %d bytes",
+			spec.Command, CommandSize)
+	}
+	if spec.New == nil {
+		return errors.New("message constructor must be set")
+	}
+
+	messageRegistryMtx.Lock()
+	defer messageRegistryMtx.Unlock()
+
+	if _, ok := messageRegistry[spec.Command]; ok {
+		return fmt.Errorf("command [%s] is already registered",
+			spec.Command)
+	}
+	messageRegistry[spec.Command] = &spec
+	return nil
+}
+
+// LookupMessage returns the spec of the message type registered with the passed
+// command, and whether there is one.
+//
+// This function is safe for concurrent access.
+func LookupMessage(command string) (MessageSpec, bool) {
+	messageRegistryMtx.RLock()
+	spec, ok := messageRegistry[command]
+	messageRegistryMtx.RUnlock()
+	if !ok {
+		return MessageSpec{}, false
+	}
+	return *spec, true
+}
+
+// MessageSupported returns whether a message with the passed command can be
+// sent to a peer which negotiated the passed protocol version and advertised
+// the passed services.  Messages which aren't registered have no requirements.
+//
+// This function is safe for concurrent access.
+func MessageSupported(command string, pver uint32, services ServiceFlag) bool {
+	spec, ok := LookupMessage(command)
+	return !ok || spec.Supported(pver, services)
+}
+
+// init registers the message types of the package.
+func init() {
+	specs := []MessageSpec{
+		{Command: CmdVersion, New: func() Message { return &MsgVersion{} }},
+		{Command: CmdVerAck, New: func() Message { return &MsgVerAck{} }},
+		{Command: CmdSendAddrV2, MinProtocolVersion: AddrV2Version,
+			New: func() Message { return &MsgSendAddrV2{} }},
+		{Command: CmdGetAddr, New: func() Message { return &MsgGetAddr{} }},
+		{Command: CmdAddrV2, MinProtocolVersion: AddrV2Version,
+			New: func() Message { return &MsgAddrV2{} }},
+		{Command: CmdAddr, New: func() Message { return &MsgAddr{} }},
+		{Command: CmdGetBlocks, New: func() Message { return &MsgGetBlocks{} }},
+		{Command: CmdBlock, New: func() Message { return &MsgBlock{} }},
+		{Command: CmdInv, New: func() Message { return &MsgInv{} }},
+		{Command: CmdGetData, New: func() Message { return &MsgGetData{} }},
+		{Command: CmdNotFound, New: func() Message { return &MsgNotFound{} }},
+		{Command: CmdTx, New: func() Message { return &MsgTx{} }},
+		{Command: CmdPing, New: func() Message { return &MsgPing{} }},
+		{Command: CmdPong, MinProtocolVersion: BIP0031Version + 1,
+			New: func() Message { return &MsgPong{} }},
+		{Command: CmdGetHeaders, New: func() Message { return &MsgGetHeaders{} }},
+		{Command: CmdHeaders, New: func() Message { return &MsgHeaders{} }},
+		{Command: CmdAlert, New: func() Message { return &MsgAlert{} }},
+		{Command: CmdMemPool, MinProtocolVersion: BIP0035Version,
+			New: func() Message { return &MsgMemPool{} }},
+		{Command: CmdFilterAdd, MinProtocolVersion: BIP0037Version,
+			RequiredServices: SFNodeBloom,
+			New:              func() Message { return &MsgFilterAdd{} }},
+		{Command: CmdFilterClear, MinProtocolVersion: BIP0037Version,
+			RequiredServices: SFNodeBloom,
+			New:              func() Message { return &MsgFilterClear{} }},
+		{Command: CmdFilterLoad, MinProtocolVersion: BIP0037Version,
+			RequiredServices: SFNodeBloom,
+			New:              func() Message { return &MsgFilterLoad{} }},
+		{Command: CmdMerkleBlock, MinProtocolVersion: BIP0037Version,
+			New: func() Message { return &MsgMerkleBlock{} }},
+		{Command: CmdReject, MinProtocolVersion: RejectVersion,
+			New: func() Message { return &MsgReject{} }},
+		{Command: CmdSendHeaders, MinProtocolVersion: SendHeadersVersion,
+			New: func() Message { return &MsgSendHeaders{} }},
+		{Command: CmdFeeFilter, MinProtocolVersion: FeeFilterVersion,
+			New: func() Message { return &MsgFeeFilter{} }},
+		{Command: CmdGetCFilters, RequiredServices: SFNodeCF,
+			New: func() Message { return &MsgGetCFilters{} }},
+		{Command: CmdGetCFHeaders, RequiredServices: SFNodeCF,
+			New: func() Message { return &MsgGetCFHeaders{} }},
+		{Command: CmdGetCFCheckpt, RequiredServices: SFNodeCF,
+			New: func() Message { return &MsgGetCFCheckpt{} }},
+		{Command: CmdCFilter, New: func() Message { return &MsgCFilter{} }},
+		{Command: CmdCFHeaders, New: func() Message { return &MsgCFHeaders{} }},
+		{Command: CmdCFCheckpt, New: func() Message { return &MsgCFCheckpt{} }},
+		{Command: CmdSendCmpct, MinProtocolVersion: ShortIDsBlocksVersion,
+			New: func() Message { return &MsgSendCmpct{} }},
+		{Command: CmdCmpctBlock, MinProtocolVersion: ShortIDsBlocksVersion,
+			New: func() Message { return &MsgCmpctBlock{} }},
+		{Command: CmdGetBlockTxn, MinProtocolVersion: ShortIDsBlocksVersion,
+			New: func() Message { return &MsgGetBlockTxn{} }},
+		{Command: CmdBlockTxn, MinProtocolVersion: ShortIDsBlocksVersion,
+			New: func() Message { return &MsgBlockTxn{} }},
+	}
+	for _, spec := range specs {
+		if err := RegisterMessage(spec); err != nil {
+			panic(err)
+		}
+	}
+}
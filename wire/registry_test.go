@@ -0,0 +1,85 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestRegisterMessage ensures registered message types can be read and invalid
+// or duplicate registrations are rejected.
+func TestRegisterMessage(t *testing.T) {
+	const command = "registrytest"
+	newMsg := func() Message { return &fakeMessage{command: command} }
+	err := RegisterMessage(MessageSpec{
+		Command:            command,
+		MinProtocolVersion: ProtocolVersion,
+		RequiredServices:   SFNodeCF,
+		New:                newMsg,
+	})
+	if err != nil {
+		t.Fatalf("RegisterMessage: unexpected error: %v", err)
+	}
+
+	// Messages with the registered command are decoded.
+	var buf bytes.Buffer
+	err = WriteMessage(&buf, newMsg(), ProtocolVersion, MainNet)
+	if err != nil {
+		t.Fatalf("WriteMessage: unexpected error: %v", err)
+	}
+	msg, _, err := ReadMessage(&buf, ProtocolVersion, MainNet)
+	if err != nil {
+		t.Fatalf("ReadMessage: unexpected error: %v", err)
+	}
+	if msg.Command() != command {
+		t.Fatalf("ReadMessage: unexpected command %v", msg.Command())
+	}
+
+	tests := []struct {
+		name string
+		spec MessageSpec
+	}{
+		{"duplicate", MessageSpec{Command: command, New: newMsg}},
+		{"builtin duplicate", MessageSpec{Command: CmdVersion, New: newMsg}},
+		{"empty command", MessageSpec{New: newMsg}},
+		{"long command", MessageSpec{Command: "somethingtoolong", New: newMsg}},
+		{"no constructor", MessageSpec{Command: "noconstruct"}},
+	}
+	for _, test := range tests {
+		if err := RegisterMessage(test.spec); err == nil {
+			t.Errorf("RegisterMessage %s: registration was accepted",
+				test.name)
+		}
+	}
+}
+
+// TestMessageSupported ensures the requirements of the registered message types
+// are applied to the protocol version and services of peers.
+func TestMessageSupported(t *testing.T) {
+	tests := []struct {
+		command  string
+		pver     uint32
+		services ServiceFlag
+		want     bool
+	}{
+		{CmdVersion, 0, 0, true},
+		{CmdPong, BIP0031Version, 0, false},
+		{CmdPong, BIP0031Version + 1, 0, true},
+		{CmdFeeFilter, SendHeadersVersion, 0, false},
+		{CmdFeeFilter, FeeFilterVersion, 0, true},
+		{CmdFilterLoad, ProtocolVersion, SFNodeNetwork, false},
+		{CmdFilterLoad, BIP0035Version, SFNodeBloom, false},
+		{CmdFilterLoad, BIP0037Version, SFNodeNetwork | SFNodeBloom, true},
+		{CmdGetCFilters, ProtocolVersion, SFNodeBloom, false},
+		{CmdGetCFilters, ProtocolVersion, SFNodeCF, true},
+		{CmdAddrV2, ShortIDsBlocksVersion, 0, false},
+		{CmdAddrV2, AddrV2Version, 0, true},
+		{"unregistered", 0, 0, true},
+	}
+	for i, test := range tests {
+		got := MessageSupported(test.command, test.pver, test.services)
+		if got != test.want {
+			t.Errorf("MessageSupported #%d (%s): got %v, want %v", i,
+				test.command, got, test.want)
+		}
+	}
+}
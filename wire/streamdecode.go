@@ -0,0 +1,181 @@
+package wire
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// streamChunkSize is the maximum number of bytes a streamReader reads from the
+// underlying reader at once.  It keeps the number of reads low when decoding
+// directly from a network connection while bounding the memory used.
+const streamChunkSize = 64 * 1024
+
+// StreamDecoder is implemented by the messages which can be decoded directly
+// from a stream, such as a network connection, without first buffering their
+// whole payload in memory.  The limits on the sizes and counts of their fields
+// are then enforced against the bytes remaining in the payload as they are
+// decoded, so a peer can't cause large allocations by merely announcing a
+// large message.
+type StreamDecoder interface {
+	Message
+
+	// BtcDecodeStream decodes the message from at most size bytes of r,
+	// which may all be consumed even when the message is shorter.
+	BtcDecodeStream(r io.Reader, size uint32, pver uint32, enc MessageEncoding) error
+}
+
+// streamReader reads at most a known number of bytes of the payload of a
+// message from an underlying reader.  The bytes are read in chunks which never
+// extend past the end of the payload, and are hashed as they are read when a
+// hasher is set so the checksum of the payload can be verified without
+// buffering it.
+type streamReader struct {
+	r         io.Reader
+	remaining uint32 // bytes of the payload not yet read from r
+	buf       []byte
+	pos       int
+	hasher    hash.Hash
+	n         int // bytes read from r
+}
+
+// newStreamReader returns a new streamReader for the size bytes of r, which
+// are hashed when a hasher is passed.
+func newStreamReader(r io.Reader, size uint32, hasher hash.Hash) *streamReader {
+	chunkSize := uint32(streamChunkSize)
+	if size < chunkSize {
+		chunkSize = size
+	}
+	return &streamReader{
+		r:         r,
+		remaining: size,
+		buf:       make([]byte, 0, chunkSize),
+		hasher:    hasher,
+	}
+}
+
+// remainingBytes returns the number of bytes of the payload which haven't been
+// consumed yet.
+func (sr *streamReader) remainingBytes() uint32 {
+	return sr.remaining + uint32(len(sr.buf)-sr.pos)
+}
+
+// fill reads the next chunk of the payload from the underlying reader.  It
+// returns an error when the whole payload has already been read.
+func (sr *streamReader) fill() error {
+	if sr.remaining == 0 {
+		return messageError("streamReader.Read", "read past the end "+
+			"of the payload")
+	}
+
+	chunkSize := uint32(cap(sr.buf))
+	if sr.remaining < chunkSize {
+		chunkSize = sr.remaining
+	}
+	n, err := sr.r.Read(sr.buf[:chunkSize])
+	sr.buf = sr.buf[:n]
+	sr.pos = 0
+	sr.n += n
+	sr.remaining -= uint32(n)
+	if sr.hasher != nil {
+		sr.hasher.Write(sr.buf)
+	}
+	if n > 0 {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Read reads the next bytes of the payload into p.
+//
+// This is part of the io.Reader interface implementation.
+func (sr *streamReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if sr.pos == len(sr.buf) {
+		if err := sr.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, sr.buf[sr.pos:])
+	sr.pos += n
+	return n, nil
+}
+
+// discard consumes the rest of the payload.
+func (sr *streamReader) discard() error {
+	sr.pos = len(sr.buf)
+	for sr.remaining > 0 {
+		if err := sr.fill(); err != nil {
+			return err
+		}
+		sr.pos = len(sr.buf)
+	}
+	return nil
+}
+
+// checksum returns the checksum of the bytes read so far, which is the first
+// four bytes of their double sha256 hash.
+func (sr *streamReader) checksum() []byte {
+	first := sr.hasher.Sum(nil)
+	second := sha256.Sum256(first)
+	return second[:4]
+}
+
+// limitStream returns a streamReader for at most size bytes of r.  It is r
+// itself when r already is a streamReader with no more than size bytes left,
+// such as the one of the payload of a message, so the bytes aren't copied
+// through a second buffer.
+func limitStream(r io.Reader, size uint32) *streamReader {
+	if sr, ok := r.(*streamReader); ok && sr.remainingBytes() <= size {
+		return sr
+	}
+	return newStreamReader(r, size, nil)
+}
+
+// checkRemaining returns an error when r is a stream limited to the payload of
+// a message and the passed count of items of at least minSize bytes each can't
+// fit in the bytes remaining in the payload.  This allows rejecting the count
+// before allocating anything for the items.
+func checkRemaining(r io.Reader, count uint64, minSize uint32, funcName, fieldName string) error {
+	sr, ok := r.(*streamReader)
+	if !ok {
+		return nil
+	}
+	remaining := sr.remainingBytes()
+	if count > uint64(remaining/minSize) {
+		str := fmt.Sprintf("too many %s to fit into the remaining "+
+			"%d bytes of the payload [count %d]", fieldName,
+			remaining, count)
+		return messageError(funcName, str)
+	}
+	return nil
+}
+
+// BtcDecodeStream decodes a block from at most size bytes of r using the
+// bitcoin protocol encoding into the receiver.  Unlike BtcDecode, the size and
+// count limits are enforced against the bytes remaining in the size bytes as
+// the block is decoded, which makes it suitable for decoding a large block
+// directly from a network connection.  All of the size bytes may be consumed
+// from r even when the block is shorter.
+//
+// This is part of the StreamDecoder interface implementation.
+func (msg *MsgBlock) BtcDecodeStream(r io.Reader, size uint32, pver uint32, enc MessageEncoding) error {
+	return msg.BtcDecode(limitStream(r, size), pver, enc)
+}
+
+// BtcDecodeStream decodes a transaction from at most size bytes of r using the
+// bitcoin protocol encoding into the receiver.  Unlike BtcDecode, the size and
+// count limits are enforced against the bytes remaining in the size bytes as
+// the transaction is decoded.  All of the size bytes may be consumed from r
+// even when the transaction is shorter.
+//
+// This is part of the StreamDecoder interface implementation.
+func (msg *MsgTx) BtcDecodeStream(r io.Reader, size uint32, pver uint32, enc MessageEncoding) error {
+	return msg.BtcDecode(limitStream(r, size), pver, enc)
+}
@@ -0,0 +1,185 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/lbryio/lbcd/chaincfg/chainhash"
+)
+
+// TestReadMessageStream tests that messages read with ReadMessageStreamN match
+// the ones read with ReadMessageWithEncodingN and leave the stream in sync.
+func TestReadMessageStream(t *testing.T) {
+	pver := ProtocolVersion
+	btcnet := MainNet
+
+	tests := []Message{
+		&blockOne,
+		multiTx,
+		NewMsgPing(123123),
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		var buf bytes.Buffer
+		_, err := WriteMessageN(&buf, test, pver, btcnet)
+		if err != nil {
+			t.Errorf("WriteMessage #%d error %v", i, err)
+			continue
+		}
+		msgLen := buf.Len()
+		_, err = WriteMessageN(&buf, NewMsgVerAck(), pver, btcnet)
+		if err != nil {
+			t.Errorf("WriteMessage #%d error %v", i, err)
+			continue
+		}
+
+		r := iotest.HalfReader(bytes.NewReader(buf.Bytes()))
+		n, msg, _, err := ReadMessageStreamN(r, pver, btcnet,
+			WitnessEncoding)
+		if err != nil {
+			t.Errorf("ReadMessageStreamN #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(msg, test) {
+			t.Errorf("ReadMessageStreamN #%d\n got: %v want: %v", i,
+				spew.Sdump(msg), spew.Sdump(test))
+			continue
+		}
+		if n != msgLen {
+			t.Errorf("ReadMessageStreamN #%d unexpected num bytes "+
+				"read - got %d, want %d", i, n, msgLen)
+			continue
+		}
+
+		msg, _, err = ReadMessage(r, pver, btcnet)
+		if err != nil {
+			t.Errorf("ReadMessage #%d error %v", i, err)
+			continue
+		}
+		if _, ok := msg.(*MsgVerAck); !ok {
+			t.Errorf("ReadMessage #%d unexpected message %T", i, msg)
+		}
+	}
+}
+
+// TestReadMessageStreamErrors tests that invalid streamed messages are rejected
+// while leaving the stream in sync.
+func TestReadMessageStreamErrors(t *testing.T) {
+	pver := ProtocolVersion
+	btcnet := MainNet
+
+	var block bytes.Buffer
+	_, err := WriteMessageN(&block, &blockOne, pver, btcnet)
+	if err != nil {
+		t.Fatalf("WriteMessage error %v", err)
+	}
+
+	// Corrupt the checksum.
+	badChecksum := append([]byte{}, block.Bytes()...)
+	badChecksum[MessageHeaderSize-1] ^= 0xff
+
+	// Announce more transactions than fit in the payload, which is
+	// rejected before decoding them.
+	badCount := append([]byte{}, block.Bytes()...)
+	badCount[MessageHeaderSize+MaxBlockHeaderPayload] = 0x20
+
+	// Append trailing bytes to the payload, which are ignored.
+	trailing := append([]byte{}, block.Bytes()[:MessageHeaderSize]...)
+	payload := append(block.Bytes()[MessageHeaderSize:], 0x00, 0x00)
+	littleEndian.PutUint32(trailing[16:20], uint32(len(payload)))
+	copy(trailing[20:24], chainhash.DoubleHashB(payload))
+	trailing = append(trailing, payload...)
+
+	tests := []struct {
+		buf   []byte
+		valid bool
+	}{
+		{badChecksum, false},
+		{badCount, false},
+		{trailing, true},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		buf := bytes.NewBuffer(append([]byte{}, test.buf...))
+		_, err := WriteMessageN(buf, NewMsgVerAck(), pver, btcnet)
+		if err != nil {
+			t.Errorf("WriteMessage #%d error %v", i, err)
+			continue
+		}
+
+		n, _, _, err := ReadMessageStreamN(buf, pver, btcnet,
+			WitnessEncoding)
+		if _, ok := err.(*MessageError); ok == test.valid {
+			t.Errorf("ReadMessageStreamN #%d unexpected error %v",
+				i, err)
+			continue
+		}
+		if test.valid && err != nil {
+			t.Errorf("ReadMessageStreamN #%d error %v", i, err)
+			continue
+		}
+		if n != len(test.buf) {
+			t.Errorf("ReadMessageStreamN #%d unexpected num bytes "+
+				"read - got %d, want %d", i, n, len(test.buf))
+			continue
+		}
+
+		msg, _, err := ReadMessage(buf, pver, btcnet)
+		if err != nil {
+			t.Errorf("ReadMessage #%d error %v", i, err)
+			continue
+		}
+		if _, ok := msg.(*MsgVerAck); !ok {
+			t.Errorf("ReadMessage #%d unexpected message %T", i, msg)
+		}
+	}
+}
+
+// TestBtcDecodeStream tests that transactions decoded with BtcDecodeStream
+// match the ones decoded with BtcDecode, and that the counts which can't fit in
+// the passed size are rejected.
+func TestBtcDecodeStream(t *testing.T) {
+	pver := ProtocolVersion
+
+	var buf bytes.Buffer
+	if err := multiTx.BtcEncode(&buf, pver, WitnessEncoding); err != nil {
+		t.Fatalf("BtcEncode error %v", err)
+	}
+	size := uint32(buf.Len())
+
+	// The bytes following the transaction aren't consumed.
+	r := bytes.NewReader(append(buf.Bytes(), 0x01, 0x02))
+	var tx MsgTx
+	err := tx.BtcDecodeStream(iotest.HalfReader(r), size, pver,
+		WitnessEncoding)
+	if err != nil {
+		t.Fatalf("BtcDecodeStream error %v", err)
+	}
+	if !reflect.DeepEqual(&tx, multiTx) {
+		t.Fatalf("BtcDecodeStream\n got: %v want: %v", spew.Sdump(&tx),
+			spew.Sdump(multiTx))
+	}
+	if r.Len() != 2 {
+		t.Fatalf("BtcDecodeStream left %d bytes, want 2", r.Len())
+	}
+
+	// A transaction truncated by the size can't be decoded.
+	r = bytes.NewReader(buf.Bytes())
+	err = tx.BtcDecodeStream(r, size-1, pver, WitnessEncoding)
+	if err == nil {
+		t.Fatal("BtcDecodeStream succeeded with a truncated size")
+	}
+
+	// Announcing more inputs than fit in the size is rejected.
+	badCount := []byte{0x01, 0x00, 0x00, 0x00, 0xfd, 0x00, 0x01}
+	r = bytes.NewReader(badCount)
+	err = tx.BtcDecodeStream(r, uint32(len(badCount)), pver, BaseEncoding)
+	if _, ok := err.(*MessageError); !ok {
+		t.Fatalf("BtcDecodeStream unexpected error %v", err)
+	}
+}